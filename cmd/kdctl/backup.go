@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"text/tabwriter"
+
+	"github.com/hewenyu/kong-discovery/internal/apihandler"
+	"github.com/hewenyu/kong-discovery/internal/etcdclient"
+)
+
+// runBackup 下载发现数据的备份归档
+func runBackup(client *adminClient, args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("backup", flag.ContinueOnError)
+	output := fs.String("o", "", "输出文件，默认为 kong-discovery-<修订版本>.tar.gz，-表示标准输出")
+	kinds := fs.String("kinds", "", "只备份指定的数据类别，以逗号分隔：namespaces、config、catalog、dns、services")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), transferTimeout)
+	defer cancel()
+
+	resp, err := client.transfer(ctx, http.MethodGet, "/v1/admin/backup"+kindsQuery(*kinds, false), "", nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	revision := resp.Header.Get(apihandler.HeaderBackupRevision)
+	if *output == "-" {
+		_, err := io.Copy(out, resp.Body)
+		return err
+	}
+
+	file := *output
+	if file == "" {
+		file = fmt.Sprintf("kong-discovery-%s.tar.gz", revision)
+	}
+	f, err := os.OpenFile(file, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return fmt.Errorf("创建备份文件失败: %w", err)
+	}
+	size, err := io.Copy(f, resp.Body)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(file)
+		return fmt.Errorf("写入备份文件失败: %w", err)
+	}
+
+	fmt.Fprintf(out, "已备份到 %s（修订版本 %s，%d 字节）\n", file, revision, size)
+	return nil
+}
+
+// runRestore 上传备份归档恢复发现数据
+func runRestore(client *adminClient, args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("restore", flag.ContinueOnError)
+	file := fs.String("f", "", "备份文件，-表示标准输入")
+	kinds := fs.String("kinds", "", "只恢复指定的数据类别，以逗号分隔：namespaces、config、catalog、dns、services")
+	replace := fs.Bool("replace", false, "删除这些类别中备份里没有的键，使其与备份完全一致")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *file == "" {
+		return fmt.Errorf("用法: kdctl restore -f <文件> [--kinds 类别] [--replace]")
+	}
+
+	var body io.Reader = os.Stdin
+	if *file != "-" {
+		f, err := os.Open(*file)
+		if err != nil {
+			return fmt.Errorf("读取备份文件失败: %w", err)
+		}
+		defer f.Close()
+		body = f
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), transferTimeout)
+	defer cancel()
+
+	resp, err := client.transfer(ctx, http.MethodPost, "/v1/admin/restore"+kindsQuery(*kinds, *replace), "application/gzip", body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var result apihandler.RestoreResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil || result.Result == nil {
+		return fmt.Errorf("解析响应失败(状态码%d)", resp.StatusCode)
+	}
+
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "KIND\tRESTORED\tDELETED")
+	for _, kind := range etcdclient.BackupKinds {
+		restored, ok := result.Result.Restored[kind]
+		if !ok {
+			continue
+		}
+		fmt.Fprintf(w, "%s\t%d\t%d\n", kind, restored, result.Result.Deleted[kind])
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "已从修订版本 %d 的备份恢复\n", result.Result.Revision)
+	return nil
+}
+
+// kindsQuery 生成备份和恢复请求的查询参数
+func kindsQuery(kinds string, replace bool) string {
+	query := url.Values{}
+	if kinds != "" {
+		query.Set("kinds", kinds)
+	}
+	if replace {
+		query.Set("replace", "true")
+	}
+	if len(query) == 0 {
+		return ""
+	}
+	return "?" + query.Encode()
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// 管理API请求的超时时间
+const requestTimeout = 10 * time.Second
+
+// transferTimeout 备份下载和恢复上传的超时时间
+const transferTimeout = 10 * time.Minute
+
+// adminClient 管理API的HTTP客户端
+type adminClient struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+// newAdminClient 创建管理API客户端
+func newAdminClient(baseURL string) *adminClient {
+	return &adminClient{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: &http.Client{},
+	}
+}
+
+// apiStatus 管理API响应中的通用字段
+type apiStatus struct {
+	Success bool   `json:"success"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Detail  string `json:"detail"`
+}
+
+// err 把失败响应转换为错误
+func (s *apiStatus) err(statusCode int) error {
+	msg := s.Message
+	if s.Detail != "" {
+		msg += ": " + s.Detail
+	}
+	return fmt.Errorf("管理API返回错误(状态码%d, %s): %s", statusCode, s.Code, msg)
+}
+
+// do 发送请求并把响应解析到result中，非2xx状态码或success为false时返回错误（result仍会被尽量填充）
+func (c *adminClient) do(method, path string, body, result interface{}) error {
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("序列化请求失败: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return fmt.Errorf("创建请求失败: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("请求管理API失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("读取响应失败: %w", err)
+	}
+
+	var status apiStatus
+	if err := json.Unmarshal(data, &status); err != nil {
+		return fmt.Errorf("解析响应失败(状态码%d): %w", resp.StatusCode, err)
+	}
+	if resp.StatusCode >= http.StatusBadRequest || !status.Success {
+		// 失败响应中也可能带有有用的数据（如批量操作的逐项结果）
+		if result != nil {
+			_ = json.Unmarshal(data, result)
+		}
+		return status.err(resp.StatusCode)
+	}
+
+	if result != nil {
+		if err := json.Unmarshal(data, result); err != nil {
+			return fmt.Errorf("解析响应失败: %w", err)
+		}
+	}
+	return nil
+}
+
+// stream 发起长连接GET请求，调用方负责关闭响应体
+func (c *adminClient) stream(ctx context.Context, path string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+path, nil)
+	if err != nil {
+		return nil, fmt.Errorf("创建请求失败: %w", err)
+	}
+	req.Header.Set("Accept", "text/event-stream")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("请求管理API失败: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("管理API返回错误(状态码%d)", resp.StatusCode)
+	}
+	return resp, nil
+}
+
+// transfer 发送非JSON的请求体（如备份归档），成功时返回响应，调用方负责关闭响应体
+func (c *adminClient) transfer(ctx context.Context, method, path, contentType string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return nil, fmt.Errorf("创建请求失败: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("请求管理API失败: %w", err)
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		defer resp.Body.Close()
+		var status apiStatus
+		data, _ := io.ReadAll(resp.Body)
+		if err := json.Unmarshal(data, &status); err != nil {
+			return nil, fmt.Errorf("管理API返回错误(状态码%d)", resp.StatusCode)
+		}
+		return nil, status.err(resp.StatusCode)
+	}
+	return resp, nil
+}
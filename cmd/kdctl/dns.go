@@ -0,0 +1,207 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/hewenyu/kong-discovery/internal/apihandler"
+	"github.com/hewenyu/kong-discovery/internal/etcdclient"
+)
+
+// runDNS 处理dns子命令
+func runDNS(client *adminClient, args []string, out io.Writer) error {
+	if len(args) == 0 || args[0] != "record" {
+		return fmt.Errorf("用法: kdctl dns record ls|add|rm|apply")
+	}
+	args = args[1:]
+	if len(args) == 0 {
+		return fmt.Errorf("用法: kdctl dns record ls|add|rm|apply")
+	}
+
+	switch args[0] {
+	case "ls", "list":
+		fs := flag.NewFlagSet("dns record ls", flag.ContinueOnError)
+		domain := fs.String("domain", "", "按域名过滤")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+
+		path := "/v1/admin/dns/records"
+		if *domain != "" {
+			path += "?domain=" + url.QueryEscape(*domain)
+		}
+
+		var resp apihandler.DNSRecordsResponse
+		if err := client.do(http.MethodGet, path, nil, &resp); err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "DOMAIN\tTYPE\tVALUE\tTTL\tVIEW\tREVISION")
+		for _, r := range resp.Records {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\t%d\n", r.Domain, r.Type, r.Value, r.TTL, r.View, r.Revision)
+		}
+		return w.Flush()
+
+	case "add":
+		if len(args) < 2 {
+			return fmt.Errorf("用法: kdctl dns record add <域名> --type A --value IP [--ttl 300] [--view 视图]")
+		}
+		domain := args[1]
+
+		fs := flag.NewFlagSet("dns record add", flag.ContinueOnError)
+		recordType := fs.String("type", "A", "记录类型")
+		value := fs.String("value", "", "记录值")
+		ttl := fs.Int("ttl", 0, "TTL（秒），0表示使用服务端默认值")
+		view := fs.String("view", "", "视图名称")
+		namespace := fs.String("namespace", "", "命名空间")
+		if err := fs.Parse(args[2:]); err != nil {
+			return err
+		}
+
+		record := &etcdclient.DNSRecord{
+			Type:      *recordType,
+			Value:     *value,
+			TTL:       *ttl,
+			View:      *view,
+			Namespace: *namespace,
+		}
+		if err := client.do(http.MethodPut, "/v1/admin/dns/records/"+url.PathEscape(domain), record, nil); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "已保存DNS记录 %s %s %s\n", domain, *recordType, *value)
+		return nil
+
+	case "rm", "delete":
+		if len(args) < 3 {
+			return fmt.Errorf("用法: kdctl dns record rm <域名> <类型> [--view 视图] [--force] [--dry-run]")
+		}
+		domain, recordType := args[1], args[2]
+
+		fs := flag.NewFlagSet("dns record rm", flag.ContinueOnError)
+		view := fs.String("view", "", "视图名称")
+		force := fs.Bool("force", false, "强制删除由服务自动维护的记录")
+		dryRun := fs.Bool("dry-run", false, "只显示将被删除的记录，不做修改")
+		if err := fs.Parse(args[3:]); err != nil {
+			return err
+		}
+
+		query := url.Values{}
+		if *view != "" {
+			query.Set("view", *view)
+		}
+		if *force {
+			query.Set("force", "true")
+		}
+		if *dryRun {
+			query.Set("dry_run", "true")
+		}
+		path := "/v1/admin/dns/records/" + url.PathEscape(domain) + "/" + url.PathEscape(recordType)
+		if len(query) > 0 {
+			path += "?" + query.Encode()
+		}
+
+		if *dryRun {
+			var resp apihandler.DryRunResponse
+			if err := client.do(http.MethodDelete, path, nil, &resp); err != nil {
+				return err
+			}
+			return printChangePlan(out, resp.Plan)
+		}
+		if err := client.do(http.MethodDelete, path, nil, nil); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "已删除DNS记录 %s %s\n", domain, recordType)
+		return nil
+
+	case "apply":
+		fs := flag.NewFlagSet("dns record apply", flag.ContinueOnError)
+		file := fs.String("f", "", "批量操作文件（JSON，格式同POST /admin/dns/records/batch），-表示标准输入")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		if *file == "" {
+			return fmt.Errorf("用法: kdctl dns record apply -f <文件>")
+		}
+		return applyDNSRecordBatch(client, *file, out)
+
+	default:
+		return fmt.Errorf("未知的dns record子命令: %s", args[0])
+	}
+}
+
+// applyDNSRecordBatch 读取批量操作文件并在一个事务中提交，输出每一项的结果
+func applyDNSRecordBatch(client *adminClient, file string, out io.Writer) error {
+	var data []byte
+	var err error
+	if file == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(file)
+	}
+	if err != nil {
+		return fmt.Errorf("读取批量操作文件失败: %w", err)
+	}
+
+	var req apihandler.DNSRecordBatchRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		return fmt.Errorf("解析批量操作文件失败: %w", err)
+	}
+
+	var resp apihandler.DNSRecordBatchResponse
+	applyErr := client.do(http.MethodPost, "/v1/admin/dns/records/batch", &req, &resp)
+
+	if len(resp.Results) > 0 {
+		w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "#\tOP\tDOMAIN\tTYPE\tSTATUS\tERROR")
+		for _, r := range resp.Results {
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\n", r.Index, r.Op, r.Domain, r.Type, r.Status, r.Error)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+	if applyErr != nil {
+		return applyErr
+	}
+
+	fmt.Fprintf(out, "已应用%d项DNS记录操作\n", len(resp.Results))
+	return nil
+}
+
+// printChangePlan 输出dry-run报告的影响范围
+func printChangePlan(out io.Writer, plan *etcdclient.ChangePlan) error {
+	if plan == nil {
+		return nil
+	}
+
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "KIND\tNAME\tDETAIL")
+	for _, i := range plan.Instances {
+		fmt.Fprintf(w, "instance\t%s/%s\t%s:%d\n", i.ServiceName, i.InstanceID, i.IPAddress, i.Port)
+	}
+	for _, r := range plan.Records {
+		fmt.Fprintf(w, "record\t%s %s\t%s %s\n", r.Domain, r.Type, r.Value, r.View)
+	}
+	for _, z := range plan.Zones {
+		fmt.Fprintf(w, "zone\t%s\t%s\n", z.Zone, z.Namespace)
+	}
+	for _, s := range plan.Services {
+		fmt.Fprintf(w, "service\t%s\t没有剩余实例\n", s)
+	}
+	for _, a := range plan.Aliases {
+		fmt.Fprintf(w, "alias\t%s\t%s\n", a.ServiceName, strings.Join(a.Targets(), ","))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	fmt.Fprintln(out, "dry-run，未做任何修改")
+	return nil
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/hewenyu/kong-discovery/internal/config"
+	"github.com/miekg/dns"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// 诊断检查的超时时间
+const doctorTimeout = 3 * time.Second
+
+// checkResult 单项诊断检查的结果
+type checkResult struct {
+	Name   string
+	Err    error
+	Detail string
+}
+
+// runDoctor 根据配置文件检查etcd、DNS端口和上游DNS的连通性
+func runDoctor(args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("doctor", flag.ContinueOnError)
+	configFile := fs.String("config", "", "配置文件路径")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	cfg, err := config.LoadConfig(*configFile)
+	if err != nil {
+		return fmt.Errorf("加载配置失败: %w", err)
+	}
+
+	var results []checkResult
+	results = append(results, checkEtcd(cfg)...)
+	for _, listener := range cfg.DNSListeners() {
+		results = append(results, checkDNSPort(listener))
+	}
+	for _, upstream := range upstreamServers(cfg) {
+		results = append(results, checkUpstream(upstream))
+	}
+
+	failed := 0
+	for _, result := range results {
+		status := "OK"
+		detail := result.Detail
+		if result.Err != nil {
+			status = "FAIL"
+			detail = result.Err.Error()
+			failed++
+		}
+		fmt.Fprintf(out, "[%-4s] %-40s %s\n", status, result.Name, detail)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d项检查失败", failed)
+	}
+	return nil
+}
+
+// checkEtcd 检查每个etcd节点是否可达
+func checkEtcd(cfg *config.Config) []checkResult {
+	client, err := clientv3.New(clientv3.Config{
+		Endpoints:   cfg.Etcd.Endpoints,
+		DialTimeout: doctorTimeout,
+		Username:    cfg.Etcd.Username,
+		Password:    cfg.Etcd.Password,
+	})
+	if err != nil {
+		return []checkResult{{Name: "etcd", Err: err}}
+	}
+	defer client.Close()
+
+	results := make([]checkResult, 0, len(cfg.Etcd.Endpoints))
+	for _, endpoint := range cfg.Etcd.Endpoints {
+		ctx, cancel := context.WithTimeout(context.Background(), doctorTimeout)
+		status, err := client.Status(ctx, endpoint)
+		cancel()
+
+		result := checkResult{Name: "etcd " + endpoint, Err: err}
+		if err == nil {
+			result.Detail = fmt.Sprintf("version %s, leader %x", status.Version, status.Leader)
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// checkDNSPort 向本机DNS服务器的一个监听地址发送一次查询，确认端口在监听并能应答
+func checkDNSPort(listener config.Listener) checkResult {
+	host := listener.Address
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		host = "127.0.0.1"
+	}
+	addr := net.JoinHostPort(host, strconv.Itoa(listener.Port))
+
+	protocol := listener.Protocol
+	if protocol == "both" || protocol == "" {
+		protocol = "udp"
+	}
+
+	rtt, err := probeDNS(addr, protocol)
+	result := checkResult{Name: "dns " + protocol + "://" + addr, Err: err}
+	if err == nil {
+		result.Detail = "rtt " + rtt.String()
+	}
+	return result
+}
+
+// checkUpstream 检查上游DNS服务器是否可达
+func checkUpstream(addr string) checkResult {
+	rtt, err := probeDNS(addr, "udp")
+	result := checkResult{Name: "upstream " + addr, Err: err}
+	if err == nil {
+		result.Detail = "rtt " + rtt.String()
+	}
+	return result
+}
+
+// probeDNS 发送一次根域NS查询，任何应答都视为可达
+func probeDNS(addr, protocol string) (time.Duration, error) {
+	c := &dns.Client{Net: protocol, Timeout: doctorTimeout}
+	m := new(dns.Msg)
+	m.SetQuestion(".", dns.TypeNS)
+
+	_, rtt, err := c.Exchange(m, addr)
+	return rtt, err
+}
+
+// upstreamServers 汇总配置中的全局上游和条件转发上游，去重后返回
+func upstreamServers(cfg *config.Config) []string {
+	seen := make(map[string]bool)
+	var servers []string
+	add := func(addr string) {
+		if addr != "" && !seen[addr] {
+			seen[addr] = true
+			servers = append(servers, addr)
+		}
+	}
+
+	add(cfg.DNS.UpstreamDNS)
+	for _, addr := range cfg.DNS.Upstream.Servers {
+		add(addr)
+	}
+	for _, rule := range cfg.DNS.ForwardRules {
+		for _, addr := range rule.Servers {
+			add(addr)
+		}
+	}
+	return servers
+}
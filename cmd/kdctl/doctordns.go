@@ -0,0 +1,440 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/hewenyu/kong-discovery/internal/apihandler"
+	"github.com/hewenyu/kong-discovery/internal/etcdclient"
+	"github.com/miekg/dns"
+)
+
+// DNS一致性检查发现的不一致类别
+const (
+	findingMissingSRV     = "missing_srv"     // 可路由的实例没有出现在SRV应答中
+	findingStaleSRV       = "stale_srv"       // SRV应答中的目标不是已注册的实例，或目标地址与实例地址不符
+	findingMissingAddress = "missing_address" // 服务有该地址族的可路由实例，但A/AAAA应答为空
+	findingStaleAddress   = "stale_address"   // A/AAAA应答中的地址不属于任何已注册的实例
+	findingMissingPTR     = "missing_ptr"     // 实例地址没有PTR记录
+	findingWrongPTR       = "wrong_ptr"       // 实例地址的PTR记录指向其他名称
+	findingStaleRecord    = "stale_record"    // 由服务维护的A/AAAA/PTR记录指向已不属于任何实例的地址
+)
+
+// dnsFinding DNS一致性检查发现的一项不一致
+type dnsFinding struct {
+	Kind     string
+	Name     string
+	Detail   string
+	Repaired bool
+	Err      error
+
+	repair func() error // 自动修复的方法，为nil表示不能自动修复
+}
+
+// dnsDoctor 对照服务的注册实例检查DNS服务器的应答和由服务维护的DNS记录
+type dnsDoctor struct {
+	client   *adminClient
+	server   string // DNS服务器地址
+	service  string
+	checkPTR bool
+	findings []*dnsFinding
+}
+
+// runDoctorDNS 检查服务的A/AAAA、SRV和PTR记录与注册实例是否一致，--repair时修复可以修复的项，
+// 仍有不一致时返回错误，可以在部署后的CI步骤中使用
+func runDoctorDNS(client *adminClient, args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("doctor dns", flag.ContinueOnError)
+	service := fs.String("service", "", "要检查的服务名称")
+	server := fs.String("server", "127.0.0.1:53", "DNS服务器地址")
+	checkPTR := fs.Bool("ptr", false, "同时检查每个实例地址的PTR记录")
+	repair := fs.Bool("repair", false, "修复缺失的PTR记录，删除由服务维护但已过期的记录")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *service == "" {
+		return fmt.Errorf("用法: kdctl doctor dns --service <服务名> [--server 地址] [--ptr] [--repair]")
+	}
+
+	var resp apihandler.ServiceInstancesResponse
+	if err := client.do(http.MethodGet, "/v1/admin/services/"+url.PathEscape(*service), nil, &resp); err != nil {
+		return fmt.Errorf("获取服务实例失败: %w", err)
+	}
+	var records apihandler.DNSRecordsResponse
+	if err := client.do(http.MethodGet, "/v1/admin/dns/records", nil, &records); err != nil {
+		return fmt.Errorf("获取DNS记录失败: %w", err)
+	}
+
+	d := &dnsDoctor{client: client, server: *server, service: *service, checkPTR: *checkPTR}
+	if err := d.check(resp.Instances, records.Records); err != nil {
+		return err
+	}
+
+	unresolved := 0
+	for _, finding := range d.findings {
+		if *repair && finding.repair != nil {
+			finding.Err = finding.repair()
+			finding.Repaired = finding.Err == nil
+		}
+		if !finding.Repaired {
+			unresolved++
+		}
+	}
+
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "KIND\tNAME\tDETAIL\tSTATUS")
+	for _, finding := range d.findings {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", finding.Kind, finding.Name, finding.Detail, finding.status(*repair))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "检查了服务%s的%d个实例，发现%d项不一致，%d项已修复\n",
+		*service, len(resp.Instances), len(d.findings), len(d.findings)-unresolved)
+
+	if unresolved > 0 {
+		return fmt.Errorf("%d项DNS不一致未修复", unresolved)
+	}
+	return nil
+}
+
+// status 返回不一致项的处理状态
+func (f *dnsFinding) status(repair bool) string {
+	switch {
+	case f.Repaired:
+		return "已修复"
+	case f.Err != nil:
+		return "修复失败: " + f.Err.Error()
+	case f.repair == nil:
+		return "需要人工处理"
+	case repair:
+		return "未修复"
+	default:
+		return "可修复（--repair）"
+	}
+}
+
+// add 记录一项不一致
+func (d *dnsDoctor) add(kind, name, detail string, repair func() error) {
+	d.findings = append(d.findings, &dnsFinding{Kind: kind, Name: name, Detail: detail, repair: repair})
+}
+
+// check 按命名空间分组检查服务域名的应答，再检查PTR记录和由服务维护的记录
+func (d *dnsDoctor) check(instances []*etcdclient.ServiceInstance, records []*etcdclient.DNSRecordEntry) error {
+	registered := make(map[string]bool)
+	byDomain := make(map[string][]*etcdclient.ServiceInstance)
+	for _, instance := range instances {
+		registered[canonicalIP(instance.IPAddress)] = true
+		domain := d.service + "." + etcdclient.NamespaceOf(instance.Namespace) + etcdclient.ServiceDomainSuffix
+		byDomain[domain] = append(byDomain[domain], instance)
+	}
+
+	domains := make([]string, 0, len(byDomain))
+	for domain := range byDomain {
+		domains = append(domains, domain)
+	}
+	sort.Strings(domains)
+
+	owned := make(map[string]*etcdclient.DNSRecordEntry)
+	for _, record := range records {
+		if record.OwnedByService(d.service) {
+			owned[record.Domain+"/"+record.Type+"/"+record.View] = record
+		}
+	}
+
+	for _, domain := range domains {
+		if err := d.checkSRV(domain, byDomain[domain]); err != nil {
+			return err
+		}
+		if err := d.checkAddresses(domain, byDomain[domain], registered); err != nil {
+			return err
+		}
+		if d.checkPTR {
+			if err := d.checkPTRRecords(domain, byDomain[domain], owned); err != nil {
+				return err
+			}
+		}
+	}
+	d.checkOwnedRecords(records, registered)
+	return nil
+}
+
+// checkSRV 检查每个可路由实例都出现在SRV应答中，且应答中的目标都是已注册的实例，附加段中的地址与实例地址一致
+func (d *dnsDoctor) checkSRV(domain string, instances []*etcdclient.ServiceInstance) error {
+	m, err := d.query(domain, dns.TypeSRV)
+	if err != nil {
+		return err
+	}
+
+	glue := make(map[string][]string)
+	for _, rr := range m.Extra {
+		switch rr := rr.(type) {
+		case *dns.A:
+			glue[strings.ToLower(rr.Hdr.Name)] = append(glue[strings.ToLower(rr.Hdr.Name)], rr.A.String())
+		case *dns.AAAA:
+			glue[strings.ToLower(rr.Hdr.Name)] = append(glue[strings.ToLower(rr.Hdr.Name)], rr.AAAA.String())
+		}
+	}
+	answered := make(map[string]*dns.SRV)
+	for _, rr := range m.Answer {
+		if srv, ok := rr.(*dns.SRV); ok {
+			answered[strings.ToLower(srv.Target)] = srv
+		}
+	}
+
+	known := make(map[string]bool)
+	for _, instance := range instances {
+		target := strings.ToLower(dns.Fqdn(instanceTarget(domain, instance)))
+		known[target] = true
+		srv, ok := answered[target]
+		if !ok {
+			if instance.Routable() {
+				d.add(findingMissingSRV, domain, fmt.Sprintf("实例%s (%s)没有出现在SRV应答中", instance.InstanceID, hostPort(instance)), nil)
+			}
+			continue
+		}
+		if int(srv.Port) != instance.Port {
+			d.add(findingStaleSRV, domain, fmt.Sprintf("实例%s的SRV端口为%d，注册的端口为%d", instance.InstanceID, srv.Port, instance.Port), nil)
+		}
+		for _, addr := range glue[target] {
+			if !sameIP(addr, instance.IPAddress) {
+				d.add(findingStaleSRV, domain, fmt.Sprintf("实例%s的SRV目标地址为%s，注册的地址为%s", instance.InstanceID, addr, instance.IPAddress), nil)
+			}
+		}
+	}
+	var unknown []string
+	for target := range answered {
+		if !known[target] {
+			unknown = append(unknown, target)
+		}
+	}
+	sort.Strings(unknown)
+	for _, target := range unknown {
+		d.add(findingStaleSRV, domain, fmt.Sprintf("SRV目标%s不是已注册的实例", strings.TrimSuffix(target, ".")), nil)
+	}
+	return nil
+}
+
+// checkAddresses 检查服务有某个地址族的可路由实例时A/AAAA应答不为空，且应答中的地址都属于已注册的实例
+func (d *dnsDoctor) checkAddresses(domain string, instances []*etcdclient.ServiceInstance, registered map[string]bool) error {
+	for _, qtype := range []uint16{dns.TypeA, dns.TypeAAAA} {
+		recordType := dns.TypeToString[qtype]
+		routable := false
+		for _, instance := range instances {
+			routable = routable || (instance.Routable() && etcdclient.AddressRecordType(instance.IPAddress) == recordType)
+		}
+
+		m, err := d.query(domain, qtype)
+		if err != nil {
+			return err
+		}
+		var addrs []string
+		for _, rr := range m.Answer {
+			switch rr := rr.(type) {
+			case *dns.A:
+				addrs = append(addrs, rr.A.String())
+			case *dns.AAAA:
+				addrs = append(addrs, rr.AAAA.String())
+			}
+		}
+
+		if routable && len(addrs) == 0 {
+			d.add(findingMissingAddress, domain, fmt.Sprintf("服务有可路由的%s地址实例，但%s应答为空", recordType, recordType), nil)
+		}
+		for _, addr := range addrs {
+			if !registered[canonicalIP(addr)] {
+				d.add(findingStaleAddress, domain, fmt.Sprintf("%s应答中的地址%s不属于任何已注册的实例", recordType, addr), nil)
+			}
+		}
+	}
+	return nil
+}
+
+// checkPTRRecords 检查每个可路由实例地址的PTR记录指向该实例的SRV目标名称，
+// 缺失的记录和由本服务维护但指向其他名称的记录可以自动修复
+func (d *dnsDoctor) checkPTRRecords(domain string, instances []*etcdclient.ServiceInstance, owned map[string]*etcdclient.DNSRecordEntry) error {
+	// 多个实例可能共享同一个地址（不同端口），PTR指向其中任意一个实例都视为正确
+	targets := make(map[string][]string)
+	namespaces := make(map[string]string)
+	var addrs []string
+	for _, instance := range instances {
+		if !instance.Routable() {
+			continue
+		}
+		if _, ok := targets[instance.IPAddress]; !ok {
+			addrs = append(addrs, instance.IPAddress)
+		}
+		targets[instance.IPAddress] = append(targets[instance.IPAddress], instanceTarget(domain, instance))
+		namespaces[instance.IPAddress] = instance.Namespace
+	}
+	sort.Strings(addrs)
+
+	for _, addr := range addrs {
+		reverse, err := dns.ReverseAddr(addr)
+		if err != nil {
+			continue
+		}
+		name := strings.TrimSuffix(reverse, ".")
+		expected := targets[addr]
+		sort.Strings(expected)
+		record := &etcdclient.DNSRecord{
+			Type:      "PTR",
+			Value:     expected[0],
+			Namespace: namespaces[addr],
+			Owner:     etcdclient.ServiceOwner(d.service),
+		}
+		put := func() error {
+			return d.client.do(http.MethodPut, "/v1/admin/dns/records/"+url.PathEscape(name), record, nil)
+		}
+
+		m, err := d.query(name, dns.TypePTR)
+		if err != nil {
+			return err
+		}
+		var values []string
+		for _, rr := range m.Answer {
+			if ptr, ok := rr.(*dns.PTR); ok {
+				values = append(values, strings.TrimSuffix(strings.ToLower(ptr.Ptr), "."))
+			}
+		}
+
+		if len(values) == 0 {
+			d.add(findingMissingPTR, name, fmt.Sprintf("实例地址%s没有PTR记录，应指向%s", addr, expected[0]), put)
+			continue
+		}
+		if containsAny(values, expected) {
+			continue
+		}
+		var repair func() error
+		if owned[name+"/PTR/"] != nil {
+			repair = put
+		}
+		d.add(findingWrongPTR, name, fmt.Sprintf("实例地址%s的PTR记录指向%s，应指向%s", addr, strings.Join(values, ","), expected[0]), repair)
+	}
+	return nil
+}
+
+// checkOwnedRecords 检查由服务维护的A/AAAA/PTR记录，指向的地址已不属于任何实例时可以删除
+func (d *dnsDoctor) checkOwnedRecords(records []*etcdclient.DNSRecordEntry, registered map[string]bool) {
+	for _, record := range records {
+		if !record.OwnedByService(d.service) {
+			continue
+		}
+
+		var addr string
+		switch record.Type {
+		case "A", "AAAA":
+			addr = record.Value
+		case "PTR":
+			addr = reverseToIP(record.Domain)
+		default:
+			continue
+		}
+		if addr == "" || registered[canonicalIP(addr)] {
+			continue
+		}
+
+		path := "/v1/admin/dns/records/" + url.PathEscape(record.Domain) + "/" + url.PathEscape(record.Type)
+		query := url.Values{"force": {"true"}}
+		if record.View != "" {
+			query.Set("view", record.View)
+		}
+		d.add(findingStaleRecord, record.Domain, fmt.Sprintf("由服务维护的%s记录指向%s，该地址不属于任何实例", record.Type, addr), func() error {
+			return d.client.do(http.MethodDelete, path+"?"+query.Encode(), nil, nil)
+		})
+	}
+}
+
+// query 向DNS服务器发送一次查询，UDP应答被截断时改用TCP重试；SERVFAIL等错误应答视为查询失败
+func (d *dnsDoctor) query(name string, qtype uint16) (*dns.Msg, error) {
+	m := new(dns.Msg)
+	m.SetQuestion(dns.Fqdn(name), qtype)
+	m.SetEdns0(dns.DefaultMsgSize, false)
+
+	c := &dns.Client{Net: "udp", Timeout: doctorTimeout}
+	resp, _, err := c.Exchange(m, d.server)
+	if err == nil && resp.Truncated {
+		c.Net = "tcp"
+		resp, _, err = c.Exchange(m, d.server)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查询%s %s失败: %w", name, dns.TypeToString[qtype], err)
+	}
+	if resp.Rcode != dns.RcodeSuccess && resp.Rcode != dns.RcodeNameError {
+		return nil, fmt.Errorf("查询%s %s失败: %s", name, dns.TypeToString[qtype], dns.RcodeToString[resp.Rcode])
+	}
+	return resp, nil
+}
+
+// instanceTarget 返回实例在SRV应答中的目标名称 <实例ID>.<服务域名>
+func instanceTarget(domain string, instance *etcdclient.ServiceInstance) string {
+	return strings.ToLower(instance.InstanceID + "." + domain)
+}
+
+// hostPort 返回实例的地址和端口
+func hostPort(instance *etcdclient.ServiceInstance) string {
+	return net.JoinHostPort(instance.IPAddress, strconv.Itoa(instance.Port))
+}
+
+// reverseToIP 把 in-addr.arpa 或 ip6.arpa 形式的反向域名转换为IP地址，不是反向域名时返回空字符串
+func reverseToIP(name string) string {
+	name = strings.TrimSuffix(strings.ToLower(name), ".")
+	switch {
+	case strings.HasSuffix(name, ".in-addr.arpa"):
+		labels := strings.Split(strings.TrimSuffix(name, ".in-addr.arpa"), ".")
+		if len(labels) != 4 {
+			return ""
+		}
+		for i, j := 0, len(labels)-1; i < j; i, j = i+1, j-1 {
+			labels[i], labels[j] = labels[j], labels[i]
+		}
+		return canonicalIP(strings.Join(labels, "."))
+	case strings.HasSuffix(name, ".ip6.arpa"):
+		nibbles := strings.Split(strings.TrimSuffix(name, ".ip6.arpa"), ".")
+		if len(nibbles) != 32 {
+			return ""
+		}
+		var b strings.Builder
+		for i := len(nibbles) - 1; i >= 0; i-- {
+			b.WriteString(nibbles[i])
+			if i%4 == 0 && i > 0 {
+				b.WriteByte(':')
+			}
+		}
+		return canonicalIP(b.String())
+	}
+	return ""
+}
+
+// canonicalIP 返回IP地址的规范形式，不是有效的IP时返回空字符串
+func canonicalIP(addr string) string {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return ""
+	}
+	return ip.String()
+}
+
+// sameIP 判断两个地址是否是同一个IP
+func sameIP(a, b string) bool {
+	return canonicalIP(a) != "" && canonicalIP(a) == canonicalIP(b)
+}
+
+// containsAny 判断values中是否包含expected中的任意一个
+func containsAny(values, expected []string) bool {
+	for _, value := range values {
+		for _, e := range expected {
+			if value == e {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,102 @@
+// kdctl 是Kong Discovery的命令行运维工具，通过管理API操作服务、DNS记录、命名空间和运行时配置
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+const usage = `kdctl - Kong Discovery 命令行工具
+
+用法:
+  kdctl [--admin URL] <命令> [参数]
+
+命令:
+  services list [--namespace NS]             列出所有服务
+  services get <服务名>                        查看服务实例
+  services deregister <服务名> <实例ID> [--dry-run] 注销服务实例
+  dns record ls [--domain 域名]                 列出DNS记录
+  dns record add <域名> --type A --value IP    添加或更新DNS记录
+  dns record rm <域名> <类型> [--view 视图] [--force] [--dry-run] 删除DNS记录
+  dns record apply -f <文件>                   原子地应用一组DNS记录变更
+  namespace create <名称>                      创建命名空间
+  config get upstream-dns                     查看上游DNS配置
+  config set upstream-dns --servers A,B       设置上游DNS配置
+  config validate [--config 配置文件] [--set k=v] 校验配置文件，列出所有错误的配置项
+  watch [--kind services|dns|config|namespaces]  监听变更事件
+  backup [-o 文件] [--kinds 类别]               下载发现数据的一致快照（tar.gz）
+  restore -f <文件> [--kinds 类别] [--replace]    从备份恢复发现数据
+  doctor [--config 配置文件]                    检查etcd、DNS端口和上游DNS的连通性
+  doctor dns --service <服务名> [--server 地址] [--ptr] [--repair] 对照注册实例检查服务的A/AAAA、SRV和PTR记录
+  version                                     查看kdctl和服务端的版本
+
+全局参数:
+  --admin URL   管理API地址，默认读取环境变量KDCTL_ADMIN，未设置时为 http://127.0.0.1:8080
+`
+
+// 管理API的默认地址
+const defaultAdminURL = "http://127.0.0.1:8080"
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "错误: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// run 解析全局参数并分发到子命令
+func run(args []string, out io.Writer) error {
+	adminURL := os.Getenv("KDCTL_ADMIN")
+	if adminURL == "" {
+		adminURL = defaultAdminURL
+	}
+
+	for len(args) > 0 {
+		switch {
+		case args[0] == "--admin" && len(args) > 1:
+			adminURL = args[1]
+			args = args[2:]
+			continue
+		case len(args[0]) > len("--admin=") && args[0][:len("--admin=")] == "--admin=":
+			adminURL = args[0][len("--admin="):]
+			args = args[1:]
+			continue
+		}
+		break
+	}
+
+	if len(args) == 0 || args[0] == "help" || args[0] == "--help" || args[0] == "-h" {
+		fmt.Fprint(out, usage)
+		return nil
+	}
+
+	client := newAdminClient(adminURL)
+	command, rest := args[0], args[1:]
+
+	switch command {
+	case "services":
+		return runServices(client, rest, out)
+	case "dns":
+		return runDNS(client, rest, out)
+	case "namespace":
+		return runNamespace(client, rest, out)
+	case "config":
+		return runConfig(client, rest, out)
+	case "watch":
+		return runWatch(client, rest, out)
+	case "backup":
+		return runBackup(client, rest, out)
+	case "restore":
+		return runRestore(client, rest, out)
+	case "doctor":
+		if len(rest) > 0 && rest[0] == "dns" {
+			return runDoctorDNS(client, rest[1:], out)
+		}
+		return runDoctor(rest, out)
+	case "version":
+		return runVersion(client, out)
+	default:
+		return fmt.Errorf("未知命令: %s，使用 kdctl help 查看帮助", command)
+	}
+}
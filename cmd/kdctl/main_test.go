@@ -0,0 +1,351 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hewenyu/kong-discovery/internal/apihandler"
+	"github.com/hewenyu/kong-discovery/internal/config"
+	"github.com/hewenyu/kong-discovery/internal/etcdclient"
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun_ServicesList(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/admin/services", r.URL.Path)
+		assert.Equal(t, "team-a", r.URL.Query().Get("namespace"))
+		_ = json.NewEncoder(w).Encode(&apihandler.ServiceListResponse{
+			Success: true,
+			Services: []*etcdclient.ServiceSummary{
+				{ServiceName: "order", Namespace: "team-a", Instances: 3, Routable: 2},
+			},
+		})
+	}))
+	defer server.Close()
+
+	var out bytes.Buffer
+	err := run([]string{"--admin", server.URL, "services", "list", "--namespace", "team-a"}, &out)
+	require.NoError(t, err)
+	assert.Contains(t, out.String(), "SERVICE")
+	assert.Contains(t, out.String(), "order")
+}
+
+func TestRun_DNSRecordAdd(t *testing.T) {
+	var got etcdclient.DNSRecord
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPut, r.Method)
+		assert.Equal(t, "/v1/admin/dns/records/api.example.com", r.URL.Path)
+		_ = json.NewDecoder(r.Body).Decode(&got)
+		_ = json.NewEncoder(w).Encode(&apihandler.DNSRecordsResponse{Success: true})
+	}))
+	defer server.Close()
+
+	var out bytes.Buffer
+	err := run([]string{"--admin=" + server.URL, "dns", "record", "add", "api.example.com", "--type", "A", "--value", "10.0.0.1", "--ttl", "30"}, &out)
+	require.NoError(t, err)
+	assert.Equal(t, "A", got.Type)
+	assert.Equal(t, "10.0.0.1", got.Value)
+	assert.Equal(t, 30, got.TTL)
+}
+
+func TestRun_DNSRecordApply(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/admin/dns/records/batch", r.URL.Path)
+		var req apihandler.DNSRecordBatchRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		require.Len(t, req.Operations, 2)
+
+		w.WriteHeader(http.StatusNotFound)
+		_ = json.NewEncoder(w).Encode(&apihandler.DNSRecordBatchResponse{
+			Success: false,
+			Code:    apihandler.CodeDNSRecordNotFound,
+			Message: "DNS记录不存在",
+			Results: []*etcdclient.DNSRecordOpResult{
+				{Index: 0, Op: "put", Domain: "a.example.com", Type: "A", Status: etcdclient.BatchStatusAborted},
+				{Index: 1, Op: "delete", Domain: "b.example.com", Type: "A", Status: etcdclient.BatchStatusNotFound},
+			},
+		})
+	}))
+	defer server.Close()
+
+	file := filepath.Join(t.TempDir(), "changes.json")
+	require.NoError(t, os.WriteFile(file, []byte(`{"operations": [
+		{"op": "put", "domain": "a.example.com", "record": {"type": "A", "value": "10.0.0.1"}},
+		{"op": "delete", "domain": "b.example.com", "type": "A"}
+	]}`), 0o644))
+
+	var out bytes.Buffer
+	err := run([]string{"--admin", server.URL, "dns", "record", "apply", "-f", file}, &out)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "DNS_RECORD_NOT_FOUND")
+	assert.Contains(t, out.String(), "not_found")
+	assert.Contains(t, out.String(), "aborted")
+}
+
+func TestRun_DNSRecordRemoveDryRun(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodDelete, r.Method)
+		assert.Equal(t, "/v1/admin/dns/records/api.example.com/A", r.URL.Path)
+		assert.Equal(t, "true", r.URL.Query().Get("dry_run"))
+		_ = json.NewEncoder(w).Encode(&apihandler.DryRunResponse{
+			Success: true,
+			DryRun:  true,
+			Plan: &etcdclient.ChangePlan{
+				Records: []*etcdclient.DNSRecordEntry{
+					{Domain: "api.example.com", DNSRecord: etcdclient.DNSRecord{Type: "A", Value: "10.0.0.1"}},
+				},
+			},
+		})
+	}))
+	defer server.Close()
+
+	var out bytes.Buffer
+	err := run([]string{"--admin", server.URL, "dns", "record", "rm", "api.example.com", "A", "--dry-run"}, &out)
+	require.NoError(t, err)
+	assert.Contains(t, out.String(), "api.example.com A")
+	assert.Contains(t, out.String(), "未做任何修改")
+}
+
+func TestRun_APIError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusConflict)
+		_ = json.NewEncoder(w).Encode(&apihandler.NamespaceResponse{Success: false, Message: "命名空间已存在"})
+	}))
+	defer server.Close()
+
+	err := run([]string{"--admin", server.URL, "namespace", "create", "team-a"}, &bytes.Buffer{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "命名空间已存在")
+}
+
+func TestRun_BackupRestore(t *testing.T) {
+	archive := []byte("archive-bytes")
+	var restored []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/admin/backup":
+			assert.Equal(t, "dns,services", r.URL.Query().Get("kinds"))
+			w.Header().Set(apihandler.HeaderBackupRevision, "42")
+			_, _ = w.Write(archive)
+		case "/v1/admin/restore":
+			assert.Equal(t, "application/gzip", r.Header.Get("Content-Type"))
+			assert.Equal(t, "true", r.URL.Query().Get("replace"))
+			restored, _ = io.ReadAll(r.Body)
+			_ = json.NewEncoder(w).Encode(&apihandler.RestoreResponse{
+				Success: true,
+				Result: &etcdclient.RestoreResult{
+					Revision: 42,
+					Restored: map[string]int{etcdclient.BackupDNS: 3},
+					Deleted:  map[string]int{etcdclient.BackupDNS: 1},
+				},
+			})
+		}
+	}))
+	defer server.Close()
+
+	file := filepath.Join(t.TempDir(), "backup.tar.gz")
+	var out bytes.Buffer
+	require.NoError(t, run([]string{"--admin", server.URL, "backup", "-o", file, "--kinds", "dns,services"}, &out))
+	assert.Contains(t, out.String(), "修订版本 42")
+	data, err := os.ReadFile(file)
+	require.NoError(t, err)
+	assert.Equal(t, archive, data)
+
+	out.Reset()
+	require.NoError(t, run([]string{"--admin", server.URL, "restore", "-f", file, "--replace"}, &out))
+	assert.Equal(t, archive, restored)
+	assert.Contains(t, out.String(), "dns")
+	assert.Contains(t, out.String(), "修订版本 42")
+
+	// 缺少备份文件
+	assert.Error(t, run([]string{"--admin", server.URL, "restore"}, &out))
+}
+
+func TestRun_RestoreError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(&apihandler.ErrorResponse{Code: apihandler.CodeInvalidBackup, Message: "备份归档无效"})
+	}))
+	defer server.Close()
+
+	file := filepath.Join(t.TempDir(), "backup.tar.gz")
+	require.NoError(t, os.WriteFile(file, []byte("bogus"), 0o600))
+	err := run([]string{"--admin", server.URL, "restore", "-f", file}, &bytes.Buffer{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "INVALID_BACKUP")
+}
+
+func TestRun_UnknownCommand(t *testing.T) {
+	assert.Error(t, run([]string{"bogus"}, &bytes.Buffer{}))
+
+	var out bytes.Buffer
+	require.NoError(t, run(nil, &out))
+	assert.Contains(t, out.String(), "kdctl")
+}
+
+func TestPrintEvents(t *testing.T) {
+	stream := "event: put\ndata: {\"type\":\"put\",\"kind\":\"services\",\"key\":\"/services/order/1\",\"revision\":42}\n\n: keepalive\n\n"
+
+	var out bytes.Buffer
+	err := printEvents(context.Background(), strings.NewReader(stream), &out)
+	require.NoError(t, err)
+	assert.Contains(t, out.String(), "/services/order/1")
+	assert.Contains(t, out.String(), "42")
+}
+
+func TestUpstreamServers(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.DNS.UpstreamDNS = "8.8.8.8:53"
+	cfg.DNS.Upstream.Servers = []string{"1.1.1.1:53", "8.8.8.8:53"}
+	cfg.DNS.ForwardRules = []config.ForwardRule{{Suffix: "corp", Servers: []string{"10.0.0.53:53"}}}
+
+	assert.Equal(t, []string{"8.8.8.8:53", "1.1.1.1:53", "10.0.0.53:53"}, upstreamServers(cfg))
+}
+
+func TestRun_ConfigValidate(t *testing.T) {
+	dir := t.TempDir()
+	valid := filepath.Join(dir, "valid.yaml")
+	require.NoError(t, os.WriteFile(valid, []byte("storage: memory\n"), 0o644))
+
+	var out bytes.Buffer
+	require.NoError(t, run([]string{"config", "validate", "--config", valid}, &out))
+	assert.Contains(t, out.String(), "配置有效")
+
+	invalid := filepath.Join(dir, "invalid.yaml")
+	require.NoError(t, os.WriteFile(invalid, []byte("storage: redis\ndns:\n  port: 0\n"), 0o644))
+
+	out.Reset()
+	err := run([]string{"config", "validate", "--config", invalid}, &out)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "2处错误")
+	assert.Contains(t, out.String(), "[FAIL] storage:")
+	assert.Contains(t, out.String(), "[FAIL] dns.port:")
+}
+
+// startFakeDNS 启动一个按answers应答的DNS服务器，键为"<名称> <类型>"，返回监听地址
+func startFakeDNS(t *testing.T, answers map[string][]string, extra map[string][]string) string {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	server := &dns.Server{PacketConn: conn, Handler: dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		key := r.Question[0].Name + " " + dns.TypeToString[r.Question[0].Qtype]
+		for _, s := range answers[key] {
+			rr, err := dns.NewRR(s)
+			require.NoError(t, err)
+			m.Answer = append(m.Answer, rr)
+		}
+		for _, s := range extra[key] {
+			rr, err := dns.NewRR(s)
+			require.NoError(t, err)
+			m.Extra = append(m.Extra, rr)
+		}
+		_ = w.WriteMsg(m)
+	})}
+	started := make(chan struct{})
+	server.NotifyStartedFunc = func() { close(started) }
+	go func() { _ = server.ActivateAndServe() }()
+	<-started
+	t.Cleanup(func() { _ = server.Shutdown() })
+	return conn.LocalAddr().String()
+}
+
+func TestRun_DoctorDNS(t *testing.T) {
+	const domain = "order.default.svc.cluster.local."
+	dnsAddr := startFakeDNS(t, map[string][]string{
+		domain + " SRV":              {domain + " 60 IN SRV 10 10 8080 order-1." + domain},
+		domain + " A":                {domain + " 60 IN A 10.0.0.7"},
+		"1.0.0.10.in-addr.arpa. PTR": {"1.0.0.10.in-addr.arpa. 60 IN PTR order-1." + domain},
+	}, map[string][]string{
+		domain + " SRV": {"order-1." + domain + " 60 IN A 10.0.0.9"},
+	})
+
+	var puts []etcdclient.DNSRecord
+	var deletes []string
+	admin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/v1/admin/services/order":
+			_ = json.NewEncoder(w).Encode(&apihandler.ServiceInstancesResponse{
+				Success:     true,
+				ServiceName: "order",
+				Instances: []*etcdclient.ServiceInstance{
+					{ServiceName: "order", InstanceID: "order-1", IPAddress: "10.0.0.1", Port: 8080},
+					{ServiceName: "order", InstanceID: "order-2", IPAddress: "10.0.0.2", Port: 8080},
+				},
+			})
+		case r.Method == http.MethodGet && r.URL.Path == "/v1/admin/dns/records":
+			owner := etcdclient.ServiceOwner("order")
+			_ = json.NewEncoder(w).Encode(&apihandler.DNSRecordsResponse{
+				Success: true,
+				Records: []*etcdclient.DNSRecordEntry{
+					{Domain: "order.example.com", DNSRecord: etcdclient.DNSRecord{Type: "A", Value: "10.0.0.5", Owner: owner}},
+					{Domain: "5.0.0.10.in-addr.arpa", DNSRecord: etcdclient.DNSRecord{Type: "PTR", Value: "order-5." + domain, Owner: owner}},
+					{Domain: "1.0.0.10.in-addr.arpa", DNSRecord: etcdclient.DNSRecord{Type: "PTR", Value: "order-1." + domain, Owner: owner}},
+					{Domain: "legacy.example.com", DNSRecord: etcdclient.DNSRecord{Type: "A", Value: "10.0.0.6"}},
+				},
+			})
+		case r.Method == http.MethodPut:
+			var record etcdclient.DNSRecord
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&record))
+			assert.Equal(t, "/v1/admin/dns/records/2.0.0.10.in-addr.arpa", r.URL.Path)
+			puts = append(puts, record)
+			_ = json.NewEncoder(w).Encode(&apihandler.DNSRecordsResponse{Success: true})
+		case r.Method == http.MethodDelete:
+			assert.Equal(t, "true", r.URL.Query().Get("force"))
+			deletes = append(deletes, r.URL.Path)
+			_ = json.NewEncoder(w).Encode(&apihandler.DNSRecordsResponse{Success: true})
+		default:
+			t.Errorf("意外的请求: %s %s", r.Method, r.URL.Path)
+		}
+	}))
+	defer admin.Close()
+
+	// 只检查不修复时列出所有不一致
+	var out bytes.Buffer
+	err := run([]string{"--admin", admin.URL, "doctor", "dns", "--service", "order", "--server", dnsAddr, "--ptr"}, &out)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "6项DNS不一致未修复")
+	for _, kind := range []string{"missing_srv", "stale_srv", "stale_address", "missing_ptr", "stale_record"} {
+		assert.Contains(t, out.String(), kind)
+	}
+	assert.Contains(t, out.String(), "10.0.0.9")
+	assert.NotContains(t, out.String(), "legacy.example.com")
+	assert.Empty(t, puts)
+	assert.Empty(t, deletes)
+
+	// 修复缺失的PTR记录和过期的记录，DNS服务器应答中的不一致需要人工处理
+	out.Reset()
+	err = run([]string{"--admin", admin.URL, "doctor", "dns", "--service", "order", "--server", dnsAddr, "--ptr", "--repair"}, &out)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "3项DNS不一致未修复")
+	require.Len(t, puts, 1)
+	assert.Equal(t, "PTR", puts[0].Type)
+	assert.Equal(t, "order-2.order.default.svc.cluster.local", puts[0].Value)
+	assert.True(t, puts[0].OwnedByService("order"))
+	assert.ElementsMatch(t, []string{"/v1/admin/dns/records/order.example.com/A", "/v1/admin/dns/records/5.0.0.10.in-addr.arpa/PTR"}, deletes)
+
+	// 缺少服务名称
+	assert.Error(t, run([]string{"--admin", admin.URL, "doctor", "dns"}, &out))
+}
+
+func TestReverseToIP(t *testing.T) {
+	assert.Equal(t, "10.0.0.1", reverseToIP("1.0.0.10.in-addr.arpa."))
+	reverse, err := dns.ReverseAddr("2001:db8::1")
+	require.NoError(t, err)
+	assert.Equal(t, "2001:db8::1", reverseToIP(reverse))
+	assert.Equal(t, "", reverseToIP("example.com"))
+	assert.Equal(t, "", reverseToIP("0.10.in-addr.arpa"))
+}
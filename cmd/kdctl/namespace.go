@@ -0,0 +1,22 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/hewenyu/kong-discovery/internal/etcdclient"
+)
+
+// runNamespace 处理namespace子命令
+func runNamespace(client *adminClient, args []string, out io.Writer) error {
+	if len(args) != 2 || args[0] != "create" {
+		return fmt.Errorf("用法: kdctl namespace create <名称>")
+	}
+
+	if err := client.do(http.MethodPost, "/v1/admin/namespaces", &etcdclient.Namespace{Name: args[1]}, nil); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "已创建命名空间 %s\n", args[1])
+	return nil
+}
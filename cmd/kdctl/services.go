@@ -0,0 +1,109 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/hewenyu/kong-discovery/internal/apihandler"
+)
+
+// runServices 处理services子命令
+func runServices(client *adminClient, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return fmt.Errorf("用法: kdctl services list|get|deregister")
+	}
+
+	switch args[0] {
+	case "list", "ls":
+		fs := flag.NewFlagSet("services list", flag.ContinueOnError)
+		namespace := fs.String("namespace", "", "按命名空间过滤")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+
+		path := "/v1/admin/services"
+		if *namespace != "" {
+			path += "?namespace=" + url.QueryEscape(*namespace)
+		}
+
+		var resp apihandler.ServiceListResponse
+		if err := client.do(http.MethodGet, path, nil, &resp); err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "SERVICE\tNAMESPACE\tINSTANCES\tROUTABLE")
+		for _, s := range resp.Services {
+			fmt.Fprintf(w, "%s\t%s\t%d\t%d\n", s.ServiceName, s.Namespace, s.Instances, s.Routable)
+		}
+		return w.Flush()
+
+	case "get":
+		if len(args) != 2 {
+			return fmt.Errorf("用法: kdctl services get <服务名>")
+		}
+
+		var resp apihandler.ServiceInstancesResponse
+		if err := client.do(http.MethodGet, "/v1/admin/services/"+url.PathEscape(args[1]), nil, &resp); err != nil {
+			return err
+		}
+
+		if catalog := resp.Catalog; catalog != nil {
+			for _, field := range [][2]string{
+				{"负责团队", catalog.Owner},
+				{"Slack频道", catalog.SlackChannel},
+				{"说明", catalog.Description},
+				{"运维手册", catalog.RunbookURL},
+				{"代码仓库", catalog.Repository},
+			} {
+				if field[1] != "" {
+					fmt.Fprintf(out, "%s: %s\n", field[0], field[1])
+				}
+			}
+		}
+		if resp.Maintenance != nil {
+			fmt.Fprintf(out, "服务处于维护模式: %s\n", resp.Maintenance.Reason)
+		}
+		w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "INSTANCE\tADDRESS\tHEALTH\tMAINTENANCE\tTTL")
+		for _, inst := range resp.Instances {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%t\t%d\n",
+				inst.InstanceID, net.JoinHostPort(inst.IPAddress, strconv.Itoa(inst.Port)), inst.HealthStatus(), inst.Maintenance, inst.TTL)
+		}
+		return w.Flush()
+
+	case "deregister":
+		if len(args) < 3 {
+			return fmt.Errorf("用法: kdctl services deregister <服务名> <实例ID> [--dry-run]")
+		}
+
+		fs := flag.NewFlagSet("services deregister", flag.ContinueOnError)
+		dryRun := fs.Bool("dry-run", false, "只显示注销的影响范围，不做修改")
+		if err := fs.Parse(args[3:]); err != nil {
+			return err
+		}
+
+		path := "/v1/admin/services/" + url.PathEscape(args[1]) + "/" + url.PathEscape(args[2])
+		if *dryRun {
+			var resp apihandler.DryRunResponse
+			if err := client.do(http.MethodDelete, path+"?dry_run=true", nil, &resp); err != nil {
+				return err
+			}
+			return printChangePlan(out, resp.Plan)
+		}
+		if err := client.do(http.MethodDelete, path, nil, nil); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "已注销实例 %s/%s\n", args[1], args[2])
+		return nil
+
+	default:
+		return fmt.Errorf("未知的services子命令: %s", args[0])
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/hewenyu/kong-discovery/internal/apihandler"
+	"github.com/hewenyu/kong-discovery/internal/etcdclient"
+)
+
+// runConfig 处理config子命令：validate校验本地配置文件，get和set操作运行时的upstream-dns配置项
+func runConfig(client *adminClient, args []string, out io.Writer) error {
+	if len(args) > 0 && args[0] == "validate" {
+		return runConfigValidate(args[1:], out)
+	}
+	if len(args) < 2 || args[1] != "upstream-dns" {
+		return fmt.Errorf("用法: kdctl config get|set upstream-dns 或 kdctl config validate [--config 配置文件]")
+	}
+
+	switch args[0] {
+	case "get":
+		var resp apihandler.UpstreamConfigResponse
+		if err := client.do(http.MethodGet, "/v1/admin/config/upstream-dns", nil, &resp); err != nil {
+			return err
+		}
+		if resp.Config == nil {
+			fmt.Fprintln(out, "未设置上游DNS配置")
+			return nil
+		}
+
+		fmt.Fprintf(out, "servers:    %s\n", strings.Join(resp.Config.Servers, ", "))
+		fmt.Fprintf(out, "mode:       %s\n", resp.Config.Mode)
+		fmt.Fprintf(out, "race_count: %d\n", resp.Config.RaceCount)
+		return nil
+
+	case "set":
+		fs := flag.NewFlagSet("config set upstream-dns", flag.ContinueOnError)
+		servers := fs.String("servers", "", "上游DNS服务器列表，逗号分隔")
+		mode := fs.String("mode", "", "转发模式：sequential 或 race")
+		raceCount := fs.Int("race-count", 0, "race模式下同时查询的上游数量，至少为2，为0时使用默认值")
+		if err := fs.Parse(args[2:]); err != nil {
+			return err
+		}
+
+		cfg := &etcdclient.UpstreamConfig{
+			Mode:      *mode,
+			RaceCount: *raceCount,
+		}
+		for _, server := range strings.Split(*servers, ",") {
+			if server = strings.TrimSpace(server); server != "" {
+				cfg.Servers = append(cfg.Servers, server)
+			}
+		}
+
+		if err := client.do(http.MethodPut, "/v1/admin/config/upstream-dns", cfg, nil); err != nil {
+			return err
+		}
+		fmt.Fprintln(out, "上游DNS配置已更新")
+		return nil
+
+	default:
+		return fmt.Errorf("未知的config子命令: %s", args[0])
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/hewenyu/kong-discovery/internal/config"
+)
+
+// runConfigValidate 按服务启动时的方式（包括环境变量和覆盖项）加载并校验配置文件，逐行列出所有错误的配置项
+func runConfigValidate(args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("config validate", flag.ContinueOnError)
+	configFile := fs.String("config", "", "配置文件路径，为空时按服务的默认位置查找")
+	var overrides config.Overrides
+	fs.Var(&overrides, "set", "与服务相同的配置覆盖项，格式为 key=value，可重复指定")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	path := *configFile
+	if path == "" {
+		path = config.GetDefaultConfigPath()
+	}
+
+	if _, err := config.LoadConfigWithOverrides(path, overrides); err != nil {
+		var invalid config.ValidationError
+		if !errors.As(err, &invalid) {
+			return fmt.Errorf("加载配置失败: %w", err)
+		}
+		for _, fe := range invalid {
+			fmt.Fprintf(out, "[FAIL] %s\n", fe.Error())
+		}
+		return fmt.Errorf("配置文件有%d处错误", len(invalid))
+	}
+
+	if path == "" {
+		fmt.Fprintln(out, "未找到配置文件，默认配置有效")
+		return nil
+	}
+	fmt.Fprintf(out, "配置有效: %s\n", path)
+	return nil
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/hewenyu/kong-discovery/internal/apihandler"
+	"github.com/hewenyu/kong-discovery/internal/version"
+)
+
+// runVersion 打印kdctl自身和服务端的版本，服务端不可达时只打印kdctl的版本并返回错误
+func runVersion(client *adminClient, out io.Writer) error {
+	local := version.Get()
+	fmt.Fprintf(out, "kdctl:  %s (commit %s, built %s)\n", local.Version, local.GitCommit, local.BuildDate)
+
+	var resp apihandler.VersionResponse
+	if err := client.do(http.MethodGet, "/version", nil, &resp); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "server: %s (commit %s, built %s)\n", resp.Version, resp.GitCommit, resp.BuildDate)
+	fmt.Fprintf(out, "api:    %s\n", strings.Join(resp.APIVersions, ", "))
+	if resp.MinSDKVersion != "" {
+		fmt.Fprintf(out, "min sdk: %s\n", resp.MinSDKVersion)
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/hewenyu/kong-discovery/internal/etcdclient"
+)
+
+// runWatch 订阅管理API的变更事件流并逐行打印，直到收到中断信号
+func runWatch(client *adminClient, args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("watch", flag.ContinueOnError)
+	kind := fs.String("kind", "", "按数据类别过滤：services、dns、config、namespaces")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	path := "/v1/admin/events"
+	if *kind != "" {
+		path += "?kind=" + url.QueryEscape(*kind)
+	}
+
+	resp, err := client.stream(ctx, path)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return printEvents(ctx, resp.Body, out)
+}
+
+// printEvents 解析Server-Sent Events流并打印每个事件
+func printEvents(ctx context.Context, body io.Reader, out io.Writer) error {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "data: ") {
+			continue
+		}
+
+		var event etcdclient.Event
+		if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &event); err != nil {
+			continue
+		}
+		fmt.Fprintf(out, "%-8d %-6s %-10s %s\n", event.Revision, event.Type, event.Kind, event.Key)
+	}
+
+	if ctx.Err() != nil {
+		return nil
+	}
+	return scanner.Err()
+}
@@ -11,36 +11,59 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/hewenyu/kong-discovery/internal/apihandler"
+	"github.com/hewenyu/kong-discovery/internal/cluster"
 	"github.com/hewenyu/kong-discovery/internal/config"
 	"github.com/hewenyu/kong-discovery/internal/dnsserver"
 	"github.com/hewenyu/kong-discovery/internal/etcdclient"
+	"github.com/hewenyu/kong-discovery/internal/eventbus"
+	"github.com/hewenyu/kong-discovery/internal/federation"
+	"github.com/hewenyu/kong-discovery/internal/mdns"
+	"github.com/hewenyu/kong-discovery/internal/reconciler"
+	"github.com/hewenyu/kong-discovery/internal/scheduler"
+	"github.com/hewenyu/kong-discovery/internal/tracing"
+	"github.com/hewenyu/kong-discovery/internal/upgrade"
+	"github.com/hewenyu/kong-discovery/internal/version"
+	"github.com/hewenyu/kong-discovery/internal/webhook"
 	"go.uber.org/zap"
 )
 
+// upgradeTimeout 升级时等待新进程就绪的最长时间
+const upgradeTimeout = 30 * time.Second
+
 var (
-	logger     config.Logger
-	configFile string
-	appConfig  *config.Config
+	logger          config.Logger
+	configFile      string
+	configOverrides config.Overrides
+	showVersion     bool
+	appConfig       *config.Config
 )
 
 func init() {
 	// 解析命令行参数
 	flag.StringVar(&configFile, "config", "", "配置文件路径")
+	flag.Var(&configOverrides, "set", "覆盖配置项，格式为 key=value，可重复指定，例如 --set dns.port=5353；优先于环境变量和配置文件")
+	flag.BoolVar(&showVersion, "version", false, "打印版本和构建信息后退出")
 }
 
 func main() {
 	flag.Parse()
 
+	if showVersion {
+		info := version.Get()
+		fmt.Printf("kong-discovery %s (commit %s, built %s, %s)\n", info.Version, info.GitCommit, info.BuildDate, info.GoVersion)
+		return
+	}
+
 	// 加载配置
 	var err error
-	appConfig, err = config.LoadConfig(configFile)
+	appConfig, err = config.LoadConfigWithOverrides(configFile, configOverrides)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "加载配置失败: %v\n", err)
 		os.Exit(1)
 	}
 
 	// 初始化日志
-	logger, err = config.NewLogger(appConfig.Log.Development)
+	logger, err = config.NewLoggerFromConfig(appConfig)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "初始化日志失败: %v\n", err)
 		os.Exit(1)
@@ -48,32 +71,33 @@ func main() {
 
 	// 打印启动信息
 	logger.Info("Kong Discovery Service Starting...",
-		zap.String("version", "0.1.0"),
+		zap.String("version", version.Version),
+		zap.String("git_commit", version.Get().GitCommit),
+		zap.String("build_date", version.BuildDate),
+		zap.String("storage", appConfig.Storage),
 		zap.String("etcd_endpoints", fmt.Sprintf("%v", appConfig.Etcd.Endpoints)),
 		zap.Int("dns_port", appConfig.DNS.Port),
 		zap.Int("management_api_port", appConfig.API.Management.Port),
 		zap.Int("registration_api_port", appConfig.API.Registration.Port),
 	)
 
-	// 初始化etcd客户端
-	etcdClient := etcdclient.NewEtcdClient(appConfig, logger)
-	if err := etcdClient.Connect(); err != nil {
-		logger.Error("连接etcd失败", zap.Error(err))
+	// 初始化链路追踪
+	shutdownTracing, err := tracing.Setup(appConfig, logger)
+	if err != nil {
+		logger.Error("初始化链路追踪失败", zap.Error(err))
 		os.Exit(1)
 	}
-	defer etcdClient.Close()
 
-	// 检查etcd连接状态
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-	if err := etcdClient.Ping(ctx); err != nil {
-		logger.Error("etcd健康检查失败", zap.Error(err))
-		os.Exit(1)
-	}
-	logger.Info("etcd连接成功并通过健康检查")
+	// 初始化etcd客户端，etcd不可用时不退出：从本地快照提供只读数据并在后台重试连接，
+	// 依赖选举的后台任务在连接成功前会持续重试选举
+	etcdClient := etcdclient.NewEtcdClient(appConfig, logger.Named(config.ComponentEtcd))
+	etcdClient.ConnectLazy(func() {
+		logger.Info("etcd连接成功并通过健康检查")
+	})
+	defer etcdClient.Close()
 
 	// 初始化并启动API处理器
-	apiHandler := apihandler.NewAPIHandler(appConfig, logger, etcdClient)
+	apiHandler := apihandler.NewAPIHandler(appConfig, logger.Named(config.ComponentAPI), etcdClient)
 
 	// 启动管理API服务
 	if err := apiHandler.StartManagementAPI(); err != nil {
@@ -132,30 +156,187 @@ func main() {
 	}
 
 	// 初始化DNS服务器并注入etcd客户端
-	dnsServer := dnsserver.NewDNSServer(appConfig, logger)
+	dnsServer := dnsserver.NewDNSServer(appConfig, logger.Named(config.ComponentDNS))
 	dnsServer.SetEtcdClient(etcdClient)
+	apiHandler.SetDNSServer(dnsServer)
 
 	// 启动DNS服务器
 	if err := dnsServer.Start(); err != nil {
 		logger.Error("启动DNS服务器失败", zap.Error(err))
 		os.Exit(1)
 	}
-	logger.Info("DNS服务器启动成功",
-		zap.String("address", appConfig.DNS.ListenAddress),
-		zap.Int("port", appConfig.DNS.Port),
-		zap.String("protocol", appConfig.DNS.Protocol))
+	logger.Info("DNS服务器启动成功", zap.Strings("addrs", dnsServer.Addrs()))
+
+	// 启动定时DNS变更调度器
+	var changeScheduler scheduler.Scheduler
+	if appConfig.Scheduler.Enabled {
+		changeScheduler = scheduler.NewScheduler(appConfig, logger.Named(config.ComponentScheduler), etcdClient)
+		if err := changeScheduler.Start(); err != nil {
+			logger.Error("启动定时DNS变更调度器失败", zap.Error(err))
+			os.Exit(1)
+		}
+	}
+
+	// 启动后台协调任务
+	var serviceReconciler reconciler.Reconciler
+	if appConfig.Reconciler.Enabled {
+		serviceReconciler = reconciler.NewReconciler(appConfig, logger.Named(config.ComponentReconciler), etcdClient)
+		if err := serviceReconciler.Start(); err != nil {
+			logger.Error("启动后台协调任务失败", zap.Error(err))
+			os.Exit(1)
+		}
+		apiHandler.SetReconciler(serviceReconciler)
+	}
+
+	// 启动webhook投递器
+	var webhookDispatcher webhook.Dispatcher
+	if appConfig.Webhook.Enabled {
+		webhookDispatcher = webhook.NewDispatcher(appConfig, logger.Named(config.ComponentWebhook), etcdClient)
+		if err := webhookDispatcher.Start(); err != nil {
+			logger.Error("启动webhook投递器失败", zap.Error(err))
+			os.Exit(1)
+		}
+	}
+
+	// 启动事件总线导出器
+	var eventExporter eventbus.Exporter
+	if appConfig.EventBus.Enabled {
+		exporter, err := eventbus.NewExporter(appConfig, logger.Named(config.ComponentEventBus), etcdClient)
+		if err != nil {
+			logger.Error("创建事件总线导出器失败", zap.Error(err))
+			os.Exit(1)
+		}
+		if err := exporter.Start(); err != nil {
+			logger.Error("启动事件总线导出器失败", zap.Error(err))
+			os.Exit(1)
+		}
+		eventExporter = exporter
+	}
+
+	// 启动联邦同步器
+	var federationSyncer federation.Syncer
+	if appConfig.Federation.Enabled {
+		syncer, err := federation.NewSyncer(appConfig, logger.Named(config.ComponentFederation), etcdClient)
+		if err != nil {
+			logger.Error("创建联邦同步器失败", zap.Error(err))
+			os.Exit(1)
+		}
+		if err := syncer.Start(); err != nil {
+			logger.Error("启动联邦同步器失败", zap.Error(err))
+			os.Exit(1)
+		}
+		federationSyncer = syncer
+	}
+
+	// 启动mDNS响应器
+	var mdnsResponder mdns.Responder
+	if appConfig.MDNS.Enabled {
+		mdnsResponder = mdns.NewResponder(appConfig, logger.Named(config.ComponentMDNS), etcdClient)
+		if err := mdnsResponder.Start(); err != nil {
+			logger.Error("启动mDNS响应器失败", zap.Error(err))
+			os.Exit(1)
+		}
+	}
+
+	// 把本节点注册到系统命名空间
+	var selfRegistrar cluster.Registrar
+	if appConfig.SelfRegistration.Enabled {
+		selfRegistrar = cluster.NewRegistrar(appConfig, logger.Named(config.ComponentCluster), etcdClient, version.Version)
+		if err := selfRegistrar.Start(); err != nil {
+			logger.Error("启动节点自身注册失败", zap.Error(err))
+			os.Exit(1)
+		}
+	}
+
+	// 由升级启动时通知旧进程开始退出
+	if err := upgrade.Ready(); err != nil {
+		logger.Warn("通知旧进程失败", zap.Error(err))
+	}
 
 	// 等待信号以优雅关闭
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
-	<-sigChan
+	upgradeChan := make(chan os.Signal, 1)
+	if signals := upgrade.Signals(); len(signals) > 0 {
+		signal.Notify(upgradeChan, signals...)
+	}
+	// SIGHUP重新加载配置文件中的etcd端点
+	reloadChan := make(chan os.Signal, 1)
+	signal.Notify(reloadChan, syscall.SIGHUP)
 
-	logger.Info("接收到关闭信号，正在优雅关闭...")
+wait:
+	for {
+		select {
+		case <-sigChan:
+			logger.Info("接收到关闭信号，正在优雅关闭...")
+			break wait
+		case <-upgradeChan:
+			logger.Info("接收到升级信号，正在启动新进程...")
+			if err := startUpgrade(apiHandler, dnsServer); err != nil {
+				logger.Error("升级失败，继续提供服务", zap.Error(err))
+				continue
+			}
+			logger.Info("新进程已接管监听套接字，正在处理完进行中的请求并退出...")
+			break wait
+		case <-reloadChan:
+			logger.Info("接收到SIGHUP，重新加载etcd端点...")
+			reloadEtcdEndpoints(etcdClient)
+		}
+	}
 
 	// 优雅关闭所有服务
 	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer shutdownCancel()
 
+	// 注销本节点，其他节点不再把本节点列为对等节点
+	if selfRegistrar != nil {
+		if err := selfRegistrar.Stop(shutdownCtx); err != nil {
+			logger.Error("注销本节点失败", zap.Error(err))
+		}
+	}
+
+	// 停止定时DNS变更调度器
+	if changeScheduler != nil {
+		if err := changeScheduler.Stop(shutdownCtx); err != nil {
+			logger.Error("停止定时DNS变更调度器失败", zap.Error(err))
+		}
+	}
+
+	// 停止后台协调任务
+	if serviceReconciler != nil {
+		if err := serviceReconciler.Stop(shutdownCtx); err != nil {
+			logger.Error("停止后台协调任务失败", zap.Error(err))
+		}
+	}
+
+	// 停止webhook投递器
+	if webhookDispatcher != nil {
+		if err := webhookDispatcher.Stop(shutdownCtx); err != nil {
+			logger.Error("停止webhook投递器失败", zap.Error(err))
+		}
+	}
+
+	// 停止事件总线导出器
+	if eventExporter != nil {
+		if err := eventExporter.Stop(shutdownCtx); err != nil {
+			logger.Error("停止事件总线导出器失败", zap.Error(err))
+		}
+	}
+
+	// 停止联邦同步器
+	if federationSyncer != nil {
+		if err := federationSyncer.Stop(shutdownCtx); err != nil {
+			logger.Error("停止联邦同步器失败", zap.Error(err))
+		}
+	}
+
+	// 停止mDNS响应器
+	if mdnsResponder != nil {
+		if err := mdnsResponder.Stop(shutdownCtx); err != nil {
+			logger.Error("停止mDNS响应器失败", zap.Error(err))
+		}
+	}
+
 	// 关闭DNS服务器
 	if err := dnsServer.Shutdown(shutdownCtx); err != nil {
 		logger.Error("关闭DNS服务器失败", zap.Error(err))
@@ -165,4 +346,53 @@ func main() {
 	if err := apiHandler.Shutdown(shutdownCtx); err != nil {
 		logger.Error("关闭API服务失败", zap.Error(err))
 	}
+
+	// 导出剩余的span
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		logger.Error("关闭链路追踪失败", zap.Error(err))
+	}
+}
+
+// reloadEtcdEndpoints 重新加载配置文件，把其中的etcd端点应用到当前连接；其他配置项的修改需要重启才生效
+func reloadEtcdEndpoints(client etcdclient.Client) {
+	if appConfig.Storage != config.StorageEtcd {
+		logger.Info("未使用etcd存储，忽略端点重新加载", zap.String("storage", appConfig.Storage))
+		return
+	}
+	cfg, err := config.LoadConfigWithOverrides(configFile, configOverrides)
+	if err != nil {
+		logger.Error("重新加载配置失败，继续使用当前的etcd端点", zap.Error(err))
+		return
+	}
+	if err := client.UpdateEndpoints(cfg.Etcd.Endpoints); err != nil {
+		logger.Error("更新etcd端点失败", zap.Error(err))
+	}
+}
+
+// startUpgrade 以相同的参数启动新进程并传递DNS和API的监听套接字，等待新进程就绪
+// 新进程启动失败时旧进程继续提供服务
+func startUpgrade(apiHandler apihandler.Handler, dnsServer dnsserver.Server) error {
+	files, err := apiHandler.Files()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		for _, list := range files {
+			for _, f := range list {
+				f.Close()
+			}
+		}
+	}()
+	if files[upgrade.NameDNS], err = dnsServer.Files(); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), upgradeTimeout)
+	defer cancel()
+	process, err := upgrade.Spawn(ctx, files)
+	if err != nil {
+		return err
+	}
+	logger.Info("新进程已就绪", zap.Int("pid", process.Pid))
+	return nil
 }
@@ -0,0 +1,223 @@
+// replay 把kong-discovery捕获的查询（dns.capture）按原有节奏或指定速率重放到DNS服务器，用于以生产流量形态做压测
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hewenyu/kong-discovery/internal/dnsserver"
+	"github.com/miekg/dns"
+)
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "错误: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// options 重放参数
+type options struct {
+	file        string
+	server      string
+	rate        float64
+	speed       float64
+	concurrency int
+	timeout     time.Duration
+	protocol    string
+	loop        int
+}
+
+// result 单个查询的重放结果
+type result struct {
+	rcode   int
+	latency time.Duration
+	err     error
+}
+
+// run 解析参数并执行重放，结束后输出统计
+func run(args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("replay", flag.ContinueOnError)
+	fs.SetOutput(out)
+	var opts options
+	fs.StringVar(&opts.file, "f", "", "查询捕获文件（dns.capture.path）")
+	fs.StringVar(&opts.server, "server", "127.0.0.1:53", "目标DNS服务器地址")
+	fs.Float64Var(&opts.rate, "rate", 0, "每秒发送的查询数，0表示按捕获时的间隔发送")
+	fs.Float64Var(&opts.speed, "speed", 1, "按捕获间隔发送时的加速倍数")
+	fs.IntVar(&opts.concurrency, "concurrency", 64, "同时等待应答的最大查询数")
+	fs.DurationVar(&opts.timeout, "timeout", 2*time.Second, "单个查询的超时时间")
+	fs.StringVar(&opts.protocol, "protocol", "", "使用的协议：udp 或 tcp，为空时使用捕获时的协议")
+	fs.IntVar(&opts.loop, "loop", 1, "重放的轮数")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if opts.file == "" {
+		return fmt.Errorf("用法: replay -f <捕获文件> [-server 地址] [-rate QPS | -speed 倍数]")
+	}
+	if opts.concurrency <= 0 {
+		opts.concurrency = 1
+	}
+	if opts.speed <= 0 {
+		opts.speed = 1
+	}
+
+	queries, err := readCapture(opts.file)
+	if err != nil {
+		return err
+	}
+	if len(queries) == 0 {
+		return fmt.Errorf("捕获文件中没有查询")
+	}
+
+	start := time.Now()
+	results := replay(context.Background(), queries, opts)
+	printSummary(out, results, time.Since(start))
+	return nil
+}
+
+// readCapture 读取捕获文件中的全部查询
+func readCapture(path string) ([]*dnsserver.CapturedQuery, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("打开捕获文件失败: %w", err)
+	}
+	defer f.Close()
+
+	var queries []*dnsserver.CapturedQuery
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		q := new(dnsserver.CapturedQuery)
+		if err := json.Unmarshal(scanner.Bytes(), q); err != nil {
+			return nil, fmt.Errorf("解析捕获文件第%d行失败: %w", line, err)
+		}
+		if _, ok := dns.StringToType[q.Type]; !ok {
+			return nil, fmt.Errorf("捕获文件第%d行的查询类型无效: %s", line, q.Type)
+		}
+		queries = append(queries, q)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("读取捕获文件失败: %w", err)
+	}
+	return queries, nil
+}
+
+// replay 按速率或捕获间隔发送所有查询，返回每个查询的结果
+func replay(ctx context.Context, queries []*dnsserver.CapturedQuery, opts options) []result {
+	clients := map[string]*dns.Client{
+		"udp": {Net: "udp", Timeout: opts.timeout},
+		"tcp": {Net: "tcp", Timeout: opts.timeout},
+	}
+
+	var (
+		mu      sync.Mutex
+		results = make([]result, 0, len(queries)*opts.loop)
+		wg      sync.WaitGroup
+		slots   = make(chan struct{}, opts.concurrency)
+	)
+
+	begin := time.Now()
+	sent := 0
+	for round := 0; round < opts.loop; round++ {
+		roundStart := time.Now()
+		for _, q := range queries {
+			// 计算发送时间：固定速率按序号，否则按相对第一个查询的捕获间隔
+			var at time.Time
+			if opts.rate > 0 {
+				at = begin.Add(time.Duration(float64(sent) / opts.rate * float64(time.Second)))
+			} else {
+				at = roundStart.Add(time.Duration(float64(q.Time.Sub(queries[0].Time)) / opts.speed))
+			}
+			if wait := time.Until(at); wait > 0 {
+				select {
+				case <-ctx.Done():
+					wg.Wait()
+					return results
+				case <-time.After(wait):
+				}
+			}
+
+			protocol := opts.protocol
+			if protocol == "" {
+				protocol = q.Protocol
+			}
+			client, ok := clients[protocol]
+			if !ok {
+				client = clients["udp"]
+			}
+
+			slots <- struct{}{}
+			wg.Add(1)
+			sent++
+			go func(q *dnsserver.CapturedQuery, client *dns.Client) {
+				defer func() {
+					<-slots
+					wg.Done()
+				}()
+
+				m := new(dns.Msg)
+				m.SetQuestion(dns.Fqdn(q.Name), dns.StringToType[q.Type])
+				resp, rtt, err := client.ExchangeContext(ctx, m, opts.server)
+				res := result{latency: rtt, err: err}
+				if err == nil {
+					res.rcode = resp.Rcode
+				}
+				mu.Lock()
+				results = append(results, res)
+				mu.Unlock()
+			}(q, client)
+		}
+	}
+	wg.Wait()
+	return results
+}
+
+// printSummary 输出发送数、错误数、各响应码数量和延迟分位数
+func printSummary(out io.Writer, results []result, elapsed time.Duration) {
+	rcodes := make(map[string]int)
+	var latencies []time.Duration
+	errors := 0
+	for _, r := range results {
+		if r.err != nil {
+			errors++
+			continue
+		}
+		rcodes[dns.RcodeToString[r.rcode]]++
+		latencies = append(latencies, r.latency)
+	}
+
+	fmt.Fprintf(out, "sent:     %d\n", len(results))
+	fmt.Fprintf(out, "errors:   %d\n", errors)
+	fmt.Fprintf(out, "duration: %s\n", elapsed.Round(time.Millisecond))
+	if elapsed > 0 {
+		fmt.Fprintf(out, "qps:      %.1f\n", float64(len(results))/elapsed.Seconds())
+	}
+
+	names := make([]string, 0, len(rcodes))
+	for name := range rcodes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(out, "%-10s%d\n", name+":", rcodes[name])
+	}
+
+	if len(latencies) == 0 {
+		return
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	for _, p := range []float64{0.5, 0.9, 0.99} {
+		idx := int(p * float64(len(latencies)-1))
+		fmt.Fprintf(out, "%-10s%s\n", fmt.Sprintf("p%.0f:", p*100), latencies[idx].Round(time.Microsecond))
+	}
+}
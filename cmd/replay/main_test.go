@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hewenyu/kong-discovery/internal/dnsserver"
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// startTestServer 启动一个对A查询应答、其他查询返回NXDOMAIN的DNS服务器
+func startTestServer(t *testing.T) string {
+	t.Helper()
+
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	server := &dns.Server{
+		PacketConn: pc,
+		Handler: dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
+			m := new(dns.Msg)
+			m.SetReply(r)
+			if r.Question[0].Qtype != dns.TypeA {
+				m.Rcode = dns.RcodeNameError
+			}
+			_ = w.WriteMsg(m)
+		}),
+	}
+	go func() { _ = server.ActivateAndServe() }()
+	t.Cleanup(func() { _ = server.Shutdown() })
+	return pc.LocalAddr().String()
+}
+
+func writeCapture(t *testing.T, queries ...*dnsserver.CapturedQuery) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "capture.jsonl")
+	f, err := os.Create(path)
+	require.NoError(t, err)
+	defer f.Close()
+	enc := json.NewEncoder(f)
+	for _, q := range queries {
+		require.NoError(t, enc.Encode(q))
+	}
+	return path
+}
+
+func TestRun_Replay(t *testing.T) {
+	now := time.Now()
+	path := writeCapture(t,
+		&dnsserver.CapturedQuery{Time: now, Protocol: "udp", Name: "a.example.com.", Type: "A"},
+		&dnsserver.CapturedQuery{Time: now.Add(10 * time.Millisecond), Protocol: "udp", Name: "b.example.com.", Type: "A"},
+		&dnsserver.CapturedQuery{Time: now.Add(20 * time.Millisecond), Protocol: "udp", Name: "c.example.com.", Type: "AAAA"},
+	)
+
+	var out bytes.Buffer
+	err := run([]string{"-f", path, "-server", startTestServer(t), "-loop", "2"}, &out)
+	require.NoError(t, err)
+	assert.Contains(t, out.String(), "sent:     6")
+	assert.Contains(t, out.String(), "errors:   0")
+	assert.Contains(t, out.String(), "NOERROR:  4")
+	assert.Contains(t, out.String(), "NXDOMAIN: 2")
+}
+
+func TestRun_ReplayInvalidCapture(t *testing.T) {
+	path := writeCapture(t, &dnsserver.CapturedQuery{Name: "a.example.com.", Type: "BOGUS"})
+
+	var out bytes.Buffer
+	assert.Error(t, run([]string{"-f", path}, &out))
+	assert.Error(t, run(nil, &out))
+}
@@ -0,0 +1,93 @@
+package apihandler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/hewenyu/kong-discovery/internal/etcdclient"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// ServiceACLRequest 定义服务解析ACL设置请求结构
+type ServiceACLRequest struct {
+	AllowCIDRs      []string `json:"allow_cidrs" validate:"dive,cidr"`                   // 允许的客户端网段
+	AllowIdentities []string `json:"allow_identities" validate:"dive,required"`          // 允许的身份（TSIG密钥名称）
+	Action          string   `json:"action" validate:"omitempty,oneof=nxdomain refused"` // 拒绝时的应答：nxdomain或refused，默认nxdomain
+}
+
+// ServiceACLResponse 定义服务解析ACL响应结构
+type ServiceACLResponse struct {
+	Success     bool                   `json:"success"`           // 是否成功
+	ServiceName string                 `json:"service_name"`      // 服务名称
+	ACL         *etcdclient.ServiceACL `json:"acl"`               // 服务的解析ACL，未设置时为null，表示所有客户端都可以解析
+	Message     string                 `json:"message,omitempty"` // 可选消息
+	Timestamp   string                 `json:"timestamp"`         // 时间戳
+}
+
+// getServiceACLHandler 获取服务的解析ACL
+func (h *EchoHandler) getServiceACLHandler(c echo.Context) error {
+	serviceName := c.Param("serviceName")
+
+	acl, err := h.etcdClient.GetServiceACL(c.Request().Context(), serviceName)
+	if err != nil {
+		h.log(c).Error("获取服务解析ACL失败", zap.String("service", serviceName), zap.Error(err))
+		return respondError(c, storageError(err))
+	}
+
+	return c.JSON(http.StatusOK, &ServiceACLResponse{
+		Success:     true,
+		ServiceName: serviceName,
+		ACL:         acl,
+		Timestamp:   time.Now().Format(time.RFC3339),
+	})
+}
+
+// putServiceACLHandler 设置服务的解析ACL
+func (h *EchoHandler) putServiceACLHandler(c echo.Context) error {
+	serviceName := c.Param("serviceName")
+
+	req := new(ServiceACLRequest)
+	if apiErr := bindRequest(c, req); apiErr != nil {
+		return respondError(c, apiErr)
+	}
+
+	acl := &etcdclient.ServiceACL{
+		ServiceName:     serviceName,
+		AllowCIDRs:      req.AllowCIDRs,
+		AllowIdentities: req.AllowIdentities,
+		Action:          req.Action,
+	}
+	if err := acl.Validate(); err != nil {
+		return respondError(c, badRequest(CodeInvalidParameter, err.Error()))
+	}
+	if err := h.etcdClient.PutServiceACL(c.Request().Context(), acl); err != nil {
+		h.log(c).Error("保存服务解析ACL失败", zap.String("service", serviceName), zap.Error(err))
+		return respondError(c, storageError(err))
+	}
+
+	return c.JSON(http.StatusOK, &ServiceACLResponse{
+		Success:     true,
+		ServiceName: serviceName,
+		ACL:         acl,
+		Message:     "服务解析ACL已更新",
+		Timestamp:   time.Now().Format(time.RFC3339),
+	})
+}
+
+// deleteServiceACLHandler 删除服务的解析ACL，之后所有客户端都可以解析该服务
+func (h *EchoHandler) deleteServiceACLHandler(c echo.Context) error {
+	serviceName := c.Param("serviceName")
+
+	if err := h.etcdClient.DeleteServiceACL(c.Request().Context(), serviceName); err != nil {
+		h.log(c).Error("删除服务解析ACL失败", zap.String("service", serviceName), zap.Error(err))
+		return respondError(c, storageError(err))
+	}
+
+	return c.JSON(http.StatusOK, &ServiceACLResponse{
+		Success:     true,
+		ServiceName: serviceName,
+		Message:     "服务解析ACL已删除",
+		Timestamp:   time.Now().Format(time.RFC3339),
+	})
+}
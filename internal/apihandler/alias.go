@@ -0,0 +1,103 @@
+package apihandler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/hewenyu/kong-discovery/internal/etcdclient"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// ServiceAliasRequest 定义服务别名设置请求结构，target和split二选一
+type ServiceAliasRequest struct {
+	Target string                   `json:"target"` // 目标服务名称
+	Split  []etcdclient.SplitTarget `json:"split"`  // 按权重分流的目标服务，如80%到A、20%到B
+}
+
+// ServiceAliasResponse 定义服务别名响应结构
+type ServiceAliasResponse struct {
+	Success     bool                       `json:"success"`                // 是否成功
+	ServiceName string                     `json:"service_name,omitempty"` // 别名
+	Alias       *etcdclient.ServiceAlias   `json:"alias,omitempty"`        // 单个别名，服务不是别名时为空
+	Aliases     []*etcdclient.ServiceAlias `json:"aliases,omitempty"`      // 别名列表
+	Message     string                     `json:"message,omitempty"`      // 可选消息
+	Timestamp   string                     `json:"timestamp"`              // 时间戳
+}
+
+// listServiceAliasesHandler 列出所有服务别名
+func (h *EchoHandler) listServiceAliasesHandler(c echo.Context) error {
+	aliases, err := h.etcdClient.ListServiceAliases(c.Request().Context())
+	if err != nil {
+		h.log(c).Error("获取服务别名列表失败", zap.Error(err))
+		return respondError(c, storageError(err))
+	}
+
+	return c.JSON(http.StatusOK, &ServiceAliasResponse{
+		Success:   true,
+		Aliases:   aliases,
+		Timestamp: time.Now().Format(time.RFC3339),
+	})
+}
+
+// getServiceAliasHandler 获取服务别名
+func (h *EchoHandler) getServiceAliasHandler(c echo.Context) error {
+	serviceName := c.Param("serviceName")
+
+	alias, err := h.etcdClient.GetServiceAlias(c.Request().Context(), serviceName)
+	if err != nil {
+		h.log(c).Error("获取服务别名失败", zap.String("service", serviceName), zap.Error(err))
+		return respondError(c, storageError(err))
+	}
+
+	return c.JSON(http.StatusOK, &ServiceAliasResponse{
+		Success:     true,
+		ServiceName: serviceName,
+		Alias:       alias,
+		Timestamp:   time.Now().Format(time.RFC3339),
+	})
+}
+
+// putServiceAliasHandler 把服务设置为别名、切换别名的目标或调整分流权重
+func (h *EchoHandler) putServiceAliasHandler(c echo.Context) error {
+	serviceName := c.Param("serviceName")
+
+	req := new(ServiceAliasRequest)
+	if apiErr := bindRequest(c, req); apiErr != nil {
+		return respondError(c, apiErr)
+	}
+
+	alias := &etcdclient.ServiceAlias{ServiceName: serviceName, Target: req.Target, Split: req.Split}
+	if err := alias.Validate(); err != nil {
+		return respondError(c, badRequest(CodeInvalidParameter, err.Error()))
+	}
+	if err := h.etcdClient.PutServiceAlias(c.Request().Context(), alias); err != nil {
+		h.log(c).Error("保存服务别名失败", zap.String("service", serviceName), zap.Error(err))
+		return respondError(c, storageError(err))
+	}
+
+	return c.JSON(http.StatusOK, &ServiceAliasResponse{
+		Success:     true,
+		ServiceName: serviceName,
+		Alias:       alias,
+		Message:     "服务别名已更新",
+		Timestamp:   time.Now().Format(time.RFC3339),
+	})
+}
+
+// deleteServiceAliasHandler 删除服务别名
+func (h *EchoHandler) deleteServiceAliasHandler(c echo.Context) error {
+	serviceName := c.Param("serviceName")
+
+	if err := h.etcdClient.DeleteServiceAlias(c.Request().Context(), serviceName); err != nil {
+		h.log(c).Error("删除服务别名失败", zap.String("service", serviceName), zap.Error(err))
+		return respondError(c, storageError(err))
+	}
+
+	return c.JSON(http.StatusOK, &ServiceAliasResponse{
+		Success:     true,
+		ServiceName: serviceName,
+		Message:     "服务别名已删除",
+		Timestamp:   time.Now().Format(time.RFC3339),
+	})
+}
@@ -0,0 +1,93 @@
+package apihandler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/hewenyu/kong-discovery/internal/etcdclient"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// ServiceAnswerPolicyRequest 定义服务应答策略设置请求结构
+type ServiceAnswerPolicyRequest struct {
+	AddressAnswers  string `json:"address_answers" validate:"omitempty,oneof=one all"`  // A/AAAA应答返回的实例数：one或all，默认one
+	Order           string `json:"order" validate:"omitempty,oneof=none random sticky"` // 记录顺序：none、random或sticky，默认none
+	IncludeDraining bool   `json:"include_draining"`                                    // 摘流中的实例是否仍出现在应答中
+}
+
+// ServiceAnswerPolicyResponse 定义服务应答策略响应结构
+type ServiceAnswerPolicyResponse struct {
+	Success     bool                            `json:"success"`           // 是否成功
+	ServiceName string                          `json:"service_name"`      // 服务名称
+	Policy      *etcdclient.ServiceAnswerPolicy `json:"policy"`            // 服务的应答策略，未设置时为null，表示使用默认的应答方式
+	Message     string                          `json:"message,omitempty"` // 可选消息
+	Timestamp   string                          `json:"timestamp"`         // 时间戳
+}
+
+// getServiceAnswerPolicyHandler 获取服务的应答策略
+func (h *EchoHandler) getServiceAnswerPolicyHandler(c echo.Context) error {
+	serviceName := c.Param("serviceName")
+
+	policy, err := h.etcdClient.GetServiceAnswerPolicy(c.Request().Context(), serviceName)
+	if err != nil {
+		h.log(c).Error("获取服务应答策略失败", zap.String("service", serviceName), zap.Error(err))
+		return respondError(c, storageError(err))
+	}
+
+	return c.JSON(http.StatusOK, &ServiceAnswerPolicyResponse{
+		Success:     true,
+		ServiceName: serviceName,
+		Policy:      policy,
+		Timestamp:   time.Now().Format(time.RFC3339),
+	})
+}
+
+// putServiceAnswerPolicyHandler 设置服务的应答策略
+func (h *EchoHandler) putServiceAnswerPolicyHandler(c echo.Context) error {
+	serviceName := c.Param("serviceName")
+
+	req := new(ServiceAnswerPolicyRequest)
+	if apiErr := bindRequest(c, req); apiErr != nil {
+		return respondError(c, apiErr)
+	}
+
+	policy := &etcdclient.ServiceAnswerPolicy{
+		ServiceName:     serviceName,
+		AddressAnswers:  req.AddressAnswers,
+		Order:           req.Order,
+		IncludeDraining: req.IncludeDraining,
+	}
+	if err := policy.Validate(); err != nil {
+		return respondError(c, badRequest(CodeInvalidParameter, err.Error()))
+	}
+	if err := h.etcdClient.PutServiceAnswerPolicy(c.Request().Context(), policy); err != nil {
+		h.log(c).Error("保存服务应答策略失败", zap.String("service", serviceName), zap.Error(err))
+		return respondError(c, storageError(err))
+	}
+
+	return c.JSON(http.StatusOK, &ServiceAnswerPolicyResponse{
+		Success:     true,
+		ServiceName: serviceName,
+		Policy:      policy,
+		Message:     "服务应答策略已更新",
+		Timestamp:   time.Now().Format(time.RFC3339),
+	})
+}
+
+// deleteServiceAnswerPolicyHandler 删除服务的应答策略，之后服务使用默认的应答方式
+func (h *EchoHandler) deleteServiceAnswerPolicyHandler(c echo.Context) error {
+	serviceName := c.Param("serviceName")
+
+	if err := h.etcdClient.DeleteServiceAnswerPolicy(c.Request().Context(), serviceName); err != nil {
+		h.log(c).Error("删除服务应答策略失败", zap.String("service", serviceName), zap.Error(err))
+		return respondError(c, storageError(err))
+	}
+
+	return c.JSON(http.StatusOK, &ServiceAnswerPolicyResponse{
+		Success:     true,
+		ServiceName: serviceName,
+		Message:     "服务应答策略已删除",
+		Timestamp:   time.Now().Format(time.RFC3339),
+	})
+}
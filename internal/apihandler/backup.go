@@ -0,0 +1,96 @@
+package apihandler
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hewenyu/kong-discovery/internal/etcdclient"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// maxRestoreSize 恢复请求允许的最大归档大小
+const maxRestoreSize = 256 << 20
+
+// HeaderBackupRevision 备份响应中携带快照的etcd修订版本
+const HeaderBackupRevision = "X-Kong-Discovery-Revision"
+
+// RestoreResponse 定义恢复响应结构
+type RestoreResponse struct {
+	Success   bool                      `json:"success"`   // 是否成功
+	Result    *etcdclient.RestoreResult `json:"result"`    // 恢复结果
+	Timestamp string                    `json:"timestamp"` // 时间戳
+}
+
+// backupKinds 解析以逗号分隔的kinds参数
+func backupKinds(c echo.Context) ([]string, error) {
+	var kinds []string
+	for _, kind := range strings.Split(c.QueryParam("kinds"), ",") {
+		if kind = strings.TrimSpace(kind); kind != "" {
+			kinds = append(kinds, kind)
+		}
+	}
+	return etcdclient.ValidBackupKinds(kinds)
+}
+
+// backupHandler 以tar.gz归档的形式返回发现数据在同一修订版本上的快照，可用kinds选择数据类别
+func (h *EchoHandler) backupHandler(c echo.Context) error {
+	kinds, err := backupKinds(c)
+	if err != nil {
+		return respondError(c, badRequest(CodeInvalidParameter, err.Error()))
+	}
+
+	snapshot, err := h.etcdClient.Snapshot(c.Request().Context(), kinds)
+	if err != nil {
+		h.log(c).Error("创建备份失败", zap.Error(err))
+		return respondError(c, storageError(err))
+	}
+
+	resp := c.Response()
+	resp.Header().Set(echo.HeaderContentType, "application/gzip")
+	resp.Header().Set(echo.HeaderContentDisposition,
+		fmt.Sprintf("attachment; filename=kong-discovery-%d.tar.gz", snapshot.Revision))
+	resp.Header().Set(HeaderBackupRevision, strconv.FormatInt(snapshot.Revision, 10))
+	resp.WriteHeader(http.StatusOK)
+
+	// 响应头已发送，之后的错误只能记录日志
+	if err := snapshot.WriteArchive(resp); err != nil {
+		h.log(c).Error("写入备份归档失败", zap.Int64("revision", snapshot.Revision), zap.Error(err))
+		return nil
+	}
+	h.log(c).Info("备份已导出", zap.Int64("revision", snapshot.Revision), zap.Strings("kinds", kinds))
+	return nil
+}
+
+// restoreHandler 从备份归档恢复发现数据
+// kinds选择恢复的数据类别，replace=true时删除这些类别中备份里没有的键
+func (h *EchoHandler) restoreHandler(c echo.Context) error {
+	kinds, err := backupKinds(c)
+	if err != nil {
+		return respondError(c, badRequest(CodeInvalidParameter, err.Error()))
+	}
+
+	body := http.MaxBytesReader(c.Response(), c.Request().Body, maxRestoreSize)
+	snapshot, err := etcdclient.ReadArchive(body)
+	if err != nil {
+		return respondError(c, badRequest(CodeInvalidBackup, err.Error()))
+	}
+
+	result, err := h.etcdClient.Restore(c.Request().Context(), snapshot, etcdclient.RestoreOptions{
+		Kinds:   kinds,
+		Replace: c.QueryParam("replace") == "true",
+	})
+	if err != nil {
+		h.log(c).Error("恢复备份失败", zap.Int64("revision", snapshot.Revision), zap.Error(err))
+		return respondError(c, storageError(err))
+	}
+
+	return c.JSON(http.StatusOK, &RestoreResponse{
+		Success:   true,
+		Result:    result,
+		Timestamp: time.Now().Format(time.RFC3339),
+	})
+}
@@ -0,0 +1,79 @@
+package apihandler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// 阻塞查询的默认和最大等待时间
+const (
+	defaultBlockingWait = 60 * time.Second
+	maxBlockingWait     = 5 * time.Minute
+)
+
+// indexHeader 返回查询结果对应的etcd修订版本，客户端在下一次阻塞查询中通过index参数传回
+const indexHeader = "X-Discovery-Index"
+
+// parseBlockingQuery 解析阻塞查询参数，index为0表示立即返回
+func parseBlockingQuery(c echo.Context) (int64, time.Duration, *APIError) {
+	var index int64
+	if value := c.QueryParam("index"); value != "" {
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || parsed < 0 {
+			return 0, 0, badRequest(CodeInvalidParameter, "index必须是非负整数: "+value)
+		}
+		index = parsed
+	}
+
+	wait := defaultBlockingWait
+	if value := c.QueryParam("wait"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed <= 0 {
+			return 0, 0, badRequest(CodeInvalidParameter, "wait必须是正的时长，如30s: "+value)
+		}
+		wait = parsed
+	}
+	if wait > maxBlockingWait {
+		wait = maxBlockingWait
+	}
+	return index, wait, nil
+}
+
+// waitForChange 处理阻塞查询：请求携带index参数时调用wait等待数据在index之后发生变更，
+// 最多等待wait参数指定的时间。发生变更或等待超时都应返回当前数据；
+// proceed为false表示客户端已断开，不需要再写响应
+func (h *EchoHandler) waitForChange(c echo.Context, wait func(ctx context.Context, index int64) error) (proceed bool, apiErr *APIError) {
+	index, timeout, apiErr := parseBlockingQuery(c)
+	if apiErr != nil {
+		return false, apiErr
+	}
+	if index == 0 {
+		return true, nil
+	}
+
+	ctx := c.Request().Context()
+	waitCtx, cancel := context.WithTimeout(ctx, timeout)
+	err := wait(waitCtx, index)
+	cancel()
+
+	switch {
+	case err == nil, errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil:
+		return true, nil
+	case ctx.Err() != nil:
+		return false, nil
+	default:
+		h.log(c).Warn("等待数据变更失败", zap.Int64("index", index), zap.Error(err))
+		return false, storageError(fmt.Errorf("等待数据变更失败: %w", err))
+	}
+}
+
+// setIndexHeader 设置阻塞查询的index响应头
+func setIndexHeader(c echo.Context, index int64) {
+	c.Response().Header().Set(indexHeader, strconv.FormatInt(index, 10))
+}
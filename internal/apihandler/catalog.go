@@ -0,0 +1,115 @@
+package apihandler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/hewenyu/kong-discovery/internal/etcdclient"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// ServiceCatalogRequest 定义服务目录设置请求结构，整体替换已有的目录信息
+type ServiceCatalogRequest struct {
+	Owner        string            `json:"owner"`                                // 负责团队
+	SlackChannel string            `json:"slack_channel"`                        // Slack频道
+	Description  string            `json:"description"`                          // 服务说明
+	RunbookURL   string            `json:"runbook_url" validate:"omitempty,url"` // 运维手册地址
+	Repository   string            `json:"repository"`                           // 代码仓库地址
+	Links        map[string]string `json:"links"`                                // 其他链接，键为链接名称
+}
+
+// ServiceCatalogResponse 定义服务目录响应结构
+type ServiceCatalogResponse struct {
+	Success     bool                         `json:"success"`                // 是否成功
+	ServiceName string                       `json:"service_name,omitempty"` // 服务名称
+	Catalog     *etcdclient.ServiceCatalog   `json:"catalog,omitempty"`      // 单个服务的目录信息，未设置时为空
+	Catalogs    []*etcdclient.ServiceCatalog `json:"catalogs,omitempty"`     // 目录列表
+	Message     string                       `json:"message,omitempty"`      // 可选消息
+	Timestamp   string                       `json:"timestamp"`              // 时间戳
+}
+
+// listServiceCatalogsHandler 列出所有服务的目录信息
+func (h *EchoHandler) listServiceCatalogsHandler(c echo.Context) error {
+	catalogs, err := h.etcdClient.ListServiceCatalogs(c.Request().Context())
+	if err != nil {
+		h.log(c).Error("获取服务目录列表失败", zap.Error(err))
+		return respondError(c, storageError(err))
+	}
+
+	return c.JSON(http.StatusOK, &ServiceCatalogResponse{
+		Success:   true,
+		Catalogs:  catalogs,
+		Timestamp: time.Now().Format(time.RFC3339),
+	})
+}
+
+// getServiceCatalogHandler 获取服务的目录信息
+func (h *EchoHandler) getServiceCatalogHandler(c echo.Context) error {
+	serviceName := c.Param("serviceName")
+
+	catalog, err := h.etcdClient.GetServiceCatalog(c.Request().Context(), serviceName)
+	if err != nil {
+		h.log(c).Error("获取服务目录失败", zap.String("service", serviceName), zap.Error(err))
+		return respondError(c, storageError(err))
+	}
+
+	return c.JSON(http.StatusOK, &ServiceCatalogResponse{
+		Success:     true,
+		ServiceName: serviceName,
+		Catalog:     catalog,
+		Timestamp:   time.Now().Format(time.RFC3339),
+	})
+}
+
+// putServiceCatalogHandler 设置服务的目录信息，服务不需要已有注册的实例
+func (h *EchoHandler) putServiceCatalogHandler(c echo.Context) error {
+	serviceName := c.Param("serviceName")
+
+	req := new(ServiceCatalogRequest)
+	if apiErr := bindRequest(c, req); apiErr != nil {
+		return respondError(c, apiErr)
+	}
+
+	catalog := &etcdclient.ServiceCatalog{
+		ServiceName:  serviceName,
+		Owner:        req.Owner,
+		SlackChannel: req.SlackChannel,
+		Description:  req.Description,
+		RunbookURL:   req.RunbookURL,
+		Repository:   req.Repository,
+		Links:        req.Links,
+	}
+	if err := catalog.Validate(); err != nil {
+		return respondError(c, badRequest(CodeInvalidParameter, err.Error()))
+	}
+	if err := h.etcdClient.PutServiceCatalog(c.Request().Context(), catalog); err != nil {
+		h.log(c).Error("保存服务目录失败", zap.String("service", serviceName), zap.Error(err))
+		return respondError(c, storageError(err))
+	}
+
+	return c.JSON(http.StatusOK, &ServiceCatalogResponse{
+		Success:     true,
+		ServiceName: serviceName,
+		Catalog:     catalog,
+		Message:     "服务目录已更新",
+		Timestamp:   time.Now().Format(time.RFC3339),
+	})
+}
+
+// deleteServiceCatalogHandler 删除服务的目录信息
+func (h *EchoHandler) deleteServiceCatalogHandler(c echo.Context) error {
+	serviceName := c.Param("serviceName")
+
+	if err := h.etcdClient.DeleteServiceCatalog(c.Request().Context(), serviceName); err != nil {
+		h.log(c).Error("删除服务目录失败", zap.String("service", serviceName), zap.Error(err))
+		return respondError(c, storageError(err))
+	}
+
+	return c.JSON(http.StatusOK, &ServiceCatalogResponse{
+		Success:     true,
+		ServiceName: serviceName,
+		Message:     "服务目录已删除",
+		Timestamp:   time.Now().Format(time.RFC3339),
+	})
+}
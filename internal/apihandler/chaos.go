@@ -0,0 +1,78 @@
+package apihandler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/hewenyu/kong-discovery/internal/chaos"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// ChaosRequest 定义故障注入请求结构，未设置的故障不注入
+type ChaosRequest struct {
+	EtcdLatencyMs       int     `json:"etcd_latency_ms" validate:"min=0"`  // 每次etcd读写操作前增加的延迟（毫秒）
+	WatchDropRate       float64 `json:"watch_drop_rate"`                   // 丢弃watch事件的比例，0到1之间
+	UpstreamTimeoutRate float64 `json:"upstream_timeout_rate"`             // 上游DNS查询模拟超时的比例，0到1之间
+	DurationSeconds     int     `json:"duration_seconds" validate:"min=0"` // 故障持续的时间（秒），之后自动清除；0表示直到手动清除
+}
+
+// ChaosResponse 定义故障注入状态响应结构
+type ChaosResponse struct {
+	Success   bool         `json:"success"`           // 是否成功
+	Faults    chaos.Faults `json:"faults"`            // 当前生效的故障
+	Stats     chaos.Stats  `json:"stats"`             // 各类故障已注入的次数
+	Message   string       `json:"message,omitempty"` // 可选消息
+	Timestamp string       `json:"timestamp"`         // 时间戳
+}
+
+// chaosResponse 返回当前的故障注入状态
+func chaosResponse(c echo.Context, message string) error {
+	inj := chaos.Global()
+	return c.JSON(http.StatusOK, &ChaosResponse{
+		Success:   true,
+		Faults:    inj.Faults(),
+		Stats:     inj.Stats(),
+		Message:   message,
+		Timestamp: time.Now().Format(time.RFC3339),
+	})
+}
+
+// getChaosHandler 获取当前注入的故障和已注入的次数
+func (h *EchoHandler) getChaosHandler(c echo.Context) error {
+	return chaosResponse(c, "")
+}
+
+// putChaosHandler 替换当前注入的故障
+func (h *EchoHandler) putChaosHandler(c echo.Context) error {
+	req := new(ChaosRequest)
+	if apiErr := bindRequest(c, req); apiErr != nil {
+		return respondError(c, apiErr)
+	}
+
+	faults := chaos.Faults{
+		EtcdLatencyMs:       req.EtcdLatencyMs,
+		WatchDropRate:       req.WatchDropRate,
+		UpstreamTimeoutRate: req.UpstreamTimeoutRate,
+	}
+	if req.DurationSeconds > 0 {
+		faults.ExpiresAt = time.Now().Add(time.Duration(req.DurationSeconds) * time.Second)
+	}
+	if err := chaos.Global().Set(faults); err != nil {
+		return respondError(c, badRequest(CodeInvalidParameter, err.Error()))
+	}
+
+	h.log(c).Warn("已注入故障",
+		zap.Int("etcd_latency_ms", faults.EtcdLatencyMs),
+		zap.Float64("watch_drop_rate", faults.WatchDropRate),
+		zap.Float64("upstream_timeout_rate", faults.UpstreamTimeoutRate),
+		zap.Int("duration_seconds", req.DurationSeconds))
+	return chaosResponse(c, "故障已注入")
+}
+
+// deleteChaosHandler 清除所有故障
+func (h *EchoHandler) deleteChaosHandler(c echo.Context) error {
+	chaos.Global().Clear()
+	h.log(c).Info("已清除注入的故障")
+	return chaosResponse(c, "故障已清除")
+}
@@ -0,0 +1,39 @@
+package apihandler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/hewenyu/kong-discovery/internal/cluster"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// ClusterPeersResponse 定义集群节点列表响应结构
+type ClusterPeersResponse struct {
+	Success   bool            `json:"success"`           // 是否成功
+	Peers     []*cluster.Peer `json:"peers"`             // 正在运行的节点
+	Count     int             `json:"count"`             // 节点数量
+	Message   string          `json:"message,omitempty"` // 可选消息
+	Timestamp string          `json:"timestamp"`         // 时间戳
+}
+
+// clusterPeersHandler 列出注册在系统命名空间中的所有kong-discovery节点及其版本和角色
+func (h *EchoHandler) clusterPeersHandler(c echo.Context) error {
+	peers, err := cluster.ListPeers(c.Request().Context(), h.etcdClient, h.cfg.SelfRegistration.ServiceName)
+	if err != nil {
+		h.log(c).Error("获取集群节点列表失败", zap.Error(err))
+		return respondError(c, storageError(err))
+	}
+
+	response := &ClusterPeersResponse{
+		Success:   true,
+		Peers:     peers,
+		Count:     len(peers),
+		Timestamp: time.Now().Format(time.RFC3339),
+	}
+	if !h.cfg.SelfRegistration.Enabled {
+		response.Message = "本节点未启用自身注册，不会出现在列表中"
+	}
+	return c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,587 @@
+package apihandler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hewenyu/kong-discovery/internal/etcdclient"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// Consul兼容API使用的响应头，Consul客户端在每次读取时解析这些响应头
+const (
+	consulIndexHeader       = "X-Consul-Index"
+	consulKnownLeaderHeader = "X-Consul-KnownLeader"
+	consulLastContactHeader = "X-Consul-LastContact"
+)
+
+// consulTagsMeta Consul标签保存在实例元数据中使用的键，多个标签以逗号分隔
+const consulTagsMeta = "consul_tags"
+
+// consulServiceCheckPrefix 服务检查ID的前缀，实例的检查ID为 service:<实例ID>
+const consulServiceCheckPrefix = "service:"
+
+// ConsulAgentService Consul /v1/agent/service/register 的请求结构
+type ConsulAgentService struct {
+	ID        string              `json:"ID"`                              // 实例ID，为空时与服务名称相同
+	Name      string              `json:"Name" validate:"required"`        // 服务名称
+	Tags      []string            `json:"Tags"`                            // 标签
+	Address   string              `json:"Address" validate:"omitempty,ip"` // IP地址，为空时使用请求的来源地址
+	Port      int                 `json:"Port" validate:"required,port"`   // 端口
+	Meta      map[string]string   `json:"Meta"`                            // 元数据
+	Namespace string              `json:"Namespace"`                       // 命名空间，为空表示default
+	Check     *ConsulAgentCheck   `json:"Check"`                           // 单个检查
+	Checks    []*ConsulAgentCheck `json:"Checks"`                          // 多个检查
+}
+
+// ConsulAgentCheck 注册时附带的检查，只使用TTL检查的TTL和初始状态，其他类型的检查被忽略
+type ConsulAgentCheck struct {
+	TTL    string `json:"TTL"`    // TTL检查的超时时间，如30s，作为实例的租约TTL
+	Status string `json:"Status"` // 初始状态：passing、warning或critical
+}
+
+// ConsulNode Consul节点，每个实例作为一个以实例ID命名的节点返回
+type ConsulNode struct {
+	ID         string `json:"ID"`
+	Node       string `json:"Node"`
+	Address    string `json:"Address"`
+	Datacenter string `json:"Datacenter"`
+}
+
+// ConsulService Consul服务实例
+type ConsulService struct {
+	ID      string            `json:"ID"`
+	Service string            `json:"Service"`
+	Tags    []string          `json:"Tags"`
+	Address string            `json:"Address"`
+	Port    int               `json:"Port"`
+	Meta    map[string]string `json:"Meta"`
+}
+
+// ConsulHealthCheck Consul健康检查
+type ConsulHealthCheck struct {
+	Node        string `json:"Node"`
+	CheckID     string `json:"CheckID"`
+	Name        string `json:"Name"`
+	Status      string `json:"Status"`
+	Output      string `json:"Output"`
+	ServiceID   string `json:"ServiceID"`
+	ServiceName string `json:"ServiceName"`
+}
+
+// ConsulServiceEntry /v1/health/service/:service 返回的条目
+type ConsulServiceEntry struct {
+	Node    *ConsulNode          `json:"Node"`
+	Service *ConsulService       `json:"Service"`
+	Checks  []*ConsulHealthCheck `json:"Checks"`
+}
+
+// ConsulCatalogService /v1/catalog/service/:service 返回的条目
+type ConsulCatalogService struct {
+	ID             string            `json:"ID"`
+	Node           string            `json:"Node"`
+	Address        string            `json:"Address"`
+	Datacenter     string            `json:"Datacenter"`
+	ServiceID      string            `json:"ServiceID"`
+	ServiceName    string            `json:"ServiceName"`
+	ServiceTags    []string          `json:"ServiceTags"`
+	ServiceAddress string            `json:"ServiceAddress"`
+	ServicePort    int               `json:"ServicePort"`
+	ServiceMeta    map[string]string `json:"ServiceMeta"`
+}
+
+// registerConsulRoutes 在服务注册API上注册Consul兼容端点
+func (h *EchoHandler) registerConsulRoutes() {
+	e := h.registrationServer
+	e.GET("/v1/catalog/services", h.consulCatalogServicesHandler)
+	e.GET("/v1/catalog/service/:service", h.consulCatalogServiceHandler)
+	e.GET("/v1/health/service/:service", h.consulHealthServiceHandler)
+	e.PUT("/v1/agent/service/register", h.consulRegisterHandler)
+	e.PUT("/v1/agent/service/deregister/:id", h.consulDeregisterHandler)
+	e.PUT("/v1/agent/check/pass/:checkId", h.consulCheckHandler(etcdclient.HealthPassing))
+	e.PUT("/v1/agent/check/warn/:checkId", h.consulCheckHandler(etcdclient.HealthWarning))
+	e.PUT("/v1/agent/check/fail/:checkId", h.consulCheckHandler(etcdclient.HealthCritical))
+	e.GET("/v1/agent/self", h.consulAgentSelfHandler)
+	e.GET("/v1/status/leader", h.consulLeaderHandler)
+}
+
+// consulDatacenter 返回配置的数据中心名称
+func (h *EchoHandler) consulDatacenter() string {
+	if h.cfg != nil && h.cfg.API.Consul.Datacenter != "" {
+		return h.cfg.API.Consul.Datacenter
+	}
+	return "dc1"
+}
+
+// consulError 以纯文本返回错误，与Consul的错误响应格式一致
+func consulError(c echo.Context, status int, message string) error {
+	return c.String(status, message)
+}
+
+// consulStorageError 将etcdclient返回的错误转为Consul风格的错误响应
+func consulStorageError(c echo.Context, err error) error {
+	apiErr := storageError(err)
+	return consulError(c, apiErr.Status, apiErr.Detail)
+}
+
+// checkConsulDatacenter 校验dc参数，只能查询本数据中心
+func (h *EchoHandler) checkConsulDatacenter(c echo.Context) error {
+	if dc := c.QueryParam("dc"); dc != "" && dc != h.consulDatacenter() {
+		return consulError(c, http.StatusInternalServerError, "No path to datacenter")
+	}
+	return nil
+}
+
+// consulBlockingRead 处理Consul的阻塞查询参数index和wait，并把读取数据前的etcd修订版本设置为X-Consul-Index
+// proceed为false时不应再读取数据，err为已经写出的错误响应（客户端已断开时为nil）
+func (h *EchoHandler) consulBlockingRead(c echo.Context, wait func(ctx context.Context, index int64) error) (proceed bool, err error) {
+	if err := h.checkConsulDatacenter(c); err != nil {
+		return false, err
+	}
+
+	ok, apiErr := h.waitForChange(c, wait)
+	if apiErr != nil {
+		return false, consulError(c, apiErr.Status, apiErr.Detail)
+	}
+	if !ok {
+		return false, nil
+	}
+
+	index, revErr := h.etcdClient.Revision(c.Request().Context())
+	if revErr != nil {
+		return false, consulStorageError(c, revErr)
+	}
+
+	// Consul客户端要求每个读取响应都带有这三个响应头
+	header := c.Response().Header()
+	header.Set(consulIndexHeader, strconv.FormatInt(index, 10))
+	header.Set(consulKnownLeaderHeader, "true")
+	header.Set(consulLastContactHeader, "0")
+	return true, nil
+}
+
+// consulNamespace 返回查询的命名空间，与Consul企业版一样通过ns参数指定
+func consulNamespace(c echo.Context) string {
+	return etcdclient.NamespaceOf(c.QueryParam("ns"))
+}
+
+// consulTags 返回实例的Consul标签
+func consulTags(instance *etcdclient.ServiceInstance) []string {
+	tags := []string{}
+	if value := instance.Metadata[consulTagsMeta]; value != "" {
+		tags = append(tags, strings.Split(value, ",")...)
+	}
+	return tags
+}
+
+// consulMeta 返回实例不含标签的元数据
+func consulMeta(instance *etcdclient.ServiceInstance) map[string]string {
+	meta := make(map[string]string, len(instance.Metadata))
+	for k, v := range instance.Metadata {
+		if k != consulTagsMeta {
+			meta[k] = v
+		}
+	}
+	return meta
+}
+
+// hasTags 判断实例是否带有所有指定的标签
+func hasTags(instance *etcdclient.ServiceInstance, tags []string) bool {
+	own := consulTags(instance)
+	for _, tag := range tags {
+		found := false
+		for _, t := range own {
+			if t == tag {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// consulChecks 把实例的健康状态和维护状态转为Consul的检查
+// draining映射为critical，使依赖passing过滤的客户端不再把流量发到摘流中的实例
+func consulChecks(instance *etcdclient.ServiceInstance, maintenance *etcdclient.MaintenanceInfo) []*ConsulHealthCheck {
+	check := func(id, name, status, output string) *ConsulHealthCheck {
+		return &ConsulHealthCheck{
+			Node:        instance.InstanceID,
+			CheckID:     id,
+			Name:        name,
+			Status:      status,
+			Output:      output,
+			ServiceID:   instance.InstanceID,
+			ServiceName: instance.ServiceName,
+		}
+	}
+
+	health := instance.HealthStatus()
+	status := health
+	if health == etcdclient.HealthDraining {
+		status = etcdclient.HealthCritical
+	}
+	checks := []*ConsulHealthCheck{
+		check(consulServiceCheckPrefix+instance.InstanceID, fmt.Sprintf("Service '%s' check", instance.ServiceName), status, health),
+	}
+
+	if maintenance != nil {
+		checks = append(checks, check("_service_maintenance:"+instance.InstanceID, "Service Maintenance Mode", etcdclient.HealthCritical, maintenance.Reason))
+	} else if instance.Maintenance {
+		checks = append(checks, check("_service_maintenance:"+instance.InstanceID, "Service Maintenance Mode", etcdclient.HealthCritical, ""))
+	}
+	return checks
+}
+
+// consulPassing 判断所有检查是否都为passing
+func consulPassing(checks []*ConsulHealthCheck) bool {
+	for _, check := range checks {
+		if check.Status != etcdclient.HealthPassing {
+			return false
+		}
+	}
+	return true
+}
+
+// namespaceInstances 返回命名空间中服务的所有实例（包括不健康的实例），按实例ID排序
+func (h *EchoHandler) namespaceInstances(ctx context.Context, namespace, serviceName string) ([]*etcdclient.ServiceInstance, error) {
+	instances, err := h.etcdClient.GetServiceInstances(ctx, serviceName)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*etcdclient.ServiceInstance, 0, len(instances))
+	for _, instance := range instances {
+		if etcdclient.NamespaceOf(instance.Namespace) == namespace {
+			result = append(result, instance)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].InstanceID < result[j].InstanceID
+	})
+	return result, nil
+}
+
+// consulCatalogServicesHandler 列出所有服务及其标签
+func (h *EchoHandler) consulCatalogServicesHandler(c echo.Context) error {
+	proceed, err := h.consulBlockingRead(c, func(waitCtx context.Context, index int64) error {
+		return h.etcdClient.WaitServicesChange(waitCtx, index)
+	})
+	if !proceed {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	namespace := consulNamespace(c)
+	services, err := h.etcdClient.ListServices(ctx)
+	if err != nil {
+		h.log(c).Error("获取服务列表失败", zap.Error(err))
+		return consulStorageError(c, err)
+	}
+
+	result := make(map[string][]string, len(services))
+	for _, service := range services {
+		instances, err := h.namespaceInstances(ctx, namespace, service.ServiceName)
+		if err != nil {
+			return consulStorageError(c, err)
+		}
+		if len(instances) == 0 {
+			continue
+		}
+
+		seen := make(map[string]bool)
+		tags := []string{}
+		for _, instance := range instances {
+			for _, tag := range consulTags(instance) {
+				if !seen[tag] {
+					seen[tag] = true
+					tags = append(tags, tag)
+				}
+			}
+		}
+		sort.Strings(tags)
+		result[service.ServiceName] = tags
+	}
+	return c.JSON(http.StatusOK, result)
+}
+
+// consulCatalogServiceHandler 列出服务的所有实例，不考虑健康状态
+func (h *EchoHandler) consulCatalogServiceHandler(c echo.Context) error {
+	serviceName := c.Param("service")
+	proceed, err := h.consulBlockingRead(c, func(waitCtx context.Context, index int64) error {
+		return h.etcdClient.WaitServiceChange(waitCtx, serviceName, index)
+	})
+	if !proceed {
+		return err
+	}
+
+	instances, err := h.namespaceInstances(c.Request().Context(), consulNamespace(c), serviceName)
+	if err != nil {
+		h.log(c).Error("查询服务实例失败", zap.String("service", serviceName), zap.Error(err))
+		return consulStorageError(c, err)
+	}
+
+	tags := c.QueryParams()["tag"]
+	result := make([]*ConsulCatalogService, 0, len(instances))
+	for _, instance := range instances {
+		if !hasTags(instance, tags) {
+			continue
+		}
+		result = append(result, &ConsulCatalogService{
+			ID:             instance.InstanceID,
+			Node:           instance.InstanceID,
+			Address:        instance.IPAddress,
+			Datacenter:     h.consulDatacenter(),
+			ServiceID:      instance.InstanceID,
+			ServiceName:    instance.ServiceName,
+			ServiceTags:    consulTags(instance),
+			ServiceAddress: instance.IPAddress,
+			ServicePort:    instance.Port,
+			ServiceMeta:    consulMeta(instance),
+		})
+	}
+	return c.JSON(http.StatusOK, result)
+}
+
+// consulHealthServiceHandler 列出服务的实例及其健康检查，passing参数只返回所有检查都通过的实例
+func (h *EchoHandler) consulHealthServiceHandler(c echo.Context) error {
+	serviceName := c.Param("service")
+	proceed, err := h.consulBlockingRead(c, func(waitCtx context.Context, index int64) error {
+		return h.etcdClient.WaitServiceChange(waitCtx, serviceName, index)
+	})
+	if !proceed {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	instances, err := h.namespaceInstances(ctx, consulNamespace(c), serviceName)
+	if err != nil {
+		h.log(c).Error("查询服务实例失败", zap.String("service", serviceName), zap.Error(err))
+		return consulStorageError(c, err)
+	}
+	maintenance, err := h.etcdClient.GetServiceMaintenance(ctx, serviceName)
+	if err != nil {
+		return consulStorageError(c, err)
+	}
+
+	passingOnly := false
+	if values, ok := c.QueryParams()["passing"]; ok {
+		passingOnly = len(values) == 0 || values[0] != "false"
+	}
+	tags := c.QueryParams()["tag"]
+
+	result := make([]*ConsulServiceEntry, 0, len(instances))
+	for _, instance := range instances {
+		if !hasTags(instance, tags) {
+			continue
+		}
+		checks := consulChecks(instance, maintenance)
+		if passingOnly && !consulPassing(checks) {
+			continue
+		}
+		result = append(result, &ConsulServiceEntry{
+			Node: &ConsulNode{
+				ID:         instance.InstanceID,
+				Node:       instance.InstanceID,
+				Address:    instance.IPAddress,
+				Datacenter: h.consulDatacenter(),
+			},
+			Service: &ConsulService{
+				ID:      instance.InstanceID,
+				Service: instance.ServiceName,
+				Tags:    consulTags(instance),
+				Address: instance.IPAddress,
+				Port:    instance.Port,
+				Meta:    consulMeta(instance),
+			},
+			Checks: checks,
+		})
+	}
+	return c.JSON(http.StatusOK, result)
+}
+
+// consulRegisterHandler 注册服务实例
+// TTL检查的TTL作为实例的租约TTL，没有TTL检查时使用配置的default_ttl
+func (h *EchoHandler) consulRegisterHandler(c echo.Context) error {
+	req := new(ConsulAgentService)
+	if apiErr := bindRequest(c, req); apiErr != nil {
+		return consulError(c, apiErr.Status, apiErr.Detail)
+	}
+
+	instance, err := h.consulInstance(c, req)
+	if err != nil {
+		return consulError(c, http.StatusBadRequest, err.Error())
+	}
+
+	if err := h.etcdClient.RegisterService(c.Request().Context(), instance); err != nil {
+		h.log(c).Error("通过Consul兼容API注册服务失败",
+			zap.String("service", instance.ServiceName),
+			zap.String("id", instance.InstanceID),
+			zap.Error(err))
+		return consulStorageError(c, err)
+	}
+
+	h.log(c).Info("通过Consul兼容API注册服务",
+		zap.String("service", instance.ServiceName),
+		zap.String("id", instance.InstanceID),
+		zap.Int("ttl", instance.TTL))
+	return c.NoContent(http.StatusOK)
+}
+
+// consulInstance 把Consul的注册请求转为服务实例
+func (h *EchoHandler) consulInstance(c echo.Context, req *ConsulAgentService) (*etcdclient.ServiceInstance, error) {
+	instance := &etcdclient.ServiceInstance{
+		ServiceName: req.Name,
+		InstanceID:  req.ID,
+		IPAddress:   req.Address,
+		Port:        req.Port,
+		Namespace:   req.Namespace,
+		Metadata:    make(map[string]string, len(req.Meta)+1),
+	}
+	if instance.InstanceID == "" {
+		instance.InstanceID = req.Name
+	}
+	if instance.IPAddress == "" {
+		instance.IPAddress = c.RealIP()
+		if net.ParseIP(instance.IPAddress) == nil {
+			return nil, fmt.Errorf("无法确定服务地址，请设置Address")
+		}
+	}
+	if apiErr := h.validateMetadata(req.Meta); apiErr != nil {
+		return nil, errors.New(apiErr.Detail)
+	}
+	for k, v := range req.Meta {
+		instance.Metadata[k] = v
+	}
+	if len(req.Tags) > 0 {
+		instance.Metadata[consulTagsMeta] = strings.Join(req.Tags, ",")
+	}
+
+	checks := req.Checks
+	if req.Check != nil {
+		checks = append([]*ConsulAgentCheck{req.Check}, checks...)
+	}
+	for _, check := range checks {
+		if check == nil || check.TTL == "" {
+			continue
+		}
+		ttl, err := time.ParseDuration(check.TTL)
+		if err != nil || ttl <= 0 {
+			return nil, fmt.Errorf("无效的检查TTL: %s", check.TTL)
+		}
+		instance.TTL = int(math.Ceil(ttl.Seconds()))
+		if check.Status != "" {
+			if check.Status != etcdclient.HealthPassing && check.Status != etcdclient.HealthWarning && check.Status != etcdclient.HealthCritical {
+				return nil, fmt.Errorf("无效的检查状态: %s", check.Status)
+			}
+			instance.Health = check.Status
+		}
+		break
+	}
+	if instance.TTL == 0 {
+		instance.TTL = 60
+		if h.cfg != nil && h.cfg.API.Consul.DefaultTTL > 0 {
+			instance.TTL = h.cfg.API.Consul.DefaultTTL
+		}
+	}
+	return instance, nil
+}
+
+// findInstanceByID 按实例ID查找实例，Consul的注销和检查端点只提供实例ID
+func (h *EchoHandler) findInstanceByID(ctx context.Context, instanceID string) (*etcdclient.ServiceInstance, error) {
+	services, err := h.etcdClient.ListServices(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, service := range services {
+		instances, err := h.etcdClient.GetServiceInstances(ctx, service.ServiceName)
+		if err != nil {
+			return nil, err
+		}
+		for _, instance := range instances {
+			if instance.InstanceID == instanceID {
+				return instance, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", etcdclient.ErrInstanceNotFound, instanceID)
+}
+
+// consulDeregisterHandler 注销服务实例
+func (h *EchoHandler) consulDeregisterHandler(c echo.Context) error {
+	ctx := c.Request().Context()
+	instance, err := h.findInstanceByID(ctx, c.Param("id"))
+	if err != nil {
+		return consulStorageError(c, err)
+	}
+
+	if err := h.etcdClient.DeregisterService(ctx, instance.ServiceName, instance.InstanceID); err != nil {
+		h.log(c).Error("通过Consul兼容API注销服务失败",
+			zap.String("service", instance.ServiceName),
+			zap.String("id", instance.InstanceID),
+			zap.Error(err))
+		return consulStorageError(c, err)
+	}
+	return c.NoContent(http.StatusOK)
+}
+
+// consulCheckHandler 更新TTL检查：刷新实例的租约并设置健康状态
+func (h *EchoHandler) consulCheckHandler(status string) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		checkID := c.Param("checkId")
+		instanceID := strings.TrimPrefix(checkID, consulServiceCheckPrefix)
+		if instanceID == checkID {
+			return consulError(c, http.StatusNotFound, "Unknown check ID "+strconv.Quote(checkID))
+		}
+
+		ctx := c.Request().Context()
+		instance, err := h.findInstanceByID(ctx, instanceID)
+		if errors.Is(err, etcdclient.ErrInstanceNotFound) {
+			return consulError(c, http.StatusNotFound, "Unknown check ID "+strconv.Quote(checkID))
+		}
+		if err != nil {
+			return consulStorageError(c, err)
+		}
+
+		if err := h.etcdClient.RefreshServiceLease(ctx, instance.ServiceName, instance.InstanceID, 0); err != nil {
+			return consulStorageError(c, err)
+		}
+		if instance.HealthStatus() != status {
+			if _, err := h.etcdClient.SetInstanceHealth(ctx, instance.ServiceName, instance.InstanceID, status); err != nil {
+				return consulStorageError(c, err)
+			}
+		}
+		return c.NoContent(http.StatusOK)
+	}
+}
+
+// consulAgentSelfHandler 返回代理信息，Prometheus在未配置数据中心时通过该端点获取
+func (h *EchoHandler) consulAgentSelfHandler(c echo.Context) error {
+	hostname, _ := os.Hostname()
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"Config": map[string]string{
+			"Datacenter": h.consulDatacenter(),
+			"NodeName":   hostname,
+		},
+		"Member": map[string]string{
+			"Name": hostname,
+		},
+	})
+}
+
+// consulLeaderHandler 返回集群领导者地址，客户端用来检查Consul是否可用
+func (h *EchoHandler) consulLeaderHandler(c echo.Context) error {
+	return c.JSON(http.StatusOK, c.Request().Host)
+}
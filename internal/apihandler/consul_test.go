@@ -0,0 +1,184 @@
+package apihandler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hewenyu/kong-discovery/internal/config"
+	"github.com/hewenyu/kong-discovery/internal/etcdclient"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConsulChecks(t *testing.T) {
+	instance := &etcdclient.ServiceInstance{ServiceName: "web", InstanceID: "web-1"}
+	checks := consulChecks(instance, nil)
+	require.Len(t, checks, 1)
+	assert.Equal(t, "service:web-1", checks[0].CheckID)
+	assert.Equal(t, etcdclient.HealthPassing, checks[0].Status)
+	assert.True(t, consulPassing(checks))
+
+	// 摘流中的实例视为critical
+	instance.Health = etcdclient.HealthDraining
+	checks = consulChecks(instance, nil)
+	assert.Equal(t, etcdclient.HealthCritical, checks[0].Status)
+	assert.Equal(t, etcdclient.HealthDraining, checks[0].Output)
+
+	// 维护模式增加一个critical检查
+	instance.Health = etcdclient.HealthPassing
+	checks = consulChecks(instance, &etcdclient.MaintenanceInfo{ServiceName: "web", Reason: "升级"})
+	require.Len(t, checks, 2)
+	assert.Equal(t, "升级", checks[1].Output)
+	assert.False(t, consulPassing(checks))
+}
+
+func TestConsulEndpoints(t *testing.T) {
+	// 跳过集成测试，除非明确要求运行
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	cfg := createTestConfig(t)
+	cfg.API.Consul.Enabled = true
+	cfg.API.Consul.Datacenter = "dc-test"
+	cfg.API.Consul.DefaultTTL = 30
+
+	e := echo.New()
+	client := etcdclient.CreateEtcdClientForTest(t)
+	t.Cleanup(func() { _ = client.Close() })
+
+	handler := &EchoHandler{
+		registrationServer: e,
+		cfg:                cfg,
+		logger:             createTestLogger(t),
+		etcdClient:         client,
+	}
+	handler.registerRegistrationRoutes()
+
+	suffix := time.Now().UnixNano()
+	serviceName := fmt.Sprintf("consul-service-%d", suffix)
+	web1, web2 := fmt.Sprintf("web-1-%d", suffix), fmt.Sprintf("web-2-%d", suffix)
+	t.Cleanup(func() {
+		_ = client.DeregisterService(context.Background(), serviceName, web1)
+		_ = client.DeregisterService(context.Background(), serviceName, web2)
+	})
+
+	serve := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// 注册两个实例，一个带TTL检查
+	rec := serve(http.MethodPut, "/v1/agent/service/register", fmt.Sprintf(
+		`{"ID": "%s", "Name": "%s", "Tags": ["v1", "primary"], "Address": "10.0.0.1", "Port": 8080, "Meta": {"zone": "a"}, "Check": {"TTL": "15s"}}`, web1, serviceName))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	rec = serve(http.MethodPut, "/v1/agent/service/register", fmt.Sprintf(
+		`{"ID": "%s", "Name": "%s", "Tags": ["v2"], "Address": "10.0.0.2", "Port": 8080}`, web2, serviceName))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	instances, err := client.GetServiceInstances(context.Background(), serviceName)
+	require.NoError(t, err)
+	ttls := map[string]int{}
+	for _, instance := range instances {
+		ttls[instance.InstanceID] = instance.TTL
+	}
+	assert.Equal(t, map[string]int{web1: 15, web2: 30}, ttls)
+
+	// 缺少端口的注册请求
+	rec = serve(http.MethodPut, "/v1/agent/service/register", `{"Name": "x", "Address": "10.0.0.3"}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	// 服务目录带有标签和Consul响应头
+	rec = serve(http.MethodGet, "/v1/catalog/services", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var services map[string][]string
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &services))
+	assert.Equal(t, []string{"primary", "v1", "v2"}, services[serviceName])
+	index, err := strconv.ParseInt(rec.Header().Get(consulIndexHeader), 10, 64)
+	require.NoError(t, err)
+	assert.Greater(t, index, int64(0))
+	assert.Equal(t, "true", rec.Header().Get(consulKnownLeaderHeader))
+	assert.Equal(t, "0", rec.Header().Get(consulLastContactHeader))
+
+	health := func(query string) []*ConsulServiceEntry {
+		rec := serve(http.MethodGet, "/v1/health/service/"+serviceName+query, "")
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		var entries []*ConsulServiceEntry
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &entries))
+		return entries
+	}
+
+	entries := health("")
+	require.Len(t, entries, 2)
+	assert.Equal(t, web1, entries[0].Service.ID)
+	assert.Equal(t, "10.0.0.1", entries[0].Service.Address)
+	assert.Equal(t, map[string]string{"zone": "a"}, entries[0].Service.Meta)
+	assert.Equal(t, "dc-test", entries[0].Node.Datacenter)
+
+	entries = health("?tag=v2")
+	require.Len(t, entries, 1)
+	assert.Equal(t, web2, entries[0].Service.ID)
+
+	// TTL检查失败后passing过滤掉该实例，恢复后重新出现
+	rec = serve(http.MethodPut, "/v1/agent/check/fail/service:"+web1, "")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	entries = health("?passing")
+	require.Len(t, entries, 1)
+	assert.Equal(t, web2, entries[0].Service.ID)
+
+	rec = serve(http.MethodPut, "/v1/agent/check/pass/service:"+web1, "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Len(t, health("?passing"), 2)
+
+	rec = serve(http.MethodPut, "/v1/agent/check/pass/unknown", "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	// catalog/service不考虑健康状态
+	rec = serve(http.MethodGet, "/v1/catalog/service/"+serviceName, "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var catalog []*ConsulCatalogService
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &catalog))
+	require.Len(t, catalog, 2)
+	assert.Equal(t, []string{"v1", "primary"}, catalog[0].ServiceTags)
+
+	// 其他数据中心
+	rec = serve(http.MethodGet, "/v1/catalog/services?dc=other", "")
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+
+	rec = serve(http.MethodGet, "/v1/agent/self", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"Datacenter":"dc-test"`)
+
+	// 注销
+	rec = serve(http.MethodPut, "/v1/agent/service/deregister/"+web2, "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Len(t, health(""), 1)
+	rec = serve(http.MethodPut, "/v1/agent/service/deregister/"+web2, "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestConsulEndpoints_Disabled(t *testing.T) {
+	e := echo.New()
+	handler := &EchoHandler{
+		registrationServer: e,
+		cfg:                &config.Config{},
+		logger:             createTestLogger(t),
+	}
+	handler.registerRegistrationRoutes()
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/catalog/services", nil)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
@@ -0,0 +1,102 @@
+package apihandler
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/hewenyu/kong-discovery/internal/etcdclient"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// DiscoveryResponse 定义服务发现响应结构
+type DiscoveryResponse struct {
+	Success     bool                          `json:"success"`               // 是否成功
+	Namespace   string                        `json:"namespace"`             // 命名空间
+	ServiceName string                        `json:"service_name"`          // 服务名称
+	Index       int64                         `json:"index"`                 // 与X-Discovery-Index响应头相同
+	Maintenance *etcdclient.MaintenanceInfo   `json:"maintenance,omitempty"` // 服务维护状态
+	Instances   []*etcdclient.ServiceInstance `json:"instances"`             // 可用的实例
+	Timestamp   string                        `json:"timestamp"`             // 时间戳
+}
+
+// discoveryETag 根据实例列表和维护状态计算ETag，内容不变时ETag不变
+func discoveryETag(discovery *etcdclient.ServiceDiscovery) (string, error) {
+	data, err := json.Marshal(struct {
+		Instances   []*etcdclient.ServiceInstance `json:"instances"`
+		Maintenance *etcdclient.MaintenanceInfo   `json:"maintenance,omitempty"`
+	}{discovery.Instances, discovery.Maintenance})
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return `"` + hex.EncodeToString(sum[:8]) + `"`, nil
+}
+
+// discoveryHandler 返回服务的可用实例
+// 携带index参数时进行长轮询：服务在index之后发生变更才返回，最多等待wait指定的时间；
+// 响应带有基于内容的ETag，可以通过If-None-Match进行条件请求
+func (h *EchoHandler) discoveryHandler(c echo.Context) error {
+	namespace := c.Param("namespace")
+	serviceName := c.Param("service")
+
+	proceed, apiErr := h.waitForChange(c, func(waitCtx context.Context, index int64) error {
+		return h.etcdClient.WaitServiceChange(waitCtx, serviceName, index)
+	})
+	if apiErr != nil {
+		return respondError(c, apiErr)
+	}
+	if !proceed {
+		return nil
+	}
+
+	discovery, err := h.etcdClient.DiscoverService(c.Request().Context(), namespace, serviceName)
+	if err != nil {
+		h.log(c).Error("查询服务实例失败", zap.String("service", serviceName), zap.Error(err))
+		return respondError(c, storageError(fmt.Errorf("查询服务实例失败: %w", err)))
+	}
+
+	setIndexHeader(c, discovery.Index)
+	if discovery.Registered == 0 && discovery.Maintenance == nil {
+		return respondError(c, newAPIError(http.StatusNotFound, CodeServiceNotFound, discovery.Namespace+"/"+serviceName))
+	}
+
+	etag, err := discoveryETag(discovery)
+	if err != nil {
+		return respondError(c, newAPIError(http.StatusInternalServerError, CodeInternal, err.Error()))
+	}
+	c.Response().Header().Set("ETag", etag)
+	// 允许缓存，但使用前必须重新验证
+	c.Response().Header().Set("Cache-Control", "public, no-cache")
+
+	if match := c.Request().Header.Get("If-None-Match"); match != "" && etagMatches(match, etag) {
+		return c.NoContent(http.StatusNotModified)
+	}
+
+	return c.JSON(http.StatusOK, &DiscoveryResponse{
+		Success:     true,
+		Namespace:   discovery.Namespace,
+		ServiceName: discovery.ServiceName,
+		Index:       discovery.Index,
+		Maintenance: discovery.Maintenance,
+		Instances:   discovery.Instances,
+		Timestamp:   time.Now().Format(time.RFC3339),
+	})
+}
+
+// etagMatches 判断If-None-Match中是否包含etag
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,381 @@
+package apihandler
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hewenyu/kong-discovery/internal/etcdclient"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// DNSRecordsResponse 定义DNS记录响应结构
+type DNSRecordsResponse struct {
+	Success   bool                         `json:"success"`           // 是否成功
+	Records   []*etcdclient.DNSRecordEntry `json:"records,omitempty"` // DNS记录列表
+	Message   string                       `json:"message,omitempty"` // 可选消息
+	Timestamp string                       `json:"timestamp"`         // 时间戳
+}
+
+// validateDNSRecord 校验DNS记录的类型和值
+func validateDNSRecord(record *etcdclient.DNSRecord) *APIError {
+	record.Type = strings.ToUpper(record.Type)
+	if record.Value == "" {
+		return badRequest(CodeInvalidParameter, "记录值不能为空")
+	}
+	if record.TTL < 0 {
+		return badRequest(CodeInvalidTTL, "TTL不能为负数")
+	}
+
+	switch record.Type {
+	case "A":
+		if ip := net.ParseIP(record.Value); ip == nil || ip.To4() == nil {
+			return badRequest(CodeInvalidParameter, "无效的IPv4地址: "+record.Value)
+		}
+	case "AAAA":
+		if ip := net.ParseIP(record.Value); ip == nil || ip.To4() != nil {
+			return badRequest(CodeInvalidParameter, "无效的IPv6地址: "+record.Value)
+		}
+	case "CNAME":
+		if !isFQDN(record.Value) {
+			return badRequest(CodeInvalidParameter, "无效的CNAME目标域名: "+record.Value)
+		}
+	case "PTR":
+		if !isFQDN(record.Value) {
+			return badRequest(CodeInvalidParameter, "无效的PTR目标域名: "+record.Value)
+		}
+	case "TXT", "SRV":
+	default:
+		return badRequest(CodeInvalidParameter, "不支持的记录类型: "+record.Type)
+	}
+
+	if owner := record.Owner; owner != nil && (owner.Kind != etcdclient.OwnerKindService || owner.Name == "") {
+		return badRequest(CodeInvalidParameter, "无效的记录所有者: "+owner.String())
+	}
+	return nil
+}
+
+// listDNSRecordsHandler 列出DNS记录，可通过domain参数按域名过滤
+func (h *EchoHandler) listDNSRecordsHandler(c echo.Context) error {
+	domain := strings.TrimSuffix(c.QueryParam("domain"), ".")
+
+	records, err := h.etcdClient.ListDNSRecords(c.Request().Context())
+	if err != nil {
+		h.log(c).Error("获取DNS记录失败", zap.Error(err))
+		return respondError(c, storageError(fmt.Errorf("获取DNS记录失败: %w", err)))
+	}
+
+	result := make([]*etcdclient.DNSRecordEntry, 0, len(records))
+	for _, record := range records {
+		if domain == "" || record.Domain == domain {
+			result = append(result, record)
+		}
+	}
+
+	return c.JSON(http.StatusOK, &DNSRecordsResponse{
+		Success:   true,
+		Records:   result,
+		Timestamp: time.Now().Format(time.RFC3339),
+	})
+}
+
+// 域名列表每页的默认和最大数量
+const (
+	defaultDNSDomainLimit = 100
+	maxDNSDomainLimit     = 1000
+)
+
+// DNSDomainsResponse 定义DNS域名列表响应结构
+type DNSDomainsResponse struct {
+	Success    bool                           `json:"success"`               // 是否成功
+	Domains    []*etcdclient.DNSDomainSummary `json:"domains"`               // 本页的域名
+	NextCursor string                         `json:"next_cursor,omitempty"` // 下一页的游标，为空表示没有更多域名
+	Message    string                         `json:"message,omitempty"`     // 可选消息
+	Timestamp  string                         `json:"timestamp"`             // 时间戳
+}
+
+// listDNSDomainsHandler 分页列出DNS域名，支持prefix（域名前缀）、view（视图）、
+// limit（每页数量，默认100，最多1000）和cursor（上一页返回的next_cursor）参数
+func (h *EchoHandler) listDNSDomainsHandler(c echo.Context) error {
+	query := etcdclient.DNSDomainQuery{
+		Prefix: strings.ToLower(c.QueryParam("prefix")),
+		View:   c.QueryParam("view"),
+		Cursor: strings.ToLower(c.QueryParam("cursor")),
+		Limit:  defaultDNSDomainLimit,
+	}
+	if strings.Contains(query.Prefix+query.View+query.Cursor, "/") {
+		return respondError(c, badRequest(CodeInvalidParameter, "prefix、view和cursor不能包含/"))
+	}
+	if param := c.QueryParam("limit"); param != "" {
+		limit, err := strconv.Atoi(param)
+		if err != nil || limit <= 0 || limit > maxDNSDomainLimit {
+			return respondError(c, badRequest(CodeInvalidParameter, fmt.Sprintf("limit必须是1到%d之间的整数", maxDNSDomainLimit)))
+		}
+		query.Limit = limit
+	}
+
+	page, err := h.etcdClient.ListDNSDomains(c.Request().Context(), query)
+	if err != nil {
+		h.log(c).Error("获取DNS域名失败", zap.Error(err))
+		return respondError(c, storageError(fmt.Errorf("获取DNS域名失败: %w", err)))
+	}
+
+	return c.JSON(http.StatusOK, &DNSDomainsResponse{
+		Success:    true,
+		Domains:    page.Domains,
+		NextCursor: page.NextCursor,
+		Timestamp:  time.Now().Format(time.RFC3339),
+	})
+}
+
+// formatETag 将记录版本号格式化为ETag
+func formatETag(revision int64) string {
+	return fmt.Sprintf("%q", strconv.FormatInt(revision, 10))
+}
+
+// expectedRevision 根据条件请求头确定写入前要求的记录版本
+// If-Match: "<revision>" 要求记录版本一致；If-None-Match: * 要求记录不存在；都没有时不校验
+func expectedRevision(c echo.Context) (int64, *APIError) {
+	if match := strings.TrimSpace(c.Request().Header.Get("If-Match")); match != "" {
+		tag := strings.Trim(strings.TrimPrefix(match, "W/"), `"`)
+		revision, err := strconv.ParseInt(tag, 10, 64)
+		if err != nil || revision <= 0 {
+			return 0, badRequest(CodeInvalidParameter, "If-Match必须是记录的ETag: "+match)
+		}
+		return revision, nil
+	}
+
+	if noneMatch := strings.TrimSpace(c.Request().Header.Get("If-None-Match")); noneMatch != "" {
+		if noneMatch != "*" {
+			return 0, badRequest(CodeInvalidParameter, "If-None-Match只支持*")
+		}
+		return 0, nil
+	}
+
+	return etcdclient.AnyRevision, nil
+}
+
+// getDNSRecordHandler 获取单条DNS记录，通过ETag响应头返回记录版本
+func (h *EchoHandler) getDNSRecordHandler(c echo.Context) error {
+	domain := strings.TrimSuffix(c.Param("domain"), ".")
+	recordType := strings.ToUpper(c.Param("type"))
+	view := c.QueryParam("view")
+
+	entry, err := h.etcdClient.GetDNSRecordEntry(c.Request().Context(), domain, recordType, view)
+	if err != nil {
+		return respondError(c, storageError(fmt.Errorf("获取DNS记录失败: %w", err)))
+	}
+
+	c.Response().Header().Set("ETag", formatETag(entry.Revision))
+	return c.JSON(http.StatusOK, &DNSRecordsResponse{
+		Success:   true,
+		Records:   []*etcdclient.DNSRecordEntry{entry},
+		Timestamp: time.Now().Format(time.RFC3339),
+	})
+}
+
+// putDNSRecordHandler 创建或更新DNS记录
+// 携带If-Match或If-None-Match请求头时进行乐观并发控制，版本不一致返回409；
+// 记录的所有者与已有记录不同时返回409，force=true时强制覆盖
+func (h *EchoHandler) putDNSRecordHandler(c echo.Context) error {
+	domain := strings.TrimSuffix(c.Param("domain"), ".")
+
+	record := new(etcdclient.DNSRecord)
+	if apiErr := bindRequest(c, record); apiErr != nil {
+		return respondError(c, apiErr)
+	}
+
+	if apiErr := validateDNSRecord(record); apiErr != nil {
+		return respondError(c, apiErr)
+	}
+
+	expected, apiErr := expectedRevision(c)
+	if apiErr != nil {
+		return respondError(c, apiErr)
+	}
+
+	var revision int64
+	var err error
+	if c.QueryParam("force") == "true" {
+		op := &etcdclient.DNSRecordOperation{Op: etcdclient.DNSRecordOpPut, Domain: domain, Record: record, Force: true}
+		if expected != etcdclient.AnyRevision {
+			op.Revision = &expected
+		}
+		revision, err = h.forceDNSRecordOp(c, op)
+	} else {
+		revision, err = h.etcdClient.PutDNSRecordAtRevision(c.Request().Context(), domain, record, expected)
+	}
+	if err != nil {
+		h.log(c).Error("保存DNS记录失败", zap.String("domain", domain), zap.Error(err))
+		return respondError(c, storageError(fmt.Errorf("保存DNS记录失败: %w", err)))
+	}
+
+	c.Response().Header().Set("ETag", formatETag(revision))
+	return c.JSON(http.StatusOK, &DNSRecordsResponse{
+		Success:   true,
+		Records:   []*etcdclient.DNSRecordEntry{{Domain: domain, Revision: revision, DNSRecord: *record}},
+		Message:   "DNS记录保存成功",
+		Timestamp: time.Now().Format(time.RFC3339),
+	})
+}
+
+// deleteDNSRecordHandler 删除DNS记录，可通过view参数指定视图
+// 由服务自动维护的记录返回409，force=true时强制删除；dry_run=true时只报告将被删除的记录
+func (h *EchoHandler) deleteDNSRecordHandler(c echo.Context) error {
+	domain := strings.TrimSuffix(c.Param("domain"), ".")
+	recordType := strings.ToUpper(c.Param("type"))
+	view := c.QueryParam("view")
+	force := c.QueryParam("force") == "true"
+
+	if isDryRun(c) {
+		plan, err := h.etcdClient.PlanDeleteDNSRecord(c.Request().Context(), domain, recordType, view, force)
+		if err != nil {
+			return respondError(c, storageError(fmt.Errorf("删除DNS记录失败: %w", err)))
+		}
+		return respondPlan(c, plan)
+	}
+
+	var err error
+	if force {
+		_, err = h.forceDNSRecordOp(c, &etcdclient.DNSRecordOperation{
+			Op: etcdclient.DNSRecordOpDelete, Domain: domain, Type: recordType, View: view, Force: true,
+		})
+	} else {
+		err = h.etcdClient.DeleteDNSRecord(c.Request().Context(), domain, recordType, view)
+	}
+	if err != nil {
+		h.log(c).Error("删除DNS记录失败",
+			zap.String("domain", domain),
+			zap.String("type", recordType),
+			zap.Error(err))
+		return respondError(c, storageError(fmt.Errorf("删除DNS记录失败: %w", err)))
+	}
+
+	return c.JSON(http.StatusOK, &DNSRecordsResponse{
+		Success:   true,
+		Message:   "DNS记录删除成功",
+		Timestamp: time.Now().Format(time.RFC3339),
+	})
+}
+
+// forceDNSRecordOp 通过单项批量操作强制写入或删除记录，忽略记录的所有者，返回写入后的版本号
+func (h *EchoHandler) forceDNSRecordOp(c echo.Context, op *etcdclient.DNSRecordOperation) (int64, error) {
+	results, err := h.etcdClient.ApplyDNSRecordBatch(c.Request().Context(), []*etcdclient.DNSRecordOperation{op})
+	if err != nil {
+		return 0, err
+	}
+	h.log(c).Warn("已强制修改DNS记录",
+		zap.String("op", op.Op),
+		zap.String("domain", op.Domain),
+		zap.String("type", results[0].Type))
+	return results[0].Revision, nil
+}
+
+// DNSRecordBatchRequest 定义DNS记录批量操作请求结构
+type DNSRecordBatchRequest struct {
+	Operations []*etcdclient.DNSRecordOperation `json:"operations" validate:"required"` // 按顺序列出的操作
+	ApplyAt    *time.Time                       `json:"apply_at,omitempty"`             // 可选的生效时间，设置后批次保存为定时变更
+	Comment    string                           `json:"comment,omitempty"`              // 定时变更的说明
+}
+
+// DNSRecordBatchResponse 定义DNS记录批量操作响应结构
+type DNSRecordBatchResponse struct {
+	Success   bool                            `json:"success"`           // 是否全部应用
+	Code      ErrorCode                       `json:"code,omitempty"`    // 失败时的错误码
+	Results   []*etcdclient.DNSRecordOpResult `json:"results"`           // 每一项操作的结果
+	Message   string                          `json:"message,omitempty"` // 可选消息
+	Detail    string                          `json:"detail,omitempty"`  // 失败时的附加说明
+	Timestamp string                          `json:"timestamp"`         // 时间戳
+}
+
+// batchDNSRecordsHandler 在单个事务中原子地应用一组DNS记录的创建、更新和删除
+// 任一项失败时整个批次都不生效，响应中列出每一项的结果
+func (h *EchoHandler) batchDNSRecordsHandler(c echo.Context) error {
+	req := new(DNSRecordBatchRequest)
+	if apiErr := bindRequest(c, req); apiErr != nil {
+		return respondError(c, apiErr)
+	}
+	if results, apiErr := validateDNSRecordOperations(req.Operations); apiErr != nil {
+		return h.respondBatch(c, results, apiErr)
+	}
+
+	if req.ApplyAt != nil {
+		return h.scheduleDNSChange(c, &etcdclient.ScheduledChange{
+			ApplyAt:    *req.ApplyAt,
+			Operations: req.Operations,
+			Comment:    req.Comment,
+		})
+	}
+
+	results, err := h.etcdClient.ApplyDNSRecordBatch(c.Request().Context(), req.Operations)
+	if err != nil {
+		h.log(c).Warn("批量DNS记录操作失败", zap.Int("operations", len(req.Operations)), zap.Error(err))
+		return h.respondBatch(c, results, storageError(fmt.Errorf("批量DNS记录操作失败: %w", err)))
+	}
+
+	h.log(c).Info("批量DNS记录操作成功", zap.Int("operations", len(req.Operations)))
+	return h.respondBatch(c, results, nil)
+}
+
+// validateDNSRecordOperations 在本地校验批量操作中的所有记录，避免无效数据进入事务
+// 校验失败时返回每一项的结果和第一个错误
+func validateDNSRecordOperations(ops []*etcdclient.DNSRecordOperation) ([]*etcdclient.DNSRecordOpResult, *APIError) {
+	results := make([]*etcdclient.DNSRecordOpResult, len(ops))
+	var invalid *APIError
+	for i, op := range ops {
+		if op == nil {
+			op = &etcdclient.DNSRecordOperation{}
+			ops[i] = op
+		}
+		op.Domain = strings.TrimSuffix(op.Domain, ".")
+		op.Type = strings.ToUpper(op.Type)
+
+		results[i] = &etcdclient.DNSRecordOpResult{Index: i, Op: op.Op, Domain: op.Domain, Type: op.Type, View: op.View}
+		if op.Op != etcdclient.DNSRecordOpPut || op.Record == nil {
+			continue
+		}
+		if apiErr := validateDNSRecord(op.Record); apiErr != nil {
+			results[i].Type = op.Record.Type
+			results[i].Status = etcdclient.BatchStatusInvalid
+			results[i].Error = apiErr.Detail
+			if invalid == nil {
+				invalid = apiErr
+			}
+		}
+	}
+	if invalid == nil {
+		return results, nil
+	}
+
+	for _, result := range results {
+		if result.Status == "" {
+			result.Status = etcdclient.BatchStatusAborted
+		}
+	}
+	return results, invalid
+}
+
+// respondBatch 返回批量操作结果，apiErr不为空时表示整个批次未应用
+func (h *EchoHandler) respondBatch(c echo.Context, results []*etcdclient.DNSRecordOpResult, apiErr *APIError) error {
+	if apiErr == nil {
+		return c.JSON(http.StatusOK, &DNSRecordBatchResponse{
+			Success:   true,
+			Results:   results,
+			Message:   fmt.Sprintf("已应用%d项DNS记录操作", len(results)),
+			Timestamp: time.Now().Format(time.RFC3339),
+		})
+	}
+
+	return c.JSON(apiErr.Status, &DNSRecordBatchResponse{
+		Success:   false,
+		Code:      apiErr.Code,
+		Results:   results,
+		Message:   localizedMessage(apiErr.Code, requestLanguage(c)),
+		Detail:    apiErr.Detail,
+		Timestamp: time.Now().Format(time.RFC3339),
+	})
+}
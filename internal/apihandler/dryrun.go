@@ -0,0 +1,55 @@
+package apihandler
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/hewenyu/kong-discovery/internal/etcdclient"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// DryRunResponse 定义破坏性操作的dry-run响应结构
+type DryRunResponse struct {
+	Success   bool                   `json:"success"`           // 是否成功
+	DryRun    bool                   `json:"dry_run"`           // 总是true，表示没有做任何修改
+	Plan      *etcdclient.ChangePlan `json:"plan"`              // 实际执行时的影响范围
+	Message   string                 `json:"message,omitempty"` // 可选消息
+	Timestamp string                 `json:"timestamp"`         // 时间戳
+}
+
+// isDryRun 判断请求是否只报告影响范围而不执行
+func isDryRun(c echo.Context) bool {
+	return c.QueryParam("dry_run") == "true"
+}
+
+// respondPlan 返回dry-run的影响范围
+func respondPlan(c echo.Context, plan *etcdclient.ChangePlan) error {
+	return c.JSON(http.StatusOK, &DryRunResponse{
+		Success:   true,
+		DryRun:    true,
+		Plan:      plan,
+		Message:   "dry-run，未做任何修改",
+		Timestamp: time.Now().Format(time.RFC3339),
+	})
+}
+
+// planDeregisterHandler 报告注销或强制驱逐实例的影响范围，强制驱逐不存在的实例时与实际执行一样返回404
+func (h *EchoHandler) planDeregisterHandler(c echo.Context, force bool) error {
+	serviceName := c.Param("serviceName")
+	instanceID := c.Param("instanceId")
+	if serviceName == "" || instanceID == "" {
+		return respondError(c, badRequest(CodeInvalidParameter, "服务名和实例ID都是必需的"))
+	}
+
+	plan, err := h.etcdClient.PlanDeregister(c.Request().Context(), serviceName, instanceID)
+	if err != nil {
+		h.log(c).Error("获取注销的影响范围失败", zap.String("service", serviceName), zap.String("id", instanceID), zap.Error(err))
+		return respondError(c, storageError(err))
+	}
+	if force && len(plan.Instances) == 0 {
+		return respondError(c, storageError(fmt.Errorf("%w: %s/%s", etcdclient.ErrInstanceNotFound, serviceName, instanceID)))
+	}
+	return respondPlan(c, plan)
+}
@@ -0,0 +1,239 @@
+package apihandler
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// 列表端点支持的响应格式
+const (
+	MIMEApplicationMsgpack  = "application/msgpack"
+	MIMEApplicationProtobuf = "application/x-protobuf"
+)
+
+// protobufContentType 响应体是JSON数据模型对应的google.protobuf.Value消息
+const protobufContentType = MIMEApplicationProtobuf + "; proto=google.protobuf.Value"
+
+// bufferedResponse 缓存处理函数写出的响应，由listResponse统一编码后再写给客户端
+type bufferedResponse struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+// Header 实现http.ResponseWriter
+func (b *bufferedResponse) Header() http.Header {
+	return b.header
+}
+
+// Write 实现http.ResponseWriter
+func (b *bufferedResponse) Write(p []byte) (int, error) {
+	return b.body.Write(p)
+}
+
+// WriteHeader 实现http.ResponseWriter
+func (b *bufferedResponse) WriteHeader(status int) {
+	b.status = status
+}
+
+// listResponse 列表端点的响应编码中间件：
+// fields参数（如 ?fields=instance_id,ip_address）只保留响应中列表项的指定字段；
+// Accept请求头按出现的顺序选择第一个支持的格式：JSON（默认）、MessagePack或protobuf；
+// 开启api.management.compression且客户端支持时，超过min_length的响应使用gzip压缩
+func (h *EchoHandler) listResponse(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		res := c.Response()
+		writer := res.Writer
+		buffered := &bufferedResponse{header: writer.Header(), status: http.StatusOK}
+		res.Writer = buffered
+		err := next(c)
+		res.Writer = writer
+		if !res.Committed {
+			// 处理函数没有写出响应，由错误处理函数正常写出
+			return err
+		}
+
+		header := writer.Header()
+		header.Add(echo.HeaderVary, "Accept")
+		body := buffered.body.Bytes()
+		fields := parseFields(c.QueryParam("fields"))
+		format := negotiateListFormat(c.Request().Header.Get(echo.HeaderAccept))
+		isJSON := strings.HasPrefix(header.Get(echo.HeaderContentType), echo.MIMEApplicationJSON)
+		if buffered.status < http.StatusMultipleChoices && isJSON && (fields != nil || format != echo.MIMEApplicationJSON) {
+			encoded, contentType, encodeErr := encodeListBody(body, fields, format)
+			if encodeErr != nil {
+				h.log(c).Warn("编码列表响应失败，返回原始JSON", zap.Error(encodeErr))
+			} else {
+				body = encoded
+				header.Set(echo.HeaderContentType, contentType)
+			}
+		}
+
+		if compression := h.cfg.API.Management.Compression; compression.Enabled {
+			header.Add(echo.HeaderVary, echo.HeaderAcceptEncoding)
+			if len(body) >= compression.MinLength && acceptsGzip(c.Request().Header.Get(echo.HeaderAcceptEncoding)) {
+				var compressed bytes.Buffer
+				zw, gzErr := gzip.NewWriterLevel(&compressed, compression.Level)
+				if gzErr == nil {
+					_, _ = zw.Write(body)
+					gzErr = zw.Close()
+				}
+				if gzErr != nil {
+					h.log(c).Warn("压缩列表响应失败，返回未压缩的响应", zap.Error(gzErr))
+				} else {
+					body = compressed.Bytes()
+					header.Set(echo.HeaderContentEncoding, "gzip")
+				}
+			}
+		}
+
+		header.Del(echo.HeaderContentLength)
+		writer.WriteHeader(buffered.status)
+		n, writeErr := writer.Write(body)
+		res.Size = int64(n)
+		if err != nil {
+			return err
+		}
+		return writeErr
+	}
+}
+
+// negotiateListFormat 根据Accept请求头选择响应格式，没有支持的格式时使用JSON
+func negotiateListFormat(accept string) string {
+	for _, part := range strings.Split(accept, ",") {
+		mediaType := strings.ToLower(strings.TrimSpace(strings.SplitN(part, ";", 2)[0]))
+		switch mediaType {
+		case MIMEApplicationMsgpack, "application/x-msgpack", "application/vnd.msgpack":
+			return MIMEApplicationMsgpack
+		case MIMEApplicationProtobuf, "application/protobuf":
+			return MIMEApplicationProtobuf
+		case echo.MIMEApplicationJSON, "application/*", "*/*":
+			return echo.MIMEApplicationJSON
+		}
+	}
+	return echo.MIMEApplicationJSON
+}
+
+// acceptsGzip 判断Accept-Encoding是否接受gzip，q=0表示明确拒绝
+func acceptsGzip(acceptEncoding string) bool {
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		coding = strings.ToLower(strings.TrimSpace(coding))
+		if coding != "gzip" && coding != "*" {
+			continue
+		}
+		if q, ok := strings.CutPrefix(strings.ReplaceAll(params, " ", ""), "q="); ok {
+			if weight, err := strconv.ParseFloat(q, 64); err == nil && weight == 0 {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// parseFields 解析逗号分隔的字段列表，为空时返回nil表示返回所有字段
+func parseFields(param string) map[string]bool {
+	var fields map[string]bool
+	for _, field := range strings.Split(param, ",") {
+		if field = strings.TrimSpace(field); field != "" {
+			if fields == nil {
+				fields = make(map[string]bool)
+			}
+			fields[field] = true
+		}
+	}
+	return fields
+}
+
+// encodeListBody 按字段选择和响应格式重新编码JSON响应体，返回编码后的响应体和Content-Type
+func encodeListBody(body []byte, fields map[string]bool, format string) ([]byte, string, error) {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, "", err
+	}
+	if fields != nil {
+		v = selectFields(v, fields)
+	}
+
+	switch format {
+	case MIMEApplicationMsgpack:
+		var buf bytes.Buffer
+		if err := encodeMsgpack(&buf, v); err != nil {
+			return nil, "", err
+		}
+		return buf.Bytes(), MIMEApplicationMsgpack, nil
+	case MIMEApplicationProtobuf:
+		value, err := structpb.NewValue(protoCompatible(v))
+		if err != nil {
+			return nil, "", err
+		}
+		data, err := proto.Marshal(value)
+		if err != nil {
+			return nil, "", err
+		}
+		return data, protobufContentType, nil
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return nil, "", err
+		}
+		return append(data, '\n'), echo.MIMEApplicationJSON, nil
+	}
+}
+
+// selectFields 只保留列表项中的指定字段：响应本身是数组时作用于数组的元素，
+// 是对象时作用于对象中各个数组字段的元素，响应外层的success、timestamp等字段保持不变
+func selectFields(v interface{}, fields map[string]bool) interface{} {
+	switch v := v.(type) {
+	case []interface{}:
+		for _, item := range v {
+			if obj, ok := item.(map[string]interface{}); ok {
+				for key := range obj {
+					if !fields[key] {
+						delete(obj, key)
+					}
+				}
+			}
+		}
+	case map[string]interface{}:
+		for key, value := range v {
+			if list, ok := value.([]interface{}); ok {
+				v[key] = selectFields(list, fields)
+			}
+		}
+	}
+	return v
+}
+
+// protoCompatible 把json.Number转换为structpb支持的数字类型
+func protoCompatible(v interface{}) interface{} {
+	switch v := v.(type) {
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n
+		}
+		f, _ := v.Float64()
+		return f
+	case []interface{}:
+		for i, item := range v {
+			v[i] = protoCompatible(item)
+		}
+	case map[string]interface{}:
+		for key, value := range v {
+			v[key] = protoCompatible(value)
+		}
+	}
+	return v
+}
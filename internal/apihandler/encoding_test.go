@@ -0,0 +1,72 @@
+package apihandler
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncodeMsgpack(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []byte
+	}{
+		{"对象", `{"b":true,"a":1}`, []byte{0x82, 0xa1, 'a', 0x01, 0xa1, 'b', 0xc3}},
+		{"数组", `[null,false,-1,"x"]`, []byte{0x94, 0xc0, 0xc2, 0xff, 0xa1, 'x'}},
+		{"整数", `[200,-100,70000]`, []byte{0x93, 0xcc, 0xc8, 0xd0, 0x9c, 0xce, 0x00, 0x01, 0x11, 0x70}},
+		{"浮点数", `1.5`, []byte{0xcb, 0x3f, 0xf8, 0, 0, 0, 0, 0, 0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dec := json.NewDecoder(bytes.NewReader([]byte(tt.input)))
+			dec.UseNumber()
+			var v interface{}
+			require.NoError(t, dec.Decode(&v))
+
+			var buf bytes.Buffer
+			require.NoError(t, encodeMsgpack(&buf, v))
+			assert.Equal(t, tt.want, buf.Bytes())
+		})
+	}
+
+	// 长字符串使用str8格式
+	var buf bytes.Buffer
+	require.NoError(t, encodeMsgpack(&buf, string(bytes.Repeat([]byte("a"), 40))))
+	assert.Equal(t, []byte{0xd9, 40}, buf.Bytes()[:2])
+}
+
+func TestNegotiateListFormat(t *testing.T) {
+	assert.Equal(t, echo.MIMEApplicationJSON, negotiateListFormat(""))
+	assert.Equal(t, echo.MIMEApplicationJSON, negotiateListFormat("text/html, */*"))
+	assert.Equal(t, MIMEApplicationMsgpack, negotiateListFormat("application/x-msgpack"))
+	assert.Equal(t, MIMEApplicationProtobuf, negotiateListFormat("text/html, application/x-protobuf;q=0.9"))
+	assert.Equal(t, echo.MIMEApplicationJSON, negotiateListFormat("application/json, application/msgpack"))
+}
+
+func TestAcceptsGzip(t *testing.T) {
+	assert.False(t, acceptsGzip(""))
+	assert.False(t, acceptsGzip("br, deflate"))
+	assert.True(t, acceptsGzip("gzip, deflate"))
+	assert.True(t, acceptsGzip("br;q=1.0, gzip;q=0.5"))
+	assert.True(t, acceptsGzip("*"))
+	assert.False(t, acceptsGzip("gzip;q=0"))
+	assert.False(t, acceptsGzip("gzip; q=0.0"))
+}
+
+func TestSelectFields(t *testing.T) {
+	var v interface{}
+	require.NoError(t, json.Unmarshal([]byte(`{"success":true,"instances":[{"instance_id":"a","ip_address":"10.0.0.1","port":80}],"timestamp":"now"}`), &v))
+
+	selected := selectFields(v, parseFields(" instance_id, ,ip_address"))
+	data, err := json.Marshal(selected)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"success":true,"instances":[{"instance_id":"a","ip_address":"10.0.0.1"}],"timestamp":"now"}`, string(data))
+
+	assert.Nil(t, parseFields(""))
+}
@@ -0,0 +1,271 @@
+package apihandler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/hewenyu/kong-discovery/internal/etcdclient"
+	"github.com/labstack/echo/v4"
+)
+
+// ErrorCode 机器可读的错误码，客户端应依据错误码而不是提示信息进行判断
+type ErrorCode string
+
+// 两个HTTP API共用的错误码
+const (
+	CodeInvalidRequest          ErrorCode = "INVALID_REQUEST"            // 请求体格式错误
+	CodeInvalidParameter        ErrorCode = "INVALID_PARAMETER"          // 请求参数无效
+	CodeInvalidTTL              ErrorCode = "INVALID_TTL"                // TTL无效
+	CodeInvalidHealthStatus     ErrorCode = "INVALID_HEALTH_STATUS"      // 未知的健康状态
+	CodeInvalidHealthTransition ErrorCode = "INVALID_HEALTH_TRANSITION"  // 不允许的健康状态转换
+	CodeInvalidNamespace        ErrorCode = "INVALID_NAMESPACE"          // 命名空间名称无效
+	CodeInvalidMetadata         ErrorCode = "INVALID_METADATA"           // 实例元数据超过限制、使用保留前缀或类型不符
+	CodeServiceNotFound         ErrorCode = "SERVICE_NOT_FOUND"          // 服务不存在
+	CodeInstanceNotFound        ErrorCode = "INSTANCE_NOT_FOUND"         // 服务实例不存在
+	CodeNamespaceMissing        ErrorCode = "NAMESPACE_MISSING"          // 命名空间不存在
+	CodeNamespaceExists         ErrorCode = "NAMESPACE_EXISTS"           // 命名空间已存在
+	CodeNamespaceNotEmpty       ErrorCode = "NAMESPACE_NOT_EMPTY"        // 命名空间不为空
+	CodeNamespaceProtected      ErrorCode = "NAMESPACE_PROTECTED"        // 默认命名空间和系统命名空间不可删除
+	CodeNamespaceMismatch       ErrorCode = "NAMESPACE_MISMATCH"         // 实例已在其他命名空间注册
+	CodeReservedName            ErrorCode = "RESERVED_NAME"              // 服务名称或命名空间是保留的名称
+	CodeQuotaExceeded           ErrorCode = "QUOTA_EXCEEDED"             // 配额已用尽
+	CodeQuotaForbidden          ErrorCode = "QUOTA_FORBIDDEN"            // 配额禁止创建该类资源
+	CodeDNSRecordNotFound       ErrorCode = "DNS_RECORD_NOT_FOUND"       // DNS记录不存在
+	CodeRevisionConflict        ErrorCode = "REVISION_CONFLICT"          // 记录已被修改，版本不一致
+	CodeRecordOwned             ErrorCode = "RECORD_OWNED"               // 记录由服务自动维护，手动修改需要强制执行
+	CodeBatchTooLarge           ErrorCode = "BATCH_TOO_LARGE"            // 批量操作数超过事务上限
+	CodeScheduledChangeNotFound ErrorCode = "SCHEDULED_CHANGE_NOT_FOUND" // 定时变更不存在
+	CodeDuplicateAddress        ErrorCode = "DUPLICATE_ADDRESS"          // 其他实例已使用相同的地址
+	CodeInstanceEvicted         ErrorCode = "INSTANCE_EVICTED"           // 实例在强制驱逐的冷却期内
+	CodeWebhookNotFound         ErrorCode = "WEBHOOK_NOT_FOUND"          // webhook不存在
+	CodeZoneNotFound            ErrorCode = "ZONE_NOT_FOUND"             // 区域不存在
+	CodeZoneExists              ErrorCode = "ZONE_EXISTS"                // 区域已被其他命名空间使用
+	CodeInvalidBackup           ErrorCode = "INVALID_BACKUP"             // 备份归档无效
+	CodeFederationDisabled      ErrorCode = "FEDERATION_DISABLED"        // 未启用多集群联邦
+	CodeClusterNotFound         ErrorCode = "CLUSTER_NOT_FOUND"          // 远程集群不存在
+	CodeFederationConflict      ErrorCode = "FEDERATION_CONFLICT"        // 目录与远程集群的配置冲突
+	CodeStaleCatalog            ErrorCode = "STALE_CATALOG"              // 目录早于已同步的版本
+	CodeUnauthorized            ErrorCode = "UNAUTHORIZED"               // 令牌无效
+	CodeSDKVersionUnsupported   ErrorCode = "SDK_VERSION_UNSUPPORTED"    // SDK版本低于服务端要求的最低版本
+	CodeDNSServerUnavailable    ErrorCode = "DNS_SERVER_UNAVAILABLE"     // DNS服务器未启动
+	CodeUpstreamNotFound        ErrorCode = "UPSTREAM_NOT_FOUND"         // 上游DNS服务器不存在
+	CodeRouteNotFound           ErrorCode = "ROUTE_NOT_FOUND"            // 请求的路径不存在
+	CodeMethodNotAllowed        ErrorCode = "METHOD_NOT_ALLOWED"         // 请求方法不被允许
+	CodeStorageError            ErrorCode = "STORAGE_ERROR"              // 存储操作失败
+	CodeStorageOffline          ErrorCode = "STORAGE_OFFLINE"            // 存储不可用，只能读取本地快照
+	CodeInternal                ErrorCode = "INTERNAL_ERROR"             // 内部错误
+)
+
+// 支持的提示信息语言
+const (
+	langZH = "zh"
+	langEN = "en"
+)
+
+// errorMessages 各错误码的多语言提示信息，未配置的语言回退到中文
+var errorMessages = map[ErrorCode]map[string]string{
+	CodeInvalidRequest:          {langZH: "请求格式错误", langEN: "malformed request body"},
+	CodeInvalidParameter:        {langZH: "请求参数无效", langEN: "invalid request parameter"},
+	CodeInvalidTTL:              {langZH: "TTL无效", langEN: "invalid TTL"},
+	CodeInvalidHealthStatus:     {langZH: "未知的健康状态", langEN: "unknown health status"},
+	CodeInvalidHealthTransition: {langZH: "不允许的健康状态转换", langEN: "health status transition not allowed"},
+	CodeInvalidNamespace:        {langZH: "命名空间名称无效", langEN: "invalid namespace name"},
+	CodeInvalidMetadata:         {langZH: "实例元数据无效", langEN: "invalid instance metadata"},
+	CodeServiceNotFound:         {langZH: "服务不存在", langEN: "service not found"},
+	CodeInstanceNotFound:        {langZH: "服务实例不存在", langEN: "service instance not found"},
+	CodeNamespaceMissing:        {langZH: "命名空间不存在", langEN: "namespace does not exist"},
+	CodeNamespaceExists:         {langZH: "命名空间已存在", langEN: "namespace already exists"},
+	CodeNamespaceNotEmpty:       {langZH: "命名空间不为空", langEN: "namespace is not empty"},
+	CodeNamespaceProtected:      {langZH: "保留的命名空间不能删除", langEN: "reserved namespaces cannot be deleted"},
+	CodeNamespaceMismatch:       {langZH: "实例已在其他命名空间注册，不能改变实例的命名空间", langEN: "the instance is registered in another namespace and cannot change namespaces"},
+	CodeReservedName:            {langZH: "服务名称或命名空间是保留的名称", langEN: "the service name or namespace is reserved"},
+	CodeQuotaExceeded:           {langZH: "命名空间配额已用尽", langEN: "namespace quota exceeded"},
+	CodeQuotaForbidden:          {langZH: "命名空间禁止创建该类资源", langEN: "resource type forbidden by namespace quota"},
+	CodeDNSRecordNotFound:       {langZH: "DNS记录不存在", langEN: "DNS record not found"},
+	CodeRevisionConflict:        {langZH: "记录已被修改，请重新获取后再更新", langEN: "record was modified concurrently, fetch it again before updating"},
+	CodeRecordOwned:             {langZH: "记录由服务自动维护，需要使用force参数强制修改", langEN: "the record is managed by its owner, use force to modify it"},
+	CodeBatchTooLarge:           {langZH: "批量操作数超过事务上限", langEN: "too many operations in one batch"},
+	CodeScheduledChangeNotFound: {langZH: "定时变更不存在", langEN: "scheduled change not found"},
+	CodeDuplicateAddress:        {langZH: "服务中已有其他实例使用相同的地址", langEN: "another instance of the service is registered with the same address"},
+	CodeInstanceEvicted:         {langZH: "实例已被强制驱逐，冷却期内不能重新注册", langEN: "the instance was evicted and cannot re-register until the cooldown ends"},
+	CodeWebhookNotFound:         {langZH: "webhook不存在", langEN: "webhook not found"},
+	CodeZoneNotFound:            {langZH: "区域不存在", langEN: "zone not found"},
+	CodeZoneExists:              {langZH: "区域已被其他命名空间使用", langEN: "zone is owned by another namespace"},
+	CodeInvalidBackup:           {langZH: "备份归档无效", langEN: "invalid backup archive"},
+	CodeFederationDisabled:      {langZH: "未启用多集群联邦", langEN: "federation is not enabled"},
+	CodeClusterNotFound:         {langZH: "远程集群不存在", langEN: "federated cluster not found"},
+	CodeFederationConflict:      {langZH: "目录与远程集群的配置冲突", langEN: "catalog conflicts with the federation configuration"},
+	CodeStaleCatalog:            {langZH: "目录早于已同步的版本", langEN: "catalog is older than the synced one"},
+	CodeUnauthorized:            {langZH: "令牌无效", langEN: "invalid token"},
+	CodeSDKVersionUnsupported:   {langZH: "SDK版本过低，请升级SDK", langEN: "SDK version is no longer supported, please upgrade the SDK"},
+	CodeDNSServerUnavailable:    {langZH: "DNS服务器未启动", langEN: "DNS server is not running"},
+	CodeUpstreamNotFound:        {langZH: "上游DNS服务器不存在", langEN: "upstream DNS server not found"},
+	CodeRouteNotFound:           {langZH: "请求的路径不存在", langEN: "route not found"},
+	CodeMethodNotAllowed:        {langZH: "请求方法不被允许", langEN: "method not allowed"},
+	CodeStorageError:            {langZH: "存储操作失败", langEN: "storage operation failed"},
+	CodeStorageOffline:          {langZH: "存储暂时不可用，当前只能读取", langEN: "storage is unavailable, serving read-only data from the local snapshot"},
+	CodeInternal:                {langZH: "内部错误", langEN: "internal error"},
+}
+
+// APIError 带错误码和HTTP状态码的API错误
+type APIError struct {
+	Status int              // HTTP状态码
+	Code   ErrorCode        // 机器可读的错误码
+	Detail string           // 附加说明，如出错的参数或底层错误
+	Fields ValidationErrors // 请求体校验未通过的字段
+}
+
+// Error 实现error接口
+func (e *APIError) Error() string {
+	if e.Detail == "" {
+		return string(e.Code)
+	}
+	return fmt.Sprintf("%s: %s", e.Code, e.Detail)
+}
+
+// newAPIError 创建API错误
+func newAPIError(status int, code ErrorCode, detail string) *APIError {
+	return &APIError{Status: status, Code: code, Detail: detail}
+}
+
+// badRequest 创建400错误
+func badRequest(code ErrorCode, detail string) *APIError {
+	return newAPIError(http.StatusBadRequest, code, detail)
+}
+
+// storageError 将etcdclient返回的错误映射为API错误，未知错误视为存储故障
+func storageError(err error) *APIError {
+	switch {
+	case errors.Is(err, etcdclient.ErrInstanceNotFound):
+		return newAPIError(http.StatusNotFound, CodeInstanceNotFound, err.Error())
+	case errors.Is(err, etcdclient.ErrInvalidHealthTransition):
+		return newAPIError(http.StatusConflict, CodeInvalidHealthTransition, err.Error())
+	case errors.Is(err, etcdclient.ErrNamespaceNotFound):
+		return newAPIError(http.StatusNotFound, CodeNamespaceMissing, err.Error())
+	case errors.Is(err, etcdclient.ErrNamespaceExists):
+		return newAPIError(http.StatusConflict, CodeNamespaceExists, err.Error())
+	case errors.Is(err, etcdclient.ErrNamespaceNotEmpty):
+		return newAPIError(http.StatusConflict, CodeNamespaceNotEmpty, err.Error())
+	case errors.Is(err, etcdclient.ErrNamespaceMismatch):
+		return newAPIError(http.StatusConflict, CodeNamespaceMismatch, err.Error())
+	case errors.Is(err, etcdclient.ErrQuotaExceeded):
+		return newAPIError(http.StatusTooManyRequests, CodeQuotaExceeded, err.Error())
+	case errors.Is(err, etcdclient.ErrQuotaForbidden):
+		return newAPIError(http.StatusForbidden, CodeQuotaForbidden, err.Error())
+	case errors.Is(err, etcdclient.ErrDNSRecordNotFound):
+		return newAPIError(http.StatusNotFound, CodeDNSRecordNotFound, err.Error())
+	case errors.Is(err, etcdclient.ErrRevisionConflict):
+		return newAPIError(http.StatusConflict, CodeRevisionConflict, err.Error())
+	case errors.Is(err, etcdclient.ErrRecordOwned):
+		return newAPIError(http.StatusConflict, CodeRecordOwned, err.Error())
+	case errors.Is(err, etcdclient.ErrInvalidMetadata):
+		return newAPIError(http.StatusBadRequest, CodeInvalidMetadata, err.Error())
+	case errors.Is(err, etcdclient.ErrInvalidBatch), errors.Is(err, etcdclient.ErrInvalidAlias), errors.Is(err, etcdclient.ErrInvalidPorts):
+		return newAPIError(http.StatusBadRequest, CodeInvalidParameter, err.Error())
+	case errors.Is(err, etcdclient.ErrBatchTooLarge):
+		return newAPIError(http.StatusRequestEntityTooLarge, CodeBatchTooLarge, err.Error())
+	case errors.Is(err, etcdclient.ErrScheduledChangeNotFound):
+		return newAPIError(http.StatusNotFound, CodeScheduledChangeNotFound, err.Error())
+	case errors.Is(err, etcdclient.ErrDuplicateAddress):
+		return newAPIError(http.StatusConflict, CodeDuplicateAddress, err.Error())
+	case errors.Is(err, etcdclient.ErrReservedName):
+		return newAPIError(http.StatusForbidden, CodeReservedName, err.Error())
+	case errors.Is(err, etcdclient.ErrInstanceEvicted):
+		return newAPIError(http.StatusConflict, CodeInstanceEvicted, err.Error())
+	case errors.Is(err, etcdclient.ErrWebhookNotFound):
+		return newAPIError(http.StatusNotFound, CodeWebhookNotFound, err.Error())
+	case errors.Is(err, etcdclient.ErrZoneNotFound):
+		return newAPIError(http.StatusNotFound, CodeZoneNotFound, err.Error())
+	case errors.Is(err, etcdclient.ErrZoneExists):
+		return newAPIError(http.StatusConflict, CodeZoneExists, err.Error())
+	case errors.Is(err, etcdclient.ErrInvalidBackup):
+		return newAPIError(http.StatusBadRequest, CodeInvalidBackup, err.Error())
+	case errors.Is(err, etcdclient.ErrStaleCatalog):
+		return newAPIError(http.StatusConflict, CodeStaleCatalog, err.Error())
+	case errors.Is(err, etcdclient.ErrOffline):
+		return newAPIError(http.StatusServiceUnavailable, CodeStorageOffline, err.Error())
+	default:
+		return newAPIError(http.StatusInternalServerError, CodeStorageError, err.Error())
+	}
+}
+
+// ErrorResponse 定义两个HTTP API共用的错误响应结构
+type ErrorResponse struct {
+	Success   bool         `json:"success"`              // 总是false
+	Code      ErrorCode    `json:"code"`                 // 机器可读的错误码
+	Message   string       `json:"message"`              // 按Accept-Language本地化的提示信息
+	Detail    string       `json:"detail,omitempty"`     // 附加说明
+	Fields    []FieldError `json:"fields,omitempty"`     // 请求体校验未通过的字段
+	RequestID string       `json:"request_id,omitempty"` // 请求ID，与服务端日志中的request_id对应
+	Timestamp string       `json:"timestamp"`            // 时间戳
+}
+
+// requestLanguage 根据Accept-Language选择提示信息语言，默认中文
+func requestLanguage(c echo.Context) string {
+	for _, tag := range strings.Split(c.Request().Header.Get("Accept-Language"), ",") {
+		tag = strings.ToLower(strings.TrimSpace(strings.SplitN(tag, ";", 2)[0]))
+		switch {
+		case strings.HasPrefix(tag, langZH):
+			return langZH
+		case strings.HasPrefix(tag, langEN):
+			return langEN
+		}
+	}
+	return langZH
+}
+
+// localizedMessage 返回错误码在指定语言下的提示信息
+func localizedMessage(code ErrorCode, lang string) string {
+	messages, ok := errorMessages[code]
+	if !ok {
+		return string(code)
+	}
+	if msg, ok := messages[lang]; ok {
+		return msg
+	}
+	return messages[langZH]
+}
+
+// respondError 以统一的错误结构返回API错误
+func respondError(c echo.Context, apiErr *APIError) error {
+	return c.JSON(apiErr.Status, &ErrorResponse{
+		Success:   false,
+		Code:      apiErr.Code,
+		Message:   localizedMessage(apiErr.Code, requestLanguage(c)),
+		Detail:    apiErr.Detail,
+		Fields:    apiErr.Fields,
+		RequestID: requestID(c),
+		Timestamp: time.Now().Format(time.RFC3339),
+	})
+}
+
+// httpErrorHandler 将echo框架产生的错误（路由不存在、方法不允许、panic等）转为统一的错误结构
+func httpErrorHandler(err error, c echo.Context) {
+	if c.Response().Committed {
+		return
+	}
+
+	var apiErr *APIError
+	var httpErr *echo.HTTPError
+	switch {
+	case errors.As(err, &apiErr):
+	case errors.As(err, &httpErr):
+		switch httpErr.Code {
+		case http.StatusNotFound:
+			apiErr = newAPIError(httpErr.Code, CodeRouteNotFound, c.Request().URL.Path)
+		case http.StatusMethodNotAllowed:
+			apiErr = newAPIError(httpErr.Code, CodeMethodNotAllowed, c.Request().Method)
+		case http.StatusBadRequest:
+			apiErr = badRequest(CodeInvalidRequest, fmt.Sprint(httpErr.Message))
+		default:
+			apiErr = newAPIError(httpErr.Code, CodeInternal, fmt.Sprint(httpErr.Message))
+		}
+	default:
+		apiErr = newAPIError(http.StatusInternalServerError, CodeInternal, err.Error())
+	}
+
+	_ = respondError(c, apiErr)
+}
@@ -0,0 +1,129 @@
+package apihandler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hewenyu/kong-discovery/internal/config"
+	"github.com/hewenyu/kong-discovery/internal/etcdclient"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStorageError_Mapping(t *testing.T) {
+	tests := []struct {
+		err    error
+		status int
+		code   ErrorCode
+	}{
+		{etcdclient.ErrInstanceNotFound, http.StatusNotFound, CodeInstanceNotFound},
+		{etcdclient.ErrInvalidHealthTransition, http.StatusConflict, CodeInvalidHealthTransition},
+		{etcdclient.ErrNamespaceNotFound, http.StatusNotFound, CodeNamespaceMissing},
+		{etcdclient.ErrNamespaceExists, http.StatusConflict, CodeNamespaceExists},
+		{etcdclient.ErrNamespaceNotEmpty, http.StatusConflict, CodeNamespaceNotEmpty},
+		{etcdclient.ErrNamespaceMismatch, http.StatusConflict, CodeNamespaceMismatch},
+		{etcdclient.ErrQuotaExceeded, http.StatusTooManyRequests, CodeQuotaExceeded},
+		{etcdclient.ErrQuotaForbidden, http.StatusForbidden, CodeQuotaForbidden},
+		{etcdclient.ErrDNSRecordNotFound, http.StatusNotFound, CodeDNSRecordNotFound},
+		{etcdclient.ErrRevisionConflict, http.StatusConflict, CodeRevisionConflict},
+		{etcdclient.ErrInvalidBatch, http.StatusBadRequest, CodeInvalidParameter},
+		{etcdclient.ErrInvalidAlias, http.StatusBadRequest, CodeInvalidParameter},
+		{etcdclient.ErrBatchTooLarge, http.StatusRequestEntityTooLarge, CodeBatchTooLarge},
+		{etcdclient.ErrScheduledChangeNotFound, http.StatusNotFound, CodeScheduledChangeNotFound},
+		{etcdclient.ErrZoneNotFound, http.StatusNotFound, CodeZoneNotFound},
+		{etcdclient.ErrZoneExists, http.StatusConflict, CodeZoneExists},
+		{fmt.Errorf("连接超时"), http.StatusInternalServerError, CodeStorageError},
+	}
+
+	for _, tt := range tests {
+		apiErr := storageError(fmt.Errorf("操作失败: %w", tt.err))
+		assert.Equal(t, tt.status, apiErr.Status, tt.err.Error())
+		assert.Equal(t, tt.code, apiErr.Code, tt.err.Error())
+		assert.Contains(t, apiErr.Detail, "操作失败")
+	}
+}
+
+func TestErrorMessages_Complete(t *testing.T) {
+	// 每个错误码都必须提供中英文提示
+	for code, messages := range errorMessages {
+		assert.NotEmpty(t, messages[langZH], code)
+		assert.NotEmpty(t, messages[langEN], code)
+	}
+}
+
+func TestRespondError_Localized(t *testing.T) {
+	cfg := &config.Config{}
+	e := echo.New()
+	handler := &EchoHandler{
+		managementServer: e,
+		cfg:              cfg,
+		logger:           createTestLogger(t),
+	}
+	handler.registerManagementRoutes()
+
+	tests := []struct {
+		acceptLanguage string
+		message        string
+	}{
+		{"", "DNS服务器未启动"},
+		{"en-US,en;q=0.9", "DNS server is not running"},
+		{"fr-FR, zh-CN;q=0.8", "DNS服务器未启动"},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/admin/dns/upstreams", nil)
+		req.Header.Set("Accept-Language", tt.acceptLanguage)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+
+		var response ErrorResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+		assert.False(t, response.Success)
+		assert.Equal(t, CodeDNSServerUnavailable, response.Code)
+		assert.Equal(t, tt.message, response.Message)
+	}
+}
+
+func TestHTTPErrorHandler(t *testing.T) {
+	cfg := &config.Config{}
+	e := echo.New()
+	handler := &EchoHandler{
+		registrationServer: e,
+		cfg:                cfg,
+		logger:             createTestLogger(t),
+	}
+	handler.registerRegistrationRoutes()
+
+	// 路由不存在
+	req := httptest.NewRequest(http.MethodGet, "/no-such-route", nil)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	var response ErrorResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	assert.Equal(t, CodeRouteNotFound, response.Code)
+	assert.Equal(t, "/no-such-route", response.Detail)
+
+	// 方法不允许
+	req = httptest.NewRequest(http.MethodPatch, "/services/register", nil)
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	assert.Equal(t, CodeMethodNotAllowed, response.Code)
+
+	// 参数校验错误
+	req = httptest.NewRequest(http.MethodPost, "/services/register", nil)
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	assert.Equal(t, CodeInvalidParameter, response.Code)
+}
@@ -0,0 +1,643 @@
+package apihandler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hewenyu/kong-discovery/internal/etcdclient"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// Eureka实例状态
+const (
+	eurekaStatusUp           = "UP"
+	eurekaStatusDown         = "DOWN"
+	eurekaStatusStarting     = "STARTING"
+	eurekaStatusOutOfService = "OUT_OF_SERVICE"
+	eurekaStatusUnknown      = "UNKNOWN"
+)
+
+// Eureka增量中实例的变更类型
+const (
+	eurekaActionAdded    = "ADDED"
+	eurekaActionModified = "MODIFIED"
+	eurekaActionDeleted  = "DELETED"
+)
+
+// 默认租约：客户端每30秒续约一次，90秒未续约的实例被剔除
+const (
+	eurekaRenewalInterval = 30
+	eurekaDefaultDuration = 90
+)
+
+// Eureka实例的附加信息保存在实例元数据中使用的键，返回给Eureka客户端时从元数据中去掉
+const (
+	eurekaMetaPrefix           = "eureka."
+	eurekaMetaInstanceID       = eurekaMetaPrefix + "instance_id"
+	eurekaMetaApp              = eurekaMetaPrefix + "app"
+	eurekaMetaHostName         = eurekaMetaPrefix + "host_name"
+	eurekaMetaStatus           = eurekaMetaPrefix + "status"
+	eurekaMetaSecurePort       = eurekaMetaPrefix + "secure_port"
+	eurekaMetaSecureOnly       = eurekaMetaPrefix + "secure_only"
+	eurekaMetaVIPAddress       = eurekaMetaPrefix + "vip_address"
+	eurekaMetaSecureVIPAddress = eurekaMetaPrefix + "secure_vip_address"
+	eurekaMetaHomePageURL      = eurekaMetaPrefix + "home_page_url"
+	eurekaMetaStatusPageURL    = eurekaMetaPrefix + "status_page_url"
+	eurekaMetaHealthCheckURL   = eurekaMetaPrefix + "health_check_url"
+	eurekaMetaDirtyTimestamp   = eurekaMetaPrefix + "last_dirty_timestamp"
+)
+
+// eurekaDataCenterClass 返回给客户端的数据中心类型
+const eurekaDataCenterClass = "com.netflix.appinfo.InstanceInfo$DefaultDataCenterInfo"
+
+// EurekaRegistration Eureka注册请求以及单个实例的响应
+type EurekaRegistration struct {
+	Instance *EurekaInstance `json:"instance" validate:"required"`
+}
+
+// EurekaInstance Eureka实例信息，字段与Eureka v2的JSON格式一致
+type EurekaInstance struct {
+	InstanceID                    string            `json:"instanceId"`
+	HostName                      string            `json:"hostName"`
+	App                           string            `json:"app"`
+	IPAddr                        string            `json:"ipAddr" validate:"required,ip"`
+	Status                        string            `json:"status" validate:"omitempty,oneof=UP DOWN STARTING OUT_OF_SERVICE UNKNOWN"`
+	OverriddenStatus              string            `json:"overriddenStatus"`
+	Port                          EurekaPort        `json:"port"`
+	SecurePort                    EurekaPort        `json:"securePort"`
+	CountryID                     int               `json:"countryId"`
+	DataCenterInfo                EurekaDataCenter  `json:"dataCenterInfo"`
+	LeaseInfo                     EurekaLeaseInfo   `json:"leaseInfo"`
+	Metadata                      map[string]string `json:"metadata"`
+	HomePageURL                   string            `json:"homePageUrl"`
+	StatusPageURL                 string            `json:"statusPageUrl"`
+	HealthCheckURL                string            `json:"healthCheckUrl"`
+	VIPAddress                    string            `json:"vipAddress"`
+	SecureVIPAddress              string            `json:"secureVipAddress"`
+	IsCoordinatingDiscoveryServer string            `json:"isCoordinatingDiscoveryServer"`
+	LastUpdatedTimestamp          string            `json:"lastUpdatedTimestamp"`
+	LastDirtyTimestamp            string            `json:"lastDirtyTimestamp"`
+	ActionType                    string            `json:"actionType,omitempty"`
+}
+
+// EurekaPort Eureka的端口，格式为 {"$": 8080, "@enabled": "true"}
+type EurekaPort struct {
+	Port    int    `json:"$"`
+	Enabled string `json:"@enabled"`
+}
+
+// UnmarshalJSON 解析端口，端口号和@enabled既可以是字符串也可以是数字或布尔值，不同语言的客户端写法不同
+func (p *EurekaPort) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Port    json.Number     `json:"$"`
+		Enabled json.RawMessage `json:"@enabled"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	if raw.Port != "" {
+		port, err := strconv.Atoi(raw.Port.String())
+		if err != nil {
+			return fmt.Errorf("无效的端口: %s", raw.Port)
+		}
+		p.Port = port
+	}
+	p.Enabled = strings.Trim(string(raw.Enabled), `"`)
+	return nil
+}
+
+// enabled 判断端口是否启用
+func (p EurekaPort) enabled() bool {
+	return p.Enabled == "true"
+}
+
+// EurekaDataCenter Eureka的数据中心信息
+type EurekaDataCenter struct {
+	Class string `json:"@class"`
+	Name  string `json:"name"`
+}
+
+// EurekaLeaseInfo Eureka的租约信息
+type EurekaLeaseInfo struct {
+	RenewalIntervalInSecs int   `json:"renewalIntervalInSecs"`
+	DurationInSecs        int   `json:"durationInSecs"`
+	RegistrationTimestamp int64 `json:"registrationTimestamp"`
+	LastRenewalTimestamp  int64 `json:"lastRenewalTimestamp"`
+	EvictionTimestamp     int64 `json:"evictionTimestamp"`
+	ServiceUpTimestamp    int64 `json:"serviceUpTimestamp"`
+}
+
+// EurekaApplication Eureka应用，即一个服务的所有实例
+type EurekaApplication struct {
+	Name     string            `json:"name"`
+	Instance []*EurekaInstance `json:"instance"`
+}
+
+// EurekaApplications 全量或增量获取返回的注册表
+type EurekaApplications struct {
+	VersionsDelta string               `json:"versions__delta"`
+	AppsHashcode  string               `json:"apps__hashcode"`
+	Application   []*EurekaApplication `json:"application"`
+}
+
+// registerEurekaRoutes 在服务注册API上注册Eureka兼容端点，/eureka 和 /eureka/v2 两种前缀都可以使用
+func (h *EchoHandler) registerEurekaRoutes() {
+	retention := 180 * time.Second
+	if h.cfg != nil && h.cfg.API.Eureka.DeltaRetention > 0 {
+		retention = time.Duration(h.cfg.API.Eureka.DeltaRetention) * time.Second
+	}
+	h.eurekaDeltas = newEurekaDeltas(h.etcdClient, h.logger, retention)
+	h.eurekaDeltas.ensureWatching()
+
+	for _, prefix := range []string{"/eureka", "/eureka/v2"} {
+		g := h.registrationServer.Group(prefix)
+		g.GET("/apps", h.eurekaAppsHandler)
+		g.GET("/apps/", h.eurekaAppsHandler)
+		g.GET("/apps/delta", h.eurekaDeltaHandler)
+		g.GET("/apps/:app", h.eurekaAppHandler)
+		g.POST("/apps/:app", h.eurekaRegisterHandler)
+		g.GET("/apps/:app/:id", h.eurekaInstanceHandler)
+		g.PUT("/apps/:app/:id", h.eurekaHeartbeatHandler)
+		g.DELETE("/apps/:app/:id", h.eurekaCancelHandler)
+		g.PUT("/apps/:app/:id/status", h.eurekaStatusHandler)
+		g.DELETE("/apps/:app/:id/status", h.eurekaDeleteStatusHandler)
+	}
+}
+
+// eurekaError 以纯文本返回错误
+func eurekaError(c echo.Context, apiErr *APIError) error {
+	return c.String(apiErr.Status, apiErr.Detail)
+}
+
+// eurekaServiceName 把Eureka应用名转为服务名称，Eureka应用名不区分大小写，服务名称使用小写
+func eurekaServiceName(app string) string {
+	return strings.ToLower(strings.TrimSpace(app))
+}
+
+// eurekaInstanceID 把Eureka实例ID转为服务实例ID
+// Spring Cloud默认的实例ID形如 host:app:8080，其中的冒号和点号不能出现在DNS标签中，替换为连字符，原始ID保存在元数据中
+func eurekaInstanceID(id string) string {
+	var b strings.Builder
+	for _, r := range id {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' {
+			b.WriteRune(r)
+		} else {
+			b.WriteRune('-')
+		}
+	}
+	return b.String()
+}
+
+// eurekaServiceInstance 把Eureka实例转为服务实例
+func eurekaServiceInstance(app string, req *EurekaInstance) (*etcdclient.ServiceInstance, error) {
+	id := req.InstanceID
+	if id == "" {
+		id = req.HostName
+	}
+	if id == "" {
+		return nil, fmt.Errorf("实例ID和主机名不能同时为空")
+	}
+
+	instance := &etcdclient.ServiceInstance{
+		ServiceName: eurekaServiceName(app),
+		InstanceID:  eurekaInstanceID(id),
+		IPAddress:   req.IPAddr,
+		Port:        req.Port.Port,
+		TTL:         req.LeaseInfo.DurationInSecs,
+		Metadata:    make(map[string]string, len(req.Metadata)+12),
+	}
+	if instance.TTL <= 0 {
+		instance.TTL = eurekaDefaultDuration
+	}
+
+	for k, v := range eurekaClientMetadata(req.Metadata) {
+		instance.Metadata[k] = v
+	}
+	if req.SecurePort.enabled() {
+		instance.Metadata[eurekaMetaSecurePort] = strconv.Itoa(req.SecurePort.Port)
+		if !req.Port.enabled() && req.Port.Enabled != "" {
+			instance.Port = req.SecurePort.Port
+			instance.Metadata[eurekaMetaSecureOnly] = "true"
+		}
+	}
+	if instance.Port < 1 || instance.Port > 65535 {
+		return nil, fmt.Errorf("无效的端口: %d", instance.Port)
+	}
+
+	status := req.Status
+	if status == "" {
+		status = eurekaStatusUp
+	}
+	instance.Health = eurekaHealth(status)
+
+	for key, value := range map[string]string{
+		eurekaMetaInstanceID:       id,
+		eurekaMetaApp:              strings.ToUpper(app),
+		eurekaMetaHostName:         req.HostName,
+		eurekaMetaStatus:           status,
+		eurekaMetaVIPAddress:       req.VIPAddress,
+		eurekaMetaSecureVIPAddress: req.SecureVIPAddress,
+		eurekaMetaHomePageURL:      req.HomePageURL,
+		eurekaMetaStatusPageURL:    req.StatusPageURL,
+		eurekaMetaHealthCheckURL:   req.HealthCheckURL,
+		eurekaMetaDirtyTimestamp:   req.LastDirtyTimestamp,
+	} {
+		if value != "" {
+			instance.Metadata[key] = value
+		}
+	}
+	return instance, nil
+}
+
+// eurekaClientMetadata 返回客户端设置的元数据，Jackson序列化空映射时会带上@class
+func eurekaClientMetadata(metadata map[string]string) map[string]string {
+	if _, ok := metadata["@class"]; !ok {
+		return metadata
+	}
+	result := make(map[string]string, len(metadata))
+	for k, v := range metadata {
+		if k != "@class" {
+			result[k] = v
+		}
+	}
+	return result
+}
+
+// eurekaHealth 把Eureka实例状态转为健康状态：OUT_OF_SERVICE对应摘流，UP以外的其他状态都不接收流量
+func eurekaHealth(status string) string {
+	switch status {
+	case eurekaStatusUp:
+		return etcdclient.HealthPassing
+	case eurekaStatusOutOfService:
+		return etcdclient.HealthDraining
+	default:
+		return etcdclient.HealthCritical
+	}
+}
+
+// eurekaStatus 返回实例的Eureka状态
+// 维护模式和摘流返回OUT_OF_SERVICE；故障的实例优先返回注册时上报的DOWN或STARTING
+func eurekaStatus(instance *etcdclient.ServiceInstance, maintenance *etcdclient.MaintenanceInfo) string {
+	if maintenance != nil || instance.Maintenance {
+		return eurekaStatusOutOfService
+	}
+	switch instance.HealthStatus() {
+	case etcdclient.HealthPassing, etcdclient.HealthWarning:
+		return eurekaStatusUp
+	case etcdclient.HealthDraining:
+		return eurekaStatusOutOfService
+	}
+	switch reported := instance.Metadata[eurekaMetaStatus]; reported {
+	case eurekaStatusDown, eurekaStatusStarting, eurekaStatusUnknown:
+		return reported
+	}
+	return eurekaStatusDown
+}
+
+// eurekaInstance 把服务实例转为Eureka实例，通过原生API注册的实例同样可以被Eureka客户端发现
+func eurekaInstance(instance *etcdclient.ServiceInstance, maintenance *etcdclient.MaintenanceInfo) *EurekaInstance {
+	meta := func(key, fallback string) string {
+		if value := instance.Metadata[key]; value != "" {
+			return value
+		}
+		return fallback
+	}
+
+	metadata := make(map[string]string, len(instance.Metadata))
+	for k, v := range instance.Metadata {
+		if !strings.HasPrefix(k, eurekaMetaPrefix) {
+			metadata[k] = v
+		}
+	}
+
+	port := EurekaPort{Port: instance.Port, Enabled: "true"}
+	securePort := EurekaPort{Port: 443, Enabled: "false"}
+	if value := instance.Metadata[eurekaMetaSecurePort]; value != "" {
+		if n, err := strconv.Atoi(value); err == nil {
+			securePort = EurekaPort{Port: n, Enabled: "true"}
+		}
+	}
+	if instance.Metadata[eurekaMetaSecureOnly] == "true" {
+		port.Enabled = "false"
+	}
+
+	dirty := meta(eurekaMetaDirtyTimestamp, "0")
+	return &EurekaInstance{
+		InstanceID:       meta(eurekaMetaInstanceID, instance.InstanceID),
+		HostName:         meta(eurekaMetaHostName, instance.IPAddress),
+		App:              meta(eurekaMetaApp, strings.ToUpper(instance.ServiceName)),
+		IPAddr:           instance.IPAddress,
+		Status:           eurekaStatus(instance, maintenance),
+		OverriddenStatus: eurekaStatusUnknown,
+		Port:             port,
+		SecurePort:       securePort,
+		CountryID:        1,
+		DataCenterInfo:   EurekaDataCenter{Class: eurekaDataCenterClass, Name: "MyOwn"},
+		LeaseInfo: EurekaLeaseInfo{
+			RenewalIntervalInSecs: eurekaRenewalInterval,
+			DurationInSecs:        instance.TTL,
+		},
+		Metadata:                      metadata,
+		HomePageURL:                   meta(eurekaMetaHomePageURL, ""),
+		StatusPageURL:                 meta(eurekaMetaStatusPageURL, ""),
+		HealthCheckURL:                meta(eurekaMetaHealthCheckURL, ""),
+		VIPAddress:                    meta(eurekaMetaVIPAddress, instance.ServiceName),
+		SecureVIPAddress:              meta(eurekaMetaSecureVIPAddress, instance.ServiceName),
+		IsCoordinatingDiscoveryServer: "false",
+		LastUpdatedTimestamp:          dirty,
+		LastDirtyTimestamp:            dirty,
+		ActionType:                    eurekaActionAdded,
+	}
+}
+
+// eurekaHashcode 计算注册表的哈希码：按状态排序的 状态_数量_ 拼接，与Eureka客户端的计算方式一致
+// 客户端应用增量后计算的哈希码与服务端不一致时会改为全量获取
+func eurekaHashcode(apps []*EurekaApplication) string {
+	counts := make(map[string]int)
+	for _, app := range apps {
+		for _, instance := range app.Instance {
+			counts[instance.Status]++
+		}
+	}
+	statuses := make([]string, 0, len(counts))
+	for status := range counts {
+		statuses = append(statuses, status)
+	}
+	sort.Strings(statuses)
+
+	var b strings.Builder
+	for _, status := range statuses {
+		fmt.Fprintf(&b, "%s_%d_", status, counts[status])
+	}
+	return b.String()
+}
+
+// eurekaApplication 返回服务在default命名空间中的所有实例，服务没有实例时返回nil
+func (h *EchoHandler) eurekaApplication(ctx context.Context, serviceName string) (*EurekaApplication, error) {
+	instances, err := h.namespaceInstances(ctx, etcdclient.DefaultNamespace, serviceName)
+	if err != nil || len(instances) == 0 {
+		return nil, err
+	}
+	maintenance, err := h.etcdClient.GetServiceMaintenance(ctx, serviceName)
+	if err != nil {
+		return nil, err
+	}
+
+	app := &EurekaApplication{Instance: make([]*EurekaInstance, 0, len(instances))}
+	for _, instance := range instances {
+		app.Instance = append(app.Instance, eurekaInstance(instance, maintenance))
+	}
+	app.Name = app.Instance[0].App
+	return app, nil
+}
+
+// eurekaRegistry 返回全量注册表和读取前的etcd修订版本
+func (h *EchoHandler) eurekaRegistry(ctx context.Context) ([]*EurekaApplication, int64, error) {
+	revision, err := h.etcdClient.Revision(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	services, err := h.etcdClient.ListServices(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// 不同命名空间中的同名服务合并为一个应用
+	apps := make([]*EurekaApplication, 0, len(services))
+	seen := make(map[string]bool, len(services))
+	for _, service := range services {
+		if seen[service.ServiceName] {
+			continue
+		}
+		seen[service.ServiceName] = true
+		app, err := h.eurekaApplication(ctx, service.ServiceName)
+		if err != nil {
+			return nil, 0, err
+		}
+		if app != nil {
+			apps = append(apps, app)
+		}
+	}
+	sort.Slice(apps, func(i, j int) bool {
+		return apps[i].Name < apps[j].Name
+	})
+	return apps, revision, nil
+}
+
+// eurekaAppsHandler 全量获取注册表
+func (h *EchoHandler) eurekaAppsHandler(c echo.Context) error {
+	apps, revision, err := h.eurekaRegistry(c.Request().Context())
+	if err != nil {
+		h.log(c).Error("获取Eureka注册表失败", zap.Error(err))
+		return eurekaError(c, storageError(err))
+	}
+	return c.JSON(http.StatusOK, map[string]*EurekaApplications{
+		"applications": {
+			VersionsDelta: strconv.FormatInt(revision, 10),
+			AppsHashcode:  eurekaHashcode(apps),
+			Application:   apps,
+		},
+	})
+}
+
+// eurekaDeltaHandler 增量获取最近变更的实例，哈希码按全量注册表计算
+func (h *EchoHandler) eurekaDeltaHandler(c echo.Context) error {
+	h.eurekaDeltas.ensureWatching()
+
+	ctx := c.Request().Context()
+	apps, revision, err := h.eurekaRegistry(ctx)
+	if err != nil {
+		h.log(c).Error("获取Eureka注册表失败", zap.Error(err))
+		return eurekaError(c, storageError(err))
+	}
+
+	byName := make(map[string]*EurekaApplication)
+	delta := []*EurekaApplication{}
+	for _, change := range h.eurekaDeltas.recent() {
+		instance := eurekaInstance(change.instance, nil)
+		instance.ActionType = change.action
+		app, ok := byName[instance.App]
+		if !ok {
+			app = &EurekaApplication{Name: instance.App}
+			byName[instance.App] = app
+			delta = append(delta, app)
+		}
+		app.Instance = append(app.Instance, instance)
+	}
+
+	return c.JSON(http.StatusOK, map[string]*EurekaApplications{
+		"applications": {
+			VersionsDelta: strconv.FormatInt(revision, 10),
+			AppsHashcode:  eurekaHashcode(apps),
+			Application:   delta,
+		},
+	})
+}
+
+// eurekaAppHandler 获取单个应用的所有实例
+func (h *EchoHandler) eurekaAppHandler(c echo.Context) error {
+	app, err := h.eurekaApplication(c.Request().Context(), eurekaServiceName(c.Param("app")))
+	if err != nil {
+		return eurekaError(c, storageError(err))
+	}
+	if app == nil {
+		return c.NoContent(http.StatusNotFound)
+	}
+	return c.JSON(http.StatusOK, map[string]*EurekaApplication{"application": app})
+}
+
+// findEurekaInstance 按应用名和Eureka实例ID查找default命名空间中的实例
+func (h *EchoHandler) findEurekaInstance(ctx context.Context, app, id string) (*etcdclient.ServiceInstance, error) {
+	serviceName := eurekaServiceName(app)
+	instances, err := h.namespaceInstances(ctx, etcdclient.DefaultNamespace, serviceName)
+	if err != nil {
+		return nil, err
+	}
+	instanceID := eurekaInstanceID(id)
+	for _, instance := range instances {
+		if instance.InstanceID == instanceID || instance.InstanceID == id {
+			return instance, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", etcdclient.ErrInstanceNotFound, id)
+}
+
+// eurekaInstanceHandler 获取单个实例
+func (h *EchoHandler) eurekaInstanceHandler(c echo.Context) error {
+	ctx := c.Request().Context()
+	instance, err := h.findEurekaInstance(ctx, c.Param("app"), c.Param("id"))
+	if errors.Is(err, etcdclient.ErrInstanceNotFound) {
+		return c.NoContent(http.StatusNotFound)
+	}
+	if err != nil {
+		return eurekaError(c, storageError(err))
+	}
+	maintenance, err := h.etcdClient.GetServiceMaintenance(ctx, instance.ServiceName)
+	if err != nil {
+		return eurekaError(c, storageError(err))
+	}
+	return c.JSON(http.StatusOK, &EurekaRegistration{Instance: eurekaInstance(instance, maintenance)})
+}
+
+// eurekaRegisterHandler 注册实例，成功时返回204
+// 实例已被运维摘流或置于维护模式时保留该状态，与Eureka的overriddenStatus一样不会被客户端重新注册覆盖
+func (h *EchoHandler) eurekaRegisterHandler(c echo.Context) error {
+	req := new(EurekaRegistration)
+	if apiErr := bindRequest(c, req); apiErr != nil {
+		return eurekaError(c, apiErr)
+	}
+	if err := c.Validate(req.Instance); err != nil {
+		return eurekaError(c, validationError(err))
+	}
+	if apiErr := h.validateMetadata(eurekaClientMetadata(req.Instance.Metadata)); apiErr != nil {
+		return eurekaError(c, apiErr)
+	}
+
+	instance, err := eurekaServiceInstance(c.Param("app"), req.Instance)
+	if err != nil {
+		return eurekaError(c, badRequest(CodeInvalidParameter, err.Error()))
+	}
+
+	ctx := c.Request().Context()
+	existing, err := h.findEurekaInstance(ctx, c.Param("app"), instance.InstanceID)
+	if err != nil && !errors.Is(err, etcdclient.ErrInstanceNotFound) {
+		return eurekaError(c, storageError(err))
+	}
+	if existing != nil {
+		instance.Maintenance = existing.Maintenance
+		if existing.HealthStatus() == etcdclient.HealthDraining {
+			instance.Health = etcdclient.HealthDraining
+		}
+	}
+
+	if err := h.etcdClient.RegisterService(ctx, instance); err != nil {
+		h.log(c).Error("通过Eureka兼容API注册服务失败",
+			zap.String("service", instance.ServiceName),
+			zap.String("id", instance.InstanceID),
+			zap.Error(err))
+		return eurekaError(c, storageError(err))
+	}
+
+	h.log(c).Info("通过Eureka兼容API注册服务",
+		zap.String("service", instance.ServiceName),
+		zap.String("id", instance.InstanceID),
+		zap.String("status", instance.Metadata[eurekaMetaStatus]),
+		zap.Int("ttl", instance.TTL))
+	return c.NoContent(http.StatusNoContent)
+}
+
+// eurekaHeartbeatHandler 续约，实例不存在时返回404，Eureka客户端收到404后会重新注册
+func (h *EchoHandler) eurekaHeartbeatHandler(c echo.Context) error {
+	ctx := c.Request().Context()
+	instance, err := h.findEurekaInstance(ctx, c.Param("app"), c.Param("id"))
+	if err == nil {
+		err = h.etcdClient.RefreshServiceLease(ctx, instance.ServiceName, instance.InstanceID, 0)
+	}
+	if errors.Is(err, etcdclient.ErrInstanceNotFound) {
+		return c.NoContent(http.StatusNotFound)
+	}
+	if err != nil {
+		return eurekaError(c, storageError(err))
+	}
+	return c.NoContent(http.StatusOK)
+}
+
+// eurekaCancelHandler 注销实例
+func (h *EchoHandler) eurekaCancelHandler(c echo.Context) error {
+	ctx := c.Request().Context()
+	instance, err := h.findEurekaInstance(ctx, c.Param("app"), c.Param("id"))
+	if err == nil {
+		err = h.etcdClient.DeregisterService(ctx, instance.ServiceName, instance.InstanceID)
+	}
+	if errors.Is(err, etcdclient.ErrInstanceNotFound) {
+		return c.NoContent(http.StatusNotFound)
+	}
+	if err != nil {
+		h.log(c).Error("通过Eureka兼容API注销服务失败",
+			zap.String("app", c.Param("app")),
+			zap.String("id", c.Param("id")),
+			zap.Error(err))
+		return eurekaError(c, storageError(err))
+	}
+	return c.NoContent(http.StatusOK)
+}
+
+// eurekaStatusHandler 覆盖实例状态（value参数），Spring Boot的service-registry端点通过它把实例置为OUT_OF_SERVICE
+func (h *EchoHandler) eurekaStatusHandler(c echo.Context) error {
+	status := c.QueryParam("value")
+	switch status {
+	case eurekaStatusUp, eurekaStatusDown, eurekaStatusStarting, eurekaStatusOutOfService, eurekaStatusUnknown:
+	default:
+		return eurekaError(c, badRequest(CodeInvalidParameter, "无效的实例状态: "+status))
+	}
+	return h.setEurekaHealth(c, eurekaHealth(status))
+}
+
+// eurekaDeleteStatusHandler 取消状态覆盖，实例恢复为UP
+func (h *EchoHandler) eurekaDeleteStatusHandler(c echo.Context) error {
+	return h.setEurekaHealth(c, etcdclient.HealthPassing)
+}
+
+// setEurekaHealth 设置实例的健康状态
+func (h *EchoHandler) setEurekaHealth(c echo.Context, health string) error {
+	ctx := c.Request().Context()
+	instance, err := h.findEurekaInstance(ctx, c.Param("app"), c.Param("id"))
+	if err == nil && instance.HealthStatus() != health {
+		_, err = h.etcdClient.SetInstanceHealth(ctx, instance.ServiceName, instance.InstanceID, health)
+	}
+	if errors.Is(err, etcdclient.ErrInstanceNotFound) {
+		return c.NoContent(http.StatusNotFound)
+	}
+	if err != nil {
+		return eurekaError(c, storageError(err))
+	}
+	return c.NoContent(http.StatusOK)
+}
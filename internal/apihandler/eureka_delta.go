@@ -0,0 +1,163 @@
+package apihandler
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hewenyu/kong-discovery/internal/config"
+	"github.com/hewenyu/kong-discovery/internal/etcdclient"
+	"go.uber.org/zap"
+)
+
+// eurekaChange 最近变更的实例
+type eurekaChange struct {
+	at       time.Time
+	action   string
+	instance *etcdclient.ServiceInstance
+}
+
+// eurekaDeltas 保存最近变更的实例，供Eureka客户端增量获取
+// 与Eureka服务端的recentlyChangedQueue一样只保存在内存中：每个节点各自监听etcd的变更，
+// 节点重启后缺少之前的变更，客户端应用增量后哈希码不一致，会自动改为全量获取
+type eurekaDeltas struct {
+	client    etcdclient.Client
+	logger    config.Logger
+	retention time.Duration
+
+	mu       sync.Mutex
+	ctx      context.Context
+	cancel   context.CancelFunc
+	watching bool
+	revision int64 // 已处理的最新修订版本
+	changes  []eurekaChange
+}
+
+// newEurekaDeltas 创建最近变更队列，需要调用ensureWatching开始监听
+func newEurekaDeltas(client etcdclient.Client, logger config.Logger, retention time.Duration) *eurekaDeltas {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &eurekaDeltas{
+		client:    client,
+		logger:    logger,
+		retention: retention,
+		ctx:       ctx,
+		cancel:    cancel,
+	}
+}
+
+// ensureWatching 未在监听时开始监听服务实例的变更，监听失败或中断后在下次调用时重新开始
+func (d *eurekaDeltas) ensureWatching() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.watching || d.ctx.Err() != nil {
+		return
+	}
+
+	// 从上次处理到的版本之后继续监听，监听中断期间的变更不会丢失
+	revision := d.revision
+	if revision == 0 {
+		ctx, cancel := context.WithTimeout(d.ctx, 5*time.Second)
+		rev, err := d.client.Revision(ctx)
+		cancel()
+		if err != nil {
+			d.logger.Warn("获取etcd修订版本失败，Eureka增量获取暂时为空", zap.Error(err))
+			return
+		}
+		revision = rev
+	}
+
+	events, err := d.client.WatchEventsFrom(d.ctx, revision+1)
+	if err != nil {
+		d.logger.Warn("监听服务变更失败，Eureka增量获取暂时为空", zap.Error(err))
+		return
+	}
+	d.watching = true
+	go d.run(events)
+}
+
+// run 把服务实例的变更加入队列，直到监听中断
+func (d *eurekaDeltas) run(events <-chan *etcdclient.Event) {
+	for event := range events {
+		d.record(event)
+	}
+
+	d.mu.Lock()
+	d.watching = false
+	d.mu.Unlock()
+	if d.ctx.Err() == nil {
+		d.logger.Warn("监听服务变更中断，Eureka增量获取将在下次请求时重新监听")
+	}
+}
+
+// record 把default命名空间中服务实例的变更加入队列
+func (d *eurekaDeltas) record(event *etcdclient.Event) {
+	d.mu.Lock()
+	d.revision = event.Revision
+	d.mu.Unlock()
+
+	// 压缩期间的变更不在队列中，客户端应用增量后哈希码不一致，会自动改为全量获取
+	if event.Type == etcdclient.EventResync {
+		d.logger.Warn("监听的版本已被压缩，Eureka增量获取缺少期间的变更", zap.Int64("revision", event.Revision))
+		return
+	}
+	if event.Kind != "services" || strings.Count(strings.TrimPrefix(event.Key, "/services/"), "/") != 1 {
+		return
+	}
+	var instance etcdclient.ServiceInstance
+	if err := json.Unmarshal(event.Value, &instance); err != nil || instance.InstanceID == "" {
+		return
+	}
+	if etcdclient.NamespaceOf(instance.Namespace) != etcdclient.DefaultNamespace {
+		return
+	}
+
+	action := eurekaActionModified
+	switch {
+	case event.Type == etcdclient.EventDelete:
+		action = eurekaActionDeleted
+	case len(event.Previous) == 0:
+		action = eurekaActionAdded
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.changes = append(d.changes, eurekaChange{at: time.Now(), action: action, instance: &instance})
+	d.pruneLocked(time.Now())
+}
+
+// pruneLocked 删除超过保留时间的变更，调用方需持有锁
+func (d *eurekaDeltas) pruneLocked(now time.Time) {
+	i := 0
+	for i < len(d.changes) && now.Sub(d.changes[i].at) > d.retention {
+		i++
+	}
+	if i > 0 {
+		d.changes = append(d.changes[:0], d.changes[i:]...)
+	}
+}
+
+// recent 返回保留时间内的变更，同一实例只返回最后一次变更，按变更时间排序
+func (d *eurekaDeltas) recent() []eurekaChange {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.pruneLocked(time.Now())
+
+	latest := make(map[string]int, len(d.changes))
+	for i, change := range d.changes {
+		latest[change.instance.ServiceName+"/"+change.instance.InstanceID] = i
+	}
+	result := make([]eurekaChange, 0, len(latest))
+	for i, change := range d.changes {
+		if latest[change.instance.ServiceName+"/"+change.instance.InstanceID] == i {
+			result = append(result, change)
+		}
+	}
+	return result
+}
+
+// stop 停止监听
+func (d *eurekaDeltas) stop() {
+	d.cancel()
+}
@@ -0,0 +1,234 @@
+package apihandler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hewenyu/kong-discovery/internal/config"
+	"github.com/hewenyu/kong-discovery/internal/etcdclient"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEurekaPort_UnmarshalJSON(t *testing.T) {
+	var p EurekaPort
+	require.NoError(t, json.Unmarshal([]byte(`{"$":8080,"@enabled":"true"}`), &p))
+	assert.Equal(t, 8080, p.Port)
+	assert.True(t, p.enabled())
+
+	// 部分客户端使用字符串端口和布尔值
+	require.NoError(t, json.Unmarshal([]byte(`{"$":"8443","@enabled":false}`), &p))
+	assert.Equal(t, 8443, p.Port)
+	assert.False(t, p.enabled())
+
+	assert.Error(t, json.Unmarshal([]byte(`{"$":"abc"}`), &p))
+}
+
+func TestEurekaServiceInstance(t *testing.T) {
+	req := &EurekaInstance{
+		InstanceID:     "host-1.example.com:orders:8080",
+		HostName:       "host-1.example.com",
+		IPAddr:         "10.0.0.1",
+		Status:         eurekaStatusStarting,
+		Port:           EurekaPort{Port: 8080, Enabled: "true"},
+		SecurePort:     EurekaPort{Port: 8443, Enabled: "true"},
+		LeaseInfo:      EurekaLeaseInfo{DurationInSecs: 30},
+		Metadata:       map[string]string{"@class": "java.util.Collections$EmptyMap", "zone": "a"},
+		VIPAddress:     "orders",
+		HealthCheckURL: "http://host-1.example.com:8080/actuator/health",
+	}
+
+	instance, err := eurekaServiceInstance("ORDERS", req)
+	require.NoError(t, err)
+	assert.Equal(t, "orders", instance.ServiceName)
+	assert.Equal(t, "host-1-example-com-orders-8080", instance.InstanceID)
+	assert.Equal(t, 8080, instance.Port)
+	assert.Equal(t, 30, instance.TTL)
+	assert.Equal(t, etcdclient.HealthCritical, instance.Health)
+	assert.NotContains(t, instance.Metadata, "@class")
+
+	// 转回Eureka实例时保留原始的实例ID、主机名和上报的状态，附加信息不出现在元数据中
+	out := eurekaInstance(instance, nil)
+	assert.Equal(t, req.InstanceID, out.InstanceID)
+	assert.Equal(t, "ORDERS", out.App)
+	assert.Equal(t, req.HostName, out.HostName)
+	assert.Equal(t, eurekaStatusStarting, out.Status)
+	assert.Equal(t, EurekaPort{Port: 8443, Enabled: "true"}, out.SecurePort)
+	assert.Equal(t, req.HealthCheckURL, out.HealthCheckURL)
+	assert.Equal(t, map[string]string{"zone": "a"}, out.Metadata)
+
+	// 摘流和维护模式返回OUT_OF_SERVICE
+	instance.Health = etcdclient.HealthDraining
+	assert.Equal(t, eurekaStatusOutOfService, eurekaInstance(instance, nil).Status)
+	instance.Health = etcdclient.HealthPassing
+	assert.Equal(t, eurekaStatusUp, eurekaInstance(instance, nil).Status)
+	assert.Equal(t, eurekaStatusOutOfService, eurekaInstance(instance, &etcdclient.MaintenanceInfo{}).Status)
+
+	// 只启用安全端口时使用安全端口注册
+	req.Port.Enabled = "false"
+	instance, err = eurekaServiceInstance("orders", req)
+	require.NoError(t, err)
+	assert.Equal(t, 8443, instance.Port)
+	assert.Equal(t, "false", eurekaInstance(instance, nil).Port.Enabled)
+
+	_, err = eurekaServiceInstance("orders", &EurekaInstance{IPAddr: "10.0.0.1", Port: EurekaPort{Port: 8080}})
+	assert.Error(t, err)
+}
+
+func TestEurekaHashcode(t *testing.T) {
+	apps := []*EurekaApplication{
+		{Name: "A", Instance: []*EurekaInstance{{Status: eurekaStatusUp}, {Status: eurekaStatusDown}}},
+		{Name: "B", Instance: []*EurekaInstance{{Status: eurekaStatusUp}}},
+	}
+	assert.Equal(t, "DOWN_1_UP_2_", eurekaHashcode(apps))
+	assert.Equal(t, "", eurekaHashcode(nil))
+}
+
+func TestEurekaEndpoints(t *testing.T) {
+	// 跳过集成测试，除非明确要求运行
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	cfg := createTestConfig(t)
+	cfg.API.Eureka.Enabled = true
+
+	e := echo.New()
+	client := etcdclient.CreateEtcdClientForTest(t)
+	t.Cleanup(func() { _ = client.Close() })
+
+	handler := &EchoHandler{
+		registrationServer: e,
+		cfg:                cfg,
+		logger:             createTestLogger(t),
+		etcdClient:         client,
+	}
+	handler.registerRegistrationRoutes()
+	t.Cleanup(handler.eurekaDeltas.stop)
+
+	suffix := time.Now().UnixNano()
+	app := fmt.Sprintf("EUREKA-APP-%d", suffix)
+	serviceName := strings.ToLower(app)
+	instanceID := fmt.Sprintf("10.0.0.1:%s:8080", serviceName)
+	storedID := eurekaInstanceID(instanceID)
+	t.Cleanup(func() {
+		_ = client.DeregisterService(context.Background(), serviceName, storedID)
+	})
+
+	serve := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+	decode := func(rec *httptest.ResponseRecorder, v interface{}) {
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), v))
+	}
+	waitDelta := func(action string) *EurekaInstance {
+		var found *EurekaInstance
+		require.Eventually(t, func() bool {
+			var resp map[string]*EurekaApplications
+			decode(serve(http.MethodGet, "/eureka/apps/delta", ""), &resp)
+			for _, a := range resp["applications"].Application {
+				for _, instance := range a.Instance {
+					if a.Name == app && instance.ActionType == action {
+						found = instance
+						return true
+					}
+				}
+			}
+			return false
+		}, 5*time.Second, 50*time.Millisecond)
+		return found
+	}
+
+	// Spring Cloud客户端发送的注册请求
+	body := fmt.Sprintf(`{"instance":{"instanceId":%q,"hostName":"10.0.0.1","app":%q,"ipAddr":"10.0.0.1","status":"UP",
+		"overriddenStatus":"UNKNOWN","port":{"$":8080,"@enabled":"true"},"securePort":{"$":443,"@enabled":"false"},"countryId":1,
+		"dataCenterInfo":{"@class":"com.netflix.appinfo.InstanceInfo$DefaultDataCenterInfo","name":"MyOwn"},
+		"leaseInfo":{"renewalIntervalInSecs":30,"durationInSecs":90},"metadata":{"management.port":"8080"},
+		"vipAddress":%q,"secureVipAddress":%q,"isCoordinatingDiscoveryServer":"false",
+		"lastUpdatedTimestamp":"1700000000000","lastDirtyTimestamp":"1700000000000"}}`, instanceID, app, serviceName, serviceName)
+	rec := serve(http.MethodPost, "/eureka/apps/"+app, body)
+	require.Equal(t, http.StatusNoContent, rec.Code, rec.Body.String())
+
+	// 注册的实例可以通过原生API发现
+	instances, err := client.GetServiceInstances(context.Background(), serviceName)
+	require.NoError(t, err)
+	require.Len(t, instances, 1)
+	assert.Equal(t, storedID, instances[0].InstanceID)
+	assert.Equal(t, 90, instances[0].TTL)
+
+	var appResp map[string]*EurekaApplication
+	decode(serve(http.MethodGet, "/eureka/apps/"+app, ""), &appResp)
+	require.Len(t, appResp["application"].Instance, 1)
+	instance := appResp["application"].Instance[0]
+	assert.Equal(t, instanceID, instance.InstanceID)
+	assert.Equal(t, eurekaStatusUp, instance.Status)
+	assert.Equal(t, 8080, instance.Port.Port)
+	assert.Equal(t, map[string]string{"management.port": "8080"}, instance.Metadata)
+
+	var full map[string]*EurekaApplications
+	decode(serve(http.MethodGet, "/eureka/v2/apps", ""), &full)
+	assert.NotEmpty(t, full["applications"].AppsHashcode)
+	assert.Contains(t, full["applications"].AppsHashcode, "UP_")
+
+	assert.Equal(t, eurekaStatusUp, waitDelta(eurekaActionAdded).Status)
+
+	// 续约
+	rec = serve(http.MethodPut, "/eureka/apps/"+app+"/"+instanceID+"?status=UP&lastDirtyTimestamp=1700000000000", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	// 覆盖状态为OUT_OF_SERVICE后实例摘流，重新注册不会恢复流量
+	rec = serve(http.MethodPut, "/eureka/apps/"+app+"/"+instanceID+"/status?value=OUT_OF_SERVICE", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, http.StatusNoContent, serve(http.MethodPost, "/eureka/apps/"+app, body).Code)
+	var instResp EurekaRegistration
+	decode(serve(http.MethodGet, "/eureka/apps/"+app+"/"+instanceID, ""), &instResp)
+	assert.Equal(t, eurekaStatusOutOfService, instResp.Instance.Status)
+
+	rec = serve(http.MethodDelete, "/eureka/apps/"+app+"/"+instanceID+"/status", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	decode(serve(http.MethodGet, "/eureka/apps/"+app+"/"+instanceID, ""), &instResp)
+	assert.Equal(t, eurekaStatusUp, instResp.Instance.Status)
+
+	rec = serve(http.MethodPut, "/eureka/apps/"+app+"/"+instanceID+"/status?value=BOGUS", "")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	// 无效的注册请求
+	rec = serve(http.MethodPost, "/eureka/apps/"+app, `{"instance":{"instanceId":"x","ipAddr":"not-an-ip","port":{"$":8080}}}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	// 注销后续约返回404，客户端会重新注册
+	rec = serve(http.MethodDelete, "/eureka/apps/"+app+"/"+instanceID, "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	rec = serve(http.MethodPut, "/eureka/apps/"+app+"/"+instanceID, "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Equal(t, http.StatusNotFound, serve(http.MethodGet, "/eureka/apps/"+app, "").Code)
+
+	assert.Equal(t, instanceID, waitDelta(eurekaActionDeleted).InstanceID)
+}
+
+func TestEurekaEndpoints_Disabled(t *testing.T) {
+	e := echo.New()
+	handler := &EchoHandler{
+		registrationServer: e,
+		cfg:                &config.Config{},
+		logger:             createTestLogger(t),
+	}
+	handler.registerRegistrationRoutes()
+
+	req := httptest.NewRequest(http.MethodGet, "/eureka/apps", nil)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
@@ -0,0 +1,66 @@
+package apihandler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/hewenyu/kong-discovery/internal/etcdclient"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// 事件流的心跳间隔，防止空闲连接被代理断开
+const eventKeepaliveInterval = 15 * time.Second
+
+// eventsHandler 以Server-Sent Events格式推送发现数据的变更，可通过kind参数按类别过滤
+func (h *EchoHandler) eventsHandler(c echo.Context) error {
+	kind := c.QueryParam("kind")
+	ctx := c.Request().Context()
+
+	events, err := h.etcdClient.WatchEvents(ctx)
+	if err != nil {
+		h.log(c).Error("监听变更事件失败", zap.Error(err))
+		return respondError(c, storageError(fmt.Errorf("监听变更事件失败: %w", err)))
+	}
+
+	resp := c.Response()
+	resp.Header().Set(echo.HeaderContentType, "text/event-stream")
+	resp.Header().Set(echo.HeaderCacheControl, "no-cache")
+	resp.Header().Set(echo.HeaderConnection, "keep-alive")
+	resp.WriteHeader(http.StatusOK)
+	resp.Flush()
+
+	keepalive := time.NewTicker(eventKeepaliveInterval)
+	defer keepalive.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-keepalive.C:
+			if _, err := fmt.Fprint(resp, ": keepalive\n\n"); err != nil {
+				return nil
+			}
+			resp.Flush()
+		case event, ok := <-events:
+			if !ok {
+				return nil
+			}
+			// resync事件不属于任何类别，总是推送，客户端收到后应重新读取全量数据
+			if kind != "" && event.Kind != kind && event.Type != etcdclient.EventResync {
+				continue
+			}
+
+			data, err := json.Marshal(event)
+			if err != nil {
+				continue
+			}
+			if _, err := fmt.Fprintf(resp, "event: %s\ndata: %s\n\n", event.Type, data); err != nil {
+				return nil
+			}
+			resp.Flush()
+		}
+	}
+}
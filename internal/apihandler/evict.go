@@ -0,0 +1,116 @@
+package apihandler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/hewenyu/kong-discovery/internal/etcdclient"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// EvictionResponse 定义强制驱逐响应结构
+type EvictionResponse struct {
+	Success   bool                 `json:"success"`           // 是否成功
+	Eviction  *etcdclient.Eviction `json:"eviction"`          // 驱逐记录
+	Message   string               `json:"message,omitempty"` // 可选消息
+	Timestamp string               `json:"timestamp"`         // 时间戳
+}
+
+// EvictionListResponse 定义驱逐记录列表响应结构
+type EvictionListResponse struct {
+	Success   bool                   `json:"success"`   // 是否成功
+	Evictions []*etcdclient.Eviction `json:"evictions"` // 仍在冷却期内的驱逐记录
+	Count     int                    `json:"count"`     // 记录数量
+	Timestamp string                 `json:"timestamp"` // 时间戳
+}
+
+// adminDeregisterHandler 处理管理API的实例删除请求，force=true时强制驱逐实例，否则与普通注销相同；
+// dry_run=true时只报告影响范围
+func (h *EchoHandler) adminDeregisterHandler(c echo.Context) error {
+	if isDryRun(c) {
+		return h.planDeregisterHandler(c, c.QueryParam("force") == "true")
+	}
+	if c.QueryParam("force") == "true" {
+		return h.evictInstanceHandler(c)
+	}
+	return h.deregisterServiceHandler(c)
+}
+
+// evictInstanceHandler 强制驱逐仍在发送心跳的异常实例：删除实例、撤销租约并清理服务的DNS记录，
+// 冷却期内（cooldown参数，单位秒，默认为heartbeat.eviction_cooldown）拒绝相同实例ID的注册
+func (h *EchoHandler) evictInstanceHandler(c echo.Context) error {
+	serviceName := c.Param("serviceName")
+	instanceID := c.Param("instanceId")
+	if serviceName == "" || instanceID == "" {
+		return respondError(c, badRequest(CodeInvalidParameter, "服务名和实例ID都是必需的"))
+	}
+
+	cooldown := h.cfg.Heartbeat.EvictionCooldown
+	if param := c.QueryParam("cooldown"); param != "" {
+		n, err := strconv.Atoi(param)
+		if err != nil || n < 0 {
+			return respondError(c, badRequest(CodeInvalidParameter, "cooldown必须是非负整数"))
+		}
+		cooldown = n
+	}
+
+	eviction, err := h.etcdClient.EvictInstance(c.Request().Context(), serviceName, instanceID,
+		time.Duration(cooldown)*time.Second, c.QueryParam("reason"))
+	if err != nil {
+		h.log(c).Error("强制驱逐服务实例失败",
+			zap.String("service", serviceName),
+			zap.String("id", instanceID),
+			zap.Error(err))
+		return respondError(c, storageError(err))
+	}
+
+	h.log(c).Warn("服务实例已被强制驱逐",
+		zap.String("service", serviceName),
+		zap.String("id", instanceID),
+		zap.Int("cooldown", cooldown))
+	return c.JSON(http.StatusOK, &EvictionResponse{
+		Success:   true,
+		Eviction:  eviction,
+		Message:   "服务实例已被强制驱逐",
+		Timestamp: time.Now().Format(time.RFC3339),
+	})
+}
+
+// listEvictionsHandler 列出仍在冷却期内的驱逐记录
+func (h *EchoHandler) listEvictionsHandler(c echo.Context) error {
+	evictions, err := h.etcdClient.ListEvictions(c.Request().Context())
+	if err != nil {
+		h.log(c).Error("获取驱逐记录失败", zap.Error(err))
+		return respondError(c, storageError(err))
+	}
+
+	return c.JSON(http.StatusOK, &EvictionListResponse{
+		Success:   true,
+		Evictions: evictions,
+		Count:     len(evictions),
+		Timestamp: time.Now().Format(time.RFC3339),
+	})
+}
+
+// deleteEvictionHandler 提前结束实例的驱逐冷却期，之后实例可以重新注册
+func (h *EchoHandler) deleteEvictionHandler(c echo.Context) error {
+	serviceName := c.Param("serviceName")
+	instanceID := c.Param("instanceId")
+	if err := h.etcdClient.DeleteEviction(c.Request().Context(), serviceName, instanceID); err != nil {
+		h.log(c).Error("删除驱逐记录失败",
+			zap.String("service", serviceName),
+			zap.String("id", instanceID),
+			zap.Error(err))
+		return respondError(c, storageError(err))
+	}
+
+	return c.JSON(http.StatusOK, &ServiceDeregistrationResponse{
+		Success:     true,
+		ServiceName: serviceName,
+		InstanceID:  instanceID,
+		Message:     "驱逐冷却期已解除",
+		Timestamp:   time.Now().Format(time.RFC3339),
+	})
+}
@@ -0,0 +1,194 @@
+package apihandler
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/hewenyu/kong-discovery/internal/config"
+	"github.com/hewenyu/kong-discovery/internal/etcdclient"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// maxCatalogSize 接收推送目录时允许的最大请求体大小
+const maxCatalogSize = 64 << 20
+
+// FederationClusterStatus 远程集群的联邦链路状态
+type FederationClusterStatus struct {
+	*etcdclient.FederationLink
+	State      string `json:"state"`      // 链路健康状态：healthy、failing或stale
+	Configured bool   `json:"configured"` // 是否仍在federation.peers中，移除配置后同步的目录需要手动删除
+}
+
+// FederationClustersResponse 定义联邦链路状态响应结构
+type FederationClustersResponse struct {
+	Success   bool                       `json:"success"`           // 是否成功
+	Cluster   string                     `json:"cluster"`           // 本集群名称
+	Clusters  []*FederationClusterStatus `json:"clusters"`          // 远程集群的链路状态
+	Message   string                     `json:"message,omitempty"` // 可选消息
+	Timestamp string                     `json:"timestamp"`         // 时间戳
+}
+
+// federationPeer 返回federation.peers中指定名称的远程集群
+func (h *EchoHandler) federationPeer(name string) (config.FederationPeer, bool) {
+	for _, peer := range h.cfg.Federation.Peers {
+		if peer.Name == name {
+			return peer, true
+		}
+	}
+	return config.FederationPeer{}, false
+}
+
+// checkFederationToken 校验请求携带的链路令牌，expected为空时不校验
+func checkFederationToken(c echo.Context, expected string) *APIError {
+	if expected == "" {
+		return nil
+	}
+	token := strings.TrimPrefix(c.Request().Header.Get(echo.HeaderAuthorization), "Bearer ")
+	if subtle.ConstantTimeCompare([]byte(token), []byte(expected)) != 1 {
+		return newAPIError(http.StatusUnauthorized, CodeUnauthorized, "联邦令牌无效")
+	}
+	return nil
+}
+
+// federationCatalogHandler 导出本集群的服务目录供远程集群拉取
+// 响应体直接是目录本身，便于远程集群的同步器解析
+func (h *EchoHandler) federationCatalogHandler(c echo.Context) error {
+	if !h.cfg.Federation.Enabled {
+		return respondError(c, newAPIError(http.StatusNotFound, CodeFederationDisabled, ""))
+	}
+	if apiErr := checkFederationToken(c, h.cfg.Federation.Token); apiErr != nil {
+		return respondError(c, apiErr)
+	}
+
+	catalog, err := h.etcdClient.ExportCatalog(c.Request().Context(), h.cfg.Federation.ClusterName)
+	if err != nil {
+		h.log(c).Error("导出服务目录失败", zap.Error(err))
+		return respondError(c, storageError(err))
+	}
+	return c.JSON(http.StatusOK, catalog)
+}
+
+// pushCatalogHandler 接收远程集群推送的服务目录
+// 只接受federation.peers中配置为push方式的集群，目录声明的集群必须与路径一致
+func (h *EchoHandler) pushCatalogHandler(c echo.Context) error {
+	if !h.cfg.Federation.Enabled {
+		return respondError(c, newAPIError(http.StatusNotFound, CodeFederationDisabled, ""))
+	}
+
+	cluster := c.Param("cluster")
+	peer, ok := h.federationPeer(cluster)
+	if !ok {
+		return respondError(c, newAPIError(http.StatusNotFound, CodeClusterNotFound, cluster))
+	}
+	if peer.Mode != etcdclient.FederationPush {
+		return respondError(c, newAPIError(http.StatusConflict, CodeFederationConflict, cluster+" 配置为定时拉取，不接受推送"))
+	}
+	if apiErr := checkFederationToken(c, peer.Token); apiErr != nil {
+		return respondError(c, apiErr)
+	}
+
+	var catalog etcdclient.Catalog
+	body := http.MaxBytesReader(c.Response(), c.Request().Body, maxCatalogSize)
+	if err := json.NewDecoder(body).Decode(&catalog); err != nil {
+		return respondError(c, badRequest(CodeInvalidRequest, err.Error()))
+	}
+	if catalog.Cluster == "" {
+		catalog.Cluster = cluster
+	}
+	if catalog.Cluster != cluster {
+		return respondError(c, newAPIError(http.StatusConflict, CodeFederationConflict, "目录来自集群 "+catalog.Cluster))
+	}
+	if catalog.GeneratedAt.IsZero() {
+		catalog.GeneratedAt = time.Now()
+	}
+
+	link, err := h.etcdClient.ApplyCatalog(c.Request().Context(), &catalog, etcdclient.FederationPush, c.RealIP())
+	if err != nil {
+		h.log(c).Warn("保存推送的目录失败", zap.String("cluster", cluster), zap.Error(err))
+		return respondError(c, storageError(err))
+	}
+
+	return c.JSON(http.StatusOK, &FederationClustersResponse{
+		Success:   true,
+		Cluster:   h.cfg.Federation.ClusterName,
+		Clusters:  []*FederationClusterStatus{h.federationClusterStatus(link, time.Now())},
+		Message:   "目录已同步",
+		Timestamp: time.Now().Format(time.RFC3339),
+	})
+}
+
+// federationClusterStatus 计算链路在now时刻的状态
+func (h *EchoHandler) federationClusterStatus(link *etcdclient.FederationLink, now time.Time) *FederationClusterStatus {
+	_, configured := h.federationPeer(link.Cluster)
+	return &FederationClusterStatus{
+		FederationLink: link,
+		State:          link.State(now, time.Duration(h.cfg.Federation.StaleAfter)*time.Second),
+		Configured:     configured,
+	}
+}
+
+// listFederationClustersHandler 查看与各远程集群的联邦链路状态
+// 已配置但从未同步过的集群也会列出，状态为stale
+func (h *EchoHandler) listFederationClustersHandler(c echo.Context) error {
+	links, err := h.etcdClient.ListFederationLinks(c.Request().Context())
+	if err != nil {
+		h.log(c).Error("获取联邦链路状态失败", zap.Error(err))
+		return respondError(c, storageError(err))
+	}
+
+	now := time.Now()
+	seen := make(map[string]bool, len(links))
+	clusters := make([]*FederationClusterStatus, 0, len(links))
+	for _, link := range links {
+		seen[link.Cluster] = true
+		clusters = append(clusters, h.federationClusterStatus(link, now))
+	}
+	for _, peer := range h.cfg.Federation.Peers {
+		if seen[peer.Name] {
+			continue
+		}
+		mode := peer.Mode
+		if mode == "" {
+			mode = etcdclient.FederationPull
+		}
+		link := &etcdclient.FederationLink{Cluster: peer.Name, Mode: mode}
+		if mode == etcdclient.FederationPull {
+			link.Source = peer.URL
+		}
+		clusters = append(clusters, h.federationClusterStatus(link, now))
+	}
+
+	return c.JSON(http.StatusOK, &FederationClustersResponse{
+		Success:   true,
+		Cluster:   h.cfg.Federation.ClusterName,
+		Clusters:  clusters,
+		Timestamp: now.Format(time.RFC3339),
+	})
+}
+
+// deleteFederationClusterHandler 删除远程集群已同步的目录和链路状态
+// 集群仍在配置中时，下一次同步会重新写入
+func (h *EchoHandler) deleteFederationClusterHandler(c echo.Context) error {
+	cluster := c.Param("cluster")
+	deleted, err := h.etcdClient.DeleteFederatedCluster(c.Request().Context(), cluster)
+	if err != nil {
+		h.log(c).Error("删除远程集群目录失败", zap.String("cluster", cluster), zap.Error(err))
+		return respondError(c, storageError(err))
+	}
+	if deleted == 0 {
+		return respondError(c, newAPIError(http.StatusNotFound, CodeClusterNotFound, cluster))
+	}
+
+	h.log(c).Info("已删除远程集群目录", zap.String("cluster", cluster), zap.Int64("keys", deleted))
+	return c.JSON(http.StatusOK, &FederationClustersResponse{
+		Success:   true,
+		Cluster:   h.cfg.Federation.ClusterName,
+		Clusters:  []*FederationClusterStatus{},
+		Message:   "远程集群目录删除成功",
+		Timestamp: time.Now().Format(time.RFC3339),
+	})
+}
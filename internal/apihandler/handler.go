@@ -3,11 +3,20 @@ package apihandler
 import (
 	"context"
 	"fmt"
+	"net"
 	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/hewenyu/kong-discovery/internal/config"
+	"github.com/hewenyu/kong-discovery/internal/dnsserver"
 	"github.com/hewenyu/kong-discovery/internal/etcdclient"
+	"github.com/hewenyu/kong-discovery/internal/reconciler"
+	"github.com/hewenyu/kong-discovery/internal/upgrade"
+	"github.com/hewenyu/kong-discovery/internal/version"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 	"go.uber.org/zap"
@@ -23,6 +32,15 @@ type Handler interface {
 
 	// Shutdown 优雅关闭API服务
 	Shutdown(ctx context.Context) error
+
+	// SetDNSServer 设置DNS服务器，用于查询DNS运行状态
+	SetDNSServer(server dnsserver.Server)
+
+	// SetReconciler 设置后台协调任务，用于查询协调统计
+	SetReconciler(r reconciler.Reconciler)
+
+	// Files 返回监听套接字的副本，用于升级时传递给新进程
+	Files() (map[string][]*os.File, error)
 }
 
 // EchoHandler 实现Handler接口
@@ -32,6 +50,9 @@ type EchoHandler struct {
 	cfg                *config.Config
 	logger             config.Logger
 	etcdClient         etcdclient.Client
+	dnsServer          dnsserver.Server
+	reconciler         reconciler.Reconciler
+	eurekaDeltas       *eurekaDeltas // Eureka兼容API最近变更的实例，未启用时为nil
 }
 
 // NewAPIHandler 创建一个新的API处理器
@@ -43,6 +64,16 @@ func NewAPIHandler(cfg *config.Config, logger config.Logger, etcdClient etcdclie
 	}
 }
 
+// SetDNSServer 设置DNS服务器
+func (h *EchoHandler) SetDNSServer(server dnsserver.Server) {
+	h.dnsServer = server
+}
+
+// SetReconciler 设置后台协调任务
+func (h *EchoHandler) SetReconciler(r reconciler.Reconciler) {
+	h.reconciler = r
+}
+
 // StartManagementAPI 启动管理API服务
 func (h *EchoHandler) StartManagementAPI() error {
 	h.logger.Info("启动管理API服务",
@@ -55,14 +86,23 @@ func (h *EchoHandler) StartManagementAPI() error {
 
 	// 添加中间件
 	h.managementServer.Use(middleware.Recover())
+	h.managementServer.Use(requestIDMiddleware)
 	h.managementServer.Use(middleware.Logger())
+	h.managementServer.Use(tracingMiddleware("management"))
 
 	// 注册路由
 	h.registerManagementRoutes()
 
+	// 绑定监听地址，升级时使用旧进程传递的套接字
+	addr := net.JoinHostPort(h.cfg.API.Management.ListenAddress, strconv.Itoa(h.cfg.API.Management.Port))
+	ln, err := listen(upgrade.NameManagement, addr)
+	if err != nil {
+		return fmt.Errorf("管理API监听失败: %w", err)
+	}
+	h.managementServer.Listener = ln
+
 	// 启动服务（非阻塞）
 	go func() {
-		addr := fmt.Sprintf("%s:%d", h.cfg.API.Management.ListenAddress, h.cfg.API.Management.Port)
 		if err := h.managementServer.Start(addr); err != nil && err != http.ErrServerClosed {
 			h.logger.Error("管理API服务启动失败", zap.Error(err))
 		}
@@ -83,14 +123,23 @@ func (h *EchoHandler) StartRegistrationAPI() error {
 
 	// 添加中间件
 	h.registrationServer.Use(middleware.Recover())
+	h.registrationServer.Use(requestIDMiddleware)
 	h.registrationServer.Use(middleware.Logger())
+	h.registrationServer.Use(tracingMiddleware("registration"))
 
 	// 注册路由
 	h.registerRegistrationRoutes()
 
+	// 绑定监听地址，升级时使用旧进程传递的套接字
+	addr := net.JoinHostPort(h.cfg.API.Registration.ListenAddress, strconv.Itoa(h.cfg.API.Registration.Port))
+	ln, err := listen(upgrade.NameRegistration, addr)
+	if err != nil {
+		return fmt.Errorf("服务注册API监听失败: %w", err)
+	}
+	h.registrationServer.Listener = ln
+
 	// 启动服务（非阻塞）
 	go func() {
-		addr := fmt.Sprintf("%s:%d", h.cfg.API.Registration.ListenAddress, h.cfg.API.Registration.Port)
 		if err := h.registrationServer.Start(addr); err != nil && err != http.ErrServerClosed {
 			h.logger.Error("服务注册API服务启动失败", zap.Error(err))
 		}
@@ -99,10 +148,57 @@ func (h *EchoHandler) StartRegistrationAPI() error {
 	return nil
 }
 
+// listen 优先使用继承的名为name的套接字，没有时绑定addr
+func listen(name, addr string) (net.Listener, error) {
+	files, err := upgrade.Take(name)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return net.Listen("tcp", addr)
+	}
+	for _, f := range files[1:] {
+		f.Close()
+	}
+	defer files[0].Close()
+	return net.FileListener(files[0])
+}
+
+// Files 返回管理API和服务注册API监听套接字的副本，用于升级时传递给新进程
+// 调用方负责关闭返回的文件
+func (h *EchoHandler) Files() (map[string][]*os.File, error) {
+	files := make(map[string][]*os.File)
+	for name, server := range map[string]*echo.Echo{
+		upgrade.NameManagement:   h.managementServer,
+		upgrade.NameRegistration: h.registrationServer,
+	} {
+		if server == nil {
+			continue
+		}
+		ln, ok := server.Listener.(*net.TCPListener)
+		if !ok {
+			return nil, fmt.Errorf("%s的监听套接字无法传递", name)
+		}
+		f, err := ln.File()
+		if err != nil {
+			for _, opened := range files {
+				opened[0].Close()
+			}
+			return nil, fmt.Errorf("复制%s的监听套接字失败: %w", name, err)
+		}
+		files[name] = []*os.File{f}
+	}
+	return files, nil
+}
+
 // Shutdown 优雅关闭API服务
 func (h *EchoHandler) Shutdown(ctx context.Context) error {
 	h.logger.Info("正在关闭API服务...")
 
+	if h.eurekaDeltas != nil {
+		h.eurekaDeltas.stop()
+	}
+
 	// 关闭管理API服务
 	if h.managementServer != nil {
 		if err := h.managementServer.Shutdown(ctx); err != nil {
@@ -122,51 +218,273 @@ func (h *EchoHandler) Shutdown(ctx context.Context) error {
 	return nil
 }
 
+// storageState 返回健康检查中的存储状态：存储不可用而使用本地快照时为offline
+func (h *EchoHandler) storageState() string {
+	if h.etcdClient.Offline() {
+		return "offline"
+	}
+	return "online"
+}
+
 // registerManagementRoutes 注册管理API路由
 func (h *EchoHandler) registerManagementRoutes() {
+	// 框架层面的错误同样使用统一的错误结构
+	h.managementServer.HTTPErrorHandler = httpErrorHandler
+	h.managementServer.Validator = &requestValidator{}
+
 	// 健康检查端点
 	h.managementServer.GET("/health", func(c echo.Context) error {
 		return c.JSON(http.StatusOK, map[string]string{
 			"status":    "ok",
 			"timestamp": time.Now().Format(time.RFC3339),
 			"service":   "kong-discovery-management-api",
+			"storage":   h.storageState(),
 		})
 	})
 
+	// 版本信息端点
+	h.managementServer.GET("/version", h.versionHandler)
+
+	// 调试端点，开启api.management.debug时才注册
+	h.registerDebugRoutes()
+
+	// 其余端点使用/v1前缀，未关闭旧路径时无版本的路径作为弃用的别名继续可用
+	// 列表端点使用listResponse，支持字段选择、MessagePack/protobuf编码和gzip压缩
+	routes := h.versionedRoutes(h.managementServer)
+
+	// 上游DNS健康状态端点
+	routes.GET("/admin/dns/upstreams", h.upstreamStatusHandler)
+
+	// 解析调试端点
+	routes.GET("/admin/dns/resolve", h.resolveHandler)
+
+	// 按负载调整权重的运行时开关端点
+	routes.GET("/admin/dns/load-weights", h.getLoadWeightsHandler)
+	routes.PUT("/admin/dns/load-weights", h.putLoadWeightsHandler)
+
+	// 查询统计端点
+	routes.GET("/admin/stats/dns", h.dnsStatsHandler)
+	routes.GET("/admin/stats/watch", h.watchStatsHandler)
+
+	// 故障注入端点，开启chaos.enabled时才注册
+	if h.cfg.Chaos.Enabled {
+		routes.GET("/admin/chaos", h.getChaosHandler)
+		routes.PUT("/admin/chaos", h.putChaosHandler)
+		routes.DELETE("/admin/chaos", h.deleteChaosHandler)
+	}
+
+	// 日志级别端点
+	routes.GET("/admin/log/levels", h.getLogLevelsHandler)
+	routes.PUT("/admin/log/levels", h.putLogLevelHandler)
+
+	// 上游DNS配置端点
+	routes.GET("/admin/config/upstream-dns", h.getUpstreamConfigHandler)
+	routes.PUT("/admin/config/upstream-dns", h.putUpstreamConfigHandler)
+	routes.GET("/admin/config/upstream-dns/status", h.upstreamStatusHandler)
+	routes.POST("/admin/config/upstream-dns/:addr/disable", h.disableUpstreamHandler)
+	routes.POST("/admin/config/upstream-dns/:addr/enable", h.enableUpstreamHandler)
+
+	// 条件转发规则端点
+	routes.GET("/admin/config/forward-rules", h.listForwardRulesHandler)
+	routes.PUT("/admin/config/forward-rules", h.putForwardRuleHandler)
+	routes.DELETE("/admin/config/forward-rules/:suffix", h.deleteForwardRuleHandler)
+
+	// 服务列表端点
+	routes.GET("/admin/services", h.listServicesHandler, h.listResponse)
+	routes.GET("/admin/services/:serviceName", h.listServiceInstancesHandler, h.listResponse)
+	routes.DELETE("/admin/services/:serviceName/:instanceId", h.adminDeregisterHandler)
+
+	// 强制驱逐的冷却期端点
+	routes.GET("/admin/evictions", h.listEvictionsHandler, h.listResponse)
+	routes.DELETE("/admin/evictions/:serviceName/:instanceId", h.deleteEvictionHandler)
+
+	// DNS记录端点
+	routes.GET("/admin/dns/records", h.listDNSRecordsHandler, h.listResponse)
+	routes.PUT("/admin/dns/records/:domain", h.putDNSRecordHandler)
+	routes.GET("/admin/dns/records/:domain/:type", h.getDNSRecordHandler)
+	routes.POST("/admin/dns/records/batch", h.batchDNSRecordsHandler)
+	routes.DELETE("/admin/dns/records/:domain/:type", h.deleteDNSRecordHandler)
+
+	// DNS域名前缀搜索与分页
+	routes.GET("/admin/dns/domains", h.listDNSDomainsHandler, h.listResponse)
+
+	// 定时DNS变更
+	routes.GET("/admin/dns/scheduled", h.listScheduledChangesHandler, h.listResponse)
+	routes.POST("/admin/dns/scheduled", h.createScheduledChangeHandler)
+	routes.GET("/admin/dns/scheduled/:id", h.getScheduledChangeHandler)
+	routes.DELETE("/admin/dns/scheduled/:id", h.cancelScheduledChangeHandler)
+
+	// 变更事件流端点
+	routes.GET("/admin/events", h.eventsHandler)
+
+	// 维护模式端点
+	routes.PUT("/admin/services/:serviceName/maintenance", h.setServiceMaintenanceHandler)
+	routes.PUT("/admin/services/:serviceName/:instanceId/maintenance", h.setInstanceMaintenanceHandler)
+
+	// 服务注册策略端点
+	routes.GET("/admin/services/:serviceName/registration-policy", h.getRegistrationPolicyHandler)
+	routes.PUT("/admin/services/:serviceName/registration-policy", h.putRegistrationPolicyHandler)
+	routes.DELETE("/admin/services/:serviceName/registration-policy", h.deleteRegistrationPolicyHandler)
+	routes.GET("/admin/services/:serviceName/acl", h.getServiceACLHandler)
+	routes.PUT("/admin/services/:serviceName/acl", h.putServiceACLHandler)
+	routes.DELETE("/admin/services/:serviceName/acl", h.deleteServiceACLHandler)
+	routes.GET("/admin/services/:serviceName/answer-policy", h.getServiceAnswerPolicyHandler)
+	routes.PUT("/admin/services/:serviceName/answer-policy", h.putServiceAnswerPolicyHandler)
+	routes.DELETE("/admin/services/:serviceName/answer-policy", h.deleteServiceAnswerPolicyHandler)
+	routes.GET("/admin/services/:serviceName/ttl-policy", h.getServiceTTLPolicyHandler)
+	routes.PUT("/admin/services/:serviceName/ttl-policy", h.putServiceTTLPolicyHandler)
+	routes.DELETE("/admin/services/:serviceName/ttl-policy", h.deleteServiceTTLPolicyHandler)
+	routes.GET("/admin/aliases", h.listServiceAliasesHandler, h.listResponse)
+	routes.GET("/admin/services/:serviceName/alias", h.getServiceAliasHandler)
+	routes.PUT("/admin/services/:serviceName/alias", h.putServiceAliasHandler)
+	routes.DELETE("/admin/services/:serviceName/alias", h.deleteServiceAliasHandler)
+
+	// 服务目录端点
+	routes.GET("/admin/catalog", h.listServiceCatalogsHandler, h.listResponse)
+	routes.GET("/admin/catalog/:serviceName", h.getServiceCatalogHandler)
+	routes.PUT("/admin/catalog/:serviceName", h.putServiceCatalogHandler)
+	routes.DELETE("/admin/catalog/:serviceName", h.deleteServiceCatalogHandler)
+
+	// 命名空间端点
+	routes.GET("/admin/namespaces", h.listNamespacesHandler, h.listResponse)
+	routes.POST("/admin/namespaces", h.createNamespaceHandler)
+	routes.GET("/admin/namespaces/:namespace", h.getNamespaceHandler)
+	routes.DELETE("/admin/namespaces/:namespace", h.deleteNamespaceHandler)
+
+	// 命名空间配额端点
+	routes.GET("/admin/namespaces/:namespace/quota", h.getNamespaceQuotaHandler)
+	routes.PUT("/admin/namespaces/:namespace/quota", h.putNamespaceQuotaHandler)
+	routes.GET("/admin/namespaces/:namespace/txt-metadata", h.getNamespaceTXTMetadataHandler)
+	routes.PUT("/admin/namespaces/:namespace/txt-metadata", h.putNamespaceTXTMetadataHandler)
+
+	// 命名空间覆盖的上游DNS端点
+	routes.GET("/admin/namespaces/:namespace/upstream", h.getNamespaceUpstreamHandler)
+	routes.PUT("/admin/namespaces/:namespace/upstream", h.putNamespaceUpstreamHandler)
+	routes.DELETE("/admin/namespaces/:namespace/upstream", h.deleteNamespaceUpstreamHandler)
+	routes.GET("/admin/namespaces/:namespace/ttl-policy", h.getNamespaceTTLPolicyHandler)
+	routes.PUT("/admin/namespaces/:namespace/ttl-policy", h.putNamespaceTTLPolicyHandler)
+	routes.DELETE("/admin/namespaces/:namespace/ttl-policy", h.deleteNamespaceTTLPolicyHandler)
+
+	// 命名空间自定义区域端点
+	routes.GET("/admin/zones", h.listZonesHandler, h.listResponse)
+	routes.GET("/admin/namespaces/:namespace/zones", h.listZonesHandler, h.listResponse)
+	routes.PUT("/admin/namespaces/:namespace/zones/:zone", h.putZoneHandler)
+	routes.DELETE("/admin/namespaces/:namespace/zones/:zone", h.deleteZoneHandler)
+
+	// webhook端点
+	routes.GET("/admin/webhooks", h.listWebhooksHandler, h.listResponse)
+	routes.POST("/admin/webhooks", h.createWebhookHandler)
+	routes.GET("/admin/webhooks/:id", h.getWebhookHandler)
+	routes.PUT("/admin/webhooks/:id", h.updateWebhookHandler)
+	routes.DELETE("/admin/webhooks/:id", h.deleteWebhookHandler)
+	routes.GET("/admin/webhooks/:id/deliveries", h.listWebhookDeliveriesHandler, h.listResponse)
+
+	// 响应策略（拦截列表）端点
+	routes.GET("/admin/dns/blocklist", h.listBlockRulesHandler, h.listResponse)
+	routes.PUT("/admin/dns/blocklist", h.putBlockRuleHandler)
+	routes.DELETE("/admin/dns/blocklist/:id", h.deleteBlockRuleHandler)
+
+	// 数据一致性检查端点
+	routes.GET("/admin/reconcile/report", h.reconcileReportHandler)
+	routes.POST("/admin/reconcile", h.runReconcileHandler)
+	routes.GET("/admin/reconcile/stats", h.reconcileStatsHandler)
+
+	// 备份与恢复端点
+	routes.GET("/admin/backup", h.backupHandler)
+	routes.POST("/admin/restore", h.restoreHandler)
+
+	// 多集群联邦端点
+	routes.GET("/admin/federation/catalog", h.federationCatalogHandler)
+	routes.GET("/admin/federation/clusters", h.listFederationClustersHandler, h.listResponse)
+	routes.POST("/admin/federation/clusters/:cluster/catalog", h.pushCatalogHandler)
+	routes.DELETE("/admin/federation/clusters/:cluster", h.deleteFederationClusterHandler)
+
+	// 集群节点端点
+	routes.GET("/admin/cluster/peers", h.clusterPeersHandler)
+
 	// 管理API的其他端点将在后续任务中添加
 }
 
 // registerRegistrationRoutes 注册服务注册API路由
 func (h *EchoHandler) registerRegistrationRoutes() {
+	// 框架层面的错误同样使用统一的错误结构
+	h.registrationServer.HTTPErrorHandler = httpErrorHandler
+	h.registrationServer.Validator = &requestValidator{}
+
+	// 拒绝低于最低版本的SDK
+	if minVersion := h.minSDKVersion(); minVersion != "" {
+		if err := version.Validate(minVersion); err != nil {
+			h.logger.Error("最低SDK版本配置无效，不限制SDK版本", zap.String("min_sdk_version", minVersion), zap.Error(err))
+		} else {
+			h.registrationServer.Use(h.sdkVersionMiddleware(minVersion))
+		}
+	}
+
 	// 健康检查端点
 	h.registrationServer.GET("/health", func(c echo.Context) error {
 		return c.JSON(http.StatusOK, map[string]string{
 			"status":    "ok",
 			"timestamp": time.Now().Format(time.RFC3339),
 			"service":   "kong-discovery-registration-api",
+			"storage":   h.storageState(),
 		})
 	})
 
+	// 版本信息端点
+	h.registrationServer.GET("/version", h.versionHandler)
+
+	// 其余端点使用/v1前缀，未关闭旧路径时无版本的路径作为弃用的别名继续可用
+	routes := h.versionedRoutes(h.registrationServer)
+
 	// 服务注册端点
-	h.registrationServer.POST("/services/register", h.registerServiceHandler)
+	routes.POST("/services/register", h.registerServiceHandler)
 
 	// 服务注销端点
-	h.registrationServer.DELETE("/services/:serviceName/:instanceId", h.deregisterServiceHandler)
+	routes.DELETE("/services/:serviceName/:instanceId", h.deregisterServiceHandler)
 
 	// 服务心跳端点
-	h.registrationServer.PUT("/services/heartbeat/:serviceName/:instanceId", h.heartbeatServiceHandler)
+	routes.PUT("/services/heartbeat/:serviceName/:instanceId", h.heartbeatServiceHandler)
+
+	// 服务实例健康状态端点
+	routes.PUT("/services/:serviceName/:instanceId/health", h.setInstanceHealthHandler)
+
+	// 外部检查器推送检查结果端点
+	routes.PUT("/services/:serviceName/:instanceId/check-result", h.checkResultHandler)
+
+	// 服务实例列表端点
+	routes.GET("/services/:serviceName", h.listServiceInstancesHandler)
+
+	// 服务实例维护模式端点
+	routes.PUT("/services/:serviceName/:instanceId/maintenance", h.setInstanceMaintenanceHandler)
+
+	// 面向服务消费方的发现端点，支持长轮询
+	h.registrationServer.GET("/v1/discovery/:namespace/:service", h.discoveryHandler)
+
+	// Consul兼容端点
+	if h.cfg != nil && h.cfg.API.Consul.Enabled {
+		h.registerConsulRoutes()
+	}
+
+	// Eureka兼容端点
+	if h.cfg != nil && h.cfg.API.Eureka.Enabled {
+		h.registerEurekaRoutes()
+	}
 
 	// 服务注册API的其他端点将在后续任务中添加
 }
 
 // ServiceRegistrationRequest 定义服务注册请求结构
 type ServiceRegistrationRequest struct {
-	ServiceName string            `json:"service_name" validate:"required"` // 服务名称
-	InstanceID  string            `json:"instance_id" validate:"required"`  // 实例ID
-	IPAddress   string            `json:"ip_address" validate:"required"`   // IP地址
-	Port        int               `json:"port" validate:"required"`         // 端口
-	TTL         int               `json:"ttl" validate:"required"`          // 租约TTL（秒）
-	Metadata    map[string]string `json:"metadata,omitempty"`               // 可选元数据
+	ServiceName string                 `json:"service_name" validate:"required"`  // 服务名称
+	InstanceID  string                 `json:"instance_id,omitempty"`             // 可选实例ID，为空时由服务端生成UUID并在响应中返回
+	IPAddress   string                 `json:"ip_address" validate:"required,ip"` // IP地址
+	Port        int                    `json:"port" validate:"required,port"`     // 端口
+	Ports       []etcdclient.NamedPort `json:"ports,omitempty"`                   // 可选命名端口列表，如http、grpc、metrics，每个端口生成 _<名称>._<协议> 的SRV记录
+	TTL         int                    `json:"ttl" validate:"ttl"`                // 租约TTL（秒），为0时使用默认值60
+	DNSTTL      int                    `json:"dns_ttl,omitempty" validate:"ttl"`  // 可选DNS应答TTL（秒）
+	Health      string                 `json:"health,omitempty"`                  // 可选初始健康状态，默认passing
+	Namespace   string                 `json:"namespace,omitempty"`               // 可选命名空间，默认default
+	Metadata    map[string]string      `json:"metadata,omitempty"`                // 可选元数据
 }
 
 // ServiceRegistrationResponse 定义服务注册响应结构
@@ -189,7 +507,8 @@ type ServiceDeregistrationResponse struct {
 
 // ServiceHeartbeatRequest 定义服务心跳请求结构
 type ServiceHeartbeatRequest struct {
-	TTL int `json:"ttl,omitempty"` // 可选的新TTL值
+	TTL    int                      `json:"ttl,omitempty" validate:"ttl"` // 可选的新TTL值
+	Health *etcdclient.HealthReport `json:"health,omitempty"`             // 可选健康信息：状态、负载指标和自定义检查，结果可以使用pass/warn/fail简写
 }
 
 // ServiceHeartbeatResponse 定义服务心跳响应结构
@@ -197,39 +516,40 @@ type ServiceHeartbeatResponse struct {
 	Success     bool   `json:"success"`           // 是否成功
 	ServiceName string `json:"service_name"`      // 服务名称
 	InstanceID  string `json:"instance_id"`       // 实例ID
+	Health      string `json:"health,omitempty"`  // 心跳后实例的健康状态
 	Message     string `json:"message,omitempty"` // 可选消息
 	Timestamp   string `json:"timestamp"`         // 时间戳
 }
 
 // registerServiceHandler 处理服务注册请求
 func (h *EchoHandler) registerServiceHandler(c echo.Context) error {
-	// 解析请求
+	// 解析并验证请求，实例ID可以省略；支持IPv4和IPv6地址，分别生成A和AAAA记录
 	req := new(ServiceRegistrationRequest)
-	if err := c.Bind(req); err != nil {
-		h.logger.Error("解析服务注册请求失败", zap.Error(err))
-		return c.JSON(http.StatusBadRequest, &ServiceRegistrationResponse{
-			Success:   false,
-			Message:   "请求格式错误: " + err.Error(),
-			Timestamp: time.Now().Format(time.RFC3339),
-		})
-	}
-
-	// 验证请求
-	if req.ServiceName == "" || req.InstanceID == "" || req.IPAddress == "" || req.Port <= 0 {
-		h.logger.Warn("服务注册请求参数无效",
+	if apiErr := bindRequest(c, req); apiErr != nil {
+		h.log(c).Warn("服务注册请求无效",
 			zap.String("service", req.ServiceName),
-			zap.String("id", req.InstanceID))
-		return c.JSON(http.StatusBadRequest, &ServiceRegistrationResponse{
-			Success:   false,
-			Message:   "请求参数无效：服务名、实例ID、IP地址和端口都是必需的",
-			Timestamp: time.Now().Format(time.RFC3339),
-		})
+			zap.String("id", req.InstanceID),
+			zap.Error(apiErr))
+		return respondError(c, apiErr)
 	}
 
 	// 设置默认TTL
-	if req.TTL <= 0 {
+	if req.TTL == 0 {
 		req.TTL = 60 // 默认60秒
 	}
+	if req.Health != "" && !etcdclient.ValidHealthStatus(req.Health) {
+		return respondError(c, badRequest(CodeInvalidHealthStatus, req.Health))
+	}
+	if apiErr := h.validateMetadata(req.Metadata); apiErr != nil {
+		h.log(c).Warn("服务注册请求的元数据无效",
+			zap.String("service", req.ServiceName),
+			zap.String("id", req.InstanceID),
+			zap.Error(apiErr))
+		return respondError(c, apiErr)
+	}
+	if err := etcdclient.ValidatePorts(req.Ports); err != nil {
+		return respondError(c, badRequest(CodeInvalidParameter, err.Error()))
+	}
 
 	// 转换为服务实例
 	instance := &etcdclient.ServiceInstance{
@@ -237,40 +557,50 @@ func (h *EchoHandler) registerServiceHandler(c echo.Context) error {
 		InstanceID:  req.InstanceID,
 		IPAddress:   req.IPAddress,
 		Port:        req.Port,
+		Ports:       req.Ports,
 		Metadata:    req.Metadata,
 		TTL:         req.TTL,
+		DNSTTL:      req.DNSTTL,
+		Health:      req.Health,
+		Namespace:   req.Namespace,
 	}
 
 	// 注册服务
 	ctx := c.Request().Context()
 	err := h.etcdClient.RegisterService(ctx, instance)
 	if err != nil {
-		h.logger.Error("注册服务实例失败",
+		h.log(c).Error("注册服务实例失败",
 			zap.String("service", req.ServiceName),
 			zap.String("id", req.InstanceID),
 			zap.Error(err))
-		return c.JSON(http.StatusInternalServerError, &ServiceRegistrationResponse{
-			Success:     false,
-			ServiceName: req.ServiceName,
-			InstanceID:  req.InstanceID,
-			Message:     "注册服务失败: " + err.Error(),
-			Timestamp:   time.Now().Format(time.RFC3339),
-		})
+		return respondError(c, storageError(fmt.Errorf("注册服务失败: %w", err)))
 	}
 
-	// 返回成功响应
-	h.logger.Info("服务注册成功",
+	// 返回成功响应，实例ID可能由服务端生成
+	h.log(c).Info("服务注册成功",
 		zap.String("service", req.ServiceName),
-		zap.String("id", req.InstanceID))
+		zap.String("id", instance.InstanceID))
 	return c.JSON(http.StatusOK, &ServiceRegistrationResponse{
 		Success:     true,
 		ServiceName: req.ServiceName,
-		InstanceID:  req.InstanceID,
+		InstanceID:  instance.InstanceID,
 		Message:     "服务注册成功",
 		Timestamp:   time.Now().Format(time.RFC3339),
 	})
 }
 
+// validateMetadata 按配置的限制校验客户端提交的实例元数据
+func (h *EchoHandler) validateMetadata(metadata map[string]string) *APIError {
+	var limits config.MetadataLimits
+	if h.cfg != nil {
+		limits = h.cfg.API.Registration.Metadata
+	}
+	if err := etcdclient.ValidateMetadata(metadata, limits); err != nil {
+		return badRequest(CodeInvalidMetadata, err.Error())
+	}
+	return nil
+}
+
 // deregisterServiceHandler 处理服务注销请求
 func (h *EchoHandler) deregisterServiceHandler(c echo.Context) error {
 	// 从URL参数中获取服务名和实例ID
@@ -279,35 +609,25 @@ func (h *EchoHandler) deregisterServiceHandler(c echo.Context) error {
 
 	// 验证参数
 	if serviceName == "" || instanceID == "" {
-		h.logger.Warn("服务注销请求参数无效",
+		h.log(c).Warn("服务注销请求参数无效",
 			zap.String("service", serviceName),
 			zap.String("id", instanceID))
-		return c.JSON(http.StatusBadRequest, &ServiceDeregistrationResponse{
-			Success:   false,
-			Message:   "请求参数无效：服务名和实例ID都是必需的",
-			Timestamp: time.Now().Format(time.RFC3339),
-		})
+		return respondError(c, badRequest(CodeInvalidParameter, "服务名和实例ID都是必需的"))
 	}
 
 	// 从etcd中注销服务
 	ctx := c.Request().Context()
 	err := h.etcdClient.DeregisterService(ctx, serviceName, instanceID)
 	if err != nil {
-		h.logger.Error("注销服务实例失败",
+		h.log(c).Error("注销服务实例失败",
 			zap.String("service", serviceName),
 			zap.String("id", instanceID),
 			zap.Error(err))
-		return c.JSON(http.StatusInternalServerError, &ServiceDeregistrationResponse{
-			Success:     false,
-			ServiceName: serviceName,
-			InstanceID:  instanceID,
-			Message:     "注销服务失败: " + err.Error(),
-			Timestamp:   time.Now().Format(time.RFC3339),
-		})
+		return respondError(c, storageError(fmt.Errorf("注销服务失败: %w", err)))
 	}
 
 	// 返回成功响应
-	h.logger.Info("服务注销成功",
+	h.log(c).Info("服务注销成功",
 		zap.String("service", serviceName),
 		zap.String("id", instanceID))
 	return c.JSON(http.StatusOK, &ServiceDeregistrationResponse{
@@ -327,49 +647,1143 @@ func (h *EchoHandler) heartbeatServiceHandler(c echo.Context) error {
 
 	// 验证参数
 	if serviceName == "" || instanceID == "" {
-		h.logger.Warn("服务心跳请求参数无效",
+		h.log(c).Warn("服务心跳请求参数无效",
 			zap.String("service", serviceName),
 			zap.String("id", instanceID))
-		return c.JSON(http.StatusBadRequest, &ServiceHeartbeatResponse{
-			Success:   false,
-			Message:   "请求参数无效：服务名和实例ID都是必需的",
-			Timestamp: time.Now().Format(time.RFC3339),
-		})
+		return respondError(c, badRequest(CodeInvalidParameter, "服务名和实例ID都是必需的"))
 	}
 
-	// 解析请求体中的TTL（如果有）
+	// 解析请求体中的TTL和健康信息，请求体为空表示不修改TTL也不上报健康信息，
+	// 请求体无法解析时返回400，不能忽略客户端上报的健康信息
 	var req ServiceHeartbeatRequest
-	var ttl int
-	if err := c.Bind(&req); err == nil && req.TTL > 0 {
-		ttl = req.TTL
+	if apiErr := bindRequest(c, &req); apiErr != nil {
+		h.log(c).Warn("服务心跳请求无效",
+			zap.String("service", serviceName),
+			zap.String("id", instanceID),
+			zap.Error(apiErr))
+		return respondError(c, apiErr)
+	}
+	ttl, report := req.TTL, req.Health
+	if report != nil {
+		normalizeHealthReport(report)
+		if err := report.Validate(); err != nil {
+			return respondError(c, badRequest(CodeInvalidHealthStatus, err.Error()))
+		}
 	}
 
-	// 刷新服务实例的租约
+	// 刷新服务实例的租约，携带健康信息时一并保存
 	ctx := c.Request().Context()
-	err := h.etcdClient.RefreshServiceLease(ctx, serviceName, instanceID, ttl)
+	instance, err := h.etcdClient.RefreshServiceLeaseWithReport(ctx, serviceName, instanceID, ttl, report)
 	if err != nil {
-		h.logger.Error("刷新服务实例租约失败",
+		h.log(c).Error("刷新服务实例租约失败",
 			zap.String("service", serviceName),
 			zap.String("id", instanceID),
 			zap.Error(err))
-		return c.JSON(http.StatusInternalServerError, &ServiceHeartbeatResponse{
-			Success:     false,
-			ServiceName: serviceName,
-			InstanceID:  instanceID,
-			Message:     "刷新服务租约失败: " + err.Error(),
-			Timestamp:   time.Now().Format(time.RFC3339),
-		})
+		return respondError(c, storageError(fmt.Errorf("刷新服务租约失败: %w", err)))
 	}
 
 	// 返回成功响应
-	h.logger.Info("服务心跳成功",
+	h.log(c).Info("服务心跳成功",
 		zap.String("service", serviceName),
 		zap.String("id", instanceID))
 	return c.JSON(http.StatusOK, &ServiceHeartbeatResponse{
 		Success:     true,
 		ServiceName: serviceName,
 		InstanceID:  instanceID,
+		Health:      instance.HealthStatus(),
 		Message:     "服务租约刷新成功",
 		Timestamp:   time.Now().Format(time.RFC3339),
 	})
 }
+
+// InstanceHealthRequest 定义服务实例健康状态更新请求结构
+type InstanceHealthRequest struct {
+	Status string `json:"status" validate:"required"` // 健康状态 (passing, warning, critical, draining)
+}
+
+// InstanceHealthResponse 定义服务实例健康状态更新响应结构
+type InstanceHealthResponse struct {
+	Success     bool   `json:"success"`           // 是否成功
+	ServiceName string `json:"service_name"`      // 服务名称
+	InstanceID  string `json:"instance_id"`       // 实例ID
+	Health      string `json:"health,omitempty"`  // 更新后的健康状态
+	Message     string `json:"message,omitempty"` // 可选消息
+	Timestamp   string `json:"timestamp"`         // 时间戳
+}
+
+// setInstanceHealthHandler 处理服务实例健康状态更新请求
+func (h *EchoHandler) setInstanceHealthHandler(c echo.Context) error {
+	serviceName := c.Param("serviceName")
+	instanceID := c.Param("instanceId")
+
+	req := new(InstanceHealthRequest)
+	if apiErr := bindRequest(c, req); apiErr != nil {
+		h.log(c).Warn("健康状态请求无效", zap.Error(apiErr))
+		return respondError(c, apiErr)
+	}
+
+	if !etcdclient.ValidHealthStatus(req.Status) {
+		return respondError(c, badRequest(CodeInvalidHealthStatus, req.Status))
+	}
+
+	ctx := c.Request().Context()
+	instance, err := h.etcdClient.SetInstanceHealth(ctx, serviceName, instanceID, req.Status)
+	if err != nil {
+		h.log(c).Error("更新服务实例健康状态失败",
+			zap.String("service", serviceName),
+			zap.String("id", instanceID),
+			zap.Error(err))
+		return respondError(c, storageError(fmt.Errorf("更新健康状态失败: %w", err)))
+	}
+
+	return c.JSON(http.StatusOK, &InstanceHealthResponse{
+		Success:     true,
+		ServiceName: serviceName,
+		InstanceID:  instanceID,
+		Health:      instance.Health,
+		Message:     "健康状态更新成功",
+		Timestamp:   time.Now().Format(time.RFC3339),
+	})
+}
+
+// CheckResultRequest 定义外部检查结果推送请求结构
+type CheckResultRequest struct {
+	Status  string `json:"status" validate:"required"`   // 检查结果：pass/passing、warn/warning或fail/critical
+	Output  string `json:"output,omitempty"`             // 可选检查输出
+	Checker string `json:"checker,omitempty"`            // 可选检查器名称
+	TTL     int    `json:"ttl,omitempty" validate:"ttl"` // 结果有效期（秒），超过后未收到新结果时实例视为critical，0表示不过期
+}
+
+// checkStatusAliases 检查器常用的简写结果
+var checkStatusAliases = map[string]string{
+	"pass": etcdclient.HealthPassing,
+	"warn": etcdclient.HealthWarning,
+	"fail": etcdclient.HealthCritical,
+}
+
+// normalizeHealthReport 把心跳健康信息中的pass/warn/fail简写转换为健康状态
+func normalizeHealthReport(report *etcdclient.HealthReport) {
+	normalize := func(status string) string {
+		status = strings.ToLower(status)
+		if alias, ok := checkStatusAliases[status]; ok {
+			return alias
+		}
+		return status
+	}
+	if report.Status != "" {
+		report.Status = normalize(report.Status)
+	}
+	for i := range report.Checks {
+		report.Checks[i].Status = normalize(report.Checks[i].Status)
+	}
+}
+
+// checkResultHandler 接收外部检查器推送的检查结果，结果与健康状态端点共用同一个状态，决定实例是否参与DNS应答
+func (h *EchoHandler) checkResultHandler(c echo.Context) error {
+	serviceName := c.Param("serviceName")
+	instanceID := c.Param("instanceId")
+
+	req := new(CheckResultRequest)
+	if apiErr := bindRequest(c, req); apiErr != nil {
+		return respondError(c, apiErr)
+	}
+
+	status := strings.ToLower(req.Status)
+	if alias, ok := checkStatusAliases[status]; ok {
+		status = alias
+	}
+	if !etcdclient.ValidCheckStatus(status) {
+		return respondError(c, badRequest(CodeInvalidHealthStatus, req.Status))
+	}
+
+	result := &etcdclient.CheckResult{
+		Status:  status,
+		Output:  req.Output,
+		Checker: req.Checker,
+		TTL:     req.TTL,
+	}
+	instance, err := h.etcdClient.SetInstanceCheckResult(c.Request().Context(), serviceName, instanceID, result)
+	if err != nil {
+		h.log(c).Error("保存检查结果失败",
+			zap.String("service", serviceName),
+			zap.String("id", instanceID),
+			zap.Error(err))
+		return respondError(c, storageError(fmt.Errorf("保存检查结果失败: %w", err)))
+	}
+
+	return c.JSON(http.StatusOK, &InstanceHealthResponse{
+		Success:     true,
+		ServiceName: serviceName,
+		InstanceID:  instanceID,
+		Health:      instance.HealthStatus(),
+		Message:     "检查结果已记录",
+		Timestamp:   time.Now().Format(time.RFC3339),
+	})
+}
+
+// ServiceListResponse 定义服务列表响应结构
+type ServiceListResponse struct {
+	Success   bool                         `json:"success"`           // 是否成功
+	Services  []*etcdclient.ServiceSummary `json:"services"`          // 服务列表
+	Index     int64                        `json:"index,omitempty"`   // 读取时的etcd修订版本，用于阻塞查询
+	Message   string                       `json:"message,omitempty"` // 可选消息
+	Timestamp string                       `json:"timestamp"`         // 时间戳
+}
+
+// listServicesHandler 列出所有已注册的服务，可通过namespace参数过滤
+// 携带index和wait参数时进行阻塞查询，任意服务在index之后发生变更才返回
+func (h *EchoHandler) listServicesHandler(c echo.Context) error {
+	namespace := c.QueryParam("namespace")
+
+	proceed, apiErr := h.waitForChange(c, h.etcdClient.WaitServicesChange)
+	if apiErr != nil {
+		return respondError(c, apiErr)
+	}
+	if !proceed {
+		return nil
+	}
+
+	// 在读取之前获取修订版本，读取期间的变更会在下一次阻塞查询中立即返回
+	index, err := h.etcdClient.Revision(c.Request().Context())
+	if err != nil {
+		return respondError(c, storageError(fmt.Errorf("获取服务列表失败: %w", err)))
+	}
+
+	services, err := h.etcdClient.ListServices(c.Request().Context())
+	if err != nil {
+		h.log(c).Error("获取服务列表失败", zap.Error(err))
+		return respondError(c, storageError(fmt.Errorf("获取服务列表失败: %w", err)))
+	}
+
+	result := make([]*etcdclient.ServiceSummary, 0, len(services))
+	for _, service := range services {
+		if namespace == "" || service.Namespace == namespace {
+			result = append(result, service)
+		}
+	}
+
+	setIndexHeader(c, index)
+	return c.JSON(http.StatusOK, &ServiceListResponse{
+		Success:   true,
+		Services:  result,
+		Index:     index,
+		Timestamp: time.Now().Format(time.RFC3339),
+	})
+}
+
+// ServiceInstancesResponse 定义服务实例列表响应结构
+type ServiceInstancesResponse struct {
+	Success     bool                          `json:"success"`               // 是否成功
+	ServiceName string                        `json:"service_name"`          // 服务名称
+	Instances   []*etcdclient.ServiceInstance `json:"instances"`             // 服务实例列表
+	Maintenance *etcdclient.MaintenanceInfo   `json:"maintenance,omitempty"` // 服务级维护状态
+	Catalog     *etcdclient.ServiceCatalog    `json:"catalog,omitempty"`     // 服务目录信息，未设置时为空
+	Index       int64                         `json:"index,omitempty"`       // 读取时的etcd修订版本，用于阻塞查询
+	Message     string                        `json:"message,omitempty"`     // 可选消息
+	Timestamp   string                        `json:"timestamp"`             // 时间戳
+}
+
+// listServiceInstancesHandler 列出服务的所有实例及其健康状态，可通过health参数过滤
+// 携带index和wait参数时进行阻塞查询，服务在index之后发生变更才返回
+func (h *EchoHandler) listServiceInstancesHandler(c echo.Context) error {
+	serviceName := c.Param("serviceName")
+	health := c.QueryParam("health")
+
+	if health != "" && !etcdclient.ValidHealthStatus(health) {
+		return respondError(c, badRequest(CodeInvalidHealthStatus, health))
+	}
+
+	proceed, apiErr := h.waitForChange(c, func(ctx context.Context, index int64) error {
+		return h.etcdClient.WaitServiceChange(ctx, serviceName, index)
+	})
+	if apiErr != nil {
+		return respondError(c, apiErr)
+	}
+	if !proceed {
+		return nil
+	}
+
+	ctx := c.Request().Context()
+	index, err := h.etcdClient.Revision(ctx)
+	if err != nil {
+		return respondError(c, storageError(fmt.Errorf("获取服务实例列表失败: %w", err)))
+	}
+
+	instances, err := h.etcdClient.GetServiceInstances(ctx, serviceName)
+	if err != nil {
+		h.log(c).Error("获取服务实例列表失败", zap.String("service", serviceName), zap.Error(err))
+		return respondError(c, storageError(fmt.Errorf("获取服务实例列表失败: %w", err)))
+	}
+
+	maintenance, err := h.etcdClient.GetServiceMaintenance(ctx, serviceName)
+	if err != nil {
+		h.log(c).Warn("获取服务维护状态失败", zap.String("service", serviceName), zap.Error(err))
+	}
+	catalog, err := h.etcdClient.GetServiceCatalog(ctx, serviceName)
+	if err != nil {
+		h.log(c).Warn("获取服务目录失败", zap.String("service", serviceName), zap.Error(err))
+	}
+
+	// 服务不存在时同样返回index，客户端可以阻塞等待服务上线
+	setIndexHeader(c, index)
+
+	// 没有任何实例且没有维护标记时视为服务不存在
+	if len(instances) == 0 && maintenance == nil {
+		return respondError(c, newAPIError(http.StatusNotFound, CodeServiceNotFound, serviceName))
+	}
+
+	result := make([]*etcdclient.ServiceInstance, 0, len(instances))
+	for _, instance := range instances {
+		// 旧数据没有健康状态字段，统一展示为passing
+		instance.Health = instance.HealthStatus()
+		if health == "" || instance.Health == health {
+			result = append(result, instance)
+		}
+	}
+
+	return c.JSON(http.StatusOK, &ServiceInstancesResponse{
+		Success:     true,
+		ServiceName: serviceName,
+		Instances:   result,
+		Maintenance: maintenance,
+		Catalog:     catalog,
+		Index:       index,
+		Timestamp:   time.Now().Format(time.RFC3339),
+	})
+}
+
+// MaintenanceRequest 定义维护模式切换请求结构
+type MaintenanceRequest struct {
+	Enabled bool   `json:"enabled"`          // 是否开启维护模式
+	Reason  string `json:"reason,omitempty"` // 可选维护原因
+}
+
+// MaintenanceResponse 定义维护模式切换响应结构
+type MaintenanceResponse struct {
+	Success     bool   `json:"success"`               // 是否成功
+	ServiceName string `json:"service_name"`          // 服务名称
+	InstanceID  string `json:"instance_id,omitempty"` // 实例ID，服务级维护时为空
+	Enabled     bool   `json:"enabled"`               // 当前是否处于维护模式
+	Message     string `json:"message,omitempty"`     // 可选消息
+	Timestamp   string `json:"timestamp"`             // 时间戳
+}
+
+// setServiceMaintenanceHandler 切换整个服务的维护模式
+func (h *EchoHandler) setServiceMaintenanceHandler(c echo.Context) error {
+	serviceName := c.Param("serviceName")
+
+	req := new(MaintenanceRequest)
+	if apiErr := bindRequest(c, req); apiErr != nil {
+		return respondError(c, apiErr)
+	}
+
+	ctx := c.Request().Context()
+	if err := h.etcdClient.SetServiceMaintenance(ctx, serviceName, req.Enabled, req.Reason); err != nil {
+		h.log(c).Error("切换服务维护模式失败", zap.String("service", serviceName), zap.Error(err))
+		return respondError(c, storageError(fmt.Errorf("切换维护模式失败: %w", err)))
+	}
+
+	return c.JSON(http.StatusOK, &MaintenanceResponse{
+		Success:     true,
+		ServiceName: serviceName,
+		Enabled:     req.Enabled,
+		Message:     "维护模式已更新",
+		Timestamp:   time.Now().Format(time.RFC3339),
+	})
+}
+
+// setInstanceMaintenanceHandler 切换单个实例的维护模式
+func (h *EchoHandler) setInstanceMaintenanceHandler(c echo.Context) error {
+	serviceName := c.Param("serviceName")
+	instanceID := c.Param("instanceId")
+
+	req := new(MaintenanceRequest)
+	if apiErr := bindRequest(c, req); apiErr != nil {
+		return respondError(c, apiErr)
+	}
+
+	ctx := c.Request().Context()
+	instance, err := h.etcdClient.SetInstanceMaintenance(ctx, serviceName, instanceID, req.Enabled)
+	if err != nil {
+		h.log(c).Error("切换实例维护模式失败",
+			zap.String("service", serviceName),
+			zap.String("id", instanceID),
+			zap.Error(err))
+		return respondError(c, storageError(fmt.Errorf("切换维护模式失败: %w", err)))
+	}
+
+	return c.JSON(http.StatusOK, &MaintenanceResponse{
+		Success:     true,
+		ServiceName: serviceName,
+		InstanceID:  instanceID,
+		Enabled:     instance.Maintenance,
+		Message:     "维护模式已更新",
+		Timestamp:   time.Now().Format(time.RFC3339),
+	})
+}
+
+// NamespaceResponse 定义命名空间响应结构
+type NamespaceResponse struct {
+	Success    bool                       `json:"success"`              // 是否成功
+	Namespace  *etcdclient.Namespace      `json:"namespace,omitempty"`  // 单个命名空间
+	Namespaces []*etcdclient.Namespace    `json:"namespaces,omitempty"` // 命名空间列表
+	Usage      *etcdclient.NamespaceUsage `json:"usage,omitempty"`      // 命名空间用量
+	Message    string                     `json:"message,omitempty"`    // 可选消息
+	Timestamp  string                     `json:"timestamp"`            // 时间戳
+}
+
+// listNamespacesHandler 列出所有命名空间
+func (h *EchoHandler) listNamespacesHandler(c echo.Context) error {
+	namespaces, err := h.etcdClient.ListNamespaces(c.Request().Context())
+	if err != nil {
+		h.log(c).Error("获取命名空间列表失败", zap.Error(err))
+		return respondError(c, storageError(fmt.Errorf("获取命名空间列表失败: %w", err)))
+	}
+
+	return c.JSON(http.StatusOK, &NamespaceResponse{
+		Success:    true,
+		Namespaces: namespaces,
+		Timestamp:  time.Now().Format(time.RFC3339),
+	})
+}
+
+// createNamespaceHandler 创建命名空间
+func (h *EchoHandler) createNamespaceHandler(c echo.Context) error {
+	ns := new(etcdclient.Namespace)
+	if apiErr := bindRequest(c, ns); apiErr != nil {
+		return respondError(c, apiErr)
+	}
+
+	if err := etcdclient.ValidateNamespaceName(ns.Name); err != nil {
+		return respondError(c, badRequest(CodeInvalidNamespace, err.Error()))
+	}
+
+	ns.CreatedAt = time.Time{}
+	if err := h.etcdClient.CreateNamespace(c.Request().Context(), ns); err != nil {
+		h.log(c).Error("创建命名空间失败", zap.String("namespace", ns.Name), zap.Error(err))
+		return respondError(c, storageError(fmt.Errorf("创建命名空间失败: %w", err)))
+	}
+
+	return c.JSON(http.StatusCreated, &NamespaceResponse{
+		Success:   true,
+		Namespace: ns,
+		Message:   "命名空间创建成功",
+		Timestamp: time.Now().Format(time.RFC3339),
+	})
+}
+
+// getNamespaceHandler 获取命名空间详情及用量
+func (h *EchoHandler) getNamespaceHandler(c echo.Context) error {
+	name := c.Param("namespace")
+	ctx := c.Request().Context()
+
+	ns, err := h.etcdClient.GetNamespace(ctx, name)
+	if err != nil {
+		h.log(c).Error("获取命名空间失败", zap.String("namespace", name), zap.Error(err))
+		return respondError(c, storageError(fmt.Errorf("获取命名空间失败: %w", err)))
+	}
+	if ns == nil {
+		if name != etcdclient.DefaultNamespace {
+			return respondError(c, newAPIError(http.StatusNotFound, CodeNamespaceMissing, name))
+		}
+		ns = &etcdclient.Namespace{Name: etcdclient.DefaultNamespace}
+	}
+
+	usage, err := h.etcdClient.GetNamespaceUsage(ctx, name)
+	if err != nil {
+		h.log(c).Warn("统计命名空间用量失败", zap.String("namespace", name), zap.Error(err))
+	}
+
+	return c.JSON(http.StatusOK, &NamespaceResponse{
+		Success:   true,
+		Namespace: ns,
+		Usage:     usage,
+		Timestamp: time.Now().Format(time.RFC3339),
+	})
+}
+
+// deleteNamespaceHandler 删除命名空间，cascade=true时一并删除其中的服务实例和DNS记录，dry_run=true时只报告影响范围
+func (h *EchoHandler) deleteNamespaceHandler(c echo.Context) error {
+	name := c.Param("namespace")
+	cascade := c.QueryParam("cascade") == "true"
+
+	if name == etcdclient.DefaultNamespace || name == etcdclient.SystemNamespace {
+		return respondError(c, newAPIError(http.StatusForbidden, CodeNamespaceProtected, name))
+	}
+
+	if isDryRun(c) {
+		plan, err := h.etcdClient.PlanDeleteNamespace(c.Request().Context(), name, cascade)
+		if err != nil {
+			return respondError(c, storageError(fmt.Errorf("删除命名空间失败: %w", err)))
+		}
+		return respondPlan(c, plan)
+	}
+
+	if err := h.etcdClient.DeleteNamespace(c.Request().Context(), name, cascade); err != nil {
+		h.log(c).Error("删除命名空间失败", zap.String("namespace", name), zap.Error(err))
+		return respondError(c, storageError(fmt.Errorf("删除命名空间失败: %w", err)))
+	}
+
+	return c.JSON(http.StatusOK, &NamespaceResponse{
+		Success:   true,
+		Message:   "命名空间删除成功",
+		Timestamp: time.Now().Format(time.RFC3339),
+	})
+}
+
+// NamespaceQuotaResponse 定义命名空间配额响应结构
+type NamespaceQuotaResponse struct {
+	Success   bool                       `json:"success"`           // 是否成功
+	Namespace string                     `json:"namespace"`         // 命名空间名称
+	Quota     *etcdclient.NamespaceQuota `json:"quota,omitempty"`   // 生效的配额
+	Usage     *etcdclient.NamespaceUsage `json:"usage,omitempty"`   // 当前用量
+	Message   string                     `json:"message,omitempty"` // 可选消息
+	Timestamp string                     `json:"timestamp"`         // 时间戳
+}
+
+// getNamespaceQuotaHandler 查看命名空间的配额与用量
+func (h *EchoHandler) getNamespaceQuotaHandler(c echo.Context) error {
+	namespace := c.Param("namespace")
+	ctx := c.Request().Context()
+
+	quota, err := h.etcdClient.GetNamespaceQuota(ctx, namespace)
+	if err != nil {
+		h.log(c).Error("获取命名空间配额失败", zap.String("namespace", namespace), zap.Error(err))
+		return respondError(c, storageError(fmt.Errorf("获取命名空间配额失败: %w", err)))
+	}
+
+	usage, err := h.etcdClient.GetNamespaceUsage(ctx, namespace)
+	if err != nil {
+		h.log(c).Error("统计命名空间用量失败", zap.String("namespace", namespace), zap.Error(err))
+		return respondError(c, storageError(fmt.Errorf("统计命名空间用量失败: %w", err)))
+	}
+
+	return c.JSON(http.StatusOK, &NamespaceQuotaResponse{
+		Success:   true,
+		Namespace: namespace,
+		Quota:     quota,
+		Usage:     usage,
+		Timestamp: time.Now().Format(time.RFC3339),
+	})
+}
+
+// putNamespaceQuotaHandler 设置命名空间的配额
+func (h *EchoHandler) putNamespaceQuotaHandler(c echo.Context) error {
+	namespace := c.Param("namespace")
+
+	quota := new(etcdclient.NamespaceQuota)
+	if apiErr := bindRequest(c, quota); apiErr != nil {
+		return respondError(c, apiErr)
+	}
+
+	ctx := c.Request().Context()
+	if err := h.etcdClient.PutNamespaceQuota(ctx, namespace, quota); err != nil {
+		h.log(c).Error("设置命名空间配额失败", zap.String("namespace", namespace), zap.Error(err))
+		return respondError(c, storageError(fmt.Errorf("设置命名空间配额失败: %w", err)))
+	}
+
+	return c.JSON(http.StatusOK, &NamespaceQuotaResponse{
+		Success:   true,
+		Namespace: namespace,
+		Quota:     quota,
+		Message:   "命名空间配额已更新",
+		Timestamp: time.Now().Format(time.RFC3339),
+	})
+}
+
+// NamespaceTXTMetadataRequest 定义设置命名空间TXT元数据的请求结构
+type NamespaceTXTMetadataRequest struct {
+	Keys []string `json:"keys"` // 通过服务域名的TXT查询公开的实例元数据键，空列表表示不公开
+}
+
+// NamespaceTXTMetadataResponse 定义命名空间TXT元数据响应结构
+type NamespaceTXTMetadataResponse struct {
+	Success   bool     `json:"success"`           // 是否成功
+	Namespace string   `json:"namespace"`         // 命名空间名称
+	Keys      []string `json:"keys"`              // 生效的元数据键
+	Message   string   `json:"message,omitempty"` // 可选消息
+	Timestamp string   `json:"timestamp"`         // 时间戳
+}
+
+// getNamespaceTXTMetadataHandler 查看命名空间通过TXT查询公开的元数据键
+func (h *EchoHandler) getNamespaceTXTMetadataHandler(c echo.Context) error {
+	namespace := c.Param("namespace")
+
+	keys, err := h.etcdClient.GetNamespaceTXTMetadata(c.Request().Context(), namespace)
+	if err != nil {
+		h.log(c).Error("获取命名空间TXT元数据设置失败", zap.String("namespace", namespace), zap.Error(err))
+		return respondError(c, storageError(fmt.Errorf("获取命名空间TXT元数据设置失败: %w", err)))
+	}
+	if keys == nil {
+		keys = []string{}
+	}
+
+	return c.JSON(http.StatusOK, &NamespaceTXTMetadataResponse{
+		Success:   true,
+		Namespace: namespace,
+		Keys:      keys,
+		Timestamp: time.Now().Format(time.RFC3339),
+	})
+}
+
+// putNamespaceTXTMetadataHandler 设置命名空间通过TXT查询公开的元数据键
+func (h *EchoHandler) putNamespaceTXTMetadataHandler(c echo.Context) error {
+	namespace := c.Param("namespace")
+
+	req := new(NamespaceTXTMetadataRequest)
+	if apiErr := bindRequest(c, req); apiErr != nil {
+		return respondError(c, apiErr)
+	}
+	keys := make([]string, 0, len(req.Keys))
+	for _, key := range req.Keys {
+		key = strings.TrimSpace(key)
+		if key == "" || strings.ContainsAny(key, "= ") {
+			return respondError(c, badRequest(CodeInvalidParameter, fmt.Sprintf("无效的元数据键: %q", key)))
+		}
+		keys = append(keys, key)
+	}
+
+	if err := h.etcdClient.PutNamespaceTXTMetadata(c.Request().Context(), namespace, keys); err != nil {
+		h.log(c).Error("设置命名空间TXT元数据失败", zap.String("namespace", namespace), zap.Error(err))
+		return respondError(c, storageError(fmt.Errorf("设置命名空间TXT元数据失败: %w", err)))
+	}
+
+	return c.JSON(http.StatusOK, &NamespaceTXTMetadataResponse{
+		Success:   true,
+		Namespace: namespace,
+		Keys:      keys,
+		Message:   "命名空间TXT元数据设置已更新",
+		Timestamp: time.Now().Format(time.RFC3339),
+	})
+}
+
+// NamespaceUpstreamResponse 定义命名空间上游DNS设置响应结构
+type NamespaceUpstreamResponse struct {
+	Success   bool                          `json:"success"`            // 是否成功
+	Namespace string                        `json:"namespace"`          // 命名空间名称
+	Upstream  *etcdclient.NamespaceUpstream `json:"upstream,omitempty"` // 命名空间覆盖的上游设置，为空表示使用全局配置
+	Message   string                        `json:"message,omitempty"`  // 可选消息
+	Timestamp string                        `json:"timestamp"`          // 时间戳
+}
+
+// getNamespaceUpstreamHandler 查看命名空间覆盖的上游DNS设置
+func (h *EchoHandler) getNamespaceUpstreamHandler(c echo.Context) error {
+	namespace := c.Param("namespace")
+
+	upstream, err := h.etcdClient.GetNamespaceUpstream(c.Request().Context(), namespace)
+	if err != nil {
+		h.log(c).Error("获取命名空间上游DNS设置失败", zap.String("namespace", namespace), zap.Error(err))
+		return respondError(c, storageError(fmt.Errorf("获取命名空间上游DNS设置失败: %w", err)))
+	}
+
+	resp := &NamespaceUpstreamResponse{
+		Success:   true,
+		Namespace: namespace,
+		Upstream:  upstream,
+		Timestamp: time.Now().Format(time.RFC3339),
+	}
+	if upstream == nil {
+		resp.Message = "命名空间未覆盖上游DNS设置，使用全局配置"
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// putNamespaceUpstreamHandler 设置命名空间覆盖的上游DNS设置，DNS服务器会自动热加载
+func (h *EchoHandler) putNamespaceUpstreamHandler(c echo.Context) error {
+	namespace := c.Param("namespace")
+
+	req := new(etcdclient.NamespaceUpstream)
+	if apiErr := bindRequest(c, req); apiErr != nil {
+		return respondError(c, apiErr)
+	}
+	if err := req.Validate(); err != nil {
+		return respondError(c, badRequest(CodeInvalidParameter, err.Error()))
+	}
+
+	if err := h.etcdClient.PutNamespaceUpstream(c.Request().Context(), namespace, req); err != nil {
+		h.log(c).Error("设置命名空间上游DNS失败", zap.String("namespace", namespace), zap.Error(err))
+		return respondError(c, storageError(fmt.Errorf("设置命名空间上游DNS失败: %w", err)))
+	}
+
+	return c.JSON(http.StatusOK, &NamespaceUpstreamResponse{
+		Success:   true,
+		Namespace: namespace,
+		Upstream:  req,
+		Message:   "命名空间上游DNS设置已更新",
+		Timestamp: time.Now().Format(time.RFC3339),
+	})
+}
+
+// deleteNamespaceUpstreamHandler 删除命名空间覆盖的上游DNS设置，恢复使用全局配置
+func (h *EchoHandler) deleteNamespaceUpstreamHandler(c echo.Context) error {
+	namespace := c.Param("namespace")
+
+	if err := h.etcdClient.PutNamespaceUpstream(c.Request().Context(), namespace, nil); err != nil {
+		h.log(c).Error("删除命名空间上游DNS设置失败", zap.String("namespace", namespace), zap.Error(err))
+		return respondError(c, storageError(fmt.Errorf("删除命名空间上游DNS设置失败: %w", err)))
+	}
+
+	return c.JSON(http.StatusOK, &NamespaceUpstreamResponse{
+		Success:   true,
+		Namespace: namespace,
+		Message:   "命名空间已恢复使用全局上游DNS配置",
+		Timestamp: time.Now().Format(time.RFC3339),
+	})
+}
+
+// ZoneResponse 定义命名空间自定义区域响应结构
+type ZoneResponse struct {
+	Success   bool                        `json:"success"`           // 是否成功
+	Zone      *etcdclient.NamespaceZone   `json:"zone,omitempty"`    // 单个区域
+	Zones     []*etcdclient.NamespaceZone `json:"zones,omitempty"`   // 区域列表
+	Message   string                      `json:"message,omitempty"` // 可选消息
+	Timestamp string                      `json:"timestamp"`         // 时间戳
+}
+
+// listZonesHandler 列出自定义区域，路径中带命名空间时只列出该命名空间的区域
+func (h *EchoHandler) listZonesHandler(c echo.Context) error {
+	namespace := c.Param("namespace")
+
+	zones, err := h.etcdClient.ListNamespaceZones(c.Request().Context())
+	if err != nil {
+		h.log(c).Error("获取区域列表失败", zap.Error(err))
+		return respondError(c, storageError(fmt.Errorf("获取区域列表失败: %w", err)))
+	}
+
+	result := make([]*etcdclient.NamespaceZone, 0, len(zones))
+	for _, zone := range zones {
+		if namespace == "" || zone.Namespace == namespace {
+			result = append(result, zone)
+		}
+	}
+
+	return c.JSON(http.StatusOK, &ZoneResponse{
+		Success:   true,
+		Zones:     result,
+		Timestamp: time.Now().Format(time.RFC3339),
+	})
+}
+
+// putZoneHandler 把自定义区域分配给命名空间
+func (h *EchoHandler) putZoneHandler(c echo.Context) error {
+	zone := &etcdclient.NamespaceZone{
+		Zone:      etcdclient.NormalizeZone(c.Param("zone")),
+		Namespace: c.Param("namespace"),
+	}
+	if err := etcdclient.ValidateZoneName(zone.Zone); err != nil {
+		return respondError(c, badRequest(CodeInvalidParameter, err.Error()))
+	}
+
+	if err := h.etcdClient.PutNamespaceZone(c.Request().Context(), zone); err != nil {
+		h.log(c).Error("分配区域失败", zap.String("zone", zone.Zone), zap.String("namespace", zone.Namespace), zap.Error(err))
+		return respondError(c, storageError(fmt.Errorf("分配区域失败: %w", err)))
+	}
+
+	return c.JSON(http.StatusOK, &ZoneResponse{
+		Success:   true,
+		Zone:      zone,
+		Message:   "区域已分配给命名空间",
+		Timestamp: time.Now().Format(time.RFC3339),
+	})
+}
+
+// deleteZoneHandler 删除命名空间的自定义区域
+func (h *EchoHandler) deleteZoneHandler(c echo.Context) error {
+	namespace, zone := c.Param("namespace"), c.Param("zone")
+
+	if err := h.etcdClient.DeleteNamespaceZone(c.Request().Context(), namespace, zone); err != nil {
+		h.log(c).Error("删除区域失败", zap.String("zone", zone), zap.String("namespace", namespace), zap.Error(err))
+		return respondError(c, storageError(fmt.Errorf("删除区域失败: %w", err)))
+	}
+
+	return c.JSON(http.StatusOK, &ZoneResponse{
+		Success:   true,
+		Message:   "区域已删除",
+		Timestamp: time.Now().Format(time.RFC3339),
+	})
+}
+
+// UpstreamStatusResponse 定义上游DNS健康状态响应结构
+type UpstreamStatusResponse struct {
+	Success   bool                       `json:"success"`           // 是否成功
+	Upstreams []dnsserver.UpstreamStatus `json:"upstreams"`         // 上游健康状态列表
+	Message   string                     `json:"message,omitempty"` // 可选消息
+	Timestamp string                     `json:"timestamp"`         // 时间戳
+}
+
+// upstreamStatusHandler 返回所有上游DNS服务器的健康状态
+func (h *EchoHandler) upstreamStatusHandler(c echo.Context) error {
+	if h.dnsServer == nil {
+		return respondError(c, newAPIError(http.StatusServiceUnavailable, CodeDNSServerUnavailable, ""))
+	}
+
+	return c.JSON(http.StatusOK, &UpstreamStatusResponse{
+		Success:   true,
+		Upstreams: h.dnsServer.UpstreamStatus(),
+		Timestamp: time.Now().Format(time.RFC3339),
+	})
+}
+
+// DisableUpstreamRequest 定义停用上游DNS的请求结构，请求体可以为空
+type DisableUpstreamRequest struct {
+	Reason string `json:"reason"` // 停用原因
+}
+
+// UpstreamToggleResponse 定义停用或恢复上游DNS的响应结构
+type UpstreamToggleResponse struct {
+	Success   bool   `json:"success"`           // 是否成功
+	Address   string `json:"address"`           // 上游地址
+	Disabled  bool   `json:"disabled"`          // 操作后是否处于停用状态
+	Message   string `json:"message,omitempty"` // 可选消息
+	Timestamp string `json:"timestamp"`         // 时间戳
+}
+
+// disableUpstreamHandler 手动停用上游DNS服务器，停用期间不参与转发和恢复探测，上游列表保持不变
+func (h *EchoHandler) disableUpstreamHandler(c echo.Context) error {
+	req := new(DisableUpstreamRequest)
+	if c.Request().ContentLength != 0 {
+		if apiErr := bindRequest(c, req); apiErr != nil {
+			return respondError(c, apiErr)
+		}
+	}
+	return h.setUpstreamDisabled(c, true, req.Reason)
+}
+
+// enableUpstreamHandler 恢复被手动停用的上游DNS服务器
+func (h *EchoHandler) enableUpstreamHandler(c echo.Context) error {
+	return h.setUpstreamDisabled(c, false, "")
+}
+
+// setUpstreamDisabled 保存上游的停用状态并立即应用到本节点，其他节点在下次加载运行时配置时生效
+func (h *EchoHandler) setUpstreamDisabled(c echo.Context, disabled bool, reason string) error {
+	if h.dnsServer == nil {
+		return respondError(c, newAPIError(http.StatusServiceUnavailable, CodeDNSServerUnavailable, ""))
+	}
+
+	addr, err := url.PathUnescape(c.Param("addr"))
+	if err != nil || addr == "" {
+		return respondError(c, badRequest(CodeInvalidParameter, "上游地址无效"))
+	}
+	if !h.dnsServer.SetUpstreamDisabled(addr, disabled) {
+		return respondError(c, newAPIError(http.StatusNotFound, CodeUpstreamNotFound, addr))
+	}
+
+	if err := h.etcdClient.SetUpstreamDisabled(c.Request().Context(), addr, disabled, reason); err != nil {
+		// 保存失败时撤销本节点的修改，保持与etcd一致
+		h.dnsServer.SetUpstreamDisabled(addr, !disabled)
+		return respondError(c, storageError(err))
+	}
+
+	message := "上游DNS已恢复"
+	if disabled {
+		message = "上游DNS已停用"
+	}
+	return c.JSON(http.StatusOK, &UpstreamToggleResponse{
+		Success:   true,
+		Address:   addr,
+		Disabled:  disabled,
+		Message:   message,
+		Timestamp: time.Now().Format(time.RFC3339),
+	})
+}
+
+// ResolveResponse 定义解析调试响应结构
+type ResolveResponse struct {
+	Success   bool                    `json:"success"`           // 是否成功
+	Trace     *dnsserver.ResolveTrace `json:"trace,omitempty"`   // 解析跟踪结果
+	Message   string                  `json:"message,omitempty"` // 可选消息
+	Timestamp string                  `json:"timestamp"`         // 时间戳
+}
+
+// resolveHandler 执行一次完整的解析流程并返回每一步的跟踪信息
+// 参数：name 查询域名，type 查询类型（默认A），client 模拟的客户端IP（用于视图选择）
+func (h *EchoHandler) resolveHandler(c echo.Context) error {
+	if h.dnsServer == nil {
+		return respondError(c, newAPIError(http.StatusServiceUnavailable, CodeDNSServerUnavailable, ""))
+	}
+
+	trace, err := h.dnsServer.Resolve(c.Request().Context(), c.QueryParam("name"), c.QueryParam("type"), c.QueryParam("client"))
+	if err != nil {
+		return respondError(c, badRequest(CodeInvalidParameter, err.Error()))
+	}
+
+	return c.JSON(http.StatusOK, &ResolveResponse{
+		Success:   true,
+		Trace:     trace,
+		Timestamp: time.Now().Format(time.RFC3339),
+	})
+}
+
+// 查询统计的默认窗口和热门域名数量
+const (
+	defaultStatsWindow = 5 * time.Minute
+	defaultStatsTopN   = 10
+)
+
+// DNSStatsResponse 定义查询统计响应结构
+type DNSStatsResponse struct {
+	Success   bool                `json:"success"`           // 是否成功
+	Stats     *dnsserver.DNSStats `json:"stats,omitempty"`   // 查询统计
+	Message   string              `json:"message,omitempty"` // 可选消息
+	Timestamp string              `json:"timestamp"`         // 时间戳
+}
+
+// dnsStatsHandler 返回查询统计：热门域名、各服务查询速率和NXDOMAIN比例
+// 参数：window 统计窗口（如1m、5m，最大15m），top 热门域名数量
+func (h *EchoHandler) dnsStatsHandler(c echo.Context) error {
+	if h.dnsServer == nil {
+		return respondError(c, newAPIError(http.StatusServiceUnavailable, CodeDNSServerUnavailable, ""))
+	}
+
+	window := defaultStatsWindow
+	if param := c.QueryParam("window"); param != "" {
+		d, err := time.ParseDuration(param)
+		if err != nil || d <= 0 || d > dnsserver.MaxStatsWindow {
+			return respondError(c, badRequest(CodeInvalidParameter, fmt.Sprintf("window必须在0到%s之间", dnsserver.MaxStatsWindow)))
+		}
+		window = d
+	}
+
+	topN := defaultStatsTopN
+	if param := c.QueryParam("top"); param != "" {
+		n, err := strconv.Atoi(param)
+		if err != nil || n <= 0 {
+			return respondError(c, badRequest(CodeInvalidParameter, "top必须是正整数"))
+		}
+		topN = n
+	}
+
+	stats := h.dnsServer.DNSStats(window, topN)
+	return c.JSON(http.StatusOK, &DNSStatsResponse{
+		Success:   true,
+		Stats:     &stats,
+		Timestamp: time.Now().Format(time.RFC3339),
+	})
+}
+
+// WatchStatsResponse 定义变更监听统计响应结构
+type WatchStatsResponse struct {
+	Success   bool                   `json:"success"`   // 是否成功
+	Stats     *etcdclient.WatchStats `json:"stats"`     // 变更监听的中断与压缩统计
+	Timestamp string                 `json:"timestamp"` // 时间戳
+}
+
+// watchStatsHandler 返回变更监听的统计：中断后重新建立的次数、因压缩从当前版本重新开始的次数和最近一次的原因
+func (h *EchoHandler) watchStatsHandler(c echo.Context) error {
+	stats := h.etcdClient.WatchStats()
+	return c.JSON(http.StatusOK, &WatchStatsResponse{
+		Success:   true,
+		Stats:     &stats,
+		Timestamp: time.Now().Format(time.RFC3339),
+	})
+}
+
+// UpstreamConfigResponse 定义上游DNS配置响应结构
+type UpstreamConfigResponse struct {
+	Success   bool                       `json:"success"`           // 是否成功
+	Config    *etcdclient.UpstreamConfig `json:"config,omitempty"`  // 上游DNS配置
+	Message   string                     `json:"message,omitempty"` // 可选消息
+	Timestamp string                     `json:"timestamp"`         // 时间戳
+}
+
+// getUpstreamConfigHandler 获取运行时上游DNS配置
+// etcd中没有配置时返回配置文件中的静态配置
+func (h *EchoHandler) getUpstreamConfigHandler(c echo.Context) error {
+	ctx := c.Request().Context()
+	upstreamCfg, err := h.etcdClient.GetUpstreamConfig(ctx)
+	if err != nil {
+		h.log(c).Debug("etcd中没有运行时上游DNS配置，返回静态配置", zap.Error(err))
+
+		servers := make([]string, 0, len(h.cfg.DNS.Upstream.Servers)+1)
+		if h.cfg.DNS.UpstreamDNS != "" {
+			servers = append(servers, h.cfg.DNS.UpstreamDNS)
+		}
+		upstreamCfg = &etcdclient.UpstreamConfig{
+			Servers:   append(servers, h.cfg.DNS.Upstream.Servers...),
+			Mode:      h.cfg.DNS.Upstream.Mode,
+			RaceCount: h.cfg.DNS.Upstream.RaceCount,
+		}
+	}
+
+	return c.JSON(http.StatusOK, &UpstreamConfigResponse{
+		Success:   true,
+		Config:    upstreamCfg,
+		Timestamp: time.Now().Format(time.RFC3339),
+	})
+}
+
+// putUpstreamConfigHandler 更新运行时上游DNS配置，DNS服务器会自动热加载
+func (h *EchoHandler) putUpstreamConfigHandler(c echo.Context) error {
+	req := new(etcdclient.UpstreamConfig)
+	if apiErr := bindRequest(c, req); apiErr != nil {
+		h.log(c).Error("解析上游DNS配置请求失败", zap.Error(apiErr))
+		return respondError(c, apiErr)
+	}
+
+	if err := req.Validate(); err != nil {
+		return respondError(c, badRequest(CodeInvalidParameter, err.Error()))
+	}
+
+	ctx := c.Request().Context()
+	if err := h.etcdClient.PutUpstreamConfig(ctx, req); err != nil {
+		h.log(c).Error("保存上游DNS配置失败", zap.Error(err))
+		return respondError(c, storageError(fmt.Errorf("保存上游DNS配置失败: %w", err)))
+	}
+
+	return c.JSON(http.StatusOK, &UpstreamConfigResponse{
+		Success:   true,
+		Config:    req,
+		Message:   "上游DNS配置更新成功",
+		Timestamp: time.Now().Format(time.RFC3339),
+	})
+}
+
+// ForwardRulesResponse 定义条件转发规则响应结构
+type ForwardRulesResponse struct {
+	Success   bool                      `json:"success"`           // 是否成功
+	Rules     []*etcdclient.ForwardRule `json:"rules,omitempty"`   // 条件转发规则列表
+	Message   string                    `json:"message,omitempty"` // 可选消息
+	Timestamp string                    `json:"timestamp"`         // 时间戳
+}
+
+// listForwardRulesHandler 列出etcd中的条件转发规则
+func (h *EchoHandler) listForwardRulesHandler(c echo.Context) error {
+	ctx := c.Request().Context()
+	rules, err := h.etcdClient.ListForwardRules(ctx)
+	if err != nil {
+		h.log(c).Error("获取条件转发规则失败", zap.Error(err))
+		return respondError(c, storageError(fmt.Errorf("获取条件转发规则失败: %w", err)))
+	}
+
+	return c.JSON(http.StatusOK, &ForwardRulesResponse{
+		Success:   true,
+		Rules:     rules,
+		Timestamp: time.Now().Format(time.RFC3339),
+	})
+}
+
+// putForwardRuleHandler 创建或更新条件转发规则
+func (h *EchoHandler) putForwardRuleHandler(c echo.Context) error {
+	req := new(etcdclient.ForwardRule)
+	if apiErr := bindRequest(c, req); apiErr != nil {
+		h.log(c).Error("解析条件转发规则请求失败", zap.Error(apiErr))
+		return respondError(c, apiErr)
+	}
+
+	if err := req.Validate(); err != nil {
+		return respondError(c, badRequest(CodeInvalidParameter, err.Error()))
+	}
+
+	ctx := c.Request().Context()
+	if err := h.etcdClient.PutForwardRule(ctx, req); err != nil {
+		h.log(c).Error("保存条件转发规则失败", zap.Error(err))
+		return respondError(c, storageError(fmt.Errorf("保存条件转发规则失败: %w", err)))
+	}
+
+	return c.JSON(http.StatusOK, &ForwardRulesResponse{
+		Success:   true,
+		Rules:     []*etcdclient.ForwardRule{req},
+		Message:   "条件转发规则保存成功",
+		Timestamp: time.Now().Format(time.RFC3339),
+	})
+}
+
+// deleteForwardRuleHandler 删除条件转发规则
+func (h *EchoHandler) deleteForwardRuleHandler(c echo.Context) error {
+	suffix := c.Param("suffix")
+
+	ctx := c.Request().Context()
+	if err := h.etcdClient.DeleteForwardRule(ctx, suffix); err != nil {
+		h.log(c).Error("删除条件转发规则失败", zap.String("suffix", suffix), zap.Error(err))
+		return respondError(c, storageError(fmt.Errorf("删除条件转发规则失败: %w", err)))
+	}
+
+	return c.JSON(http.StatusOK, &ForwardRulesResponse{
+		Success:   true,
+		Message:   "条件转发规则删除成功",
+		Timestamp: time.Now().Format(time.RFC3339),
+	})
+}
+
+// BlocklistResponse 定义拦截规则响应结构
+type BlocklistResponse struct {
+	Success   bool                         `json:"success"`           // 是否成功
+	Rules     []dnsserver.PolicyRuleStatus `json:"rules,omitempty"`   // 拦截规则及命中次数
+	Message   string                       `json:"message,omitempty"` // 可选消息
+	Timestamp string                       `json:"timestamp"`         // 时间戳
+}
+
+// listBlockRulesHandler 列出拦截规则及其命中次数
+func (h *EchoHandler) listBlockRulesHandler(c echo.Context) error {
+	ctx := c.Request().Context()
+	rules, err := h.etcdClient.ListBlockRules(ctx)
+	if err != nil {
+		h.log(c).Error("获取拦截规则失败", zap.Error(err))
+		return respondError(c, storageError(fmt.Errorf("获取拦截规则失败: %w", err)))
+	}
+
+	// 命中次数来自DNS服务器的内存计数
+	hits := make(map[string]uint64)
+	if h.dnsServer != nil {
+		for _, status := range h.dnsServer.PolicyStatus() {
+			hits[status.ID] = status.Hits
+		}
+	}
+
+	result := make([]dnsserver.PolicyRuleStatus, 0, len(rules))
+	for _, rule := range rules {
+		result = append(result, dnsserver.PolicyRuleStatus{
+			BlockRule: *rule,
+			Hits:      hits[rule.ID],
+		})
+	}
+
+	return c.JSON(http.StatusOK, &BlocklistResponse{
+		Success:   true,
+		Rules:     result,
+		Timestamp: time.Now().Format(time.RFC3339),
+	})
+}
+
+// putBlockRuleHandler 创建或更新拦截规则
+func (h *EchoHandler) putBlockRuleHandler(c echo.Context) error {
+	req := new(etcdclient.BlockRule)
+	if apiErr := bindRequest(c, req); apiErr != nil {
+		h.log(c).Error("解析拦截规则请求失败", zap.Error(apiErr))
+		return respondError(c, apiErr)
+	}
+
+	if err := req.Validate(); err != nil {
+		return respondError(c, badRequest(CodeInvalidParameter, err.Error()))
+	}
+
+	ctx := c.Request().Context()
+	if err := h.etcdClient.PutBlockRule(ctx, req); err != nil {
+		h.log(c).Error("保存拦截规则失败", zap.Error(err))
+		return respondError(c, storageError(fmt.Errorf("保存拦截规则失败: %w", err)))
+	}
+
+	return c.JSON(http.StatusOK, &BlocklistResponse{
+		Success:   true,
+		Rules:     []dnsserver.PolicyRuleStatus{{BlockRule: *req}},
+		Message:   "拦截规则保存成功",
+		Timestamp: time.Now().Format(time.RFC3339),
+	})
+}
+
+// deleteBlockRuleHandler 删除拦截规则
+func (h *EchoHandler) deleteBlockRuleHandler(c echo.Context) error {
+	id := c.Param("id")
+
+	ctx := c.Request().Context()
+	if err := h.etcdClient.DeleteBlockRule(ctx, id); err != nil {
+		h.log(c).Error("删除拦截规则失败", zap.String("id", id), zap.Error(err))
+		return respondError(c, storageError(fmt.Errorf("删除拦截规则失败: %w", err)))
+	}
+
+	return c.JSON(http.StatusOK, &BlocklistResponse{
+		Success:   true,
+		Message:   "拦截规则删除成功",
+		Timestamp: time.Now().Format(time.RFC3339),
+	})
+}
@@ -1,34 +1,48 @@
 package apihandler
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/google/uuid"
+	"github.com/hewenyu/kong-discovery/internal/chaos"
 	"github.com/hewenyu/kong-discovery/internal/config"
+	"github.com/hewenyu/kong-discovery/internal/dnsserver"
 	"github.com/hewenyu/kong-discovery/internal/etcdclient"
+	"github.com/hewenyu/kong-discovery/internal/reconciler"
+	"github.com/hewenyu/kong-discovery/internal/version"
 	"github.com/labstack/echo/v4"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
 )
 
 // 创建一个测试用的配置，使用环境变量中的etcd地址
 func createTestConfig(t *testing.T) *config.Config {
 	t.Helper()
 
-	// 从环境变量中获取etcd地址
-	etcdEndpoints := os.Getenv("KONG_DISCOVERY_ETCD_ENDPOINTS")
-	require.NotEmpty(t, etcdEndpoints, "环境变量KONG_DISCOVERY_ETCD_ENDPOINTS必须设置")
-
 	// 创建配置
 	cfg := &config.Config{}
-	cfg.Etcd.Endpoints = []string{etcdEndpoints}
+	etcdclient.ApplyTestStorage(cfg)
 	cfg.Etcd.Username = "" // 如果需要认证，设置相应的值
 	cfg.Etcd.Password = "" // 如果需要认证，设置相应的值
 	cfg.API.Management.ListenAddress = "localhost"
@@ -243,6 +257,152 @@ func TestServiceRegistration(t *testing.T) {
 	assert.Equal(t, "1.0.0", instances[0].Metadata["version"])
 }
 
+func TestServiceRegistration_InvalidIPAddress(t *testing.T) {
+	e := echo.New()
+	handler := &EchoHandler{
+		registrationServer: e,
+		logger:             createTestLogger(t),
+	}
+	handler.registerRegistrationRoutes()
+
+	reqBody := `{"service_name": "test-service", "ip_address": "backend.local", "port": 8080, "ttl": 60}`
+	req := httptest.NewRequest(http.MethodPost, "/services/register", strings.NewReader(reqBody))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	var resp ErrorResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, CodeInvalidParameter, resp.Code)
+}
+
+func TestServiceRegistration_InvalidMetadata(t *testing.T) {
+	cfg := createTestConfig(t)
+	cfg.API.Registration.Metadata = config.MetadataLimits{
+		MaxKeys:      4,
+		MaxValueSize: 64,
+		Types:        map[string]string{"canary": "bool"},
+	}
+
+	e := echo.New()
+	handler := &EchoHandler{
+		registrationServer: e,
+		cfg:                cfg,
+		logger:             createTestLogger(t),
+	}
+	handler.registerRegistrationRoutes()
+
+	for name, metadata := range map[string]string{
+		"值超过大小上限": fmt.Sprintf(`{"blob": %q}`, strings.Repeat("x", 65)),
+		"键数量超过上限": `{"a": "1", "b": "2", "c": "3", "d": "4", "e": "5"}`,
+		"保留前缀":    `{"kd.domain": "order.example.com"}`,
+		"类型不符":    `{"canary": "maybe"}`,
+	} {
+		reqBody := fmt.Sprintf(`{"service_name": "test-service", "ip_address": "10.0.0.1", "port": 8080, "metadata": %s}`, metadata)
+		req := httptest.NewRequest(http.MethodPost, "/v1/services/register", strings.NewReader(reqBody))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		require.Equal(t, http.StatusBadRequest, rec.Code, name)
+
+		var resp ErrorResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		assert.Equal(t, CodeInvalidMetadata, resp.Code, name)
+	}
+}
+
+func TestServiceRegistration_NamedPorts(t *testing.T) {
+	// 跳过集成测试，除非明确要求运行
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	e := echo.New()
+	client := etcdclient.CreateEtcdClientForTest(t)
+	t.Cleanup(func() { _ = client.Close() })
+
+	handler := &EchoHandler{
+		registrationServer: e,
+		cfg:                createTestConfig(t),
+		logger:             createTestLogger(t),
+		etcdClient:         client,
+	}
+	handler.registerRegistrationRoutes()
+
+	register := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/v1/services/register", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// 无效的命名端口
+	rec := register(`{"service_name": "test-service", "ip_address": "10.0.0.1", "port": 8080, "ports": [{"name": "HTTP", "port": 8080}]}`)
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	var errResp ErrorResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &errResp))
+	assert.Equal(t, CodeInvalidParameter, errResp.Code)
+
+	serviceName := fmt.Sprintf("named-ports-%d", time.Now().UnixNano())
+	rec = register(fmt.Sprintf(`{"service_name": %q, "instance_id": "pod-1", "ip_address": "10.0.0.1", "port": 8080, "ttl": 60,
+		"ports": [{"name": "http", "port": 8080}, {"name": "grpc", "port": 9090}, {"name": "metrics", "port": 9100}]}`, serviceName))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	defer cleanupTestData(t, client, serviceName, "pod-1")
+
+	// 服务发现API返回实例的命名端口
+	req := httptest.NewRequest(http.MethodGet, "/v1/discovery/default/"+serviceName, nil)
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var resp DiscoveryResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Len(t, resp.Instances, 1)
+	assert.Equal(t, []etcdclient.NamedPort{{Name: "http", Port: 8080}, {Name: "grpc", Port: 9090}, {Name: "metrics", Port: 9100}}, resp.Instances[0].Ports)
+}
+
+func TestServiceRegistration_GeneratedInstanceID(t *testing.T) {
+	// 跳过集成测试，除非明确要求运行
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	e := echo.New()
+	client := etcdclient.CreateEtcdClientForTest(t)
+	t.Cleanup(func() { _ = client.Close() })
+
+	handler := &EchoHandler{
+		registrationServer: e,
+		cfg:                createTestConfig(t),
+		logger:             createTestLogger(t),
+		etcdClient:         client,
+	}
+	handler.registerRegistrationRoutes()
+
+	// 省略instance_id时由服务端生成UUID并在响应中返回
+	testServiceName := fmt.Sprintf("test-service-%d", time.Now().UnixNano())
+	reqBody := fmt.Sprintf(`{"service_name": "%s", "ip_address": "192.168.1.100", "port": 8080, "ttl": 60}`, testServiceName)
+	req := httptest.NewRequest(http.MethodPost, "/services/register", strings.NewReader(reqBody))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var response ServiceRegistrationResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	_, err := uuid.Parse(response.InstanceID)
+	require.NoError(t, err, "生成的实例ID应为UUID")
+	defer cleanupTestData(t, client, testServiceName, response.InstanceID)
+
+	// 使用返回的ID发送心跳
+	req = httptest.NewRequest(http.MethodPut, "/services/heartbeat/"+testServiceName+"/"+response.InstanceID, strings.NewReader(`{}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+}
+
 func TestServiceRegistration_BadRequest(t *testing.T) {
 	// 跳过集成测试，除非明确要求运行
 	if testing.Short() {
@@ -518,6 +678,93 @@ func TestServiceHeartbeat(t *testing.T) {
 	assert.NotEqual(t, originalTTL, instances[0].TTL)
 }
 
+func TestServiceHeartbeat_HealthReport(t *testing.T) {
+	// 跳过集成测试，除非明确要求运行
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	e := echo.New()
+	client := etcdclient.CreateEtcdClientForTest(t)
+	defer client.Close()
+
+	serviceName := fmt.Sprintf("report-service-%d", time.Now().UnixNano())
+	defer cleanupTestData(t, client, serviceName, "instance-001")
+
+	ctx := context.Background()
+	require.NoError(t, client.RegisterService(ctx, &etcdclient.ServiceInstance{
+		ServiceName: serviceName,
+		InstanceID:  "instance-001",
+		IPAddress:   "192.168.1.100",
+		Port:        8080,
+		TTL:         60,
+	}))
+
+	handler := &EchoHandler{
+		registrationServer: e,
+		cfg:                createTestConfig(t),
+		logger:             createTestLogger(t),
+		etcdClient:         client,
+	}
+	handler.registerRegistrationRoutes()
+
+	heartbeat := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, "/services/heartbeat/"+serviceName+"/instance-001", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// 检查结果可以使用简写，最严重的一项决定健康状态
+	rec := heartbeat(`{"health":{"load":{"cpu":0.4,"connections":120},"checks":[{"name":"db","status":"pass"},{"name":"cache","status":"warn","output":"slow"}]}}`)
+	require.Equal(t, http.StatusOK, rec.Code)
+	var response ServiceHeartbeatResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	assert.Equal(t, etcdclient.HealthWarning, response.Health)
+
+	instances, err := client.GetServiceInstances(ctx, serviceName)
+	require.NoError(t, err)
+	require.Len(t, instances, 1)
+	require.NotNil(t, instances[0].Report)
+	assert.Equal(t, 120.0, instances[0].Report.Load["connections"])
+	assert.Equal(t, etcdclient.HealthWarning, instances[0].Report.Checks[1].Status)
+
+	// 健康信息随实例列表返回
+	req := httptest.NewRequest(http.MethodGet, "/services/"+serviceName, nil)
+	listRec := httptest.NewRecorder()
+	e.ServeHTTP(listRec, req)
+	require.Equal(t, http.StatusOK, listRec.Code)
+	assert.Contains(t, listRec.Body.String(), `"report"`)
+
+	rec = heartbeat(`{"health":{"status":"fail"}}`)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	assert.Equal(t, etcdclient.HealthCritical, response.Health)
+
+	rec = heartbeat(`{"health":{"status":"draining"}}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	rec = heartbeat(`{"health":{"checks":[{"status":"pass"}]}}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	// 无法解析的健康信息返回400，不会当作没有携带健康信息的心跳
+	for _, body := range []string{`{"health":{"load":{"cpu":"high"}}}`, `{"health":`, `{"ttl":"30"}`} {
+		rec = heartbeat(body)
+		require.Equal(t, http.StatusBadRequest, rec.Code, body)
+		var errResp ErrorResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &errResp))
+		assert.Equal(t, CodeInvalidRequest, errResp.Code, body)
+	}
+
+	// 空请求体只刷新租约，保留上次上报的健康信息
+	req = httptest.NewRequest(http.MethodPut, "/services/heartbeat/"+serviceName+"/instance-001", nil)
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	assert.Equal(t, etcdclient.HealthCritical, response.Health)
+}
+
 func TestServiceHeartbeat_NotFound(t *testing.T) {
 	// 跳过集成测试，除非明确要求运行
 	if testing.Short() {
@@ -554,13 +801,2632 @@ func TestServiceHeartbeat_NotFound(t *testing.T) {
 	// 执行请求
 	e.ServeHTTP(rec, req)
 
-	// 验证响应 - 应该返回失败
-	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	// 验证响应 - 实例不存在应返回404和对应的错误码
+	assert.Equal(t, http.StatusNotFound, rec.Code)
 
-	var response ServiceHeartbeatResponse
+	var response ErrorResponse
 	err := json.Unmarshal(rec.Body.Bytes(), &response)
 	require.NoError(t, err)
 
 	assert.False(t, response.Success)
-	assert.Contains(t, response.Message, "刷新服务租约失败")
+	assert.Equal(t, CodeInstanceNotFound, response.Code)
+	assert.Equal(t, "服务实例不存在", response.Message)
+	assert.Contains(t, response.Detail, "刷新服务租约失败")
+}
+
+func TestPutUpstreamConfig_InvalidRaceCount(t *testing.T) {
+	e := echo.New()
+	handler := &EchoHandler{
+		managementServer: e,
+		cfg:              createTestConfig(t),
+		logger:           createTestLogger(t),
+	}
+	handler.registerManagementRoutes()
+
+	// race_count小于2时拒绝，不会被静默改为2
+	req := httptest.NewRequest(http.MethodPut, "/admin/config/upstream-dns", strings.NewReader(`{"servers":["8.8.8.8:53"],"mode":"race","race_count":1}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusBadRequest, rec.Code, rec.Body.String())
+
+	var resp ErrorResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, CodeInvalidParameter, resp.Code)
+	assert.Contains(t, resp.Detail, "race_count")
+}
+
+func TestUpstreamStatus(t *testing.T) {
+	// 准备测试配置，无需etcd
+	cfg := &config.Config{}
+	cfg.DNS.UpstreamDNS = "10.0.0.1:53"
+	cfg.DNS.Upstream.Servers = []string{"10.0.0.2:53"}
+	logger := createTestLogger(t)
+
+	// 创建Echo实例
+	e := echo.New()
+
+	// 创建handler并注册路由
+	handler := &EchoHandler{
+		managementServer: e,
+		cfg:              cfg,
+		logger:           logger,
+		dnsServer:        dnsserver.NewDNSServer(cfg, logger),
+	}
+	handler.registerManagementRoutes()
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/dns/upstreams", nil)
+	rec := httptest.NewRecorder()
+
+	// 执行请求
+	e.ServeHTTP(rec, req)
+
+	// 验证响应
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var response UpstreamStatusResponse
+	err := json.Unmarshal(rec.Body.Bytes(), &response)
+	require.NoError(t, err)
+
+	assert.True(t, response.Success)
+	require.Len(t, response.Upstreams, 2)
+	assert.Equal(t, "10.0.0.1:53", response.Upstreams[0].Address)
+	assert.Equal(t, "closed", response.Upstreams[0].State)
+}
+
+func TestDisableUpstream(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.DNS.UpstreamDNS = "10.0.0.1:53"
+	cfg.DNS.Upstream.Servers = []string{"10.0.0.2:53"}
+	logger := createTestLogger(t)
+	e := echo.New()
+
+	client := etcdclient.CreateEtcdClientForTest(t)
+	defer client.Close()
+	defer client.SetUpstreamDisabled(context.Background(), "10.0.0.2:53", false, "")
+
+	handler := &EchoHandler{
+		managementServer: e,
+		cfg:              cfg,
+		logger:           logger,
+		etcdClient:       client,
+		dnsServer:        dnsserver.NewDNSServer(cfg, logger),
+	}
+	handler.registerManagementRoutes()
+
+	// 停用上游，状态中立即可见并保存到etcd
+	req := httptest.NewRequest(http.MethodPost, "/admin/config/upstream-dns/10.0.0.2:53/disable", strings.NewReader(`{"reason":"丢包"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/config/upstream-dns/status", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var status UpstreamStatusResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &status))
+	require.Len(t, status.Upstreams, 2)
+	assert.False(t, status.Upstreams[0].Disabled)
+	assert.True(t, status.Upstreams[0].Reachable)
+	assert.True(t, status.Upstreams[1].Disabled)
+
+	disabled, err := client.ListDisabledUpstreams(context.Background())
+	require.NoError(t, err)
+	require.Len(t, disabled, 1)
+	assert.Equal(t, "丢包", disabled[0].Reason)
+
+	// 不存在的上游
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/admin/config/upstream-dns/10.0.0.9:53/disable", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	// 恢复上游
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/admin/config/upstream-dns/10.0.0.2:53/enable", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	disabled, err = client.ListDisabledUpstreams(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, disabled)
+}
+
+func TestInstanceHealth(t *testing.T) {
+	// 跳过集成测试，除非明确要求运行
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	cfg := createTestConfig(t)
+	logger := createTestLogger(t)
+	e := echo.New()
+
+	client := etcdclient.CreateEtcdClientForTest(t)
+	defer client.Close()
+
+	testServiceName := fmt.Sprintf("test-service-%d", time.Now().UnixNano())
+	testInstanceID := "instance-001"
+	defer cleanupTestData(t, client, testServiceName, testInstanceID)
+
+	err := client.RegisterService(context.Background(), &etcdclient.ServiceInstance{
+		ServiceName: testServiceName,
+		InstanceID:  testInstanceID,
+		IPAddress:   "192.168.1.100",
+		Port:        8080,
+		TTL:         60,
+	})
+	require.NoError(t, err)
+
+	handler := &EchoHandler{
+		registrationServer: e,
+		cfg:                cfg,
+		logger:             logger,
+		etcdClient:         client,
+	}
+	handler.registerRegistrationRoutes()
+
+	// 更新健康状态
+	req := httptest.NewRequest(http.MethodPut, "/services/"+testServiceName+"/"+testInstanceID+"/health", strings.NewReader(`{"status": "draining"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var healthResp InstanceHealthResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &healthResp))
+	assert.True(t, healthResp.Success)
+	assert.Equal(t, etcdclient.HealthDraining, healthResp.Health)
+
+	// 非法状态
+	req = httptest.NewRequest(http.MethodPut, "/services/"+testServiceName+"/"+testInstanceID+"/health", strings.NewReader(`{"status": "sick"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	// 不允许的状态转换
+	req = httptest.NewRequest(http.MethodPut, "/services/"+testServiceName+"/"+testInstanceID+"/health", strings.NewReader(`{"status": "critical"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusConflict, rec.Code)
+
+	// 不存在的实例
+	req = httptest.NewRequest(http.MethodPut, "/services/"+testServiceName+"/missing/health", strings.NewReader(`{"status": "passing"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	// 列表中展示健康状态并支持过滤
+	req = httptest.NewRequest(http.MethodGet, "/services/"+testServiceName+"?health=draining", nil)
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var listResp ServiceInstancesResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &listResp))
+	require.Len(t, listResp.Instances, 1)
+	assert.Equal(t, etcdclient.HealthDraining, listResp.Instances[0].Health)
+
+	req = httptest.NewRequest(http.MethodGet, "/services/"+testServiceName+"?health=passing", nil)
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	listResp = ServiceInstancesResponse{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &listResp))
+	assert.Empty(t, listResp.Instances)
+}
+
+func TestServiceRegistration_QuotaExceeded(t *testing.T) {
+	// 跳过集成测试，除非明确要求运行
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	cfg := createTestConfig(t)
+	logger := createTestLogger(t)
+	e := echo.New()
+
+	client := etcdclient.CreateEtcdClientForTest(t)
+	defer client.Close()
+
+	// 设置只允许一个实例的命名空间配额
+	namespace := fmt.Sprintf("quota-ns-%d", time.Now().UnixNano())
+	require.NoError(t, client.CreateNamespace(context.Background(), &etcdclient.Namespace{Name: namespace}))
+	defer func() { _ = client.DeleteNamespace(context.Background(), namespace, true) }()
+	err := client.PutNamespaceQuota(context.Background(), namespace, &etcdclient.NamespaceQuota{MaxInstances: 1})
+	require.NoError(t, err)
+
+	testServiceName := namespace + "-svc"
+	defer cleanupTestData(t, client, testServiceName, "instance-001")
+
+	handler := &EchoHandler{
+		registrationServer: e,
+		cfg:                cfg,
+		logger:             logger,
+		etcdClient:         client,
+	}
+	handler.registerRegistrationRoutes()
+
+	register := func(instanceID string) int {
+		reqBody := fmt.Sprintf(`{"service_name": "%s", "instance_id": "%s", "ip_address": "10.0.0.1", "port": 8080, "ttl": 30, "namespace": "%s"}`,
+			testServiceName, instanceID, namespace)
+		req := httptest.NewRequest(http.MethodPost, "/services/register", strings.NewReader(reqBody))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusOK, register("instance-001"))
+	assert.Equal(t, http.StatusTooManyRequests, register("instance-002"))
+}
+
+func TestNamespaceLifecycle(t *testing.T) {
+	// 跳过集成测试，除非明确要求运行
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	cfg := createTestConfig(t)
+	logger := createTestLogger(t)
+	e := echo.New()
+
+	client := etcdclient.CreateEtcdClientForTest(t)
+	defer client.Close()
+
+	handler := &EchoHandler{
+		managementServer: e,
+		cfg:              cfg,
+		logger:           logger,
+		etcdClient:       client,
+	}
+	handler.registerManagementRoutes()
+
+	name := fmt.Sprintf("ns-%d", time.Now().UnixNano())
+	defer func() { _ = client.DeleteNamespace(context.Background(), name, true) }()
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// 创建命名空间
+	assert.Equal(t, http.StatusCreated, do(http.MethodPost, "/admin/namespaces", `{"name": "`+name+`"}`).Code)
+	assert.Equal(t, http.StatusConflict, do(http.MethodPost, "/admin/namespaces", `{"name": "`+name+`"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, do(http.MethodPost, "/admin/namespaces", `{"name": "Bad_Name"}`).Code)
+
+	// 查看命名空间
+	rec := do(http.MethodGet, "/admin/namespaces/"+name, "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var resp NamespaceResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.NotNil(t, resp.Namespace)
+	assert.Equal(t, name, resp.Namespace.Name)
+	require.NotNil(t, resp.Usage)
+	assert.Equal(t, 0, resp.Usage.Instances)
+
+	// 非空命名空间拒绝删除，级联删除成功
+	err := client.RegisterService(context.Background(), &etcdclient.ServiceInstance{
+		ServiceName: name + "-svc",
+		InstanceID:  "instance-001",
+		IPAddress:   "10.0.0.1",
+		Port:        8080,
+		TTL:         30,
+		Namespace:   name,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusConflict, do(http.MethodDelete, "/admin/namespaces/"+name, "").Code)
+	assert.Equal(t, http.StatusOK, do(http.MethodDelete, "/admin/namespaces/"+name+"?cascade=true", "").Code)
+	assert.Equal(t, http.StatusNotFound, do(http.MethodGet, "/admin/namespaces/"+name, "").Code)
+}
+
+func TestDNSRecordEndpoints(t *testing.T) {
+	// 跳过集成测试，除非明确要求运行
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	cfg := createTestConfig(t)
+	logger := createTestLogger(t)
+	e := echo.New()
+
+	client := etcdclient.CreateEtcdClientForTest(t)
+	defer client.Close()
+
+	handler := &EchoHandler{
+		managementServer: e,
+		cfg:              cfg,
+		logger:           logger,
+		etcdClient:       client,
+	}
+	handler.registerManagementRoutes()
+
+	domain := fmt.Sprintf("records-%d.example.com", time.Now().UnixNano())
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// 校验非法记录
+	assert.Equal(t, http.StatusBadRequest, do(http.MethodPut, "/admin/dns/records/"+domain, `{"type": "A", "value": "not-an-ip"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, do(http.MethodPut, "/admin/dns/records/"+domain, `{"type": "MX", "value": "mail"}`).Code)
+
+	// 创建并列出记录
+	assert.Equal(t, http.StatusOK, do(http.MethodPut, "/admin/dns/records/"+domain, `{"type": "a", "value": "10.0.0.1", "ttl": 30}`).Code)
+
+	rec := do(http.MethodGet, "/admin/dns/records?domain="+domain, "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var resp DNSRecordsResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Len(t, resp.Records, 1)
+	assert.Equal(t, "A", resp.Records[0].Type)
+	assert.Equal(t, 30, resp.Records[0].TTL)
+
+	// 删除记录
+	assert.Equal(t, http.StatusOK, do(http.MethodDelete, "/admin/dns/records/"+domain+"/A", "").Code)
+	assert.Equal(t, http.StatusNotFound, do(http.MethodDelete, "/admin/dns/records/"+domain+"/A", "").Code)
+}
+
+func TestDNSRecordEndpoints_OptimisticConcurrency(t *testing.T) {
+	// 跳过集成测试，除非明确要求运行
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	cfg := createTestConfig(t)
+	logger := createTestLogger(t)
+	e := echo.New()
+
+	client := etcdclient.CreateEtcdClientForTest(t)
+	defer client.Close()
+
+	handler := &EchoHandler{
+		managementServer: e,
+		cfg:              cfg,
+		logger:           logger,
+		etcdClient:       client,
+	}
+	handler.registerManagementRoutes()
+
+	domain := fmt.Sprintf("etag-%d.example.com", time.Now().UnixNano())
+	defer func() { _ = client.DeleteDNSRecord(context.Background(), domain, "A", "") }()
+
+	do := func(method, path, body string, headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// If-None-Match: * 只允许创建
+	rec := do(http.MethodPut, "/admin/dns/records/"+domain, `{"type": "A", "value": "10.0.0.1"}`, map[string]string{"If-None-Match": "*"})
+	require.Equal(t, http.StatusOK, rec.Code)
+	created := rec.Header().Get("ETag")
+	require.NotEmpty(t, created)
+
+	rec = do(http.MethodPut, "/admin/dns/records/"+domain, `{"type": "A", "value": "10.0.0.2"}`, map[string]string{"If-None-Match": "*"})
+	assert.Equal(t, http.StatusConflict, rec.Code)
+
+	// GET返回与写入时一致的ETag
+	rec = do(http.MethodGet, "/admin/dns/records/"+domain+"/A", "", nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, created, rec.Header().Get("ETag"))
+
+	// 两个管理员基于同一版本修改，后到者失败
+	rec = do(http.MethodPut, "/admin/dns/records/"+domain, `{"type": "A", "value": "10.0.0.3"}`, map[string]string{"If-Match": created})
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.NotEqual(t, created, rec.Header().Get("ETag"))
+
+	rec = do(http.MethodPut, "/admin/dns/records/"+domain, `{"type": "A", "value": "10.0.0.4"}`, map[string]string{"If-Match": created})
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	var errResp ErrorResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &errResp))
+	assert.Equal(t, CodeRevisionConflict, errResp.Code)
+
+	// 记录值保持为第一次成功修改的结果
+	rec = do(http.MethodGet, "/admin/dns/records/"+domain+"/A", "", nil)
+	var resp DNSRecordsResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Len(t, resp.Records, 1)
+	assert.Equal(t, "10.0.0.3", resp.Records[0].Value)
+
+	// 非法的If-Match
+	rec = do(http.MethodPut, "/admin/dns/records/"+domain, `{"type": "A", "value": "10.0.0.5"}`, map[string]string{"If-Match": "abc"})
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	// 不存在的记录
+	assert.Equal(t, http.StatusNotFound, do(http.MethodGet, "/admin/dns/records/"+domain+"/AAAA", "", nil).Code)
+}
+
+func TestDNSRecordEndpoints_Ownership(t *testing.T) {
+	// 跳过集成测试，除非明确要求运行
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	cfg := createTestConfig(t)
+	logger := createTestLogger(t)
+	e := echo.New()
+
+	client := etcdclient.CreateEtcdClientForTest(t)
+	defer client.Close()
+
+	handler := &EchoHandler{
+		managementServer: e,
+		cfg:              cfg,
+		logger:           logger,
+		etcdClient:       client,
+	}
+	handler.registerManagementRoutes()
+
+	serviceName := fmt.Sprintf("owned-api-%d", time.Now().UnixNano())
+	domain := serviceName + ".default.svc.cluster.local"
+	defer func() { _ = client.DeleteDNSRecord(context.Background(), domain, "A", "") }()
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	owned := fmt.Sprintf(`{"type": "A", "value": "10.0.0.1", "owner": {"kind": "service", "name": "%s"}}`, serviceName)
+	require.Equal(t, http.StatusOK, do(http.MethodPut, "/admin/dns/records/"+domain, owned).Code)
+
+	// 手动修改和删除自动维护的记录返回409
+	rec := do(http.MethodPut, "/admin/dns/records/"+domain, `{"type": "A", "value": "10.0.0.2"}`)
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	var errResp ErrorResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &errResp))
+	assert.Equal(t, CodeRecordOwned, errResp.Code)
+	assert.Equal(t, http.StatusConflict, do(http.MethodDelete, "/admin/dns/records/"+domain+"/A", "").Code)
+
+	// 无效的所有者
+	rec = do(http.MethodPut, "/admin/dns/records/"+domain, `{"type": "A", "value": "10.0.0.2", "owner": {"kind": "zone", "name": "x"}}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	// force=true时强制修改，记录成为手动记录
+	rec = do(http.MethodPut, "/admin/dns/records/"+domain+"?force=true", `{"type": "A", "value": "10.0.0.2"}`)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.NotEmpty(t, rec.Header().Get("ETag"))
+	entry, err := client.GetDNSRecordEntry(context.Background(), domain, "A", "")
+	require.NoError(t, err)
+	assert.Equal(t, "10.0.0.2", entry.Value)
+	assert.Nil(t, entry.Owner)
+
+	// 强制删除自动维护的记录
+	require.Equal(t, http.StatusOK, do(http.MethodPut, "/admin/dns/records/"+domain+"?force=true", owned).Code)
+	assert.Equal(t, http.StatusOK, do(http.MethodDelete, "/admin/dns/records/"+domain+"/A?force=true", "").Code)
+	assert.Equal(t, http.StatusNotFound, do(http.MethodDelete, "/admin/dns/records/"+domain+"/A?force=true", "").Code)
+}
+
+func TestDNSRecordBatchEndpoint(t *testing.T) {
+	// 跳过集成测试，除非明确要求运行
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	cfg := createTestConfig(t)
+	logger := createTestLogger(t)
+	e := echo.New()
+
+	client := etcdclient.CreateEtcdClientForTest(t)
+	defer client.Close()
+
+	handler := &EchoHandler{
+		managementServer: e,
+		cfg:              cfg,
+		logger:           logger,
+		etcdClient:       client,
+	}
+	handler.registerManagementRoutes()
+
+	prefix := fmt.Sprintf("batch-api-%d", time.Now().UnixNano())
+	defer func() {
+		for _, name := range []string{"a", "b"} {
+			_ = client.DeleteDNSRecord(context.Background(), name+"."+prefix+".example.com", "A", "")
+		}
+	}()
+
+	do := func(body string) (*httptest.ResponseRecorder, DNSRecordBatchResponse) {
+		req := httptest.NewRequest(http.MethodPost, "/admin/dns/records/batch", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+
+		var resp DNSRecordBatchResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		return rec, resp
+	}
+
+	// 无效记录在提交前被拒绝，其余项标记为aborted
+	rec, resp := do(fmt.Sprintf(`{"operations": [
+		{"op": "put", "domain": "a.%[1]s.example.com", "record": {"type": "A", "value": "10.0.0.1"}},
+		{"op": "put", "domain": "b.%[1]s.example.com", "record": {"type": "A", "value": "bad-ip"}}
+	]}`, prefix))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.False(t, resp.Success)
+	assert.Equal(t, CodeInvalidParameter, resp.Code)
+	require.Len(t, resp.Results, 2)
+	assert.Equal(t, etcdclient.BatchStatusAborted, resp.Results[0].Status)
+	assert.Equal(t, etcdclient.BatchStatusInvalid, resp.Results[1].Status)
+
+	// 全部成功
+	rec, resp = do(fmt.Sprintf(`{"operations": [
+		{"op": "put", "domain": "a.%[1]s.example.com", "record": {"type": "a", "value": "10.0.0.1"}},
+		{"op": "put", "domain": "b.%[1]s.example.com", "record": {"type": "A", "value": "10.0.0.2"}, "revision": 0}
+	]}`, prefix))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.True(t, resp.Success)
+	require.Len(t, resp.Results, 2)
+	assert.Equal(t, etcdclient.BatchStatusApplied, resp.Results[1].Status)
+
+	// 删除不存在的记录导致整个批次回滚
+	rec, resp = do(fmt.Sprintf(`{"operations": [
+		{"op": "delete", "domain": "a.%[1]s.example.com", "type": "a"},
+		{"op": "delete", "domain": "c.%[1]s.example.com", "type": "A"}
+	]}`, prefix))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Equal(t, CodeDNSRecordNotFound, resp.Code)
+	assert.Equal(t, etcdclient.BatchStatusNotFound, resp.Results[1].Status)
+
+	_, err := client.GetDNSRecordEntry(context.Background(), "a."+prefix+".example.com", "A", "")
+	assert.NoError(t, err, "失败的批次不应删除任何记录")
+}
+
+func TestResolveDebug(t *testing.T) {
+	// 准备测试配置，无需etcd
+	cfg := &config.Config{}
+	logger := createTestLogger(t)
+	e := echo.New()
+
+	handler := &EchoHandler{
+		managementServer: e,
+		cfg:              cfg,
+		logger:           logger,
+		dnsServer:        dnsserver.NewDNSServer(cfg, logger),
+	}
+	handler.registerManagementRoutes()
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/dns/resolve?name=test.local&type=A", nil)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var response ResolveResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	assert.True(t, response.Success)
+	require.NotNil(t, response.Trace)
+	assert.Equal(t, "NOERROR", response.Trace.Rcode)
+	assert.NotEmpty(t, response.Trace.Wire)
+
+	// 缺少域名参数
+	req = httptest.NewRequest(http.MethodGet, "/admin/dns/resolve", nil)
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestDNSStats(t *testing.T) {
+	// 准备测试配置，无需etcd
+	cfg := &config.Config{}
+	logger := createTestLogger(t)
+	e := echo.New()
+
+	handler := &EchoHandler{
+		managementServer: e,
+		cfg:              cfg,
+		logger:           logger,
+		dnsServer:        dnsserver.NewDNSServer(cfg, logger),
+	}
+	handler.registerManagementRoutes()
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/stats/dns?window=1m&top=5", nil)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var response DNSStatsResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	assert.True(t, response.Success)
+	require.NotNil(t, response.Stats)
+	assert.Equal(t, "1m0s", response.Stats.Window)
+	assert.Equal(t, uint64(0), response.Stats.Queries)
+
+	// 超出最大窗口
+	req = httptest.NewRequest(http.MethodGet, "/admin/stats/dns?window=1h", nil)
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	// 无效的top参数
+	req = httptest.NewRequest(http.MethodGet, "/admin/stats/dns?top=abc", nil)
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestScheduledChangeEndpoints(t *testing.T) {
+	// 跳过集成测试，除非明确要求运行
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	cfg := createTestConfig(t)
+	logger := createTestLogger(t)
+	e := echo.New()
+
+	client := etcdclient.CreateEtcdClientForTest(t)
+	defer client.Close()
+
+	handler := &EchoHandler{
+		managementServer: e,
+		cfg:              cfg,
+		logger:           logger,
+		etcdClient:       client,
+	}
+	handler.registerManagementRoutes()
+
+	domain := fmt.Sprintf("scheduled-api-%d.example.com", time.Now().UnixNano())
+	applyAt := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+
+	do := func(method, path, body string) (*httptest.ResponseRecorder, ScheduledChangesResponse) {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+
+		var resp ScheduledChangesResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		return rec, resp
+	}
+
+	// 缺少生效时间
+	rec, _ := do(http.MethodPost, "/admin/dns/scheduled", fmt.Sprintf(`{"operations": [
+		{"op": "put", "domain": "%s", "record": {"type": "A", "value": "10.0.0.1"}}
+	]}`, domain))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	// 无效记录在保存前被拒绝
+	rec, _ = do(http.MethodPost, "/admin/dns/scheduled", fmt.Sprintf(`{"apply_at": %q, "operations": [
+		{"op": "put", "domain": "%s", "record": {"type": "A", "value": "bad-ip"}}
+	]}`, applyAt, domain))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	// 创建
+	rec, resp := do(http.MethodPost, "/admin/dns/scheduled", fmt.Sprintf(`{"apply_at": %q, "comment": "迁移", "operations": [
+		{"op": "put", "domain": "%s.", "record": {"type": "a", "value": "10.0.0.1"}}
+	]}`, applyAt, domain))
+	require.Equal(t, http.StatusAccepted, rec.Code)
+	require.Len(t, resp.Changes, 1)
+	id := resp.Changes[0].ID
+	assert.NotEmpty(t, id)
+	assert.Equal(t, etcdclient.ScheduleStatusPending, resp.Changes[0].Status)
+	assert.Equal(t, domain, resp.Changes[0].Operations[0].Domain)
+	defer func() { _ = client.CancelScheduledChange(context.Background(), id) }()
+
+	// 批量接口携带apply_at时同样保存为定时变更
+	rec, resp = do(http.MethodPost, "/admin/dns/records/batch", fmt.Sprintf(`{"apply_at": %q, "operations": [
+		{"op": "delete", "domain": "%s", "type": "A"}
+	]}`, applyAt, domain))
+	require.Equal(t, http.StatusAccepted, rec.Code)
+	require.Len(t, resp.Changes, 1)
+	batchID := resp.Changes[0].ID
+	defer func() { _ = client.CancelScheduledChange(context.Background(), batchID) }()
+
+	// 列表和按状态过滤
+	rec, resp = do(http.MethodGet, "/admin/dns/scheduled?status=pending", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var found int
+	for _, change := range resp.Changes {
+		if change.ID == id || change.ID == batchID {
+			found++
+		}
+	}
+	assert.Equal(t, 2, found)
+
+	rec, resp = do(http.MethodGet, "/admin/dns/scheduled?status=failed", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	for _, change := range resp.Changes {
+		assert.NotEqual(t, id, change.ID)
+	}
+
+	rec, resp = do(http.MethodGet, "/admin/dns/scheduled/"+id, "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	require.Len(t, resp.Changes, 1)
+	assert.Equal(t, "迁移", resp.Changes[0].Comment)
+
+	// 取消后不存在
+	rec, _ = do(http.MethodDelete, "/admin/dns/scheduled/"+id, "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	req := httptest.NewRequest(http.MethodDelete, "/admin/dns/scheduled/"+id, nil)
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	var errResp ErrorResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &errResp))
+	assert.Equal(t, CodeScheduledChangeNotFound, errResp.Code)
+
+	// 记录尚未生效
+	_, err := client.GetDNSRecordEntry(context.Background(), domain, "A", "")
+	assert.ErrorIs(t, err, etcdclient.ErrDNSRecordNotFound)
+}
+
+func TestTracingMiddleware(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previousProvider, previousPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previousProvider)
+		otel.SetTextMapPropagator(previousPropagator)
+	})
+
+	e := echo.New()
+	e.HTTPErrorHandler = httpErrorHandler
+	e.Use(tracingMiddleware("management"))
+
+	var handlerSpan trace.SpanContext
+	e.GET("/admin/services/:serviceName", func(c echo.Context) error {
+		handlerSpan = trace.SpanContextFromContext(c.Request().Context())
+		return respondError(c, newAPIError(http.StatusServiceUnavailable, CodeStorageError, "etcd不可用"))
+	})
+
+	// 继承请求头中的trace上下文
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	req := httptest.NewRequest(http.MethodGet, "/admin/services/user-service", nil)
+	req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	span := spans[0]
+	assert.Equal(t, "GET /admin/services/:serviceName", span.Name())
+	assert.Equal(t, traceID, span.SpanContext().TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", span.Parent().SpanID().String())
+	assert.Equal(t, span.SpanContext().SpanID(), handlerSpan.SpanID(), "处理函数应能从请求上下文获取span")
+	assert.Contains(t, span.Attributes(), attribute.Int("http.status_code", http.StatusServiceUnavailable))
+}
+
+func TestLogLevelsEndpoints(t *testing.T) {
+	cfg := &config.Config{}
+	logger, err := config.NewLoggerFromConfig(cfg)
+	require.NoError(t, err)
+	e := echo.New()
+
+	handler := &EchoHandler{
+		managementServer: e,
+		cfg:              cfg,
+		logger:           logger.Named(config.ComponentAPI),
+	}
+	handler.registerManagementRoutes()
+
+	do := func(method, body string) (*httptest.ResponseRecorder, LogLevelsResponse) {
+		req := httptest.NewRequest(method, "/admin/log/levels", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+
+		var resp LogLevelsResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		return rec, resp
+	}
+
+	rec, resp := do(http.MethodGet, "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, resp.Levels, config.ComponentLevel{Component: config.ComponentDNS, Level: "info", Inherited: true})
+
+	// 设置组件级别
+	rec, resp = do(http.MethodPut, `{"component": "dns", "level": "debug"}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, resp.Levels, config.ComponentLevel{Component: config.ComponentDNS, Level: "debug"})
+
+	// 省略组件时设置全局级别
+	rec, resp = do(http.MethodPut, `{"level": "warn"}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, config.ComponentLevel{Component: config.RootComponent, Level: "warn"}, resp.Levels[0])
+
+	// 无效的组件和级别
+	rec, _ = do(http.MethodPut, `{"component": "kong", "level": "debug"}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	rec, _ = do(http.MethodPut, `{"component": "dns", "level": "verbose"}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	rec, _ = do(http.MethodPut, `{"component": "root", "level": ""}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestDiscoveryEndpoint(t *testing.T) {
+	// 跳过集成测试，除非明确要求运行
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	cfg := createTestConfig(t)
+	logger := createTestLogger(t)
+	e := echo.New()
+
+	client := etcdclient.CreateEtcdClientForTest(t)
+	t.Cleanup(func() { _ = client.Close() })
+
+	handler := &EchoHandler{
+		registrationServer: e,
+		cfg:                cfg,
+		logger:             logger,
+		etcdClient:         client,
+	}
+	handler.registerRegistrationRoutes()
+
+	ctx := context.Background()
+	serviceName := fmt.Sprintf("discovery-api-%d", time.Now().UnixNano())
+	register := func(id string) {
+		require.NoError(t, client.RegisterService(ctx, &etcdclient.ServiceInstance{
+			ServiceName: serviceName, InstanceID: id, IPAddress: "10.0.0.1", Port: 8080, TTL: 30,
+			Metadata: map[string]string{"version": "1.0.0"},
+		}))
+		t.Cleanup(func() { _ = client.DeregisterService(context.Background(), serviceName, id) })
+	}
+
+	get := func(query string, header map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/v1/discovery/default/"+serviceName+query, nil)
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// 服务不存在
+	rec := get("", nil)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	register("instance-001")
+	rec = get("", nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+	var resp DiscoveryResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Len(t, resp.Instances, 1)
+	assert.Equal(t, "1.0.0", resp.Instances[0].Metadata["version"])
+	assert.Equal(t, fmt.Sprint(resp.Index), rec.Header().Get(indexHeader))
+	etag := rec.Header().Get("ETag")
+	require.NotEmpty(t, etag)
+
+	// 内容未变时条件请求返回304
+	rec = get("", map[string]string{"If-None-Match": etag})
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+
+	// 参数校验
+	assert.Equal(t, http.StatusBadRequest, get("?index=abc", nil).Code)
+	assert.Equal(t, http.StatusBadRequest, get("?index=1&wait=-1s", nil).Code)
+
+	// 没有变更时等待到超时后返回当前状态
+	start := time.Now()
+	rec = get(fmt.Sprintf("?index=%d&wait=200ms", resp.Index), nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.GreaterOrEqual(t, time.Since(start), 200*time.Millisecond)
+	assert.Equal(t, etag, rec.Header().Get("ETag"))
+
+	// 长轮询在服务变更时返回
+	done := make(chan *httptest.ResponseRecorder, 1)
+	go func() { done <- get(fmt.Sprintf("?index=%d&wait=5s", resp.Index), nil) }()
+	time.Sleep(100 * time.Millisecond)
+	register("instance-002")
+
+	select {
+	case rec = <-done:
+		require.Equal(t, http.StatusOK, rec.Code)
+		var changed DiscoveryResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &changed))
+		assert.Len(t, changed.Instances, 2)
+		assert.Greater(t, changed.Index, resp.Index)
+		assert.NotEqual(t, etag, rec.Header().Get("ETag"))
+	case <-time.After(3 * time.Second):
+		t.Fatal("服务变更后长轮询应返回")
+	}
+}
+
+func TestServiceListBlockingQuery(t *testing.T) {
+	// 跳过集成测试，除非明确要求运行
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	cfg := createTestConfig(t)
+	logger := createTestLogger(t)
+	e := echo.New()
+
+	client := etcdclient.CreateEtcdClientForTest(t)
+	t.Cleanup(func() { _ = client.Close() })
+
+	handler := &EchoHandler{
+		managementServer: e,
+		cfg:              cfg,
+		logger:           logger,
+		etcdClient:       client,
+	}
+	handler.registerManagementRoutes()
+
+	ctx := context.Background()
+	serviceName := fmt.Sprintf("blocking-api-%d", time.Now().UnixNano())
+	register := func(id string) {
+		require.NoError(t, client.RegisterService(ctx, &etcdclient.ServiceInstance{
+			ServiceName: serviceName, InstanceID: id, IPAddress: "10.0.0.1", Port: 8080, TTL: 30,
+		}))
+		t.Cleanup(func() { _ = client.DeregisterService(context.Background(), serviceName, id) })
+	}
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	// 服务不存在时也返回index，可以阻塞等待服务上线
+	rec := get("/admin/services/" + serviceName)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	index, err := strconv.ParseInt(rec.Header().Get(indexHeader), 10, 64)
+	require.NoError(t, err)
+	require.Greater(t, index, int64(0))
+
+	done := make(chan *httptest.ResponseRecorder, 1)
+	go func() { done <- get(fmt.Sprintf("/admin/services/%s?index=%d&wait=5s", serviceName, index)) }()
+	time.Sleep(100 * time.Millisecond)
+	register("instance-001")
+
+	select {
+	case rec = <-done:
+		require.Equal(t, http.StatusOK, rec.Code)
+		var resp ServiceInstancesResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		assert.Len(t, resp.Instances, 1)
+		assert.Greater(t, resp.Index, index)
+		assert.Equal(t, fmt.Sprint(resp.Index), rec.Header().Get(indexHeader))
+	case <-time.After(3 * time.Second):
+		t.Fatal("服务上线后阻塞查询应返回")
+	}
+
+	// 服务列表：没有变更时等待到超时后返回
+	rec = get("/admin/services")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var list ServiceListResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &list))
+	require.Greater(t, list.Index, int64(0))
+
+	start := time.Now()
+	rec = get(fmt.Sprintf("/admin/services?index=%d&wait=200ms", list.Index))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.GreaterOrEqual(t, time.Since(start), 200*time.Millisecond)
+
+	// 任意服务变更都会唤醒服务列表的阻塞查询
+	go func() { done <- get(fmt.Sprintf("/admin/services?index=%d&wait=5s", list.Index)) }()
+	time.Sleep(100 * time.Millisecond)
+	register("instance-002")
+
+	select {
+	case rec = <-done:
+		require.Equal(t, http.StatusOK, rec.Code)
+		var changed ServiceListResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &changed))
+		assert.Greater(t, changed.Index, list.Index)
+	case <-time.After(3 * time.Second):
+		t.Fatal("服务变更后阻塞查询应返回")
+	}
+
+	assert.Equal(t, http.StatusBadRequest, get("/admin/services?index=-1").Code)
+}
+
+func TestReconcileEndpoints(t *testing.T) {
+	// 跳过集成测试，除非明确要求运行
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	cfg := createTestConfig(t)
+	logger := createTestLogger(t)
+	e := echo.New()
+
+	client := etcdclient.CreateEtcdClientForTest(t)
+	t.Cleanup(func() { _ = client.Close() })
+
+	handler := &EchoHandler{
+		managementServer: e,
+		cfg:              cfg,
+		logger:           logger,
+		etcdClient:       client,
+	}
+	handler.registerManagementRoutes()
+
+	ctx := context.Background()
+	serviceName := fmt.Sprintf("reconcile-api-%d", time.Now().UnixNano())
+	domain := serviceName + ".default.svc.cluster.local"
+	require.NoError(t, client.PutDNSRecord(ctx, domain, &etcdclient.DNSRecord{Type: "A", Value: "10.0.0.1", Owner: etcdclient.ServiceOwner(serviceName)}))
+	t.Cleanup(func() {
+		_, _ = client.ApplyDNSRecordBatch(context.Background(), []*etcdclient.DNSRecordOperation{
+			{Op: etcdclient.DNSRecordOpDelete, Domain: domain, Type: "A", Force: true},
+		})
+	})
+
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodGet, "/admin/reconcile/report", nil),
+		httptest.NewRequest(http.MethodPost, "/admin/reconcile?dry_run=true", nil),
+	} {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		var resp ReconcileResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		require.NotNil(t, resp.Report)
+		assert.True(t, resp.Report.DryRun)
+		assert.GreaterOrEqual(t, resp.Counts[etcdclient.DriftOrphanedDNSRecord], 1)
+
+		var found bool
+		for _, finding := range resp.Report.Findings {
+			if finding.Key == "/dns/records/"+domain+"/A" {
+				found = true
+				assert.Equal(t, etcdclient.DriftOrphanedDNSRecord, finding.Kind)
+				assert.False(t, finding.Repaired)
+			}
+		}
+		assert.True(t, found, "报告应包含残留的服务记录")
+	}
+
+	// 只读检查不修改数据
+	_, err := client.GetDNSRecordEntry(ctx, domain, "A", "")
+	assert.NoError(t, err)
+
+	// 未启用后台协调时统计为空
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/admin/reconcile/stats", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var stats ReconcileStatsResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &stats))
+	assert.False(t, stats.Enabled)
+	assert.Nil(t, stats.Stats)
+
+	// 启用后返回协调任务的统计
+	handler.SetReconciler(reconciler.NewReconciler(cfg, logger, client))
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/admin/reconcile/stats", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &stats))
+	assert.True(t, stats.Enabled)
+	require.NotNil(t, stats.Stats)
+	assert.False(t, stats.Stats.Leading)
+	assert.Equal(t, 0, stats.Stats.Runs)
+}
+
+func TestRegistrationPolicyEndpoints(t *testing.T) {
+	// 跳过集成测试，除非明确要求运行
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	cfg := createTestConfig(t)
+	logger := createTestLogger(t)
+	management, registration := echo.New(), echo.New()
+
+	client := etcdclient.CreateEtcdClientForTest(t)
+	t.Cleanup(func() { _ = client.Close() })
+
+	handler := &EchoHandler{
+		managementServer:   management,
+		registrationServer: registration,
+		cfg:                cfg,
+		logger:             logger,
+		etcdClient:         client,
+	}
+	handler.registerManagementRoutes()
+	handler.registerRegistrationRoutes()
+
+	serviceName := fmt.Sprintf("policy-service-%d", time.Now().UnixNano())
+	policyPath := "/admin/services/" + serviceName + "/registration-policy"
+	t.Cleanup(func() {
+		_ = client.DeleteRegistrationPolicy(context.Background(), serviceName)
+		_ = client.DeregisterService(context.Background(), serviceName, "instance-001")
+	})
+
+	serve := func(e *echo.Echo, method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// 未设置时返回全局默认策略
+	rec := serve(management, http.MethodGet, policyPath, "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var policy RegistrationPolicyResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &policy))
+	assert.Equal(t, etcdclient.DuplicateAllow, policy.DuplicateAddress)
+	assert.Equal(t, "default", policy.Source)
+
+	// 无效的策略
+	rec = serve(management, http.MethodPut, policyPath, `{"duplicate_address": "ignore"}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = serve(management, http.MethodPut, policyPath, `{"duplicate_address": "reject"}`)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	// 相同地址以不同实例ID注册时返回409
+	register := func(id string) *httptest.ResponseRecorder {
+		body := fmt.Sprintf(`{"service_name": "%s", "instance_id": "%s", "ip_address": "10.0.0.1", "port": 8080, "ttl": 30}`, serviceName, id)
+		return serve(registration, http.MethodPost, "/services/register", body)
+	}
+	require.Equal(t, http.StatusOK, register("instance-001").Code)
+	rec = register("instance-002")
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	var errResp ErrorResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &errResp))
+	assert.Equal(t, CodeDuplicateAddress, errResp.Code)
+
+	// 删除后恢复默认策略
+	rec = serve(management, http.MethodDelete, policyPath, "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &policy))
+	assert.Equal(t, "default", policy.Source)
+}
+
+func TestServiceACLEndpoints(t *testing.T) {
+	// 跳过集成测试，除非明确要求运行
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	e := echo.New()
+	client := etcdclient.CreateEtcdClientForTest(t)
+	t.Cleanup(func() { _ = client.Close() })
+
+	handler := &EchoHandler{
+		managementServer: e,
+		cfg:              createTestConfig(t),
+		logger:           createTestLogger(t),
+		etcdClient:       client,
+	}
+	handler.registerManagementRoutes()
+
+	serviceName := fmt.Sprintf("acl-service-%d", time.Now().UnixNano())
+	aclPath := "/admin/services/" + serviceName + "/acl"
+	t.Cleanup(func() { _ = client.DeleteServiceACL(context.Background(), serviceName) })
+
+	serve := func(method, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, aclPath, strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// 未设置时ACL为空
+	rec := serve(http.MethodGet, "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var resp ServiceACLResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Nil(t, resp.ACL)
+
+	// 无效的网段和动作
+	rec = serve(http.MethodPut, `{"allow_cidrs": ["10.0.0.1"]}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	rec = serve(http.MethodPut, `{"allow_cidrs": ["10.0.0.0/8"], "action": "drop"}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = serve(http.MethodPut, `{"allow_cidrs": ["10.0.0.0/8"], "allow_identities": ["Billing-Key."], "action": "refused"}`)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	rec = serve(http.MethodGet, "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	resp = ServiceACLResponse{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.NotNil(t, resp.ACL)
+	assert.Equal(t, []string{"10.0.0.0/8"}, resp.ACL.AllowCIDRs)
+	assert.Equal(t, []string{"billing-key"}, resp.ACL.AllowIdentities)
+	assert.Equal(t, etcdclient.ACLActionRefused, resp.ACL.Action)
+
+	rec = serve(http.MethodDelete, "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	acl, err := client.GetServiceACL(context.Background(), serviceName)
+	require.NoError(t, err)
+	assert.Nil(t, acl)
+}
+
+func TestServiceAnswerPolicyEndpoints(t *testing.T) {
+	// 跳过集成测试，除非明确要求运行
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	e := echo.New()
+	client := etcdclient.CreateEtcdClientForTest(t)
+	t.Cleanup(func() { _ = client.Close() })
+
+	handler := &EchoHandler{
+		managementServer: e,
+		cfg:              createTestConfig(t),
+		logger:           createTestLogger(t),
+		etcdClient:       client,
+	}
+	handler.registerManagementRoutes()
+
+	serviceName := fmt.Sprintf("answer-policy-service-%d", time.Now().UnixNano())
+	policyPath := "/admin/services/" + serviceName + "/answer-policy"
+	t.Cleanup(func() { _ = client.DeleteServiceAnswerPolicy(context.Background(), serviceName) })
+
+	serve := func(method, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, policyPath, strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// 未设置时策略为空
+	rec := serve(http.MethodGet, "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var resp ServiceAnswerPolicyResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Nil(t, resp.Policy)
+
+	// 不支持的地址应答方式和顺序
+	rec = serve(http.MethodPut, `{"address_answers": "two"}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	rec = serve(http.MethodPut, `{"order": "weighted"}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = serve(http.MethodPut, `{"address_answers": "all", "order": "random", "include_draining": true}`)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	rec = serve(http.MethodGet, "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	resp = ServiceAnswerPolicyResponse{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.NotNil(t, resp.Policy)
+	assert.Equal(t, etcdclient.AddressAnswersAll, resp.Policy.AddressAnswers)
+	assert.Equal(t, etcdclient.AnswerOrderRandom, resp.Policy.Order)
+	assert.True(t, resp.Policy.IncludeDraining)
+
+	rec = serve(http.MethodDelete, "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	policy, err := client.GetServiceAnswerPolicy(context.Background(), serviceName)
+	require.NoError(t, err)
+	assert.Nil(t, policy)
+}
+
+func TestTTLPolicyEndpoints(t *testing.T) {
+	// 跳过集成测试，除非明确要求运行
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	e := echo.New()
+	client := etcdclient.CreateEtcdClientForTest(t)
+	t.Cleanup(func() { _ = client.Close() })
+
+	handler := &EchoHandler{
+		managementServer: e,
+		cfg:              createTestConfig(t),
+		logger:           createTestLogger(t),
+		etcdClient:       client,
+	}
+	handler.registerManagementRoutes()
+
+	serviceName := fmt.Sprintf("ttl-policy-service-%d", time.Now().UnixNano())
+	namespace := fmt.Sprintf("ttl-ns-%d", time.Now().UnixNano())
+	t.Cleanup(func() { _ = client.DeleteServiceTTLPolicy(context.Background(), serviceName) })
+
+	serve := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// 服务TTL策略
+	servicePath := "/admin/services/" + serviceName + "/ttl-policy"
+	rec := serve(http.MethodGet, servicePath, "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var serviceResp ServiceTTLPolicyResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &serviceResp))
+	assert.Nil(t, serviceResp.Policy)
+
+	rec = serve(http.MethodPut, servicePath, `{"min_ttl": 600, "max_ttl": 60}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	rec = serve(http.MethodPut, servicePath, `{"max_ttl": -1}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = serve(http.MethodPut, servicePath, `{"default_ttl": 30, "max_ttl": 300}`)
+	require.Equal(t, http.StatusOK, rec.Code)
+	rec = serve(http.MethodGet, servicePath, "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	serviceResp = ServiceTTLPolicyResponse{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &serviceResp))
+	require.NotNil(t, serviceResp.Policy)
+	assert.Equal(t, 30, serviceResp.Policy.DefaultTTL)
+	assert.Equal(t, 300, serviceResp.Policy.MaxTTL)
+
+	rec = serve(http.MethodDelete, servicePath, "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	policy, err := client.GetServiceTTLPolicy(context.Background(), serviceName)
+	require.NoError(t, err)
+	assert.Nil(t, policy)
+
+	// 命名空间TTL策略，命名空间不存在时返回404
+	namespacePath := "/admin/namespaces/" + namespace + "/ttl-policy"
+	rec = serve(http.MethodPut, namespacePath, `{"default_ttl": 120}`)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	require.NoError(t, client.CreateNamespace(context.Background(), &etcdclient.Namespace{Name: namespace}))
+	t.Cleanup(func() { _ = client.DeleteNamespace(context.Background(), namespace, true) })
+
+	rec = serve(http.MethodPut, namespacePath, `{"default_ttl": 120, "max_ttl": 600}`)
+	require.Equal(t, http.StatusOK, rec.Code)
+	rec = serve(http.MethodGet, namespacePath, "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var namespaceResp NamespaceTTLPolicyResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &namespaceResp))
+	assert.Equal(t, &etcdclient.TTLPolicy{DefaultTTL: 120, MaxTTL: 600}, namespaceResp.Policy)
+
+	rec = serve(http.MethodDelete, namespacePath, "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	nsPolicy, err := client.GetNamespaceTTLPolicy(context.Background(), namespace)
+	require.NoError(t, err)
+	assert.Nil(t, nsPolicy)
+}
+
+func TestWebhookEndpoints(t *testing.T) {
+	// 跳过集成测试，除非明确要求运行
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	cfg := createTestConfig(t)
+	logger := createTestLogger(t)
+	e := echo.New()
+
+	client := etcdclient.CreateEtcdClientForTest(t)
+	t.Cleanup(func() { _ = client.Close() })
+
+	handler := &EchoHandler{
+		managementServer: e,
+		cfg:              cfg,
+		logger:           logger,
+		etcdClient:       client,
+	}
+	handler.registerManagementRoutes()
+
+	serve := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// 无效的回调地址和事件类型
+	rec := serve(http.MethodPost, "/admin/webhooks", `{"url": "not-a-url"}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	rec = serve(http.MethodPost, "/admin/webhooks", `{"url": "https://example.com/hook", "events": ["unknown"]}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = serve(http.MethodPost, "/admin/webhooks", `{"url": "https://example.com/hook", "secret": "secret", "events": ["service.registered"]}`)
+	require.Equal(t, http.StatusCreated, rec.Code)
+	var resp WebhooksResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Len(t, resp.Webhooks, 1)
+	id := resp.Webhooks[0].ID
+	t.Cleanup(func() { _ = client.DeleteWebhook(context.Background(), id) })
+
+	// 响应中不返回密钥
+	assert.True(t, resp.Webhooks[0].HasSecret)
+	assert.NotContains(t, rec.Body.String(), `"secret"`)
+
+	// 更新时未提供密钥则保留原密钥
+	rec = serve(http.MethodPut, "/admin/webhooks/"+id, `{"url": "https://example.com/hook2", "disabled": true}`)
+	require.Equal(t, http.StatusOK, rec.Code)
+	stored, err := client.GetWebhook(context.Background(), id)
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/hook2", stored.URL)
+	assert.Equal(t, "secret", stored.Secret)
+	assert.True(t, stored.Disabled)
+	assert.Empty(t, stored.Events)
+
+	rec = serve(http.MethodGet, "/admin/webhooks", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	found := false
+	for _, webhook := range resp.Webhooks {
+		found = found || webhook.ID == id
+	}
+	assert.True(t, found)
+
+	// 投递记录
+	delivery := &etcdclient.WebhookDelivery{
+		ID:        etcdclient.NewWebhookDeliveryID(),
+		WebhookID: id,
+		Event:     etcdclient.WebhookServiceRegistered,
+		Status:    etcdclient.DeliveryFailed,
+		Attempts:  5,
+		LastError: "回调地址返回状态码 500",
+	}
+	require.NoError(t, client.PutWebhookDelivery(context.Background(), delivery))
+	rec = serve(http.MethodGet, "/admin/webhooks/"+id+"/deliveries", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var deliveries WebhookDeliveriesResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &deliveries))
+	require.Len(t, deliveries.Deliveries, 1)
+	assert.Equal(t, etcdclient.DeliveryFailed, deliveries.Deliveries[0].Status)
+
+	// 删除后返回404
+	rec = serve(http.MethodDelete, "/admin/webhooks/"+id, "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	for _, path := range []string{"/admin/webhooks/" + id, "/admin/webhooks/" + id + "/deliveries"} {
+		rec = serve(http.MethodGet, path, "")
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		var errResp ErrorResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &errResp))
+		assert.Equal(t, CodeWebhookNotFound, errResp.Code)
+	}
+}
+
+func TestBackupRestoreEndpoints(t *testing.T) {
+	// 跳过集成测试，除非明确要求运行
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	cfg := createTestConfig(t)
+	logger := createTestLogger(t)
+	e := echo.New()
+
+	client := etcdclient.CreateEtcdClientForTest(t)
+	t.Cleanup(func() { _ = client.Close() })
+
+	handler := &EchoHandler{
+		managementServer: e,
+		cfg:              cfg,
+		logger:           logger,
+		etcdClient:       client,
+	}
+	handler.registerManagementRoutes()
+
+	serve := func(method, path string, body []byte) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewReader(body))
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	domain := fmt.Sprintf("backup-%d.example.com", time.Now().UnixNano())
+	recordKey := "/dns/records/" + domain + "/A"
+	t.Cleanup(func() { _ = client.DeleteDNSRecord(context.Background(), domain, "A", "") })
+	require.NoError(t, client.PutDNSRecord(context.Background(), domain, &etcdclient.DNSRecord{Type: "A", Value: "10.0.0.1", TTL: 60}))
+
+	// 无效的数据类别
+	rec := serve(http.MethodGet, "/admin/backup?kinds=dns,election", nil)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = serve(http.MethodGet, "/admin/backup?kinds=dns", nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/gzip", rec.Header().Get(echo.HeaderContentType))
+	revision := rec.Header().Get(HeaderBackupRevision)
+	assert.NotEmpty(t, revision)
+	assert.Contains(t, rec.Header().Get(echo.HeaderContentDisposition), "kong-discovery-"+revision+".tar.gz")
+
+	snapshot, err := etcdclient.ReadArchive(rec.Body)
+	require.NoError(t, err)
+	assert.Equal(t, revision, fmt.Sprint(snapshot.Revision))
+	require.Contains(t, snapshot.Entries, etcdclient.BackupDNS)
+	assert.NotContains(t, snapshot.Entries, etcdclient.BackupServices)
+
+	// 只用本测试的记录恢复，避免覆盖其他测试的数据
+	var own []*etcdclient.BackupEntry
+	for _, entry := range snapshot.Entries[etcdclient.BackupDNS] {
+		if entry.Key == recordKey {
+			own = append(own, entry)
+		}
+	}
+	require.Len(t, own, 1)
+	snapshot.Entries = map[string][]*etcdclient.BackupEntry{etcdclient.BackupDNS: own}
+	var archive bytes.Buffer
+	require.NoError(t, snapshot.WriteArchive(&archive))
+
+	require.NoError(t, client.DeleteDNSRecord(context.Background(), domain, "A", ""))
+
+	// 无效的归档
+	rec = serve(http.MethodPost, "/admin/restore", []byte("not an archive"))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	var errResp ErrorResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &errResp))
+	assert.Equal(t, CodeInvalidBackup, errResp.Code)
+
+	rec = serve(http.MethodPost, "/admin/restore?kinds=dns", archive.Bytes())
+	require.Equal(t, http.StatusOK, rec.Code)
+	var resp RestoreResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, 1, resp.Result.Restored[etcdclient.BackupDNS])
+
+	entry, err := client.GetDNSRecordEntry(context.Background(), domain, "A", "")
+	require.NoError(t, err)
+	assert.Equal(t, "10.0.0.1", entry.Value)
+}
+
+func TestFederationEndpoints(t *testing.T) {
+	// 跳过集成测试，除非明确要求运行
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	cfg := createTestConfig(t)
+	logger := createTestLogger(t)
+	e := echo.New()
+
+	client := etcdclient.CreateEtcdClientForTest(t)
+	t.Cleanup(func() { _ = client.Close() })
+
+	handler := &EchoHandler{
+		managementServer: e,
+		cfg:              cfg,
+		logger:           logger,
+		etcdClient:       client,
+	}
+	handler.registerManagementRoutes()
+
+	serve := func(method, path, token, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		if token != "" {
+			req.Header.Set(echo.HeaderAuthorization, "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// 未启用联邦时不导出目录
+	rec := serve(http.MethodGet, "/admin/federation/catalog", "", "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Contains(t, rec.Body.String(), string(CodeFederationDisabled))
+
+	suffix := time.Now().UnixNano()
+	pushed := fmt.Sprintf("pushed-%d", suffix)
+	pulled := fmt.Sprintf("pulled-%d", suffix)
+	t.Cleanup(func() { _, _ = client.DeleteFederatedCluster(context.Background(), pushed) })
+	cfg.Federation.Enabled = true
+	cfg.Federation.ClusterName = "cluster-a"
+	cfg.Federation.Token = "catalog-token"
+	cfg.Federation.StaleAfter = 300
+	cfg.Federation.Peers = []config.FederationPeer{
+		{Name: pushed, Mode: etcdclient.FederationPush, Token: "push-token"},
+		{Name: pulled, URL: "http://cluster-b:8080", Mode: etcdclient.FederationPull},
+	}
+
+	// 拉取本集群目录需要令牌
+	rec = serve(http.MethodGet, "/admin/federation/catalog", "", "")
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	rec = serve(http.MethodGet, "/admin/federation/catalog", "catalog-token", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var catalog etcdclient.Catalog
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &catalog))
+	assert.Equal(t, "cluster-a", catalog.Cluster)
+
+	body := `{"services": {"orders": [{"service_name": "orders", "instance_id": "orders-1", "ip_address": "192.168.1.10", "port": 9000}]}}`
+	tests := []struct {
+		name    string
+		cluster string
+		token   string
+		body    string
+		status  int
+		code    ErrorCode
+	}{
+		{"未配置的集群", "unknown", "push-token", body, http.StatusNotFound, CodeClusterNotFound},
+		{"拉取方式的集群不接受推送", pulled, "", body, http.StatusConflict, CodeFederationConflict},
+		{"令牌无效", pushed, "wrong", body, http.StatusUnauthorized, CodeUnauthorized},
+		{"目录声明的集群不一致", pushed, "push-token", `{"cluster": "other"}`, http.StatusConflict, CodeFederationConflict},
+		{"请求体格式错误", pushed, "push-token", "{", http.StatusBadRequest, CodeInvalidRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(http.MethodPost, "/admin/federation/clusters/"+tt.cluster+"/catalog", tt.token, tt.body)
+			assert.Equal(t, tt.status, rec.Code)
+			assert.Contains(t, rec.Body.String(), string(tt.code))
+		})
+	}
+
+	rec = serve(http.MethodPost, "/admin/federation/clusters/"+pushed+"/catalog", "push-token", body)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var resp FederationClustersResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Len(t, resp.Clusters, 1)
+	assert.Equal(t, etcdclient.LinkHealthy, resp.Clusters[0].State)
+	assert.Equal(t, 1, resp.Clusters[0].Services)
+
+	// 早于已同步目录的推送被拒绝
+	rec = serve(http.MethodPost, "/admin/federation/clusters/"+pushed+"/catalog", "push-token", `{"generated_at": "2020-01-01T00:00:00Z"}`)
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	assert.Contains(t, rec.Body.String(), string(CodeStaleCatalog))
+
+	// 已配置但从未同步的集群也会列出
+	rec = serve(http.MethodGet, "/admin/federation/clusters", "", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	states := make(map[string]string)
+	for _, cluster := range resp.Clusters {
+		states[cluster.Cluster] = cluster.State
+		if cluster.Cluster == pulled {
+			assert.Equal(t, "http://cluster-b:8080", cluster.Source)
+			assert.True(t, cluster.Configured)
+		}
+	}
+	assert.Equal(t, etcdclient.LinkHealthy, states[pushed])
+	assert.Equal(t, etcdclient.LinkStale, states[pulled])
+
+	rec = serve(http.MethodDelete, "/admin/federation/clusters/"+pushed, "", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	rec = serve(http.MethodDelete, "/admin/federation/clusters/"+pushed, "", "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestNamespaceTXTMetadata(t *testing.T) {
+	// 跳过集成测试，除非明确要求运行
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	cfg := createTestConfig(t)
+	logger := createTestLogger(t)
+	e := echo.New()
+
+	client := etcdclient.CreateEtcdClientForTest(t)
+	defer client.Close()
+
+	namespace := fmt.Sprintf("txt-ns-%d", time.Now().UnixNano())
+	require.NoError(t, client.CreateNamespace(context.Background(), &etcdclient.Namespace{Name: namespace}))
+	defer func() { _ = client.DeleteNamespace(context.Background(), namespace, true) }()
+
+	handler := &EchoHandler{
+		managementServer: e,
+		cfg:              cfg,
+		logger:           logger,
+		etcdClient:       client,
+	}
+	handler.registerManagementRoutes()
+
+	put := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, "/admin/namespaces/"+namespace+"/txt-metadata", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// 键中不能包含等号
+	assert.Equal(t, http.StatusBadRequest, put(`{"keys": ["a=b"]}`).Code)
+	assert.Equal(t, http.StatusOK, put(`{"keys": ["version", "region"]}`).Code)
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/namespaces/"+namespace+"/txt-metadata", nil)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+	var resp NamespaceTXTMetadataResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, []string{"version", "region"}, resp.Keys)
+}
+
+func TestCheckResult(t *testing.T) {
+	// 跳过集成测试，除非明确要求运行
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	cfg := createTestConfig(t)
+	logger := createTestLogger(t)
+	e := echo.New()
+
+	client := etcdclient.CreateEtcdClientForTest(t)
+	defer client.Close()
+
+	testServiceName := fmt.Sprintf("test-service-%d", time.Now().UnixNano())
+	testInstanceID := "instance-001"
+	defer cleanupTestData(t, client, testServiceName, testInstanceID)
+
+	err := client.RegisterService(context.Background(), &etcdclient.ServiceInstance{
+		ServiceName: testServiceName,
+		InstanceID:  testInstanceID,
+		IPAddress:   "192.168.1.100",
+		Port:        8080,
+		TTL:         60,
+	})
+	require.NoError(t, err)
+
+	handler := &EchoHandler{
+		registrationServer: e,
+		cfg:                cfg,
+		logger:             logger,
+		etcdClient:         client,
+	}
+	handler.registerRegistrationRoutes()
+
+	push := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, "/services/"+testServiceName+"/"+testInstanceID+"/check-result", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// 检查器的简写结果映射为健康状态
+	rec := push(`{"status": "fail", "output": "connection refused", "checker": "nagios", "ttl": 60}`)
+	require.Equal(t, http.StatusOK, rec.Code)
+	var resp InstanceHealthResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, etcdclient.HealthCritical, resp.Health)
+
+	rec = push(`{"status": "pass"}`)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, etcdclient.HealthPassing, resp.Health)
+
+	// 检查器不能设置摘流状态
+	assert.Equal(t, http.StatusBadRequest, push(`{"status": "draining"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, push(`{"status": "pass", "ttl": -1}`).Code)
+}
+
+func TestClusterPeers(t *testing.T) {
+	// 跳过集成测试，除非明确要求运行
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	cfg := createTestConfig(t)
+	cfg.SelfRegistration.Enabled = true
+	cfg.SelfRegistration.ServiceName = fmt.Sprintf("kong-discovery-%d", time.Now().UnixNano())
+	logger := createTestLogger(t)
+	e := echo.New()
+
+	client := etcdclient.CreateEtcdClientForTest(t)
+	defer client.Close()
+
+	ctx := context.Background()
+	if err := client.CreateNamespace(ctx, &etcdclient.Namespace{Name: etcdclient.SystemNamespace}); err != nil {
+		require.ErrorIs(t, err, etcdclient.ErrNamespaceExists)
+	}
+	for _, id := range []string{"node-b-12345678", "node-a-12345678"} {
+		require.NoError(t, client.RegisterService(ctx, &etcdclient.ServiceInstance{
+			ServiceName: cfg.SelfRegistration.ServiceName,
+			InstanceID:  id,
+			IPAddress:   "10.0.0.1",
+			Port:        53,
+			TTL:         30,
+			Namespace:   etcdclient.SystemNamespace,
+			Metadata:    map[string]string{"hostname": id[:6], "version": "1.0.0"},
+		}))
+		defer func(id string) { _ = client.DeregisterService(ctx, cfg.SelfRegistration.ServiceName, id) }(id)
+	}
+
+	handler := &EchoHandler{
+		managementServer: e,
+		cfg:              cfg,
+		logger:           logger,
+		etcdClient:       client,
+	}
+	handler.registerManagementRoutes()
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/cluster/peers", nil)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var resp ClusterPeersResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Equal(t, 2, resp.Count)
+	assert.Equal(t, "node-a", resp.Peers[0].Hostname, "按主机名排序")
+	assert.Equal(t, "1.0.0", resp.Peers[0].Version)
+	assert.Equal(t, "follower", resp.Peers[0].Role)
+
+	// 系统命名空间不能删除
+	req = httptest.NewRequest(http.MethodDelete, "/admin/namespaces/"+etcdclient.SystemNamespace, nil)
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+}
+
+func TestVersionAndMinSDKVersion(t *testing.T) {
+	// 跳过集成测试，除非明确要求运行
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	cfg := createTestConfig(t)
+	cfg.API.Registration.MinSDKVersion = "1.2.0"
+	logger := createTestLogger(t)
+	e := echo.New()
+
+	client := etcdclient.CreateEtcdClientForTest(t)
+	defer client.Close()
+
+	handler := &EchoHandler{
+		registrationServer: e,
+		cfg:                cfg,
+		logger:             logger,
+		etcdClient:         client,
+	}
+	handler.registerRegistrationRoutes()
+
+	do := func(path string, header map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodDelete, path, nil)
+		if path == "/version" {
+			req.Method = http.MethodGet
+		}
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// 版本信息始终可以获取，旧版本SDK据此得知需要升级到的版本
+	rec := do("/version", map[string]string{version.SDKHeader: "0.1.0"})
+	require.Equal(t, http.StatusOK, rec.Code)
+	var info VersionResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &info))
+	assert.Equal(t, version.Version, info.Version)
+	assert.Equal(t, "1.2.0", info.MinSDKVersion)
+	assert.Contains(t, info.APIVersions, "v1")
+	assert.NotEmpty(t, info.GitCommit)
+
+	// 通过请求头或User-Agent上报的过低版本被拒绝
+	for _, header := range []map[string]string{
+		{version.SDKHeader: "1.1.9"},
+		{"User-Agent": "kong-discovery-sdk/1.0.0"},
+		{version.SDKHeader: "not-a-version"},
+	} {
+		rec = do("/services/order/instance-1", header)
+		require.Equal(t, http.StatusUpgradeRequired, rec.Code, header)
+		var errResp ErrorResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &errResp))
+		assert.Equal(t, CodeSDKVersionUnsupported, errResp.Code)
+		assert.Contains(t, errResp.Detail, "1.2.0")
+		assert.Equal(t, "1.2.0", rec.Header().Get(MinSDKVersionHeader))
+	}
+
+	// 满足最低版本的SDK和非SDK客户端不受限制（请求到达处理器，返回的不是426）
+	for _, header := range []map[string]string{
+		{version.SDKHeader: "1.2.0"},
+		{"User-Agent": "kong-discovery-sdk/2.0.0"},
+		{"User-Agent": "curl/8.5.0"},
+	} {
+		rec = do("/services/order/instance-1", header)
+		assert.NotEqual(t, http.StatusUpgradeRequired, rec.Code, header)
+	}
+}
+
+func TestVersionedRoutes(t *testing.T) {
+	cfg := createTestConfig(t)
+	logger := createTestLogger(t)
+
+	ping := func(c echo.Context) error { return c.String(http.StatusOK, "pong") }
+	get := func(e *echo.Echo, path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	// 默认同时提供/v1路径和带弃用提示的旧路径
+	e := echo.New()
+	handler := &EchoHandler{cfg: cfg, logger: logger}
+	handler.versionedRoutes(e).GET("/services/:serviceName", ping)
+
+	rec := get(e, "/v1/services/order")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Empty(t, rec.Header().Get("Deprecation"))
+
+	rec = get(e, "/services/order")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "true", rec.Header().Get("Deprecation"))
+	assert.Equal(t, `</v1/services/order>; rel="successor-version"`, rec.Header().Get("Link"))
+	assert.Empty(t, rec.Header().Get("Sunset"))
+
+	// 设置停止服务日期后返回Sunset头
+	cfg.API.LegacyPaths.Sunset = "2027-06-30"
+	e = echo.New()
+	handler.versionedRoutes(e).GET("/services/:serviceName", ping)
+	rec = get(e, "/services/order")
+	assert.Equal(t, "Wed, 30 Jun 2027 00:00:00 GMT", rec.Header().Get("Sunset"))
+
+	// 关闭旧路径后只有/v1路径可用
+	cfg.API.LegacyPaths.Disabled = true
+	e = echo.New()
+	handler.versionedRoutes(e).GET("/services/:serviceName", ping)
+	assert.Equal(t, http.StatusOK, get(e, "/v1/services/order").Code)
+	assert.Equal(t, http.StatusNotFound, get(e, "/services/order").Code)
+}
+
+func TestRequestIDMiddleware(t *testing.T) {
+	e := echo.New()
+	e.Use(requestIDMiddleware)
+	e.GET("/ok", func(c echo.Context) error {
+		return c.String(http.StatusOK, requestID(c))
+	})
+	e.GET("/fail", func(c echo.Context) error {
+		return respondError(c, badRequest(CodeInvalidRequest, "服务名称不能为空"))
+	})
+
+	serve := func(path, id string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if id != "" {
+			req.Header.Set(echo.HeaderXRequestID, id)
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// 沿用客户端携带的请求ID
+	rec := serve("/ok", "client-req-1")
+	assert.Equal(t, "client-req-1", rec.Header().Get(echo.HeaderXRequestID))
+	assert.Equal(t, "client-req-1", rec.Body.String())
+
+	// 未携带或携带无效的请求ID时生成新的ID
+	rec = serve("/ok", "")
+	generated := rec.Header().Get(echo.HeaderXRequestID)
+	assert.Len(t, generated, 36)
+	assert.Equal(t, generated, rec.Body.String())
+
+	rec = serve("/ok", "bad id\twith spaces")
+	assert.NotEqual(t, "bad id\twith spaces", rec.Header().Get(echo.HeaderXRequestID))
+	rec = serve("/ok", strings.Repeat("a", maxRequestIDLength+1))
+	assert.Len(t, rec.Header().Get(echo.HeaderXRequestID), 36)
+
+	// 错误响应中返回请求ID
+	rec = serve("/fail", "client-req-2")
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	var resp ErrorResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, "client-req-2", resp.RequestID)
+}
+
+func TestServiceCatalogEndpoints(t *testing.T) {
+	// 跳过集成测试，除非明确要求运行
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	cfg := createTestConfig(t)
+	logger := createTestLogger(t)
+	management := echo.New()
+
+	client := etcdclient.CreateEtcdClientForTest(t)
+	t.Cleanup(func() { _ = client.Close() })
+
+	handler := &EchoHandler{
+		managementServer: management,
+		cfg:              cfg,
+		logger:           logger,
+		etcdClient:       client,
+	}
+	handler.registerManagementRoutes()
+
+	serviceName := fmt.Sprintf("catalog-service-%d", time.Now().UnixNano())
+	catalogPath := "/v1/admin/catalog/" + serviceName
+	t.Cleanup(func() {
+		_ = client.DeleteServiceCatalog(context.Background(), serviceName)
+		_ = client.DeregisterService(context.Background(), serviceName, "instance-001")
+	})
+
+	serve := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		management.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// 未设置时目录信息为空
+	rec := serve(http.MethodGet, catalogPath, "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var resp ServiceCatalogResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Nil(t, resp.Catalog)
+
+	// 无效的链接地址
+	rec = serve(http.MethodPut, catalogPath, `{"owner": "team-pay", "runbook_url": "wiki/payments"}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	rec = serve(http.MethodPut, catalogPath, `{"owner": "team-pay", "links": {"dashboard": "grafana"}}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	// 服务没有实例时也可以设置目录信息
+	rec = serve(http.MethodPut, catalogPath, `{
+		"owner": "team-pay",
+		"slack_channel": "#pay-oncall",
+		"description": "支付服务",
+		"runbook_url": "https://wiki.example.com/payments",
+		"repository": "git@git.example.com:pay/payments.git",
+		"links": {"dashboard": "https://grafana.example.com/d/pay"}
+	}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	rec = serve(http.MethodGet, "/v1/admin/catalog", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	found := false
+	for _, catalog := range resp.Catalogs {
+		if catalog.ServiceName == serviceName {
+			found = true
+			assert.Equal(t, "#pay-oncall", catalog.SlackChannel)
+		}
+	}
+	assert.True(t, found, "目录列表应包含该服务")
+
+	// 实例列表中返回服务目录信息
+	require.NoError(t, client.RegisterService(context.Background(), &etcdclient.ServiceInstance{
+		ServiceName: serviceName,
+		InstanceID:  "instance-001",
+		IPAddress:   "10.0.0.1",
+		Port:        8080,
+		TTL:         30,
+	}))
+	rec = serve(http.MethodGet, "/v1/admin/services/"+serviceName, "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var instances ServiceInstancesResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &instances))
+	require.NotNil(t, instances.Catalog)
+	assert.Equal(t, "team-pay", instances.Catalog.Owner)
+	assert.Equal(t, "https://wiki.example.com/payments", instances.Catalog.RunbookURL)
+
+	rec = serve(http.MethodDelete, catalogPath, "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	rec = serve(http.MethodGet, catalogPath, "")
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Nil(t, resp.Catalog)
+}
+
+func TestDebugEndpoints(t *testing.T) {
+	newHandler := func(enabled bool) *EchoHandler {
+		cfg := createTestConfig(t)
+		cfg.API.Management.Debug.Enabled = enabled
+		cfg.API.Management.Debug.Token = "debug-secret"
+		cfg.API.Management.Debug.DumpDir = t.TempDir()
+		handler := &EchoHandler{
+			managementServer: echo.New(),
+			cfg:              cfg,
+			logger:           createTestLogger(t),
+		}
+		handler.registerManagementRoutes()
+		return handler
+	}
+	serve := func(h *EchoHandler, method, path, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		if token != "" {
+			req.Header.Set(echo.HeaderAuthorization, "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		h.managementServer.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// 未开启时不注册调试端点
+	disabled := newHandler(false)
+	assert.Equal(t, http.StatusNotFound, serve(disabled, http.MethodGet, "/debug/vars", "debug-secret").Code)
+
+	handler := newHandler(true)
+	assert.Equal(t, http.StatusUnauthorized, serve(handler, http.MethodGet, "/debug/vars", "").Code)
+	assert.Equal(t, http.StatusUnauthorized, serve(handler, http.MethodGet, "/debug/pprof/", "wrong").Code)
+
+	rec := serve(handler, http.MethodGet, "/debug/vars", "debug-secret")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "memstats")
+
+	rec = serve(handler, http.MethodGet, "/debug/pprof/goroutine?debug=1", "debug-secret")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "goroutine profile")
+
+	rec = serve(handler, http.MethodPost, "/debug/dump", "debug-secret")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var dump DebugDumpResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &dump))
+	assert.Equal(t, handler.cfg.API.Management.Debug.DumpDir, filepath.Dir(dump.Goroutines))
+	goroutines, err := os.ReadFile(dump.Goroutines)
+	require.NoError(t, err)
+	assert.Contains(t, string(goroutines), "goroutine")
+	heap, err := os.Stat(dump.Heap)
+	require.NoError(t, err)
+	assert.Positive(t, heap.Size())
+}
+
+func TestChaosEndpoints(t *testing.T) {
+	newHandler := func(enabled bool) *EchoHandler {
+		cfg := createTestConfig(t)
+		cfg.Chaos.Enabled = enabled
+		handler := &EchoHandler{
+			managementServer: echo.New(),
+			cfg:              cfg,
+			logger:           createTestLogger(t),
+		}
+		handler.registerManagementRoutes()
+		return handler
+	}
+	serve := func(h *EchoHandler, method, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/v1/admin/chaos", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		h.managementServer.ServeHTTP(rec, req)
+		return rec
+	}
+	t.Cleanup(chaos.Global().Clear)
+
+	// 未开启时不注册故障注入端点
+	assert.Equal(t, http.StatusNotFound, serve(newHandler(false), http.MethodGet, "").Code)
+
+	handler := newHandler(true)
+	assert.Equal(t, http.StatusBadRequest, serve(handler, http.MethodPut, `{"watch_drop_rate":2}`).Code)
+
+	rec := serve(handler, http.MethodPut, `{"etcd_latency_ms":50,"upstream_timeout_rate":0.5,"duration_seconds":60}`)
+	require.Equal(t, http.StatusOK, rec.Code)
+	var resp ChaosResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, 50, resp.Faults.EtcdLatencyMs)
+	assert.Equal(t, 0.5, resp.Faults.UpstreamTimeoutRate)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), resp.Faults.ExpiresAt, 5*time.Second)
+
+	rec = serve(handler, http.MethodDelete, "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	resp = ChaosResponse{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, chaos.Faults{}, resp.Faults)
+}
+
+func TestLoadWeightsEndpoints(t *testing.T) {
+	// 跳过集成测试，除非明确要求运行
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	cfg := createTestConfig(t)
+	cfg.DNS.LoadWeights.Enabled = true
+	client := etcdclient.CreateEtcdClientForTest(t)
+	defer client.Close()
+	defer func() { _ = client.PutLoadWeightsState(context.Background(), &etcdclient.LoadWeightsState{}) }()
+
+	handler := &EchoHandler{
+		managementServer: echo.New(),
+		cfg:              cfg,
+		logger:           createTestLogger(t),
+		etcdClient:       client,
+	}
+	handler.registerManagementRoutes()
+	serve := func(method, body string) *LoadWeightsResponse {
+		req := httptest.NewRequest(method, "/v1/admin/dns/load-weights", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		handler.managementServer.ServeHTTP(rec, req)
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		var resp LoadWeightsResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		return &resp
+	}
+
+	resp := serve(http.MethodPut, `{"disabled":true,"reason":"weights oscillating"}`)
+	assert.True(t, resp.Enabled)
+	assert.False(t, resp.Active)
+
+	resp = serve(http.MethodGet, "")
+	require.NotNil(t, resp.State)
+	assert.True(t, resp.State.Disabled)
+	assert.Equal(t, "weights oscillating", resp.State.Reason)
+
+	resp = serve(http.MethodPut, `{"disabled":false}`)
+	assert.True(t, resp.Active)
+}
+
+func TestEvictInstanceEndpoints(t *testing.T) {
+	// 跳过集成测试，除非明确要求运行
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	cfg := createTestConfig(t)
+	cfg.Heartbeat.EvictionCooldown = 300
+	management, registration := echo.New(), echo.New()
+
+	client := etcdclient.CreateEtcdClientForTest(t)
+	t.Cleanup(func() { _ = client.Close() })
+
+	handler := &EchoHandler{
+		managementServer:   management,
+		registrationServer: registration,
+		cfg:                cfg,
+		logger:             createTestLogger(t),
+		etcdClient:         client,
+	}
+	handler.registerManagementRoutes()
+	handler.registerRegistrationRoutes()
+
+	serviceName := fmt.Sprintf("evict-service-%d", time.Now().UnixNano())
+	instancePath := "/admin/services/" + serviceName + "/instance-001"
+	t.Cleanup(func() {
+		_ = client.DeleteEviction(context.Background(), serviceName, "instance-001")
+		_ = client.DeregisterService(context.Background(), serviceName, "instance-001")
+	})
+
+	serve := func(e *echo.Echo, method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+	register := func() *httptest.ResponseRecorder {
+		body := fmt.Sprintf(`{"service_name": "%s", "instance_id": "instance-001", "ip_address": "10.0.0.1", "port": 8080, "ttl": 30}`, serviceName)
+		return serve(registration, http.MethodPost, "/services/register", body)
+	}
+
+	require.Equal(t, http.StatusOK, register().Code)
+	assert.Equal(t, http.StatusBadRequest, serve(management, http.MethodDelete, instancePath+"?force=true&cooldown=-1", "").Code)
+
+	rec := serve(management, http.MethodDelete, instancePath+"?force=true&reason=stuck", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var resp EvictionResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, "stuck", resp.Eviction.Reason)
+	assert.WithinDuration(t, time.Now().Add(5*time.Minute), resp.Eviction.ExpiresAt, 5*time.Second)
+
+	// 实例已删除，冷却期内心跳和重新注册都失败
+	rec = serve(registration, http.MethodPut, "/services/heartbeat/"+serviceName+"/instance-001", "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	rec = register()
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	var errResp ErrorResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &errResp))
+	assert.Equal(t, CodeInstanceEvicted, errResp.Code)
+
+	rec = serve(management, http.MethodGet, "/admin/evictions", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), serviceName)
+
+	// 解除冷却期后可以重新注册
+	require.Equal(t, http.StatusOK, serve(management, http.MethodDelete, "/admin/evictions/"+serviceName+"/instance-001", "").Code)
+	assert.Equal(t, http.StatusOK, register().Code)
+	assert.Equal(t, http.StatusNotFound, serve(management, http.MethodDelete, "/admin/services/"+serviceName+"/missing?force=true", "").Code)
+}
+
+func TestRegisterService_ReservedName(t *testing.T) {
+	// 跳过集成测试，除非明确要求运行
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	cfg := createTestConfig(t)
+	cfg.API.Registration.Reserved.ServiceNames = []string{"admin"}
+	registration := echo.New()
+	client := etcdclient.NewEtcdClient(cfg, createTestLogger(t))
+	require.NoError(t, client.Connect())
+	t.Cleanup(func() { _ = client.Close() })
+
+	handler := &EchoHandler{
+		registrationServer: registration,
+		cfg:                cfg,
+		logger:             createTestLogger(t),
+		etcdClient:         client,
+	}
+	handler.registerRegistrationRoutes()
+
+	body := `{"service_name": "admin", "ip_address": "10.0.0.1", "port": 8080, "ttl": 30}`
+	req := httptest.NewRequest(http.MethodPost, "/services/register", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	registration.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	var errResp ErrorResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &errResp))
+	assert.Equal(t, CodeReservedName, errResp.Code)
+	assert.Contains(t, errResp.Detail, "admin")
+}
+
+func TestDryRunEndpoints(t *testing.T) {
+	// 跳过集成测试，除非明确要求运行
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	cfg := createTestConfig(t)
+	logger := createTestLogger(t)
+	e := echo.New()
+
+	client := etcdclient.CreateEtcdClientForTest(t)
+	defer client.Close()
+
+	handler := &EchoHandler{
+		managementServer: e,
+		cfg:              cfg,
+		logger:           logger,
+		etcdClient:       client,
+	}
+	handler.registerManagementRoutes()
+
+	ctx := context.Background()
+	suffix := time.Now().UnixNano()
+	serviceName := fmt.Sprintf("dryrun-service-%d", suffix)
+	namespace := fmt.Sprintf("dryrun-ns-%d", suffix)
+	domain := fmt.Sprintf("dryrun-%d.example.com", suffix)
+	require.NoError(t, client.RegisterService(ctx, &etcdclient.ServiceInstance{
+		ServiceName: serviceName, InstanceID: "dryrun-001", IPAddress: "10.0.0.1", Port: 8080, TTL: 30,
+	}))
+	require.NoError(t, client.PutDNSRecord(ctx, domain, &etcdclient.DNSRecord{Type: "A", Value: "10.0.0.1"}))
+	require.NoError(t, client.CreateNamespace(ctx, &etcdclient.Namespace{Name: namespace}))
+	defer func() {
+		_ = client.DeregisterService(context.Background(), serviceName, "dryrun-001")
+		_ = client.DeleteDNSRecord(context.Background(), domain, "A", "")
+		_ = client.DeleteNamespace(context.Background(), namespace, true)
+	}()
+
+	plan := func(path string) (int, *DryRunResponse) {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, path, nil))
+		var resp DryRunResponse
+		_ = json.Unmarshal(rec.Body.Bytes(), &resp)
+		return rec.Code, &resp
+	}
+
+	code, resp := plan("/admin/services/" + serviceName + "/dryrun-001?dry_run=true")
+	require.Equal(t, http.StatusOK, code)
+	assert.True(t, resp.DryRun)
+	require.Len(t, resp.Plan.Instances, 1)
+	assert.Equal(t, []string{serviceName}, resp.Plan.Services)
+	code, _ = plan("/admin/services/" + serviceName + "/missing?dry_run=true&force=true")
+	assert.Equal(t, http.StatusNotFound, code)
+
+	code, resp = plan("/admin/dns/records/" + domain + "/A?dry_run=true")
+	require.Equal(t, http.StatusOK, code)
+	require.Len(t, resp.Plan.Records, 1)
+	assert.Equal(t, "10.0.0.1", resp.Plan.Records[0].Value)
+
+	code, resp = plan("/admin/namespaces/" + namespace + "?dry_run=true")
+	require.Equal(t, http.StatusOK, code)
+	assert.Empty(t, resp.Plan.Instances)
+
+	// 没有做任何修改
+	instances, err := client.GetServiceInstances(ctx, serviceName)
+	require.NoError(t, err)
+	assert.Len(t, instances, 1)
+	_, err = client.GetDNSRecordEntry(ctx, domain, "A", "")
+	assert.NoError(t, err)
+	ns, err := client.GetNamespace(ctx, namespace)
+	require.NoError(t, err)
+	assert.NotNil(t, ns)
+}
+
+func TestListResponseEncoding(t *testing.T) {
+	// 跳过集成测试，除非明确要求运行
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	cfg := createTestConfig(t)
+	cfg.API.Management.Compression.Enabled = true
+	cfg.API.Management.Compression.Level = 5
+	cfg.API.Management.Compression.MinLength = 1
+	logger := createTestLogger(t)
+	e := echo.New()
+
+	client := etcdclient.CreateEtcdClientForTest(t)
+	defer client.Close()
+
+	handler := &EchoHandler{
+		managementServer: e,
+		cfg:              cfg,
+		logger:           logger,
+		etcdClient:       client,
+	}
+	handler.registerManagementRoutes()
+
+	ctx := context.Background()
+	serviceName := fmt.Sprintf("encoding-service-%d", time.Now().UnixNano())
+	require.NoError(t, client.RegisterService(ctx, &etcdclient.ServiceInstance{
+		ServiceName: serviceName, InstanceID: "encoding-001", IPAddress: "10.0.0.1", Port: 8080, TTL: 30,
+	}))
+	defer func() {
+		_ = client.DeregisterService(context.Background(), serviceName, "encoding-001")
+	}()
+
+	path := "/admin/services/" + serviceName + "?fields=instance_id,ip_address"
+	request := func(accept, acceptEncoding string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set(echo.HeaderAccept, accept)
+		req.Header.Set(echo.HeaderAcceptEncoding, acceptEncoding)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		return rec
+	}
+
+	// 字段选择
+	rec := request("", "")
+	assert.Empty(t, rec.Header().Get(echo.HeaderContentEncoding))
+	var resp struct {
+		Success   bool                     `json:"success"`
+		Instances []map[string]interface{} `json:"instances"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.True(t, resp.Success)
+	require.Len(t, resp.Instances, 1)
+	assert.Equal(t, map[string]interface{}{"instance_id": "encoding-001", "ip_address": "10.0.0.1"}, resp.Instances[0])
+
+	// gzip压缩
+	rec = request("", "gzip")
+	assert.Equal(t, "gzip", rec.Header().Get(echo.HeaderContentEncoding))
+	zr, err := gzip.NewReader(rec.Body)
+	require.NoError(t, err)
+	body, err := io.ReadAll(zr)
+	require.NoError(t, err)
+	assert.Contains(t, string(body), `"instance_id":"encoding-001"`)
+
+	// MessagePack编码
+	rec = request(MIMEApplicationMsgpack, "")
+	assert.Equal(t, MIMEApplicationMsgpack, rec.Header().Get(echo.HeaderContentType))
+	assert.True(t, bytes.Contains(rec.Body.Bytes(), []byte("\xabinstance_id\xacencoding-001")))
+
+	// protobuf编码
+	rec = request(MIMEApplicationProtobuf, "")
+	assert.Equal(t, protobufContentType, rec.Header().Get(echo.HeaderContentType))
+	var value structpb.Value
+	require.NoError(t, proto.Unmarshal(rec.Body.Bytes(), &value))
+	instances := value.GetStructValue().GetFields()["instances"].GetListValue().GetValues()
+	require.Len(t, instances, 1)
+	assert.Equal(t, "10.0.0.1", instances[0].GetStructValue().GetFields()["ip_address"].GetStringValue())
+	assert.Nil(t, instances[0].GetStructValue().GetFields()["port"])
+
+	// 错误响应保持原样
+	req := httptest.NewRequest(http.MethodGet, "/admin/services/Invalid_Name!?fields=instance_id", nil)
+	req.Header.Set(echo.HeaderAccept, MIMEApplicationMsgpack)
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.NotEqual(t, http.StatusOK, rec.Code)
+	assert.True(t, strings.HasPrefix(rec.Header().Get(echo.HeaderContentType), echo.MIMEApplicationJSON))
+}
+
+func TestListDNSDomainsHandler(t *testing.T) {
+	// 跳过集成测试，除非明确要求运行
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	cfg := createTestConfig(t)
+	logger := createTestLogger(t)
+	e := echo.New()
+
+	client := etcdclient.CreateEtcdClientForTest(t)
+	defer client.Close()
+
+	handler := &EchoHandler{
+		managementServer: e,
+		cfg:              cfg,
+		logger:           logger,
+		etcdClient:       client,
+	}
+	handler.registerManagementRoutes()
+
+	ctx := context.Background()
+	prefix := fmt.Sprintf("domains-api-%d", time.Now().UnixNano())
+	domains := []string{prefix + "-1.example.com", prefix + "-2.example.com", prefix + "-3.example.com"}
+	for _, domain := range domains {
+		require.NoError(t, client.PutDNSRecord(ctx, domain, &etcdclient.DNSRecord{Type: "A", Value: "10.0.0.1"}))
+	}
+	defer func() {
+		for _, domain := range domains {
+			_ = client.DeleteDNSRecord(context.Background(), domain, "A", "")
+		}
+	}()
+
+	list := func(query string) (int, *DNSDomainsResponse) {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/dns/domains?"+query, nil))
+		var resp DNSDomainsResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		return rec.Code, &resp
+	}
+
+	// 逐页读取所有匹配的域名
+	var listed []string
+	cursor := ""
+	for {
+		code, resp := list("prefix=" + prefix + "&limit=2&cursor=" + cursor)
+		require.Equal(t, http.StatusOK, code)
+		for _, domain := range resp.Domains {
+			listed = append(listed, domain.Domain)
+			assert.Equal(t, []string{"A"}, domain.Types)
+		}
+		if resp.NextCursor == "" {
+			break
+		}
+		cursor = resp.NextCursor
+	}
+	assert.Equal(t, domains, listed)
+
+	// 无效参数
+	code, _ := list("limit=0")
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = list("limit=5000")
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = list("prefix=a/b")
+	assert.Equal(t, http.StatusBadRequest, code)
+}
+
+func TestNamespaceUpstreamEndpoints(t *testing.T) {
+	// 跳过集成测试，除非明确要求运行
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	cfg := createTestConfig(t)
+	logger := createTestLogger(t)
+	e := echo.New()
+
+	client := etcdclient.CreateEtcdClientForTest(t)
+	defer client.Close()
+
+	handler := &EchoHandler{
+		managementServer: e,
+		cfg:              cfg,
+		logger:           logger,
+		etcdClient:       client,
+	}
+	handler.registerManagementRoutes()
+
+	namespace := fmt.Sprintf("upstream-api-%d", time.Now().UnixNano())
+	require.NoError(t, client.CreateNamespace(context.Background(), &etcdclient.Namespace{Name: namespace}))
+	defer func() { _ = client.DeleteNamespace(context.Background(), namespace, true) }()
+
+	path := "/admin/namespaces/" + namespace + "/upstream"
+	do := func(method, body string) (int, *NamespaceUpstreamResponse) {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		var resp NamespaceUpstreamResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		return rec.Code, &resp
+	}
+
+	code, resp := do(http.MethodGet, "")
+	require.Equal(t, http.StatusOK, code)
+	assert.Nil(t, resp.Upstream)
+
+	code, _ = do(http.MethodPut, `{"servers": ["10.0.0.53:53"], "client_subnets": ["not-a-cidr"]}`)
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	code, _ = do(http.MethodPut, `{"servers": ["10.0.0.53:53"], "mode": "race", "client_subnets": ["10.20.0.0/16"]}`)
+	require.Equal(t, http.StatusOK, code)
+	code, resp = do(http.MethodGet, "")
+	require.Equal(t, http.StatusOK, code)
+	require.NotNil(t, resp.Upstream)
+	assert.Equal(t, []string{"10.0.0.53:53"}, resp.Upstream.Servers)
+	assert.Equal(t, etcdclient.UpstreamModeRace, resp.Upstream.Mode)
+	assert.Equal(t, []string{"10.20.0.0/16"}, resp.Upstream.ClientSubnets)
+
+	code, _ = do(http.MethodDelete, "")
+	require.Equal(t, http.StatusOK, code)
+	code, resp = do(http.MethodGet, "")
+	require.Equal(t, http.StatusOK, code)
+	assert.Nil(t, resp.Upstream)
+
+	// 不存在的命名空间
+	path = "/admin/namespaces/missing-" + namespace + "/upstream"
+	code, _ = do(http.MethodPut, `{"servers": ["10.0.0.53:53"]}`)
+	assert.Equal(t, http.StatusNotFound, code)
 }
@@ -0,0 +1,69 @@
+package apihandler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/hewenyu/kong-discovery/internal/etcdclient"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// LoadWeightsRequest 定义负载权重开关请求结构
+type LoadWeightsRequest struct {
+	Disabled bool   `json:"disabled"`         // 是否关闭按负载调整权重
+	Reason   string `json:"reason,omitempty"` // 关闭的原因
+}
+
+// LoadWeightsResponse 定义负载权重状态响应结构
+type LoadWeightsResponse struct {
+	Success   bool                         `json:"success"`           // 是否成功
+	Enabled   bool                         `json:"enabled"`           // 配置中是否开启了按负载调整权重
+	Active    bool                         `json:"active"`            // 当前是否按负载调整权重（配置开启且未被开关关闭）
+	State     *etcdclient.LoadWeightsState `json:"state,omitempty"`   // 运行时开关，未设置时为空
+	Message   string                       `json:"message,omitempty"` // 可选消息
+	Timestamp string                       `json:"timestamp"`         // 时间戳
+}
+
+// loadWeightsResponse 返回按负载调整权重的配置和运行时开关
+func (h *EchoHandler) loadWeightsResponse(c echo.Context, state *etcdclient.LoadWeightsState, message string) error {
+	enabled := h.cfg.DNS.LoadWeights.Enabled
+	return c.JSON(http.StatusOK, &LoadWeightsResponse{
+		Success:   true,
+		Enabled:   enabled,
+		Active:    enabled && (state == nil || !state.Disabled),
+		State:     state,
+		Message:   message,
+		Timestamp: time.Now().Format(time.RFC3339),
+	})
+}
+
+// getLoadWeightsHandler 获取按负载调整权重的状态
+func (h *EchoHandler) getLoadWeightsHandler(c echo.Context) error {
+	state, err := h.etcdClient.GetLoadWeightsState(c.Request().Context())
+	if err != nil {
+		h.log(c).Error("获取负载权重开关失败", zap.Error(err))
+		return respondError(c, storageError(err))
+	}
+	return h.loadWeightsResponse(c, state, "")
+}
+
+// putLoadWeightsHandler 设置按负载调整权重的运行时开关，所有DNS节点在下次加载运行时配置时生效
+func (h *EchoHandler) putLoadWeightsHandler(c echo.Context) error {
+	req := new(LoadWeightsRequest)
+	if apiErr := bindRequest(c, req); apiErr != nil {
+		return respondError(c, apiErr)
+	}
+
+	state := &etcdclient.LoadWeightsState{Disabled: req.Disabled, Reason: req.Reason}
+	if err := h.etcdClient.PutLoadWeightsState(c.Request().Context(), state); err != nil {
+		h.log(c).Error("保存负载权重开关失败", zap.Error(err))
+		return respondError(c, storageError(err))
+	}
+
+	message := "已恢复按负载调整权重"
+	if state.Disabled {
+		message = "已关闭按负载调整权重"
+	}
+	return h.loadWeightsResponse(c, state, message)
+}
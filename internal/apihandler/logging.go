@@ -0,0 +1,81 @@
+package apihandler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/hewenyu/kong-discovery/internal/config"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// LogLevelRequest 定义调整日志级别的请求结构
+type LogLevelRequest struct {
+	Component string `json:"component"` // 组件名称，root或为空表示全局级别
+	Level     string `json:"level"`     // 日志级别，组件级别为空时恢复沿用全局级别
+}
+
+// LogLevelsResponse 定义日志级别响应结构
+type LogLevelsResponse struct {
+	Success   bool                    `json:"success"`           // 是否成功
+	Levels    []config.ComponentLevel `json:"levels"`            // 全局和各组件的日志级别
+	Message   string                  `json:"message,omitempty"` // 可选消息
+	Timestamp string                  `json:"timestamp"`         // 时间戳
+}
+
+// levelController 返回支持运行时调整级别的日志记录器
+func (h *EchoHandler) levelController() (config.LevelController, *APIError) {
+	controller, ok := h.logger.(config.LevelController)
+	if !ok {
+		return nil, newAPIError(http.StatusNotImplemented, CodeInternal, "当前日志记录器不支持调整级别")
+	}
+	return controller, nil
+}
+
+// getLogLevelsHandler 返回全局和各组件的日志级别
+func (h *EchoHandler) getLogLevelsHandler(c echo.Context) error {
+	controller, apiErr := h.levelController()
+	if apiErr != nil {
+		return respondError(c, apiErr)
+	}
+
+	return c.JSON(http.StatusOK, &LogLevelsResponse{
+		Success:   true,
+		Levels:    controller.Levels(),
+		Timestamp: time.Now().Format(time.RFC3339),
+	})
+}
+
+// putLogLevelHandler 在运行时调整全局或组件的日志级别，重启后恢复为配置文件中的级别
+func (h *EchoHandler) putLogLevelHandler(c echo.Context) error {
+	controller, apiErr := h.levelController()
+	if apiErr != nil {
+		return respondError(c, apiErr)
+	}
+
+	req := new(LogLevelRequest)
+	if apiErr := bindRequest(c, req); apiErr != nil {
+		return respondError(c, apiErr)
+	}
+	if req.Component == "" {
+		req.Component = config.RootComponent
+	}
+	if req.Component == config.RootComponent && req.Level == "" {
+		return respondError(c, badRequest(CodeInvalidParameter, "全局日志级别不能为空"))
+	}
+
+	if err := controller.SetLevel(req.Component, req.Level); err != nil {
+		return respondError(c, badRequest(CodeInvalidParameter, err.Error()))
+	}
+
+	h.log(c).Info("日志级别已调整",
+		zap.String("component", req.Component),
+		zap.String("level", req.Level))
+
+	return c.JSON(http.StatusOK, &LogLevelsResponse{
+		Success:   true,
+		Levels:    controller.Levels(),
+		Message:   "日志级别已调整",
+		Timestamp: time.Now().Format(time.RFC3339),
+	})
+}
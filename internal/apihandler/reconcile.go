@@ -0,0 +1,69 @@
+package apihandler
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/hewenyu/kong-discovery/internal/etcdclient"
+	"github.com/hewenyu/kong-discovery/internal/reconciler"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// ReconcileResponse 定义数据一致性检查响应结构
+type ReconcileResponse struct {
+	Success   bool                    `json:"success"`   // 是否成功
+	Report    *etcdclient.DriftReport `json:"report"`    // 检查结果
+	Counts    map[string]int          `json:"counts"`    // 按类别统计的不一致数量
+	Timestamp string                  `json:"timestamp"` // 时间戳
+}
+
+// ReconcileStatsResponse 定义后台协调统计响应结构
+type ReconcileStatsResponse struct {
+	Success   bool              `json:"success"`         // 是否成功
+	Enabled   bool              `json:"enabled"`         // 本节点是否参与后台协调
+	Stats     *reconciler.Stats `json:"stats,omitempty"` // 本节点执行协调的统计
+	Timestamp string            `json:"timestamp"`       // 时间戳
+}
+
+// reconcileReportHandler 检查数据一致性并返回发现的问题，不做任何修改
+func (h *EchoHandler) reconcileReportHandler(c echo.Context) error {
+	return h.reconcile(c, true)
+}
+
+// runReconcileHandler 立即执行一次数据一致性检查并修复，dry_run=true时只检查不修复
+func (h *EchoHandler) runReconcileHandler(c echo.Context) error {
+	return h.reconcile(c, c.QueryParam("dry_run") == "true")
+}
+
+// reconcile 执行数据一致性检查并返回结果
+func (h *EchoHandler) reconcile(c echo.Context, dryRun bool) error {
+	report, err := h.etcdClient.ReconcileDrift(c.Request().Context(), etcdclient.DriftOptions{DryRun: dryRun})
+	if err != nil {
+		h.log(c).Error("数据一致性检查失败", zap.Error(err))
+		return respondError(c, storageError(fmt.Errorf("数据一致性检查失败: %w", err)))
+	}
+
+	return c.JSON(http.StatusOK, &ReconcileResponse{
+		Success:   true,
+		Report:    report,
+		Counts:    report.Counts(),
+		Timestamp: time.Now().Format(time.RFC3339),
+	})
+}
+
+// reconcileStatsHandler 返回本节点后台协调任务的统计，包括每次全量检查修复的数量
+// 只有领导者执行协调，查看其他节点时统计为0
+func (h *EchoHandler) reconcileStatsHandler(c echo.Context) error {
+	resp := &ReconcileStatsResponse{
+		Success:   true,
+		Timestamp: time.Now().Format(time.RFC3339),
+	}
+	if h.reconciler != nil {
+		stats := h.reconciler.Stats()
+		resp.Enabled = true
+		resp.Stats = &stats
+	}
+	return c.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,100 @@
+package apihandler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/hewenyu/kong-discovery/internal/etcdclient"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// RegistrationPolicyRequest 定义服务注册策略设置请求结构
+type RegistrationPolicyRequest struct {
+	DuplicateAddress string `json:"duplicate_address" validate:"oneof=allow replace reject"` // 重复地址的处理方式：allow、replace或reject
+}
+
+// RegistrationPolicyResponse 定义服务注册策略响应结构
+type RegistrationPolicyResponse struct {
+	Success          bool   `json:"success"`           // 是否成功
+	ServiceName      string `json:"service_name"`      // 服务名称
+	DuplicateAddress string `json:"duplicate_address"` // 生效的重复地址处理方式
+	Source           string `json:"source"`            // 策略来源：service表示服务单独设置，default表示全局默认
+	Message          string `json:"message,omitempty"` // 可选消息
+	Timestamp        string `json:"timestamp"`         // 时间戳
+}
+
+// getRegistrationPolicyHandler 获取服务生效的注册策略
+func (h *EchoHandler) getRegistrationPolicyHandler(c echo.Context) error {
+	serviceName := c.Param("serviceName")
+
+	policy, err := h.etcdClient.GetRegistrationPolicy(c.Request().Context(), serviceName)
+	if err != nil {
+		h.log(c).Error("获取服务注册策略失败", zap.String("service", serviceName), zap.Error(err))
+		return respondError(c, storageError(err))
+	}
+
+	resp := &RegistrationPolicyResponse{
+		Success:          true,
+		ServiceName:      serviceName,
+		DuplicateAddress: h.defaultDuplicatePolicy(),
+		Source:           "default",
+		Timestamp:        time.Now().Format(time.RFC3339),
+	}
+	if policy != nil {
+		resp.DuplicateAddress = policy.DuplicateAddress
+		resp.Source = "service"
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// putRegistrationPolicyHandler 设置服务的注册策略
+func (h *EchoHandler) putRegistrationPolicyHandler(c echo.Context) error {
+	serviceName := c.Param("serviceName")
+
+	req := new(RegistrationPolicyRequest)
+	if apiErr := bindRequest(c, req); apiErr != nil {
+		return respondError(c, apiErr)
+	}
+	policy := &etcdclient.RegistrationPolicy{ServiceName: serviceName, DuplicateAddress: req.DuplicateAddress}
+	if err := h.etcdClient.PutRegistrationPolicy(c.Request().Context(), policy); err != nil {
+		h.log(c).Error("保存服务注册策略失败", zap.String("service", serviceName), zap.Error(err))
+		return respondError(c, storageError(err))
+	}
+
+	return c.JSON(http.StatusOK, &RegistrationPolicyResponse{
+		Success:          true,
+		ServiceName:      serviceName,
+		DuplicateAddress: policy.DuplicateAddress,
+		Source:           "service",
+		Message:          "服务注册策略已更新",
+		Timestamp:        time.Now().Format(time.RFC3339),
+	})
+}
+
+// deleteRegistrationPolicyHandler 删除服务的注册策略，恢复使用全局默认策略
+func (h *EchoHandler) deleteRegistrationPolicyHandler(c echo.Context) error {
+	serviceName := c.Param("serviceName")
+
+	if err := h.etcdClient.DeleteRegistrationPolicy(c.Request().Context(), serviceName); err != nil {
+		h.log(c).Error("删除服务注册策略失败", zap.String("service", serviceName), zap.Error(err))
+		return respondError(c, storageError(err))
+	}
+
+	return c.JSON(http.StatusOK, &RegistrationPolicyResponse{
+		Success:          true,
+		ServiceName:      serviceName,
+		DuplicateAddress: h.defaultDuplicatePolicy(),
+		Source:           "default",
+		Message:          "已恢复使用默认注册策略",
+		Timestamp:        time.Now().Format(time.RFC3339),
+	})
+}
+
+// defaultDuplicatePolicy 返回配置的全局默认重复地址处理方式
+func (h *EchoHandler) defaultDuplicatePolicy() string {
+	if h.cfg != nil && etcdclient.ValidDuplicatePolicy(h.cfg.API.Registration.DuplicateAddress) {
+		return h.cfg.API.Registration.DuplicateAddress
+	}
+	return etcdclient.DuplicateAllow
+}
@@ -0,0 +1,51 @@
+package apihandler
+
+import (
+	"github.com/google/uuid"
+	"github.com/hewenyu/kong-discovery/internal/config"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// maxRequestIDLength 沿用客户端请求ID的最大长度，超长或含有其他字符的请求ID会被替换为新生成的ID
+const maxRequestIDLength = 128
+
+// requestIDMiddleware 为每个请求确定请求ID：沿用客户端在X-Request-ID中携带的ID，没有时生成UUID
+// 请求ID在响应头和错误响应中返回，并作为request_id字段附加到处理该请求时的所有日志中，
+// 包括访问日志和etcd客户端的日志，客户端可以据此在服务端日志中找到失败请求的完整记录
+func requestIDMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		req := c.Request()
+		id := req.Header.Get(echo.HeaderXRequestID)
+		if !validRequestID(id) {
+			id = uuid.New().String()
+			req.Header.Set(echo.HeaderXRequestID, id)
+		}
+		c.Response().Header().Set(echo.HeaderXRequestID, id)
+		c.SetRequest(req.WithContext(config.ContextWithLogFields(req.Context(), zap.String("request_id", id))))
+		return next(c)
+	}
+}
+
+// validRequestID 判断客户端携带的请求ID能否沿用：非空、不超过最大长度且只包含可见的ASCII字符，避免日志注入
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// requestID 返回当前请求的请求ID，未经过requestIDMiddleware时返回空字符串
+func requestID(c echo.Context) string {
+	return c.Response().Header().Get(echo.HeaderXRequestID)
+}
+
+// log 返回附加了当前请求ID的日志记录器
+func (h *EchoHandler) log(c echo.Context) config.Logger {
+	return config.LoggerFromContext(c.Request().Context(), h.logger)
+}
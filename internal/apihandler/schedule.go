@@ -0,0 +1,121 @@
+package apihandler
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/hewenyu/kong-discovery/internal/etcdclient"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// ScheduledChangeRequest 定义创建定时DNS变更的请求结构
+type ScheduledChangeRequest struct {
+	ApplyAt    time.Time                        `json:"apply_at" validate:"required"`   // 生效时间
+	Operations []*etcdclient.DNSRecordOperation `json:"operations" validate:"required"` // 要应用的操作，与批量操作格式相同
+	Comment    string                           `json:"comment,omitempty"`              // 可选说明
+}
+
+// ScheduledChangesResponse 定义定时DNS变更响应结构
+type ScheduledChangesResponse struct {
+	Success   bool                          `json:"success"`           // 是否成功
+	Changes   []*etcdclient.ScheduledChange `json:"changes"`           // 定时变更列表
+	Message   string                        `json:"message,omitempty"` // 可选消息
+	Timestamp string                        `json:"timestamp"`         // 时间戳
+}
+
+// listScheduledChangesHandler 列出定时DNS变更，可按status过滤
+func (h *EchoHandler) listScheduledChangesHandler(c echo.Context) error {
+	status := c.QueryParam("status")
+
+	changes, err := h.etcdClient.ListScheduledChanges(c.Request().Context())
+	if err != nil {
+		h.log(c).Error("获取定时变更失败", zap.Error(err))
+		return respondError(c, storageError(fmt.Errorf("获取定时变更失败: %w", err)))
+	}
+
+	result := make([]*etcdclient.ScheduledChange, 0, len(changes))
+	for _, change := range changes {
+		if status == "" || change.Status == status {
+			result = append(result, change)
+		}
+	}
+
+	return c.JSON(http.StatusOK, &ScheduledChangesResponse{
+		Success:   true,
+		Changes:   result,
+		Timestamp: time.Now().Format(time.RFC3339),
+	})
+}
+
+// createScheduledChangeHandler 创建定时DNS变更，到达生效时间后由调度器原子地应用
+func (h *EchoHandler) createScheduledChangeHandler(c echo.Context) error {
+	req := new(ScheduledChangeRequest)
+	if apiErr := bindRequest(c, req); apiErr != nil {
+		return respondError(c, apiErr)
+	}
+	if _, apiErr := validateDNSRecordOperations(req.Operations); apiErr != nil {
+		return respondError(c, apiErr)
+	}
+
+	return h.scheduleDNSChange(c, &etcdclient.ScheduledChange{
+		ApplyAt:    req.ApplyAt,
+		Operations: req.Operations,
+		Comment:    req.Comment,
+	})
+}
+
+// scheduleDNSChange 保存定时变更并返回202
+func (h *EchoHandler) scheduleDNSChange(c echo.Context, change *etcdclient.ScheduledChange) error {
+	if change.ApplyAt.IsZero() {
+		return respondError(c, badRequest(CodeInvalidParameter, "apply_at不能为空"))
+	}
+
+	if _, err := h.etcdClient.ScheduleDNSChange(c.Request().Context(), change); err != nil {
+		h.log(c).Error("创建定时变更失败", zap.Error(err))
+		return respondError(c, storageError(fmt.Errorf("创建定时变更失败: %w", err)))
+	}
+
+	message := "定时变更已创建"
+	if !change.ApplyAt.After(time.Now()) {
+		message = "生效时间已过，定时变更将在下次调度时应用"
+	}
+	return c.JSON(http.StatusAccepted, &ScheduledChangesResponse{
+		Success:   true,
+		Changes:   []*etcdclient.ScheduledChange{change},
+		Message:   message,
+		Timestamp: time.Now().Format(time.RFC3339),
+	})
+}
+
+// getScheduledChangeHandler 获取单个定时DNS变更
+func (h *EchoHandler) getScheduledChangeHandler(c echo.Context) error {
+	change, err := h.etcdClient.GetScheduledChange(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return respondError(c, storageError(fmt.Errorf("获取定时变更失败: %w", err)))
+	}
+
+	return c.JSON(http.StatusOK, &ScheduledChangesResponse{
+		Success:   true,
+		Changes:   []*etcdclient.ScheduledChange{change},
+		Timestamp: time.Now().Format(time.RFC3339),
+	})
+}
+
+// cancelScheduledChangeHandler 取消尚未执行的定时变更，也用于清除执行失败的变更
+func (h *EchoHandler) cancelScheduledChangeHandler(c echo.Context) error {
+	id := c.Param("id")
+
+	if err := h.etcdClient.CancelScheduledChange(c.Request().Context(), id); err != nil {
+		h.log(c).Error("取消定时变更失败", zap.String("id", id), zap.Error(err))
+		return respondError(c, storageError(fmt.Errorf("取消定时变更失败: %w", err)))
+	}
+
+	return c.JSON(http.StatusOK, &ScheduledChangesResponse{
+		Success:   true,
+		Changes:   []*etcdclient.ScheduledChange{},
+		Message:   "定时变更已取消",
+		Timestamp: time.Now().Format(time.RFC3339),
+	})
+}
@@ -0,0 +1,60 @@
+package apihandler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/hewenyu/kong-discovery/internal/tracing"
+	"github.com/labstack/echo/v4"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracingMiddleware 为每个HTTP请求创建服务端span
+// 请求头中携带的trace上下文（traceparent）会被继承，处理函数通过c.Request().Context()传递给下游调用
+func tracingMiddleware(server string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			ctx := otel.GetTextMapPropagator().Extract(req.Context(), propagation.HeaderCarrier(req.Header))
+
+			// 使用路由模板而不是实际路径命名，避免span名称基数过高
+			route := c.Path()
+			if route == "" {
+				route = req.URL.Path
+			}
+			ctx, span := tracing.Start(ctx, req.Method+" "+route,
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(
+					attribute.String("http.server", server),
+					attribute.String("http.method", req.Method),
+					attribute.String("http.route", route),
+					attribute.String("http.target", req.URL.RequestURI()),
+					attribute.String("net.peer.ip", c.RealIP()),
+				))
+			defer span.End()
+			c.SetRequest(req.WithContext(ctx))
+
+			err := next(c)
+
+			status := c.Response().Status
+			if err != nil {
+				span.RecordError(err)
+				var he *echo.HTTPError
+				if !c.Response().Committed && errors.As(err, &he) {
+					status = he.Code
+				} else if !c.Response().Committed {
+					status = http.StatusInternalServerError
+				}
+			}
+			span.SetAttributes(attribute.Int("http.status_code", status))
+			if status >= http.StatusInternalServerError {
+				span.SetStatus(codes.Error, http.StatusText(status))
+			}
+			return err
+		}
+	}
+}
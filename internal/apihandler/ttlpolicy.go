@@ -0,0 +1,165 @@
+package apihandler
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/hewenyu/kong-discovery/internal/etcdclient"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// TTLPolicyRequest 定义服务或命名空间TTL策略设置请求结构，字段为0表示该级别不设置
+type TTLPolicyRequest struct {
+	DefaultTTL int `json:"default_ttl" validate:"ttl"` // 记录未设置TTL时使用的TTL（秒）
+	MinTTL     int `json:"min_ttl" validate:"ttl"`     // 应答TTL的下限（秒）
+	MaxTTL     int `json:"max_ttl" validate:"ttl"`     // 应答TTL的上限（秒）
+}
+
+// policy 转换为TTL策略
+func (r *TTLPolicyRequest) policy() etcdclient.TTLPolicy {
+	return etcdclient.TTLPolicy{DefaultTTL: r.DefaultTTL, MinTTL: r.MinTTL, MaxTTL: r.MaxTTL}
+}
+
+// ServiceTTLPolicyResponse 定义服务TTL策略响应结构
+type ServiceTTLPolicyResponse struct {
+	Success     bool                         `json:"success"`           // 是否成功
+	ServiceName string                       `json:"service_name"`      // 服务名称
+	Policy      *etcdclient.ServiceTTLPolicy `json:"policy"`            // 服务的TTL策略，未设置时为null，表示使用命名空间和全局的TTL设置
+	Message     string                       `json:"message,omitempty"` // 可选消息
+	Timestamp   string                       `json:"timestamp"`         // 时间戳
+}
+
+// getServiceTTLPolicyHandler 获取服务的TTL策略
+func (h *EchoHandler) getServiceTTLPolicyHandler(c echo.Context) error {
+	serviceName := c.Param("serviceName")
+
+	policy, err := h.etcdClient.GetServiceTTLPolicy(c.Request().Context(), serviceName)
+	if err != nil {
+		h.log(c).Error("获取服务TTL策略失败", zap.String("service", serviceName), zap.Error(err))
+		return respondError(c, storageError(err))
+	}
+
+	return c.JSON(http.StatusOK, &ServiceTTLPolicyResponse{
+		Success:     true,
+		ServiceName: serviceName,
+		Policy:      policy,
+		Timestamp:   time.Now().Format(time.RFC3339),
+	})
+}
+
+// putServiceTTLPolicyHandler 设置服务的TTL策略，DNS服务器会自动热加载
+func (h *EchoHandler) putServiceTTLPolicyHandler(c echo.Context) error {
+	serviceName := c.Param("serviceName")
+
+	req := new(TTLPolicyRequest)
+	if apiErr := bindRequest(c, req); apiErr != nil {
+		return respondError(c, apiErr)
+	}
+
+	policy := &etcdclient.ServiceTTLPolicy{ServiceName: serviceName, TTLPolicy: req.policy()}
+	if err := policy.Validate(); err != nil {
+		return respondError(c, badRequest(CodeInvalidParameter, err.Error()))
+	}
+	if err := h.etcdClient.PutServiceTTLPolicy(c.Request().Context(), policy); err != nil {
+		h.log(c).Error("保存服务TTL策略失败", zap.String("service", serviceName), zap.Error(err))
+		return respondError(c, storageError(err))
+	}
+
+	return c.JSON(http.StatusOK, &ServiceTTLPolicyResponse{
+		Success:     true,
+		ServiceName: serviceName,
+		Policy:      policy,
+		Message:     "服务TTL策略已更新",
+		Timestamp:   time.Now().Format(time.RFC3339),
+	})
+}
+
+// deleteServiceTTLPolicyHandler 删除服务的TTL策略，之后服务使用命名空间和全局的TTL设置
+func (h *EchoHandler) deleteServiceTTLPolicyHandler(c echo.Context) error {
+	serviceName := c.Param("serviceName")
+
+	if err := h.etcdClient.DeleteServiceTTLPolicy(c.Request().Context(), serviceName); err != nil {
+		h.log(c).Error("删除服务TTL策略失败", zap.String("service", serviceName), zap.Error(err))
+		return respondError(c, storageError(err))
+	}
+
+	return c.JSON(http.StatusOK, &ServiceTTLPolicyResponse{
+		Success:     true,
+		ServiceName: serviceName,
+		Message:     "服务TTL策略已删除",
+		Timestamp:   time.Now().Format(time.RFC3339),
+	})
+}
+
+// NamespaceTTLPolicyResponse 定义命名空间TTL策略响应结构
+type NamespaceTTLPolicyResponse struct {
+	Success   bool                  `json:"success"`           // 是否成功
+	Namespace string                `json:"namespace"`         // 命名空间名称
+	Policy    *etcdclient.TTLPolicy `json:"policy"`            // 命名空间的TTL策略，未设置时为null，表示使用全局设置
+	Message   string                `json:"message,omitempty"` // 可选消息
+	Timestamp string                `json:"timestamp"`         // 时间戳
+}
+
+// getNamespaceTTLPolicyHandler 获取命名空间的TTL策略
+func (h *EchoHandler) getNamespaceTTLPolicyHandler(c echo.Context) error {
+	namespace := c.Param("namespace")
+
+	policy, err := h.etcdClient.GetNamespaceTTLPolicy(c.Request().Context(), namespace)
+	if err != nil {
+		h.log(c).Error("获取命名空间TTL策略失败", zap.String("namespace", namespace), zap.Error(err))
+		return respondError(c, storageError(fmt.Errorf("获取命名空间TTL策略失败: %w", err)))
+	}
+
+	return c.JSON(http.StatusOK, &NamespaceTTLPolicyResponse{
+		Success:   true,
+		Namespace: namespace,
+		Policy:    policy,
+		Timestamp: time.Now().Format(time.RFC3339),
+	})
+}
+
+// putNamespaceTTLPolicyHandler 设置命名空间的TTL策略，DNS服务器会自动热加载
+func (h *EchoHandler) putNamespaceTTLPolicyHandler(c echo.Context) error {
+	namespace := c.Param("namespace")
+
+	req := new(TTLPolicyRequest)
+	if apiErr := bindRequest(c, req); apiErr != nil {
+		return respondError(c, apiErr)
+	}
+
+	policy := req.policy()
+	if err := policy.Validate(); err != nil {
+		return respondError(c, badRequest(CodeInvalidParameter, err.Error()))
+	}
+	if err := h.etcdClient.PutNamespaceTTLPolicy(c.Request().Context(), namespace, &policy); err != nil {
+		h.log(c).Error("设置命名空间TTL策略失败", zap.String("namespace", namespace), zap.Error(err))
+		return respondError(c, storageError(fmt.Errorf("设置命名空间TTL策略失败: %w", err)))
+	}
+
+	return c.JSON(http.StatusOK, &NamespaceTTLPolicyResponse{
+		Success:   true,
+		Namespace: namespace,
+		Policy:    &policy,
+		Message:   "命名空间TTL策略已更新",
+		Timestamp: time.Now().Format(time.RFC3339),
+	})
+}
+
+// deleteNamespaceTTLPolicyHandler 删除命名空间的TTL策略，恢复使用全局设置
+func (h *EchoHandler) deleteNamespaceTTLPolicyHandler(c echo.Context) error {
+	namespace := c.Param("namespace")
+
+	if err := h.etcdClient.PutNamespaceTTLPolicy(c.Request().Context(), namespace, nil); err != nil {
+		h.log(c).Error("删除命名空间TTL策略失败", zap.String("namespace", namespace), zap.Error(err))
+		return respondError(c, storageError(fmt.Errorf("删除命名空间TTL策略失败: %w", err)))
+	}
+
+	return c.JSON(http.StatusOK, &NamespaceTTLPolicyResponse{
+		Success:   true,
+		Namespace: namespace,
+		Message:   "命名空间已恢复使用全局TTL设置",
+		Timestamp: time.Now().Format(time.RFC3339),
+	})
+}
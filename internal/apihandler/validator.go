@@ -0,0 +1,282 @@
+package apihandler
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// maxTTL DNS记录TTL的上限（RFC 2181）
+const maxTTL = 1<<31 - 1
+
+// FieldError 单个请求字段的校验错误
+type FieldError struct {
+	Field   string `json:"field"`           // 字段名称，与请求体中的JSON字段名一致
+	Rule    string `json:"rule"`            // 未通过的规则
+	Param   string `json:"param,omitempty"` // 规则参数，如min=1中的1
+	Message string `json:"message"`         // 说明
+}
+
+// ValidationErrors 请求体校验失败的所有字段
+type ValidationErrors []FieldError
+
+// Error 实现error接口
+func (v ValidationErrors) Error() string {
+	parts := make([]string, 0, len(v))
+	for _, fe := range v {
+		parts = append(parts, fe.Field+": "+fe.Message)
+	}
+	return "请求参数校验失败: " + strings.Join(parts, "; ")
+}
+
+// requestValidator 根据结构体字段的validate标签校验请求体，实现echo.Validator
+//
+// 支持的规则（逗号分隔，按顺序检查，每个字段只报告第一个未通过的规则）：
+//
+//	required      不能为零值，切片和映射不能为空
+//	omitempty     字段为零值时跳过其余规则
+//	ip/ipv4/ipv6  IP地址
+//	cidr          网段，如10.0.0.0/8
+//	fqdn          域名，可以带结尾的点号
+//	url           http或https地址
+//	port          1到65535之间的端口号
+//	ttl           0到2147483647之间的TTL
+//	min=N/max=N   数值的大小，或字符串、切片的长度
+//	oneof=a b c   取值之一
+//	dive          其后的规则作用于切片的每个元素
+type requestValidator struct{}
+
+// Validate 校验请求体，失败时返回ValidationErrors
+func (v *requestValidator) Validate(i interface{}) error {
+	val := reflect.ValueOf(i)
+	for val.Kind() == reflect.Ptr {
+		if val.IsNil() {
+			return nil
+		}
+		val = val.Elem()
+	}
+	if val.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var errs ValidationErrors
+	typ := val.Type()
+	for idx := 0; idx < typ.NumField(); idx++ {
+		field := typ.Field(idx)
+		tag := field.Tag.Get("validate")
+		if tag == "" || !field.IsExported() {
+			continue
+		}
+		if fe := validateField(fieldName(field), val.Field(idx), strings.Split(tag, ",")); fe != nil {
+			errs = append(errs, *fe)
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// fieldName 返回字段在JSON中的名称
+func fieldName(field reflect.StructField) string {
+	name := strings.Split(field.Tag.Get("json"), ",")[0]
+	if name == "" || name == "-" {
+		return field.Name
+	}
+	return name
+}
+
+// validateField 按规则依次校验字段
+func validateField(name string, value reflect.Value, rules []string) *FieldError {
+	for i, rule := range rules {
+		rule = strings.TrimSpace(rule)
+		switch rule {
+		case "":
+			continue
+		case "omitempty":
+			if value.IsZero() {
+				return nil
+			}
+			continue
+		case "dive":
+			if value.Kind() != reflect.Slice && value.Kind() != reflect.Array {
+				return &FieldError{Field: name, Rule: rule, Message: "不是列表"}
+			}
+			for j := 0; j < value.Len(); j++ {
+				if fe := validateField(fmt.Sprintf("%s[%d]", name, j), value.Index(j), rules[i+1:]); fe != nil {
+					return fe
+				}
+			}
+			return nil
+		}
+
+		ruleName, param, _ := strings.Cut(rule, "=")
+		if msg := checkRule(ruleName, param, value); msg != "" {
+			return &FieldError{Field: name, Rule: ruleName, Param: param, Message: msg}
+		}
+	}
+	return nil
+}
+
+// checkRule 检查单条规则，通过时返回空字符串，否则返回说明
+func checkRule(rule, param string, value reflect.Value) string {
+	switch rule {
+	case "required":
+		if isEmpty(value) {
+			return "不能为空"
+		}
+	case "ip":
+		if net.ParseIP(value.String()) == nil {
+			return "必须是有效的IPv4或IPv6地址"
+		}
+	case "ipv4":
+		if ip := net.ParseIP(value.String()); ip == nil || ip.To4() == nil {
+			return "必须是有效的IPv4地址"
+		}
+	case "ipv6":
+		if ip := net.ParseIP(value.String()); ip == nil || ip.To4() != nil {
+			return "必须是有效的IPv6地址"
+		}
+	case "cidr":
+		if _, _, err := net.ParseCIDR(value.String()); err != nil {
+			return "必须是有效的网段"
+		}
+	case "fqdn":
+		if !isFQDN(value.String()) {
+			return "必须是有效的域名"
+		}
+	case "url":
+		u, err := url.Parse(value.String())
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return "必须是有效的http或https地址"
+		}
+	case "port":
+		if n, ok := intValue(value); !ok || n < 1 || n > 65535 {
+			return "必须是1到65535之间的端口号"
+		}
+	case "ttl":
+		if n, ok := intValue(value); !ok || n < 0 || n > maxTTL {
+			return fmt.Sprintf("必须在0到%d之间", maxTTL)
+		}
+	case "min", "max":
+		limit, err := strconv.ParseInt(param, 10, 64)
+		if err != nil {
+			return "无效的校验规则: " + rule + "=" + param
+		}
+		n, ok := intValue(value)
+		if !ok {
+			n, ok = lengthValue(value)
+		}
+		if !ok {
+			return "无效的校验规则: " + rule
+		}
+		if rule == "min" && n < limit {
+			return "不能小于" + param
+		}
+		if rule == "max" && n > limit {
+			return "不能大于" + param
+		}
+	case "oneof":
+		options := strings.Fields(param)
+		for _, option := range options {
+			if value.String() == option {
+				return ""
+			}
+		}
+		return "必须是以下值之一: " + strings.Join(options, ", ")
+	default:
+		return "未知的校验规则: " + rule
+	}
+	return ""
+}
+
+// isEmpty 判断字段是否为空：零值，或长度为0的切片和映射
+func isEmpty(value reflect.Value) bool {
+	switch value.Kind() {
+	case reflect.Slice, reflect.Map:
+		return value.Len() == 0
+	case reflect.String:
+		return strings.TrimSpace(value.String()) == ""
+	}
+	if t, ok := value.Interface().(time.Time); ok {
+		return t.IsZero()
+	}
+	return value.IsZero()
+}
+
+// intValue 返回整数字段的值
+func intValue(value reflect.Value) (int64, bool) {
+	switch value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return value.Int(), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(value.Uint()), true
+	}
+	return 0, false
+}
+
+// lengthValue 返回字符串、切片或映射的长度
+func lengthValue(value reflect.Value) (int64, bool) {
+	switch value.Kind() {
+	case reflect.String, reflect.Slice, reflect.Array, reflect.Map:
+		return int64(value.Len()), true
+	}
+	return 0, false
+}
+
+// isFQDN 判断是否为有效的域名：由点号分隔的标签组成，每个标签1到63个字母、数字或连字符，
+// 不以连字符开头或结尾，总长度不超过253
+func isFQDN(name string) bool {
+	name = strings.TrimSuffix(name, ".")
+	if name == "" || len(name) > 253 {
+		return false
+	}
+	for _, label := range strings.Split(name, ".") {
+		if len(label) == 0 || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+		for _, r := range label {
+			if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// bindRequest 解析并校验请求体，请求体格式错误返回CodeInvalidRequest，字段校验失败返回带字段列表的400错误
+func bindRequest(c echo.Context, req interface{}) *APIError {
+	if err := c.Bind(req); err != nil {
+		return badRequest(CodeInvalidRequest, err.Error())
+	}
+	if err := c.Validate(req); err != nil {
+		return validationError(err)
+	}
+	return nil
+}
+
+// validationError 将校验错误转为API错误，所有未通过的字段都只违反TTL规则时使用CodeInvalidTTL
+func validationError(err error) *APIError {
+	var fieldErrs ValidationErrors
+	if !errors.As(err, &fieldErrs) {
+		return badRequest(CodeInvalidParameter, err.Error())
+	}
+
+	code := CodeInvalidTTL
+	for _, fe := range fieldErrs {
+		if fe.Rule != "ttl" {
+			code = CodeInvalidParameter
+			break
+		}
+	}
+	apiErr := badRequest(code, fieldErrs.Error())
+	apiErr.Fields = fieldErrs
+	return apiErr
+}
@@ -0,0 +1,128 @@
+package apihandler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestValidator(t *testing.T) {
+	type request struct {
+		Name    string   `json:"name" validate:"required"`
+		IP      string   `json:"ip" validate:"omitempty,ip"`
+		Domain  string   `json:"domain" validate:"omitempty,fqdn"`
+		Port    int      `json:"port" validate:"omitempty,port"`
+		TTL     int      `json:"ttl" validate:"ttl"`
+		Mode    string   `json:"mode" validate:"omitempty,oneof=race sequential"`
+		Count   int      `json:"count" validate:"min=1,max=5"`
+		Tags    []string `json:"tags" validate:"max=2"`
+		Subnets []string `json:"subnets" validate:"dive,cidr"`
+		Hook    string   `json:"hook" validate:"omitempty,url"`
+	}
+	valid := func() request {
+		return request{Name: "svc", Count: 1}
+	}
+
+	v := &requestValidator{}
+	r := valid()
+	require.NoError(t, v.Validate(&r))
+
+	tests := []struct {
+		name   string
+		modify func(r *request)
+		field  string
+		rule   string
+	}{
+		{"缺少必填字段", func(r *request) { r.Name = "  " }, "name", "required"},
+		{"无效的IP地址", func(r *request) { r.IP = "backend.local" }, "ip", "ip"},
+		{"无效的域名", func(r *request) { r.Domain = "bad_domain-.example.com" }, "domain", "fqdn"},
+		{"端口超出范围", func(r *request) { r.Port = 70000 }, "port", "port"},
+		{"TTL为负数", func(r *request) { r.TTL = -1 }, "ttl", "ttl"},
+		{"不在可选值中", func(r *request) { r.Mode = "random" }, "mode", "oneof"},
+		{"小于最小值", func(r *request) { r.Count = 0 }, "count", "min"},
+		{"大于最大值", func(r *request) { r.Count = 6 }, "count", "max"},
+		{"列表过长", func(r *request) { r.Tags = []string{"a", "b", "c"} }, "tags", "max"},
+		{"列表元素无效", func(r *request) { r.Subnets = []string{"10.0.0.0/8", "10.0.0.1"} }, "subnets[1]", "cidr"},
+		{"无效的回调地址", func(r *request) { r.Hook = "ftp://example.com" }, "hook", "url"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := valid()
+			tt.modify(&r)
+			err := v.Validate(&r)
+			require.Error(t, err)
+			fieldErrs, ok := err.(ValidationErrors)
+			require.True(t, ok)
+			require.Len(t, fieldErrs, 1)
+			assert.Equal(t, tt.field, fieldErrs[0].Field)
+			assert.Equal(t, tt.rule, fieldErrs[0].Rule)
+		})
+	}
+
+	// 列出所有未通过的字段
+	r = request{IP: "x", Count: 1}
+	err := v.Validate(&r)
+	require.Error(t, err)
+	assert.Len(t, err.(ValidationErrors), 2)
+}
+
+func TestIsFQDN(t *testing.T) {
+	assert.True(t, isFQDN("example.com"))
+	assert.True(t, isFQDN("api.example.com."))
+	assert.True(t, isFQDN("_sip._tcp.example.com"))
+	assert.False(t, isFQDN(""))
+	assert.False(t, isFQDN("."))
+	assert.False(t, isFQDN("a..b"))
+	assert.False(t, isFQDN("-a.example.com"))
+	assert.False(t, isFQDN("a b.example.com"))
+	assert.False(t, isFQDN(strings.Repeat("a", 64)+".com"))
+}
+
+func TestServiceRegistration_ValidationFields(t *testing.T) {
+	e := echo.New()
+	handler := &EchoHandler{
+		registrationServer: e,
+		logger:             createTestLogger(t),
+	}
+	handler.registerRegistrationRoutes()
+
+	serve := func(body string) (*httptest.ResponseRecorder, ErrorResponse) {
+		req := httptest.NewRequest(http.MethodPost, "/services/register", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+
+		var resp ErrorResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		return rec, resp
+	}
+
+	// 返回所有未通过校验的字段
+	rec, resp := serve(`{"ip_address": "10.0.0.300", "port": 70000}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, CodeInvalidParameter, resp.Code)
+	fields := make(map[string]string)
+	for _, fe := range resp.Fields {
+		fields[fe.Field] = fe.Rule
+	}
+	assert.Equal(t, map[string]string{"service_name": "required", "ip_address": "ip", "port": "port"}, fields)
+
+	// 只有TTL无效时使用TTL错误码
+	rec, resp = serve(`{"service_name": "svc", "ip_address": "10.0.0.1", "port": 8080, "dns_ttl": -5}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, CodeInvalidTTL, resp.Code)
+	require.Len(t, resp.Fields, 1)
+	assert.Equal(t, "dns_ttl", resp.Fields[0].Field)
+
+	// 请求体格式错误不是字段校验错误
+	rec, resp = serve(`{"port": "abc"}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, CodeInvalidRequest, resp.Code)
+	assert.Empty(t, resp.Fields)
+}
@@ -0,0 +1,78 @@
+package apihandler
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/hewenyu/kong-discovery/internal/version"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// MinSDKVersionHeader 拒绝过低版本的SDK时，响应中携带服务端要求的最低版本
+const MinSDKVersionHeader = "X-Min-SDK-Version"
+
+// VersionResponse 定义版本和构建信息响应结构
+type VersionResponse struct {
+	Success bool `json:"success"` // 是否成功
+	version.Info
+	MinSDKVersion string `json:"min_sdk_version,omitempty"` // 允许的最低SDK版本
+	Timestamp     string `json:"timestamp"`                 // 时间戳
+}
+
+// versionHandler 返回服务的版本、构建信息和支持的API版本
+func (h *EchoHandler) versionHandler(c echo.Context) error {
+	return c.JSON(http.StatusOK, &VersionResponse{
+		Success:       true,
+		Info:          version.Get(),
+		MinSDKVersion: h.minSDKVersion(),
+		Timestamp:     time.Now().Format(time.RFC3339),
+	})
+}
+
+// minSDKVersion 返回配置的最低SDK版本，为空表示不限制
+func (h *EchoHandler) minSDKVersion() string {
+	if h.cfg == nil {
+		return ""
+	}
+	return h.cfg.API.Registration.MinSDKVersion
+}
+
+// sdkVersionMiddleware 拒绝版本低于api.registration.min_sdk_version的SDK请求
+// SDK版本优先读取X-SDK-Version请求头，其次从User-Agent中识别；不是SDK发出的请求不受限制，
+// /health和/version始终放行，旧版本的SDK可以据此得知需要升级到的版本
+func (h *EchoHandler) sdkVersionMiddleware(minVersion string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if path := c.Path(); path == "/health" || path == "/version" {
+				return next(c)
+			}
+
+			sdkVersion := c.Request().Header.Get(version.SDKHeader)
+			if sdkVersion == "" {
+				sdkVersion, _ = version.FromUserAgent(c.Request().UserAgent())
+			}
+			if sdkVersion == "" {
+				return next(c)
+			}
+
+			cmp, err := version.Compare(sdkVersion, minVersion)
+			if err == nil && cmp >= 0 {
+				return next(c)
+			}
+
+			detail := fmt.Sprintf("SDK版本 %s 低于服务端要求的最低版本 %s", sdkVersion, minVersion)
+			if err != nil {
+				detail = fmt.Sprintf("无法识别的SDK版本 %q，服务端要求的最低版本为 %s", sdkVersion, minVersion)
+			}
+			h.log(c).Warn("拒绝过低版本的SDK请求",
+				zap.String("sdk_version", sdkVersion),
+				zap.String("min_sdk_version", minVersion),
+				zap.String("path", c.Request().URL.Path),
+				zap.String("client", c.RealIP()))
+			c.Response().Header().Set(MinSDKVersionHeader, minVersion)
+			return respondError(c, newAPIError(http.StatusUpgradeRequired, CodeSDKVersionUnsupported, detail))
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package apihandler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// APIPrefix 当前版本API的路径前缀
+const APIPrefix = "/v1"
+
+// versionedRoutes 在/v1前缀下注册路由，启用旧路径时同时在无版本的旧路径上注册同一处理器，
+// 旧路径的响应带有Deprecation、Sunset和指向新路径的Link头，提示客户端迁移
+type versionedRoutes struct {
+	e      *echo.Echo
+	legacy bool
+	sunset string // HTTP-date格式的停止服务时间，为空时不返回Sunset头
+}
+
+// versionedRoutes 创建server上的版本化路由注册器
+func (h *EchoHandler) versionedRoutes(server *echo.Echo) *versionedRoutes {
+	r := &versionedRoutes{e: server, legacy: true}
+	if h.cfg == nil {
+		return r
+	}
+
+	r.legacy = !h.cfg.API.LegacyPaths.Disabled
+	if sunset := h.cfg.API.LegacyPaths.Sunset; sunset != "" && r.legacy {
+		t, err := time.Parse(time.DateOnly, sunset)
+		if err != nil {
+			t, err = time.Parse(time.RFC3339, sunset)
+		}
+		if err != nil {
+			h.logger.Error("旧路径的停止服务时间无效，不返回Sunset头", zap.String("sunset", sunset), zap.Error(err))
+		} else {
+			r.sunset = t.UTC().Format(http.TimeFormat)
+		}
+	}
+	return r
+}
+
+// GET 注册GET路由，m为只作用于该路由的中间件
+func (r *versionedRoutes) GET(path string, handler echo.HandlerFunc, m ...echo.MiddlewareFunc) {
+	r.add(http.MethodGet, path, handler, m...)
+}
+
+// POST 注册POST路由
+func (r *versionedRoutes) POST(path string, handler echo.HandlerFunc) {
+	r.add(http.MethodPost, path, handler)
+}
+
+// PUT 注册PUT路由
+func (r *versionedRoutes) PUT(path string, handler echo.HandlerFunc) {
+	r.add(http.MethodPut, path, handler)
+}
+
+// DELETE 注册DELETE路由
+func (r *versionedRoutes) DELETE(path string, handler echo.HandlerFunc) {
+	r.add(http.MethodDelete, path, handler)
+}
+
+// add 在/v1前缀和旧路径上注册路由
+func (r *versionedRoutes) add(method, path string, handler echo.HandlerFunc, m ...echo.MiddlewareFunc) {
+	r.e.Add(method, APIPrefix+path, handler, m...)
+	if r.legacy {
+		r.e.Add(method, path, handler, append([]echo.MiddlewareFunc{r.deprecated}, m...)...)
+	}
+}
+
+// deprecated 为旧路径的响应添加弃用提示头
+func (r *versionedRoutes) deprecated(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		header := c.Response().Header()
+		header.Set("Deprecation", "true")
+		if r.sunset != "" {
+			header.Set("Sunset", r.sunset)
+		}
+		header.Set("Link", "<"+APIPrefix+c.Request().URL.Path+`>; rel="successor-version"`)
+		return next(c)
+	}
+}
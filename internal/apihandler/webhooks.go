@@ -0,0 +1,191 @@
+package apihandler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/hewenyu/kong-discovery/internal/etcdclient"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// WebhookRequest 定义webhook创建和更新请求结构
+type WebhookRequest struct {
+	URL      string   `json:"url" validate:"required,url"` // 回调地址
+	Secret   string   `json:"secret"`                      // 签名密钥，更新时为空表示保留原密钥
+	Events   []string `json:"events"`                      // 订阅的事件类型，为空表示全部
+	Disabled bool     `json:"disabled"`                    // 是否暂停投递
+}
+
+// WebhookView webhook的响应结构，不返回签名密钥
+type WebhookView struct {
+	ID        string    `json:"id"`         // webhook ID
+	URL       string    `json:"url"`        // 回调地址
+	HasSecret bool      `json:"has_secret"` // 是否设置了签名密钥
+	Events    []string  `json:"events"`     // 订阅的事件类型
+	Disabled  bool      `json:"disabled"`   // 是否暂停投递
+	CreatedAt time.Time `json:"created_at"` // 创建时间
+	UpdatedAt time.Time `json:"updated_at"` // 更新时间
+}
+
+// WebhooksResponse 定义webhook响应结构
+type WebhooksResponse struct {
+	Success   bool           `json:"success"`           // 是否成功
+	Webhooks  []*WebhookView `json:"webhooks"`          // webhook列表
+	Message   string         `json:"message,omitempty"` // 可选消息
+	Timestamp string         `json:"timestamp"`         // 时间戳
+}
+
+// WebhookDeliveriesResponse 定义webhook投递记录响应结构
+type WebhookDeliveriesResponse struct {
+	Success    bool                          `json:"success"`    // 是否成功
+	WebhookID  string                        `json:"webhook_id"` // webhook ID
+	Deliveries []*etcdclient.WebhookDelivery `json:"deliveries"` // 最近的投递记录，从新到旧
+	Timestamp  string                        `json:"timestamp"`  // 时间戳
+}
+
+// newWebhookView 转换为不含密钥的响应结构
+func newWebhookView(webhook *etcdclient.Webhook) *WebhookView {
+	events := webhook.Events
+	if events == nil {
+		events = []string{}
+	}
+	return &WebhookView{
+		ID:        webhook.ID,
+		URL:       webhook.URL,
+		HasSecret: webhook.Secret != "",
+		Events:    events,
+		Disabled:  webhook.Disabled,
+		CreatedAt: webhook.CreatedAt,
+		UpdatedAt: webhook.UpdatedAt,
+	}
+}
+
+// respondWebhook 返回单个webhook
+func respondWebhook(c echo.Context, status int, webhook *etcdclient.Webhook, message string) error {
+	return c.JSON(status, &WebhooksResponse{
+		Success:   true,
+		Webhooks:  []*WebhookView{newWebhookView(webhook)},
+		Message:   message,
+		Timestamp: time.Now().Format(time.RFC3339),
+	})
+}
+
+// listWebhooksHandler 列出所有webhook
+func (h *EchoHandler) listWebhooksHandler(c echo.Context) error {
+	webhooks, err := h.etcdClient.ListWebhooks(c.Request().Context())
+	if err != nil {
+		h.log(c).Error("获取webhook列表失败", zap.Error(err))
+		return respondError(c, storageError(err))
+	}
+
+	views := make([]*WebhookView, 0, len(webhooks))
+	for _, webhook := range webhooks {
+		views = append(views, newWebhookView(webhook))
+	}
+	return c.JSON(http.StatusOK, &WebhooksResponse{
+		Success:   true,
+		Webhooks:  views,
+		Timestamp: time.Now().Format(time.RFC3339),
+	})
+}
+
+// createWebhookHandler 注册webhook
+func (h *EchoHandler) createWebhookHandler(c echo.Context) error {
+	req := new(WebhookRequest)
+	if apiErr := bindRequest(c, req); apiErr != nil {
+		return respondError(c, apiErr)
+	}
+
+	webhook := &etcdclient.Webhook{
+		URL:      req.URL,
+		Secret:   req.Secret,
+		Events:   req.Events,
+		Disabled: req.Disabled,
+	}
+	if err := webhook.Validate(); err != nil {
+		return respondError(c, badRequest(CodeInvalidParameter, err.Error()))
+	}
+
+	if err := h.etcdClient.PutWebhook(c.Request().Context(), webhook); err != nil {
+		h.log(c).Error("保存webhook失败", zap.Error(err))
+		return respondError(c, storageError(err))
+	}
+	return respondWebhook(c, http.StatusCreated, webhook, "webhook注册成功")
+}
+
+// getWebhookHandler 获取webhook
+func (h *EchoHandler) getWebhookHandler(c echo.Context) error {
+	webhook, err := h.etcdClient.GetWebhook(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return respondError(c, storageError(err))
+	}
+	return respondWebhook(c, http.StatusOK, webhook, "")
+}
+
+// updateWebhookHandler 更新webhook，请求中的密钥为空时保留原密钥
+func (h *EchoHandler) updateWebhookHandler(c echo.Context) error {
+	req := new(WebhookRequest)
+	if apiErr := bindRequest(c, req); apiErr != nil {
+		return respondError(c, apiErr)
+	}
+
+	ctx := c.Request().Context()
+	webhook, err := h.etcdClient.GetWebhook(ctx, c.Param("id"))
+	if err != nil {
+		return respondError(c, storageError(err))
+	}
+
+	webhook.URL = req.URL
+	webhook.Events = req.Events
+	webhook.Disabled = req.Disabled
+	if req.Secret != "" {
+		webhook.Secret = req.Secret
+	}
+	if err := webhook.Validate(); err != nil {
+		return respondError(c, badRequest(CodeInvalidParameter, err.Error()))
+	}
+
+	if err := h.etcdClient.PutWebhook(ctx, webhook); err != nil {
+		h.log(c).Error("保存webhook失败", zap.String("id", webhook.ID), zap.Error(err))
+		return respondError(c, storageError(err))
+	}
+	return respondWebhook(c, http.StatusOK, webhook, "webhook更新成功")
+}
+
+// deleteWebhookHandler 删除webhook及其投递记录
+func (h *EchoHandler) deleteWebhookHandler(c echo.Context) error {
+	id := c.Param("id")
+	if err := h.etcdClient.DeleteWebhook(c.Request().Context(), id); err != nil {
+		h.log(c).Error("删除webhook失败", zap.String("id", id), zap.Error(err))
+		return respondError(c, storageError(err))
+	}
+
+	return c.JSON(http.StatusOK, &WebhooksResponse{
+		Success:   true,
+		Webhooks:  []*WebhookView{},
+		Message:   "webhook删除成功",
+		Timestamp: time.Now().Format(time.RFC3339),
+	})
+}
+
+// listWebhookDeliveriesHandler 查看webhook最近的投递状态
+func (h *EchoHandler) listWebhookDeliveriesHandler(c echo.Context) error {
+	id := c.Param("id")
+	ctx := c.Request().Context()
+	if _, err := h.etcdClient.GetWebhook(ctx, id); err != nil {
+		return respondError(c, storageError(err))
+	}
+
+	deliveries, err := h.etcdClient.ListWebhookDeliveries(ctx, id)
+	if err != nil {
+		h.log(c).Error("获取webhook投递记录失败", zap.String("id", id), zap.Error(err))
+		return respondError(c, storageError(err))
+	}
+	return c.JSON(http.StatusOK, &WebhookDeliveriesResponse{
+		Success:    true,
+		WebhookID:  id,
+		Deliveries: deliveries,
+		Timestamp:  time.Now().Format(time.RFC3339),
+	})
+}
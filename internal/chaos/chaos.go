@@ -0,0 +1,145 @@
+package chaos
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Faults 当前注入的故障，零值表示不注入任何故障
+type Faults struct {
+	EtcdLatencyMs       int       `json:"etcd_latency_ms"`       // 每次etcd读写操作前增加的延迟（毫秒）
+	WatchDropRate       float64   `json:"watch_drop_rate"`       // 丢弃watch事件的比例，0到1之间
+	UpstreamTimeoutRate float64   `json:"upstream_timeout_rate"` // 上游DNS查询模拟超时的比例，0到1之间
+	ExpiresAt           time.Time `json:"expires_at,omitempty"`  // 故障自动清除的时间，为空表示直到手动清除
+}
+
+// Validate 校验故障设置
+func (f *Faults) Validate() error {
+	if f.EtcdLatencyMs < 0 {
+		return fmt.Errorf("etcd_latency_ms不能为负数")
+	}
+	if f.WatchDropRate < 0 || f.WatchDropRate > 1 {
+		return fmt.Errorf("watch_drop_rate必须在0到1之间")
+	}
+	if f.UpstreamTimeoutRate < 0 || f.UpstreamTimeoutRate > 1 {
+		return fmt.Errorf("upstream_timeout_rate必须在0到1之间")
+	}
+	return nil
+}
+
+// Stats 各类故障已注入的次数，清除故障时不重置
+type Stats struct {
+	EtcdDelayed        uint64 `json:"etcd_delayed"`         // 被延迟的etcd操作数
+	WatchEventsDropped uint64 `json:"watch_events_dropped"` // 被丢弃的watch事件数
+	UpstreamTimeouts   uint64 `json:"upstream_timeouts"`    // 模拟超时的上游DNS查询数
+}
+
+// Injector 保存当前注入的故障，由存储客户端、DNS服务器和管理API共享
+type Injector struct {
+	mu     sync.RWMutex
+	faults Faults
+	now    func() time.Time
+	rand   func() float64
+
+	etcdDelayed        atomic.Uint64
+	watchEventsDropped atomic.Uint64
+	upstreamTimeouts   atomic.Uint64
+}
+
+// NewInjector 创建不注入任何故障的Injector
+func NewInjector() *Injector {
+	return &Injector{now: time.Now, rand: rand.Float64}
+}
+
+// global 进程内共享的Injector
+var global = NewInjector()
+
+// Global 返回进程内共享的Injector。只有开启chaos.enabled时各组件才会查询它
+func Global() *Injector {
+	return global
+}
+
+// Set 替换当前注入的故障
+func (i *Injector) Set(faults Faults) error {
+	if err := faults.Validate(); err != nil {
+		return err
+	}
+	i.mu.Lock()
+	i.faults = faults
+	i.mu.Unlock()
+	return nil
+}
+
+// Clear 清除所有故障
+func (i *Injector) Clear() {
+	i.mu.Lock()
+	i.faults = Faults{}
+	i.mu.Unlock()
+}
+
+// Faults 返回当前生效的故障，已过期时返回零值
+func (i *Injector) Faults() Faults {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	if !i.faults.ExpiresAt.IsZero() && !i.now().Before(i.faults.ExpiresAt) {
+		return Faults{}
+	}
+	return i.faults
+}
+
+// Stats 返回各类故障已注入的次数
+func (i *Injector) Stats() Stats {
+	return Stats{
+		EtcdDelayed:        i.etcdDelayed.Load(),
+		WatchEventsDropped: i.watchEventsDropped.Load(),
+		UpstreamTimeouts:   i.upstreamTimeouts.Load(),
+	}
+}
+
+// DelayEtcd 按设置的延迟阻塞一次etcd操作，ctx先结束时返回ctx的错误
+func (i *Injector) DelayEtcd(ctx context.Context) error {
+	latency := time.Duration(i.Faults().EtcdLatencyMs) * time.Millisecond
+	if latency <= 0 {
+		return nil
+	}
+	i.etcdDelayed.Add(1)
+
+	timer := time.NewTimer(latency)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// DropWatchEvent 判断是否丢弃一个watch事件
+func (i *Injector) DropWatchEvent() bool {
+	if !i.hit(i.Faults().WatchDropRate) {
+		return false
+	}
+	i.watchEventsDropped.Add(1)
+	return true
+}
+
+// UpstreamTimeout 判断一次上游DNS查询是否模拟超时
+func (i *Injector) UpstreamTimeout() bool {
+	if !i.hit(i.Faults().UpstreamTimeoutRate) {
+		return false
+	}
+	i.upstreamTimeouts.Add(1)
+	return true
+}
+
+// hit 按比例rate随机判断是否注入故障
+func (i *Injector) hit(rate float64) bool {
+	if rate <= 0 {
+		return false
+	}
+	return rate >= 1 || i.rand() < rate
+}
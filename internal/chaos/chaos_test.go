@@ -0,0 +1,88 @@
+package chaos
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hewenyu/kong-discovery/internal/memstore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+func TestInjector_Faults(t *testing.T) {
+	inj := NewInjector()
+	now := time.Now()
+	inj.now = func() time.Time { return now }
+
+	assert.False(t, inj.DropWatchEvent())
+	assert.False(t, inj.UpstreamTimeout())
+	assert.NoError(t, inj.DelayEtcd(context.Background()))
+
+	assert.Error(t, inj.Set(Faults{WatchDropRate: 1.5}))
+	assert.Error(t, inj.Set(Faults{EtcdLatencyMs: -1}))
+
+	require.NoError(t, inj.Set(Faults{WatchDropRate: 1, UpstreamTimeoutRate: 0.5, ExpiresAt: now.Add(time.Minute)}))
+	inj.rand = func() float64 { return 0.7 }
+	assert.True(t, inj.DropWatchEvent())
+	assert.False(t, inj.UpstreamTimeout())
+	inj.rand = func() float64 { return 0.2 }
+	assert.True(t, inj.UpstreamTimeout())
+	assert.Equal(t, Stats{WatchEventsDropped: 1, UpstreamTimeouts: 1}, inj.Stats())
+
+	// 过期后不再注入故障
+	now = now.Add(time.Minute)
+	assert.Equal(t, Faults{}, inj.Faults())
+	assert.False(t, inj.DropWatchEvent())
+
+	require.NoError(t, inj.Set(Faults{EtcdLatencyMs: 1000}))
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, inj.DelayEtcd(ctx), context.DeadlineExceeded)
+
+	inj.Clear()
+	assert.Equal(t, Faults{}, inj.Faults())
+	assert.Equal(t, uint64(1), inj.Stats().EtcdDelayed)
+}
+
+func TestWrapClient(t *testing.T) {
+	inj := NewInjector()
+	client := memstore.New().NewClient()
+	WrapClient(client, inj)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// 延迟在读写操作和事务提交前生效
+	require.NoError(t, inj.Set(Faults{EtcdLatencyMs: 20}))
+	start := time.Now()
+	_, err := client.Put(ctx, "/chaos/a", "1")
+	require.NoError(t, err)
+	_, err = client.Txn(ctx).If(clientv3.Compare(clientv3.Version("/chaos/a"), ">", 0)).Then(clientv3.OpGet("/chaos/a")).Commit()
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, time.Since(start), 40*time.Millisecond)
+	assert.Equal(t, uint64(2), inj.Stats().EtcdDelayed)
+
+	// 丢弃全部事件时watch收不到应答，清除后恢复
+	require.NoError(t, inj.Set(Faults{WatchDropRate: 1}))
+	events := client.Watch(ctx, "/chaos/", clientv3.WithPrefix())
+	_, err = client.Put(ctx, "/chaos/b", "1")
+	require.NoError(t, err)
+	select {
+	case resp := <-events:
+		t.Fatalf("丢弃的事件不应送达: %v", resp.Events)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	inj.Clear()
+	_, err = client.Put(ctx, "/chaos/c", "1")
+	require.NoError(t, err)
+	select {
+	case resp := <-events:
+		require.Len(t, resp.Events, 1)
+		assert.Equal(t, "/chaos/c", string(resp.Events[0].Kv.Key))
+	case <-time.After(time.Second):
+		t.Fatal("清除故障后应收到watch事件")
+	}
+	assert.Equal(t, uint64(1), inj.Stats().WatchEventsDropped)
+}
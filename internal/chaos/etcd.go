@@ -0,0 +1,116 @@
+package chaos
+
+import (
+	"context"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// WrapClient 让客户端的读写操作和watch经过Injector：读写操作前按设置增加延迟，watch应答中的事件按比例丢弃。
+// 只替换客户端的KV和Watcher，租约、选举等其他操作不受影响
+func WrapClient(client *clientv3.Client, inj *Injector) {
+	client.KV = &faultyKV{KV: client.KV, inj: inj}
+	client.Watcher = &faultyWatcher{Watcher: client.Watcher, inj: inj}
+}
+
+// faultyKV 在每次读写操作前增加延迟的KV
+type faultyKV struct {
+	clientv3.KV
+	inj *Injector
+}
+
+func (k *faultyKV) Get(ctx context.Context, key string, opts ...clientv3.OpOption) (*clientv3.GetResponse, error) {
+	if err := k.inj.DelayEtcd(ctx); err != nil {
+		return nil, err
+	}
+	return k.KV.Get(ctx, key, opts...)
+}
+
+func (k *faultyKV) Put(ctx context.Context, key, val string, opts ...clientv3.OpOption) (*clientv3.PutResponse, error) {
+	if err := k.inj.DelayEtcd(ctx); err != nil {
+		return nil, err
+	}
+	return k.KV.Put(ctx, key, val, opts...)
+}
+
+func (k *faultyKV) Delete(ctx context.Context, key string, opts ...clientv3.OpOption) (*clientv3.DeleteResponse, error) {
+	if err := k.inj.DelayEtcd(ctx); err != nil {
+		return nil, err
+	}
+	return k.KV.Delete(ctx, key, opts...)
+}
+
+func (k *faultyKV) Do(ctx context.Context, op clientv3.Op) (clientv3.OpResponse, error) {
+	if err := k.inj.DelayEtcd(ctx); err != nil {
+		return clientv3.OpResponse{}, err
+	}
+	return k.KV.Do(ctx, op)
+}
+
+// Txn 延迟在提交时生效
+func (k *faultyKV) Txn(ctx context.Context) clientv3.Txn {
+	return &faultyTxn{Txn: k.KV.Txn(ctx), ctx: ctx, inj: k.inj}
+}
+
+// faultyTxn 提交前增加延迟的事务
+type faultyTxn struct {
+	clientv3.Txn
+	ctx context.Context
+	inj *Injector
+}
+
+func (t *faultyTxn) If(cs ...clientv3.Cmp) clientv3.Txn {
+	t.Txn = t.Txn.If(cs...)
+	return t
+}
+
+func (t *faultyTxn) Then(ops ...clientv3.Op) clientv3.Txn {
+	t.Txn = t.Txn.Then(ops...)
+	return t
+}
+
+func (t *faultyTxn) Else(ops ...clientv3.Op) clientv3.Txn {
+	t.Txn = t.Txn.Else(ops...)
+	return t
+}
+
+func (t *faultyTxn) Commit() (*clientv3.TxnResponse, error) {
+	if err := t.inj.DelayEtcd(t.ctx); err != nil {
+		return nil, err
+	}
+	return t.Txn.Commit()
+}
+
+// faultyWatcher 按比例丢弃watch事件的Watcher，压缩、取消等不带事件的应答原样转发
+type faultyWatcher struct {
+	clientv3.Watcher
+	inj *Injector
+}
+
+func (w *faultyWatcher) Watch(ctx context.Context, key string, opts ...clientv3.OpOption) clientv3.WatchChan {
+	in := w.Watcher.Watch(ctx, key, opts...)
+	out := make(chan clientv3.WatchResponse)
+	go func() {
+		defer close(out)
+		for resp := range in {
+			if len(resp.Events) > 0 {
+				kept := make([]*clientv3.Event, 0, len(resp.Events))
+				for _, ev := range resp.Events {
+					if !w.inj.DropWatchEvent() {
+						kept = append(kept, ev)
+					}
+				}
+				if len(kept) == 0 {
+					continue
+				}
+				resp.Events = kept
+			}
+			select {
+			case out <- resp:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
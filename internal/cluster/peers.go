@@ -0,0 +1,94 @@
+package cluster
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hewenyu/kong-discovery/internal/etcdclient"
+)
+
+// 节点在集群中的角色
+const (
+	RoleLeader   = "leader"   // 至少持有一个后台任务（协调、调度、webhook等）的领导权
+	RoleFollower = "follower" // 只提供DNS和API服务
+)
+
+// Peer 集群中一个正在运行的kong-discovery节点
+type Peer struct {
+	InstanceID       string    `json:"instance_id"`       // 节点的实例ID
+	Hostname         string    `json:"hostname"`          // 主机名
+	Address          string    `json:"address"`           // 节点地址
+	Version          string    `json:"version"`           // 版本号
+	Role             string    `json:"role"`              // 角色：leader或follower
+	Leading          []string  `json:"leading,omitempty"` // 持有领导权的选举名称
+	DNSPort          int       `json:"dns_port"`          // DNS端口
+	ManagementPort   int       `json:"management_port"`   // 管理API端口
+	RegistrationPort int       `json:"registration_port"` // 服务注册API端口
+	Health           string    `json:"health"`            // 健康状态
+	StartedAt        time.Time `json:"started_at"`        // 启动时间
+}
+
+// ListPeers 列出注册在系统命名空间中的所有节点，按主机名和实例ID排序
+// 各后台任务的选举候选者标识以主机名开头，据此把领导权归属到节点；同一主机上的多个节点无法区分时都会显示为领导者
+func ListPeers(ctx context.Context, client etcdclient.Client, serviceName string) ([]*Peer, error) {
+	if serviceName == "" {
+		serviceName = DefaultServiceName
+	}
+
+	instances, err := client.GetServiceInstances(ctx, serviceName)
+	if err != nil {
+		return nil, err
+	}
+	leaders, err := client.ElectionLeaders(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	peers := make([]*Peer, 0, len(instances))
+	for _, instance := range instances {
+		if etcdclient.NamespaceOf(instance.Namespace) != etcdclient.SystemNamespace {
+			continue
+		}
+		peers = append(peers, newPeer(instance, leaders))
+	}
+	sort.Slice(peers, func(i, j int) bool {
+		if peers[i].Hostname != peers[j].Hostname {
+			return peers[i].Hostname < peers[j].Hostname
+		}
+		return peers[i].InstanceID < peers[j].InstanceID
+	})
+	return peers, nil
+}
+
+// newPeer 从节点注册的实例生成对等节点信息
+func newPeer(instance *etcdclient.ServiceInstance, leaders map[string]string) *Peer {
+	meta := instance.Metadata
+	peer := &Peer{
+		InstanceID: instance.InstanceID,
+		Hostname:   meta[MetaHostname],
+		Address:    instance.IPAddress,
+		Version:    meta[MetaVersion],
+		Role:       RoleFollower,
+		DNSPort:    instance.Port,
+		Health:     instance.HealthStatus(),
+	}
+	peer.ManagementPort, _ = strconv.Atoi(meta[MetaManagementPort])
+	peer.RegistrationPort, _ = strconv.Atoi(meta[MetaRegistrationPort])
+	peer.StartedAt, _ = time.Parse(time.RFC3339, meta[MetaStartedAt])
+
+	if peer.Hostname != "" {
+		for election, candidate := range leaders {
+			if strings.HasPrefix(candidate, peer.Hostname+"-") {
+				peer.Leading = append(peer.Leading, election)
+			}
+		}
+	}
+	if len(peer.Leading) > 0 {
+		sort.Strings(peer.Leading)
+		peer.Role = RoleLeader
+	}
+	return peer
+}
@@ -0,0 +1,191 @@
+package cluster
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hewenyu/kong-discovery/internal/config"
+	"github.com/hewenyu/kong-discovery/internal/etcdclient"
+	"github.com/hewenyu/kong-discovery/internal/sdk"
+	"go.uber.org/zap"
+)
+
+// DefaultServiceName 未配置时节点自身注册使用的服务名称
+const DefaultServiceName = "kong-discovery"
+
+// 节点实例元数据的键
+const (
+	MetaVersion          = "version"           // 版本号
+	MetaHostname         = "hostname"          // 主机名，用于关联选举中的领导者
+	MetaDNSPort          = "dns_port"          // DNS端口
+	MetaManagementPort   = "management_port"   // 管理API端口
+	MetaRegistrationPort = "registration_port" // 服务注册API端口
+	MetaStartedAt        = "started_at"        // 启动时间（RFC3339）
+)
+
+const (
+	defaultTTL = 30
+
+	// retryDelay 注册或刷新失败（如etcd尚未连接）后的重试间隔
+	retryDelay = 5 * time.Second
+)
+
+// Registrar 定义节点自身注册接口
+type Registrar interface {
+	// Start 启动注册（非阻塞）
+	Start() error
+
+	// Stop 停止注册并注销本节点
+	Stop(ctx context.Context) error
+}
+
+// SelfRegistrar 把本节点的DNS和API端点注册到系统命名空间，并按TTL的三分之一刷新租约
+// etcd尚未连接或实例被删除（如租约过期、被手动注销）时在后台重新注册，不影响节点提供服务
+type SelfRegistrar struct {
+	cfg      *config.Config
+	logger   config.Logger
+	client   etcdclient.Client
+	instance *etcdclient.ServiceInstance
+	interval time.Duration
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewRegistrar 创建节点自身注册器
+func NewRegistrar(cfg *config.Config, logger config.Logger, client etcdclient.Client, version string) Registrar {
+	ttl := cfg.SelfRegistration.TTL
+	if ttl <= 0 {
+		ttl = defaultTTL
+	}
+	serviceName := cfg.SelfRegistration.ServiceName
+	if serviceName == "" {
+		serviceName = DefaultServiceName
+	}
+
+	hostname, _ := os.Hostname()
+	return &SelfRegistrar{
+		cfg:    cfg,
+		logger: logger,
+		client: client,
+		instance: &etcdclient.ServiceInstance{
+			ServiceName: serviceName,
+			InstanceID:  fmt.Sprintf("%s-%s", hostname, uuid.New().String()[:8]),
+			IPAddress:   cfg.SelfRegistration.Address,
+			Port:        cfg.DNS.Port,
+			TTL:         ttl,
+			Namespace:   etcdclient.SystemNamespace,
+			Metadata: map[string]string{
+				MetaVersion:          version,
+				MetaHostname:         hostname,
+				MetaDNSPort:          strconv.Itoa(cfg.DNS.Port),
+				MetaManagementPort:   strconv.Itoa(cfg.API.Management.Port),
+				MetaRegistrationPort: strconv.Itoa(cfg.API.Registration.Port),
+				MetaStartedAt:        time.Now().UTC().Format(time.RFC3339),
+			},
+		},
+		interval: time.Duration(ttl) * time.Second / 3,
+	}
+}
+
+// Start 启动注册
+func (r *SelfRegistrar) Start() error {
+	if r.done != nil {
+		return fmt.Errorf("节点注册已启动")
+	}
+
+	if r.instance.IPAddress == "" {
+		ip, err := sdk.DetectIP()
+		if err != nil {
+			return fmt.Errorf("探测本节点地址失败: %w", err)
+		}
+		r.instance.IPAddress = ip
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r.cancel = cancel
+	r.done = make(chan struct{})
+
+	r.logger.Info("启动节点自身注册",
+		zap.String("service", r.instance.ServiceName),
+		zap.String("id", r.instance.InstanceID),
+		zap.String("address", r.instance.IPAddress))
+
+	go r.run(ctx)
+	return nil
+}
+
+// Stop 停止注册并注销本节点，其他节点的对等节点列表中立即不再出现本节点
+func (r *SelfRegistrar) Stop(ctx context.Context) error {
+	if r.done == nil {
+		return nil
+	}
+
+	r.cancel()
+	select {
+	case <-r.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	if err := r.client.DeregisterService(ctx, r.instance.ServiceName, r.instance.InstanceID); err != nil &&
+		!errors.Is(err, etcdclient.ErrInstanceNotFound) {
+		return fmt.Errorf("注销本节点失败: %w", err)
+	}
+	return nil
+}
+
+// run 注册后定期刷新租约，失败时按retryDelay重试
+func (r *SelfRegistrar) run(ctx context.Context) {
+	defer close(r.done)
+
+	registered := false
+	for {
+		var err error
+		if registered {
+			err = r.client.RefreshServiceLease(ctx, r.instance.ServiceName, r.instance.InstanceID, r.instance.TTL)
+			if errors.Is(err, etcdclient.ErrInstanceNotFound) {
+				r.logger.Warn("本节点的注册已不存在，重新注册")
+				err = r.register(ctx)
+			}
+		} else {
+			err = r.register(ctx)
+		}
+		registered = err == nil
+
+		delay := r.interval
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			r.logger.Warn("节点自身注册失败，稍后重试", zap.Error(err))
+			delay = retryDelay
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+	}
+}
+
+// register 创建系统命名空间（已存在时忽略）并注册本节点
+func (r *SelfRegistrar) register(ctx context.Context) error {
+	err := r.client.CreateNamespace(ctx, &etcdclient.Namespace{Name: etcdclient.SystemNamespace})
+	if err != nil && !errors.Is(err, etcdclient.ErrNamespaceExists) {
+		return err
+	}
+
+	instance := *r.instance
+	if err := r.client.RegisterService(ctx, &instance); err != nil {
+		return err
+	}
+	r.logger.Debug("本节点已注册", zap.String("id", instance.InstanceID))
+	return nil
+}
@@ -0,0 +1,127 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/hewenyu/kong-discovery/internal/config"
+	"github.com/hewenyu/kong-discovery/internal/etcdclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// 创建测试用的配置
+func createTestConfig(t *testing.T) *config.Config {
+	t.Helper()
+
+	cfg := &config.Config{}
+	etcdclient.ApplyTestStorage(cfg)
+	cfg.DNS.Port = 5353
+	cfg.API.Management.Port = 9090
+	cfg.API.Registration.Port = 8080
+	cfg.SelfRegistration.Enabled = true
+	cfg.SelfRegistration.ServiceName = fmt.Sprintf("kong-discovery-%d", time.Now().UnixNano())
+	cfg.SelfRegistration.Address = "10.0.0.10"
+	cfg.SelfRegistration.TTL = 30
+	return cfg
+}
+
+// 创建测试用的日志记录器
+func createTestLogger(t *testing.T) config.Logger {
+	t.Helper()
+
+	logger, err := config.NewLogger(true)
+	require.NoError(t, err, "创建测试日志记录器失败")
+	return logger
+}
+
+func TestSelfRegistrar_ListPeers(t *testing.T) {
+	// 跳过集成测试，除非明确要求运行
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	client := etcdclient.CreateEtcdClientForTest(t)
+	t.Cleanup(func() { _ = client.Close() })
+
+	cfg := createTestConfig(t)
+	registrar := NewRegistrar(cfg, createTestLogger(t), client, "1.2.3")
+	require.NoError(t, registrar.Start())
+	require.Error(t, registrar.Start(), "重复启动应返回错误")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var peers []*Peer
+	require.Eventually(t, func() bool {
+		var err error
+		peers, err = ListPeers(ctx, client, cfg.SelfRegistration.ServiceName)
+		return err == nil && len(peers) == 1
+	}, 3*time.Second, 50*time.Millisecond, "本节点应注册到系统命名空间")
+
+	hostname, _ := os.Hostname()
+	peer := peers[0]
+	assert.Equal(t, hostname, peer.Hostname)
+	assert.Equal(t, "10.0.0.10", peer.Address)
+	assert.Equal(t, "1.2.3", peer.Version)
+	assert.Equal(t, 5353, peer.DNSPort)
+	assert.Equal(t, 9090, peer.ManagementPort)
+	assert.Equal(t, 8080, peer.RegistrationPort)
+	assert.Equal(t, etcdclient.HealthPassing, peer.Health)
+	assert.False(t, peer.StartedAt.IsZero())
+
+	// 系统命名空间不能删除
+	assert.Error(t, client.DeleteNamespace(ctx, etcdclient.SystemNamespace, true))
+
+	// 持有选举领导权的节点显示为领导者
+	election := fmt.Sprintf("peers-test-%d", time.Now().UnixNano())
+	leadership, err := client.Campaign(ctx, election, hostname+"-abcdef12")
+	require.NoError(t, err)
+	peers, err = ListPeers(ctx, client, cfg.SelfRegistration.ServiceName)
+	require.NoError(t, err)
+	require.Len(t, peers, 1)
+	assert.Equal(t, RoleLeader, peers[0].Role)
+	assert.Contains(t, peers[0].Leading, election)
+
+	require.NoError(t, leadership.Resign(ctx))
+	peers, err = ListPeers(ctx, client, cfg.SelfRegistration.ServiceName)
+	require.NoError(t, err)
+	assert.NotContains(t, peers[0].Leading, election)
+
+	// 停止后注销本节点
+	require.NoError(t, registrar.Stop(ctx))
+	peers, err = ListPeers(ctx, client, cfg.SelfRegistration.ServiceName)
+	require.NoError(t, err)
+	assert.Empty(t, peers)
+}
+
+func TestSelfRegistrar_Reregister(t *testing.T) {
+	// 跳过集成测试，除非明确要求运行
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	client := etcdclient.CreateEtcdClientForTest(t)
+	t.Cleanup(func() { _ = client.Close() })
+
+	cfg := createTestConfig(t)
+	cfg.SelfRegistration.TTL = 1
+	registrar := NewRegistrar(cfg, createTestLogger(t), client, "1.2.3").(*SelfRegistrar)
+	require.NoError(t, registrar.Start())
+	t.Cleanup(func() { _ = registrar.Stop(context.Background()) })
+
+	ctx := context.Background()
+	serviceName := cfg.SelfRegistration.ServiceName
+	registered := func() bool {
+		instances, err := client.GetServiceInstances(ctx, serviceName)
+		return err == nil && len(instances) == 1
+	}
+	require.Eventually(t, registered, 3*time.Second, 50*time.Millisecond)
+
+	// 实例被手动注销后，下一次刷新时重新注册
+	require.NoError(t, client.DeregisterService(ctx, serviceName, registrar.instance.InstanceID))
+	require.Eventually(t, registered, 3*time.Second, 50*time.Millisecond, "实例被删除后应重新注册")
+}
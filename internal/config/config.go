@@ -3,52 +3,460 @@ package config
 import (
 	"fmt"
 	"os"
+	"path"
 	"strings"
 
 	"github.com/spf13/viper"
 )
 
+// 存储后端
+const (
+	StorageEtcd     = "etcd"     // 使用etcd集群
+	StorageMemory   = "memory"   // 使用进程内存，数据不持久化，用于演示和单元测试
+	StorageSQLite   = "sqlite"   // 使用SQLite数据库文件，用于开发和单节点部署
+	StoragePostgres = "postgres" // 使用PostgreSQL数据库，用于不能运行etcd的生产环境
+)
+
+// 应答记录超过上限时的选取方式
+const (
+	SelectionRoundRobin = "round_robin" // 每次应答从下一个位置开始选取，所有记录依次轮流出现
+	SelectionRandom     = "random"      // 每次应答从随机位置开始选取
+	SelectionSticky     = "sticky"      // 按客户端地址一致性哈希选取，同一客户端总是得到相同的记录
+)
+
 // Config 应用程序配置结构
 type Config struct {
+	// 存储后端："etcd"、"memory"、"sqlite" 或 "postgres"
+	Storage string `mapstructure:"storage"`
+
+	// SQL存储配置，storage为sqlite或postgres时使用
+	SQL struct {
+		DSN          string `mapstructure:"dsn"`           // 数据库连接串：sqlite为数据库文件路径，postgres为连接URL
+		PollInterval int    `mapstructure:"poll_interval"` // 轮询其他节点写入的间隔（毫秒），postgres收到通知时立即读取
+		Retention    int    `mapstructure:"retention"`     // 保留的历史修订版本数，更早的历史定期压缩
+	} `mapstructure:"sql"`
+
 	// etcd配置
 	Etcd struct {
-		Endpoints []string `mapstructure:"endpoints"`
-		Username  string   `mapstructure:"username"`
-		Password  string   `mapstructure:"password"`
+		Endpoints        []string `mapstructure:"endpoints"`
+		Username         string   `mapstructure:"username"`
+		Password         string   `mapstructure:"password"`
+		MaxTxnOps        int      `mapstructure:"max_txn_ops"`        // 单个事务允许的最大操作数，需与etcd的--max-txn-ops一致
+		AutoSyncInterval int      `mapstructure:"auto_sync_interval"` // 从etcd成员列表同步端点的间隔（秒），为0时不同步
+
+		// 启动时连接失败后的重试退避
+		Retry struct {
+			InitialBackoff int `mapstructure:"initial_backoff"` // 首次重试前的等待时间（毫秒），之后每次翻倍
+			MaxBackoff     int `mapstructure:"max_backoff"`     // 重试等待时间的上限（毫秒）
+		} `mapstructure:"retry"`
+
+		// 本地快照：连接成功后定期保存发现数据，启动时连接失败则从快照提供只读数据直到连接成功
+		Snapshot struct {
+			Path      string `mapstructure:"path"`       // 快照文件路径，为空时不保存
+			Interval  int    `mapstructure:"interval"`   // 保存间隔（秒），关闭时还会再保存一次
+			WarmStart bool   `mapstructure:"warm_start"` // 启动时快照存在则先用快照提供查询，在后台连接存储
+		} `mapstructure:"snapshot"`
 	} `mapstructure:"etcd"`
 
+	// 密钥引用配置：etcd凭据、令牌等敏感配置项可以写成 ${file:路径}、${env:变量名} 或 ${vault:路径#字段}，启动时读取
+	Secrets struct {
+		Vault struct {
+			Address string `mapstructure:"address"` // Vault地址，为空时使用环境变量VAULT_ADDR
+			Token   string `mapstructure:"token"`   // 访问令牌，可以是文件或环境变量引用，为空时使用环境变量VAULT_TOKEN
+			Timeout int    `mapstructure:"timeout"` // 读取单个密钥的超时时间（秒）
+		} `mapstructure:"vault"`
+	} `mapstructure:"secrets"`
+
 	// DNS服务配置
 	DNS struct {
 		ListenAddress string `mapstructure:"listen_address"`
 		Port          int    `mapstructure:"port"`
 		Protocol      string `mapstructure:"protocol"` // "udp", "tcp", 或 "both"
 		UpstreamDNS   string `mapstructure:"upstream_dns"`
+		DefaultTTL    int    `mapstructure:"default_ttl"`   // 未设置TTL的DNS记录使用的TTL（秒）
+		ServiceTTL    int    `mapstructure:"service_ttl"`   // 服务发现应答的默认TTL（秒）
+		QueryTimeout  int    `mapstructure:"query_timeout"` // 单个查询（包括etcd查询和上游转发）的超时时间（毫秒）
+
+		// 全局TTL限制，命名空间和服务的TTL策略（PUT /admin/namespaces/<命名空间>/ttl-policy、
+		// PUT /admin/services/<服务>/ttl-policy）得到的TTL最终都受此限制
+		TTLPolicy struct {
+			MinTTL int `mapstructure:"min_ttl"` // 应答TTL的下限（秒），0表示不限制
+			MaxTTL int `mapstructure:"max_ttl"` // 应答TTL的上限（秒），0表示不限制
+		} `mapstructure:"ttl_policy"`
+
+		// 上游DNS服务器列表及熔断配置
+		Upstream struct {
+			Servers          []string `mapstructure:"servers"`           // 额外的上游DNS服务器，按顺序排在upstream_dns之后
+			FailureThreshold int      `mapstructure:"failure_threshold"` // 连续失败多少次后熔断
+			Cooldown         int      `mapstructure:"cooldown"`          // 熔断后等待多少秒再进行恢复探测
+			ProbeInterval    int      `mapstructure:"probe_interval"`    // 恢复探测的检查间隔（秒）
+			Mode             string   `mapstructure:"mode"`              // 转发模式："sequential" 或 "race"
+			RaceCount        int      `mapstructure:"race_count"`        // race模式下同时查询的上游数量
+			ReloadInterval   int      `mapstructure:"reload_interval"`   // 从etcd重新加载上游配置的间隔（秒）
+
+			// 是否随机改变转发查询中域名的大小写（DNS 0x20）并要求上游应答原样返回，个别不保留大小写的上游需要关闭
+			CaseRandomization bool `mapstructure:"case_randomization"`
+
+			// 到上游的连接复用
+			Protocol     string `mapstructure:"protocol"`       // 转发查询使用的协议："udp" 或 "tcp"
+			MaxIdleConns int    `mapstructure:"max_idle_conns"` // 每个上游最多保留的空闲连接数，0表示每次查询新建连接
+			IdleTimeout  int    `mapstructure:"idle_timeout"`   // 空闲连接的最长保留时间（秒）
+		} `mapstructure:"upstream"`
+
+		// 按域名后缀的条件转发规则，未匹配的查询使用上面的全局上游
+		ForwardRules []ForwardRule `mapstructure:"forward_rules"`
+
+		// 按客户端来源网段划分的DNS视图
+		Views []View `mapstructure:"views"`
+
+		// 同时监听的多个地址，设置后取代上面的listen_address、port和protocol
+		Listeners []Listener `mapstructure:"listeners"`
+
+		// 每个UDP监听地址打开的套接字数，大于1时使用SO_REUSEPORT（仅Linux），为0时与CPU数相同
+		UDPSockets int `mapstructure:"udp_sockets"`
+
+		// 使用systemd套接字激活传递的套接字（LISTEN_FDS），设置后忽略listeners
+		SocketActivation bool `mapstructure:"socket_activation"`
+
+		// TSIG密钥，使用这些密钥签名的查询以密钥名称作为身份，可在服务解析ACL中授权
+		TSIGKeys []TSIGKey `mapstructure:"tsig_keys"`
+
+		// Kong兼容模式，作为Kong网关的dns_resolver时开启
+		KongCompat struct {
+			Enabled bool `mapstructure:"enabled"` // 是否启用Kong兼容模式
+			MaxTTL  int  `mapstructure:"max_ttl"` // 服务应答TTL的上限（秒），0表示不限制
+		} `mapstructure:"kong_compat"`
+
+		// 应答大小控制，使实例很多的服务的应答不超过UDP的大小限制
+		Response struct {
+			Compress          bool   `mapstructure:"compress"`           // 启用名称压缩
+			MaxAnswers        int    `mapstructure:"max_answers"`        // 本地应答中每种类型的最大记录数，超出时轮转返回，0表示不限制
+			MaxSRVRecords     int    `mapstructure:"max_srv_records"`    // SRV应答的最大记录数，优先于max_answers，0表示使用max_answers
+			Selection         string `mapstructure:"selection"`          // 超过上限时的选取方式：round_robin、random或sticky
+			MinimalAdditional bool   `mapstructure:"minimal_additional"` // 不在附加段返回SRV目标的地址记录
+		} `mapstructure:"response"`
+
+		// 过载保护：限制同时处理的查询数，超出的查询立即拒绝
+		Overload struct {
+			MaxInflight int    `mapstructure:"max_inflight"` // 同时处理的最大查询数，0表示不限制
+			Action      string `mapstructure:"action"`       // 超出上限时的处理："servfail" 返回SERVFAIL，"drop" 不应答
+		} `mapstructure:"overload"`
+
+		// 查询捕获：把收到的查询逐行写入JSON文件，可用cmd/replay按原有节奏重放做压测
+		Capture struct {
+			Path        string  `mapstructure:"path"`         // 捕获文件路径，为空时不捕获
+			SampleRatio float64 `mapstructure:"sample_ratio"` // 采样比例，0到1之间
+			Buffer      int     `mapstructure:"buffer"`       // 写入队列长度，队列满时丢弃新的查询
+		} `mapstructure:"capture"`
+
+		// 热门域名预取：定期保存查询最多的域名，重启后在就绪之前先解析一遍
+		Prefetch struct {
+			Path     string `mapstructure:"path"`     // 热门域名文件路径，为空时不保存也不预取
+			TopN     int    `mapstructure:"top_n"`    // 保存的热门域名数量
+			Interval int    `mapstructure:"interval"` // 保存间隔（秒），关闭时还会再保存一次
+			Timeout  int    `mapstructure:"timeout"`  // 启动时预取的最长时间（秒），超时后不再等待剩余的域名
+		} `mapstructure:"prefetch"`
+
+		// 热门服务域名的预生成应答：预先打包最热门的服务域名的A、AAAA和SRV应答，命中时只替换ID直接发送
+		Precompute struct {
+			TopN     int `mapstructure:"top_n"`    // 预生成应答的热门服务域名数，0表示关闭
+			Interval int `mapstructure:"interval"` // 重新选择热门域名并重新生成全部应答的间隔（秒）
+		} `mapstructure:"precompute"`
+
+		// 按实例随心跳上报的负载自动调整SRV权重和A应答选取实例的概率，负载越高分到的流量越少
+		LoadWeights struct {
+			Enabled   bool    `mapstructure:"enabled"`   // 是否开启，运行时可以通过 PUT /admin/dns/load-weights 临时关闭
+			Metric    string  `mapstructure:"metric"`    // 使用的负载指标，即心跳health.load中的键，如cpu、inflight
+			MaxLoad   float64 `mapstructure:"max_load"`  // 视为满载的指标值，达到后权重降到下限
+			Smoothing float64 `mapstructure:"smoothing"` // 指数平滑中最新一次上报的占比，0到1之间，越小变化越平缓
+			MinRatio  float64 `mapstructure:"min_ratio"` // 权重下限占实例配置权重的比例，满载的实例仍分到少量流量
+			MaxAge    int     `mapstructure:"max_age"`   // 负载上报的有效期（秒），超过后实例恢复配置的权重
+		} `mapstructure:"load_weights"`
+
+		// 是否应答ANY查询：开启后返回该名称所有已知类型的记录，关闭时ANY查询与其他不支持的类型一样转发到上游
+		AnyQuery bool `mapstructure:"any_query"`
 	} `mapstructure:"dns"`
 
 	// API服务配置
 	API struct {
+		// 无版本的旧路径（如 /services/register），作为 /v1 路径的别名保留，响应带有Deprecation头
+		LegacyPaths struct {
+			Disabled bool   `mapstructure:"disabled"` // 关闭旧路径，只提供/v1路径
+			Sunset   string `mapstructure:"sunset"`   // 计划停止提供旧路径的日期（2006-01-02或RFC3339），设置后响应带有Sunset头
+		} `mapstructure:"legacy_paths"`
+
 		// 管理API端口配置
 		Management struct {
 			ListenAddress string `mapstructure:"listen_address"`
 			Port          int    `mapstructure:"port"`
+
+			// 调试端点：/debug/pprof、/debug/vars和POST /debug/dump，排查生产实例的性能问题时开启
+			Debug struct {
+				Enabled bool   `mapstructure:"enabled"`  // 是否开启调试端点
+				Token   string `mapstructure:"token"`    // 访问调试端点必须携带的Bearer令牌，开启时必须设置
+				DumpDir string `mapstructure:"dump_dir"` // 转储文件的目录，为空时使用系统临时目录
+			} `mapstructure:"debug"`
+
+			// 列表端点的响应压缩，客户端通过Accept-Encoding: gzip声明支持时生效
+			Compression struct {
+				Enabled   bool `mapstructure:"enabled"`    // 是否压缩列表端点的响应
+				Level     int  `mapstructure:"level"`      // gzip压缩级别，1（最快）到9（最小）
+				MinLength int  `mapstructure:"min_length"` // 响应体小于该字节数时不压缩
+			} `mapstructure:"compression"`
 		} `mapstructure:"management"`
 
 		// 服务注册API端口配置
 		Registration struct {
-			ListenAddress string `mapstructure:"listen_address"`
-			Port          int    `mapstructure:"port"`
+			ListenAddress    string         `mapstructure:"listen_address"`
+			Port             int            `mapstructure:"port"`
+			DuplicateAddress string         `mapstructure:"duplicate_address"` // 不同实例ID注册相同IP:端口时的默认处理：allow、replace或reject，可按服务单独设置
+			MinSDKVersion    string         `mapstructure:"min_sdk_version"`   // 允许的最低SDK版本，低于该版本的SDK请求返回426，为空时不限制
+			Metadata         MetadataLimits `mapstructure:"metadata"`          // 客户端注册时提交的实例元数据的限制
+			Reserved         ReservedNames  `mapstructure:"reserved"`          // 不允许注册的服务名称和命名空间
 		} `mapstructure:"registration"`
+
+		// Consul兼容API，挂载在服务注册API上，供consul_sd、Fabio、registrator等工具直接使用
+		Consul struct {
+			Enabled    bool   `mapstructure:"enabled"`     // 是否启用Consul兼容端点
+			Datacenter string `mapstructure:"datacenter"`  // 返回给客户端的数据中心名称
+			DefaultTTL int    `mapstructure:"default_ttl"` // 注册时没有TTL检查的服务使用的租约TTL（秒）
+		} `mapstructure:"consul"`
+
+		// Eureka兼容API，挂载在服务注册API上，供尚未迁移的Spring Cloud服务注册和发现
+		Eureka struct {
+			Enabled        bool `mapstructure:"enabled"`         // 是否启用Eureka兼容端点
+			DeltaRetention int  `mapstructure:"delta_retention"` // 增量获取返回的变更保留时间（秒）
+		} `mapstructure:"eureka"`
 	} `mapstructure:"api"`
 
+	// 命名空间配置
+	Namespace struct {
+		// 未单独设置配额的命名空间使用的默认配额，0表示不限制
+		DefaultQuota Quota `mapstructure:"default_quota"`
+
+		// 未单独设置时通过服务域名的TXT查询公开的实例元数据键，为空时不公开任何元数据
+		DefaultTXTMetadata []string `mapstructure:"default_txt_metadata"`
+	} `mapstructure:"namespace"`
+
+	// 定时DNS变更调度配置
+	Scheduler struct {
+		Enabled       bool `mapstructure:"enabled"`        // 是否在本节点参与调度（多节点通过etcd选举只有一个执行）
+		CheckInterval int  `mapstructure:"check_interval"` // 检查到期变更的间隔（秒）
+	} `mapstructure:"scheduler"`
+
+	// webhook投递配置
+	Webhook struct {
+		Enabled       bool `mapstructure:"enabled"`        // 是否在本节点参与投递（多节点通过etcd选举只有一个执行）
+		MaxAttempts   int  `mapstructure:"max_attempts"`   // 每个事件的最大投递次数
+		Timeout       int  `mapstructure:"timeout"`        // 单次回调请求的超时时间（秒）
+		RetryInterval int  `mapstructure:"retry_interval"` // 首次重试的间隔（秒），之后每次加倍
+	} `mapstructure:"webhook"`
+
+	// 事件总线导出配置
+	EventBus struct {
+		Enabled bool   `mapstructure:"enabled"` // 是否把服务和DNS记录的变更发布到消息系统（多节点通过etcd选举只有一个发布）
+		Type    string `mapstructure:"type"`    // 消息系统类型："nats" 或 "kafka"
+		Format  string `mapstructure:"format"`  // 消息格式："json" 或 "protobuf"
+		Topic   string `mapstructure:"topic"`   // 主题前缀，服务变更发布到 <topic>.services，DNS记录变更发布到 <topic>.dns
+
+		NATS struct {
+			URL      string `mapstructure:"url"`      // NATS服务器地址，例如 nats://localhost:4222
+			Token    string `mapstructure:"token"`    // 认证令牌
+			Username string `mapstructure:"username"` // 用户名
+			Password string `mapstructure:"password"` // 密码
+		} `mapstructure:"nats"`
+
+		Kafka struct {
+			RestProxy string `mapstructure:"rest_proxy"` // Kafka REST代理地址，例如 http://localhost:8082
+			Username  string `mapstructure:"username"`   // Basic认证用户名
+			Password  string `mapstructure:"password"`   // Basic认证密码
+		} `mapstructure:"kafka"`
+	} `mapstructure:"event_bus"`
+
+	// mDNS通告配置
+	MDNS struct {
+		Enabled         bool   `mapstructure:"enabled"`          // 是否通过多播DNS在本地网段通告已注册的服务
+		Interface       string `mapstructure:"interface"`        // 通告使用的网络接口，为空时由系统选择
+		RefreshInterval int    `mapstructure:"refresh_interval"` // 从etcd刷新服务实例的间隔（秒）
+	} `mapstructure:"mdns"`
+
+	// 多集群联邦配置
+	Federation struct {
+		Enabled     bool             `mapstructure:"enabled"`      // 是否启用联邦，启用后可以解析 <服务>.<集群>.svc.cluster.local（多节点通过etcd选举只有一个同步）
+		ClusterName string           `mapstructure:"cluster_name"` // 本集群名称，推送目录时作为来源集群
+		Interval    int              `mapstructure:"interval"`     // 拉取和推送目录的间隔（秒）
+		Timeout     int              `mapstructure:"timeout"`      // 单次拉取或推送的超时时间（秒）
+		StaleAfter  int              `mapstructure:"stale_after"`  // 远程集群超过多少秒没有成功同步后不再参与DNS应答，0表示不过期
+		Conflict    string           `mapstructure:"conflict"`     // 集群名称与本地命名空间同名时的优先级："local" 或 "remote"
+		Token       string           `mapstructure:"token"`        // 远程集群拉取本集群目录时必须携带的令牌，为空时不校验
+		Peers       []FederationPeer `mapstructure:"peers"`        // 同步其目录的远程集群
+		PushTo      []FederationPeer `mapstructure:"push_to"`      // 主动推送本集群目录的远程集群
+	} `mapstructure:"federation"`
+
+	// 节点自身注册配置
+	SelfRegistration struct {
+		Enabled     bool   `mapstructure:"enabled"`      // 是否把本节点的DNS和API端点注册到系统命名空间，对等节点列表依赖该注册
+		ServiceName string `mapstructure:"service_name"` // 注册使用的服务名称
+		Address     string `mapstructure:"address"`      // 注册的节点地址，为空时自动探测
+		TTL         int    `mapstructure:"ttl"`          // 注册的租约TTL（秒），节点异常退出后经过该时间从列表中消失
+	} `mapstructure:"self_registration"`
+
+	// 实例心跳配置
+	Heartbeat struct {
+		GracePeriod      int `mapstructure:"grace_period"`      // 实例错过心跳后在TTL之外继续保留的时间（秒），超过后才从DNS中移除
+		EvictionCooldown int `mapstructure:"eviction_cooldown"` // 强制驱逐的实例在该时间内不能用相同的实例ID重新注册（秒），0表示不限制
+
+		// 抖动抑制：频繁加入和离开的实例（如崩溃重启中的部署）暂时不参与DNS应答
+		FlapDamping struct {
+			Enabled     bool `mapstructure:"enabled"`       // 是否开启抖动抑制
+			Threshold   int  `mapstructure:"threshold"`     // 统计窗口内同一地址加入超过该次数后开始抑制
+			Window      int  `mapstructure:"window"`        // 统计窗口（秒），距离上次加入超过该时间后重新计数
+			HoldDown    int  `mapstructure:"hold_down"`     // 第一次抑制的时长（秒），之后每多加入一次翻倍
+			MaxHoldDown int  `mapstructure:"max_hold_down"` // 抑制时长的上限（秒），0表示不限制
+		} `mapstructure:"flap_damping"`
+	} `mapstructure:"heartbeat"`
+
+	// 后台协调任务配置
+	Reconciler struct {
+		Enabled   bool `mapstructure:"enabled"`    // 是否在本节点参与协调（多节点通过etcd选举只有一个执行）
+		Interval  int  `mapstructure:"interval"`   // 全量检查的间隔（秒）
+		Jitter    int  `mapstructure:"jitter"`     // 每次全量检查前额外等待的最长随机时间（秒），避免各节点同时执行
+		BatchSize int  `mapstructure:"batch_size"` // 每次全量检查最多修复的数量，其余留到下一次检查，0表示不限制
+		DryRun    bool `mapstructure:"dry_run"`    // 只检查并记录数据不一致，不自动修复
+	} `mapstructure:"reconciler"`
+
+	// 链路追踪配置
+	Tracing struct {
+		Enabled     bool    `mapstructure:"enabled"`      // 是否启用OpenTelemetry链路追踪
+		Endpoint    string  `mapstructure:"endpoint"`     // OTLP gRPC接收端地址
+		Insecure    bool    `mapstructure:"insecure"`     // 是否使用明文连接
+		ServiceName string  `mapstructure:"service_name"` // 上报的服务名
+		SampleRatio float64 `mapstructure:"sample_ratio"` // 采样比例，0到1之间
+	} `mapstructure:"tracing"`
+
+	// 故障注入配置，只用于在预发环境演练降级行为，开启后可以通过管理API模拟etcd延迟、丢失watch事件和上游DNS超时
+	Chaos struct {
+		Enabled bool `mapstructure:"enabled"` // 是否开启故障注入，关闭时不注册故障注入端点，也不包装存储客户端
+	} `mapstructure:"chaos"`
+
 	// 日志配置
 	Log struct {
-		Level       string `mapstructure:"level"`
-		Development bool   `mapstructure:"development"`
+		Level       string            `mapstructure:"level"`       // 全局日志级别
+		Development bool              `mapstructure:"development"` // 开发模式（彩色console编码、Warn级别带堆栈）
+		Encoding    string            `mapstructure:"encoding"`    // 编码格式：json或console，为空时按开发模式选择
+		Components  map[string]string `mapstructure:"components"`  // 组件日志级别（dns、api、etcd、sdk、scheduler、reconciler），未设置的组件沿用全局级别
+
+		// 文件输出，设置path后日志同时写入文件并按大小和时间轮转
+		File struct {
+			Path       string `mapstructure:"path"`        // 日志文件路径
+			MaxSize    int    `mapstructure:"max_size"`    // 单个文件最大大小（MB）
+			MaxAge     int    `mapstructure:"max_age"`     // 旧文件最长保留天数，0表示不按时间清理
+			MaxBackups int    `mapstructure:"max_backups"` // 最多保留的旧文件数，0表示不限制
+			Compress   bool   `mapstructure:"compress"`    // 是否压缩旧文件
+		} `mapstructure:"file"`
 	} `mapstructure:"log"`
 }
 
+// ForwardRule 条件转发规则配置
+type ForwardRule struct {
+	Suffix  string   `mapstructure:"suffix"`  // 域名后缀，例如 corp.example
+	Servers []string `mapstructure:"servers"` // 该后缀使用的上游DNS服务器
+	Mode    string   `mapstructure:"mode"`    // 转发模式："sequential" 或 "race"
+}
+
+// FederationPeer 联邦中的一个远程集群
+type FederationPeer struct {
+	Name  string `mapstructure:"name"`  // 集群名称，用作域名中的集群标签
+	URL   string `mapstructure:"url"`   // 远程集群的管理API地址
+	Mode  string `mapstructure:"mode"`  // peers中的同步方式："pull" 定时拉取，"push" 等待对方推送；push_to中忽略
+	Token string `mapstructure:"token"` // 链路的共享令牌：拉取和推送时携带，接收推送时校验
+}
+
+// Quota 命名空间配额配置，0表示不限制，负数表示禁止创建该类资源
+type Quota struct {
+	MaxServices   int `mapstructure:"max_services"`    // 最大服务数
+	MaxInstances  int `mapstructure:"max_instances"`   // 最大实例数
+	MaxDNSRecords int `mapstructure:"max_dns_records"` // 最大DNS记录数
+}
+
+// MetadataLimits 实例元数据的限制，数量和大小为0表示不限制
+type MetadataLimits struct {
+	MaxKeys      int               `mapstructure:"max_keys"`       // 最多的键数量
+	MaxValueSize int               `mapstructure:"max_value_size"` // 单个值的最大字节数
+	Types        map[string]string `mapstructure:"types"`          // 键的值类型：string、int、uint16、float或bool
+}
+
+// ReservedNames 保留的名称，避免注册的服务遮蔽关键的名称。支持path.Match通配符（如kube-*），不区分大小写
+type ReservedNames struct {
+	ServiceNames []string `mapstructure:"service_names"` // 不允许注册的服务名称
+	Namespaces   []string `mapstructure:"namespaces"`    // 不允许创建或注册服务的命名空间
+}
+
+// ReservedService 返回与服务名称匹配的保留规则，没有匹配时返回空字符串
+func (r *ReservedNames) ReservedService(name string) string {
+	return matchReserved(r.ServiceNames, name)
+}
+
+// ReservedNamespace 返回与命名空间匹配的保留规则，没有匹配时返回空字符串
+func (r *ReservedNames) ReservedNamespace(name string) string {
+	return matchReserved(r.Namespaces, name)
+}
+
+// matchReserved 返回第一个与名称匹配的规则，无效的通配符在配置校验时拒绝，这里视为不匹配
+func matchReserved(patterns []string, name string) string {
+	name = strings.ToLower(name)
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(strings.ToLower(pattern), name); ok {
+			return pattern
+		}
+	}
+	return ""
+}
+
+// View DNS视图配置，来源地址匹配CIDRs的客户端使用该视图的记录
+type View struct {
+	Name  string   `mapstructure:"name"`  // 视图名称
+	CIDRs []string `mapstructure:"cidrs"` // 客户端来源网段
+}
+
+// Listener DNS服务器的一个监听地址
+type Listener struct {
+	Address  string `mapstructure:"address"`  // 监听地址，例如 127.0.0.1 或 ::1
+	Port     int    `mapstructure:"port"`     // 监听端口
+	Protocol string `mapstructure:"protocol"` // "udp"、"tcp" 或 "both"，为空时使用dns.protocol
+	FD       int    `mapstructure:"fd"`       // 继承的已绑定套接字的文件描述符，设置后忽略地址、端口和协议
+}
+
+// TSIGKey DNS查询签名使用的TSIG密钥
+type TSIGKey struct {
+	Name   string `mapstructure:"name"`   // 密钥名称，即客户端的身份
+	Secret string `mapstructure:"secret"` // base64编码的密钥，签名算法由客户端选择（例如hmac-sha256）
+}
+
+// DNSListeners 返回DNS服务器的所有监听地址
+// 未配置dns.listeners时使用listen_address、port和protocol组成的单个监听地址
+func (c *Config) DNSListeners() []Listener {
+	if len(c.DNS.Listeners) == 0 {
+		return []Listener{{Address: c.DNS.ListenAddress, Port: c.DNS.Port, Protocol: c.DNS.Protocol}}
+	}
+	listeners := make([]Listener, len(c.DNS.Listeners))
+	for i, l := range c.DNS.Listeners {
+		if l.Protocol == "" {
+			l.Protocol = c.DNS.Protocol
+		}
+		listeners[i] = l
+	}
+	return listeners
+}
+
 // LoadConfig 从文件和环境变量加载配置
 func LoadConfig(configPath string) (*Config, error) {
+	return LoadConfigWithOverrides(configPath, nil)
+}
+
+// LoadConfigWithOverrides 从文件和环境变量加载配置，再应用命令行中 key=value 形式的覆盖项
+// 优先级从高到低：命令行覆盖项、KD_前缀的环境变量、KONG_DISCOVERY_前缀的环境变量、配置文件、默认值
+func LoadConfigWithOverrides(configPath string, overrides []string) (*Config, error) {
 	v := viper.New()
 
 	// 设置默认值
@@ -85,36 +493,212 @@ func LoadConfig(configPath string) (*Config, error) {
 	// 从环境变量覆盖
 	bindEnvVariables(v)
 
+	// KD_环境变量和命令行覆盖项
+	if err := applyOverrides(v, overrides); err != nil {
+		return nil, err
+	}
+
 	var config Config
 	if err := v.Unmarshal(&config); err != nil {
 		return nil, fmt.Errorf("解析配置错误: %w", err)
 	}
 
+	// 读取敏感配置项引用的密钥
+	if err := config.resolveSecrets(); err != nil {
+		return nil, fmt.Errorf("读取密钥失败: %w", err)
+	}
+
+	// 启动前校验全部配置，错误的端口、枚举值等在这里报告，而不是在各组件启动时才失败
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
 	return &config, nil
 }
 
 // setDefaults 设置配置默认值
 func setDefaults(v *viper.Viper) {
+	// 存储后端默认配置
+	v.SetDefault("storage", StorageEtcd)
+	v.SetDefault("sql.dsn", "")
+	v.SetDefault("sql.poll_interval", 1000)
+	v.SetDefault("sql.retention", 10000)
+
 	// etcd默认配置
 	v.SetDefault("etcd.endpoints", []string{"localhost:2379"})
 	v.SetDefault("etcd.username", "")
 	v.SetDefault("etcd.password", "")
+	v.SetDefault("etcd.max_txn_ops", 128)
+	v.SetDefault("etcd.retry.initial_backoff", 500)
+	v.SetDefault("etcd.retry.max_backoff", 30000)
+	v.SetDefault("etcd.snapshot.path", "")
+	v.SetDefault("etcd.snapshot.interval", 60)
+	v.SetDefault("etcd.snapshot.warm_start", true)
+	v.SetDefault("etcd.auto_sync_interval", 0)
+
+	// 密钥引用默认配置
+	v.SetDefault("secrets.vault.address", "")
+	v.SetDefault("secrets.vault.token", "")
+	v.SetDefault("secrets.vault.timeout", 5)
 
 	// DNS服务默认配置
 	v.SetDefault("dns.listen_address", "0.0.0.0")
 	v.SetDefault("dns.port", 53)
 	v.SetDefault("dns.protocol", "both")
 	v.SetDefault("dns.upstream_dns", "8.8.8.8:53")
+	v.SetDefault("dns.default_ttl", 300)
+	v.SetDefault("dns.service_ttl", 60)
+	v.SetDefault("dns.ttl_policy.min_ttl", 0)
+	v.SetDefault("dns.ttl_policy.max_ttl", 0)
+	v.SetDefault("dns.query_timeout", 5000)
+	v.SetDefault("dns.upstream.servers", []string{})
+	v.SetDefault("dns.upstream.failure_threshold", 3)
+	v.SetDefault("dns.upstream.cooldown", 30)
+	v.SetDefault("dns.upstream.probe_interval", 5)
+	v.SetDefault("dns.upstream.mode", "sequential")
+	v.SetDefault("dns.upstream.race_count", 2)
+	v.SetDefault("dns.upstream.reload_interval", 10)
+	v.SetDefault("dns.upstream.case_randomization", true)
+	v.SetDefault("dns.upstream.protocol", "udp")
+	v.SetDefault("dns.upstream.max_idle_conns", 16)
+	v.SetDefault("dns.upstream.idle_timeout", 30)
+	v.SetDefault("dns.kong_compat.enabled", false)
+	v.SetDefault("dns.kong_compat.max_ttl", 10)
+	v.SetDefault("dns.response.compress", false)
+	v.SetDefault("dns.response.max_answers", 0)
+	v.SetDefault("dns.response.max_srv_records", 0)
+	v.SetDefault("dns.response.selection", SelectionRoundRobin)
+	v.SetDefault("dns.response.minimal_additional", false)
+	v.SetDefault("dns.any_query", false)
+	v.SetDefault("dns.socket_activation", false)
+	v.SetDefault("dns.udp_sockets", 1)
+	v.SetDefault("dns.overload.max_inflight", 10000)
+	v.SetDefault("dns.overload.action", "servfail")
+	v.SetDefault("dns.capture.path", "")
+	v.SetDefault("dns.capture.sample_ratio", 1.0)
+	v.SetDefault("dns.capture.buffer", 4096)
+	v.SetDefault("dns.prefetch.path", "")
+	v.SetDefault("dns.prefetch.top_n", 100)
+	v.SetDefault("dns.prefetch.interval", 60)
+	v.SetDefault("dns.prefetch.timeout", 10)
+	v.SetDefault("dns.precompute.top_n", 0)
+	v.SetDefault("dns.precompute.interval", 10)
+	v.SetDefault("dns.load_weights.enabled", false)
+	v.SetDefault("dns.load_weights.metric", "cpu")
+	v.SetDefault("dns.load_weights.max_load", 1.0)
+	v.SetDefault("dns.load_weights.smoothing", 0.3)
+	v.SetDefault("dns.load_weights.min_ratio", 0.1)
+	v.SetDefault("dns.load_weights.max_age", 60)
 
 	// API服务默认配置
 	v.SetDefault("api.management.listen_address", "0.0.0.0")
 	v.SetDefault("api.management.port", 8080)
+	v.SetDefault("api.management.debug.enabled", false)
+	v.SetDefault("api.management.debug.token", "")
+	v.SetDefault("api.management.debug.dump_dir", "")
+	v.SetDefault("api.management.compression.enabled", true)
+	v.SetDefault("api.management.compression.level", 5)
+	v.SetDefault("api.management.compression.min_length", 1024)
+	v.SetDefault("api.legacy_paths.disabled", false)
+	v.SetDefault("api.legacy_paths.sunset", "")
 	v.SetDefault("api.registration.listen_address", "0.0.0.0")
 	v.SetDefault("api.registration.port", 8081)
+	v.SetDefault("api.registration.duplicate_address", "allow")
+	v.SetDefault("api.registration.min_sdk_version", "")
+	v.SetDefault("api.registration.metadata.max_keys", 64)
+	v.SetDefault("api.registration.metadata.max_value_size", 4096)
+	v.SetDefault("api.registration.metadata.types", map[string]string{})
+	v.SetDefault("api.registration.reserved.service_names", []string{"admin", "localhost", "local", "internal", "arpa", "com", "net", "org"})
+	v.SetDefault("api.registration.reserved.namespaces", []string{"admin", "kube-system", "kube-public", "kube-node-lease"})
+	v.SetDefault("api.consul.enabled", false)
+	v.SetDefault("api.consul.datacenter", "dc1")
+	v.SetDefault("api.consul.default_ttl", 60)
+	v.SetDefault("api.eureka.enabled", false)
+	v.SetDefault("api.eureka.delta_retention", 180)
+
+	// 命名空间默认配置，0表示不限制
+	v.SetDefault("namespace.default_quota.max_services", 0)
+	v.SetDefault("namespace.default_quota.max_instances", 0)
+	v.SetDefault("namespace.default_quota.max_dns_records", 0)
+	v.SetDefault("namespace.default_txt_metadata", []string{})
+
+	// 定时变更调度默认配置
+	v.SetDefault("scheduler.enabled", true)
+	v.SetDefault("scheduler.check_interval", 5)
+
+	// webhook投递默认配置
+	v.SetDefault("webhook.enabled", true)
+	v.SetDefault("webhook.max_attempts", 5)
+	v.SetDefault("webhook.timeout", 10)
+	v.SetDefault("webhook.retry_interval", 2)
+
+	// 事件总线默认配置
+	v.SetDefault("event_bus.enabled", false)
+	v.SetDefault("event_bus.type", "nats")
+	v.SetDefault("event_bus.format", "json")
+	v.SetDefault("event_bus.topic", "kong-discovery")
+	v.SetDefault("event_bus.nats.url", "nats://localhost:4222")
+	v.SetDefault("event_bus.nats.token", "")
+	v.SetDefault("event_bus.nats.username", "")
+	v.SetDefault("event_bus.nats.password", "")
+	v.SetDefault("event_bus.kafka.rest_proxy", "http://localhost:8082")
+	v.SetDefault("event_bus.kafka.username", "")
+	v.SetDefault("event_bus.kafka.password", "")
+
+	// mDNS通告默认配置
+	v.SetDefault("mdns.enabled", false)
+	v.SetDefault("mdns.interface", "")
+	v.SetDefault("mdns.refresh_interval", 10)
+
+	// 节点自身注册默认配置
+	v.SetDefault("self_registration.enabled", true)
+	v.SetDefault("self_registration.service_name", "kong-discovery")
+	v.SetDefault("self_registration.address", "")
+	v.SetDefault("self_registration.ttl", 30)
+
+	// 实例心跳默认配置
+	v.SetDefault("heartbeat.grace_period", 0)
+	v.SetDefault("heartbeat.eviction_cooldown", 300)
+	v.SetDefault("heartbeat.flap_damping.enabled", false)
+	v.SetDefault("heartbeat.flap_damping.threshold", 3)
+	v.SetDefault("heartbeat.flap_damping.window", 300)
+	v.SetDefault("heartbeat.flap_damping.hold_down", 30)
+	v.SetDefault("heartbeat.flap_damping.max_hold_down", 600)
+
+	// 后台协调任务默认配置
+	v.SetDefault("reconciler.enabled", true)
+	v.SetDefault("reconciler.interval", 60)
+	v.SetDefault("reconciler.jitter", 10)
+	v.SetDefault("reconciler.batch_size", 1000)
+	v.SetDefault("reconciler.dry_run", false)
+
+	// 多集群联邦默认配置
+	v.SetDefault("federation.enabled", false)
+	v.SetDefault("federation.cluster_name", "")
+	v.SetDefault("federation.interval", 30)
+	v.SetDefault("federation.timeout", 10)
+	v.SetDefault("federation.stale_after", 300)
+	v.SetDefault("federation.conflict", "local")
+	v.SetDefault("federation.token", "")
+
+	// 链路追踪默认配置
+	v.SetDefault("chaos.enabled", false)
+	v.SetDefault("tracing.enabled", false)
+	v.SetDefault("tracing.endpoint", "localhost:4317")
+	v.SetDefault("tracing.insecure", true)
+	v.SetDefault("tracing.service_name", "kong-discovery")
+	v.SetDefault("tracing.sample_ratio", 1.0)
 
 	// 日志默认配置
 	v.SetDefault("log.level", "info")
 	v.SetDefault("log.development", true)
+	v.SetDefault("log.encoding", "")
+	v.SetDefault("log.components", map[string]string{})
+	v.SetDefault("log.file.path", "")
+	v.SetDefault("log.file.max_size", 100)
+	v.SetDefault("log.file.max_age", 7)
+	v.SetDefault("log.file.max_backups", 10)
+	v.SetDefault("log.file.compress", false)
 }
 
 // bindEnvVariables 绑定特定的环境变量
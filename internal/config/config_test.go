@@ -20,15 +20,19 @@ func TestLoadConfig(t *testing.T) {
 	assert.Equal(t, 8081, config.API.Registration.Port, "注册API端口应为8081")
 	assert.Equal(t, "both", config.DNS.Protocol, "DNS协议应为both")
 	assert.Equal(t, "8.8.8.8:53", config.DNS.UpstreamDNS, "上游DNS应为8.8.8.8:53")
+	assert.Equal(t, StorageEtcd, config.Storage, "默认使用etcd存储")
+	assert.Equal(t, 1000, config.SQL.PollInterval, "SQL存储默认每秒轮询一次")
 }
 
 func TestLoadConfigFromEnvVars(t *testing.T) {
 	// 设置环境变量
 	os.Setenv("KONG_DISCOVERY_DNS_PORT", "5353")
 	os.Setenv("KONG_DISCOVERY_MANAGEMENT_API_PORT", "9090")
+	os.Setenv("KONG_DISCOVERY_STORAGE", StorageMemory)
 	defer func() {
 		os.Unsetenv("KONG_DISCOVERY_DNS_PORT")
 		os.Unsetenv("KONG_DISCOVERY_MANAGEMENT_API_PORT")
+		os.Unsetenv("KONG_DISCOVERY_STORAGE")
 	}()
 
 	// 加载配置
@@ -39,6 +43,7 @@ func TestLoadConfigFromEnvVars(t *testing.T) {
 	// 验证环境变量覆盖
 	assert.Equal(t, 5353, config.DNS.Port, "环境变量应正确覆盖DNS端口")
 	assert.Equal(t, 9090, config.API.Management.Port, "环境变量应正确覆盖管理API端口")
+	assert.Equal(t, StorageMemory, config.Storage, "环境变量应正确覆盖存储后端")
 
 	// 确认其他值不受影响
 	assert.Equal(t, 8081, config.API.Registration.Port, "注册API端口不应被环境变量影响")
@@ -54,3 +59,23 @@ func TestLoadConfigWithMissingFile(t *testing.T) {
 	// 不应该返回配置对象
 	assert.Nil(t, config, "加载不存在的配置文件应该返回nil配置")
 }
+
+func TestDNSListeners(t *testing.T) {
+	cfg := &Config{}
+	cfg.DNS.ListenAddress = "0.0.0.0"
+	cfg.DNS.Port = 53
+	cfg.DNS.Protocol = "both"
+
+	// 未配置listeners时使用单个监听地址
+	assert.Equal(t, []Listener{{Address: "0.0.0.0", Port: 53, Protocol: "both"}}, cfg.DNSListeners())
+
+	// 配置listeners后忽略listen_address和port，未设置协议的监听地址使用dns.protocol
+	cfg.DNS.Listeners = []Listener{
+		{Address: "127.0.0.1", Port: 53},
+		{Address: "10.8.0.1", Port: 5353, Protocol: "udp"},
+	}
+	assert.Equal(t, []Listener{
+		{Address: "127.0.0.1", Port: 53, Protocol: "both"},
+		{Address: "10.8.0.1", Port: 5353, Protocol: "udp"},
+	}, cfg.DNSListeners())
+}
@@ -1,10 +1,49 @@
 package config
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"sync/atomic"
+
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// 可以单独设置日志级别的组件
+const (
+	ComponentDNS        = "dns"        // DNS服务器
+	ComponentAPI        = "api"        // 管理API和服务注册API
+	ComponentEtcd       = "etcd"       // etcd客户端
+	ComponentSDK        = "sdk"        // 服务注册SDK
+	ComponentScheduler  = "scheduler"  // 定时变更调度器
+	ComponentReconciler = "reconciler" // 后台协调任务
+	ComponentMDNS       = "mdns"       // mDNS响应器
+	ComponentWebhook    = "webhook"    // webhook投递器
+	ComponentEventBus   = "eventbus"   // 事件总线导出
+	ComponentFederation = "federation" // 多集群联邦同步
+	ComponentCluster    = "cluster"    // 节点自身注册
+)
+
+// RootComponent 表示全局日志级别，未单独设置级别的组件使用全局级别
+const RootComponent = "root"
+
+// 日志编码格式
+const (
+	EncodingJSON    = "json"
+	EncodingConsole = "console"
 )
 
+// knownComponents 所有可设置级别的组件
+var knownComponents = []string{ComponentDNS, ComponentAPI, ComponentEtcd, ComponentSDK, ComponentScheduler, ComponentReconciler, ComponentMDNS, ComponentWebhook, ComponentEventBus, ComponentFederation, ComponentCluster}
+
+// ErrUnknownComponent 组件名称无效
+var ErrUnknownComponent = errors.New("未知的日志组件")
+
 // Logger 定义日志接口
 type Logger interface {
 	Debug(msg string, fields ...zapcore.Field)
@@ -12,32 +51,298 @@ type Logger interface {
 	Warn(msg string, fields ...zapcore.Field)
 	Error(msg string, fields ...zapcore.Field)
 	Fatal(msg string, fields ...zapcore.Field)
+
+	// Named 返回指定组件的日志记录器，使用该组件的日志级别
+	Named(component string) Logger
+
+	// With 返回附加了字段的日志记录器，沿用当前组件的日志级别
+	With(fields ...zapcore.Field) Logger
+}
+
+// logFieldsKey 上下文中日志关联字段的键
+type logFieldsKey struct{}
+
+// ContextWithLogFields 返回携带日志关联字段（如请求ID、查询ID）的上下文，
+// 处理该请求的各组件通过LoggerFromContext取得的日志记录器都会附加这些字段
+func ContextWithLogFields(ctx context.Context, fields ...zapcore.Field) context.Context {
+	if existing, ok := ctx.Value(logFieldsKey{}).([]zapcore.Field); ok {
+		fields = append(append([]zapcore.Field(nil), existing...), fields...)
+	}
+	return context.WithValue(ctx, logFieldsKey{}, fields)
+}
+
+// LoggerFromContext 返回附加了上下文中关联字段的日志记录器，上下文中没有关联字段时返回logger本身
+func LoggerFromContext(ctx context.Context, logger Logger) Logger {
+	if ctx == nil {
+		return logger
+	}
+	fields, _ := ctx.Value(logFieldsKey{}).([]zapcore.Field)
+	if len(fields) == 0 {
+		return logger
+	}
+	return logger.With(fields...)
+}
+
+// ComponentLevel 组件当前的日志级别
+type ComponentLevel struct {
+	Component string `json:"component"` // 组件名称，root表示全局级别
+	Level     string `json:"level"`     // 生效的级别
+	Inherited bool   `json:"inherited"` // 是否沿用全局级别
+}
+
+// LevelController 支持在运行时查看和调整日志级别
+type LevelController interface {
+	// Levels 返回全局和各组件的日志级别
+	Levels() []ComponentLevel
+
+	// SetLevel 设置组件的日志级别，component为root时设置全局级别；
+	// level为空时组件恢复沿用全局级别
+	SetLevel(component, level string) error
+}
+
+// componentLevel 组件的日志级别，未设置时沿用全局级别
+type componentLevel struct {
+	level zap.AtomicLevel
+	set   atomic.Bool
+}
+
+// levelRegistry 保存全局和各组件的日志级别，所有派生的日志记录器共享
+type levelRegistry struct {
+	root       zap.AtomicLevel
+	mu         sync.RWMutex
+	components map[string]*componentLevel
+}
+
+// newLevelRegistry 创建级别注册表，所有已知组件默认沿用全局级别
+func newLevelRegistry(root zapcore.Level) *levelRegistry {
+	r := &levelRegistry{
+		root:       zap.NewAtomicLevelAt(root),
+		components: make(map[string]*componentLevel, len(knownComponents)),
+	}
+	for _, name := range knownComponents {
+		r.components[name] = &componentLevel{level: zap.NewAtomicLevel()}
+	}
+	return r
+}
+
+// component 返回组件的级别，未知组件返回nil
+func (r *levelRegistry) component(name string) *componentLevel {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.components[name]
 }
 
-// ZapLogger 实现Logger接口
+// enabler 返回组件使用的级别判断函数
+func (r *levelRegistry) enabler(name string) func(zapcore.Level) bool {
+	c := r.component(name)
+	if c == nil {
+		return r.root.Enabled
+	}
+	return func(l zapcore.Level) bool {
+		if c.set.Load() {
+			return c.level.Enabled(l)
+		}
+		return r.root.Enabled(l)
+	}
+}
+
+// setLevel 设置全局或组件的级别
+func (r *levelRegistry) setLevel(component, level string) error {
+	if component == RootComponent || component == "" {
+		lvl, err := zapcore.ParseLevel(level)
+		if err != nil {
+			return fmt.Errorf("无效的日志级别 %q: %w", level, err)
+		}
+		r.root.SetLevel(lvl)
+		return nil
+	}
+
+	c := r.component(component)
+	if c == nil {
+		return fmt.Errorf("%w: %s", ErrUnknownComponent, component)
+	}
+	if level == "" {
+		c.set.Store(false)
+		return nil
+	}
+	lvl, err := zapcore.ParseLevel(level)
+	if err != nil {
+		return fmt.Errorf("无效的日志级别 %q: %w", level, err)
+	}
+	c.level.SetLevel(lvl)
+	c.set.Store(true)
+	return nil
+}
+
+// levels 返回全局和各组件的级别
+func (r *levelRegistry) levels() []ComponentLevel {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	root := r.root.Level().String()
+	result := []ComponentLevel{{Component: RootComponent, Level: root}}
+	names := make([]string, 0, len(r.components))
+	for name := range r.components {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		c := r.components[name]
+		if c.set.Load() {
+			result = append(result, ComponentLevel{Component: name, Level: c.level.Level().String()})
+		} else {
+			result = append(result, ComponentLevel{Component: name, Level: root, Inherited: true})
+		}
+	}
+	return result
+}
+
+// levelCore 按组件级别过滤日志的zapcore.Core
+type levelCore struct {
+	zapcore.Core
+	enabled func(zapcore.Level) bool
+}
+
+// Enabled 实现zapcore.LevelEnabler
+func (c *levelCore) Enabled(l zapcore.Level) bool {
+	return c.enabled(l)
+}
+
+// With 添加字段并保留级别过滤
+func (c *levelCore) With(fields []zapcore.Field) zapcore.Core {
+	return &levelCore{Core: c.Core.With(fields), enabled: c.enabled}
+}
+
+// Check 只有级别满足时才交给底层Core
+func (c *levelCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.enabled(ent.Level) {
+		return ce
+	}
+	return c.Core.Check(ent, ce)
+}
+
+// ZapLogger 实现Logger和LevelController接口
 type ZapLogger struct {
-	logger *zap.Logger
+	logger    *zap.Logger
+	levels    *levelRegistry
+	component string
 }
 
 // NewLogger 创建并返回一个新的Logger实例
+// 开发模式使用console编码和Debug级别，否则使用JSON编码和Info级别
 func NewLogger(isDevelopment bool) (Logger, error) {
-	var config zap.Config
+	cfg := &Config{}
+	cfg.Log.Development = isDevelopment
 	if isDevelopment {
-		config = zap.NewDevelopmentConfig()
-	} else {
-		config = zap.NewProductionConfig()
+		cfg.Log.Level = "debug"
 	}
+	return NewLoggerFromConfig(cfg)
+}
 
-	zapLogger, err := config.Build()
-	if err != nil {
-		return nil, err
+// NewLoggerFromConfig 按日志配置创建Logger，支持编码格式、组件级别和带轮转的文件输出
+func NewLoggerFromConfig(cfg *Config) (Logger, error) {
+	level := zapcore.InfoLevel
+	if cfg.Log.Level != "" {
+		var err error
+		if level, err = zapcore.ParseLevel(cfg.Log.Level); err != nil {
+			return nil, fmt.Errorf("无效的日志级别 %q: %w", cfg.Log.Level, err)
+		}
+	}
+	levels := newLevelRegistry(level)
+	for component, componentLevel := range cfg.Log.Components {
+		if err := levels.setLevel(component, componentLevel); err != nil {
+			return nil, err
+		}
+	}
+
+	encoderConfig := zap.NewProductionEncoderConfig()
+	if cfg.Log.Development {
+		encoderConfig = zap.NewDevelopmentEncoderConfig()
+	}
+	encoding := cfg.Log.Encoding
+	if encoding == "" {
+		encoding = EncodingJSON
+		if cfg.Log.Development {
+			encoding = EncodingConsole
+		}
+	}
+
+	var encoder zapcore.Encoder
+	switch encoding {
+	case EncodingJSON:
+		encoder = zapcore.NewJSONEncoder(encoderConfig)
+	case EncodingConsole:
+		encoder = zapcore.NewConsoleEncoder(encoderConfig)
+	default:
+		return nil, fmt.Errorf("无效的日志编码格式 %q，只支持json和console", encoding)
+	}
+
+	writers := []zapcore.WriteSyncer{zapcore.Lock(os.Stderr)}
+	if file := cfg.Log.File; file.Path != "" {
+		writers = append(writers, zapcore.AddSync(&lumberjack.Logger{
+			Filename:   file.Path,
+			MaxSize:    file.MaxSize,
+			MaxAge:     file.MaxAge,
+			MaxBackups: file.MaxBackups,
+			Compress:   file.Compress,
+		}))
+	}
+
+	// 底层Core记录所有级别，由levelCore按组件过滤
+	core := zapcore.NewCore(encoder, zapcore.NewMultiWriteSyncer(writers...), zapcore.DebugLevel)
+
+	options := []zap.Option{zap.AddCaller(), zap.AddCallerSkip(1)}
+	if cfg.Log.Development {
+		options = append(options, zap.Development(), zap.AddStacktrace(zapcore.WarnLevel))
+	} else {
+		options = append(options, zap.AddStacktrace(zapcore.ErrorLevel))
 	}
 
 	return &ZapLogger{
-		logger: zapLogger,
+		logger: zap.New(&levelCore{Core: core, enabled: levels.enabler(RootComponent)}, options...),
+		levels: levels,
 	}, nil
 }
 
+// Named 返回指定组件的日志记录器
+// 嵌套调用时沿用最外层组件的级别，名称以点号拼接
+func (l *ZapLogger) Named(component string) Logger {
+	owner := l.component
+	if owner == "" {
+		owner = component
+	}
+	enabled := l.levels.enabler(owner)
+	return &ZapLogger{
+		logger: l.logger.Named(component).WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+			if lc, ok := core.(*levelCore); ok {
+				core = lc.Core
+			}
+			return &levelCore{Core: core, enabled: enabled}
+		})),
+		levels:    l.levels,
+		component: owner,
+	}
+}
+
+// With 返回附加了字段的日志记录器
+func (l *ZapLogger) With(fields ...zapcore.Field) Logger {
+	return &ZapLogger{
+		logger:    l.logger.With(fields...),
+		levels:    l.levels,
+		component: l.component,
+	}
+}
+
+// Levels 返回全局和各组件的日志级别
+func (l *ZapLogger) Levels() []ComponentLevel {
+	return l.levels.levels()
+}
+
+// SetLevel 在运行时设置组件的日志级别
+func (l *ZapLogger) SetLevel(component, level string) error {
+	return l.levels.setLevel(component, level)
+}
+
 // Debug 记录Debug级别日志
 func (l *ZapLogger) Debug(msg string, fields ...zapcore.Field) {
 	l.logger.Debug(msg, fields...)
@@ -1,6 +1,11 @@
 package config
 
 import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -37,3 +42,124 @@ func testLoggerMethods(t *testing.T, logger Logger) {
 		// 不测试Fatal，它会调用os.Exit
 	}, "日志方法不应panic")
 }
+
+// readLogLines 读取JSON格式日志文件中每一行的消息
+func readLogLines(t *testing.T, path string) []map[string]interface{} {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	var lines []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		if line == "" {
+			continue
+		}
+		var entry map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(line), &entry), "日志行应为JSON: %s", line)
+		lines = append(lines, entry)
+	}
+	return lines
+}
+
+// logMessages 返回日志条目中的消息列表
+func logMessages(lines []map[string]interface{}) []string {
+	messages := make([]string, 0, len(lines))
+	for _, line := range lines {
+		messages = append(messages, line["msg"].(string))
+	}
+	return messages
+}
+
+func TestNewLoggerFromConfig_ComponentLevels(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kong-discovery.log")
+
+	cfg := &Config{}
+	cfg.Log.Level = "info"
+	cfg.Log.Encoding = EncodingJSON
+	cfg.Log.Components = map[string]string{ComponentDNS: "debug", ComponentEtcd: "error"}
+	cfg.Log.File.Path = path
+	cfg.Log.File.MaxSize = 1
+
+	logger, err := NewLoggerFromConfig(cfg)
+	require.NoError(t, err)
+
+	dnsLogger := logger.Named(ComponentDNS)
+	etcdLogger := logger.Named(ComponentEtcd)
+	apiLogger := logger.Named(ComponentAPI)
+
+	dnsLogger.Debug("dns-debug")
+	etcdLogger.Warn("etcd-warn")
+	etcdLogger.Error("etcd-error")
+	apiLogger.Debug("api-debug")
+	apiLogger.Info("api-info")
+	logger.Debug("root-debug")
+
+	lines := readLogLines(t, path)
+	assert.Equal(t, []string{"dns-debug", "etcd-error", "api-info"}, logMessages(lines))
+	assert.Equal(t, ComponentDNS, lines[0]["logger"])
+
+	// 运行时调整级别
+	controller, ok := logger.(LevelController)
+	require.True(t, ok)
+	require.NoError(t, controller.SetLevel(RootComponent, "debug"))
+	require.NoError(t, controller.SetLevel(ComponentEtcd, ""))
+	apiLogger.Debug("api-debug-2")
+	etcdLogger.Debug("etcd-debug-2")
+
+	lines = readLogLines(t, path)
+	assert.Equal(t, []string{"api-debug-2", "etcd-debug-2"}, logMessages(lines)[3:])
+
+	levels := controller.Levels()
+	assert.Equal(t, ComponentLevel{Component: RootComponent, Level: "debug"}, levels[0])
+	assert.Contains(t, levels, ComponentLevel{Component: ComponentDNS, Level: "debug"})
+	assert.Contains(t, levels, ComponentLevel{Component: ComponentEtcd, Level: "debug", Inherited: true})
+
+	assert.ErrorIs(t, controller.SetLevel("unknown", "debug"), ErrUnknownComponent)
+	assert.Error(t, controller.SetLevel(ComponentDNS, "verbose"))
+}
+
+func TestNewLoggerFromConfig_Invalid(t *testing.T) {
+	cfg := &Config{}
+	cfg.Log.Level = "verbose"
+	_, err := NewLoggerFromConfig(cfg)
+	assert.Error(t, err, "无效的全局级别应返回错误")
+
+	cfg = &Config{}
+	cfg.Log.Encoding = "xml"
+	_, err = NewLoggerFromConfig(cfg)
+	assert.Error(t, err, "无效的编码格式应返回错误")
+
+	cfg = &Config{}
+	cfg.Log.Components = map[string]string{"unknown": "debug"}
+	_, err = NewLoggerFromConfig(cfg)
+	assert.ErrorIs(t, err, ErrUnknownComponent)
+}
+
+func TestLoggerFromContext(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kong-discovery.log")
+
+	cfg := &Config{}
+	cfg.Log.Level = "info"
+	cfg.Log.Encoding = EncodingJSON
+	cfg.Log.File.Path = path
+
+	logger, err := NewLoggerFromConfig(cfg)
+	require.NoError(t, err)
+	apiLogger := logger.Named(ComponentAPI)
+
+	// 没有日志字段时返回原日志记录器
+	assert.Same(t, apiLogger, LoggerFromContext(context.Background(), apiLogger))
+
+	ctx := ContextWithLogFields(context.Background(), zap.String("request_id", "req-1"))
+	ctx = ContextWithLogFields(ctx, zap.String("user", "admin"))
+	LoggerFromContext(ctx, apiLogger).Info("with-fields")
+	apiLogger.Info("without-fields")
+
+	lines := readLogLines(t, path)
+	require.Len(t, lines, 2)
+	assert.Equal(t, "req-1", lines[0]["request_id"])
+	assert.Equal(t, "admin", lines[0]["user"])
+	assert.Equal(t, ComponentAPI, lines[0]["logger"], "附加字段后应保留组件名称")
+	assert.NotContains(t, lines[1], "request_id")
+}
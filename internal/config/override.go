@@ -0,0 +1,85 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// EnvPrefix 覆盖配置项的环境变量前缀，配置项路径转为大写、点换成下划线，例如 KD_DNS_PORT 覆盖 dns.port
+const EnvPrefix = "KD_"
+
+// legacyEnvPrefix 旧的环境变量前缀，仍然支持，同一配置项同时设置时KD_优先
+const legacyEnvPrefix = "KONG_DISCOVERY"
+
+// Overrides 命令行中 --set key=value 形式的配置覆盖项，实现flag.Value，可重复指定
+type Overrides []string
+
+// String 实现flag.Value
+func (o *Overrides) String() string {
+	return strings.Join(*o, ",")
+}
+
+// Set 实现flag.Value，只检查格式，配置项是否存在在加载配置时检查
+func (o *Overrides) Set(value string) error {
+	if key, _, ok := strings.Cut(value, "="); !ok || strings.TrimSpace(key) == "" {
+		return fmt.Errorf("覆盖项必须是 key=value 格式，例如 dns.port=5353，当前为%q", value)
+	}
+	*o = append(*o, value)
+	return nil
+}
+
+// envKey 返回覆盖配置项的环境变量名
+func envKey(key string) string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
+// mapKeys 映射类型的配置项，其下的任意子键都可以覆盖，例如 log.components.dns=debug
+var mapKeys = []string{"log.components", "api.registration.metadata.types"}
+
+// knownKey 判断配置项是否存在
+func knownKey(keys map[string]bool, key string) bool {
+	if keys[key] {
+		return true
+	}
+	for _, prefix := range mapKeys {
+		if strings.HasPrefix(key, prefix+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// applyOverrides 按优先级应用KD_环境变量和命令行覆盖项，命令行覆盖项在后，优先级最高
+// 列表类型的配置项用逗号分隔多个值，例如 KD_ETCD_ENDPOINTS=etcd-0:2379,etcd-1:2379
+func applyOverrides(v *viper.Viper, overrides []string) error {
+	keys := make(map[string]bool)
+	for _, key := range v.AllKeys() {
+		keys[key] = true
+	}
+
+	// 按配置项排序，同一份环境总是得到相同的结果
+	sorted := v.AllKeys()
+	sort.Strings(sorted)
+	for _, key := range sorted {
+		if value, ok := os.LookupEnv(envKey(key)); ok {
+			v.Set(key, value)
+		}
+	}
+
+	for _, override := range overrides {
+		key, value, ok := strings.Cut(override, "=")
+		if !ok {
+			return fmt.Errorf("覆盖项必须是 key=value 格式，当前为%q", override)
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		if !knownKey(keys, key) {
+			return fmt.Errorf("未知的配置项: %s", key)
+		}
+		v.Set(key, value)
+	}
+	return nil
+}
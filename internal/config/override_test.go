@@ -0,0 +1,51 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadConfigWithOverrides(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("storage: memory\ndns:\n  port: 1053\n  query_timeout: 2000\n"), 0o644))
+
+	t.Setenv("KONG_DISCOVERY_DNS_PORT", "2053")
+	t.Setenv("KD_DNS_PORT", "3053")
+	t.Setenv("KD_ETCD_ENDPOINTS", "etcd-0:2379,etcd-1:2379")
+	t.Setenv("KD_HEARTBEAT_FLAP_DAMPING_ENABLED", "true")
+
+	// 环境变量：KD_优先于KONG_DISCOVERY_，都优先于配置文件
+	cfg, err := LoadConfigWithOverrides(path, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 3053, cfg.DNS.Port)
+	assert.Equal(t, 2000, cfg.DNS.QueryTimeout, "未覆盖的配置项使用配置文件的值")
+	assert.Equal(t, []string{"etcd-0:2379", "etcd-1:2379"}, cfg.Etcd.Endpoints, "列表用逗号分隔")
+	assert.True(t, cfg.Heartbeat.FlapDamping.Enabled)
+
+	// 命令行覆盖项优先级最高
+	cfg, err = LoadConfigWithOverrides(path, []string{"dns.port=4053", "DNS.Query_Timeout=100", "log.components.dns=debug"})
+	require.NoError(t, err)
+	assert.Equal(t, 4053, cfg.DNS.Port)
+	assert.Equal(t, 100, cfg.DNS.QueryTimeout, "配置项不区分大小写")
+	assert.Equal(t, "debug", cfg.Log.Components["dns"])
+
+	_, err = LoadConfigWithOverrides(path, []string{"dns.prot=53"})
+	assert.ErrorContains(t, err, "未知的配置项: dns.prot")
+
+	// 覆盖后的配置同样需要通过校验
+	_, err = LoadConfigWithOverrides(path, []string{"dns.port=0"})
+	assert.ErrorContains(t, err, "dns.port")
+}
+
+func TestOverrides_Set(t *testing.T) {
+	var overrides Overrides
+	require.NoError(t, overrides.Set("dns.port=5353"))
+	require.NoError(t, overrides.Set("etcd.endpoints=a:2379,b:2379"))
+	assert.Error(t, overrides.Set("dns.port"))
+	assert.Error(t, overrides.Set("=5353"))
+	assert.Equal(t, Overrides{"dns.port=5353", "etcd.endpoints=a:2379,b:2379"}, overrides)
+}
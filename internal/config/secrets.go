@@ -0,0 +1,210 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// 密钥引用的来源
+const (
+	SecretFile  = "file"  // ${file:/run/secrets/etcd-password} 读取文件内容，去掉末尾的换行
+	SecretEnv   = "env"   // ${env:ETCD_PASSWORD} 读取环境变量
+	SecretVault = "vault" // ${vault:secret/data/kong-discovery#password} 从Vault的KV引擎读取字段
+)
+
+// defaultVaultTimeout 未配置时读取单个Vault密钥的超时时间
+const defaultVaultTimeout = 5 * time.Second
+
+// parseSecretRef 解析 ${来源:引用} 形式的密钥引用，不是引用时返回false
+func parseSecretRef(value string) (source, ref string, ok bool) {
+	if !strings.HasPrefix(value, "${") || !strings.HasSuffix(value, "}") {
+		return "", "", false
+	}
+	source, ref, ok = strings.Cut(value[2:len(value)-1], ":")
+	if !ok {
+		return "", "", false
+	}
+	switch source {
+	case SecretFile, SecretEnv, SecretVault:
+		return source, ref, true
+	}
+	return "", "", false
+}
+
+// secretField 可以使用密钥引用的配置项
+type secretField struct {
+	path  string  // 配置项路径，用于错误信息
+	value *string // 配置项的值，解析后替换为密钥
+}
+
+// secretFields 返回所有敏感配置项
+func (c *Config) secretFields() []secretField {
+	fields := []secretField{
+		{"sql.dsn", &c.SQL.DSN},
+		{"etcd.username", &c.Etcd.Username},
+		{"etcd.password", &c.Etcd.Password},
+		{"event_bus.nats.token", &c.EventBus.NATS.Token},
+		{"event_bus.nats.username", &c.EventBus.NATS.Username},
+		{"event_bus.nats.password", &c.EventBus.NATS.Password},
+		{"event_bus.kafka.username", &c.EventBus.Kafka.Username},
+		{"event_bus.kafka.password", &c.EventBus.Kafka.Password},
+		{"federation.token", &c.Federation.Token},
+		{"api.management.debug.token", &c.API.Management.Debug.Token},
+	}
+	for i := range c.Federation.Peers {
+		fields = append(fields, secretField{fmt.Sprintf("federation.peers[%d].token", i), &c.Federation.Peers[i].Token})
+	}
+	for i := range c.Federation.PushTo {
+		fields = append(fields, secretField{fmt.Sprintf("federation.push_to[%d].token", i), &c.Federation.PushTo[i].Token})
+	}
+	for i := range c.DNS.TSIGKeys {
+		fields = append(fields, secretField{fmt.Sprintf("dns.tsig_keys[%d].secret", i), &c.DNS.TSIGKeys[i].Secret})
+	}
+	return fields
+}
+
+// resolveSecrets 把敏感配置项中的密钥引用替换为实际的密钥，不是引用的值保持不变
+// Vault的令牌本身可以是文件或环境变量引用；同一Vault路径只读取一次
+func (c *Config) resolveSecrets() error {
+	vault := &vaultReader{
+		address: c.Secrets.Vault.Address,
+		token:   c.Secrets.Vault.Token,
+		timeout: time.Duration(c.Secrets.Vault.Timeout) * time.Second,
+		cache:   make(map[string]map[string]interface{}),
+	}
+	if vault.address == "" {
+		vault.address = os.Getenv("VAULT_ADDR")
+	}
+	if vault.token == "" {
+		vault.token = os.Getenv("VAULT_TOKEN")
+	}
+	if source, ref, ok := parseSecretRef(vault.token); ok {
+		if source == SecretVault {
+			return fmt.Errorf("secrets.vault.token: Vault令牌不能引用Vault中的密钥")
+		}
+		token, err := readSecret(source, ref, nil)
+		if err != nil {
+			return fmt.Errorf("secrets.vault.token: %w", err)
+		}
+		vault.token = token
+	}
+
+	for _, field := range c.secretFields() {
+		source, ref, ok := parseSecretRef(*field.value)
+		if !ok {
+			continue
+		}
+		secret, err := readSecret(source, ref, vault)
+		if err != nil {
+			return fmt.Errorf("%s: %w", field.path, err)
+		}
+		*field.value = secret
+	}
+	return nil
+}
+
+// readSecret 从来源读取一个密钥
+func readSecret(source, ref string, vault *vaultReader) (string, error) {
+	switch source {
+	case SecretFile:
+		data, err := os.ReadFile(ref)
+		if err != nil {
+			return "", fmt.Errorf("读取密钥文件失败: %w", err)
+		}
+		// Docker和Kubernetes挂载的密钥文件通常以换行结尾
+		return strings.TrimRight(string(data), "\r\n"), nil
+	case SecretEnv:
+		value, ok := os.LookupEnv(ref)
+		if !ok {
+			return "", fmt.Errorf("环境变量%s未设置", ref)
+		}
+		return value, nil
+	case SecretVault:
+		return vault.read(ref)
+	}
+	return "", fmt.Errorf("不支持的密钥来源: %s", source)
+}
+
+// vaultReader 通过HTTP API读取Vault KV引擎中的密钥
+type vaultReader struct {
+	address string
+	token   string
+	timeout time.Duration
+	cache   map[string]map[string]interface{} // 按路径缓存已读取的字段
+}
+
+// read 读取 路径#字段 形式引用的密钥，同时支持KV v1和KV v2（路径包含data/）
+func (r *vaultReader) read(ref string) (string, error) {
+	path, field, ok := strings.Cut(ref, "#")
+	if !ok || path == "" || field == "" {
+		return "", fmt.Errorf("Vault引用必须是 路径#字段 格式，例如 secret/data/kong-discovery#password，当前为%q", ref)
+	}
+	if r.address == "" {
+		return "", fmt.Errorf("未配置Vault地址（secrets.vault.address或环境变量VAULT_ADDR）")
+	}
+
+	data, ok := r.cache[path]
+	if !ok {
+		var err error
+		if data, err = r.fetch(path); err != nil {
+			return "", err
+		}
+		r.cache[path] = data
+	}
+
+	value, ok := data[field]
+	if !ok {
+		return "", fmt.Errorf("Vault密钥%s中没有字段%s", path, field)
+	}
+	s, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("Vault密钥%s的字段%s不是字符串", path, field)
+	}
+	return s, nil
+}
+
+// fetch 读取路径下的全部字段
+func (r *vaultReader) fetch(path string) (map[string]interface{}, error) {
+	timeout := r.timeout
+	if timeout <= 0 {
+		timeout = defaultVaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	url := strings.TrimRight(r.address, "/") + "/v1/" + strings.TrimLeft(path, "/")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("创建Vault请求失败: %w", err)
+	}
+	req.Header.Set("X-Vault-Token", r.token)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("读取Vault密钥%s失败: %w", path, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("读取Vault密钥%s失败: HTTP %d", path, resp.StatusCode)
+	}
+
+	var body struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("解析Vault响应失败: %w", err)
+	}
+
+	// KV v2的字段在data.data中，同时带有data.metadata
+	if inner, ok := body.Data["data"].(map[string]interface{}); ok {
+		if _, hasMetadata := body.Data["metadata"]; hasMetadata {
+			return inner, nil
+		}
+	}
+	return body.Data, nil
+}
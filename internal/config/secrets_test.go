@@ -0,0 +1,96 @@
+package config
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSecretRef(t *testing.T) {
+	source, ref, ok := parseSecretRef("${file:/run/secrets/etcd-password}")
+	assert.True(t, ok)
+	assert.Equal(t, SecretFile, source)
+	assert.Equal(t, "/run/secrets/etcd-password", ref)
+
+	source, ref, ok = parseSecretRef("${vault:secret/data/kd#password}")
+	assert.True(t, ok)
+	assert.Equal(t, SecretVault, source)
+	assert.Equal(t, "secret/data/kd#password", ref)
+
+	// 普通的值（包括看起来相似的明文密码）不是引用
+	for _, value := range []string{"", "password", "file:/run/secrets/x", "${unknown:x}", "${file}", "p${env:X}"} {
+		_, _, ok := parseSecretRef(value)
+		assert.False(t, ok, value)
+	}
+}
+
+func TestConfig_ResolveSecrets(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "etcd-password"), []byte("s3cret\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "vault-token"), []byte("root-token\n"), 0o600))
+	t.Setenv("KD_TEST_ETCD_USER", "kong")
+
+	var requests int
+	vault := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("X-Vault-Token") != "root-token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/secret/data/kd":
+			w.Write([]byte(`{"data":{"data":{"token":"fed-token","tsig":"dHNpZw=="},"metadata":{"version":3}}}`))
+		case "/v1/kv/kd":
+			w.Write([]byte(`{"data":{"dsn":"postgres://kd@db/kd"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer vault.Close()
+
+	cfg := &Config{}
+	cfg.Secrets.Vault.Address = vault.URL
+	cfg.Secrets.Vault.Token = "${file:" + filepath.Join(dir, "vault-token") + "}"
+	cfg.Etcd.Username = "${env:KD_TEST_ETCD_USER}"
+	cfg.Etcd.Password = "${file:" + filepath.Join(dir, "etcd-password") + "}"
+	cfg.SQL.DSN = "${vault:kv/kd#dsn}"
+	cfg.Federation.Token = "${vault:secret/data/kd#token}"
+	cfg.DNS.TSIGKeys = []TSIGKey{{Name: "kdctl.", Secret: "${vault:secret/data/kd#tsig}"}}
+	cfg.EventBus.NATS.Password = "plain"
+
+	require.NoError(t, cfg.resolveSecrets())
+	assert.Equal(t, "kong", cfg.Etcd.Username)
+	assert.Equal(t, "s3cret", cfg.Etcd.Password, "去掉文件末尾的换行")
+	assert.Equal(t, "postgres://kd@db/kd", cfg.SQL.DSN, "KV v1")
+	assert.Equal(t, "fed-token", cfg.Federation.Token, "KV v2")
+	assert.Equal(t, "dHNpZw==", cfg.DNS.TSIGKeys[0].Secret)
+	assert.Equal(t, "plain", cfg.EventBus.NATS.Password, "不是引用的值保持不变")
+	assert.Equal(t, 2, requests, "同一Vault路径只读取一次")
+
+	// 读取失败时报告配置项路径
+	cfg = &Config{}
+	cfg.Etcd.Password = "${file:" + filepath.Join(dir, "missing") + "}"
+	assert.ErrorContains(t, cfg.resolveSecrets(), "etcd.password: 读取密钥文件失败")
+
+	cfg = &Config{}
+	cfg.Etcd.Password = "${env:KD_TEST_UNSET}"
+	assert.ErrorContains(t, cfg.resolveSecrets(), "环境变量KD_TEST_UNSET未设置")
+
+	cfg = &Config{}
+	cfg.Secrets.Vault.Address = vault.URL
+	cfg.Secrets.Vault.Token = "root-token"
+	cfg.Federation.Token = "${vault:secret/data/kd#missing}"
+	assert.ErrorContains(t, cfg.resolveSecrets(), "federation.token: Vault密钥secret/data/kd中没有字段missing")
+
+	cfg.Federation.Token = "${vault:secret/data/kd}"
+	assert.ErrorContains(t, cfg.resolveSecrets(), "路径#字段")
+
+	cfg.Secrets.Vault.Token = "wrong"
+	cfg.Federation.Token = "${vault:secret/data/kd#token}"
+	assert.ErrorContains(t, cfg.resolveSecrets(), "HTTP 403")
+}
@@ -0,0 +1,428 @@
+package config
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/url"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// FieldError 单个配置项的校验错误
+type FieldError struct {
+	Field   string // 配置项路径，例如 dns.listeners[1].port
+	Message string // 期望的取值和实际的值
+}
+
+// Error 实现error接口
+func (e FieldError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+// ValidationError 配置校验发现的所有错误，按配置项在配置文件中的顺序排列
+type ValidationError []FieldError
+
+// Error 实现error接口，每个错误占一行
+func (e ValidationError) Error() string {
+	lines := make([]string, len(e))
+	for i, fe := range e {
+		lines[i] = fe.Error()
+	}
+	return fmt.Sprintf("配置校验失败（%d处错误）:\n  %s", len(e), strings.Join(lines, "\n  "))
+}
+
+// validator 收集校验错误，不在第一个错误处停止
+type validator struct {
+	errs ValidationError
+}
+
+// add 记录一个错误
+func (v *validator) add(field, format string, args ...interface{}) {
+	v.errs = append(v.errs, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// port 校验端口在1到65535之间
+func (v *validator) port(field string, port int) {
+	if port < 1 || port > 65535 {
+		v.add(field, "端口必须在1到65535之间，当前为%d", port)
+	}
+}
+
+// nonNegative 校验数值不为负数
+func (v *validator) nonNegative(field string, n int) {
+	if n < 0 {
+		v.add(field, "不能为负数，当前为%d", n)
+	}
+}
+
+// positive 校验数值大于0
+func (v *validator) positive(field string, n int) {
+	if n <= 0 {
+		v.add(field, "必须大于0，当前为%d", n)
+	}
+}
+
+// ratio 校验比例在0到1之间
+func (v *validator) ratio(field string, r float64) {
+	if r < 0 || r > 1 {
+		v.add(field, "必须在0到1之间，当前为%g", r)
+	}
+}
+
+// oneOf 校验取值属于allowed之一
+func (v *validator) oneOf(field, value string, allowed ...string) {
+	if !slices.Contains(allowed, value) {
+		v.add(field, "必须是%s之一，当前为%q", strings.Join(allowed, "、"), value)
+	}
+}
+
+// required 校验字符串不为空
+func (v *validator) required(field, value string) {
+	if strings.TrimSpace(value) == "" {
+		v.add(field, "不能为空")
+	}
+}
+
+// hostPort 校验地址为 主机:端口 格式
+func (v *validator) hostPort(field, addr string) {
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		v.add(field, "必须是 主机:端口 格式，当前为%q", addr)
+		return
+	}
+	if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+		v.add(field, "端口必须在1到65535之间，当前为%q", port)
+	}
+}
+
+// reservedPattern 校验保留名称的通配符
+func (v *validator) reservedPattern(field, pattern string) {
+	if strings.TrimSpace(pattern) == "" {
+		v.add(field, "不能为空")
+		return
+	}
+	if _, err := path.Match(pattern, ""); err != nil {
+		v.add(field, "不是有效的通配符: %q", pattern)
+	}
+}
+
+// httpURL 校验地址为http或https的URL
+func (v *validator) httpURL(field, raw string) {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		v.add(field, "必须是http或https地址，当前为%q", raw)
+	}
+}
+
+// Validate 校验配置，返回的ValidationError包含所有错误的配置项路径和期望的取值
+// 值为0时各组件使用内置默认值的配置项只校验不为负数
+func (c *Config) Validate() error {
+	v := &validator{}
+	c.validateStorage(v)
+	c.validateDNS(v)
+	c.validateAPI(v)
+	c.validateBackground(v)
+	c.validateObservability(v)
+	if len(v.errs) > 0 {
+		return v.errs
+	}
+	return nil
+}
+
+// validateStorage 校验存储后端配置
+func (c *Config) validateStorage(v *validator) {
+	v.oneOf("storage", c.Storage, StorageEtcd, StorageMemory, StorageSQLite, StoragePostgres)
+	switch c.Storage {
+	case StorageSQLite, StoragePostgres:
+		v.required("sql.dsn", c.SQL.DSN)
+	case StorageEtcd:
+		if len(c.Etcd.Endpoints) == 0 {
+			v.add("etcd.endpoints", "storage为etcd时至少需要一个地址")
+		}
+		for i, endpoint := range c.Etcd.Endpoints {
+			v.required(fmt.Sprintf("etcd.endpoints[%d]", i), endpoint)
+		}
+	}
+	v.nonNegative("sql.poll_interval", c.SQL.PollInterval)
+	v.nonNegative("sql.retention", c.SQL.Retention)
+	v.nonNegative("etcd.max_txn_ops", c.Etcd.MaxTxnOps)
+	v.nonNegative("etcd.auto_sync_interval", c.Etcd.AutoSyncInterval)
+	v.nonNegative("etcd.retry.initial_backoff", c.Etcd.Retry.InitialBackoff)
+	v.nonNegative("etcd.retry.max_backoff", c.Etcd.Retry.MaxBackoff)
+	if c.Etcd.Retry.MaxBackoff > 0 && c.Etcd.Retry.MaxBackoff < c.Etcd.Retry.InitialBackoff {
+		v.add("etcd.retry.max_backoff", "不能小于initial_backoff（%d），当前为%d", c.Etcd.Retry.InitialBackoff, c.Etcd.Retry.MaxBackoff)
+	}
+	v.nonNegative("etcd.snapshot.interval", c.Etcd.Snapshot.Interval)
+	v.nonNegative("secrets.vault.timeout", c.Secrets.Vault.Timeout)
+}
+
+// validateDNS 校验DNS服务配置
+func (c *Config) validateDNS(v *validator) {
+	dns := &c.DNS
+	v.oneOf("dns.protocol", dns.Protocol, "udp", "tcp", "both")
+	// 套接字激活或配置了listeners时不使用listen_address和port
+	if !dns.SocketActivation && len(dns.Listeners) == 0 {
+		v.port("dns.port", dns.Port)
+	}
+	for i, l := range dns.Listeners {
+		field := fmt.Sprintf("dns.listeners[%d]", i)
+		if l.FD > 0 {
+			continue
+		}
+		v.nonNegative(field+".fd", l.FD)
+		v.port(field+".port", l.Port)
+		if l.Protocol != "" {
+			v.oneOf(field+".protocol", l.Protocol, "udp", "tcp", "both")
+		}
+	}
+	v.nonNegative("dns.udp_sockets", dns.UDPSockets)
+	v.nonNegative("dns.response.max_answers", dns.Response.MaxAnswers)
+	v.nonNegative("dns.response.max_srv_records", dns.Response.MaxSRVRecords)
+	if dns.Response.Selection != "" {
+		v.oneOf("dns.response.selection", dns.Response.Selection, SelectionRoundRobin, SelectionRandom, SelectionSticky)
+	}
+
+	if dns.UpstreamDNS != "" {
+		v.hostPort("dns.upstream_dns", dns.UpstreamDNS)
+	}
+	for i, server := range dns.Upstream.Servers {
+		v.hostPort(fmt.Sprintf("dns.upstream.servers[%d]", i), server)
+	}
+	v.nonNegative("dns.default_ttl", dns.DefaultTTL)
+	v.nonNegative("dns.service_ttl", dns.ServiceTTL)
+	v.nonNegative("dns.ttl_policy.min_ttl", dns.TTLPolicy.MinTTL)
+	v.nonNegative("dns.ttl_policy.max_ttl", dns.TTLPolicy.MaxTTL)
+	if dns.TTLPolicy.MaxTTL > 0 && dns.TTLPolicy.MinTTL > dns.TTLPolicy.MaxTTL {
+		v.add("dns.ttl_policy.min_ttl", "不能大于dns.ttl_policy.max_ttl(%d)，当前为%d", dns.TTLPolicy.MaxTTL, dns.TTLPolicy.MinTTL)
+	}
+	v.nonNegative("dns.query_timeout", dns.QueryTimeout)
+	v.nonNegative("dns.upstream.failure_threshold", dns.Upstream.FailureThreshold)
+	v.nonNegative("dns.upstream.cooldown", dns.Upstream.Cooldown)
+	v.nonNegative("dns.upstream.probe_interval", dns.Upstream.ProbeInterval)
+	v.oneOf("dns.upstream.mode", dns.Upstream.Mode, "sequential", "race")
+	if dns.Upstream.Mode == "race" && dns.Upstream.RaceCount < 2 {
+		v.add("dns.upstream.race_count", "race模式下至少同时查询2个上游，当前为%d", dns.Upstream.RaceCount)
+	}
+	v.nonNegative("dns.upstream.reload_interval", dns.Upstream.ReloadInterval)
+	v.oneOf("dns.upstream.protocol", dns.Upstream.Protocol, "udp", "tcp")
+	v.nonNegative("dns.upstream.max_idle_conns", dns.Upstream.MaxIdleConns)
+	v.nonNegative("dns.upstream.idle_timeout", dns.Upstream.IdleTimeout)
+
+	for i, rule := range dns.ForwardRules {
+		field := fmt.Sprintf("dns.forward_rules[%d]", i)
+		v.required(field+".suffix", rule.Suffix)
+		if len(rule.Servers) == 0 {
+			v.add(field+".servers", "至少需要一个上游DNS服务器")
+		}
+		for j, server := range rule.Servers {
+			v.hostPort(fmt.Sprintf("%s.servers[%d]", field, j), server)
+		}
+		if rule.Mode != "" {
+			v.oneOf(field+".mode", rule.Mode, "sequential", "race")
+		}
+	}
+
+	for i, view := range dns.Views {
+		field := fmt.Sprintf("dns.views[%d]", i)
+		v.required(field+".name", view.Name)
+		for j, cidr := range view.CIDRs {
+			if _, _, err := net.ParseCIDR(cidr); err != nil {
+				v.add(fmt.Sprintf("%s.cidrs[%d]", field, j), "必须是CIDR格式的网段，如10.0.0.0/8，当前为%q", cidr)
+			}
+		}
+	}
+
+	for i, key := range dns.TSIGKeys {
+		field := fmt.Sprintf("dns.tsig_keys[%d]", i)
+		v.required(field+".name", key.Name)
+		if _, err := base64.StdEncoding.DecodeString(key.Secret); err != nil || key.Secret == "" {
+			v.add(field+".secret", "必须是base64编码的密钥")
+		}
+	}
+
+	v.nonNegative("dns.kong_compat.max_ttl", dns.KongCompat.MaxTTL)
+	v.nonNegative("dns.overload.max_inflight", dns.Overload.MaxInflight)
+	v.oneOf("dns.overload.action", dns.Overload.Action, "servfail", "drop")
+	v.ratio("dns.capture.sample_ratio", dns.Capture.SampleRatio)
+	v.nonNegative("dns.capture.buffer", dns.Capture.Buffer)
+	v.nonNegative("dns.prefetch.top_n", dns.Prefetch.TopN)
+	v.nonNegative("dns.prefetch.interval", dns.Prefetch.Interval)
+	v.nonNegative("dns.prefetch.timeout", dns.Prefetch.Timeout)
+	v.nonNegative("dns.precompute.top_n", dns.Precompute.TopN)
+	v.nonNegative("dns.precompute.interval", dns.Precompute.Interval)
+	if lw := dns.LoadWeights; lw.Enabled {
+		v.required("dns.load_weights.metric", lw.Metric)
+		if lw.MaxLoad <= 0 {
+			v.add("dns.load_weights.max_load", "必须大于0，当前为%v", lw.MaxLoad)
+		}
+		if lw.Smoothing <= 0 || lw.Smoothing > 1 {
+			v.add("dns.load_weights.smoothing", "必须大于0且不超过1，当前为%v", lw.Smoothing)
+		}
+		v.ratio("dns.load_weights.min_ratio", lw.MinRatio)
+		v.positive("dns.load_weights.max_age", lw.MaxAge)
+	}
+}
+
+// validateAPI 校验HTTP API配置
+func (c *Config) validateAPI(v *validator) {
+	api := &c.API
+	if sunset := api.LegacyPaths.Sunset; sunset != "" {
+		if _, err := time.Parse("2006-01-02", sunset); err != nil {
+			if _, err := time.Parse(time.RFC3339, sunset); err != nil {
+				v.add("api.legacy_paths.sunset", "必须是2006-01-02或RFC3339格式的日期，当前为%q", sunset)
+			}
+		}
+	}
+	v.port("api.management.port", api.Management.Port)
+	if api.Management.Debug.Enabled && api.Management.Debug.Token == "" {
+		v.add("api.management.debug.token", "开启调试端点时必须设置令牌")
+	}
+	if api.Management.Compression.Enabled && (api.Management.Compression.Level < 1 || api.Management.Compression.Level > 9) {
+		v.add("api.management.compression.level", "必须在1到9之间，当前为%d", api.Management.Compression.Level)
+	}
+	v.nonNegative("api.management.compression.min_length", api.Management.Compression.MinLength)
+	v.port("api.registration.port", api.Registration.Port)
+	if api.Management.Port == api.Registration.Port && api.Management.ListenAddress == api.Registration.ListenAddress {
+		v.add("api.registration.port", "不能与api.management.port使用相同的地址和端口（%d）", api.Registration.Port)
+	}
+	if api.Registration.DuplicateAddress != "" {
+		v.oneOf("api.registration.duplicate_address", api.Registration.DuplicateAddress, "allow", "replace", "reject")
+	}
+	v.nonNegative("api.registration.metadata.max_keys", api.Registration.Metadata.MaxKeys)
+	v.nonNegative("api.registration.metadata.max_value_size", api.Registration.Metadata.MaxValueSize)
+	for _, key := range sortedKeys(api.Registration.Metadata.Types) {
+		v.oneOf("api.registration.metadata.types."+key, api.Registration.Metadata.Types[key], "string", "int", "uint16", "float", "bool")
+	}
+	reserved := api.Registration.Reserved
+	for i, pattern := range reserved.ServiceNames {
+		v.reservedPattern(fmt.Sprintf("api.registration.reserved.service_names[%d]", i), pattern)
+	}
+	for i, pattern := range reserved.Namespaces {
+		v.reservedPattern(fmt.Sprintf("api.registration.reserved.namespaces[%d]", i), pattern)
+	}
+	if api.Consul.Enabled {
+		v.positive("api.consul.default_ttl", api.Consul.DefaultTTL)
+	}
+	v.nonNegative("api.eureka.delta_retention", api.Eureka.DeltaRetention)
+}
+
+// validateBackground 校验命名空间、实例和后台任务配置
+func (c *Config) validateBackground(v *validator) {
+	v.nonNegative("scheduler.check_interval", c.Scheduler.CheckInterval)
+	if c.Webhook.Enabled {
+		v.positive("webhook.max_attempts", c.Webhook.MaxAttempts)
+	}
+	v.nonNegative("webhook.timeout", c.Webhook.Timeout)
+	v.nonNegative("webhook.retry_interval", c.Webhook.RetryInterval)
+
+	if c.EventBus.Enabled {
+		v.oneOf("event_bus.type", c.EventBus.Type, "nats", "kafka")
+		v.oneOf("event_bus.format", c.EventBus.Format, "json", "protobuf")
+		v.required("event_bus.topic", c.EventBus.Topic)
+		switch c.EventBus.Type {
+		case "nats":
+			v.required("event_bus.nats.url", c.EventBus.NATS.URL)
+		case "kafka":
+			v.httpURL("event_bus.kafka.rest_proxy", c.EventBus.Kafka.RestProxy)
+		}
+	}
+
+	v.nonNegative("mdns.refresh_interval", c.MDNS.RefreshInterval)
+
+	if c.Federation.Enabled {
+		v.required("federation.cluster_name", c.Federation.ClusterName)
+	}
+	if c.Federation.Conflict != "" {
+		v.oneOf("federation.conflict", c.Federation.Conflict, "local", "remote")
+	}
+	v.nonNegative("federation.interval", c.Federation.Interval)
+	v.nonNegative("federation.timeout", c.Federation.Timeout)
+	v.nonNegative("federation.stale_after", c.Federation.StaleAfter)
+	for i, peer := range c.Federation.Peers {
+		field := fmt.Sprintf("federation.peers[%d]", i)
+		v.required(field+".name", peer.Name)
+		// 未设置同步方式时为拉取
+		switch peer.Mode {
+		case "", "pull":
+			v.httpURL(field+".url", peer.URL)
+		default:
+			v.oneOf(field+".mode", peer.Mode, "pull", "push")
+		}
+	}
+	for i, peer := range c.Federation.PushTo {
+		field := fmt.Sprintf("federation.push_to[%d]", i)
+		v.required(field+".name", peer.Name)
+		v.httpURL(field+".url", peer.URL)
+	}
+
+	v.nonNegative("self_registration.ttl", c.SelfRegistration.TTL)
+	if c.SelfRegistration.Enabled {
+		if pattern := c.API.Registration.Reserved.ReservedService(c.SelfRegistration.ServiceName); pattern != "" {
+			v.add("self_registration.service_name", "与保留的服务名称%q冲突", pattern)
+		}
+	}
+
+	v.nonNegative("heartbeat.grace_period", c.Heartbeat.GracePeriod)
+	v.nonNegative("heartbeat.eviction_cooldown", c.Heartbeat.EvictionCooldown)
+	damping := c.Heartbeat.FlapDamping
+	if damping.Enabled {
+		v.positive("heartbeat.flap_damping.threshold", damping.Threshold)
+		v.positive("heartbeat.flap_damping.hold_down", damping.HoldDown)
+	}
+	v.nonNegative("heartbeat.flap_damping.window", damping.Window)
+	v.nonNegative("heartbeat.flap_damping.max_hold_down", damping.MaxHoldDown)
+	if damping.MaxHoldDown > 0 && damping.MaxHoldDown < damping.HoldDown {
+		v.add("heartbeat.flap_damping.max_hold_down", "不能小于hold_down（%d），当前为%d", damping.HoldDown, damping.MaxHoldDown)
+	}
+
+	v.nonNegative("reconciler.interval", c.Reconciler.Interval)
+	v.nonNegative("reconciler.jitter", c.Reconciler.Jitter)
+	v.nonNegative("reconciler.batch_size", c.Reconciler.BatchSize)
+}
+
+// validateObservability 校验链路追踪和日志配置
+func (c *Config) validateObservability(v *validator) {
+	if c.Tracing.Enabled {
+		v.required("tracing.endpoint", c.Tracing.Endpoint)
+	}
+	v.ratio("tracing.sample_ratio", c.Tracing.SampleRatio)
+
+	if c.Log.Level != "" {
+		if _, err := zapcore.ParseLevel(c.Log.Level); err != nil {
+			v.add("log.level", "必须是debug、info、warn、error、dpanic、panic或fatal之一，当前为%q", c.Log.Level)
+		}
+	}
+	if c.Log.Encoding != "" {
+		v.oneOf("log.encoding", c.Log.Encoding, EncodingJSON, EncodingConsole)
+	}
+	for _, component := range sortedKeys(c.Log.Components) {
+		level := c.Log.Components[component]
+		field := "log.components." + component
+		if !slices.Contains(knownComponents, component) {
+			v.add(field, "未知的组件，必须是%s之一", strings.Join(knownComponents, "、"))
+			continue
+		}
+		if _, err := zapcore.ParseLevel(level); level != "" && err != nil {
+			v.add(field, "无效的日志级别%q", level)
+		}
+	}
+	v.nonNegative("log.file.max_size", c.Log.File.MaxSize)
+	v.nonNegative("log.file.max_age", c.Log.File.MaxAge)
+	v.nonNegative("log.file.max_backups", c.Log.File.MaxBackups)
+}
+
+// sortedKeys 返回排序后的键，使同一配置的错误总是以相同的顺序输出
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
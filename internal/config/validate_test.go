@@ -0,0 +1,130 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// validConfig 返回一份通过校验的最小配置
+func validConfig() *Config {
+	cfg := &Config{Storage: StorageMemory}
+	cfg.DNS.Port = 53
+	cfg.DNS.Protocol = "both"
+	cfg.DNS.Upstream.Mode = "sequential"
+	cfg.DNS.Upstream.Protocol = "udp"
+	cfg.DNS.Overload.Action = "servfail"
+	cfg.API.Management.Port = 8080
+	cfg.API.Registration.Port = 8081
+	return cfg
+}
+
+func TestValidate(t *testing.T) {
+	require.NoError(t, validConfig().Validate())
+
+	cfg := validConfig()
+	cfg.Storage = StorageSQLite
+	cfg.DNS.Port = 0
+	cfg.DNS.UpstreamDNS = "8.8.8.8"
+	cfg.DNS.Upstream.Mode = "race"
+	cfg.DNS.TTLPolicy.MinTTL = 600
+	cfg.DNS.TTLPolicy.MaxTTL = 60
+	cfg.DNS.Views = []View{{Name: "office", CIDRs: []string{"10.0.0.0/8", "10.1.2.3"}}}
+	cfg.API.Registration.Metadata.Types = map[string]string{"canary": "boolean"}
+	cfg.Heartbeat.FlapDamping.Enabled = true
+	cfg.Log.Components = map[string]string{"dns": "verbose", "disk": "info"}
+
+	err := cfg.Validate()
+	var invalid ValidationError
+	require.True(t, errors.As(err, &invalid))
+
+	fields := make([]string, len(invalid))
+	for i, fe := range invalid {
+		fields[i] = fe.Field
+	}
+	assert.Equal(t, []string{
+		"sql.dsn",
+		"dns.port",
+		"dns.upstream_dns",
+		"dns.ttl_policy.min_ttl",
+		"dns.upstream.race_count",
+		"dns.views[0].cidrs[1]",
+		"api.registration.metadata.types.canary",
+		"heartbeat.flap_damping.threshold",
+		"heartbeat.flap_damping.hold_down",
+		"log.components.disk",
+		"log.components.dns",
+	}, fields)
+	assert.Contains(t, err.Error(), "dns.port: 端口必须在1到65535之间，当前为0")
+	assert.Contains(t, err.Error(), "11处错误")
+}
+
+func TestValidate_Listeners(t *testing.T) {
+	// 配置了listeners时不校验dns.port
+	cfg := validConfig()
+	cfg.DNS.Port = 0
+	cfg.DNS.Listeners = []Listener{{Address: "127.0.0.1", Port: 5353}, {FD: 3}, {Address: "::1", Port: 70000, Protocol: "sctp"}}
+
+	err := cfg.Validate()
+	var invalid ValidationError
+	require.True(t, errors.As(err, &invalid))
+	require.Len(t, invalid, 2)
+	assert.Equal(t, "dns.listeners[2].port", invalid[0].Field)
+	assert.Equal(t, "dns.listeners[2].protocol", invalid[1].Field)
+}
+
+func TestValidate_RaceCount(t *testing.T) {
+	cfg := validConfig()
+	cfg.DNS.Upstream.Mode = "race"
+	cfg.DNS.Upstream.RaceCount = 1
+
+	err := cfg.Validate()
+	var invalid ValidationError
+	require.True(t, errors.As(err, &invalid))
+	require.Len(t, invalid, 1)
+	assert.Equal(t, "dns.upstream.race_count", invalid[0].Field)
+
+	cfg.DNS.Upstream.RaceCount = 2
+	assert.NoError(t, cfg.Validate())
+}
+
+func TestLoadConfig_Invalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("dns:\n  port: 0\napi:\n  management:\n    port: 8081\n"), 0o644))
+
+	_, err := LoadConfig(path)
+	var invalid ValidationError
+	require.True(t, errors.As(err, &invalid), "加载时校验配置")
+	assert.Equal(t, "dns.port", invalid[0].Field)
+	assert.Equal(t, "api.registration.port", invalid[1].Field, "两个API不能使用相同的端口")
+}
+
+func TestValidate_ReservedNames(t *testing.T) {
+	cfg := validConfig()
+	cfg.API.Registration.Reserved = ReservedNames{ServiceNames: []string{"kong-*", "[a-"}, Namespaces: []string{""}}
+	cfg.SelfRegistration.Enabled = true
+	cfg.SelfRegistration.ServiceName = "kong-discovery"
+
+	err := cfg.Validate()
+	var invalid ValidationError
+	require.True(t, errors.As(err, &invalid))
+	fields := make([]string, len(invalid))
+	for i, fe := range invalid {
+		fields[i] = fe.Field
+	}
+	assert.Equal(t, []string{
+		"api.registration.reserved.service_names[1]",
+		"api.registration.reserved.namespaces[0]",
+		"self_registration.service_name",
+	}, fields)
+
+	reserved := ReservedNames{ServiceNames: []string{"admin", "kube-*"}}
+	assert.Equal(t, "admin", reserved.ReservedService("Admin"))
+	assert.Equal(t, "kube-*", reserved.ReservedService("kube-dns"))
+	assert.Empty(t, reserved.ReservedService("orders"))
+	assert.Empty(t, reserved.ReservedNamespace("admin"))
+}
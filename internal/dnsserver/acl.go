@@ -0,0 +1,133 @@
+package dnsserver
+
+import (
+	"net"
+	"sync"
+
+	"github.com/hewenyu/kong-discovery/internal/config"
+	"github.com/hewenyu/kong-discovery/internal/etcdclient"
+	"github.com/miekg/dns"
+	"go.uber.org/zap"
+)
+
+// queryClient 发起查询的客户端
+type queryClient struct {
+	addr     net.Addr // 来源地址
+	view     string   // 来源地址所属的视图
+	identity string   // 通过TSIG校验的身份，查询未签名时为空
+}
+
+// compiledACL 解析后的服务解析ACL
+type compiledACL struct {
+	networks   []*net.IPNet
+	identities map[string]bool
+	action     string
+}
+
+// allows 判断客户端是否被允许解析服务
+func (a *compiledACL) allows(client queryClient) bool {
+	if client.identity != "" && a.identities[client.identity] {
+		return true
+	}
+	if client.addr == nil {
+		return false
+	}
+	ip := clientIP(client.addr)
+	if ip == nil {
+		return false
+	}
+	for _, n := range a.networks {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// rcode 返回拒绝时的响应码
+func (a *compiledACL) rcode() int {
+	if a.action == etcdclient.ACLActionRefused {
+		return dns.RcodeRefused
+	}
+	return dns.RcodeNameError
+}
+
+// serviceACLs 按服务名称保存的解析ACL，随运行时配置从etcd重新加载
+type serviceACLs struct {
+	mu   sync.RWMutex
+	acls map[string]*compiledACL
+}
+
+// newServiceACLs 创建空的解析ACL集合
+func newServiceACLs() *serviceACLs {
+	return &serviceACLs{acls: make(map[string]*compiledACL)}
+}
+
+// setACLs 替换所有服务的解析ACL，无效的网段会被记录并跳过
+func (s *serviceACLs) setACLs(acls []*etcdclient.ServiceACL, logger config.Logger) {
+	compiled := make(map[string]*compiledACL, len(acls))
+	for _, acl := range acls {
+		c := &compiledACL{identities: make(map[string]bool, len(acl.AllowIdentities)), action: acl.Action}
+		for _, cidr := range acl.AllowCIDRs {
+			_, ipNet, err := net.ParseCIDR(cidr)
+			if err != nil {
+				logger.Warn("无效的服务解析ACL网段",
+					zap.String("service", acl.ServiceName),
+					zap.String("cidr", cidr),
+					zap.Error(err))
+				continue
+			}
+			c.networks = append(c.networks, ipNet)
+		}
+		for _, identity := range acl.AllowIdentities {
+			c.identities[etcdclient.NormalizeIdentity(identity)] = true
+		}
+		compiled[acl.ServiceName] = c
+	}
+
+	s.mu.Lock()
+	s.acls = compiled
+	s.mu.Unlock()
+}
+
+// get 返回服务的解析ACL，服务未设置ACL时返回nil
+func (s *serviceACLs) get(serviceName string) *compiledACL {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.acls[serviceName]
+}
+
+// tsigSecrets 把配置的TSIG密钥转为miekg/dns使用的密钥表，未配置密钥时返回nil
+func tsigSecrets(keys []config.TSIGKey) map[string]string {
+	if len(keys) == 0 {
+		return nil
+	}
+	secrets := make(map[string]string, len(keys))
+	for _, key := range keys {
+		secrets[dns.Fqdn(etcdclient.NormalizeIdentity(key.Name))] = key.Secret
+	}
+	return secrets
+}
+
+// requestIdentity 返回查询通过TSIG校验的身份，并去掉查询中的TSIG记录，避免转发到上游
+// 查询携带TSIG但校验失败时ok为false；未配置TSIG密钥时忽略签名，按未签名的查询处理
+func (s *DNSServer) requestIdentity(w dns.ResponseWriter, r *dns.Msg) (req *dns.Msg, identity string, ok bool) {
+	t := r.IsTsig()
+	if t == nil {
+		return r, "", true
+	}
+
+	req = r.Copy()
+	req.Extra = req.Extra[:len(req.Extra)-1]
+	if s.tsigSecrets == nil {
+		return req, "", true
+	}
+	if err := w.TsigStatus(); err != nil {
+		s.logger.Warn("TSIG校验失败",
+			zap.String("key", t.Hdr.Name),
+			zap.String("client", addrString(w.RemoteAddr())),
+			zap.Error(err))
+		return req, "", false
+	}
+	return req, etcdclient.NormalizeIdentity(t.Hdr.Name), true
+}
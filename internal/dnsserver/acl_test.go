@@ -0,0 +1,173 @@
+package dnsserver
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hewenyu/kong-discovery/internal/config"
+	"github.com/hewenyu/kong-discovery/internal/etcdclient"
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompiledACL_Allows(t *testing.T) {
+	acls := newServiceACLs()
+	acls.setACLs([]*etcdclient.ServiceACL{{
+		ServiceName:     "payments",
+		AllowCIDRs:      []string{"10.1.0.0/16", "invalid"},
+		AllowIdentities: []string{"Billing-Key."},
+		Action:          etcdclient.ACLActionRefused,
+	}}, createTestLogger(t))
+
+	assert.Nil(t, acls.get("orders"))
+	acl := acls.get("payments")
+	require.NotNil(t, acl)
+	assert.Equal(t, dns.RcodeRefused, acl.rcode())
+
+	addr := func(ip string) net.Addr { return &net.UDPAddr{IP: net.ParseIP(ip), Port: 53000} }
+	assert.True(t, acl.allows(queryClient{addr: addr("10.1.2.3")}))
+	assert.False(t, acl.allows(queryClient{addr: addr("10.2.0.1")}))
+	assert.True(t, acl.allows(queryClient{addr: addr("10.2.0.1"), identity: "billing-key"}))
+	assert.False(t, acl.allows(queryClient{addr: addr("10.2.0.1"), identity: "other-key"}))
+	assert.False(t, acl.allows(queryClient{}))
+}
+
+func TestDNSServer_ServiceACL(t *testing.T) {
+	// 跳过集成测试，除非明确要求运行
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	client := etcdclient.CreateEtcdClientForTest(t)
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	serviceName := fmt.Sprintf("acl-service-%d", time.Now().UnixNano())
+	defer func() {
+		_ = client.DeregisterService(context.Background(), serviceName, "acl-001")
+		_ = client.DeleteServiceACL(context.Background(), serviceName)
+	}()
+	require.NoError(t, client.RegisterService(ctx, &etcdclient.ServiceInstance{ServiceName: serviceName, InstanceID: "acl-001", IPAddress: "10.0.20.1", Port: 8080, TTL: 60}))
+
+	server := NewDNSServer(&config.Config{}, createTestLogger(t)).(*DNSServer)
+	server.SetEtcdClient(client)
+
+	query := func() *dns.Msg {
+		r := new(dns.Msg)
+		r.SetQuestion(serviceName+".default.svc.cluster.local.", dns.TypeA)
+		w := &recordingWriter{}
+		server.handleDNSRequest(w, r)
+		require.NotNil(t, w.msg)
+		return w.msg
+	}
+
+	// 未设置ACL时所有客户端都可以解析
+	m := query()
+	require.Len(t, m.Answer, 1)
+
+	// recordingWriter的来源地址10.1.2.3不在允许的网段内
+	require.NoError(t, client.PutServiceACL(ctx, &etcdclient.ServiceACL{ServiceName: serviceName, AllowCIDRs: []string{"192.168.0.0/16"}}))
+	server.applyServiceACLs()
+	m = query()
+	assert.Equal(t, dns.RcodeNameError, m.Rcode)
+	assert.Empty(t, m.Answer)
+
+	require.NoError(t, client.PutServiceACL(ctx, &etcdclient.ServiceACL{ServiceName: serviceName, AllowCIDRs: []string{"192.168.0.0/16"}, Action: etcdclient.ACLActionRefused}))
+	server.applyServiceACLs()
+	assert.Equal(t, dns.RcodeRefused, query().Rcode)
+
+	// 来源地址在允许的网段内
+	require.NoError(t, client.PutServiceACL(ctx, &etcdclient.ServiceACL{ServiceName: serviceName, AllowCIDRs: []string{"10.1.0.0/16"}}))
+	server.applyServiceACLs()
+	m = query()
+	require.Len(t, m.Answer, 1)
+	assert.Equal(t, "10.0.20.1", m.Answer[0].(*dns.A).A.String())
+
+	// 删除ACL后恢复
+	require.NoError(t, client.DeleteServiceACL(ctx, serviceName))
+	server.applyServiceACLs()
+	require.Len(t, query().Answer, 1)
+}
+
+func TestDNSServer_ServiceACLWithTSIG(t *testing.T) {
+	// 跳过集成测试，除非明确要求运行
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	client := etcdclient.CreateEtcdClientForTest(t)
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	serviceName := fmt.Sprintf("tsig-service-%d", time.Now().UnixNano())
+	defer func() {
+		_ = client.DeregisterService(context.Background(), serviceName, "tsig-001")
+		_ = client.DeleteServiceACL(context.Background(), serviceName)
+	}()
+	require.NoError(t, client.RegisterService(ctx, &etcdclient.ServiceInstance{ServiceName: serviceName, InstanceID: "tsig-001", IPAddress: "10.0.30.1", Port: 8080, TTL: 60}))
+	// 本机地址不在允许的网段内，只有使用billing-key签名的查询可以解析
+	require.NoError(t, client.PutServiceACL(ctx, &etcdclient.ServiceACL{ServiceName: serviceName, AllowCIDRs: []string{"192.0.2.0/24"}, AllowIdentities: []string{"billing-key"}}))
+
+	billingSecret := base64.StdEncoding.EncodeToString([]byte("billing-secret"))
+	otherSecret := base64.StdEncoding.EncodeToString([]byte("other-secret"))
+	cfg := &config.Config{}
+	cfg.DNS.Listeners = []config.Listener{{Address: "127.0.0.1", Port: 0, Protocol: "udp"}}
+	cfg.DNS.TSIGKeys = []config.TSIGKey{
+		{Name: "billing-key", Secret: billingSecret},
+		{Name: "other-key", Secret: otherSecret},
+	}
+	server := NewDNSServer(cfg, createTestLogger(t)).(*DNSServer)
+	server.SetEtcdClient(client)
+	require.NoError(t, server.Start())
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		assert.NoError(t, server.Shutdown(ctx))
+	}()
+	addr := strings.TrimPrefix(server.Addrs()[0], "udp://")
+
+	exchange := func(key, secret string) (*dns.Msg, error) {
+		m := new(dns.Msg)
+		m.SetQuestion(serviceName+".default.svc.cluster.local.", dns.TypeA)
+		c := &dns.Client{Net: "udp", Timeout: 2 * time.Second}
+		if key != "" {
+			m.SetTsig(key, dns.HmacSHA256, 300, time.Now().Unix())
+			c.TsigSecret = map[string]string{key: secret}
+		}
+		r, _, err := c.Exchange(m, addr)
+		return r, err
+	}
+
+	// 未签名的查询被拒绝
+	r, err := exchange("", "")
+	require.NoError(t, err)
+	assert.Equal(t, dns.RcodeNameError, r.Rcode)
+
+	// 使用允许的密钥签名，应答也经过签名
+	r, err = exchange("billing-key.", billingSecret)
+	require.NoError(t, err)
+	require.Len(t, r.Answer, 1)
+	assert.Equal(t, "10.0.30.1", r.Answer[0].(*dns.A).A.String())
+	assert.NotNil(t, r.IsTsig())
+
+	// 其他已知密钥签名的查询不在ACL中
+	r, err = exchange("other-key.", otherSecret)
+	require.NoError(t, err)
+	assert.Equal(t, dns.RcodeNameError, r.Rcode)
+
+	// 签名无法校验的查询返回NOTAUTH
+	r, err = exchange("billing-key.", otherSecret)
+	require.NoError(t, err)
+	assert.Equal(t, dns.RcodeNotAuth, r.Rcode)
+	assert.Empty(t, r.Answer)
+}
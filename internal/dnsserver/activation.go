@@ -0,0 +1,55 @@
+package dnsserver
+
+import (
+	"fmt"
+	"net"
+	"os"
+)
+
+// fileConn 把已绑定的套接字文件转换为TCP监听器或UDP连接，返回对应的协议
+// 转换时会复制文件描述符，调用方随后应关闭f
+func fileConn(f *os.File) (net.Listener, net.PacketConn, error) {
+	if ln, err := net.FileListener(f); err == nil {
+		return ln, nil, nil
+	}
+	conn, err := net.FilePacketConn(f)
+	if err != nil {
+		return nil, nil, fmt.Errorf("文件描述符 %s 不是可用的TCP或UDP套接字: %w", f.Name(), err)
+	}
+	return nil, conn, nil
+}
+
+// Files 返回所有服务器监听套接字的副本，用于升级时传递给新进程
+// 调用方负责关闭返回的文件
+func (s *DNSServer) Files() ([]*os.File, error) {
+	type filer interface {
+		File() (*os.File, error)
+	}
+
+	files := make([]*os.File, 0, len(s.servers))
+	for _, server := range s.servers {
+		var conn any = server.PacketConn
+		if server.Listener != nil {
+			conn = server.Listener
+		}
+		f, ok := conn.(filer)
+		if !ok {
+			closeFiles(files)
+			return nil, fmt.Errorf("DNS服务器 %s 的套接字无法传递", serverAddr(server))
+		}
+		file, err := f.File()
+		if err != nil {
+			closeFiles(files)
+			return nil, fmt.Errorf("复制DNS服务器 %s 的套接字失败: %w", serverAddr(server), err)
+		}
+		files = append(files, file)
+	}
+	return files, nil
+}
+
+// closeFiles 关闭所有文件
+func closeFiles(files []*os.File) {
+	for _, f := range files {
+		f.Close()
+	}
+}
@@ -0,0 +1,109 @@
+//go:build unix
+
+package dnsserver
+
+import (
+	"context"
+	"net"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/hewenyu/kong-discovery/internal/config"
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// inheritFD 复制套接字的文件描述符，模拟由父进程传递的已绑定套接字
+func inheritFD(t *testing.T, f *os.File) int {
+	t.Helper()
+	fd, err := syscall.Dup(int(f.Fd()))
+	require.NoError(t, err)
+	return fd
+}
+
+func TestDNSServer_InheritedFD(t *testing.T) {
+	udpConn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	udpFile, err := udpConn.(*net.UDPConn).File()
+	require.NoError(t, err)
+	udpConn.Close()
+	defer udpFile.Close()
+
+	tcpListener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	tcpFile, err := tcpListener.(*net.TCPListener).File()
+	require.NoError(t, err)
+	tcpAddr := tcpListener.Addr().String()
+	tcpListener.Close()
+	defer tcpFile.Close()
+
+	cfg := &config.Config{}
+	cfg.DNS.Protocol = "both"
+	cfg.DNS.Listeners = []config.Listener{
+		{FD: inheritFD(t, udpFile)},
+		{FD: inheritFD(t, tcpFile)},
+	}
+	logger, err := config.NewLogger(true)
+	require.NoError(t, err)
+
+	server := NewDNSServer(cfg, logger)
+	require.NoError(t, server.Start())
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		assert.NoError(t, server.Shutdown(ctx))
+	}()
+
+	addrs := server.Addrs()
+	require.Len(t, addrs, 2)
+	assert.Equal(t, "tcp://"+tcpAddr, addrs[1])
+	udpAddr := strings.TrimPrefix(addrs[0], "udp://")
+
+	// 按套接字类型分别以UDP和TCP应答
+	m := new(dns.Msg)
+	m.SetQuestion("test.local.", dns.TypeA)
+	for protocol, addr := range map[string]string{"udp": udpAddr, "tcp": tcpAddr} {
+		c := &dns.Client{Net: protocol}
+		r, _, err := c.Exchange(m, addr)
+		require.NoError(t, err, "%s %s", protocol, addr)
+		require.Len(t, r.Answer, 1)
+		assert.Equal(t, "1.2.3.4", r.Answer[0].(*dns.A).A.String())
+	}
+}
+
+func TestDNSServer_FilesHandoff(t *testing.T) {
+	logger, err := config.NewLogger(true)
+	require.NoError(t, err)
+
+	cfg := &config.Config{}
+	cfg.DNS.Listeners = []config.Listener{{Address: "127.0.0.1", Port: 0, Protocol: "udp"}}
+	oldServer := NewDNSServer(cfg, logger)
+	require.NoError(t, oldServer.Start())
+	addr := strings.TrimPrefix(oldServer.Addrs()[0], "udp://")
+
+	// 新的服务器使用旧服务器传递的套接字，地址保持不变
+	files, err := oldServer.Files()
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	defer closeFiles(files)
+
+	newCfg := &config.Config{}
+	newCfg.DNS.Listeners = []config.Listener{{FD: inheritFD(t, files[0])}}
+	newServer := NewDNSServer(newCfg, logger)
+	require.NoError(t, newServer.Start())
+	defer newServer.Shutdown(context.Background())
+	assert.Equal(t, []string{"udp://" + addr}, newServer.Addrs())
+
+	// 旧服务器关闭后新服务器继续在同一地址上应答
+	require.NoError(t, oldServer.Shutdown(context.Background()))
+
+	m := new(dns.Msg)
+	m.SetQuestion("test.local.", dns.TypeA)
+	r, _, err := new(dns.Client).Exchange(m, addr)
+	require.NoError(t, err)
+	require.Len(t, r.Answer, 1)
+}
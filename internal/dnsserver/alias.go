@@ -0,0 +1,101 @@
+package dnsserver
+
+import (
+	"math/rand"
+	"sync"
+
+	"github.com/hewenyu/kong-discovery/internal/etcdclient"
+)
+
+// aliasRoute 别名的目标服务，只有一个目标时不需要抽样
+type aliasRoute struct {
+	targets []string
+	weights []int
+	total   int
+}
+
+// pick 按权重抽取一个目标服务
+func (r *aliasRoute) pick() string {
+	if len(r.targets) == 1 {
+		return r.targets[0]
+	}
+	n := rand.Intn(r.total)
+	for i, weight := range r.weights {
+		if n < weight {
+			return r.targets[i]
+		}
+		n -= weight
+	}
+	return r.targets[len(r.targets)-1]
+}
+
+// serviceAliases 别名到目标服务的映射，随运行时配置从etcd重新加载
+type serviceAliases struct {
+	mu     sync.RWMutex
+	routes map[string]*aliasRoute
+}
+
+// newServiceAliases 创建空的别名映射
+func newServiceAliases() *serviceAliases {
+	return &serviceAliases{routes: make(map[string]*aliasRoute)}
+}
+
+// setAliases 替换所有别名；目标中含有别名或定义无效的别名会被忽略，避免解析时形成链或环
+func (a *serviceAliases) setAliases(aliases []*etcdclient.ServiceAlias) {
+	isAlias := make(map[string]bool, len(aliases))
+	for _, alias := range aliases {
+		isAlias[alias.ServiceName] = true
+	}
+
+	routes := make(map[string]*aliasRoute, len(aliases))
+	for _, alias := range aliases {
+		if alias.Validate() != nil {
+			continue
+		}
+		route := &aliasRoute{}
+		if len(alias.Split) == 0 {
+			route.targets, route.weights, route.total = []string{alias.Target}, []int{1}, 1
+		}
+		for _, t := range alias.Split {
+			route.targets = append(route.targets, t.Service)
+			route.weights = append(route.weights, t.Weight)
+			route.total += t.Weight
+		}
+		chained := false
+		for _, target := range route.targets {
+			chained = chained || isAlias[target]
+		}
+		if !chained {
+			routes[alias.ServiceName] = route
+		}
+	}
+
+	a.mu.Lock()
+	a.routes = routes
+	a.mu.Unlock()
+}
+
+// target 返回本次查询使用的目标服务，分流时按权重抽取；服务不是别名时返回空字符串
+func (a *serviceAliases) target(serviceName string) string {
+	a.mu.RLock()
+	route := a.routes[serviceName]
+	a.mu.RUnlock()
+	if route == nil {
+		return ""
+	}
+	return route.pick()
+}
+
+// isAlias 判断服务是否是别名
+func (a *serviceAliases) isAlias(serviceName string) bool {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.routes[serviceName] != nil
+}
+
+// aliasDomain 把服务域名中的别名替换为目标服务名称，保留RFC 2782的端口名和协议前缀
+func aliasDomain(domain, serviceName, target string) string {
+	_, _, base := etcdclient.ParseServiceDomain(domain)
+	prefix := domain[:len(domain)-len(base)]
+	return prefix + target + base[len(serviceName):]
+}
@@ -0,0 +1,125 @@
+package dnsserver
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hewenyu/kong-discovery/internal/config"
+	"github.com/hewenyu/kong-discovery/internal/etcdclient"
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAliasDomain(t *testing.T) {
+	assert.Equal(t, "payments-v2.default.svc.cluster.local",
+		aliasDomain("payments.default.svc.cluster.local", "payments", "payments-v2"))
+	assert.Equal(t, "_payments._tcp.payments-v2.default.svc.cluster.local",
+		aliasDomain("_payments._tcp.payments.default.svc.cluster.local", "payments", "payments-v2"))
+}
+
+func TestServiceAliases_SetAliases(t *testing.T) {
+	aliases := newServiceAliases()
+	aliases.setAliases([]*etcdclient.ServiceAlias{
+		{ServiceName: "payments", Target: "payments-v2"},
+		{ServiceName: "a", Target: "b"},
+		{ServiceName: "b", Target: "c"},
+	})
+
+	assert.Equal(t, "payments-v2", aliases.target("payments"))
+	assert.Equal(t, "c", aliases.target("b"))
+	// 指向别名的别名被忽略
+	assert.Empty(t, aliases.target("a"))
+	assert.Empty(t, aliases.target("payments-v2"))
+}
+
+func TestDNSServer_ServiceAlias(t *testing.T) {
+	// 跳过集成测试，除非明确要求运行
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	client := etcdclient.CreateEtcdClientForTest(t)
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	alias := fmt.Sprintf("payments-%d", time.Now().UnixNano())
+	blue, green := alias+"-v1", alias+"-v2"
+	defer func() {
+		_ = client.DeregisterService(context.Background(), blue, "blue-001")
+		_ = client.DeregisterService(context.Background(), green, "green-001")
+		_ = client.DeleteServiceAlias(context.Background(), alias)
+	}()
+	require.NoError(t, client.RegisterService(ctx, &etcdclient.ServiceInstance{ServiceName: blue, InstanceID: "blue-001", IPAddress: "10.0.30.1", Port: 8080, TTL: 60}))
+	require.NoError(t, client.RegisterService(ctx, &etcdclient.ServiceInstance{ServiceName: green, InstanceID: "green-001", IPAddress: "10.0.30.2", Port: 8080, TTL: 60}))
+
+	server := NewDNSServer(&config.Config{}, createTestLogger(t)).(*DNSServer)
+	server.SetEtcdClient(client)
+
+	name := alias + ".default.svc.cluster.local."
+	query := func() *dns.Msg {
+		r := new(dns.Msg)
+		r.SetQuestion(name, dns.TypeA)
+		w := &recordingWriter{}
+		server.handleDNSRequest(w, r)
+		require.NotNil(t, w.msg)
+		return w.msg
+	}
+
+	require.NoError(t, client.PutServiceAlias(ctx, &etcdclient.ServiceAlias{ServiceName: alias, Target: blue}))
+	server.applyServiceAliases()
+	m := query()
+	require.Len(t, m.Answer, 1)
+	assert.Equal(t, name, m.Answer[0].Header().Name)
+	assert.Equal(t, "10.0.30.1", m.Answer[0].(*dns.A).A.String())
+
+	// 切换别名的目标
+	require.NoError(t, client.PutServiceAlias(ctx, &etcdclient.ServiceAlias{ServiceName: alias, Target: green}))
+	server.applyServiceAliases()
+	m = query()
+	require.Len(t, m.Answer, 1)
+	assert.Equal(t, "10.0.30.2", m.Answer[0].(*dns.A).A.String())
+
+	// 删除别名后按普通服务处理，别名本身没有实例
+	require.NoError(t, client.DeleteServiceAlias(ctx, alias))
+	server.applyServiceAliases()
+	assert.Empty(t, query().Answer)
+}
+
+func TestServiceAliases_Split(t *testing.T) {
+	aliases := newServiceAliases()
+	aliases.setAliases([]*etcdclient.ServiceAlias{{
+		ServiceName: "payments",
+		Split: []etcdclient.SplitTarget{
+			{Service: "payments-v1", Weight: 80},
+			{Service: "payments-v2", Weight: 20},
+			{Service: "payments-v3", Weight: 0},
+		},
+	}})
+
+	counts := make(map[string]int)
+	for i := 0; i < 10000; i++ {
+		counts[aliases.target("payments")]++
+	}
+	assert.InDelta(t, 8000, counts["payments-v1"], 400)
+	assert.InDelta(t, 2000, counts["payments-v2"], 400)
+	assert.Zero(t, counts["payments-v3"], "权重为0的目标不分配查询")
+}
+
+func TestQueryAnalytics_Splits(t *testing.T) {
+	a := newQueryAnalytics()
+	now := time.Now()
+	for i := 0; i < 3; i++ {
+		a.recordSplit("payments", "payments-v1", now)
+	}
+	a.recordSplit("payments", "payments-v2", now)
+
+	stats := a.snapshot(time.Minute, 10, now)
+	require.Len(t, stats.Splits, 2)
+	assert.Equal(t, AliasSplit{Alias: "payments", Target: "payments-v1", Queries: 3, Ratio: 0.75}, stats.Splits[0])
+	assert.Equal(t, AliasSplit{Alias: "payments", Target: "payments-v2", Queries: 1, Ratio: 0.25}, stats.Splits[1])
+}
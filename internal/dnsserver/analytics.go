@@ -0,0 +1,273 @@
+package dnsserver
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hewenyu/kong-discovery/internal/etcdclient"
+	"github.com/miekg/dns"
+)
+
+const (
+	// analyticsBucketWidth 每个统计桶覆盖的时间
+	analyticsBucketWidth = 10 * time.Second
+
+	// analyticsBuckets 保留的统计桶数量，决定最大统计窗口（15分钟）
+	analyticsBuckets = 90
+
+	// analyticsMaxNamesPerBucket 每个桶最多单独统计的域名数，超出部分计入otherNames
+	analyticsMaxNamesPerBucket = 10000
+
+	// MaxStatsWindow 查询统计支持的最大时间窗口
+	MaxStatsWindow = analyticsBucketWidth * analyticsBuckets
+)
+
+// NameCount 域名及其查询次数
+type NameCount struct {
+	Name  string `json:"name"`  // 查询域名
+	Count uint64 `json:"count"` // 查询次数
+}
+
+// ServiceRate 服务的查询量与速率
+type ServiceRate struct {
+	Service string  `json:"service"` // 服务名
+	Queries uint64  `json:"queries"` // 查询次数
+	QPS     float64 `json:"qps"`     // 每秒查询数
+}
+
+// AliasSplit 别名的查询实际分配到各目标服务的情况
+type AliasSplit struct {
+	Alias   string  `json:"alias"`   // 别名
+	Target  string  `json:"target"`  // 目标服务
+	Queries uint64  `json:"queries"` // 分配到该目标的查询数
+	Ratio   float64 `json:"ratio"`   // 占该别名查询数的比例
+}
+
+// DNSStats 时间窗口内的查询统计
+type DNSStats struct {
+	Window       string        `json:"window"`        // 统计窗口
+	Queries      uint64        `json:"queries"`       // 查询总数
+	QPS          float64       `json:"qps"`           // 每秒查询数
+	NXDomain     uint64        `json:"nxdomain"`      // NXDOMAIN响应数
+	NXDomainRate float64       `json:"nxdomain_rate"` // NXDOMAIN比例
+	OtherNames   uint64        `json:"other_names"`   // 因域名过多未单独统计的查询数
+	Overloaded   uint64        `json:"overloaded"`    // 因并发查询数达到上限被拒绝的查询数
+	Limited      uint64        `json:"limited"`       // 记录数超过上限、只返回部分记录的应答数
+	Truncated    uint64        `json:"truncated"`     // 超过客户端UDP接收大小被截断的应答数
+	Precomputed  uint64        `json:"precomputed"`   // 直接发送预生成应答的查询数
+	TopNames     []NameCount   `json:"top_names"`     // 查询最多的域名
+	Services     []ServiceRate `json:"services"`      // 各服务的查询速率
+	Splits       []AliasSplit  `json:"splits"`        // 别名查询实际分配到各目标服务的比例
+}
+
+// analyticsBucket 一个时间桶内的统计数据
+type analyticsBucket struct {
+	start       int64 // 桶的起始时间（按桶宽取整的Unix秒）
+	queries     uint64
+	nxdomain    uint64
+	otherNames  uint64
+	overloaded  uint64
+	limited     uint64
+	truncated   uint64
+	precomputed uint64
+	names       map[string]uint64
+	services    map[string]uint64
+	splits      map[[2]string]uint64 // 按（别名, 目标服务）统计的查询数
+}
+
+// queryAnalytics 基于环形时间桶的轻量查询统计
+type queryAnalytics struct {
+	mu      sync.Mutex
+	buckets [analyticsBuckets]analyticsBucket
+}
+
+// newQueryAnalytics 创建查询统计收集器
+func newQueryAnalytics() *queryAnalytics {
+	return &queryAnalytics{}
+}
+
+// bucketStart 返回时间所属桶的起始时间
+func bucketStart(now time.Time) int64 {
+	width := int64(analyticsBucketWidth / time.Second)
+	return now.Unix() / width * width
+}
+
+// bucket 返回时间所属的桶，调用方需持有锁
+func (a *queryAnalytics) bucket(now time.Time) *analyticsBucket {
+	start := bucketStart(now)
+	idx := int(start/int64(analyticsBucketWidth/time.Second)) % analyticsBuckets
+
+	b := &a.buckets[idx]
+	if b.start != start {
+		// 桶已过期，重置后复用
+		*b = analyticsBucket{
+			start:    start,
+			names:    make(map[string]uint64),
+			services: make(map[string]uint64),
+			splits:   make(map[[2]string]uint64),
+		}
+	}
+	return b
+}
+
+// record 记录一次查询
+func (a *queryAnalytics) record(name string, rcode int, now time.Time) {
+	name = strings.TrimSuffix(strings.ToLower(name), ".")
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	b := a.bucket(now)
+	b.queries++
+	if rcode == dns.RcodeNameError {
+		b.nxdomain++
+	}
+
+	if _, ok := b.names[name]; ok || len(b.names) < analyticsMaxNamesPerBucket {
+		b.names[name]++
+	} else {
+		b.otherNames++
+	}
+
+	if service := serviceFromDomain(name); service != "" {
+		b.services[service]++
+	}
+}
+
+// recordOverload 记录一次因过载被拒绝的查询
+func (a *queryAnalytics) recordOverload(now time.Time) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.bucket(now).overloaded++
+}
+
+// recordLimited 记录一次只返回部分记录的应答
+func (a *queryAnalytics) recordLimited(now time.Time) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.bucket(now).limited++
+}
+
+// recordTruncated 记录一次被截断的UDP应答
+func (a *queryAnalytics) recordTruncated(now time.Time) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.bucket(now).truncated++
+}
+
+// recordPrecomputed 记录一次直接发送预生成应答的查询
+func (a *queryAnalytics) recordPrecomputed(now time.Time) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.bucket(now).precomputed++
+}
+
+// recordSplit 记录一次别名查询分配到的目标服务
+func (a *queryAnalytics) recordSplit(alias, target string, now time.Time) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.bucket(now).splits[[2]string{alias, target}]++
+}
+
+// serviceFromDomain 从服务域名中提取服务名，非服务域名返回空字符串
+func serviceFromDomain(domain string) string {
+	name, _ := etcdclient.ServiceNameFromDomain(domain)
+	return name
+}
+
+// snapshot 汇总最近window时间内的统计，topN为返回的热门域名数量
+func (a *queryAnalytics) snapshot(window time.Duration, topN int, now time.Time) DNSStats {
+	if window <= 0 || window > MaxStatsWindow {
+		window = MaxStatsWindow
+	}
+	oldest := bucketStart(now.Add(-window)) + int64(analyticsBucketWidth/time.Second)
+
+	stats := DNSStats{Window: window.String()}
+	names := make(map[string]uint64)
+	services := make(map[string]uint64)
+	splits := make(map[[2]string]uint64)
+
+	a.mu.Lock()
+	for i := range a.buckets {
+		b := &a.buckets[i]
+		if b.names == nil || b.start < oldest || b.start > now.Unix() {
+			continue
+		}
+		stats.Queries += b.queries
+		stats.NXDomain += b.nxdomain
+		stats.OtherNames += b.otherNames
+		stats.Overloaded += b.overloaded
+		stats.Limited += b.limited
+		stats.Truncated += b.truncated
+		stats.Precomputed += b.precomputed
+		for name, count := range b.names {
+			names[name] += count
+		}
+		for service, count := range b.services {
+			services[service] += count
+		}
+		for split, count := range b.splits {
+			splits[split] += count
+		}
+	}
+	a.mu.Unlock()
+
+	seconds := window.Seconds()
+	stats.QPS = float64(stats.Queries) / seconds
+	if stats.Queries > 0 {
+		stats.NXDomainRate = float64(stats.NXDomain) / float64(stats.Queries)
+	}
+
+	stats.TopNames = make([]NameCount, 0, len(names))
+	for name, count := range names {
+		stats.TopNames = append(stats.TopNames, NameCount{Name: name, Count: count})
+	}
+	sort.Slice(stats.TopNames, func(i, j int) bool {
+		if stats.TopNames[i].Count != stats.TopNames[j].Count {
+			return stats.TopNames[i].Count > stats.TopNames[j].Count
+		}
+		return stats.TopNames[i].Name < stats.TopNames[j].Name
+	})
+	if topN > 0 && len(stats.TopNames) > topN {
+		stats.TopNames = stats.TopNames[:topN]
+	}
+
+	stats.Services = make([]ServiceRate, 0, len(services))
+	for service, count := range services {
+		stats.Services = append(stats.Services, ServiceRate{
+			Service: service,
+			Queries: count,
+			QPS:     float64(count) / seconds,
+		})
+	}
+	sort.Slice(stats.Services, func(i, j int) bool {
+		if stats.Services[i].Queries != stats.Services[j].Queries {
+			return stats.Services[i].Queries > stats.Services[j].Queries
+		}
+		return stats.Services[i].Service < stats.Services[j].Service
+	})
+
+	aliasTotals := make(map[string]uint64)
+	for split, count := range splits {
+		aliasTotals[split[0]] += count
+	}
+	stats.Splits = make([]AliasSplit, 0, len(splits))
+	for split, count := range splits {
+		stats.Splits = append(stats.Splits, AliasSplit{
+			Alias:   split[0],
+			Target:  split[1],
+			Queries: count,
+			Ratio:   float64(count) / float64(aliasTotals[split[0]]),
+		})
+	}
+	sort.Slice(stats.Splits, func(i, j int) bool {
+		if stats.Splits[i].Alias != stats.Splits[j].Alias {
+			return stats.Splits[i].Alias < stats.Splits[j].Alias
+		}
+		return stats.Splits[i].Target < stats.Splits[j].Target
+	})
+
+	return stats
+}
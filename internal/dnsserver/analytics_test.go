@@ -0,0 +1,60 @@
+package dnsserver
+
+import (
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryAnalytics_Snapshot(t *testing.T) {
+	a := newQueryAnalytics()
+	now := time.Unix(1700000000, 0)
+
+	for i := 0; i < 3; i++ {
+		a.record("api.svc.cluster.local.", dns.RcodeSuccess, now)
+	}
+	a.record("web.svc.cluster.local.", dns.RcodeSuccess, now)
+	a.record("Missing.Example.com.", dns.RcodeNameError, now)
+
+	stats := a.snapshot(time.Minute, 2, now)
+	assert.Equal(t, "1m0s", stats.Window)
+	assert.Equal(t, uint64(5), stats.Queries)
+	assert.Equal(t, uint64(1), stats.NXDomain)
+	assert.InDelta(t, 0.2, stats.NXDomainRate, 1e-9)
+	assert.InDelta(t, 5.0/60, stats.QPS, 1e-9)
+
+	// 热门域名按次数降序并截断到topN
+	require.Len(t, stats.TopNames, 2)
+	assert.Equal(t, NameCount{Name: "api.svc.cluster.local", Count: 3}, stats.TopNames[0])
+	assert.Equal(t, "missing.example.com", stats.TopNames[1].Name)
+
+	// 只统计服务域名
+	require.Len(t, stats.Services, 2)
+	assert.Equal(t, "api", stats.Services[0].Service)
+	assert.Equal(t, uint64(3), stats.Services[0].Queries)
+	assert.Equal(t, "web", stats.Services[1].Service)
+}
+
+func TestQueryAnalytics_WindowExpiry(t *testing.T) {
+	a := newQueryAnalytics()
+	now := time.Unix(1700000000, 0)
+
+	a.record("old.example.com.", dns.RcodeSuccess, now.Add(-2*time.Minute))
+	a.record("new.example.com.", dns.RcodeSuccess, now)
+
+	// 窗口之外的查询不计入
+	stats := a.snapshot(time.Minute, 10, now)
+	assert.Equal(t, uint64(1), stats.Queries)
+	require.Len(t, stats.TopNames, 1)
+	assert.Equal(t, "new.example.com", stats.TopNames[0].Name)
+
+	assert.Equal(t, uint64(2), a.snapshot(5*time.Minute, 10, now).Queries)
+
+	// 环形桶被复用后旧数据不再出现
+	later := now.Add(MaxStatsWindow)
+	a.record("new.example.com.", dns.RcodeSuccess, later)
+	assert.Equal(t, uint64(1), a.snapshot(MaxStatsWindow, 10, later).Queries)
+}
@@ -0,0 +1,140 @@
+package dnsserver
+
+import (
+	"hash/fnv"
+	"math/rand"
+	"net"
+	"sort"
+	"sync"
+
+	"github.com/hewenyu/kong-discovery/internal/etcdclient"
+)
+
+// serviceAnswerPolicies 按服务名称保存的应答策略，随运行时配置从etcd重新加载
+type serviceAnswerPolicies struct {
+	mu       sync.RWMutex
+	policies map[string]*etcdclient.ServiceAnswerPolicy
+}
+
+// newServiceAnswerPolicies 创建空的应答策略集合
+func newServiceAnswerPolicies() *serviceAnswerPolicies {
+	return &serviceAnswerPolicies{policies: make(map[string]*etcdclient.ServiceAnswerPolicy)}
+}
+
+// setPolicies 替换所有服务的应答策略
+func (p *serviceAnswerPolicies) setPolicies(policies []*etcdclient.ServiceAnswerPolicy) {
+	m := make(map[string]*etcdclient.ServiceAnswerPolicy, len(policies))
+	for _, policy := range policies {
+		m[policy.ServiceName] = policy
+	}
+
+	p.mu.Lock()
+	p.policies = m
+	p.mu.Unlock()
+}
+
+// get 返回服务的应答策略，服务未设置策略时返回nil
+func (p *serviceAnswerPolicies) get(serviceName string) *etcdclient.ServiceAnswerPolicy {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.policies[serviceName]
+}
+
+// recordOptions 返回按应答策略生成服务记录的选项，未设置策略时使用默认选项。
+// 按负载调整权重开启时，SRV权重和单个地址的A/AAAA应答都按实例上报的负载调整；
+// 实例都未设置DNS TTL时使用服务或命名空间TTL策略的默认TTL
+func (s *DNSServer) recordOptions(cluster, domain string, policy *etcdclient.ServiceAnswerPolicy) etcdclient.ServiceRecordOptions {
+	opts := etcdclient.ServiceRecordOptions{
+		LoadWeights: s.loadWeightsActive(),
+		DefaultTTL:  etcdclient.DefaultTTLOf(s.serviceTTLPolicies(cluster, domain)...),
+	}
+	if policy != nil {
+		opts.IncludeDraining = policy.IncludeDraining
+	}
+	return opts
+}
+
+// orderAnswers 按应答顺序原地排列records，key返回记录的标识（地址或SRV目标）。
+// random每次随机排列；sticky按客户端地址一致性哈希排列，见stickyOrder
+func orderAnswers[T any](records []T, order string, client queryClient, key func(T) string) {
+	if len(records) < 2 {
+		return
+	}
+	switch order {
+	case etcdclient.AnswerOrderRandom:
+		rand.Shuffle(len(records), func(i, j int) { records[i], records[j] = records[j], records[i] })
+	case etcdclient.AnswerOrderSticky:
+		stickyOrder(records, client, key)
+	}
+}
+
+// stickyOrder 按最高随机权重（rendezvous）哈希排列records：每条记录的得分由客户端地址和记录标识共同决定，
+// 得分高的排在前面。同一客户端总是得到相同的顺序，不同客户端的首选记录均匀分散；实例增减时
+// 只有首选实例被移除的客户端，或新实例得分最高的客户端改变首选，其他客户端保持原来的实例
+func stickyOrder[T any](records []T, client queryClient, key func(T) string) {
+	var ip net.IP
+	if client.addr != nil {
+		ip = clientIP(client.addr).To16()
+	}
+
+	type scored struct {
+		record T
+		key    string
+		score  uint64
+	}
+	list := make([]scored, len(records))
+	for i, record := range records {
+		k := key(record)
+		list[i] = scored{record: record, key: k, score: rendezvousScore(ip, k)}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].score != list[j].score {
+			return list[i].score > list[j].score
+		}
+		return list[i].key < list[j].key
+	})
+	for i := range list {
+		records[i] = list[i].record
+	}
+}
+
+// rendezvousScore 返回客户端地址与记录标识的哈希得分
+func rendezvousScore(ip net.IP, key string) uint64 {
+	h := fnv.New64a()
+	h.Write(ip)
+	h.Write([]byte(key))
+	// FNV的高位对输入末尾的变化不够敏感，混合后各记录的得分才均匀分布
+	x := h.Sum64()
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+	return x
+}
+
+// serviceAddresses 返回服务A/AAAA应答中的地址。未设置应答策略时返回一个实例的地址，
+// Kong兼容模式下返回所有实例的地址；设置了策略时按策略的顺序排列，one只返回排在最前的地址
+func (s *DNSServer) serviceAddresses(records map[string]*etcdclient.DNSRecord, record *etcdclient.DNSRecord, recordType string,
+	policy *etcdclient.ServiceAnswerPolicy, client queryClient) []string {
+	if policy == nil {
+		if s.kongCompat() {
+			return kongAddresses(records, recordType)
+		}
+		return []string{record.Value}
+	}
+	if policy.AddressAnswers == etcdclient.AddressAnswersOne && policy.Order == etcdclient.AnswerOrderNone && !s.kongCompat() {
+		return []string{record.Value}
+	}
+
+	addrs := kongAddresses(records, recordType)
+	if len(addrs) == 0 {
+		return []string{record.Value}
+	}
+	orderAnswers(addrs, policy.Order, client, func(addr string) string { return addr })
+	// Kong兼容模式下每个地址都是负载均衡器的一个目标，总是返回所有地址
+	if policy.AddressAnswers == etcdclient.AddressAnswersOne && !s.kongCompat() {
+		addrs = addrs[:1]
+	}
+	return addrs
+}
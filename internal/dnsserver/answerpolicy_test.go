@@ -0,0 +1,180 @@
+package dnsserver
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/hewenyu/kong-discovery/internal/config"
+	"github.com/hewenyu/kong-discovery/internal/etcdclient"
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOrderAnswers(t *testing.T) {
+	identity := func(s string) string { return s }
+	client := func(ip string) queryClient {
+		return queryClient{addr: &net.UDPAddr{IP: net.ParseIP(ip), Port: 53000}}
+	}
+
+	// none保持原有顺序
+	addrs := []string{"10.0.0.3", "10.0.0.1", "10.0.0.2"}
+	orderAnswers(addrs, etcdclient.AnswerOrderNone, client("10.1.2.3"), identity)
+	assert.Equal(t, []string{"10.0.0.3", "10.0.0.1", "10.0.0.2"}, addrs)
+
+	// sticky与记录的原有顺序无关，同一客户端总是得到相同的顺序
+	first := []string{"10.0.0.3", "10.0.0.1", "10.0.0.2"}
+	second := []string{"10.0.0.2", "10.0.0.3", "10.0.0.1"}
+	orderAnswers(first, etcdclient.AnswerOrderSticky, client("10.1.2.3"), identity)
+	orderAnswers(second, etcdclient.AnswerOrderSticky, client("10.1.2.3"), identity)
+	assert.Equal(t, first, second)
+	assert.ElementsMatch(t, []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"}, first)
+
+	// 不同客户端的首选地址分散在所有地址上
+	preferred := make(map[string]bool)
+	for i := 0; i < 64; i++ {
+		addrs := []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"}
+		orderAnswers(addrs, etcdclient.AnswerOrderSticky, client(fmt.Sprintf("10.2.0.%d", i)), identity)
+		preferred[addrs[0]] = true
+	}
+	assert.Len(t, preferred, 3)
+
+	// random只改变顺序
+	addrs = []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"}
+	orderAnswers(addrs, etcdclient.AnswerOrderRandom, client("10.1.2.3"), identity)
+	assert.ElementsMatch(t, []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"}, addrs)
+}
+
+func TestStickyOrder_Consistent(t *testing.T) {
+	identity := func(s string) string { return s }
+	clients := make([]queryClient, 200)
+	for i := range clients {
+		clients[i] = queryClient{addr: &net.UDPAddr{IP: net.ParseIP(fmt.Sprintf("10.3.%d.%d", i/250, i%250)), Port: 53000}}
+	}
+	preferred := func(addrs []string) []string {
+		result := make([]string, len(clients))
+		for i, client := range clients {
+			ordered := append([]string(nil), addrs...)
+			stickyOrder(ordered, client, identity)
+			result[i] = ordered[0]
+		}
+		return result
+	}
+
+	addrs := []string{"10.0.0.1", "10.0.0.2", "10.0.0.3", "10.0.0.4"}
+	before := preferred(addrs)
+	counts := make(map[string]int)
+	for _, addr := range before {
+		counts[addr]++
+	}
+	// 首选实例大致均匀分布
+	for _, addr := range addrs {
+		assert.Greater(t, counts[addr], 20, addr)
+	}
+
+	// 移除一个实例时只有首选该实例的客户端改变首选
+	after := preferred([]string{"10.0.0.1", "10.0.0.2", "10.0.0.4"})
+	for i := range clients {
+		if before[i] != "10.0.0.3" {
+			assert.Equal(t, before[i], after[i])
+		} else {
+			assert.NotEqual(t, "10.0.0.3", after[i])
+		}
+	}
+
+	// 增加一个实例时改变首选的客户端都转到新实例
+	after = preferred(append(addrs, "10.0.0.5"))
+	moved := 0
+	for i := range clients {
+		if before[i] != after[i] {
+			assert.Equal(t, "10.0.0.5", after[i])
+			moved++
+		}
+	}
+	assert.Greater(t, moved, 0)
+	assert.Less(t, moved, len(clients)/2)
+}
+
+func TestDNSServer_ServiceAnswerPolicy(t *testing.T) {
+	// 跳过集成测试，除非明确要求运行
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	client := etcdclient.CreateEtcdClientForTest(t)
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	serviceName := fmt.Sprintf("answer-policy-service-%d", time.Now().UnixNano())
+	ids := []string{"policy-001", "policy-002", "policy-003"}
+	defer func() {
+		for _, id := range ids {
+			_ = client.DeregisterService(context.Background(), serviceName, id)
+		}
+		_ = client.DeleteServiceAnswerPolicy(context.Background(), serviceName)
+	}()
+	for i, id := range ids {
+		require.NoError(t, client.RegisterService(ctx, &etcdclient.ServiceInstance{
+			ServiceName: serviceName, InstanceID: id, IPAddress: fmt.Sprintf("10.0.40.%d", i+1), Port: 8080, TTL: 60}))
+	}
+	_, err := client.SetInstanceHealth(ctx, serviceName, "policy-003", etcdclient.HealthDraining)
+	require.NoError(t, err)
+
+	server := NewDNSServer(&config.Config{}, createTestLogger(t)).(*DNSServer)
+	server.SetEtcdClient(client)
+
+	query := func(qtype uint16) *dns.Msg {
+		r := new(dns.Msg)
+		r.SetQuestion(serviceName+".default.svc.cluster.local.", qtype)
+		w := &recordingWriter{}
+		server.handleDNSRequest(w, r)
+		require.NotNil(t, w.msg)
+		return w.msg
+	}
+	addresses := func(m *dns.Msg) []string {
+		var result []string
+		for _, rr := range m.Answer {
+			result = append(result, rr.(*dns.A).A.String())
+		}
+		return result
+	}
+	setPolicy := func(policy *etcdclient.ServiceAnswerPolicy) {
+		policy.ServiceName = serviceName
+		require.NoError(t, client.PutServiceAnswerPolicy(ctx, policy))
+		server.applyServiceAnswerPolicies()
+	}
+
+	// 未设置策略时返回一个实例的地址，摘流中的实例不出现在应答中
+	require.Len(t, query(dns.TypeA).Answer, 1)
+	assert.Len(t, query(dns.TypeSRV).Answer, 2)
+
+	// all返回所有可路由实例的地址
+	setPolicy(&etcdclient.ServiceAnswerPolicy{AddressAnswers: etcdclient.AddressAnswersAll})
+	assert.ElementsMatch(t, []string{"10.0.40.1", "10.0.40.2"}, addresses(query(dns.TypeA)))
+
+	// 包括摘流中的实例
+	setPolicy(&etcdclient.ServiceAnswerPolicy{AddressAnswers: etcdclient.AddressAnswersAll, IncludeDraining: true})
+	assert.ElementsMatch(t, []string{"10.0.40.1", "10.0.40.2", "10.0.40.3"}, addresses(query(dns.TypeA)))
+	assert.Len(t, query(dns.TypeSRV).Answer, 3)
+
+	// sticky时同一客户端总是得到同一个地址和相同的SRV顺序
+	setPolicy(&etcdclient.ServiceAnswerPolicy{Order: etcdclient.AnswerOrderSticky})
+	preferred := addresses(query(dns.TypeA))
+	require.Len(t, preferred, 1)
+	srv := query(dns.TypeSRV).Answer
+	for i := 0; i < 5; i++ {
+		assert.Equal(t, preferred, addresses(query(dns.TypeA)))
+		assert.Equal(t, srv, query(dns.TypeSRV).Answer)
+	}
+
+	// 删除策略后恢复默认的应答方式
+	require.NoError(t, client.DeleteServiceAnswerPolicy(ctx, serviceName))
+	server.applyServiceAnswerPolicies()
+	require.Len(t, query(dns.TypeA).Answer, 1)
+	assert.Len(t, query(dns.TypeSRV).Answer, 2)
+}
@@ -0,0 +1,139 @@
+package dnsserver
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/hewenyu/kong-discovery/internal/config"
+	"github.com/miekg/dns"
+	"go.uber.org/zap"
+)
+
+// 未配置时捕获队列的长度，队列满时丢弃新的记录，不阻塞查询
+const defaultCaptureBuffer = 4096
+
+// CapturedQuery 捕获文件中的一条查询记录，每行一个JSON对象，可由cmd/replay按原有节奏重放
+type CapturedQuery struct {
+	Time       time.Time `json:"time"`             // 收到查询的时间
+	Client     string    `json:"client,omitempty"` // 客户端地址
+	Protocol   string    `json:"protocol"`         // 传输协议：udp 或 tcp
+	Name       string    `json:"name"`             // 查询域名
+	Type       string    `json:"type"`             // 查询类型
+	Rcode      string    `json:"rcode"`            // 应答的响应码
+	DurationMs float64   `json:"duration_ms"`      // 处理耗时（毫秒）
+}
+
+// queryCapture 把查询异步写入捕获文件
+type queryCapture struct {
+	records     chan *CapturedQuery
+	done        chan struct{}
+	file        *os.File
+	sampleRatio float64
+	dropped     atomic.Uint64
+	logger      config.Logger
+}
+
+// openQueryCapture 打开（追加写入）捕获文件并启动写入协程
+func openQueryCapture(cfg *config.Config, logger config.Logger) (*queryCapture, error) {
+	f, err := os.OpenFile(cfg.DNS.Capture.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("打开查询捕获文件失败: %w", err)
+	}
+
+	buffer := cfg.DNS.Capture.Buffer
+	if buffer <= 0 {
+		buffer = defaultCaptureBuffer
+	}
+	sampleRatio := cfg.DNS.Capture.SampleRatio
+	if sampleRatio <= 0 || sampleRatio > 1 {
+		sampleRatio = 1
+	}
+
+	c := &queryCapture{
+		records:     make(chan *CapturedQuery, buffer),
+		done:        make(chan struct{}),
+		file:        f,
+		sampleRatio: sampleRatio,
+		logger:      logger,
+	}
+	go c.run()
+	return c, nil
+}
+
+// record 按采样比例把查询放入写入队列，队列满时丢弃
+func (c *queryCapture) record(w dns.ResponseWriter, r, m *dns.Msg, start time.Time) {
+	if c == nil || len(r.Question) == 0 {
+		return
+	}
+	if c.sampleRatio < 1 && rand.Float64() >= c.sampleRatio {
+		return
+	}
+
+	q := &CapturedQuery{
+		Time:       start,
+		Client:     addrString(w.RemoteAddr()),
+		Protocol:   "udp",
+		Name:       r.Question[0].Name,
+		Type:       dns.TypeToString[r.Question[0].Qtype],
+		Rcode:      dns.RcodeToString[m.Rcode],
+		DurationMs: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if _, ok := w.RemoteAddr().(*net.TCPAddr); ok {
+		q.Protocol = "tcp"
+	}
+
+	select {
+	case c.records <- q:
+	default:
+		c.dropped.Add(1)
+	}
+}
+
+// run 把队列中的记录写入文件，队列关闭后刷新缓冲区
+func (c *queryCapture) run() {
+	defer close(c.done)
+
+	buf := bufio.NewWriter(c.file)
+	enc := json.NewEncoder(buf)
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case q, ok := <-c.records:
+			if !ok {
+				if err := buf.Flush(); err != nil {
+					c.logger.Warn("写入查询捕获文件失败", zap.Error(err))
+				}
+				return
+			}
+			if err := enc.Encode(q); err != nil {
+				c.logger.Warn("写入查询捕获文件失败", zap.Error(err))
+			}
+		case <-ticker.C:
+			// 定期刷新，捕获过程中也能读取到最近的查询
+			if err := buf.Flush(); err != nil {
+				c.logger.Warn("写入查询捕获文件失败", zap.Error(err))
+			}
+		}
+	}
+}
+
+// close 写入队列中剩余的记录并关闭文件
+func (c *queryCapture) close() error {
+	if c == nil {
+		return nil
+	}
+	close(c.records)
+	<-c.done
+	if dropped := c.dropped.Load(); dropped > 0 {
+		c.logger.Warn("查询捕获队列已满，部分查询未记录", zap.Uint64("dropped", dropped))
+	}
+	return c.file.Close()
+}
@@ -0,0 +1,49 @@
+package dnsserver
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hewenyu/kong-discovery/internal/config"
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDNSServer_QueryCapture(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.DNS.Capture.Path = filepath.Join(t.TempDir(), "capture.jsonl")
+	server := NewDNSServer(cfg, createTestLogger(t)).(*DNSServer)
+	capture, err := openQueryCapture(cfg, server.logger)
+	require.NoError(t, err)
+	server.capture = capture
+
+	for _, name := range []string{"test.local.", "Test.Local."} {
+		r := new(dns.Msg)
+		r.SetQuestion(name, dns.TypeA)
+		server.handleDNSRequest(&recordingWriter{}, r)
+	}
+	require.NoError(t, capture.close())
+
+	// 每个查询一行，保留查询时的写法
+	f, err := os.Open(cfg.DNS.Capture.Path)
+	require.NoError(t, err)
+	defer f.Close()
+	var queries []CapturedQuery
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var q CapturedQuery
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &q))
+		queries = append(queries, q)
+	}
+	require.Len(t, queries, 2)
+	assert.Equal(t, "test.local.", queries[0].Name)
+	assert.Equal(t, "Test.Local.", queries[1].Name)
+	assert.Equal(t, "A", queries[0].Type)
+	assert.Equal(t, "NOERROR", queries[0].Rcode)
+	assert.Equal(t, "udp", queries[0].Protocol)
+	assert.Equal(t, "10.1.2.3:53000", queries[0].Client)
+}
@@ -0,0 +1,26 @@
+package dnsserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// injectedTimeout 查询没有截止时间时模拟超时等待的时间，与miekg/dns默认的读取超时一致
+const injectedTimeout = 2 * time.Second
+
+// injectedUpstreamTimeout 模拟一次上游DNS查询超时：等到查询的截止时间（或默认的读取超时）后返回超时错误，
+// ctx被取消时立即返回。超时按普通的上游失败计入健康统计，可以验证熔断和切换上游的行为
+func injectedUpstreamTimeout(ctx context.Context) error {
+	timer := time.NewTimer(injectedTimeout)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+		if errors.Is(ctx.Err(), context.Canceled) {
+			return ctx.Err()
+		}
+	}
+	return fmt.Errorf("上游DNS查询超时（故障注入）: %w", context.DeadlineExceeded)
+}
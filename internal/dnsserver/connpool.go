@@ -0,0 +1,141 @@
+package dnsserver
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// 未配置时空闲连接的最长保留时间
+const defaultConnIdleTimeout = 30 * time.Second
+
+// pooledConn 连接池中的空闲连接
+type pooledConn struct {
+	*dns.Conn
+	idleSince time.Time
+}
+
+// upstreamConns 按上游地址复用的连接池，由默认上游和所有条件转发规则共享，
+// 同一时刻每个连接只承载一个查询，并发查询使用不同的连接
+type upstreamConns struct {
+	mu          sync.Mutex
+	idle        map[string][]*pooledConn
+	maxIdle     int // 每个上游最多保留的空闲连接数，0表示不复用连接
+	idleTimeout time.Duration
+	closed      bool
+}
+
+// newUpstreamConns 创建上游连接池
+func newUpstreamConns(maxIdle int, idleTimeout time.Duration) *upstreamConns {
+	if idleTimeout <= 0 {
+		idleTimeout = defaultConnIdleTimeout
+	}
+	return &upstreamConns{
+		idle:        make(map[string][]*pooledConn),
+		maxIdle:     maxIdle,
+		idleTimeout: idleTimeout,
+	}
+}
+
+// exchange 使用池中的连接向上游发送查询，没有可用的空闲连接时新建连接。
+// 查询成功后连接放回池中；失败的连接可能还会收到迟到的应答，直接关闭
+func (p *upstreamConns) exchange(ctx context.Context, c *dns.Client, req *dns.Msg, addr string) (*dns.Msg, error) {
+	conn := p.get(addr, time.Now())
+	if conn == nil {
+		var err error
+		if conn, err = dialUpstream(ctx, c, addr); err != nil {
+			return nil, err
+		}
+	}
+
+	// miekg/dns只遵守ctx的截止时间，取消时关闭连接使读取立即返回
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	resp, _, err := c.ExchangeWithConnContext(ctx, req, conn)
+	if !stop() || err != nil {
+		conn.Close()
+		if err == nil {
+			err = ctx.Err()
+		}
+		return nil, err
+	}
+
+	p.put(addr, conn, time.Now())
+	return resp, nil
+}
+
+// get 取出一个未过期的空闲连接，过期的连接直接关闭
+func (p *upstreamConns) get(addr string, now time.Time) *dns.Conn {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	conns := p.idle[addr]
+	for len(conns) > 0 {
+		// 优先使用最近放回的连接，较早的连接更可能已过期
+		pc := conns[len(conns)-1]
+		conns = conns[:len(conns)-1]
+		if now.Sub(pc.idleSince) < p.idleTimeout {
+			p.idle[addr] = conns
+			return pc.Conn
+		}
+		pc.Close()
+	}
+	delete(p.idle, addr)
+	return nil
+}
+
+// put 把连接放回池中，池已满或已关闭时关闭连接
+func (p *upstreamConns) put(addr string, conn *dns.Conn, now time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed || len(p.idle[addr]) >= p.maxIdle {
+		conn.Close()
+		return
+	}
+	p.idle[addr] = append(p.idle[addr], &pooledConn{Conn: conn, idleSince: now})
+}
+
+// prune 关闭空闲超过idleTimeout的连接
+func (p *upstreamConns) prune(now time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for addr, conns := range p.idle {
+		kept := conns[:0]
+		for _, pc := range conns {
+			if now.Sub(pc.idleSince) < p.idleTimeout {
+				kept = append(kept, pc)
+				continue
+			}
+			pc.Close()
+		}
+		if len(kept) == 0 {
+			delete(p.idle, addr)
+			continue
+		}
+		p.idle[addr] = kept
+	}
+}
+
+// idleCount 返回上游当前的空闲连接数
+func (p *upstreamConns) idleCount(addr string) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.idle[addr])
+}
+
+// close 关闭所有空闲连接，之后放回的连接也会被关闭
+func (p *upstreamConns) close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.closed = true
+	for _, conns := range p.idle {
+		for _, pc := range conns {
+			pc.Close()
+		}
+	}
+	p.idle = make(map[string][]*pooledConn)
+}
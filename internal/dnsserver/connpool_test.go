@@ -0,0 +1,67 @@
+package dnsserver
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpstreamConns_Reuse(t *testing.T) {
+	addr := startFakeUpstream(t, "10.0.0.1", 0)
+	conns := newUpstreamConns(2, time.Minute)
+	defer conns.close()
+	c := new(dns.Client)
+
+	query := func() {
+		req := new(dns.Msg)
+		req.SetQuestion("pool.example.com.", dns.TypeA)
+		resp, err := conns.exchange(context.Background(), c, req, addr)
+		require.NoError(t, err)
+		require.Len(t, resp.Answer, 1)
+	}
+
+	// 查询成功后连接放回池中，下一次查询复用同一个连接
+	query()
+	require.Equal(t, 1, conns.idleCount(addr))
+	local := conns.idle[addr][0].LocalAddr().String()
+	query()
+	require.Equal(t, 1, conns.idleCount(addr))
+	assert.Equal(t, local, conns.idle[addr][0].LocalAddr().String())
+
+	// 空闲超时后连接被关闭
+	conns.prune(time.Now().Add(2 * time.Minute))
+	assert.Equal(t, 0, conns.idleCount(addr))
+}
+
+func TestUpstreamConns_Disabled(t *testing.T) {
+	addr := startFakeUpstream(t, "10.0.0.1", 0)
+	conns := newUpstreamConns(0, 0)
+
+	// 不复用连接时查询后立即关闭
+	req := new(dns.Msg)
+	req.SetQuestion("pool.example.com.", dns.TypeA)
+	_, err := conns.exchange(context.Background(), new(dns.Client), req, addr)
+	require.NoError(t, err)
+	assert.Equal(t, 0, conns.idleCount(addr))
+}
+
+func TestUpstreamConns_Cancel(t *testing.T) {
+	addr := startFakeUpstream(t, "10.0.0.1", time.Second)
+	conns := newUpstreamConns(2, time.Minute)
+	defer conns.close()
+
+	// 取消查询时立即返回，连接不放回池中
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	req := new(dns.Msg)
+	req.SetQuestion("slow.example.com.", dns.TypeA)
+	start := time.Now()
+	_, err := conns.exchange(ctx, new(dns.Client), req, addr)
+	require.Error(t, err)
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+	assert.Equal(t, 0, conns.idleCount(addr))
+}
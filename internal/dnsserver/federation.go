@@ -0,0 +1,59 @@
+package dnsserver
+
+import (
+	"context"
+	"strings"
+
+	"github.com/hewenyu/kong-discovery/internal/etcdclient"
+	"go.uber.org/zap"
+)
+
+// 集群名称与本地命名空间同名时的优先级
+const (
+	conflictLocal  = "local"  // 本地命名空间优先
+	conflictRemote = "remote" // 远程集群优先
+)
+
+// federatedCluster 判断服务域名是否指向联邦中的远程集群，是则返回集群名称
+// 只有 <服务>.<集群>.svc.cluster.local 形式且集群配置在federation.peers中的域名才会从远程目录应答；
+// 集群名称同时也是本地命名空间时按federation.conflict决定，默认本地优先
+func (s *DNSServer) federatedCluster(ctx context.Context, domain string, trace *ResolveTrace) string {
+	federation := s.cfg.Federation
+	if !federation.Enabled {
+		return ""
+	}
+
+	_, _, domain = etcdclient.ParseServiceDomain(domain)
+	labels := strings.Split(strings.TrimSuffix(domain, serviceDomainSuffix), ".")
+	if len(labels) != 2 {
+		return ""
+	}
+	cluster := labels[1]
+
+	known := false
+	for _, peer := range federation.Peers {
+		if peer.Name == cluster {
+			known = true
+			break
+		}
+	}
+	if !known {
+		return ""
+	}
+
+	if federation.Conflict == conflictRemote {
+		return cluster
+	}
+
+	namespace, err := s.etcdClient.GetNamespace(ctx, cluster)
+	if err != nil {
+		// 无法确认是否冲突时仍按远程集群应答，避免etcd抖动时联邦域名解析失败
+		s.log(ctx).Debug("检查命名空间失败", zap.String("namespace", cluster), zap.Error(err))
+		return cluster
+	}
+	if namespace != nil {
+		trace.add("federation", "集群 %s 与本地命名空间同名，按本地服务应答", cluster)
+		return ""
+	}
+	return cluster
+}
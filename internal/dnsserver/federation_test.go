@@ -0,0 +1,91 @@
+package dnsserver
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hewenyu/kong-discovery/internal/config"
+	"github.com/hewenyu/kong-discovery/internal/etcdclient"
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDNSServer_FederatedServiceQuery(t *testing.T) {
+	// 跳过集成测试，除非明确要求运行
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	client := etcdclient.CreateEtcdClientForTest(t)
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	suffix := time.Now().UnixNano()
+	cluster := fmt.Sprintf("dns-peer-%d", suffix)
+	serviceName := fmt.Sprintf("federated-service-%d", suffix)
+	defer func() {
+		_, _ = client.DeleteFederatedCluster(context.Background(), cluster)
+		_ = client.DeregisterService(context.Background(), serviceName, "local-001")
+		_ = client.DeleteNamespace(context.Background(), cluster, false)
+	}()
+
+	_, err := client.ApplyCatalog(ctx, &etcdclient.Catalog{
+		Cluster:     cluster,
+		GeneratedAt: time.Now(),
+		Services: map[string][]*etcdclient.ServiceInstance{
+			serviceName: {{ServiceName: serviceName, InstanceID: "remote-001", IPAddress: "192.168.10.1", Port: 9000}},
+		},
+	}, etcdclient.FederationPush, "")
+	require.NoError(t, err)
+
+	cfg := &config.Config{}
+	cfg.Federation.Enabled = true
+	cfg.Federation.Peers = []config.FederationPeer{{Name: cluster, Mode: etcdclient.FederationPush}}
+	server := NewDNSServer(cfg, createTestLogger(t)).(*DNSServer)
+	server.SetEtcdClient(client)
+
+	domain := serviceName + "." + cluster + ".svc.cluster.local."
+	query := func(qtype uint16) *dns.Msg {
+		r := new(dns.Msg)
+		r.SetQuestion(domain, qtype)
+		w := &recordingWriter{}
+		server.handleDNSRequest(w, r)
+		require.NotNil(t, w.msg)
+		return w.msg
+	}
+
+	// 集群标签指向远程集群时返回远程实例
+	m := query(dns.TypeA)
+	require.Len(t, m.Answer, 1)
+	assert.Equal(t, "192.168.10.1", m.Answer[0].(*dns.A).A.String())
+
+	m = query(dns.TypeSRV)
+	require.Len(t, m.Answer, 1)
+	assert.Equal(t, uint16(9000), m.Answer[0].(*dns.SRV).Port)
+	require.Len(t, m.Extra, 1)
+	assert.Equal(t, "192.168.10.1", m.Extra[0].(*dns.A).A.String())
+
+	// 集群名称与本地命名空间同名时默认本地优先
+	require.NoError(t, client.CreateNamespace(ctx, &etcdclient.Namespace{Name: cluster}))
+	require.NoError(t, client.RegisterService(ctx, &etcdclient.ServiceInstance{ServiceName: serviceName, InstanceID: "local-001", IPAddress: "10.0.10.1", Port: 8080, TTL: 60, Namespace: cluster}))
+	m = query(dns.TypeA)
+	require.Len(t, m.Answer, 1)
+	assert.Equal(t, "10.0.10.1", m.Answer[0].(*dns.A).A.String())
+
+	// conflict为remote时远程集群优先
+	cfg.Federation.Conflict = "remote"
+	m = query(dns.TypeA)
+	require.Len(t, m.Answer, 1)
+	assert.Equal(t, "192.168.10.1", m.Answer[0].(*dns.A).A.String())
+
+	// 未启用联邦时按本地服务应答
+	cfg.Federation.Enabled = false
+	m = query(dns.TypeA)
+	require.Len(t, m.Answer, 1)
+	assert.Equal(t, "10.0.10.1", m.Answer[0].(*dns.A).A.String())
+}
@@ -0,0 +1,134 @@
+package dnsserver
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hewenyu/kong-discovery/internal/config"
+	"github.com/hewenyu/kong-discovery/internal/etcdclient"
+)
+
+// forwardRule 一条条件转发规则及其独立的上游池
+type forwardRule struct {
+	suffix string
+	pool   *upstreamPool
+}
+
+// forwardRuleSet 按域名后缀匹配的条件转发规则集合
+type forwardRuleSet struct {
+	mu               sync.RWMutex
+	rules            []*forwardRule // 按后缀长度从长到短排序
+	failureThreshold int
+	cooldown         time.Duration
+	disabled         *disabledUpstreams
+}
+
+// newForwardRuleSet 创建条件转发规则集合，disabled为被手动停用的上游，可以为nil
+func newForwardRuleSet(failureThreshold int, cooldown time.Duration, disabled *disabledUpstreams) *forwardRuleSet {
+	return &forwardRuleSet{
+		failureThreshold: failureThreshold,
+		cooldown:         cooldown,
+		disabled:         disabled,
+	}
+}
+
+// staticForwardRules 将配置文件中的转发规则转换为etcd规则模型
+func staticForwardRules(cfg *config.Config) []*etcdclient.ForwardRule {
+	rules := make([]*etcdclient.ForwardRule, 0, len(cfg.DNS.ForwardRules))
+	for _, r := range cfg.DNS.ForwardRules {
+		rules = append(rules, &etcdclient.ForwardRule{
+			Suffix:  r.Suffix,
+			Servers: r.Servers,
+			Mode:    r.Mode,
+		})
+	}
+	return rules
+}
+
+// setRules 替换转发规则，已存在后缀的上游池保留健康统计
+func (f *forwardRuleSet) setRules(rules []*etcdclient.ForwardRule) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	existing := make(map[string]*forwardRule, len(f.rules))
+	for _, r := range f.rules {
+		existing[r.suffix] = r
+	}
+
+	// 后出现的同后缀规则覆盖先出现的
+	bySuffix := make(map[string]*etcdclient.ForwardRule, len(rules))
+	for _, r := range rules {
+		suffix := etcdclient.NormalizeForwardSuffix(r.Suffix)
+		if suffix == "" || len(r.Servers) == 0 {
+			continue
+		}
+		bySuffix[suffix] = r
+	}
+
+	result := make([]*forwardRule, 0, len(bySuffix))
+	for suffix, r := range bySuffix {
+		rule, ok := existing[suffix]
+		if ok {
+			rule.pool.setAddresses(r.Servers)
+		} else {
+			rule = &forwardRule{
+				suffix: suffix,
+				pool:   newUpstreamPool(r.Servers, f.failureThreshold, f.cooldown, f.disabled),
+			}
+			rule.pool.rule = suffix
+		}
+		rule.pool.setMode(r.Mode, 0)
+		result = append(result, rule)
+	}
+
+	// 最长后缀优先匹配
+	sort.Slice(result, func(i, j int) bool {
+		if len(result[i].suffix) != len(result[j].suffix) {
+			return len(result[i].suffix) > len(result[j].suffix)
+		}
+		return result[i].suffix < result[j].suffix
+	})
+
+	f.rules = result
+}
+
+// match 返回与域名匹配的规则上游池，没有匹配时返回nil
+func (f *forwardRuleSet) match(domain string) *upstreamPool {
+	domain = strings.TrimSuffix(strings.ToLower(domain), ".")
+
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	for _, r := range f.rules {
+		if domain == r.suffix || strings.HasSuffix(domain, "."+r.suffix) {
+			return r.pool
+		}
+	}
+	return nil
+}
+
+// status 返回所有规则上游的健康状态
+func (f *forwardRuleSet) status() []UpstreamStatus {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	var result []UpstreamStatus
+	for _, r := range f.rules {
+		result = append(result, r.pool.status()...)
+	}
+	return result
+}
+
+// pools 返回所有规则的上游池
+func (f *forwardRuleSet) pools() []*upstreamPool {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	result := make([]*upstreamPool, 0, len(f.rules))
+	for _, r := range f.rules {
+		result = append(result, r.pool)
+	}
+	return result
+}
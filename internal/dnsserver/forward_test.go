@@ -0,0 +1,71 @@
+package dnsserver
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hewenyu/kong-discovery/internal/config"
+	"github.com/hewenyu/kong-discovery/internal/etcdclient"
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestForwardRuleSet_Match(t *testing.T) {
+	rules := newForwardRuleSet(3, time.Second, nil)
+	rules.setRules([]*etcdclient.ForwardRule{
+		{Suffix: "*.corp.example", Servers: []string{"10.1.1.53:53"}},
+		{Suffix: "dev.corp.example.", Servers: []string{"10.1.1.54:53"}},
+		{Suffix: "consul", Servers: []string{"10.2.2.8:8600"}},
+		{Suffix: "empty.example", Servers: nil},
+	})
+
+	// 最长后缀优先
+	pool := rules.match("api.dev.corp.example.")
+	require.NotNil(t, pool)
+	assert.Equal(t, "dev.corp.example", pool.rule)
+
+	pool = rules.match("WWW.Corp.Example.")
+	require.NotNil(t, pool)
+	assert.Equal(t, "corp.example", pool.rule)
+
+	pool = rules.match("web.service.consul.")
+	require.NotNil(t, pool)
+	assert.Equal(t, "10.2.2.8:8600", pool.status()[0].Address)
+
+	// 不匹配后缀的部分字符串
+	assert.Nil(t, rules.match("notconsul."))
+	assert.Nil(t, rules.match("empty.example."), "没有上游的规则应被忽略")
+	assert.Nil(t, rules.match("example.com."))
+}
+
+func TestDNSServer_ConditionalForwarding(t *testing.T) {
+	corp := startFakeUpstream(t, "10.1.1.1", 0)
+	public := startFakeUpstream(t, "8.8.4.4", 0)
+
+	cfg := &config.Config{}
+	cfg.DNS.UpstreamDNS = public
+	cfg.DNS.ForwardRules = []config.ForwardRule{
+		{Suffix: "corp.example", Servers: []string{corp}},
+	}
+	server := NewDNSServer(cfg, createTestLogger(t)).(*DNSServer)
+
+	resolve := func(name string) string {
+		r := new(dns.Msg)
+		r.SetQuestion(name, dns.TypeA)
+		m := new(dns.Msg)
+		m.SetReply(r)
+		require.NoError(t, server.forwardToUpstream(server.selectUpstreamPool(r, nil), r, m))
+		require.Len(t, m.Answer, 1)
+		return m.Answer[0].(*dns.A).A.String()
+	}
+
+	assert.Equal(t, "10.1.1.1", resolve("intranet.corp.example."))
+	assert.Equal(t, "8.8.4.4", resolve("example.com."))
+
+	// 状态中包含规则上游
+	status := server.UpstreamStatus()
+	require.Len(t, status, 2)
+	assert.Equal(t, "", status[0].Rule)
+	assert.Equal(t, "corp.example", status[1].Rule)
+}
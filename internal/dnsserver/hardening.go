@@ -0,0 +1,105 @@
+package dnsserver
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"syscall"
+
+	"github.com/miekg/dns"
+)
+
+// 随机源端口的取值范围，避开系统保留端口
+const (
+	minSourcePort = 1024
+	maxSourcePort = 65535
+)
+
+// 随机源端口被占用时的重试次数，仍然失败时交给系统分配临时端口
+const sourcePortAttempts = 3
+
+// randomUint32 返回密码学安全的随机数，用于端口和大小写随机化，不能被攻击者预测
+func randomUint32() uint32 {
+	var b [4]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return 0
+	}
+	return binary.BigEndian.Uint32(b[:])
+}
+
+// randomizeCase 随机改变域名中每个字母的大小写（DNS 0x20编码），上游按原样返回问题，
+// 伪造应答的攻击者需要同时猜中查询ID、源端口和每个字母的大小写
+func randomizeCase(name string) string {
+	b := []byte(name)
+	var bits uint32
+	for i, c := range b {
+		if i%32 == 0 {
+			bits = randomUint32()
+		}
+		if c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' {
+			if bits&1 == 1 {
+				b[i] = c ^ 0x20
+			}
+		}
+		bits >>= 1
+	}
+	return string(b)
+}
+
+// validateUpstreamResponse 检查上游应答的问题与发出的查询一致，caseSensitive为true时按0x20编码逐字比较名称
+func validateUpstreamResponse(req, resp *dns.Msg, caseSensitive bool) error {
+	if len(resp.Question) != len(req.Question) {
+		return fmt.Errorf("上游DNS应答的问题数与查询不一致")
+	}
+	for i, q := range req.Question {
+		got := resp.Question[i]
+		if got.Qtype != q.Qtype || got.Qclass != q.Qclass {
+			return fmt.Errorf("上游DNS应答的问题类型与查询不一致: %s", dns.TypeToString[got.Qtype])
+		}
+		if (caseSensitive && got.Name != q.Name) || !strings.EqualFold(got.Name, q.Name) {
+			return fmt.Errorf("上游DNS应答的问题名称与查询不一致: %s", got.Name)
+		}
+	}
+	return nil
+}
+
+// restoreCase 把应答中与查询名称相同（忽略大小写）的名称恢复为客户端查询时的写法
+func restoreCase(resp *dns.Msg, name string) {
+	if len(resp.Question) > 0 && strings.EqualFold(resp.Question[0].Name, name) {
+		resp.Question[0].Name = name
+	}
+	for _, section := range [][]dns.RR{resp.Answer, resp.Ns, resp.Extra} {
+		for _, rr := range section {
+			if hdr := rr.Header(); strings.EqualFold(hdr.Name, name) {
+				hdr.Name = name
+			}
+		}
+	}
+}
+
+// randomSourcePort 返回随机的本地UDP端口
+func randomSourcePort() int {
+	return minSourcePort + int(randomUint32()%(maxSourcePort-minSourcePort+1))
+}
+
+// dialUpstream 建立到上游的连接，UDP连接绑定随机源端口；
+// 随机端口被占用时换一个端口重试，多次失败后交给系统分配
+func dialUpstream(ctx context.Context, c *dns.Client, addr string) (*dns.Conn, error) {
+	if c.Net != "" && c.Net != "udp" {
+		return c.DialContext(ctx, addr)
+	}
+
+	client := *c
+	for attempt := 0; attempt < sourcePortAttempts; attempt++ {
+		client.Dialer = &net.Dialer{LocalAddr: &net.UDPAddr{Port: randomSourcePort()}}
+		conn, err := client.DialContext(ctx, addr)
+		if !errors.Is(err, syscall.EADDRINUSE) {
+			return conn, err
+		}
+	}
+	return c.DialContext(ctx, addr)
+}
@@ -0,0 +1,98 @@
+package dnsserver
+
+import (
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/hewenyu/kong-discovery/internal/config"
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRandomizeCase(t *testing.T) {
+	name := "www.example-0x20.com."
+	changed := false
+	for i := 0; i < 10; i++ {
+		got := randomizeCase(name)
+		assert.True(t, strings.EqualFold(name, got))
+		if got != name {
+			changed = true
+		}
+	}
+	// 连续10次都没有改变任何字母的概率可以忽略
+	assert.True(t, changed)
+	assert.Equal(t, ".", randomizeCase("."))
+}
+
+func TestValidateUpstreamResponse(t *testing.T) {
+	req := new(dns.Msg)
+	req.SetQuestion("ExAmPle.CoM.", dns.TypeA)
+
+	resp := new(dns.Msg)
+	resp.SetReply(req)
+	assert.NoError(t, validateUpstreamResponse(req, resp, true))
+
+	// 大小写被改写：开启0x20时拒绝，未开启时接受
+	resp.Question[0].Name = "example.com."
+	assert.Error(t, validateUpstreamResponse(req, resp, true))
+	assert.NoError(t, validateUpstreamResponse(req, resp, false))
+
+	// 名称、类型或问题数不一致时拒绝
+	resp.Question[0] = dns.Question{Name: "evil.com.", Qtype: dns.TypeA, Qclass: dns.ClassINET}
+	assert.Error(t, validateUpstreamResponse(req, resp, false))
+	resp.Question[0] = dns.Question{Name: "example.com.", Qtype: dns.TypeAAAA, Qclass: dns.ClassINET}
+	assert.Error(t, validateUpstreamResponse(req, resp, false))
+	resp.Question = nil
+	assert.Error(t, validateUpstreamResponse(req, resp, false))
+}
+
+// startSpoofingUpstream 启动一个把问题名称改写为小写后应答的上游，模拟不知道0x20编码的伪造应答
+func startSpoofingUpstream(t *testing.T) string {
+	t.Helper()
+
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	server := &dns.Server{
+		PacketConn: pc,
+		Handler: dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
+			m := new(dns.Msg)
+			m.SetReply(r)
+			m.Question[0].Name = strings.ToLower(m.Question[0].Name)
+			rr, _ := dns.NewRR(m.Question[0].Name + " 60 IN A 6.6.6.6")
+			m.Answer = append(m.Answer, rr)
+			_ = w.WriteMsg(m)
+		}),
+	}
+	go func() { _ = server.ActivateAndServe() }()
+	t.Cleanup(func() { _ = server.Shutdown() })
+
+	return pc.LocalAddr().String()
+}
+
+func TestDNSServer_ForwardCaseRandomization(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.DNS.Upstream.Servers = []string{startFakeUpstream(t, "10.0.0.9", 0)}
+	cfg.DNS.Upstream.CaseRandomization = true
+	server := NewDNSServer(cfg, createTestLogger(t)).(*DNSServer)
+
+	// 正常上游原样返回问题，应答中的名称恢复为客户端的写法
+	r := new(dns.Msg)
+	r.SetQuestion("Forward.Example.com.", dns.TypeA)
+	m := new(dns.Msg)
+	m.SetReply(r)
+	require.NoError(t, server.forwardToUpstream(server.upstreams, r, m))
+	require.Len(t, m.Answer, 1)
+	assert.Equal(t, "Forward.Example.com.", m.Answer[0].Header().Name)
+	assert.Equal(t, "10.0.0.9", m.Answer[0].(*dns.A).A.String())
+
+	// 问题名称大小写不一致的应答被拒绝
+	cfg.DNS.Upstream.Servers = []string{startSpoofingUpstream(t)}
+	server = NewDNSServer(cfg, createTestLogger(t)).(*DNSServer)
+	m = new(dns.Msg)
+	m.SetReply(r)
+	assert.Error(t, server.forwardToUpstream(server.upstreams, r, m))
+	assert.Empty(t, m.Answer)
+}
@@ -0,0 +1,112 @@
+package dnsserver
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/hewenyu/kong-discovery/internal/etcdclient"
+	"github.com/miekg/dns"
+	"go.uber.org/zap"
+)
+
+// Kong兼容模式
+//
+// Kong网关把kong-discovery作为dns_resolver时，按dns_order（默认LAST,SRV,A,CNAME）依次查询上游主机名，
+// 负载均衡器把应答中的每条记录作为一个目标：
+//   - SRV应答只使用优先级数值最小的一组记录，按权重分配流量，每个目标名称再单独解析A记录，不使用附加段
+//   - A应答中的每个地址都是一个目标
+//   - 记录的TTL到期后才重新解析，TTL决定实例变化多久后生效
+//
+// 兼容模式据此调整应答：服务的A/AAAA应答返回所有实例地址，SRV目标名称可以解析，权重为0的SRV记录按1返回
+// （Kong不接受权重为0的目标），服务应答的TTL不超过max_ttl，本地存在但没有所查类型的域名返回NODATA，
+// 使Kong立即按dns_order查询下一种类型，而不是等待上游返回NXDOMAIN
+
+// kongCompat 判断是否启用了Kong兼容模式
+func (s *DNSServer) kongCompat() bool {
+	return s.cfg.DNS.KongCompat.Enabled
+}
+
+// serviceTTL 返回服务应答使用的TTL，policies为服务域名适用的TTL策略，Kong兼容模式下不超过max_ttl
+func (s *DNSServer) serviceTTL(ttl int, policies ...*etcdclient.TTLPolicy) int {
+	ttl = s.recordTTL(ttl, policies...)
+	if s.kongCompat() && s.cfg.DNS.KongCompat.MaxTTL > 0 && ttl > s.cfg.DNS.KongCompat.MaxTTL {
+		return s.cfg.DNS.KongCompat.MaxTTL
+	}
+	return ttl
+}
+
+// kongAddresses 返回服务所有实例中指定类型（A或AAAA）的地址，去重并排序
+func kongAddresses(records map[string]*etcdclient.DNSRecord, recordType string) []string {
+	seen := make(map[string]bool)
+	addrs := []string{}
+	for key, record := range records {
+		if !strings.HasPrefix(key, "SRV-") || seen[record.Address] {
+			continue
+		}
+		if etcdclient.AddressRecordType(record.Address) == recordType {
+			seen[record.Address] = true
+			addrs = append(addrs, record.Address)
+		}
+	}
+	sort.Strings(addrs)
+	return addrs
+}
+
+// kongSRV 调整SRV记录：权重为0时改为1
+func kongSRV(srv *dns.SRV) {
+	if srv.Weight == 0 {
+		srv.Weight = 1
+	}
+}
+
+// handleInstanceTarget 解析SRV目标名称 <实例ID>.<服务域名> 的A/AAAA查询
+// 通过对服务域名的SRV查询找到目标，服务的解析ACL和联邦规则同样适用
+func (s *DNSServer) handleInstanceTarget(ctx context.Context, domain string, qtype uint16, client queryClient, m *dns.Msg, trace *ResolveTrace) bool {
+	_, parent, ok := strings.Cut(domain, ".")
+	if !ok || !strings.HasSuffix(parent, serviceDomainSuffix) {
+		return false
+	}
+	trace.add("kong", "按SRV目标名称解析，查询服务域名 %s", parent)
+
+	srvMsg := new(dns.Msg)
+	if !s.handleServiceQuery(ctx, parent, dns.TypeSRV, client, srvMsg, trace) {
+		return false
+	}
+	if srvMsg.Rcode != dns.RcodeSuccess {
+		m.Rcode = srvMsg.Rcode
+		return true
+	}
+
+	target := dns.Fqdn(domain)
+	found := false
+	for _, rr := range srvMsg.Extra {
+		if !strings.EqualFold(rr.Header().Name, target) {
+			continue
+		}
+		found = true
+		if rr.Header().Rrtype == qtype {
+			rr.Header().Name = target
+			m.Answer = append(m.Answer, rr)
+		}
+	}
+	if !found {
+		return false
+	}
+	s.log(ctx).Debug("解析SRV目标名称", zap.String("domain", domain), zap.Int("answers", len(m.Answer)))
+	return true
+}
+
+// kongNameExists 判断域名在本地是否有其他类型的记录，有则以NODATA应答所查类型
+func (s *DNSServer) kongNameExists(ctx context.Context, view, domain, recordType string, trace *ResolveTrace) bool {
+	for _, other := range []string{"A", "AAAA", "CNAME", "TXT", "SRV"} {
+		if other == recordType {
+			continue
+		}
+		if record, err := s.etcdClient.GetDNSRecordInView(ctx, view, domain, other); err == nil && record != nil {
+			trace.add("kong", "域名存在%s记录，返回NODATA", other)
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,191 @@
+package dnsserver
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/hewenyu/kong-discovery/internal/config"
+	"github.com/hewenyu/kong-discovery/internal/etcdclient"
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// kongTarget Kong负载均衡器中的一个目标
+type kongTarget struct {
+	IP     string
+	Port   int
+	Weight int
+	TTL    uint32
+}
+
+// kongResolve 按Kong的方式解析上游主机名：依次查询SRV和A，SRV应答只使用优先级最小的一组记录，
+// 每个目标名称单独查询A记录，不使用附加段；A应答中的每个地址都是一个权重为10的目标
+func kongResolve(t *testing.T, server *DNSServer, name string, port int) []kongTarget {
+	t.Helper()
+	query := func(name string, qtype uint16) *dns.Msg {
+		r := new(dns.Msg)
+		r.SetQuestion(dns.Fqdn(name), qtype)
+		w := &recordingWriter{}
+		server.handleDNSRequest(w, r)
+		require.NotNil(t, w.msg)
+		return w.msg
+	}
+
+	var targets []kongTarget
+	srvResp := query(name, dns.TypeSRV)
+	if srvResp.Rcode == dns.RcodeSuccess && len(srvResp.Answer) > 0 {
+		lowest := -1
+		for _, rr := range srvResp.Answer {
+			if srv, ok := rr.(*dns.SRV); ok && (lowest < 0 || int(srv.Priority) < lowest) {
+				lowest = int(srv.Priority)
+			}
+		}
+		for _, rr := range srvResp.Answer {
+			srv, ok := rr.(*dns.SRV)
+			if !ok || int(srv.Priority) != lowest {
+				continue
+			}
+			aResp := query(srv.Target, dns.TypeA)
+			require.Equal(t, dns.RcodeSuccess, aResp.Rcode, "SRV目标 %s 无法解析", srv.Target)
+			for _, arr := range aResp.Answer {
+				if a, ok := arr.(*dns.A); ok {
+					targets = append(targets, kongTarget{IP: a.A.String(), Port: int(srv.Port), Weight: int(srv.Weight), TTL: a.Hdr.Ttl})
+				}
+			}
+		}
+	} else {
+		require.Contains(t, []int{dns.RcodeSuccess, dns.RcodeNameError}, srvResp.Rcode)
+		for _, rr := range query(name, dns.TypeA).Answer {
+			if a, ok := rr.(*dns.A); ok {
+				targets = append(targets, kongTarget{IP: a.A.String(), Port: port, Weight: 10, TTL: a.Hdr.Ttl})
+			}
+		}
+	}
+
+	sort.Slice(targets, func(i, j int) bool { return targets[i].IP < targets[j].IP })
+	return targets
+}
+
+func TestDNSServer_KongCompat(t *testing.T) {
+	// 跳过集成测试，除非明确要求运行
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	client := etcdclient.CreateEtcdClientForTest(t)
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	suffix := time.Now().UnixNano()
+	serviceName := fmt.Sprintf("kong-upstream-%d", suffix)
+	legacy := fmt.Sprintf("legacy-%d.kong.local", suffix)
+	instances := []*etcdclient.ServiceInstance{
+		{ServiceName: serviceName, InstanceID: "a", IPAddress: "10.0.20.1", Port: 8080, TTL: 60, DNSTTL: 60,
+			Metadata: map[string]string{etcdclient.MetadataSRVWeight: "0"}},
+		{ServiceName: serviceName, InstanceID: "b", IPAddress: "10.0.20.2", Port: 8081, TTL: 60, DNSTTL: 60,
+			Metadata: map[string]string{etcdclient.MetadataSRVWeight: "30"}},
+		{ServiceName: serviceName, InstanceID: "backup", IPAddress: "10.0.20.3", Port: 8082, TTL: 60, DNSTTL: 60,
+			Metadata: map[string]string{etcdclient.MetadataSRVPriority: "20"}},
+	}
+	for _, instance := range instances {
+		require.NoError(t, client.RegisterService(ctx, instance))
+	}
+	require.NoError(t, client.PutDNSRecord(ctx, legacy, &etcdclient.DNSRecord{Type: "A", Value: "10.0.30.1", TTL: 30}))
+	defer func() {
+		for _, instance := range instances {
+			_ = client.DeregisterService(context.Background(), serviceName, instance.InstanceID)
+		}
+		_ = client.DeleteDNSRecord(context.Background(), legacy, "A", "")
+	}()
+
+	cfg := &config.Config{}
+	cfg.DNS.KongCompat.Enabled = true
+	cfg.DNS.KongCompat.MaxTTL = 10
+	server := NewDNSServer(cfg, createTestLogger(t)).(*DNSServer)
+	server.SetEtcdClient(client)
+	domain := serviceName + ".svc.cluster.local"
+
+	// SRV：只使用优先级最小的一组，权重0按1返回，目标名称可以解析，TTL不超过max_ttl
+	assert.Equal(t, []kongTarget{
+		{IP: "10.0.20.1", Port: 8080, Weight: 1, TTL: 10},
+		{IP: "10.0.20.2", Port: 8081, Weight: 30, TTL: 10},
+	}, kongResolve(t, server, domain, 80))
+
+	// 服务的A应答包含所有实例地址
+	r := new(dns.Msg)
+	r.SetQuestion(dns.Fqdn(domain), dns.TypeA)
+	w := &recordingWriter{}
+	server.handleDNSRequest(w, r)
+	require.Len(t, w.msg.Answer, 3)
+	addrs := []string{}
+	for _, rr := range w.msg.Answer {
+		addrs = append(addrs, rr.(*dns.A).A.String())
+	}
+	assert.Equal(t, []string{"10.0.20.1", "10.0.20.2", "10.0.20.3"}, addrs)
+
+	// 只有A记录的域名：SRV查询返回NODATA，Kong继续查询A记录
+	r = new(dns.Msg)
+	r.SetQuestion(dns.Fqdn(legacy), dns.TypeSRV)
+	w = &recordingWriter{}
+	server.handleDNSRequest(w, r)
+	assert.Equal(t, dns.RcodeSuccess, w.msg.Rcode)
+	assert.Empty(t, w.msg.Answer)
+	assert.Equal(t, []kongTarget{{IP: "10.0.30.1", Port: 80, Weight: 10, TTL: 30}}, kongResolve(t, server, legacy, 80))
+
+	// 未启用兼容模式时SRV目标名称无法解析，服务的A应答只有一个地址
+	cfg.DNS.KongCompat.Enabled = false
+	r = new(dns.Msg)
+	r.SetQuestion("a."+dns.Fqdn(domain), dns.TypeA)
+	w = &recordingWriter{}
+	server.handleDNSRequest(w, r)
+	assert.Equal(t, dns.RcodeNameError, w.msg.Rcode)
+
+	r = new(dns.Msg)
+	r.SetQuestion(dns.Fqdn(domain), dns.TypeA)
+	w = &recordingWriter{}
+	server.handleDNSRequest(w, r)
+	require.Len(t, w.msg.Answer, 1)
+	assert.Equal(t, uint32(60), w.msg.Answer[0].Header().Ttl)
+}
+
+func TestDNSServer_KongCompatACL(t *testing.T) {
+	// 跳过集成测试，除非明确要求运行
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	client := etcdclient.CreateEtcdClientForTest(t)
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	serviceName := "kong-acl-" + strconv.FormatInt(time.Now().UnixNano(), 10)
+	require.NoError(t, client.RegisterService(ctx, &etcdclient.ServiceInstance{ServiceName: serviceName, InstanceID: "a", IPAddress: "10.0.21.1", Port: 8080, TTL: 60}))
+	require.NoError(t, client.PutServiceACL(ctx, &etcdclient.ServiceACL{ServiceName: serviceName, AllowCIDRs: []string{"192.168.0.0/16"}}))
+	defer func() {
+		_ = client.DeregisterService(context.Background(), serviceName, "a")
+		_ = client.DeleteServiceACL(context.Background(), serviceName)
+	}()
+
+	cfg := &config.Config{}
+	cfg.DNS.KongCompat.Enabled = true
+	server := NewDNSServer(cfg, createTestLogger(t)).(*DNSServer)
+	server.SetEtcdClient(client)
+	server.applyServiceACLs()
+
+	// SRV目标名称同样受服务解析ACL限制
+	r := new(dns.Msg)
+	r.SetQuestion("a."+serviceName+".svc.cluster.local.", dns.TypeA)
+	w := &recordingWriter{}
+	server.handleDNSRequest(w, r)
+	assert.Equal(t, dns.RcodeNameError, w.msg.Rcode)
+	assert.Empty(t, w.msg.Answer)
+}
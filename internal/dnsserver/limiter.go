@@ -0,0 +1,65 @@
+package dnsserver
+
+import (
+	"context"
+	"time"
+
+	"github.com/miekg/dns"
+	"go.uber.org/zap"
+)
+
+// 过载时的处理方式
+const (
+	OverloadServfail = "servfail" // 立即返回SERVFAIL，客户端可以尽快重试其他服务器
+	OverloadDrop     = "drop"     // 不应答，客户端超时后重试
+)
+
+// queryLimiter 限制同时处理的查询数，达到上限的查询立即被拒绝，避免洪泛时goroutine堆积耗尽内存
+type queryLimiter struct {
+	slots chan struct{} // 为nil时不限制
+}
+
+// newQueryLimiter 创建查询限制器，max不大于0时不限制
+func newQueryLimiter(max int) *queryLimiter {
+	l := &queryLimiter{}
+	if max > 0 {
+		l.slots = make(chan struct{}, max)
+	}
+	return l
+}
+
+// acquire 尝试占用一个查询名额，不等待
+func (l *queryLimiter) acquire() bool {
+	if l.slots == nil {
+		return true
+	}
+	select {
+	case l.slots <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// release 释放acquire占用的名额
+func (l *queryLimiter) release() {
+	if l.slots != nil {
+		<-l.slots
+	}
+}
+
+// shed 按配置拒绝过载时的查询并记录统计
+func (s *DNSServer) shed(w dns.ResponseWriter, r *dns.Msg) {
+	s.analytics.recordOverload(time.Now())
+	s.logger.Debug("并发查询数已达上限，拒绝查询",
+		zap.Uint16("id", r.Id),
+		zap.String("client", addrString(w.RemoteAddr())),
+		zap.String("action", s.cfg.DNS.Overload.Action))
+
+	if s.cfg.DNS.Overload.Action == OverloadDrop {
+		return
+	}
+	m := new(dns.Msg)
+	m.SetRcode(r, dns.RcodeServerFailure)
+	s.writeResponse(context.Background(), w, m)
+}
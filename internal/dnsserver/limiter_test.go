@@ -0,0 +1,59 @@
+package dnsserver
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hewenyu/kong-discovery/internal/config"
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryLimiter(t *testing.T) {
+	l := newQueryLimiter(2)
+	assert.True(t, l.acquire())
+	assert.True(t, l.acquire())
+	assert.False(t, l.acquire())
+	l.release()
+	assert.True(t, l.acquire())
+
+	// 不限制时总是成功
+	unlimited := newQueryLimiter(0)
+	for i := 0; i < 100; i++ {
+		assert.True(t, unlimited.acquire())
+	}
+}
+
+func TestDNSServer_OverloadShedding(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.DNS.Overload.MaxInflight = 1
+	server := NewDNSServer(cfg, createTestLogger(t)).(*DNSServer)
+
+	query := func() *dns.Msg {
+		r := new(dns.Msg)
+		r.SetQuestion("test.local.", dns.TypeA)
+		w := &recordingWriter{}
+		server.handleDNSRequest(w, r)
+		return w.msg
+	}
+
+	// 未达上限时正常应答
+	m := query()
+	require.NotNil(t, m)
+	assert.Equal(t, dns.RcodeSuccess, m.Rcode)
+
+	// 占满名额后立即返回SERVFAIL并计入统计
+	require.True(t, server.limiter.acquire())
+	m = query()
+	require.NotNil(t, m)
+	assert.Equal(t, dns.RcodeServerFailure, m.Rcode)
+
+	// drop模式下不应答
+	cfg.DNS.Overload.Action = OverloadDrop
+	assert.Nil(t, query())
+
+	stats := server.DNSStats(time.Minute, 10)
+	assert.Equal(t, uint64(2), stats.Overloaded)
+	assert.Equal(t, uint64(1), stats.Queries)
+}
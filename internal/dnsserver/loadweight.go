@@ -0,0 +1,33 @@
+package dnsserver
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// applyLoadWeights 从etcd加载按负载调整权重的运行时开关，未开启dns.load_weights时不读取
+func (s *DNSServer) applyLoadWeights() {
+	if s.etcdClient == nil || !s.cfg.DNS.LoadWeights.Enabled {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	state, err := s.etcdClient.GetLoadWeightsState(ctx)
+	if err != nil {
+		s.logger.Debug("读取负载权重开关失败", zap.Error(err))
+		return
+	}
+	disabled := state != nil && state.Disabled
+	if s.loadWeightsOff.Swap(disabled) != disabled {
+		s.logger.Warn("负载权重开关已变化", zap.Bool("disabled", disabled))
+	}
+}
+
+// loadWeightsActive 判断是否按实例上报的负载调整权重：配置开启且没有通过运行时开关关闭
+func (s *DNSServer) loadWeightsActive() bool {
+	return s.cfg.DNS.LoadWeights.Enabled && !s.loadWeightsOff.Load()
+}
@@ -0,0 +1,132 @@
+package dnsserver
+
+import (
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hewenyu/kong-discovery/internal/config"
+	"github.com/hewenyu/kong-discovery/internal/etcdclient"
+	"go.uber.org/zap"
+)
+
+// namespaceNetwork 命名空间的一个客户端网段
+type namespaceNetwork struct {
+	namespace string
+	ipNet     *net.IPNet
+	prefix    int
+}
+
+// namespaceUpstreams 命名空间覆盖的上游池，随运行时配置从etcd重新加载
+type namespaceUpstreams struct {
+	mu               sync.RWMutex
+	pools            map[string]*upstreamPool
+	networks         []namespaceNetwork
+	failureThreshold int
+	cooldown         time.Duration
+	disabled         *disabledUpstreams
+}
+
+// newNamespaceUpstreams 创建空的命名空间上游集合，disabled为被手动停用的上游，可以为nil
+func newNamespaceUpstreams(failureThreshold int, cooldown time.Duration, disabled *disabledUpstreams) *namespaceUpstreams {
+	return &namespaceUpstreams{
+		pools:            make(map[string]*upstreamPool),
+		failureThreshold: failureThreshold,
+		cooldown:         cooldown,
+		disabled:         disabled,
+	}
+}
+
+// setNamespaces 按命名空间的上游设置替换上游池，已存在命名空间的上游池保留健康统计，无效网段会被记录并跳过
+func (n *namespaceUpstreams) setNamespaces(namespaces []*etcdclient.Namespace, logger config.Logger) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	pools := make(map[string]*upstreamPool, len(namespaces))
+	var networks []namespaceNetwork
+	for _, ns := range namespaces {
+		upstream := ns.Upstream
+		if upstream == nil || len(upstream.Servers) == 0 {
+			continue
+		}
+
+		pool, ok := n.pools[ns.Name]
+		if ok {
+			pool.setAddresses(upstream.Servers)
+		} else {
+			pool = newUpstreamPool(upstream.Servers, n.failureThreshold, n.cooldown, n.disabled)
+			pool.namespace = ns.Name
+		}
+		pool.setMode(upstream.Mode, upstream.RaceCount)
+		pools[ns.Name] = pool
+
+		for _, cidr := range upstream.ClientSubnets {
+			_, ipNet, err := net.ParseCIDR(cidr)
+			if err != nil {
+				logger.Warn("无效的命名空间客户端网段",
+					zap.String("namespace", ns.Name),
+					zap.String("cidr", cidr),
+					zap.Error(err))
+				continue
+			}
+			prefix, _ := ipNet.Mask.Size()
+			networks = append(networks, namespaceNetwork{namespace: ns.Name, ipNet: ipNet, prefix: prefix})
+		}
+	}
+
+	n.pools = pools
+	n.networks = networks
+}
+
+// pool 返回命名空间覆盖的上游池，命名空间没有覆盖上游时返回nil
+func (n *namespaceUpstreams) pool(namespace string) *upstreamPool {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	return n.pools[namespace]
+}
+
+// matchClient 返回客户端地址所属命名空间的上游池，多个网段匹配时最长前缀优先，未匹配时返回nil
+func (n *namespaceUpstreams) matchClient(addr net.Addr) *upstreamPool {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	if len(n.networks) == 0 || addr == nil {
+		return nil
+	}
+	ip := clientIP(addr)
+	if ip == nil {
+		return nil
+	}
+
+	var best *upstreamPool
+	bestPrefix := -1
+	for _, network := range n.networks {
+		if network.prefix > bestPrefix && network.ipNet.Contains(ip) {
+			best, bestPrefix = n.pools[network.namespace], network.prefix
+		}
+	}
+	return best
+}
+
+// allPools 返回所有命名空间的上游池，按命名空间名称排序
+func (n *namespaceUpstreams) allPools() []*upstreamPool {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	result := make([]*upstreamPool, 0, len(n.pools))
+	for _, pool := range n.pools {
+		result = append(result, pool)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].namespace < result[j].namespace })
+	return result
+}
+
+// status 返回所有命名空间上游的健康状态
+func (n *namespaceUpstreams) status() []UpstreamStatus {
+	var result []UpstreamStatus
+	for _, pool := range n.allPools() {
+		result = append(result, pool.status()...)
+	}
+	return result
+}
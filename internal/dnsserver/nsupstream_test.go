@@ -0,0 +1,83 @@
+package dnsserver
+
+import (
+	"net"
+	"testing"
+
+	"github.com/hewenyu/kong-discovery/internal/config"
+	"github.com/hewenyu/kong-discovery/internal/etcdclient"
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDNSServer_NamespaceUpstreams(t *testing.T) {
+	teamA := startFakeUpstream(t, "10.1.0.1", 0)
+	teamB := startFakeUpstream(t, "10.2.0.1", 0)
+	corp := startFakeUpstream(t, "10.3.0.1", 0)
+	public := startFakeUpstream(t, "8.8.4.4", 0)
+
+	cfg := &config.Config{}
+	cfg.DNS.UpstreamDNS = public
+	cfg.DNS.ForwardRules = []config.ForwardRule{
+		{Suffix: "corp.example", Servers: []string{corp}},
+	}
+	server := NewDNSServer(cfg, createTestLogger(t)).(*DNSServer)
+	server.zones.setZones([]*etcdclient.NamespaceZone{{Zone: "team-a.internal", Namespace: "team-a"}})
+	server.nsUpstreams.setNamespaces([]*etcdclient.Namespace{
+		{Name: etcdclient.DefaultNamespace},
+		{Name: "team-a", Upstream: &etcdclient.NamespaceUpstream{Servers: []string{teamA}}},
+		{Name: "team-b", Upstream: &etcdclient.NamespaceUpstream{
+			Servers:       []string{teamB},
+			Mode:          etcdclient.UpstreamModeRace,
+			ClientSubnets: []string{"10.20.0.0/16", "invalid"},
+		}},
+	}, server.logger)
+
+	resolve := func(name string, addr net.Addr) string {
+		r := new(dns.Msg)
+		r.SetQuestion(name, dns.TypeA)
+		m := new(dns.Msg)
+		m.SetReply(r)
+		require.NoError(t, server.forwardToUpstream(server.selectUpstreamPool(r, addr), r, m))
+		require.Len(t, m.Answer, 1)
+		return m.Answer[0].(*dns.A).A.String()
+	}
+	teamBClient := &net.UDPAddr{IP: net.ParseIP("10.20.1.2"), Port: 53000}
+	otherClient := &net.UDPAddr{IP: net.ParseIP("192.168.1.2"), Port: 53000}
+
+	// 命名空间的服务域名和自定义区域使用命名空间的上游
+	assert.Equal(t, "10.1.0.1", resolve("legacy.team-a.svc.cluster.local.", otherClient))
+	assert.Equal(t, "10.1.0.1", resolve("_http._tcp.legacy.team-a.svc.cluster.local.", otherClient))
+	assert.Equal(t, "10.1.0.1", resolve("legacy.team-a.internal.", teamBClient))
+
+	// 来自命名空间网段的其他查询使用命名空间的上游，条件转发规则优先
+	assert.Equal(t, "10.2.0.1", resolve("example.com.", teamBClient))
+	assert.Equal(t, "10.3.0.1", resolve("intranet.corp.example.", teamBClient))
+
+	// 没有覆盖上游的命名空间和其他客户端使用默认上游
+	assert.Equal(t, "8.8.4.4", resolve("order.default.svc.cluster.local.", otherClient))
+	assert.Equal(t, "8.8.4.4", resolve("example.com.", otherClient))
+	assert.Equal(t, "8.8.4.4", resolve("example.com.", nil))
+
+	// 状态中包含命名空间上游
+	var namespaces []string
+	for _, status := range server.UpstreamStatus() {
+		if status.Namespace != "" {
+			namespaces = append(namespaces, status.Namespace)
+		}
+	}
+	assert.Equal(t, []string{"team-a", "team-b"}, namespaces)
+	mode, _ := server.nsUpstreams.pool("team-b").forwardMode()
+	assert.Equal(t, etcdclient.UpstreamModeRace, mode)
+
+	// 重新加载时保留健康统计，删除的覆盖不再生效
+	pool := server.nsUpstreams.pool("team-a")
+	server.nsUpstreams.setNamespaces([]*etcdclient.Namespace{
+		{Name: "team-a", Upstream: &etcdclient.NamespaceUpstream{Servers: []string{teamA}}},
+		{Name: "team-b"},
+	}, server.logger)
+	assert.Same(t, pool, server.nsUpstreams.pool("team-a"))
+	assert.Nil(t, server.nsUpstreams.pool("team-b"))
+	assert.Equal(t, "8.8.4.4", resolve("example.com.", teamBClient))
+}
@@ -0,0 +1,138 @@
+package dnsserver
+
+import (
+	"net"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/hewenyu/kong-discovery/internal/etcdclient"
+	"github.com/miekg/dns"
+)
+
+// 黑洞响应的TTL（秒）
+const sinkholeTTL = 60
+
+// PolicyRuleStatus 拦截规则及其命中次数
+type PolicyRuleStatus struct {
+	etcdclient.BlockRule
+	Hits uint64 `json:"hits"` // 命中次数
+}
+
+// policyRule 编译后的拦截规则
+type policyRule struct {
+	rule  etcdclient.BlockRule
+	regex *regexp.Regexp
+	hits  *uint64
+}
+
+// matches 判断域名是否命中规则，域名已小写且不带尾部点号
+func (r *policyRule) matches(domain string) bool {
+	pattern := strings.Trim(strings.ToLower(r.rule.Pattern), ".")
+	switch r.rule.Match {
+	case etcdclient.BlockMatchExact:
+		return domain == pattern
+	case etcdclient.BlockMatchSuffix:
+		return domain == pattern || strings.HasSuffix(domain, "."+pattern)
+	case etcdclient.BlockMatchRegex:
+		return r.regex != nil && r.regex.MatchString(domain)
+	default:
+		return false
+	}
+}
+
+// responsePolicy 响应策略（拦截列表）
+type responsePolicy struct {
+	mu    sync.RWMutex
+	rules []*policyRule
+	hits  map[string]*uint64 // 按规则ID保存命中计数，规则重新加载时保留
+}
+
+// newResponsePolicy 创建响应策略
+func newResponsePolicy() *responsePolicy {
+	return &responsePolicy{
+		hits: make(map[string]*uint64),
+	}
+}
+
+// setRules 替换拦截规则，无效规则会被跳过
+func (p *responsePolicy) setRules(rules []*etcdclient.BlockRule) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	compiled := make([]*policyRule, 0, len(rules))
+	hits := make(map[string]*uint64, len(rules))
+	for _, r := range rules {
+		if err := r.Validate(); err != nil {
+			continue
+		}
+
+		counter, ok := p.hits[r.ID]
+		if !ok {
+			counter = new(uint64)
+		}
+		hits[r.ID] = counter
+
+		pr := &policyRule{rule: *r, hits: counter}
+		if r.Match == etcdclient.BlockMatchRegex {
+			pr.regex = regexp.MustCompile(r.Pattern)
+		}
+		compiled = append(compiled, pr)
+	}
+
+	p.rules = compiled
+	p.hits = hits
+}
+
+// match 返回域名命中的第一条规则，并增加命中计数
+func (p *responsePolicy) match(name string) *etcdclient.BlockRule {
+	domain := strings.TrimSuffix(strings.ToLower(name), ".")
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	for _, r := range p.rules {
+		if r.matches(domain) {
+			atomic.AddUint64(r.hits, 1)
+			rule := r.rule
+			return &rule
+		}
+	}
+	return nil
+}
+
+// status 返回所有规则及命中次数
+func (p *responsePolicy) status() []PolicyRuleStatus {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	result := make([]PolicyRuleStatus, 0, len(p.rules))
+	for _, r := range p.rules {
+		result = append(result, PolicyRuleStatus{
+			BlockRule: r.rule,
+			Hits:      atomic.LoadUint64(r.hits),
+		})
+	}
+	return result
+}
+
+// applyPolicy 按命中的规则构造响应
+func applyPolicy(rule *etcdclient.BlockRule, q dns.Question, m *dns.Msg) {
+	if rule.Action != etcdclient.BlockActionSinkhole {
+		m.Rcode = dns.RcodeNameError
+		return
+	}
+
+	// 黑洞IP只回答与其地址族匹配的查询，其他类型返回空应答（NODATA）
+	ip := net.ParseIP(rule.SinkholeIP)
+	hdr := dns.RR_Header{Name: q.Name, Class: dns.ClassINET, Ttl: sinkholeTTL}
+	switch {
+	case q.Qtype == dns.TypeA && ip.To4() != nil:
+		hdr.Rrtype = dns.TypeA
+		m.Answer = append(m.Answer, &dns.A{Hdr: hdr, A: ip.To4()})
+	case q.Qtype == dns.TypeAAAA && ip.To4() == nil:
+		hdr.Rrtype = dns.TypeAAAA
+		m.Answer = append(m.Answer, &dns.AAAA{Hdr: hdr, AAAA: ip})
+	}
+}
@@ -0,0 +1,64 @@
+package dnsserver
+
+import (
+	"testing"
+
+	"github.com/hewenyu/kong-discovery/internal/etcdclient"
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResponsePolicy_Match(t *testing.T) {
+	policy := newResponsePolicy()
+	policy.setRules([]*etcdclient.BlockRule{
+		{ID: "exact", Match: etcdclient.BlockMatchExact, Pattern: "bad.example.com", Action: etcdclient.BlockActionNXDomain},
+		{ID: "suffix", Match: etcdclient.BlockMatchSuffix, Pattern: "malware.test", Action: etcdclient.BlockActionSinkhole, SinkholeIP: "0.0.0.0"},
+		{ID: "regex", Match: etcdclient.BlockMatchRegex, Pattern: `^ads?\d*\.`, Action: etcdclient.BlockActionNXDomain},
+		{ID: "invalid", Match: etcdclient.BlockMatchRegex, Pattern: `(`, Action: etcdclient.BlockActionNXDomain},
+	})
+
+	assert.Equal(t, "exact", policy.match("Bad.Example.com.").ID)
+	assert.Nil(t, policy.match("sub.bad.example.com."), "exact规则不应匹配子域名")
+	assert.Equal(t, "suffix", policy.match("x.y.malware.test.").ID)
+	assert.Equal(t, "suffix", policy.match("malware.test.").ID)
+	assert.Equal(t, "regex", policy.match("ads2.tracker.net.").ID)
+	assert.Nil(t, policy.match("good.example.com."))
+
+	// 命中次数
+	hits := make(map[string]uint64)
+	for _, status := range policy.status() {
+		hits[status.ID] = status.Hits
+	}
+	assert.Len(t, hits, 3, "无效规则应被跳过")
+	assert.Equal(t, uint64(1), hits["exact"])
+	assert.Equal(t, uint64(2), hits["suffix"])
+
+	// 重新加载规则时保留命中次数
+	policy.setRules([]*etcdclient.BlockRule{
+		{ID: "suffix", Match: etcdclient.BlockMatchSuffix, Pattern: "malware.test", Action: etcdclient.BlockActionNXDomain},
+	})
+	require.Len(t, policy.status(), 1)
+	assert.Equal(t, uint64(2), policy.status()[0].Hits)
+}
+
+func TestApplyPolicy(t *testing.T) {
+	sinkhole := &etcdclient.BlockRule{Action: etcdclient.BlockActionSinkhole, SinkholeIP: "10.255.255.1"}
+
+	// A查询返回黑洞IP
+	m := new(dns.Msg)
+	applyPolicy(sinkhole, dns.Question{Name: "bad.test.", Qtype: dns.TypeA, Qclass: dns.ClassINET}, m)
+	require.Len(t, m.Answer, 1)
+	assert.Equal(t, "10.255.255.1", m.Answer[0].(*dns.A).A.String())
+	assert.Equal(t, dns.RcodeSuccess, m.Rcode)
+
+	// AAAA查询返回空应答
+	m = new(dns.Msg)
+	applyPolicy(sinkhole, dns.Question{Name: "bad.test.", Qtype: dns.TypeAAAA, Qclass: dns.ClassINET}, m)
+	assert.Empty(t, m.Answer)
+
+	// NXDOMAIN动作
+	m = new(dns.Msg)
+	applyPolicy(&etcdclient.BlockRule{Action: etcdclient.BlockActionNXDomain}, dns.Question{Name: "bad.test.", Qtype: dns.TypeA}, m)
+	assert.Equal(t, dns.RcodeNameError, m.Rcode)
+}
@@ -0,0 +1,308 @@
+package dnsserver
+
+import (
+	"context"
+	"encoding/binary"
+	"sync"
+	"time"
+
+	"github.com/hewenyu/kong-discovery/internal/etcdclient"
+	"github.com/miekg/dns"
+	"go.uber.org/zap"
+)
+
+const (
+	// 未配置时重新生成全部预生成应答的间隔
+	defaultPrecomputeInterval = 10 * time.Second
+
+	// precomputeWindow 选择热门服务域名使用的查询统计窗口
+	precomputeWindow = time.Minute
+
+	// precomputeDebounce 收到服务变更后等待的时间，批量部署时多个变更合并为一次重新生成
+	precomputeDebounce = 200 * time.Millisecond
+)
+
+// precomputeTypes 预生成应答的查询类型
+var precomputeTypes = []uint16{dns.TypeA, dns.TypeAAAA, dns.TypeSRV}
+
+// precomputeKey 预生成应答的键
+type precomputeKey struct {
+	name  string // 查询名称，与问题中的名称完全一致（小写，带尾部点号）
+	qtype uint16
+	view  string // 客户端所属的视图
+}
+
+// precomputedEntry 一个预先打包的应答
+type precomputedEntry struct {
+	service string // 应答所属的服务，服务变更时失效
+	packed  []byte // ID为0、未设置RD和CD标志的应答报文
+}
+
+// precomputedResponses 热门服务域名预先打包的应答
+type precomputedResponses struct {
+	mu      sync.RWMutex
+	entries map[precomputeKey]*precomputedEntry
+	version uint64 // 每次失效时增加，生成期间发生过失效的结果不保存
+}
+
+// newPrecomputedResponses 创建空的预生成应答集合
+func newPrecomputedResponses() *precomputedResponses {
+	return &precomputedResponses{entries: make(map[precomputeKey]*precomputedEntry)}
+}
+
+// get 返回预生成的应答，没有时返回nil
+func (p *precomputedResponses) get(key precomputeKey) *precomputedEntry {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.entries[key]
+}
+
+// currentVersion 返回当前版本，开始生成前读取
+func (p *precomputedResponses) currentVersion() uint64 {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.version
+}
+
+// invalidate 删除服务的所有预生成应答，service为空时删除全部
+func (p *precomputedResponses) invalidate(service string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.version++
+	if service == "" {
+		p.entries = make(map[precomputeKey]*precomputedEntry)
+		return
+	}
+	for key, entry := range p.entries {
+		if entry.service == service {
+			delete(p.entries, key)
+		}
+	}
+}
+
+// replace 用新生成的应答替换全部应答；从version开始生成以来发生过失效时放弃，返回是否已替换
+func (p *precomputedResponses) replace(entries map[precomputeKey]*precomputedEntry, version uint64) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.version != version {
+		return false
+	}
+	p.entries = entries
+	return true
+}
+
+// precomputable 判断服务的应答是否与客户端无关、可以预先生成：
+// 设置了解析ACL、别名（可能按权重分流）或random、sticky应答顺序的服务每个查询的应答可能不同，
+// 按负载调整权重开启时每个查询按权重随机选取地址
+func (s *DNSServer) precomputable(service string) bool {
+	if s.acls.get(service) != nil || s.aliases.isAlias(service) || s.loadWeightsActive() {
+		return false
+	}
+	if policy := s.answerPolicies.get(service); policy != nil && policy.Order != etcdclient.AnswerOrderNone {
+		return false
+	}
+	return true
+}
+
+// servePrecomputed 查询命中预生成应答时直接发送，只替换ID和从查询复制的RD、CD标志，返回是否已应答。
+// 只处理单个问题的标准查询，带TSIG签名或查询捕获开启时按正常流程解析
+func (s *DNSServer) servePrecomputed(w dns.ResponseWriter, r *dns.Msg) bool {
+	if s.precomputed == nil || s.capture != nil || r.Opcode != dns.OpcodeQuery || len(r.Question) != 1 ||
+		len(r.Answer) > 0 || len(r.Ns) > 0 {
+		return false
+	}
+	// 应答不包含OPT记录，带EDNS0的查询得到相同的应答；预生成的应答不超过512字节，不会被截断
+	for _, rr := range r.Extra {
+		if rr.Header().Rrtype != dns.TypeOPT {
+			return false
+		}
+	}
+	q := r.Question[0]
+	if q.Qclass != dns.ClassINET {
+		return false
+	}
+
+	entry := s.precomputed.get(precomputeKey{name: q.Name, qtype: q.Qtype, view: s.views.selectView(w.RemoteAddr())})
+	if entry == nil || !s.precomputable(entry.service) {
+		return false
+	}
+
+	resp := make([]byte, len(entry.packed))
+	copy(resp, entry.packed)
+	binary.BigEndian.PutUint16(resp, r.Id)
+	if r.RecursionDesired {
+		resp[2] |= 0x01
+	}
+	if r.CheckingDisabled {
+		resp[3] |= 0x10
+	}
+	if _, err := w.Write(resp); err != nil {
+		s.logger.Error("发送DNS响应失败", zap.Error(err))
+	}
+
+	now := time.Now()
+	s.analytics.record(q.Name, dns.RcodeSuccess, now)
+	s.analytics.recordPrecomputed(now)
+	return true
+}
+
+// rebuildPrecomputed 按查询统计选出最热门的服务域名，重新生成全部预生成应答
+func (s *DNSServer) rebuildPrecomputed(ctx context.Context) {
+	version := s.precomputed.currentVersion()
+	stats := s.analytics.snapshot(precomputeWindow, 0, time.Now())
+	view := s.views.selectView(nil)
+
+	entries := make(map[precomputeKey]*precomputedEntry)
+	names := 0
+	for _, top := range stats.TopNames {
+		if names >= s.cfg.DNS.Precompute.TopN {
+			break
+		}
+		service := serviceFromDomain(top.Name)
+		if service == "" || !s.precomputable(service) || s.federatedCluster(ctx, top.Name, nil) != "" {
+			continue
+		}
+		names++
+		for _, qtype := range precomputeTypes {
+			if packed := s.precomputeResponse(ctx, top.Name, qtype); packed != nil {
+				entries[precomputeKey{name: dns.Fqdn(top.Name), qtype: qtype, view: view}] = &precomputedEntry{service: service, packed: packed}
+			}
+		}
+	}
+
+	if !s.precomputed.replace(entries, version) {
+		s.logger.Debug("生成预生成应答期间服务发生变更，等待下一次生成")
+	}
+}
+
+// precomputeResponse 按正常流程解析默认视图中的一个服务域名并打包应答，应答不能预先生成时返回nil：
+// 没有记录、记录数达到max_answers上限（每次应答选取的记录不同），或不压缩时超过512字节
+func (s *DNSServer) precomputeResponse(ctx context.Context, domain string, qtype uint16) []byte {
+	r := new(dns.Msg)
+	r.SetQuestion(dns.Fqdn(domain), qtype)
+	r.RecursionDesired = false
+	m := new(dns.Msg)
+	m.SetReply(r)
+	m.Authoritative = true
+
+	client := queryClient{view: s.views.selectView(nil)}
+	queryCtx, cancel := s.queryContext(ctx)
+	defer cancel()
+	if !s.handleQuery(queryCtx, r.Question[0], client, m, nil) || m.Rcode != dns.RcodeSuccess || len(m.Answer) == 0 {
+		return nil
+	}
+
+	counts := make(map[uint16]int)
+	for _, rr := range m.Answer {
+		counts[rr.Header().Rrtype]++
+	}
+	for rrtype, n := range counts {
+		if limit := s.answerLimit(rrtype); limit > 0 && n >= limit {
+			return nil
+		}
+	}
+	s.shapeAnswers(m, client, nil)
+
+	// 与正常流程一致：不压缩也能放下时由压缩配置决定是否压缩
+	m.Compress = false
+	if m.Len() > dns.MinMsgSize {
+		return nil
+	}
+	m.Compress = s.cfg.DNS.Response.Compress
+	packed, err := m.Pack()
+	if err != nil {
+		s.logger.Warn("打包预生成应答失败", zap.String("name", domain), zap.Error(err))
+		return nil
+	}
+	return packed
+}
+
+// precomputePeriodically 定期重新生成预生成应答，并在服务变更时丢弃受影响的应答、稍后重新生成，直到服务器关闭。
+// 监听变更失败或中断时丢弃全部应答，重新监听成功前不使用预生成应答
+func (s *DNSServer) precomputePeriodically() {
+	interval := time.Duration(s.cfg.DNS.Precompute.Interval) * time.Second
+	if interval <= 0 {
+		interval = defaultPrecomputeInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-s.stopCh:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	watch := func() <-chan *etcdclient.Event {
+		if s.etcdClient == nil {
+			return nil
+		}
+		events, err := s.etcdClient.WatchEvents(ctx)
+		if err != nil {
+			s.logger.Warn("监听服务变更失败，暂不使用预生成应答", zap.Error(err))
+			return nil
+		}
+		return events
+	}
+
+	events := watch()
+	if events != nil {
+		s.rebuildPrecomputed(ctx)
+	}
+	debounce := time.NewTimer(precomputeDebounce)
+	debounce.Stop()
+	pending := false
+
+	for {
+		select {
+		case <-s.stopCh:
+			return
+		case event, ok := <-events:
+			if !ok {
+				s.logger.Warn("监听服务变更中断，暂不使用预生成应答")
+				s.precomputed.invalidate("")
+				events = nil
+				continue
+			}
+			if !s.invalidatePrecomputed(event) {
+				continue
+			}
+			if !pending {
+				pending = true
+				debounce.Reset(precomputeDebounce)
+			}
+		case <-debounce.C:
+			pending = false
+			s.rebuildPrecomputed(ctx)
+		case <-ticker.C:
+			if events == nil {
+				if events = watch(); events == nil {
+					continue
+				}
+			}
+			s.rebuildPrecomputed(ctx)
+		}
+	}
+}
+
+// invalidatePrecomputed 按变更丢弃受影响的预生成应答，返回是否需要重新生成：
+// 服务实例的变更只影响该服务；配置变更（ACL、别名、应答策略等）先重新加载运行时配置，
+// 其他变更和压缩后的resync丢弃全部应答
+func (s *DNSServer) invalidatePrecomputed(event *etcdclient.Event) bool {
+	switch event.Kind {
+	case "services":
+		if service, ok := etcdclient.ServiceNameFromKey(event.Key); ok {
+			s.precomputed.invalidate(service)
+			return true
+		}
+		return false
+	case "config":
+		s.applyRuntimeConfig()
+	}
+	s.precomputed.invalidate("")
+	return true
+}
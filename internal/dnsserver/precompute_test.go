@@ -0,0 +1,106 @@
+package dnsserver
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hewenyu/kong-discovery/internal/config"
+	"github.com/hewenyu/kong-discovery/internal/etcdclient"
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrecomputedResponses(t *testing.T) {
+	p := newPrecomputedResponses()
+	key := precomputeKey{name: "web.default.svc.cluster.local.", qtype: dns.TypeA}
+	other := precomputeKey{name: "api.default.svc.cluster.local.", qtype: dns.TypeA}
+
+	version := p.currentVersion()
+	require.True(t, p.replace(map[precomputeKey]*precomputedEntry{
+		key:   {service: "web", packed: []byte{1}},
+		other: {service: "api", packed: []byte{2}},
+	}, version))
+	require.NotNil(t, p.get(key))
+
+	// 只删除变更的服务的应答
+	p.invalidate("web")
+	assert.Nil(t, p.get(key))
+	assert.NotNil(t, p.get(other))
+
+	// 生成期间发生过失效时不替换
+	assert.False(t, p.replace(map[precomputeKey]*precomputedEntry{key: {service: "web"}}, version))
+	assert.Nil(t, p.get(key))
+
+	p.invalidate("")
+	assert.Nil(t, p.get(other))
+}
+
+func TestDNSServer_Precompute(t *testing.T) {
+	// 跳过集成测试，除非明确要求运行
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	client := etcdclient.CreateEtcdClientForTest(t)
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	serviceName := fmt.Sprintf("precompute-service-%d", time.Now().UnixNano())
+	defer func() {
+		_ = client.DeregisterService(context.Background(), serviceName, "precompute-001")
+		_ = client.DeregisterService(context.Background(), serviceName, "precompute-002")
+		_ = client.DeleteServiceACL(context.Background(), serviceName)
+	}()
+	require.NoError(t, client.RegisterService(ctx, &etcdclient.ServiceInstance{ServiceName: serviceName, InstanceID: "precompute-001", IPAddress: "10.0.50.1", Port: 8080, TTL: 60}))
+
+	cfg := &config.Config{}
+	cfg.DNS.Precompute.TopN = 10
+	server := NewDNSServer(cfg, createTestLogger(t)).(*DNSServer)
+	server.SetEtcdClient(client)
+
+	name := serviceName + ".default.svc.cluster.local."
+	query := func() *recordingWriter {
+		r := new(dns.Msg)
+		r.SetQuestion(name, dns.TypeA)
+		r.Id = 4242
+		w := &recordingWriter{}
+		server.handleDNSRequest(w, r)
+		require.NotNil(t, w.msg)
+		return w
+	}
+
+	// 第一次查询按正常流程解析，计入查询统计后成为热门域名
+	normal := query()
+	require.Nil(t, normal.packed)
+	require.Len(t, normal.msg.Answer, 1)
+	server.rebuildPrecomputed(ctx)
+
+	// 预生成的应答与正常流程的应答完全一致
+	w := query()
+	require.NotNil(t, w.packed)
+	expected, err := normal.msg.Pack()
+	require.NoError(t, err)
+	assert.Equal(t, expected, w.packed)
+	assert.Equal(t, uint16(4242), w.msg.Id)
+	assert.True(t, w.msg.RecursionDesired)
+	assert.Equal(t, uint64(1), server.DNSStats(time.Minute, 0).Precomputed)
+
+	// 服务变更后丢弃该服务的应答，重新生成后包含新实例
+	require.NoError(t, client.RegisterService(ctx, &etcdclient.ServiceInstance{ServiceName: serviceName, InstanceID: "precompute-002", IPAddress: "10.0.50.2", Port: 8080, TTL: 60}))
+	assert.True(t, server.invalidatePrecomputed(&etcdclient.Event{Type: etcdclient.EventPut, Kind: "services", Key: "/services/" + serviceName + "/precompute-002"}))
+	assert.Nil(t, query().packed)
+	server.rebuildPrecomputed(ctx)
+	require.NotNil(t, query().packed)
+
+	// 设置解析ACL后不再使用预生成应答
+	require.NoError(t, client.PutServiceACL(ctx, &etcdclient.ServiceACL{ServiceName: serviceName, AllowCIDRs: []string{"192.168.0.0/16"}}))
+	server.applyServiceACLs()
+	w = query()
+	assert.Nil(t, w.packed)
+	assert.Equal(t, dns.RcodeNameError, w.msg.Rcode)
+}
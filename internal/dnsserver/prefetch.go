@@ -0,0 +1,164 @@
+package dnsserver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+	"go.uber.org/zap"
+)
+
+const (
+	// 未配置时保存的热门域名数量、保存间隔和启动时预取的最长时间
+	defaultPrefetchTopN     = 100
+	defaultPrefetchInterval = 60 * time.Second
+	defaultPrefetchTimeout  = 10 * time.Second
+
+	// prefetchConcurrency 预取时同时进行的解析数
+	prefetchConcurrency = 16
+)
+
+// hotNames 热门域名文件的内容
+type hotNames struct {
+	SavedAt time.Time   `json:"saved_at"` // 保存时间
+	Names   []NameCount `json:"names"`    // 按查询次数从多到少排列的域名
+}
+
+// saveHotNames 把查询统计窗口内最热门的域名写入文件，先写临时文件再替换
+// 窗口内没有查询时（如刚启动就关闭）不覆盖已有的文件
+func (s *DNSServer) saveHotNames() error {
+	path := s.cfg.DNS.Prefetch.Path
+	topN := s.cfg.DNS.Prefetch.TopN
+	if topN <= 0 {
+		topN = defaultPrefetchTopN
+	}
+
+	stats := s.analytics.snapshot(MaxStatsWindow, topN, time.Now())
+	if len(stats.TopNames) == 0 {
+		return nil
+	}
+	data, err := json.Marshal(&hotNames{SavedAt: time.Now().UTC(), Names: stats.TopNames})
+	if err != nil {
+		return fmt.Errorf("序列化热门域名失败: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("创建热门域名目录失败: %w", err)
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("创建热门域名文件失败: %w", err)
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return fmt.Errorf("写入热门域名失败: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("写入热门域名失败: %w", err)
+	}
+	if err := os.Rename(f.Name(), path); err != nil {
+		return fmt.Errorf("替换热门域名文件失败: %w", err)
+	}
+	return nil
+}
+
+// loadHotNames 读取热门域名文件，文件不存在时返回空列表
+func loadHotNames(path string) ([]NameCount, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取热门域名文件失败: %w", err)
+	}
+
+	var file hotNames
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("解析热门域名文件失败: %w", err)
+	}
+	return file.Names, nil
+}
+
+// prefetch 按A记录解析上次运行保存的热门域名，服务域名从etcd解析，其他域名转发到上游
+// 预取建立到etcd和上游的连接并使上游缓存这些域名，重启后最初的查询不再承担这些延迟。
+// 在ctx到期前返回，返回成功解析的域名数
+func (s *DNSServer) prefetch(ctx context.Context) int {
+	names, err := loadHotNames(s.cfg.DNS.Prefetch.Path)
+	if err != nil {
+		s.logger.Warn("读取热门域名失败，跳过预取", zap.Error(err))
+		return 0
+	}
+	if len(names) == 0 {
+		return 0
+	}
+
+	start := time.Now()
+	var (
+		mu        sync.Mutex
+		succeeded int
+		wg        sync.WaitGroup
+	)
+	sem := make(chan struct{}, prefetchConcurrency)
+	for _, name := range names {
+		select {
+		case <-ctx.Done():
+		case sem <- struct{}{}:
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			r := new(dns.Msg)
+			r.SetQuestion(dns.Fqdn(name), dns.TypeA)
+			r.RecursionDesired = true
+
+			queryCtx, cancel := s.queryContext(ctx)
+			defer cancel()
+			if m := s.resolve(queryCtx, r, nil, "", nil); m.Rcode != dns.RcodeServerFailure {
+				mu.Lock()
+				succeeded++
+				mu.Unlock()
+			}
+		}(name.Name)
+	}
+	wg.Wait()
+
+	s.logger.Info("热门域名预取完成",
+		zap.Int("names", len(names)),
+		zap.Int("succeeded", succeeded),
+		zap.Duration("elapsed", time.Since(start)))
+	return succeeded
+}
+
+// saveHotNamesPeriodically 定期保存热门域名，直到服务器关闭
+func (s *DNSServer) saveHotNamesPeriodically() {
+	interval := time.Duration(s.cfg.DNS.Prefetch.Interval) * time.Second
+	if interval <= 0 {
+		interval = defaultPrefetchInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stopCh:
+			return
+		case <-ticker.C:
+			if err := s.saveHotNames(); err != nil {
+				s.logger.Warn("保存热门域名失败", zap.Error(err))
+			}
+		}
+	}
+}
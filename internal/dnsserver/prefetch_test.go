@@ -0,0 +1,59 @@
+package dnsserver
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hewenyu/kong-discovery/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDNSServer_SaveHotNames(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.DNS.Prefetch.Path = filepath.Join(t.TempDir(), "state", "hot-names.json")
+	cfg.DNS.Prefetch.TopN = 2
+	server := NewDNSServer(cfg, createTestLogger(t)).(*DNSServer)
+
+	// 没有查询时不写入文件
+	require.NoError(t, server.saveHotNames())
+	_, err := os.Stat(cfg.DNS.Prefetch.Path)
+	assert.True(t, os.IsNotExist(err))
+
+	now := time.Now()
+	for name, count := range map[string]int{"a.example.com.": 3, "B.example.com.": 5, "c.example.com.": 1} {
+		for i := 0; i < count; i++ {
+			server.analytics.record(name, 0, now)
+		}
+	}
+	require.NoError(t, server.saveHotNames())
+
+	names, err := loadHotNames(cfg.DNS.Prefetch.Path)
+	require.NoError(t, err)
+	assert.Equal(t, []NameCount{{Name: "b.example.com", Count: 5}, {Name: "a.example.com", Count: 3}}, names)
+
+	// 文件不存在时返回空列表
+	names, err = loadHotNames(filepath.Join(t.TempDir(), "missing.json"))
+	require.NoError(t, err)
+	assert.Empty(t, names)
+}
+
+func TestDNSServer_Prefetch(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.DNS.Prefetch.Path = filepath.Join(t.TempDir(), "hot-names.json")
+	require.NoError(t, os.WriteFile(cfg.DNS.Prefetch.Path,
+		[]byte(`{"names":[{"name":"test.local","count":2},{"name":"unknown.example.com","count":1}]}`), 0o644))
+	server := NewDNSServer(cfg, createTestLogger(t)).(*DNSServer)
+
+	// 没有上游时未知域名返回NXDOMAIN，同样视为解析完成
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	assert.Equal(t, 2, server.prefetch(ctx))
+
+	// 文件损坏时跳过预取
+	require.NoError(t, os.WriteFile(cfg.DNS.Prefetch.Path, []byte("{"), 0o644))
+	assert.Zero(t, server.prefetch(ctx))
+}
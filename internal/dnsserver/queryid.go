@@ -0,0 +1,26 @@
+package dnsserver
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"strconv"
+	"sync/atomic"
+)
+
+// queryIDPrefix 进程启动时随机生成的查询ID前缀，使不同节点和重启前后的查询ID不重复
+var queryIDPrefix = func() string {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return "00000000"
+	}
+	return hex.EncodeToString(b)
+}()
+
+var queryIDCounter atomic.Uint64
+
+// newQueryID 生成查询ID，格式为“进程前缀-序号”
+// 查询ID作为query_id字段附加到处理该查询时的所有日志中，用于关联同一查询的转发、etcd查询等日志
+// DNS报文头中的ID只有16位且由客户端决定，不能用于关联日志
+func newQueryID() string {
+	return queryIDPrefix + "-" + strconv.FormatUint(queryIDCounter.Add(1), 10)
+}
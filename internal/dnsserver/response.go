@@ -0,0 +1,155 @@
+package dnsserver
+
+import (
+	"math/rand"
+	"net"
+	"sort"
+	"time"
+
+	"github.com/hewenyu/kong-discovery/internal/config"
+	"github.com/miekg/dns"
+)
+
+// shapeAnswers 按配置精简本地应答：每种类型的记录超过上限时按selection选取其中一部分，
+// 附加段只保留仍被应答中的SRV记录引用的地址记录，开启minimal_additional时不返回地址记录。
+// 转发到上游的应答保持上游的内容
+func (s *DNSServer) shapeAnswers(m *dns.Msg, client queryClient, trace *ResolveTrace) {
+	if !m.Authoritative {
+		return
+	}
+	cfg := s.cfg.DNS.Response
+
+	total := len(m.Answer)
+	var limited bool
+	m.Answer, limited = limitAnswers(m.Answer, s.answerLimit, s.selectionOrder(client))
+	if limited {
+		trace.add("response", "应答中的记录超过上限，按%s返回%d条中的%d条", s.selection(), total, len(m.Answer))
+		s.analytics.recordLimited(time.Now())
+	}
+	if limited || cfg.MinimalAdditional {
+		extras := len(m.Extra)
+		m.Extra = pruneGlue(m.Extra, m.Answer, cfg.MinimalAdditional)
+		if dropped := extras - len(m.Extra); dropped > 0 {
+			trace.add("response", "附加段省略%d条地址记录", dropped)
+		}
+	}
+}
+
+// answerLimit 返回应答中一种类型的记录数上限，0表示不限制；SRV记录优先使用max_srv_records
+func (s *DNSServer) answerLimit(rrtype uint16) int {
+	cfg := s.cfg.DNS.Response
+	if rrtype == dns.TypeSRV && cfg.MaxSRVRecords > 0 {
+		return cfg.MaxSRVRecords
+	}
+	return cfg.MaxAnswers
+}
+
+// selection 返回记录超过上限时的选取方式
+func (s *DNSServer) selection() string {
+	switch s.cfg.DNS.Response.Selection {
+	case config.SelectionRandom, config.SelectionSticky:
+		return s.cfg.DNS.Response.Selection
+	}
+	return config.SelectionRoundRobin
+}
+
+// selectionOrder 返回记录超过上限时排列一种类型的记录的方法，排在前面的记录出现在应答中
+func (s *DNSServer) selectionOrder(client queryClient) func(rrs []dns.RR) {
+	if s.selection() == config.SelectionSticky {
+		return func(rrs []dns.RR) { stickyOrder(rrs, client, dns.RR.String) }
+	}
+	return func(rrs []dns.RR) { rotateAnswers(rrs, s.selectionStart(len(rrs))) }
+}
+
+// selectionStart 返回从n条记录中开始选取的位置：轮转时每次应答从下一个位置开始，随机时从随机位置开始
+func (s *DNSServer) selectionStart(n int) int {
+	if s.selection() == config.SelectionRandom {
+		return rand.Intn(n)
+	}
+	return int((s.answerRotation.Add(1) - 1) % uint64(n))
+}
+
+// limitAnswers 每种类型最多保留limit返回的条数，超出时用order排列该类型的记录，保留排在前面的记录。
+// 返回是否省略了记录
+func limitAnswers(answers []dns.RR, limit func(rrtype uint16) int, order func(rrs []dns.RR)) ([]dns.RR, bool) {
+	byType := make(map[uint16][]dns.RR)
+	var types []uint16
+	for _, rr := range answers {
+		t := rr.Header().Rrtype
+		if _, ok := byType[t]; !ok {
+			types = append(types, t)
+		}
+		byType[t] = append(byType[t], rr)
+	}
+
+	limited := false
+	result := make([]dns.RR, 0, len(answers))
+	for _, t := range types {
+		rrs := byType[t]
+		max := limit(t)
+		if max <= 0 || len(rrs) <= max {
+			result = append(result, rrs...)
+			continue
+		}
+		limited = true
+		order(rrs)
+		result = append(result, rrs[:max]...)
+	}
+	if !limited {
+		return answers, false
+	}
+	return result, true
+}
+
+// rotateAnswers 把记录按内容排序后从first开始循环排列，连续的应答依次从不同位置开始，所有记录都有机会出现在应答中
+func rotateAnswers(rrs []dns.RR, first int) {
+	// 服务记录从map中读取，顺序每次不同，排序后轮转才能依次覆盖所有记录
+	sort.Slice(rrs, func(i, j int) bool { return rrs[i].String() < rrs[j].String() })
+	rotated := append(append(make([]dns.RR, 0, len(rrs)), rrs[first:]...), rrs[:first]...)
+	copy(rrs, rotated)
+}
+
+// pruneGlue 删除附加段中不再被应答中的SRV记录引用的地址记录，minimal为true时删除全部地址记录
+func pruneGlue(extra, answers []dns.RR, minimal bool) []dns.RR {
+	targets := make(map[string]bool)
+	for _, rr := range answers {
+		if srv, ok := rr.(*dns.SRV); ok {
+			targets[srv.Target] = true
+		}
+	}
+
+	result := extra[:0]
+	for _, rr := range extra {
+		hdr := rr.Header()
+		if hdr.Rrtype == dns.TypeA || hdr.Rrtype == dns.TypeAAAA {
+			if minimal || !targets[hdr.Name] {
+				continue
+			}
+		}
+		result = append(result, rr)
+	}
+	return result
+}
+
+// fitResponse 通过UDP发送时截断超过客户端接收大小的应答：不压缩超出时先压缩名称，仍然超出时
+// 依次省略附加段和应答中放不下的记录并设置TC标志，客户端改用TCP重试。开启compress时总是压缩名称
+func (s *DNSServer) fitResponse(w dns.ResponseWriter, r, m *dns.Msg) {
+	if _, ok := w.RemoteAddr().(*net.UDPAddr); ok {
+		// Truncate会在应答不压缩也能放下时关闭压缩，压缩配置在截断之后设置
+		truncated := m.Truncated
+		m.Truncate(udpSize(r))
+		if m.Truncated && !truncated {
+			s.analytics.recordTruncated(time.Now())
+		}
+	}
+	m.Compress = m.Compress || s.cfg.DNS.Response.Compress
+}
+
+// udpSize 返回客户端能接收的UDP应答大小，查询未携带EDNS0时为512字节
+func udpSize(r *dns.Msg) int {
+	size := dns.MinMsgSize
+	if opt := r.IsEdns0(); opt != nil && int(opt.UDPSize()) > size {
+		size = int(opt.UDPSize())
+	}
+	return size
+}
@@ -0,0 +1,177 @@
+package dnsserver
+
+import (
+	"fmt"
+	"math/rand"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/hewenyu/kong-discovery/internal/config"
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// srvAnswer 返回n个SRV记录及其目标的A记录
+func srvAnswer(t *testing.T, n int) (answers, extra []dns.RR) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		srv, err := dns.NewRR(fmt.Sprintf("web.default.svc.cluster.local. 30 SRV 10 50 8080 web-%d.web.default.svc.cluster.local.", i))
+		require.NoError(t, err)
+		glue, err := dns.NewRR(fmt.Sprintf("web-%d.web.default.svc.cluster.local. 30 A 10.0.0.%d", i, i+1))
+		require.NoError(t, err)
+		answers = append(answers, srv)
+		extra = append(extra, glue)
+	}
+	return answers, extra
+}
+
+func TestLimitAnswers(t *testing.T) {
+	answers, _ := srvAnswer(t, 5)
+	limitOf := func(max int) func(uint16) int { return func(uint16) int { return max } }
+
+	// 未超过上限时保持不变
+	limited, ok := limitAnswers(answers, limitOf(5), func(rrs []dns.RR) { rotateAnswers(rrs, 3) })
+	assert.False(t, ok)
+	assert.Equal(t, answers, limited)
+
+	// 连续的应答从不同位置开始，所有记录都会出现，与记录原来的顺序无关
+	seen := make(map[string]int)
+	for offset := 0; offset < 5; offset++ {
+		shuffled := append([]dns.RR(nil), answers...)
+		rand.Shuffle(len(shuffled), func(i, j int) { shuffled[i], shuffled[j] = shuffled[j], shuffled[i] })
+		limited, ok := limitAnswers(shuffled, limitOf(2), func(rrs []dns.RR) { rotateAnswers(rrs, offset) })
+		require.True(t, ok)
+		require.Len(t, limited, 2)
+		for _, rr := range limited {
+			seen[rr.(*dns.SRV).Target]++
+		}
+	}
+	assert.Len(t, seen, 5)
+	for target, count := range seen {
+		assert.Equal(t, 2, count, target)
+	}
+
+	// 每种类型分别限制
+	cname, err := dns.NewRR("www.example.com. 30 CNAME web.default.svc.cluster.local.")
+	require.NoError(t, err)
+	limited, ok = limitAnswers(append([]dns.RR{cname}, answers...), limitOf(3), func(rrs []dns.RR) { rotateAnswers(rrs, 0) })
+	require.True(t, ok)
+	require.Len(t, limited, 4)
+	assert.Equal(t, cname, limited[0])
+}
+
+func TestDNSServer_ShapeAnswers(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.DNS.Response.MaxAnswers = 2
+	server := NewDNSServer(cfg, createTestLogger(t)).(*DNSServer)
+
+	answers, extra := srvAnswer(t, 4)
+	m := &dns.Msg{MsgHdr: dns.MsgHdr{Authoritative: true}, Answer: answers, Extra: extra}
+	trace := &ResolveTrace{}
+	server.shapeAnswers(m, queryClient{}, trace)
+
+	// 附加段只保留仍在应答中的SRV目标的地址
+	require.Len(t, m.Answer, 2)
+	require.Len(t, m.Extra, 2)
+	for i, rr := range m.Answer {
+		assert.Equal(t, rr.(*dns.SRV).Target, m.Extra[i].Header().Name)
+	}
+	assert.Len(t, trace.Steps, 2)
+	assert.Equal(t, uint64(1), server.DNSStats(time.Minute, 0).Limited)
+
+	// SRV记录优先使用max_srv_records，每次应答轮转到下一批实例
+	cfg.DNS.Response.MaxSRVRecords = 1
+	seen := make(map[string]bool)
+	for i := 0; i < 4; i++ {
+		answers, extra = srvAnswer(t, 4)
+		m = &dns.Msg{MsgHdr: dns.MsgHdr{Authoritative: true}, Answer: answers, Extra: extra}
+		server.shapeAnswers(m, queryClient{}, nil)
+		require.Len(t, m.Answer, 1)
+		seen[m.Answer[0].(*dns.SRV).Target] = true
+	}
+	assert.Len(t, seen, 4)
+
+	// 随机选取
+	cfg.DNS.Response.Selection = config.SelectionRandom
+	answers, extra = srvAnswer(t, 4)
+	m = &dns.Msg{MsgHdr: dns.MsgHdr{Authoritative: true}, Answer: answers, Extra: extra}
+	server.shapeAnswers(m, queryClient{}, nil)
+	assert.Len(t, m.Answer, 1)
+	assert.Len(t, m.Extra, 1)
+
+	// 一致性哈希选取，同一客户端总是得到同一个实例
+	cfg.DNS.Response.Selection = config.SelectionSticky
+	client := queryClient{addr: &net.UDPAddr{IP: net.ParseIP("10.1.2.3"), Port: 53000}}
+	var target string
+	for i := 0; i < 4; i++ {
+		answers, extra = srvAnswer(t, 4)
+		rand.Shuffle(len(answers), func(i, j int) { answers[i], answers[j] = answers[j], answers[i] })
+		m = &dns.Msg{MsgHdr: dns.MsgHdr{Authoritative: true}, Answer: answers, Extra: extra}
+		server.shapeAnswers(m, client, nil)
+		require.Len(t, m.Answer, 1)
+		if i == 0 {
+			target = m.Answer[0].(*dns.SRV).Target
+		}
+		assert.Equal(t, target, m.Answer[0].(*dns.SRV).Target)
+	}
+	cfg.DNS.Response.MaxSRVRecords = 0
+
+	// 开启minimal_additional时不返回地址记录
+	cfg.DNS.Response.MaxAnswers = 0
+	cfg.DNS.Response.MinimalAdditional = true
+	answers, extra = srvAnswer(t, 4)
+	m = &dns.Msg{MsgHdr: dns.MsgHdr{Authoritative: true}, Answer: answers, Extra: extra}
+	server.shapeAnswers(m, queryClient{}, nil)
+	assert.Len(t, m.Answer, 4)
+	assert.Empty(t, m.Extra)
+
+	// 转发的应答不受影响
+	answers, extra = srvAnswer(t, 4)
+	m = &dns.Msg{Answer: answers, Extra: extra}
+	server.shapeAnswers(m, queryClient{}, nil)
+	assert.Len(t, m.Extra, 4)
+}
+
+func TestDNSServer_FitResponse(t *testing.T) {
+	cfg := &config.Config{}
+	server := NewDNSServer(cfg, createTestLogger(t)).(*DNSServer)
+
+	r := new(dns.Msg)
+	r.SetQuestion("web.default.svc.cluster.local.", dns.TypeSRV)
+	build := func() *dns.Msg {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		m.Answer, m.Extra = srvAnswer(t, 40)
+		return m
+	}
+
+	// 没有EDNS0的UDP查询：应答不超过512字节，放不下时设置TC
+	m := build()
+	server.fitResponse(&recordingWriter{}, r, m)
+	wire, err := m.Pack()
+	require.NoError(t, err)
+	assert.LessOrEqual(t, len(wire), dns.MinMsgSize)
+	assert.True(t, m.Truncated)
+	assert.Less(t, len(m.Answer), 40)
+	assert.Equal(t, uint64(1), server.DNSStats(time.Minute, 0).Truncated)
+
+	// EDNS0声明了更大的接收大小时全部返回，超过512字节时自动压缩
+	r.SetEdns0(4096, false)
+	m = build()
+	server.fitResponse(&recordingWriter{}, r, m)
+	assert.False(t, m.Truncated)
+	assert.Len(t, m.Answer, 40)
+	assert.True(t, m.Compress)
+
+	// 开启compress时放得下的应答同样压缩
+	cfg.DNS.Response.Compress = true
+	r = new(dns.Msg)
+	r.SetQuestion("web.default.svc.cluster.local.", dns.TypeSRV)
+	m = new(dns.Msg)
+	m.SetReply(r)
+	m.Answer, _ = srvAnswer(t, 1)
+	server.fitResponse(&recordingWriter{}, r, m)
+	assert.True(t, m.Compress)
+}
@@ -0,0 +1,45 @@
+package dnsserver
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"runtime"
+
+	"go.uber.org/zap"
+)
+
+// udpSockets 返回每个UDP监听地址打开的套接字数，为0时与可用的CPU数相同
+// 当前平台不支持SO_REUSEPORT时只打开一个
+func (s *DNSServer) udpSockets() int {
+	n := s.cfg.DNS.UDPSockets
+	if n == 0 {
+		n = runtime.GOMAXPROCS(0)
+	}
+	if n > 1 && !reusePortSupported {
+		s.logger.Warn("当前平台不支持SO_REUSEPORT，每个UDP地址只打开一个套接字", zap.Int("udp_sockets", n))
+		return 1
+	}
+	return max(n, 1)
+}
+
+// listenUDPReusePort 在同一地址上打开n个设置了SO_REUSEPORT的UDP套接字，内核按来源地址把数据包分散到各个套接字，
+// 每个套接字由独立的服务器读取，不再受单个读取循环的限制。端口为0时其余套接字绑定到第一个套接字分配的端口
+func listenUDPReusePort(addr string, n int) ([]net.PacketConn, error) {
+	lc := net.ListenConfig{Control: reusePortControl}
+	conns := make([]net.PacketConn, 0, n)
+	for i := 0; i < n; i++ {
+		conn, err := lc.ListenPacket(context.Background(), "udp", addr)
+		if err != nil {
+			for _, c := range conns {
+				c.Close()
+			}
+			return nil, fmt.Errorf("监听UDP地址 %s 失败（第%d个套接字）: %w", addr, i+1, err)
+		}
+		if i == 0 {
+			addr = conn.LocalAddr().String()
+		}
+		conns = append(conns, conn)
+	}
+	return conns, nil
+}
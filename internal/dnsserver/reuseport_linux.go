@@ -0,0 +1,23 @@
+//go:build linux
+
+package dnsserver
+
+import (
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// reusePortSupported 当前平台的SO_REUSEPORT是否在套接字之间分散数据包
+const reusePortSupported = true
+
+// reusePortControl 在绑定前为套接字设置SO_REUSEPORT
+func reusePortControl(network, address string, c syscall.RawConn) error {
+	var sockErr error
+	if err := c.Control(func(fd uintptr) {
+		sockErr = unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_REUSEPORT, 1)
+	}); err != nil {
+		return err
+	}
+	return sockErr
+}
@@ -0,0 +1,17 @@
+//go:build !linux
+
+package dnsserver
+
+import (
+	"errors"
+	"syscall"
+)
+
+// reusePortSupported 当前平台的SO_REUSEPORT是否在套接字之间分散数据包，
+// 其他平台上要么不支持，要么所有数据包都交给同一个套接字
+const reusePortSupported = false
+
+// reusePortControl 当前平台不使用SO_REUSEPORT
+func reusePortControl(network, address string, c syscall.RawConn) error {
+	return errors.New("当前平台不支持SO_REUSEPORT")
+}
@@ -0,0 +1,50 @@
+package dnsserver
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDNSServer_UDPSocketsReusePort(t *testing.T) {
+	// 跳过集成测试，除非明确要求运行
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+	if !reusePortSupported {
+		t.Skip("当前平台不支持SO_REUSEPORT")
+	}
+
+	cfg := createTestConfig(t)
+	cfg.DNS.Port = 0
+	cfg.DNS.UDPSockets = 4
+	server := NewDNSServer(cfg, createTestLogger(t))
+	require.NoError(t, server.Start())
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		assert.NoError(t, server.Shutdown(ctx))
+	}()
+
+	// 端口为0时所有套接字绑定到同一个端口
+	addrs := server.Addrs()
+	require.Len(t, addrs, 4)
+	for _, addr := range addrs[1:] {
+		assert.Equal(t, addrs[0], addr)
+	}
+
+	// 来自不同客户端端口的查询被分散到各个套接字，都能得到应答
+	m := new(dns.Msg)
+	m.SetQuestion("test.local.", dns.TypeA)
+	for i := 0; i < 16; i++ {
+		r, _, err := new(dns.Client).Exchange(m, strings.TrimPrefix(addrs[0], "udp://"))
+		require.NoError(t, err)
+		require.Len(t, r.Answer, 1)
+		assert.Equal(t, "1.2.3.4", r.Answer[0].(*dns.A).A.String())
+	}
+}
@@ -2,14 +2,23 @@ package dnsserver
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net"
+	"os"
 	"strconv"
 	"strings"
+	"sync/atomic"
+	"time"
 
+	"github.com/hewenyu/kong-discovery/internal/chaos"
 	"github.com/hewenyu/kong-discovery/internal/config"
 	"github.com/hewenyu/kong-discovery/internal/etcdclient"
+	"github.com/hewenyu/kong-discovery/internal/tracing"
+	"github.com/hewenyu/kong-discovery/internal/upgrade"
 	"github.com/miekg/dns"
+	"go.opentelemetry.io/otel/attribute"
+	oteltrace "go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 )
 
@@ -24,27 +33,111 @@ type Server interface {
 	// Shutdown 优雅关闭DNS服务器
 	Shutdown(ctx context.Context) error
 
+	// Addrs 返回所有服务器实际监听的地址
+	Addrs() []string
+
+	// Files 返回所有服务器监听套接字的副本，用于升级时传递给新进程
+	Files() ([]*os.File, error)
+
 	// SetEtcdClient 设置etcd客户端
 	SetEtcdClient(client etcdclient.Client)
+
+	// UpstreamStatus 返回所有上游DNS服务器的健康状态
+	UpstreamStatus() []UpstreamStatus
+
+	// SetUpstreamDisabled 立即停用或恢复上游DNS服务器，地址不属于任何上游池时返回false
+	SetUpstreamDisabled(addr string, disabled bool) bool
+
+	// PolicyStatus 返回拦截规则及其命中次数
+	PolicyStatus() []PolicyRuleStatus
+
+	// Resolve 执行一次完整的解析流程并返回跟踪结果
+	Resolve(ctx context.Context, name, qtype, client string) (*ResolveTrace, error)
+
+	// DNSStats 返回最近window时间内的查询统计
+	DNSStats(window time.Duration, topN int) DNSStats
 }
 
 // DNSServer 实现Server接口
 type DNSServer struct {
-	udpServer   *dns.Server
-	tcpServer   *dns.Server
-	cfg         *config.Config
-	logger      config.Logger
-	shutdownErr chan error
-	etcdClient  etcdclient.Client
+	servers        []*dns.Server // 每个监听地址和协议对应一个服务器
+	cfg            *config.Config
+	logger         config.Logger
+	shutdownErr    chan error
+	etcdClient     etcdclient.Client
+	upstreams      *upstreamPool
+	forwarding     *forwardRuleSet
+	nsUpstreams    *namespaceUpstreams // 命名空间覆盖的上游
+	disabled       *disabledUpstreams  // 被手动停用的上游，由所有上游池共享
+	conns          *upstreamConns      // 到上游的复用连接
+	limiter        *queryLimiter       // 同时处理的查询数限制
+	capture        *queryCapture       // 查询捕获，未开启时为nil
+	policy         *responsePolicy
+	views          *viewSelector
+	analytics      *queryAnalytics
+	acls           *serviceACLs
+	answerPolicies *serviceAnswerPolicies
+	ttlPolicies    *ttlPolicies          // 服务和命名空间的TTL策略
+	zones          *namespaceZones       // 命名空间自定义区域
+	aliases        *serviceAliases       // 服务别名
+	tsigSecrets    map[string]string     // TSIG密钥名称（FQDN）到密钥的映射，未配置时为nil
+	answerRotation atomic.Uint64         // 应答记录超过上限时轮转选取的起始位置
+	precomputed    *precomputedResponses // 热门服务域名的预生成应答，未开启时为nil
+	loadWeightsOff atomic.Bool           // 按负载调整权重是否被运行时开关关闭
+	stopCh         chan struct{}
 }
 
 // NewDNSServer 创建一个新的DNS服务器
 func NewDNSServer(cfg *config.Config, logger config.Logger) Server {
-	return &DNSServer{
+	disabled := &disabledUpstreams{}
+	s := &DNSServer{
 		cfg:         cfg,
 		logger:      logger,
-		shutdownErr: make(chan error, 2), // 用于收集UDP和TCP服务器的关闭错误
+		shutdownErr: make(chan error, 2), // 用于收集服务器的运行错误，Start时按服务器数量重新创建
+		upstreams: newUpstreamPool(
+			upstreamAddresses(cfg),
+			cfg.DNS.Upstream.FailureThreshold,
+			time.Duration(cfg.DNS.Upstream.Cooldown)*time.Second,
+			disabled),
+		disabled: disabled,
+		conns: newUpstreamConns(
+			cfg.DNS.Upstream.MaxIdleConns,
+			time.Duration(cfg.DNS.Upstream.IdleTimeout)*time.Second),
+		limiter: newQueryLimiter(cfg.DNS.Overload.MaxInflight),
+		stopCh:  make(chan struct{}),
+	}
+	s.upstreams.setMode(cfg.DNS.Upstream.Mode, cfg.DNS.Upstream.RaceCount)
+	s.forwarding = newForwardRuleSet(
+		cfg.DNS.Upstream.FailureThreshold,
+		time.Duration(cfg.DNS.Upstream.Cooldown)*time.Second,
+		disabled)
+	s.forwarding.setRules(staticForwardRules(cfg))
+	s.nsUpstreams = newNamespaceUpstreams(
+		cfg.DNS.Upstream.FailureThreshold,
+		time.Duration(cfg.DNS.Upstream.Cooldown)*time.Second,
+		disabled)
+	s.policy = newResponsePolicy()
+	s.views = newViewSelector(cfg.DNS.Views, logger)
+	s.analytics = newQueryAnalytics()
+	s.acls = newServiceACLs()
+	s.answerPolicies = newServiceAnswerPolicies()
+	s.ttlPolicies = newTTLPolicies()
+	s.zones = newNamespaceZones()
+	s.aliases = newServiceAliases()
+	s.tsigSecrets = tsigSecrets(cfg.DNS.TSIGKeys)
+	if cfg.DNS.Precompute.TopN > 0 {
+		s.precomputed = newPrecomputedResponses()
+	}
+	return s
+}
+
+// upstreamAddresses 汇总配置中的上游DNS服务器地址
+func upstreamAddresses(cfg *config.Config) []string {
+	addrs := make([]string, 0, len(cfg.DNS.Upstream.Servers)+1)
+	if cfg.DNS.UpstreamDNS != "" {
+		addrs = append(addrs, cfg.DNS.UpstreamDNS)
 	}
+	return append(addrs, cfg.DNS.Upstream.Servers...)
 }
 
 // SetEtcdClient 设置etcd客户端
@@ -52,123 +145,399 @@ func (s *DNSServer) SetEtcdClient(client etcdclient.Client) {
 	s.etcdClient = client
 }
 
-// Start 启动DNS服务器
+// UpstreamStatus 返回所有上游DNS服务器的健康状态
+func (s *DNSServer) UpstreamStatus() []UpstreamStatus {
+	return append(append(s.upstreams.status(), s.forwarding.status()...), s.nsUpstreams.status()...)
+}
+
+// SetUpstreamDisabled 立即停用或恢复上游DNS服务器，地址不属于任何上游池时返回false
+// 停用状态同时保存在etcd中，其他节点在下次加载运行时配置时生效
+func (s *DNSServer) SetUpstreamDisabled(addr string, disabled bool) bool {
+	found := s.upstreams.contains(addr)
+	for _, pool := range append(s.forwarding.pools(), s.nsUpstreams.allPools()...) {
+		found = found || pool.contains(addr)
+	}
+	if !found {
+		return false
+	}
+	s.disabled.update(addr, disabled)
+	return true
+}
+
+// PolicyStatus 返回拦截规则及其命中次数
+func (s *DNSServer) PolicyStatus() []PolicyRuleStatus {
+	return s.policy.status()
+}
+
+// DNSStats 返回最近window时间内的查询统计
+func (s *DNSServer) DNSStats(window time.Duration, topN int) DNSStats {
+	return s.analytics.snapshot(window, topN, time.Now())
+}
+
+// Start 启动DNS服务器，在配置的每个监听地址上按协议启动UDP和TCP服务器
+// 开启套接字激活或监听地址指定了文件描述符时，直接使用继承的已绑定套接字，无需绑定特权端口的权限
+// 所有地址都在返回前完成绑定，任一地址绑定失败时关闭已启动的服务器并返回错误
 func (s *DNSServer) Start() error {
-	s.logger.Info("启动DNS服务器",
-		zap.String("address", s.cfg.DNS.ListenAddress),
-		zap.Int("port", s.cfg.DNS.Port),
-		zap.String("protocol", s.cfg.DNS.Protocol))
+	// 升级时使用旧进程传递的套接字
+	files, err := upgrade.Take(upgrade.NameDNS)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 && s.cfg.DNS.SocketActivation {
+		if files, err = upgrade.TakeAll(); err != nil {
+			return err
+		}
+		if len(files) == 0 {
+			s.logger.Warn("已开启套接字激活，但没有收到systemd传递的套接字，使用配置的监听地址")
+		}
+	}
+
+	var listeners []config.Listener
+	if len(files) == 0 {
+		listeners = s.cfg.DNSListeners()
+	}
+	for _, l := range listeners {
+		if l.FD > 0 {
+			continue
+		}
+		switch l.Protocol {
+		case "udp", "tcp", "both":
+		default:
+			return fmt.Errorf("不支持的DNS协议: %s", l.Protocol)
+		}
+	}
+
+	// 开启查询捕获
+	if s.cfg.DNS.Capture.Path != "" {
+		capture, err := openQueryCapture(s.cfg, s.logger)
+		if err != nil {
+			return err
+		}
+		s.capture = capture
+		s.logger.Info("已开启查询捕获", zap.String("path", s.cfg.DNS.Capture.Path))
+	}
 
 	// 创建DNS处理器
 	handler := dns.NewServeMux()
 	handler.HandleFunc(".", s.handleDNSRequest)
 
+	s.shutdownErr = make(chan error, len(files)+2*len(listeners))
+	for i, f := range files {
+		if err := s.startFileServer(f, handler); err != nil {
+			closeFiles(files[i+1:])
+			s.closeServers(context.Background())
+			return err
+		}
+	}
+	for _, l := range listeners {
+		var err error
+		if l.FD > 0 {
+			err = s.startFileServer(os.NewFile(uintptr(l.FD), "fd"+strconv.Itoa(l.FD)), handler)
+		} else {
+			err = s.startListener(l, handler)
+		}
+		if err != nil {
+			s.closeServers(context.Background())
+			return err
+		}
+	}
+
+	// 启动上游恢复探测和运行时配置热加载
+	s.applyRuntimeConfig()
+	go s.probeUpstreams()
+	go s.reloadRuntimeConfig()
+	if s.precomputed != nil {
+		go s.precomputePeriodically()
+	}
+
+	// 预取上次运行保存的热门域名后再返回，之后节点才被视为就绪（如升级时通知旧进程退出）
+	if s.cfg.DNS.Prefetch.Path != "" {
+		timeout := time.Duration(s.cfg.DNS.Prefetch.Timeout) * time.Second
+		if timeout <= 0 {
+			timeout = defaultPrefetchTimeout
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		s.prefetch(ctx)
+		cancel()
+		go s.saveHotNamesPeriodically()
+	}
+
+	return nil
+}
+
+// startListener 按监听地址的协议绑定并启动UDP和TCP服务器
+func (s *DNSServer) startListener(l config.Listener, handler dns.Handler) error {
 	// 创建服务器地址
-	addr := net.JoinHostPort(s.cfg.DNS.ListenAddress, strconv.Itoa(s.cfg.DNS.Port))
+	addr := net.JoinHostPort(l.Address, strconv.Itoa(l.Port))
+	s.logger.Info("启动DNS服务器",
+		zap.String("address", l.Address),
+		zap.Int("port", l.Port),
+		zap.String("protocol", l.Protocol))
 
-	// 根据配置启动对应协议的服务器
-	switch s.cfg.DNS.Protocol {
-	case "udp":
-		return s.startUDPServer(addr, handler)
-	case "tcp":
-		return s.startTCPServer(addr, handler)
-	case "both":
+	if l.Protocol == "udp" || l.Protocol == "both" {
 		if err := s.startUDPServer(addr, handler); err != nil {
 			return err
 		}
+	}
+	if l.Protocol == "tcp" || l.Protocol == "both" {
 		return s.startTCPServer(addr, handler)
-	default:
-		return fmt.Errorf("不支持的DNS协议: %s", s.cfg.DNS.Protocol)
 	}
+	return nil
 }
 
-// startUDPServer 启动UDP服务器
+// startUDPServer 绑定UDP地址并在后台启动服务器，配置了多个UDP套接字时每个套接字启动一个服务器
 func (s *DNSServer) startUDPServer(addr string, handler dns.Handler) error {
-	s.udpServer = &dns.Server{
-		Addr:    addr,
-		Net:     "udp",
-		Handler: handler,
-	}
-
-	s.logger.Info("启动UDP DNS服务器", zap.String("addr", addr))
-
-	// 在后台启动UDP服务器
-	go func() {
-		if err := s.udpServer.ListenAndServe(); err != nil {
-			// miekg/dns没有ErrServerClosed，我们需要自己判断服务关闭情况
-			s.logger.Error("UDP DNS服务器错误", zap.Error(err))
-			s.shutdownErr <- err
+	if n := s.udpSockets(); n > 1 {
+		conns, err := listenUDPReusePort(addr, n)
+		if err != nil {
+			return err
 		}
-	}()
+		s.logger.Info("启动UDP DNS服务器",
+			zap.String("addr", conns[0].LocalAddr().String()), zap.Int("sockets", n))
+		for i, conn := range conns {
+			server := &dns.Server{PacketConn: conn, Net: "udp", Handler: handler, TsigSecret: s.tsigSecrets}
+			if err := s.serve(server, "UDP"); err != nil {
+				for _, c := range conns[i+1:] {
+					c.Close()
+				}
+				return err
+			}
+		}
+		return nil
+	}
 
-	return nil
+	conn, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return fmt.Errorf("监听UDP地址 %s 失败: %w", addr, err)
+	}
+	server := &dns.Server{PacketConn: conn, Net: "udp", Handler: handler, TsigSecret: s.tsigSecrets}
+	s.logger.Info("启动UDP DNS服务器", zap.String("addr", conn.LocalAddr().String()))
+	return s.serve(server, "UDP")
 }
 
-// startTCPServer 启动TCP服务器
+// startTCPServer 绑定TCP地址并在后台启动服务器
 func (s *DNSServer) startTCPServer(addr string, handler dns.Handler) error {
-	s.tcpServer = &dns.Server{
-		Addr:    addr,
-		Net:     "tcp",
-		Handler: handler,
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("监听TCP地址 %s 失败: %w", addr, err)
+	}
+	server := &dns.Server{Listener: ln, Net: "tcp", Handler: handler, TsigSecret: s.tsigSecrets}
+	s.logger.Info("启动TCP DNS服务器", zap.String("addr", ln.Addr().String()))
+	return s.serve(server, "TCP")
+}
+
+// startFileServer 在继承的已绑定套接字上启动服务器，按套接字类型选择UDP或TCP
+func (s *DNSServer) startFileServer(f *os.File, handler dns.Handler) error {
+	ln, conn, err := fileConn(f)
+	f.Close()
+	if err != nil {
+		return err
 	}
 
-	s.logger.Info("启动TCP DNS服务器", zap.String("addr", addr))
+	if ln != nil {
+		server := &dns.Server{Listener: ln, Net: "tcp", Handler: handler, TsigSecret: s.tsigSecrets}
+		s.logger.Info("在继承的套接字上启动TCP DNS服务器",
+			zap.String("fd", f.Name()), zap.String("addr", ln.Addr().String()))
+		return s.serve(server, "TCP")
+	}
+	server := &dns.Server{PacketConn: conn, Net: "udp", Handler: handler, TsigSecret: s.tsigSecrets}
+	s.logger.Info("在继承的套接字上启动UDP DNS服务器",
+		zap.String("fd", f.Name()), zap.String("addr", conn.LocalAddr().String()))
+	return s.serve(server, "UDP")
+}
+
+// serve 在后台处理已绑定连接上的请求，等待服务器开始处理后返回
+func (s *DNSServer) serve(server *dns.Server, protocol string) error {
+	started := make(chan struct{})
+	server.NotifyStartedFunc = func() { close(started) }
 
-	// 在后台启动TCP服务器
+	failed := make(chan error, 1)
 	go func() {
-		if err := s.tcpServer.ListenAndServe(); err != nil {
+		if err := server.ActivateAndServe(); err != nil {
 			// miekg/dns没有ErrServerClosed，我们需要自己判断服务关闭情况
-			s.logger.Error("TCP DNS服务器错误", zap.Error(err))
+			s.logger.Error(protocol+" DNS服务器错误", zap.Error(err))
+			failed <- err
 			s.shutdownErr <- err
 		}
 	}()
 
-	return nil
+	select {
+	case <-started:
+		s.servers = append(s.servers, server)
+		return nil
+	case err := <-failed:
+		return fmt.Errorf("启动%s DNS服务器失败: %w", protocol, err)
+	}
 }
 
 // Shutdown 优雅关闭DNS服务器
 func (s *DNSServer) Shutdown(ctx context.Context) error {
-	s.logger.Info("正在关闭DNS服务器...")
+	s.log(ctx).Info("正在关闭DNS服务器...")
 
-	// 关闭UDP服务器
-	if s.udpServer != nil {
-		if err := s.udpServer.ShutdownContext(ctx); err != nil {
-			s.logger.Error("关闭UDP DNS服务器出错", zap.Error(err))
-			return err
+	// 停止上游恢复探测和配置热加载
+	select {
+	case <-s.stopCh:
+	default:
+		close(s.stopCh)
+	}
+
+	err := s.closeServers(ctx)
+	s.conns.close()
+	if s.cfg.DNS.Prefetch.Path != "" {
+		if saveErr := s.saveHotNames(); saveErr != nil {
+			s.log(ctx).Warn("保存热门域名失败", zap.Error(saveErr))
 		}
-		s.logger.Info("UDP DNS服务器已关闭")
 	}
+	// 所有服务器关闭后不会再有新的查询记录
+	if captureErr := s.capture.close(); captureErr != nil && err == nil {
+		err = captureErr
+	}
+	s.capture = nil
+	return err
+}
 
-	// 关闭TCP服务器
-	if s.tcpServer != nil {
-		if err := s.tcpServer.ShutdownContext(ctx); err != nil {
-			s.logger.Error("关闭TCP DNS服务器出错", zap.Error(err))
-			return err
+// closeServers 关闭所有已启动的服务器，返回第一个错误
+func (s *DNSServer) closeServers(ctx context.Context) error {
+	var firstErr error
+	for _, server := range s.servers {
+		if err := server.ShutdownContext(ctx); err != nil {
+			s.log(ctx).Error("关闭DNS服务器出错", zap.String("net", server.Net), zap.Error(err))
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
 		}
-		s.logger.Info("TCP DNS服务器已关闭")
+		s.log(ctx).Info("DNS服务器已关闭", zap.String("net", server.Net), zap.String("addr", serverAddr(server)))
+	}
+	s.servers = nil
+	return firstErr
+}
+
+// Addrs 返回所有服务器实际监听的地址，格式为 协议://地址
+func (s *DNSServer) Addrs() []string {
+	addrs := make([]string, 0, len(s.servers))
+	for _, server := range s.servers {
+		addrs = append(addrs, server.Net+"://"+serverAddr(server))
 	}
+	return addrs
+}
 
-	return nil
+// serverAddr 返回服务器实际监听的地址
+func serverAddr(server *dns.Server) string {
+	if server.PacketConn != nil {
+		return server.PacketConn.LocalAddr().String()
+	}
+	if server.Listener != nil {
+		return server.Listener.Addr().String()
+	}
+	return server.Addr
 }
 
 // handleDNSRequest 处理DNS请求
 func (s *DNSServer) handleDNSRequest(w dns.ResponseWriter, r *dns.Msg) {
+	// 同时处理的查询数达到上限时立即拒绝，不再查询etcd或上游
+	if !s.limiter.acquire() {
+		s.shed(w, r)
+		return
+	}
+	defer s.limiter.release()
+	start := time.Now()
+
+	// 热门服务域名直接发送预生成的应答，不再查询etcd
+	if s.servePrecomputed(w, r) {
+		return
+	}
+
+	queryID := newQueryID()
+	ctx := config.ContextWithLogFields(context.Background(), zap.String("query_id", queryID))
+
+	// 报文已由miekg/dns解析完成，解析结果作为根span的属性记录
+	ctx, span := tracing.Start(ctx, "dns.query",
+		oteltrace.WithSpanKind(oteltrace.SpanKindServer),
+		oteltrace.WithAttributes(
+			attribute.String("dns.query_id", queryID),
+			attribute.Int("dns.id", int(r.Id)),
+			attribute.Int("dns.question_count", len(r.Question)),
+			attribute.String("dns.client", addrString(w.RemoteAddr())),
+		))
+	defer span.End()
+	// 每个查询都有独立的超时，超时后正在进行的etcd查询和上游转发都会被取消，慢速上游不会堆积goroutine
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+	if len(r.Question) > 0 {
+		span.SetAttributes(
+			attribute.String("dns.question.name", r.Question[0].Name),
+			attribute.String("dns.question.type", dns.TypeToString[r.Question[0].Qtype]),
+		)
+	}
+
+	req, identity, ok := s.requestIdentity(w, r)
+	var m *dns.Msg
+	if ok {
+		m = s.resolve(ctx, req, w.RemoteAddr(), identity, nil)
+	} else {
+		m = new(dns.Msg)
+		m.SetRcode(req, dns.RcodeNotAuth)
+	}
+	s.fitResponse(w, r, m)
+	// 签名的查询使用同一密钥签名应答，由miekg/dns在发送时生成签名
+	if t := r.IsTsig(); t != nil && identity != "" {
+		m.SetTsig(t.Hdr.Name, t.Algorithm, t.Fudge, time.Now().Unix())
+	}
+	span.SetAttributes(attribute.String("dns.rcode", dns.RcodeToString[m.Rcode]))
+
+	// 记录查询统计
+	now := time.Now()
+	for _, q := range r.Question {
+		s.analytics.record(q.Name, m.Rcode, now)
+	}
+	s.capture.record(w, r, m, start)
+
+	// 发送响应
+	s.writeResponse(ctx, w, m)
+}
+
+// resolve 执行完整的解析流程并返回响应，identity为查询通过TSIG校验的身份，trace不为空时记录每一步的解析过程
+func (s *DNSServer) resolve(ctx context.Context, r *dns.Msg, addr net.Addr, identity string, trace *ResolveTrace) *dns.Msg {
 	m := new(dns.Msg)
+	// 问题数不为1的查询无法得到明确的应答（RFC 9619），返回FORMERR，不查询etcd也不转发
+	if len(r.Question) != 1 {
+		trace.add("query", "查询包含%d个问题，返回FORMERR", len(r.Question))
+		m.SetRcode(r, dns.RcodeFormatError)
+		return m
+	}
 	m.SetReply(r)
 	m.Authoritative = true
 
 	// 标记是否处理了所有查询
 	allQueriesHandled := true
 
+	// 根据客户端来源地址选择视图
+	client := queryClient{addr: addr, view: s.views.selectView(addr), identity: identity}
+	trace.setView(client.view)
+
 	// 遍历所有的问题
 	for _, q := range r.Question {
-		s.logger.Info("收到DNS查询",
+		s.log(ctx).Info("收到DNS查询",
+			zap.Uint16("id", r.Id),
 			zap.String("name", q.Name),
 			zap.String("type", dns.TypeToString[q.Qtype]),
-			zap.String("client", w.RemoteAddr().String()))
+			zap.String("client", addrString(addr)))
+
+		// 命中拦截规则时直接按规则响应，不再查询etcd或上游
+		if rule := s.policy.match(q.Name); rule != nil {
+			s.log(ctx).Info("DNS查询命中拦截规则",
+				zap.String("name", q.Name),
+				zap.String("rule", rule.ID),
+				zap.String("action", rule.Action))
+			trace.add("policy", "命中拦截规则 %s (%s %s)，动作 %s", rule.ID, rule.Match, rule.Pattern, rule.Action)
+			applyPolicy(rule, q, m)
+			return m
+		}
+		trace.add("policy", "未命中拦截规则")
 
 		// 处理DNS查询
-		found := s.handleQuery(q, m)
+		found := s.handleQuery(ctx, q, client, m, trace)
 
 		// 如果没有找到答案，标记为未处理所有查询
 		if !found {
@@ -177,45 +546,125 @@ func (s *DNSServer) handleDNSRequest(w dns.ResponseWriter, r *dns.Msg) {
 	}
 
 	// 如果没有处理所有查询，并且配置了上游DNS，尝试转发
-	if !allQueriesHandled && s.cfg.DNS.UpstreamDNS != "" {
-		err := s.forwardToUpstream(r, m)
+	pool := s.selectUpstreamPool(r, addr)
+	if !allQueriesHandled && pool.size() > 0 {
+		if pool.rule != "" {
+			trace.add("forward", "匹配条件转发规则 %s", pool.rule)
+		}
+		if pool.namespace != "" {
+			trace.add("forward", "使用命名空间 %s 的上游DNS", pool.namespace)
+		}
+		addr, err := s.forward(ctx, pool, r, m)
 		if err != nil {
-			s.logger.Error("向上游DNS转发查询失败", zap.Error(err))
+			s.log(ctx).Error("向上游DNS转发查询失败", zap.Error(err))
+			trace.add("upstream", "转发失败: %v", err)
 			// 如果转发失败，设置响应代码为 SERVFAIL
 			m.SetRcode(r, dns.RcodeServerFailure)
+		} else {
+			trace.setUpstream(addr)
+			trace.add("upstream", "由上游 %s 应答", addr)
 		}
 	} else if !allQueriesHandled {
 		// 如果没有找到答案且没有配置上游DNS，设置响应代码为 NXDOMAIN
+		trace.add("upstream", "未配置上游DNS，返回NXDOMAIN")
 		m.SetRcode(r, dns.RcodeNameError)
 	}
 
-	// 发送响应
+	s.shapeAnswers(m, client, trace)
+	return m
+}
+
+// 未配置时单个查询的超时时间
+const defaultQueryTimeout = 5 * time.Second
+
+// queryContext 返回带有查询超时的上下文
+func (s *DNSServer) queryContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	timeout := time.Duration(s.cfg.DNS.QueryTimeout) * time.Millisecond
+	if timeout <= 0 {
+		timeout = defaultQueryTimeout
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// log 返回附加了ctx中日志字段（如查询ID）的日志记录器
+func (s *DNSServer) log(ctx context.Context) config.Logger {
+	return config.LoggerFromContext(ctx, s.logger)
+}
+
+// addrString 返回地址的字符串形式，地址为空时返回空字符串
+func addrString(addr net.Addr) string {
+	if addr == nil {
+		return ""
+	}
+	return addr.String()
+}
+
+// writeResponse 发送DNS响应
+func (s *DNSServer) writeResponse(ctx context.Context, w dns.ResponseWriter, m *dns.Msg) {
 	if err := w.WriteMsg(m); err != nil {
-		s.logger.Error("发送DNS响应失败", zap.Error(err))
+		s.log(ctx).Error("发送DNS响应失败", zap.Error(err))
 	}
 }
 
-// forwardToUpstream 将DNS查询转发到上游DNS服务器
-func (s *DNSServer) forwardToUpstream(r *dns.Msg, m *dns.Msg) error {
-	s.logger.Info("转发查询到上游DNS服务器",
-		zap.String("upstream", s.cfg.DNS.UpstreamDNS))
+// selectUpstreamPool 根据查询域名和客户端地址选择上游池，依次使用：
+// 查询名称所属命名空间（服务域名或自定义区域）覆盖的上游、匹配的条件转发规则、
+// 客户端网段所属命名空间覆盖的上游，都没有时使用默认上游
+func (s *DNSServer) selectUpstreamPool(r *dns.Msg, addr net.Addr) *upstreamPool {
+	if len(r.Question) > 0 {
+		name := strings.TrimSuffix(strings.ToLower(r.Question[0].Name), ".")
+		if pool := s.nsUpstreams.pool(s.namespaceOf(name)); pool != nil {
+			return pool
+		}
+		if pool := s.forwarding.match(name); pool != nil {
+			return pool
+		}
+	}
+	if pool := s.nsUpstreams.matchClient(addr); pool != nil {
+		return pool
+	}
+	return s.upstreams
+}
 
-	// 创建一个新的客户端
-	c := new(dns.Client)
+// namespaceOf 返回查询名称所属的命名空间，名称不是服务域名也不在自定义区域内时返回空字符串
+func (s *DNSServer) namespaceOf(name string) string {
+	if namespace, ok := etcdclient.NamespaceFromDomain(name); ok {
+		return namespace
+	}
+	namespace, _ := s.zones.namespaceOf(name)
+	return namespace
+}
 
-	// 复制原始请求
-	req := r.Copy()
-	req.Id = dns.Id() // 生成新的ID
+// forwardToUpstream 将DNS查询转发到上游DNS服务器
+func (s *DNSServer) forwardToUpstream(pool *upstreamPool, r *dns.Msg, m *dns.Msg) error {
+	_, err := s.forward(context.Background(), pool, r, m)
+	return err
+}
 
-	// 发送到上游DNS服务器
-	resp, _, err := c.Exchange(req, s.cfg.DNS.UpstreamDNS)
-	if err != nil {
-		return err
+// forward 将DNS查询转发到上游DNS服务器，返回实际应答的上游地址
+func (s *DNSServer) forward(ctx context.Context, pool *upstreamPool, r *dns.Msg, m *dns.Msg) (addr string, err error) {
+	ctx, span := tracing.Start(ctx, "dns.forward", oteltrace.WithAttributes(attribute.String("dns.forward_rule", pool.rule)))
+	defer func() {
+		span.SetAttributes(attribute.String("dns.upstream", addr))
+		tracing.EndSpan(span, err)
+	}()
+
+	candidates := pool.candidates(time.Now())
+	if len(candidates) == 0 {
+		return "", fmt.Errorf("所有上游DNS服务器均已熔断")
 	}
 
-	// 检查响应
-	if resp == nil {
-		return fmt.Errorf("上游DNS返回空响应")
+	c := s.upstreamClient()
+
+	var resp *dns.Msg
+	mode, raceCount := pool.forwardMode()
+	span.SetAttributes(attribute.String("dns.forward_mode", mode))
+	if mode == etcdclient.UpstreamModeRace && len(candidates) > 1 {
+		resp, addr, err = s.raceUpstreams(ctx, c, pool, r, candidates, raceCount)
+	} else {
+		resp, addr, err = s.sequentialUpstreams(ctx, c, pool, r, candidates)
+	}
+	if err != nil {
+		return "", err
 	}
 
 	// 将上游DNS的响应复制到我们的响应中
@@ -225,18 +674,384 @@ func (s *DNSServer) forwardToUpstream(r *dns.Msg, m *dns.Msg) error {
 	m.Rcode = resp.Rcode
 	m.Authoritative = false // 因为这是从上游转发的，所以不是权威响应
 
-	return nil
+	return addr, nil
+}
+
+// sequentialUpstreams 按健康评分依次尝试未熔断的上游，直到有一个成功
+func (s *DNSServer) sequentialUpstreams(ctx context.Context, c *dns.Client, pool *upstreamPool, r *dns.Msg, candidates []*upstream) (*dns.Msg, string, error) {
+	var lastErr error
+	for _, u := range candidates {
+		// 查询已超时或被取消时不再尝试剩余的上游
+		if err := ctx.Err(); err != nil {
+			return nil, "", err
+		}
+		s.log(ctx).Info("转发查询到上游DNS服务器",
+			zap.String("upstream", u.addr))
+
+		resp, err := s.exchangeWithUpstream(ctx, c, pool, r, u)
+		if err != nil {
+			s.log(ctx).Warn("上游DNS查询失败",
+				zap.String("upstream", u.addr),
+				zap.Error(err))
+			lastErr = err
+			continue
+		}
+		return resp, u.addr, nil
+	}
+
+	return nil, "", lastErr
+}
+
+// raceUpstreams 同时向评分最高的N个上游发送查询，返回最先成功的响应，并取消其余仍在进行的查询
+func (s *DNSServer) raceUpstreams(ctx context.Context, c *dns.Client, pool *upstreamPool, r *dns.Msg, candidates []*upstream, n int) (*dns.Msg, string, error) {
+	if n > len(candidates) {
+		n = len(candidates)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		resp *dns.Msg
+		addr string
+		err  error
+	}
+
+	// 使用带缓冲的通道，避免较慢的查询在返回后阻塞
+	results := make(chan result, n)
+	for _, u := range candidates[:n] {
+		s.log(ctx).Info("并发转发查询到上游DNS服务器",
+			zap.String("upstream", u.addr))
+
+		go func(u *upstream) {
+			resp, err := s.exchangeWithUpstream(ctx, c, pool, r, u)
+			if err != nil && !errors.Is(err, context.Canceled) {
+				s.log(ctx).Warn("上游DNS查询失败",
+					zap.String("upstream", u.addr),
+					zap.Error(err))
+			}
+			results <- result{resp: resp, addr: u.addr, err: err}
+		}(u)
+	}
+
+	var lastErr error
+	for i := 0; i < n; i++ {
+		res := <-results
+		if res.err == nil {
+			return res.resp, res.addr, nil
+		}
+		lastErr = res.err
+	}
+
+	return nil, "", lastErr
+}
+
+// upstreamClient 返回向上游发送查询的客户端，按配置使用UDP或TCP
+func (s *DNSServer) upstreamClient() *dns.Client {
+	c := new(dns.Client)
+	if s.cfg.DNS.Upstream.Protocol == "tcp" {
+		c.Net = "tcp"
+	}
+	return c
+}
+
+// exchangeWithUpstream 向单个上游发送查询并记录健康统计，ctx被取消时立即返回
+func (s *DNSServer) exchangeWithUpstream(ctx context.Context, c *dns.Client, pool *upstreamPool, r *dns.Msg, u *upstream) (*dns.Msg, error) {
+	// 复制原始请求
+	req := r.Copy()
+	req.Id = dns.Id() // 生成新的ID
+	caseRandomized := s.cfg.DNS.Upstream.CaseRandomization
+	if caseRandomized {
+		for i := range req.Question {
+			req.Question[i].Name = randomizeCase(req.Question[i].Name)
+		}
+	}
+
+	start := time.Now()
+	var resp *dns.Msg
+	var err error
+	if s.cfg.Chaos.Enabled && chaos.Global().UpstreamTimeout() {
+		err = injectedUpstreamTimeout(ctx)
+	} else {
+		resp, err = s.conns.exchange(ctx, c, req, u.addr)
+	}
+	s.log(ctx).Debug("上游DNS事务结束",
+		zap.Uint16("id", r.Id),
+		zap.Uint16("upstream_id", req.Id),
+		zap.String("upstream", u.addr),
+		zap.Duration("duration", time.Since(start)),
+		zap.Error(err))
+	if err == nil && resp == nil {
+		err = fmt.Errorf("上游DNS返回空响应")
+	}
+	// 问题与查询不一致的应答可能是伪造的，按失败处理
+	if err == nil {
+		err = validateUpstreamResponse(req, resp, caseRandomized)
+	}
+	if err == nil && resp.Rcode == dns.RcodeServerFailure {
+		err = fmt.Errorf("上游DNS返回SERVFAIL")
+	}
+
+	if err != nil {
+		// 因其他上游已应答而被取消的查询不计入失败
+		if !errors.Is(ctx.Err(), context.Canceled) {
+			pool.recordFailure(u, time.Now(), err)
+		}
+		return nil, err
+	}
+
+	pool.recordSuccess(u, time.Since(start))
+	if caseRandomized && len(r.Question) > 0 {
+		restoreCase(resp, r.Question[0].Name)
+	}
+	return resp, nil
+}
+
+// reloadRuntimeConfig 定期从etcd加载运行时配置
+func (s *DNSServer) reloadRuntimeConfig() {
+	interval := time.Duration(s.cfg.DNS.Upstream.ReloadInterval) * time.Second
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stopCh:
+			return
+		case <-ticker.C:
+			s.applyRuntimeConfig()
+		}
+	}
+}
+
+// applyRuntimeConfig 从etcd读取上游、转发和响应策略配置并应用
+func (s *DNSServer) applyRuntimeConfig() {
+	s.applyUpstreamConfig()
+	s.applyNamespaceUpstreams()
+	s.applyResponsePolicy()
+	s.applyServiceACLs()
+	s.applyServiceAnswerPolicies()
+	s.applyTTLPolicies()
+	s.applyNamespaceZones()
+	s.applyServiceAliases()
+	s.applyLoadWeights()
+}
+
+// applyServiceAliases 从etcd加载服务别名
+func (s *DNSServer) applyServiceAliases() {
+	if s.etcdClient == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	aliases, err := s.etcdClient.ListServiceAliases(ctx)
+	if err != nil {
+		s.logger.Debug("读取服务别名失败", zap.Error(err))
+		return
+	}
+	s.aliases.setAliases(aliases)
+}
+
+// applyNamespaceUpstreams 从etcd加载命名空间覆盖的上游设置
+func (s *DNSServer) applyNamespaceUpstreams() {
+	if s.etcdClient == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	namespaces, err := s.etcdClient.ListNamespaces(ctx)
+	if err != nil {
+		s.logger.Debug("读取命名空间上游设置失败", zap.Error(err))
+		return
+	}
+	s.nsUpstreams.setNamespaces(namespaces, s.logger)
+}
+
+// applyNamespaceZones 从etcd加载命名空间的自定义区域
+func (s *DNSServer) applyNamespaceZones() {
+	if s.etcdClient == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	zones, err := s.etcdClient.ListNamespaceZones(ctx)
+	if err != nil {
+		s.logger.Debug("读取命名空间区域失败", zap.Error(err))
+		return
+	}
+	s.zones.setZones(zones)
+}
+
+// applyServiceACLs 从etcd加载服务解析ACL
+func (s *DNSServer) applyServiceACLs() {
+	if s.etcdClient == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	acls, err := s.etcdClient.ListServiceACLs(ctx)
+	if err != nil {
+		s.logger.Debug("读取服务解析ACL失败", zap.Error(err))
+		return
+	}
+	s.acls.setACLs(acls, s.logger)
+}
+
+// applyServiceAnswerPolicies 从etcd加载服务应答策略
+func (s *DNSServer) applyServiceAnswerPolicies() {
+	if s.etcdClient == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	policies, err := s.etcdClient.ListServiceAnswerPolicies(ctx)
+	if err != nil {
+		s.logger.Debug("读取服务应答策略失败", zap.Error(err))
+		return
+	}
+	s.answerPolicies.setPolicies(policies)
+}
+
+// applyTTLPolicies 从etcd加载服务和命名空间的TTL策略
+func (s *DNSServer) applyTTLPolicies() {
+	if s.etcdClient == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	policies, err := s.etcdClient.ListServiceTTLPolicies(ctx)
+	if err != nil {
+		s.logger.Debug("读取服务TTL策略失败", zap.Error(err))
+		return
+	}
+	namespaces, err := s.etcdClient.ListNamespaces(ctx)
+	if err != nil {
+		s.logger.Debug("读取命名空间TTL策略失败", zap.Error(err))
+		return
+	}
+	s.ttlPolicies.setServices(policies)
+	s.ttlPolicies.setNamespaces(namespaces)
+}
+
+// applyResponsePolicy 从etcd加载拦截规则
+func (s *DNSServer) applyResponsePolicy() {
+	if s.etcdClient == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	rules, err := s.etcdClient.ListBlockRules(ctx)
+	if err != nil {
+		s.logger.Debug("读取拦截规则失败", zap.Error(err))
+		return
+	}
+	s.policy.setRules(rules)
+}
+
+// applyUpstreamConfig 从etcd读取上游配置和条件转发规则并应用，etcd中没有上游配置时保持现状
+func (s *DNSServer) applyUpstreamConfig() {
+	if s.etcdClient == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// 条件转发规则：配置文件中的规则在前，etcd中相同后缀的规则覆盖之
+	if rules, err := s.etcdClient.ListForwardRules(ctx); err != nil {
+		s.logger.Debug("读取条件转发规则失败", zap.Error(err))
+	} else {
+		s.forwarding.setRules(append(staticForwardRules(s.cfg), rules...))
+	}
+
+	if disabled, err := s.etcdClient.ListDisabledUpstreams(ctx); err != nil {
+		s.logger.Debug("读取停用的上游DNS失败", zap.Error(err))
+	} else {
+		addrs := make([]string, 0, len(disabled))
+		for _, d := range disabled {
+			addrs = append(addrs, d.Address)
+		}
+		s.disabled.set(addrs)
+	}
+
+	upstreamCfg, err := s.etcdClient.GetUpstreamConfig(ctx)
+	if err != nil {
+		s.logger.Debug("读取运行时上游DNS配置失败", zap.Error(err))
+		return
+	}
+
+	if err := upstreamCfg.Validate(); err != nil {
+		s.logger.Warn("运行时上游DNS配置无效", zap.Error(err))
+		return
+	}
+
+	s.upstreams.setAddresses(upstreamCfg.Servers)
+	s.upstreams.setMode(upstreamCfg.Mode, upstreamCfg.RaceCount)
+}
+
+// probeUpstreams 定期对已熔断且冷却结束的上游发送探测查询
+func (s *DNSServer) probeUpstreams() {
+	interval := time.Duration(s.cfg.DNS.Upstream.ProbeInterval) * time.Second
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	c := s.upstreamClient()
+	probe := new(dns.Msg)
+	probe.SetQuestion(".", dns.TypeNS)
+
+	for {
+		select {
+		case <-s.stopCh:
+			return
+		case <-ticker.C:
+			s.conns.prune(time.Now())
+			pools := append([]*upstreamPool{s.upstreams}, s.forwarding.pools()...)
+			pools = append(pools, s.nsUpstreams.allPools()...)
+			for _, pool := range pools {
+				for _, u := range pool.probeTargets(time.Now()) {
+					if _, err := s.exchangeWithUpstream(context.Background(), c, pool, probe, u); err != nil {
+						s.logger.Debug("上游DNS恢复探测失败",
+							zap.String("upstream", u.addr),
+							zap.Error(err))
+						continue
+					}
+					s.logger.Info("上游DNS恢复探测成功", zap.String("upstream", u.addr))
+				}
+			}
+		}
+	}
 }
 
 // handleQuery 处理单个DNS查询问题
-func (s *DNSServer) handleQuery(q dns.Question, m *dns.Msg) bool {
+func (s *DNSServer) handleQuery(ctx context.Context, q dns.Question, client queryClient, m *dns.Msg, trace *ResolveTrace) bool {
 	// 1. 移除尾部的点号，并转换为小写
 	domain := strings.TrimSuffix(strings.ToLower(q.Name), ".")
 
 	// 2. 先检查硬编码测试记录
 	if domain == "test.local" && q.Qtype == dns.TypeA {
-		rr, err := dns.NewRR(fmt.Sprintf("%s. A 1.2.3.4", domain))
+		rr, err := dns.NewRR(fmt.Sprintf("%s. %d A 1.2.3.4", domain, s.recordTTL(0)))
 		if err == nil {
+			trace.add("static", "命中硬编码测试记录")
 			m.Answer = append(m.Answer, rr)
 			return true
 		}
@@ -244,132 +1059,346 @@ func (s *DNSServer) handleQuery(q dns.Question, m *dns.Msg) bool {
 
 	// 3. 如果etcdClient未设置，无法查询etcd
 	if s.etcdClient == nil {
-		s.logger.Warn("etcd客户端未设置，无法查询DNS记录")
+		s.log(ctx).Warn("etcd客户端未设置，无法查询DNS记录")
+		trace.add("etcd", "etcd客户端未设置，跳过本地记录查询")
 		return false
 	}
 
-	// 4. 检查是否为服务域名（以.svc.cluster.local结尾）
+	// 4. 命名空间自定义区域中的名称按对应的集群风格服务域名应答，应答中的名称改回查询的名称
+	if serviceDomain, ok := s.zones.serviceDomain(domain); ok {
+		trace.add("zone", "%s 映射为服务域名 %s", domain, serviceDomain)
+		answers, extras := len(m.Answer), len(m.Extra)
+		handled := s.handleName(ctx, serviceDomain, q.Qtype, client, m, trace)
+		renameOwner(m.Answer[answers:], serviceDomain, domain)
+		renameOwner(m.Extra[extras:], serviceDomain, domain)
+		return handled
+	}
+
+	return s.handleName(ctx, domain, q.Qtype, client, m, trace)
+}
+
+// handleName 在本地记录和服务中查找名称，domain为小写且不带尾部点号
+func (s *DNSServer) handleName(ctx context.Context, domain string, qtype uint16, client queryClient, m *dns.Msg, trace *ResolveTrace) bool {
+	// 开启ANY应答时返回该名称所有已知类型的记录
+	if qtype == dns.TypeANY && s.cfg.DNS.AnyQuery {
+		return s.handleAnyQuery(ctx, domain, client, m, trace)
+	}
+
+	// 检查是否为服务域名（以.svc.cluster.local结尾）
+	if strings.HasSuffix(domain, serviceDomainSuffix) {
+		return s.handleServiceQuery(ctx, domain, qtype, client, m, trace)
+	}
+
+	// 处理常规DNS记录查询
+	return s.handleRegularDNSQuery(ctx, domain, qtype, client.view, m, trace)
+}
+
+// ANY查询依次查找的记录类型
+var (
+	anyServiceTypes = []uint16{dns.TypeA, dns.TypeAAAA, dns.TypeSRV, dns.TypeTXT}
+	anyRecordTypes  = []uint16{dns.TypeA, dns.TypeAAAA, dns.TypeCNAME, dns.TypeTXT, dns.TypeSRV}
+)
+
+// handleAnyQuery 处理ANY查询，把该名称各类型的记录集合并到同一个应答中，任一类型找到记录即视为已处理
+func (s *DNSServer) handleAnyQuery(ctx context.Context, domain string, client queryClient, m *dns.Msg, trace *ResolveTrace) bool {
+	types := anyRecordTypes
 	if strings.HasSuffix(domain, serviceDomainSuffix) {
-		return s.handleServiceQuery(domain, q.Qtype, m)
+		types = anyServiceTypes
 	}
 
-	// 5. 处理常规DNS记录查询
-	return s.handleRegularDNSQuery(domain, q.Qtype, m)
+	found := false
+	for _, qtype := range types {
+		trace.add("any", "查询%s记录", dns.TypeToString[qtype])
+		var handled bool
+		if strings.HasSuffix(domain, serviceDomainSuffix) {
+			handled = s.handleServiceQuery(ctx, domain, qtype, client, m, trace)
+		} else {
+			handled = s.handleRegularDNSQuery(ctx, domain, qtype, client.view, m, trace)
+		}
+		if handled {
+			found = true
+		}
+		// 服务解析ACL拒绝时不再查询其他类型
+		if m.Rcode != dns.RcodeSuccess {
+			return true
+		}
+	}
+	return found
+}
+
+// 记录未设置TTL且配置中也没有默认值时使用的TTL（秒）
+const fallbackRecordTTL = 300
+
+// recordTTL 返回应答中使用的TTL，记录未设置TTL时依次使用policies（按服务、命名空间的顺序）中的默认TTL
+// 和配置的默认值，结果受各级TTL策略和全局TTL限制的约束，见etcdclient.ResolveTTL
+func (s *DNSServer) recordTTL(ttl int, policies ...*etcdclient.TTLPolicy) int {
+	fallback := fallbackRecordTTL
+	if s.cfg.DNS.DefaultTTL > 0 {
+		fallback = s.cfg.DNS.DefaultTTL
+	}
+	policies = append(policies[:len(policies):len(policies)], s.globalTTLPolicy())
+	return etcdclient.ResolveTTL(ttl, fallback, policies...)
 }
 
 // handleServiceQuery 处理服务发现查询
-func (s *DNSServer) handleServiceQuery(domain string, qtype uint16, m *dns.Msg) bool {
-	ctx := context.Background()
+// 域名为 <服务>.<集群>.svc.cluster.local 且集群是联邦中的远程集群时，从同步的远程目录应答；
+// RFC 2782形式的 _<端口名>._<协议>.<服务>.<命名空间>.svc.cluster.local 只返回提供该端口的实例；
+// 服务是别名时按目标服务应答（分流的别名每次查询按权重抽取目标），应答中的名称保持为查询的名称
+func (s *DNSServer) handleServiceQuery(ctx context.Context, domain string, qtype uint16, client queryClient, m *dns.Msg, trace *ResolveTrace) bool {
+	serviceName, _ := etcdclient.ServiceNameFromDomain(domain)
+	cluster := s.federatedCluster(ctx, domain, trace)
+	if target := s.aliases.target(serviceName); cluster == "" && target != "" {
+		targetDomain := aliasDomain(domain, serviceName, target)
+		trace.add("alias", "服务 %s 是别名，本次查询使用目标服务 %s", serviceName, target)
+		s.analytics.recordSplit(serviceName, target, time.Now())
+		answers, extras := len(m.Answer), len(m.Extra)
+		handled := s.handleServiceQuery(ctx, targetDomain, qtype, client, m, trace)
+		renameOwner(m.Answer[answers:], targetDomain, domain)
+		renameOwner(m.Extra[extras:], targetDomain, domain)
+		return handled
+	}
+	if cluster != "" {
+		trace.add("federation", "查询集群 %s 的服务 %s", cluster, serviceName)
+	} else {
+		// 服务设置了解析ACL时，只有允许的客户端能得到应答，其他客户端按ACL的动作拒绝且不转发到上游
+		if acl := s.acls.get(serviceName); acl != nil {
+			if !acl.allows(client) {
+				s.log(ctx).Info("客户端无权解析服务",
+					zap.String("service", serviceName),
+					zap.String("client", addrString(client.addr)),
+					zap.String("identity", client.identity))
+				trace.add("acl", "客户端不在服务 %s 的解析ACL中，返回%s", serviceName, dns.RcodeToString[acl.rcode()])
+				m.Rcode = acl.rcode()
+				return true
+			}
+			trace.add("acl", "客户端在服务 %s 的解析ACL中", serviceName)
+		}
+		trace.add("etcd", "查询服务实例 %s", etcdclient.ServiceInstancePrefix(serviceName))
+	}
+
+	// 应答策略只作用于本集群的服务
+	var policy *etcdclient.ServiceAnswerPolicy
+	if cluster == "" {
+		if policy = s.answerPolicies.get(serviceName); policy != nil {
+			trace.add("policy", "服务 %s 的应答策略: 地址应答%s，顺序%s，包括摘流实例%t",
+				serviceName, policy.AddressAnswers, policy.Order, policy.IncludeDraining)
+		}
+	}
+
+	// TXT查询返回命名空间允许公开的实例元数据，远程集群的服务不支持
+	if qtype == dns.TypeTXT {
+		if cluster != "" {
+			return false
+		}
+		return s.handleServiceTXTQuery(ctx, domain, m, trace)
+	}
 
 	// 如果请求的是SRV记录，我们需要特别处理
 	if qtype == dns.TypeSRV {
-		return s.handleSRVQuery(domain, m)
+		return s.handleSRVQuery(ctx, cluster, domain, policy, client, m, trace)
 	}
 
-	// 对于A记录，我们返回服务的IP地址
-	if qtype == dns.TypeA {
-		records, err := s.etcdClient.ServiceToDNSRecords(ctx, domain)
+	// 对于A和AAAA记录，返回服务对应地址族的IP地址
+	if qtype == dns.TypeA || qtype == dns.TypeAAAA {
+		records, err := s.serviceRecords(ctx, cluster, domain, s.recordOptions(cluster, domain, policy))
 		if err != nil {
-			s.logger.Debug("获取服务DNS记录失败",
+			s.log(ctx).Debug("获取服务DNS记录失败",
 				zap.String("domain", domain),
 				zap.Error(err))
-			return false
+			trace.add("service", "没有可用的服务实例: %v", err)
+			// Kong兼容模式下域名可能是SRV应答中的目标名称，其中的服务名称所在位置与命名空间相同
+			if s.kongCompat() && s.handleInstanceTarget(ctx, domain, qtype, client, m, trace) {
+				return true
+			}
+			return s.namespaceMissing(err, m, trace)
+		}
+
+		recordType := dns.TypeToString[qtype]
+		record, ok := records[recordType]
+		if !ok {
+			// 服务存在但没有该地址族的实例，返回没有应答记录的NOERROR，不转发到上游
+			trace.add("service", "服务没有%s地址的实例", recordType)
+			return true
 		}
 
-		// 查找A记录
-		if aRecord, ok := records["A"]; ok {
-			rr, err := dns.NewRR(fmt.Sprintf("%s. A %s", domain, aRecord.Value))
+		// Kong兼容模式下返回所有实例的地址，每个地址都是Kong负载均衡器的一个目标
+		addrs := s.serviceAddresses(records, record, recordType, policy, client)
+		for _, addr := range addrs {
+			trace.add("service", "匹配实例地址 %s", addr)
+			rr, err := dns.NewRR(fmt.Sprintf("%s. %d %s %s", domain, s.serviceTTL(record.TTL, s.serviceTTLPolicies(cluster, domain)...), recordType, addr))
 			if err != nil {
-				s.logger.Error("创建A记录失败", zap.Error(err))
+				s.log(ctx).Error("创建"+recordType+"记录失败", zap.Error(err))
 				return false
 			}
 			m.Answer = append(m.Answer, rr)
-			return true
 		}
+		return true
 	}
 
 	return false
 }
 
-// handleSRVQuery 处理SRV查询
-func (s *DNSServer) handleSRVQuery(domain string, m *dns.Msg) bool {
-	ctx := context.Background()
+// namespaceMissing 服务域名中的命名空间不存在时以NXDOMAIN应答，不转发到上游
+func (s *DNSServer) namespaceMissing(err error, m *dns.Msg, trace *ResolveTrace) bool {
+	if !errors.Is(err, etcdclient.ErrNamespaceNotFound) {
+		return false
+	}
+	trace.add("service", "命名空间不存在，返回NXDOMAIN")
+	m.Rcode = dns.RcodeNameError
+	return true
+}
+
+// serviceRecords 从etcd获取服务实例对应的DNS记录，cluster不为空时获取远程集群中的服务
+func (s *DNSServer) serviceRecords(ctx context.Context, cluster, domain string, opts etcdclient.ServiceRecordOptions) (map[string]*etcdclient.DNSRecord, error) {
+	if cluster != "" {
+		ctx, span := tracing.Start(ctx, "etcd.get", oteltrace.WithAttributes(attribute.String("federation.cluster", cluster)))
+		records, err := s.etcdClient.FederatedServiceToDNSRecords(ctx, cluster, domain)
+		tracing.EndSpan(span, err)
+		return records, err
+	}
 
+	serviceName, _ := etcdclient.ServiceNameFromDomain(domain)
+	ctx, span := tracing.Start(ctx, "etcd.get", oteltrace.WithAttributes(
+		attribute.String("etcd.prefix", etcdclient.ServiceInstancePrefix(serviceName))))
+	records, err := s.etcdClient.ServiceToDNSRecordsWithOptions(ctx, domain, opts)
+	tracing.EndSpan(span, err)
+	return records, err
+}
+
+// handleSRVQuery 处理SRV查询，服务设置了应答策略时按策略的顺序排列SRV记录
+func (s *DNSServer) handleSRVQuery(ctx context.Context, cluster, domain string, policy *etcdclient.ServiceAnswerPolicy,
+	client queryClient, m *dns.Msg, trace *ResolveTrace) bool {
 	// 获取服务的DNS记录
-	records, err := s.etcdClient.ServiceToDNSRecords(ctx, domain)
+	records, err := s.serviceRecords(ctx, cluster, domain, s.recordOptions(cluster, domain, policy))
 	if err != nil {
-		s.logger.Debug("获取服务DNS记录失败",
+		s.log(ctx).Debug("获取服务DNS记录失败",
 			zap.String("domain", domain),
 			zap.Error(err))
-		return false
+		trace.add("service", "没有可用的服务实例: %v", err)
+		return s.namespaceMissing(err, m, trace)
 	}
 
-	// 添加所有SRV记录
+	// 添加所有SRV记录，并在附加段返回目标的A记录，客户端无需再逐个解析目标
+	first := len(m.Answer)
 	added := false
 	for key, record := range records {
 		if strings.HasPrefix(key, "SRV-") {
-			rr, err := dns.NewRR(fmt.Sprintf("%s. SRV %s", domain, record.Value))
+			rr, err := dns.NewRR(fmt.Sprintf("%s. %d SRV %s", domain, s.serviceTTL(record.TTL, s.serviceTTLPolicies(cluster, domain)...), record.Value))
 			if err != nil {
-				s.logger.Error("创建SRV记录失败", zap.Error(err))
+				s.log(ctx).Error("创建SRV记录失败", zap.Error(err))
 				continue
 			}
+			if srv, ok := rr.(*dns.SRV); ok && s.kongCompat() {
+				kongSRV(srv)
+			}
+			trace.add("service", "匹配实例 %s", record.Value)
 			m.Answer = append(m.Answer, rr)
 			added = true
+
+			if srv, ok := rr.(*dns.SRV); ok && record.Address != "" {
+				glueType := etcdclient.AddressRecordType(record.Address)
+				if glue, err := dns.NewRR(fmt.Sprintf("%s %d %s %s", srv.Target, srv.Hdr.Ttl, glueType, record.Address)); err == nil && glueType != "" {
+					m.Extra = append(m.Extra, glue)
+				}
+			}
 		}
 	}
 
+	if policy != nil && added {
+		orderAnswers(m.Answer[first:], policy.Order, client, func(rr dns.RR) string { return rr.String() })
+	}
 	return added
 }
 
+// handleServiceTXTQuery 处理服务域名的TXT查询，每个实例一条TXT记录，内容为 key=value 形式的元数据
+func (s *DNSServer) handleServiceTXTQuery(ctx context.Context, domain string, m *dns.Msg, trace *ResolveTrace) bool {
+	records, err := s.etcdClient.ServiceTXTRecords(ctx, domain)
+	if err != nil {
+		s.log(ctx).Debug("获取服务TXT记录失败",
+			zap.String("domain", domain),
+			zap.Error(err))
+		trace.add("service", "没有可用的服务实例: %v", err)
+		return s.namespaceMissing(err, m, trace)
+	}
+
+	policies := s.serviceTTLPolicies("", domain)
+	ttl := s.serviceTTL(s.serviceDefaultTTL(policies), policies...)
+	for _, txt := range records {
+		trace.add("service", "实例元数据 %s", strings.Join(txt, " "))
+		m.Answer = append(m.Answer, &dns.TXT{
+			Hdr: dns.RR_Header{Name: dns.Fqdn(domain), Rrtype: dns.TypeTXT, Class: dns.ClassINET, Ttl: uint32(ttl)},
+			Txt: txt,
+		})
+	}
+	return true
+}
+
 // handleRegularDNSQuery 处理常规DNS记录查询
-func (s *DNSServer) handleRegularDNSQuery(domain string, qtype uint16, m *dns.Msg) bool {
+func (s *DNSServer) handleRegularDNSQuery(ctx context.Context, domain string, qtype uint16, view string, m *dns.Msg, trace *ResolveTrace) bool {
 	// 获取记录类型字符串
 	recordType := dns.TypeToString[qtype]
 
 	// 从etcd获取DNS记录
-	ctx := context.Background()
-	record, err := s.etcdClient.GetDNSRecord(ctx, domain, recordType)
+	keys := etcdclient.DNSRecordLookupKeys(view, domain, recordType)
+	trace.add("etcd", "查询键 %s", strings.Join(keys, ", "))
+	spanCtx, span := tracing.Start(ctx, "etcd.get", oteltrace.WithAttributes(attribute.StringSlice("etcd.keys", keys)))
+	record, err := s.etcdClient.GetDNSRecordInView(spanCtx, view, domain, recordType)
+	tracing.EndSpan(span, err)
 	if err != nil {
-		s.logger.Debug("从etcd获取DNS记录失败",
+		s.log(ctx).Debug("从etcd获取DNS记录失败",
 			zap.String("domain", domain),
 			zap.String("type", recordType),
+			zap.String("view", view),
 			zap.Error(err))
-		return false
+		trace.add("etcd", "未找到记录: %v", err)
+		// Kong兼容模式下域名存在其他类型的记录时返回NODATA，不转发到上游
+		return s.kongCompat() && s.kongNameExists(ctx, view, domain, recordType, trace)
 	}
+	trace.add("etcd", "找到记录 %s %s (view=%q)", record.Type, record.Value, record.View)
 
 	// 创建适当的DNS记录响应
+	ttl := s.recordTTL(record.TTL, s.recordTTLPolicies(record)...)
 	switch qtype {
 	case dns.TypeA:
-		rr, err := dns.NewRR(fmt.Sprintf("%s. A %s", domain, record.Value))
+		rr, err := dns.NewRR(fmt.Sprintf("%s. %d A %s", domain, ttl, record.Value))
 		if err != nil {
-			s.logger.Error("创建A记录失败", zap.Error(err))
+			s.log(ctx).Error("创建A记录失败", zap.Error(err))
 			return false
 		}
 		m.Answer = append(m.Answer, rr)
 		return true
 
 	case dns.TypeAAAA:
-		rr, err := dns.NewRR(fmt.Sprintf("%s. AAAA %s", domain, record.Value))
+		rr, err := dns.NewRR(fmt.Sprintf("%s. %d AAAA %s", domain, ttl, record.Value))
 		if err != nil {
-			s.logger.Error("创建AAAA记录失败", zap.Error(err))
+			s.log(ctx).Error("创建AAAA记录失败", zap.Error(err))
 			return false
 		}
 		m.Answer = append(m.Answer, rr)
 		return true
 
 	case dns.TypeCNAME:
-		rr, err := dns.NewRR(fmt.Sprintf("%s. CNAME %s", domain, record.Value))
+		rr, err := dns.NewRR(fmt.Sprintf("%s. %d CNAME %s", domain, ttl, record.Value))
+		if err != nil {
+			s.log(ctx).Error("创建CNAME记录失败", zap.Error(err))
+			return false
+		}
+		m.Answer = append(m.Answer, rr)
+		return true
+
+	case dns.TypePTR:
+		rr, err := dns.NewRR(fmt.Sprintf("%s. %d PTR %s", domain, ttl, record.Value))
 		if err != nil {
-			s.logger.Error("创建CNAME记录失败", zap.Error(err))
+			s.log(ctx).Error("创建PTR记录失败", zap.Error(err))
 			return false
 		}
 		m.Answer = append(m.Answer, rr)
 		return true
 
 	case dns.TypeTXT:
-		rr, err := dns.NewRR(fmt.Sprintf("%s. TXT \"%s\"", domain, record.Value))
+		rr, err := dns.NewRR(fmt.Sprintf("%s. %d TXT \"%s\"", domain, ttl, record.Value))
 		if err != nil {
-			s.logger.Error("创建TXT记录失败", zap.Error(err))
+			s.log(ctx).Error("创建TXT记录失败", zap.Error(err))
 			return false
 		}
 		m.Answer = append(m.Answer, rr)
@@ -377,16 +1406,16 @@ func (s *DNSServer) handleRegularDNSQuery(domain string, qtype uint16, m *dns.Ms
 
 	case dns.TypeSRV:
 		// SRV记录的值格式应为: "priority weight port target"
-		rr, err := dns.NewRR(fmt.Sprintf("%s. SRV %s", domain, record.Value))
+		rr, err := dns.NewRR(fmt.Sprintf("%s. %d SRV %s", domain, ttl, record.Value))
 		if err != nil {
-			s.logger.Error("创建SRV记录失败", zap.Error(err))
+			s.log(ctx).Error("创建SRV记录失败", zap.Error(err))
 			return false
 		}
 		m.Answer = append(m.Answer, rr)
 		return true
 
 	default:
-		s.logger.Warn("不支持的DNS记录类型",
+		s.log(ctx).Warn("不支持的DNS记录类型",
 			zap.String("domain", domain),
 			zap.String("type", recordType))
 		return false
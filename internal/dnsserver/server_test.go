@@ -2,7 +2,8 @@ package dnsserver
 
 import (
 	"context"
-	"os"
+	"fmt"
+	"net"
 	"testing"
 	"time"
 
@@ -11,19 +12,19 @@ import (
 	"github.com/miekg/dns"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 )
 
 // 创建一个测试用的配置，使用环境变量中的etcd地址
 func createTestConfig(t *testing.T) *config.Config {
 	t.Helper()
 
-	// 从环境变量中获取etcd地址
-	etcdEndpoints := os.Getenv("KONG_DISCOVERY_ETCD_ENDPOINTS")
-	require.NotEmpty(t, etcdEndpoints, "环境变量KONG_DISCOVERY_ETCD_ENDPOINTS必须设置")
-
 	// 创建配置
 	cfg := &config.Config{}
-	cfg.Etcd.Endpoints = []string{etcdEndpoints}
+	etcdclient.ApplyTestStorage(cfg)
 	cfg.Etcd.Username = "" // 如果需要认证，设置相应的值
 	cfg.Etcd.Password = "" // 如果需要认证，设置相应的值
 	cfg.DNS.ListenAddress = "127.0.0.1"
@@ -170,6 +171,71 @@ func TestDNSServer_QueryHardcodedRecord(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestDNSServer_MultipleListeners(t *testing.T) {
+	// 跳过集成测试，除非明确要求运行
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	cfg := createTestConfig(t)
+	cfg.DNS.Listeners = []config.Listener{
+		{Address: "127.0.0.1", Port: 15354, Protocol: "udp"},
+		{Address: "127.0.0.1", Port: 15355, Protocol: "tcp"},
+	}
+	logger := createTestLogger(t)
+
+	server := NewDNSServer(cfg, logger)
+	require.NoError(t, server.Start())
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		assert.NoError(t, server.Shutdown(ctx))
+	}()
+
+	m := new(dns.Msg)
+	m.SetQuestion("test.local.", dns.TypeA)
+
+	// 每个监听地址按各自的协议应答
+	for protocol, addr := range map[string]string{"udp": "127.0.0.1:15354", "tcp": "127.0.0.1:15355"} {
+		c := &dns.Client{Net: protocol}
+		r, _, err := c.Exchange(m, addr)
+		require.NoError(t, err, "%s %s", protocol, addr)
+		require.Len(t, r.Answer, 1)
+		assert.Equal(t, "1.2.3.4", r.Answer[0].(*dns.A).A.String())
+	}
+
+	// 未在监听地址上启用的协议不应有服务
+	c := &dns.Client{Net: "tcp", Timeout: 500 * time.Millisecond}
+	_, _, err := c.Exchange(m, "127.0.0.1:15354")
+	assert.Error(t, err)
+}
+
+func TestDNSServer_ListenerBindFailure(t *testing.T) {
+	// 跳过集成测试，除非明确要求运行
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	// 占用一个UDP端口
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer conn.Close()
+	busyPort := conn.LocalAddr().(*net.UDPAddr).Port
+
+	cfg := createTestConfig(t)
+	cfg.DNS.Listeners = []config.Listener{
+		{Address: "127.0.0.1", Port: 15356, Protocol: "udp"},
+		{Address: "127.0.0.1", Port: busyPort, Protocol: "udp"},
+	}
+	server := NewDNSServer(cfg, createTestLogger(t))
+	require.Error(t, server.Start())
+
+	// 绑定失败时已启动的监听地址应被关闭
+	released, err := net.ListenPacket("udp", "127.0.0.1:15356")
+	require.NoError(t, err)
+	released.Close()
+}
+
 func TestDNSServer_QueryEtcdRecord(t *testing.T) {
 	// 跳过集成测试，除非明确要求运行
 	if testing.Short() {
@@ -219,6 +285,7 @@ func TestDNSServer_QueryEtcdRecord(t *testing.T) {
 	if len(r.Answer) > 0 {
 		if a, ok := r.Answer[0].(*dns.A); ok {
 			assert.Equal(t, "5.6.7.8", a.A.String())
+			assert.Equal(t, uint32(300), a.Hdr.Ttl, "应答应使用记录中的TTL")
 		} else {
 			t.Errorf("Expected A record, got %T", r.Answer[0])
 		}
@@ -328,3 +395,409 @@ func TestDNSServer_NoUpstreamDNS(t *testing.T) {
 	err = server.Shutdown(ctx)
 	assert.NoError(t, err)
 }
+
+func TestDNSServer_RecordTTL(t *testing.T) {
+	cfg := &config.Config{}
+	server := NewDNSServer(cfg, createTestLogger(t)).(*DNSServer)
+
+	// 记录自带TTL时直接使用
+	assert.Equal(t, 120, server.recordTTL(120))
+
+	// 未配置默认值时使用内置默认TTL
+	assert.Equal(t, fallbackRecordTTL, server.recordTTL(0))
+
+	// 使用配置的默认TTL
+	cfg.DNS.DefaultTTL = 30
+	assert.Equal(t, 30, server.recordTTL(0))
+	assert.Equal(t, 30, server.recordTTL(-1))
+}
+
+// recordingWriter 记录写出的DNS响应，用于直接调用handleDNSRequest
+type recordingWriter struct {
+	dns.ResponseWriter
+	msg    *dns.Msg
+	packed []byte
+}
+
+func (w *recordingWriter) RemoteAddr() net.Addr {
+	return &net.UDPAddr{IP: net.ParseIP("10.1.2.3"), Port: 53000}
+}
+
+func (w *recordingWriter) WriteMsg(m *dns.Msg) error {
+	w.msg = m
+	return nil
+}
+
+// Write 记录直接写出的应答报文，如预生成的应答
+func (w *recordingWriter) Write(b []byte) (int, error) {
+	w.packed = b
+	w.msg = new(dns.Msg)
+	return len(b), w.msg.Unpack(b)
+}
+
+func TestDNSServer_QuerySpans(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	upstream := startFakeUpstream(t, "10.0.0.9", 0)
+	cfg := &config.Config{}
+	cfg.DNS.Upstream.Servers = []string{upstream}
+	server := NewDNSServer(cfg, createTestLogger(t)).(*DNSServer)
+
+	r := new(dns.Msg)
+	r.SetQuestion("example.com.", dns.TypeA)
+	w := &recordingWriter{}
+	server.handleDNSRequest(w, r)
+	require.NotNil(t, w.msg)
+
+	spans := recorder.Ended()
+	names := make(map[string]sdktrace.ReadOnlySpan, len(spans))
+	for _, span := range spans {
+		names[span.Name()] = span
+	}
+	require.Contains(t, names, "dns.query")
+	require.Contains(t, names, "dns.forward")
+
+	// 转发span是查询span的子span，记录应答的上游
+	root, forward := names["dns.query"], names["dns.forward"]
+	assert.Equal(t, root.SpanContext().SpanID(), forward.Parent().SpanID())
+	assert.Contains(t, forward.Attributes(), attribute.String("dns.upstream", upstream))
+	assert.Contains(t, root.Attributes(), attribute.String("dns.question.name", "example.com."))
+	assert.Contains(t, root.Attributes(), attribute.String("dns.rcode", "NOERROR"))
+}
+
+func TestDNSServer_SRVAdditionalRecords(t *testing.T) {
+	// 跳过集成测试，除非明确要求运行
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	client := etcdclient.CreateEtcdClientForTest(t)
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	serviceName := "srv-glue-service"
+	instance := &etcdclient.ServiceInstance{ServiceName: serviceName, InstanceID: "instance-001", IPAddress: "10.0.0.7", Port: 8080, TTL: 60}
+	require.NoError(t, client.RegisterService(ctx, instance))
+	defer func() { _ = client.DeregisterService(context.Background(), serviceName, "instance-001") }()
+
+	server := NewDNSServer(&config.Config{}, createTestLogger(t)).(*DNSServer)
+	server.SetEtcdClient(client)
+
+	r := new(dns.Msg)
+	r.SetQuestion(serviceName+".default.svc.cluster.local.", dns.TypeSRV)
+	w := &recordingWriter{}
+	server.handleDNSRequest(w, r)
+	require.NotNil(t, w.msg)
+	require.Len(t, w.msg.Answer, 1)
+	require.Len(t, w.msg.Extra, 1)
+
+	// 附加段返回SRV目标的A记录
+	srv := w.msg.Answer[0].(*dns.SRV)
+	glue := w.msg.Extra[0].(*dns.A)
+	assert.Equal(t, uint16(8080), srv.Port)
+	assert.Equal(t, srv.Target, glue.Hdr.Name)
+	assert.Equal(t, "10.0.0.7", glue.A.String())
+}
+
+func TestDNSServer_DualStackServiceQuery(t *testing.T) {
+	// 跳过集成测试，除非明确要求运行
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	client := etcdclient.CreateEtcdClientForTest(t)
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	serviceName := "dual-stack-service"
+	instance := &etcdclient.ServiceInstance{ServiceName: serviceName, InstanceID: "instance-v6", IPAddress: "2001:db8::7", Port: 8080, TTL: 60}
+	require.NoError(t, client.RegisterService(ctx, instance))
+	defer func() { _ = client.DeregisterService(context.Background(), serviceName, "instance-v6") }()
+
+	server := NewDNSServer(&config.Config{}, createTestLogger(t)).(*DNSServer)
+	server.SetEtcdClient(client)
+	query := func(qtype uint16) *dns.Msg {
+		r := new(dns.Msg)
+		r.SetQuestion(serviceName+".default.svc.cluster.local.", qtype)
+		w := &recordingWriter{}
+		server.handleDNSRequest(w, r)
+		require.NotNil(t, w.msg)
+		return w.msg
+	}
+
+	// 只有IPv6实例时AAAA查询返回实例地址
+	m := query(dns.TypeAAAA)
+	require.Len(t, m.Answer, 1)
+	assert.Equal(t, "2001:db8::7", m.Answer[0].(*dns.AAAA).AAAA.String())
+
+	// A查询返回没有应答记录的NOERROR
+	m = query(dns.TypeA)
+	assert.Equal(t, dns.RcodeSuccess, m.Rcode)
+	assert.Empty(t, m.Answer)
+
+	// SRV目标的附加记录使用AAAA
+	m = query(dns.TypeSRV)
+	require.Len(t, m.Extra, 1)
+	assert.Equal(t, "2001:db8::7", m.Extra[0].(*dns.AAAA).AAAA.String())
+}
+
+func TestDNSServer_MultipleQuestions(t *testing.T) {
+	server := NewDNSServer(&config.Config{}, createTestLogger(t)).(*DNSServer)
+
+	// 包含多个问题的查询返回FORMERR
+	r := new(dns.Msg)
+	r.SetQuestion("test.local.", dns.TypeA)
+	r.Question = append(r.Question, dns.Question{Name: "example.com.", Qtype: dns.TypeA, Qclass: dns.ClassINET})
+	w := &recordingWriter{}
+	server.handleDNSRequest(w, r)
+	require.NotNil(t, w.msg)
+	assert.Equal(t, dns.RcodeFormatError, w.msg.Rcode)
+	assert.Empty(t, w.msg.Answer)
+}
+
+func TestDNSServer_AnyQuery(t *testing.T) {
+	// 跳过集成测试，除非明确要求运行
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	client := etcdclient.CreateEtcdClientForTest(t)
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	domain := "any.etcd.local"
+	require.NoError(t, client.PutDNSRecord(ctx, domain, &etcdclient.DNSRecord{Type: "A", Value: "10.0.0.1", TTL: 60}))
+	require.NoError(t, client.PutDNSRecord(ctx, domain, &etcdclient.DNSRecord{Type: "TXT", Value: "v=any", TTL: 60}))
+	defer func() {
+		_ = client.DeleteDNSRecord(context.Background(), domain, "A", "")
+		_ = client.DeleteDNSRecord(context.Background(), domain, "TXT", "")
+	}()
+
+	cfg := &config.Config{}
+	server := NewDNSServer(cfg, createTestLogger(t)).(*DNSServer)
+	server.SetEtcdClient(client)
+	query := func() *dns.Msg {
+		r := new(dns.Msg)
+		r.SetQuestion(domain+".", dns.TypeANY)
+		w := &recordingWriter{}
+		server.handleDNSRequest(w, r)
+		require.NotNil(t, w.msg)
+		return w.msg
+	}
+
+	// 未开启时ANY查询不由本地记录应答
+	assert.Empty(t, query().Answer)
+
+	// 开启后返回该名称所有类型的记录
+	cfg.DNS.AnyQuery = true
+	m := query()
+	assert.Equal(t, dns.RcodeSuccess, m.Rcode)
+	types := make(map[uint16]bool)
+	for _, rr := range m.Answer {
+		types[rr.Header().Rrtype] = true
+	}
+	assert.Equal(t, map[uint16]bool{dns.TypeA: true, dns.TypeTXT: true}, types)
+}
+
+func TestDNSServer_RFC2782ServiceQuery(t *testing.T) {
+	// 跳过集成测试，除非明确要求运行
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	client := etcdclient.CreateEtcdClientForTest(t)
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	serviceName := "rfc2782-service"
+	for id, port := range map[string]int{"http": 8080, "grpc": 9090} {
+		instance := &etcdclient.ServiceInstance{
+			ServiceName: serviceName, InstanceID: id, IPAddress: "10.0.0.8", Port: port, TTL: 60,
+			Metadata: map[string]string{etcdclient.MetadataSRVService: id},
+		}
+		require.NoError(t, client.RegisterService(ctx, instance))
+		defer func(id string) { _ = client.DeregisterService(context.Background(), serviceName, id) }(id)
+	}
+
+	server := NewDNSServer(&config.Config{}, createTestLogger(t)).(*DNSServer)
+	server.SetEtcdClient(client)
+
+	// 直接由注册的实例生成SRV记录，不需要单独写入SRV记录
+	r := new(dns.Msg)
+	r.SetQuestion("_grpc._tcp."+serviceName+".default.svc.cluster.local.", dns.TypeSRV)
+	w := &recordingWriter{}
+	server.handleDNSRequest(w, r)
+	require.NotNil(t, w.msg)
+	require.Len(t, w.msg.Answer, 1)
+	srv := w.msg.Answer[0].(*dns.SRV)
+	assert.Equal(t, "_grpc._tcp."+serviceName+".default.svc.cluster.local.", srv.Hdr.Name)
+	assert.Equal(t, uint16(9090), srv.Port)
+	assert.Equal(t, "grpc."+serviceName+".default.svc.cluster.local.", srv.Target)
+	require.Len(t, w.msg.Extra, 1)
+}
+
+func TestDNSServer_ServiceNamespaceIsolation(t *testing.T) {
+	// 跳过集成测试，除非明确要求运行
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	client := etcdclient.CreateEtcdClientForTest(t)
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	suffix := time.Now().UnixNano()
+	teamA, teamB := fmt.Sprintf("team-a-%d", suffix), fmt.Sprintf("team-b-%d", suffix)
+	for id, namespace := range map[string]string{"a1": teamA, "b1": teamB} {
+		require.NoError(t, client.CreateNamespace(ctx, &etcdclient.Namespace{Name: namespace}))
+		require.NoError(t, client.RegisterService(ctx, &etcdclient.ServiceInstance{
+			ServiceName: "api", InstanceID: id, IPAddress: "10.0.50." + id[1:], Port: 8080, TTL: 60, Namespace: namespace,
+			Metadata: map[string]string{"team": namespace},
+		}))
+		defer func(id, namespace string) {
+			_ = client.DeregisterService(context.Background(), "api", id)
+			_ = client.DeleteNamespace(context.Background(), namespace, true)
+		}(id, namespace)
+	}
+	for _, namespace := range []string{teamA, teamB} {
+		require.NoError(t, client.PutNamespaceTXTMetadata(ctx, namespace, []string{"team"}))
+	}
+
+	// 配置上游DNS，不存在的命名空间也不能转发
+	cfg := &config.Config{}
+	cfg.DNS.UpstreamDNS = startFakeUpstream(t, "10.9.9.9", 0)
+	server := NewDNSServer(cfg, createTestLogger(t)).(*DNSServer)
+	server.SetEtcdClient(client)
+
+	query := func(name string, qtype uint16) *dns.Msg {
+		r := new(dns.Msg)
+		r.SetQuestion(name, qtype)
+		w := &recordingWriter{}
+		server.handleDNSRequest(w, r)
+		require.NotNil(t, w.msg)
+		return w.msg
+	}
+
+	// 每个命名空间只返回自己的实例
+	for id, namespace := range map[string]string{"a1": teamA, "b1": teamB} {
+		domain := "api." + namespace + ".svc.cluster.local."
+		m := query(domain, dns.TypeSRV)
+		require.Equal(t, dns.RcodeSuccess, m.Rcode)
+		require.Len(t, m.Answer, 1, namespace)
+		assert.Equal(t, id+"."+domain, m.Answer[0].(*dns.SRV).Target)
+
+		m = query(domain, dns.TypeA)
+		require.Len(t, m.Answer, 1, namespace)
+		assert.Equal(t, "10.0.50."+id[1:], m.Answer[0].(*dns.A).A.String())
+
+		m = query(domain, dns.TypeTXT)
+		require.Len(t, m.Answer, 1, namespace)
+		assert.Contains(t, m.Answer[0].(*dns.TXT).Txt, "team="+namespace)
+	}
+
+	// 不存在的命名空间返回NXDOMAIN，不转发到上游
+	for _, qtype := range []uint16{dns.TypeSRV, dns.TypeA, dns.TypeTXT} {
+		m := query("api.nonexistent.svc.cluster.local.", qtype)
+		assert.Equal(t, dns.RcodeNameError, m.Rcode, dns.TypeToString[qtype])
+		assert.Empty(t, m.Answer)
+	}
+
+	// 服务不在default命名空间中，default命名空间存在时按未知服务转发到上游
+	m := query("api.default.svc.cluster.local.", dns.TypeSRV)
+	for _, rr := range m.Answer {
+		assert.NotEqual(t, dns.TypeSRV, rr.Header().Rrtype)
+	}
+}
+
+func TestDNSServer_NamedPortSRVQuery(t *testing.T) {
+	// 跳过集成测试，除非明确要求运行
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	client := etcdclient.CreateEtcdClientForTest(t)
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	serviceName := "named-port-service"
+	require.NoError(t, client.RegisterService(ctx, &etcdclient.ServiceInstance{
+		ServiceName: serviceName, InstanceID: "pod-1", IPAddress: "10.0.0.10", Port: 8080, TTL: 60,
+		Ports: []etcdclient.NamedPort{{Name: "http", Port: 8080}, {Name: "grpc", Port: 9090}, {Name: "metrics", Port: 9100}},
+	}))
+	defer func() { _ = client.DeregisterService(context.Background(), serviceName, "pod-1") }()
+
+	server := NewDNSServer(&config.Config{}, createTestLogger(t)).(*DNSServer)
+	server.SetEtcdClient(client)
+
+	// 同一个实例的每个命名端口都有各自的SRV记录，目标和附加段的地址相同
+	for name, port := range map[string]uint16{"http": 8080, "grpc": 9090, "metrics": 9100} {
+		r := new(dns.Msg)
+		r.SetQuestion("_"+name+"._tcp."+serviceName+".default.svc.cluster.local.", dns.TypeSRV)
+		w := &recordingWriter{}
+		server.handleDNSRequest(w, r)
+		require.NotNil(t, w.msg)
+		require.Len(t, w.msg.Answer, 1, name)
+		srv := w.msg.Answer[0].(*dns.SRV)
+		assert.Equal(t, port, srv.Port, name)
+		assert.Equal(t, "pod-1."+serviceName+".default.svc.cluster.local.", srv.Target)
+		require.Len(t, w.msg.Extra, 1)
+		assert.Equal(t, "10.0.0.10", w.msg.Extra[0].(*dns.A).A.String())
+	}
+}
+
+func TestDNSServer_ServiceTXTQuery(t *testing.T) {
+	// 跳过集成测试，除非明确要求运行
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	client := etcdclient.CreateEtcdClientForTest(t)
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	serviceName := "txt-service"
+	instance := &etcdclient.ServiceInstance{
+		ServiceName: serviceName, InstanceID: "instance-txt", IPAddress: "10.0.0.9", Port: 8080, TTL: 60,
+		Metadata: map[string]string{"version": "2.0.1", "internal": "secret"},
+	}
+	require.NoError(t, client.RegisterService(ctx, instance))
+	defer func() { _ = client.DeregisterService(context.Background(), serviceName, "instance-txt") }()
+
+	previous, err := client.GetNamespaceTXTMetadata(ctx, etcdclient.DefaultNamespace)
+	require.NoError(t, err)
+	require.NoError(t, client.PutNamespaceTXTMetadata(ctx, etcdclient.DefaultNamespace, []string{"version"}))
+	defer func() {
+		_ = client.PutNamespaceTXTMetadata(context.Background(), etcdclient.DefaultNamespace, previous)
+	}()
+
+	server := NewDNSServer(&config.Config{}, createTestLogger(t)).(*DNSServer)
+	server.SetEtcdClient(client)
+
+	r := new(dns.Msg)
+	r.SetQuestion(serviceName+".default.svc.cluster.local.", dns.TypeTXT)
+	w := &recordingWriter{}
+	server.handleDNSRequest(w, r)
+	require.NotNil(t, w.msg)
+	require.Len(t, w.msg.Answer, 1)
+
+	// 只公开命名空间允许的元数据
+	txt := w.msg.Answer[0].(*dns.TXT)
+	assert.Equal(t, []string{"instance=instance-txt", "version=2.0.1"}, txt.Txt)
+}
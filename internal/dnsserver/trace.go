@@ -0,0 +1,118 @@
+package dnsserver
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+// 当前版本未启用应答缓存，跟踪结果中的缓存状态固定为disabled
+const traceCacheDisabled = "disabled"
+
+// TraceStep 解析流程中的一个步骤
+type TraceStep struct {
+	Stage  string `json:"stage"`  // 阶段 (policy, static, etcd, service, forward, upstream)
+	Detail string `json:"detail"` // 步骤详情
+}
+
+// ResolveTrace 一次解析的完整跟踪结果
+type ResolveTrace struct {
+	Name     string      `json:"name"`               // 查询域名
+	Type     string      `json:"type"`               // 查询类型
+	Client   string      `json:"client,omitempty"`   // 模拟的客户端地址
+	View     string      `json:"view,omitempty"`     // 客户端所属视图
+	Cache    string      `json:"cache"`              // 缓存命中情况
+	Steps    []TraceStep `json:"steps"`              // 解析步骤
+	Upstream string      `json:"upstream,omitempty"` // 最终应答的上游
+	Rcode    string      `json:"rcode"`              // 响应码
+	Answer   []string    `json:"answer"`             // 应答记录
+	Message  string      `json:"message"`            // dig风格的完整响应
+	Wire     string      `json:"wire"`               // base64编码的线上格式响应
+}
+
+// add 记录一个解析步骤，trace为空时忽略
+func (t *ResolveTrace) add(stage, format string, args ...interface{}) {
+	if t == nil {
+		return
+	}
+	t.Steps = append(t.Steps, TraceStep{Stage: stage, Detail: fmt.Sprintf(format, args...)})
+}
+
+// setView 记录客户端所属视图
+func (t *ResolveTrace) setView(view string) {
+	if t != nil {
+		t.View = view
+	}
+}
+
+// setUpstream 记录最终应答的上游
+func (t *ResolveTrace) setUpstream(addr string) {
+	if t != nil {
+		t.Upstream = addr
+	}
+}
+
+// setResponse 记录最终响应
+func (t *ResolveTrace) setResponse(m *dns.Msg) error {
+	t.Rcode = dns.RcodeToString[m.Rcode]
+	t.Answer = make([]string, 0, len(m.Answer))
+	for _, rr := range m.Answer {
+		t.Answer = append(t.Answer, rr.String())
+	}
+	t.Message = m.String()
+
+	wire, err := m.Pack()
+	if err != nil {
+		return fmt.Errorf("序列化DNS响应失败: %w", err)
+	}
+	t.Wire = base64.StdEncoding.EncodeToString(wire)
+	return nil
+}
+
+// Resolve 以client的身份执行一次完整的解析流程并返回跟踪结果，用于排查解析问题；
+// ctx被取消（如HTTP客户端断开）或超过查询超时时停止解析
+func (s *DNSServer) Resolve(ctx context.Context, name, qtype, client string) (*ResolveTrace, error) {
+	if name == "" {
+		return nil, fmt.Errorf("查询域名不能为空")
+	}
+
+	if qtype == "" {
+		qtype = "A"
+	}
+	t, ok := dns.StringToType[strings.ToUpper(qtype)]
+	if !ok {
+		return nil, fmt.Errorf("不支持的查询类型: %s", qtype)
+	}
+
+	var addr net.Addr
+	if client != "" {
+		ip := net.ParseIP(client)
+		if ip == nil {
+			return nil, fmt.Errorf("无效的客户端地址: %s", client)
+		}
+		addr = &net.UDPAddr{IP: ip}
+	}
+
+	r := new(dns.Msg)
+	r.SetQuestion(dns.Fqdn(name), t)
+	r.RecursionDesired = true
+
+	trace := &ResolveTrace{
+		Name:   r.Question[0].Name,
+		Type:   dns.TypeToString[t],
+		Client: client,
+		Cache:  traceCacheDisabled,
+	}
+
+	ctx, cancel := s.queryContext(ctx)
+	defer cancel()
+	m := s.resolve(ctx, r, addr, "", trace)
+	if err := trace.setResponse(m); err != nil {
+		return nil, err
+	}
+	return trace, nil
+}
@@ -0,0 +1,82 @@
+package dnsserver
+
+import (
+	"context"
+	"encoding/base64"
+	"testing"
+
+	"github.com/hewenyu/kong-discovery/internal/config"
+	"github.com/hewenyu/kong-discovery/internal/etcdclient"
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// traceStages 返回跟踪结果中的阶段列表
+func traceStages(trace *ResolveTrace) []string {
+	stages := make([]string, 0, len(trace.Steps))
+	for _, step := range trace.Steps {
+		stages = append(stages, step.Stage)
+	}
+	return stages
+}
+
+func TestDNSServer_ResolveStaticRecord(t *testing.T) {
+	server := NewDNSServer(&config.Config{}, createTestLogger(t)).(*DNSServer)
+
+	trace, err := server.Resolve(context.Background(), "test.local", "a", "")
+	require.NoError(t, err)
+	assert.Equal(t, "test.local.", trace.Name)
+	assert.Equal(t, "A", trace.Type)
+	assert.Equal(t, "NOERROR", trace.Rcode)
+	assert.Equal(t, traceCacheDisabled, trace.Cache)
+	assert.Equal(t, []string{"policy", "static"}, traceStages(trace))
+	require.Len(t, trace.Answer, 1)
+	assert.Contains(t, trace.Answer[0], "1.2.3.4")
+
+	// 线上格式可以被解析回原始响应
+	wire, err := base64.StdEncoding.DecodeString(trace.Wire)
+	require.NoError(t, err)
+	m := new(dns.Msg)
+	require.NoError(t, m.Unpack(wire))
+	require.Len(t, m.Answer, 1)
+}
+
+func TestDNSServer_ResolveForwarded(t *testing.T) {
+	upstream := startFakeUpstream(t, "10.0.0.9", 0)
+
+	cfg := &config.Config{}
+	cfg.DNS.Upstream.Servers = []string{upstream}
+	server := NewDNSServer(cfg, createTestLogger(t)).(*DNSServer)
+
+	trace, err := server.Resolve(context.Background(), "example.com", "A", "10.1.2.3")
+	require.NoError(t, err)
+	assert.Equal(t, upstream, trace.Upstream)
+	assert.Contains(t, traceStages(trace), "upstream")
+	require.Len(t, trace.Answer, 1)
+	assert.Contains(t, trace.Answer[0], "10.0.0.9")
+}
+
+func TestDNSServer_ResolveBlocked(t *testing.T) {
+	server := NewDNSServer(&config.Config{}, createTestLogger(t)).(*DNSServer)
+	server.policy.setRules([]*etcdclient.BlockRule{
+		{ID: "r1", Match: etcdclient.BlockMatchExact, Pattern: "ads.example.com", Action: etcdclient.BlockActionNXDomain},
+	})
+
+	trace, err := server.Resolve(context.Background(), "ads.example.com", "A", "")
+	require.NoError(t, err)
+	assert.Equal(t, "NXDOMAIN", trace.Rcode)
+	require.Len(t, trace.Steps, 1)
+	assert.Contains(t, trace.Steps[0].Detail, "r1")
+}
+
+func TestDNSServer_ResolveInvalidInput(t *testing.T) {
+	server := NewDNSServer(&config.Config{}, createTestLogger(t)).(*DNSServer)
+
+	_, err := server.Resolve(context.Background(), "", "A", "")
+	assert.Error(t, err)
+	_, err = server.Resolve(context.Background(), "example.com", "BOGUS", "")
+	assert.Error(t, err)
+	_, err = server.Resolve(context.Background(), "example.com", "A", "not-an-ip")
+	assert.Error(t, err)
+}
@@ -0,0 +1,89 @@
+package dnsserver
+
+import (
+	"sync"
+
+	"github.com/hewenyu/kong-discovery/internal/etcdclient"
+)
+
+// ttlPolicies 服务和命名空间的TTL策略，随运行时配置从etcd重新加载
+type ttlPolicies struct {
+	mu         sync.RWMutex
+	services   map[string]*etcdclient.TTLPolicy
+	namespaces map[string]*etcdclient.TTLPolicy
+}
+
+// newTTLPolicies 创建空的TTL策略集合
+func newTTLPolicies() *ttlPolicies {
+	return &ttlPolicies{
+		services:   make(map[string]*etcdclient.TTLPolicy),
+		namespaces: make(map[string]*etcdclient.TTLPolicy),
+	}
+}
+
+// setServices 替换所有服务的TTL策略
+func (t *ttlPolicies) setServices(policies []*etcdclient.ServiceTTLPolicy) {
+	m := make(map[string]*etcdclient.TTLPolicy, len(policies))
+	for _, policy := range policies {
+		ttl := policy.TTLPolicy
+		m[policy.ServiceName] = &ttl
+	}
+
+	t.mu.Lock()
+	t.services = m
+	t.mu.Unlock()
+}
+
+// setNamespaces 按命名空间的TTL策略替换命名空间策略
+func (t *ttlPolicies) setNamespaces(namespaces []*etcdclient.Namespace) {
+	m := make(map[string]*etcdclient.TTLPolicy, len(namespaces))
+	for _, ns := range namespaces {
+		if ns.TTLPolicy != nil {
+			m[ns.Name] = ns.TTLPolicy
+		}
+	}
+
+	t.mu.Lock()
+	t.namespaces = m
+	t.mu.Unlock()
+}
+
+// get 返回服务和命名空间的TTL策略，按服务、命名空间的顺序排列，未设置的级别为nil
+func (t *ttlPolicies) get(serviceName, namespace string) []*etcdclient.TTLPolicy {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return []*etcdclient.TTLPolicy{t.services[serviceName], t.namespaces[etcdclient.NamespaceOf(namespace)]}
+}
+
+// globalTTLPolicy 返回配置中的全局TTL限制
+func (s *DNSServer) globalTTLPolicy() *etcdclient.TTLPolicy {
+	return &etcdclient.TTLPolicy{MinTTL: s.cfg.DNS.TTLPolicy.MinTTL, MaxTTL: s.cfg.DNS.TTLPolicy.MaxTTL}
+}
+
+// serviceTTLPolicies 返回服务域名适用的TTL策略，远程集群的服务只受全局限制
+func (s *DNSServer) serviceTTLPolicies(cluster, domain string) []*etcdclient.TTLPolicy {
+	if cluster != "" {
+		return nil
+	}
+	serviceName, _ := etcdclient.ServiceNameFromDomain(domain)
+	namespace, _ := etcdclient.NamespaceFromDomain(domain)
+	return s.ttlPolicies.get(serviceName, namespace)
+}
+
+// recordTTLPolicies 返回DNS记录适用的TTL策略：记录所属命名空间的策略，由服务维护的记录还使用该服务的策略
+func (s *DNSServer) recordTTLPolicies(record *etcdclient.DNSRecord) []*etcdclient.TTLPolicy {
+	serviceName := ""
+	if record.Owner != nil && record.Owner.Kind == etcdclient.OwnerKindService {
+		serviceName = record.Owner.Name
+	}
+	return s.ttlPolicies.get(serviceName, record.Namespace)
+}
+
+// serviceDefaultTTL 返回服务应答在实例未设置DNS TTL时使用的TTL：服务或命名空间TTL策略的默认TTL，
+// 都未设置时使用配置的服务默认TTL（为0时由recordTTL使用记录的默认TTL）
+func (s *DNSServer) serviceDefaultTTL(policies []*etcdclient.TTLPolicy) int {
+	if ttl := etcdclient.DefaultTTLOf(policies...); ttl > 0 {
+		return ttl
+	}
+	return s.cfg.DNS.ServiceTTL
+}
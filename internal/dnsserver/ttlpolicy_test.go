@@ -0,0 +1,102 @@
+package dnsserver
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hewenyu/kong-discovery/internal/config"
+	"github.com/hewenyu/kong-discovery/internal/etcdclient"
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDNSServer_RecordTTLHierarchy(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.DNS.DefaultTTL = 300
+	cfg.DNS.TTLPolicy.MaxTTL = 3600
+	server := NewDNSServer(cfg, createTestLogger(t)).(*DNSServer)
+	server.ttlPolicies.setServices([]*etcdclient.ServiceTTLPolicy{
+		{ServiceName: "payments", TTLPolicy: etcdclient.TTLPolicy{MaxTTL: 60}},
+	})
+	server.ttlPolicies.setNamespaces([]*etcdclient.Namespace{
+		{Name: etcdclient.DefaultNamespace},
+		{Name: "team-a", TTLPolicy: &etcdclient.TTLPolicy{DefaultTTL: 120, MinTTL: 30}},
+	})
+
+	// 全局上限约束没有策略的记录
+	assert.Equal(t, 3600, server.recordTTL(7*24*3600))
+	assert.Equal(t, 300, server.recordTTL(0))
+
+	// 命名空间的默认TTL和下限
+	record := &etcdclient.DNSRecord{Type: "A", Value: "10.0.0.1", Namespace: "team-a"}
+	assert.Equal(t, 120, server.recordTTL(record.TTL, server.recordTTLPolicies(record)...))
+	record.TTL = 5
+	assert.Equal(t, 30, server.recordTTL(record.TTL, server.recordTTLPolicies(record)...))
+
+	// 服务维护的记录还受服务策略约束
+	record.TTL = 7 * 24 * 3600
+	record.Owner = etcdclient.ServiceOwner("payments")
+	assert.Equal(t, 60, server.recordTTL(record.TTL, server.recordTTLPolicies(record)...))
+
+	// 服务域名按域名中的服务和命名空间选择策略，远程集群的服务只受全局限制
+	assert.Equal(t, 60, server.serviceTTL(600, server.serviceTTLPolicies("", "payments.team-a.svc.cluster.local")...))
+	assert.Equal(t, 600, server.serviceTTL(600, server.serviceTTLPolicies("remote", "payments.team-a.svc.cluster.local")...))
+	assert.Equal(t, 120, server.serviceDefaultTTL(server.serviceTTLPolicies("", "orders.team-a.svc.cluster.local")))
+}
+
+func TestDNSServer_ServiceTTLPolicy(t *testing.T) {
+	// 跳过集成测试，除非明确要求运行
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	client := etcdclient.CreateEtcdClientForTest(t)
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	serviceName := fmt.Sprintf("ttl-policy-service-%d", time.Now().UnixNano())
+	defer func() {
+		_ = client.DeregisterService(context.Background(), serviceName, "ttl-001")
+		_ = client.DeleteServiceTTLPolicy(context.Background(), serviceName)
+	}()
+	require.NoError(t, client.RegisterService(ctx, &etcdclient.ServiceInstance{
+		ServiceName: serviceName, InstanceID: "ttl-001", IPAddress: "10.0.41.1", Port: 8080, TTL: 60}))
+
+	cfg := &config.Config{}
+	cfg.DNS.ServiceTTL = 60
+	server := NewDNSServer(cfg, createTestLogger(t)).(*DNSServer)
+	server.SetEtcdClient(client)
+
+	queryTTL := func(qtype uint16) uint32 {
+		r := new(dns.Msg)
+		r.SetQuestion(serviceName+".default.svc.cluster.local.", qtype)
+		w := &recordingWriter{}
+		server.handleDNSRequest(w, r)
+		require.NotNil(t, w.msg)
+		require.NotEmpty(t, w.msg.Answer)
+		return w.msg.Answer[0].Header().Ttl
+	}
+
+	assert.Equal(t, uint32(60), queryTTL(dns.TypeA))
+
+	// 服务的默认TTL用于没有设置DNS TTL的实例
+	require.NoError(t, client.PutServiceTTLPolicy(ctx, &etcdclient.ServiceTTLPolicy{ServiceName: serviceName, TTLPolicy: etcdclient.TTLPolicy{DefaultTTL: 15}}))
+	server.applyTTLPolicies()
+	assert.Equal(t, uint32(15), queryTTL(dns.TypeA))
+	assert.Equal(t, uint32(15), queryTTL(dns.TypeSRV))
+
+	// 全局上限优先于服务的默认TTL
+	cfg.DNS.TTLPolicy.MaxTTL = 10
+	assert.Equal(t, uint32(10), queryTTL(dns.TypeA))
+	cfg.DNS.TTLPolicy.MaxTTL = 0
+
+	// 删除策略后恢复使用配置的服务默认TTL
+	require.NoError(t, client.DeleteServiceTTLPolicy(ctx, serviceName))
+	server.applyTTLPolicies()
+	assert.Equal(t, uint32(60), queryTTL(dns.TypeA))
+}
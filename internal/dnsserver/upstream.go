@@ -0,0 +1,380 @@
+package dnsserver
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hewenyu/kong-discovery/internal/etcdclient"
+)
+
+// 熔断器状态
+const (
+	breakerClosed   = "closed"    // 正常，允许转发
+	breakerOpen     = "open"      // 熔断中，不参与转发
+	breakerHalfOpen = "half-open" // 冷却结束，允许一次探测
+)
+
+// 延迟EWMA的平滑系数
+const latencyEWMAAlpha = 0.3
+
+// 成功率EWMA的平滑系数，评分主要反映最近几十次转发，长时间运行后上游恢复或变差都能及时体现
+const successEWMAAlpha = 0.1
+
+// UpstreamStatus 上游DNS服务器的健康状态快照
+type UpstreamStatus struct {
+	Rule                string     `json:"rule,omitempty"`          // 所属条件转发规则的域名后缀，为空表示默认上游或命名空间的上游
+	Namespace           string     `json:"namespace,omitempty"`     // 覆盖上游设置的命名空间，为空表示不属于命名空间
+	Address             string     `json:"address"`                 // 上游地址
+	State               string     `json:"state"`                   // 熔断器状态
+	Score               float64    `json:"score"`                   // 健康评分（0-100）
+	ConsecutiveFailures int        `json:"consecutive_failures"`    // 连续失败次数
+	TotalQueries        uint64     `json:"total_queries"`           // 总转发次数
+	TotalFailures       uint64     `json:"total_failures"`          // 总失败次数
+	LatencyMs           float64    `json:"latency_ms"`              // 延迟EWMA（毫秒）
+	OpenedAt            *time.Time `json:"opened_at,omitempty"`     // 最近一次熔断时间
+	Reachable           bool       `json:"reachable"`               // 是否可达：未处于熔断状态
+	Disabled            bool       `json:"disabled"`                // 是否被手动停用
+	LastError           string     `json:"last_error,omitempty"`    // 最近一次转发失败的原因
+	LastErrorAt         *time.Time `json:"last_error_at,omitempty"` // 最近一次转发失败的时间
+}
+
+// upstream 单个上游DNS服务器及其健康统计
+type upstream struct {
+	mu                  sync.Mutex
+	addr                string
+	state               string
+	consecutiveFailures int
+	totalQueries        uint64
+	totalFailures       uint64
+	latencyEWMA         float64 // 毫秒
+	successEWMA         float64 // 成功率EWMA（0-1），没有转发记录时不使用
+	openedAt            time.Time
+	probing             bool // 半开状态下是否已有探测请求在途
+	lastError           string
+	lastErrorAt         time.Time
+}
+
+// disabledUpstreams 被手动停用的上游地址，由所有上游池共享
+type disabledUpstreams struct {
+	mu    sync.RWMutex
+	addrs map[string]bool
+}
+
+// has 判断上游是否被停用
+func (d *disabledUpstreams) has(addr string) bool {
+	if d == nil {
+		return false
+	}
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.addrs[addr]
+}
+
+// set 替换停用的上游列表
+func (d *disabledUpstreams) set(addrs []string) {
+	m := make(map[string]bool, len(addrs))
+	for _, addr := range addrs {
+		m[addr] = true
+	}
+	d.mu.Lock()
+	d.addrs = m
+	d.mu.Unlock()
+}
+
+// update 停用或恢复单个上游
+func (d *disabledUpstreams) update(addr string, disabled bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.addrs == nil {
+		d.addrs = make(map[string]bool)
+	}
+	if disabled {
+		d.addrs[addr] = true
+	} else {
+		delete(d.addrs, addr)
+	}
+}
+
+// upstreamPool 管理多个上游DNS服务器的健康评分与熔断
+type upstreamPool struct {
+	mu               sync.RWMutex
+	upstreams        []*upstream
+	failureThreshold int
+	cooldown         time.Duration
+	rule             string // 所属条件转发规则的域名后缀，为空表示默认上游或命名空间的上游
+	namespace        string // 覆盖上游设置的命名空间，为空表示不属于命名空间
+	mode             string // 转发模式
+	raceCount        int    // race模式下同时查询的上游数量
+	disabled         *disabledUpstreams
+}
+
+// newUpstreamPool 创建上游服务器池，disabled为被手动停用的上游，可以为nil
+func newUpstreamPool(addrs []string, failureThreshold int, cooldown time.Duration, disabled *disabledUpstreams) *upstreamPool {
+	if failureThreshold <= 0 {
+		failureThreshold = 3
+	}
+	if cooldown <= 0 {
+		cooldown = 30 * time.Second
+	}
+
+	p := &upstreamPool{
+		failureThreshold: failureThreshold,
+		cooldown:         cooldown,
+		disabled:         disabled,
+	}
+	p.setAddresses(addrs)
+	p.setMode(etcdclient.UpstreamModeSequential, 2)
+	return p
+}
+
+// setAddresses 更新上游地址列表，已存在的上游保留其健康统计
+func (p *upstreamPool) setAddresses(addrs []string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	existing := make(map[string]*upstream, len(p.upstreams))
+	for _, u := range p.upstreams {
+		existing[u.addr] = u
+	}
+
+	seen := make(map[string]bool, len(addrs))
+	upstreams := make([]*upstream, 0, len(addrs))
+	for _, addr := range addrs {
+		if addr == "" || seen[addr] {
+			continue
+		}
+		seen[addr] = true
+
+		if u, ok := existing[addr]; ok {
+			upstreams = append(upstreams, u)
+			continue
+		}
+		upstreams = append(upstreams, &upstream{addr: addr, state: breakerClosed})
+	}
+
+	p.upstreams = upstreams
+}
+
+// setMode 设置转发模式，raceCount为0时使用默认值；小于2的值由配置校验和管理API拒绝
+func (p *upstreamPool) setMode(mode string, raceCount int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if mode == "" {
+		mode = etcdclient.UpstreamModeSequential
+	}
+	if raceCount == 0 {
+		raceCount = etcdclient.DefaultRaceCount
+	}
+	p.mode = mode
+	p.raceCount = raceCount
+}
+
+// forwardMode 返回当前转发模式及race模式下的并发数量
+func (p *upstreamPool) forwardMode() (string, int) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.mode, p.raceCount
+}
+
+// contains 判断地址是否属于该上游池
+func (p *upstreamPool) contains(addr string) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	for _, u := range p.upstreams {
+		if u.addr == addr {
+			return true
+		}
+	}
+	return false
+}
+
+// size 返回上游服务器数量
+func (p *upstreamPool) size() int {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return len(p.upstreams)
+}
+
+// candidates 返回当前未熔断且未被停用的上游，按健康评分从高到低排序
+// 如果全部熔断，则返回一个冷却结束的上游作为探测请求
+func (p *upstreamPool) candidates(now time.Time) []*upstream {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	result := make([]*upstream, 0, len(p.upstreams))
+	scores := make(map[*upstream]float64, len(p.upstreams))
+	for _, u := range p.upstreams {
+		if p.disabled.has(u.addr) {
+			continue
+		}
+		u.mu.Lock()
+		if u.state == breakerClosed {
+			result = append(result, u)
+			scores[u] = u.scoreLocked()
+		}
+		u.mu.Unlock()
+	}
+
+	if len(result) == 0 {
+		for _, u := range p.upstreams {
+			if !p.disabled.has(u.addr) && u.claimProbe(now, p.cooldown) {
+				return []*upstream{u}
+			}
+		}
+		return nil
+	}
+
+	// 稳定排序，评分相同时保持配置顺序
+	sort.SliceStable(result, func(i, j int) bool {
+		return scores[result[i]] > scores[result[j]]
+	})
+
+	return result
+}
+
+// probeTargets 返回冷却结束、需要进行恢复探测的上游，并将其标记为探测中；被停用的上游不做探测
+func (p *upstreamPool) probeTargets(now time.Time) []*upstream {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	var result []*upstream
+	for _, u := range p.upstreams {
+		if !p.disabled.has(u.addr) && u.claimProbe(now, p.cooldown) {
+			result = append(result, u)
+		}
+	}
+	return result
+}
+
+// status 返回所有上游的健康状态快照
+func (p *upstreamPool) status() []UpstreamStatus {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	result := make([]UpstreamStatus, 0, len(p.upstreams))
+	for _, u := range p.upstreams {
+		status := u.snapshot()
+		status.Rule = p.rule
+		status.Namespace = p.namespace
+		status.Disabled = p.disabled.has(u.addr)
+		result = append(result, status)
+	}
+	return result
+}
+
+// recordSuccess 记录一次成功的转发
+func (p *upstreamPool) recordSuccess(u *upstream, latency time.Duration) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	ms := float64(latency) / float64(time.Millisecond)
+	if u.totalQueries == 0 || u.latencyEWMA == 0 {
+		u.latencyEWMA = ms
+	} else {
+		u.latencyEWMA = latencyEWMAAlpha*ms + (1-latencyEWMAAlpha)*u.latencyEWMA
+	}
+
+	u.updateSuccessRate(1)
+	u.totalQueries++
+	u.consecutiveFailures = 0
+	u.state = breakerClosed
+	u.probing = false
+}
+
+// recordFailure 记录一次失败的转发及其原因，达到阈值时熔断
+func (p *upstreamPool) recordFailure(u *upstream, now time.Time, cause error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if cause != nil {
+		u.lastError = cause.Error()
+		u.lastErrorAt = now
+	}
+	u.updateSuccessRate(0)
+	u.totalQueries++
+	u.totalFailures++
+	u.consecutiveFailures++
+	u.probing = false
+
+	// 半开状态下探测失败，重新熔断
+	if u.state == breakerHalfOpen || u.consecutiveFailures >= p.failureThreshold {
+		u.state = breakerOpen
+		u.openedAt = now
+	}
+}
+
+// claimProbe 冷却结束后进入半开状态，并占用唯一的探测名额
+func (u *upstream) claimProbe(now time.Time, cooldown time.Duration) bool {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if u.state == breakerClosed || u.probing || now.Sub(u.openedAt) < cooldown {
+		return false
+	}
+
+	u.state = breakerHalfOpen
+	u.probing = true
+	return true
+}
+
+// updateSuccessRate 在已持有锁的情况下把一次转发的结果（成功为1，失败为0）计入成功率EWMA
+func (u *upstream) updateSuccessRate(result float64) {
+	if u.totalQueries == 0 {
+		u.successEWMA = result
+		return
+	}
+	u.successEWMA = successEWMAAlpha*result + (1-successEWMAAlpha)*u.successEWMA
+}
+
+// score 计算健康评分：近期成功率乘以延迟惩罚系数
+func (u *upstream) score() float64 {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.scoreLocked()
+}
+
+// scoreLocked 在已持有锁的情况下计算健康评分
+func (u *upstream) scoreLocked() float64 {
+	// 使用近期成功率而不是累计成功率，历史上的大量成功或失败不会长期掩盖上游当前的状态
+	successRate := 1.0
+	if u.totalQueries > 0 {
+		successRate = u.successEWMA
+	}
+
+	// 延迟越高评分越低，100ms时评分减半
+	latencyFactor := 1.0 / (1.0 + u.latencyEWMA/100.0)
+
+	// 连续失败进一步降低评分
+	failureFactor := 1.0 / float64(1+u.consecutiveFailures)
+
+	return 100 * successRate * latencyFactor * failureFactor
+}
+
+// snapshot 返回上游状态快照
+func (u *upstream) snapshot() UpstreamStatus {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	status := UpstreamStatus{
+		Address:             u.addr,
+		State:               u.state,
+		Score:               u.scoreLocked(),
+		ConsecutiveFailures: u.consecutiveFailures,
+		TotalQueries:        u.totalQueries,
+		TotalFailures:       u.totalFailures,
+		LatencyMs:           u.latencyEWMA,
+		Reachable:           u.state != breakerOpen,
+		LastError:           u.lastError,
+	}
+	if !u.openedAt.IsZero() {
+		openedAt := u.openedAt
+		status.OpenedAt = &openedAt
+	}
+	if !u.lastErrorAt.IsZero() {
+		lastErrorAt := u.lastErrorAt
+		status.LastErrorAt = &lastErrorAt
+	}
+	return status
+}
@@ -0,0 +1,249 @@
+package dnsserver
+
+import (
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/hewenyu/kong-discovery/internal/config"
+	"github.com/hewenyu/kong-discovery/internal/etcdclient"
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpstreamPool_CircuitBreaker(t *testing.T) {
+	pool := newUpstreamPool([]string{"10.0.0.1:53", "10.0.0.2:53"}, 2, 10*time.Second, nil)
+	now := time.Now()
+
+	// 初始状态下两个上游都可用
+	candidates := pool.candidates(now)
+	require.Len(t, candidates, 2)
+	first := candidates[0]
+	assert.Equal(t, "10.0.0.1:53", first.addr)
+
+	// 连续失败达到阈值后熔断
+	pool.recordFailure(first, now, nil)
+	assert.Len(t, pool.candidates(now), 2, "未达到阈值前不应熔断")
+	pool.recordFailure(first, now, nil)
+
+	candidates = pool.candidates(now)
+	require.Len(t, candidates, 1)
+	assert.Equal(t, "10.0.0.2:53", candidates[0].addr)
+
+	status := pool.status()
+	assert.Equal(t, breakerOpen, status[0].State)
+	assert.Equal(t, 2, status[0].ConsecutiveFailures)
+	assert.NotNil(t, status[0].OpenedAt)
+
+	// 冷却期内不进行探测
+	assert.Empty(t, pool.probeTargets(now.Add(5*time.Second)))
+
+	// 冷却结束后只放行一次探测
+	probes := pool.probeTargets(now.Add(11 * time.Second))
+	require.Len(t, probes, 1)
+	assert.Equal(t, breakerHalfOpen, pool.status()[0].State)
+	assert.Empty(t, pool.probeTargets(now.Add(12*time.Second)))
+
+	// 探测成功后恢复
+	pool.recordSuccess(probes[0], 20*time.Millisecond)
+	assert.Equal(t, breakerClosed, pool.status()[0].State)
+	assert.Len(t, pool.candidates(now.Add(12*time.Second)), 2)
+}
+
+func TestUpstreamPool_HalfOpenFailureReopens(t *testing.T) {
+	pool := newUpstreamPool([]string{"10.0.0.1:53"}, 1, time.Second, nil)
+	now := time.Now()
+
+	u := pool.candidates(now)[0]
+	pool.recordFailure(u, now, nil)
+
+	// 全部熔断且冷却期内没有可用上游
+	assert.Empty(t, pool.candidates(now))
+
+	// 冷却结束后作为探测请求返回
+	later := now.Add(2 * time.Second)
+	candidates := pool.candidates(later)
+	require.Len(t, candidates, 1)
+
+	// 探测失败后重新熔断并重置冷却时间
+	pool.recordFailure(candidates[0], later, nil)
+	assert.Equal(t, breakerOpen, pool.status()[0].State)
+	assert.Empty(t, pool.candidates(later.Add(500*time.Millisecond)))
+}
+
+func TestUpstreamPool_ScoreOrdering(t *testing.T) {
+	pool := newUpstreamPool([]string{"10.0.0.1:53", "10.0.0.2:53"}, 3, time.Second, nil)
+	candidates := pool.candidates(time.Now())
+
+	// 第一个上游延迟高，第二个上游延迟低
+	pool.recordSuccess(candidates[0], 300*time.Millisecond)
+	pool.recordSuccess(candidates[1], 10*time.Millisecond)
+
+	ordered := pool.candidates(time.Now())
+	require.Len(t, ordered, 2)
+	assert.Equal(t, "10.0.0.2:53", ordered[0].addr, "低延迟上游应优先")
+}
+
+func TestUpstreamPool_ScoreReflectsRecentQueries(t *testing.T) {
+	pool := newUpstreamPool([]string{"10.0.0.1:53", "10.0.0.2:53"}, 1000, time.Second, nil)
+	candidates := pool.candidates(time.Now())
+	recovered, degraded := candidates[0], candidates[1]
+
+	// 第一个上游长时间失败后恢复，第二个上游长时间正常后开始失败
+	for i := 0; i < 1000; i++ {
+		pool.recordFailure(recovered, time.Now(), nil)
+		pool.recordSuccess(degraded, 10*time.Millisecond)
+	}
+	for i := 0; i < 50; i++ {
+		pool.recordSuccess(recovered, 10*time.Millisecond)
+	}
+	for i := 0; i < 50; i++ {
+		if i%2 == 0 {
+			pool.recordFailure(degraded, time.Now(), nil)
+		} else {
+			pool.recordSuccess(degraded, 10*time.Millisecond)
+		}
+	}
+
+	assert.Greater(t, recovered.score(), 80.0, "恢复后的评分不应被历史失败拉低")
+	assert.Less(t, degraded.score(), 60.0, "近期频繁失败应及时降低评分")
+
+	ordered := pool.candidates(time.Now())
+	require.Len(t, ordered, 2)
+	assert.Equal(t, "10.0.0.1:53", ordered[0].addr, "近期成功率高的上游应优先")
+}
+
+func TestUpstreamPool_SetAddressesKeepsStats(t *testing.T) {
+	pool := newUpstreamPool([]string{"10.0.0.1:53"}, 3, time.Second, nil)
+	u := pool.candidates(time.Now())[0]
+	pool.recordSuccess(u, 10*time.Millisecond)
+
+	pool.setAddresses([]string{"10.0.0.1:53", "10.0.0.3:53", "10.0.0.3:53", ""})
+
+	status := pool.status()
+	require.Len(t, status, 2)
+	assert.Equal(t, uint64(1), status[0].TotalQueries)
+	assert.Equal(t, "10.0.0.3:53", status[1].Address)
+}
+
+func TestUpstreamPool_Disabled(t *testing.T) {
+	disabled := &disabledUpstreams{}
+	pool := newUpstreamPool([]string{"10.0.0.1:53", "10.0.0.2:53"}, 1, time.Second, disabled)
+	now := time.Now()
+
+	// 停用的上游不参与转发
+	disabled.update("10.0.0.1:53", true)
+	candidates := pool.candidates(now)
+	require.Len(t, candidates, 1)
+	assert.Equal(t, "10.0.0.2:53", candidates[0].addr)
+
+	// 记录失败原因，熔断后不可达
+	pool.recordFailure(candidates[0], now, fmt.Errorf("i/o timeout"))
+	status := pool.status()
+	assert.True(t, status[0].Disabled)
+	assert.False(t, status[1].Reachable)
+	assert.Equal(t, "i/o timeout", status[1].LastError)
+	assert.NotNil(t, status[1].LastErrorAt)
+
+	// 停用的上游不做探测，也不作为全部熔断后的探测请求
+	later := now.Add(2 * time.Second)
+	probes := pool.probeTargets(later)
+	require.Len(t, probes, 1)
+	assert.Equal(t, "10.0.0.2:53", probes[0].addr)
+
+	// 恢复后重新参与转发
+	disabled.update("10.0.0.1:53", false)
+	assert.Len(t, pool.candidates(later), 1)
+	assert.Equal(t, "10.0.0.1:53", pool.candidates(later)[0].addr)
+}
+
+// startFakeUpstream 启动一个本地假上游DNS服务器，按指定延迟返回固定的A记录
+func startFakeUpstream(t *testing.T, ip string, delay time.Duration) string {
+	t.Helper()
+
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	server := &dns.Server{
+		PacketConn: pc,
+		Handler: dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
+			time.Sleep(delay)
+			m := new(dns.Msg)
+			m.SetReply(r)
+			rr, _ := dns.NewRR(fmt.Sprintf("%s 60 IN A %s", r.Question[0].Name, ip))
+			m.Answer = append(m.Answer, rr)
+			_ = w.WriteMsg(m)
+		}),
+	}
+	go func() { _ = server.ActivateAndServe() }()
+	t.Cleanup(func() { _ = server.Shutdown() })
+
+	return pc.LocalAddr().String()
+}
+
+func TestDNSServer_RaceForward(t *testing.T) {
+	slow := startFakeUpstream(t, "10.0.0.1", 500*time.Millisecond)
+	fast := startFakeUpstream(t, "10.0.0.2", 0)
+
+	cfg := &config.Config{}
+	cfg.DNS.Upstream.Servers = []string{slow, fast}
+	cfg.DNS.Upstream.Mode = etcdclient.UpstreamModeRace
+	cfg.DNS.Upstream.RaceCount = 2
+	server := NewDNSServer(cfg, createTestLogger(t)).(*DNSServer)
+
+	r := new(dns.Msg)
+	r.SetQuestion("example.com.", dns.TypeA)
+	m := new(dns.Msg)
+	m.SetReply(r)
+
+	start := time.Now()
+	err := server.forwardToUpstream(server.upstreams, r, m)
+	require.NoError(t, err)
+	assert.Less(t, time.Since(start), 400*time.Millisecond, "race模式应返回最快的响应")
+
+	require.Len(t, m.Answer, 1)
+	assert.Equal(t, "10.0.0.2", m.Answer[0].(*dns.A).A.String())
+}
+
+func TestDNSServer_SequentialForwardSkipsFailedUpstream(t *testing.T) {
+	good := startFakeUpstream(t, "10.0.0.3", 0)
+
+	cfg := &config.Config{}
+	cfg.DNS.Upstream.Servers = []string{"127.0.0.1:1", good}
+	cfg.DNS.Upstream.FailureThreshold = 1
+	server := NewDNSServer(cfg, createTestLogger(t)).(*DNSServer)
+
+	r := new(dns.Msg)
+	r.SetQuestion("example.com.", dns.TypeA)
+	m := new(dns.Msg)
+	m.SetReply(r)
+
+	err := server.forwardToUpstream(server.upstreams, r, m)
+	require.NoError(t, err)
+	require.Len(t, m.Answer, 1)
+	assert.Equal(t, "10.0.0.3", m.Answer[0].(*dns.A).A.String())
+
+	// 失败的上游应被熔断
+	status := server.UpstreamStatus()
+	assert.Equal(t, breakerOpen, status[0].State)
+	assert.Equal(t, breakerClosed, status[1].State)
+}
+
+func TestDNSServer_QueryTimeout(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.DNS.Upstream.Servers = []string{startFakeUpstream(t, "10.0.0.1", time.Second)}
+	cfg.DNS.QueryTimeout = 100
+	server := NewDNSServer(cfg, createTestLogger(t)).(*DNSServer)
+
+	// 上游应答慢于查询超时时立即返回SERVFAIL，不等待上游
+	r := new(dns.Msg)
+	r.SetQuestion("slow.example.com.", dns.TypeA)
+	w := &recordingWriter{}
+	start := time.Now()
+	server.handleDNSRequest(w, r)
+	require.NotNil(t, w.msg)
+	assert.Equal(t, dns.RcodeServerFailure, w.msg.Rcode)
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+}
@@ -0,0 +1,81 @@
+package dnsserver
+
+import (
+	"net"
+	"strings"
+
+	"github.com/hewenyu/kong-discovery/internal/config"
+	"go.uber.org/zap"
+)
+
+// viewNetwork 视图中的一个来源网段
+type viewNetwork struct {
+	view   string
+	ipNet  *net.IPNet
+	prefix int
+}
+
+// viewSelector 根据客户端来源地址选择DNS视图
+type viewSelector struct {
+	networks []viewNetwork
+}
+
+// newViewSelector 根据配置创建视图选择器，无效网段会被记录并跳过
+func newViewSelector(views []config.View, logger config.Logger) *viewSelector {
+	selector := &viewSelector{}
+	for _, v := range views {
+		for _, cidr := range v.CIDRs {
+			_, ipNet, err := net.ParseCIDR(cidr)
+			if err != nil {
+				logger.Warn("无效的DNS视图网段",
+					zap.String("view", v.Name),
+					zap.String("cidr", cidr),
+					zap.Error(err))
+				continue
+			}
+			prefix, _ := ipNet.Mask.Size()
+			selector.networks = append(selector.networks, viewNetwork{
+				view:   v.Name,
+				ipNet:  ipNet,
+				prefix: prefix,
+			})
+		}
+	}
+	return selector
+}
+
+// selectView 返回客户端地址所属的视图，多个网段匹配时最长前缀优先，未匹配时返回空字符串
+func (v *viewSelector) selectView(addr net.Addr) string {
+	if len(v.networks) == 0 || addr == nil {
+		return ""
+	}
+
+	ip := clientIP(addr)
+	if ip == nil {
+		return ""
+	}
+
+	best, bestPrefix := "", -1
+	for _, n := range v.networks {
+		if n.prefix > bestPrefix && n.ipNet.Contains(ip) {
+			best, bestPrefix = n.view, n.prefix
+		}
+	}
+	return best
+}
+
+// clientIP 从客户端地址中提取IP
+func clientIP(addr net.Addr) net.IP {
+	switch a := addr.(type) {
+	case *net.UDPAddr:
+		return a.IP
+	case *net.TCPAddr:
+		return a.IP
+	}
+
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		host = addr.String()
+	}
+	return net.ParseIP(strings.Trim(host, "[]"))
+}
@@ -0,0 +1,27 @@
+package dnsserver
+
+import (
+	"net"
+	"testing"
+
+	"github.com/hewenyu/kong-discovery/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestViewSelector_SelectView(t *testing.T) {
+	selector := newViewSelector([]config.View{
+		{Name: "internal", CIDRs: []string{"10.0.0.0/8", "invalid-cidr"}},
+		{Name: "dmz", CIDRs: []string{"10.10.0.0/16", "2001:db8::/32"}},
+	}, createTestLogger(t))
+
+	assert.Equal(t, "internal", selector.selectView(&net.UDPAddr{IP: net.ParseIP("10.1.2.3"), Port: 5353}))
+	assert.Equal(t, "dmz", selector.selectView(&net.UDPAddr{IP: net.ParseIP("10.10.2.3"), Port: 5353}), "最长前缀应优先")
+	assert.Equal(t, "dmz", selector.selectView(&net.TCPAddr{IP: net.ParseIP("2001:db8::1"), Port: 5353}))
+	assert.Equal(t, "", selector.selectView(&net.UDPAddr{IP: net.ParseIP("192.168.1.1"), Port: 5353}))
+	assert.Equal(t, "", selector.selectView(nil))
+}
+
+func TestViewSelector_NoViews(t *testing.T) {
+	selector := newViewSelector(nil, createTestLogger(t))
+	assert.Equal(t, "", selector.selectView(&net.UDPAddr{IP: net.ParseIP("10.1.2.3")}))
+}
@@ -0,0 +1,86 @@
+package dnsserver
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/hewenyu/kong-discovery/internal/etcdclient"
+	"github.com/miekg/dns"
+)
+
+// namespaceZones 命名空间自定义区域到命名空间的映射，随运行时配置从etcd重新加载
+type namespaceZones struct {
+	mu    sync.RWMutex
+	zones map[string]string
+}
+
+// newNamespaceZones 创建空的区域映射
+func newNamespaceZones() *namespaceZones {
+	return &namespaceZones{zones: make(map[string]string)}
+}
+
+// setZones 替换所有区域映射
+func (z *namespaceZones) setZones(zones []*etcdclient.NamespaceZone) {
+	mapped := make(map[string]string, len(zones))
+	for _, zone := range zones {
+		mapped[etcdclient.NormalizeZone(zone.Zone)] = etcdclient.NamespaceOf(zone.Namespace)
+	}
+
+	z.mu.Lock()
+	z.zones = mapped
+	z.mu.Unlock()
+}
+
+// serviceDomain 返回自定义区域中的名称对应的集群风格服务域名，
+// 名称不在任何区域内时返回false；区域嵌套时按最长的区域匹配
+func (z *namespaceZones) serviceDomain(domain string) (string, bool) {
+	z.mu.RLock()
+	defer z.mu.RUnlock()
+
+	if len(z.zones) == 0 {
+		return "", false
+	}
+	for i := strings.IndexByte(domain, '.'); i >= 0; {
+		zone := domain[i+1:]
+		if namespace, ok := z.zones[zone]; ok {
+			return etcdclient.ZoneServiceDomain(domain, zone, namespace)
+		}
+		next := strings.IndexByte(zone, '.')
+		if next < 0 {
+			break
+		}
+		i += next + 1
+	}
+	return "", false
+}
+
+// namespaceOf 返回名称所在自定义区域所属的命名空间，名称不在任何区域内时返回false；区域嵌套时按最长的区域匹配
+func (z *namespaceZones) namespaceOf(domain string) (string, bool) {
+	z.mu.RLock()
+	defer z.mu.RUnlock()
+
+	if len(z.zones) == 0 {
+		return "", false
+	}
+	for zone := domain; zone != ""; {
+		if namespace, ok := z.zones[zone]; ok {
+			return namespace, true
+		}
+		i := strings.IndexByte(zone, '.')
+		if i < 0 {
+			break
+		}
+		zone = zone[i+1:]
+	}
+	return "", false
+}
+
+// renameOwner 把应答中由集群风格服务域名生成的记录改回客户端查询的名称
+func renameOwner(rrs []dns.RR, from, to string) {
+	from, to = dns.Fqdn(from), dns.Fqdn(to)
+	for _, rr := range rrs {
+		if hdr := rr.Header(); strings.EqualFold(hdr.Name, from) {
+			hdr.Name = to
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package dnsserver
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hewenyu/kong-discovery/internal/config"
+	"github.com/hewenyu/kong-discovery/internal/etcdclient"
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNamespaceZones_ServiceDomain(t *testing.T) {
+	zones := newNamespaceZones()
+	_, ok := zones.serviceDomain("order.team-a.internal")
+	assert.False(t, ok, "没有区域时不映射")
+
+	zones.setZones([]*etcdclient.NamespaceZone{
+		{Zone: "team-a.internal", Namespace: "team-a"},
+		{Zone: "eu.team-a.internal", Namespace: "team-a-eu"},
+	})
+
+	domain, ok := zones.serviceDomain("order.team-a.internal")
+	require.True(t, ok)
+	assert.Equal(t, "order.team-a.svc.cluster.local", domain)
+
+	// 嵌套的区域按最长的区域匹配
+	domain, ok = zones.serviceDomain("order.eu.team-a.internal")
+	require.True(t, ok)
+	assert.Equal(t, "order.team-a-eu.svc.cluster.local", domain)
+
+	domain, ok = zones.serviceDomain("_http._tcp.order.team-a.internal")
+	require.True(t, ok)
+	assert.Equal(t, "_http._tcp.order.team-a.svc.cluster.local", domain)
+
+	_, ok = zones.serviceDomain("team-a.internal")
+	assert.False(t, ok)
+	_, ok = zones.serviceDomain("order.team-b.internal")
+	assert.False(t, ok)
+}
+
+func TestDNSServer_NamespaceZoneQuery(t *testing.T) {
+	// 跳过集成测试，除非明确要求运行
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	client := etcdclient.CreateEtcdClientForTest(t)
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	namespace := fmt.Sprintf("zone-ns-%d", time.Now().UnixNano())
+	require.NoError(t, client.CreateNamespace(ctx, &etcdclient.Namespace{Name: namespace}))
+	defer func() { _ = client.DeleteNamespace(context.Background(), namespace, true) }()
+	require.NoError(t, client.PutNamespaceZone(ctx, &etcdclient.NamespaceZone{Zone: "team-a.internal", Namespace: namespace}))
+
+	instance := &etcdclient.ServiceInstance{ServiceName: "order", InstanceID: "order-1", IPAddress: "10.0.0.21", Port: 8080, TTL: 60, Namespace: namespace}
+	require.NoError(t, client.RegisterService(ctx, instance))
+	defer func() { _ = client.DeregisterService(context.Background(), "order", "order-1") }()
+
+	server := NewDNSServer(&config.Config{}, createTestLogger(t)).(*DNSServer)
+	server.SetEtcdClient(client)
+	server.applyNamespaceZones()
+
+	query := func(name string, qtype uint16) *dns.Msg {
+		r := new(dns.Msg)
+		r.SetQuestion(name, qtype)
+		w := &recordingWriter{}
+		server.handleDNSRequest(w, r)
+		require.NotNil(t, w.msg)
+		return w.msg
+	}
+
+	// 区域中的名称返回命名空间中服务的地址，应答名称与查询一致
+	m := query("order.team-a.internal.", dns.TypeA)
+	require.Len(t, m.Answer, 1)
+	assert.Equal(t, "order.team-a.internal.", m.Answer[0].Header().Name)
+	assert.Equal(t, "10.0.0.21", m.Answer[0].(*dns.A).A.String())
+
+	// 集群风格的名称仍然可以解析
+	m = query("order."+namespace+".svc.cluster.local.", dns.TypeA)
+	require.Len(t, m.Answer, 1)
+
+	m = query("order.team-a.internal.", dns.TypeSRV)
+	require.Len(t, m.Answer, 1)
+	assert.Equal(t, "order.team-a.internal.", m.Answer[0].Header().Name)
+	assert.Equal(t, uint16(8080), m.Answer[0].(*dns.SRV).Port)
+}
@@ -0,0 +1,247 @@
+// Package etcdapi 进程内实现etcd gRPC接口时共用的部分
+//
+// 内存存储（memstore）和SQL存储（sqlstore）都实现etcd客户端使用的KV、租约和监听接口，
+// 这里提供两者共用的请求语义（key范围、事务比较、排序）、双向流以及到*clientv3.Client的包装，
+// 保证不同存储对同一请求的行为与etcd一致
+package etcdapi
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"slices"
+	"time"
+
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"google.golang.org/grpc"
+)
+
+// 响应头中的集群和成员ID，进程内的存储只有一个成员
+const (
+	ClusterID = 1
+	MemberID  = 1
+)
+
+// MinLeaseTTL 租约的最小TTL（秒），与etcd默认配置下的最小值一致
+const MinLeaseTTL = 2
+
+// keepAliveTimeout 首次续约的响应超时，与etcd客户端的默认值一致
+const keepAliveTimeout = 5 * time.Second
+
+// Header 返回指定修订版本的响应头
+func Header(rev int64) *pb.ResponseHeader {
+	return &pb.ResponseHeader{ClusterId: ClusterID, MemberId: MemberID, Revision: rev, RaftTerm: 1}
+}
+
+// KeyRange 请求中的key和range_end：End为空表示单个key，为"\x00"表示Key及之后的所有key
+type KeyRange struct {
+	Key []byte
+	End []byte
+}
+
+// Single 判断是否只包含单个key
+func (r KeyRange) Single() bool {
+	return len(r.End) == 0
+}
+
+// Unbounded 判断范围是否没有上界
+func (r KeyRange) Unbounded() bool {
+	return len(r.End) == 1 && r.End[0] == 0
+}
+
+// Contains 判断key是否在范围内
+func (r KeyRange) Contains(key []byte) bool {
+	if r.Single() {
+		return bytes.Equal(key, r.Key)
+	}
+	if bytes.Compare(key, r.Key) < 0 {
+		return false
+	}
+	return r.Unbounded() || bytes.Compare(key, r.End) < 0
+}
+
+// Compare 判断事务的比较条件是否成立，kvs为比较范围内的当前键值；范围比较要求所有key都满足条件，
+// 与etcd一致，key不存在时按空键值比较，但比较值的条件不成立
+func Compare(c *pb.Compare, kvs []*mvccpb.KeyValue) bool {
+	if len(kvs) == 0 {
+		if c.Target == pb.Compare_VALUE {
+			return false
+		}
+		return compareKV(c, &mvccpb.KeyValue{})
+	}
+	for _, kv := range kvs {
+		if !compareKV(c, kv) {
+			return false
+		}
+	}
+	return true
+}
+
+// compareKV 判断单个键值是否满足比较条件
+func compareKV(c *pb.Compare, kv *mvccpb.KeyValue) bool {
+	var result int
+	switch c.Target {
+	case pb.Compare_VALUE:
+		result = bytes.Compare(kv.Value, c.GetValue())
+	case pb.Compare_VERSION:
+		result = cmp.Compare(kv.Version, c.GetVersion())
+	case pb.Compare_CREATE:
+		result = cmp.Compare(kv.CreateRevision, c.GetCreateRevision())
+	case pb.Compare_MOD:
+		result = cmp.Compare(kv.ModRevision, c.GetModRevision())
+	case pb.Compare_LEASE:
+		result = cmp.Compare(kv.Lease, c.GetLease())
+	}
+
+	switch c.Result {
+	case pb.Compare_EQUAL:
+		return result == 0
+	case pb.Compare_NOT_EQUAL:
+		return result != 0
+	case pb.Compare_GREATER:
+		return result > 0
+	case pb.Compare_LESS:
+		return result < 0
+	}
+	return false
+}
+
+// RangeResponse 对按key升序排列的键值应用读取请求中的过滤、排序、数量限制和只返回key/计数选项
+func RangeResponse(in *pb.RangeRequest, kvs []*mvccpb.KeyValue) *pb.RangeResponse {
+	var result []*mvccpb.KeyValue
+	for _, kv := range kvs {
+		if (in.MinModRevision > 0 && kv.ModRevision < in.MinModRevision) ||
+			(in.MaxModRevision > 0 && kv.ModRevision > in.MaxModRevision) ||
+			(in.MinCreateRevision > 0 && kv.CreateRevision < in.MinCreateRevision) ||
+			(in.MaxCreateRevision > 0 && kv.CreateRevision > in.MaxCreateRevision) {
+			continue
+		}
+		result = append(result, kv)
+	}
+	sortKVs(result, in.SortOrder, in.SortTarget)
+
+	resp := &pb.RangeResponse{Count: int64(len(result))}
+	if in.CountOnly {
+		return resp
+	}
+	if in.Limit > 0 && int64(len(result)) > in.Limit {
+		result = result[:in.Limit]
+		resp.More = true
+	}
+	resp.Kvs = make([]*mvccpb.KeyValue, 0, len(result))
+	for _, kv := range result {
+		out := *kv
+		if in.KeysOnly {
+			out.Value = nil
+		}
+		resp.Kvs = append(resp.Kvs, &out)
+	}
+	return resp
+}
+
+// sortKVs 按请求的排序方式排序，kvs已按key升序排列；与etcd一致，只指定排序字段时按升序排列
+func sortKVs(kvs []*mvccpb.KeyValue, order pb.RangeRequest_SortOrder, target pb.RangeRequest_SortTarget) {
+	if order == pb.RangeRequest_NONE {
+		if target == pb.RangeRequest_KEY {
+			return
+		}
+		order = pb.RangeRequest_ASCEND
+	}
+
+	var less func(a, b *mvccpb.KeyValue) int
+	switch target {
+	case pb.RangeRequest_VERSION:
+		less = func(a, b *mvccpb.KeyValue) int { return cmp.Compare(a.Version, b.Version) }
+	case pb.RangeRequest_CREATE:
+		less = func(a, b *mvccpb.KeyValue) int { return cmp.Compare(a.CreateRevision, b.CreateRevision) }
+	case pb.RangeRequest_MOD:
+		less = func(a, b *mvccpb.KeyValue) int { return cmp.Compare(a.ModRevision, b.ModRevision) }
+	case pb.RangeRequest_VALUE:
+		less = func(a, b *mvccpb.KeyValue) int { return bytes.Compare(a.Value, b.Value) }
+	default:
+		less = func(a, b *mvccpb.KeyValue) int { return bytes.Compare(a.Key, b.Key) }
+	}
+	if order == pb.RangeRequest_DESCEND {
+		asc := less
+		less = func(a, b *mvccpb.KeyValue) int { return asc(b, a) }
+	}
+	slices.SortStableFunc(kvs, less)
+}
+
+// SetTxnHeaders 设置事务及其中各操作响应的响应头
+func SetTxnHeaders(resp *pb.TxnResponse, header *pb.ResponseHeader) {
+	resp.Header = header
+	for _, op := range resp.Responses {
+		switch r := op.Response.(type) {
+		case *pb.ResponseOp_ResponseRange:
+			r.ResponseRange.Header = header
+		case *pb.ResponseOp_ResponsePut:
+			r.ResponsePut.Header = header
+		case *pb.ResponseOp_ResponseDeleteRange:
+			r.ResponseDeleteRange.Header = header
+		case *pb.ResponseOp_ResponseTxn:
+			SetTxnHeaders(r.ResponseTxn, header)
+		}
+	}
+}
+
+// Backend 进程内实现的etcd服务端
+type Backend interface {
+	pb.KVClient
+	pb.LeaseClient
+	pb.WatchClient
+
+	// Status 返回存储的状态
+	Status(ctx context.Context) (*pb.StatusResponse, error)
+}
+
+// NewClient 创建使用backend的etcd客户端，关闭客户端不影响存储中的数据和其他客户端
+// 客户端只支持KV、租约、监听和状态查询，集群成员和认证接口不可用
+func NewClient(backend Backend) *clientv3.Client {
+	c := clientv3.NewCtxClient(context.Background())
+	c.KV = clientv3.NewKVFromKVClient(backend, c)
+	c.Lease = clientv3.NewLeaseFromLeaseClient(backend, c, keepAliveTimeout)
+	c.Watcher = clientv3.NewWatchFromWatchClient(backend, c)
+	c.Maintenance = clientv3.NewMaintenanceFromMaintenanceClient(&maintenance{backend: backend}, c)
+	return c
+}
+
+// maintenance 维护接口，只支持状态查询
+type maintenance struct {
+	backend Backend
+}
+
+func (m *maintenance) Status(ctx context.Context, _ *pb.StatusRequest, _ ...grpc.CallOption) (*pb.StatusResponse, error) {
+	return m.backend.Status(ctx)
+}
+
+func (m *maintenance) Alarm(context.Context, *pb.AlarmRequest, ...grpc.CallOption) (*pb.AlarmResponse, error) {
+	return nil, rpctypes.ErrGRPCNotCapable
+}
+
+func (m *maintenance) Defragment(context.Context, *pb.DefragmentRequest, ...grpc.CallOption) (*pb.DefragmentResponse, error) {
+	return nil, rpctypes.ErrGRPCNotCapable
+}
+
+func (m *maintenance) Hash(context.Context, *pb.HashRequest, ...grpc.CallOption) (*pb.HashResponse, error) {
+	return nil, rpctypes.ErrGRPCNotCapable
+}
+
+func (m *maintenance) HashKV(context.Context, *pb.HashKVRequest, ...grpc.CallOption) (*pb.HashKVResponse, error) {
+	return nil, rpctypes.ErrGRPCNotCapable
+}
+
+func (m *maintenance) Snapshot(context.Context, *pb.SnapshotRequest, ...grpc.CallOption) (pb.Maintenance_SnapshotClient, error) {
+	return nil, rpctypes.ErrGRPCNotCapable
+}
+
+func (m *maintenance) MoveLeader(context.Context, *pb.MoveLeaderRequest, ...grpc.CallOption) (*pb.MoveLeaderResponse, error) {
+	return nil, rpctypes.ErrGRPCNotCapable
+}
+
+func (m *maintenance) Downgrade(context.Context, *pb.DowngradeRequest, ...grpc.CallOption) (*pb.DowngradeResponse, error) {
+	return nil, rpctypes.ErrGRPCNotCapable
+}
@@ -0,0 +1,59 @@
+package etcdapi
+
+import (
+	"context"
+
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"google.golang.org/grpc"
+)
+
+// readOnly 拒绝所有写操作的存储，读取和监听交给被包装的存储
+type readOnly struct {
+	Backend
+	err error
+}
+
+// ReadOnly 包装backend，写入、删除、包含写操作的事务和创建租约都返回err
+func ReadOnly(backend Backend, err error) Backend {
+	return &readOnly{Backend: backend, err: err}
+}
+
+func (r *readOnly) Put(context.Context, *pb.PutRequest, ...grpc.CallOption) (*pb.PutResponse, error) {
+	return nil, r.err
+}
+
+func (r *readOnly) DeleteRange(context.Context, *pb.DeleteRangeRequest, ...grpc.CallOption) (*pb.DeleteRangeResponse, error) {
+	return nil, r.err
+}
+
+func (r *readOnly) Txn(ctx context.Context, in *pb.TxnRequest, opts ...grpc.CallOption) (*pb.TxnResponse, error) {
+	if writesTxn(in) {
+		return nil, r.err
+	}
+	return r.Backend.Txn(ctx, in, opts...)
+}
+
+func (r *readOnly) Compact(context.Context, *pb.CompactionRequest, ...grpc.CallOption) (*pb.CompactionResponse, error) {
+	return nil, r.err
+}
+
+func (r *readOnly) LeaseGrant(context.Context, *pb.LeaseGrantRequest, ...grpc.CallOption) (*pb.LeaseGrantResponse, error) {
+	return nil, r.err
+}
+
+// writesTxn 判断事务的任一分支（包括嵌套事务）是否包含写操作
+func writesTxn(in *pb.TxnRequest) bool {
+	for _, ops := range [][]*pb.RequestOp{in.Success, in.Failure} {
+		for _, op := range ops {
+			switch r := op.Request.(type) {
+			case *pb.RequestOp_RequestPut, *pb.RequestOp_RequestDeleteRange:
+				return true
+			case *pb.RequestOp_RequestTxn:
+				if writesTxn(r.RequestTxn) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,211 @@
+package etcdapi
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	"google.golang.org/grpc/metadata"
+)
+
+// errRawMessage 进程内的流只支持类型化的Send和Recv
+var errRawMessage = errors.New("etcdapi: 流不支持SendMsg和RecvMsg")
+
+// Stream 双向流的客户端一侧，实现grpc.ClientStream；响应在无界队列中等待Recv，
+// 存储通知监听者时不会因为客户端读取较慢而阻塞
+type Stream[T any] struct {
+	ctx    context.Context
+	mu     sync.Mutex
+	queue  []T
+	notify chan struct{}
+}
+
+// NewStream 创建在ctx取消时结束的流
+func NewStream[T any](ctx context.Context) *Stream[T] {
+	return &Stream[T]{ctx: ctx, notify: make(chan struct{}, 1)}
+}
+
+// Push 把响应加入队列
+func (st *Stream[T]) Push(resp T) {
+	st.mu.Lock()
+	st.queue = append(st.queue, resp)
+	st.mu.Unlock()
+
+	select {
+	case st.notify <- struct{}{}:
+	default:
+	}
+}
+
+// Recv 等待下一个响应，流的ctx取消后返回错误
+func (st *Stream[T]) Recv() (T, error) {
+	for {
+		st.mu.Lock()
+		if len(st.queue) > 0 {
+			resp := st.queue[0]
+			var zero T
+			st.queue[0] = zero
+			st.queue = st.queue[1:]
+			st.mu.Unlock()
+			return resp, nil
+		}
+		st.mu.Unlock()
+
+		select {
+		case <-st.notify:
+		case <-st.ctx.Done():
+			var zero T
+			return zero, st.ctx.Err()
+		}
+	}
+}
+
+func (st *Stream[T]) Header() (metadata.MD, error) { return metadata.MD{}, nil }
+func (st *Stream[T]) Trailer() metadata.MD         { return metadata.MD{} }
+func (st *Stream[T]) CloseSend() error             { return nil }
+func (st *Stream[T]) Context() context.Context     { return st.ctx }
+func (st *Stream[T]) SendMsg(any) error            { return errRawMessage }
+func (st *Stream[T]) RecvMsg(any) error            { return errRawMessage }
+
+// KeepAliveStream 续约流，每个续约请求对应一个响应
+type KeepAliveStream struct {
+	*Stream[*pb.LeaseKeepAliveResponse]
+	keepAlive func(ctx context.Context, id int64) (*pb.LeaseKeepAliveResponse, error)
+}
+
+// NewKeepAliveStream 创建续约流，keepAlive为租约续约并返回响应，租约不存在时响应的TTL为0
+func NewKeepAliveStream(ctx context.Context, keepAlive func(ctx context.Context, id int64) (*pb.LeaseKeepAliveResponse, error)) *KeepAliveStream {
+	return &KeepAliveStream{Stream: NewStream[*pb.LeaseKeepAliveResponse](ctx), keepAlive: keepAlive}
+}
+
+// Send 发送续约请求
+func (ks *KeepAliveStream) Send(req *pb.LeaseKeepAliveRequest) error {
+	if err := ks.ctx.Err(); err != nil {
+		return err
+	}
+	resp, err := ks.keepAlive(ks.ctx, req.ID)
+	if err != nil {
+		return err
+	}
+	ks.Push(resp)
+	return nil
+}
+
+// WatchServer 处理监听流中的请求
+type WatchServer interface {
+	// CreateWatch 创建监听：先确认创建，再发送起始版本之后的历史事件，之后的写入实时通知
+	CreateWatch(ws *WatchStream, req *pb.WatchCreateRequest)
+
+	// CancelWatch 取消监听
+	CancelWatch(ws *WatchStream, id int64)
+
+	// Progress 返回当前修订版本，表示之前的事件都已发送
+	Progress(ws *WatchStream)
+
+	// CloseWatchStream 监听流结束后删除其中的所有监听
+	CloseWatchStream(ws *WatchStream)
+}
+
+// WatchStream 监听流，一个流中可以创建多个监听
+type WatchStream struct {
+	*Stream[*pb.WatchResponse]
+	server WatchServer
+
+	// 以下字段由WatchServer同步访问
+	NextID   int64
+	Watchers map[int64]*Watcher
+}
+
+// NewWatchStream 创建监听流，ctx取消时通知server关闭流
+func NewWatchStream(ctx context.Context, server WatchServer) *WatchStream {
+	ws := &WatchStream{
+		Stream:   NewStream[*pb.WatchResponse](ctx),
+		server:   server,
+		Watchers: make(map[int64]*Watcher),
+	}
+	context.AfterFunc(ctx, func() { server.CloseWatchStream(ws) })
+	return ws
+}
+
+// Closed 判断流是否已结束
+func (ws *WatchStream) Closed() bool {
+	return ws.ctx.Err() != nil
+}
+
+// Send 发送创建、取消监听或查询进度的请求
+func (ws *WatchStream) Send(req *pb.WatchRequest) error {
+	if err := ws.ctx.Err(); err != nil {
+		return err
+	}
+	switch r := req.RequestUnion.(type) {
+	case *pb.WatchRequest_CreateRequest:
+		ws.server.CreateWatch(ws, r.CreateRequest)
+	case *pb.WatchRequest_CancelRequest:
+		ws.server.CancelWatch(ws, r.CancelRequest.WatchId)
+	case *pb.WatchRequest_ProgressRequest:
+		ws.server.Progress(ws)
+	}
+	return nil
+}
+
+// NewWatcher 按创建请求在流中分配一个监听，并发送创建确认
+func (ws *WatchStream) NewWatcher(req *pb.WatchCreateRequest, header *pb.ResponseHeader) *Watcher {
+	w := &Watcher{ID: ws.NextID, stream: ws, keys: KeyRange{Key: req.Key, End: req.RangeEnd}, prevKV: req.PrevKv}
+	ws.NextID++
+	for _, filter := range req.Filters {
+		switch filter {
+		case pb.WatchCreateRequest_NOPUT:
+			w.noPut = true
+		case pb.WatchCreateRequest_NODELETE:
+			w.noDelete = true
+		}
+	}
+	ws.Push(&pb.WatchResponse{Header: header, WatchId: w.ID, Created: true})
+	return w
+}
+
+// Watcher 一个监听
+type Watcher struct {
+	ID       int64
+	stream   *WatchStream
+	keys     KeyRange
+	prevKV   bool
+	noPut    bool
+	noDelete bool
+}
+
+// Stream 返回监听所在的流
+func (w *Watcher) Stream() *WatchStream {
+	return w.stream
+}
+
+// Send 把匹配的事件发送给监听者，events中的事件总是带有PrevKv，按监听的选项决定是否返回
+func (w *Watcher) Send(header *pb.ResponseHeader, events []*mvccpb.Event) {
+	var matched []*mvccpb.Event
+	for _, event := range events {
+		if !w.keys.Contains(event.Kv.Key) ||
+			(event.Type == mvccpb.PUT && w.noPut) || (event.Type == mvccpb.DELETE && w.noDelete) {
+			continue
+		}
+		out := &mvccpb.Event{Type: event.Type, Kv: event.Kv}
+		if w.prevKV {
+			out.PrevKv = event.PrevKv
+		}
+		matched = append(matched, out)
+	}
+	if len(matched) > 0 {
+		w.stream.Push(&pb.WatchResponse{Header: header, WatchId: w.ID, Events: matched})
+	}
+}
+
+// Compacted 通知监听者起始版本已被压缩并取消监听，与etcd的行为一致
+func (w *Watcher) Compacted(header *pb.ResponseHeader, compactRevision int64) {
+	w.stream.Push(&pb.WatchResponse{Header: header, WatchId: w.ID, CompactRevision: compactRevision, Canceled: true})
+}
+
+// Canceled 确认监听已取消
+func (w *Watcher) Canceled(header *pb.ResponseHeader) {
+	w.stream.Push(&pb.WatchResponse{Header: header, WatchId: w.ID, Canceled: true})
+}
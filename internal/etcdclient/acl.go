@@ -0,0 +1,172 @@
+package etcdclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.uber.org/zap"
+)
+
+// 服务解析ACL在etcd中的键前缀
+const serviceACLPrefix = "/config/acl/"
+
+// 客户端不在ACL允许范围内时的应答
+const (
+	ACLActionNXDomain = "nxdomain" // 返回NXDOMAIN，服务对该客户端不可见
+	ACLActionRefused  = "refused"  // 返回REFUSED
+)
+
+// ServiceACL 服务级别的解析ACL，设置后只有允许的客户端能解析到服务
+// 来源地址在AllowCIDRs内或使用AllowIdentities中的TSIG密钥签名的查询被允许，两者都为空时拒绝所有客户端
+type ServiceACL struct {
+	ServiceName     string    `json:"service_name"`               // 服务名称
+	AllowCIDRs      []string  `json:"allow_cidrs,omitempty"`      // 允许的客户端网段
+	AllowIdentities []string  `json:"allow_identities,omitempty"` // 允许的身份，即DNS查询签名使用的TSIG密钥名称
+	Action          string    `json:"action"`                     // 拒绝时的应答：nxdomain或refused
+	UpdatedAt       time.Time `json:"updated_at"`                 // 更新时间
+}
+
+// Validate 校验ACL并规范化身份名称，未设置拒绝动作时使用nxdomain
+func (a *ServiceACL) Validate() error {
+	if a.ServiceName == "" || strings.Contains(a.ServiceName, "/") {
+		return fmt.Errorf("无效的服务名称: %q", a.ServiceName)
+	}
+	for _, cidr := range a.AllowCIDRs {
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			return fmt.Errorf("无效的网段: %s", cidr)
+		}
+	}
+	for i, identity := range a.AllowIdentities {
+		identity = NormalizeIdentity(identity)
+		if identity == "" {
+			return fmt.Errorf("身份名称不能为空")
+		}
+		a.AllowIdentities[i] = identity
+	}
+
+	switch a.Action {
+	case "":
+		a.Action = ACLActionNXDomain
+	case ACLActionNXDomain, ACLActionRefused:
+	default:
+		return fmt.Errorf("不支持的拒绝动作: %s", a.Action)
+	}
+	return nil
+}
+
+// NormalizeIdentity 规范化身份名称：TSIG密钥名称不区分大小写，忽略尾部的点号
+func NormalizeIdentity(identity string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(identity)), ".")
+}
+
+// getServiceACLKey 生成服务解析ACL的etcd键
+func getServiceACLKey(serviceName string) string {
+	return serviceACLPrefix + serviceName
+}
+
+// GetServiceACL 获取服务的解析ACL，未设置时返回nil
+func (e *EtcdClient) GetServiceACL(ctx context.Context, serviceName string) (*ServiceACL, error) {
+	if e.conn() == nil {
+		return nil, fmt.Errorf("etcd客户端未连接")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, etcdTimeout)
+	defer cancel()
+
+	resp, err := e.conn().Get(ctx, getServiceACLKey(serviceName))
+	if err != nil {
+		return nil, fmt.Errorf("获取服务解析ACL失败: %w", err)
+	}
+	if len(resp.Kvs) == 0 {
+		return nil, nil
+	}
+
+	var acl ServiceACL
+	if err := json.Unmarshal(resp.Kvs[0].Value, &acl); err != nil {
+		return nil, fmt.Errorf("解析服务解析ACL失败: %w", err)
+	}
+	return &acl, nil
+}
+
+// ListServiceACLs 获取所有服务的解析ACL，按服务名称排序
+func (e *EtcdClient) ListServiceACLs(ctx context.Context) ([]*ServiceACL, error) {
+	if e.conn() == nil {
+		return nil, fmt.Errorf("etcd客户端未连接")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, etcdTimeout)
+	defer cancel()
+
+	resp, err := e.conn().Get(ctx, serviceACLPrefix, clientv3.WithPrefix())
+	if err != nil {
+		e.log(ctx).Error("获取服务解析ACL列表失败", zap.Error(err))
+		return nil, fmt.Errorf("获取服务解析ACL列表失败: %w", err)
+	}
+
+	acls := make([]*ServiceACL, 0, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		var acl ServiceACL
+		if err := json.Unmarshal(kv.Value, &acl); err != nil {
+			e.log(ctx).Warn("解析服务解析ACL失败", zap.String("key", string(kv.Key)), zap.Error(err))
+			continue
+		}
+		acls = append(acls, &acl)
+	}
+	sort.Slice(acls, func(i, j int) bool {
+		return acls[i].ServiceName < acls[j].ServiceName
+	})
+
+	return acls, nil
+}
+
+// PutServiceACL 设置服务的解析ACL
+func (e *EtcdClient) PutServiceACL(ctx context.Context, acl *ServiceACL) error {
+	if e.conn() == nil {
+		return fmt.Errorf("etcd客户端未连接")
+	}
+	if err := acl.Validate(); err != nil {
+		return err
+	}
+
+	acl.UpdatedAt = time.Now()
+	data, err := json.Marshal(acl)
+	if err != nil {
+		return fmt.Errorf("序列化服务解析ACL失败: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, etcdTimeout)
+	defer cancel()
+
+	if _, err := e.conn().Put(ctx, getServiceACLKey(acl.ServiceName), string(data)); err != nil {
+		e.log(ctx).Error("保存服务解析ACL失败", zap.String("service", acl.ServiceName), zap.Error(err))
+		return fmt.Errorf("保存服务解析ACL失败: %w", err)
+	}
+
+	e.log(ctx).Info("服务解析ACL已更新",
+		zap.String("service", acl.ServiceName),
+		zap.Strings("allow_cidrs", acl.AllowCIDRs),
+		zap.Strings("allow_identities", acl.AllowIdentities),
+		zap.String("action", acl.Action))
+	return nil
+}
+
+// DeleteServiceACL 删除服务的解析ACL，之后所有客户端都可以解析该服务
+func (e *EtcdClient) DeleteServiceACL(ctx context.Context, serviceName string) error {
+	if e.conn() == nil {
+		return fmt.Errorf("etcd客户端未连接")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, etcdTimeout)
+	defer cancel()
+
+	if _, err := e.conn().Delete(ctx, getServiceACLKey(serviceName)); err != nil {
+		return fmt.Errorf("删除服务解析ACL失败: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package etcdclient
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServiceACL_Validate(t *testing.T) {
+	acl := &ServiceACL{ServiceName: "payments", AllowCIDRs: []string{"10.0.0.0/8"}, AllowIdentities: []string{" Billing-Key. "}}
+	require.NoError(t, acl.Validate())
+	assert.Equal(t, ACLActionNXDomain, acl.Action)
+	assert.Equal(t, []string{"billing-key"}, acl.AllowIdentities)
+
+	tests := []struct {
+		name string
+		acl  ServiceACL
+	}{
+		{"缺少服务名称", ServiceACL{}},
+		{"无效的服务名称", ServiceACL{ServiceName: "a/b"}},
+		{"无效的网段", ServiceACL{ServiceName: "payments", AllowCIDRs: []string{"10.0.0.1"}}},
+		{"空身份", ServiceACL{ServiceName: "payments", AllowIdentities: []string{"."}}},
+		{"不支持的动作", ServiceACL{ServiceName: "payments", Action: "drop"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Error(t, tt.acl.Validate())
+		})
+	}
+}
+
+func TestEtcdClient_ServiceACL(t *testing.T) {
+	// 跳过集成测试，除非明确要求运行
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	client := CreateEtcdClientForTest(t)
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	serviceName := fmt.Sprintf("acl-service-%d", time.Now().UnixNano())
+	defer func() { _ = client.DeleteServiceACL(context.Background(), serviceName) }()
+
+	acl, err := client.GetServiceACL(ctx, serviceName)
+	require.NoError(t, err)
+	assert.Nil(t, acl)
+
+	require.NoError(t, client.PutServiceACL(ctx, &ServiceACL{ServiceName: serviceName, AllowCIDRs: []string{"10.0.0.0/8"}, Action: ACLActionRefused}))
+	acl, err = client.GetServiceACL(ctx, serviceName)
+	require.NoError(t, err)
+	require.NotNil(t, acl)
+	assert.Equal(t, []string{"10.0.0.0/8"}, acl.AllowCIDRs)
+	assert.Equal(t, ACLActionRefused, acl.Action)
+	assert.False(t, acl.UpdatedAt.IsZero())
+
+	acls, err := client.ListServiceACLs(ctx)
+	require.NoError(t, err)
+	found := false
+	for _, a := range acls {
+		found = found || a.ServiceName == serviceName
+	}
+	assert.True(t, found)
+
+	assert.Error(t, client.PutServiceACL(ctx, &ServiceACL{ServiceName: serviceName, AllowCIDRs: []string{"bad"}}))
+
+	require.NoError(t, client.DeleteServiceACL(ctx, serviceName))
+	acl, err = client.GetServiceACL(ctx, serviceName)
+	require.NoError(t, err)
+	assert.Nil(t, acl)
+}
@@ -0,0 +1,209 @@
+package etcdclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.uber.org/zap"
+)
+
+// 服务别名在etcd中的键前缀
+const serviceAliasPrefix = "/config/aliases/"
+
+// ErrInvalidAlias 别名定义无效，或与已有的别名构成链
+var ErrInvalidAlias = errors.New("无效的服务别名")
+
+// ServiceAlias 服务别名，对别名的DNS查询返回目标服务的实例
+// 修改目标是对单个键的写入，所有节点在下次加载运行时配置时同时切换，可用于蓝绿发布；
+// 设置了Split时每次查询按权重抽取一个目标服务，用于逐步迁移流量
+type ServiceAlias struct {
+	ServiceName string        `json:"service_name"`     // 别名
+	Target      string        `json:"target,omitempty"` // 目标服务名称，与Split二选一
+	Split       []SplitTarget `json:"split,omitempty"`  // 按权重分流的目标服务
+	UpdatedAt   time.Time     `json:"updated_at"`       // 更新时间
+}
+
+// SplitTarget 分流中的一个目标服务
+type SplitTarget struct {
+	Service string `json:"service"` // 目标服务名称
+	Weight  int    `json:"weight"`  // 权重，按与其他目标的权重之比分配查询，0表示暂不分配
+}
+
+// Targets 返回别名指向的所有目标服务
+func (a *ServiceAlias) Targets() []string {
+	if len(a.Split) == 0 {
+		return []string{a.Target}
+	}
+	targets := make([]string, 0, len(a.Split))
+	for _, t := range a.Split {
+		targets = append(targets, t.Service)
+	}
+	return targets
+}
+
+// Validate 校验别名和目标服务名称
+func (a *ServiceAlias) Validate() error {
+	if a.ServiceName == "" || strings.Contains(a.ServiceName, "/") {
+		return fmt.Errorf("无效的服务名称: %q", a.ServiceName)
+	}
+	if a.Target != "" && len(a.Split) > 0 {
+		return fmt.Errorf("%w: target和split不能同时设置", ErrInvalidAlias)
+	}
+
+	total := 0
+	seen := make(map[string]bool, len(a.Split))
+	for _, t := range a.Split {
+		if t.Weight < 0 {
+			return fmt.Errorf("%w: 目标服务 %s 的权重不能为负数", ErrInvalidAlias, t.Service)
+		}
+		if seen[t.Service] {
+			return fmt.Errorf("%w: 目标服务 %s 重复", ErrInvalidAlias, t.Service)
+		}
+		seen[t.Service] = true
+		total += t.Weight
+	}
+	if len(a.Split) > 0 && total == 0 {
+		return fmt.Errorf("%w: 分流的权重之和必须大于0", ErrInvalidAlias)
+	}
+
+	for _, target := range a.Targets() {
+		if target == "" || strings.Contains(target, "/") {
+			return fmt.Errorf("无效的目标服务名称: %q", target)
+		}
+		if target == a.ServiceName {
+			return fmt.Errorf("%w: 服务不能是自身的别名", ErrInvalidAlias)
+		}
+	}
+	return nil
+}
+
+// getServiceAliasKey 生成服务别名的etcd键
+func getServiceAliasKey(serviceName string) string {
+	return serviceAliasPrefix + serviceName
+}
+
+// GetServiceAlias 获取服务别名，服务不是别名时返回nil
+func (e *EtcdClient) GetServiceAlias(ctx context.Context, serviceName string) (*ServiceAlias, error) {
+	if e.conn() == nil {
+		return nil, fmt.Errorf("etcd客户端未连接")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, etcdTimeout)
+	defer cancel()
+
+	resp, err := e.conn().Get(ctx, getServiceAliasKey(serviceName))
+	if err != nil {
+		return nil, fmt.Errorf("获取服务别名失败: %w", err)
+	}
+	if len(resp.Kvs) == 0 {
+		return nil, nil
+	}
+
+	var alias ServiceAlias
+	if err := json.Unmarshal(resp.Kvs[0].Value, &alias); err != nil {
+		return nil, fmt.Errorf("解析服务别名失败: %w", err)
+	}
+	return &alias, nil
+}
+
+// ListServiceAliases 获取所有服务别名，按别名排序
+func (e *EtcdClient) ListServiceAliases(ctx context.Context) ([]*ServiceAlias, error) {
+	if e.conn() == nil {
+		return nil, fmt.Errorf("etcd客户端未连接")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, etcdTimeout)
+	defer cancel()
+
+	resp, err := e.conn().Get(ctx, serviceAliasPrefix, clientv3.WithPrefix())
+	if err != nil {
+		e.log(ctx).Error("获取服务别名列表失败", zap.Error(err))
+		return nil, fmt.Errorf("获取服务别名列表失败: %w", err)
+	}
+
+	aliases := make([]*ServiceAlias, 0, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		var alias ServiceAlias
+		if err := json.Unmarshal(kv.Value, &alias); err != nil {
+			e.log(ctx).Warn("解析服务别名失败", zap.String("key", string(kv.Key)), zap.Error(err))
+			continue
+		}
+		aliases = append(aliases, &alias)
+	}
+	sort.Slice(aliases, func(i, j int) bool {
+		return aliases[i].ServiceName < aliases[j].ServiceName
+	})
+
+	return aliases, nil
+}
+
+// PutServiceAlias 创建服务别名或切换别名的目标
+// 别名只能指向实际的服务：目标是别名、或者别名已被其他别名指向时返回ErrInvalidAlias
+func (e *EtcdClient) PutServiceAlias(ctx context.Context, alias *ServiceAlias) error {
+	if e.conn() == nil {
+		return fmt.Errorf("etcd客户端未连接")
+	}
+	if err := alias.Validate(); err != nil {
+		return err
+	}
+	if err := e.checkReservedService(alias.ServiceName); err != nil {
+		return err
+	}
+
+	aliases, err := e.ListServiceAliases(ctx)
+	if err != nil {
+		return err
+	}
+	for _, other := range aliases {
+		for _, target := range alias.Targets() {
+			if other.ServiceName == target {
+				return fmt.Errorf("%w: 目标服务 %s 本身是别名", ErrInvalidAlias, target)
+			}
+		}
+		for _, target := range other.Targets() {
+			if target == alias.ServiceName {
+				return fmt.Errorf("%w: 服务 %s 已是别名 %s 的目标", ErrInvalidAlias, alias.ServiceName, other.ServiceName)
+			}
+		}
+	}
+
+	alias.UpdatedAt = time.Now()
+	data, err := json.Marshal(alias)
+	if err != nil {
+		return fmt.Errorf("序列化服务别名失败: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, etcdTimeout)
+	defer cancel()
+
+	if _, err := e.conn().Put(ctx, getServiceAliasKey(alias.ServiceName), string(data)); err != nil {
+		e.log(ctx).Error("保存服务别名失败", zap.String("service", alias.ServiceName), zap.Error(err))
+		return fmt.Errorf("保存服务别名失败: %w", err)
+	}
+
+	e.log(ctx).Info("服务别名已更新",
+		zap.String("service", alias.ServiceName),
+		zap.Strings("targets", alias.Targets()))
+	return nil
+}
+
+// DeleteServiceAlias 删除服务别名，之后对该名称的查询按普通服务处理
+func (e *EtcdClient) DeleteServiceAlias(ctx context.Context, serviceName string) error {
+	if e.conn() == nil {
+		return fmt.Errorf("etcd客户端未连接")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, etcdTimeout)
+	defer cancel()
+
+	if _, err := e.conn().Delete(ctx, getServiceAliasKey(serviceName)); err != nil {
+		return fmt.Errorf("删除服务别名失败: %w", err)
+	}
+	return nil
+}
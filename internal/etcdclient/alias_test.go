@@ -0,0 +1,75 @@
+package etcdclient
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServiceAlias_Validate(t *testing.T) {
+	assert.NoError(t, (&ServiceAlias{ServiceName: "payments", Target: "payments-v2"}).Validate())
+	assert.Error(t, (&ServiceAlias{ServiceName: "payments"}).Validate())
+	assert.Error(t, (&ServiceAlias{ServiceName: "a/b", Target: "c"}).Validate())
+	assert.ErrorIs(t, (&ServiceAlias{ServiceName: "payments", Target: "payments"}).Validate(), ErrInvalidAlias)
+
+	split := []SplitTarget{{Service: "payments-v1", Weight: 80}, {Service: "payments-v2", Weight: 20}}
+	assert.NoError(t, (&ServiceAlias{ServiceName: "payments", Split: split}).Validate())
+	assert.ErrorIs(t, (&ServiceAlias{ServiceName: "payments", Target: "payments-v1", Split: split}).Validate(), ErrInvalidAlias)
+	assert.ErrorIs(t, (&ServiceAlias{ServiceName: "payments", Split: []SplitTarget{{Service: "a", Weight: 0}}}).Validate(), ErrInvalidAlias)
+	assert.ErrorIs(t, (&ServiceAlias{ServiceName: "payments", Split: []SplitTarget{{Service: "a", Weight: -1}, {Service: "b", Weight: 2}}}).Validate(), ErrInvalidAlias)
+	assert.ErrorIs(t, (&ServiceAlias{ServiceName: "payments", Split: []SplitTarget{{Service: "a", Weight: 1}, {Service: "a", Weight: 2}}}).Validate(), ErrInvalidAlias)
+	assert.ErrorIs(t, (&ServiceAlias{ServiceName: "payments", Split: []SplitTarget{{Service: "payments", Weight: 1}}}).Validate(), ErrInvalidAlias)
+}
+
+func TestEtcdClient_ServiceAlias(t *testing.T) {
+	// 跳过集成测试，除非明确要求运行
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	client := CreateEtcdClientForTest(t)
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	alias := fmt.Sprintf("alias-%d", time.Now().UnixNano())
+	defer func() {
+		_ = client.DeleteServiceAlias(context.Background(), alias)
+		_ = client.DeleteServiceAlias(context.Background(), alias+"-other")
+	}()
+
+	got, err := client.GetServiceAlias(ctx, alias)
+	require.NoError(t, err)
+	assert.Nil(t, got)
+
+	require.NoError(t, client.PutServiceAlias(ctx, &ServiceAlias{ServiceName: alias, Target: alias + "-v1"}))
+	require.NoError(t, client.PutServiceAlias(ctx, &ServiceAlias{ServiceName: alias, Target: alias + "-v2"}))
+	got, err = client.GetServiceAlias(ctx, alias)
+	require.NoError(t, err)
+	require.NotNil(t, got)
+	assert.Equal(t, alias+"-v2", got.Target)
+
+	// 别名不能指向别名，也不能被设置为其他别名的目标
+	err = client.PutServiceAlias(ctx, &ServiceAlias{ServiceName: alias + "-other", Target: alias})
+	assert.ErrorIs(t, err, ErrInvalidAlias)
+	err = client.PutServiceAlias(ctx, &ServiceAlias{ServiceName: alias + "-v2", Target: alias + "-v3"})
+	assert.ErrorIs(t, err, ErrInvalidAlias)
+
+	aliases, err := client.ListServiceAliases(ctx)
+	require.NoError(t, err)
+	found := false
+	for _, a := range aliases {
+		found = found || a.ServiceName == alias
+	}
+	assert.True(t, found)
+
+	require.NoError(t, client.DeleteServiceAlias(ctx, alias))
+	got, err = client.GetServiceAlias(ctx, alias)
+	require.NoError(t, err)
+	assert.Nil(t, got)
+}
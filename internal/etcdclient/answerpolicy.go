@@ -0,0 +1,192 @@
+package etcdclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.uber.org/zap"
+)
+
+// 服务应答策略在etcd中的键前缀
+const serviceAnswerPolicyPrefix = "/config/answer-policies/"
+
+// 服务A/AAAA应答返回的实例数
+const (
+	AddressAnswersOne = "one" // 每个地址族只返回一个实例的地址
+	AddressAnswersAll = "all" // 返回所有实例的地址
+)
+
+// 服务应答中记录的顺序
+const (
+	AnswerOrderNone   = "none"   // 按实例的存储顺序
+	AnswerOrderRandom = "random" // 每次应答随机排列
+	AnswerOrderSticky = "sticky" // 按客户端地址一致性哈希排列，同一客户端总是得到相同的顺序，实例增减时大部分客户端不受影响
+)
+
+// ServiceAnswerPolicy 服务级别的应答策略，决定服务域名的应答包含哪些实例以及记录的顺序
+type ServiceAnswerPolicy struct {
+	ServiceName     string    `json:"service_name"`     // 服务名称
+	AddressAnswers  string    `json:"address_answers"`  // A/AAAA应答返回的实例数：one或all
+	Order           string    `json:"order"`            // 记录顺序：none、random或sticky，one时决定返回哪个实例
+	IncludeDraining bool      `json:"include_draining"` // 摘流中的实例是否仍出现在应答中
+	UpdatedAt       time.Time `json:"updated_at"`       // 更新时间
+}
+
+// Validate 校验应答策略，未设置的字段使用默认值：每个地址族一个实例、按存储顺序
+func (p *ServiceAnswerPolicy) Validate() error {
+	if p.ServiceName == "" || strings.Contains(p.ServiceName, "/") {
+		return fmt.Errorf("无效的服务名称: %q", p.ServiceName)
+	}
+
+	switch p.AddressAnswers {
+	case "":
+		p.AddressAnswers = AddressAnswersOne
+	case AddressAnswersOne, AddressAnswersAll:
+	default:
+		return fmt.Errorf("不支持的地址应答方式: %s", p.AddressAnswers)
+	}
+
+	switch p.Order {
+	case "":
+		p.Order = AnswerOrderNone
+	case AnswerOrderNone, AnswerOrderRandom, AnswerOrderSticky:
+	default:
+		return fmt.Errorf("不支持的应答顺序: %s", p.Order)
+	}
+	return nil
+}
+
+// ServiceRecordOptions 生成服务DNS记录时的选项
+type ServiceRecordOptions struct {
+	IncludeDraining bool // 摘流中的实例同样生成记录
+	LoadWeights     bool // 按实例上报的负载调整SRV权重，并按调整后的权重选取A/AAAA记录的实例
+	DefaultTTL      int  // 实例都未设置DNS TTL时使用的TTL，为0时使用配置中的服务默认TTL
+}
+
+// answerInstances 返回参与DNS应答的实例，includeDraining为true时包括摘流中的实例，
+// 维护模式和抖动抑制期内的实例总是排除
+func answerInstances(instances []*ServiceInstance, includeDraining bool) []*ServiceInstance {
+	if !includeDraining {
+		return routableInstances(instances)
+	}
+	now := time.Now()
+	result := make([]*ServiceInstance, 0, len(instances))
+	for _, instance := range instances {
+		draining := instance.HealthStatus() == HealthDraining && !instance.Maintenance && !instance.Suppressed(now)
+		if instance.Routable() || draining {
+			result = append(result, instance)
+		}
+	}
+	return result
+}
+
+// getServiceAnswerPolicyKey 生成服务应答策略的etcd键
+func getServiceAnswerPolicyKey(serviceName string) string {
+	return serviceAnswerPolicyPrefix + serviceName
+}
+
+// GetServiceAnswerPolicy 获取服务的应答策略，未设置时返回nil
+func (e *EtcdClient) GetServiceAnswerPolicy(ctx context.Context, serviceName string) (*ServiceAnswerPolicy, error) {
+	if e.conn() == nil {
+		return nil, fmt.Errorf("etcd客户端未连接")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, etcdTimeout)
+	defer cancel()
+
+	resp, err := e.conn().Get(ctx, getServiceAnswerPolicyKey(serviceName))
+	if err != nil {
+		return nil, fmt.Errorf("获取服务应答策略失败: %w", err)
+	}
+	if len(resp.Kvs) == 0 {
+		return nil, nil
+	}
+
+	var policy ServiceAnswerPolicy
+	if err := json.Unmarshal(resp.Kvs[0].Value, &policy); err != nil {
+		return nil, fmt.Errorf("解析服务应答策略失败: %w", err)
+	}
+	return &policy, nil
+}
+
+// ListServiceAnswerPolicies 获取所有服务的应答策略，按服务名称排序
+func (e *EtcdClient) ListServiceAnswerPolicies(ctx context.Context) ([]*ServiceAnswerPolicy, error) {
+	if e.conn() == nil {
+		return nil, fmt.Errorf("etcd客户端未连接")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, etcdTimeout)
+	defer cancel()
+
+	resp, err := e.conn().Get(ctx, serviceAnswerPolicyPrefix, clientv3.WithPrefix())
+	if err != nil {
+		e.log(ctx).Error("获取服务应答策略列表失败", zap.Error(err))
+		return nil, fmt.Errorf("获取服务应答策略列表失败: %w", err)
+	}
+
+	policies := make([]*ServiceAnswerPolicy, 0, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		var policy ServiceAnswerPolicy
+		if err := json.Unmarshal(kv.Value, &policy); err != nil {
+			e.log(ctx).Warn("解析服务应答策略失败", zap.String("key", string(kv.Key)), zap.Error(err))
+			continue
+		}
+		policies = append(policies, &policy)
+	}
+	sort.Slice(policies, func(i, j int) bool {
+		return policies[i].ServiceName < policies[j].ServiceName
+	})
+
+	return policies, nil
+}
+
+// PutServiceAnswerPolicy 设置服务的应答策略
+func (e *EtcdClient) PutServiceAnswerPolicy(ctx context.Context, policy *ServiceAnswerPolicy) error {
+	if e.conn() == nil {
+		return fmt.Errorf("etcd客户端未连接")
+	}
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+
+	policy.UpdatedAt = time.Now()
+	data, err := json.Marshal(policy)
+	if err != nil {
+		return fmt.Errorf("序列化服务应答策略失败: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, etcdTimeout)
+	defer cancel()
+
+	if _, err := e.conn().Put(ctx, getServiceAnswerPolicyKey(policy.ServiceName), string(data)); err != nil {
+		e.log(ctx).Error("保存服务应答策略失败", zap.String("service", policy.ServiceName), zap.Error(err))
+		return fmt.Errorf("保存服务应答策略失败: %w", err)
+	}
+
+	e.log(ctx).Info("服务应答策略已更新",
+		zap.String("service", policy.ServiceName),
+		zap.String("address_answers", policy.AddressAnswers),
+		zap.String("order", policy.Order),
+		zap.Bool("include_draining", policy.IncludeDraining))
+	return nil
+}
+
+// DeleteServiceAnswerPolicy 删除服务的应答策略，之后服务使用默认的应答方式
+func (e *EtcdClient) DeleteServiceAnswerPolicy(ctx context.Context, serviceName string) error {
+	if e.conn() == nil {
+		return fmt.Errorf("etcd客户端未连接")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, etcdTimeout)
+	defer cancel()
+
+	if _, err := e.conn().Delete(ctx, getServiceAnswerPolicyKey(serviceName)); err != nil {
+		return fmt.Errorf("删除服务应答策略失败: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package etcdclient
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServiceAnswerPolicy_Validate(t *testing.T) {
+	policy := &ServiceAnswerPolicy{ServiceName: "payments"}
+	require.NoError(t, policy.Validate())
+	assert.Equal(t, AddressAnswersOne, policy.AddressAnswers)
+	assert.Equal(t, AnswerOrderNone, policy.Order)
+
+	tests := []struct {
+		name   string
+		policy ServiceAnswerPolicy
+	}{
+		{"缺少服务名称", ServiceAnswerPolicy{}},
+		{"无效的服务名称", ServiceAnswerPolicy{ServiceName: "a/b"}},
+		{"不支持的地址应答方式", ServiceAnswerPolicy{ServiceName: "payments", AddressAnswers: "two"}},
+		{"不支持的应答顺序", ServiceAnswerPolicy{ServiceName: "payments", Order: "weighted"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Error(t, tt.policy.Validate())
+		})
+	}
+}
+
+func TestAnswerInstances(t *testing.T) {
+	instances := []*ServiceInstance{
+		{InstanceID: "passing"},
+		{InstanceID: "draining", Health: HealthDraining},
+		{InstanceID: "draining-maintenance", Health: HealthDraining, Maintenance: true},
+		{InstanceID: "critical", Health: HealthCritical},
+	}
+	ids := func(instances []*ServiceInstance) []string {
+		var result []string
+		for _, instance := range instances {
+			result = append(result, instance.InstanceID)
+		}
+		return result
+	}
+
+	assert.Equal(t, []string{"passing"}, ids(answerInstances(instances, false)))
+	assert.Equal(t, []string{"passing", "draining"}, ids(answerInstances(instances, true)))
+}
+
+func TestEtcdClient_ServiceAnswerPolicy(t *testing.T) {
+	// 跳过集成测试，除非明确要求运行
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	client := CreateEtcdClientForTest(t)
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	serviceName := fmt.Sprintf("answer-policy-service-%d", time.Now().UnixNano())
+	defer func() {
+		_ = client.DeleteServiceAnswerPolicy(context.Background(), serviceName)
+		_ = client.DeregisterService(context.Background(), serviceName, "policy-001")
+		_ = client.DeregisterService(context.Background(), serviceName, "policy-002")
+	}()
+
+	policy, err := client.GetServiceAnswerPolicy(ctx, serviceName)
+	require.NoError(t, err)
+	assert.Nil(t, policy)
+
+	require.NoError(t, client.PutServiceAnswerPolicy(ctx, &ServiceAnswerPolicy{ServiceName: serviceName, AddressAnswers: AddressAnswersAll, Order: AnswerOrderSticky, IncludeDraining: true}))
+	policy, err = client.GetServiceAnswerPolicy(ctx, serviceName)
+	require.NoError(t, err)
+	require.NotNil(t, policy)
+	assert.Equal(t, AddressAnswersAll, policy.AddressAnswers)
+	assert.Equal(t, AnswerOrderSticky, policy.Order)
+	assert.True(t, policy.IncludeDraining)
+	assert.False(t, policy.UpdatedAt.IsZero())
+
+	policies, err := client.ListServiceAnswerPolicies(ctx)
+	require.NoError(t, err)
+	found := false
+	for _, p := range policies {
+		found = found || p.ServiceName == serviceName
+	}
+	assert.True(t, found)
+
+	assert.Error(t, client.PutServiceAnswerPolicy(ctx, &ServiceAnswerPolicy{ServiceName: serviceName, Order: "weighted"}))
+
+	require.NoError(t, client.DeleteServiceAnswerPolicy(ctx, serviceName))
+	policy, err = client.GetServiceAnswerPolicy(ctx, serviceName)
+	require.NoError(t, err)
+	assert.Nil(t, policy)
+
+	// 摘流中的实例只在IncludeDraining时生成记录
+	require.NoError(t, client.RegisterService(ctx, &ServiceInstance{ServiceName: serviceName, InstanceID: "policy-001", IPAddress: "10.0.30.1", Port: 8080, TTL: 60}))
+	require.NoError(t, client.RegisterService(ctx, &ServiceInstance{ServiceName: serviceName, InstanceID: "policy-002", IPAddress: "10.0.30.2", Port: 8080, TTL: 60}))
+	_, err = client.SetInstanceHealth(ctx, serviceName, "policy-002", HealthDraining)
+	require.NoError(t, err)
+
+	domain := serviceName + ".default.svc.cluster.local"
+	records, err := client.ServiceToDNSRecords(ctx, domain)
+	require.NoError(t, err)
+	assert.Len(t, srvRecords(records), 1)
+
+	records, err = client.ServiceToDNSRecordsWithOptions(ctx, domain, ServiceRecordOptions{IncludeDraining: true})
+	require.NoError(t, err)
+	assert.Len(t, srvRecords(records), 2)
+}
+
+// srvRecords 返回DNS记录中的SRV记录
+func srvRecords(records map[string]*DNSRecord) []*DNSRecord {
+	var result []*DNSRecord
+	for key, record := range records {
+		if strings.HasPrefix(key, "SRV-") {
+			result = append(result, record)
+		}
+	}
+	return result
+}
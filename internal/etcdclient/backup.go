@@ -0,0 +1,382 @@
+package etcdclient
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.uber.org/zap"
+)
+
+// 备份的数据类别
+const (
+	BackupNamespaces = "namespaces" // 命名空间及配额
+	BackupConfig     = "config"     // 运行时配置：上游DNS、转发和拦截规则、注册策略、维护状态、webhook、命名空间区域、服务别名
+	BackupCatalog    = "catalog"    // 服务目录
+	BackupDNS        = "dns"        // DNS记录、视图记录和定时变更
+	BackupServices   = "services"   // 服务实例
+)
+
+// BackupKinds 所有数据类别，按恢复顺序排列：命名空间在前，服务实例在最后
+var BackupKinds = []string{BackupNamespaces, BackupConfig, BackupCatalog, BackupDNS, BackupServices}
+
+// backupPrefixes 各数据类别在etcd中的键前缀
+// 选举、投递记录和事件总线检查点属于运行状态，不做备份
+var backupPrefixes = map[string]string{
+	BackupNamespaces: namespacePrefix,
+	BackupConfig:     "/config/",
+	BackupCatalog:    serviceCatalogPrefix,
+	BackupDNS:        "/dns/",
+	BackupServices:   "/services/",
+}
+
+// backupFormatVersion 备份归档的格式版本
+const backupFormatVersion = 1
+
+// backupManifestName 归档中描述备份的文件名
+const backupManifestName = "manifest.json"
+
+// defaultRestoreTTL 恢复的服务实例没有TTL时使用的租约时长（秒）
+const defaultRestoreTTL = 30
+
+// ErrInvalidBackup 备份归档无效
+var ErrInvalidBackup = errors.New("无效的备份")
+
+// BackupEntry 备份中的一个键值
+type BackupEntry struct {
+	Key   string `json:"key"`   // etcd键
+	Value string `json:"value"` // 值
+}
+
+// Snapshot 发现数据在同一个etcd修订版本上的一致快照
+type Snapshot struct {
+	Revision  int64                     // 读取时的etcd修订版本
+	CreatedAt time.Time                 // 创建时间
+	Entries   map[string][]*BackupEntry // 按数据类别分组的键值
+}
+
+// backupManifest 归档中的manifest.json
+type backupManifest struct {
+	Version   int            `json:"version"`    // 格式版本
+	Revision  int64          `json:"revision"`   // etcd修订版本
+	CreatedAt time.Time      `json:"created_at"` // 创建时间
+	Counts    map[string]int `json:"counts"`     // 各数据类别的键数量
+}
+
+// ValidBackupKinds 校验数据类别，为空时返回全部类别
+func ValidBackupKinds(kinds []string) ([]string, error) {
+	if len(kinds) == 0 {
+		return BackupKinds, nil
+	}
+	selected := make(map[string]bool, len(kinds))
+	for _, kind := range kinds {
+		if _, ok := backupPrefixes[kind]; !ok {
+			return nil, fmt.Errorf("%w: 未知的数据类别 %s", ErrInvalidBackup, kind)
+		}
+		selected[kind] = true
+	}
+	// 按恢复顺序返回
+	result := make([]string, 0, len(selected))
+	for _, kind := range BackupKinds {
+		if selected[kind] {
+			result = append(result, kind)
+		}
+	}
+	return result, nil
+}
+
+// Snapshot 在同一个修订版本上读取指定类别的发现数据，kinds为空时读取全部
+func (e *EtcdClient) Snapshot(ctx context.Context, kinds []string) (*Snapshot, error) {
+	if e.conn() == nil {
+		return nil, fmt.Errorf("etcd客户端未连接")
+	}
+	kinds, err := ValidBackupKinds(kinds)
+	if err != nil {
+		return nil, err
+	}
+
+	ops := make([]clientv3.Op, len(kinds))
+	for i, kind := range kinds {
+		ops[i] = clientv3.OpGet(backupPrefixes[kind], clientv3.WithPrefix())
+	}
+
+	readCtx, cancel := context.WithTimeout(ctx, etcdTimeout)
+	resp, err := e.conn().Txn(readCtx).Then(ops...).Commit()
+	cancel()
+	if err != nil {
+		e.log(ctx).Error("读取备份数据失败", zap.Error(err))
+		return nil, fmt.Errorf("读取备份数据失败: %w", err)
+	}
+
+	snapshot := &Snapshot{
+		Revision:  resp.Header.Revision,
+		CreatedAt: time.Now(),
+		Entries:   make(map[string][]*BackupEntry, len(kinds)),
+	}
+	for i, kind := range kinds {
+		entries := []*BackupEntry{}
+		for _, kv := range resp.Responses[i].GetResponseRange().Kvs {
+			entries = append(entries, &BackupEntry{Key: string(kv.Key), Value: string(kv.Value)})
+		}
+		snapshot.Entries[kind] = entries
+	}
+	return snapshot, nil
+}
+
+// WriteArchive 把快照写为tar.gz归档：manifest.json和每个数据类别一个 <类别>.json
+func (s *Snapshot) WriteArchive(w io.Writer) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	manifest := &backupManifest{
+		Version:   backupFormatVersion,
+		Revision:  s.Revision,
+		CreatedAt: s.CreatedAt,
+		Counts:    make(map[string]int, len(s.Entries)),
+	}
+	for kind, entries := range s.Entries {
+		manifest.Counts[kind] = len(entries)
+	}
+
+	writeFile := func(name string, v any) error {
+		data, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return fmt.Errorf("序列化 %s 失败: %w", name, err)
+		}
+		header := &tar.Header{Name: name, Mode: 0o600, Size: int64(len(data)), ModTime: s.CreatedAt}
+		if err := tw.WriteHeader(header); err != nil {
+			return fmt.Errorf("写入 %s 失败: %w", name, err)
+		}
+		if _, err := tw.Write(data); err != nil {
+			return fmt.Errorf("写入 %s 失败: %w", name, err)
+		}
+		return nil
+	}
+
+	if err := writeFile(backupManifestName, manifest); err != nil {
+		return err
+	}
+	for _, kind := range BackupKinds {
+		entries, ok := s.Entries[kind]
+		if !ok {
+			continue
+		}
+		if err := writeFile(kind+".json", entries); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("写入归档失败: %w", err)
+	}
+	return gz.Close()
+}
+
+// ReadArchive 读取WriteArchive生成的归档，校验每个键都属于其数据类别
+func ReadArchive(r io.Reader) (*Snapshot, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("%w: 不是gzip格式: %v", ErrInvalidBackup, err)
+	}
+	defer gz.Close()
+
+	var manifest *backupManifest
+	entries := make(map[string][]*BackupEntry)
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: 读取归档失败: %v", ErrInvalidBackup, err)
+		}
+
+		name := header.Name
+		switch {
+		case name == backupManifestName:
+			manifest = new(backupManifest)
+			if err := json.NewDecoder(tr).Decode(manifest); err != nil {
+				return nil, fmt.Errorf("%w: 解析 %s 失败: %v", ErrInvalidBackup, name, err)
+			}
+		case strings.HasSuffix(name, ".json"):
+			kind := strings.TrimSuffix(name, ".json")
+			prefix, ok := backupPrefixes[kind]
+			if !ok {
+				// 忽略未知的文件，便于以后扩展格式
+				continue
+			}
+			var list []*BackupEntry
+			if err := json.NewDecoder(tr).Decode(&list); err != nil {
+				return nil, fmt.Errorf("%w: 解析 %s 失败: %v", ErrInvalidBackup, name, err)
+			}
+			for _, entry := range list {
+				if !strings.HasPrefix(entry.Key, prefix) {
+					return nil, fmt.Errorf("%w: 键 %s 不属于 %s", ErrInvalidBackup, entry.Key, kind)
+				}
+			}
+			entries[kind] = list
+		}
+	}
+
+	if manifest == nil {
+		return nil, fmt.Errorf("%w: 缺少 %s", ErrInvalidBackup, backupManifestName)
+	}
+	if manifest.Version != backupFormatVersion {
+		return nil, fmt.Errorf("%w: 不支持的格式版本 %d", ErrInvalidBackup, manifest.Version)
+	}
+	return &Snapshot{Revision: manifest.Revision, CreatedAt: manifest.CreatedAt, Entries: entries}, nil
+}
+
+// RestoreOptions 恢复选项
+type RestoreOptions struct {
+	Kinds   []string // 恢复的数据类别，为空表示全部
+	Replace bool     // 删除当前存在但备份中没有的键，使这些类别与备份完全一致
+}
+
+// RestoreResult 恢复结果
+type RestoreResult struct {
+	Revision int64          `json:"revision"` // 备份的etcd修订版本
+	Restored map[string]int `json:"restored"` // 各数据类别写入的键数量
+	Deleted  map[string]int `json:"deleted"`  // 各数据类别删除的键数量（仅Replace）
+}
+
+// Restore 把快照写回etcd，按命名空间、配置、DNS记录、服务实例的顺序恢复
+// 服务实例重新创建与其TTL相同的租约，实例随后的心跳会续约，不再存在的实例在TTL后过期
+// 写入按事务上限分批提交，整个恢复不是原子的，失败后可以重新执行
+func (e *EtcdClient) Restore(ctx context.Context, snapshot *Snapshot, opts RestoreOptions) (*RestoreResult, error) {
+	if e.conn() == nil {
+		return nil, fmt.Errorf("etcd客户端未连接")
+	}
+	kinds, err := ValidBackupKinds(opts.Kinds)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &RestoreResult{
+		Revision: snapshot.Revision,
+		Restored: make(map[string]int),
+		Deleted:  make(map[string]int),
+	}
+	for _, kind := range kinds {
+		entries, ok := snapshot.Entries[kind]
+		if !ok {
+			// 备份中没有该类别时跳过，避免Replace清空未备份的数据
+			continue
+		}
+
+		ops, err := e.restoreOps(ctx, kind, entries)
+		if err != nil {
+			return result, err
+		}
+		if err := e.commitOps(ctx, ops); err != nil {
+			return result, fmt.Errorf("恢复 %s 失败: %w", kind, err)
+		}
+		result.Restored[kind] = len(entries)
+
+		if opts.Replace {
+			deleted, err := e.deleteMissing(ctx, kind, entries)
+			result.Deleted[kind] = deleted
+			if err != nil {
+				return result, fmt.Errorf("清理 %s 失败: %w", kind, err)
+			}
+		}
+	}
+
+	e.log(ctx).Info("备份恢复完成",
+		zap.Int64("revision", snapshot.Revision),
+		zap.Any("restored", result.Restored),
+		zap.Any("deleted", result.Deleted))
+	return result, nil
+}
+
+// restoreOps 生成写入操作，服务实例按TTL分组共用租约
+func (e *EtcdClient) restoreOps(ctx context.Context, kind string, entries []*BackupEntry) ([]clientv3.Op, error) {
+	ops := make([]clientv3.Op, 0, len(entries))
+	if kind != BackupServices {
+		for _, entry := range entries {
+			ops = append(ops, clientv3.OpPut(entry.Key, entry.Value))
+		}
+		return ops, nil
+	}
+
+	leases := make(map[int]clientv3.LeaseID)
+	for _, entry := range entries {
+		var instance ServiceInstance
+		if err := json.Unmarshal([]byte(entry.Value), &instance); err != nil {
+			return nil, fmt.Errorf("%w: 解析服务实例 %s 失败: %v", ErrInvalidBackup, entry.Key, err)
+		}
+		ttl := instance.TTL
+		if ttl <= 0 {
+			ttl = defaultRestoreTTL
+		}
+		lease, ok := leases[ttl]
+		if !ok {
+			grantCtx, cancel := context.WithTimeout(ctx, etcdTimeout)
+			resp, err := e.conn().Grant(grantCtx, int64(ttl))
+			cancel()
+			if err != nil {
+				return nil, fmt.Errorf("创建etcd租约失败: %w", err)
+			}
+			lease = resp.ID
+			leases[ttl] = lease
+		}
+		ops = append(ops, clientv3.OpPut(entry.Key, entry.Value, clientv3.WithLease(lease)))
+	}
+	return ops, nil
+}
+
+// commitOps 按事务上限分批提交操作
+func (e *EtcdClient) commitOps(ctx context.Context, ops []clientv3.Op) error {
+	batch := e.maxTxnOps()
+	for start := 0; start < len(ops); start += batch {
+		end := min(start+batch, len(ops))
+		txnCtx, cancel := context.WithTimeout(ctx, etcdTimeout)
+		_, err := e.conn().Txn(txnCtx).Then(ops[start:end]...).Commit()
+		cancel()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteMissing 删除类别中当前存在但备份中没有的键
+func (e *EtcdClient) deleteMissing(ctx context.Context, kind string, entries []*BackupEntry) (int, error) {
+	keep := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		keep[entry.Key] = true
+	}
+
+	getCtx, cancel := context.WithTimeout(ctx, etcdTimeout)
+	resp, err := e.conn().Get(getCtx, backupPrefixes[kind], clientv3.WithPrefix(), clientv3.WithKeysOnly())
+	cancel()
+	if err != nil {
+		return 0, err
+	}
+
+	var missing []string
+	for _, kv := range resp.Kvs {
+		if !keep[string(kv.Key)] {
+			missing = append(missing, string(kv.Key))
+		}
+	}
+	sort.Strings(missing)
+
+	ops := make([]clientv3.Op, len(missing))
+	for i, key := range missing {
+		ops[i] = clientv3.OpDelete(key)
+	}
+	if err := e.commitOps(ctx, ops); err != nil {
+		return 0, err
+	}
+	return len(missing), nil
+}
@@ -0,0 +1,174 @@
+package etcdclient
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidBackupKinds(t *testing.T) {
+	kinds, err := ValidBackupKinds(nil)
+	require.NoError(t, err)
+	assert.Equal(t, BackupKinds, kinds)
+
+	// 按恢复顺序返回并去重
+	kinds, err = ValidBackupKinds([]string{"services", "namespaces", "services"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{BackupNamespaces, BackupServices}, kinds)
+
+	_, err = ValidBackupKinds([]string{"election"})
+	assert.ErrorIs(t, err, ErrInvalidBackup)
+}
+
+func TestSnapshot_Archive(t *testing.T) {
+	snapshot := &Snapshot{
+		Revision:  42,
+		CreatedAt: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC),
+		Entries: map[string][]*BackupEntry{
+			BackupDNS:      {{Key: "/dns/records/a.example.com/A", Value: `{"type":"A","value":"10.0.0.1"}`}},
+			BackupServices: {},
+		},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, snapshot.WriteArchive(&buf))
+
+	got, err := ReadArchive(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	assert.Equal(t, int64(42), got.Revision)
+	assert.True(t, snapshot.CreatedAt.Equal(got.CreatedAt))
+	assert.Equal(t, snapshot.Entries[BackupDNS], got.Entries[BackupDNS])
+	assert.Empty(t, got.Entries[BackupServices])
+	_, ok := got.Entries[BackupConfig]
+	assert.False(t, ok, "未备份的类别不应出现")
+}
+
+// writeTestArchive 生成包含指定文件的tar.gz
+func writeTestArchive(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, content := range files {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0o600, Size: int64(len(content))}))
+		_, err := tw.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+	return buf.Bytes()
+}
+
+func TestReadArchive_Invalid(t *testing.T) {
+	manifest := `{"version":1,"revision":1}`
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"不是gzip", []byte("not an archive")},
+		{"缺少manifest", writeTestArchive(t, map[string]string{"dns.json": "[]"})},
+		{"未知的格式版本", writeTestArchive(t, map[string]string{"manifest.json": `{"version":99}`})},
+		{"键不属于类别", writeTestArchive(t, map[string]string{
+			"manifest.json": manifest,
+			"dns.json":      `[{"key":"/election/x","value":"1"}]`,
+		})},
+		{"无效的JSON", writeTestArchive(t, map[string]string{"manifest.json": manifest, "dns.json": "{"})},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ReadArchive(bytes.NewReader(tt.data))
+			assert.ErrorIs(t, err, ErrInvalidBackup)
+		})
+	}
+
+	// 未知的文件被忽略
+	snapshot, err := ReadArchive(bytes.NewReader(writeTestArchive(t, map[string]string{
+		"manifest.json": manifest,
+		"README":        "说明",
+		"future.json":   "{}",
+	})))
+	require.NoError(t, err)
+	assert.Empty(t, snapshot.Entries)
+}
+
+func TestEtcdClient_SnapshotRestore(t *testing.T) {
+	// 跳过集成测试，除非明确要求运行
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	client := CreateEtcdClientForTest(t)
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	serviceName := fmt.Sprintf("backup-service-%d", time.Now().UnixNano())
+	domain := serviceName + ".example.com"
+	defer func() {
+		_ = client.DeregisterService(context.Background(), serviceName, "instance-001")
+		_ = client.DeleteDNSRecord(context.Background(), domain, "A", "")
+	}()
+
+	require.NoError(t, client.PutDNSRecord(ctx, domain, &DNSRecord{Type: "A", Value: "10.0.0.1", TTL: 60}))
+	require.NoError(t, client.RegisterService(ctx, &ServiceInstance{ServiceName: serviceName, InstanceID: "instance-001", IPAddress: "10.0.0.2", Port: 8080, TTL: 30}))
+
+	snapshot, err := client.Snapshot(ctx, []string{BackupDNS, BackupServices})
+	require.NoError(t, err)
+	assert.Greater(t, snapshot.Revision, int64(0))
+	_, ok := snapshot.Entries[BackupNamespaces]
+	assert.False(t, ok)
+
+	// 只保留本测试的键，避免恢复时覆盖其他测试的数据
+	recordKey := "/dns/records/" + domain + "/A"
+	instanceKey := getServiceInstanceKey(serviceName, "instance-001")
+	own := &Snapshot{Revision: snapshot.Revision, Entries: map[string][]*BackupEntry{}}
+	for kind, entries := range snapshot.Entries {
+		for _, entry := range entries {
+			if entry.Key == recordKey || entry.Key == instanceKey {
+				own.Entries[kind] = append(own.Entries[kind], entry)
+			}
+		}
+	}
+	require.Len(t, own.Entries[BackupDNS], 1)
+	require.Len(t, own.Entries[BackupServices], 1)
+
+	require.NoError(t, client.DeleteDNSRecord(ctx, domain, "A", ""))
+	require.NoError(t, client.DeregisterService(ctx, serviceName, "instance-001"))
+
+	// 只恢复DNS记录
+	result, err := client.Restore(ctx, own, RestoreOptions{Kinds: []string{BackupDNS}})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{BackupDNS: 1}, result.Restored)
+	entry, err := client.GetDNSRecordEntry(ctx, domain, "A", "")
+	require.NoError(t, err)
+	assert.Equal(t, "10.0.0.1", entry.Value)
+	instances, err := client.GetServiceInstances(ctx, serviceName)
+	require.NoError(t, err)
+	assert.Empty(t, instances)
+
+	// 恢复服务实例，重新绑定租约后可以继续续约
+	result, err = client.Restore(ctx, own, RestoreOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Restored[BackupServices])
+	instances, err = client.GetServiceInstances(ctx, serviceName)
+	require.NoError(t, err)
+	require.Len(t, instances, 1)
+	assert.Equal(t, "10.0.0.2", instances[0].IPAddress)
+	require.NoError(t, client.RefreshServiceLease(ctx, serviceName, "instance-001", 0))
+
+	report, err := client.ReconcileDrift(ctx, DriftOptions{DryRun: true})
+	require.NoError(t, err)
+	for _, finding := range report.Findings {
+		assert.NotEqual(t, instanceKey, finding.Key, "恢复的实例应绑定租约: %s", finding.Detail)
+	}
+}
@@ -0,0 +1,357 @@
+package etcdclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.uber.org/zap"
+)
+
+// 批量操作类型
+const (
+	DNSRecordOpPut    = "put"
+	DNSRecordOpDelete = "delete"
+)
+
+// 批量操作中单项的结果状态
+const (
+	BatchStatusApplied  = "applied"   // 已应用
+	BatchStatusInvalid  = "invalid"   // 操作本身无效
+	BatchStatusConflict = "conflict"  // 版本不一致
+	BatchStatusNotFound = "not_found" // 要删除的记录不存在
+	BatchStatusQuota    = "quota"     // 超出命名空间配额
+	BatchStatusOwned    = "owned"     // 记录由其他所有者维护
+	BatchStatusAborted  = "aborted"   // 本项没有问题，但因其他项失败而未应用
+)
+
+// defaultMaxTxnOps etcd服务端默认的单事务最大操作数
+const defaultMaxTxnOps = 128
+
+var (
+	// ErrInvalidBatch 批量操作中存在无效的项
+	ErrInvalidBatch = errors.New("批量操作无效")
+
+	// ErrBatchTooLarge 批量操作数超过单个事务的上限
+	ErrBatchTooLarge = errors.New("批量操作数超过事务上限")
+)
+
+// DNSRecordOperation 批量操作中的一项
+type DNSRecordOperation struct {
+	Op       string     `json:"op"`                 // 操作类型：put 或 delete
+	Domain   string     `json:"domain"`             // 域名
+	Record   *DNSRecord `json:"record,omitempty"`   // put时写入的记录
+	Type     string     `json:"type,omitempty"`     // delete时的记录类型
+	View     string     `json:"view,omitempty"`     // delete时的视图
+	Revision *int64     `json:"revision,omitempty"` // 可选的期望版本，0表示要求记录不存在
+	Force    bool       `json:"force,omitempty"`    // 忽略记录的所有者，强制覆盖或删除自动维护的记录
+}
+
+// key 返回操作对应的etcd键
+func (op *DNSRecordOperation) key() string {
+	if op.Op == DNSRecordOpPut && op.Record != nil {
+		return recordKey(op.Domain, op.Record)
+	}
+	return recordKey(op.Domain, &DNSRecord{Type: op.Type, View: op.View})
+}
+
+// DNSRecordOpResult 批量操作中单项的结果
+type DNSRecordOpResult struct {
+	Index    int    `json:"index"`              // 操作在请求中的序号
+	Op       string `json:"op"`                 // 操作类型
+	Domain   string `json:"domain"`             // 域名
+	Type     string `json:"type"`               // 记录类型
+	View     string `json:"view,omitempty"`     // 视图
+	Status   string `json:"status"`             // 结果状态
+	Revision int64  `json:"revision,omitempty"` // 应用后的版本号
+	Error    string `json:"error,omitempty"`    // 失败原因
+}
+
+// maxTxnOps 返回单个事务允许的最大操作数
+func (e *EtcdClient) maxTxnOps() int {
+	if e.cfg != nil && e.cfg.Etcd.MaxTxnOps > 0 {
+		return e.cfg.Etcd.MaxTxnOps
+	}
+	return defaultMaxTxnOps
+}
+
+// ApplyDNSRecordBatch 在单个etcd事务中原子地应用一组DNS记录操作
+// 任一项失败时所有操作都不生效，返回的结果列表标明每一项的状态，error包装第一个失败项的原因
+func (e *EtcdClient) ApplyDNSRecordBatch(ctx context.Context, ops []*DNSRecordOperation) ([]*DNSRecordOpResult, error) {
+	return e.applyDNSRecordBatch(ctx, ops, nil, nil)
+}
+
+// applyDNSRecordBatch 应用批量操作，guards和extraOps会加入同一个事务，
+// 用于把批量操作与其他键的校验和修改绑定在一起（如删除已执行的定时变更）
+func (e *EtcdClient) applyDNSRecordBatch(ctx context.Context, ops []*DNSRecordOperation, guards []clientv3.Cmp, extraOps []clientv3.Op) ([]*DNSRecordOpResult, error) {
+	if e.conn() == nil {
+		return nil, fmt.Errorf("etcd客户端未连接")
+	}
+
+	results := make([]*DNSRecordOpResult, len(ops))
+	keys := make([]string, len(ops))
+	seen := make(map[string]int, len(ops))
+	var firstErr error
+	fail := func(i int, status string, err error) {
+		results[i].Status = status
+		results[i].Error = err.Error()
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	// 校验每一项操作，同一个键在一个事务中只能出现一次
+	for i, op := range ops {
+		results[i] = &DNSRecordOpResult{Index: i, Op: op.Op, Domain: op.Domain, Type: op.Type, View: op.View}
+		if op.Op == DNSRecordOpPut && op.Record != nil {
+			results[i].Type = op.Record.Type
+			results[i].View = op.Record.View
+		}
+
+		switch {
+		case op.Op != DNSRecordOpPut && op.Op != DNSRecordOpDelete:
+			fail(i, BatchStatusInvalid, fmt.Errorf("%w: 未知的操作类型 %q", ErrInvalidBatch, op.Op))
+			continue
+		case op.Domain == "":
+			fail(i, BatchStatusInvalid, fmt.Errorf("%w: 域名不能为空", ErrInvalidBatch))
+			continue
+		case op.Op == DNSRecordOpPut && op.Record == nil:
+			fail(i, BatchStatusInvalid, fmt.Errorf("%w: put操作缺少record", ErrInvalidBatch))
+			continue
+		case op.Op == DNSRecordOpDelete && op.Type == "":
+			fail(i, BatchStatusInvalid, fmt.Errorf("%w: delete操作缺少type", ErrInvalidBatch))
+			continue
+		}
+
+		keys[i] = op.key()
+		if j, ok := seen[keys[i]]; ok {
+			fail(i, BatchStatusInvalid, fmt.Errorf("%w: 与第%d项操作同一条记录", ErrInvalidBatch, j))
+			continue
+		}
+		seen[keys[i]] = i
+	}
+	if firstErr != nil {
+		markAborted(results)
+		return results, firstErr
+	}
+
+	if len(ops)+len(extraOps) > e.maxTxnOps() {
+		err := fmt.Errorf("%w: %d > %d", ErrBatchTooLarge, len(ops)+len(extraOps), e.maxTxnOps())
+		markAborted(results)
+		return results, err
+	}
+
+	// 校验命名空间配额
+	if err := e.checkBatchDNSRecordQuota(ctx, ops, keys, results); err != nil {
+		markAborted(results)
+		return results, err
+	}
+
+	// 校验所有权，读取时的状态在提交时必须仍然成立
+	ownerGuards, err := e.checkBatchOwnership(ctx, ops, keys, results)
+	if err != nil {
+		markAborted(results)
+		return results, err
+	}
+
+	// 构造事务：比较条件保证版本一致、删除的记录存在
+	cmps := append(append([]clientv3.Cmp{}, guards...), ownerGuards...)
+	thenOps := append([]clientv3.Op{}, extraOps...)
+	var elseOps []clientv3.Op
+	for i, op := range ops {
+		key := keys[i]
+		switch {
+		case op.Revision != nil && *op.Revision == 0:
+			cmps = append(cmps, clientv3.Compare(clientv3.CreateRevision(key), "=", 0))
+		case op.Revision != nil:
+			cmps = append(cmps, clientv3.Compare(clientv3.ModRevision(key), "=", *op.Revision))
+		case op.Op == DNSRecordOpDelete:
+			cmps = append(cmps, clientv3.Compare(clientv3.CreateRevision(key), ">", 0))
+		}
+		if op.Revision != nil || op.Op == DNSRecordOpDelete {
+			elseOps = append(elseOps, clientv3.OpGet(key, clientv3.WithKeysOnly()))
+		}
+
+		if op.Op == DNSRecordOpDelete {
+			thenOps = append(thenOps, clientv3.OpDelete(key))
+			continue
+		}
+		data, err := json.Marshal(op.Record)
+		if err != nil {
+			return nil, fmt.Errorf("序列化DNS记录失败: %w", err)
+		}
+		thenOps = append(thenOps, clientv3.OpPut(key, string(data)))
+	}
+
+	txnCtx, cancel := context.WithTimeout(ctx, etcdTimeout)
+	defer cancel()
+
+	resp, err := e.conn().Txn(txnCtx).If(cmps...).Then(thenOps...).Else(elseOps...).Commit()
+	if err != nil {
+		e.log(ctx).Error("批量保存DNS记录失败", zap.Int("operations", len(ops)), zap.Error(err))
+		return nil, fmt.Errorf("批量保存DNS记录失败: %w", err)
+	}
+
+	if resp.Succeeded {
+		for _, result := range results {
+			result.Status = BatchStatusApplied
+			if result.Op == DNSRecordOpPut {
+				result.Revision = resp.Header.Revision
+			}
+		}
+		e.log(ctx).Info("批量DNS记录操作成功",
+			zap.Int("operations", len(ops)),
+			zap.Int64("revision", resp.Header.Revision))
+		return results, nil
+	}
+
+	// 事务未通过比较，根据else分支读取的当前版本找出失败的项
+	current := make(map[string]int64, len(elseOps))
+	for _, r := range resp.Responses {
+		for _, kv := range r.GetResponseRange().Kvs {
+			current[string(kv.Key)] = kv.ModRevision
+		}
+	}
+	for i, op := range ops {
+		rev, exists := current[keys[i]]
+		switch {
+		case op.Revision != nil && *op.Revision == 0 && exists:
+			fail(i, BatchStatusConflict, fmt.Errorf("%w: %s 已存在", ErrRevisionConflict, keys[i]))
+		case op.Revision != nil && *op.Revision > 0 && rev != *op.Revision:
+			fail(i, BatchStatusConflict, fmt.Errorf("%w: %s 当前版本为%d", ErrRevisionConflict, keys[i], rev))
+		case op.Revision == nil && op.Op == DNSRecordOpDelete && !exists:
+			fail(i, BatchStatusNotFound, fmt.Errorf("%w: %s", ErrDNSRecordNotFound, keys[i]))
+		}
+	}
+	if firstErr == nil {
+		// 比较失败后记录又被修改，无法确定具体是哪一项
+		firstErr = fmt.Errorf("%w: 记录在提交期间被修改", ErrRevisionConflict)
+	}
+	markAborted(results)
+
+	e.log(ctx).Warn("批量DNS记录操作未应用", zap.Int("operations", len(ops)), zap.Error(firstErr))
+	return results, firstErr
+}
+
+// markAborted 把尚未标记状态的项标记为aborted
+func markAborted(results []*DNSRecordOpResult) {
+	for _, result := range results {
+		if result.Status == "" {
+			result.Status = BatchStatusAborted
+		}
+	}
+}
+
+// checkBatchOwnership 在一次读取中获取非强制操作涉及的记录并校验所有权，
+// 返回保证读取到的记录在提交前没有变化的比较条件
+func (e *EtcdClient) checkBatchOwnership(ctx context.Context, ops []*DNSRecordOperation, keys []string, results []*DNSRecordOpResult) ([]clientv3.Cmp, error) {
+	var indexes []int
+	var gets []clientv3.Op
+	for i, op := range ops {
+		if !op.Force {
+			indexes = append(indexes, i)
+			gets = append(gets, clientv3.OpGet(keys[i]))
+		}
+	}
+	if len(gets) == 0 {
+		return nil, nil
+	}
+
+	getCtx, cancel := context.WithTimeout(ctx, etcdTimeout)
+	resp, err := e.conn().Txn(getCtx).Then(gets...).Commit()
+	cancel()
+	if err != nil {
+		return nil, fmt.Errorf("获取DNS记录失败: %w", err)
+	}
+
+	var firstErr error
+	cmps := make([]clientv3.Cmp, 0, len(indexes))
+	for n, i := range indexes {
+		var existing *DNSRecord
+		var modRevision int64
+		if kvs := resp.Responses[n].GetResponseRange().Kvs; len(kvs) > 0 {
+			existing = &DNSRecord{}
+			if err := json.Unmarshal(kvs[0].Value, existing); err != nil {
+				// 无法解析的记录视为手动记录
+				existing = &DNSRecord{}
+			}
+			modRevision = kvs[0].ModRevision
+		}
+
+		var record *DNSRecord
+		if ops[i].Op == DNSRecordOpPut {
+			record = ops[i].Record
+		}
+		if err := checkRecordOwner(keys[i], existing, record); err != nil {
+			results[i].Status = BatchStatusOwned
+			results[i].Error = err.Error()
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		cmps = append(cmps, ownerGuard(keys[i], modRevision))
+	}
+	return cmps, firstErr
+}
+
+// checkBatchDNSRecordQuota 按命名空间汇总批量操作后的DNS记录数并校验配额
+// 删除释放的配额可以被同一批次中新建的记录使用
+func (e *EtcdClient) checkBatchDNSRecordQuota(ctx context.Context, ops []*DNSRecordOperation, keys []string, results []*DNSRecordOpResult) error {
+	// 新建记录按命名空间分组
+	creates := make(map[string][]int)
+	for i, op := range ops {
+		if op.Op == DNSRecordOpPut {
+			namespace := NamespaceOf(op.Record.Namespace)
+			creates[namespace] = append(creates[namespace], i)
+		}
+	}
+
+	var firstErr error
+	for namespace, indexes := range creates {
+		quota, err := e.GetNamespaceQuota(ctx, namespace)
+		if err != nil {
+			return err
+		}
+		if quota.MaxDNSRecords == 0 {
+			continue
+		}
+
+		existing, err := e.namespaceDNSRecordKeys(ctx, namespace)
+		if err != nil {
+			return err
+		}
+		owned := make(map[string]bool, len(existing))
+		for _, key := range existing {
+			owned[key] = true
+		}
+
+		// 删除的记录先释放配额
+		count := len(existing)
+		for i, op := range ops {
+			if op.Op == DNSRecordOpDelete && owned[keys[i]] {
+				count--
+			}
+		}
+
+		for _, i := range indexes {
+			if owned[keys[i]] {
+				// 更新已有记录不占用新配额
+				continue
+			}
+			if err := checkLimit(namespace, "DNS记录数", count, quota.MaxDNSRecords); err != nil {
+				results[i].Status = BatchStatusQuota
+				results[i].Error = err.Error()
+				if firstErr == nil {
+					firstErr = err
+				}
+				continue
+			}
+			count++
+		}
+	}
+
+	return firstErr
+}
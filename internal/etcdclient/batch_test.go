@@ -0,0 +1,126 @@
+package etcdclient
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEtcdClient_ApplyDNSRecordBatch(t *testing.T) {
+	// 跳过集成测试，除非明确要求运行
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	client := CreateEtcdClientForTest(t)
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	prefix := fmt.Sprintf("batch-%d", time.Now().UnixNano())
+	a, b, c := prefix+"-a.example.com", prefix+"-b.example.com", prefix+"-c.example.com"
+	defer func() {
+		for _, domain := range []string{a, b, c} {
+			_ = client.DeleteDNSRecord(context.Background(), domain, "A", "")
+		}
+	}()
+
+	rev, err := client.PutDNSRecordAtRevision(ctx, a, &DNSRecord{Type: "A", Value: "10.0.0.1"}, AnyRevision)
+	require.NoError(t, err)
+
+	// 一个批次中同时创建、更新和删除
+	results, err := client.ApplyDNSRecordBatch(ctx, []*DNSRecordOperation{
+		{Op: DNSRecordOpPut, Domain: b, Record: &DNSRecord{Type: "A", Value: "10.0.0.2"}},
+		{Op: DNSRecordOpPut, Domain: c, Record: &DNSRecord{Type: "A", Value: "10.0.0.3"}, Revision: new(int64)},
+		{Op: DNSRecordOpDelete, Domain: a, Type: "A", Revision: &rev},
+	})
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+	for _, result := range results {
+		assert.Equal(t, BatchStatusApplied, result.Status)
+	}
+	assert.Greater(t, results[0].Revision, rev)
+
+	_, err = client.GetDNSRecordEntry(ctx, a, "A", "")
+	assert.ErrorIs(t, err, ErrDNSRecordNotFound)
+
+	// 任一项失败时整个批次都不生效
+	results, err = client.ApplyDNSRecordBatch(ctx, []*DNSRecordOperation{
+		{Op: DNSRecordOpPut, Domain: b, Record: &DNSRecord{Type: "A", Value: "10.0.0.20"}},
+		{Op: DNSRecordOpDelete, Domain: a, Type: "A"},
+		{Op: DNSRecordOpPut, Domain: c, Record: &DNSRecord{Type: "A", Value: "10.0.0.30"}, Revision: &rev},
+	})
+	assert.ErrorIs(t, err, ErrDNSRecordNotFound)
+	require.Len(t, results, 3)
+	assert.Equal(t, BatchStatusAborted, results[0].Status)
+	assert.Equal(t, BatchStatusNotFound, results[1].Status)
+	assert.Equal(t, BatchStatusConflict, results[2].Status)
+
+	entry, err := client.GetDNSRecordEntry(ctx, b, "A", "")
+	require.NoError(t, err)
+	assert.Equal(t, "10.0.0.2", entry.Value)
+
+	// 同一条记录不能在一个批次中出现两次
+	results, err = client.ApplyDNSRecordBatch(ctx, []*DNSRecordOperation{
+		{Op: DNSRecordOpPut, Domain: b, Record: &DNSRecord{Type: "A", Value: "10.0.0.21"}},
+		{Op: DNSRecordOpDelete, Domain: b, Type: "A"},
+		{Op: "patch", Domain: c},
+	})
+	assert.ErrorIs(t, err, ErrInvalidBatch)
+	assert.Equal(t, BatchStatusAborted, results[0].Status)
+	assert.Equal(t, BatchStatusInvalid, results[1].Status)
+	assert.Equal(t, BatchStatusInvalid, results[2].Status)
+}
+
+func TestEtcdClient_ApplyDNSRecordBatch_Limits(t *testing.T) {
+	// 跳过集成测试，除非明确要求运行
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	cfg := createTestConfig(t)
+	cfg.Etcd.MaxTxnOps = 2
+	client := NewEtcdClient(cfg, createTestLogger(t))
+	require.NoError(t, client.Connect())
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	namespace := fmt.Sprintf("batch-ns-%d", time.Now().UnixNano())
+	require.NoError(t, client.CreateNamespace(ctx, &Namespace{Name: namespace}))
+	defer func() { _ = client.DeleteNamespace(context.Background(), namespace, true) }()
+	require.NoError(t, client.PutNamespaceQuota(ctx, namespace, &NamespaceQuota{MaxDNSRecords: 1}))
+
+	put := func(name string) *DNSRecordOperation {
+		return &DNSRecordOperation{
+			Op:     DNSRecordOpPut,
+			Domain: name + "." + namespace + ".example.com",
+			Record: &DNSRecord{Type: "A", Value: "10.0.0.1", Namespace: namespace},
+		}
+	}
+
+	// 超过事务上限
+	_, err := client.ApplyDNSRecordBatch(ctx, []*DNSRecordOperation{put("a"), put("b"), put("c")})
+	assert.ErrorIs(t, err, ErrBatchTooLarge)
+
+	// 批次内新建的记录合计超出配额
+	results, err := client.ApplyDNSRecordBatch(ctx, []*DNSRecordOperation{put("a"), put("b")})
+	assert.ErrorIs(t, err, ErrQuotaExceeded)
+	assert.Equal(t, BatchStatusAborted, results[0].Status)
+	assert.Equal(t, BatchStatusQuota, results[1].Status)
+
+	// 同一批次中删除释放的配额可以被新建记录使用
+	_, err = client.ApplyDNSRecordBatch(ctx, []*DNSRecordOperation{put("a")})
+	require.NoError(t, err)
+	_, err = client.ApplyDNSRecordBatch(ctx, []*DNSRecordOperation{
+		{Op: DNSRecordOpDelete, Domain: "a." + namespace + ".example.com", Type: "A"},
+		put("b"),
+	})
+	require.NoError(t, err)
+}
@@ -0,0 +1,155 @@
+package etcdclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"regexp"
+
+	"github.com/google/uuid"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.uber.org/zap"
+)
+
+// 拦截规则在etcd中的键前缀
+const blockRulePrefix = "/config/blocklist/"
+
+// 拦截规则的匹配方式
+const (
+	BlockMatchExact  = "exact"  // 完全匹配域名
+	BlockMatchSuffix = "suffix" // 匹配域名及其所有子域名
+	BlockMatchRegex  = "regex"  // 正则表达式匹配
+)
+
+// 拦截规则命中后的动作
+const (
+	BlockActionNXDomain = "nxdomain" // 返回NXDOMAIN
+	BlockActionSinkhole = "sinkhole" // 返回黑洞IP
+)
+
+// BlockRule 表示一条DNS响应策略（拦截）规则
+type BlockRule struct {
+	ID         string `json:"id"`                    // 规则ID
+	Match      string `json:"match"`                 // 匹配方式 (exact, suffix, regex)
+	Pattern    string `json:"pattern"`               // 匹配的域名、后缀或正则表达式
+	Action     string `json:"action"`                // 命中动作 (nxdomain, sinkhole)
+	SinkholeIP string `json:"sinkhole_ip,omitempty"` // sinkhole动作返回的IP
+	Comment    string `json:"comment,omitempty"`     // 备注，例如威胁情报来源
+}
+
+// Validate 校验拦截规则
+func (r *BlockRule) Validate() error {
+	if r.Pattern == "" {
+		return fmt.Errorf("拦截规则的匹配模式不能为空")
+	}
+
+	switch r.Match {
+	case BlockMatchExact, BlockMatchSuffix:
+	case BlockMatchRegex:
+		if _, err := regexp.Compile(r.Pattern); err != nil {
+			return fmt.Errorf("无效的正则表达式: %w", err)
+		}
+	default:
+		return fmt.Errorf("不支持的匹配方式: %s", r.Match)
+	}
+
+	switch r.Action {
+	case BlockActionNXDomain:
+	case BlockActionSinkhole:
+		if net.ParseIP(r.SinkholeIP) == nil {
+			return fmt.Errorf("无效的黑洞IP: %s", r.SinkholeIP)
+		}
+	default:
+		return fmt.Errorf("不支持的拦截动作: %s", r.Action)
+	}
+
+	return nil
+}
+
+// ListBlockRules 获取所有拦截规则
+func (e *EtcdClient) ListBlockRules(ctx context.Context) ([]*BlockRule, error) {
+	if e.conn() == nil {
+		return nil, fmt.Errorf("etcd客户端未连接")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, etcdTimeout)
+	defer cancel()
+
+	resp, err := e.conn().Get(ctx, blockRulePrefix, clientv3.WithPrefix())
+	if err != nil {
+		e.log(ctx).Error("获取拦截规则失败", zap.Error(err))
+		return nil, fmt.Errorf("获取拦截规则失败: %w", err)
+	}
+
+	rules := make([]*BlockRule, 0, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		var rule BlockRule
+		if err := json.Unmarshal(kv.Value, &rule); err != nil {
+			e.log(ctx).Warn("解析拦截规则失败",
+				zap.String("key", string(kv.Key)),
+				zap.Error(err))
+			continue
+		}
+		rules = append(rules, &rule)
+	}
+
+	return rules, nil
+}
+
+// PutBlockRule 保存拦截规则，未指定ID时自动生成
+func (e *EtcdClient) PutBlockRule(ctx context.Context, rule *BlockRule) error {
+	if e.conn() == nil {
+		return fmt.Errorf("etcd客户端未连接")
+	}
+
+	if err := rule.Validate(); err != nil {
+		return err
+	}
+	if rule.ID == "" {
+		rule.ID = uuid.New().String()
+	}
+
+	data, err := json.Marshal(rule)
+	if err != nil {
+		e.log(ctx).Error("序列化拦截规则失败", zap.Error(err))
+		return fmt.Errorf("序列化拦截规则失败: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, etcdTimeout)
+	defer cancel()
+
+	if _, err := e.conn().Put(ctx, blockRulePrefix+rule.ID, string(data)); err != nil {
+		e.log(ctx).Error("保存拦截规则失败", zap.String("id", rule.ID), zap.Error(err))
+		return fmt.Errorf("保存拦截规则失败: %w", err)
+	}
+
+	e.log(ctx).Info("拦截规则保存成功",
+		zap.String("id", rule.ID),
+		zap.String("match", rule.Match),
+		zap.String("pattern", rule.Pattern))
+	return nil
+}
+
+// DeleteBlockRule 删除拦截规则
+func (e *EtcdClient) DeleteBlockRule(ctx context.Context, id string) error {
+	if e.conn() == nil {
+		return fmt.Errorf("etcd客户端未连接")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, etcdTimeout)
+	defer cancel()
+
+	resp, err := e.conn().Delete(ctx, blockRulePrefix+id)
+	if err != nil {
+		e.log(ctx).Error("删除拦截规则失败", zap.String("id", id), zap.Error(err))
+		return fmt.Errorf("删除拦截规则失败: %w", err)
+	}
+
+	if resp.Deleted == 0 {
+		return fmt.Errorf("拦截规则不存在: %s", id)
+	}
+
+	e.log(ctx).Info("拦截规则删除成功", zap.String("id", id))
+	return nil
+}
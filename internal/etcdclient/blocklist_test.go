@@ -0,0 +1,49 @@
+package etcdclient
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBlockRule_Validate(t *testing.T) {
+	assert.NoError(t, (&BlockRule{Match: BlockMatchSuffix, Pattern: "bad.test", Action: BlockActionNXDomain}).Validate())
+	assert.NoError(t, (&BlockRule{Match: BlockMatchExact, Pattern: "bad.test", Action: BlockActionSinkhole, SinkholeIP: "::"}).Validate())
+	assert.Error(t, (&BlockRule{Match: BlockMatchRegex, Pattern: "(", Action: BlockActionNXDomain}).Validate(), "无效正则应校验失败")
+	assert.Error(t, (&BlockRule{Match: BlockMatchExact, Pattern: "bad.test", Action: BlockActionSinkhole}).Validate(), "sinkhole缺少IP应校验失败")
+	assert.Error(t, (&BlockRule{Match: "glob", Pattern: "bad.test", Action: BlockActionNXDomain}).Validate())
+}
+
+func TestEtcdClient_BlockRules(t *testing.T) {
+	// 跳过集成测试，除非明确要求运行
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	// 创建etcd客户端并连接
+	client := CreateEtcdClientForTest(t)
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	rule := &BlockRule{Match: BlockMatchSuffix, Pattern: "blocked.test", Action: BlockActionNXDomain}
+	err := client.PutBlockRule(ctx, rule)
+	require.NoError(t, err, "保存拦截规则应该成功")
+	require.NotEmpty(t, rule.ID, "应自动生成规则ID")
+	defer func() { _ = client.DeleteBlockRule(context.Background(), rule.ID) }()
+
+	rules, err := client.ListBlockRules(ctx)
+	require.NoError(t, err)
+	var found bool
+	for _, r := range rules {
+		if r.ID == rule.ID {
+			found = true
+			assert.Equal(t, "blocked.test", r.Pattern)
+		}
+	}
+	assert.True(t, found, "应该找到保存的拦截规则")
+}
@@ -0,0 +1,159 @@
+package etcdclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.uber.org/zap"
+)
+
+// 服务目录在etcd中的键前缀
+const serviceCatalogPrefix = "/catalog/"
+
+// ServiceCatalog 服务级别的目录信息，记录服务的负责团队、说明和相关链接，与实例注册相互独立
+// 服务没有实例时目录信息仍然保留
+type ServiceCatalog struct {
+	ServiceName  string            `json:"service_name"`            // 服务名称
+	Owner        string            `json:"owner,omitempty"`         // 负责团队
+	SlackChannel string            `json:"slack_channel,omitempty"` // Slack频道
+	Description  string            `json:"description,omitempty"`   // 服务说明
+	RunbookURL   string            `json:"runbook_url,omitempty"`   // 运维手册地址
+	Repository   string            `json:"repository,omitempty"`    // 代码仓库地址
+	Links        map[string]string `json:"links,omitempty"`         // 其他链接，如监控面板，键为链接名称
+	UpdatedAt    time.Time         `json:"updated_at"`              // 更新时间
+}
+
+// Validate 校验服务名称和链接地址
+func (s *ServiceCatalog) Validate() error {
+	if s.ServiceName == "" || strings.Contains(s.ServiceName, "/") {
+		return fmt.Errorf("无效的服务名称: %q", s.ServiceName)
+	}
+	if s.RunbookURL != "" && !validCatalogURL(s.RunbookURL) {
+		return fmt.Errorf("无效的运维手册地址: %s", s.RunbookURL)
+	}
+	for name, link := range s.Links {
+		if name == "" {
+			return fmt.Errorf("链接名称不能为空")
+		}
+		if !validCatalogURL(link) {
+			return fmt.Errorf("无效的链接地址: %s=%s", name, link)
+		}
+	}
+	return nil
+}
+
+// validCatalogURL 判断是否为http或https地址
+func validCatalogURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// getServiceCatalogKey 生成服务目录的etcd键
+func getServiceCatalogKey(serviceName string) string {
+	return serviceCatalogPrefix + serviceName
+}
+
+// GetServiceCatalog 获取服务的目录信息，未设置时返回nil
+func (e *EtcdClient) GetServiceCatalog(ctx context.Context, serviceName string) (*ServiceCatalog, error) {
+	if e.conn() == nil {
+		return nil, fmt.Errorf("etcd客户端未连接")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, etcdTimeout)
+	defer cancel()
+
+	resp, err := e.conn().Get(ctx, getServiceCatalogKey(serviceName))
+	if err != nil {
+		return nil, fmt.Errorf("获取服务目录失败: %w", err)
+	}
+	if len(resp.Kvs) == 0 {
+		return nil, nil
+	}
+
+	var catalog ServiceCatalog
+	if err := json.Unmarshal(resp.Kvs[0].Value, &catalog); err != nil {
+		return nil, fmt.Errorf("解析服务目录失败: %w", err)
+	}
+	return &catalog, nil
+}
+
+// ListServiceCatalogs 获取所有服务的目录信息，按服务名称排序
+func (e *EtcdClient) ListServiceCatalogs(ctx context.Context) ([]*ServiceCatalog, error) {
+	if e.conn() == nil {
+		return nil, fmt.Errorf("etcd客户端未连接")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, etcdTimeout)
+	defer cancel()
+
+	resp, err := e.conn().Get(ctx, serviceCatalogPrefix, clientv3.WithPrefix())
+	if err != nil {
+		e.log(ctx).Error("获取服务目录列表失败", zap.Error(err))
+		return nil, fmt.Errorf("获取服务目录列表失败: %w", err)
+	}
+
+	catalogs := make([]*ServiceCatalog, 0, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		var catalog ServiceCatalog
+		if err := json.Unmarshal(kv.Value, &catalog); err != nil {
+			e.log(ctx).Warn("解析服务目录失败", zap.String("key", string(kv.Key)), zap.Error(err))
+			continue
+		}
+		catalogs = append(catalogs, &catalog)
+	}
+	sort.Slice(catalogs, func(i, j int) bool {
+		return catalogs[i].ServiceName < catalogs[j].ServiceName
+	})
+
+	return catalogs, nil
+}
+
+// PutServiceCatalog 设置服务的目录信息，整体替换已有的内容
+func (e *EtcdClient) PutServiceCatalog(ctx context.Context, catalog *ServiceCatalog) error {
+	if e.conn() == nil {
+		return fmt.Errorf("etcd客户端未连接")
+	}
+	if err := catalog.Validate(); err != nil {
+		return err
+	}
+
+	catalog.UpdatedAt = time.Now()
+	data, err := json.Marshal(catalog)
+	if err != nil {
+		return fmt.Errorf("序列化服务目录失败: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, etcdTimeout)
+	defer cancel()
+
+	if _, err := e.conn().Put(ctx, getServiceCatalogKey(catalog.ServiceName), string(data)); err != nil {
+		e.log(ctx).Error("保存服务目录失败", zap.String("service", catalog.ServiceName), zap.Error(err))
+		return fmt.Errorf("保存服务目录失败: %w", err)
+	}
+
+	e.log(ctx).Info("服务目录已更新",
+		zap.String("service", catalog.ServiceName),
+		zap.String("owner", catalog.Owner))
+	return nil
+}
+
+// DeleteServiceCatalog 删除服务的目录信息
+func (e *EtcdClient) DeleteServiceCatalog(ctx context.Context, serviceName string) error {
+	if e.conn() == nil {
+		return fmt.Errorf("etcd客户端未连接")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, etcdTimeout)
+	defer cancel()
+
+	if _, err := e.conn().Delete(ctx, getServiceCatalogKey(serviceName)); err != nil {
+		return fmt.Errorf("删除服务目录失败: %w", err)
+	}
+	return nil
+}
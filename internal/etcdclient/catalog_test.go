@@ -0,0 +1,77 @@
+package etcdclient
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServiceCatalog_Validate(t *testing.T) {
+	assert.NoError(t, (&ServiceCatalog{ServiceName: "payments", Owner: "team-pay"}).Validate())
+	assert.Error(t, (&ServiceCatalog{}).Validate())
+	assert.Error(t, (&ServiceCatalog{ServiceName: "a/b"}).Validate())
+	assert.Error(t, (&ServiceCatalog{ServiceName: "payments", RunbookURL: "wiki/payments"}).Validate())
+	assert.NoError(t, (&ServiceCatalog{ServiceName: "payments", Links: map[string]string{"dashboard": "https://grafana.example.com/d/pay"}}).Validate())
+	assert.Error(t, (&ServiceCatalog{ServiceName: "payments", Links: map[string]string{"dashboard": "grafana"}}).Validate())
+	assert.Error(t, (&ServiceCatalog{ServiceName: "payments", Links: map[string]string{"": "https://grafana.example.com"}}).Validate())
+}
+
+func TestEtcdClient_ServiceCatalog(t *testing.T) {
+	// 跳过集成测试，除非明确要求运行
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	client := CreateEtcdClientForTest(t)
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	service := fmt.Sprintf("catalog-%d", time.Now().UnixNano())
+	defer func() { _ = client.DeleteServiceCatalog(context.Background(), service) }()
+
+	got, err := client.GetServiceCatalog(ctx, service)
+	require.NoError(t, err)
+	assert.Nil(t, got)
+
+	catalog := &ServiceCatalog{
+		ServiceName: service,
+		Owner:       "team-pay",
+		Description: "支付服务",
+		RunbookURL:  "https://wiki.example.com/payments",
+		Links:       map[string]string{"dashboard": "https://grafana.example.com/d/pay"},
+	}
+	require.NoError(t, client.PutServiceCatalog(ctx, catalog))
+
+	got, err = client.GetServiceCatalog(ctx, service)
+	require.NoError(t, err)
+	require.NotNil(t, got)
+	assert.Equal(t, "team-pay", got.Owner)
+	assert.Equal(t, catalog.Links, got.Links)
+	assert.False(t, got.UpdatedAt.IsZero())
+
+	catalogs, err := client.ListServiceCatalogs(ctx)
+	require.NoError(t, err)
+	names := make([]string, 0, len(catalogs))
+	for _, c := range catalogs {
+		names = append(names, c.ServiceName)
+	}
+	assert.Contains(t, names, service)
+
+	// 服务目录与实例无关，整体替换
+	require.NoError(t, client.PutServiceCatalog(ctx, &ServiceCatalog{ServiceName: service, Owner: "team-core"}))
+	got, err = client.GetServiceCatalog(ctx, service)
+	require.NoError(t, err)
+	assert.Equal(t, "team-core", got.Owner)
+	assert.Empty(t, got.RunbookURL)
+
+	require.NoError(t, client.DeleteServiceCatalog(ctx, service))
+	got, err = client.GetServiceCatalog(ctx, service)
+	require.NoError(t, err)
+	assert.Nil(t, got)
+}
@@ -0,0 +1,127 @@
+package etcdclient
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.uber.org/zap"
+)
+
+// ServiceDomainSuffix 服务域名后缀，服务域名格式为 <service>.<namespace>.svc.cluster.local
+const ServiceDomainSuffix = ".svc.cluster.local"
+
+// ServiceNameFromDomain 从服务域名中提取服务名，支持RFC 2782形式的域名，不是服务域名时返回false
+func ServiceNameFromDomain(domain string) (string, bool) {
+	domain = strings.TrimSuffix(strings.ToLower(domain), ".")
+	if !strings.HasSuffix(domain, ServiceDomainSuffix) {
+		return "", false
+	}
+	_, _, domain = ParseServiceDomain(domain)
+	name := strings.Split(strings.TrimSuffix(domain, ServiceDomainSuffix), ".")[0]
+	return name, name != ""
+}
+
+// NamespaceFromDomain 从服务域名中提取命名空间，即服务域名后缀前的标签，不是带命名空间的服务域名时返回false
+func NamespaceFromDomain(domain string) (string, bool) {
+	domain = strings.TrimSuffix(strings.ToLower(domain), ".")
+	if !strings.HasSuffix(domain, ServiceDomainSuffix) {
+		return "", false
+	}
+	labels := strings.Split(strings.TrimSuffix(domain, ServiceDomainSuffix), ".")
+	if len(labels) < 2 || labels[len(labels)-1] == "" {
+		return "", false
+	}
+	return labels[len(labels)-1], true
+}
+
+// serviceDomainNamespace 返回服务域名所属的命名空间，域名中没有命名空间时为default
+func serviceDomainNamespace(domain string) string {
+	if namespace, ok := NamespaceFromDomain(domain); ok {
+		return namespace
+	}
+	return DefaultNamespace
+}
+
+// ServiceNameFromKey 从服务实例键 /services/<service>/<instance> 中提取服务名
+func ServiceNameFromKey(key string) (string, bool) {
+	parts := strings.Split(strings.TrimPrefix(key, "/services/"), "/")
+	if !strings.HasPrefix(key, "/services/") || len(parts) != 2 || parts[0] == "" {
+		return "", false
+	}
+	return parts[0], true
+}
+
+// ListOrphanedServiceRecords 列出由服务维护、但服务已经没有任何实例的DNS记录
+// 实例租约自然过期时不会经过注销流程，这些记录需要由后台任务清理；服务域名下手动维护的记录不受影响
+func (e *EtcdClient) ListOrphanedServiceRecords(ctx context.Context) ([]*DNSRecordEntry, error) {
+	entries, err := e.ListDNSRecords(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	services, err := e.ListServices(ctx)
+	if err != nil {
+		return nil, err
+	}
+	alive := make(map[string]bool, len(services))
+	for _, service := range services {
+		alive[service.ServiceName] = true
+	}
+
+	var orphaned []*DNSRecordEntry
+	for _, entry := range entries {
+		if name, ok := ServiceNameFromDomain(entry.Domain); ok && !alive[name] && entry.OwnedByService(name) {
+			orphaned = append(orphaned, entry)
+		}
+	}
+	return orphaned, nil
+}
+
+// CleanupServiceRecords 删除服务域名下由该服务维护的DNS记录（包括各视图中的记录），返回删除的记录数
+// 只有服务没有任何实例时才会删除，检查和删除在同一个事务中完成，清理期间服务重新注册不会被误删；
+// 手动维护的记录和其他所有者的记录保留不动
+func (e *EtcdClient) CleanupServiceRecords(ctx context.Context, serviceName string) (int, error) {
+	if e.conn() == nil {
+		return 0, fmt.Errorf("etcd客户端未连接")
+	}
+
+	entries, err := e.ListDNSRecords(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var ops []clientv3.Op
+	for _, entry := range entries {
+		if name, ok := ServiceNameFromDomain(entry.Domain); ok && name == serviceName && entry.OwnedByService(serviceName) {
+			ops = append(ops, clientv3.OpDelete(recordKey(entry.Domain, &entry.DNSRecord)))
+		}
+	}
+	if len(ops) == 0 {
+		return 0, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, etcdTimeout)
+	defer cancel()
+
+	resp, err := e.conn().Txn(ctx).
+		If(clientv3.Compare(clientv3.CreateRevision(getServicePrefix(serviceName)), "=", 0).WithPrefix()).
+		Then(ops...).
+		Commit()
+	if err != nil {
+		e.log(ctx).Error("清理服务DNS记录失败", zap.String("service", serviceName), zap.Error(err))
+		return 0, fmt.Errorf("清理服务DNS记录失败: %w", err)
+	}
+	if !resp.Succeeded {
+		// 服务已经重新上线，保留记录
+		return 0, nil
+	}
+
+	deleted := 0
+	for _, r := range resp.Responses {
+		deleted += int(r.GetResponseDeleteRange().Deleted)
+	}
+	e.log(ctx).Info("已清理服务的残留DNS记录", zap.String("service", serviceName), zap.Int("deleted", deleted))
+	return deleted, nil
+}
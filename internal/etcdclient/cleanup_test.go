@@ -0,0 +1,102 @@
+package etcdclient
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServiceNameFromDomain(t *testing.T) {
+	tests := []struct {
+		domain string
+		name   string
+		ok     bool
+	}{
+		{"nginx.default.svc.cluster.local", "nginx", true},
+		{"nginx.svc.cluster.local.", "nginx", true},
+		{"NGINX.prod.svc.cluster.local", "nginx", true},
+		{"_http._tcp.nginx.prod.svc.cluster.local", "nginx", true},
+		{"svc.cluster.local", "", false},
+		{"nginx.example.com", "", false},
+	}
+	for _, tt := range tests {
+		name, ok := ServiceNameFromDomain(tt.domain)
+		assert.Equal(t, tt.ok, ok, tt.domain)
+		assert.Equal(t, tt.name, name, tt.domain)
+	}
+
+	name, ok := ServiceNameFromKey("/services/nginx/instance-001")
+	assert.True(t, ok)
+	assert.Equal(t, "nginx", name)
+	_, ok = ServiceNameFromKey("/services/nginx")
+	assert.False(t, ok)
+}
+
+func TestEtcdClient_CleanupServiceRecords(t *testing.T) {
+	// 跳过集成测试，除非明确要求运行
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	client := CreateEtcdClientForTest(t)
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	serviceName := fmt.Sprintf("cleanup-service-%d", time.Now().UnixNano())
+	domain := serviceName + ".default.svc.cluster.local"
+	owner := ServiceOwner(serviceName)
+	require.NoError(t, client.PutDNSRecord(ctx, domain, &DNSRecord{Type: "A", Value: "10.0.0.1", TTL: 60, Owner: owner}))
+	require.NoError(t, client.PutDNSRecord(ctx, domain, &DNSRecord{Type: "A", Value: "192.168.0.1", TTL: 60, View: "internal", Owner: owner}))
+	// 服务域名下手动维护的记录不是残留记录
+	require.NoError(t, client.PutDNSRecord(ctx, domain, &DNSRecord{Type: "TXT", Value: "manual", TTL: 60}))
+	defer func() {
+		_, _ = client.ApplyDNSRecordBatch(context.Background(), []*DNSRecordOperation{
+			{Op: DNSRecordOpDelete, Domain: domain, Type: "A", Force: true},
+			{Op: DNSRecordOpDelete, Domain: domain, Type: "A", View: "internal", Force: true},
+		})
+		_ = client.DeleteDNSRecord(context.Background(), domain, "TXT", "")
+	}()
+
+	// 服务还有实例时不清理
+	require.NoError(t, client.RegisterService(ctx, &ServiceInstance{
+		ServiceName: serviceName, InstanceID: "instance-001", IPAddress: "10.0.0.1", Port: 8080, TTL: 30,
+	}))
+	deleted, err := client.CleanupServiceRecords(ctx, serviceName)
+	require.NoError(t, err)
+	assert.Equal(t, 0, deleted)
+
+	orphaned, err := client.ListOrphanedServiceRecords(ctx)
+	require.NoError(t, err)
+	for _, entry := range orphaned {
+		assert.NotEqual(t, domain, entry.Domain, "服务有实例时记录不是残留记录")
+	}
+
+	// 实例下线后服务维护的记录成为残留记录，清理会删除所有视图中的记录，手动记录保留
+	require.NoError(t, client.DeregisterService(ctx, serviceName, "instance-001"))
+	orphaned, err = client.ListOrphanedServiceRecords(ctx)
+	require.NoError(t, err)
+	count := 0
+	for _, entry := range orphaned {
+		if entry.Domain == domain {
+			count++
+		}
+	}
+	assert.Equal(t, 2, count)
+
+	deleted, err = client.CleanupServiceRecords(ctx, serviceName)
+	require.NoError(t, err)
+	assert.Equal(t, 2, deleted)
+
+	_, err = client.GetDNSRecordEntry(ctx, domain, "A", "")
+	assert.ErrorIs(t, err, ErrDNSRecordNotFound)
+	_, err = client.GetDNSRecordEntry(ctx, domain, "A", "internal")
+	assert.ErrorIs(t, err, ErrDNSRecordNotFound)
+	_, err = client.GetDNSRecordEntry(ctx, domain, "TXT", "")
+	assert.NoError(t, err, "手动维护的记录不应被清理")
+}
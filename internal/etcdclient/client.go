@@ -3,10 +3,16 @@ package etcdclient
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/hewenyu/kong-discovery/internal/chaos"
 	"github.com/hewenyu/kong-discovery/internal/config"
+	"github.com/hewenyu/kong-discovery/internal/memstore"
+	"github.com/hewenyu/kong-discovery/internal/sqlstore"
 	clientv3 "go.etcd.io/etcd/client/v3"
 	"go.uber.org/zap"
 )
@@ -16,10 +22,14 @@ const etcdTimeout = 5 * time.Second
 
 // DNSRecord 表示存储在etcd中的DNS记录
 type DNSRecord struct {
-	Type  string   `json:"type"`           // 记录类型 (A, AAAA, SRV, CNAME等)
-	Value string   `json:"value"`          // 记录值 (对于A记录是IP地址，CNAME是目标域名等)
-	TTL   int      `json:"ttl"`            // 记录的TTL (秒)
-	Tags  []string `json:"tags,omitempty"` // 可选标签，用于记录分组或筛选
+	Type      string       `json:"type" validate:"required"`                  // 记录类型 (A, AAAA, SRV, CNAME等)
+	Value     string       `json:"value" validate:"required"`                 // 记录值 (对于A记录是IP地址，CNAME是目标域名等)
+	TTL       int          `json:"ttl" validate:"ttl"`                        // 记录的TTL (秒)
+	Tags      []string     `json:"tags,omitempty"`                            // 可选标签，用于记录分组或筛选
+	View      string       `json:"view,omitempty"`                            // 可选视图，为空表示默认视图
+	Namespace string       `json:"namespace,omitempty"`                       // 所属命名空间，为空表示default
+	Address   string       `json:"address,omitempty" validate:"omitempty,ip"` // SRV记录目标的IP地址，用于在附加段返回目标的A记录
+	Owner     *RecordOwner `json:"owner,omitempty"`                           // 自动维护记录的所有者，为空表示手动维护的记录
 }
 
 // Client 定义etcd客户端接口
@@ -33,6 +43,21 @@ type Client interface {
 	// Ping 检查etcd集群状态
 	Ping(ctx context.Context) error
 
+	// Endpoints 返回当前连接使用的etcd端点
+	Endpoints() []string
+
+	// UpdateEndpoints 更新etcd端点列表并应用到当前连接，不中断进行中的请求
+	UpdateEndpoints(endpoints []string) error
+
+	// ConnectLazy 连接存储，失败时从本地快照提供只读数据并在后台重试，连接成功后调用onConnected
+	ConnectLazy(onConnected func())
+
+	// Offline 判断是否因存储不可用而在使用本地快照
+	Offline() bool
+
+	// SaveSnapshot 把全部发现数据写入本地快照文件
+	SaveSnapshot(ctx context.Context, path string) error
+
 	// Get 从etcd获取指定key的值
 	Get(ctx context.Context, key string) (string, error)
 
@@ -45,6 +70,48 @@ type Client interface {
 	// PutDNSRecord 将DNS记录存储到etcd
 	PutDNSRecord(ctx context.Context, domain string, record *DNSRecord) error
 
+	// GetDNSRecordInView 获取指定视图下的DNS记录，视图中不存在时回退到默认记录
+	GetDNSRecordInView(ctx context.Context, view, domain, recordType string) (*DNSRecord, error)
+
+	// ListDNSRecords 列出所有DNS记录
+	ListDNSRecords(ctx context.Context) ([]*DNSRecordEntry, error)
+
+	// ListDNSDomains 按前缀分页列出DNS域名
+	ListDNSDomains(ctx context.Context, query DNSDomainQuery) (*DNSDomainPage, error)
+
+	// GetDNSRecordEntry 精确读取一条DNS记录及其版本号，不做视图回退
+	GetDNSRecordEntry(ctx context.Context, domain, recordType, view string) (*DNSRecordEntry, error)
+
+	// PutDNSRecordAtRevision 仅当记录的当前版本与revision一致、且所有者相同时写入，返回写入后的版本号
+	PutDNSRecordAtRevision(ctx context.Context, domain string, record *DNSRecord, revision int64) (int64, error)
+
+	// ApplyDNSRecordBatch 在单个事务中原子地应用一组DNS记录操作
+	ApplyDNSRecordBatch(ctx context.Context, ops []*DNSRecordOperation) ([]*DNSRecordOpResult, error)
+
+	// ScheduleDNSChange 保存一组待定时执行的DNS记录变更
+	ScheduleDNSChange(ctx context.Context, change *ScheduledChange) (string, error)
+
+	// ListScheduledChanges 列出所有定时变更
+	ListScheduledChanges(ctx context.Context) ([]*ScheduledChange, error)
+
+	// GetScheduledChange 获取定时变更
+	GetScheduledChange(ctx context.Context, id string) (*ScheduledChange, error)
+
+	// CancelScheduledChange 取消定时变更
+	CancelScheduledChange(ctx context.Context, id string) error
+
+	// ApplyScheduledChange 执行一个定时变更
+	ApplyScheduledChange(ctx context.Context, id string) ([]*DNSRecordOpResult, error)
+
+	// Campaign 参与领导者选举，阻塞直到成为领导者
+	Campaign(ctx context.Context, name, candidate string) (*Leadership, error)
+
+	// ElectionLeaders 返回各选举当前领导者的候选者标识
+	ElectionLeaders(ctx context.Context) (map[string]string, error)
+
+	// DeleteDNSRecord 删除没有所有者的DNS记录
+	DeleteDNSRecord(ctx context.Context, domain, recordType, view string) error
+
 	// GetDNSRecordsForDomain 获取域名的所有DNS记录
 	GetDNSRecordsForDomain(ctx context.Context, domain string) (map[string]*DNSRecord, error)
 
@@ -57,20 +124,308 @@ type Client interface {
 	// GetServiceInstances 获取指定服务的所有实例
 	GetServiceInstances(ctx context.Context, serviceName string) ([]*ServiceInstance, error)
 
+	// ListServices 列出所有已注册的服务
+	ListServices(ctx context.Context) ([]*ServiceSummary, error)
+
+	// DiscoverService 查询命名空间中服务的可用实例，返回读取时的修订版本
+	DiscoverService(ctx context.Context, namespace, serviceName string) (*ServiceDiscovery, error)
+
+	// WaitServiceChange 阻塞等待服务在index之后发生变更
+	WaitServiceChange(ctx context.Context, serviceName string, index int64) error
+
+	// WaitServicesChange 阻塞等待任意服务在index之后发生变更
+	WaitServicesChange(ctx context.Context, index int64) error
+
+	// Revision 返回etcd当前的修订版本
+	Revision(ctx context.Context) (int64, error)
+
+	// ListOrphanedServiceRecords 列出服务已没有任何实例的服务域名DNS记录
+	ListOrphanedServiceRecords(ctx context.Context) ([]*DNSRecordEntry, error)
+
+	// CleanupServiceRecords 在服务没有任何实例时删除其服务域名下由该服务维护的DNS记录
+	CleanupServiceRecords(ctx context.Context, serviceName string) (int, error)
+
+	// PlanDeregister 报告注销服务实例的影响范围，不做修改
+	PlanDeregister(ctx context.Context, serviceName, instanceID string) (*ChangePlan, error)
+
+	// PlanDeleteDNSRecord 报告删除DNS记录的影响范围，不做修改
+	PlanDeleteDNSRecord(ctx context.Context, domain, recordType, view string, force bool) (*ChangePlan, error)
+
+	// PlanDeleteNamespace 报告删除命名空间的影响范围，不做修改
+	PlanDeleteNamespace(ctx context.Context, name string, cascade bool) (*ChangePlan, error)
+
+	// ReconcileDrift 检查服务实例、DNS记录和命名空间之间的数据一致性，不是dry-run时修复
+	ReconcileDrift(ctx context.Context, opts DriftOptions) (*DriftReport, error)
+
 	// ServiceToDNSRecords 将服务实例转换为DNS记录
 	ServiceToDNSRecords(ctx context.Context, domain string) (map[string]*DNSRecord, error)
 
+	// ServiceToDNSRecordsWithOptions 按选项将服务实例转换为DNS记录
+	ServiceToDNSRecordsWithOptions(ctx context.Context, domain string, opts ServiceRecordOptions) (map[string]*DNSRecord, error)
+
+	// ServiceTXTRecords 返回服务域名TXT应答的内容，只包含命名空间允许公开的实例元数据
+	ServiceTXTRecords(ctx context.Context, domain string) ([][]string, error)
+
+	// EvictInstance 强制驱逐服务实例，冷却期内拒绝相同实例ID的注册
+	EvictInstance(ctx context.Context, serviceName, instanceID string, cooldown time.Duration, reason string) (*Eviction, error)
+
+	// ListEvictions 列出仍在冷却期内的驱逐记录
+	ListEvictions(ctx context.Context) ([]*Eviction, error)
+
+	// DeleteEviction 提前结束实例的驱逐冷却期
+	DeleteEviction(ctx context.Context, serviceName, instanceID string) error
+
 	// RefreshServiceLease 刷新服务实例的租约
 	RefreshServiceLease(ctx context.Context, serviceName, instanceID string, ttl int) error
+
+	// RefreshServiceLeaseWithReport 刷新服务实例的租约并保存心跳携带的健康信息
+	RefreshServiceLeaseWithReport(ctx context.Context, serviceName, instanceID string, ttl int, report *HealthReport) (*ServiceInstance, error)
+
+	// SetInstanceHealth 更新服务实例的健康状态
+	SetInstanceHealth(ctx context.Context, serviceName, instanceID, status string) (*ServiceInstance, error)
+
+	// SetInstanceCheckResult 保存外部检查器推送的检查结果并更新实例的健康状态
+	SetInstanceCheckResult(ctx context.Context, serviceName, instanceID string, result *CheckResult) (*ServiceInstance, error)
+
+	// GetServiceMaintenance 获取服务的维护状态，未处于维护模式时返回nil
+	GetServiceMaintenance(ctx context.Context, serviceName string) (*MaintenanceInfo, error)
+
+	// SetServiceMaintenance 开启或关闭服务的维护模式
+	SetServiceMaintenance(ctx context.Context, serviceName string, enabled bool, reason string) error
+
+	// SetInstanceMaintenance 开启或关闭单个实例的维护模式
+	SetInstanceMaintenance(ctx context.Context, serviceName, instanceID string, enabled bool) (*ServiceInstance, error)
+
+	// GetRegistrationPolicy 获取服务的注册策略，未单独设置时返回nil
+	GetRegistrationPolicy(ctx context.Context, serviceName string) (*RegistrationPolicy, error)
+
+	// PutRegistrationPolicy 设置服务的注册策略
+	PutRegistrationPolicy(ctx context.Context, policy *RegistrationPolicy) error
+
+	// DeleteRegistrationPolicy 删除服务的注册策略
+	DeleteRegistrationPolicy(ctx context.Context, serviceName string) error
+
+	// GetServiceACL 获取服务的解析ACL，未设置时返回nil
+	GetServiceACL(ctx context.Context, serviceName string) (*ServiceACL, error)
+
+	// ListServiceACLs 获取所有服务的解析ACL
+	ListServiceACLs(ctx context.Context) ([]*ServiceACL, error)
+
+	// PutServiceACL 设置服务的解析ACL
+	PutServiceACL(ctx context.Context, acl *ServiceACL) error
+
+	// DeleteServiceACL 删除服务的解析ACL
+	DeleteServiceACL(ctx context.Context, serviceName string) error
+
+	// GetServiceAnswerPolicy 获取服务的应答策略，未设置时返回nil
+	GetServiceAnswerPolicy(ctx context.Context, serviceName string) (*ServiceAnswerPolicy, error)
+
+	// ListServiceAnswerPolicies 获取所有服务的应答策略
+	ListServiceAnswerPolicies(ctx context.Context) ([]*ServiceAnswerPolicy, error)
+
+	// PutServiceAnswerPolicy 设置服务的应答策略
+	PutServiceAnswerPolicy(ctx context.Context, policy *ServiceAnswerPolicy) error
+
+	// DeleteServiceAnswerPolicy 删除服务的应答策略
+	DeleteServiceAnswerPolicy(ctx context.Context, serviceName string) error
+
+	// GetServiceTTLPolicy 获取服务的TTL策略，未设置时返回nil
+	GetServiceTTLPolicy(ctx context.Context, serviceName string) (*ServiceTTLPolicy, error)
+
+	// ListServiceTTLPolicies 获取所有服务的TTL策略
+	ListServiceTTLPolicies(ctx context.Context) ([]*ServiceTTLPolicy, error)
+
+	// PutServiceTTLPolicy 设置服务的TTL策略
+	PutServiceTTLPolicy(ctx context.Context, policy *ServiceTTLPolicy) error
+
+	// DeleteServiceTTLPolicy 删除服务的TTL策略
+	DeleteServiceTTLPolicy(ctx context.Context, serviceName string) error
+
+	// GetLoadWeightsState 获取按负载调整权重的运行时开关，未设置时返回nil
+	GetLoadWeightsState(ctx context.Context) (*LoadWeightsState, error)
+
+	// PutLoadWeightsState 设置按负载调整权重的运行时开关
+	PutLoadWeightsState(ctx context.Context, state *LoadWeightsState) error
+
+	// GetServiceAlias 获取服务别名，服务不是别名时返回nil
+	GetServiceAlias(ctx context.Context, serviceName string) (*ServiceAlias, error)
+
+	// ListServiceAliases 获取所有服务别名
+	ListServiceAliases(ctx context.Context) ([]*ServiceAlias, error)
+
+	// PutServiceAlias 创建服务别名或切换别名的目标
+	PutServiceAlias(ctx context.Context, alias *ServiceAlias) error
+
+	// DeleteServiceAlias 删除服务别名
+	DeleteServiceAlias(ctx context.Context, serviceName string) error
+
+	// GetServiceCatalog 获取服务的目录信息，未设置时返回nil
+	GetServiceCatalog(ctx context.Context, serviceName string) (*ServiceCatalog, error)
+
+	// ListServiceCatalogs 获取所有服务的目录信息
+	ListServiceCatalogs(ctx context.Context) ([]*ServiceCatalog, error)
+
+	// PutServiceCatalog 设置服务的目录信息
+	PutServiceCatalog(ctx context.Context, catalog *ServiceCatalog) error
+
+	// DeleteServiceCatalog 删除服务的目录信息
+	DeleteServiceCatalog(ctx context.Context, serviceName string) error
+
+	// ListNamespaces 列出所有命名空间
+	ListNamespaces(ctx context.Context) ([]*Namespace, error)
+
+	// GetNamespace 获取命名空间，不存在时返回nil
+	GetNamespace(ctx context.Context, name string) (*Namespace, error)
+
+	// CreateNamespace 创建命名空间
+	CreateNamespace(ctx context.Context, ns *Namespace) error
+
+	// DeleteNamespace 删除命名空间，cascade为true时一并删除其中的数据
+	DeleteNamespace(ctx context.Context, name string, cascade bool) error
+
+	// GetNamespaceQuota 获取命名空间生效的配额
+	GetNamespaceQuota(ctx context.Context, namespace string) (*NamespaceQuota, error)
+
+	// PutNamespaceQuota 设置命名空间的配额
+	PutNamespaceQuota(ctx context.Context, namespace string, quota *NamespaceQuota) error
+
+	// GetNamespaceTXTMetadata 获取命名空间通过TXT查询公开的元数据键
+	GetNamespaceTXTMetadata(ctx context.Context, namespace string) ([]string, error)
+
+	// PutNamespaceTXTMetadata 设置命名空间通过TXT查询公开的元数据键
+	PutNamespaceTXTMetadata(ctx context.Context, namespace string, keys []string) error
+
+	// GetNamespaceUpstream 获取命名空间覆盖的上游DNS设置，未覆盖时返回nil
+	GetNamespaceUpstream(ctx context.Context, namespace string) (*NamespaceUpstream, error)
+
+	// PutNamespaceUpstream 设置命名空间覆盖的上游DNS设置，upstream为nil时恢复使用全局配置
+	PutNamespaceUpstream(ctx context.Context, namespace string, upstream *NamespaceUpstream) error
+
+	// GetNamespaceTTLPolicy 获取命名空间的TTL策略，未设置时返回nil
+	GetNamespaceTTLPolicy(ctx context.Context, namespace string) (*TTLPolicy, error)
+
+	// PutNamespaceTTLPolicy 设置命名空间的TTL策略，policy为nil时恢复使用全局设置
+	PutNamespaceTTLPolicy(ctx context.Context, namespace string, policy *TTLPolicy) error
+
+	// ListNamespaceZones 列出所有命名空间的自定义区域
+	ListNamespaceZones(ctx context.Context) ([]*NamespaceZone, error)
+
+	// PutNamespaceZone 把自定义区域分配给命名空间
+	PutNamespaceZone(ctx context.Context, zone *NamespaceZone) error
+
+	// DeleteNamespaceZone 删除命名空间的自定义区域
+	DeleteNamespaceZone(ctx context.Context, namespace, zone string) error
+
+	// GetNamespaceUsage 统计命名空间的资源用量
+	GetNamespaceUsage(ctx context.Context, namespace string) (*NamespaceUsage, error)
+
+	// WatchEvents 监听发现数据的变更
+	WatchEvents(ctx context.Context) (<-chan *Event, error)
+
+	// WatchEventsFrom 从指定的修订版本开始监听发现数据的变更
+	WatchEventsFrom(ctx context.Context, revision int64) (<-chan *Event, error)
+
+	// WatchStats 返回变更监听的中断与压缩统计
+	WatchStats() WatchStats
+
+	// GetUpstreamConfig 获取运行时上游DNS配置
+	GetUpstreamConfig(ctx context.Context) (*UpstreamConfig, error)
+
+	// PutUpstreamConfig 保存运行时上游DNS配置
+	PutUpstreamConfig(ctx context.Context, cfg *UpstreamConfig) error
+
+	// ListDisabledUpstreams 列出所有被手动停用的上游DNS服务器
+	ListDisabledUpstreams(ctx context.Context) ([]*DisabledUpstream, error)
+
+	// SetUpstreamDisabled 停用或恢复上游DNS服务器
+	SetUpstreamDisabled(ctx context.Context, addr string, disabled bool, reason string) error
+
+	// ListForwardRules 获取所有条件转发规则
+	ListForwardRules(ctx context.Context) ([]*ForwardRule, error)
+
+	// PutForwardRule 保存条件转发规则
+	PutForwardRule(ctx context.Context, rule *ForwardRule) error
+
+	// DeleteForwardRule 删除条件转发规则
+	DeleteForwardRule(ctx context.Context, suffix string) error
+
+	// ListBlockRules 获取所有拦截规则
+	ListBlockRules(ctx context.Context) ([]*BlockRule, error)
+
+	// PutBlockRule 保存拦截规则
+	PutBlockRule(ctx context.Context, rule *BlockRule) error
+
+	// DeleteBlockRule 删除拦截规则
+	DeleteBlockRule(ctx context.Context, id string) error
+
+	// ListWebhooks 获取所有webhook
+	ListWebhooks(ctx context.Context) ([]*Webhook, error)
+
+	// GetWebhook 获取webhook
+	GetWebhook(ctx context.Context, id string) (*Webhook, error)
+
+	// PutWebhook 创建或更新webhook
+	PutWebhook(ctx context.Context, webhook *Webhook) error
+
+	// DeleteWebhook 删除webhook及其投递记录
+	DeleteWebhook(ctx context.Context, id string) error
+
+	// ListWebhookDeliveries 获取webhook最近的投递记录
+	ListWebhookDeliveries(ctx context.Context, webhookID string) ([]*WebhookDelivery, error)
+
+	// PutWebhookDelivery 保存投递状态
+	PutWebhookDelivery(ctx context.Context, delivery *WebhookDelivery) error
+
+	// GetEventBusCheckpoint 获取事件总线已发布的修订版本
+	GetEventBusCheckpoint(ctx context.Context) (int64, error)
+
+	// PutEventBusCheckpoint 保存事件总线已发布的修订版本
+	PutEventBusCheckpoint(ctx context.Context, revision int64) error
+
+	// Snapshot 在同一个修订版本上读取发现数据用于备份
+	Snapshot(ctx context.Context, kinds []string) (*Snapshot, error)
+
+	// Restore 把备份的发现数据写回etcd
+	Restore(ctx context.Context, snapshot *Snapshot, opts RestoreOptions) (*RestoreResult, error)
+
+	// ExportCatalog 导出本集群的服务目录，供远程集群同步
+	ExportCatalog(ctx context.Context, cluster string) (*Catalog, error)
+
+	// ApplyCatalog 用远程集群的目录替换已同步的服务，并记录一次成功的同步
+	ApplyCatalog(ctx context.Context, catalog *Catalog, mode, source string) (*FederationLink, error)
+
+	// RecordFederationFailure 记录一次与远程集群同步失败
+	RecordFederationFailure(ctx context.Context, cluster, mode, source string, cause error) (*FederationLink, error)
+
+	// ListFederationLinks 列出所有联邦链路的状态
+	ListFederationLinks(ctx context.Context) ([]*FederationLink, error)
+
+	// DeleteFederatedCluster 删除远程集群已同步的目录和链路状态
+	DeleteFederatedCluster(ctx context.Context, cluster string) (int64, error)
+
+	// FederatedServiceToDNSRecords 将远程集群中服务的实例转换为DNS记录
+	FederatedServiceToDNSRecords(ctx context.Context, cluster, domain string) (map[string]*DNSRecord, error)
 }
 
 // EtcdClient 实现Client接口
 type EtcdClient struct {
-	client *clientv3.Client
+	client atomic.Pointer[clientv3.Client] // 当前使用的客户端，延迟连接成功后会被替换
 	cfg    *config.Config
 	logger config.Logger
+
+	mu        sync.Mutex
+	store     *sqlstore.Store    // storage为sqlite或postgres时打开的SQL存储，关闭客户端时一起关闭
+	offline   bool               // 存储不可用，client为本地快照上的只读客户端
+	cancel    context.CancelFunc // 停止后台的连接重试和快照保存
+	endpoints []string           // 运行时更新的etcd端点，为nil时使用配置中的端点
+	wg        sync.WaitGroup
+
+	watches watchStats // 变更监听的中断与压缩统计
 }
 
+// memoryStore 进程内共享的内存存储，同一进程中的多个客户端看到相同的数据，与连接同一个etcd集群一致
+var memoryStore = sync.OnceValue(memstore.New)
+
 // NewEtcdClient 创建一个新的etcd客户端
 func NewEtcdClient(cfg *config.Config, logger config.Logger) Client {
 	return &EtcdClient{
@@ -79,66 +434,181 @@ func NewEtcdClient(cfg *config.Config, logger config.Logger) Client {
 	}
 }
 
+// conn 返回当前使用的etcd客户端，未连接或已关闭时为nil
+func (e *EtcdClient) conn() *clientv3.Client {
+	return e.client.Load()
+}
+
 // Connect 连接到etcd集群
 func (e *EtcdClient) Connect() error {
-	var err error
-	e.logger.Info("连接到etcd集群", zap.Strings("endpoints", e.cfg.Etcd.Endpoints))
-
-	e.client, err = clientv3.New(clientv3.Config{
-		Endpoints:   e.cfg.Etcd.Endpoints,
-		DialTimeout: 5 * time.Second,
-		Username:    e.cfg.Etcd.Username,
-		Password:    e.cfg.Etcd.Password,
+	client, store, err := e.dial()
+	if err != nil {
+		return err
+	}
+	e.mu.Lock()
+	e.store = store
+	e.mu.Unlock()
+	e.client.Store(client)
+	return nil
+}
+
+// dial 按storage配置创建客户端，使用SQL存储时同时返回打开的存储。开启故障注入时客户端的读写和watch经过故障注入层
+func (e *EtcdClient) dial() (*clientv3.Client, *sqlstore.Store, error) {
+	client, store, err := e.dialStorage()
+	if err != nil || !e.cfg.Chaos.Enabled {
+		return client, store, err
+	}
+	e.logger.Warn("已开启故障注入，存储操作可能被延迟，watch事件可能被丢弃")
+	chaos.WrapClient(client, chaos.Global())
+	return client, store, nil
+}
+
+// dialStorage 按storage配置创建客户端
+func (e *EtcdClient) dialStorage() (*clientv3.Client, *sqlstore.Store, error) {
+	switch e.cfg.Storage {
+	case config.StorageMemory:
+		e.logger.Warn("使用内存存储，数据只保存在当前进程中，退出后丢失")
+		return memoryStore().NewClient(), nil, nil
+	case config.StorageSQLite, config.StoragePostgres:
+		return e.dialSQL()
+	}
+
+	endpoints := e.etcdEndpoints()
+	e.logger.Info("连接到etcd集群", zap.Strings("endpoints", endpoints))
+
+	client, err := clientv3.New(clientv3.Config{
+		Endpoints:        endpoints,
+		AutoSyncInterval: e.autoSyncInterval(),
+		DialTimeout:      5 * time.Second,
+		Username:         e.cfg.Etcd.Username,
+		Password:         e.cfg.Etcd.Password,
 	})
 
 	if err != nil {
 		e.logger.Error("连接etcd失败", zap.Error(err))
-		return fmt.Errorf("连接etcd失败: %w", err)
+		return nil, nil, fmt.Errorf("连接etcd失败: %w", err)
 	}
 
-	return nil
+	return client, nil, nil
+}
+
+// dialSQL 打开SQL存储并创建使用它的客户端
+func (e *EtcdClient) dialSQL() (*clientv3.Client, *sqlstore.Store, error) {
+	e.logger.Info("使用SQL存储", zap.String("storage", e.cfg.Storage))
+
+	store, err := sqlstore.Open(e.cfg.Storage, e.cfg.SQL.DSN, sqlstore.Options{
+		PollInterval: time.Duration(e.cfg.SQL.PollInterval) * time.Millisecond,
+		Retention:    int64(e.cfg.SQL.Retention),
+		Logger:       e.logger,
+	})
+	if err != nil {
+		e.logger.Error("打开SQL存储失败", zap.Error(err))
+		return nil, nil, fmt.Errorf("打开SQL存储失败: %w", err)
+	}
+	return store.NewClient(), store, nil
+}
+
+// inProcess 判断存储是否在当前进程中实现，这类存储没有etcd端点
+func (e *EtcdClient) inProcess() bool {
+	switch e.cfg.Storage {
+	case config.StorageMemory, config.StorageSQLite, config.StoragePostgres:
+		return true
+	}
+	return false
 }
 
-// Close 关闭连接
+// closeClient 关闭客户端，inProcess表示客户端使用进程内的存储
+func closeClient(client *clientv3.Client, inProcess bool) error {
+	err := client.Close()
+	// 进程内存储的客户端没有网络连接，关闭时返回的是其context被取消的错误
+	if inProcess && errors.Is(err, context.Canceled) {
+		return nil
+	}
+	return err
+}
+
+// Close 关闭连接，已连接存储且配置了本地快照时先保存最新的快照
 func (e *EtcdClient) Close() error {
-	if e.client != nil {
-		e.logger.Info("关闭etcd连接")
-		return e.client.Close()
+	e.mu.Lock()
+	cancel, offline := e.cancel, e.offline
+	e.mu.Unlock()
+	if cancel != nil {
+		cancel()
 	}
-	return nil
+	e.wg.Wait()
+
+	if path := e.cfg.Etcd.Snapshot.Path; path != "" && !offline && e.conn() != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), etcdTimeout)
+		if err := e.SaveSnapshot(ctx, path); err != nil {
+			e.logger.Warn("关闭前保存本地快照失败", zap.Error(err))
+		} else {
+			e.logger.Info("已保存本地快照", zap.String("path", path))
+		}
+		cancel()
+	}
+
+	client := e.client.Swap(nil)
+	if client == nil {
+		return nil
+	}
+	e.logger.Info("关闭etcd连接")
+
+	e.mu.Lock()
+	store, offline := e.store, e.offline
+	e.store = nil
+	e.mu.Unlock()
+
+	err := closeClient(client, offline || e.inProcess())
+	if store != nil {
+		err = errors.Join(err, store.Close())
+	}
+	return err
 }
 
-// Ping 检查etcd集群状态
+// Ping 检查etcd集群状态，使用本地快照时返回ErrOffline
 func (e *EtcdClient) Ping(ctx context.Context) error {
-	if e.client == nil {
+	if e.conn() == nil {
 		return fmt.Errorf("etcd客户端未连接")
 	}
+	if e.Offline() {
+		return ErrOffline
+	}
 
-	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
-	defer cancel()
-
-	_, err := e.client.Status(ctx, e.cfg.Etcd.Endpoints[0])
-	if err != nil {
-		e.logger.Error("etcd健康检查失败", zap.Error(err))
+	if err := e.status(ctx, e.conn()); err != nil {
+		e.log(ctx).Error("etcd健康检查失败", zap.Error(err))
 		return fmt.Errorf("etcd健康检查失败: %w", err)
 	}
 
-	e.logger.Info("etcd健康检查成功")
+	e.log(ctx).Info("etcd健康检查成功")
 	return nil
 }
 
+// status 查询client所连接存储的状态
+func (e *EtcdClient) status(ctx context.Context, client *clientv3.Client) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	// 进程内存储只有一个成员，不区分端点；etcd的端点可能在运行时更新，使用客户端当前的列表
+	var endpoint string
+	if !e.inProcess() {
+		endpoint = client.Endpoints()[0]
+	}
+	_, err := client.Status(ctx, endpoint)
+	return err
+}
+
 // Get 从etcd获取指定key的值
 func (e *EtcdClient) Get(ctx context.Context, key string) (string, error) {
-	if e.client == nil {
+	if e.conn() == nil {
 		return "", fmt.Errorf("etcd客户端未连接")
 	}
 
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
-	resp, err := e.client.Get(ctx, key)
+	resp, err := e.conn().Get(ctx, key)
 	if err != nil {
-		e.logger.Error("从etcd获取数据失败", zap.String("key", key), zap.Error(err))
+		e.log(ctx).Error("从etcd获取数据失败", zap.String("key", key), zap.Error(err))
 		return "", fmt.Errorf("从etcd获取数据失败: %w", err)
 	}
 
@@ -151,16 +621,16 @@ func (e *EtcdClient) Get(ctx context.Context, key string) (string, error) {
 
 // GetWithPrefix 从etcd获取指定前缀的所有key-value
 func (e *EtcdClient) GetWithPrefix(ctx context.Context, prefix string) (map[string]string, error) {
-	if e.client == nil {
+	if e.conn() == nil {
 		return nil, fmt.Errorf("etcd客户端未连接")
 	}
 
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
-	resp, err := e.client.Get(ctx, prefix, clientv3.WithPrefix())
+	resp, err := e.conn().Get(ctx, prefix, clientv3.WithPrefix())
 	if err != nil {
-		e.logger.Error("从etcd获取前缀数据失败", zap.String("prefix", prefix), zap.Error(err))
+		e.log(ctx).Error("从etcd获取前缀数据失败", zap.String("prefix", prefix), zap.Error(err))
 		return nil, fmt.Errorf("从etcd获取前缀数据失败: %w", err)
 	}
 
@@ -177,20 +647,55 @@ func getDNSRecordKey(domain, recordType string) string {
 	return fmt.Sprintf("/dns/records/%s/%s", domain, recordType)
 }
 
+// getViewDNSRecordKey 生成视图DNS记录的etcd键
+func getViewDNSRecordKey(view, domain, recordType string) string {
+	return fmt.Sprintf("/dns/views/%s/%s/%s", view, domain, recordType)
+}
+
+// DNSRecordLookupKeys 返回查询指定视图下的DNS记录时依次读取的etcd键
+func DNSRecordLookupKeys(view, domain, recordType string) []string {
+	if view == "" {
+		return []string{getDNSRecordKey(domain, recordType)}
+	}
+	return []string{getViewDNSRecordKey(view, domain, recordType), getDNSRecordKey(domain, recordType)}
+}
+
+// recordKey 根据记录的视图生成etcd键
+func recordKey(domain string, record *DNSRecord) string {
+	if record.View != "" {
+		return getViewDNSRecordKey(record.View, domain, record.Type)
+	}
+	return getDNSRecordKey(domain, record.Type)
+}
+
 // GetDNSRecord 从etcd获取DNS记录
 func (e *EtcdClient) GetDNSRecord(ctx context.Context, domain string, recordType string) (*DNSRecord, error) {
-	if e.client == nil {
-		return nil, fmt.Errorf("etcd客户端未连接")
+	return e.getDNSRecordByKey(ctx, getDNSRecordKey(domain, recordType))
+}
+
+// GetDNSRecordInView 获取指定视图下的DNS记录，视图中不存在时回退到默认记录
+func (e *EtcdClient) GetDNSRecordInView(ctx context.Context, view, domain, recordType string) (*DNSRecord, error) {
+	if view != "" {
+		record, err := e.getDNSRecordByKey(ctx, getViewDNSRecordKey(view, domain, recordType))
+		if err == nil {
+			return record, nil
+		}
 	}
+	return e.GetDNSRecord(ctx, domain, recordType)
+}
 
-	key := getDNSRecordKey(domain, recordType)
+// getDNSRecordByKey 按etcd键读取DNS记录
+func (e *EtcdClient) getDNSRecordByKey(ctx context.Context, key string) (*DNSRecord, error) {
+	if e.conn() == nil {
+		return nil, fmt.Errorf("etcd客户端未连接")
+	}
 
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
-	resp, err := e.client.Get(ctx, key)
+	resp, err := e.conn().Get(ctx, key)
 	if err != nil {
-		e.logger.Error("从etcd获取DNS记录失败", zap.String("key", key), zap.Error(err))
+		e.log(ctx).Error("从etcd获取DNS记录失败", zap.String("key", key), zap.Error(err))
 		return nil, fmt.Errorf("从etcd获取DNS记录失败: %w", err)
 	}
 
@@ -200,46 +705,22 @@ func (e *EtcdClient) GetDNSRecord(ctx context.Context, domain string, recordType
 
 	var record DNSRecord
 	if err := json.Unmarshal(resp.Kvs[0].Value, &record); err != nil {
-		e.logger.Error("解析DNS记录失败", zap.String("key", key), zap.Error(err))
+		e.log(ctx).Error("解析DNS记录失败", zap.String("key", key), zap.Error(err))
 		return nil, fmt.Errorf("解析DNS记录失败: %w", err)
 	}
 
 	return &record, nil
 }
 
-// PutDNSRecord 将DNS记录存储到etcd
+// PutDNSRecord 将DNS记录存储到etcd，不校验版本（后写入者覆盖）
 func (e *EtcdClient) PutDNSRecord(ctx context.Context, domain string, record *DNSRecord) error {
-	if e.client == nil {
-		return fmt.Errorf("etcd客户端未连接")
-	}
-
-	key := getDNSRecordKey(domain, record.Type)
-
-	recordJSON, err := json.Marshal(record)
-	if err != nil {
-		e.logger.Error("序列化DNS记录失败", zap.String("domain", domain), zap.Error(err))
-		return fmt.Errorf("序列化DNS记录失败: %w", err)
-	}
-
-	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
-	defer cancel()
-
-	_, err = e.client.Put(ctx, key, string(recordJSON))
-	if err != nil {
-		e.logger.Error("保存DNS记录到etcd失败", zap.String("key", key), zap.Error(err))
-		return fmt.Errorf("保存DNS记录到etcd失败: %w", err)
-	}
-
-	e.logger.Info("DNS记录保存成功",
-		zap.String("domain", domain),
-		zap.String("type", record.Type),
-		zap.String("value", record.Value))
-	return nil
+	_, err := e.PutDNSRecordAtRevision(ctx, domain, record, AnyRevision)
+	return err
 }
 
 // GetDNSRecordsForDomain 获取域名的所有DNS记录
 func (e *EtcdClient) GetDNSRecordsForDomain(ctx context.Context, domain string) (map[string]*DNSRecord, error) {
-	if e.client == nil {
+	if e.conn() == nil {
 		return nil, fmt.Errorf("etcd客户端未连接")
 	}
 
@@ -248,9 +729,9 @@ func (e *EtcdClient) GetDNSRecordsForDomain(ctx context.Context, domain string)
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
-	resp, err := e.client.Get(ctx, prefix, clientv3.WithPrefix())
+	resp, err := e.conn().Get(ctx, prefix, clientv3.WithPrefix())
 	if err != nil {
-		e.logger.Error("从etcd获取DNS记录失败", zap.String("prefix", prefix), zap.Error(err))
+		e.log(ctx).Error("从etcd获取DNS记录失败", zap.String("prefix", prefix), zap.Error(err))
 		return nil, fmt.Errorf("从etcd获取DNS记录失败: %w", err)
 	}
 
@@ -258,7 +739,7 @@ func (e *EtcdClient) GetDNSRecordsForDomain(ctx context.Context, domain string)
 	for _, kv := range resp.Kvs {
 		var record DNSRecord
 		if err := json.Unmarshal(kv.Value, &record); err != nil {
-			e.logger.Error("解析DNS记录失败", zap.String("key", string(kv.Key)), zap.Error(err))
+			e.log(ctx).Error("解析DNS记录失败", zap.String("key", string(kv.Key)), zap.Error(err))
 			continue
 		}
 
@@ -269,3 +750,8 @@ func (e *EtcdClient) GetDNSRecordsForDomain(ctx context.Context, domain string)
 
 	return records, nil
 }
+
+// log 返回附加了ctx中日志字段（如请求ID）的日志记录器
+func (e *EtcdClient) log(ctx context.Context) config.Logger {
+	return config.LoggerFromContext(ctx, e.logger)
+}
@@ -2,9 +2,11 @@ package etcdclient
 
 import (
 	"context"
+	"path/filepath"
 	"testing"
 	"time"
 
+	"github.com/hewenyu/kong-discovery/internal/config"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -62,6 +64,30 @@ func TestEtcdClient_Ping(t *testing.T) {
 	assert.NoError(t, err, "Ping etcd应该成功")
 }
 
+func TestEtcdClient_SQLiteStorage(t *testing.T) {
+	cfg := &config.Config{Storage: config.StorageSQLite}
+	cfg.SQL.DSN = filepath.Join(t.TempDir(), "kong-discovery.db")
+	logger := createTestLogger(t)
+
+	client := NewEtcdClient(cfg, logger)
+	require.NoError(t, client.Connect(), "打开SQLite存储应该成功")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, client.Ping(ctx))
+	record := &DNSRecord{Type: "A", Value: "10.0.0.1", TTL: 60}
+	require.NoError(t, client.PutDNSRecord(ctx, "sql.test.", record))
+	require.NoError(t, client.Close(), "关闭SQLite存储应该成功")
+
+	// 重新打开后数据仍然存在
+	client = NewEtcdClient(cfg, logger)
+	require.NoError(t, client.Connect())
+	defer client.Close()
+	got, err := client.GetDNSRecord(ctx, "sql.test.", "A")
+	require.NoError(t, err)
+	assert.Equal(t, record.Value, got.Value)
+}
+
 func TestEtcdClient_GetAndGetWithPrefix(t *testing.T) {
 	// 跳过集成测试，除非明确要求运行
 	if testing.Short() {
@@ -216,6 +242,7 @@ func TestEtcdClient_ServiceOperations(t *testing.T) {
 	assert.NoError(t, err, "将服务转换为DNS记录应该成功")
 	assert.NotEmpty(t, dnsRecords, "应该返回至少一条DNS记录")
 	assert.Contains(t, dnsRecords, "A", "返回的记录中应该包含A记录")
+	assert.Equal(t, defaultServiceDNSTTL, dnsRecords["A"].TTL, "未设置DNS TTL时应使用默认值")
 
 	// 测试DeregisterService
 	err = client.DeregisterService(ctx, testService.ServiceName, testService.InstanceID)
@@ -369,3 +396,61 @@ func TestEtcdClient_RefreshServiceLease(t *testing.T) {
 	err = client.DeregisterService(ctx, testService.ServiceName, testService.InstanceID)
 	assert.NoError(t, err, "注销服务实例应该成功")
 }
+
+func TestEtcdClient_DNSRecordInView(t *testing.T) {
+	// 跳过集成测试，除非明确要求运行
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	// 创建etcd客户端并连接
+	client := CreateEtcdClientForTest(t)
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	testDomain := "view.example.com"
+
+	// 默认视图记录
+	err := client.PutDNSRecord(ctx, testDomain, &DNSRecord{Type: "A", Value: "203.0.113.10", TTL: 300})
+	require.NoError(t, err)
+
+	// 内部视图记录
+	err = client.PutDNSRecord(ctx, testDomain, &DNSRecord{Type: "A", Value: "10.0.0.10", TTL: 300, View: "internal"})
+	require.NoError(t, err)
+
+	record, err := client.GetDNSRecordInView(ctx, "internal", testDomain, "A")
+	require.NoError(t, err)
+	assert.Equal(t, "10.0.0.10", record.Value)
+
+	// 视图中没有记录时回退到默认记录
+	record, err = client.GetDNSRecordInView(ctx, "dmz", testDomain, "A")
+	require.NoError(t, err)
+	assert.Equal(t, "203.0.113.10", record.Value)
+
+	record, err = client.GetDNSRecordInView(ctx, "", testDomain, "A")
+	require.NoError(t, err)
+	assert.Equal(t, "203.0.113.10", record.Value)
+}
+
+func TestEtcdClient_ServiceDNSTTL(t *testing.T) {
+	cfg := &config.Config{}
+	client := &EtcdClient{cfg: cfg}
+
+	// 未设置任何TTL时使用内置默认值
+	instances := []*ServiceInstance{{InstanceID: "a"}, {InstanceID: "b"}}
+	assert.Equal(t, defaultServiceDNSTTL, client.serviceDNSTTL(instances, 0))
+
+	// 使用配置中的服务默认TTL
+	cfg.DNS.ServiceTTL = 15
+	assert.Equal(t, 15, client.serviceDNSTTL(instances, 0))
+
+	// TTL策略的默认TTL优先于配置
+	assert.Equal(t, 90, client.serviceDNSTTL(instances, 90))
+
+	// 实例设置的DNS TTL优先，取最小值
+	instances[0].DNSTTL = 45
+	instances[1].DNSTTL = 20
+	assert.Equal(t, 20, client.serviceDNSTTL(instances, 90))
+}
@@ -0,0 +1,174 @@
+package etcdclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.uber.org/zap"
+)
+
+// ServiceDiscovery 面向服务消费方的查询结果
+type ServiceDiscovery struct {
+	Namespace   string             `json:"namespace"`             // 命名空间
+	ServiceName string             `json:"service_name"`          // 服务名称
+	Registered  int                `json:"registered"`            // 命名空间中已注册的实例总数
+	Instances   []*ServiceInstance `json:"instances"`             // 可用的实例，按实例ID排序
+	Maintenance *MaintenanceInfo   `json:"maintenance,omitempty"` // 服务维护状态，维护中时实例列表为空
+	Index       int64              `json:"index"`                 // 读取时的etcd修订版本，用于等待后续变更
+}
+
+// DiscoverService 查询命名空间中服务的可用实例
+// 实例和维护状态在同一个etcd修订版本中读取，返回的Index可以传给WaitServiceChange等待之后的变更
+func (e *EtcdClient) DiscoverService(ctx context.Context, namespace, serviceName string) (*ServiceDiscovery, error) {
+	if e.conn() == nil {
+		return nil, fmt.Errorf("etcd客户端未连接")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, etcdTimeout)
+	defer cancel()
+
+	resp, err := e.conn().Txn(ctx).Then(
+		clientv3.OpGet(getServicePrefix(serviceName), clientv3.WithPrefix()),
+		clientv3.OpGet(getMaintenanceKey(serviceName)),
+	).Commit()
+	if err != nil {
+		e.log(ctx).Error("查询服务实例失败", zap.String("service", serviceName), zap.Error(err))
+		return nil, fmt.Errorf("查询服务实例失败: %w", err)
+	}
+
+	discovery := &ServiceDiscovery{
+		Namespace:   NamespaceOf(namespace),
+		ServiceName: serviceName,
+		Instances:   []*ServiceInstance{},
+		Index:       resp.Header.Revision,
+	}
+
+	for _, kv := range resp.Responses[0].GetResponseRange().Kvs {
+		var instance ServiceInstance
+		if err := json.Unmarshal(kv.Value, &instance); err != nil {
+			e.log(ctx).Warn("解析服务实例数据失败", zap.String("key", string(kv.Key)), zap.Error(err))
+			continue
+		}
+		if NamespaceOf(instance.Namespace) != discovery.Namespace {
+			continue
+		}
+		discovery.Registered++
+		if instance.Routable() {
+			discovery.Instances = append(discovery.Instances, &instance)
+		}
+	}
+
+	if kvs := resp.Responses[1].GetResponseRange().Kvs; len(kvs) > 0 {
+		var info MaintenanceInfo
+		if err := json.Unmarshal(kvs[0].Value, &info); err != nil {
+			return nil, fmt.Errorf("解析服务维护状态失败: %w", err)
+		}
+		discovery.Maintenance = &info
+		// 与DNS应答保持一致，维护中的服务不返回实例
+		discovery.Instances = []*ServiceInstance{}
+	}
+
+	sort.Slice(discovery.Instances, func(i, j int) bool {
+		return discovery.Instances[i].InstanceID < discovery.Instances[j].InstanceID
+	})
+	return discovery, nil
+}
+
+// WaitServiceChange 阻塞等待服务实例或维护状态在index之后发生变更
+// 发生变更时返回nil，ctx超时或取消时返回ctx的错误；
+// index之后已经发生的变更会立即返回，历史版本已被压缩时同样视为发生了变更
+func (e *EtcdClient) WaitServiceChange(ctx context.Context, serviceName string, index int64) error {
+	return e.waitForChange(ctx, index,
+		watchTarget{key: getServicePrefix(serviceName), prefix: true},
+		watchTarget{key: getMaintenanceKey(serviceName)},
+	)
+}
+
+// WaitServicesChange 阻塞等待任意服务的实例或维护状态在index之后发生变更，语义与WaitServiceChange相同
+func (e *EtcdClient) WaitServicesChange(ctx context.Context, index int64) error {
+	return e.waitForChange(ctx, index,
+		watchTarget{key: "/services/", prefix: true},
+		watchTarget{key: maintenancePrefix, prefix: true},
+	)
+}
+
+// Revision 返回etcd当前的修订版本
+// 在读取数据之前获取，作为阻塞查询的index时不会漏掉读取期间发生的变更
+func (e *EtcdClient) Revision(ctx context.Context) (int64, error) {
+	if e.conn() == nil {
+		return 0, fmt.Errorf("etcd客户端未连接")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, etcdTimeout)
+	defer cancel()
+
+	resp, err := e.conn().Get(ctx, "/", clientv3.WithCountOnly())
+	if err != nil {
+		return 0, fmt.Errorf("获取etcd修订版本失败: %w", err)
+	}
+	return resp.Header.Revision, nil
+}
+
+// watchTarget 要监听的键或前缀
+type watchTarget struct {
+	key    string
+	prefix bool
+}
+
+// waitForChange 阻塞等待任一目标在index之后发生变更
+func (e *EtcdClient) waitForChange(ctx context.Context, index int64, targets ...watchTarget) error {
+	if e.conn() == nil {
+		return fmt.Errorf("etcd客户端未连接")
+	}
+
+	watchCtx, cancel := context.WithCancel(clientv3.WithRequireLeader(ctx))
+	defer cancel()
+
+	// 所有监听的响应汇总到同一个通道
+	responses := make(chan clientv3.WatchResponse)
+	closed := make(chan struct{}, len(targets))
+	for _, target := range targets {
+		opts := []clientv3.OpOption{clientv3.WithRev(index + 1)}
+		if target.prefix {
+			opts = append(opts, clientv3.WithPrefix())
+		}
+		watchCh := e.conn().Watch(watchCtx, target.key, opts...)
+		go func() {
+			for resp := range watchCh {
+				select {
+				case responses <- resp:
+				case <-watchCtx.Done():
+					return
+				}
+			}
+			closed <- struct{}{}
+		}()
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-closed:
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return fmt.Errorf("监听变更中断")
+		case resp := <-responses:
+			if err := resp.Err(); err != nil {
+				if errors.Is(err, rpctypes.ErrCompacted) {
+					return nil
+				}
+				return fmt.Errorf("监听变更失败: %w", err)
+			}
+			if len(resp.Events) > 0 {
+				return nil
+			}
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package etcdclient
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEtcdClient_DiscoverService(t *testing.T) {
+	// 跳过集成测试，除非明确要求运行
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	client := CreateEtcdClientForTest(t)
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	serviceName := fmt.Sprintf("discover-service-%d", time.Now().UnixNano())
+	for _, id := range []string{"instance-002", "instance-001", "instance-003"} {
+		err := client.RegisterService(ctx, &ServiceInstance{
+			ServiceName: serviceName,
+			InstanceID:  id,
+			IPAddress:   "10.0.0.1",
+			Port:        8080,
+			TTL:         30,
+			Metadata:    map[string]string{"zone": "a"},
+		})
+		require.NoError(t, err)
+		defer func(id string) { _ = client.DeregisterService(context.Background(), serviceName, id) }(id)
+	}
+	_, err := client.SetInstanceHealth(ctx, serviceName, "instance-003", HealthCritical)
+	require.NoError(t, err)
+
+	// 只返回健康的实例，按实例ID排序
+	discovery, err := client.DiscoverService(ctx, "", serviceName)
+	require.NoError(t, err)
+	assert.Equal(t, DefaultNamespace, discovery.Namespace)
+	assert.Equal(t, 3, discovery.Registered)
+	require.Len(t, discovery.Instances, 2)
+	assert.Equal(t, "instance-001", discovery.Instances[0].InstanceID)
+	assert.Equal(t, "a", discovery.Instances[0].Metadata["zone"])
+	assert.Greater(t, discovery.Index, int64(0))
+
+	// 其他命名空间中看不到该服务
+	other, err := client.DiscoverService(ctx, "other", serviceName)
+	require.NoError(t, err)
+	assert.Equal(t, 0, other.Registered)
+	assert.Empty(t, other.Instances)
+
+	// 维护中的服务不返回实例
+	require.NoError(t, client.SetServiceMaintenance(ctx, serviceName, true, "升级"))
+	defer func() { _ = client.SetServiceMaintenance(context.Background(), serviceName, false, "") }()
+	discovery, err = client.DiscoverService(ctx, "", serviceName)
+	require.NoError(t, err)
+	require.NotNil(t, discovery.Maintenance)
+	assert.Empty(t, discovery.Instances)
+}
+
+func TestEtcdClient_WaitServiceChange(t *testing.T) {
+	// 跳过集成测试，除非明确要求运行
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	client := CreateEtcdClientForTest(t)
+	// 注册的实例通过t.Cleanup清理，客户端需要在它们之后关闭
+	t.Cleanup(func() { _ = client.Close() })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	serviceName := fmt.Sprintf("wait-service-%d", time.Now().UnixNano())
+	register := func(id string) {
+		require.NoError(t, client.RegisterService(ctx, &ServiceInstance{
+			ServiceName: serviceName, InstanceID: id, IPAddress: "10.0.0.1", Port: 8080, TTL: 30,
+		}))
+		t.Cleanup(func() { _ = client.DeregisterService(context.Background(), serviceName, id) })
+	}
+
+	register("instance-001")
+	discovery, err := client.DiscoverService(ctx, "", serviceName)
+	require.NoError(t, err)
+
+	// 没有变更时等待到超时
+	waitCtx, waitCancel := context.WithTimeout(ctx, 200*time.Millisecond)
+	err = client.WaitServiceChange(waitCtx, serviceName, discovery.Index)
+	waitCancel()
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// index之后已经发生的变更立即返回
+	register("instance-002")
+	require.NoError(t, client.WaitServiceChange(ctx, serviceName, discovery.Index))
+
+	// 等待期间的变更唤醒等待者，维护状态的变更同样有效
+	discovery, err = client.DiscoverService(ctx, "", serviceName)
+	require.NoError(t, err)
+	done := make(chan error, 1)
+	go func() { done <- client.WaitServiceChange(ctx, serviceName, discovery.Index) }()
+	time.Sleep(100 * time.Millisecond)
+	require.NoError(t, client.SetServiceMaintenance(ctx, serviceName, true, ""))
+	defer func() { _ = client.SetServiceMaintenance(context.Background(), serviceName, false, "") }()
+
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(3 * time.Second):
+		t.Fatal("维护状态变更后等待者应被唤醒")
+	}
+}
@@ -0,0 +1,258 @@
+package etcdclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrDNSRecordNotFound DNS记录不存在
+	ErrDNSRecordNotFound = errors.New("DNS记录不存在")
+
+	// ErrRevisionConflict 记录已被其他请求修改，版本号不一致
+	ErrRevisionConflict = errors.New("记录版本冲突")
+)
+
+// AnyRevision 表示写入时不校验记录版本
+const AnyRevision int64 = -1
+
+// DNSRecordEntry 带域名和版本号的DNS记录，用于列表展示和并发控制
+type DNSRecordEntry struct {
+	Domain   string `json:"domain"`   // 域名
+	Revision int64  `json:"revision"` // etcd中的修改版本号，用作ETag
+	DNSRecord
+}
+
+// parseDNSRecordKey 从etcd键中解析域名
+// 支持 /dns/records/<domain>/<type> 和 /dns/views/<view>/<domain>/<type> 两种格式
+func parseDNSRecordKey(key string) (domain string, ok bool) {
+	var parts []string
+	switch {
+	case strings.HasPrefix(key, "/dns/records/"):
+		parts = strings.Split(strings.TrimPrefix(key, "/dns/records/"), "/")
+		if len(parts) != 2 {
+			return "", false
+		}
+		return parts[0], true
+	case strings.HasPrefix(key, "/dns/views/"):
+		parts = strings.Split(strings.TrimPrefix(key, "/dns/views/"), "/")
+		if len(parts) != 3 {
+			return "", false
+		}
+		return parts[1], true
+	default:
+		return "", false
+	}
+}
+
+// ListDNSRecords 列出所有DNS记录（包括各视图中的记录），按域名和类型排序
+func (e *EtcdClient) ListDNSRecords(ctx context.Context) ([]*DNSRecordEntry, error) {
+	if e.conn() == nil {
+		return nil, fmt.Errorf("etcd客户端未连接")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, etcdTimeout)
+	defer cancel()
+
+	var entries []*DNSRecordEntry
+	for _, prefix := range []string{"/dns/records/", "/dns/views/"} {
+		resp, err := e.conn().Get(ctx, prefix, clientv3.WithPrefix())
+		if err != nil {
+			e.log(ctx).Error("获取DNS记录失败", zap.String("prefix", prefix), zap.Error(err))
+			return nil, fmt.Errorf("获取DNS记录失败: %w", err)
+		}
+
+		for _, kv := range resp.Kvs {
+			domain, ok := parseDNSRecordKey(string(kv.Key))
+			if !ok {
+				continue
+			}
+
+			var record DNSRecord
+			if err := json.Unmarshal(kv.Value, &record); err != nil {
+				e.log(ctx).Warn("解析DNS记录失败", zap.String("key", string(kv.Key)), zap.Error(err))
+				continue
+			}
+			entries = append(entries, &DNSRecordEntry{Domain: domain, Revision: kv.ModRevision, DNSRecord: record})
+		}
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Domain != entries[j].Domain {
+			return entries[i].Domain < entries[j].Domain
+		}
+		if entries[i].Type != entries[j].Type {
+			return entries[i].Type < entries[j].Type
+		}
+		return entries[i].View < entries[j].View
+	})
+
+	return entries, nil
+}
+
+// GetDNSRecordEntry 精确读取一条DNS记录及其版本号，view为空时读取默认视图，不做视图回退
+func (e *EtcdClient) GetDNSRecordEntry(ctx context.Context, domain, recordType, view string) (*DNSRecordEntry, error) {
+	if e.conn() == nil {
+		return nil, fmt.Errorf("etcd客户端未连接")
+	}
+
+	key := recordKey(domain, &DNSRecord{Type: recordType, View: view})
+
+	ctx, cancel := context.WithTimeout(ctx, etcdTimeout)
+	defer cancel()
+
+	resp, err := e.conn().Get(ctx, key)
+	if err != nil {
+		e.log(ctx).Error("获取DNS记录失败", zap.String("key", key), zap.Error(err))
+		return nil, fmt.Errorf("获取DNS记录失败: %w", err)
+	}
+	if len(resp.Kvs) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrDNSRecordNotFound, key)
+	}
+
+	var record DNSRecord
+	if err := json.Unmarshal(resp.Kvs[0].Value, &record); err != nil {
+		e.log(ctx).Error("解析DNS记录失败", zap.String("key", key), zap.Error(err))
+		return nil, fmt.Errorf("解析DNS记录失败: %w", err)
+	}
+
+	return &DNSRecordEntry{Domain: domain, Revision: resp.Kvs[0].ModRevision, DNSRecord: record}, nil
+}
+
+// PutDNSRecordAtRevision 使用etcd事务比较并写入DNS记录，返回写入后的版本号
+// revision为AnyRevision时不校验；为0时要求记录不存在；大于0时要求记录当前版本与之相等，
+// 否则返回ErrRevisionConflict。已有记录的所有者与新记录不同时返回ErrRecordOwned，
+// 需要覆盖时通过批量操作的force选项强制写入
+func (e *EtcdClient) PutDNSRecordAtRevision(ctx context.Context, domain string, record *DNSRecord, revision int64) (int64, error) {
+	if e.conn() == nil {
+		return 0, fmt.Errorf("etcd客户端未连接")
+	}
+
+	key := recordKey(domain, record)
+
+	// 校验命名空间配额
+	if err := e.checkDNSRecordQuota(ctx, key, record); err != nil {
+		e.log(ctx).Warn("DNS记录超出命名空间配额", zap.String("domain", domain), zap.Error(err))
+		return 0, err
+	}
+
+	recordJSON, err := json.Marshal(record)
+	if err != nil {
+		e.log(ctx).Error("序列化DNS记录失败", zap.String("domain", domain), zap.Error(err))
+		return 0, fmt.Errorf("序列化DNS记录失败: %w", err)
+	}
+
+	var written int64
+	err = retryTxn(ctx, func() error {
+		existing, modRevision, err := e.ownedRecord(ctx, key)
+		if err != nil {
+			return err
+		}
+		if err := checkRecordOwner(key, existing, record); err != nil {
+			return err
+		}
+
+		// 所有权校验读取的状态必须在提交时仍然成立
+		cmps := []clientv3.Cmp{ownerGuard(key, modRevision)}
+		switch {
+		case revision == 0:
+			cmps = append(cmps, clientv3.Compare(clientv3.CreateRevision(key), "=", 0))
+		case revision > 0:
+			cmps = append(cmps, clientv3.Compare(clientv3.ModRevision(key), "=", revision))
+		}
+
+		ctx, cancel := context.WithTimeout(ctx, etcdTimeout)
+		defer cancel()
+
+		resp, err := e.conn().Txn(ctx).
+			If(cmps...).
+			Then(clientv3.OpPut(key, string(recordJSON))).
+			Commit()
+		if err != nil {
+			return fmt.Errorf("保存DNS记录到etcd失败: %w", err)
+		}
+		if !resp.Succeeded {
+			if revision != AnyRevision {
+				return fmt.Errorf("%w: %s", ErrRevisionConflict, key)
+			}
+			// 读取之后记录被其他请求修改，重新校验所有权
+			return errTxnConflict
+		}
+		written = resp.Header.Revision
+		return nil
+	})
+	switch {
+	case errors.Is(err, ErrRevisionConflict):
+		e.log(ctx).Warn("DNS记录版本冲突", zap.String("key", key), zap.Int64("revision", revision))
+		return 0, err
+	case errors.Is(err, ErrRecordOwned):
+		e.log(ctx).Warn("DNS记录由其他所有者维护", zap.String("key", key), zap.Stringer("owner", record.Owner))
+		return 0, err
+	case err != nil:
+		e.log(ctx).Error("保存DNS记录到etcd失败", zap.String("key", key), zap.Error(err))
+		return 0, err
+	}
+
+	e.log(ctx).Info("DNS记录保存成功",
+		zap.String("domain", domain),
+		zap.String("type", record.Type),
+		zap.String("value", record.Value))
+	return written, nil
+}
+
+// DeleteDNSRecord 删除DNS记录，view为空时删除默认视图中的记录
+// 记录有所有者时返回ErrRecordOwned，这类记录由所有者的清理流程删除，或通过批量操作的force选项强制删除
+func (e *EtcdClient) DeleteDNSRecord(ctx context.Context, domain, recordType, view string) error {
+	if e.conn() == nil {
+		return fmt.Errorf("etcd客户端未连接")
+	}
+
+	key := recordKey(domain, &DNSRecord{Type: recordType, View: view})
+
+	err := retryTxn(ctx, func() error {
+		existing, modRevision, err := e.ownedRecord(ctx, key)
+		if err != nil {
+			return err
+		}
+		if existing == nil {
+			return fmt.Errorf("%w: %s", ErrDNSRecordNotFound, key)
+		}
+		if err := checkRecordOwner(key, existing, nil); err != nil {
+			return err
+		}
+
+		ctx, cancel := context.WithTimeout(ctx, etcdTimeout)
+		defer cancel()
+
+		resp, err := e.conn().Txn(ctx).
+			If(ownerGuard(key, modRevision)).
+			Then(clientv3.OpDelete(key)).
+			Commit()
+		if err != nil {
+			return fmt.Errorf("删除DNS记录失败: %w", err)
+		}
+		if !resp.Succeeded {
+			return errTxnConflict
+		}
+		return nil
+	})
+	if err != nil {
+		if !errors.Is(err, ErrDNSRecordNotFound) && !errors.Is(err, ErrRecordOwned) {
+			e.log(ctx).Error("删除DNS记录失败", zap.String("key", key), zap.Error(err))
+		}
+		return err
+	}
+
+	e.log(ctx).Info("DNS记录删除成功",
+		zap.String("domain", domain),
+		zap.String("type", recordType),
+		zap.String("view", view))
+	return nil
+}
@@ -0,0 +1,98 @@
+package etcdclient
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDNSRecordKey(t *testing.T) {
+	domain, ok := parseDNSRecordKey("/dns/records/api.example.com/A")
+	assert.True(t, ok)
+	assert.Equal(t, "api.example.com", domain)
+
+	domain, ok = parseDNSRecordKey("/dns/views/internal/api.example.com/A")
+	assert.True(t, ok)
+	assert.Equal(t, "api.example.com", domain)
+
+	_, ok = parseDNSRecordKey("/services/order/1")
+	assert.False(t, ok)
+}
+
+func TestEtcdClient_ListAndDeleteDNSRecords(t *testing.T) {
+	// 跳过集成测试，除非明确要求运行
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	client := CreateEtcdClientForTest(t)
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	domain := fmt.Sprintf("list-%d.example.com", time.Now().UnixNano())
+	require.NoError(t, client.PutDNSRecord(ctx, domain, &DNSRecord{Type: "A", Value: "10.0.0.1"}))
+	require.NoError(t, client.PutDNSRecord(ctx, domain, &DNSRecord{Type: "A", Value: "10.0.0.2", View: "internal"}))
+
+	records, err := client.ListDNSRecords(ctx)
+	require.NoError(t, err)
+	var found []*DNSRecordEntry
+	for _, record := range records {
+		if record.Domain == domain {
+			found = append(found, record)
+		}
+	}
+	require.Len(t, found, 2)
+	assert.Equal(t, "", found[0].View)
+	assert.Equal(t, "internal", found[1].View)
+
+	require.NoError(t, client.DeleteDNSRecord(ctx, domain, "A", "internal"))
+	require.NoError(t, client.DeleteDNSRecord(ctx, domain, "A", ""))
+	assert.Error(t, client.DeleteDNSRecord(ctx, domain, "A", ""), "删除不存在的记录应返回错误")
+}
+
+func TestEtcdClient_PutDNSRecordAtRevision(t *testing.T) {
+	// 跳过集成测试，除非明确要求运行
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	client := CreateEtcdClientForTest(t)
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	domain := fmt.Sprintf("cas-%d.example.com", time.Now().UnixNano())
+	defer func() { _ = client.DeleteDNSRecord(ctx, domain, "A", "") }()
+
+	// revision为0时只允许创建
+	rev, err := client.PutDNSRecordAtRevision(ctx, domain, &DNSRecord{Type: "A", Value: "10.0.0.1"}, 0)
+	require.NoError(t, err)
+	_, err = client.PutDNSRecordAtRevision(ctx, domain, &DNSRecord{Type: "A", Value: "10.0.0.2"}, 0)
+	assert.ErrorIs(t, err, ErrRevisionConflict)
+
+	entry, err := client.GetDNSRecordEntry(ctx, domain, "A", "")
+	require.NoError(t, err)
+	assert.Equal(t, rev, entry.Revision)
+	assert.Equal(t, "10.0.0.1", entry.Value)
+
+	// 版本一致时写入成功，旧版本写入失败
+	newRev, err := client.PutDNSRecordAtRevision(ctx, domain, &DNSRecord{Type: "A", Value: "10.0.0.3"}, rev)
+	require.NoError(t, err)
+	assert.Greater(t, newRev, rev)
+	_, err = client.PutDNSRecordAtRevision(ctx, domain, &DNSRecord{Type: "A", Value: "10.0.0.4"}, rev)
+	assert.ErrorIs(t, err, ErrRevisionConflict)
+
+	// AnyRevision不校验版本
+	_, err = client.PutDNSRecordAtRevision(ctx, domain, &DNSRecord{Type: "A", Value: "10.0.0.5"}, AnyRevision)
+	require.NoError(t, err)
+
+	_, err = client.GetDNSRecordEntry(ctx, domain, "AAAA", "")
+	assert.ErrorIs(t, err, ErrDNSRecordNotFound)
+}
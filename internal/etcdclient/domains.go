@@ -0,0 +1,95 @@
+package etcdclient
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.uber.org/zap"
+)
+
+// DNSDomainQuery 按前缀分页列出DNS域名的查询条件
+type DNSDomainQuery struct {
+	Prefix string // 域名前缀，为空时列出所有域名
+	View   string // 视图名称，为空时列出默认视图中的域名
+	Cursor string // 上一页返回的NextCursor，为空时从第一页开始
+	Limit  int    // 每页最多返回的域名数量
+}
+
+// DNSDomainSummary 一个域名及其下的记录类型
+type DNSDomainSummary struct {
+	Domain string   `json:"domain"` // 域名
+	Types  []string `json:"types"`  // 域名下的记录类型，按字典序排列
+}
+
+// DNSDomainPage 一页域名列表
+type DNSDomainPage struct {
+	Domains    []*DNSDomainSummary `json:"domains"`               // 本页的域名
+	NextCursor string              `json:"next_cursor,omitempty"` // 下一页的游标，为空表示没有更多域名
+}
+
+// ListDNSDomains 按etcd键的顺序分页列出域名，前缀过滤和分页都通过范围查询在存储端完成，
+// 只读取键不读取记录内容。翻页时的各次读取使用第一次读取的修订版本，保证同一次列表内结果一致
+func (e *EtcdClient) ListDNSDomains(ctx context.Context, query DNSDomainQuery) (*DNSDomainPage, error) {
+	if e.conn() == nil {
+		return nil, fmt.Errorf("etcd客户端未连接")
+	}
+	if query.Limit <= 0 {
+		return nil, fmt.Errorf("limit必须大于0")
+	}
+
+	base := "/dns/records/"
+	if query.View != "" {
+		base = "/dns/views/" + query.View + "/"
+	}
+	start := base + query.Prefix
+	end := clientv3.GetPrefixRangeEnd(start)
+	if query.Cursor != "" {
+		// 游标域名的键都以"<cursor>/"开头，从"<cursor>0"开始即跳过游标域名本身（'0'是'/'的下一个字符）
+		if from := base + query.Cursor + "0"; from > start {
+			start = from
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, etcdTimeout)
+	defer cancel()
+
+	page := &DNSDomainPage{Domains: []*DNSDomainSummary{}}
+	var current *DNSDomainSummary
+	var rev int64
+	for start < end {
+		opts := []clientv3.OpOption{clientv3.WithRange(end), clientv3.WithKeysOnly(), clientv3.WithLimit(int64(query.Limit) + 1)}
+		if rev > 0 {
+			opts = append(opts, clientv3.WithRev(rev))
+		}
+		resp, err := e.conn().Get(ctx, start, opts...)
+		if err != nil {
+			e.log(ctx).Error("获取DNS域名失败", zap.String("prefix", query.Prefix), zap.Error(err))
+			return nil, fmt.Errorf("获取DNS域名失败: %w", err)
+		}
+		rev = resp.Header.Revision
+
+		for _, kv := range resp.Kvs {
+			domain, recordType, ok := strings.Cut(strings.TrimPrefix(string(kv.Key), base), "/")
+			if !ok || strings.Contains(recordType, "/") {
+				continue
+			}
+			if current == nil || current.Domain != domain {
+				if len(page.Domains) == query.Limit {
+					page.NextCursor = current.Domain
+					return page, nil
+				}
+				current = &DNSDomainSummary{Domain: domain}
+				page.Domains = append(page.Domains, current)
+			}
+			current.Types = append(current.Types, recordType)
+		}
+
+		if !resp.More || len(resp.Kvs) == 0 {
+			break
+		}
+		start = string(resp.Kvs[len(resp.Kvs)-1].Key) + "\x00"
+	}
+	return page, nil
+}
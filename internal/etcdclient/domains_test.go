@@ -0,0 +1,73 @@
+package etcdclient
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEtcdClient_ListDNSDomains(t *testing.T) {
+	// 跳过集成测试，除非明确要求运行
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	client := CreateEtcdClientForTest(t)
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	prefix := fmt.Sprintf("domains-%d", time.Now().UnixNano())
+	records := []struct {
+		domain string
+		record *DNSRecord
+	}{
+		{prefix + "-a.example.com", &DNSRecord{Type: "A", Value: "10.0.0.1"}},
+		{prefix + "-a.example.com", &DNSRecord{Type: "TXT", Value: "v=1"}},
+		{prefix + "-b.example.com", &DNSRecord{Type: "A", Value: "10.0.0.2"}},
+		{prefix + "-c.example.com", &DNSRecord{Type: "AAAA", Value: "::1"}},
+		{prefix + "-c.example.com", &DNSRecord{Type: "A", Value: "10.0.0.3", View: "internal"}},
+		{"other-" + prefix + ".example.com", &DNSRecord{Type: "A", Value: "10.0.0.4"}},
+	}
+	for _, r := range records {
+		require.NoError(t, client.PutDNSRecord(ctx, r.domain, r.record))
+	}
+	defer func() {
+		for _, r := range records {
+			_ = client.DeleteDNSRecord(context.Background(), r.domain, r.record.Type, r.record.View)
+		}
+	}()
+
+	// 按前缀分页
+	page, err := client.ListDNSDomains(ctx, DNSDomainQuery{Prefix: prefix, Limit: 2})
+	require.NoError(t, err)
+	require.Len(t, page.Domains, 2)
+	assert.Equal(t, &DNSDomainSummary{Domain: prefix + "-a.example.com", Types: []string{"A", "TXT"}}, page.Domains[0])
+	assert.Equal(t, prefix+"-b.example.com", page.Domains[1].Domain)
+	assert.Equal(t, prefix+"-b.example.com", page.NextCursor)
+
+	page, err = client.ListDNSDomains(ctx, DNSDomainQuery{Prefix: prefix, Limit: 2, Cursor: page.NextCursor})
+	require.NoError(t, err)
+	require.Len(t, page.Domains, 1)
+	assert.Equal(t, &DNSDomainSummary{Domain: prefix + "-c.example.com", Types: []string{"AAAA"}}, page.Domains[0])
+	assert.Empty(t, page.NextCursor)
+
+	// 视图中的域名
+	page, err = client.ListDNSDomains(ctx, DNSDomainQuery{Prefix: prefix, View: "internal", Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, page.Domains, 1)
+	assert.Equal(t, prefix+"-c.example.com", page.Domains[0].Domain)
+
+	// 没有匹配的域名
+	page, err = client.ListDNSDomains(ctx, DNSDomainQuery{Prefix: prefix + "-z", Limit: 10})
+	require.NoError(t, err)
+	assert.Empty(t, page.Domains)
+
+	_, err = client.ListDNSDomains(ctx, DNSDomainQuery{Prefix: prefix})
+	assert.Error(t, err, "limit为0应返回错误")
+}
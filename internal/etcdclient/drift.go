@@ -0,0 +1,213 @@
+package etcdclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.uber.org/zap"
+)
+
+// 数据不一致的类别
+const (
+	DriftCorruptInstance   = "corrupt_instance"    // 实例数据无法解析或与键不匹配，对所有查询不可见
+	DriftCorruptDNSRecord  = "corrupt_dns_record"  // DNS记录无法解析，对所有查询不可见
+	DriftOrphanedDNSRecord = "orphaned_dns_record" // 由服务维护的记录，但服务已没有任何实例
+	DriftMissingNamespace  = "missing_namespace"   // 实例或记录所属的命名空间已不存在（级联删除中途失败）
+	DriftUnleasedInstance  = "unleased_instance"   // 实例没有绑定租约，永远不会过期
+)
+
+// DriftFinding 一项数据不一致
+type DriftFinding struct {
+	Kind       string `json:"kind"`            // 不一致的类别
+	Key        string `json:"key"`             // 涉及的etcd键
+	Detail     string `json:"detail"`          // 说明
+	Repairable bool   `json:"repairable"`      // 是否可以自动修复
+	Repaired   bool   `json:"repaired"`        // 本次是否已修复
+	Error      string `json:"error,omitempty"` // 修复失败的原因
+
+	modRevision int64  // 检查时键的修改版本，修复时键已变化则放弃
+	guardPrefix string // 修复时要求该前缀下没有任何键
+}
+
+// DriftReport 一次数据一致性检查的结果
+type DriftReport struct {
+	Revision  int64           `json:"revision"`   // 检查时的etcd修订版本
+	CheckedAt time.Time       `json:"checked_at"` // 检查时间
+	DryRun    bool            `json:"dry_run"`    // 是否只检查不修复
+	Findings  []*DriftFinding `json:"findings"`   // 发现的不一致
+	Repaired  int             `json:"repaired"`   // 修复的数量
+	Deferred  int             `json:"deferred"`   // 超过单次修复数量上限、留到下一次检查修复的数量
+}
+
+// DriftOptions 数据一致性检查的选项
+type DriftOptions struct {
+	DryRun     bool // 只检查不修复
+	MaxRepairs int  // 最多修复的数量，0表示不限制
+}
+
+// Counts 按类别统计不一致的数量
+func (r *DriftReport) Counts() map[string]int {
+	counts := make(map[string]int)
+	for _, finding := range r.Findings {
+		counts[finding.Kind]++
+	}
+	return counts
+}
+
+// RepairedCounts 按类别统计本次修复的数量
+func (r *DriftReport) RepairedCounts() map[string]int {
+	counts := make(map[string]int)
+	for _, finding := range r.Findings {
+		if finding.Repaired {
+			counts[finding.Kind]++
+		}
+	}
+	return counts
+}
+
+// ReconcileDrift 检查服务实例、DNS记录和命名空间之间的数据一致性，不是dry-run时修复可以修复的项，
+// 设置了MaxRepairs时超出的部分留到下一次检查
+// 所有数据在同一个etcd修订版本中读取；修复时逐项校验键的版本，检查之后被修改过的键不会被误删
+func (e *EtcdClient) ReconcileDrift(ctx context.Context, opts DriftOptions) (*DriftReport, error) {
+	dryRun := opts.DryRun
+	if e.conn() == nil {
+		return nil, fmt.Errorf("etcd客户端未连接")
+	}
+
+	readCtx, cancel := context.WithTimeout(ctx, etcdTimeout)
+	resp, err := e.conn().Txn(readCtx).Then(
+		clientv3.OpGet("/services/", clientv3.WithPrefix()),
+		clientv3.OpGet("/dns/records/", clientv3.WithPrefix()),
+		clientv3.OpGet("/dns/views/", clientv3.WithPrefix()),
+		clientv3.OpGet(namespacePrefix, clientv3.WithPrefix(), clientv3.WithKeysOnly()),
+	).Commit()
+	cancel()
+	if err != nil {
+		e.log(ctx).Error("读取一致性检查数据失败", zap.Error(err))
+		return nil, fmt.Errorf("读取一致性检查数据失败: %w", err)
+	}
+
+	report := &DriftReport{
+		Revision:  resp.Header.Revision,
+		CheckedAt: time.Now(),
+		DryRun:    dryRun,
+		Findings:  []*DriftFinding{},
+	}
+
+	namespaces := map[string]bool{DefaultNamespace: true}
+	for _, kv := range resp.Responses[3].GetResponseRange().Kvs {
+		namespaces[strings.TrimPrefix(string(kv.Key), namespacePrefix)] = true
+	}
+
+	add := func(kind, key string, modRevision int64, repairable bool, format string, args ...interface{}) *DriftFinding {
+		finding := &DriftFinding{
+			Kind:        kind,
+			Key:         key,
+			Detail:      fmt.Sprintf(format, args...),
+			Repairable:  repairable,
+			modRevision: modRevision,
+		}
+		report.Findings = append(report.Findings, finding)
+		return finding
+	}
+
+	services := make(map[string]bool)
+	for _, kv := range resp.Responses[0].GetResponseRange().Kvs {
+		key := string(kv.Key)
+		var instance ServiceInstance
+		if err := json.Unmarshal(kv.Value, &instance); err != nil {
+			add(DriftCorruptInstance, key, kv.ModRevision, true, "无法解析实例数据: %v", err)
+			continue
+		}
+		if expected := getServiceInstanceKey(instance.ServiceName, instance.InstanceID); expected != key {
+			add(DriftCorruptInstance, key, kv.ModRevision, true, "实例数据与键不匹配，应为 %s", expected)
+			continue
+		}
+		if ns := NamespaceOf(instance.Namespace); !namespaces[ns] {
+			add(DriftMissingNamespace, key, kv.ModRevision, true, "实例所属的命名空间 %s 不存在", ns)
+			continue
+		}
+		if kv.Lease == 0 {
+			add(DriftUnleasedInstance, key, kv.ModRevision, false, "实例没有绑定租约，不会随心跳中断过期")
+		}
+		services[instance.ServiceName] = true
+	}
+
+	for _, r := range resp.Responses[1:3] {
+		for _, kv := range r.GetResponseRange().Kvs {
+			key := string(kv.Key)
+			domain, ok := parseDNSRecordKey(key)
+			if !ok {
+				continue
+			}
+			var record DNSRecord
+			if err := json.Unmarshal(kv.Value, &record); err != nil {
+				add(DriftCorruptDNSRecord, key, kv.ModRevision, true, "无法解析DNS记录: %v", err)
+				continue
+			}
+			if ns := NamespaceOf(record.Namespace); !namespaces[ns] {
+				add(DriftMissingNamespace, key, kv.ModRevision, true, "记录所属的命名空间 %s 不存在", ns)
+				continue
+			}
+			if name, ok := ServiceNameFromDomain(domain); ok && !services[name] && record.OwnedByService(name) {
+				finding := add(DriftOrphanedDNSRecord, key, kv.ModRevision, true, "服务 %s 已没有任何实例", name)
+				finding.guardPrefix = getServicePrefix(name)
+			}
+		}
+	}
+
+	if !dryRun {
+		for _, finding := range report.Findings {
+			if !finding.Repairable || ctx.Err() != nil {
+				continue
+			}
+			if opts.MaxRepairs > 0 && report.Repaired >= opts.MaxRepairs {
+				report.Deferred++
+				continue
+			}
+			if err := e.repairDrift(ctx, finding); err != nil {
+				finding.Error = err.Error()
+				continue
+			}
+			finding.Repaired = true
+			report.Repaired++
+		}
+	}
+
+	if len(report.Findings) > 0 {
+		e.log(ctx).Info("数据一致性检查完成",
+			zap.Int64("revision", report.Revision),
+			zap.Int("findings", len(report.Findings)),
+			zap.Int("repaired", report.Repaired),
+			zap.Int("deferred", report.Deferred),
+			zap.Bool("dry_run", dryRun))
+	}
+	return report, nil
+}
+
+// repairDrift 删除不一致的键，键在检查之后被修改过时放弃
+func (e *EtcdClient) repairDrift(ctx context.Context, finding *DriftFinding) error {
+	ctx, cancel := context.WithTimeout(ctx, etcdTimeout)
+	defer cancel()
+
+	cmps := []clientv3.Cmp{clientv3.Compare(clientv3.ModRevision(finding.Key), "=", finding.modRevision)}
+	if finding.guardPrefix != "" {
+		cmps = append(cmps, clientv3.Compare(clientv3.CreateRevision(finding.guardPrefix), "=", 0).WithPrefix())
+	}
+
+	resp, err := e.conn().Txn(ctx).If(cmps...).Then(clientv3.OpDelete(finding.Key)).Commit()
+	if err != nil {
+		e.log(ctx).Error("修复数据不一致失败", zap.String("key", finding.Key), zap.Error(err))
+		return fmt.Errorf("修复数据不一致失败: %w", err)
+	}
+	if !resp.Succeeded {
+		return fmt.Errorf("%w: 键在检查之后已被修改", ErrRevisionConflict)
+	}
+
+	e.log(ctx).Info("已修复数据不一致", zap.String("kind", finding.Kind), zap.String("key", finding.Key))
+	return nil
+}
@@ -0,0 +1,114 @@
+package etcdclient
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEtcdClient_ReconcileDrift(t *testing.T) {
+	// 跳过集成测试，除非明确要求运行
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	client := CreateEtcdClientForTest(t)
+	defer client.Close()
+	raw := client.(*EtcdClient).conn()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	suffix := time.Now().UnixNano()
+	serviceName := fmt.Sprintf("drift-service-%d", suffix)
+
+	// 构造几类不一致：无法解析的实例、命名空间已删除的实例、没有实例的服务记录、没有租约的实例
+	corruptKey := getServiceInstanceKey(serviceName, "corrupt")
+	ghostKey := getServiceInstanceKey(serviceName+"-ghost", "instance-001")
+	unleasedKey := getServiceInstanceKey(serviceName+"-unleased", "instance-001")
+	orphanDomain := serviceName + ".default.svc.cluster.local"
+	orphanKey := getDNSRecordKey(orphanDomain, "A")
+
+	_, err := raw.Put(ctx, corruptKey, "not-json")
+	require.NoError(t, err)
+	_, err = raw.Put(ctx, ghostKey, fmt.Sprintf(`{"service_name":"%s-ghost","instance_id":"instance-001","namespace":"ghost-%d"}`, serviceName, suffix))
+	require.NoError(t, err)
+	_, err = raw.Put(ctx, unleasedKey, fmt.Sprintf(`{"service_name":"%s-unleased","instance_id":"instance-001"}`, serviceName))
+	require.NoError(t, err)
+	require.NoError(t, client.PutDNSRecord(ctx, orphanDomain, &DNSRecord{Type: "A", Value: "10.0.0.1", Owner: ServiceOwner(serviceName)}))
+	defer func() {
+		for _, key := range []string{corruptKey, ghostKey, unleasedKey, orphanKey} {
+			_, _ = raw.Delete(context.Background(), key)
+		}
+	}()
+
+	findings := func(report *DriftReport) map[string]*DriftFinding {
+		result := make(map[string]*DriftFinding)
+		for _, finding := range report.Findings {
+			result[finding.Key] = finding
+		}
+		return result
+	}
+
+	// dry-run只报告，不修改数据
+	report, err := client.ReconcileDrift(ctx, DriftOptions{DryRun: true})
+	require.NoError(t, err)
+	assert.True(t, report.DryRun)
+	found := findings(report)
+	require.Contains(t, found, corruptKey)
+	assert.Equal(t, DriftCorruptInstance, found[corruptKey].Kind)
+	require.Contains(t, found, ghostKey)
+	assert.Equal(t, DriftMissingNamespace, found[ghostKey].Kind)
+	require.Contains(t, found, unleasedKey)
+	assert.Equal(t, DriftUnleasedInstance, found[unleasedKey].Kind)
+	assert.False(t, found[unleasedKey].Repairable)
+	require.Contains(t, found, orphanKey)
+	assert.Equal(t, DriftOrphanedDNSRecord, found[orphanKey].Kind)
+	for _, finding := range found {
+		assert.False(t, finding.Repaired)
+	}
+	assert.GreaterOrEqual(t, report.Counts()[DriftCorruptInstance], 1)
+
+	exists, err := client.(*EtcdClient).keyExists(ctx, corruptKey)
+	require.NoError(t, err)
+	assert.True(t, exists, "dry-run不应删除数据")
+
+	// 限制单次修复的数量时，其余可修复的项留到下一次检查
+	repairable := 0
+	for _, finding := range report.Findings {
+		if finding.Repairable {
+			repairable++
+		}
+	}
+	report, err = client.ReconcileDrift(ctx, DriftOptions{MaxRepairs: 1})
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.Repaired)
+	assert.Equal(t, repairable-1, report.Deferred)
+	repaired := 0
+	for _, n := range report.RepairedCounts() {
+		repaired += n
+	}
+	assert.Equal(t, 1, repaired)
+
+	// 修复可以修复的项，没有租约的实例只报告
+	report, err = client.ReconcileDrift(ctx, DriftOptions{})
+	require.NoError(t, err)
+	found = findings(report)
+	for _, key := range []string{corruptKey, ghostKey, orphanKey} {
+		exists, err := client.(*EtcdClient).keyExists(ctx, key)
+		require.NoError(t, err)
+		assert.False(t, exists, key)
+	}
+	assert.False(t, found[unleasedKey].Repaired)
+
+	// 修复后再次检查不再报告
+	report, err = client.ReconcileDrift(ctx, DriftOptions{DryRun: true})
+	require.NoError(t, err)
+	found = findings(report)
+	assert.NotContains(t, found, corruptKey)
+	assert.NotContains(t, found, orphanKey)
+}
@@ -0,0 +1,188 @@
+package etcdclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// ChangePlan 破坏性操作在dry-run模式下报告的影响范围，生成时不做任何修改
+type ChangePlan struct {
+	Instances []*ServiceInstance `json:"instances"`       // 将被删除的服务实例
+	Records   []*DNSRecordEntry  `json:"records"`         // 将被删除的DNS记录，包括服务没有实例后由清理流程删除的记录
+	Zones     []*NamespaceZone   `json:"zones,omitempty"` // 将被删除的自定义区域
+	Services  []string           `json:"services"`        // 因此没有任何实例的服务
+	Aliases   []*ServiceAlias    `json:"aliases"`         // 目标服务因此没有任何实例的别名
+}
+
+// newChangePlan 返回空的影响范围，列表字段序列化为空数组而不是null
+func newChangePlan() *ChangePlan {
+	return &ChangePlan{
+		Instances: []*ServiceInstance{},
+		Records:   []*DNSRecordEntry{},
+		Services:  []string{},
+		Aliases:   []*ServiceAlias{},
+	}
+}
+
+// PlanDeregister 报告注销服务实例的影响范围，实例不存在时返回空的影响范围
+// 服务因此没有任何实例时，由服务维护的DNS记录会被清理（强制驱逐时立即清理，普通注销时由后台协调任务清理）
+func (e *EtcdClient) PlanDeregister(ctx context.Context, serviceName, instanceID string) (*ChangePlan, error) {
+	if e.conn() == nil {
+		return nil, fmt.Errorf("etcd客户端未连接")
+	}
+
+	plan := newChangePlan()
+
+	getCtx, cancel := context.WithTimeout(ctx, etcdTimeout)
+	resp, err := e.conn().Get(getCtx, getServiceInstanceKey(serviceName, instanceID))
+	cancel()
+	if err != nil {
+		return nil, fmt.Errorf("获取服务实例数据失败: %w", err)
+	}
+	if len(resp.Kvs) == 0 {
+		return plan, nil
+	}
+
+	var instance ServiceInstance
+	if err := json.Unmarshal(resp.Kvs[0].Value, &instance); err != nil {
+		// 无法解析的实例同样会被删除，按键中的名称报告
+		instance = ServiceInstance{ServiceName: serviceName, InstanceID: instanceID}
+	}
+	plan.Instances = append(plan.Instances, &instance)
+
+	if err := e.planDependents(ctx, plan); err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+// PlanDeleteDNSRecord 报告删除DNS记录的影响范围，记录不存在时返回ErrDNSRecordNotFound，
+// 记录有所有者且force为false时返回ErrRecordOwned，与实际删除的结果一致
+func (e *EtcdClient) PlanDeleteDNSRecord(ctx context.Context, domain, recordType, view string, force bool) (*ChangePlan, error) {
+	entry, err := e.GetDNSRecordEntry(ctx, domain, recordType, view)
+	if err != nil {
+		return nil, err
+	}
+	if !force {
+		if err := checkRecordOwner(recordKey(domain, &entry.DNSRecord), &entry.DNSRecord, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	plan := newChangePlan()
+	plan.Records = append(plan.Records, entry)
+	return plan, nil
+}
+
+// PlanDeleteNamespace 报告删除命名空间的影响范围，校验规则与DeleteNamespace相同
+func (e *EtcdClient) PlanDeleteNamespace(ctx context.Context, name string, cascade bool) (*ChangePlan, error) {
+	if e.conn() == nil {
+		return nil, fmt.Errorf("etcd客户端未连接")
+	}
+
+	instances, recordKeys, err := e.checkNamespaceDeletable(ctx, name, cascade)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := newChangePlan()
+	sort.Slice(instances, func(i, j int) bool {
+		if instances[i].ServiceName != instances[j].ServiceName {
+			return instances[i].ServiceName < instances[j].ServiceName
+		}
+		return instances[i].InstanceID < instances[j].InstanceID
+	})
+	plan.Instances = append(plan.Instances, instances...)
+
+	if len(recordKeys) > 0 {
+		removed := make(map[string]bool, len(recordKeys))
+		for _, key := range recordKeys {
+			removed[key] = true
+		}
+		entries, err := e.ListDNSRecords(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			if removed[recordKey(entry.Domain, &entry.DNSRecord)] {
+				plan.Records = append(plan.Records, entry)
+			}
+		}
+	}
+
+	zones, err := e.ListNamespaceZones(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, zone := range zones {
+		if zone.Namespace == name {
+			plan.Zones = append(plan.Zones, zone)
+		}
+	}
+
+	if err := e.planDependents(ctx, plan); err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+// planDependents 根据将被删除的实例找出因此没有任何实例的服务，
+// 补充这些服务由清理流程删除的DNS记录和指向它们的别名
+func (e *EtcdClient) planDependents(ctx context.Context, plan *ChangePlan) error {
+	removing := make(map[string]int)
+	for _, instance := range plan.Instances {
+		removing[instance.ServiceName]++
+	}
+
+	emptied := make(map[string]bool)
+	for serviceName, count := range removing {
+		getCtx, cancel := context.WithTimeout(ctx, etcdTimeout)
+		resp, err := e.conn().Get(getCtx, getServicePrefix(serviceName), clientv3.WithPrefix(), clientv3.WithCountOnly())
+		cancel()
+		if err != nil {
+			return fmt.Errorf("获取服务实例数据失败: %w", err)
+		}
+		if resp.Count <= int64(count) {
+			emptied[serviceName] = true
+			plan.Services = append(plan.Services, serviceName)
+		}
+	}
+	if len(emptied) == 0 {
+		return nil
+	}
+	sort.Strings(plan.Services)
+
+	entries, err := e.ListDNSRecords(ctx)
+	if err != nil {
+		return err
+	}
+	planned := make(map[string]bool, len(plan.Records))
+	for _, entry := range plan.Records {
+		planned[recordKey(entry.Domain, &entry.DNSRecord)] = true
+	}
+	for _, entry := range entries {
+		name, ok := ServiceNameFromDomain(entry.Domain)
+		if !ok || !emptied[name] || !entry.OwnedByService(name) || planned[recordKey(entry.Domain, &entry.DNSRecord)] {
+			continue
+		}
+		plan.Records = append(plan.Records, entry)
+	}
+
+	aliases, err := e.ListServiceAliases(ctx)
+	if err != nil {
+		return err
+	}
+	for _, alias := range aliases {
+		for _, target := range alias.Targets() {
+			if emptied[target] {
+				plan.Aliases = append(plan.Aliases, alias)
+				break
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package etcdclient
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEtcdClient_PlanDeregister(t *testing.T) {
+	// 跳过集成测试，除非明确要求运行
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	client := CreateEtcdClientForTest(t)
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	serviceName := fmt.Sprintf("plan-service-%d", time.Now().UnixNano())
+	domain := serviceName + ".default.svc.cluster.local"
+	for _, id := range []string{"plan-001", "plan-002"} {
+		require.NoError(t, client.RegisterService(ctx, &ServiceInstance{ServiceName: serviceName, InstanceID: id, IPAddress: "10.0.0.1", Port: 8080, TTL: 30}))
+	}
+	require.NoError(t, client.PutDNSRecord(ctx, domain, &DNSRecord{Type: "TXT", Value: "owned", Owner: ServiceOwner(serviceName)}))
+	require.NoError(t, client.PutServiceAlias(ctx, &ServiceAlias{ServiceName: serviceName + "-alias", Target: serviceName}))
+	defer func() {
+		_ = client.DeregisterService(context.Background(), serviceName, "plan-001")
+		_ = client.DeregisterService(context.Background(), serviceName, "plan-002")
+		_ = client.DeleteServiceAlias(context.Background(), serviceName+"-alias")
+		_, _ = client.ApplyDNSRecordBatch(context.Background(), []*DNSRecordOperation{
+			{Op: DNSRecordOpDelete, Domain: domain, Type: "TXT", Force: true},
+		})
+	}()
+
+	// 服务还有其他实例时只影响实例本身
+	plan, err := client.PlanDeregister(ctx, serviceName, "plan-001")
+	require.NoError(t, err)
+	require.Len(t, plan.Instances, 1)
+	assert.Equal(t, "plan-001", plan.Instances[0].InstanceID)
+	assert.Empty(t, plan.Services)
+	assert.Empty(t, plan.Records)
+	assert.Empty(t, plan.Aliases)
+
+	// 注销最后一个实例时报告服务维护的记录和指向服务的别名
+	require.NoError(t, client.DeregisterService(ctx, serviceName, "plan-002"))
+	plan, err = client.PlanDeregister(ctx, serviceName, "plan-001")
+	require.NoError(t, err)
+	assert.Equal(t, []string{serviceName}, plan.Services)
+	require.Len(t, plan.Records, 1)
+	assert.Equal(t, "TXT", plan.Records[0].Type)
+	require.Len(t, plan.Aliases, 1)
+	assert.Equal(t, serviceName+"-alias", plan.Aliases[0].ServiceName)
+
+	// dry-run不做修改
+	instances, err := client.GetServiceInstances(ctx, serviceName)
+	require.NoError(t, err)
+	assert.Len(t, instances, 1)
+
+	plan, err = client.PlanDeregister(ctx, serviceName, "missing")
+	require.NoError(t, err)
+	assert.Empty(t, plan.Instances)
+
+	// 删除记录的影响范围与实际删除的校验一致
+	_, err = client.PlanDeleteDNSRecord(ctx, domain, "TXT", "", false)
+	assert.ErrorIs(t, err, ErrRecordOwned)
+	plan, err = client.PlanDeleteDNSRecord(ctx, domain, "TXT", "", true)
+	require.NoError(t, err)
+	assert.Len(t, plan.Records, 1)
+	_, err = client.PlanDeleteDNSRecord(ctx, domain, "A", "", true)
+	assert.ErrorIs(t, err, ErrDNSRecordNotFound)
+}
+
+func TestEtcdClient_PlanDeleteNamespace(t *testing.T) {
+	// 跳过集成测试，除非明确要求运行
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	client := CreateEtcdClientForTest(t)
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	suffix := time.Now().UnixNano()
+	namespace := fmt.Sprintf("plan-ns-%d", suffix)
+	serviceName := fmt.Sprintf("plan-ns-service-%d", suffix)
+	zone := fmt.Sprintf("plan-%d.internal", suffix)
+	require.NoError(t, client.CreateNamespace(ctx, &Namespace{Name: namespace}))
+	defer func() { _ = client.DeleteNamespace(context.Background(), namespace, true) }()
+	require.NoError(t, client.RegisterService(ctx, &ServiceInstance{
+		ServiceName: serviceName, InstanceID: "plan-001", Namespace: namespace, IPAddress: "10.0.0.1", Port: 8080, TTL: 30,
+	}))
+	require.NoError(t, client.PutDNSRecord(ctx, "manual."+zone, &DNSRecord{Type: "A", Value: "10.0.0.2", Namespace: namespace}))
+	require.NoError(t, client.PutNamespaceZone(ctx, &NamespaceZone{Zone: zone, Namespace: namespace}))
+
+	_, err := client.PlanDeleteNamespace(ctx, namespace, false)
+	assert.ErrorIs(t, err, ErrNamespaceNotEmpty)
+
+	plan, err := client.PlanDeleteNamespace(ctx, namespace, true)
+	require.NoError(t, err)
+	require.Len(t, plan.Instances, 1)
+	assert.Equal(t, serviceName, plan.Instances[0].ServiceName)
+	require.Len(t, plan.Records, 1)
+	assert.Equal(t, "manual."+zone, plan.Records[0].Domain)
+	require.Len(t, plan.Zones, 1)
+	assert.Equal(t, zone, plan.Zones[0].Zone)
+	assert.Equal(t, []string{serviceName}, plan.Services)
+
+	// dry-run不做修改
+	ns, err := client.GetNamespace(ctx, namespace)
+	require.NoError(t, err)
+	assert.NotNil(t, ns)
+
+	_, err = client.PlanDeleteNamespace(ctx, "plan-missing-namespace", true)
+	assert.ErrorIs(t, err, ErrNamespaceNotFound)
+}
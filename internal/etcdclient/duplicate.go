@@ -0,0 +1,170 @@
+package etcdclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.uber.org/zap"
+)
+
+// 服务注册策略在etcd中的键前缀
+const registrationPolicyPrefix = "/config/registration/"
+
+// 相同IP:端口以不同实例ID重复注册时的处理方式
+const (
+	DuplicateAllow   = "allow"   // 不检测，允许多个实例使用相同的地址
+	DuplicateReplace = "replace" // 删除使用相同地址的旧实例，保留新注册的实例
+	DuplicateReject  = "reject"  // 拒绝注册
+)
+
+// ErrDuplicateAddress 服务中已有其他实例使用相同的IP和端口
+var ErrDuplicateAddress = errors.New("服务中已有实例使用相同的地址")
+
+// RegistrationPolicy 服务级别的注册策略
+type RegistrationPolicy struct {
+	ServiceName      string    `json:"service_name"`      // 服务名称
+	DuplicateAddress string    `json:"duplicate_address"` // 重复地址的处理方式：allow、replace或reject
+	UpdatedAt        time.Time `json:"updated_at"`        // 更新时间
+}
+
+// ValidDuplicatePolicy 判断重复地址的处理方式是否有效
+func ValidDuplicatePolicy(policy string) bool {
+	switch policy {
+	case DuplicateAllow, DuplicateReplace, DuplicateReject:
+		return true
+	}
+	return false
+}
+
+// getRegistrationPolicyKey 生成服务注册策略的etcd键
+func getRegistrationPolicyKey(serviceName string) string {
+	return registrationPolicyPrefix + serviceName
+}
+
+// GetRegistrationPolicy 获取服务的注册策略，未单独设置时返回nil
+func (e *EtcdClient) GetRegistrationPolicy(ctx context.Context, serviceName string) (*RegistrationPolicy, error) {
+	if e.conn() == nil {
+		return nil, fmt.Errorf("etcd客户端未连接")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, etcdTimeout)
+	defer cancel()
+
+	resp, err := e.conn().Get(ctx, getRegistrationPolicyKey(serviceName))
+	if err != nil {
+		return nil, fmt.Errorf("获取服务注册策略失败: %w", err)
+	}
+	if len(resp.Kvs) == 0 {
+		return nil, nil
+	}
+
+	var policy RegistrationPolicy
+	if err := json.Unmarshal(resp.Kvs[0].Value, &policy); err != nil {
+		return nil, fmt.Errorf("解析服务注册策略失败: %w", err)
+	}
+	return &policy, nil
+}
+
+// PutRegistrationPolicy 设置服务的注册策略
+func (e *EtcdClient) PutRegistrationPolicy(ctx context.Context, policy *RegistrationPolicy) error {
+	if e.conn() == nil {
+		return fmt.Errorf("etcd客户端未连接")
+	}
+	if policy.ServiceName == "" {
+		return fmt.Errorf("服务名称不能为空")
+	}
+	if !ValidDuplicatePolicy(policy.DuplicateAddress) {
+		return fmt.Errorf("无效的重复地址处理方式: %s", policy.DuplicateAddress)
+	}
+
+	policy.UpdatedAt = time.Now()
+	data, err := json.Marshal(policy)
+	if err != nil {
+		return fmt.Errorf("序列化服务注册策略失败: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, etcdTimeout)
+	defer cancel()
+
+	if _, err := e.conn().Put(ctx, getRegistrationPolicyKey(policy.ServiceName), string(data)); err != nil {
+		e.log(ctx).Error("保存服务注册策略失败", zap.String("service", policy.ServiceName), zap.Error(err))
+		return fmt.Errorf("保存服务注册策略失败: %w", err)
+	}
+
+	e.log(ctx).Info("服务注册策略已更新",
+		zap.String("service", policy.ServiceName),
+		zap.String("duplicate_address", policy.DuplicateAddress))
+	return nil
+}
+
+// DeleteRegistrationPolicy 删除服务的注册策略，之后使用全局默认策略
+func (e *EtcdClient) DeleteRegistrationPolicy(ctx context.Context, serviceName string) error {
+	if e.conn() == nil {
+		return fmt.Errorf("etcd客户端未连接")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, etcdTimeout)
+	defer cancel()
+
+	if _, err := e.conn().Delete(ctx, getRegistrationPolicyKey(serviceName)); err != nil {
+		return fmt.Errorf("删除服务注册策略失败: %w", err)
+	}
+	return nil
+}
+
+// duplicatePolicy 返回服务生效的重复地址处理方式：服务单独设置的策略优先，否则使用配置的默认值
+func (e *EtcdClient) duplicatePolicy(ctx context.Context, serviceName string) (string, error) {
+	policy, err := e.GetRegistrationPolicy(ctx, serviceName)
+	if err != nil {
+		return "", err
+	}
+	if policy != nil {
+		return policy.DuplicateAddress, nil
+	}
+	if e.cfg != nil && ValidDuplicatePolicy(e.cfg.API.Registration.DuplicateAddress) {
+		return e.cfg.API.Registration.DuplicateAddress, nil
+	}
+	return DuplicateAllow, nil
+}
+
+// findDuplicateInstances 查找服务中与instance使用相同IP和端口的其他实例
+func (e *EtcdClient) findDuplicateInstances(ctx context.Context, instance *ServiceInstance) ([]*storedInstance, error) {
+	ctx, cancel := context.WithTimeout(ctx, etcdTimeout)
+	defer cancel()
+
+	resp, err := e.conn().Get(ctx, getServicePrefix(instance.ServiceName), clientv3.WithPrefix())
+	if err != nil {
+		return nil, fmt.Errorf("查询服务实例失败: %w", err)
+	}
+
+	var duplicates []*storedInstance
+	for _, kv := range resp.Kvs {
+		var existing ServiceInstance
+		if err := json.Unmarshal(kv.Value, &existing); err != nil {
+			continue
+		}
+		if existing.InstanceID == instance.InstanceID ||
+			existing.IPAddress != instance.IPAddress || existing.Port != instance.Port {
+			continue
+		}
+		duplicates = append(duplicates, &storedInstance{
+			key:         string(kv.Key),
+			modRevision: kv.ModRevision,
+			lease:       clientv3.LeaseID(kv.Lease),
+			instance:    &existing,
+		})
+	}
+	return duplicates, nil
+}
+
+// storedInstance 服务实例及其在etcd中的键和租约
+type storedInstance struct {
+	key         string
+	modRevision int64 // 读取时的修订版本
+	lease       clientv3.LeaseID
+	instance    *ServiceInstance
+}
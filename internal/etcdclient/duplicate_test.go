@@ -0,0 +1,80 @@
+package etcdclient
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+func TestEtcdClient_RegisterService_DuplicateAddress(t *testing.T) {
+	// 跳过集成测试，除非明确要求运行
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	client := CreateEtcdClientForTest(t)
+	defer client.Close()
+	raw := client.(*EtcdClient).conn()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	service := fmt.Sprintf("dup-svc-%d", time.Now().UnixNano())
+	instance := func(id string) *ServiceInstance {
+		return &ServiceInstance{ServiceName: service, InstanceID: id, IPAddress: "10.0.0.1", Port: 8080, TTL: 30}
+	}
+	defer func() {
+		_, _ = raw.Delete(context.Background(), getServicePrefix(service), clientv3.WithPrefix())
+		_ = client.DeleteRegistrationPolicy(context.Background(), service)
+	}()
+
+	// 默认允许相同地址
+	require.NoError(t, client.RegisterService(ctx, instance("instance-001")))
+	require.NoError(t, client.RegisterService(ctx, instance("instance-002")))
+	instances, err := client.GetServiceInstances(ctx, service)
+	require.NoError(t, err)
+	assert.Len(t, instances, 2)
+	require.NoError(t, client.DeregisterService(ctx, service, "instance-002"))
+
+	// reject：相同地址的新实例被拒绝，同一实例重复注册不受影响
+	require.NoError(t, client.PutRegistrationPolicy(ctx, &RegistrationPolicy{ServiceName: service, DuplicateAddress: DuplicateReject}))
+	err = client.RegisterService(ctx, instance("instance-003"))
+	assert.ErrorIs(t, err, ErrDuplicateAddress)
+	assert.Contains(t, err.Error(), "instance-001")
+	require.NoError(t, client.RegisterService(ctx, instance("instance-001")))
+	other := instance("instance-004")
+	other.Port = 9090
+	require.NoError(t, client.RegisterService(ctx, other), "不同端口不是重复地址")
+
+	// replace：删除旧实例并撤销其租约
+	policy, err := client.GetRegistrationPolicy(ctx, service)
+	require.NoError(t, err)
+	assert.Equal(t, DuplicateReject, policy.DuplicateAddress)
+	require.NoError(t, client.PutRegistrationPolicy(ctx, &RegistrationPolicy{ServiceName: service, DuplicateAddress: DuplicateReplace}))
+
+	old, err := raw.Get(ctx, getServiceInstanceKey(service, "instance-001"))
+	require.NoError(t, err)
+	require.Len(t, old.Kvs, 1)
+	oldLease := clientv3.LeaseID(old.Kvs[0].Lease)
+
+	require.NoError(t, client.RegisterService(ctx, instance("instance-005")))
+	instances, err = client.GetServiceInstances(ctx, service)
+	require.NoError(t, err)
+	ids := make([]string, 0, len(instances))
+	for _, i := range instances {
+		ids = append(ids, i.InstanceID)
+	}
+	assert.ElementsMatch(t, []string{"instance-004", "instance-005"}, ids)
+
+	ttl, err := raw.TimeToLive(ctx, oldLease)
+	require.NoError(t, err)
+	assert.Equal(t, int64(-1), ttl.TTL, "被替换实例的租约应被撤销")
+
+	// 无效的策略
+	assert.Error(t, client.PutRegistrationPolicy(ctx, &RegistrationPolicy{ServiceName: service, DuplicateAddress: "ignore"}))
+}
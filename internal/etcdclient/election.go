@@ -0,0 +1,92 @@
+package etcdclient
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/hewenyu/kong-discovery/internal/config"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/client/v3/concurrency"
+	"go.uber.org/zap"
+)
+
+// electionPrefix 领导者选举在etcd中的键前缀
+const electionPrefix = "/election/"
+
+// leaderSessionTTL 领导者会话的租约TTL（秒），持有者失联后经过该时间其他节点才能接任
+const leaderSessionTTL = 10
+
+// Leadership 表示当前节点在某个选举中持有的领导权
+type Leadership struct {
+	session  *concurrency.Session
+	election *concurrency.Election
+	logger   config.Logger
+	name     string
+}
+
+// Done 在领导权丢失（会话过期或etcd连接中断）时关闭
+func (l *Leadership) Done() <-chan struct{} {
+	return l.session.Done()
+}
+
+// Resign 主动放弃领导权并释放会话
+func (l *Leadership) Resign(ctx context.Context) error {
+	defer l.session.Close()
+	if err := l.election.Resign(ctx); err != nil {
+		return fmt.Errorf("放弃领导权失败: %w", err)
+	}
+	l.logger.Info("已放弃领导权", zap.String("election", l.name))
+	return nil
+}
+
+// Campaign 参与名为name的选举，阻塞直到成为领导者或ctx被取消
+func (e *EtcdClient) Campaign(ctx context.Context, name, candidate string) (*Leadership, error) {
+	if e.conn() == nil {
+		return nil, fmt.Errorf("etcd客户端未连接")
+	}
+
+	session, err := concurrency.NewSession(e.conn(), concurrency.WithTTL(leaderSessionTTL), concurrency.WithContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("创建选举会话失败: %w", err)
+	}
+
+	election := concurrency.NewElection(session, electionPrefix+name)
+	if err := election.Campaign(ctx, candidate); err != nil {
+		session.Close()
+		return nil, fmt.Errorf("参与选举失败: %w", err)
+	}
+
+	e.log(ctx).Info("成为领导者", zap.String("election", name), zap.String("candidate", candidate))
+	return &Leadership{session: session, election: election, logger: e.logger, name: name}, nil
+}
+
+// ElectionLeaders 返回各选举当前的领导者，键为选举名称，值为领导者的候选者标识
+func (e *EtcdClient) ElectionLeaders(ctx context.Context) (map[string]string, error) {
+	if e.conn() == nil {
+		return nil, fmt.Errorf("etcd客户端未连接")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, etcdTimeout)
+	defer cancel()
+
+	resp, err := e.conn().Get(ctx, electionPrefix, clientv3.WithPrefix())
+	if err != nil {
+		return nil, fmt.Errorf("获取选举状态失败: %w", err)
+	}
+
+	// 每个候选者在 /election/<选举名称>/<租约ID> 下有一个键，创建版本最小的是领导者
+	leaders := make(map[string]string)
+	revisions := make(map[string]int64)
+	for _, kv := range resp.Kvs {
+		name, _, ok := strings.Cut(strings.TrimPrefix(string(kv.Key), electionPrefix), "/")
+		if !ok || name == "" {
+			continue
+		}
+		if rev, seen := revisions[name]; !seen || kv.CreateRevision < rev {
+			revisions[name] = kv.CreateRevision
+			leaders[name] = string(kv.Value)
+		}
+	}
+	return leaders, nil
+}
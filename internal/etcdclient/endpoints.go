@@ -0,0 +1,63 @@
+package etcdclient
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// etcdEndpoints 返回连接etcd使用的端点，运行时更新过时返回更新后的列表
+func (e *EtcdClient) etcdEndpoints() []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.endpoints != nil {
+		return slices.Clone(e.endpoints)
+	}
+	return e.cfg.Etcd.Endpoints
+}
+
+// autoSyncInterval 返回从etcd成员列表同步端点的间隔，为0时不同步
+func (e *EtcdClient) autoSyncInterval() time.Duration {
+	return time.Duration(e.cfg.Etcd.AutoSyncInterval) * time.Second
+}
+
+// Endpoints 返回当前连接使用的etcd端点，开启auto_sync_interval时包括从成员列表同步到的端点。
+// 使用进程内存储时返回nil
+func (e *EtcdClient) Endpoints() []string {
+	if e.inProcess() {
+		return nil
+	}
+	e.mu.Lock()
+	offline := e.offline
+	e.mu.Unlock()
+	if client := e.conn(); client != nil && !offline {
+		return client.Endpoints()
+	}
+	return e.etcdEndpoints()
+}
+
+// UpdateEndpoints 更新etcd端点列表并应用到当前连接，不重建连接：进行中的请求、watch和租约续期不受影响，
+// 之后的请求在新列表中负载均衡，到已移除端点的连接被关闭。存储不可用正在重试时，下一次重试使用新列表
+func (e *EtcdClient) UpdateEndpoints(endpoints []string) error {
+	if e.inProcess() {
+		return fmt.Errorf("storage为%s时没有etcd端点", e.cfg.Storage)
+	}
+	if len(endpoints) == 0 {
+		return fmt.Errorf("etcd端点列表不能为空")
+	}
+
+	endpoints = slices.Clone(endpoints)
+	e.mu.Lock()
+	e.endpoints = endpoints
+	offline := e.offline
+	e.mu.Unlock()
+
+	// 本地快照上的只读客户端没有端点；客户端保存传入的切片，传入副本
+	if client := e.conn(); client != nil && !offline {
+		client.SetEndpoints(slices.Clone(endpoints)...)
+	}
+	e.logger.Info("etcd端点已更新", zap.Strings("endpoints", endpoints))
+	return nil
+}
@@ -0,0 +1,43 @@
+package etcdclient
+
+import (
+	"testing"
+
+	"github.com/hewenyu/kong-discovery/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEtcdClient_UpdateEndpoints(t *testing.T) {
+	// 客户端不等待连接建立，端点不需要可以访问
+	cfg := &config.Config{}
+	cfg.Storage = config.StorageEtcd
+	cfg.Etcd.Endpoints = []string{"127.0.0.1:1"}
+	client := NewEtcdClient(cfg, createTestLogger(t)).(*EtcdClient)
+
+	// 连接前更新的端点在连接时使用
+	require.NoError(t, client.UpdateEndpoints([]string{"127.0.0.1:2"}))
+	require.NoError(t, client.Connect())
+	defer client.Close()
+	assert.Equal(t, []string{"127.0.0.1:2"}, client.conn().Endpoints())
+
+	// 更新应用到当前连接，不重建客户端
+	conn := client.conn()
+	endpoints := []string{"127.0.0.1:3", "127.0.0.1:4"}
+	require.NoError(t, client.UpdateEndpoints(endpoints))
+	endpoints[0] = "changed"
+	assert.Same(t, conn, client.conn())
+	assert.Equal(t, []string{"127.0.0.1:3", "127.0.0.1:4"}, client.Endpoints())
+	assert.Equal(t, []string{"127.0.0.1:1"}, cfg.Etcd.Endpoints, "不修改共享的配置")
+
+	assert.Error(t, client.UpdateEndpoints(nil))
+}
+
+func TestEtcdClient_UpdateEndpointsInProcess(t *testing.T) {
+	client := NewEtcdClient(&config.Config{Storage: config.StorageMemory}, createTestLogger(t))
+	require.NoError(t, client.Connect())
+	defer client.Close()
+
+	assert.Nil(t, client.Endpoints())
+	assert.ErrorContains(t, client.UpdateEndpoints([]string{"127.0.0.1:2379"}), "没有etcd端点")
+}
@@ -0,0 +1,49 @@
+package etcdclient
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+)
+
+// eventBusCheckpointKey 事件总线已发布修订版本的etcd键
+const eventBusCheckpointKey = "/eventbus/checkpoint"
+
+// GetEventBusCheckpoint 获取事件总线已发布的修订版本，尚未发布过时返回0
+func (e *EtcdClient) GetEventBusCheckpoint(ctx context.Context) (int64, error) {
+	if e.conn() == nil {
+		return 0, fmt.Errorf("etcd客户端未连接")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, etcdTimeout)
+	defer cancel()
+
+	resp, err := e.conn().Get(ctx, eventBusCheckpointKey)
+	if err != nil {
+		return 0, fmt.Errorf("获取事件总线检查点失败: %w", err)
+	}
+	if len(resp.Kvs) == 0 {
+		return 0, nil
+	}
+
+	revision, err := strconv.ParseInt(string(resp.Kvs[0].Value), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("解析事件总线检查点失败: %w", err)
+	}
+	return revision, nil
+}
+
+// PutEventBusCheckpoint 保存事件总线已发布的修订版本
+func (e *EtcdClient) PutEventBusCheckpoint(ctx context.Context, revision int64) error {
+	if e.conn() == nil {
+		return fmt.Errorf("etcd客户端未连接")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, etcdTimeout)
+	defer cancel()
+
+	if _, err := e.conn().Put(ctx, eventBusCheckpointKey, strconv.FormatInt(revision, 10)); err != nil {
+		return fmt.Errorf("保存事件总线检查点失败: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,222 @@
+package etcdclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.uber.org/zap"
+)
+
+// 事件类型
+const (
+	EventPut    = "put"    // 创建或更新
+	EventDelete = "delete" // 删除
+	EventResync = "resync" // 监听的版本已被压缩，期间的变更丢失，需要重新读取全量数据；Revision为继续监听前的当前版本
+)
+
+// Event 表示etcd中发现数据的一次变更
+type Event struct {
+	Type     string          `json:"type"`               // 事件类型 (put 或 delete)
+	Kind     string          `json:"kind"`               // 数据类别 (services, dns, config, namespaces)
+	Key      string          `json:"key"`                // 变更的etcd键
+	Value    json.RawMessage `json:"value,omitempty"`    // 变更后的值，删除事件为删除前的值
+	Previous json.RawMessage `json:"previous,omitempty"` // 更新事件变更前的值，新建时为空
+	Revision int64           `json:"revision"`           // etcd修订版本
+}
+
+// 需要关注变更的数据类别
+var eventKinds = []string{"services", "dns", "config", "namespaces"}
+
+// eventKind 根据etcd键返回数据类别，不关注的键返回空字符串
+func eventKind(key string) string {
+	for _, kind := range eventKinds {
+		if strings.HasPrefix(key, "/"+kind+"/") {
+			return kind
+		}
+	}
+	return ""
+}
+
+// WatchEvents 监听发现数据的变更，ctx取消后关闭返回的通道
+func (e *EtcdClient) WatchEvents(ctx context.Context) (<-chan *Event, error) {
+	return e.WatchEventsFrom(ctx, 0)
+}
+
+// 监听中断后重新建立的退避时间
+const (
+	watchRetryMin = time.Second
+	watchRetryMax = 30 * time.Second
+)
+
+// WatchStats 变更监听的统计，所有监听共享
+type WatchStats struct {
+	Restarts    int64     `json:"restarts"`             // 监听中断后从中断处重新建立的次数
+	Compactions int64     `json:"compactions"`          // 要监听的版本已被压缩，从当前版本重新开始的次数
+	LastRestart time.Time `json:"last_restart"`         // 最近一次重新建立监听的时间
+	LastError   string    `json:"last_error,omitempty"` // 最近一次中断的原因
+}
+
+// watchStats 变更监听的统计
+type watchStats struct {
+	mu    sync.Mutex
+	stats WatchStats
+}
+
+// record 记录一次重新建立监听
+func (w *watchStats) record(compacted bool, err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if compacted {
+		w.stats.Compactions++
+	} else {
+		w.stats.Restarts++
+	}
+	w.stats.LastRestart = time.Now()
+	w.stats.LastError = err.Error()
+}
+
+// WatchStats 返回变更监听的统计
+func (e *EtcdClient) WatchStats() WatchStats {
+	e.watches.mu.Lock()
+	defer e.watches.mu.Unlock()
+	return e.watches.stats
+}
+
+// WatchEventsFrom 从指定的修订版本（包含）开始监听发现数据的变更，revision为0时从当前开始。
+// 监听中断（失去leader、连接切换等）时从最后收到的版本之后重新建立，不丢失变更；要监听的版本已被压缩时
+// 期间的变更无法再读取，发送一个EventResync事件后从当前版本继续，消费方收到后应重新读取全量数据
+func (e *EtcdClient) WatchEventsFrom(ctx context.Context, revision int64) (<-chan *Event, error) {
+	if e.conn() == nil {
+		return nil, fmt.Errorf("etcd客户端未连接")
+	}
+
+	// 从当前开始时先取得当前版本，重新建立监听时才能从中断处继续
+	if revision <= 0 {
+		rev, err := e.Revision(ctx)
+		if err != nil {
+			return nil, err
+		}
+		revision = rev + 1
+	}
+
+	watch := func(rev int64) clientv3.WatchChan {
+		client := e.conn()
+		if client == nil {
+			closed := make(chan clientv3.WatchResponse)
+			close(closed)
+			return closed
+		}
+		return client.Watch(clientv3.WithRequireLeader(ctx), "/",
+			clientv3.WithPrefix(), clientv3.WithPrevKV(), clientv3.WithRev(rev))
+	}
+
+	events := make(chan *Event, 64)
+	next := revision // 下一个要接收的版本
+	watchCh := watch(next)
+
+	go func() {
+		defer close(events)
+
+		backoff := watchRetryMin
+		for {
+			resp, ok := <-watchCh
+			if ctx.Err() != nil {
+				return
+			}
+
+			var err error
+			switch {
+			case !ok:
+				err = fmt.Errorf("监听通道被关闭")
+			case resp.CompactRevision > 0 || errors.Is(resp.Err(), rpctypes.ErrCompacted):
+				rev, rerr := e.Revision(ctx)
+				if rerr != nil {
+					err = rerr
+					break
+				}
+				e.watches.record(true, fmt.Errorf("版本%d已被压缩到%d", next, resp.CompactRevision))
+				e.log(ctx).Warn("监听的版本已被压缩，期间的变更无法读取，从当前版本继续并通知重新读取全量数据",
+					zap.Int64("revision", next),
+					zap.Int64("compact_revision", resp.CompactRevision),
+					zap.Int64("current_revision", rev))
+				select {
+				case events <- &Event{Type: EventResync, Revision: rev}:
+				case <-ctx.Done():
+					return
+				}
+				next = rev + 1
+				backoff = watchRetryMin
+				watchCh = watch(next)
+				continue
+			default:
+				err = resp.Err()
+			}
+
+			if err != nil {
+				if e.conn() == nil {
+					e.log(ctx).Warn("etcd连接已关闭，停止监听变更")
+					return
+				}
+				e.watches.record(false, err)
+				e.log(ctx).Warn("监听etcd变更中断，稍后从中断处重新监听",
+					zap.Int64("revision", next),
+					zap.Duration("backoff", backoff),
+					zap.Error(err))
+				select {
+				case <-time.After(backoff):
+				case <-ctx.Done():
+					return
+				}
+				backoff = min(backoff*2, watchRetryMax)
+				watchCh = watch(next)
+				continue
+			}
+			backoff = watchRetryMin
+
+			for _, ev := range resp.Events {
+				next = ev.Kv.ModRevision + 1
+				key := string(ev.Kv.Key)
+				kind := eventKind(key)
+				if kind == "" {
+					continue
+				}
+
+				event := &Event{
+					Type:     EventPut,
+					Kind:     kind,
+					Key:      key,
+					Revision: ev.Kv.ModRevision,
+				}
+				value := ev.Kv.Value
+				if ev.Type == clientv3.EventTypeDelete {
+					event.Type = EventDelete
+					value = nil
+					if ev.PrevKv != nil {
+						value = ev.PrevKv.Value
+					}
+				}
+				if json.Valid(value) {
+					event.Value = json.RawMessage(value)
+				}
+				if event.Type == EventPut && ev.PrevKv != nil && json.Valid(ev.PrevKv.Value) {
+					event.Previous = json.RawMessage(ev.PrevKv.Value)
+				}
+
+				select {
+				case events <- event:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return events, nil
+}
@@ -0,0 +1,96 @@
+package etcdclient
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEtcdClient_WatchEventsFrom(t *testing.T) {
+	// 跳过集成测试，除非明确要求运行
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	client := CreateEtcdClientForTest(t)
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	domain := fmt.Sprintf("watch-from-%d.example.com", time.Now().UnixNano())
+	defer func() { _ = client.DeleteDNSRecord(context.Background(), domain, "A", "") }()
+
+	require.NoError(t, client.PutDNSRecord(ctx, domain, &DNSRecord{Type: "A", Value: "10.0.0.1", TTL: 60}))
+	entry, err := client.GetDNSRecordEntry(ctx, domain, "A", "")
+	require.NoError(t, err)
+
+	// 从已经发生的修订版本开始监听时先收到该版本的变更
+	events, err := client.WatchEventsFrom(ctx, entry.Revision)
+	require.NoError(t, err)
+	select {
+	case event := <-events:
+		assert.Equal(t, EventPut, event.Type)
+		assert.Equal(t, "dns", event.Kind)
+		assert.Equal(t, "/dns/records/"+domain+"/A", event.Key)
+		assert.Equal(t, entry.Revision, event.Revision)
+	case <-ctx.Done():
+		t.Fatal("等待变更事件超时")
+	}
+}
+
+func TestEtcdClient_WatchEventsCompacted(t *testing.T) {
+	// 跳过集成测试，除非明确要求运行
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	client := CreateEtcdClientForTest(t)
+	defer client.Close()
+	e := client.(*EtcdClient)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	domain := fmt.Sprintf("watch-compacted-%d.example.com", time.Now().UnixNano())
+	defer func() { _ = client.DeleteDNSRecord(context.Background(), domain, "A", "") }()
+
+	require.NoError(t, client.PutDNSRecord(ctx, domain, &DNSRecord{Type: "A", Value: "10.0.0.1", TTL: 60}))
+	first, err := client.GetDNSRecordEntry(ctx, domain, "A", "")
+	require.NoError(t, err)
+	require.NoError(t, client.PutDNSRecord(ctx, domain, &DNSRecord{Type: "A", Value: "10.0.0.2", TTL: 60}))
+	current, err := client.Revision(ctx)
+	require.NoError(t, err)
+	_, err = e.conn().Compact(ctx, current)
+	require.NoError(t, err)
+
+	// 起始版本已被压缩，先收到resync事件，之后从当前版本继续
+	before := client.WatchStats().Compactions
+	events, err := client.WatchEventsFrom(ctx, first.Revision)
+	require.NoError(t, err)
+	next := func() *Event {
+		select {
+		case event := <-events:
+			require.NotNil(t, event)
+			return event
+		case <-ctx.Done():
+			t.Fatal("等待变更事件超时")
+			return nil
+		}
+	}
+
+	event := next()
+	assert.Equal(t, EventResync, event.Type)
+	assert.GreaterOrEqual(t, event.Revision, current)
+	assert.Equal(t, before+1, client.WatchStats().Compactions)
+
+	require.NoError(t, client.PutDNSRecord(ctx, domain, &DNSRecord{Type: "A", Value: "10.0.0.3", TTL: 60}))
+	event = next()
+	assert.Equal(t, EventPut, event.Type)
+	assert.Equal(t, "/dns/records/"+domain+"/A", event.Key)
+	assert.Contains(t, string(event.Value), "10.0.0.3")
+}
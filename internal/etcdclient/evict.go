@@ -0,0 +1,202 @@
+package etcdclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.uber.org/zap"
+)
+
+// 驱逐记录在etcd中的键前缀，记录带有租约，冷却期结束后自动删除
+const evictionPrefix = "/evictions/"
+
+// ErrInstanceEvicted 实例在驱逐冷却期内重新注册
+var ErrInstanceEvicted = errors.New("实例已被强制驱逐，冷却期内不能重新注册")
+
+// Eviction 强制驱逐的记录，冷却期内相同服务和实例ID的注册会被拒绝
+type Eviction struct {
+	ServiceName string    `json:"service_name"`     // 服务名称
+	InstanceID  string    `json:"instance_id"`      // 实例ID
+	Reason      string    `json:"reason,omitempty"` // 驱逐的原因
+	EvictedAt   time.Time `json:"evicted_at"`       // 驱逐时间
+	ExpiresAt   time.Time `json:"expires_at"`       // 冷却期结束的时间
+}
+
+// getEvictionKey 生成实例驱逐记录的键
+func getEvictionKey(serviceName, instanceID string) string {
+	return evictionPrefix + serviceName + "/" + instanceID
+}
+
+// EvictInstance 强制驱逐服务实例：删除实例并撤销它的租约，cooldown大于0时写入驱逐记录，
+// 冷却期内相同实例ID的注册返回ErrInstanceEvicted。服务因此没有实例时同时清理服务域名下的DNS记录。
+// 实例不存在时返回ErrInstanceNotFound
+func (e *EtcdClient) EvictInstance(ctx context.Context, serviceName, instanceID string, cooldown time.Duration, reason string) (*Eviction, error) {
+	if e.conn() == nil {
+		return nil, fmt.Errorf("etcd客户端未连接")
+	}
+
+	key := getServiceInstanceKey(serviceName, instanceID)
+	now := time.Now()
+	eviction := &Eviction{
+		ServiceName: serviceName,
+		InstanceID:  instanceID,
+		Reason:      reason,
+		EvictedAt:   now,
+		ExpiresAt:   now.Add(cooldown),
+	}
+
+	// 驱逐记录的租约在冷却期结束后到期，所有重试共用同一个租约，驱逐失败时撤销
+	cooldownLease := clientv3.NoLease
+	if cooldown > 0 {
+		grantCtx, cancel := context.WithTimeout(ctx, etcdTimeout)
+		lease, err := e.conn().Grant(grantCtx, int64((cooldown+time.Second-1)/time.Second))
+		cancel()
+		if err != nil {
+			e.log(ctx).Error("创建etcd租约失败", zap.Error(err))
+			return nil, fmt.Errorf("创建etcd租约失败: %w", err)
+		}
+		cooldownLease = lease.ID
+	}
+	data, err := json.Marshal(eviction)
+	if err != nil {
+		return nil, fmt.Errorf("序列化驱逐记录失败: %w", err)
+	}
+
+	var instanceLease clientv3.LeaseID
+	err = retryTxn(ctx, func() error {
+		ctx, cancel := context.WithTimeout(ctx, etcdTimeout)
+		defer cancel()
+
+		resp, err := e.conn().Get(ctx, key)
+		if err != nil {
+			return fmt.Errorf("获取服务实例数据失败: %w", err)
+		}
+		if len(resp.Kvs) == 0 {
+			return fmt.Errorf("%w: %s/%s", ErrInstanceNotFound, serviceName, instanceID)
+		}
+
+		ops := []clientv3.Op{clientv3.OpDelete(key)}
+		if cooldownLease != clientv3.NoLease {
+			ops = append(ops, clientv3.OpPut(getEvictionKey(serviceName, instanceID), string(data), clientv3.WithLease(cooldownLease)))
+		}
+		txnResp, err := e.conn().Txn(ctx).
+			If(clientv3.Compare(clientv3.ModRevision(key), "=", resp.Kvs[0].ModRevision)).
+			Then(ops...).
+			Commit()
+		if err != nil {
+			return fmt.Errorf("驱逐服务实例失败: %w", err)
+		}
+		if !txnResp.Succeeded {
+			return errTxnConflict
+		}
+		instanceLease = clientv3.LeaseID(resp.Kvs[0].Lease)
+		return nil
+	})
+	if err != nil {
+		if cooldownLease != clientv3.NoLease {
+			e.revokeLease(cooldownLease)
+		}
+		if !errors.Is(err, ErrInstanceNotFound) {
+			e.log(ctx).Error("驱逐服务实例失败",
+				zap.String("service", serviceName),
+				zap.String("id", instanceID),
+				zap.Error(err))
+		}
+		return nil, err
+	}
+
+	// 实例的租约只绑定了这个实例，撤销后实例的心跳立即失败
+	if instanceLease != clientv3.NoLease {
+		e.revokeLease(instanceLease)
+	}
+
+	// 清理失败不影响驱逐结果，后台协调任务会清理残留的记录
+	if removed, err := e.CleanupServiceRecords(ctx, serviceName); err != nil {
+		e.log(ctx).Warn("清理服务DNS记录失败", zap.String("service", serviceName), zap.Error(err))
+	} else if removed > 0 {
+		e.log(ctx).Info("已清理服务DNS记录", zap.String("service", serviceName), zap.Int("records", removed))
+	}
+
+	e.log(ctx).Warn("服务实例已被强制驱逐",
+		zap.String("service", serviceName),
+		zap.String("id", instanceID),
+		zap.Duration("cooldown", cooldown),
+		zap.String("reason", reason))
+	return eviction, nil
+}
+
+// ListEvictions 列出仍在冷却期内的驱逐记录，按服务名和实例ID排序
+func (e *EtcdClient) ListEvictions(ctx context.Context) ([]*Eviction, error) {
+	if e.conn() == nil {
+		return nil, fmt.Errorf("etcd客户端未连接")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, etcdTimeout)
+	defer cancel()
+
+	resp, err := e.conn().Get(ctx, evictionPrefix, clientv3.WithPrefix())
+	if err != nil {
+		return nil, fmt.Errorf("获取驱逐记录失败: %w", err)
+	}
+
+	evictions := make([]*Eviction, 0, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		var eviction Eviction
+		if err := json.Unmarshal(kv.Value, &eviction); err != nil {
+			e.log(ctx).Warn("解析驱逐记录失败", zap.String("key", string(kv.Key)), zap.Error(err))
+			continue
+		}
+		evictions = append(evictions, &eviction)
+	}
+	sort.Slice(evictions, func(i, j int) bool {
+		if evictions[i].ServiceName != evictions[j].ServiceName {
+			return evictions[i].ServiceName < evictions[j].ServiceName
+		}
+		return evictions[i].InstanceID < evictions[j].InstanceID
+	})
+	return evictions, nil
+}
+
+// DeleteEviction 提前结束实例的驱逐冷却期，记录不存在时视为成功
+func (e *EtcdClient) DeleteEviction(ctx context.Context, serviceName, instanceID string) error {
+	if e.conn() == nil {
+		return fmt.Errorf("etcd客户端未连接")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, etcdTimeout)
+	defer cancel()
+
+	if _, err := e.conn().Delete(ctx, getEvictionKey(serviceName, instanceID)); err != nil {
+		return fmt.Errorf("删除驱逐记录失败: %w", err)
+	}
+
+	e.log(ctx).Info("驱逐冷却期已解除", zap.String("service", serviceName), zap.String("id", instanceID))
+	return nil
+}
+
+// checkEviction 在注册事务之前检查实例是否处于驱逐冷却期，返回事务需要的比较条件，
+// 保证检查之后到提交之前没有新的驱逐记录
+func (e *EtcdClient) checkEviction(ctx context.Context, instance *ServiceInstance) (clientv3.Cmp, error) {
+	key := getEvictionKey(instance.ServiceName, instance.InstanceID)
+
+	getCtx, cancel := context.WithTimeout(ctx, etcdTimeout)
+	resp, err := e.conn().Get(getCtx, key)
+	cancel()
+	if err != nil {
+		return clientv3.Cmp{}, fmt.Errorf("获取驱逐记录失败: %w", err)
+	}
+	if len(resp.Kvs) > 0 {
+		var eviction Eviction
+		if err := json.Unmarshal(resp.Kvs[0].Value, &eviction); err == nil {
+			return clientv3.Cmp{}, fmt.Errorf("%w: %s/%s，冷却期到%s结束", ErrInstanceEvicted,
+				instance.ServiceName, instance.InstanceID, eviction.ExpiresAt.Format(time.RFC3339))
+		}
+		return clientv3.Cmp{}, fmt.Errorf("%w: %s/%s", ErrInstanceEvicted, instance.ServiceName, instance.InstanceID)
+	}
+	return clientv3.Compare(clientv3.CreateRevision(key), "=", 0), nil
+}
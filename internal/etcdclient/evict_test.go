@@ -0,0 +1,72 @@
+package etcdclient
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEtcdClient_EvictInstance(t *testing.T) {
+	// 跳过集成测试，除非明确要求运行
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	client := CreateEtcdClientForTest(t)
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	serviceName := fmt.Sprintf("evict-service-%d", time.Now().UnixNano())
+	domain := serviceName + ".default.svc.cluster.local"
+	instance := func() *ServiceInstance {
+		return &ServiceInstance{ServiceName: serviceName, InstanceID: "evict-001", IPAddress: "10.0.0.1", Port: 8080, TTL: 30}
+	}
+	require.NoError(t, client.RegisterService(ctx, instance()))
+	require.NoError(t, client.PutDNSRecord(ctx, domain, &DNSRecord{Type: "TXT", Value: "owner=team-a", TTL: 60, Owner: ServiceOwner(serviceName)}))
+	defer func() {
+		_ = client.DeleteEviction(context.Background(), serviceName, "evict-001")
+		_ = client.DeregisterService(context.Background(), serviceName, "evict-001")
+		_, _ = client.ApplyDNSRecordBatch(context.Background(), []*DNSRecordOperation{
+			{Op: DNSRecordOpDelete, Domain: domain, Type: "TXT", Force: true},
+		})
+	}()
+
+	eviction, err := client.EvictInstance(ctx, serviceName, "evict-001", time.Minute, "stuck")
+	require.NoError(t, err)
+	assert.Equal(t, "stuck", eviction.Reason)
+
+	// 实例和服务域名下的记录都已删除，心跳失败
+	instances, err := client.GetServiceInstances(ctx, serviceName)
+	require.NoError(t, err)
+	assert.Empty(t, instances)
+	_, err = client.GetDNSRecord(ctx, domain, "TXT")
+	assert.Error(t, err)
+	assert.ErrorIs(t, client.RefreshServiceLease(ctx, serviceName, "evict-001", 0), ErrInstanceNotFound)
+
+	// 冷却期内拒绝相同实例ID的注册，其他实例ID不受影响
+	assert.ErrorIs(t, client.RegisterService(ctx, instance()), ErrInstanceEvicted)
+	other := instance()
+	other.InstanceID = "evict-002"
+	require.NoError(t, client.RegisterService(ctx, other))
+	require.NoError(t, client.DeregisterService(ctx, serviceName, "evict-002"))
+
+	evictions, err := client.ListEvictions(ctx)
+	require.NoError(t, err)
+	found := false
+	for _, e := range evictions {
+		found = found || (e.ServiceName == serviceName && e.InstanceID == "evict-001")
+	}
+	assert.True(t, found)
+
+	require.NoError(t, client.DeleteEviction(ctx, serviceName, "evict-001"))
+	require.NoError(t, client.RegisterService(ctx, instance()))
+
+	_, err = client.EvictInstance(ctx, serviceName, "missing", time.Minute, "")
+	assert.ErrorIs(t, err, ErrInstanceNotFound)
+}
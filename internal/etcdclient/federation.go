@@ -0,0 +1,394 @@
+package etcdclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.uber.org/zap"
+)
+
+// 联邦数据在etcd中的键前缀
+// /federation/services/<集群>/<服务> 保存远程集群中服务的可用实例，
+// /federation/links/<集群> 保存与远程集群的链路状态
+const (
+	federationServicesPrefix = "/federation/services/"
+	federationLinksPrefix    = "/federation/links/"
+)
+
+// 联邦链路的同步方式
+const (
+	FederationPull = "pull" // 本集群定时从远程集群拉取目录
+	FederationPush = "push" // 远程集群主动推送目录
+)
+
+// 联邦链路的健康状态
+const (
+	LinkHealthy = "healthy" // 最近一次同步成功
+	LinkFailing = "failing" // 最近一次同步失败，已同步的目录尚未过期
+	LinkStale   = "stale"   // 超过过期时间没有成功同步，目录不再参与DNS应答
+)
+
+// ErrStaleCatalog 目录的生成时间早于已同步的目录，通常是乱序到达的推送
+var ErrStaleCatalog = errors.New("目录早于已同步的版本")
+
+// Catalog 一个集群导出的服务目录，只包含可以参与DNS应答的实例
+type Catalog struct {
+	Cluster     string                        `json:"cluster"`      // 来源集群名称
+	Revision    int64                         `json:"revision"`     // 来源集群生成目录时的etcd修订版本
+	GeneratedAt time.Time                     `json:"generated_at"` // 生成时间
+	Services    map[string][]*ServiceInstance `json:"services"`     // 服务名到可用实例的映射
+}
+
+// FederationLink 与一个远程集群的联邦链路状态
+type FederationLink struct {
+	Cluster             string    `json:"cluster"`              // 远程集群名称
+	Mode                string    `json:"mode"`                 // 同步方式：pull或push
+	Source              string    `json:"source,omitempty"`     // 拉取的地址或推送方的地址
+	LastAttempt         time.Time `json:"last_attempt"`         // 最近一次同步的时间
+	LastSuccess         time.Time `json:"last_success"`         // 最近一次成功同步的时间
+	LastError           string    `json:"last_error,omitempty"` // 最近一次同步失败的原因
+	ConsecutiveFailures int       `json:"consecutive_failures"` // 连续失败次数
+	Revision            int64     `json:"revision"`             // 已同步目录在来源集群的修订版本
+	GeneratedAt         time.Time `json:"generated_at"`         // 已同步目录的生成时间
+	Services            int       `json:"services"`             // 已同步的服务数
+	Instances           int       `json:"instances"`            // 已同步的实例数
+}
+
+// State 返回链路在now时刻的健康状态，staleAfter为0时目录不会过期
+func (l *FederationLink) State(now time.Time, staleAfter time.Duration) string {
+	if l.LastSuccess.IsZero() || (staleAfter > 0 && now.Sub(l.LastSuccess) > staleAfter) {
+		return LinkStale
+	}
+	if l.ConsecutiveFailures > 0 {
+		return LinkFailing
+	}
+	return LinkHealthy
+}
+
+// ValidateClusterName 校验联邦集群名称，名称会作为DNS标签使用
+func ValidateClusterName(name string) error {
+	if !namespaceNamePattern.MatchString(name) {
+		return fmt.Errorf("无效的集群名称: %q，只能包含小写字母、数字和连字符，长度不超过63", name)
+	}
+	return nil
+}
+
+// getFederatedServicePrefix 生成远程集群服务的etcd键前缀
+func getFederatedServicePrefix(cluster string) string {
+	return federationServicesPrefix + cluster + "/"
+}
+
+// getFederationLinkKey 生成联邦链路状态的etcd键
+func getFederationLinkKey(cluster string) string {
+	return federationLinksPrefix + cluster
+}
+
+// federationStaleAfter 返回远程集群目录的过期时间，0表示不过期
+func (e *EtcdClient) federationStaleAfter() time.Duration {
+	if e.cfg == nil {
+		return 0
+	}
+	return time.Duration(e.cfg.Federation.StaleAfter) * time.Second
+}
+
+// ExportCatalog 导出本集群的服务目录，供远程集群同步
+// 只包含可以参与DNS应答的实例，不包含处于维护模式的服务，也不包含从其他集群同步来的服务，
+// 因此目录不会在集群之间传递转发
+func (e *EtcdClient) ExportCatalog(ctx context.Context, cluster string) (*Catalog, error) {
+	if e.conn() == nil {
+		return nil, fmt.Errorf("etcd客户端未连接")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, etcdTimeout)
+	defer cancel()
+
+	resp, err := e.conn().Txn(ctx).Then(
+		clientv3.OpGet("/services/", clientv3.WithPrefix()),
+		clientv3.OpGet(maintenancePrefix, clientv3.WithPrefix(), clientv3.WithKeysOnly()),
+	).Commit()
+	if err != nil {
+		e.log(ctx).Error("导出服务目录失败", zap.Error(err))
+		return nil, fmt.Errorf("导出服务目录失败: %w", err)
+	}
+
+	maintenance := make(map[string]bool)
+	for _, kv := range resp.Responses[1].GetResponseRange().Kvs {
+		maintenance[strings.TrimPrefix(string(kv.Key), maintenancePrefix)] = true
+	}
+
+	catalog := &Catalog{
+		Cluster:     cluster,
+		Revision:    resp.Header.Revision,
+		GeneratedAt: time.Now(),
+		Services:    make(map[string][]*ServiceInstance),
+	}
+	for _, kv := range resp.Responses[0].GetResponseRange().Kvs {
+		var instance ServiceInstance
+		if err := json.Unmarshal(kv.Value, &instance); err != nil {
+			e.log(ctx).Warn("解析服务实例数据失败", zap.String("key", string(kv.Key)), zap.Error(err))
+			continue
+		}
+		if !instance.Routable() || maintenance[instance.ServiceName] {
+			continue
+		}
+		catalog.Services[instance.ServiceName] = append(catalog.Services[instance.ServiceName], &instance)
+	}
+
+	return catalog, nil
+}
+
+// getFederationLink 读取联邦链路状态，不存在时返回nil
+func (e *EtcdClient) getFederationLink(ctx context.Context, cluster string) (*FederationLink, error) {
+	ctx, cancel := context.WithTimeout(ctx, etcdTimeout)
+	defer cancel()
+
+	resp, err := e.conn().Get(ctx, getFederationLinkKey(cluster))
+	if err != nil {
+		return nil, fmt.Errorf("获取联邦链路状态失败: %w", err)
+	}
+	if len(resp.Kvs) == 0 {
+		return nil, nil
+	}
+
+	var link FederationLink
+	if err := json.Unmarshal(resp.Kvs[0].Value, &link); err != nil {
+		return nil, fmt.Errorf("解析联邦链路状态失败: %w", err)
+	}
+	return &link, nil
+}
+
+// ApplyCatalog 用远程集群的目录替换已同步的服务，并记录一次成功的同步
+// 生成时间早于已同步目录的目录返回ErrStaleCatalog；目录中服务名无效的条目被忽略
+func (e *EtcdClient) ApplyCatalog(ctx context.Context, catalog *Catalog, mode, source string) (*FederationLink, error) {
+	if e.conn() == nil {
+		return nil, fmt.Errorf("etcd客户端未连接")
+	}
+	if err := ValidateClusterName(catalog.Cluster); err != nil {
+		return nil, err
+	}
+
+	link, err := e.getFederationLink(ctx, catalog.Cluster)
+	if err != nil {
+		return nil, err
+	}
+	if link == nil {
+		link = &FederationLink{Cluster: catalog.Cluster}
+	}
+	if catalog.GeneratedAt.Before(link.GeneratedAt) {
+		return nil, fmt.Errorf("%w: %s 的目录生成于 %s，已同步的目录生成于 %s", ErrStaleCatalog,
+			catalog.Cluster, catalog.GeneratedAt.Format(time.RFC3339), link.GeneratedAt.Format(time.RFC3339))
+	}
+
+	prefix := getFederatedServicePrefix(catalog.Cluster)
+	getCtx, cancel := context.WithTimeout(ctx, etcdTimeout)
+	resp, err := e.conn().Get(getCtx, prefix, clientv3.WithPrefix())
+	cancel()
+	if err != nil {
+		return nil, fmt.Errorf("获取已同步的服务失败: %w", err)
+	}
+	existing := make(map[string]string, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		existing[string(kv.Key)] = string(kv.Value)
+	}
+
+	var ops []clientv3.Op
+	services, instances := 0, 0
+	for name, serviceInstances := range catalog.Services {
+		if name == "" || strings.Contains(name, "/") || len(serviceInstances) == 0 {
+			e.log(ctx).Warn("忽略目录中无效的服务", zap.String("cluster", catalog.Cluster), zap.String("service", name))
+			continue
+		}
+		data, err := json.Marshal(serviceInstances)
+		if err != nil {
+			return nil, fmt.Errorf("序列化服务实例失败: %w", err)
+		}
+		services++
+		instances += len(serviceInstances)
+
+		key := prefix + name
+		if value, ok := existing[key]; ok {
+			delete(existing, key)
+			if value == string(data) {
+				continue
+			}
+		}
+		ops = append(ops, clientv3.OpPut(key, string(data)))
+	}
+	for key := range existing {
+		ops = append(ops, clientv3.OpDelete(key))
+	}
+
+	now := time.Now()
+	link.Mode = mode
+	link.Source = source
+	link.LastAttempt = now
+	link.LastSuccess = now
+	link.LastError = ""
+	link.ConsecutiveFailures = 0
+	link.Revision = catalog.Revision
+	link.GeneratedAt = catalog.GeneratedAt
+	link.Services = services
+	link.Instances = instances
+	data, err := json.Marshal(link)
+	if err != nil {
+		return nil, fmt.Errorf("序列化联邦链路状态失败: %w", err)
+	}
+	// 链路状态最后写入，目录过大需要拆分事务时，中途失败不会被记为成功的同步
+	ops = append(ops, clientv3.OpPut(getFederationLinkKey(catalog.Cluster), string(data)))
+
+	if err := e.commitOps(ctx, ops); err != nil {
+		e.log(ctx).Error("保存远程集群目录失败", zap.String("cluster", catalog.Cluster), zap.Error(err))
+		return nil, fmt.Errorf("保存远程集群目录失败: %w", err)
+	}
+
+	e.log(ctx).Debug("已同步远程集群目录",
+		zap.String("cluster", catalog.Cluster),
+		zap.String("mode", mode),
+		zap.Int64("revision", catalog.Revision),
+		zap.Int("services", services),
+		zap.Int("changes", len(ops)-1))
+	return link, nil
+}
+
+// RecordFederationFailure 记录一次失败的同步，已同步的目录保持不变直到过期
+func (e *EtcdClient) RecordFederationFailure(ctx context.Context, cluster, mode, source string, cause error) (*FederationLink, error) {
+	if e.conn() == nil {
+		return nil, fmt.Errorf("etcd客户端未连接")
+	}
+
+	link, err := e.getFederationLink(ctx, cluster)
+	if err != nil {
+		return nil, err
+	}
+	if link == nil {
+		link = &FederationLink{Cluster: cluster}
+	}
+	link.Mode = mode
+	link.Source = source
+	link.LastAttempt = time.Now()
+	link.LastError = cause.Error()
+	link.ConsecutiveFailures++
+
+	data, err := json.Marshal(link)
+	if err != nil {
+		return nil, fmt.Errorf("序列化联邦链路状态失败: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, etcdTimeout)
+	defer cancel()
+
+	if _, err := e.conn().Put(ctx, getFederationLinkKey(cluster), string(data)); err != nil {
+		return nil, fmt.Errorf("保存联邦链路状态失败: %w", err)
+	}
+	return link, nil
+}
+
+// ListFederationLinks 列出所有联邦链路的状态，按集群名称排序
+func (e *EtcdClient) ListFederationLinks(ctx context.Context) ([]*FederationLink, error) {
+	if e.conn() == nil {
+		return nil, fmt.Errorf("etcd客户端未连接")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, etcdTimeout)
+	defer cancel()
+
+	resp, err := e.conn().Get(ctx, federationLinksPrefix, clientv3.WithPrefix())
+	if err != nil {
+		e.log(ctx).Error("获取联邦链路状态失败", zap.Error(err))
+		return nil, fmt.Errorf("获取联邦链路状态失败: %w", err)
+	}
+
+	links := make([]*FederationLink, 0, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		var link FederationLink
+		if err := json.Unmarshal(kv.Value, &link); err != nil {
+			e.log(ctx).Warn("解析联邦链路状态失败", zap.String("key", string(kv.Key)), zap.Error(err))
+			continue
+		}
+		links = append(links, &link)
+	}
+	sort.Slice(links, func(i, j int) bool {
+		return links[i].Cluster < links[j].Cluster
+	})
+
+	return links, nil
+}
+
+// DeleteFederatedCluster 删除远程集群已同步的目录和链路状态，返回删除的键数量
+func (e *EtcdClient) DeleteFederatedCluster(ctx context.Context, cluster string) (int64, error) {
+	if e.conn() == nil {
+		return 0, fmt.Errorf("etcd客户端未连接")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, etcdTimeout)
+	defer cancel()
+
+	resp, err := e.conn().Txn(ctx).Then(
+		clientv3.OpDelete(getFederatedServicePrefix(cluster), clientv3.WithPrefix()),
+		clientv3.OpDelete(getFederationLinkKey(cluster)),
+	).Commit()
+	if err != nil {
+		e.log(ctx).Error("删除远程集群目录失败", zap.String("cluster", cluster), zap.Error(err))
+		return 0, fmt.Errorf("删除远程集群目录失败: %w", err)
+	}
+
+	return resp.Responses[0].GetResponseDeleteRange().Deleted + resp.Responses[1].GetResponseDeleteRange().Deleted, nil
+}
+
+// FederatedServiceToDNSRecords 将远程集群中服务的实例转换为DNS记录
+// domain格式为 service.cluster.svc.cluster.local（可带RFC 2782的端口名和协议前缀），集群目录已过期时返回错误
+func (e *EtcdClient) FederatedServiceToDNSRecords(ctx context.Context, cluster, domain string) (map[string]*DNSRecord, error) {
+	if e.conn() == nil {
+		return nil, fmt.Errorf("etcd客户端未连接")
+	}
+
+	portName, protocol, domain := ParseServiceDomain(domain)
+	serviceName := strings.Split(domain, ".")[0]
+
+	ctx, cancel := context.WithTimeout(ctx, etcdTimeout)
+	defer cancel()
+
+	resp, err := e.conn().Txn(ctx).Then(
+		clientv3.OpGet(getFederationLinkKey(cluster)),
+		clientv3.OpGet(getFederatedServicePrefix(cluster)+serviceName),
+	).Commit()
+	if err != nil {
+		return nil, fmt.Errorf("获取远程集群服务失败: %w", err)
+	}
+
+	linkKvs := resp.Responses[0].GetResponseRange().Kvs
+	if len(linkKvs) == 0 {
+		return nil, fmt.Errorf("尚未同步集群 %s 的目录", cluster)
+	}
+	var link FederationLink
+	if err := json.Unmarshal(linkKvs[0].Value, &link); err != nil {
+		return nil, fmt.Errorf("解析联邦链路状态失败: %w", err)
+	}
+	if link.State(time.Now(), e.federationStaleAfter()) == LinkStale {
+		return nil, fmt.Errorf("集群 %s 的目录已过期，最近一次成功同步于 %s", cluster, link.LastSuccess.Format(time.RFC3339))
+	}
+
+	serviceKvs := resp.Responses[1].GetResponseRange().Kvs
+	if len(serviceKvs) == 0 {
+		return nil, fmt.Errorf("集群 %s 中未找到服务: %s", cluster, serviceName)
+	}
+	var instances []*ServiceInstance
+	if err := json.Unmarshal(serviceKvs[0].Value, &instances); err != nil {
+		return nil, fmt.Errorf("解析远程集群服务实例失败: %w", err)
+	}
+	if len(instances) == 0 {
+		return nil, fmt.Errorf("集群 %s 中的服务没有可用的实例: %s", cluster, serviceName)
+	}
+	instances = instancesForPort(instances, portName, protocol)
+	if len(instances) == 0 {
+		return nil, fmt.Errorf("集群 %s 中的服务没有提供 _%s._%s 的实例: %s", cluster, portName, protocol, serviceName)
+	}
+
+	return e.serviceDNSRecords(domain, instances, ServiceRecordOptions{}), nil
+}
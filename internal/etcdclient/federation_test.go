@@ -0,0 +1,143 @@
+package etcdclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFederationLink_State(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name string
+		link FederationLink
+		want string
+	}{
+		{"从未同步", FederationLink{ConsecutiveFailures: 1}, LinkStale},
+		{"最近同步成功", FederationLink{LastSuccess: now.Add(-time.Minute)}, LinkHealthy},
+		{"失败但未过期", FederationLink{LastSuccess: now.Add(-time.Minute), ConsecutiveFailures: 2}, LinkFailing},
+		{"超过过期时间", FederationLink{LastSuccess: now.Add(-10 * time.Minute)}, LinkStale},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.link.State(now, 5*time.Minute))
+		})
+	}
+
+	// staleAfter为0时目录不过期
+	link := FederationLink{LastSuccess: now.Add(-24 * time.Hour)}
+	assert.Equal(t, LinkHealthy, link.State(now, 0))
+}
+
+func TestValidateClusterName(t *testing.T) {
+	assert.NoError(t, ValidateClusterName("cluster-b"))
+	assert.Error(t, ValidateClusterName(""))
+	assert.Error(t, ValidateClusterName("Cluster_B"))
+	assert.Error(t, ValidateClusterName("cluster.b"))
+}
+
+func TestEtcdClient_Federation(t *testing.T) {
+	// 跳过集成测试，除非明确要求运行
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	client := CreateEtcdClientForTest(t)
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	suffix := time.Now().UnixNano()
+	serviceName := fmt.Sprintf("federation-service-%d", suffix)
+	cluster := fmt.Sprintf("cluster-%d", suffix)
+	defer func() {
+		_ = client.DeregisterService(context.Background(), serviceName, "instance-001")
+		_, _ = client.DeleteFederatedCluster(context.Background(), cluster)
+	}()
+
+	// 导出的目录包含本集群可用的实例
+	require.NoError(t, client.RegisterService(ctx, &ServiceInstance{ServiceName: serviceName, InstanceID: "instance-001", IPAddress: "10.0.0.1", Port: 8080, TTL: 30}))
+	catalog, err := client.ExportCatalog(ctx, "local")
+	require.NoError(t, err)
+	assert.Equal(t, "local", catalog.Cluster)
+	assert.Greater(t, catalog.Revision, int64(0))
+	require.Len(t, catalog.Services[serviceName], 1)
+
+	// 处于维护模式的服务不导出
+	require.NoError(t, client.SetServiceMaintenance(ctx, serviceName, true, "升级"))
+	catalog, err = client.ExportCatalog(ctx, "local")
+	require.NoError(t, err)
+	assert.NotContains(t, catalog.Services, serviceName)
+	require.NoError(t, client.SetServiceMaintenance(ctx, serviceName, false, ""))
+
+	// 同步远程集群的目录后可以解析远程服务
+	generated := time.Now()
+	remote := &Catalog{
+		Cluster:     cluster,
+		Revision:    100,
+		GeneratedAt: generated,
+		Services: map[string][]*ServiceInstance{
+			"orders":   {{ServiceName: "orders", InstanceID: "orders-1", IPAddress: "192.168.1.10", Port: 9000}},
+			"payments": {{ServiceName: "payments", InstanceID: "payments-1", IPAddress: "192.168.1.20", Port: 9001}},
+		},
+	}
+	link, err := client.ApplyCatalog(ctx, remote, FederationPull, "http://cluster-b:8080")
+	require.NoError(t, err)
+	assert.Equal(t, 2, link.Services)
+	assert.Equal(t, 2, link.Instances)
+	assert.Equal(t, int64(100), link.Revision)
+
+	records, err := client.FederatedServiceToDNSRecords(ctx, cluster, "orders."+cluster+".svc.cluster.local")
+	require.NoError(t, err)
+	assert.Equal(t, "192.168.1.10", records["A"].Value)
+	assert.Equal(t, "10 10 9000 orders-1.orders."+cluster+".svc.cluster.local", records["SRV-0"].Value)
+
+	// 新目录中没有的服务被删除
+	remote = &Catalog{
+		Cluster:     cluster,
+		Revision:    101,
+		GeneratedAt: generated.Add(time.Second),
+		Services:    map[string][]*ServiceInstance{"orders": remote.Services["orders"]},
+	}
+	_, err = client.ApplyCatalog(ctx, remote, FederationPull, "http://cluster-b:8080")
+	require.NoError(t, err)
+	_, err = client.FederatedServiceToDNSRecords(ctx, cluster, "payments."+cluster+".svc.cluster.local")
+	assert.Error(t, err)
+
+	// 乱序到达的旧目录被拒绝
+	_, err = client.ApplyCatalog(ctx, &Catalog{Cluster: cluster, GeneratedAt: generated}, FederationPull, "")
+	assert.True(t, errors.Is(err, ErrStaleCatalog))
+
+	// 同步失败不影响已同步的目录
+	link, err = client.RecordFederationFailure(ctx, cluster, FederationPull, "http://cluster-b:8080", errors.New("连接被拒绝"))
+	require.NoError(t, err)
+	assert.Equal(t, 1, link.ConsecutiveFailures)
+	assert.Equal(t, "连接被拒绝", link.LastError)
+	assert.Equal(t, LinkFailing, link.State(time.Now(), time.Minute))
+	_, err = client.FederatedServiceToDNSRecords(ctx, cluster, "orders."+cluster+".svc.cluster.local")
+	assert.NoError(t, err)
+
+	links, err := client.ListFederationLinks(ctx)
+	require.NoError(t, err)
+	found := false
+	for _, l := range links {
+		if l.Cluster == cluster {
+			found = true
+			assert.Equal(t, int64(101), l.Revision)
+		}
+	}
+	assert.True(t, found)
+
+	deleted, err := client.DeleteFederatedCluster(ctx, cluster)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), deleted)
+	_, err = client.FederatedServiceToDNSRecords(ctx, cluster, "orders."+cluster+".svc.cluster.local")
+	assert.Error(t, err)
+}
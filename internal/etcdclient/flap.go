@@ -0,0 +1,143 @@
+package etcdclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// 实例抖动记录在etcd中的键前缀，记录带有租约，地址在统计窗口内没有再次加入时自动删除
+const flapPrefix = "/flap/"
+
+// flapRecord 记录一个地址在统计窗口内加入服务的次数
+// 按地址而不是实例ID记录：崩溃重启的实例通常以新的实例ID、相同的地址重新注册
+type flapRecord struct {
+	Joins    int       `json:"joins"`     // 窗口内的加入次数
+	LastJoin time.Time `json:"last_join"` // 最近一次加入的时间
+}
+
+// getFlapKey 生成服务中一个地址的抖动记录键
+func getFlapKey(serviceName, ip string, port int) string {
+	return flapPrefix + serviceName + "/" + net.JoinHostPort(ip, strconv.Itoa(port))
+}
+
+// Suppressed 判断实例是否因频繁加入和离开处于抑制期，抑制期内的实例不参与DNS应答
+func (s *ServiceInstance) Suppressed(now time.Time) bool {
+	return s.SuppressedUntil != nil && now.Before(*s.SuppressedUntil)
+}
+
+// leaseTTL 返回实例租约的TTL：在实例TTL之上加上心跳宽限期，心跳短暂中断时实例不会立即从DNS中消失
+func (e *EtcdClient) leaseTTL(ttl int) int64 {
+	if e.cfg != nil && e.cfg.Heartbeat.GracePeriod > 0 {
+		return int64(ttl + e.cfg.Heartbeat.GracePeriod)
+	}
+	return int64(ttl)
+}
+
+// flapDampingEnabled 判断是否开启抖动抑制
+func (e *EtcdClient) flapDampingEnabled() bool {
+	return e.cfg != nil && e.cfg.Heartbeat.FlapDamping.Enabled && e.cfg.Heartbeat.FlapDamping.Threshold > 0
+}
+
+// holdDown 计算窗口内第joins次加入后的抑制时长，未超过阈值时返回0
+// 超过阈值后第一次抑制hold_down秒，之后每多加入一次翻倍，不超过max_hold_down
+func (e *EtcdClient) holdDown(joins int) time.Duration {
+	damping := e.cfg.Heartbeat.FlapDamping
+	excess := joins - damping.Threshold
+	if excess <= 0 {
+		return 0
+	}
+
+	hold := time.Duration(damping.HoldDown) * time.Second
+	limit := time.Duration(damping.MaxHoldDown) * time.Second
+	for i := 1; i < excess; i++ {
+		if limit > 0 && hold >= limit {
+			break
+		}
+		hold *= 2
+	}
+	if limit > 0 && hold > limit {
+		hold = limit
+	}
+	return hold
+}
+
+// dampFlapping 在注册事务之前检查实例是否为新加入，返回事务需要的比较条件和写入操作
+// 实例已存在时沿用原有的抑制状态；新加入时计入地址的加入次数（距离上次加入超过统计窗口时重新计数），
+// 超过阈值后设置实例的抑制时间。比较条件保证读取之后实例和抖动记录没有被并发修改
+func (e *EtcdClient) dampFlapping(ctx context.Context, key string, instance *ServiceInstance) ([]clientv3.Cmp, []clientv3.Op, int, error) {
+	getCtx, cancel := context.WithTimeout(ctx, etcdTimeout)
+	resp, err := e.conn().Get(getCtx, key)
+	cancel()
+	if err != nil {
+		return nil, nil, 0, fmt.Errorf("获取服务实例数据失败: %w", err)
+	}
+
+	if len(resp.Kvs) > 0 {
+		var existing ServiceInstance
+		if err := json.Unmarshal(resp.Kvs[0].Value, &existing); err == nil {
+			instance.SuppressedUntil = existing.SuppressedUntil
+		}
+		return []clientv3.Cmp{clientv3.Compare(clientv3.ModRevision(key), "=", resp.Kvs[0].ModRevision)}, nil, 0, nil
+	}
+
+	damping := e.cfg.Heartbeat.FlapDamping
+	flapKey := getFlapKey(instance.ServiceName, instance.IPAddress, instance.Port)
+	getCtx, cancel = context.WithTimeout(ctx, etcdTimeout)
+	resp, err = e.conn().Get(getCtx, flapKey)
+	cancel()
+	if err != nil {
+		return nil, nil, 0, fmt.Errorf("获取实例抖动记录失败: %w", err)
+	}
+
+	now := time.Now()
+	record := flapRecord{}
+	var modRevision int64
+	if len(resp.Kvs) > 0 {
+		modRevision = resp.Kvs[0].ModRevision
+		// 无法解析的记录视为不存在，重新计数
+		if err := json.Unmarshal(resp.Kvs[0].Value, &record); err != nil {
+			record = flapRecord{}
+		}
+	}
+	if damping.Window > 0 && now.Sub(record.LastJoin) > time.Duration(damping.Window)*time.Second {
+		record.Joins = 0
+	}
+	record.Joins++
+	record.LastJoin = now
+
+	instance.SuppressedUntil = nil
+	if hold := e.holdDown(record.Joins); hold > 0 {
+		until := now.Add(hold)
+		instance.SuppressedUntil = &until
+	}
+
+	data, err := json.Marshal(&record)
+	if err != nil {
+		return nil, nil, 0, fmt.Errorf("序列化实例抖动记录失败: %w", err)
+	}
+
+	// 记录保留到统计窗口和最长抑制时间都结束之后，之后的加入重新计数
+	ttl := damping.Window + damping.MaxHoldDown
+	if ttl <= 0 {
+		ttl = damping.HoldDown
+	}
+	grantCtx, cancel := context.WithTimeout(ctx, etcdTimeout)
+	lease, err := e.conn().Grant(grantCtx, int64(max(ttl, 1)))
+	cancel()
+	if err != nil {
+		return nil, nil, 0, fmt.Errorf("创建etcd租约失败: %w", err)
+	}
+
+	cmps := []clientv3.Cmp{
+		clientv3.Compare(clientv3.CreateRevision(key), "=", 0),
+		clientv3.Compare(clientv3.ModRevision(flapKey), "=", modRevision),
+	}
+	ops := []clientv3.Op{clientv3.OpPut(flapKey, string(data), clientv3.WithLease(lease.ID))}
+	return cmps, ops, record.Joins, nil
+}
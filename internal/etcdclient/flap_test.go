@@ -0,0 +1,121 @@
+package etcdclient
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hewenyu/kong-discovery/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+func TestEtcdClient_HoldDown(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Heartbeat.FlapDamping.Threshold = 3
+	cfg.Heartbeat.FlapDamping.HoldDown = 30
+	cfg.Heartbeat.FlapDamping.MaxHoldDown = 100
+	e := &EtcdClient{cfg: cfg}
+
+	assert.Zero(t, e.holdDown(1))
+	assert.Zero(t, e.holdDown(3), "未超过阈值不抑制")
+	assert.Equal(t, 30*time.Second, e.holdDown(4))
+	assert.Equal(t, 60*time.Second, e.holdDown(5))
+	assert.Equal(t, 100*time.Second, e.holdDown(6), "不超过上限")
+	assert.Equal(t, 100*time.Second, e.holdDown(100))
+
+	cfg.Heartbeat.FlapDamping.MaxHoldDown = 0
+	assert.Equal(t, 120*time.Second, e.holdDown(6), "0表示不限制")
+}
+
+func TestServiceInstance_Suppressed(t *testing.T) {
+	now := time.Now()
+	until := now.Add(time.Minute)
+	instance := &ServiceInstance{SuppressedUntil: &until}
+	assert.True(t, instance.Suppressed(now))
+	assert.False(t, instance.Routable())
+	assert.False(t, instance.Suppressed(until.Add(time.Second)))
+
+	past := now.Add(-time.Minute)
+	instance.SuppressedUntil = &past
+	assert.True(t, instance.Routable(), "抑制结束后恢复应答")
+}
+
+func TestEtcdClient_RegisterService_FlapDamping(t *testing.T) {
+	// 跳过集成测试，除非明确要求运行
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	client := CreateEtcdClientForTest(t)
+	defer client.Close()
+	e := client.(*EtcdClient)
+	raw := e.conn()
+
+	e.cfg.Heartbeat.GracePeriod = 15
+	e.cfg.Heartbeat.FlapDamping.Enabled = true
+	e.cfg.Heartbeat.FlapDamping.Threshold = 2
+	e.cfg.Heartbeat.FlapDamping.Window = 60
+	e.cfg.Heartbeat.FlapDamping.HoldDown = 30
+	e.cfg.Heartbeat.FlapDamping.MaxHoldDown = 120
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	service := fmt.Sprintf("flap-svc-%d", time.Now().UnixNano())
+	defer func() {
+		_, _ = raw.Delete(context.Background(), getServicePrefix(service), clientv3.WithPrefix())
+		_, _ = raw.Delete(context.Background(), flapPrefix+service+"/", clientv3.WithPrefix())
+	}()
+
+	// 崩溃重启：每次以新的实例ID、相同的地址加入
+	join := func(id string) *ServiceInstance {
+		instance := &ServiceInstance{ServiceName: service, InstanceID: id, IPAddress: "10.0.0.1", Port: 8080, TTL: 30}
+		require.NoError(t, client.RegisterService(ctx, instance))
+		return instance
+	}
+
+	first := join("instance-001")
+	assert.Nil(t, first.SuppressedUntil)
+
+	// 租约TTL包括宽限期
+	resp, err := raw.Get(ctx, getServiceInstanceKey(service, "instance-001"))
+	require.NoError(t, err)
+	require.Len(t, resp.Kvs, 1)
+	ttl, err := raw.TimeToLive(ctx, clientv3.LeaseID(resp.Kvs[0].Lease))
+	require.NoError(t, err)
+	assert.Equal(t, int64(45), ttl.GrantedTTL)
+
+	require.NoError(t, client.DeregisterService(ctx, service, "instance-001"))
+	second := join("instance-002")
+	assert.Nil(t, second.SuppressedUntil, "未超过阈值")
+
+	require.NoError(t, client.DeregisterService(ctx, service, "instance-002"))
+	third := join("instance-003")
+	require.NotNil(t, third.SuppressedUntil, "超过阈值后抑制")
+	assert.WithinDuration(t, time.Now().Add(30*time.Second), *third.SuppressedUntil, 5*time.Second)
+
+	instances, err := client.GetServiceInstances(ctx, service)
+	require.NoError(t, err)
+	require.Len(t, instances, 1)
+	assert.Empty(t, routableInstances(instances), "抑制期内的实例不参与应答")
+
+	// 同一实例重复注册不是新加入，沿用原有的抑制状态
+	again := &ServiceInstance{ServiceName: service, InstanceID: "instance-003", IPAddress: "10.0.0.1", Port: 8080, TTL: 30}
+	require.NoError(t, client.RegisterService(ctx, again))
+	require.NotNil(t, again.SuppressedUntil)
+	assert.Equal(t, third.SuppressedUntil.Unix(), again.SuppressedUntil.Unix())
+
+	// 再次加入时抑制时长翻倍
+	require.NoError(t, client.DeregisterService(ctx, service, "instance-003"))
+	fourth := join("instance-004")
+	require.NotNil(t, fourth.SuppressedUntil)
+	assert.WithinDuration(t, time.Now().Add(60*time.Second), *fourth.SuppressedUntil, 5*time.Second)
+
+	// 其他地址不受影响
+	other := &ServiceInstance{ServiceName: service, InstanceID: "instance-005", IPAddress: "10.0.0.2", Port: 8080, TTL: 30}
+	require.NoError(t, client.RegisterService(ctx, other))
+	assert.Nil(t, other.SuppressedUntil)
+}
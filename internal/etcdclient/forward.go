@@ -0,0 +1,129 @@
+package etcdclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.uber.org/zap"
+)
+
+// 条件转发规则在etcd中的键前缀
+const forwardRulePrefix = "/config/forward-rules/"
+
+// ForwardRule 表示按域名后缀的条件转发规则
+type ForwardRule struct {
+	Suffix  string   `json:"suffix"`         // 域名后缀，例如 corp.example
+	Servers []string `json:"servers"`        // 该后缀使用的上游DNS服务器
+	Mode    string   `json:"mode,omitempty"` // 转发模式 (sequential 或 race)
+}
+
+// NormalizeForwardSuffix 规范化转发规则的域名后缀：小写并去掉通配符前缀和首尾的点号
+func NormalizeForwardSuffix(suffix string) string {
+	suffix = strings.ToLower(strings.TrimSpace(suffix))
+	suffix = strings.TrimPrefix(suffix, "*")
+	return strings.Trim(suffix, ".")
+}
+
+// Validate 校验条件转发规则
+func (r *ForwardRule) Validate() error {
+	if NormalizeForwardSuffix(r.Suffix) == "" {
+		return fmt.Errorf("转发规则的域名后缀不能为空")
+	}
+
+	upstream := UpstreamConfig{Servers: r.Servers, Mode: r.Mode}
+	return upstream.Validate()
+}
+
+// getForwardRuleKey 生成转发规则的etcd键
+func getForwardRuleKey(suffix string) string {
+	return forwardRulePrefix + NormalizeForwardSuffix(suffix)
+}
+
+// ListForwardRules 获取所有条件转发规则
+func (e *EtcdClient) ListForwardRules(ctx context.Context) ([]*ForwardRule, error) {
+	if e.conn() == nil {
+		return nil, fmt.Errorf("etcd客户端未连接")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, etcdTimeout)
+	defer cancel()
+
+	resp, err := e.conn().Get(ctx, forwardRulePrefix, clientv3.WithPrefix())
+	if err != nil {
+		e.log(ctx).Error("获取条件转发规则失败", zap.Error(err))
+		return nil, fmt.Errorf("获取条件转发规则失败: %w", err)
+	}
+
+	rules := make([]*ForwardRule, 0, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		var rule ForwardRule
+		if err := json.Unmarshal(kv.Value, &rule); err != nil {
+			e.log(ctx).Warn("解析条件转发规则失败",
+				zap.String("key", string(kv.Key)),
+				zap.Error(err))
+			continue
+		}
+		rules = append(rules, &rule)
+	}
+
+	return rules, nil
+}
+
+// PutForwardRule 保存条件转发规则，相同后缀的规则会被覆盖
+func (e *EtcdClient) PutForwardRule(ctx context.Context, rule *ForwardRule) error {
+	if e.conn() == nil {
+		return fmt.Errorf("etcd客户端未连接")
+	}
+
+	if err := rule.Validate(); err != nil {
+		return err
+	}
+	rule.Suffix = NormalizeForwardSuffix(rule.Suffix)
+
+	data, err := json.Marshal(rule)
+	if err != nil {
+		e.log(ctx).Error("序列化条件转发规则失败", zap.Error(err))
+		return fmt.Errorf("序列化条件转发规则失败: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, etcdTimeout)
+	defer cancel()
+
+	if _, err := e.conn().Put(ctx, getForwardRuleKey(rule.Suffix), string(data)); err != nil {
+		e.log(ctx).Error("保存条件转发规则失败", zap.String("suffix", rule.Suffix), zap.Error(err))
+		return fmt.Errorf("保存条件转发规则失败: %w", err)
+	}
+
+	e.log(ctx).Info("条件转发规则保存成功",
+		zap.String("suffix", rule.Suffix),
+		zap.Strings("servers", rule.Servers))
+	return nil
+}
+
+// DeleteForwardRule 删除条件转发规则
+func (e *EtcdClient) DeleteForwardRule(ctx context.Context, suffix string) error {
+	if e.conn() == nil {
+		return fmt.Errorf("etcd客户端未连接")
+	}
+
+	key := getForwardRuleKey(suffix)
+
+	ctx, cancel := context.WithTimeout(ctx, etcdTimeout)
+	defer cancel()
+
+	resp, err := e.conn().Delete(ctx, key)
+	if err != nil {
+		e.log(ctx).Error("删除条件转发规则失败", zap.String("suffix", suffix), zap.Error(err))
+		return fmt.Errorf("删除条件转发规则失败: %w", err)
+	}
+
+	if resp.Deleted == 0 {
+		return fmt.Errorf("条件转发规则不存在: %s", suffix)
+	}
+
+	e.log(ctx).Info("条件转发规则删除成功", zap.String("suffix", suffix))
+	return nil
+}
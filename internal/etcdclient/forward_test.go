@@ -0,0 +1,46 @@
+package etcdclient
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEtcdClient_ForwardRules(t *testing.T) {
+	// 跳过集成测试，除非明确要求运行
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	// 创建etcd客户端并连接
+	client := CreateEtcdClientForTest(t)
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	rule := &ForwardRule{Suffix: "*.Test-Corp.Example.", Servers: []string{"10.1.1.53:53"}}
+	err := client.PutForwardRule(ctx, rule)
+	require.NoError(t, err, "保存条件转发规则应该成功")
+	assert.Equal(t, "test-corp.example", rule.Suffix, "后缀应被规范化")
+
+	rules, err := client.ListForwardRules(ctx)
+	require.NoError(t, err)
+	var found bool
+	for _, r := range rules {
+		if r.Suffix == "test-corp.example" {
+			found = true
+			assert.Equal(t, []string{"10.1.1.53:53"}, r.Servers)
+		}
+	}
+	assert.True(t, found, "应该找到保存的转发规则")
+
+	err = client.DeleteForwardRule(ctx, "test-corp.example")
+	require.NoError(t, err, "删除条件转发规则应该成功")
+
+	err = client.DeleteForwardRule(ctx, "test-corp.example")
+	assert.Error(t, err, "删除不存在的规则应该失败")
+}
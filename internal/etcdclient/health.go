@@ -0,0 +1,221 @@
+package etcdclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// 服务实例的健康状态
+const (
+	HealthPassing  = "passing"  // 健康，正常参与DNS应答
+	HealthWarning  = "warning"  // 告警，仍参与DNS应答
+	HealthCritical = "critical" // 故障，不参与DNS应答
+	HealthDraining = "draining" // 摘流中，不再接收新流量
+)
+
+var (
+	// ErrInstanceNotFound 服务实例不存在
+	ErrInstanceNotFound = errors.New("服务实例不存在")
+
+	// ErrInvalidHealthTransition 不允许的健康状态转换
+	ErrInvalidHealthTransition = errors.New("不允许的健康状态转换")
+)
+
+// healthTransitions 定义健康状态之间允许的转换
+// 摘流中的实例只能显式恢复为passing，避免被健康检查结果意外拉回流量
+var healthTransitions = map[string][]string{
+	HealthPassing:  {HealthPassing, HealthWarning, HealthCritical, HealthDraining},
+	HealthWarning:  {HealthPassing, HealthWarning, HealthCritical, HealthDraining},
+	HealthCritical: {HealthPassing, HealthWarning, HealthCritical, HealthDraining},
+	HealthDraining: {HealthPassing, HealthDraining},
+}
+
+// ValidHealthStatus 判断健康状态是否合法
+func ValidHealthStatus(status string) bool {
+	_, ok := healthTransitions[status]
+	return ok
+}
+
+// CanTransitionHealth 判断是否允许从一个健康状态转换到另一个健康状态
+func CanTransitionHealth(from, to string) bool {
+	if from == "" {
+		from = HealthPassing
+	}
+	for _, allowed := range healthTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckResult 外部检查器（Nagios、blackbox exporter等）推送的检查结果
+type CheckResult struct {
+	Status    string    `json:"status"`            // 检查结果：passing、warning或critical
+	Output    string    `json:"output,omitempty"`  // 检查输出
+	Checker   string    `json:"checker,omitempty"` // 推送结果的检查器名称
+	TTL       int       `json:"ttl,omitempty"`     // 结果的有效期（秒），超过后仍未收到新结果时实例视为critical，0表示不过期
+	UpdatedAt time.Time `json:"updated_at"`        // 收到结果的时间
+}
+
+// Expired 判断检查结果是否已超过有效期
+func (r *CheckResult) Expired(now time.Time) bool {
+	return r.TTL > 0 && now.Sub(r.UpdatedAt) > time.Duration(r.TTL)*time.Second
+}
+
+// ValidCheckStatus 判断检查结果状态是否合法，检查器只能报告passing、warning和critical
+func ValidCheckStatus(status string) bool {
+	switch status {
+	case HealthPassing, HealthWarning, HealthCritical:
+		return true
+	default:
+		return false
+	}
+}
+
+// 心跳健康信息中负载指标和自定义检查的数量上限
+const maxReportEntries = 32
+
+// ReportedCheck 实例随心跳上报的一项自定义检查
+type ReportedCheck struct {
+	Name   string `json:"name"`             // 检查名称
+	Status string `json:"status"`           // 检查结果：passing、warning或critical
+	Output string `json:"output,omitempty"` // 可选检查输出
+}
+
+// HealthReport 实例随心跳上报的健康信息，不需要单独的健康检查端点或主动检查
+type HealthReport struct {
+	Status    string             `json:"status,omitempty"` // 实例自报的健康状态：passing、warning或critical，为空时由检查结果决定
+	Load      map[string]float64 `json:"load,omitempty"`   // 负载指标，如cpu、memory、connections
+	Checks    []ReportedCheck    `json:"checks,omitempty"` // 自定义检查的结果
+	UpdatedAt time.Time          `json:"updated_at"`       // 收到上报的时间
+}
+
+// Validate 校验心跳健康信息
+func (r *HealthReport) Validate() error {
+	if r.Status != "" && !ValidCheckStatus(r.Status) {
+		return fmt.Errorf("无效的健康状态: %s", r.Status)
+	}
+	if len(r.Load) > maxReportEntries {
+		return fmt.Errorf("负载指标不能超过%d项", maxReportEntries)
+	}
+	if len(r.Checks) > maxReportEntries {
+		return fmt.Errorf("自定义检查不能超过%d项", maxReportEntries)
+	}
+	for _, check := range r.Checks {
+		if check.Name == "" {
+			return fmt.Errorf("自定义检查的名称不能为空")
+		}
+		if !ValidCheckStatus(check.Status) {
+			return fmt.Errorf("自定义检查%s的结果无效: %s", check.Name, check.Status)
+		}
+	}
+	return nil
+}
+
+// HealthStatus 返回上报决定的健康状态：设置了status时使用status，否则为自定义检查中最严重的结果；
+// 都没有时返回空，实例的健康状态保持不变
+func (r *HealthReport) HealthStatus() string {
+	if r.Status != "" {
+		return r.Status
+	}
+	status := ""
+	for _, check := range r.Checks {
+		switch {
+		case check.Status == HealthCritical:
+			return HealthCritical
+		case check.Status == HealthWarning, status == "":
+			status = check.Status
+		}
+	}
+	return status
+}
+
+// HealthStatus 返回实例的健康状态，未设置时视为passing；
+// 外部检查结果超过有效期仍未更新时视为critical，摘流中的实例保持draining
+func (s *ServiceInstance) HealthStatus() string {
+	if s.Health == HealthDraining {
+		return s.Health
+	}
+	if s.Check != nil && s.Check.Expired(time.Now()) {
+		return HealthCritical
+	}
+	if s.Health == "" {
+		return HealthPassing
+	}
+	return s.Health
+}
+
+// Routable 判断实例是否应出现在DNS应答中，维护模式和抖动抑制期内的实例不参与应答
+func (s *ServiceInstance) Routable() bool {
+	if s.Maintenance || s.Suppressed(time.Now()) {
+		return false
+	}
+	switch s.HealthStatus() {
+	case HealthPassing, HealthWarning:
+		return true
+	default:
+		return false
+	}
+}
+
+// SetInstanceHealth 更新服务实例的健康状态，保留实例原有的租约
+func (e *EtcdClient) SetInstanceHealth(ctx context.Context, serviceName, instanceID, status string) (*ServiceInstance, error) {
+	if !ValidHealthStatus(status) {
+		return nil, fmt.Errorf("无效的健康状态: %s", status)
+	}
+
+	instance, err := e.updateServiceInstance(ctx, serviceName, instanceID, func(instance *ServiceInstance) error {
+		if !CanTransitionHealth(instance.Health, status) {
+			return fmt.Errorf("%w: %s -> %s", ErrInvalidHealthTransition, instance.HealthStatus(), status)
+		}
+		instance.Health = status
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	e.log(ctx).Info("服务实例健康状态更新成功",
+		zap.String("service", serviceName),
+		zap.String("id", instanceID),
+		zap.String("health", status))
+
+	return instance, nil
+}
+
+// SetInstanceCheckResult 保存外部检查器推送的检查结果，并把实例的健康状态更新为检查结果；
+// 摘流中的实例只记录结果，健康状态保持draining
+func (e *EtcdClient) SetInstanceCheckResult(ctx context.Context, serviceName, instanceID string, result *CheckResult) (*ServiceInstance, error) {
+	if !ValidCheckStatus(result.Status) {
+		return nil, fmt.Errorf("无效的检查结果: %s", result.Status)
+	}
+	if result.TTL < 0 {
+		return nil, fmt.Errorf("无效的检查结果有效期: %d", result.TTL)
+	}
+	result.UpdatedAt = time.Now()
+
+	instance, err := e.updateServiceInstance(ctx, serviceName, instanceID, func(instance *ServiceInstance) error {
+		instance.Check = result
+		if instance.HealthStatus() != HealthDraining {
+			instance.Health = result.Status
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	e.log(ctx).Debug("收到服务实例检查结果",
+		zap.String("service", serviceName),
+		zap.String("id", instanceID),
+		zap.String("status", result.Status),
+		zap.String("checker", result.Checker),
+		zap.String("health", instance.HealthStatus()))
+
+	return instance, nil
+}
@@ -0,0 +1,233 @@
+package etcdclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHealthTransitions(t *testing.T) {
+	assert.True(t, ValidHealthStatus(HealthWarning))
+	assert.False(t, ValidHealthStatus("unknown"))
+	assert.False(t, ValidHealthStatus(""))
+
+	// 未设置状态视为passing
+	assert.True(t, CanTransitionHealth("", HealthCritical))
+	assert.True(t, CanTransitionHealth(HealthCritical, HealthDraining))
+
+	// 摘流中的实例只能显式恢复为passing
+	assert.True(t, CanTransitionHealth(HealthDraining, HealthPassing))
+	assert.False(t, CanTransitionHealth(HealthDraining, HealthWarning))
+	assert.False(t, CanTransitionHealth(HealthDraining, HealthCritical))
+
+	assert.True(t, (&ServiceInstance{}).Routable())
+	assert.True(t, (&ServiceInstance{Health: HealthWarning}).Routable())
+	assert.False(t, (&ServiceInstance{Health: HealthCritical}).Routable())
+	assert.False(t, (&ServiceInstance{Health: HealthDraining}).Routable())
+}
+
+func TestCheckResult_Expired(t *testing.T) {
+	now := time.Now()
+	assert.False(t, (&CheckResult{Status: HealthPassing, UpdatedAt: now.Add(-time.Hour)}).Expired(now), "TTL为0时不过期")
+	assert.False(t, (&CheckResult{Status: HealthPassing, TTL: 30, UpdatedAt: now.Add(-10 * time.Second)}).Expired(now))
+	assert.True(t, (&CheckResult{Status: HealthPassing, TTL: 30, UpdatedAt: now.Add(-time.Minute)}).Expired(now))
+
+	// 检查结果过期后实例视为critical，不参与DNS应答
+	expired := &CheckResult{Status: HealthPassing, TTL: 30, UpdatedAt: now.Add(-time.Minute)}
+	instance := &ServiceInstance{Health: HealthPassing, Check: expired}
+	assert.Equal(t, HealthCritical, instance.HealthStatus())
+	assert.False(t, instance.Routable())
+
+	// 摘流中的实例保持draining
+	instance = &ServiceInstance{Health: HealthDraining, Check: expired}
+	assert.Equal(t, HealthDraining, instance.HealthStatus())
+
+	assert.True(t, ValidCheckStatus(HealthCritical))
+	assert.False(t, ValidCheckStatus(HealthDraining))
+}
+
+func TestHealthReport(t *testing.T) {
+	assert.Equal(t, "", (&HealthReport{Load: map[string]float64{"cpu": 0.5}}).HealthStatus())
+	assert.Equal(t, HealthWarning, (&HealthReport{Checks: []ReportedCheck{
+		{Name: "db", Status: HealthPassing},
+		{Name: "cache", Status: HealthWarning},
+		{Name: "queue", Status: HealthPassing},
+	}}).HealthStatus())
+	assert.Equal(t, HealthCritical, (&HealthReport{Checks: []ReportedCheck{
+		{Name: "db", Status: HealthCritical},
+		{Name: "cache", Status: HealthWarning},
+	}}).HealthStatus())
+	// 显式的状态优先于检查结果
+	assert.Equal(t, HealthPassing, (&HealthReport{Status: HealthPassing, Checks: []ReportedCheck{{Name: "db", Status: HealthCritical}}}).HealthStatus())
+
+	assert.NoError(t, (&HealthReport{Status: HealthWarning}).Validate())
+	assert.Error(t, (&HealthReport{Status: HealthDraining}).Validate())
+	assert.Error(t, (&HealthReport{Checks: []ReportedCheck{{Status: HealthPassing}}}).Validate())
+	assert.Error(t, (&HealthReport{Checks: []ReportedCheck{{Name: "db", Status: "unknown"}}}).Validate())
+}
+
+func TestEtcdClient_RefreshServiceLeaseWithReport(t *testing.T) {
+	// 跳过集成测试，除非明确要求运行
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	client := CreateEtcdClientForTest(t)
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	serviceName := fmt.Sprintf("report-service-%d", time.Now().UnixNano())
+	require.NoError(t, client.RegisterService(ctx, &ServiceInstance{ServiceName: serviceName, InstanceID: "report-001", IPAddress: "10.0.0.1", Port: 8080, TTL: 30}))
+	defer func() { _ = client.DeregisterService(context.Background(), serviceName, "report-001") }()
+
+	report := &HealthReport{
+		Load:   map[string]float64{"cpu": 0.93},
+		Checks: []ReportedCheck{{Name: "db", Status: HealthCritical, Output: "connection refused"}},
+	}
+	instance, err := client.RefreshServiceLeaseWithReport(ctx, serviceName, "report-001", 0, report)
+	require.NoError(t, err)
+	assert.Equal(t, HealthCritical, instance.HealthStatus())
+
+	instances, err := client.GetServiceInstances(ctx, serviceName)
+	require.NoError(t, err)
+	require.Len(t, instances, 1)
+	require.NotNil(t, instances[0].Report)
+	assert.Equal(t, 0.93, instances[0].Report.Load["cpu"])
+	assert.Equal(t, "connection refused", instances[0].Report.Checks[0].Output)
+	assert.False(t, instances[0].Report.UpdatedAt.IsZero())
+	assert.False(t, instances[0].Routable())
+
+	// 不携带健康信息的心跳保留上一次的上报和健康状态
+	require.NoError(t, client.RefreshServiceLease(ctx, serviceName, "report-001", 0))
+	instances, err = client.GetServiceInstances(ctx, serviceName)
+	require.NoError(t, err)
+	require.NotNil(t, instances[0].Report)
+	assert.Equal(t, HealthCritical, instances[0].HealthStatus())
+
+	// 摘流中的实例只记录上报
+	_, err = client.SetInstanceHealth(ctx, serviceName, "report-001", HealthDraining)
+	require.NoError(t, err)
+	instance, err = client.RefreshServiceLeaseWithReport(ctx, serviceName, "report-001", 0, &HealthReport{Status: HealthPassing})
+	require.NoError(t, err)
+	assert.Equal(t, HealthDraining, instance.HealthStatus())
+	assert.Equal(t, HealthPassing, instance.Report.Status)
+
+	_, err = client.RefreshServiceLeaseWithReport(ctx, serviceName, "report-001", 0, &HealthReport{Status: "unknown"})
+	assert.Error(t, err)
+}
+
+func TestEtcdClient_SetInstanceCheckResult(t *testing.T) {
+	// 跳过集成测试，除非明确要求运行
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	client := CreateEtcdClientForTest(t)
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	serviceName := fmt.Sprintf("check-service-%d", time.Now().UnixNano())
+	require.NoError(t, client.RegisterService(ctx, &ServiceInstance{ServiceName: serviceName, InstanceID: "check-001", IPAddress: "10.0.0.1", Port: 8080, TTL: 30}))
+	defer func() { _ = client.DeregisterService(context.Background(), serviceName, "check-001") }()
+
+	instance, err := client.SetInstanceCheckResult(ctx, serviceName, "check-001", &CheckResult{Status: HealthCritical, Output: "HTTP 500", Checker: "blackbox", TTL: 60})
+	require.NoError(t, err)
+	assert.Equal(t, HealthCritical, instance.HealthStatus())
+	require.NotNil(t, instance.Check)
+	assert.Equal(t, "HTTP 500", instance.Check.Output)
+
+	instances, err := client.GetServiceInstances(ctx, serviceName)
+	require.NoError(t, err)
+	require.Len(t, instances, 1)
+	assert.False(t, instances[0].Routable())
+
+	// 摘流中的实例只记录结果
+	_, err = client.SetInstanceHealth(ctx, serviceName, "check-001", HealthDraining)
+	require.NoError(t, err)
+	instance, err = client.SetInstanceCheckResult(ctx, serviceName, "check-001", &CheckResult{Status: HealthPassing})
+	require.NoError(t, err)
+	assert.Equal(t, HealthDraining, instance.HealthStatus())
+	assert.Equal(t, HealthPassing, instance.Check.Status)
+
+	_, err = client.SetInstanceCheckResult(ctx, serviceName, "check-001", &CheckResult{Status: HealthDraining})
+	assert.Error(t, err)
+	_, err = client.SetInstanceCheckResult(ctx, serviceName, "missing", &CheckResult{Status: HealthPassing})
+	assert.True(t, errors.Is(err, ErrInstanceNotFound))
+}
+
+func TestEtcdClient_SetInstanceHealth(t *testing.T) {
+	// 跳过集成测试，除非明确要求运行
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	client := CreateEtcdClientForTest(t)
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	serviceName := fmt.Sprintf("health-service-%d", time.Now().UnixNano())
+	for _, id := range []string{"instance-001", "instance-002"} {
+		err := client.RegisterService(ctx, &ServiceInstance{
+			ServiceName: serviceName,
+			InstanceID:  id,
+			IPAddress:   "10.0.0.1",
+			Port:        8080,
+			TTL:         30,
+		})
+		require.NoError(t, err)
+		defer func(id string) { _ = client.DeregisterService(context.Background(), serviceName, id) }(id)
+	}
+
+	// 注册时默认为passing
+	instances, err := client.GetServiceInstances(ctx, serviceName)
+	require.NoError(t, err)
+	for _, instance := range instances {
+		assert.Equal(t, HealthPassing, instance.Health)
+	}
+
+	// 将第一个实例标记为critical后，DNS记录只包含健康实例
+	instance, err := client.SetInstanceHealth(ctx, serviceName, "instance-001", HealthCritical)
+	require.NoError(t, err)
+	assert.Equal(t, HealthCritical, instance.Health)
+
+	records, err := client.ServiceToDNSRecords(ctx, serviceName+".svc.cluster.local")
+	require.NoError(t, err)
+	assert.Len(t, records, 2, "应只有一条A记录和一条SRV记录")
+	assert.Contains(t, records["SRV-0"].Value, "instance-002")
+
+	// 全部实例不健康时不返回记录
+	_, err = client.SetInstanceHealth(ctx, serviceName, "instance-002", HealthDraining)
+	require.NoError(t, err)
+	_, err = client.ServiceToDNSRecords(ctx, serviceName+".svc.cluster.local")
+	assert.Error(t, err)
+
+	// 摘流中的实例不能直接转为critical
+	_, err = client.SetInstanceHealth(ctx, serviceName, "instance-002", HealthCritical)
+	assert.True(t, errors.Is(err, ErrInvalidHealthTransition))
+
+	// 心跳刷新租约时保留健康状态
+	err = client.RefreshServiceLease(ctx, serviceName, "instance-002", 0)
+	require.NoError(t, err)
+	instances, err = client.GetServiceInstances(ctx, serviceName)
+	require.NoError(t, err)
+	for _, instance := range instances {
+		if instance.InstanceID == "instance-002" {
+			assert.Equal(t, HealthDraining, instance.Health)
+		}
+	}
+
+	// 不存在的实例
+	_, err = client.SetInstanceHealth(ctx, serviceName, "missing", HealthPassing)
+	assert.True(t, errors.Is(err, ErrInstanceNotFound))
+}
@@ -0,0 +1,136 @@
+package etcdclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// 按负载调整权重的运行时开关在etcd中的键
+const loadWeightsKey = "/config/load-weights"
+
+// LoadWeightsState 按负载调整权重的运行时开关，所有节点共享，用于在不重启的情况下立即关闭
+type LoadWeightsState struct {
+	Disabled  bool      `json:"disabled"`         // 是否关闭，关闭后所有实例使用配置的权重
+	Reason    string    `json:"reason,omitempty"` // 关闭的原因
+	UpdatedAt time.Time `json:"updated_at"`       // 更新时间
+}
+
+// GetLoadWeightsState 获取按负载调整权重的运行时开关，未设置时返回nil
+func (e *EtcdClient) GetLoadWeightsState(ctx context.Context) (*LoadWeightsState, error) {
+	if e.conn() == nil {
+		return nil, fmt.Errorf("etcd客户端未连接")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, etcdTimeout)
+	defer cancel()
+
+	resp, err := e.conn().Get(ctx, loadWeightsKey)
+	if err != nil {
+		return nil, fmt.Errorf("获取负载权重开关失败: %w", err)
+	}
+	if len(resp.Kvs) == 0 {
+		return nil, nil
+	}
+
+	var state LoadWeightsState
+	if err := json.Unmarshal(resp.Kvs[0].Value, &state); err != nil {
+		return nil, fmt.Errorf("解析负载权重开关失败: %w", err)
+	}
+	return &state, nil
+}
+
+// PutLoadWeightsState 设置按负载调整权重的运行时开关
+func (e *EtcdClient) PutLoadWeightsState(ctx context.Context, state *LoadWeightsState) error {
+	if e.conn() == nil {
+		return fmt.Errorf("etcd客户端未连接")
+	}
+
+	state.UpdatedAt = time.Now()
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("序列化负载权重开关失败: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, etcdTimeout)
+	defer cancel()
+
+	if _, err := e.conn().Put(ctx, loadWeightsKey, string(data)); err != nil {
+		e.log(ctx).Error("保存负载权重开关失败", zap.Error(err))
+		return fmt.Errorf("保存负载权重开关失败: %w", err)
+	}
+
+	e.log(ctx).Warn("负载权重开关已更新", zap.Bool("disabled", state.Disabled), zap.String("reason", state.Reason))
+	return nil
+}
+
+// LoadScore 实例平滑后的负载
+type LoadScore struct {
+	Metric    string    `json:"metric"`     // 负载指标
+	Value     float64   `json:"value"`      // 平滑后的值
+	UpdatedAt time.Time `json:"updated_at"` // 最近一次更新的时间
+}
+
+// smoothLoad 用心跳上报的负载更新实例平滑后的负载：上一次的值仍在有效期内时做指数平滑，否则直接使用本次的值。
+// 未开启按负载调整权重或上报中没有配置的指标时不更新
+func (e *EtcdClient) smoothLoad(instance *ServiceInstance, report *HealthReport, now time.Time) {
+	cfg := e.cfg.DNS.LoadWeights
+	if !cfg.Enabled {
+		return
+	}
+	value, ok := report.Load[cfg.Metric]
+	if !ok || math.IsNaN(value) || math.IsInf(value, 0) {
+		return
+	}
+	if value < 0 {
+		value = 0
+	}
+
+	if prev := instance.LoadScore; prev != nil && now.Sub(prev.UpdatedAt) <= e.loadMaxAge() {
+		value = cfg.Smoothing*value + (1-cfg.Smoothing)*prev.Value
+	}
+	instance.LoadScore = &LoadScore{Metric: cfg.Metric, Value: value, UpdatedAt: now}
+}
+
+// loadMaxAge 返回负载上报的有效期
+func (e *EtcdClient) loadMaxAge() time.Duration {
+	return time.Duration(e.cfg.DNS.LoadWeights.MaxAge) * time.Second
+}
+
+// loadWeight 返回按负载调整后的权重：负载在有效期内时按负载占max_load的比例降低配置的权重，
+// 最低降到min_ratio，且不小于1（权重为0的SRV记录只在没有其他记录时使用）；配置的权重为0时保持0
+func (e *EtcdClient) loadWeight(instance *ServiceInstance, weight int, now time.Time) int {
+	cfg := e.cfg.DNS.LoadWeights
+	score := instance.LoadScore
+	if weight == 0 || score == nil || score.Metric != cfg.Metric || now.Sub(score.UpdatedAt) > e.loadMaxAge() {
+		return weight
+	}
+
+	load := math.Min(math.Max(score.Value/cfg.MaxLoad, 0), 1)
+	adjusted := int(math.Round(float64(weight) * (1 - load*(1-cfg.MinRatio))))
+	return max(adjusted, 1)
+}
+
+// pickWeighted 按权重随机选取一个实例，权重都为0时返回第一个
+func pickWeighted(instances []*ServiceInstance, weights []int) *ServiceInstance {
+	total := 0
+	for _, w := range weights {
+		total += w
+	}
+	if total == 0 {
+		return instances[0]
+	}
+	n := rand.Intn(total)
+	for i, w := range weights {
+		if n < w {
+			return instances[i]
+		}
+		n -= w
+	}
+	return instances[len(instances)-1]
+}
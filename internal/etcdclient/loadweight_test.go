@@ -0,0 +1,107 @@
+package etcdclient
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hewenyu/kong-discovery/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadWeight(t *testing.T) {
+	client := &EtcdClient{cfg: &config.Config{}}
+	lw := &client.cfg.DNS.LoadWeights
+	lw.Enabled, lw.Metric, lw.MaxLoad, lw.Smoothing, lw.MinRatio, lw.MaxAge = true, "cpu", 1, 0.5, 0.1, 60
+	now := time.Now()
+	instance := &ServiceInstance{}
+
+	// 没有负载时使用配置的权重
+	assert.Equal(t, 100, client.loadWeight(instance, 100, now))
+
+	// 第一次上报直接使用，之后按smoothing平滑
+	client.smoothLoad(instance, &HealthReport{Load: map[string]float64{"cpu": 0.8}}, now)
+	assert.InDelta(t, 0.8, instance.LoadScore.Value, 1e-9)
+	client.smoothLoad(instance, &HealthReport{Load: map[string]float64{"cpu": 0.2}}, now)
+	assert.InDelta(t, 0.5, instance.LoadScore.Value, 1e-9)
+	client.smoothLoad(instance, &HealthReport{Load: map[string]float64{"mem": 0.9}}, now)
+	assert.InDelta(t, 0.5, instance.LoadScore.Value, 1e-9)
+
+	// 100 × (1 - 0.5 × 0.9) = 55
+	assert.Equal(t, 55, client.loadWeight(instance, 100, now))
+	// 超过满载时降到min_ratio，且不小于1
+	instance.LoadScore.Value = 3
+	assert.Equal(t, 10, client.loadWeight(instance, 100, now))
+	assert.Equal(t, 1, client.loadWeight(instance, 5, now))
+	assert.Equal(t, 0, client.loadWeight(instance, 0, now))
+
+	// 过期的负载不再生效，之后的上报重新开始平滑
+	later := now.Add(61 * time.Second)
+	assert.Equal(t, 100, client.loadWeight(instance, 100, later))
+	client.smoothLoad(instance, &HealthReport{Load: map[string]float64{"cpu": 0.1}}, later)
+	assert.InDelta(t, 0.1, instance.LoadScore.Value, 1e-9)
+
+	// 未开启时不记录负载
+	client.cfg.DNS.LoadWeights.Enabled = false
+	other := &ServiceInstance{}
+	client.smoothLoad(other, &HealthReport{Load: map[string]float64{"cpu": 0.8}}, now)
+	assert.Nil(t, other.LoadScore)
+}
+
+func TestEtcdClient_LoadWeights(t *testing.T) {
+	// 跳过集成测试，除非明确要求运行
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	cfg := createTestConfig(t)
+	lw := &cfg.DNS.LoadWeights
+	lw.Enabled, lw.Metric, lw.MaxLoad, lw.Smoothing, lw.MinRatio, lw.MaxAge = true, "cpu", 1, 1, 0.1, 60
+	client := NewEtcdClient(cfg, createTestLogger(t))
+	require.NoError(t, client.Connect())
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	serviceName := fmt.Sprintf("load-service-%d", time.Now().UnixNano())
+	domain := serviceName + ".default.svc.cluster.local"
+	for i, ip := range []string{"10.0.0.1", "10.0.0.2"} {
+		id := fmt.Sprintf("load-%03d", i+1)
+		require.NoError(t, client.RegisterService(ctx, &ServiceInstance{
+			ServiceName: serviceName, InstanceID: id, IPAddress: ip, Port: 8080, TTL: 30,
+			Metadata: map[string]string{MetadataSRVWeight: "100"},
+		}))
+		defer func() { _ = client.DeregisterService(context.Background(), serviceName, id) }()
+	}
+
+	_, err := client.RefreshServiceLeaseWithReport(ctx, serviceName, "load-001", 0, &HealthReport{Load: map[string]float64{"cpu": 1}})
+	require.NoError(t, err)
+
+	srvWeights := func(opts ServiceRecordOptions) map[string]string {
+		records, err := client.ServiceToDNSRecordsWithOptions(ctx, domain, opts)
+		require.NoError(t, err)
+		weights := make(map[string]string)
+		for _, record := range records {
+			if record.Type == "SRV" {
+				fields := strings.Fields(record.Value)
+				weights[record.Address] = fields[1]
+			}
+		}
+		return weights
+	}
+	assert.Equal(t, map[string]string{"10.0.0.1": "10", "10.0.0.2": "100"}, srvWeights(ServiceRecordOptions{LoadWeights: true}))
+	assert.Equal(t, map[string]string{"10.0.0.1": "100", "10.0.0.2": "100"}, srvWeights(ServiceRecordOptions{}))
+
+	// 运行时开关
+	defer func() { _ = client.PutLoadWeightsState(context.Background(), &LoadWeightsState{}) }()
+	require.NoError(t, client.PutLoadWeightsState(ctx, &LoadWeightsState{Disabled: true, Reason: "test"}))
+	state, err := client.GetLoadWeightsState(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, state)
+	assert.True(t, state.Disabled)
+	assert.Equal(t, "test", state.Reason)
+}
@@ -0,0 +1,80 @@
+package etcdclient
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEtcdClient_Maintenance(t *testing.T) {
+	// 跳过集成测试，除非明确要求运行
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	client := CreateEtcdClientForTest(t)
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	serviceName := fmt.Sprintf("maint-service-%d", time.Now().UnixNano())
+	domain := serviceName + ".svc.cluster.local"
+	for _, id := range []string{"instance-001", "instance-002"} {
+		err := client.RegisterService(ctx, &ServiceInstance{
+			ServiceName: serviceName,
+			InstanceID:  id,
+			IPAddress:   "10.0.0.1",
+			Port:        8080,
+			TTL:         30,
+			Metadata:    map[string]string{"version": "1.0.0"},
+		})
+		require.NoError(t, err)
+		defer func(id string) { _ = client.DeregisterService(context.Background(), serviceName, id) }(id)
+	}
+
+	// 单个实例进入维护模式后不参与DNS应答，但注册数据保留
+	instance, err := client.SetInstanceMaintenance(ctx, serviceName, "instance-001", true)
+	require.NoError(t, err)
+	assert.True(t, instance.Maintenance)
+	assert.Equal(t, "1.0.0", instance.Metadata["version"], "维护模式不应丢失元数据")
+
+	records, err := client.ServiceToDNSRecords(ctx, domain)
+	require.NoError(t, err)
+	assert.Contains(t, records["SRV-0"].Value, "instance-002")
+	assert.NotContains(t, records, "SRV-1")
+
+	// 整个服务进入维护模式
+	err = client.SetServiceMaintenance(ctx, serviceName, true, "升级数据库")
+	require.NoError(t, err)
+	defer func() { _ = client.SetServiceMaintenance(context.Background(), serviceName, false, "") }()
+
+	info, err := client.GetServiceMaintenance(ctx, serviceName)
+	require.NoError(t, err)
+	require.NotNil(t, info)
+	assert.Equal(t, "升级数据库", info.Reason)
+
+	_, err = client.ServiceToDNSRecords(ctx, domain)
+	assert.Error(t, err, "维护中的服务不应返回DNS记录")
+
+	instances, err := client.GetServiceInstances(ctx, serviceName)
+	require.NoError(t, err)
+	assert.Len(t, instances, 2, "维护模式不应删除注册数据")
+
+	// 退出维护模式后恢复
+	require.NoError(t, client.SetServiceMaintenance(ctx, serviceName, false, ""))
+	_, err = client.SetInstanceMaintenance(ctx, serviceName, "instance-001", false)
+	require.NoError(t, err)
+
+	info, err = client.GetServiceMaintenance(ctx, serviceName)
+	require.NoError(t, err)
+	assert.Nil(t, info)
+
+	records, err = client.ServiceToDNSRecords(ctx, domain)
+	require.NoError(t, err)
+	assert.Contains(t, records, "SRV-1")
+}
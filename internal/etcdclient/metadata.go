@@ -0,0 +1,116 @@
+package etcdclient
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hewenyu/kong-discovery/internal/config"
+)
+
+// ErrInvalidMetadata 实例元数据不符合限制或类型约定
+var ErrInvalidMetadata = errors.New("无效的实例元数据")
+
+// ReservedMetadataPrefix 保留给kong-discovery内部使用的元数据键前缀，客户端注册时不能设置
+const ReservedMetadataPrefix = "kd."
+
+// maxMetadataKeyLength 元数据键的最大长度
+const maxMetadataKeyLength = 128
+
+// 元数据值的类型
+const (
+	MetadataTypeString = "string" // 任意字符串
+	MetadataTypeInt    = "int"    // 整数
+	MetadataTypeUint16 = "uint16" // 0到65535之间的整数
+	MetadataTypeFloat  = "float"  // 浮点数
+	MetadataTypeBool   = "bool"   // true或false
+)
+
+// builtinMetadataTypes 内置元数据键的类型，配置中可以覆盖或增加其他键的类型
+var builtinMetadataTypes = map[string]string{
+	MetadataSRVPriority: MetadataTypeUint16,
+	MetadataSRVWeight:   MetadataTypeUint16,
+}
+
+// ValidMetadataType 判断是否为支持的元数据值类型
+func ValidMetadataType(typ string) bool {
+	switch typ {
+	case MetadataTypeString, MetadataTypeInt, MetadataTypeUint16, MetadataTypeFloat, MetadataTypeBool:
+		return true
+	}
+	return false
+}
+
+// ValidateMetadata 按限制校验客户端提交的实例元数据：键数量、键名、值大小、保留前缀和声明了类型的键的取值
+// 元数据会出现在TXT应答、Consul和Eureka兼容API的响应中，不加限制时过大的值会导致应答生成失败
+func ValidateMetadata(metadata map[string]string, limits config.MetadataLimits) error {
+	if limits.MaxKeys > 0 && len(metadata) > limits.MaxKeys {
+		return fmt.Errorf("%w: 键数量%d超过上限%d", ErrInvalidMetadata, len(metadata), limits.MaxKeys)
+	}
+
+	// 按键排序，多个键无效时总是报告同一个
+	keys := make([]string, 0, len(metadata))
+	for key := range metadata {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if err := validateMetadataKey(key); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidMetadata, err)
+		}
+		value := metadata[key]
+		if limits.MaxValueSize > 0 && len(value) > limits.MaxValueSize {
+			return fmt.Errorf("%w: 键%s的值大小%d字节超过上限%d字节", ErrInvalidMetadata, key, len(value), limits.MaxValueSize)
+		}
+		typ, ok := limits.Types[key]
+		if !ok {
+			typ = builtinMetadataTypes[key]
+		}
+		if !metadataValueMatches(typ, value) {
+			return fmt.Errorf("%w: 键%s的值%q不是%s类型", ErrInvalidMetadata, key, value, typ)
+		}
+	}
+	return nil
+}
+
+// validateMetadataKey 校验元数据键：非空、不超过最大长度、只包含可见的ASCII字符且不含等号（TXT应答使用key=value格式），
+// 不能使用保留前缀
+func validateMetadataKey(key string) error {
+	if key == "" {
+		return fmt.Errorf("键不能为空")
+	}
+	if len(key) > maxMetadataKeyLength {
+		return fmt.Errorf("键%.32s...的长度超过%d", key, maxMetadataKeyLength)
+	}
+	for i := 0; i < len(key); i++ {
+		if key[i] <= ' ' || key[i] > '~' || key[i] == '=' {
+			return fmt.Errorf("键%q包含不允许的字符", key)
+		}
+	}
+	if strings.HasPrefix(strings.ToLower(key), ReservedMetadataPrefix) {
+		return fmt.Errorf("键%s使用了保留前缀%s", key, ReservedMetadataPrefix)
+	}
+	return nil
+}
+
+// metadataValueMatches 判断值是否符合类型，未声明类型的键接受任意值
+func metadataValueMatches(typ, value string) bool {
+	switch typ {
+	case MetadataTypeInt:
+		_, err := strconv.ParseInt(value, 10, 64)
+		return err == nil
+	case MetadataTypeUint16:
+		_, err := strconv.ParseUint(value, 10, 16)
+		return err == nil
+	case MetadataTypeFloat:
+		_, err := strconv.ParseFloat(value, 64)
+		return err == nil
+	case MetadataTypeBool:
+		return value == "true" || value == "false"
+	default:
+		return true
+	}
+}
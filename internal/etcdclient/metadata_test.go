@@ -0,0 +1,54 @@
+package etcdclient
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/hewenyu/kong-discovery/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateMetadata(t *testing.T) {
+	limits := config.MetadataLimits{
+		MaxKeys:      3,
+		MaxValueSize: 16,
+		Types:        map[string]string{"canary": MetadataTypeBool, "ratio": MetadataTypeFloat},
+	}
+
+	tests := []struct {
+		name     string
+		metadata map[string]string
+		valid    bool
+	}{
+		{"空元数据", nil, true},
+		{"普通键值", map[string]string{"version": "v1", "zone": "a"}, true},
+		{"声明类型的键", map[string]string{"canary": "true", "ratio": "0.5", MetadataSRVWeight: "20"}, true},
+		{"键数量超过上限", map[string]string{"a": "1", "b": "2", "c": "3", "d": "4"}, false},
+		{"值超过大小上限", map[string]string{"blob": strings.Repeat("x", 17)}, false},
+		{"保留前缀", map[string]string{"kd.domain": "order.example.com"}, false},
+		{"保留前缀不区分大小写", map[string]string{"KD.domain": "order.example.com"}, false},
+		{"空键", map[string]string{"": "v"}, false},
+		{"键包含等号", map[string]string{"a=b": "v"}, false},
+		{"键包含空格", map[string]string{"a b": "v"}, false},
+		{"布尔类型不符", map[string]string{"canary": "yes"}, false},
+		{"浮点类型不符", map[string]string{"ratio": "half"}, false},
+		{"内置的SRV权重超出范围", map[string]string{MetadataSRVWeight: "70000"}, false},
+		{"内置的SRV优先级不是整数", map[string]string{MetadataSRVPriority: "high"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateMetadata(tt.metadata, limits)
+			if tt.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.True(t, errors.Is(err, ErrInvalidMetadata), "应返回ErrInvalidMetadata: %v", err)
+			}
+		})
+	}
+
+	// 数量和大小为0时不限制，保留前缀总是检查
+	unlimited := config.MetadataLimits{}
+	assert.NoError(t, ValidateMetadata(map[string]string{"a": strings.Repeat("x", 1<<20), "b": "", "c": "", "d": ""}, unlimited))
+	assert.ErrorIs(t, ValidateMetadata(map[string]string{"kd.owner": "x"}, unlimited), ErrInvalidMetadata)
+}
@@ -0,0 +1,296 @@
+package etcdclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.uber.org/zap"
+)
+
+// DefaultNamespace 未指定命名空间的服务和记录归属的命名空间
+const DefaultNamespace = "default"
+
+// SystemNamespace 保留的命名空间，kong-discovery各节点把自身的端点注册在其中，不能删除
+const SystemNamespace = "kong-discovery-system"
+
+// 命名空间在etcd中的键前缀
+const namespacePrefix = "/namespaces/"
+
+// Namespace 表示一个命名空间
+type Namespace struct {
+	Name      string          `json:"name"`            // 命名空间名称
+	Quota     *NamespaceQuota `json:"quota,omitempty"` // 配额，为空时使用全局默认配额
+	CreatedAt time.Time       `json:"created_at"`      // 创建时间
+
+	// 通过服务域名的TXT查询公开的实例元数据键，为nil时使用全局默认设置
+	TXTMetadata []string `json:"txt_metadata,omitempty"`
+
+	// 命名空间覆盖的上游DNS和转发设置，为空时使用全局上游配置
+	Upstream *NamespaceUpstream `json:"upstream,omitempty"`
+
+	// 命名空间的TTL策略，为空时使用全局设置
+	TTLPolicy *TTLPolicy `json:"ttl_policy,omitempty"`
+}
+
+// NamespaceOf 返回命名空间名称，空字符串视为默认命名空间
+func NamespaceOf(namespace string) string {
+	if namespace == "" {
+		return DefaultNamespace
+	}
+	return namespace
+}
+
+// getNamespaceKey 生成命名空间的etcd键
+func getNamespaceKey(name string) string {
+	return namespacePrefix + name
+}
+
+// GetNamespace 获取命名空间，不存在时返回nil
+func (e *EtcdClient) GetNamespace(ctx context.Context, name string) (*Namespace, error) {
+	if e.conn() == nil {
+		return nil, fmt.Errorf("etcd客户端未连接")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, etcdTimeout)
+	defer cancel()
+
+	resp, err := e.conn().Get(ctx, getNamespaceKey(name))
+	if err != nil {
+		e.log(ctx).Error("获取命名空间失败", zap.String("namespace", name), zap.Error(err))
+		return nil, fmt.Errorf("获取命名空间失败: %w", err)
+	}
+
+	if len(resp.Kvs) == 0 {
+		return nil, nil
+	}
+
+	var ns Namespace
+	if err := json.Unmarshal(resp.Kvs[0].Value, &ns); err != nil {
+		return nil, fmt.Errorf("解析命名空间失败: %w", err)
+	}
+
+	return &ns, nil
+}
+
+// PutNamespace 保存命名空间
+func (e *EtcdClient) PutNamespace(ctx context.Context, ns *Namespace) error {
+	if e.conn() == nil {
+		return fmt.Errorf("etcd客户端未连接")
+	}
+
+	if ns.Name == "" {
+		return fmt.Errorf("命名空间名称不能为空")
+	}
+	if ns.CreatedAt.IsZero() {
+		ns.CreatedAt = time.Now()
+	}
+
+	data, err := json.Marshal(ns)
+	if err != nil {
+		return fmt.Errorf("序列化命名空间失败: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, etcdTimeout)
+	defer cancel()
+
+	if _, err := e.conn().Put(ctx, getNamespaceKey(ns.Name), string(data)); err != nil {
+		e.log(ctx).Error("保存命名空间失败", zap.String("namespace", ns.Name), zap.Error(err))
+		return fmt.Errorf("保存命名空间失败: %w", err)
+	}
+
+	e.log(ctx).Info("命名空间保存成功", zap.String("namespace", ns.Name))
+	return nil
+}
+
+var (
+	// ErrNamespaceNotFound 命名空间不存在
+	ErrNamespaceNotFound = errors.New("命名空间不存在")
+
+	// ErrNamespaceExists 命名空间已存在
+	ErrNamespaceExists = errors.New("命名空间已存在")
+
+	// ErrNamespaceNotEmpty 命名空间中仍有服务或DNS记录
+	ErrNamespaceNotEmpty = errors.New("命名空间不为空")
+
+	// ErrNamespaceMismatch 实例已在其他命名空间注册，重复注册不能改变实例所在的命名空间
+	ErrNamespaceMismatch = errors.New("实例已在其他命名空间注册")
+)
+
+// 命名空间名称格式：小写字母、数字和连字符，以字母或数字开头和结尾
+var namespaceNamePattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$`)
+
+// ValidateNamespaceName 校验命名空间名称，名称会作为DNS标签使用
+func ValidateNamespaceName(name string) error {
+	if !namespaceNamePattern.MatchString(name) {
+		return fmt.Errorf("无效的命名空间名称: %q，只能包含小写字母、数字和连字符，长度不超过63", name)
+	}
+	return nil
+}
+
+// ListNamespaces 列出所有命名空间，默认命名空间总是存在
+func (e *EtcdClient) ListNamespaces(ctx context.Context) ([]*Namespace, error) {
+	if e.conn() == nil {
+		return nil, fmt.Errorf("etcd客户端未连接")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, etcdTimeout)
+	defer cancel()
+
+	resp, err := e.conn().Get(ctx, namespacePrefix, clientv3.WithPrefix())
+	if err != nil {
+		e.log(ctx).Error("获取命名空间列表失败", zap.Error(err))
+		return nil, fmt.Errorf("获取命名空间列表失败: %w", err)
+	}
+
+	namespaces := make([]*Namespace, 0, len(resp.Kvs)+1)
+	hasDefault := false
+	for _, kv := range resp.Kvs {
+		var ns Namespace
+		if err := json.Unmarshal(kv.Value, &ns); err != nil {
+			e.log(ctx).Warn("解析命名空间失败", zap.String("key", string(kv.Key)), zap.Error(err))
+			continue
+		}
+		if ns.Name == DefaultNamespace {
+			hasDefault = true
+		}
+		namespaces = append(namespaces, &ns)
+	}
+
+	if !hasDefault {
+		namespaces = append([]*Namespace{{Name: DefaultNamespace}}, namespaces...)
+	}
+
+	return namespaces, nil
+}
+
+// CreateNamespace 创建命名空间，已存在时返回ErrNamespaceExists
+func (e *EtcdClient) CreateNamespace(ctx context.Context, ns *Namespace) error {
+	if e.conn() == nil {
+		return fmt.Errorf("etcd客户端未连接")
+	}
+
+	if err := ValidateNamespaceName(ns.Name); err != nil {
+		return err
+	}
+	if err := e.checkReservedNamespace(ns.Name); err != nil {
+		return err
+	}
+	if ns.CreatedAt.IsZero() {
+		ns.CreatedAt = time.Now()
+	}
+
+	data, err := json.Marshal(ns)
+	if err != nil {
+		return fmt.Errorf("序列化命名空间失败: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, etcdTimeout)
+	defer cancel()
+
+	key := getNamespaceKey(ns.Name)
+	resp, err := e.conn().Txn(ctx).
+		If(clientv3.Compare(clientv3.CreateRevision(key), "=", 0)).
+		Then(clientv3.OpPut(key, string(data))).
+		Commit()
+	if err != nil {
+		e.log(ctx).Error("创建命名空间失败", zap.String("namespace", ns.Name), zap.Error(err))
+		return fmt.Errorf("创建命名空间失败: %w", err)
+	}
+	if !resp.Succeeded {
+		return fmt.Errorf("%w: %s", ErrNamespaceExists, ns.Name)
+	}
+
+	e.log(ctx).Info("命名空间创建成功", zap.String("namespace", ns.Name))
+	return nil
+}
+
+// DeleteNamespace 删除命名空间
+// cascade为false时命名空间不为空则拒绝删除；为true时一并删除其中的服务实例和DNS记录，命名空间的自定义区域总是一并删除
+func (e *EtcdClient) DeleteNamespace(ctx context.Context, name string, cascade bool) error {
+	if e.conn() == nil {
+		return fmt.Errorf("etcd客户端未连接")
+	}
+
+	instances, recordKeys, err := e.checkNamespaceDeletable(ctx, name, cascade)
+	if err != nil {
+		return err
+	}
+
+	zoneKeys, err := e.namespaceZoneKeys(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	keys := append(recordKeys, zoneKeys...)
+	for _, instance := range instances {
+		keys = append(keys, getServiceInstanceKey(instance.ServiceName, instance.InstanceID))
+	}
+	keys = append(keys, getNamespaceMembersKey(name), getNamespaceKey(name))
+
+	ctx, cancel := context.WithTimeout(ctx, etcdTimeout)
+	defer cancel()
+
+	for _, key := range keys {
+		if _, err := e.conn().Delete(ctx, key); err != nil {
+			e.log(ctx).Error("删除命名空间数据失败", zap.String("key", key), zap.Error(err))
+			return fmt.Errorf("删除命名空间数据失败: %w", err)
+		}
+	}
+
+	e.log(ctx).Info("命名空间删除成功",
+		zap.String("namespace", name),
+		zap.Int("instances", len(instances)),
+		zap.Int("dns_records", len(recordKeys)))
+	return nil
+}
+
+// checkNamespaceDeletable 校验命名空间可以删除，返回其中的服务实例和DNS记录的键
+func (e *EtcdClient) checkNamespaceDeletable(ctx context.Context, name string, cascade bool) ([]*ServiceInstance, []string, error) {
+	if name == DefaultNamespace || name == SystemNamespace {
+		return nil, nil, fmt.Errorf("保留的命名空间不能删除: %s", name)
+	}
+
+	ns, err := e.GetNamespace(ctx, name)
+	if err != nil {
+		return nil, nil, err
+	}
+	if ns == nil {
+		return nil, nil, fmt.Errorf("%w: %s", ErrNamespaceNotFound, name)
+	}
+
+	instances, err := e.listNamespaceInstances(ctx, name)
+	if err != nil {
+		return nil, nil, err
+	}
+	recordKeys, err := e.namespaceDNSRecordKeys(ctx, name)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !cascade && (len(instances) > 0 || len(recordKeys) > 0) {
+		return nil, nil, fmt.Errorf("%w: %s 中仍有%d个实例和%d条DNS记录", ErrNamespaceNotEmpty, name, len(instances), len(recordKeys))
+	}
+	return instances, recordKeys, nil
+}
+
+// ensureNamespace 校验命名空间是否存在，默认命名空间总是存在
+func (e *EtcdClient) ensureNamespace(ctx context.Context, name string) error {
+	name = NamespaceOf(name)
+	if name == DefaultNamespace {
+		return nil
+	}
+
+	ns, err := e.GetNamespace(ctx, name)
+	if err != nil {
+		return err
+	}
+	if ns == nil {
+		return fmt.Errorf("%w: %s", ErrNamespaceNotFound, name)
+	}
+	return nil
+}
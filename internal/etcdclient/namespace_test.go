@@ -0,0 +1,139 @@
+package etcdclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateNamespaceName(t *testing.T) {
+	assert.NoError(t, ValidateNamespaceName("team-a"))
+	assert.NoError(t, ValidateNamespaceName("a1"))
+	assert.Error(t, ValidateNamespaceName(""))
+	assert.Error(t, ValidateNamespaceName("Team"))
+	assert.Error(t, ValidateNamespaceName("-team"))
+	assert.Error(t, ValidateNamespaceName("team.a"))
+}
+
+func TestEtcdClient_NamespaceLifecycle(t *testing.T) {
+	// 跳过集成测试，除非明确要求运行
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	client := CreateEtcdClientForTest(t)
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	name := fmt.Sprintf("ns-%d", time.Now().UnixNano())
+	service := name + "-svc"
+	instance := &ServiceInstance{
+		ServiceName: service,
+		InstanceID:  "instance-001",
+		IPAddress:   "10.0.0.1",
+		Port:        8080,
+		TTL:         30,
+		Namespace:   name,
+	}
+
+	// 命名空间不存在时拒绝注册
+	err := client.RegisterService(ctx, instance)
+	assert.True(t, errors.Is(err, ErrNamespaceNotFound))
+
+	// 创建命名空间，重复创建返回冲突
+	require.NoError(t, client.CreateNamespace(ctx, &Namespace{Name: name}))
+	defer func() { _ = client.DeleteNamespace(context.Background(), name, true) }()
+	err = client.CreateNamespace(ctx, &Namespace{Name: name})
+	assert.True(t, errors.Is(err, ErrNamespaceExists))
+
+	namespaces, err := client.ListNamespaces(ctx)
+	require.NoError(t, err)
+	names := make([]string, 0, len(namespaces))
+	for _, ns := range namespaces {
+		names = append(names, ns.Name)
+	}
+	assert.Contains(t, names, DefaultNamespace, "默认命名空间总是存在")
+	assert.Contains(t, names, name)
+
+	// 注册服务和DNS记录
+	require.NoError(t, client.RegisterService(ctx, instance))
+	require.NoError(t, client.PutDNSRecord(ctx, name+".example.com", &DNSRecord{Type: "A", Value: "10.0.0.2", Namespace: name}))
+
+	// 非空命名空间默认拒绝删除
+	err = client.DeleteNamespace(ctx, name, false)
+	assert.True(t, errors.Is(err, ErrNamespaceNotEmpty))
+
+	// 级联删除命名空间及其数据
+	require.NoError(t, client.DeleteNamespace(ctx, name, true))
+
+	ns, err := client.GetNamespace(ctx, name)
+	require.NoError(t, err)
+	assert.Nil(t, ns)
+
+	instances, err := client.GetServiceInstances(ctx, service)
+	require.NoError(t, err)
+	assert.Empty(t, instances)
+
+	_, err = client.GetDNSRecord(ctx, name+".example.com", "A")
+	assert.Error(t, err, "DNS记录应被级联删除")
+
+	// 默认命名空间不能删除
+	assert.Error(t, client.DeleteNamespace(ctx, DefaultNamespace, true))
+}
+
+func TestEtcdClient_NamespaceMismatch(t *testing.T) {
+	// 跳过集成测试，除非明确要求运行
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	client := CreateEtcdClientForTest(t)
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	namespace := fmt.Sprintf("mismatch-ns-%d", time.Now().UnixNano())
+	require.NoError(t, client.CreateNamespace(ctx, &Namespace{Name: namespace}))
+	defer func() { _ = client.DeleteNamespace(context.Background(), namespace, true) }()
+
+	service := namespace + "-svc"
+	defer func() {
+		_ = client.DeregisterService(context.Background(), service, "instance-001")
+		_ = client.DeregisterService(context.Background(), service, "instance-002")
+	}()
+	instance := func(id, ns string) *ServiceInstance {
+		return &ServiceInstance{ServiceName: service, InstanceID: id, IPAddress: "10.0.0.1", Port: 8080, TTL: 30, Namespace: ns}
+	}
+	require.NoError(t, client.RegisterService(ctx, instance("instance-001", namespace)))
+
+	// 同一命名空间中重复注册成功
+	require.NoError(t, client.RegisterService(ctx, instance("instance-001", namespace)))
+
+	// 重复注册不能把实例移到其他命名空间，原实例保持不变
+	err := client.RegisterService(ctx, instance("instance-001", ""))
+	assert.True(t, errors.Is(err, ErrNamespaceMismatch), "改变命名空间的重复注册应返回ErrNamespaceMismatch")
+	stored, err := client.GetServiceInstances(ctx, service)
+	require.NoError(t, err)
+	require.Len(t, stored, 1)
+	assert.Equal(t, namespace, stored[0].Namespace)
+
+	// 不同命名空间中的同名服务分别列出
+	require.NoError(t, client.RegisterService(ctx, instance("instance-002", "")))
+	services, err := client.ListServices(ctx)
+	require.NoError(t, err)
+	counts := make(map[string]int)
+	for _, summary := range services {
+		if summary.ServiceName == service {
+			counts[summary.Namespace] = summary.Instances
+		}
+	}
+	assert.Equal(t, map[string]int{namespace: 1, DefaultNamespace: 1}, counts)
+}
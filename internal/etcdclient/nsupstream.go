@@ -0,0 +1,64 @@
+package etcdclient
+
+import (
+	"context"
+	"fmt"
+	"net"
+)
+
+// NamespaceUpstream 命名空间覆盖的上游DNS和转发设置，
+// 用于该命名空间的服务域名和自定义区域中未能在本地应答的查询，以及来自ClientSubnets的查询
+type NamespaceUpstream struct {
+	Servers       []string `json:"servers"`                  // 上游DNS服务器地址列表
+	Mode          string   `json:"mode,omitempty"`           // 转发模式 (sequential 或 race)
+	RaceCount     int      `json:"race_count,omitempty"`     // race模式下同时查询的上游数量
+	ClientSubnets []string `json:"client_subnets,omitempty"` // 属于该命名空间的客户端网段（CIDR），来自这些网段的查询同样使用命名空间的上游
+}
+
+// Validate 校验命名空间的上游DNS设置
+func (u *NamespaceUpstream) Validate() error {
+	upstream := UpstreamConfig{Servers: u.Servers, Mode: u.Mode, RaceCount: u.RaceCount}
+	if err := upstream.Validate(); err != nil {
+		return err
+	}
+	for _, cidr := range u.ClientSubnets {
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			return fmt.Errorf("无效的客户端网段: %s", cidr)
+		}
+	}
+	return nil
+}
+
+// GetNamespaceUpstream 获取命名空间覆盖的上游DNS设置，命名空间不存在或未覆盖时返回nil
+func (e *EtcdClient) GetNamespaceUpstream(ctx context.Context, namespace string) (*NamespaceUpstream, error) {
+	ns, err := e.GetNamespace(ctx, NamespaceOf(namespace))
+	if err != nil || ns == nil {
+		return nil, err
+	}
+	return ns.Upstream, nil
+}
+
+// PutNamespaceUpstream 设置命名空间覆盖的上游DNS设置，upstream为nil时恢复使用全局配置；
+// 默认命名空间首次设置时自动创建，DNS服务器在下次加载运行时配置时生效
+func (e *EtcdClient) PutNamespaceUpstream(ctx context.Context, namespace string, upstream *NamespaceUpstream) error {
+	namespace = NamespaceOf(namespace)
+	if upstream != nil {
+		if err := upstream.Validate(); err != nil {
+			return err
+		}
+	}
+
+	ns, err := e.GetNamespace(ctx, namespace)
+	if err != nil {
+		return err
+	}
+	if ns == nil {
+		if namespace != DefaultNamespace {
+			return fmt.Errorf("%w: %s", ErrNamespaceNotFound, namespace)
+		}
+		ns = &Namespace{Name: namespace}
+	}
+	ns.Upstream = upstream
+
+	return e.PutNamespace(ctx, ns)
+}
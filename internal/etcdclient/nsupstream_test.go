@@ -0,0 +1,74 @@
+package etcdclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNamespaceUpstream_Validate(t *testing.T) {
+	assert.NoError(t, (&NamespaceUpstream{Servers: []string{"10.0.0.53:53"}, ClientSubnets: []string{"10.0.0.0/8", "fd00::/8"}}).Validate())
+	assert.Error(t, (&NamespaceUpstream{}).Validate(), "上游列表不能为空")
+	assert.Error(t, (&NamespaceUpstream{Servers: []string{"10.0.0.53:53"}, Mode: "random"}).Validate())
+	assert.Error(t, (&NamespaceUpstream{Servers: []string{"10.0.0.53:53"}, ClientSubnets: []string{"10.0.0.1"}}).Validate())
+}
+
+func TestNamespaceFromDomain(t *testing.T) {
+	namespace, ok := NamespaceFromDomain("order.team-a.svc.cluster.local.")
+	assert.True(t, ok)
+	assert.Equal(t, "team-a", namespace)
+
+	namespace, ok = NamespaceFromDomain("_http._tcp.order.Team-A.svc.cluster.local")
+	assert.True(t, ok)
+	assert.Equal(t, "team-a", namespace)
+
+	_, ok = NamespaceFromDomain("order.svc.cluster.local")
+	assert.False(t, ok)
+	_, ok = NamespaceFromDomain("example.com")
+	assert.False(t, ok)
+}
+
+func TestEtcdClient_NamespaceUpstream(t *testing.T) {
+	// 跳过集成测试，除非明确要求运行
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	client := CreateEtcdClientForTest(t)
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	namespace := fmt.Sprintf("upstream-ns-%d", time.Now().UnixNano())
+	upstream := &NamespaceUpstream{Servers: []string{"10.0.0.53:53"}, ClientSubnets: []string{"10.20.0.0/16"}}
+
+	// 不存在的命名空间
+	err := client.PutNamespaceUpstream(ctx, namespace, upstream)
+	assert.True(t, errors.Is(err, ErrNamespaceNotFound))
+
+	require.NoError(t, client.CreateNamespace(ctx, &Namespace{Name: namespace}))
+	defer func() { _ = client.DeleteNamespace(context.Background(), namespace, true) }()
+
+	got, err := client.GetNamespaceUpstream(ctx, namespace)
+	require.NoError(t, err)
+	assert.Nil(t, got)
+
+	require.NoError(t, client.PutNamespaceUpstream(ctx, namespace, upstream))
+	got, err = client.GetNamespaceUpstream(ctx, namespace)
+	require.NoError(t, err)
+	assert.Equal(t, upstream, got)
+
+	// 无效的设置不会保存
+	assert.Error(t, client.PutNamespaceUpstream(ctx, namespace, &NamespaceUpstream{}))
+
+	require.NoError(t, client.PutNamespaceUpstream(ctx, namespace, nil))
+	got, err = client.GetNamespaceUpstream(ctx, namespace)
+	require.NoError(t, err)
+	assert.Nil(t, got)
+}
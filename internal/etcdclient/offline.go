@@ -0,0 +1,257 @@
+package etcdclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/hewenyu/kong-discovery/internal/etcdapi"
+	"github.com/hewenyu/kong-discovery/internal/memstore"
+	"github.com/hewenyu/kong-discovery/internal/sqlstore"
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.uber.org/zap"
+)
+
+// ErrOffline 存储不可用，当前只能读取本地快照中的数据
+var ErrOffline = errors.New("存储不可用，当前只能读取本地快照")
+
+// 未配置时使用的重试退避和快照保存间隔
+const (
+	defaultRetryInitialBackoff = 500 * time.Millisecond
+	defaultRetryMaxBackoff     = 30 * time.Second
+	defaultSnapshotInterval    = time.Minute
+)
+
+// Offline 判断是否因存储不可用而在使用本地快照
+func (e *EtcdClient) Offline() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.offline
+}
+
+// ConnectLazy 连接存储，连接或健康检查失败时不返回错误：先从本地快照加载只读数据继续提供查询，
+// 再在后台按指数退避重试，连接成功后切换到存储并调用onConnected。关闭客户端后停止重试
+func (e *EtcdClient) ConnectLazy(onConnected func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	e.mu.Lock()
+	e.cancel = cancel
+	e.mu.Unlock()
+
+	// 热启动：已有本地快照时先用快照提供查询，在后台建立连接，缩短重启后无法解析的时间
+	if e.cfg.Etcd.Snapshot.WarmStart && e.snapshotExists() {
+		e.logger.Info("从本地快照热启动，在后台连接存储")
+		e.goOffline(ctx, onConnected, false)
+		return
+	}
+
+	client, store, err := e.tryDial(ctx)
+	if err == nil {
+		e.online(ctx, client, store, onConnected)
+		return
+	}
+	e.logger.Error("连接存储失败，使用本地快照提供只读数据并在后台重试", zap.Error(err))
+	e.goOffline(ctx, onConnected, true)
+}
+
+// snapshotExists 判断是否配置了本地快照且文件存在
+func (e *EtcdClient) snapshotExists() bool {
+	if e.cfg.Etcd.Snapshot.Path == "" {
+		return false
+	}
+	_, err := os.Stat(e.cfg.Etcd.Snapshot.Path)
+	return err == nil
+}
+
+// goOffline 切换到本地快照上的只读客户端并在后台连接存储，failed表示刚刚连接失败，首次重试前需要等待
+func (e *EtcdClient) goOffline(ctx context.Context, onConnected func(), failed bool) {
+	e.mu.Lock()
+	e.offline = true
+	e.mu.Unlock()
+	e.client.Store(e.loadSnapshot())
+
+	e.wg.Add(1)
+	go func() {
+		defer e.wg.Done()
+		e.retryConnect(ctx, onConnected, failed)
+	}()
+}
+
+// tryDial 创建客户端并检查存储状态，失败时关闭已创建的客户端
+func (e *EtcdClient) tryDial(ctx context.Context) (*clientv3.Client, *sqlstore.Store, error) {
+	client, store, err := e.dial()
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := e.status(ctx, client); err != nil {
+		closeClient(client, e.inProcess())
+		if store != nil {
+			store.Close()
+		}
+		return nil, nil, fmt.Errorf("etcd健康检查失败: %w", err)
+	}
+	return client, store, nil
+}
+
+// retryConnect 按带随机抖动的指数退避重试连接，直到成功或ctx被取消；wait为false时立即进行首次连接
+func (e *EtcdClient) retryConnect(ctx context.Context, onConnected func(), wait bool) {
+	backoff := time.Duration(e.cfg.Etcd.Retry.InitialBackoff) * time.Millisecond
+	if backoff <= 0 {
+		backoff = defaultRetryInitialBackoff
+	}
+	maxBackoff := time.Duration(e.cfg.Etcd.Retry.MaxBackoff) * time.Millisecond
+	if maxBackoff <= 0 {
+		maxBackoff = defaultRetryMaxBackoff
+	}
+
+	for attempt := 1; ; attempt++ {
+		if wait {
+			// 在[backoff/2, backoff)之间随机等待，避免多个节点同时重连
+			delay := backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(delay):
+			}
+			if backoff *= 2; backoff > maxBackoff {
+				backoff = maxBackoff
+			}
+		}
+		wait = true
+
+		client, store, err := e.tryDial(ctx)
+		if err == nil {
+			e.log(ctx).Info("已连接存储，停止使用本地快照", zap.Int("attempts", attempt))
+			e.online(ctx, client, store, onConnected)
+			return
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		e.log(ctx).Warn("连接存储失败，稍后重试", zap.Int("attempt", attempt), zap.Error(err))
+	}
+}
+
+// online 切换到已连接的客户端，关闭快照上的客户端，启动快照保存并调用onConnected
+func (e *EtcdClient) online(ctx context.Context, client *clientv3.Client, store *sqlstore.Store, onConnected func()) {
+	e.mu.Lock()
+	if ctx.Err() != nil {
+		// 等待重试期间客户端已被关闭
+		e.mu.Unlock()
+		closeClient(client, e.inProcess())
+		if store != nil {
+			store.Close()
+		}
+		return
+	}
+	e.store = store
+	e.offline = false
+	e.mu.Unlock()
+
+	if previous := e.client.Swap(client); previous != nil {
+		closeClient(previous, true)
+	}
+
+	if e.cfg.Etcd.Snapshot.Path != "" {
+		e.wg.Add(1)
+		go func() {
+			defer e.wg.Done()
+			e.saveSnapshots(ctx)
+		}()
+	}
+	if onConnected != nil {
+		onConnected()
+	}
+}
+
+// loadSnapshot 把本地快照加载到内存存储中，返回其上的只读客户端；快照不存在或无效时数据为空
+func (e *EtcdClient) loadSnapshot() *clientv3.Client {
+	store := memstore.New()
+	client := etcdapi.NewClient(etcdapi.ReadOnly(store, ErrOffline))
+
+	path := e.cfg.Etcd.Snapshot.Path
+	if path == "" {
+		e.logger.Warn("未配置本地快照，存储恢复前只能转发查询")
+		return client
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		e.logger.Warn("读取本地快照失败，存储恢复前只能转发查询", zap.String("path", path), zap.Error(err))
+		return client
+	}
+	defer f.Close()
+
+	snapshot, err := ReadArchive(f)
+	if err != nil {
+		e.logger.Warn("本地快照无效，存储恢复前只能转发查询", zap.String("path", path), zap.Error(err))
+		return client
+	}
+	var count int
+	for _, entries := range snapshot.Entries {
+		for _, entry := range entries {
+			// 服务实例不关联租约，切换到存储前不会过期
+			store.Put(context.Background(), &pb.PutRequest{Key: []byte(entry.Key), Value: []byte(entry.Value)})
+			count++
+		}
+	}
+	e.logger.Info("已加载本地快照",
+		zap.String("path", path),
+		zap.Int("keys", count),
+		zap.Int64("revision", snapshot.Revision),
+		zap.Time("created_at", snapshot.CreatedAt))
+	return client
+}
+
+// saveSnapshots 定期把发现数据保存为本地快照，直到ctx被取消
+func (e *EtcdClient) saveSnapshots(ctx context.Context) {
+	interval := time.Duration(e.cfg.Etcd.Snapshot.Interval) * time.Second
+	if interval <= 0 {
+		interval = defaultSnapshotInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := e.SaveSnapshot(ctx, e.cfg.Etcd.Snapshot.Path); err != nil && ctx.Err() == nil {
+			e.log(ctx).Warn("保存本地快照失败", zap.Error(err))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// SaveSnapshot 把全部发现数据写入本地快照文件，先写临时文件再替换，读取快照时不会看到写了一半的文件
+func (e *EtcdClient) SaveSnapshot(ctx context.Context, path string) error {
+	snapshot, err := e.Snapshot(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("创建快照目录失败: %w", err)
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("创建快照文件失败: %w", err)
+	}
+	defer os.Remove(f.Name())
+
+	if err := snapshot.WriteArchive(f); err != nil {
+		f.Close()
+		return fmt.Errorf("写入快照失败: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("写入快照失败: %w", err)
+	}
+	if err := os.Rename(f.Name(), path); err != nil {
+		return fmt.Errorf("替换快照文件失败: %w", err)
+	}
+	return nil
+}
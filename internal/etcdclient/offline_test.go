@@ -0,0 +1,121 @@
+package etcdclient
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hewenyu/kong-discovery/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEtcdClient_ConnectLazy(t *testing.T) {
+	dir := t.TempDir()
+	snapshotPath := filepath.Join(dir, "snapshot.tar.gz")
+	ctx := context.Background()
+
+	// 先在一个可用的存储上保存快照
+	source := &config.Config{Storage: config.StorageSQLite}
+	source.SQL.DSN = filepath.Join(dir, "source.db")
+	sourceClient := NewEtcdClient(source, createTestLogger(t))
+	require.NoError(t, sourceClient.Connect())
+	record := &DNSRecord{Type: "A", Value: "10.0.0.1", TTL: 60}
+	require.NoError(t, sourceClient.PutDNSRecord(ctx, "snapshot.test.", record))
+	require.NoError(t, sourceClient.SaveSnapshot(ctx, snapshotPath))
+	require.NoError(t, sourceClient.Close())
+
+	// 数据库所在目录不存在，启动时无法打开存储
+	cfg := &config.Config{Storage: config.StorageSQLite}
+	cfg.SQL.DSN = filepath.Join(dir, "missing", "kong-discovery.db")
+	cfg.Etcd.Retry.InitialBackoff = 20
+	cfg.Etcd.Retry.MaxBackoff = 50
+	cfg.Etcd.Snapshot.Path = snapshotPath
+
+	connected := make(chan struct{})
+	client := NewEtcdClient(cfg, createTestLogger(t))
+	client.ConnectLazy(func() { close(connected) })
+	defer client.Close()
+
+	// 存储恢复前从快照读取，写入被拒绝
+	assert.True(t, client.Offline())
+	assert.ErrorIs(t, client.Ping(ctx), ErrOffline)
+	got, err := client.GetDNSRecord(ctx, "snapshot.test.", "A")
+	require.NoError(t, err)
+	assert.Equal(t, record.Value, got.Value)
+	err = client.PutDNSRecord(ctx, "offline.test.", record)
+	assert.ErrorIs(t, err, ErrOffline)
+
+	// 存储可用后自动切换
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "missing"), 0o755))
+	select {
+	case <-connected:
+	case <-time.After(5 * time.Second):
+		t.Fatal("存储恢复后未重新连接")
+	}
+	assert.False(t, client.Offline())
+	require.NoError(t, client.Ping(ctx))
+	require.NoError(t, client.PutDNSRecord(ctx, "online.test.", record))
+}
+
+func TestEtcdClient_ConnectLazyWithoutSnapshot(t *testing.T) {
+	cfg := &config.Config{Storage: config.StorageSQLite}
+	cfg.SQL.DSN = filepath.Join(t.TempDir(), "missing", "kong-discovery.db")
+
+	client := NewEtcdClient(cfg, createTestLogger(t))
+	client.ConnectLazy(nil)
+
+	// 没有快照时数据为空，查询不会失败
+	records, err := client.ListDNSRecords(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, records)
+	require.NoError(t, client.Close())
+}
+
+func TestEtcdClient_SnapshotOnCloseAndWarmStart(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	cfg := &config.Config{Storage: config.StorageSQLite}
+	cfg.SQL.DSN = filepath.Join(dir, "kong-discovery.db")
+	cfg.Etcd.Snapshot.Path = filepath.Join(dir, "snapshot.tar.gz")
+	cfg.Etcd.Snapshot.Interval = 3600
+	cfg.Etcd.Snapshot.WarmStart = true
+
+	// 没有快照时同步连接，关闭时保存快照
+	client := NewEtcdClient(cfg, createTestLogger(t))
+	client.ConnectLazy(nil)
+	require.False(t, client.Offline())
+	record := &DNSRecord{Type: "A", Value: "10.0.0.2", TTL: 60}
+	require.NoError(t, client.PutDNSRecord(ctx, "warm.test.", record))
+	require.NoError(t, client.Close())
+
+	f, err := os.Open(cfg.Etcd.Snapshot.Path)
+	require.NoError(t, err)
+	snapshot, err := ReadArchive(f)
+	f.Close()
+	require.NoError(t, err)
+	var keys []string
+	for _, entry := range snapshot.Entries[BackupDNS] {
+		keys = append(keys, entry.Key)
+	}
+	assert.Contains(t, keys, "/dns/records/warm.test./A")
+
+	// 快照存在时先用快照应答，随后在后台连接
+	connected := make(chan struct{})
+	client = NewEtcdClient(cfg, createTestLogger(t))
+	client.ConnectLazy(func() { close(connected) })
+	defer client.Close()
+	got, err := client.GetDNSRecord(ctx, "warm.test.", "A")
+	require.NoError(t, err)
+	assert.Equal(t, record.Value, got.Value)
+
+	select {
+	case <-connected:
+	case <-time.After(5 * time.Second):
+		t.Fatal("热启动后未连接存储")
+	}
+	assert.False(t, client.Offline())
+}
@@ -0,0 +1,94 @@
+package etcdclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// OwnerKindService 记录由服务自动维护，服务没有实例后由清理任务删除
+const OwnerKindService = "service"
+
+// ErrRecordOwned 记录由其他所有者维护，手动修改需要强制执行
+var ErrRecordOwned = errors.New("DNS记录由其他所有者维护")
+
+// RecordOwner DNS记录的所有者，没有所有者的记录是手动维护的记录
+type RecordOwner struct {
+	Kind string `json:"kind"` // 所有者类型，目前只有service
+	Name string `json:"name"` // 所有者名称，如服务名
+}
+
+// ServiceOwner 返回服务作为所有者的引用
+func ServiceOwner(serviceName string) *RecordOwner {
+	return &RecordOwner{Kind: OwnerKindService, Name: serviceName}
+}
+
+// String 返回kind/name形式的所有者，没有所有者时返回manual
+func (o *RecordOwner) String() string {
+	if o == nil {
+		return "manual"
+	}
+	return o.Kind + "/" + o.Name
+}
+
+// Equal 判断两个所有者是否相同，两者都为空表示都是手动维护的记录
+func (o *RecordOwner) Equal(other *RecordOwner) bool {
+	if o == nil || other == nil {
+		return o == nil && other == nil
+	}
+	return o.Kind == other.Kind && o.Name == other.Name
+}
+
+// OwnedByService 判断记录是否由指定服务维护
+func (r *DNSRecord) OwnedByService(serviceName string) bool {
+	return r.Owner.Equal(ServiceOwner(serviceName))
+}
+
+// checkRecordOwner 校验对已有记录的修改是否符合所有权：
+// 写入时新记录的所有者必须与已有记录相同，手动记录不会被自动维护的记录覆盖，反之亦然；
+// 删除时只能删除手动记录。record为nil表示删除操作
+func checkRecordOwner(key string, existing *DNSRecord, record *DNSRecord) error {
+	if existing == nil {
+		return nil
+	}
+	if record == nil {
+		if existing.Owner != nil {
+			return fmt.Errorf("%w: %s 由 %s 维护", ErrRecordOwned, key, existing.Owner)
+		}
+		return nil
+	}
+	if !existing.Owner.Equal(record.Owner) {
+		return fmt.Errorf("%w: %s 由 %s 维护，不能被 %s 修改", ErrRecordOwned, key, existing.Owner, record.Owner)
+	}
+	return nil
+}
+
+// ownedRecord 读取键当前的记录和修改版本，用于在写入前校验所有权，记录不存在时返回nil和0
+// 无法解析的记录视为手动记录
+func (e *EtcdClient) ownedRecord(ctx context.Context, key string) (*DNSRecord, int64, error) {
+	getCtx, cancel := context.WithTimeout(ctx, etcdTimeout)
+	resp, err := e.conn().Get(getCtx, key)
+	cancel()
+	if err != nil {
+		return nil, 0, fmt.Errorf("获取DNS记录失败: %w", err)
+	}
+	if len(resp.Kvs) == 0 {
+		return nil, 0, nil
+	}
+	var record DNSRecord
+	if err := json.Unmarshal(resp.Kvs[0].Value, &record); err != nil {
+		return &DNSRecord{}, resp.Kvs[0].ModRevision, nil
+	}
+	return &record, resp.Kvs[0].ModRevision, nil
+}
+
+// ownerGuard 返回校验所有权时读取的状态在提交时仍然成立的比较条件
+func ownerGuard(key string, modRevision int64) clientv3.Cmp {
+	if modRevision == 0 {
+		return clientv3.Compare(clientv3.CreateRevision(key), "=", 0)
+	}
+	return clientv3.Compare(clientv3.ModRevision(key), "=", modRevision)
+}
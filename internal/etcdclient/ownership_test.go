@@ -0,0 +1,90 @@
+package etcdclient
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckRecordOwner(t *testing.T) {
+	owner := ServiceOwner("orders")
+	manual := &DNSRecord{Type: "A", Value: "10.0.0.1"}
+	owned := &DNSRecord{Type: "A", Value: "10.0.0.1", Owner: owner}
+
+	assert.NoError(t, checkRecordOwner("k", nil, owned), "新建记录不受限制")
+	assert.NoError(t, checkRecordOwner("k", manual, manual))
+	assert.NoError(t, checkRecordOwner("k", owned, &DNSRecord{Owner: ServiceOwner("orders")}), "所有者可以更新自己的记录")
+	assert.ErrorIs(t, checkRecordOwner("k", owned, manual), ErrRecordOwned)
+	assert.ErrorIs(t, checkRecordOwner("k", owned, &DNSRecord{Owner: ServiceOwner("payments")}), ErrRecordOwned)
+	assert.ErrorIs(t, checkRecordOwner("k", manual, owned), ErrRecordOwned, "自动维护的记录不能覆盖手动记录")
+
+	assert.NoError(t, checkRecordOwner("k", manual, nil))
+	assert.ErrorIs(t, checkRecordOwner("k", owned, nil), ErrRecordOwned)
+
+	assert.Equal(t, "service/orders", owner.String())
+	assert.Equal(t, "manual", (*RecordOwner)(nil).String())
+}
+
+func TestEtcdClient_RecordOwnership(t *testing.T) {
+	// 跳过集成测试，除非明确要求运行
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	client := CreateEtcdClientForTest(t)
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	serviceName := fmt.Sprintf("owned-service-%d", time.Now().UnixNano())
+	domain := serviceName + ".default.svc.cluster.local"
+	owner := ServiceOwner(serviceName)
+	defer func() {
+		_, _ = client.ApplyDNSRecordBatch(context.Background(), []*DNSRecordOperation{
+			{Op: DNSRecordOpDelete, Domain: domain, Type: "A", Force: true},
+		})
+	}()
+
+	require.NoError(t, client.PutDNSRecord(ctx, domain, &DNSRecord{Type: "A", Value: "10.0.0.1", Owner: owner}))
+
+	// 所有者可以更新，手动修改和删除被拒绝
+	require.NoError(t, client.PutDNSRecord(ctx, domain, &DNSRecord{Type: "A", Value: "10.0.0.2", Owner: owner}))
+	assert.ErrorIs(t, client.PutDNSRecord(ctx, domain, &DNSRecord{Type: "A", Value: "10.0.0.3"}), ErrRecordOwned)
+	assert.ErrorIs(t, client.DeleteDNSRecord(ctx, domain, "A", ""), ErrRecordOwned)
+
+	// 批量操作同样校验所有权，整个批次不生效
+	results, err := client.ApplyDNSRecordBatch(ctx, []*DNSRecordOperation{
+		{Op: DNSRecordOpPut, Domain: domain, Record: &DNSRecord{Type: "TXT", Value: "manual"}},
+		{Op: DNSRecordOpPut, Domain: domain, Record: &DNSRecord{Type: "A", Value: "10.0.0.3"}},
+	})
+	assert.ErrorIs(t, err, ErrRecordOwned)
+	require.Len(t, results, 2)
+	assert.Equal(t, BatchStatusAborted, results[0].Status)
+	assert.Equal(t, BatchStatusOwned, results[1].Status)
+	_, err = client.GetDNSRecordEntry(ctx, domain, "TXT", "")
+	assert.ErrorIs(t, err, ErrDNSRecordNotFound)
+
+	entry, err := client.GetDNSRecordEntry(ctx, domain, "A", "")
+	require.NoError(t, err)
+	assert.Equal(t, "10.0.0.2", entry.Value)
+	assert.True(t, entry.OwnedByService(serviceName))
+
+	// 强制修改后记录成为手动记录
+	_, err = client.ApplyDNSRecordBatch(ctx, []*DNSRecordOperation{
+		{Op: DNSRecordOpPut, Domain: domain, Record: &DNSRecord{Type: "A", Value: "10.0.0.3"}, Force: true},
+	})
+	require.NoError(t, err)
+	entry, err = client.GetDNSRecordEntry(ctx, domain, "A", "")
+	require.NoError(t, err)
+	assert.Equal(t, "10.0.0.3", entry.Value)
+	assert.Nil(t, entry.Owner)
+
+	// 手动记录不会被自动维护的记录覆盖
+	assert.ErrorIs(t, client.PutDNSRecord(ctx, domain, &DNSRecord{Type: "A", Value: "10.0.0.4", Owner: owner}), ErrRecordOwned)
+	require.NoError(t, client.DeleteDNSRecord(ctx, domain, "A", ""))
+}
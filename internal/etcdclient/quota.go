@@ -0,0 +1,292 @@
+package etcdclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrQuotaExceeded 命名空间配额已用尽
+	ErrQuotaExceeded = errors.New("命名空间配额已用尽")
+
+	// ErrQuotaForbidden 命名空间禁止创建该类资源
+	ErrQuotaForbidden = errors.New("命名空间禁止创建该类资源")
+)
+
+// NamespaceQuota 命名空间配额，0表示不限制，负数表示禁止创建该类资源
+type NamespaceQuota struct {
+	MaxServices   int `json:"max_services"`    // 最大服务数
+	MaxInstances  int `json:"max_instances"`   // 最大实例数
+	MaxDNSRecords int `json:"max_dns_records"` // 最大DNS记录数
+}
+
+// unlimited 判断配额是否完全不限制
+func (q *NamespaceQuota) unlimited() bool {
+	return q.MaxServices == 0 && q.MaxInstances == 0 && q.MaxDNSRecords == 0
+}
+
+// NamespaceUsage 命名空间的资源用量
+type NamespaceUsage struct {
+	Services   int `json:"services"`    // 服务数
+	Instances  int `json:"instances"`   // 实例数
+	DNSRecords int `json:"dns_records"` // DNS记录数
+}
+
+// checkLimit 校验在当前用量上新增一个资源是否超出限制
+func checkLimit(namespace, resource string, used, limit int) error {
+	if limit < 0 {
+		return fmt.Errorf("%w: 命名空间 %s 不允许创建%s", ErrQuotaForbidden, namespace, resource)
+	}
+	if limit > 0 && used+1 > limit {
+		return fmt.Errorf("%w: 命名空间 %s 的%s已达上限 (%d/%d)", ErrQuotaExceeded, namespace, resource, used, limit)
+	}
+	return nil
+}
+
+// GetNamespaceQuota 获取命名空间生效的配额，未单独设置时使用配置中的默认配额
+func (e *EtcdClient) GetNamespaceQuota(ctx context.Context, namespace string) (*NamespaceQuota, error) {
+	ns, err := e.GetNamespace(ctx, NamespaceOf(namespace))
+	if err != nil {
+		return nil, err
+	}
+	if ns != nil && ns.Quota != nil {
+		return ns.Quota, nil
+	}
+
+	quota := &NamespaceQuota{}
+	if e.cfg != nil {
+		quota.MaxServices = e.cfg.Namespace.DefaultQuota.MaxServices
+		quota.MaxInstances = e.cfg.Namespace.DefaultQuota.MaxInstances
+		quota.MaxDNSRecords = e.cfg.Namespace.DefaultQuota.MaxDNSRecords
+	}
+	return quota, nil
+}
+
+// PutNamespaceQuota 设置命名空间的配额，默认命名空间首次设置时自动创建
+func (e *EtcdClient) PutNamespaceQuota(ctx context.Context, namespace string, quota *NamespaceQuota) error {
+	namespace = NamespaceOf(namespace)
+
+	ns, err := e.GetNamespace(ctx, namespace)
+	if err != nil {
+		return err
+	}
+	if ns == nil {
+		if namespace != DefaultNamespace {
+			return fmt.Errorf("%w: %s", ErrNamespaceNotFound, namespace)
+		}
+		ns = &Namespace{Name: namespace}
+	}
+	ns.Quota = quota
+
+	return e.PutNamespace(ctx, ns)
+}
+
+// GetNamespaceUsage 统计命名空间的资源用量
+func (e *EtcdClient) GetNamespaceUsage(ctx context.Context, namespace string) (*NamespaceUsage, error) {
+	namespace = NamespaceOf(namespace)
+
+	instances, err := e.listNamespaceInstances(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	services := make(map[string]struct{})
+	for _, instance := range instances {
+		services[instance.ServiceName] = struct{}{}
+	}
+
+	records, err := e.countNamespaceDNSRecords(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	return &NamespaceUsage{
+		Services:   len(services),
+		Instances:  len(instances),
+		DNSRecords: records,
+	}, nil
+}
+
+// listNamespaceInstances 列出命名空间下的所有服务实例
+func (e *EtcdClient) listNamespaceInstances(ctx context.Context, namespace string) ([]*ServiceInstance, error) {
+	if e.conn() == nil {
+		return nil, fmt.Errorf("etcd客户端未连接")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, etcdTimeout)
+	defer cancel()
+
+	resp, err := e.conn().Get(ctx, "/services/", clientv3.WithPrefix())
+	if err != nil {
+		e.log(ctx).Error("获取服务实例失败", zap.Error(err))
+		return nil, fmt.Errorf("获取服务实例失败: %w", err)
+	}
+
+	instances := make([]*ServiceInstance, 0, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		var instance ServiceInstance
+		if err := json.Unmarshal(kv.Value, &instance); err != nil {
+			continue
+		}
+		if NamespaceOf(instance.Namespace) == namespace {
+			instances = append(instances, &instance)
+		}
+	}
+
+	return instances, nil
+}
+
+// countNamespaceDNSRecords 统计命名空间下的DNS记录数（包括各视图中的记录）
+func (e *EtcdClient) countNamespaceDNSRecords(ctx context.Context, namespace string) (int, error) {
+	keys, err := e.namespaceDNSRecordKeys(ctx, namespace)
+	if err != nil {
+		return 0, err
+	}
+	return len(keys), nil
+}
+
+// namespaceDNSRecordKeys 列出命名空间下所有DNS记录的etcd键（包括各视图中的记录）
+func (e *EtcdClient) namespaceDNSRecordKeys(ctx context.Context, namespace string) ([]string, error) {
+	if e.conn() == nil {
+		return nil, fmt.Errorf("etcd客户端未连接")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, etcdTimeout)
+	defer cancel()
+
+	var keys []string
+	for _, prefix := range []string{"/dns/records/", "/dns/views/"} {
+		resp, err := e.conn().Get(ctx, prefix, clientv3.WithPrefix())
+		if err != nil {
+			e.log(ctx).Error("获取DNS记录失败", zap.String("prefix", prefix), zap.Error(err))
+			return nil, fmt.Errorf("获取DNS记录失败: %w", err)
+		}
+
+		for _, kv := range resp.Kvs {
+			var record DNSRecord
+			if err := json.Unmarshal(kv.Value, &record); err != nil {
+				continue
+			}
+			if NamespaceOf(record.Namespace) == namespace {
+				keys = append(keys, string(kv.Key))
+			}
+		}
+	}
+
+	return keys, nil
+}
+
+// keyExists 判断etcd中是否存在指定的键
+func (e *EtcdClient) keyExists(ctx context.Context, key string) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, etcdTimeout)
+	defer cancel()
+
+	resp, err := e.conn().Get(ctx, key, clientv3.WithCountOnly())
+	if err != nil {
+		return false, fmt.Errorf("检查键是否存在失败: %w", err)
+	}
+	return resp.Count > 0, nil
+}
+
+// 命名空间成员版本在etcd中的键前缀，配额受限的命名空间中每加入一个新实例都在注册事务中写入一次
+// 注册事务比较它的修订版本，只有同一命名空间中并发加入的实例才会使配额校验失效，其他实例的心跳不受影响
+// 实例删除时不写入：校验期间被删除的实例只会使计数偏大，不会突破配额
+const namespaceMembersPrefix = "/namespace-members/"
+
+// getNamespaceMembersKey 生成命名空间成员版本的etcd键
+func getNamespaceMembersKey(namespace string) string {
+	return namespaceMembersPrefix + namespace
+}
+
+// checkInstanceQuota 注册新实例前校验命名空间的服务数和实例数配额，在同一命名空间中重复注册已存在的实例不占用新配额
+// 返回注册事务需要附加的比较条件和操作，用于确认校验之后命名空间中没有并发加入的实例
+func (e *EtcdClient) checkInstanceQuota(ctx context.Context, instance *ServiceInstance) ([]clientv3.Cmp, []clientv3.Op, error) {
+	namespace := NamespaceOf(instance.Namespace)
+
+	quota, err := e.GetNamespaceQuota(ctx, namespace)
+	if err != nil {
+		return nil, nil, err
+	}
+	if quota.unlimited() {
+		return nil, nil, nil
+	}
+
+	key := getServiceInstanceKey(instance.ServiceName, instance.InstanceID)
+	membersKey := getNamespaceMembersKey(namespace)
+
+	// 先读取成员版本再统计实例，统计之后加入的实例都会改变成员版本
+	getCtx, cancel := context.WithTimeout(ctx, etcdTimeout)
+	resp, err := e.conn().Txn(getCtx).Then(clientv3.OpGet(key), clientv3.OpGet(membersKey)).Commit()
+	cancel()
+	if err != nil {
+		return nil, nil, fmt.Errorf("读取命名空间成员版本失败: %w", err)
+	}
+	// 实例已在本命名空间中存在时只需确认写入时仍是同一个实例，从其他命名空间移入的实例计入本命名空间的配额
+	keyCmp := clientv3.Compare(clientv3.CreateRevision(key), "=", 0)
+	if kvs := resp.Responses[0].GetResponseRange().Kvs; len(kvs) > 0 {
+		var stored ServiceInstance
+		if err := json.Unmarshal(kvs[0].Value, &stored); err == nil && NamespaceOf(stored.Namespace) == namespace {
+			return []clientv3.Cmp{clientv3.Compare(clientv3.CreateRevision(key), "=", kvs[0].CreateRevision)}, nil, nil
+		}
+		keyCmp = clientv3.Compare(clientv3.ModRevision(key), "=", kvs[0].ModRevision)
+	}
+	var membersRevision int64
+	if kvs := resp.Responses[1].GetResponseRange().Kvs; len(kvs) > 0 {
+		membersRevision = kvs[0].ModRevision
+	}
+
+	instances, err := e.listNamespaceInstances(ctx, namespace)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	services := make(map[string]struct{})
+	for _, existing := range instances {
+		services[existing.ServiceName] = struct{}{}
+	}
+
+	if _, ok := services[instance.ServiceName]; !ok {
+		if err := checkLimit(namespace, "服务数", len(services), quota.MaxServices); err != nil {
+			return nil, nil, err
+		}
+	}
+	if err := checkLimit(namespace, "实例数", len(instances), quota.MaxInstances); err != nil {
+		return nil, nil, err
+	}
+
+	cmps := []clientv3.Cmp{keyCmp, clientv3.Compare(clientv3.ModRevision(membersKey), "=", membersRevision)}
+	return cmps, []clientv3.Op{clientv3.OpPut(membersKey, "")}, nil
+}
+
+// checkDNSRecordQuota 创建新DNS记录前校验命名空间的DNS记录数配额，更新已存在的记录不占用新配额
+func (e *EtcdClient) checkDNSRecordQuota(ctx context.Context, key string, record *DNSRecord) error {
+	namespace := NamespaceOf(record.Namespace)
+
+	quota, err := e.GetNamespaceQuota(ctx, namespace)
+	if err != nil {
+		return err
+	}
+	if quota.MaxDNSRecords == 0 {
+		return nil
+	}
+
+	exists, err := e.keyExists(ctx, key)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return nil
+	}
+
+	count, err := e.countNamespaceDNSRecords(ctx, namespace)
+	if err != nil {
+		return err
+	}
+
+	return checkLimit(namespace, "DNS记录数", count, quota.MaxDNSRecords)
+}
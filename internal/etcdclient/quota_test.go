@@ -0,0 +1,176 @@
+package etcdclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+func TestCheckLimit(t *testing.T) {
+	assert.NoError(t, checkLimit("default", "实例数", 100, 0), "0表示不限制")
+	assert.NoError(t, checkLimit("default", "实例数", 1, 2))
+	assert.True(t, errors.Is(checkLimit("default", "实例数", 2, 2), ErrQuotaExceeded))
+	assert.True(t, errors.Is(checkLimit("default", "实例数", 0, -1), ErrQuotaForbidden))
+}
+
+func TestEtcdClient_NamespaceQuota(t *testing.T) {
+	// 跳过集成测试，除非明确要求运行
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	client := CreateEtcdClientForTest(t)
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	namespace := fmt.Sprintf("quota-ns-%d", time.Now().UnixNano())
+	require.NoError(t, client.CreateNamespace(ctx, &Namespace{Name: namespace}))
+	defer func() { _ = client.DeleteNamespace(context.Background(), namespace, true) }()
+
+	err := client.PutNamespaceQuota(ctx, namespace, &NamespaceQuota{MaxServices: 1, MaxInstances: 2, MaxDNSRecords: -1})
+	require.NoError(t, err)
+
+	register := func(service, id string) error {
+		return client.RegisterService(ctx, &ServiceInstance{
+			ServiceName: service,
+			InstanceID:  id,
+			IPAddress:   "10.0.0.1",
+			Port:        8080,
+			TTL:         30,
+			Namespace:   namespace,
+		})
+	}
+	service := namespace + "-svc"
+	defer func() {
+		_ = client.DeregisterService(context.Background(), service, "instance-001")
+		_ = client.DeregisterService(context.Background(), service, "instance-002")
+	}()
+
+	require.NoError(t, register(service, "instance-001"))
+	require.NoError(t, register(service, "instance-002"))
+
+	// 重复注册已存在的实例不占用新配额
+	require.NoError(t, register(service, "instance-002"))
+
+	// 实例数已达上限
+	err = register(service, "instance-003")
+	assert.True(t, errors.Is(err, ErrQuotaExceeded), "超出实例配额应返回ErrQuotaExceeded")
+
+	// 服务数已达上限
+	err = register(namespace+"-other", "instance-001")
+	assert.True(t, errors.Is(err, ErrQuotaExceeded), "超出服务配额应返回ErrQuotaExceeded")
+
+	// 禁止创建DNS记录
+	err = client.PutDNSRecord(ctx, namespace+".example.com", &DNSRecord{Type: "A", Value: "10.0.0.1", Namespace: namespace})
+	assert.True(t, errors.Is(err, ErrQuotaForbidden))
+
+	usage, err := client.GetNamespaceUsage(ctx, namespace)
+	require.NoError(t, err)
+	assert.Equal(t, 1, usage.Services)
+	assert.Equal(t, 2, usage.Instances)
+	assert.Equal(t, 0, usage.DNSRecords)
+}
+
+func TestEtcdClient_NamespaceQuota_ConcurrentHeartbeats(t *testing.T) {
+	// 跳过集成测试，除非明确要求运行
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	client := CreateEtcdClientForTest(t)
+	defer client.Close()
+	e := client.(*EtcdClient)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	namespace := fmt.Sprintf("quota-hb-ns-%d", time.Now().UnixNano())
+	require.NoError(t, client.CreateNamespace(ctx, &Namespace{Name: namespace}))
+	defer func() { _ = client.DeleteNamespace(context.Background(), namespace, true) }()
+	require.NoError(t, client.PutNamespaceQuota(ctx, namespace, &NamespaceQuota{MaxInstances: 100}))
+
+	service := namespace + "-svc"
+	other := namespace + "-other"
+	defer func() {
+		_ = client.DeregisterService(context.Background(), service, "busy-001")
+		_ = client.DeregisterService(context.Background(), other, "busy-002")
+		_ = client.DeregisterService(context.Background(), service, "joined")
+	}()
+	require.NoError(t, client.RegisterService(ctx, &ServiceInstance{
+		ServiceName: service, InstanceID: "busy-001", IPAddress: "10.0.0.1", Port: 8080, TTL: 30, Namespace: namespace,
+	}))
+	require.NoError(t, client.RegisterService(ctx, &ServiceInstance{
+		ServiceName: other, InstanceID: "busy-002", IPAddress: "10.0.0.2", Port: 8080, TTL: 30,
+	}))
+
+	// guarded 判断配额校验之后，附加了校验条件的事务能否成功提交
+	guarded := func(cmps []clientv3.Cmp) bool {
+		resp, err := e.conn().Txn(ctx).If(cmps...).Commit()
+		require.NoError(t, err)
+		return resp.Succeeded
+	}
+	newInstance := &ServiceInstance{ServiceName: service, InstanceID: "instance-001", Namespace: namespace}
+
+	// 校验之后本命名空间和其他命名空间的实例发送心跳，不影响注册
+	cmps, ops, err := e.checkInstanceQuota(ctx, newInstance)
+	require.NoError(t, err)
+	require.NotEmpty(t, ops, "新实例应写入命名空间成员版本")
+	require.NoError(t, client.RefreshServiceLease(ctx, service, "busy-001", 30))
+	require.NoError(t, client.RefreshServiceLease(ctx, other, "busy-002", 30))
+	assert.True(t, guarded(cmps), "其他实例的心跳不应使配额校验失效")
+
+	// 校验之后本命名空间加入了新实例，需要重新校验
+	cmps, _, err = e.checkInstanceQuota(ctx, newInstance)
+	require.NoError(t, err)
+	require.NoError(t, client.RegisterService(ctx, &ServiceInstance{
+		ServiceName: service, InstanceID: "joined", IPAddress: "10.0.0.3", Port: 8080, TTL: 30, Namespace: namespace,
+	}))
+	assert.False(t, guarded(cmps), "并发加入的实例应使配额校验失效")
+}
+
+func TestEtcdClient_NamespaceQuota_InstanceFromOtherNamespace(t *testing.T) {
+	// 跳过集成测试，除非明确要求运行
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	client := CreateEtcdClientForTest(t)
+	defer client.Close()
+	e := client.(*EtcdClient)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	namespace := fmt.Sprintf("quota-move-ns-%d", time.Now().UnixNano())
+	require.NoError(t, client.CreateNamespace(ctx, &Namespace{Name: namespace}))
+	defer func() { _ = client.DeleteNamespace(context.Background(), namespace, true) }()
+	require.NoError(t, client.PutNamespaceQuota(ctx, namespace, &NamespaceQuota{MaxInstances: 1}))
+
+	service := namespace + "-svc"
+	defer func() {
+		_ = client.DeregisterService(context.Background(), service, "instance-001")
+		_ = client.DeregisterService(context.Background(), service, "instance-002")
+	}()
+	require.NoError(t, client.RegisterService(ctx, &ServiceInstance{
+		ServiceName: service, InstanceID: "instance-001", IPAddress: "10.0.0.1", Port: 8080, TTL: 30, Namespace: namespace,
+	}))
+	require.NoError(t, client.RegisterService(ctx, &ServiceInstance{
+		ServiceName: service, InstanceID: "instance-002", IPAddress: "10.0.0.2", Port: 8080, TTL: 30,
+	}))
+
+	// 已在本命名空间中的实例重复注册不占用新配额
+	_, _, err := e.checkInstanceQuota(ctx, &ServiceInstance{ServiceName: service, InstanceID: "instance-001", Namespace: namespace})
+	assert.NoError(t, err)
+
+	// 默认命名空间中已存在的实例移入本命名空间时计入本命名空间的配额
+	_, _, err = e.checkInstanceQuota(ctx, &ServiceInstance{ServiceName: service, InstanceID: "instance-002", Namespace: namespace})
+	assert.True(t, errors.Is(err, ErrQuotaExceeded), "从其他命名空间移入的实例应计入配额")
+}
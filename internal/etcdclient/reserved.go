@@ -0,0 +1,31 @@
+package etcdclient
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrReservedName 服务名称或命名空间与配置的保留名称冲突
+var ErrReservedName = errors.New("名称已被保留")
+
+// checkReservedService 校验服务名称不是保留的名称
+func (e *EtcdClient) checkReservedService(serviceName string) error {
+	if e.cfg == nil {
+		return nil
+	}
+	if pattern := e.cfg.API.Registration.Reserved.ReservedService(serviceName); pattern != "" {
+		return fmt.Errorf("%w: 服务名称 %s 与保留名称 %q 冲突", ErrReservedName, serviceName, pattern)
+	}
+	return nil
+}
+
+// checkReservedNamespace 校验命名空间不是保留的名称，默认命名空间总是允许
+func (e *EtcdClient) checkReservedNamespace(namespace string) error {
+	if e.cfg == nil || namespace == DefaultNamespace {
+		return nil
+	}
+	if pattern := e.cfg.API.Registration.Reserved.ReservedNamespace(namespace); pattern != "" {
+		return fmt.Errorf("%w: 命名空间 %s 与保留名称 %q 冲突", ErrReservedName, namespace, pattern)
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package etcdclient
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEtcdClient_ReservedNames(t *testing.T) {
+	// 跳过集成测试，除非明确要求运行
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	cfg := createTestConfig(t)
+	cfg.API.Registration.Reserved.ServiceNames = []string{"admin", "com"}
+	cfg.API.Registration.Reserved.Namespaces = []string{"kube-*"}
+	client := NewEtcdClient(cfg, createTestLogger(t))
+	require.NoError(t, client.Connect())
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err := client.RegisterService(ctx, &ServiceInstance{ServiceName: "Admin", IPAddress: "10.0.0.1", Port: 80, TTL: 30})
+	assert.ErrorIs(t, err, ErrReservedName)
+	assert.ErrorIs(t, client.CreateNamespace(ctx, &Namespace{Name: "kube-system"}), ErrReservedName)
+	err = client.PutServiceAlias(ctx, &ServiceAlias{ServiceName: "com", Target: "orders"})
+	assert.ErrorIs(t, err, ErrReservedName)
+
+	// 其他名称不受影响
+	serviceName := fmt.Sprintf("reserved-service-%d", time.Now().UnixNano())
+	require.NoError(t, client.RegisterService(ctx, &ServiceInstance{ServiceName: serviceName, InstanceID: "reserved-001", IPAddress: "10.0.0.1", Port: 80, TTL: 30}))
+	require.NoError(t, client.DeregisterService(ctx, serviceName, "reserved-001"))
+}
@@ -0,0 +1,220 @@
+package etcdclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.uber.org/zap"
+)
+
+// scheduledChangePrefix 定时DNS变更在etcd中的键前缀
+const scheduledChangePrefix = "/dns/scheduled/"
+
+// 定时变更的状态
+const (
+	ScheduleStatusPending = "pending" // 等待执行
+	ScheduleStatusFailed  = "failed"  // 执行失败，需要人工处理
+)
+
+// ErrScheduledChangeNotFound 定时变更不存在
+var ErrScheduledChangeNotFound = errors.New("定时变更不存在")
+
+// ScheduledChange 在指定时间自动应用的一组DNS记录变更
+type ScheduledChange struct {
+	ID          string                `json:"id"`                     // 变更ID
+	ApplyAt     time.Time             `json:"apply_at"`               // 生效时间
+	Operations  []*DNSRecordOperation `json:"operations"`             // 要应用的操作，与批量操作格式相同
+	Comment     string                `json:"comment,omitempty"`      // 可选说明
+	Status      string                `json:"status"`                 // 状态 (pending, failed)
+	CreatedAt   time.Time             `json:"created_at"`             // 创建时间
+	AttemptedAt *time.Time            `json:"attempted_at,omitempty"` // 最近一次执行时间
+	Error       string                `json:"error,omitempty"`        // 执行失败的原因
+	Results     []*DNSRecordOpResult  `json:"results,omitempty"`      // 执行失败时每一项的结果
+}
+
+// getScheduledChangeKey 生成定时变更的etcd键
+func getScheduledChangeKey(id string) string {
+	return scheduledChangePrefix + id
+}
+
+// ScheduleDNSChange 保存一组待定时执行的DNS记录变更，返回分配的变更ID
+func (e *EtcdClient) ScheduleDNSChange(ctx context.Context, change *ScheduledChange) (string, error) {
+	if e.conn() == nil {
+		return "", fmt.Errorf("etcd客户端未连接")
+	}
+	if change.ApplyAt.IsZero() {
+		return "", fmt.Errorf("%w: 生效时间不能为空", ErrInvalidBatch)
+	}
+	if len(change.Operations) == 0 {
+		return "", fmt.Errorf("%w: 操作列表不能为空", ErrInvalidBatch)
+	}
+	// 执行时需要在同一事务中删除变更本身，占用一个操作名额
+	if len(change.Operations)+1 > e.maxTxnOps() {
+		return "", fmt.Errorf("%w: %d > %d", ErrBatchTooLarge, len(change.Operations)+1, e.maxTxnOps())
+	}
+
+	change.ID = uuid.New().String()
+	change.Status = ScheduleStatusPending
+	change.CreatedAt = time.Now()
+	change.AttemptedAt = nil
+	change.Error = ""
+	change.Results = nil
+
+	data, err := json.Marshal(change)
+	if err != nil {
+		return "", fmt.Errorf("序列化定时变更失败: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, etcdTimeout)
+	defer cancel()
+
+	if _, err := e.conn().Put(ctx, getScheduledChangeKey(change.ID), string(data)); err != nil {
+		e.log(ctx).Error("保存定时变更失败", zap.String("id", change.ID), zap.Error(err))
+		return "", fmt.Errorf("保存定时变更失败: %w", err)
+	}
+
+	e.log(ctx).Info("定时DNS变更已创建",
+		zap.String("id", change.ID),
+		zap.Time("apply_at", change.ApplyAt),
+		zap.Int("operations", len(change.Operations)))
+	return change.ID, nil
+}
+
+// ListScheduledChanges 列出所有定时变更，按生效时间排序
+func (e *EtcdClient) ListScheduledChanges(ctx context.Context) ([]*ScheduledChange, error) {
+	if e.conn() == nil {
+		return nil, fmt.Errorf("etcd客户端未连接")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, etcdTimeout)
+	defer cancel()
+
+	resp, err := e.conn().Get(ctx, scheduledChangePrefix, clientv3.WithPrefix())
+	if err != nil {
+		e.log(ctx).Error("获取定时变更失败", zap.Error(err))
+		return nil, fmt.Errorf("获取定时变更失败: %w", err)
+	}
+
+	changes := make([]*ScheduledChange, 0, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		var change ScheduledChange
+		if err := json.Unmarshal(kv.Value, &change); err != nil {
+			e.log(ctx).Warn("解析定时变更失败", zap.String("key", string(kv.Key)), zap.Error(err))
+			continue
+		}
+		changes = append(changes, &change)
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		if !changes[i].ApplyAt.Equal(changes[j].ApplyAt) {
+			return changes[i].ApplyAt.Before(changes[j].ApplyAt)
+		}
+		return changes[i].CreatedAt.Before(changes[j].CreatedAt)
+	})
+	return changes, nil
+}
+
+// GetScheduledChange 获取定时变更
+func (e *EtcdClient) GetScheduledChange(ctx context.Context, id string) (*ScheduledChange, error) {
+	if e.conn() == nil {
+		return nil, fmt.Errorf("etcd客户端未连接")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, etcdTimeout)
+	defer cancel()
+
+	resp, err := e.conn().Get(ctx, getScheduledChangeKey(id))
+	if err != nil {
+		return nil, fmt.Errorf("获取定时变更失败: %w", err)
+	}
+	if len(resp.Kvs) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrScheduledChangeNotFound, id)
+	}
+
+	var change ScheduledChange
+	if err := json.Unmarshal(resp.Kvs[0].Value, &change); err != nil {
+		return nil, fmt.Errorf("解析定时变更失败: %w", err)
+	}
+	return &change, nil
+}
+
+// CancelScheduledChange 取消定时变更，失败的变更也通过该方法清除
+func (e *EtcdClient) CancelScheduledChange(ctx context.Context, id string) error {
+	if e.conn() == nil {
+		return fmt.Errorf("etcd客户端未连接")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, etcdTimeout)
+	defer cancel()
+
+	resp, err := e.conn().Delete(ctx, getScheduledChangeKey(id))
+	if err != nil {
+		e.log(ctx).Error("取消定时变更失败", zap.String("id", id), zap.Error(err))
+		return fmt.Errorf("取消定时变更失败: %w", err)
+	}
+	if resp.Deleted == 0 {
+		return fmt.Errorf("%w: %s", ErrScheduledChangeNotFound, id)
+	}
+
+	e.log(ctx).Info("定时DNS变更已取消", zap.String("id", id))
+	return nil
+}
+
+// ApplyScheduledChange 执行一个定时变更
+// 变更中的操作与删除变更本身在同一事务中提交，保证不会重复执行；
+// 事务失败时变更被标记为failed并保留结果，不再自动重试
+func (e *EtcdClient) ApplyScheduledChange(ctx context.Context, id string) ([]*DNSRecordOpResult, error) {
+	change, err := e.GetScheduledChange(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if change.Status != ScheduleStatusPending {
+		return change.Results, fmt.Errorf("定时变更 %s 的状态为%s，不能执行", id, change.Status)
+	}
+
+	key := getScheduledChangeKey(id)
+	guards := []clientv3.Cmp{clientv3.Compare(clientv3.CreateRevision(key), ">", 0)}
+	results, applyErr := e.applyDNSRecordBatch(ctx, change.Operations, guards, []clientv3.Op{clientv3.OpDelete(key)})
+	if applyErr == nil {
+		e.log(ctx).Info("定时DNS变更已执行",
+			zap.String("id", id),
+			zap.Int("operations", len(change.Operations)))
+		return results, nil
+	}
+
+	// 执行期间变更被取消
+	if _, err := e.GetScheduledChange(ctx, id); errors.Is(err, ErrScheduledChangeNotFound) {
+		return results, err
+	}
+
+	now := time.Now()
+	change.Status = ScheduleStatusFailed
+	change.AttemptedAt = &now
+	change.Error = applyErr.Error()
+	change.Results = results
+
+	data, err := json.Marshal(change)
+	if err != nil {
+		return results, fmt.Errorf("序列化定时变更失败: %w", err)
+	}
+
+	putCtx, cancel := context.WithTimeout(ctx, etcdTimeout)
+	defer cancel()
+
+	// 只更新仍然存在的变更，避免与取消操作竞争时把它重新写回
+	if _, err := e.conn().Txn(putCtx).
+		If(clientv3.Compare(clientv3.CreateRevision(key), ">", 0)).
+		Then(clientv3.OpPut(key, string(data))).
+		Commit(); err != nil {
+		e.log(ctx).Error("标记定时变更失败状态出错", zap.String("id", id), zap.Error(err))
+	}
+
+	e.log(ctx).Warn("定时DNS变更执行失败", zap.String("id", id), zap.Error(applyErr))
+	return results, applyErr
+}
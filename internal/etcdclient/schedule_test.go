@@ -0,0 +1,122 @@
+package etcdclient
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEtcdClient_ScheduledChanges(t *testing.T) {
+	// 跳过集成测试，除非明确要求运行
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	client := CreateEtcdClientForTest(t)
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	domain := fmt.Sprintf("scheduled-%d.example.com", time.Now().UnixNano())
+	defer func() {
+		_ = client.DeleteDNSRecord(context.Background(), domain, "A", "")
+	}()
+
+	// 缺少生效时间或操作时拒绝
+	_, err := client.ScheduleDNSChange(ctx, &ScheduledChange{})
+	assert.ErrorIs(t, err, ErrInvalidBatch)
+
+	later := &ScheduledChange{
+		ApplyAt:    time.Now().Add(time.Hour),
+		Operations: []*DNSRecordOperation{{Op: DNSRecordOpPut, Domain: domain, Record: &DNSRecord{Type: "A", Value: "10.0.0.9"}}},
+	}
+	laterID, err := client.ScheduleDNSChange(ctx, later)
+	require.NoError(t, err)
+	defer func() { _ = client.CancelScheduledChange(context.Background(), laterID) }()
+
+	sooner := &ScheduledChange{
+		ApplyAt:    time.Now().Add(time.Minute),
+		Operations: []*DNSRecordOperation{{Op: DNSRecordOpPut, Domain: domain, Record: &DNSRecord{Type: "A", Value: "10.0.0.1"}}},
+		Comment:    "切换地址",
+	}
+	soonerID, err := client.ScheduleDNSChange(ctx, sooner)
+	require.NoError(t, err)
+
+	// 列表按生效时间排序
+	changes, err := client.ListScheduledChanges(ctx)
+	require.NoError(t, err)
+	var ids []string
+	for _, change := range changes {
+		if change.ID == soonerID || change.ID == laterID {
+			ids = append(ids, change.ID)
+		}
+	}
+	assert.Equal(t, []string{soonerID, laterID}, ids)
+
+	got, err := client.GetScheduledChange(ctx, soonerID)
+	require.NoError(t, err)
+	assert.Equal(t, ScheduleStatusPending, got.Status)
+	assert.Equal(t, "切换地址", got.Comment)
+
+	// 执行后记录生效，变更被删除，不能重复执行
+	results, err := client.ApplyScheduledChange(ctx, soonerID)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, BatchStatusApplied, results[0].Status)
+
+	entry, err := client.GetDNSRecordEntry(ctx, domain, "A", "")
+	require.NoError(t, err)
+	assert.Equal(t, "10.0.0.1", entry.Value)
+
+	_, err = client.ApplyScheduledChange(ctx, soonerID)
+	assert.ErrorIs(t, err, ErrScheduledChangeNotFound)
+
+	// 取消
+	require.NoError(t, client.CancelScheduledChange(ctx, laterID))
+	assert.ErrorIs(t, client.CancelScheduledChange(ctx, laterID), ErrScheduledChangeNotFound)
+	_, err = client.GetScheduledChange(ctx, laterID)
+	assert.ErrorIs(t, err, ErrScheduledChangeNotFound)
+}
+
+func TestEtcdClient_ApplyScheduledChange_Failed(t *testing.T) {
+	// 跳过集成测试，除非明确要求运行
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	client := CreateEtcdClientForTest(t)
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	domain := fmt.Sprintf("scheduled-failed-%d.example.com", time.Now().UnixNano())
+
+	// 删除不存在的记录会使整个变更失败
+	id, err := client.ScheduleDNSChange(ctx, &ScheduledChange{
+		ApplyAt:    time.Now(),
+		Operations: []*DNSRecordOperation{{Op: DNSRecordOpDelete, Domain: domain, Type: "A"}},
+	})
+	require.NoError(t, err)
+	defer func() { _ = client.CancelScheduledChange(context.Background(), id) }()
+
+	_, err = client.ApplyScheduledChange(ctx, id)
+	assert.ErrorIs(t, err, ErrDNSRecordNotFound)
+
+	change, err := client.GetScheduledChange(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, ScheduleStatusFailed, change.Status)
+	assert.NotNil(t, change.AttemptedAt)
+	assert.NotEmpty(t, change.Error)
+	require.Len(t, change.Results, 1)
+	assert.Equal(t, BatchStatusNotFound, change.Results[0].Status)
+
+	// 失败的变更不会被再次执行
+	_, err = client.ApplyScheduledChange(ctx, id)
+	assert.Error(t, err)
+}
@@ -3,60 +3,227 @@ package etcdclient
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/google/uuid"
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
 	clientv3 "go.etcd.io/etcd/client/v3"
 	"go.uber.org/zap"
 )
 
 // ServiceInstance 表示一个服务实例
 type ServiceInstance struct {
-	ServiceName string            `json:"service_name"`       // 服务名称
-	InstanceID  string            `json:"instance_id"`        // 实例ID（UUID）
-	IPAddress   string            `json:"ip_address"`         // IP地址
-	Port        int               `json:"port"`               // 端口
-	Metadata    map[string]string `json:"metadata,omitempty"` // 可选元数据（版本、区域等）
-	TTL         int               `json:"ttl"`                // 租约TTL（秒）
+	ServiceName string            `json:"service_name"`          // 服务名称
+	InstanceID  string            `json:"instance_id"`           // 实例ID（UUID）
+	IPAddress   string            `json:"ip_address"`            // IP地址
+	Port        int               `json:"port"`                  // 端口
+	Ports       []NamedPort       `json:"ports,omitempty"`       // 可选命名端口列表，每个端口生成RFC 2782形式的SRV记录
+	Metadata    map[string]string `json:"metadata,omitempty"`    // 可选元数据（版本、区域等）
+	TTL         int               `json:"ttl"`                   // 租约TTL（秒）
+	DNSTTL      int               `json:"dns_ttl,omitempty"`     // DNS应答TTL（秒），为0时使用全局默认值
+	Health      string            `json:"health,omitempty"`      // 健康状态 (passing, warning, critical, draining)
+	Maintenance bool              `json:"maintenance,omitempty"` // 是否处于维护模式
+	Namespace   string            `json:"namespace,omitempty"`   // 所属命名空间，为空表示default
+	Check       *CheckResult      `json:"check,omitempty"`       // 外部检查器最近推送的检查结果
+	Report      *HealthReport     `json:"report,omitempty"`      // 实例最近一次随心跳上报的健康信息
+	LoadScore   *LoadScore        `json:"load_score,omitempty"`  // 按负载调整权重时平滑后的负载
+
+	// 频繁加入和离开的实例在该时间之前不参与DNS应答，由注册时的抖动抑制设置
+	SuppressedUntil *time.Time `json:"suppressed_until,omitempty"`
 }
 
 // RegisterService 将服务实例注册到etcd
+// 命名空间和配额校验之后，实例在单个事务中写入：事务确认命名空间仍然存在、校验之后命名空间中没有并发加入的实例，
+// 条件不满足时重新校验并按退避重试。重复注册会替换实例原有的租约
+// 服务的注册策略开启重复地址检测时，其他实例已使用相同的IP和端口会拒绝注册（ErrDuplicateAddress）或在同一事务中删除旧实例（事务确认旧实例在检测之后没有变化），
+// 实例在强制驱逐的冷却期内时拒绝注册（ErrInstanceEvicted），服务名称或命名空间是保留的名称时拒绝注册（ErrReservedName），
+// 实例已在其他命名空间注册时拒绝注册（ErrNamespaceMismatch）
 func (e *EtcdClient) RegisterService(ctx context.Context, instance *ServiceInstance) error {
-	if e.client == nil {
+	if e.conn() == nil {
 		return fmt.Errorf("etcd客户端未连接")
 	}
 
+	// 未指定实例ID时生成UUID，调用方从instance中读取生成的ID
+	if instance.InstanceID == "" {
+		instance.InstanceID = uuid.New().String()
+	}
+
+	// 未指定健康状态时默认为passing
+	if instance.Health == "" {
+		instance.Health = HealthPassing
+	}
+	if !ValidHealthStatus(instance.Health) {
+		return fmt.Errorf("无效的健康状态: %s", instance.Health)
+	}
+	if err := ValidatePorts(instance.Ports); err != nil {
+		return err
+	}
+
 	// 生成服务实例键
 	key := getServiceInstanceKey(instance.ServiceName, instance.InstanceID)
+	namespace := NamespaceOf(instance.Namespace)
 
-	// 序列化服务实例
-	data, err := json.Marshal(instance)
-	if err != nil {
-		e.logger.Error("序列化服务实例失败",
-			zap.String("service", instance.ServiceName),
-			zap.String("id", instance.InstanceID),
-			zap.Error(err))
-		return fmt.Errorf("序列化服务实例失败: %w", err)
+	// 保留的服务名称和命名空间不能注册
+	if err := e.checkReservedService(instance.ServiceName); err != nil {
+		return err
+	}
+	if err := e.checkReservedNamespace(namespace); err != nil {
+		return err
 	}
 
-	ctx, cancel := context.WithTimeout(ctx, etcdTimeout)
-	defer cancel()
+	// 抑制状态只由抖动抑制设置，不接受调用方传入
+	instance.SuppressedUntil = nil
 
-	// 创建租约
-	lease, err := e.client.Grant(ctx, int64(instance.TTL))
+	// 创建租约，所有重试共用同一个租约，注册失败时撤销
+	grantCtx, cancel := context.WithTimeout(ctx, etcdTimeout)
+	lease, err := e.conn().Grant(grantCtx, e.leaseTTL(instance.TTL))
+	cancel()
 	if err != nil {
-		e.logger.Error("创建etcd租约失败", zap.Error(err))
+		e.log(ctx).Error("创建etcd租约失败", zap.Error(err))
 		return fmt.Errorf("创建etcd租约失败: %w", err)
 	}
 
-	// 写入带租约的键值
-	_, err = e.client.Put(ctx, key, string(data), clientv3.WithLease(lease.ID))
+	duplicatePolicy, err := e.duplicatePolicy(ctx, instance.ServiceName)
 	if err != nil {
-		e.logger.Error("注册服务实例失败", zap.Error(err))
-		return fmt.Errorf("注册服务实例失败: %w", err)
+		e.revokeLease(lease.ID)
+		return err
 	}
 
-	e.logger.Info("服务实例注册成功",
+	var prevLease clientv3.LeaseID
+	var replaced []*storedInstance
+	err = retryTxn(ctx, func() error {
+		// 校验命名空间是否存在
+		if err := e.ensureNamespace(ctx, namespace); err != nil {
+			return err
+		}
+
+		// 重复注册不能改变实例所在的命名空间
+		namespaceCmp, err := e.checkInstanceNamespace(ctx, key, namespace)
+		if err != nil {
+			return err
+		}
+
+		// 校验命名空间配额
+		quotaCmps, quotaOps, err := e.checkInstanceQuota(ctx, instance)
+		if err != nil {
+			e.log(ctx).Warn("服务实例超出命名空间配额",
+				zap.String("service", instance.ServiceName),
+				zap.String("id", instance.InstanceID),
+				zap.String("namespace", namespace),
+				zap.Error(err))
+			return err
+		}
+
+		// 检测使用相同地址的其他实例
+		var duplicates []*storedInstance
+		if duplicatePolicy != DuplicateAllow {
+			if duplicates, err = e.findDuplicateInstances(ctx, instance); err != nil {
+				return err
+			}
+			if len(duplicates) > 0 && duplicatePolicy == DuplicateReject {
+				return fmt.Errorf("%w: %s:%d 已由实例 %s 使用", ErrDuplicateAddress,
+					instance.IPAddress, instance.Port, duplicates[0].instance.InstanceID)
+			}
+		}
+
+		// 被强制驱逐的实例在冷却期内不能重新注册
+		evictionCmp, err := e.checkEviction(ctx, instance)
+		if err != nil {
+			return err
+		}
+
+		// 开启抖动抑制时，新加入的实例计入所在地址的加入次数，重复注册沿用原有的抑制状态
+		var cmps []clientv3.Cmp
+		var flapOps []clientv3.Op
+		var joins int
+		if e.flapDampingEnabled() {
+			if cmps, flapOps, joins, err = e.dampFlapping(ctx, key, instance); err != nil {
+				return err
+			}
+		}
+		cmps = append(cmps, evictionCmp)
+
+		data, err := json.Marshal(instance)
+		if err != nil {
+			e.log(ctx).Error("序列化服务实例失败",
+				zap.String("service", instance.ServiceName),
+				zap.String("id", instance.InstanceID),
+				zap.Error(err))
+			return fmt.Errorf("序列化服务实例失败: %w", err)
+		}
+
+		if namespace != DefaultNamespace {
+			cmps = append(cmps, clientv3.Compare(clientv3.CreateRevision(getNamespaceKey(namespace)), ">", 0))
+		}
+		cmps = append(cmps, namespaceCmp)
+		cmps = append(cmps, quotaCmps...)
+		// 检测之后被替换的实例发生变化时重新检测
+		for _, duplicate := range duplicates {
+			cmps = append(cmps, clientv3.Compare(clientv3.ModRevision(duplicate.key), "=", duplicate.modRevision))
+		}
+
+		ops := []clientv3.Op{clientv3.OpPut(key, string(data), clientv3.WithLease(lease.ID), clientv3.WithPrevKV())}
+		for _, duplicate := range duplicates {
+			ops = append(ops, clientv3.OpDelete(duplicate.key))
+		}
+		ops = append(ops, flapOps...)
+		ops = append(ops, quotaOps...)
+
+		txnCtx, cancel := context.WithTimeout(ctx, etcdTimeout)
+		defer cancel()
+
+		resp, err := e.conn().Txn(txnCtx).
+			If(cmps...).
+			Then(ops...).
+			Commit()
+		if err != nil {
+			e.log(ctx).Error("注册服务实例失败", zap.Error(err))
+			return fmt.Errorf("注册服务实例失败: %w", err)
+		}
+		if !resp.Succeeded {
+			return errTxnConflict
+		}
+		if prev := resp.Responses[0].GetResponsePut().PrevKv; prev != nil {
+			prevLease = clientv3.LeaseID(prev.Lease)
+		}
+		replaced = duplicates
+		if instance.SuppressedUntil != nil && joins > 0 {
+			e.log(ctx).Warn("实例频繁加入和离开，暂时不参与DNS应答",
+				zap.String("service", instance.ServiceName),
+				zap.String("id", instance.InstanceID),
+				zap.Int("joins", joins),
+				zap.Time("suppressed_until", *instance.SuppressedUntil))
+		}
+		return nil
+	})
+	if err != nil {
+		e.revokeLease(lease.ID)
+		return err
+	}
+
+	// 重复注册时旧租约只绑定了这个实例，已不再使用
+	if prevLease != clientv3.NoLease && prevLease != lease.ID {
+		e.revokeLease(prevLease)
+	}
+
+	// 被替换的旧实例已删除，撤销它们的租约
+	for _, duplicate := range replaced {
+		e.log(ctx).Info("替换使用相同地址的旧实例",
+			zap.String("service", instance.ServiceName),
+			zap.String("id", instance.InstanceID),
+			zap.String("replaced", duplicate.instance.InstanceID))
+		if duplicate.lease != clientv3.NoLease {
+			e.revokeLease(duplicate.lease)
+		}
+	}
+
+	e.log(ctx).Info("服务实例注册成功",
 		zap.String("service", instance.ServiceName),
 		zap.String("id", instance.InstanceID),
 		zap.String("ip", instance.IPAddress),
@@ -65,38 +232,115 @@ func (e *EtcdClient) RegisterService(ctx context.Context, instance *ServiceInsta
 	return nil
 }
 
+// checkInstanceNamespace 校验重复注册的实例仍在原来的命名空间中，返回确认实例在写入前没有被重新创建的比较条件
+// 实例键中不包含命名空间，允许改变命名空间会使另一个命名空间中的同名实例被覆盖
+func (e *EtcdClient) checkInstanceNamespace(ctx context.Context, key, namespace string) (clientv3.Cmp, error) {
+	ctx, cancel := context.WithTimeout(ctx, etcdTimeout)
+	defer cancel()
+
+	resp, err := e.conn().Get(ctx, key)
+	if err != nil {
+		return clientv3.Cmp{}, fmt.Errorf("获取服务实例失败: %w", err)
+	}
+	if len(resp.Kvs) == 0 {
+		return clientv3.Compare(clientv3.CreateRevision(key), "=", 0), nil
+	}
+
+	var stored ServiceInstance
+	if err := json.Unmarshal(resp.Kvs[0].Value, &stored); err != nil {
+		return clientv3.Cmp{}, fmt.Errorf("解析服务实例失败: %w", err)
+	}
+	if existing := NamespaceOf(stored.Namespace); existing != namespace {
+		return clientv3.Cmp{}, fmt.Errorf("%w: 实例 %s 属于命名空间 %s", ErrNamespaceMismatch, stored.InstanceID, existing)
+	}
+	return clientv3.Compare(clientv3.CreateRevision(key), "=", resp.Kvs[0].CreateRevision), nil
+}
+
 // DeregisterService 从etcd注销服务实例
+// 删除在事务中比较实例的修订版本，并发修改时重试；删除成功后撤销实例的租约。实例不存在时视为成功
 func (e *EtcdClient) DeregisterService(ctx context.Context, serviceName, instanceID string) error {
-	if e.client == nil {
+	if e.conn() == nil {
 		return fmt.Errorf("etcd客户端未连接")
 	}
 
 	// 生成服务实例键
 	key := getServiceInstanceKey(serviceName, instanceID)
 
-	ctx, cancel := context.WithTimeout(ctx, etcdTimeout)
-	defer cancel()
+	var lease clientv3.LeaseID
+	err := retryTxn(ctx, func() error {
+		ctx, cancel := context.WithTimeout(ctx, etcdTimeout)
+		defer cancel()
+
+		resp, err := e.conn().Get(ctx, key)
+		if err != nil {
+			return fmt.Errorf("注销服务实例失败: %w", err)
+		}
+		if len(resp.Kvs) == 0 {
+			return nil
+		}
 
-	// 删除键
-	_, err := e.client.Delete(ctx, key)
+		txnResp, err := e.conn().Txn(ctx).
+			If(clientv3.Compare(clientv3.ModRevision(key), "=", resp.Kvs[0].ModRevision)).
+			Then(clientv3.OpDelete(key)).
+			Commit()
+		if err != nil {
+			return fmt.Errorf("注销服务实例失败: %w", err)
+		}
+		if !txnResp.Succeeded {
+			return errTxnConflict
+		}
+		lease = clientv3.LeaseID(resp.Kvs[0].Lease)
+		return nil
+	})
 	if err != nil {
-		e.logger.Error("注销服务实例失败",
+		e.log(ctx).Error("注销服务实例失败",
 			zap.String("service", serviceName),
 			zap.String("id", instanceID),
 			zap.Error(err))
-		return fmt.Errorf("注销服务实例失败: %w", err)
+		return err
 	}
 
-	e.logger.Info("服务实例注销成功",
+	if lease != clientv3.NoLease {
+		e.revokeLease(lease)
+	}
+
+	e.log(ctx).Info("服务实例注销成功",
 		zap.String("service", serviceName),
 		zap.String("id", instanceID))
 
 	return nil
 }
 
+// revokeLease 撤销不再使用的租约，失败时只记录日志，租约到期后会自动释放
+func (e *EtcdClient) revokeLease(id clientv3.LeaseID) {
+	ctx, cancel := context.WithTimeout(context.Background(), etcdTimeout)
+	defer cancel()
+
+	if _, err := e.conn().Revoke(ctx, id); err != nil && !errors.Is(err, rpctypes.ErrLeaseNotFound) {
+		e.logger.Warn("撤销etcd租约失败", zap.Int64("lease", int64(id)), zap.Error(err))
+	}
+}
+
+// revokeLeaseIfUnused 租约已经没有绑定任何键时撤销租约
+// 从备份恢复的实例按TTL共用租约，其他实例仍在使用时保留租约，到期后自动释放
+func (e *EtcdClient) revokeLeaseIfUnused(id clientv3.LeaseID) {
+	ctx, cancel := context.WithTimeout(context.Background(), etcdTimeout)
+	defer cancel()
+
+	resp, err := e.conn().TimeToLive(ctx, id, clientv3.WithAttachedKeys())
+	if err != nil {
+		e.logger.Warn("查询etcd租约失败", zap.Int64("lease", int64(id)), zap.Error(err))
+		return
+	}
+	if resp.TTL < 0 || len(resp.Keys) > 0 {
+		return
+	}
+	e.revokeLease(id)
+}
+
 // GetServiceInstances 获取指定服务的所有实例
 func (e *EtcdClient) GetServiceInstances(ctx context.Context, serviceName string) ([]*ServiceInstance, error) {
-	if e.client == nil {
+	if e.conn() == nil {
 		return nil, fmt.Errorf("etcd客户端未连接")
 	}
 
@@ -107,9 +351,9 @@ func (e *EtcdClient) GetServiceInstances(ctx context.Context, serviceName string
 	defer cancel()
 
 	// 查询前缀
-	resp, err := e.client.Get(ctx, prefix, clientv3.WithPrefix())
+	resp, err := e.conn().Get(ctx, prefix, clientv3.WithPrefix())
 	if err != nil {
-		e.logger.Error("获取服务实例列表失败",
+		e.log(ctx).Error("获取服务实例列表失败",
 			zap.String("service", serviceName),
 			zap.Error(err))
 		return nil, fmt.Errorf("获取服务实例列表失败: %w", err)
@@ -119,7 +363,7 @@ func (e *EtcdClient) GetServiceInstances(ctx context.Context, serviceName string
 	for _, kv := range resp.Kvs {
 		var instance ServiceInstance
 		if err := json.Unmarshal(kv.Value, &instance); err != nil {
-			e.logger.Warn("解析服务实例数据失败",
+			e.log(ctx).Warn("解析服务实例数据失败",
 				zap.String("key", string(kv.Key)),
 				zap.Error(err))
 			continue
@@ -130,8 +374,83 @@ func (e *EtcdClient) GetServiceInstances(ctx context.Context, serviceName string
 	return instances, nil
 }
 
+// ServiceSummary 服务概要信息
+type ServiceSummary struct {
+	ServiceName string `json:"service_name"` // 服务名称
+	Namespace   string `json:"namespace"`    // 所属命名空间
+	Instances   int    `json:"instances"`    // 实例总数
+	Routable    int    `json:"routable"`     // 可参与DNS应答的实例数
+}
+
+// ListServices 列出所有已注册的服务及其实例数，不同命名空间中的同名服务分别列出，按服务名和命名空间排序
+func (e *EtcdClient) ListServices(ctx context.Context) ([]*ServiceSummary, error) {
+	if e.conn() == nil {
+		return nil, fmt.Errorf("etcd客户端未连接")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, etcdTimeout)
+	defer cancel()
+
+	resp, err := e.conn().Get(ctx, "/services/", clientv3.WithPrefix())
+	if err != nil {
+		e.log(ctx).Error("获取服务列表失败", zap.Error(err))
+		return nil, fmt.Errorf("获取服务列表失败: %w", err)
+	}
+
+	summaries := make(map[serviceID]*ServiceSummary)
+	for _, kv := range resp.Kvs {
+		var instance ServiceInstance
+		if err := json.Unmarshal(kv.Value, &instance); err != nil {
+			e.log(ctx).Warn("解析服务实例数据失败", zap.String("key", string(kv.Key)), zap.Error(err))
+			continue
+		}
+
+		// 不同命名空间中的同名服务分别统计
+		id := serviceID{namespace: NamespaceOf(instance.Namespace), name: instance.ServiceName}
+		summary, ok := summaries[id]
+		if !ok {
+			summary = &ServiceSummary{
+				ServiceName: id.name,
+				Namespace:   id.namespace,
+			}
+			summaries[id] = summary
+		}
+		summary.Instances++
+		if instance.Routable() {
+			summary.Routable++
+		}
+	}
+
+	result := make([]*ServiceSummary, 0, len(summaries))
+	for _, summary := range summaries {
+		result = append(result, summary)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].ServiceName != result[j].ServiceName {
+			return result[i].ServiceName < result[j].ServiceName
+		}
+		return result[i].Namespace < result[j].Namespace
+	})
+
+	return result, nil
+}
+
+// serviceID 命名空间中的一个服务
+type serviceID struct {
+	namespace string
+	name      string
+}
+
 // ServiceToDNSRecords 将服务实例转换为DNS记录
 func (e *EtcdClient) ServiceToDNSRecords(ctx context.Context, domain string) (map[string]*DNSRecord, error) {
+	return e.ServiceToDNSRecordsWithOptions(ctx, domain, ServiceRecordOptions{})
+}
+
+// ServiceToDNSRecordsWithOptions 按选项将服务实例转换为DNS记录
+func (e *EtcdClient) ServiceToDNSRecordsWithOptions(ctx context.Context, domain string, opts ServiceRecordOptions) (map[string]*DNSRecord, error) {
+	// RFC 2782形式的域名只包含提供该端口名称和协议的实例，SRV目标仍在服务域名下
+	portName, protocol, domain := ParseServiceDomain(domain)
+
 	// 提取服务名（假设domain格式为service.namespace.svc.cluster.local）
 	parts := strings.Split(domain, ".")
 	if len(parts) < 1 {
@@ -139,118 +458,367 @@ func (e *EtcdClient) ServiceToDNSRecords(ctx context.Context, domain string) (ma
 	}
 
 	serviceName := parts[0]
+	namespace := serviceDomainNamespace(domain)
 
-	// 获取服务实例
+	// 获取服务实例，只有域名所在命名空间的实例参与应答
 	instances, err := e.GetServiceInstances(ctx, serviceName)
 	if err != nil {
 		return nil, fmt.Errorf("获取服务实例失败: %w", err)
 	}
+	instances = instancesInNamespace(instances, namespace)
 
 	if len(instances) == 0 {
+		// 命名空间不存在时返回ErrNamespaceNotFound，DNS服务器以NXDOMAIN应答
+		if err := e.ensureNamespace(ctx, namespace); err != nil {
+			return nil, err
+		}
 		return nil, fmt.Errorf("未找到服务实例: %s", serviceName)
 	}
 
-	// 创建DNS记录
+	// 服务处于维护模式时不参与DNS应答
+	maintenance, err := e.GetServiceMaintenance(ctx, serviceName)
+	if err != nil {
+		return nil, fmt.Errorf("获取服务维护状态失败: %w", err)
+	}
+	if maintenance != nil {
+		return nil, fmt.Errorf("服务处于维护模式: %s", serviceName)
+	}
+
+	// 只有健康的实例才参与DNS应答，服务的应答策略允许时包括摘流中的实例
+	instances = answerInstances(instances, opts.IncludeDraining)
+	if len(instances) == 0 {
+		return nil, fmt.Errorf("服务没有健康的实例: %s", serviceName)
+	}
+	instances = instancesForPort(instances, portName, protocol)
+	if len(instances) == 0 {
+		return nil, fmt.Errorf("服务没有提供 _%s._%s 的实例: %s", portName, protocol, serviceName)
+	}
+
+	return e.serviceDNSRecords(domain, instances, opts), nil
+}
+
+// serviceDNSRecords 根据可用实例生成服务域名的A/AAAA和SRV记录
+func (e *EtcdClient) serviceDNSRecords(domain string, instances []*ServiceInstance, opts ServiceRecordOptions) map[string]*DNSRecord {
 	records := make(map[string]*DNSRecord)
+	ttl := e.serviceDNSTTL(instances, opts.DefaultTTL)
+
+	weights := make([]int, len(instances))
+	now := time.Now()
+	for i, instance := range instances {
+		weights[i] = srvMetadataValue(instance, MetadataSRVWeight)
+		if opts.LoadWeights {
+			weights[i] = e.loadWeight(instance, weights[i], now)
+		}
+	}
 
-	// A记录 - 使用第一个实例的IP（简单负载均衡可以在DNS层之上实现）
-	records["A"] = &DNSRecord{
-		Type:  "A",
-		Value: instances[0].IPAddress,
-		TTL:   60,
+	// A/AAAA记录 - 每个地址族使用第一个实例的IP（简单负载均衡可以在DNS层之上实现），
+	// 按负载调整权重时按调整后的权重随机选取实例。服务同时注册了IPv4和IPv6实例时两种记录都会生成
+	for _, recordType := range []string{"A", "AAAA"} {
+		var family []*ServiceInstance
+		var familyWeights []int
+		for i, instance := range instances {
+			if AddressRecordType(instance.IPAddress) == recordType {
+				family = append(family, instance)
+				familyWeights = append(familyWeights, weights[i])
+			}
+		}
+		if len(family) == 0 {
+			continue
+		}
+		instance := family[0]
+		if opts.LoadWeights {
+			instance = pickWeighted(family, familyWeights)
+		}
+		records[recordType] = &DNSRecord{
+			Type:  recordType,
+			Value: instance.IPAddress,
+			TTL:   ttl,
+		}
 	}
 
 	// SRV记录 - 列出所有实例的IP:Port
 	for i, instance := range instances {
 		// SRV记录格式：priority weight port target
-		srvValue := fmt.Sprintf("10 10 %d %s.%s", instance.Port, instance.InstanceID, domain)
+		srvValue := fmt.Sprintf("%d %d %d %s.%s",
+			srvMetadataValue(instance, MetadataSRVPriority), weights[i],
+			instance.Port, instance.InstanceID, domain)
 		records[fmt.Sprintf("SRV-%d", i)] = &DNSRecord{
-			Type:  "SRV",
-			Value: srvValue,
-			TTL:   60,
+			Type:    "SRV",
+			Value:   srvValue,
+			TTL:     ttl,
+			Address: instance.IPAddress,
 		}
 	}
 
-	return records, nil
+	return records
+}
+
+// 实例元数据中设置SRV记录优先级和权重的键，取值0到65535，未设置或无效时都为10
+// 客户端只使用优先级数值最小的一组实例，同一组内按权重分配流量
+const (
+	MetadataSRVPriority = "srv_priority"
+	MetadataSRVWeight   = "srv_weight"
+)
+
+// defaultSRVValue 实例未设置SRV优先级或权重时使用的值
+const defaultSRVValue = 10
+
+// srvMetadataValue 返回实例元数据中的SRV优先级或权重
+func srvMetadataValue(instance *ServiceInstance, key string) int {
+	value, ok := instance.Metadata[key]
+	if !ok {
+		return defaultSRVValue
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 || n > 65535 {
+		return defaultSRVValue
+	}
+	return n
+}
+
+// AddressRecordType 返回IP地址对应的DNS记录类型：IPv4为A，IPv6为AAAA，不是有效的IP时返回空字符串
+func AddressRecordType(ip string) string {
+	parsed := net.ParseIP(ip)
+	switch {
+	case parsed == nil:
+		return ""
+	case parsed.To4() != nil:
+		return "A"
+	default:
+		return "AAAA"
+	}
+}
+
+// instancesInNamespace 过滤出属于指定命名空间的实例
+func instancesInNamespace(instances []*ServiceInstance, namespace string) []*ServiceInstance {
+	result := make([]*ServiceInstance, 0, len(instances))
+	for _, instance := range instances {
+		if NamespaceOf(instance.Namespace) == namespace {
+			result = append(result, instance)
+		}
+	}
+	return result
+}
+
+// routableInstances 过滤出可以参与DNS应答的实例
+func routableInstances(instances []*ServiceInstance) []*ServiceInstance {
+	result := make([]*ServiceInstance, 0, len(instances))
+	for _, instance := range instances {
+		if instance.Routable() {
+			result = append(result, instance)
+		}
+	}
+	return result
+}
+
+// 服务发现应答的默认TTL（秒），配置中未设置时使用
+const defaultServiceDNSTTL = 60
+
+// serviceDNSTTL 计算服务DNS记录的TTL
+// 取实例中设置的最小DNS TTL，都未设置时使用defaultTTL（服务或命名空间TTL策略的默认TTL），再使用配置中的服务默认TTL
+func (e *EtcdClient) serviceDNSTTL(instances []*ServiceInstance, defaultTTL int) int {
+	ttl := 0
+	for _, instance := range instances {
+		if instance.DNSTTL > 0 && (ttl == 0 || instance.DNSTTL < ttl) {
+			ttl = instance.DNSTTL
+		}
+	}
+	if ttl > 0 {
+		return ttl
+	}
+	if defaultTTL > 0 {
+		return defaultTTL
+	}
+
+	if e.cfg != nil && e.cfg.DNS.ServiceTTL > 0 {
+		return e.cfg.DNS.ServiceTTL
+	}
+	return defaultServiceDNSTTL
 }
 
 // RefreshServiceLease 刷新服务实例的租约
 func (e *EtcdClient) RefreshServiceLease(ctx context.Context, serviceName, instanceID string, ttl int) error {
-	if e.client == nil {
-		return fmt.Errorf("etcd客户端未连接")
+	_, err := e.RefreshServiceLeaseWithReport(ctx, serviceName, instanceID, ttl, nil)
+	return err
+}
+
+// RefreshServiceLeaseWithReport 刷新服务实例的租约，report不为nil时同时保存心跳携带的健康信息，
+// 报告了健康状态时更新实例的健康状态（摘流中的实例保持draining）。返回更新后的实例
+// 实例在读取之后被并发修改（驱逐、摘流、维护、注销等）时事务失败并重新读取，心跳不会覆盖这些修改，
+// 也不会重新写入已注销的实例；写入成功后撤销不再使用的旧租约
+func (e *EtcdClient) RefreshServiceLeaseWithReport(ctx context.Context, serviceName, instanceID string, ttl int, report *HealthReport) (*ServiceInstance, error) {
+	if report != nil {
+		if err := report.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	if e.conn() == nil {
+		return nil, fmt.Errorf("etcd客户端未连接")
 	}
 
 	// 生成服务实例键
 	key := getServiceInstanceKey(serviceName, instanceID)
 
-	// 首先获取当前服务实例数据
+	var instance ServiceInstance
+	var prevLease clientv3.LeaseID
+	err := retryTxn(ctx, func() error {
+		ctx, cancel := context.WithTimeout(ctx, etcdTimeout)
+		defer cancel()
+
+		// 首先获取当前服务实例数据
+		resp, err := e.conn().Get(ctx, key)
+		if err != nil {
+			e.log(ctx).Error("获取服务实例数据失败",
+				zap.String("service", serviceName),
+				zap.String("id", instanceID),
+				zap.Error(err))
+			return fmt.Errorf("获取服务实例数据失败: %w", err)
+		}
+
+		if len(resp.Kvs) == 0 {
+			e.log(ctx).Warn("服务实例不存在，无法刷新租约",
+				zap.String("service", serviceName),
+				zap.String("id", instanceID))
+			return fmt.Errorf("%w: %s/%s", ErrInstanceNotFound, serviceName, instanceID)
+		}
+
+		// 解析服务实例数据
+		instance = ServiceInstance{}
+		if err := json.Unmarshal(resp.Kvs[0].Value, &instance); err != nil {
+			e.log(ctx).Error("解析服务实例数据失败",
+				zap.String("service", serviceName),
+				zap.String("id", instanceID),
+				zap.Error(err))
+			return fmt.Errorf("解析服务实例数据失败: %w", err)
+		}
+
+		// 如果提供了TTL，则更新实例的TTL
+		if ttl > 0 {
+			instance.TTL = ttl
+		}
+
+		if report != nil {
+			report.UpdatedAt = time.Now()
+			instance.Report = report
+			e.smoothLoad(&instance, report, report.UpdatedAt)
+			if status := report.HealthStatus(); status != "" && instance.HealthStatus() != HealthDraining {
+				instance.Health = status
+			}
+		}
+
+		// 序列化更新后的服务实例
+		data, err := json.Marshal(&instance)
+		if err != nil {
+			e.log(ctx).Error("序列化服务实例失败",
+				zap.String("service", serviceName),
+				zap.String("id", instanceID),
+				zap.Error(err))
+			return fmt.Errorf("序列化服务实例失败: %w", err)
+		}
+
+		// 创建新的租约
+		lease, err := e.conn().Grant(ctx, e.leaseTTL(instance.TTL))
+		if err != nil {
+			e.log(ctx).Error("创建etcd租约失败", zap.Error(err))
+			return fmt.Errorf("创建etcd租约失败: %w", err)
+		}
+
+		// 实例自读取之后没有变化时才使用新租约写入
+		txnResp, err := e.conn().Txn(ctx).
+			If(clientv3.Compare(clientv3.ModRevision(key), "=", resp.Kvs[0].ModRevision)).
+			Then(clientv3.OpPut(key, string(data), clientv3.WithLease(lease.ID))).
+			Commit()
+		if err != nil {
+			e.revokeLease(lease.ID)
+			e.log(ctx).Error("刷新服务实例租约失败",
+				zap.String("service", serviceName),
+				zap.String("id", instanceID),
+				zap.Error(err))
+			return fmt.Errorf("刷新服务实例租约失败: %w", err)
+		}
+		if !txnResp.Succeeded {
+			e.revokeLease(lease.ID)
+			return errTxnConflict
+		}
+		prevLease = clientv3.LeaseID(resp.Kvs[0].Lease)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// 实例已改用新租约，旧租约不再被其他实例使用时撤销
+	if prevLease != clientv3.NoLease {
+		e.revokeLeaseIfUnused(prevLease)
+	}
+
+	e.log(ctx).Info("服务实例租约刷新成功",
+		zap.String("service", serviceName),
+		zap.String("id", instanceID),
+		zap.Int("ttl", instance.TTL))
+
+	return &instance, nil
+}
+
+// updateServiceInstance 读取服务实例并通过update修改后写回
+// 写入时比较修订版本避免覆盖并发修改，并沿用实例原有的租约
+func (e *EtcdClient) updateServiceInstance(ctx context.Context, serviceName, instanceID string, update func(*ServiceInstance) error) (*ServiceInstance, error) {
+	if e.conn() == nil {
+		return nil, fmt.Errorf("etcd客户端未连接")
+	}
+
+	key := getServiceInstanceKey(serviceName, instanceID)
+
 	ctx, cancel := context.WithTimeout(ctx, etcdTimeout)
 	defer cancel()
 
-	resp, err := e.client.Get(ctx, key)
+	resp, err := e.conn().Get(ctx, key)
 	if err != nil {
-		e.logger.Error("获取服务实例数据失败",
+		e.log(ctx).Error("获取服务实例数据失败",
 			zap.String("service", serviceName),
 			zap.String("id", instanceID),
 			zap.Error(err))
-		return fmt.Errorf("获取服务实例数据失败: %w", err)
+		return nil, fmt.Errorf("获取服务实例数据失败: %w", err)
 	}
 
 	if len(resp.Kvs) == 0 {
-		e.logger.Warn("服务实例不存在，无法刷新租约",
-			zap.String("service", serviceName),
-			zap.String("id", instanceID))
-		return fmt.Errorf("服务实例不存在: %s/%s", serviceName, instanceID)
+		return nil, fmt.Errorf("%w: %s/%s", ErrInstanceNotFound, serviceName, instanceID)
 	}
 
-	// 解析服务实例数据
 	var instance ServiceInstance
 	if err := json.Unmarshal(resp.Kvs[0].Value, &instance); err != nil {
-		e.logger.Error("解析服务实例数据失败",
+		e.log(ctx).Error("解析服务实例数据失败",
 			zap.String("service", serviceName),
 			zap.String("id", instanceID),
 			zap.Error(err))
-		return fmt.Errorf("解析服务实例数据失败: %w", err)
+		return nil, fmt.Errorf("解析服务实例数据失败: %w", err)
 	}
 
-	// 如果提供了TTL，则更新实例的TTL
-	if ttl > 0 {
-		instance.TTL = ttl
+	if err := update(&instance); err != nil {
+		return nil, err
 	}
 
-	// 创建新的租约
-	lease, err := e.client.Grant(ctx, int64(instance.TTL))
-	if err != nil {
-		e.logger.Error("创建etcd租约失败", zap.Error(err))
-		return fmt.Errorf("创建etcd租约失败: %w", err)
-	}
-
-	// 序列化更新后的服务实例
 	data, err := json.Marshal(&instance)
 	if err != nil {
-		e.logger.Error("序列化服务实例失败",
-			zap.String("service", serviceName),
-			zap.String("id", instanceID),
-			zap.Error(err))
-		return fmt.Errorf("序列化服务实例失败: %w", err)
+		return nil, fmt.Errorf("序列化服务实例失败: %w", err)
 	}
 
-	// 使用新租约写入服务实例数据
-	_, err = e.client.Put(ctx, key, string(data), clientv3.WithLease(lease.ID))
+	txnResp, err := e.conn().Txn(ctx).
+		If(clientv3.Compare(clientv3.ModRevision(key), "=", resp.Kvs[0].ModRevision)).
+		Then(clientv3.OpPut(key, string(data), clientv3.WithIgnoreLease())).
+		Commit()
 	if err != nil {
-		e.logger.Error("刷新服务实例租约失败",
+		e.log(ctx).Error("更新服务实例失败",
 			zap.String("service", serviceName),
 			zap.String("id", instanceID),
 			zap.Error(err))
-		return fmt.Errorf("刷新服务实例租约失败: %w", err)
+		return nil, fmt.Errorf("更新服务实例失败: %w", err)
+	}
+	if !txnResp.Succeeded {
+		return nil, fmt.Errorf("服务实例已被并发修改，请重试: %s/%s", serviceName, instanceID)
 	}
 
-	e.logger.Info("服务实例租约刷新成功",
-		zap.String("service", serviceName),
-		zap.String("id", instanceID),
-		zap.Int("ttl", instance.TTL))
-
-	return nil
+	return &instance, nil
 }
 
 // getServiceInstanceKey 生成服务实例在etcd中的键
@@ -258,6 +826,11 @@ func getServiceInstanceKey(serviceName, instanceID string) string {
 	return fmt.Sprintf("/services/%s/%s", serviceName, instanceID)
 }
 
+// ServiceInstancePrefix 返回服务实例在etcd中的键前缀
+func ServiceInstancePrefix(serviceName string) string {
+	return getServicePrefix(serviceName)
+}
+
 // getServicePrefix 生成服务在etcd中的键前缀
 func getServicePrefix(serviceName string) string {
 	return fmt.Sprintf("/services/%s/", serviceName)
@@ -83,3 +83,52 @@ func TestServiceToDNSRecords(t *testing.T) {
 	}
 	assert.True(t, foundSRV, "应该存在SRV记录")
 }
+
+func TestAddressRecordType(t *testing.T) {
+	assert.Equal(t, "A", AddressRecordType("10.0.0.1"))
+	assert.Equal(t, "AAAA", AddressRecordType("2001:db8::1"))
+	assert.Equal(t, "A", AddressRecordType("::ffff:10.0.0.1"), "IPv4映射地址按IPv4处理")
+	assert.Equal(t, "", AddressRecordType("backend.local"))
+}
+
+func TestSRVMetadataValue(t *testing.T) {
+	instance := &ServiceInstance{Metadata: map[string]string{
+		MetadataSRVPriority: "0",
+		MetadataSRVWeight:   "70000",
+	}}
+	assert.Equal(t, 0, srvMetadataValue(instance, MetadataSRVPriority))
+	// 超出范围或无效的值使用默认值
+	assert.Equal(t, defaultSRVValue, srvMetadataValue(instance, MetadataSRVWeight))
+	instance.Metadata[MetadataSRVWeight] = "abc"
+	assert.Equal(t, defaultSRVValue, srvMetadataValue(instance, MetadataSRVWeight))
+	assert.Equal(t, defaultSRVValue, srvMetadataValue(&ServiceInstance{}, MetadataSRVWeight))
+}
+
+// TestServiceToDNSRecords_DualStack 测试同时注册了IPv4和IPv6实例的服务
+func TestServiceToDNSRecords_DualStack(t *testing.T) {
+	// 跳过集成测试，除非明确要求运行
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	client := CreateEtcdClientForTest(t)
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	testServiceName := fmt.Sprintf("test-dualstack-%d", time.Now().UnixNano())
+	for id, ip := range map[string]string{"instance-v6": "2001:db8::10", "instance-v4": "192.168.1.100"} {
+		require.NoError(t, client.RegisterService(ctx, &ServiceInstance{
+			ServiceName: testServiceName, InstanceID: id, IPAddress: ip, Port: 8080, TTL: 60,
+		}))
+		defer func(id string) { _ = client.DeregisterService(context.Background(), testServiceName, id) }(id)
+	}
+
+	records, err := client.ServiceToDNSRecords(ctx, testServiceName+".default.svc.cluster.local")
+	require.NoError(t, err)
+	require.Contains(t, records, "A")
+	require.Contains(t, records, "AAAA")
+	assert.Equal(t, "192.168.1.100", records["A"].Value)
+	assert.Equal(t, "2001:db8::10", records["AAAA"].Value)
+}
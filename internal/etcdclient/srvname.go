@@ -0,0 +1,149 @@
+package etcdclient
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// 实例元数据中声明RFC 2782服务名称和传输协议的键。
+// 声明了端口名称的实例只出现在 _<端口名>._<协议>.<服务>.<命名空间>.svc.cluster.local 的应答中，
+// 未声明端口名称的实例匹配任意端口名称；协议未设置时为tcp。实例注册了命名端口列表时不使用这两个键
+const (
+	MetadataSRVService  = "srv_service"
+	MetadataSRVProtocol = "srv_protocol"
+)
+
+// defaultSRVProtocol 实例未声明传输协议时使用的协议
+const defaultSRVProtocol = "tcp"
+
+// ErrInvalidPorts 实例的命名端口列表无效
+var ErrInvalidPorts = errors.New("无效的命名端口")
+
+// maxPortNameLength 端口名称的最大长度，与RFC 6335中服务名称的限制一致
+const maxPortNameLength = 15
+
+// NamedPort 实例提供的一个命名端口，生成 _<名称>._<协议>.<服务域名> 的SRV记录
+type NamedPort struct {
+	Name     string `json:"name"`               // 端口名称，如http、grpc、metrics
+	Port     int    `json:"port"`               // 端口号
+	Protocol string `json:"protocol,omitempty"` // 传输协议（tcp、udp、sctp），为空表示tcp
+}
+
+// protocol 返回端口的传输协议，未设置时为tcp
+func (p *NamedPort) protocol() string {
+	if p.Protocol == "" {
+		return defaultSRVProtocol
+	}
+	return p.Protocol
+}
+
+// ValidatePorts 校验实例的命名端口列表：名称为不超过15个字符的小写字母、数字和连字符，
+// 至少包含一个字母且不以连字符开头或结尾；同一协议下的名称不能重复
+func ValidatePorts(ports []NamedPort) error {
+	seen := make(map[string]bool, len(ports))
+	for _, port := range ports {
+		if err := validatePortName(port.Name); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidPorts, err)
+		}
+		if port.Port < 1 || port.Port > 65535 {
+			return fmt.Errorf("%w: 端口%s的端口号%d不在1到65535之间", ErrInvalidPorts, port.Name, port.Port)
+		}
+		switch port.Protocol {
+		case "", "tcp", "udp", "sctp":
+		default:
+			return fmt.Errorf("%w: 端口%s的协议%q不是tcp、udp或sctp", ErrInvalidPorts, port.Name, port.Protocol)
+		}
+		key := port.Name + "/" + port.protocol()
+		if seen[key] {
+			return fmt.Errorf("%w: 端口名称%s在协议%s下重复", ErrInvalidPorts, port.Name, port.protocol())
+		}
+		seen[key] = true
+	}
+	return nil
+}
+
+// validatePortName 校验端口名称
+func validatePortName(name string) error {
+	if name == "" || len(name) > maxPortNameLength {
+		return fmt.Errorf("端口名称%q的长度应为1到%d个字符", name, maxPortNameLength)
+	}
+	if strings.HasPrefix(name, "-") || strings.HasSuffix(name, "-") {
+		return fmt.Errorf("端口名称%q不能以连字符开头或结尾", name)
+	}
+	letter := false
+	for _, c := range name {
+		switch {
+		case c >= 'a' && c <= 'z':
+			letter = true
+		case c >= '0' && c <= '9', c == '-':
+		default:
+			return fmt.Errorf("端口名称%q只能包含小写字母、数字和连字符", name)
+		}
+	}
+	if !letter {
+		return fmt.Errorf("端口名称%q至少需要包含一个字母", name)
+	}
+	return nil
+}
+
+// ParseServiceDomain 解析RFC 2782形式的服务域名 _<端口名>._<协议>.<服务域名>，
+// 返回端口名、协议（均为小写，不含下划线）和去掉前缀后的服务域名；不是该形式的域名原样返回，端口名和协议为空
+func ParseServiceDomain(domain string) (portName, protocol, base string) {
+	labels := strings.SplitN(domain, ".", 3)
+	if len(labels) < 3 || !strings.HasPrefix(labels[0], "_") || !strings.HasPrefix(labels[1], "_") {
+		return "", "", domain
+	}
+	portName = strings.ToLower(strings.TrimPrefix(labels[0], "_"))
+	protocol = strings.ToLower(strings.TrimPrefix(labels[1], "_"))
+	if portName == "" || protocol == "" {
+		return "", "", domain
+	}
+	return portName, protocol, labels[2]
+}
+
+// instancesForPort 过滤出提供指定端口名称和协议的实例，portName为空时不过滤。
+// 注册了命名端口列表的实例返回副本，端口替换为该命名端口的端口号，SRV记录使用该端口
+func instancesForPort(instances []*ServiceInstance, portName, protocol string) []*ServiceInstance {
+	if portName == "" {
+		return instances
+	}
+	result := make([]*ServiceInstance, 0, len(instances))
+	for _, instance := range instances {
+		if len(instance.Ports) > 0 {
+			if port, ok := instance.namedPort(portName, protocol); ok {
+				named := *instance
+				named.Port = port
+				result = append(result, &named)
+			}
+			continue
+		}
+		if instance.providesPort(portName, protocol) {
+			result = append(result, instance)
+		}
+	}
+	return result
+}
+
+// namedPort 返回实例命名端口列表中指定名称和协议的端口号
+func (s *ServiceInstance) namedPort(portName, protocol string) (int, bool) {
+	for _, port := range s.Ports {
+		if port.Name == portName && port.protocol() == protocol {
+			return port.Port, true
+		}
+	}
+	return 0, false
+}
+
+// providesPort 根据元数据判断没有命名端口列表的实例是否提供指定端口名称和协议的服务
+func (s *ServiceInstance) providesPort(portName, protocol string) bool {
+	instanceProtocol := strings.ToLower(s.Metadata[MetadataSRVProtocol])
+	if instanceProtocol == "" {
+		instanceProtocol = defaultSRVProtocol
+	}
+	if instanceProtocol != protocol {
+		return false
+	}
+	name := strings.ToLower(s.Metadata[MetadataSRVService])
+	return name == "" || name == portName
+}
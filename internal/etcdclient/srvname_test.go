@@ -0,0 +1,176 @@
+package etcdclient
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseServiceDomain(t *testing.T) {
+	tests := []struct {
+		domain   string
+		portName string
+		protocol string
+		base     string
+	}{
+		{"_http._tcp.order.default.svc.cluster.local", "http", "tcp", "order.default.svc.cluster.local"},
+		{"_DNS._UDP.coredns.kube-system.svc.cluster.local", "dns", "udp", "coredns.kube-system.svc.cluster.local"},
+		{"order.default.svc.cluster.local", "", "", "order.default.svc.cluster.local"},
+		{"_http.order.default.svc.cluster.local", "", "", "_http.order.default.svc.cluster.local"},
+		{"_._tcp.order.svc.cluster.local", "", "", "_._tcp.order.svc.cluster.local"},
+	}
+	for _, tt := range tests {
+		portName, protocol, base := ParseServiceDomain(tt.domain)
+		assert.Equal(t, tt.portName, portName, tt.domain)
+		assert.Equal(t, tt.protocol, protocol, tt.domain)
+		assert.Equal(t, tt.base, base, tt.domain)
+	}
+}
+
+// TestServiceToDNSRecords_PortName 测试RFC 2782形式的域名只返回提供该端口的实例
+func TestServiceToDNSRecords_PortName(t *testing.T) {
+	// 跳过集成测试，除非明确要求运行
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	client := CreateEtcdClientForTest(t)
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	serviceName := fmt.Sprintf("test-srvname-%d", time.Now().UnixNano())
+	instances := []*ServiceInstance{
+		{InstanceID: "web", IPAddress: "10.0.0.1", Port: 8080, Metadata: map[string]string{MetadataSRVService: "http"}},
+		{InstanceID: "rpc", IPAddress: "10.0.0.2", Port: 9090, Metadata: map[string]string{MetadataSRVService: "grpc"}},
+		{InstanceID: "dns", IPAddress: "10.0.0.3", Port: 53, Metadata: map[string]string{MetadataSRVService: "http", MetadataSRVProtocol: "udp"}},
+		{InstanceID: "any", IPAddress: "10.0.0.4", Port: 8000},
+	}
+	for _, instance := range instances {
+		instance.ServiceName = serviceName
+		instance.TTL = 60
+		require.NoError(t, client.RegisterService(ctx, instance))
+		defer func(id string) { _ = client.DeregisterService(context.Background(), serviceName, id) }(instance.InstanceID)
+	}
+
+	base := serviceName + ".default.svc.cluster.local"
+	records, err := client.ServiceToDNSRecords(ctx, "_http._tcp."+base)
+	require.NoError(t, err)
+
+	// 声明了http/tcp的实例和未声明端口名称的实例，SRV目标在服务域名下
+	var targets []string
+	for key, record := range records {
+		if record.Type == "SRV" {
+			targets = append(targets, record.Value)
+			assert.Contains(t, key, "SRV-")
+		}
+	}
+	assert.ElementsMatch(t, []string{
+		"10 10 8080 web." + base,
+		"10 10 8000 any." + base,
+	}, targets)
+
+	// 没有实例提供的端口名称返回错误
+	_, err = client.ServiceToDNSRecords(ctx, "_grpc._udp."+base)
+	assert.Error(t, err)
+}
+
+func TestValidatePorts(t *testing.T) {
+	assert.NoError(t, ValidatePorts(nil))
+	assert.NoError(t, ValidatePorts([]NamedPort{
+		{Name: "http", Port: 8080},
+		{Name: "grpc", Port: 9090},
+		{Name: "metrics", Port: 9100},
+		{Name: "dns", Port: 53, Protocol: "udp"},
+		{Name: "dns", Port: 53, Protocol: "tcp"},
+	}))
+
+	tests := []struct {
+		name  string
+		ports []NamedPort
+	}{
+		{"名称为空", []NamedPort{{Port: 8080}}},
+		{"名称过长", []NamedPort{{Name: "a-very-long-port-name", Port: 8080}}},
+		{"大写字母", []NamedPort{{Name: "HTTP", Port: 8080}}},
+		{"下划线", []NamedPort{{Name: "_http", Port: 8080}}},
+		{"连字符结尾", []NamedPort{{Name: "http-", Port: 8080}}},
+		{"纯数字", []NamedPort{{Name: "8080", Port: 8080}}},
+		{"端口号超出范围", []NamedPort{{Name: "http", Port: 70000}}},
+		{"未知协议", []NamedPort{{Name: "http", Port: 8080, Protocol: "quic"}}},
+		{"名称重复", []NamedPort{{Name: "http", Port: 8080}, {Name: "http", Port: 8081, Protocol: "tcp"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.ErrorIs(t, ValidatePorts(tt.ports), ErrInvalidPorts)
+		})
+	}
+}
+
+// TestServiceToDNSRecords_NamedPorts 测试注册了命名端口列表的实例按端口名称生成SRV记录
+func TestServiceToDNSRecords_NamedPorts(t *testing.T) {
+	// 跳过集成测试，除非明确要求运行
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	client := CreateEtcdClientForTest(t)
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	serviceName := fmt.Sprintf("test-namedport-%d", time.Now().UnixNano())
+	ports := []NamedPort{{Name: "http", Port: 8080}, {Name: "grpc", Port: 9090}, {Name: "metrics", Port: 9100}}
+	for _, id := range []string{"pod-1", "pod-2"} {
+		require.NoError(t, client.RegisterService(ctx, &ServiceInstance{
+			ServiceName: serviceName, InstanceID: id, IPAddress: "10.0.0.1", Port: 8080, Ports: ports, TTL: 60,
+			// 有命名端口列表时不使用元数据中的端口名称
+			Metadata: map[string]string{MetadataSRVService: "legacy"},
+		}))
+		defer func(id string) { _ = client.DeregisterService(context.Background(), serviceName, id) }(id)
+	}
+
+	// 无效的端口列表拒绝注册
+	err := client.RegisterService(ctx, &ServiceInstance{
+		ServiceName: serviceName, InstanceID: "pod-3", IPAddress: "10.0.0.3", Port: 8080, TTL: 60,
+		Ports: []NamedPort{{Name: "http", Port: 8080}, {Name: "http", Port: 8081}},
+	})
+	assert.ErrorIs(t, err, ErrInvalidPorts)
+
+	base := serviceName + ".default.svc.cluster.local"
+	srvValues := func(domain string) []string {
+		records, err := client.ServiceToDNSRecords(ctx, domain)
+		require.NoError(t, err)
+		var values []string
+		for _, record := range records {
+			if record.Type == "SRV" {
+				values = append(values, record.Value)
+			}
+		}
+		return values
+	}
+
+	assert.ElementsMatch(t, []string{"10 10 9090 pod-1." + base, "10 10 9090 pod-2." + base}, srvValues("_grpc._tcp."+base))
+	assert.ElementsMatch(t, []string{"10 10 9100 pod-1." + base, "10 10 9100 pod-2." + base}, srvValues("_metrics._tcp."+base))
+	// 不带端口名称的服务域名使用实例的主端口
+	assert.ElementsMatch(t, []string{"10 10 8080 pod-1." + base, "10 10 8080 pod-2." + base}, srvValues(base))
+
+	// 实例没有声明的端口名称和协议
+	_, err = client.ServiceToDNSRecords(ctx, "_legacy._tcp."+base)
+	assert.Error(t, err)
+	_, err = client.ServiceToDNSRecords(ctx, "_grpc._udp."+base)
+	assert.Error(t, err)
+
+	// 实例数据中的端口保持不变
+	instances, err := client.GetServiceInstances(ctx, serviceName)
+	require.NoError(t, err)
+	for _, instance := range instances {
+		assert.Equal(t, 8080, instance.Port)
+		assert.Equal(t, ports, instance.Ports)
+	}
+}
@@ -10,17 +10,24 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
-// 创建一个测试用的配置，使用环境变量中的etcd地址
+// ApplyTestStorage 设置测试使用的存储：设置了环境变量KONG_DISCOVERY_ETCD_ENDPOINTS时连接该etcd，
+// 否则使用内存存储，测试无需启动etcd
+func ApplyTestStorage(cfg *config.Config) {
+	if etcdEndpoints := os.Getenv("KONG_DISCOVERY_ETCD_ENDPOINTS"); etcdEndpoints != "" {
+		cfg.Storage = config.StorageEtcd
+		cfg.Etcd.Endpoints = []string{etcdEndpoints}
+		return
+	}
+	cfg.Storage = config.StorageMemory
+}
+
+// 创建一个测试用的配置，使用环境变量中的etcd地址或内存存储
 func createTestConfig(t *testing.T) *config.Config {
 	t.Helper()
 
-	// 从环境变量中获取etcd地址
-	etcdEndpoints := os.Getenv("KONG_DISCOVERY_ETCD_ENDPOINTS")
-	require.NotEmpty(t, etcdEndpoints, "环境变量KONG_DISCOVERY_ETCD_ENDPOINTS必须设置")
-
 	// 创建配置
 	cfg := &config.Config{}
-	cfg.Etcd.Endpoints = []string{etcdEndpoints}
+	ApplyTestStorage(cfg)
 	cfg.Etcd.Username = "" // 如果需要认证，设置相应的值
 	cfg.Etcd.Password = "" // 如果需要认证，设置相应的值
 
@@ -37,7 +44,7 @@ func createTestLogger(t *testing.T) config.Logger {
 	return logger
 }
 
-// CreateEtcdClientForTest 创建并连接真实的etcd客户端，供测试使用
+// CreateEtcdClientForTest 创建并连接测试用的etcd客户端，未设置etcd地址时使用内存存储
 // 这是一个导出函数，可以被其他包使用
 func CreateEtcdClientForTest(t *testing.T) Client {
 	t.Helper()
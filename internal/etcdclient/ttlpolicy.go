@@ -0,0 +1,234 @@
+package etcdclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.uber.org/zap"
+)
+
+// 服务TTL策略在etcd中的键前缀
+const serviceTTLPolicyPrefix = "/config/ttl-policies/"
+
+// TTLPolicy 应答TTL策略，字段为0表示该级别不设置
+//
+// TTL按 全局 → 命名空间 → 服务 → 记录 的层级确定：记录（或实例）自身设置的TTL优先，
+// 未设置时依次使用服务、命名空间的默认TTL和全局默认TTL；得到的TTL再依次受服务、命名空间和全局的上下限约束，
+// 越靠近全局的限制越优先，避免为频繁重新部署的服务发布过长的TTL
+type TTLPolicy struct {
+	DefaultTTL int `json:"default_ttl,omitempty"` // 记录未设置TTL时使用的TTL（秒）
+	MinTTL     int `json:"min_ttl,omitempty"`     // 应答TTL的下限（秒）
+	MaxTTL     int `json:"max_ttl,omitempty"`     // 应答TTL的上限（秒）
+}
+
+// Validate 校验TTL策略：各项不能为负数，下限不能大于上限，默认TTL必须在上下限之间
+func (p *TTLPolicy) Validate() error {
+	if p.DefaultTTL < 0 || p.MinTTL < 0 || p.MaxTTL < 0 {
+		return fmt.Errorf("TTL不能为负数")
+	}
+	if p.MaxTTL > 0 && p.MinTTL > p.MaxTTL {
+		return fmt.Errorf("min_ttl(%d)不能大于max_ttl(%d)", p.MinTTL, p.MaxTTL)
+	}
+	if p.DefaultTTL > 0 && (p.DefaultTTL < p.MinTTL || (p.MaxTTL > 0 && p.DefaultTTL > p.MaxTTL)) {
+		return fmt.Errorf("default_ttl(%d)必须在min_ttl和max_ttl之间", p.DefaultTTL)
+	}
+	return nil
+}
+
+// Clamp 把ttl限制在策略的上下限之间
+func (p *TTLPolicy) Clamp(ttl int) int {
+	if p.MinTTL > 0 && ttl < p.MinTTL {
+		ttl = p.MinTTL
+	}
+	if p.MaxTTL > 0 && ttl > p.MaxTTL {
+		ttl = p.MaxTTL
+	}
+	return ttl
+}
+
+// DefaultTTLOf 返回policies中第一个设置了默认TTL的策略的默认TTL，policies按从具体到全局的顺序排列，都未设置时返回0
+func DefaultTTLOf(policies ...*TTLPolicy) int {
+	for _, policy := range policies {
+		if policy != nil && policy.DefaultTTL > 0 {
+			return policy.DefaultTTL
+		}
+	}
+	return 0
+}
+
+// ResolveTTL 按TTL层级计算应答TTL，policies按从具体到全局的顺序排列（服务、命名空间、全局），nil会被跳过。
+// ttl大于0时使用ttl，否则使用策略中的默认TTL，都未设置时使用fallback；结果依次受各级上下限约束，后面的策略优先
+func ResolveTTL(ttl, fallback int, policies ...*TTLPolicy) int {
+	if ttl <= 0 {
+		ttl = DefaultTTLOf(policies...)
+	}
+	if ttl <= 0 {
+		ttl = fallback
+	}
+	for _, policy := range policies {
+		if policy != nil {
+			ttl = policy.Clamp(ttl)
+		}
+	}
+	return ttl
+}
+
+// ServiceTTLPolicy 服务级别的TTL策略，作用于服务域名的应答和由该服务维护的DNS记录
+type ServiceTTLPolicy struct {
+	ServiceName string `json:"service_name"` // 服务名称
+	TTLPolicy
+	UpdatedAt time.Time `json:"updated_at"` // 更新时间
+}
+
+// Validate 校验服务TTL策略
+func (p *ServiceTTLPolicy) Validate() error {
+	if p.ServiceName == "" || strings.Contains(p.ServiceName, "/") {
+		return fmt.Errorf("无效的服务名称: %q", p.ServiceName)
+	}
+	return p.TTLPolicy.Validate()
+}
+
+// getServiceTTLPolicyKey 生成服务TTL策略的etcd键
+func getServiceTTLPolicyKey(serviceName string) string {
+	return serviceTTLPolicyPrefix + serviceName
+}
+
+// GetServiceTTLPolicy 获取服务的TTL策略，未设置时返回nil
+func (e *EtcdClient) GetServiceTTLPolicy(ctx context.Context, serviceName string) (*ServiceTTLPolicy, error) {
+	if e.conn() == nil {
+		return nil, fmt.Errorf("etcd客户端未连接")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, etcdTimeout)
+	defer cancel()
+
+	resp, err := e.conn().Get(ctx, getServiceTTLPolicyKey(serviceName))
+	if err != nil {
+		return nil, fmt.Errorf("获取服务TTL策略失败: %w", err)
+	}
+	if len(resp.Kvs) == 0 {
+		return nil, nil
+	}
+
+	var policy ServiceTTLPolicy
+	if err := json.Unmarshal(resp.Kvs[0].Value, &policy); err != nil {
+		return nil, fmt.Errorf("解析服务TTL策略失败: %w", err)
+	}
+	return &policy, nil
+}
+
+// ListServiceTTLPolicies 获取所有服务的TTL策略，按服务名称排序
+func (e *EtcdClient) ListServiceTTLPolicies(ctx context.Context) ([]*ServiceTTLPolicy, error) {
+	if e.conn() == nil {
+		return nil, fmt.Errorf("etcd客户端未连接")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, etcdTimeout)
+	defer cancel()
+
+	resp, err := e.conn().Get(ctx, serviceTTLPolicyPrefix, clientv3.WithPrefix())
+	if err != nil {
+		e.log(ctx).Error("获取服务TTL策略列表失败", zap.Error(err))
+		return nil, fmt.Errorf("获取服务TTL策略列表失败: %w", err)
+	}
+
+	policies := make([]*ServiceTTLPolicy, 0, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		var policy ServiceTTLPolicy
+		if err := json.Unmarshal(kv.Value, &policy); err != nil {
+			e.log(ctx).Warn("解析服务TTL策略失败", zap.String("key", string(kv.Key)), zap.Error(err))
+			continue
+		}
+		policies = append(policies, &policy)
+	}
+	sort.Slice(policies, func(i, j int) bool {
+		return policies[i].ServiceName < policies[j].ServiceName
+	})
+
+	return policies, nil
+}
+
+// PutServiceTTLPolicy 设置服务的TTL策略
+func (e *EtcdClient) PutServiceTTLPolicy(ctx context.Context, policy *ServiceTTLPolicy) error {
+	if e.conn() == nil {
+		return fmt.Errorf("etcd客户端未连接")
+	}
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+
+	policy.UpdatedAt = time.Now()
+	data, err := json.Marshal(policy)
+	if err != nil {
+		return fmt.Errorf("序列化服务TTL策略失败: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, etcdTimeout)
+	defer cancel()
+
+	if _, err := e.conn().Put(ctx, getServiceTTLPolicyKey(policy.ServiceName), string(data)); err != nil {
+		e.log(ctx).Error("保存服务TTL策略失败", zap.String("service", policy.ServiceName), zap.Error(err))
+		return fmt.Errorf("保存服务TTL策略失败: %w", err)
+	}
+
+	e.log(ctx).Info("服务TTL策略已更新",
+		zap.String("service", policy.ServiceName),
+		zap.Int("default_ttl", policy.DefaultTTL),
+		zap.Int("min_ttl", policy.MinTTL),
+		zap.Int("max_ttl", policy.MaxTTL))
+	return nil
+}
+
+// DeleteServiceTTLPolicy 删除服务的TTL策略，之后服务使用命名空间和全局的TTL设置
+func (e *EtcdClient) DeleteServiceTTLPolicy(ctx context.Context, serviceName string) error {
+	if e.conn() == nil {
+		return fmt.Errorf("etcd客户端未连接")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, etcdTimeout)
+	defer cancel()
+
+	if _, err := e.conn().Delete(ctx, getServiceTTLPolicyKey(serviceName)); err != nil {
+		return fmt.Errorf("删除服务TTL策略失败: %w", err)
+	}
+	return nil
+}
+
+// GetNamespaceTTLPolicy 获取命名空间的TTL策略，命名空间不存在或未设置时返回nil
+func (e *EtcdClient) GetNamespaceTTLPolicy(ctx context.Context, namespace string) (*TTLPolicy, error) {
+	ns, err := e.GetNamespace(ctx, NamespaceOf(namespace))
+	if err != nil || ns == nil {
+		return nil, err
+	}
+	return ns.TTLPolicy, nil
+}
+
+// PutNamespaceTTLPolicy 设置命名空间的TTL策略，policy为nil时恢复使用全局设置；
+// 默认命名空间首次设置时自动创建，DNS服务器在下次加载运行时配置时生效
+func (e *EtcdClient) PutNamespaceTTLPolicy(ctx context.Context, namespace string, policy *TTLPolicy) error {
+	namespace = NamespaceOf(namespace)
+	if policy != nil {
+		if err := policy.Validate(); err != nil {
+			return err
+		}
+	}
+
+	ns, err := e.GetNamespace(ctx, namespace)
+	if err != nil {
+		return err
+	}
+	if ns == nil {
+		if namespace != DefaultNamespace {
+			return fmt.Errorf("%w: %s", ErrNamespaceNotFound, namespace)
+		}
+		ns = &Namespace{Name: namespace}
+	}
+	ns.TTLPolicy = policy
+
+	return e.PutNamespace(ctx, ns)
+}
@@ -0,0 +1,135 @@
+package etcdclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTTLPolicy_Validate(t *testing.T) {
+	assert.NoError(t, (&TTLPolicy{}).Validate())
+	assert.NoError(t, (&TTLPolicy{DefaultTTL: 60, MinTTL: 5, MaxTTL: 300}).Validate())
+	assert.NoError(t, (&TTLPolicy{DefaultTTL: 600, MinTTL: 5}).Validate())
+
+	tests := []struct {
+		name   string
+		policy TTLPolicy
+	}{
+		{"负数", TTLPolicy{MaxTTL: -1}},
+		{"下限大于上限", TTLPolicy{MinTTL: 600, MaxTTL: 60}},
+		{"默认TTL超过上限", TTLPolicy{DefaultTTL: 3600, MaxTTL: 300}},
+		{"默认TTL低于下限", TTLPolicy{DefaultTTL: 1, MinTTL: 5}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Error(t, tt.policy.Validate())
+		})
+	}
+
+	assert.Error(t, (&ServiceTTLPolicy{ServiceName: "a/b"}).Validate())
+}
+
+func TestResolveTTL(t *testing.T) {
+	service := &TTLPolicy{DefaultTTL: 30, MaxTTL: 120}
+	namespace := &TTLPolicy{DefaultTTL: 600, MinTTL: 10}
+	global := &TTLPolicy{MaxTTL: 3600}
+
+	// 记录自身的TTL优先，受服务的上限约束
+	assert.Equal(t, 120, ResolveTTL(7*24*3600, 300, service, namespace, global))
+	// 未设置TTL时使用最具体的默认TTL
+	assert.Equal(t, 30, ResolveTTL(0, 300, service, namespace, global))
+	assert.Equal(t, 600, ResolveTTL(0, 300, nil, namespace, global))
+	assert.Equal(t, 300, ResolveTTL(0, 300, nil, nil, global))
+	// 命名空间下限作用于记录TTL
+	assert.Equal(t, 10, ResolveTTL(1, 300, nil, namespace, global))
+	// 全局限制优先于更具体的策略
+	assert.Equal(t, 3600, ResolveTTL(0, 300, &TTLPolicy{MinTTL: 7200}, global))
+	assert.Equal(t, 5, ResolveTTL(0, 300, &TTLPolicy{MaxTTL: 1}, &TTLPolicy{MinTTL: 5}))
+}
+
+func TestEtcdClient_ServiceTTLPolicy(t *testing.T) {
+	// 跳过集成测试，除非明确要求运行
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	client := CreateEtcdClientForTest(t)
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	serviceName := fmt.Sprintf("ttl-policy-service-%d", time.Now().UnixNano())
+	defer func() { _ = client.DeleteServiceTTLPolicy(context.Background(), serviceName) }()
+
+	policy, err := client.GetServiceTTLPolicy(ctx, serviceName)
+	require.NoError(t, err)
+	assert.Nil(t, policy)
+
+	require.NoError(t, client.PutServiceTTLPolicy(ctx, &ServiceTTLPolicy{ServiceName: serviceName, TTLPolicy: TTLPolicy{DefaultTTL: 30, MaxTTL: 120}}))
+	policy, err = client.GetServiceTTLPolicy(ctx, serviceName)
+	require.NoError(t, err)
+	require.NotNil(t, policy)
+	assert.Equal(t, 30, policy.DefaultTTL)
+	assert.Equal(t, 120, policy.MaxTTL)
+	assert.False(t, policy.UpdatedAt.IsZero())
+
+	policies, err := client.ListServiceTTLPolicies(ctx)
+	require.NoError(t, err)
+	found := false
+	for _, p := range policies {
+		found = found || p.ServiceName == serviceName
+	}
+	assert.True(t, found)
+
+	assert.Error(t, client.PutServiceTTLPolicy(ctx, &ServiceTTLPolicy{ServiceName: serviceName, TTLPolicy: TTLPolicy{MinTTL: 600, MaxTTL: 60}}))
+
+	require.NoError(t, client.DeleteServiceTTLPolicy(ctx, serviceName))
+	policy, err = client.GetServiceTTLPolicy(ctx, serviceName)
+	require.NoError(t, err)
+	assert.Nil(t, policy)
+}
+
+func TestEtcdClient_NamespaceTTLPolicy(t *testing.T) {
+	// 跳过集成测试，除非明确要求运行
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	client := CreateEtcdClientForTest(t)
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	namespace := fmt.Sprintf("ttl-ns-%d", time.Now().UnixNano())
+	policy := &TTLPolicy{DefaultTTL: 120, MaxTTL: 600}
+
+	// 不存在的命名空间
+	err := client.PutNamespaceTTLPolicy(ctx, namespace, policy)
+	assert.True(t, errors.Is(err, ErrNamespaceNotFound))
+
+	require.NoError(t, client.CreateNamespace(ctx, &Namespace{Name: namespace}))
+	defer func() { _ = client.DeleteNamespace(context.Background(), namespace, true) }()
+
+	got, err := client.GetNamespaceTTLPolicy(ctx, namespace)
+	require.NoError(t, err)
+	assert.Nil(t, got)
+
+	require.NoError(t, client.PutNamespaceTTLPolicy(ctx, namespace, policy))
+	got, err = client.GetNamespaceTTLPolicy(ctx, namespace)
+	require.NoError(t, err)
+	assert.Equal(t, policy, got)
+
+	assert.Error(t, client.PutNamespaceTTLPolicy(ctx, namespace, &TTLPolicy{DefaultTTL: 3600, MaxTTL: 600}))
+
+	require.NoError(t, client.PutNamespaceTTLPolicy(ctx, namespace, nil))
+	got, err = client.GetNamespaceTTLPolicy(ctx, namespace)
+	require.NoError(t, err)
+	assert.Nil(t, got)
+}
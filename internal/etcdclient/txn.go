@@ -0,0 +1,45 @@
+package etcdclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// 事务冲突时的最大尝试次数和退避时间
+const (
+	txnMaxAttempts = 5
+	txnBaseBackoff = 20 * time.Millisecond
+	txnMaxBackoff  = 500 * time.Millisecond
+)
+
+// errTxnConflict 事务的比较条件不满足（读取之后数据被并发修改），由retryTxn重新执行
+var errTxnConflict = errors.New("事务冲突")
+
+// retryTxn 执行attempt，返回errTxnConflict时按带随机抖动的指数退避重试
+// 每次重试都应重新读取数据并构造事务；次数用尽后返回ErrRevisionConflict
+func retryTxn(ctx context.Context, attempt func() error) error {
+	backoff := txnBaseBackoff
+	for i := 1; ; i++ {
+		err := attempt()
+		if !errors.Is(err, errTxnConflict) {
+			return err
+		}
+		if i >= txnMaxAttempts {
+			return fmt.Errorf("%w: 重试%d次后仍然冲突", ErrRevisionConflict, txnMaxAttempts)
+		}
+
+		// 在[backoff/2, backoff)之间随机等待，避免并发请求同时重试
+		wait := backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)))
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+		if backoff *= 2; backoff > txnMaxBackoff {
+			backoff = txnMaxBackoff
+		}
+	}
+}
@@ -0,0 +1,226 @@
+package etcdclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+func TestRetryTxn(t *testing.T) {
+	ctx := context.Background()
+
+	// 冲突后重试成功
+	attempts := 0
+	err := retryTxn(ctx, func() error {
+		attempts++
+		if attempts < 3 {
+			return errTxnConflict
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, attempts)
+
+	// 其他错误直接返回，不重试
+	attempts = 0
+	boom := errors.New("boom")
+	err = retryTxn(ctx, func() error {
+		attempts++
+		return boom
+	})
+	assert.ErrorIs(t, err, boom)
+	assert.Equal(t, 1, attempts)
+
+	// 重试次数用尽后返回版本冲突
+	attempts = 0
+	err = retryTxn(ctx, func() error {
+		attempts++
+		return errTxnConflict
+	})
+	assert.ErrorIs(t, err, ErrRevisionConflict)
+	assert.Equal(t, txnMaxAttempts, attempts)
+
+	// ctx取消时停止重试
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	err = retryTxn(cancelled, func() error { return errTxnConflict })
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestEtcdClient_RegisterService_Transactional(t *testing.T) {
+	// 跳过集成测试，除非明确要求运行
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	client := CreateEtcdClientForTest(t)
+	defer client.Close()
+	raw := client.(*EtcdClient).conn()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	namespace := fmt.Sprintf("txn-ns-%d", time.Now().UnixNano())
+	require.NoError(t, client.CreateNamespace(ctx, &Namespace{Name: namespace, Quota: &NamespaceQuota{MaxInstances: 1}}))
+	defer func() { _ = client.DeleteNamespace(context.Background(), namespace, true) }()
+
+	service := namespace + "-svc"
+	instance := func(id string) *ServiceInstance {
+		return &ServiceInstance{ServiceName: service, InstanceID: id, IPAddress: "10.0.0.1", Port: 8080, TTL: 30, Namespace: namespace}
+	}
+
+	// 并发注册时配额校验不会被同时通过，只有一个实例注册成功
+	const concurrency = 5
+	var wg sync.WaitGroup
+	errs := make([]error, concurrency)
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = client.RegisterService(ctx, instance(fmt.Sprintf("instance-%03d", i)))
+		}(i)
+	}
+	wg.Wait()
+
+	succeeded := 0
+	for _, err := range errs {
+		if err == nil {
+			succeeded++
+			continue
+		}
+		assert.True(t, errors.Is(err, ErrQuotaExceeded) || errors.Is(err, ErrRevisionConflict), err)
+	}
+	assert.Equal(t, 1, succeeded)
+
+	instances, err := client.GetServiceInstances(ctx, service)
+	require.NoError(t, err)
+	require.Len(t, instances, 1)
+	id := instances[0].InstanceID
+
+	// 重复注册替换租约，旧租约被撤销
+	key := getServiceInstanceKey(service, id)
+	before, err := raw.Get(ctx, key)
+	require.NoError(t, err)
+	oldLease := clientv3.LeaseID(before.Kvs[0].Lease)
+
+	require.NoError(t, client.RegisterService(ctx, instance(id)))
+	after, err := raw.Get(ctx, key)
+	require.NoError(t, err)
+	newLease := clientv3.LeaseID(after.Kvs[0].Lease)
+	assert.NotEqual(t, oldLease, newLease)
+
+	ttl, err := raw.TimeToLive(ctx, oldLease)
+	require.NoError(t, err)
+	assert.Equal(t, int64(-1), ttl.TTL, "旧租约应被撤销")
+
+	// 注销删除实例并撤销租约，重复注销视为成功
+	require.NoError(t, client.DeregisterService(ctx, service, id))
+	require.NoError(t, client.DeregisterService(ctx, service, id))
+	ttl, err = raw.TimeToLive(ctx, newLease)
+	require.NoError(t, err)
+	assert.Equal(t, int64(-1), ttl.TTL, "注销后租约应被撤销")
+
+	// 命名空间不存在时注册失败，并且不会留下租约绑定的数据
+	err = client.RegisterService(ctx, &ServiceInstance{ServiceName: service, InstanceID: "orphan", TTL: 30, Namespace: namespace + "-missing"})
+	assert.ErrorIs(t, err, ErrNamespaceNotFound)
+}
+
+func TestEtcdClient_RefreshServiceLease_Transactional(t *testing.T) {
+	// 跳过集成测试，除非明确要求运行
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	client := CreateEtcdClientForTest(t)
+	defer client.Close()
+	raw := client.(*EtcdClient).conn()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	service := fmt.Sprintf("heartbeat-txn-%d", time.Now().UnixNano())
+	for _, id := range []string{"hb-1", "hb-2"} {
+		require.NoError(t, client.RegisterService(ctx, &ServiceInstance{ServiceName: service, InstanceID: id, IPAddress: "10.0.0.1", Port: 8080, TTL: 30}))
+		defer func(id string) { _ = client.DeregisterService(context.Background(), service, id) }(id)
+	}
+	key := getServiceInstanceKey(service, "hb-1")
+	leaseOf := func(key string) clientv3.LeaseID {
+		resp, err := raw.Get(ctx, key)
+		require.NoError(t, err)
+		require.Len(t, resp.Kvs, 1)
+		return clientv3.LeaseID(resp.Kvs[0].Lease)
+	}
+
+	// 心跳改用新租约，撤销不再使用的旧租约
+	oldLease := leaseOf(key)
+	require.NoError(t, client.RefreshServiceLease(ctx, service, "hb-1", 0))
+	assert.NotEqual(t, oldLease, leaseOf(key))
+	ttl, err := raw.TimeToLive(ctx, oldLease)
+	require.NoError(t, err)
+	assert.Equal(t, int64(-1), ttl.TTL, "旧租约应被撤销")
+
+	// 其他实例仍在使用的租约（如从备份恢复的实例）不撤销
+	shared, err := raw.Grant(ctx, 30)
+	require.NoError(t, err)
+	for _, id := range []string{"hb-1", "hb-2"} {
+		k := getServiceInstanceKey(service, id)
+		resp, err := raw.Get(ctx, k)
+		require.NoError(t, err)
+		_, err = raw.Put(ctx, k, string(resp.Kvs[0].Value), clientv3.WithLease(shared.ID))
+		require.NoError(t, err)
+	}
+	require.NoError(t, client.RefreshServiceLease(ctx, service, "hb-1", 0))
+	ttl, err = raw.TimeToLive(ctx, shared.ID)
+	require.NoError(t, err)
+	assert.Greater(t, ttl.TTL, int64(0), "共用的租约不应被撤销")
+	assert.Equal(t, shared.ID, leaseOf(getServiceInstanceKey(service, "hb-2")))
+
+	// 并发的心跳不会覆盖维护模式的修改
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				_, err := client.RefreshServiceLeaseWithReport(ctx, service, "hb-1", 0, &HealthReport{Status: HealthPassing})
+				if err != nil && !errors.Is(err, ErrRevisionConflict) {
+					t.Errorf("心跳失败: %v", err)
+					return
+				}
+			}
+		}()
+	}
+	_, err = client.SetInstanceMaintenance(ctx, service, "hb-1", true)
+	require.NoError(t, err)
+	close(stop)
+	wg.Wait()
+
+	instances, err := client.GetServiceInstances(ctx, service)
+	require.NoError(t, err)
+	for _, instance := range instances {
+		if instance.InstanceID == "hb-1" {
+			assert.True(t, instance.Maintenance, "心跳不应覆盖维护模式")
+		}
+	}
+
+	// 注销后的心跳返回实例不存在，不会重新写入实例
+	require.NoError(t, client.DeregisterService(ctx, service, "hb-1"))
+	_, err = client.RefreshServiceLeaseWithReport(ctx, service, "hb-1", 0, &HealthReport{Status: HealthPassing})
+	assert.ErrorIs(t, err, ErrInstanceNotFound)
+	resp, err := raw.Get(ctx, key)
+	require.NoError(t, err)
+	assert.Empty(t, resp.Kvs)
+}
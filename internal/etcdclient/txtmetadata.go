@@ -0,0 +1,95 @@
+package etcdclient
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// TXT应答中标识实例的键，总是作为第一个字符串返回
+const txtInstanceKey = "instance"
+
+// GetNamespaceTXTMetadata 获取命名空间通过TXT查询公开的元数据键，未单独设置时使用配置中的默认值
+func (e *EtcdClient) GetNamespaceTXTMetadata(ctx context.Context, namespace string) ([]string, error) {
+	ns, err := e.GetNamespace(ctx, NamespaceOf(namespace))
+	if err != nil {
+		return nil, err
+	}
+	if ns != nil && ns.TXTMetadata != nil {
+		return ns.TXTMetadata, nil
+	}
+	if e.cfg != nil {
+		return e.cfg.Namespace.DefaultTXTMetadata, nil
+	}
+	return nil, nil
+}
+
+// PutNamespaceTXTMetadata 设置命名空间通过TXT查询公开的元数据键，空列表表示不公开；默认命名空间首次设置时自动创建
+func (e *EtcdClient) PutNamespaceTXTMetadata(ctx context.Context, namespace string, keys []string) error {
+	namespace = NamespaceOf(namespace)
+
+	ns, err := e.GetNamespace(ctx, namespace)
+	if err != nil {
+		return err
+	}
+	if ns == nil {
+		if namespace != DefaultNamespace {
+			return fmt.Errorf("%w: %s", ErrNamespaceNotFound, namespace)
+		}
+		ns = &Namespace{Name: namespace}
+	}
+	if keys == nil {
+		keys = []string{}
+	}
+	ns.TXTMetadata = keys
+
+	return e.PutNamespace(ctx, ns)
+}
+
+// ServiceTXTRecords 返回服务域名TXT应答的内容：每个可用实例一组 key=value 字符串，
+// 第一个为实例ID，其后是命名空间允许公开、且实例设置了的元数据，按键排序
+func (e *EtcdClient) ServiceTXTRecords(ctx context.Context, domain string) ([][]string, error) {
+	serviceName, ok := ServiceNameFromDomain(domain)
+	if !ok {
+		return nil, fmt.Errorf("无效的服务域名: %s", domain)
+	}
+
+	namespace := serviceDomainNamespace(domain)
+
+	instances, err := e.GetServiceInstances(ctx, serviceName)
+	if err != nil {
+		return nil, fmt.Errorf("获取服务实例失败: %w", err)
+	}
+	instances = instancesInNamespace(instances, namespace)
+	if len(instances) == 0 {
+		if err := e.ensureNamespace(ctx, namespace); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("未找到服务实例: %s", serviceName)
+	}
+	instances = routableInstances(instances)
+	if len(instances) == 0 {
+		return nil, fmt.Errorf("服务没有健康的实例: %s", serviceName)
+	}
+
+	allowed, err := e.GetNamespaceTXTMetadata(ctx, namespace)
+	if err != nil {
+		return nil, fmt.Errorf("获取命名空间TXT元数据设置失败: %w", err)
+	}
+	keys := append([]string(nil), allowed...)
+	sort.Strings(keys)
+
+	sort.Slice(instances, func(i, j int) bool { return instances[i].InstanceID < instances[j].InstanceID })
+	records := make([][]string, 0, len(instances))
+	for _, instance := range instances {
+		txt := []string{txtInstanceKey + "=" + instance.InstanceID}
+		for _, key := range keys {
+			if value, ok := instance.Metadata[key]; ok {
+				txt = append(txt, key+"="+strings.ReplaceAll(value, "\n", " "))
+			}
+		}
+		records = append(records, txt)
+	}
+	return records, nil
+}
@@ -0,0 +1,63 @@
+package etcdclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEtcdClient_ServiceTXTRecords(t *testing.T) {
+	// 跳过集成测试，除非明确要求运行
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	client := CreateEtcdClientForTest(t)
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	namespace := fmt.Sprintf("txt-ns-%d", time.Now().UnixNano())
+	require.NoError(t, client.CreateNamespace(ctx, &Namespace{Name: namespace}))
+	defer func() { _ = client.DeleteNamespace(context.Background(), namespace, true) }()
+
+	service := namespace + "-svc"
+	instance := &ServiceInstance{
+		ServiceName: service,
+		InstanceID:  "instance-001",
+		IPAddress:   "10.0.0.1",
+		Port:        8080,
+		TTL:         30,
+		Namespace:   namespace,
+		Metadata:    map[string]string{"version": "1.2.0", "region": "cn-east", "secret": "hidden"},
+	}
+	require.NoError(t, client.RegisterService(ctx, instance))
+	defer func() { _ = client.DeregisterService(context.Background(), service, "instance-001") }()
+
+	domain := service + "." + namespace + ".svc.cluster.local"
+
+	// 未设置时使用配置默认值（为空），只返回实例ID
+	records, err := client.ServiceTXTRecords(ctx, domain)
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{{"instance=instance-001"}}, records)
+
+	// 只公开允许的元数据键，按键排序
+	require.NoError(t, client.PutNamespaceTXTMetadata(ctx, namespace, []string{"version", "region", "owner"}))
+	keys, err := client.GetNamespaceTXTMetadata(ctx, namespace)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"version", "region", "owner"}, keys)
+
+	records, err = client.ServiceTXTRecords(ctx, domain)
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{{"instance=instance-001", "region=cn-east", "version=1.2.0"}}, records)
+
+	// 不存在的命名空间不能设置
+	err = client.PutNamespaceTXTMetadata(ctx, namespace+"-missing", []string{"version"})
+	assert.True(t, errors.Is(err, ErrNamespaceNotFound))
+}
@@ -0,0 +1,185 @@
+package etcdclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.uber.org/zap"
+)
+
+// 上游DNS配置在etcd中的键
+const upstreamConfigKey = "/config/upstream-dns"
+
+// 上游转发模式
+const (
+	UpstreamModeSequential = "sequential" // 按健康评分依次尝试
+	UpstreamModeRace       = "race"       // 同时向多个上游发送，取最快的成功响应
+)
+
+// UpstreamConfig 表示存储在etcd中、可运行时修改的上游DNS配置
+type UpstreamConfig struct {
+	Servers   []string `json:"servers"`              // 上游DNS服务器地址列表
+	Mode      string   `json:"mode,omitempty"`       // 转发模式 (sequential 或 race)
+	RaceCount int      `json:"race_count,omitempty"` // race模式下同时查询的上游数量，至少为2，为0时使用默认值2
+}
+
+// DefaultRaceCount race模式下未设置并发数量时同时查询的上游数量
+const DefaultRaceCount = 2
+
+// Validate 校验上游DNS配置
+func (c *UpstreamConfig) Validate() error {
+	if len(c.Servers) == 0 {
+		return fmt.Errorf("上游DNS服务器列表不能为空")
+	}
+
+	switch c.Mode {
+	case "", UpstreamModeSequential, UpstreamModeRace:
+	default:
+		return fmt.Errorf("不支持的上游转发模式: %s", c.Mode)
+	}
+
+	if c.RaceCount != 0 && c.RaceCount < 2 {
+		return fmt.Errorf("race_count至少为2，为0时使用默认值%d: %d", DefaultRaceCount, c.RaceCount)
+	}
+
+	return nil
+}
+
+// GetUpstreamConfig 从etcd获取上游DNS配置
+func (e *EtcdClient) GetUpstreamConfig(ctx context.Context) (*UpstreamConfig, error) {
+	if e.conn() == nil {
+		return nil, fmt.Errorf("etcd客户端未连接")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, etcdTimeout)
+	defer cancel()
+
+	resp, err := e.conn().Get(ctx, upstreamConfigKey)
+	if err != nil {
+		e.log(ctx).Error("从etcd获取上游DNS配置失败", zap.Error(err))
+		return nil, fmt.Errorf("从etcd获取上游DNS配置失败: %w", err)
+	}
+
+	if len(resp.Kvs) == 0 {
+		return nil, fmt.Errorf("上游DNS配置不存在: %s", upstreamConfigKey)
+	}
+
+	var cfg UpstreamConfig
+	if err := json.Unmarshal(resp.Kvs[0].Value, &cfg); err != nil {
+		e.log(ctx).Error("解析上游DNS配置失败", zap.Error(err))
+		return nil, fmt.Errorf("解析上游DNS配置失败: %w", err)
+	}
+
+	return &cfg, nil
+}
+
+// PutUpstreamConfig 将上游DNS配置保存到etcd
+func (e *EtcdClient) PutUpstreamConfig(ctx context.Context, cfg *UpstreamConfig) error {
+	if e.conn() == nil {
+		return fmt.Errorf("etcd客户端未连接")
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		e.log(ctx).Error("序列化上游DNS配置失败", zap.Error(err))
+		return fmt.Errorf("序列化上游DNS配置失败: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, etcdTimeout)
+	defer cancel()
+
+	if _, err := e.conn().Put(ctx, upstreamConfigKey, string(data)); err != nil {
+		e.log(ctx).Error("保存上游DNS配置失败", zap.Error(err))
+		return fmt.Errorf("保存上游DNS配置失败: %w", err)
+	}
+
+	e.log(ctx).Info("上游DNS配置保存成功",
+		zap.Strings("servers", cfg.Servers),
+		zap.String("mode", cfg.Mode))
+	return nil
+}
+
+// 手动停用的上游DNS在etcd中的键前缀
+const upstreamDisabledPrefix = "/config/upstream-disabled/"
+
+// DisabledUpstream 被手动停用的上游DNS服务器，停用期间不参与转发和恢复探测
+type DisabledUpstream struct {
+	Address    string    `json:"address"`          // 上游地址
+	Reason     string    `json:"reason,omitempty"` // 停用原因
+	DisabledAt time.Time `json:"disabled_at"`      // 停用时间
+}
+
+// ListDisabledUpstreams 列出所有被手动停用的上游DNS服务器
+func (e *EtcdClient) ListDisabledUpstreams(ctx context.Context) ([]*DisabledUpstream, error) {
+	if e.conn() == nil {
+		return nil, fmt.Errorf("etcd客户端未连接")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, etcdTimeout)
+	defer cancel()
+
+	resp, err := e.conn().Get(ctx, upstreamDisabledPrefix, clientv3.WithPrefix())
+	if err != nil {
+		e.log(ctx).Error("获取停用的上游DNS失败", zap.Error(err))
+		return nil, fmt.Errorf("获取停用的上游DNS失败: %w", err)
+	}
+
+	result := make([]*DisabledUpstream, 0, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		var d DisabledUpstream
+		if err := json.Unmarshal(kv.Value, &d); err != nil {
+			e.log(ctx).Warn("解析停用的上游DNS失败", zap.String("key", string(kv.Key)), zap.Error(err))
+			continue
+		}
+		result = append(result, &d)
+	}
+	return result, nil
+}
+
+// SetUpstreamDisabled 停用或恢复上游DNS服务器，不修改上游列表
+func (e *EtcdClient) SetUpstreamDisabled(ctx context.Context, addr string, disabled bool, reason string) error {
+	if e.conn() == nil {
+		return fmt.Errorf("etcd客户端未连接")
+	}
+	if addr == "" {
+		return fmt.Errorf("上游地址不能为空")
+	}
+
+	key := upstreamDisabledPrefix + addr
+
+	ctx, cancel := context.WithTimeout(ctx, etcdTimeout)
+	defer cancel()
+
+	if !disabled {
+		if _, err := e.conn().Delete(ctx, key); err != nil {
+			e.log(ctx).Error("恢复上游DNS失败", zap.String("upstream", addr), zap.Error(err))
+			return fmt.Errorf("恢复上游DNS失败: %w", err)
+		}
+		e.log(ctx).Info("上游DNS已恢复", zap.String("upstream", addr))
+		return nil
+	}
+
+	data, err := json.Marshal(&DisabledUpstream{
+		Address:    addr,
+		Reason:     reason,
+		DisabledAt: time.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("序列化停用的上游DNS失败: %w", err)
+	}
+
+	if _, err := e.conn().Put(ctx, key, string(data)); err != nil {
+		e.log(ctx).Error("停用上游DNS失败", zap.String("upstream", addr), zap.Error(err))
+		return fmt.Errorf("停用上游DNS失败: %w", err)
+	}
+
+	e.log(ctx).Info("上游DNS已停用", zap.String("upstream", addr), zap.String("reason", reason))
+	return nil
+}
@@ -0,0 +1,54 @@
+package etcdclient
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpstreamConfig_Validate(t *testing.T) {
+	assert.Error(t, (&UpstreamConfig{}).Validate(), "空服务器列表应校验失败")
+	assert.Error(t, (&UpstreamConfig{Servers: []string{"8.8.8.8:53"}, Mode: "random"}).Validate(), "未知模式应校验失败")
+	assert.NoError(t, (&UpstreamConfig{Servers: []string{"8.8.8.8:53"}, Mode: UpstreamModeRace, RaceCount: 2}).Validate())
+	assert.NoError(t, (&UpstreamConfig{Servers: []string{"8.8.8.8:53"}, Mode: UpstreamModeRace}).Validate(), "未设置时使用默认值")
+	assert.Error(t, (&UpstreamConfig{Servers: []string{"8.8.8.8:53"}, Mode: UpstreamModeRace, RaceCount: 1}).Validate(), "race_count小于2应校验失败")
+	assert.Error(t, (&UpstreamConfig{Servers: []string{"8.8.8.8:53"}, RaceCount: -1}).Validate(), "race_count为负数应校验失败")
+}
+
+func TestEtcdClient_UpstreamConfig(t *testing.T) {
+	// 跳过集成测试，除非明确要求运行
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	// 创建etcd客户端并连接
+	client := CreateEtcdClientForTest(t)
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// 确保测试结束后清理
+	defer func() {
+		_, _ = client.(*EtcdClient).conn().Delete(context.Background(), upstreamConfigKey)
+	}()
+
+	upstreamCfg := &UpstreamConfig{
+		Servers:   []string{"10.0.0.1:53", "10.0.0.2:53"},
+		Mode:      UpstreamModeRace,
+		RaceCount: 2,
+	}
+	err := client.PutUpstreamConfig(ctx, upstreamCfg)
+	require.NoError(t, err, "保存上游DNS配置应该成功")
+
+	retrieved, err := client.GetUpstreamConfig(ctx)
+	require.NoError(t, err, "获取上游DNS配置应该成功")
+	assert.Equal(t, upstreamCfg, retrieved)
+
+	// 无效配置应被拒绝
+	err = client.PutUpstreamConfig(ctx, &UpstreamConfig{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,293 @@
+package etcdclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.uber.org/zap"
+)
+
+// webhook及其投递记录在etcd中的键前缀
+const (
+	webhookPrefix         = "/config/webhooks/"
+	webhookDeliveryPrefix = "/webhooks/deliveries/"
+)
+
+// maxWebhookDeliveries 每个webhook保留的最近投递记录数
+const maxWebhookDeliveries = 50
+
+// webhook订阅的事件类型
+const (
+	WebhookServiceRegistered   = "service.registered"     // 服务实例注册
+	WebhookServiceDeregistered = "service.deregistered"   // 服务实例注销或租约过期
+	WebhookHealthChanged       = "service.health_changed" // 实例健康状态或维护模式变化
+	WebhookDNSRecordChanged    = "dns.record_changed"     // DNS记录创建、更新或删除
+)
+
+// WebhookEvents 所有可订阅的事件类型
+var WebhookEvents = []string{WebhookServiceRegistered, WebhookServiceDeregistered, WebhookHealthChanged, WebhookDNSRecordChanged}
+
+// 投递状态
+const (
+	DeliveryPending   = "pending"   // 正在投递或等待重试
+	DeliverySucceeded = "succeeded" // 投递成功
+	DeliveryFailed    = "failed"    // 重试次数用尽仍未成功
+)
+
+// ErrWebhookNotFound webhook不存在
+var ErrWebhookNotFound = errors.New("webhook不存在")
+
+// Webhook 事件发生时回调的HTTP地址
+type Webhook struct {
+	ID        string    `json:"id"`               // webhook ID
+	URL       string    `json:"url"`              // 回调地址
+	Secret    string    `json:"secret,omitempty"` // HMAC-SHA256签名密钥，为空时不签名
+	Events    []string  `json:"events"`           // 订阅的事件类型，为空表示全部
+	Disabled  bool      `json:"disabled"`         // 是否暂停投递
+	CreatedAt time.Time `json:"created_at"`       // 创建时间
+	UpdatedAt time.Time `json:"updated_at"`       // 更新时间
+}
+
+// Validate 校验webhook配置
+func (w *Webhook) Validate() error {
+	u, err := url.Parse(w.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("无效的回调地址: %s", w.URL)
+	}
+	for _, event := range w.Events {
+		if !validWebhookEvent(event) {
+			return fmt.Errorf("不支持的事件类型: %s", event)
+		}
+	}
+	return nil
+}
+
+// Subscribes 判断webhook是否订阅了指定的事件类型
+func (w *Webhook) Subscribes(event string) bool {
+	if w.Disabled {
+		return false
+	}
+	if len(w.Events) == 0 {
+		return true
+	}
+	for _, e := range w.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// validWebhookEvent 判断事件类型是否有效
+func validWebhookEvent(event string) bool {
+	for _, e := range WebhookEvents {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookDelivery 一次事件投递的状态
+type WebhookDelivery struct {
+	ID           string    `json:"id"`                      // 投递ID，按时间排序
+	WebhookID    string    `json:"webhook_id"`              // webhook ID
+	Event        string    `json:"event"`                   // 事件类型
+	Status       string    `json:"status"`                  // 投递状态 (pending, succeeded, failed)
+	Attempts     int       `json:"attempts"`                // 已尝试次数
+	ResponseCode int       `json:"response_code,omitempty"` // 最近一次响应的HTTP状态码
+	LastError    string    `json:"last_error,omitempty"`    // 最近一次失败的原因
+	CreatedAt    time.Time `json:"created_at"`              // 事件发生时间
+	UpdatedAt    time.Time `json:"updated_at"`              // 最近一次尝试的时间
+}
+
+// NewWebhookDeliveryID 生成按时间排序的投递ID
+func NewWebhookDeliveryID() string {
+	return fmt.Sprintf("%020d-%s", time.Now().UnixNano(), uuid.New().String()[:8])
+}
+
+// getWebhookKey 生成webhook的etcd键
+func getWebhookKey(id string) string {
+	return webhookPrefix + id
+}
+
+// getWebhookDeliveryPrefix 生成webhook投递记录的etcd键前缀
+func getWebhookDeliveryPrefix(webhookID string) string {
+	return webhookDeliveryPrefix + webhookID + "/"
+}
+
+// ListWebhooks 获取所有webhook
+func (e *EtcdClient) ListWebhooks(ctx context.Context) ([]*Webhook, error) {
+	if e.conn() == nil {
+		return nil, fmt.Errorf("etcd客户端未连接")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, etcdTimeout)
+	defer cancel()
+
+	resp, err := e.conn().Get(ctx, webhookPrefix, clientv3.WithPrefix())
+	if err != nil {
+		e.log(ctx).Error("获取webhook列表失败", zap.Error(err))
+		return nil, fmt.Errorf("获取webhook列表失败: %w", err)
+	}
+
+	webhooks := make([]*Webhook, 0, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		var webhook Webhook
+		if err := json.Unmarshal(kv.Value, &webhook); err != nil {
+			e.log(ctx).Warn("解析webhook失败", zap.String("key", string(kv.Key)), zap.Error(err))
+			continue
+		}
+		webhooks = append(webhooks, &webhook)
+	}
+	return webhooks, nil
+}
+
+// GetWebhook 获取webhook，不存在时返回ErrWebhookNotFound
+func (e *EtcdClient) GetWebhook(ctx context.Context, id string) (*Webhook, error) {
+	if e.conn() == nil {
+		return nil, fmt.Errorf("etcd客户端未连接")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, etcdTimeout)
+	defer cancel()
+
+	resp, err := e.conn().Get(ctx, getWebhookKey(id))
+	if err != nil {
+		return nil, fmt.Errorf("获取webhook失败: %w", err)
+	}
+	if len(resp.Kvs) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrWebhookNotFound, id)
+	}
+
+	var webhook Webhook
+	if err := json.Unmarshal(resp.Kvs[0].Value, &webhook); err != nil {
+		return nil, fmt.Errorf("解析webhook失败: %w", err)
+	}
+	return &webhook, nil
+}
+
+// PutWebhook 创建或更新webhook，未指定ID时自动生成
+func (e *EtcdClient) PutWebhook(ctx context.Context, webhook *Webhook) error {
+	if e.conn() == nil {
+		return fmt.Errorf("etcd客户端未连接")
+	}
+	if err := webhook.Validate(); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if webhook.ID == "" {
+		webhook.ID = uuid.New().String()
+	}
+	if webhook.CreatedAt.IsZero() {
+		webhook.CreatedAt = now
+	}
+	webhook.UpdatedAt = now
+
+	data, err := json.Marshal(webhook)
+	if err != nil {
+		return fmt.Errorf("序列化webhook失败: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, etcdTimeout)
+	defer cancel()
+
+	if _, err := e.conn().Put(ctx, getWebhookKey(webhook.ID), string(data)); err != nil {
+		e.log(ctx).Error("保存webhook失败", zap.String("id", webhook.ID), zap.Error(err))
+		return fmt.Errorf("保存webhook失败: %w", err)
+	}
+
+	e.log(ctx).Info("webhook保存成功", zap.String("id", webhook.ID), zap.String("url", webhook.URL))
+	return nil
+}
+
+// DeleteWebhook 删除webhook及其投递记录
+func (e *EtcdClient) DeleteWebhook(ctx context.Context, id string) error {
+	if e.conn() == nil {
+		return fmt.Errorf("etcd客户端未连接")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, etcdTimeout)
+	defer cancel()
+
+	resp, err := e.conn().Txn(ctx).Then(
+		clientv3.OpDelete(getWebhookKey(id)),
+		clientv3.OpDelete(getWebhookDeliveryPrefix(id), clientv3.WithPrefix()),
+	).Commit()
+	if err != nil {
+		e.log(ctx).Error("删除webhook失败", zap.String("id", id), zap.Error(err))
+		return fmt.Errorf("删除webhook失败: %w", err)
+	}
+	if resp.Responses[0].GetResponseDeleteRange().Deleted == 0 {
+		return fmt.Errorf("%w: %s", ErrWebhookNotFound, id)
+	}
+
+	e.log(ctx).Info("webhook删除成功", zap.String("id", id))
+	return nil
+}
+
+// ListWebhookDeliveries 获取webhook最近的投递记录，按时间从新到旧排序
+func (e *EtcdClient) ListWebhookDeliveries(ctx context.Context, webhookID string) ([]*WebhookDelivery, error) {
+	if e.conn() == nil {
+		return nil, fmt.Errorf("etcd客户端未连接")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, etcdTimeout)
+	defer cancel()
+
+	resp, err := e.conn().Get(ctx, getWebhookDeliveryPrefix(webhookID),
+		clientv3.WithPrefix(), clientv3.WithSort(clientv3.SortByKey, clientv3.SortDescend))
+	if err != nil {
+		return nil, fmt.Errorf("获取webhook投递记录失败: %w", err)
+	}
+
+	deliveries := make([]*WebhookDelivery, 0, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		var delivery WebhookDelivery
+		if err := json.Unmarshal(kv.Value, &delivery); err != nil {
+			continue
+		}
+		deliveries = append(deliveries, &delivery)
+	}
+	return deliveries, nil
+}
+
+// PutWebhookDelivery 保存投递状态，只保留每个webhook最近的maxWebhookDeliveries条记录
+func (e *EtcdClient) PutWebhookDelivery(ctx context.Context, delivery *WebhookDelivery) error {
+	if e.conn() == nil {
+		return fmt.Errorf("etcd客户端未连接")
+	}
+
+	data, err := json.Marshal(delivery)
+	if err != nil {
+		return fmt.Errorf("序列化webhook投递记录失败: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, etcdTimeout)
+	defer cancel()
+
+	prefix := getWebhookDeliveryPrefix(delivery.WebhookID)
+	if _, err := e.conn().Put(ctx, prefix+delivery.ID, string(data)); err != nil {
+		return fmt.Errorf("保存webhook投递记录失败: %w", err)
+	}
+
+	// 清理超出保留数量的旧记录
+	resp, err := e.conn().Get(ctx, prefix, clientv3.WithPrefix(), clientv3.WithKeysOnly(),
+		clientv3.WithSort(clientv3.SortByKey, clientv3.SortDescend))
+	if err != nil {
+		return nil
+	}
+	for _, kv := range resp.Kvs[min(len(resp.Kvs), maxWebhookDeliveries):] {
+		if _, err := e.conn().Delete(ctx, string(kv.Key)); err != nil {
+			e.log(ctx).Warn("清理webhook投递记录失败", zap.String("key", string(kv.Key)), zap.Error(err))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package etcdclient
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhook_Validate(t *testing.T) {
+	assert.NoError(t, (&Webhook{URL: "https://example.com/hook"}).Validate())
+	assert.NoError(t, (&Webhook{URL: "http://10.0.0.1:8080/hook", Events: []string{WebhookHealthChanged}}).Validate())
+	assert.Error(t, (&Webhook{URL: ""}).Validate())
+	assert.Error(t, (&Webhook{URL: "ftp://example.com"}).Validate())
+	assert.Error(t, (&Webhook{URL: "https://example.com", Events: []string{"service.updated"}}).Validate())
+}
+
+func TestWebhook_Subscribes(t *testing.T) {
+	all := &Webhook{URL: "https://example.com"}
+	assert.True(t, all.Subscribes(WebhookServiceRegistered))
+	assert.True(t, all.Subscribes(WebhookDNSRecordChanged))
+
+	health := &Webhook{URL: "https://example.com", Events: []string{WebhookHealthChanged}}
+	assert.True(t, health.Subscribes(WebhookHealthChanged))
+	assert.False(t, health.Subscribes(WebhookServiceRegistered))
+
+	disabled := &Webhook{URL: "https://example.com", Disabled: true}
+	assert.False(t, disabled.Subscribes(WebhookServiceRegistered))
+}
+
+func TestEtcdClient_Webhooks(t *testing.T) {
+	// 跳过集成测试，除非明确要求运行
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	client := CreateEtcdClientForTest(t)
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	webhook := &Webhook{URL: "https://example.com/hook", Secret: "secret", Events: []string{WebhookServiceRegistered}}
+	require.NoError(t, client.PutWebhook(ctx, webhook))
+	require.NotEmpty(t, webhook.ID)
+	defer func() { _ = client.DeleteWebhook(context.Background(), webhook.ID) }()
+
+	got, err := client.GetWebhook(ctx, webhook.ID)
+	require.NoError(t, err)
+	assert.Equal(t, webhook.URL, got.URL)
+	assert.Equal(t, "secret", got.Secret)
+	assert.Equal(t, webhook.Events, got.Events)
+	assert.False(t, got.CreatedAt.IsZero())
+
+	webhooks, err := client.ListWebhooks(ctx)
+	require.NoError(t, err)
+	found := false
+	for _, w := range webhooks {
+		found = found || w.ID == webhook.ID
+	}
+	assert.True(t, found)
+
+	// 无效的配置被拒绝
+	assert.Error(t, client.PutWebhook(ctx, &Webhook{URL: "not-a-url"}))
+
+	// 投递记录按时间从新到旧排序，只保留最近的记录
+	for i := 0; i < maxWebhookDeliveries+5; i++ {
+		delivery := &WebhookDelivery{
+			ID:        fmt.Sprintf("%020d-test", i),
+			WebhookID: webhook.ID,
+			Event:     WebhookServiceRegistered,
+			Status:    DeliverySucceeded,
+			Attempts:  1,
+		}
+		require.NoError(t, client.PutWebhookDelivery(ctx, delivery))
+	}
+	deliveries, err := client.ListWebhookDeliveries(ctx, webhook.ID)
+	require.NoError(t, err)
+	require.Len(t, deliveries, maxWebhookDeliveries)
+	assert.Equal(t, fmt.Sprintf("%020d-test", maxWebhookDeliveries+4), deliveries[0].ID)
+	assert.Equal(t, fmt.Sprintf("%020d-test", 5), deliveries[len(deliveries)-1].ID)
+
+	// 删除webhook时同时删除投递记录
+	require.NoError(t, client.DeleteWebhook(ctx, webhook.ID))
+	_, err = client.GetWebhook(ctx, webhook.ID)
+	assert.ErrorIs(t, err, ErrWebhookNotFound)
+	assert.ErrorIs(t, client.DeleteWebhook(ctx, webhook.ID), ErrWebhookNotFound)
+
+	deliveries, err = client.ListWebhookDeliveries(ctx, webhook.ID)
+	require.NoError(t, err)
+	assert.Empty(t, deliveries)
+}
@@ -0,0 +1,198 @@
+package etcdclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.uber.org/zap"
+)
+
+// 命名空间自定义区域在etcd中的键前缀
+const zonePrefix = "/config/zones/"
+
+// NamespaceZone 命名空间拥有的自定义DNS区域，其中的服务可以通过 <服务>.<区域> 解析
+type NamespaceZone struct {
+	Zone      string    `json:"zone"`       // 区域名称，如 team-a.internal
+	Namespace string    `json:"namespace"`  // 拥有该区域的命名空间
+	CreatedAt time.Time `json:"created_at"` // 创建时间
+}
+
+var (
+	// ErrZoneNotFound 区域不存在
+	ErrZoneNotFound = errors.New("区域不存在")
+
+	// ErrZoneExists 区域已被其他命名空间使用
+	ErrZoneExists = errors.New("区域已被其他命名空间使用")
+)
+
+// 区域名称格式：至少两个由小写字母、数字和连字符组成的标签
+var zoneNamePattern = regexp.MustCompile(`^([a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?\.)+[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$`)
+
+// NormalizeZone 规范化区域名称：转为小写并去掉首尾的点号
+func NormalizeZone(zone string) string {
+	return strings.Trim(strings.ToLower(strings.TrimSpace(zone)), ".")
+}
+
+// ValidateZoneName 校验区域名称，集群风格的服务域名后缀不能作为自定义区域
+func ValidateZoneName(zone string) error {
+	if len(zone) > 253 || !zoneNamePattern.MatchString(zone) {
+		return fmt.Errorf("无效的区域名称: %q，需要至少两个标签，只能包含小写字母、数字和连字符", zone)
+	}
+	suffix := strings.TrimPrefix(ServiceDomainSuffix, ".")
+	if zone == suffix || strings.HasSuffix(zone, ServiceDomainSuffix) || strings.HasSuffix(suffix, "."+zone) {
+		return fmt.Errorf("区域 %s 与服务域名后缀 %s 冲突", zone, ServiceDomainSuffix)
+	}
+	return nil
+}
+
+// getZoneKey 生成区域的etcd键
+func getZoneKey(zone string) string {
+	return zonePrefix + zone
+}
+
+// ListNamespaceZones 列出所有命名空间的自定义区域
+func (e *EtcdClient) ListNamespaceZones(ctx context.Context) ([]*NamespaceZone, error) {
+	if e.conn() == nil {
+		return nil, fmt.Errorf("etcd客户端未连接")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, etcdTimeout)
+	defer cancel()
+
+	resp, err := e.conn().Get(ctx, zonePrefix, clientv3.WithPrefix())
+	if err != nil {
+		e.log(ctx).Error("获取区域列表失败", zap.Error(err))
+		return nil, fmt.Errorf("获取区域列表失败: %w", err)
+	}
+
+	zones := make([]*NamespaceZone, 0, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		var zone NamespaceZone
+		if err := json.Unmarshal(kv.Value, &zone); err != nil {
+			e.log(ctx).Warn("解析区域失败", zap.String("key", string(kv.Key)), zap.Error(err))
+			continue
+		}
+		zones = append(zones, &zone)
+	}
+	return zones, nil
+}
+
+// PutNamespaceZone 把区域分配给命名空间，区域已属于其他命名空间时返回ErrZoneExists，重复分配给同一命名空间视为成功
+func (e *EtcdClient) PutNamespaceZone(ctx context.Context, zone *NamespaceZone) error {
+	if e.conn() == nil {
+		return fmt.Errorf("etcd客户端未连接")
+	}
+
+	zone.Zone = NormalizeZone(zone.Zone)
+	zone.Namespace = NamespaceOf(zone.Namespace)
+	if err := ValidateZoneName(zone.Zone); err != nil {
+		return err
+	}
+	if err := e.ensureNamespace(ctx, zone.Namespace); err != nil {
+		return err
+	}
+	if zone.CreatedAt.IsZero() {
+		zone.CreatedAt = time.Now()
+	}
+
+	data, err := json.Marshal(zone)
+	if err != nil {
+		return fmt.Errorf("序列化区域失败: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, etcdTimeout)
+	defer cancel()
+
+	key := getZoneKey(zone.Zone)
+	resp, err := e.conn().Txn(ctx).
+		If(clientv3.Compare(clientv3.CreateRevision(key), "=", 0)).
+		Then(clientv3.OpPut(key, string(data))).
+		Else(clientv3.OpGet(key)).
+		Commit()
+	if err != nil {
+		e.log(ctx).Error("保存区域失败", zap.String("zone", zone.Zone), zap.Error(err))
+		return fmt.Errorf("保存区域失败: %w", err)
+	}
+	if !resp.Succeeded {
+		var existing NamespaceZone
+		if kvs := resp.Responses[0].GetResponseRange().Kvs; len(kvs) > 0 {
+			_ = json.Unmarshal(kvs[0].Value, &existing)
+		}
+		if existing.Namespace != zone.Namespace {
+			return fmt.Errorf("%w: %s 属于命名空间 %s", ErrZoneExists, zone.Zone, existing.Namespace)
+		}
+		*zone = existing
+		return nil
+	}
+
+	e.log(ctx).Info("区域分配成功", zap.String("zone", zone.Zone), zap.String("namespace", zone.Namespace))
+	return nil
+}
+
+// DeleteNamespaceZone 删除命名空间的自定义区域，区域不存在或不属于该命名空间时返回ErrZoneNotFound
+func (e *EtcdClient) DeleteNamespaceZone(ctx context.Context, namespace, zone string) error {
+	if e.conn() == nil {
+		return fmt.Errorf("etcd客户端未连接")
+	}
+
+	zone = NormalizeZone(zone)
+	namespace = NamespaceOf(namespace)
+
+	ctx, cancel := context.WithTimeout(ctx, etcdTimeout)
+	defer cancel()
+
+	key := getZoneKey(zone)
+	resp, err := e.conn().Get(ctx, key)
+	if err != nil {
+		return fmt.Errorf("获取区域失败: %w", err)
+	}
+	if len(resp.Kvs) == 0 {
+		return fmt.Errorf("%w: %s", ErrZoneNotFound, zone)
+	}
+	var existing NamespaceZone
+	if err := json.Unmarshal(resp.Kvs[0].Value, &existing); err != nil {
+		return fmt.Errorf("解析区域失败: %w", err)
+	}
+	if existing.Namespace != namespace {
+		return fmt.Errorf("%w: %s 不属于命名空间 %s", ErrZoneNotFound, zone, namespace)
+	}
+
+	if _, err := e.conn().Delete(ctx, key); err != nil {
+		e.log(ctx).Error("删除区域失败", zap.String("zone", zone), zap.Error(err))
+		return fmt.Errorf("删除区域失败: %w", err)
+	}
+
+	e.log(ctx).Info("区域删除成功", zap.String("zone", zone), zap.String("namespace", namespace))
+	return nil
+}
+
+// namespaceZoneKeys 返回命名空间拥有的区域的etcd键，删除命名空间时一并删除
+func (e *EtcdClient) namespaceZoneKeys(ctx context.Context, namespace string) ([]string, error) {
+	zones, err := e.ListNamespaceZones(ctx)
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0)
+	for _, zone := range zones {
+		if zone.Namespace == namespace {
+			keys = append(keys, getZoneKey(zone.Zone))
+		}
+	}
+	return keys, nil
+}
+
+// ZoneServiceDomain 把自定义区域中的名称转换为集群风格的服务域名，
+// 如 _http._tcp.order.team-a.internal 转换为 _http._tcp.order.<命名空间>.svc.cluster.local
+func ZoneServiceDomain(name, zone, namespace string) (string, bool) {
+	prefix := strings.TrimSuffix(name, "."+zone)
+	if prefix == name || prefix == "" {
+		return "", false
+	}
+	return prefix + "." + NamespaceOf(namespace) + ServiceDomainSuffix, true
+}
@@ -0,0 +1,86 @@
+package etcdclient
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateZoneName(t *testing.T) {
+	assert.NoError(t, ValidateZoneName("team-a.internal"))
+	assert.NoError(t, ValidateZoneName("svc.example.com"))
+	assert.Error(t, ValidateZoneName("internal"), "至少需要两个标签")
+	assert.Error(t, ValidateZoneName("Team-A.internal"), "需要先规范化为小写")
+	assert.Error(t, ValidateZoneName("a..internal"))
+	assert.Error(t, ValidateZoneName("svc.cluster.local"))
+	assert.Error(t, ValidateZoneName("cluster.local"))
+	assert.Error(t, ValidateZoneName("team.svc.cluster.local"))
+
+	assert.Equal(t, "team-a.internal", NormalizeZone(" Team-A.Internal. "))
+}
+
+func TestZoneServiceDomain(t *testing.T) {
+	domain, ok := ZoneServiceDomain("order.team-a.internal", "team-a.internal", "team-a")
+	require.True(t, ok)
+	assert.Equal(t, "order.team-a.svc.cluster.local", domain)
+
+	domain, ok = ZoneServiceDomain("_http._tcp.order.team-a.internal", "team-a.internal", "")
+	require.True(t, ok)
+	assert.Equal(t, "_http._tcp.order.default.svc.cluster.local", domain)
+
+	_, ok = ZoneServiceDomain("team-a.internal", "team-a.internal", "team-a")
+	assert.False(t, ok, "区域本身不是服务名称")
+	_, ok = ZoneServiceDomain("order.team-b.internal", "team-a.internal", "team-a")
+	assert.False(t, ok)
+}
+
+func TestEtcdClient_NamespaceZones(t *testing.T) {
+	// 跳过集成测试，除非明确要求运行
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	client := CreateEtcdClientForTest(t)
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	namespace := fmt.Sprintf("zone-ns-%d", time.Now().UnixNano())
+	require.NoError(t, client.CreateNamespace(ctx, &Namespace{Name: namespace}))
+	defer func() { _ = client.DeleteNamespace(context.Background(), namespace, true) }()
+
+	zoneName := namespace + ".internal"
+	require.NoError(t, client.PutNamespaceZone(ctx, &NamespaceZone{Zone: zoneName, Namespace: namespace}))
+	// 重复分配给同一命名空间视为成功
+	require.NoError(t, client.PutNamespaceZone(ctx, &NamespaceZone{Zone: zoneName, Namespace: namespace}))
+
+	// 区域只能属于一个命名空间
+	err := client.PutNamespaceZone(ctx, &NamespaceZone{Zone: zoneName, Namespace: DefaultNamespace})
+	assert.ErrorIs(t, err, ErrZoneExists)
+
+	// 不存在的命名空间不能拥有区域
+	err = client.PutNamespaceZone(ctx, &NamespaceZone{Zone: "other." + zoneName, Namespace: namespace + "-missing"})
+	assert.ErrorIs(t, err, ErrNamespaceNotFound)
+
+	zones, err := client.ListNamespaceZones(ctx)
+	require.NoError(t, err)
+	found := false
+	for _, zone := range zones {
+		if zone.Zone == zoneName {
+			found = true
+			assert.Equal(t, namespace, zone.Namespace)
+		}
+	}
+	assert.True(t, found)
+
+	assert.ErrorIs(t, client.DeleteNamespaceZone(ctx, DefaultNamespace, zoneName), ErrZoneNotFound)
+
+	// 删除命名空间时一并删除其区域
+	require.NoError(t, client.DeleteNamespace(ctx, namespace, true))
+	assert.ErrorIs(t, client.DeleteNamespaceZone(ctx, namespace, zoneName), ErrZoneNotFound)
+}
@@ -0,0 +1,100 @@
+package eventbus
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hewenyu/kong-discovery/internal/etcdclient"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// 消息格式
+const (
+	FormatJSON     = "json"
+	FormatProtobuf = "protobuf"
+)
+
+// Message 发布到消息系统的变更消息，protobuf格式的定义见event.proto
+type Message struct {
+	Type      string          `json:"type"`               // 变更类型 (put 或 delete)
+	Kind      string          `json:"kind"`               // 数据类别 (services 或 dns)
+	Key       string          `json:"key"`                // 变更的etcd键
+	Value     json.RawMessage `json:"value,omitempty"`    // 变更后的值，删除事件为删除前的值
+	Previous  json.RawMessage `json:"previous,omitempty"` // 更新事件变更前的值，新建时为空
+	Revision  int64           `json:"revision"`           // etcd修订版本
+	Timestamp time.Time       `json:"timestamp"`          // 发布时间
+}
+
+// event.proto中的字段编号
+const (
+	fieldType protowire.Number = iota + 1
+	fieldKind
+	fieldKey
+	fieldValue
+	fieldPrevious
+	fieldRevision
+	fieldTimestamp
+)
+
+// newMessage 把etcd变更转换为消息
+func newMessage(event *etcdclient.Event, timestamp time.Time) *Message {
+	return &Message{
+		Type:      event.Type,
+		Kind:      event.Kind,
+		Key:       event.Key,
+		Value:     event.Value,
+		Previous:  event.Previous,
+		Revision:  event.Revision,
+		Timestamp: timestamp,
+	}
+}
+
+// validFormat 判断消息格式是否支持
+func validFormat(format string) bool {
+	return format == FormatJSON || format == FormatProtobuf
+}
+
+// Encode 按指定格式序列化消息
+func (m *Message) Encode(format string) ([]byte, error) {
+	switch format {
+	case FormatJSON:
+		return json.Marshal(m)
+	case FormatProtobuf:
+		return m.marshalProto(), nil
+	default:
+		return nil, fmt.Errorf("不支持的消息格式: %s", format)
+	}
+}
+
+// marshalProto 按event.proto编码，proto3中的零值字段省略
+func (m *Message) marshalProto() []byte {
+	var b []byte
+	appendString := func(num protowire.Number, v string) {
+		if v != "" {
+			b = protowire.AppendTag(b, num, protowire.BytesType)
+			b = protowire.AppendString(b, v)
+		}
+	}
+	appendBytes := func(num protowire.Number, v []byte) {
+		if len(v) > 0 {
+			b = protowire.AppendTag(b, num, protowire.BytesType)
+			b = protowire.AppendBytes(b, v)
+		}
+	}
+	appendInt := func(num protowire.Number, v int64) {
+		if v != 0 {
+			b = protowire.AppendTag(b, num, protowire.VarintType)
+			b = protowire.AppendVarint(b, uint64(v))
+		}
+	}
+
+	appendString(fieldType, m.Type)
+	appendString(fieldKind, m.Kind)
+	appendString(fieldKey, m.Key)
+	appendBytes(fieldValue, m.Value)
+	appendBytes(fieldPrevious, m.Previous)
+	appendInt(fieldRevision, m.Revision)
+	appendInt(fieldTimestamp, m.Timestamp.UnixNano())
+	return b
+}
@@ -0,0 +1,87 @@
+package eventbus
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/hewenyu/kong-discovery/internal/etcdclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func testEvent() *etcdclient.Event {
+	return &etcdclient.Event{
+		Type:     etcdclient.EventPut,
+		Kind:     "services",
+		Key:      "/services/svc/i1",
+		Value:    json.RawMessage(`{"service_name":"svc","instance_id":"i1"}`),
+		Previous: json.RawMessage(`{"service_name":"svc","instance_id":"i1","health":"critical"}`),
+		Revision: 42,
+	}
+}
+
+func TestMessage_EncodeJSON(t *testing.T) {
+	timestamp := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	data, err := newMessage(testEvent(), timestamp).Encode(FormatJSON)
+	require.NoError(t, err)
+
+	var msg Message
+	require.NoError(t, json.Unmarshal(data, &msg))
+	assert.Equal(t, "put", msg.Type)
+	assert.Equal(t, "services", msg.Kind)
+	assert.Equal(t, "/services/svc/i1", msg.Key)
+	assert.JSONEq(t, `{"service_name":"svc","instance_id":"i1"}`, string(msg.Value))
+	assert.Equal(t, int64(42), msg.Revision)
+	assert.True(t, timestamp.Equal(msg.Timestamp))
+}
+
+func TestMessage_EncodeProtobuf(t *testing.T) {
+	timestamp := time.Unix(0, 1700000000123456789)
+	event := testEvent()
+	data, err := newMessage(event, timestamp).Encode(FormatProtobuf)
+	require.NoError(t, err)
+
+	// 按event.proto的字段编号解码
+	fields := make(map[protowire.Number]any)
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		require.Greater(t, n, 0)
+		data = data[n:]
+		switch typ {
+		case protowire.BytesType:
+			v, n := protowire.ConsumeBytes(data)
+			require.Greater(t, n, 0)
+			fields[num] = string(v)
+			data = data[n:]
+		case protowire.VarintType:
+			v, n := protowire.ConsumeVarint(data)
+			require.Greater(t, n, 0)
+			fields[num] = int64(v)
+			data = data[n:]
+		default:
+			t.Fatalf("意外的字段类型 %v", typ)
+		}
+	}
+
+	assert.Equal(t, "put", fields[fieldType])
+	assert.Equal(t, "services", fields[fieldKind])
+	assert.Equal(t, event.Key, fields[fieldKey])
+	assert.Equal(t, string(event.Value), fields[fieldValue])
+	assert.Equal(t, string(event.Previous), fields[fieldPrevious])
+	assert.Equal(t, int64(42), fields[fieldRevision])
+	assert.Equal(t, timestamp.UnixNano(), fields[fieldTimestamp])
+
+	// 删除事件没有变更前的值，零值字段省略
+	event.Type = etcdclient.EventDelete
+	event.Previous = nil
+	data, err = newMessage(event, timestamp).Encode(FormatProtobuf)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "critical")
+}
+
+func TestMessage_EncodeUnknownFormat(t *testing.T) {
+	_, err := newMessage(testEvent(), time.Now()).Encode("avro")
+	assert.Error(t, err)
+}
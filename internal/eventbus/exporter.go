@@ -0,0 +1,300 @@
+package eventbus
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hewenyu/kong-discovery/internal/config"
+	"github.com/hewenyu/kong-discovery/internal/etcdclient"
+	"go.uber.org/zap"
+)
+
+// electionName 事件总线导出使用的选举名称，多个节点只有领导者发布，避免重复消息
+const electionName = "event-bus-exporter"
+
+// 消息系统类型
+const (
+	TypeNATS  = "nats"
+	TypeKafka = "kafka"
+)
+
+const (
+	defaultTopic       = "kong-discovery"
+	campaignRetryDelay = 5 * time.Second
+	// checkpointInterval 保存已发布修订版本的间隔，领导者切换后最多重复发布这段时间内的消息
+	checkpointInterval = time.Second
+	minRetryInterval   = time.Second
+	maxRetryInterval   = 30 * time.Second
+)
+
+// Publisher 定义消息发布接口
+type Publisher interface {
+	// Publish 发布一条消息，key为消息键（变更的etcd键）
+	Publish(ctx context.Context, topic, key string, data []byte) error
+
+	// Close 关闭与消息系统的连接
+	Close() error
+}
+
+// Exporter 定义事件总线导出器接口
+type Exporter interface {
+	// Start 启动导出器（非阻塞）
+	Start() error
+
+	// Stop 停止导出器，保存已发布的修订版本
+	Stop(ctx context.Context) error
+}
+
+// EventExporter 监听服务实例和DNS记录的变更，按顺序发布到NATS或Kafka
+// 多个节点通过etcd选举产生唯一的领导者，领导者切换后从上次保存的修订版本继续发布，
+// 因此消息至少发布一次，下游可按键和修订版本去重
+type EventExporter struct {
+	cfg       *config.Config
+	logger    config.Logger
+	client    etcdclient.Client
+	publisher Publisher
+	format    string
+	topic     string
+	candidate string
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewExporter 创建事件总线导出器，消息系统类型或格式无效时返回错误
+func NewExporter(cfg *config.Config, logger config.Logger, client etcdclient.Client) (Exporter, error) {
+	format := cfg.EventBus.Format
+	if format == "" {
+		format = FormatJSON
+	}
+	if !validFormat(format) {
+		return nil, fmt.Errorf("不支持的消息格式: %s", format)
+	}
+	topic := cfg.EventBus.Topic
+	if topic == "" {
+		topic = defaultTopic
+	}
+
+	publisher, err := newPublisher(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	hostname, _ := os.Hostname()
+	return &EventExporter{
+		cfg:       cfg,
+		logger:    logger,
+		client:    client,
+		publisher: publisher,
+		format:    format,
+		topic:     topic,
+		candidate: fmt.Sprintf("%s-%s", hostname, uuid.New().String()[:8]),
+	}, nil
+}
+
+// newPublisher 按配置创建消息发布器
+func newPublisher(cfg *config.Config) (Publisher, error) {
+	bus := cfg.EventBus
+	switch bus.Type {
+	case TypeNATS, "":
+		return newNATSPublisher(bus.NATS.URL, bus.NATS.Token, bus.NATS.Username, bus.NATS.Password)
+	case TypeKafka:
+		return newKafkaPublisher(bus.Kafka.RestProxy, bus.Kafka.Username, bus.Kafka.Password)
+	default:
+		return nil, fmt.Errorf("不支持的消息系统类型: %s", bus.Type)
+	}
+}
+
+// Start 启动导出器
+func (x *EventExporter) Start() error {
+	if x.done != nil {
+		return fmt.Errorf("事件总线导出器已启动")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	x.cancel = cancel
+	x.done = make(chan struct{})
+
+	x.logger.Info("启动事件总线导出器",
+		zap.String("candidate", x.candidate),
+		zap.String("type", x.cfg.EventBus.Type),
+		zap.String("format", x.format),
+		zap.String("topic", x.topic))
+
+	go x.run(ctx)
+	return nil
+}
+
+// Stop 停止导出器
+func (x *EventExporter) Stop(ctx context.Context) error {
+	if x.done == nil {
+		return nil
+	}
+
+	x.cancel()
+	select {
+	case <-x.done:
+		return x.publisher.Close()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// run 循环参与选举，成为领导者后发布变更，领导权丢失后重新参与选举
+func (x *EventExporter) run(ctx context.Context) {
+	defer close(x.done)
+
+	for {
+		leadership, err := x.client.Campaign(ctx, electionName, x.candidate)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			x.logger.Warn("参与事件总线导出器选举失败，稍后重试", zap.Error(err))
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(campaignRetryDelay):
+				continue
+			}
+		}
+
+		x.lead(ctx, leadership)
+		if ctx.Err() != nil {
+			return
+		}
+		x.logger.Warn("事件总线导出器领导权丢失，重新参与选举")
+	}
+}
+
+// lead 作为领导者从检查点开始监听并发布变更，直到领导权丢失或导出器停止
+func (x *EventExporter) lead(ctx context.Context, leadership *etcdclient.Leadership) {
+	leadCtx, cancelLead := context.WithCancel(ctx)
+	defer cancelLead()
+	go func() {
+		select {
+		case <-leadership.Done():
+			cancelLead()
+		case <-leadCtx.Done():
+		}
+	}()
+
+	defer func() {
+		resignCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		// 领导权已丢失时放弃失败是预期的，只在停止时记录
+		if err := leadership.Resign(resignCtx); err != nil && ctx.Err() != nil {
+			x.logger.Warn("放弃事件总线导出器领导权失败", zap.Error(err))
+		}
+		cancel()
+	}()
+
+	checkpoint, err := x.client.GetEventBusCheckpoint(leadCtx)
+	if err != nil {
+		x.logger.Error("获取事件总线检查点失败", zap.Error(err))
+		return
+	}
+
+	// 从检查点所在的修订版本重新发布，同一修订版本中的变更可能只发布了一部分
+	events, err := x.client.WatchEventsFrom(leadCtx, checkpoint)
+	if err != nil {
+		x.logger.Error("监听变更事件失败", zap.Error(err))
+		return
+	}
+	x.logger.Info("开始发布变更事件", zap.Int64("from_revision", checkpoint))
+
+	published := checkpoint
+	saved := checkpoint
+	save := func() {
+		if published == saved {
+			return
+		}
+		saveCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := x.client.PutEventBusCheckpoint(saveCtx, published); err != nil {
+			x.logger.Warn("保存事件总线检查点失败", zap.Int64("revision", published), zap.Error(err))
+			return
+		}
+		saved = published
+	}
+	defer save()
+
+	ticker := time.NewTicker(checkpointInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-leadCtx.Done():
+			return
+		case <-ticker.C:
+			save()
+		case event, ok := <-events:
+			if !ok {
+				x.logger.Warn("监听变更事件中断，重新参与选举")
+				return
+			}
+			// 压缩期间的变更已无法发布，检查点前进到继续监听的版本
+			if event.Type == etcdclient.EventResync {
+				x.logger.Warn("监听的版本已被压缩，期间的变更未发布", zap.Int64("from_revision", published), zap.Int64("to_revision", event.Revision))
+				published = event.Revision
+				continue
+			}
+			if !exported(event) {
+				continue
+			}
+			if !x.publish(leadCtx, event) {
+				return
+			}
+			published = event.Revision
+		}
+	}
+}
+
+// exported 判断变更是否需要发布：服务实例和DNS记录，不包括定时变更
+func exported(event *etcdclient.Event) bool {
+	switch event.Kind {
+	case "services":
+		return true
+	case "dns":
+		return !strings.HasPrefix(event.Key, "/dns/scheduled/")
+	}
+	return false
+}
+
+// publish 发布一条变更，失败时按指数退避重试直到成功，保证消息顺序
+// ctx取消时返回false
+func (x *EventExporter) publish(ctx context.Context, event *etcdclient.Event) bool {
+	data, err := newMessage(event, time.Now()).Encode(x.format)
+	if err != nil {
+		x.logger.Error("序列化变更消息失败，跳过", zap.String("key", event.Key), zap.Error(err))
+		return true
+	}
+	topic := x.topic + "." + event.Kind
+
+	interval := minRetryInterval
+	for {
+		err := x.publisher.Publish(ctx, topic, event.Key, data)
+		if err == nil {
+			return true
+		}
+		if ctx.Err() != nil {
+			return false
+		}
+		x.logger.Warn("发布变更消息失败，稍后重试",
+			zap.String("topic", topic),
+			zap.String("key", event.Key),
+			zap.Int64("revision", event.Revision),
+			zap.Duration("retry_in", interval),
+			zap.Error(err))
+
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(interval):
+		}
+		interval = min(interval*2, maxRetryInterval)
+	}
+}
@@ -0,0 +1,146 @@
+package eventbus
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hewenyu/kong-discovery/internal/config"
+	"github.com/hewenyu/kong-discovery/internal/etcdclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// 创建测试用的配置
+func createTestConfig(t *testing.T) *config.Config {
+	t.Helper()
+
+	cfg := &config.Config{}
+	etcdclient.ApplyTestStorage(cfg)
+	cfg.EventBus.Enabled = true
+	cfg.EventBus.Type = TypeNATS
+	cfg.EventBus.Format = FormatJSON
+	cfg.EventBus.Topic = fmt.Sprintf("test-%d", time.Now().UnixNano())
+	return cfg
+}
+
+// 创建测试用的日志记录器
+func createTestLogger(t *testing.T) config.Logger {
+	t.Helper()
+
+	logger, err := config.NewLogger(true)
+	require.NoError(t, err, "创建测试日志记录器失败")
+	return logger
+}
+
+func TestNewExporter_InvalidConfig(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.EventBus.Type = "rabbitmq"
+	_, err := NewExporter(cfg, nil, nil)
+	assert.ErrorContains(t, err, "rabbitmq")
+
+	cfg.EventBus.Type = TypeKafka
+	cfg.EventBus.Kafka.RestProxy = "http://localhost:8082"
+	cfg.EventBus.Format = "avro"
+	_, err = NewExporter(cfg, nil, nil)
+	assert.ErrorContains(t, err, "avro")
+}
+
+func TestExported(t *testing.T) {
+	assert.True(t, exported(&etcdclient.Event{Kind: "services", Key: "/services/a/1"}))
+	assert.True(t, exported(&etcdclient.Event{Kind: "dns", Key: "/dns/records/a.example.com/A"}))
+	assert.False(t, exported(&etcdclient.Event{Kind: "dns", Key: "/dns/scheduled/abc"}))
+	assert.False(t, exported(&etcdclient.Event{Kind: "config", Key: "/config/upstream"}))
+	assert.False(t, exported(&etcdclient.Event{Kind: "namespaces", Key: "/namespaces/default"}))
+}
+
+// waitMessage 等待指定键的消息，忽略其他测试产生的变更
+func waitMessage(t *testing.T, server *fakeNATS, key string) (string, *Message) {
+	t.Helper()
+
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case raw := <-server.messages:
+			var msg Message
+			require.NoError(t, json.Unmarshal(raw.Data, &msg))
+			if msg.Key == key {
+				return raw.Subject, &msg
+			}
+		case <-timeout:
+			t.Fatalf("等待 %s 的消息超时", key)
+		}
+	}
+}
+
+func TestEventExporter(t *testing.T) {
+	// 跳过集成测试，除非明确要求运行
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	client := etcdclient.CreateEtcdClientForTest(t)
+	t.Cleanup(func() { _ = client.Close() })
+
+	server := startFakeNATS(t)
+	cfg := createTestConfig(t)
+	cfg.EventBus.NATS.URL = server.url()
+
+	ctx := context.Background()
+	// 从当前版本开始发布，避免重放之前测试遗留的检查点
+	require.NoError(t, client.PutEventBusCheckpoint(ctx, 0))
+
+	start := func() Exporter {
+		exporter, err := NewExporter(cfg, createTestLogger(t), client)
+		require.NoError(t, err)
+		require.NoError(t, exporter.Start())
+		// 等待成为领导者并开始监听
+		time.Sleep(500 * time.Millisecond)
+		return exporter
+	}
+	stop := func(exporter Exporter) {
+		stopCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+		require.NoError(t, exporter.Stop(stopCtx))
+	}
+
+	serviceName := fmt.Sprintf("eventbus-service-%d", time.Now().UnixNano())
+	serviceKey := "/services/" + serviceName + "/instance-001"
+	domain := serviceName + ".example.com"
+	t.Cleanup(func() {
+		_ = client.DeregisterService(context.Background(), serviceName, "instance-001")
+		_ = client.DeleteDNSRecord(context.Background(), domain, "A", "")
+	})
+
+	exporter := start()
+	instance := &etcdclient.ServiceInstance{ServiceName: serviceName, InstanceID: "instance-001", IPAddress: "10.0.0.1", Port: 8080, TTL: 30}
+	require.NoError(t, client.RegisterService(ctx, instance))
+	require.NoError(t, client.PutDNSRecord(ctx, domain, &etcdclient.DNSRecord{Type: "A", Value: "10.0.0.2", TTL: 60}))
+
+	subject, msg := waitMessage(t, server, serviceKey)
+	assert.Equal(t, cfg.EventBus.Topic+".services", subject)
+	assert.Equal(t, etcdclient.EventPut, msg.Type)
+	var registered etcdclient.ServiceInstance
+	require.NoError(t, json.Unmarshal(msg.Value, &registered))
+	assert.Equal(t, "10.0.0.1", registered.IPAddress)
+
+	subject, msg = waitMessage(t, server, "/dns/records/"+domain+"/A")
+	assert.Equal(t, cfg.EventBus.Topic+".dns", subject)
+	assert.Equal(t, "dns", msg.Kind)
+	stop(exporter)
+
+	// 停止时保存已发布的修订版本
+	checkpoint, err := client.GetEventBusCheckpoint(ctx)
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, checkpoint, msg.Revision)
+
+	// 停止期间的变更在重新启动后从检查点继续发布
+	require.NoError(t, client.DeregisterService(ctx, serviceName, "instance-001"))
+	exporter = start()
+	defer stop(exporter)
+
+	_, msg = waitMessage(t, server, serviceKey)
+	assert.Equal(t, etcdclient.EventDelete, msg.Type)
+}
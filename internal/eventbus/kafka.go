@@ -0,0 +1,108 @@
+package eventbus
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// kafkaTimeout 单次发布请求的超时时间
+const kafkaTimeout = 10 * time.Second
+
+// Kafka REST代理v2 API的内容类型，消息以base64编码的二进制发送，保持与格式无关
+const (
+	kafkaContentType = "application/vnd.kafka.binary.v2+json"
+	kafkaAccept      = "application/vnd.kafka.v2+json"
+)
+
+// kafkaPublisher 通过Kafka REST代理发布消息
+type kafkaPublisher struct {
+	endpoint   string
+	username   string
+	password   string
+	httpClient *http.Client
+}
+
+// kafkaRecord REST代理的消息记录，[]byte按base64编码
+type kafkaRecord struct {
+	Key   []byte `json:"key"`
+	Value []byte `json:"value"`
+}
+
+// kafkaProduceResponse REST代理的发布结果
+type kafkaProduceResponse struct {
+	Offsets []struct {
+		Partition int    `json:"partition"`
+		Offset    int64  `json:"offset"`
+		ErrorCode *int   `json:"error_code"`
+		Error     string `json:"error"`
+	} `json:"offsets"`
+}
+
+// newKafkaPublisher 创建Kafka发布器
+func newKafkaPublisher(restProxy, username, password string) (*kafkaPublisher, error) {
+	u, err := url.Parse(restProxy)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("无效的Kafka REST代理地址: %s", restProxy)
+	}
+	return &kafkaPublisher{
+		endpoint:   strings.TrimSuffix(restProxy, "/"),
+		username:   username,
+		password:   password,
+		httpClient: &http.Client{Timeout: kafkaTimeout},
+	}, nil
+}
+
+// Publish 发布消息，相同键的消息进入同一分区以保证顺序
+func (p *kafkaPublisher) Publish(ctx context.Context, topic, key string, data []byte) error {
+	body, err := json.Marshal(map[string][]kafkaRecord{
+		"records": {{Key: []byte(key), Value: data}},
+	})
+	if err != nil {
+		return fmt.Errorf("序列化Kafka消息失败: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.endpoint+"/topics/"+url.PathEscape(topic), bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("创建Kafka请求失败: %w", err)
+	}
+	req.Header.Set("Content-Type", kafkaContentType)
+	req.Header.Set("Accept", kafkaAccept)
+	if p.username != "" {
+		req.SetBasicAuth(p.username, p.password)
+	}
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("请求Kafka REST代理失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("Kafka REST代理返回状态码 %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+
+	var result kafkaProduceResponse
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return fmt.Errorf("解析Kafka REST代理响应失败: %w", err)
+	}
+	for _, offset := range result.Offsets {
+		if offset.ErrorCode != nil {
+			return fmt.Errorf("发布到Kafka主题 %s 失败: %s", topic, offset.Error)
+		}
+	}
+	return nil
+}
+
+// Close 释放空闲连接
+func (p *kafkaPublisher) Close() error {
+	p.httpClient.CloseIdleConnections()
+	return nil
+}
@@ -0,0 +1,68 @@
+package eventbus
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKafkaPublisher(t *testing.T) {
+	var (
+		path    string
+		header  http.Header
+		records map[string][]kafkaRecord
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		header = r.Header
+		body, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(body, &records)
+
+		if r.URL.Path == "/topics/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error_code":40401,"message":"Topic not found"}`))
+			return
+		}
+		if r.URL.Path == "/topics/failing" {
+			w.Write([]byte(`{"offsets":[{"partition":null,"offset":null,"error_code":50002,"error":"Leader not available"}]}`))
+			return
+		}
+		w.Write([]byte(`{"offsets":[{"partition":0,"offset":42,"error_code":null,"error":null}]}`))
+	}))
+	defer server.Close()
+
+	publisher, err := newKafkaPublisher(server.URL+"/", "user", "pass")
+	require.NoError(t, err)
+	defer publisher.Close()
+
+	require.NoError(t, publisher.Publish(context.Background(), "kong-discovery.services", "/services/a/1", []byte{0x0a, 0x03}))
+	assert.Equal(t, "/topics/kong-discovery.services", path)
+	assert.Equal(t, kafkaContentType, header.Get("Content-Type"))
+	username, password, ok := (&http.Request{Header: header}).BasicAuth()
+	require.True(t, ok)
+	assert.Equal(t, "user", username)
+	assert.Equal(t, "pass", password)
+
+	// 键和值以base64编码的二进制发送
+	require.Len(t, records["records"], 1)
+	assert.Equal(t, "/services/a/1", string(records["records"][0].Key))
+	assert.Equal(t, []byte{0x0a, 0x03}, records["records"][0].Value)
+
+	err = publisher.Publish(context.Background(), "missing", "", []byte("x"))
+	assert.ErrorContains(t, err, "404")
+	err = publisher.Publish(context.Background(), "failing", "", []byte("x"))
+	assert.ErrorContains(t, err, "Leader not available")
+}
+
+func TestNewKafkaPublisher(t *testing.T) {
+	for _, url := range []string{"", "localhost:8082", "kafka://localhost:9092"} {
+		_, err := newKafkaPublisher(url, "", "")
+		assert.Error(t, err, url)
+	}
+}
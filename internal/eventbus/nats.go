@@ -0,0 +1,189 @@
+package eventbus
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// natsTimeout 连接和等待服务器确认的默认超时时间
+const natsTimeout = 5 * time.Second
+
+// natsPublisher 使用NATS核心协议发布消息
+// 每次发布后发送PING并等待PONG，确保服务器已收到消息且没有返回错误（如无权限发布到该主题）
+type natsPublisher struct {
+	url      *url.URL
+	token    string
+	username string
+	password string
+
+	mu     sync.Mutex
+	conn   net.Conn
+	reader *bufio.Reader
+	writer *bufio.Writer
+}
+
+// newNATSPublisher 创建NATS发布器，连接在首次发布时建立
+func newNATSPublisher(rawURL, token, username, password string) (*natsPublisher, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "nats" && u.Scheme != "tls") || u.Host == "" {
+		return nil, fmt.Errorf("无效的NATS地址: %s", rawURL)
+	}
+	if u.Port() == "" {
+		u.Host = net.JoinHostPort(u.Hostname(), "4222")
+	}
+	// 地址中的用户信息优先于配置
+	if u.User != nil {
+		username = u.User.Username()
+		password, _ = u.User.Password()
+	}
+	return &natsPublisher{url: u, token: token, username: username, password: password}, nil
+}
+
+// Publish 发布消息，NATS消息没有键，key仅用于接口一致
+func (p *natsPublisher) Publish(ctx context.Context, topic, key string, data []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.conn == nil {
+		if err := p.connect(ctx); err != nil {
+			return err
+		}
+	}
+
+	if err := p.publish(ctx, topic, data); err != nil {
+		p.closeConn()
+		return err
+	}
+	return nil
+}
+
+// publish 发送PUB和PING，等待服务器的PONG
+func (p *natsPublisher) publish(ctx context.Context, topic string, data []byte) error {
+	p.setDeadline(ctx)
+
+	fmt.Fprintf(p.writer, "PUB %s %d\r\n", topic, len(data))
+	p.writer.Write(data)
+	p.writer.WriteString("\r\nPING\r\n")
+	if err := p.writer.Flush(); err != nil {
+		return fmt.Errorf("发送NATS消息失败: %w", err)
+	}
+	return p.waitPong()
+}
+
+// connect 建立连接，读取INFO，发送CONNECT并等待服务器确认
+func (p *natsPublisher) connect(ctx context.Context) error {
+	dialer := &net.Dialer{Timeout: natsTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", p.url.Host)
+	if err != nil {
+		return fmt.Errorf("连接NATS服务器 %s 失败: %w", p.url.Host, err)
+	}
+	if p.url.Scheme == "tls" {
+		conn = tls.Client(conn, &tls.Config{ServerName: p.url.Hostname()})
+	}
+
+	p.conn = conn
+	p.reader = bufio.NewReader(conn)
+	p.writer = bufio.NewWriter(conn)
+	p.setDeadline(ctx)
+
+	line, err := p.readLine()
+	if err != nil {
+		p.closeConn()
+		return fmt.Errorf("读取NATS服务器信息失败: %w", err)
+	}
+	if !strings.HasPrefix(line, "INFO ") {
+		p.closeConn()
+		return fmt.Errorf("NATS服务器返回了无效的握手: %s", line)
+	}
+
+	options := map[string]any{
+		"verbose":  false,
+		"pedantic": false,
+		"name":     "kong-discovery",
+		"lang":     "go",
+		"protocol": 1,
+	}
+	if p.token != "" {
+		options["auth_token"] = p.token
+	}
+	if p.username != "" {
+		options["user"] = p.username
+		options["pass"] = p.password
+	}
+	connect, _ := json.Marshal(options)
+
+	fmt.Fprintf(p.writer, "CONNECT %s\r\nPING\r\n", connect)
+	if err := p.writer.Flush(); err != nil {
+		p.closeConn()
+		return fmt.Errorf("发送NATS连接请求失败: %w", err)
+	}
+	if err := p.waitPong(); err != nil {
+		p.closeConn()
+		return err
+	}
+	return nil
+}
+
+// waitPong 读取服务器消息直到收到PONG，期间应答服务器的PING
+func (p *natsPublisher) waitPong() error {
+	for {
+		line, err := p.readLine()
+		if err != nil {
+			return fmt.Errorf("等待NATS服务器确认失败: %w", err)
+		}
+		switch {
+		case line == "PONG":
+			return nil
+		case line == "PING":
+			p.writer.WriteString("PONG\r\n")
+			if err := p.writer.Flush(); err != nil {
+				return fmt.Errorf("应答NATS服务器失败: %w", err)
+			}
+		case strings.HasPrefix(line, "-ERR"):
+			return fmt.Errorf("NATS服务器返回错误: %s", strings.TrimSpace(strings.TrimPrefix(line, "-ERR")))
+		}
+		// 忽略+OK和INFO（集群拓扑变化）
+	}
+}
+
+// readLine 读取一行协议消息，去掉行尾的\r\n
+func (p *natsPublisher) readLine() (string, error) {
+	line, err := p.reader.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// setDeadline 按ctx的截止时间设置读写超时，没有截止时间时使用默认超时
+func (p *natsPublisher) setDeadline(ctx context.Context) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(natsTimeout)
+	}
+	p.conn.SetDeadline(deadline)
+}
+
+// closeConn 关闭连接，下次发布时重新连接
+func (p *natsPublisher) closeConn() {
+	if p.conn != nil {
+		p.conn.Close()
+		p.conn = nil
+	}
+}
+
+// Close 关闭连接
+func (p *natsPublisher) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closeConn()
+	return nil
+}
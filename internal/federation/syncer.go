@@ -0,0 +1,368 @@
+package federation
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hewenyu/kong-discovery/internal/config"
+	"github.com/hewenyu/kong-discovery/internal/etcdclient"
+	"go.uber.org/zap"
+)
+
+// electionName 联邦同步使用的选举名称，多个节点只有领导者拉取和推送目录
+const electionName = "federation-sync"
+
+// CatalogPath 远程集群拉取本集群目录的管理API路径
+const CatalogPath = "/v1/admin/federation/catalog"
+
+// PushPath 返回远程集群接收cluster推送目录的管理API路径
+func PushPath(cluster string) string {
+	return "/v1/admin/federation/clusters/" + cluster + "/catalog"
+}
+
+const (
+	defaultInterval    = 30 * time.Second
+	defaultTimeout     = 10 * time.Second
+	campaignRetryDelay = 5 * time.Second
+	// maxErrorBody 记录远程集群错误响应时最多读取的字节数
+	maxErrorBody = 512
+	// maxCatalogSize 拉取目录时允许的最大响应大小
+	maxCatalogSize = 64 << 20
+)
+
+// Syncer 定义联邦同步器接口
+type Syncer interface {
+	// Start 启动同步器（非阻塞）
+	Start() error
+
+	// Stop 停止同步器
+	Stop(ctx context.Context) error
+}
+
+// FederationSyncer 定时从远程集群拉取服务目录，并把本集群的目录推送到远程集群
+// 多个节点通过etcd选举产生唯一的领导者执行同步，所有节点都从etcd中同步的目录应答联邦域名
+type FederationSyncer struct {
+	cfg        *config.Config
+	logger     config.Logger
+	client     etcdclient.Client
+	httpClient *http.Client
+	interval   time.Duration
+	candidate  string
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewSyncer 创建联邦同步器，联邦配置无效时返回错误
+func NewSyncer(cfg *config.Config, logger config.Logger, client etcdclient.Client) (Syncer, error) {
+	if err := Validate(cfg); err != nil {
+		return nil, err
+	}
+
+	interval := time.Duration(cfg.Federation.Interval) * time.Second
+	if interval <= 0 {
+		interval = defaultInterval
+	}
+	timeout := time.Duration(cfg.Federation.Timeout) * time.Second
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+
+	hostname, _ := os.Hostname()
+	return &FederationSyncer{
+		cfg:        cfg,
+		logger:     logger,
+		client:     client,
+		httpClient: &http.Client{Timeout: timeout},
+		interval:   interval,
+		candidate:  fmt.Sprintf("%s-%s", hostname, uuid.New().String()[:8]),
+	}, nil
+}
+
+// Validate 校验联邦配置：集群名称有效且互不相同，远程集群不能与本集群同名
+func Validate(cfg *config.Config) error {
+	federation := cfg.Federation
+	if err := etcdclient.ValidateClusterName(federation.ClusterName); err != nil {
+		return fmt.Errorf("federation.cluster_name: %w", err)
+	}
+	switch federation.Conflict {
+	case "", "local", "remote":
+	default:
+		return fmt.Errorf("federation.conflict: 不支持的取值 %q，只能是local或remote", federation.Conflict)
+	}
+
+	seen := map[string]bool{federation.ClusterName: true}
+	for _, peer := range federation.Peers {
+		if err := etcdclient.ValidateClusterName(peer.Name); err != nil {
+			return fmt.Errorf("federation.peers: %w", err)
+		}
+		if seen[peer.Name] {
+			return fmt.Errorf("federation.peers: 集群 %s 与本集群或其他远程集群重名", peer.Name)
+		}
+		seen[peer.Name] = true
+
+		switch peerMode(peer) {
+		case etcdclient.FederationPull:
+			if err := validURL(peer.URL); err != nil {
+				return fmt.Errorf("federation.peers: 集群 %s: %w", peer.Name, err)
+			}
+		case etcdclient.FederationPush:
+		default:
+			return fmt.Errorf("federation.peers: 集群 %s 的同步方式 %q 无效，只能是pull或push", peer.Name, peer.Mode)
+		}
+	}
+
+	for _, target := range federation.PushTo {
+		if err := validURL(target.URL); err != nil {
+			return fmt.Errorf("federation.push_to: %s: %w", target.Name, err)
+		}
+	}
+	return nil
+}
+
+// peerMode 返回远程集群的同步方式，未设置时为拉取
+func peerMode(peer config.FederationPeer) string {
+	if peer.Mode == "" {
+		return etcdclient.FederationPull
+	}
+	return peer.Mode
+}
+
+// validURL 校验远程集群的管理API地址
+func validURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("无效的地址: %q", raw)
+	}
+	return nil
+}
+
+// Start 启动同步器
+func (s *FederationSyncer) Start() error {
+	if s.done != nil {
+		return fmt.Errorf("联邦同步器已启动")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	s.done = make(chan struct{})
+
+	s.logger.Info("启动联邦同步器",
+		zap.String("candidate", s.candidate),
+		zap.String("cluster", s.cfg.Federation.ClusterName),
+		zap.Int("peers", len(s.cfg.Federation.Peers)),
+		zap.Int("push_to", len(s.cfg.Federation.PushTo)),
+		zap.Duration("interval", s.interval))
+
+	go s.run(ctx)
+	return nil
+}
+
+// Stop 停止同步器
+func (s *FederationSyncer) Stop(ctx context.Context) error {
+	if s.done == nil {
+		return nil
+	}
+
+	s.cancel()
+	select {
+	case <-s.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// run 循环参与选举，成为领导者后定时同步，领导权丢失后重新参与选举
+func (s *FederationSyncer) run(ctx context.Context) {
+	defer close(s.done)
+
+	for {
+		leadership, err := s.client.Campaign(ctx, electionName, s.candidate)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			s.logger.Warn("参与联邦同步器选举失败，稍后重试", zap.Error(err))
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(campaignRetryDelay):
+				continue
+			}
+		}
+
+		s.lead(ctx, leadership)
+		if ctx.Err() != nil {
+			return
+		}
+		s.logger.Warn("联邦同步器领导权丢失，重新参与选举")
+	}
+}
+
+// lead 作为领导者立即同步一次，之后按间隔同步，直到领导权丢失或同步器停止
+func (s *FederationSyncer) lead(ctx context.Context, leadership *etcdclient.Leadership) {
+	leadCtx, cancelLead := context.WithCancel(ctx)
+	defer cancelLead()
+	go func() {
+		select {
+		case <-leadership.Done():
+			cancelLead()
+		case <-leadCtx.Done():
+		}
+	}()
+
+	defer func() {
+		resignCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		// 领导权已丢失时放弃失败是预期的，只在停止时记录
+		if err := leadership.Resign(resignCtx); err != nil && ctx.Err() != nil {
+			s.logger.Warn("放弃联邦同步器领导权失败", zap.Error(err))
+		}
+		cancel()
+	}()
+
+	s.logger.Info("成为联邦同步器领导者")
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		s.syncOnce(leadCtx)
+
+		select {
+		case <-leadCtx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// syncOnce 拉取所有pull方式的远程集群目录，并向push_to中的集群推送本集群目录
+func (s *FederationSyncer) syncOnce(ctx context.Context) {
+	for _, peer := range s.cfg.Federation.Peers {
+		if ctx.Err() != nil {
+			return
+		}
+		if peerMode(peer) == etcdclient.FederationPull {
+			s.pullPeer(ctx, peer)
+		}
+	}
+
+	if len(s.cfg.Federation.PushTo) == 0 || ctx.Err() != nil {
+		return
+	}
+	catalog, err := s.client.ExportCatalog(ctx, s.cfg.Federation.ClusterName)
+	if err != nil {
+		s.logger.Error("导出本集群目录失败，跳过本轮推送", zap.Error(err))
+		return
+	}
+	data, err := json.Marshal(catalog)
+	if err != nil {
+		s.logger.Error("序列化本集群目录失败", zap.Error(err))
+		return
+	}
+	for _, target := range s.cfg.Federation.PushTo {
+		if ctx.Err() != nil {
+			return
+		}
+		if err := s.push(ctx, target, data); err != nil {
+			s.logger.Warn("推送目录失败",
+				zap.String("target", target.Name),
+				zap.String("url", target.URL),
+				zap.Error(err))
+		}
+	}
+}
+
+// pullPeer 拉取远程集群的目录并记录链路状态
+func (s *FederationSyncer) pullPeer(ctx context.Context, peer config.FederationPeer) {
+	catalog, err := s.pull(ctx, peer)
+	if err == nil {
+		var link *etcdclient.FederationLink
+		link, err = s.client.ApplyCatalog(ctx, catalog, etcdclient.FederationPull, peer.URL)
+		if err == nil {
+			s.logger.Debug("已拉取远程集群目录",
+				zap.String("cluster", peer.Name),
+				zap.Int64("revision", link.Revision),
+				zap.Int("services", link.Services))
+			return
+		}
+	}
+	if ctx.Err() != nil {
+		return
+	}
+
+	s.logger.Warn("拉取远程集群目录失败", zap.String("cluster", peer.Name), zap.String("url", peer.URL), zap.Error(err))
+	if _, recordErr := s.client.RecordFederationFailure(ctx, peer.Name, etcdclient.FederationPull, peer.URL, err); recordErr != nil {
+		s.logger.Warn("记录联邦链路状态失败", zap.String("cluster", peer.Name), zap.Error(recordErr))
+	}
+}
+
+// pull 从远程集群的管理API获取目录，目录声明的集群名称必须与配置一致
+func (s *FederationSyncer) pull(ctx context.Context, peer config.FederationPeer) (*etcdclient.Catalog, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(peer.URL, "/")+CatalogPath, nil)
+	if err != nil {
+		return nil, fmt.Errorf("创建请求失败: %w", err)
+	}
+	setToken(req, peer.Token)
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("请求失败: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, responseError(resp)
+	}
+
+	var catalog etcdclient.Catalog
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxCatalogSize)).Decode(&catalog); err != nil {
+		return nil, fmt.Errorf("解析目录失败: %w", err)
+	}
+	if catalog.Cluster != peer.Name {
+		return nil, fmt.Errorf("目录来自集群 %q，与配置的集群名称 %q 不一致", catalog.Cluster, peer.Name)
+	}
+	return &catalog, nil
+}
+
+// push 把本集群的目录推送到远程集群
+func (s *FederationSyncer) push(ctx context.Context, target config.FederationPeer, data []byte) error {
+	endpoint := strings.TrimSuffix(target.URL, "/") + PushPath(s.cfg.Federation.ClusterName)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("创建请求失败: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	setToken(req, target.Token)
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("请求失败: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return responseError(resp)
+	}
+	return nil
+}
+
+// setToken 设置链路的共享令牌
+func setToken(req *http.Request, token string) {
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+}
+
+// responseError 把远程集群的非成功响应转为错误
+func responseError(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+	return fmt.Errorf("远程集群返回状态码%d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+}
@@ -0,0 +1,202 @@
+package federation
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hewenyu/kong-discovery/internal/config"
+	"github.com/hewenyu/kong-discovery/internal/etcdclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// 创建测试用的配置
+func createTestConfig(t *testing.T) *config.Config {
+	t.Helper()
+
+	cfg := &config.Config{}
+	etcdclient.ApplyTestStorage(cfg)
+	cfg.Federation.Enabled = true
+	cfg.Federation.ClusterName = "cluster-a"
+	cfg.Federation.Interval = 1
+	cfg.Federation.Timeout = 2
+	return cfg
+}
+
+// 创建测试用的日志记录器
+func createTestLogger(t *testing.T) config.Logger {
+	t.Helper()
+
+	logger, err := config.NewLogger(true)
+	require.NoError(t, err, "创建测试日志记录器失败")
+	return logger
+}
+
+func TestValidate(t *testing.T) {
+	peer := func(name, url, mode string) config.FederationPeer {
+		return config.FederationPeer{Name: name, URL: url, Mode: mode}
+	}
+	tests := []struct {
+		name    string
+		modify  func(cfg *config.Config)
+		wantErr string
+	}{
+		{"有效配置", func(cfg *config.Config) {
+			cfg.Federation.Peers = []config.FederationPeer{peer("cluster-b", "http://b:8080", ""), peer("cluster-c", "", "push")}
+		}, ""},
+		{"缺少本集群名称", func(cfg *config.Config) { cfg.Federation.ClusterName = "" }, "cluster_name"},
+		{"无效的冲突策略", func(cfg *config.Config) { cfg.Federation.Conflict = "merge" }, "conflict"},
+		{"与本集群同名", func(cfg *config.Config) {
+			cfg.Federation.Peers = []config.FederationPeer{peer("cluster-a", "http://a:8080", "pull")}
+		}, "重名"},
+		{"远程集群重名", func(cfg *config.Config) {
+			cfg.Federation.Peers = []config.FederationPeer{peer("cluster-b", "", "push"), peer("cluster-b", "", "push")}
+		}, "重名"},
+		{"拉取缺少地址", func(cfg *config.Config) {
+			cfg.Federation.Peers = []config.FederationPeer{peer("cluster-b", "", "pull")}
+		}, "无效的地址"},
+		{"无效的同步方式", func(cfg *config.Config) {
+			cfg.Federation.Peers = []config.FederationPeer{peer("cluster-b", "http://b:8080", "sync")}
+		}, "同步方式"},
+		{"推送目标地址无效", func(cfg *config.Config) {
+			cfg.Federation.PushTo = []config.FederationPeer{peer("cluster-b", "b:8080", "")}
+		}, "push_to"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{}
+			cfg.Federation.ClusterName = "cluster-a"
+			tt.modify(cfg)
+			err := Validate(cfg)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// waitLink 等待远程集群的链路状态满足条件
+func waitLink(t *testing.T, client etcdclient.Client, cluster string, cond func(*etcdclient.FederationLink) bool) *etcdclient.FederationLink {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		links, err := client.ListFederationLinks(context.Background())
+		require.NoError(t, err)
+		for _, link := range links {
+			if link.Cluster == cluster && cond(link) {
+				return link
+			}
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	t.Fatalf("等待集群 %s 的链路状态超时", cluster)
+	return nil
+}
+
+func TestFederationSyncer(t *testing.T) {
+	// 跳过集成测试，除非明确要求运行
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	client := etcdclient.CreateEtcdClientForTest(t)
+	t.Cleanup(func() { _ = client.Close() })
+
+	suffix := time.Now().UnixNano()
+	pulled := fmt.Sprintf("pulled-%d", suffix)
+	failing := fmt.Sprintf("failing-%d", suffix)
+	t.Cleanup(func() {
+		_, _ = client.DeleteFederatedCluster(context.Background(), pulled)
+		_, _ = client.DeleteFederatedCluster(context.Background(), failing)
+	})
+
+	// 远程集群的管理API，校验令牌后返回目录
+	peer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != CatalogPath || r.Header.Get("Authorization") != "Bearer secret" {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(&etcdclient.Catalog{
+			Cluster:     pulled,
+			Revision:    7,
+			GeneratedAt: time.Now(),
+			Services: map[string][]*etcdclient.ServiceInstance{
+				"orders": {{ServiceName: "orders", InstanceID: "orders-1", IPAddress: "192.168.1.10", Port: 9000}},
+			},
+		})
+	}))
+	defer peer.Close()
+
+	broken := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer broken.Close()
+
+	// 接收本集群推送的目录
+	var mu sync.Mutex
+	var pushed []*etcdclient.Catalog
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != PushPath("cluster-a") {
+			http.NotFound(w, r)
+			return
+		}
+		var catalog etcdclient.Catalog
+		if err := json.NewDecoder(r.Body).Decode(&catalog); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		pushed = append(pushed, &catalog)
+		mu.Unlock()
+	}))
+	defer target.Close()
+
+	cfg := createTestConfig(t)
+	cfg.Federation.Peers = []config.FederationPeer{
+		{Name: pulled, URL: peer.URL, Mode: etcdclient.FederationPull, Token: "secret"},
+		{Name: failing, URL: broken.URL},
+	}
+	cfg.Federation.PushTo = []config.FederationPeer{{Name: "cluster-z", URL: target.URL}}
+
+	syncer, err := NewSyncer(cfg, createTestLogger(t), client)
+	require.NoError(t, err)
+	require.NoError(t, syncer.Start())
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		require.NoError(t, syncer.Stop(ctx))
+	}()
+
+	link := waitLink(t, client, pulled, func(l *etcdclient.FederationLink) bool { return !l.LastSuccess.IsZero() })
+	assert.Equal(t, etcdclient.FederationPull, link.Mode)
+	assert.Equal(t, int64(7), link.Revision)
+	assert.Equal(t, 1, link.Services)
+
+	records, err := client.FederatedServiceToDNSRecords(context.Background(), pulled, "orders."+pulled+".svc.cluster.local")
+	require.NoError(t, err)
+	assert.Equal(t, "192.168.1.10", records["A"].Value)
+
+	// 拉取失败记录在链路状态中
+	link = waitLink(t, client, failing, func(l *etcdclient.FederationLink) bool { return l.ConsecutiveFailures > 0 })
+	assert.Contains(t, link.LastError, "503")
+	assert.Equal(t, etcdclient.LinkStale, link.State(time.Now(), time.Minute))
+
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(pushed) > 0
+	}, 5*time.Second, 100*time.Millisecond)
+	mu.Lock()
+	assert.Equal(t, "cluster-a", pushed[0].Cluster)
+	mu.Unlock()
+}
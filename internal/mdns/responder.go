@@ -0,0 +1,486 @@
+package mdns
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hewenyu/kong-discovery/internal/config"
+	"github.com/hewenyu/kong-discovery/internal/etcdclient"
+	"github.com/miekg/dns"
+	"go.uber.org/zap"
+)
+
+// mDNS多播地址和端口
+var (
+	ipv4Group = &net.UDPAddr{IP: net.ParseIP("224.0.0.251"), Port: 5353}
+	ipv6Group = &net.UDPAddr{IP: net.ParseIP("ff02::fb"), Port: 5353}
+)
+
+const (
+	// servicesName DNS-SD服务类型枚举名称
+	servicesName = "_services._dns-sd._udp.local."
+
+	// recordTTL 通告记录的TTL（秒），与常见mDNS实现的主机记录一致
+	recordTTL = 120
+	// legacyTTL 非5353端口的单播查询（传统DNS客户端）应答的最大TTL（秒）
+	legacyTTL = 10
+
+	// cacheFlush 唯一记录的cache-flush位，告知接收方替换缓存中的同名记录
+	cacheFlush = 1 << 15
+	// unicastResponse 查询中要求单播应答的QU位
+	unicastResponse = 1 << 15
+
+	defaultRefreshInterval = 10 * time.Second
+)
+
+// Responder 定义mDNS响应器接口
+type Responder interface {
+	// Start 启动响应器（非阻塞）
+	Start() error
+
+	// Stop 停止响应器，发送记录撤销通告
+	Stop(ctx context.Context) error
+}
+
+// ServiceResponder 通过多播DNS在本地网段通告已注册的服务（DNS-SD）
+// 每个服务通告为 _<服务名>._tcp.local，实例为 <实例ID>._<服务名>._tcp.local，
+// SRV记录指向 <实例ID>.local 并附带A/AAAA记录，TXT记录为实例的元数据
+type ServiceResponder struct {
+	cfg      *config.Config
+	logger   config.Logger
+	client   etcdclient.Client
+	interval time.Duration
+
+	mu      sync.RWMutex
+	records map[string][]dns.RR // 按小写名称索引的记录
+	conns   []*groupConn
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// groupConn 加入多播组的连接
+type groupConn struct {
+	*net.UDPConn
+	group *net.UDPAddr
+}
+
+// NewResponder 创建mDNS响应器
+func NewResponder(cfg *config.Config, logger config.Logger, client etcdclient.Client) Responder {
+	interval := defaultRefreshInterval
+	if cfg.MDNS.RefreshInterval > 0 {
+		interval = time.Duration(cfg.MDNS.RefreshInterval) * time.Second
+	}
+	return &ServiceResponder{
+		cfg:      cfg,
+		logger:   logger,
+		client:   client,
+		interval: interval,
+		records:  make(map[string][]dns.RR),
+	}
+}
+
+// Start 加入mDNS多播组并开始应答查询
+func (r *ServiceResponder) Start() error {
+	if r.done != nil {
+		return fmt.Errorf("mDNS响应器已启动")
+	}
+
+	var iface *net.Interface
+	if name := r.cfg.MDNS.Interface; name != "" {
+		var err error
+		if iface, err = net.InterfaceByName(name); err != nil {
+			return fmt.Errorf("查找网络接口 %s 失败: %w", name, err)
+		}
+	}
+
+	conn, err := net.ListenMulticastUDP("udp4", iface, ipv4Group)
+	if err != nil {
+		return fmt.Errorf("加入mDNS多播组失败: %w", err)
+	}
+	r.conns = append(r.conns, &groupConn{UDPConn: conn, group: ipv4Group})
+	if conn6, err := net.ListenMulticastUDP("udp6", iface, ipv6Group); err != nil {
+		r.logger.Warn("加入IPv6 mDNS多播组失败，只在IPv4上通告", zap.Error(err))
+	} else {
+		r.conns = append(r.conns, &groupConn{UDPConn: conn6, group: ipv6Group})
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r.cancel = cancel
+	r.done = make(chan struct{})
+
+	r.logger.Info("启动mDNS响应器",
+		zap.String("interface", r.cfg.MDNS.Interface),
+		zap.Duration("refresh_interval", r.interval))
+
+	for _, c := range r.conns {
+		go r.serve(c)
+	}
+	go r.run(ctx)
+	return nil
+}
+
+// Stop 停止响应器，撤销已通告的记录
+func (r *ServiceResponder) Stop(ctx context.Context) error {
+	if r.done == nil {
+		return nil
+	}
+
+	r.cancel()
+	select {
+	case <-r.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	r.mu.RLock()
+	r.announce(instancePointers(r.records), 0)
+	r.mu.RUnlock()
+	for _, c := range r.conns {
+		c.Close()
+	}
+	return nil
+}
+
+// run 定期从etcd刷新服务实例，通告新增的实例并撤销已删除的实例
+func (r *ServiceResponder) run(ctx context.Context) {
+	defer close(r.done)
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		r.refresh(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// refresh 重新生成记录，并对变化的实例发送通告
+func (r *ServiceResponder) refresh(ctx context.Context) {
+	instances, err := r.routableInstances(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			r.logger.Warn("刷新mDNS通告的服务实例失败", zap.Error(err))
+		}
+		return
+	}
+	records := buildRecords(instances)
+
+	r.mu.Lock()
+	previous := r.records
+	r.records = records
+	r.mu.Unlock()
+
+	added := diffPointers(instancePointers(records), instancePointers(previous))
+	removed := diffPointers(instancePointers(previous), instancePointers(records))
+	if len(added) > 0 {
+		r.mu.RLock()
+		r.announce(added, recordTTL)
+		r.mu.RUnlock()
+	}
+	if len(removed) > 0 {
+		r.announce(removed, 0)
+	}
+}
+
+// routableInstances 查询所有可参与应答的服务实例
+// 设置了解析ACL的服务不通过mDNS通告：组播查询无法识别客户端，通告会绕过ACL
+func (r *ServiceResponder) routableInstances(ctx context.Context) ([]*etcdclient.ServiceInstance, error) {
+	services, err := r.client.ListServices(ctx)
+	if err != nil {
+		return nil, err
+	}
+	acls, err := r.client.ListServiceACLs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	restricted := make(map[string]bool, len(acls))
+	for _, acl := range acls {
+		restricted[acl.ServiceName] = true
+	}
+
+	// 不同命名空间中的同名服务分别列出，实例只查询一次
+	var result []*etcdclient.ServiceInstance
+	queried := make(map[string]bool, len(services))
+	for _, service := range services {
+		if service.Routable == 0 || restricted[service.ServiceName] || queried[service.ServiceName] {
+			continue
+		}
+		queried[service.ServiceName] = true
+		instances, err := r.client.GetServiceInstances(ctx, service.ServiceName)
+		if err != nil {
+			return nil, err
+		}
+		for _, instance := range instances {
+			if instance.Routable() {
+				result = append(result, instance)
+			}
+		}
+	}
+	return result, nil
+}
+
+// serviceType 返回服务的DNS-SD服务类型名称
+func serviceType(serviceName string) string {
+	return "_" + serviceName + "._tcp.local."
+}
+
+// instanceName 返回实例的DNS-SD实例名称
+func instanceName(instance *etcdclient.ServiceInstance) string {
+	return instance.InstanceID + "." + serviceType(instance.ServiceName)
+}
+
+// hostName 返回实例的主机名
+func hostName(instance *etcdclient.ServiceInstance) string {
+	return instance.InstanceID + ".local."
+}
+
+// buildRecords 生成所有服务实例的PTR、SRV、TXT和A/AAAA记录
+func buildRecords(instances []*etcdclient.ServiceInstance) map[string][]dns.RR {
+	records := make(map[string][]dns.RR)
+	add := func(rr dns.RR) {
+		name := strings.ToLower(rr.Header().Name)
+		records[name] = append(records[name], rr)
+	}
+	header := func(name string, rrtype uint16, unique bool) dns.RR_Header {
+		class := uint16(dns.ClassINET)
+		if unique {
+			class |= cacheFlush
+		}
+		return dns.RR_Header{Name: name, Rrtype: rrtype, Class: class, Ttl: recordTTL}
+	}
+
+	instances = append([]*etcdclient.ServiceInstance(nil), instances...)
+	sort.Slice(instances, func(i, j int) bool {
+		if instances[i].ServiceName != instances[j].ServiceName {
+			return instances[i].ServiceName < instances[j].ServiceName
+		}
+		return instances[i].InstanceID < instances[j].InstanceID
+	})
+
+	services := make(map[string]bool)
+	for _, instance := range instances {
+		ip := net.ParseIP(instance.IPAddress)
+		if ip == nil {
+			continue
+		}
+		svcType := serviceType(instance.ServiceName)
+		name := instanceName(instance)
+		host := hostName(instance)
+
+		if !services[svcType] {
+			services[svcType] = true
+			add(&dns.PTR{Hdr: header(servicesName, dns.TypePTR, false), Ptr: svcType})
+		}
+		add(&dns.PTR{Hdr: header(svcType, dns.TypePTR, false), Ptr: name})
+		add(&dns.SRV{Hdr: header(name, dns.TypeSRV, true), Target: host, Port: uint16(instance.Port)})
+		add(&dns.TXT{Hdr: header(name, dns.TypeTXT, true), Txt: txtRecord(instance.Metadata)})
+		if ip4 := ip.To4(); ip4 != nil {
+			add(&dns.A{Hdr: header(host, dns.TypeA, true), A: ip4})
+		} else {
+			add(&dns.AAAA{Hdr: header(host, dns.TypeAAAA, true), AAAA: ip})
+		}
+	}
+	return records
+}
+
+// txtRecord 把元数据转换为按键排序的 key=value 字符串，没有元数据时返回一个空字符串
+func txtRecord(metadata map[string]string) []string {
+	if len(metadata) == 0 {
+		return []string{""}
+	}
+	txt := make([]string, 0, len(metadata))
+	for key, value := range metadata {
+		txt = append(txt, key+"="+value)
+	}
+	sort.Strings(txt)
+	return txt
+}
+
+// instancePointers 返回所有服务类型到实例的PTR记录
+func instancePointers(records map[string][]dns.RR) []dns.RR {
+	var pointers []dns.RR
+	for _, rr := range records[servicesName] {
+		pointers = append(pointers, records[strings.ToLower(rr.(*dns.PTR).Ptr)]...)
+	}
+	return pointers
+}
+
+// diffPointers 返回在a中但不在b中的PTR记录
+func diffPointers(a, b []dns.RR) []dns.RR {
+	existing := make(map[string]bool, len(b))
+	for _, rr := range b {
+		existing[rr.String()] = true
+	}
+	var diff []dns.RR
+	for _, rr := range a {
+		if !existing[rr.String()] {
+			diff = append(diff, rr)
+		}
+	}
+	return diff
+}
+
+// lookup 返回与问题匹配的记录和附加记录，调用方需持有读锁
+func (r *ServiceResponder) lookup(q dns.Question) (answers, extra []dns.RR) {
+	for _, rr := range r.records[strings.ToLower(q.Name)] {
+		if q.Qtype == dns.TypeANY || rr.Header().Rrtype == q.Qtype {
+			answers = append(answers, rr)
+		}
+	}
+	extra = r.additional(answers)
+	return answers, extra
+}
+
+// additional 返回应答记录的附加记录：PTR附带目标的SRV和TXT，SRV附带目标主机的地址
+func (r *ServiceResponder) additional(answers []dns.RR) []dns.RR {
+	var extra []dns.RR
+	seen := make(map[string]bool)
+	var addName func(name string)
+	addName = func(name string) {
+		name = strings.ToLower(name)
+		if seen[name] {
+			return
+		}
+		seen[name] = true
+		for _, rr := range r.records[name] {
+			extra = append(extra, rr)
+			if srv, ok := rr.(*dns.SRV); ok {
+				addName(srv.Target)
+			}
+		}
+	}
+	for _, rr := range answers {
+		switch record := rr.(type) {
+		case *dns.PTR:
+			if strings.ToLower(record.Hdr.Name) != servicesName {
+				addName(record.Ptr)
+			}
+		case *dns.SRV:
+			addName(record.Target)
+		}
+	}
+	return extra
+}
+
+// serve 读取查询并应答，直到连接关闭
+func (r *ServiceResponder) serve(conn *groupConn) {
+	buf := make([]byte, dns.MaxMsgSize)
+	for {
+		n, src, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			return
+		}
+
+		req := new(dns.Msg)
+		if err := req.Unpack(buf[:n]); err != nil || req.Response || req.Opcode != dns.OpcodeQuery {
+			continue
+		}
+
+		resp, unicast := r.respond(req, src.Port != conn.group.Port)
+		if resp == nil {
+			continue
+		}
+		out, err := resp.Pack()
+		if err != nil {
+			r.logger.Warn("打包mDNS应答失败", zap.Error(err))
+			continue
+		}
+
+		dst := conn.group
+		if unicast {
+			dst = src
+		}
+		if _, err := conn.WriteToUDP(out, dst); err != nil {
+			r.logger.Debug("发送mDNS应答失败", zap.String("dst", dst.String()), zap.Error(err))
+		}
+	}
+}
+
+// respond 生成查询的应答，返回是否应单播发送；没有匹配的记录时返回nil
+// legacy表示查询来自非5353端口的传统DNS客户端，按普通DNS应答的格式回复
+func (r *ServiceResponder) respond(req *dns.Msg, legacy bool) (*dns.Msg, bool) {
+	resp := new(dns.Msg)
+	resp.Response = true
+	resp.Authoritative = true
+
+	unicast := legacy
+	r.mu.RLock()
+	for _, q := range req.Question {
+		if q.Qclass&unicastResponse != 0 {
+			unicast = true
+		}
+		q.Qclass &^= unicastResponse
+		answers, extra := r.lookup(q)
+		resp.Answer = append(resp.Answer, answers...)
+		resp.Extra = append(resp.Extra, extra...)
+	}
+	r.mu.RUnlock()
+
+	if len(resp.Answer) == 0 {
+		return nil, false
+	}
+
+	if legacy {
+		// 传统客户端需要原样返回ID和问题，并且不能使用cache-flush位
+		resp.Id = req.Id
+		resp.Question = req.Question
+		resp.Answer = legacyRecords(resp.Answer)
+		resp.Extra = legacyRecords(resp.Extra)
+	}
+	return resp, unicast
+}
+
+// legacyRecords 复制记录，去掉cache-flush位并限制TTL
+func legacyRecords(records []dns.RR) []dns.RR {
+	result := make([]dns.RR, 0, len(records))
+	for _, rr := range records {
+		rr = dns.Copy(rr)
+		rr.Header().Class &^= cacheFlush
+		if rr.Header().Ttl > legacyTTL {
+			rr.Header().Ttl = legacyTTL
+		}
+		result = append(result, rr)
+	}
+	return result
+}
+
+// announce 多播发送实例的通告，ttl为0时表示撤销（goodbye）
+// pointers为服务类型到实例的PTR记录，撤销时只发送PTR记录
+func (r *ServiceResponder) announce(pointers []dns.RR, ttl uint32) {
+	if len(pointers) == 0 {
+		return
+	}
+
+	msg := new(dns.Msg)
+	msg.Response = true
+	msg.Authoritative = true
+	for _, rr := range pointers {
+		rr = dns.Copy(rr)
+		rr.Header().Ttl = ttl
+		msg.Answer = append(msg.Answer, rr)
+	}
+	if ttl > 0 {
+		msg.Extra = r.additional(pointers)
+	}
+
+	out, err := msg.Pack()
+	if err != nil {
+		r.logger.Warn("打包mDNS通告失败", zap.Error(err))
+		return
+	}
+	for _, conn := range r.conns {
+		if _, err := conn.WriteToUDP(out, conn.group); err != nil {
+			r.logger.Debug("发送mDNS通告失败", zap.String("dst", conn.group.String()), zap.Error(err))
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package mdns
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/hewenyu/kong-discovery/internal/config"
+	"github.com/hewenyu/kong-discovery/internal/etcdclient"
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// 创建测试用的日志记录器
+func createTestLogger(t *testing.T) config.Logger {
+	t.Helper()
+
+	logger, err := config.NewLogger(true)
+	require.NoError(t, err, "创建测试日志记录器失败")
+	return logger
+}
+
+// newTestResponder 创建使用给定实例记录的响应器，不加入多播组
+func newTestResponder(t *testing.T, instances ...*etcdclient.ServiceInstance) *ServiceResponder {
+	t.Helper()
+
+	r := NewResponder(&config.Config{}, createTestLogger(t), nil).(*ServiceResponder)
+	r.records = buildRecords(instances)
+	return r
+}
+
+func testInstances() []*etcdclient.ServiceInstance {
+	return []*etcdclient.ServiceInstance{
+		{ServiceName: "web", InstanceID: "web-1", IPAddress: "192.168.1.10", Port: 8080, Metadata: map[string]string{"version": "1.0", "env": "dev"}},
+		{ServiceName: "web", InstanceID: "web-2", IPAddress: "fd00::2", Port: 8081},
+		{ServiceName: "api", InstanceID: "api-1", IPAddress: "192.168.1.20", Port: 9000},
+	}
+}
+
+func TestRespond_ServiceEnumeration(t *testing.T) {
+	r := newTestResponder(t, testInstances()...)
+
+	req := new(dns.Msg)
+	req.SetQuestion(servicesName, dns.TypePTR)
+	resp, unicast := r.respond(req, false)
+	require.NotNil(t, resp)
+	assert.False(t, unicast)
+
+	var types []string
+	for _, rr := range resp.Answer {
+		types = append(types, rr.(*dns.PTR).Ptr)
+	}
+	assert.Equal(t, []string{"_api._tcp.local.", "_web._tcp.local."}, types)
+	// 服务类型枚举不附带实例记录
+	assert.Empty(t, resp.Extra)
+}
+
+func TestRespond_BrowseService(t *testing.T) {
+	r := newTestResponder(t, testInstances()...)
+
+	req := new(dns.Msg)
+	req.SetQuestion("_web._tcp.local.", dns.TypePTR)
+	resp, _ := r.respond(req, false)
+	require.NotNil(t, resp)
+	assert.True(t, resp.Authoritative)
+	assert.Zero(t, resp.Id, "多播应答的ID应为0")
+
+	require.Len(t, resp.Answer, 2)
+	assert.Equal(t, "web-1._web._tcp.local.", resp.Answer[0].(*dns.PTR).Ptr)
+	assert.Equal(t, "web-2._web._tcp.local.", resp.Answer[1].(*dns.PTR).Ptr)
+
+	// 附加段包含每个实例的SRV、TXT以及主机地址
+	extra := make(map[string]dns.RR)
+	for _, rr := range resp.Extra {
+		extra[dns.TypeToString[rr.Header().Rrtype]+" "+rr.Header().Name] = rr
+	}
+	srv := extra["SRV web-1._web._tcp.local."].(*dns.SRV)
+	assert.Equal(t, "web-1.local.", srv.Target)
+	assert.Equal(t, uint16(8080), srv.Port)
+	assert.Equal(t, uint16(dns.ClassINET|cacheFlush), srv.Hdr.Class)
+	assert.Equal(t, []string{"env=dev", "version=1.0"}, extra["TXT web-1._web._tcp.local."].(*dns.TXT).Txt)
+	assert.Equal(t, []string{""}, extra["TXT web-2._web._tcp.local."].(*dns.TXT).Txt)
+	assert.Equal(t, "192.168.1.10", extra["A web-1.local."].(*dns.A).A.String())
+	assert.Equal(t, "fd00::2", extra["AAAA web-2.local."].(*dns.AAAA).AAAA.String())
+}
+
+func TestRespond_UnicastAndLegacy(t *testing.T) {
+	r := newTestResponder(t, testInstances()...)
+
+	// QU位要求单播应答
+	req := new(dns.Msg)
+	req.SetQuestion("web-1.local.", dns.TypeA)
+	req.Question[0].Qclass |= unicastResponse
+	resp, unicast := r.respond(req, false)
+	require.NotNil(t, resp)
+	assert.True(t, unicast)
+	require.Len(t, resp.Answer, 1)
+
+	// 传统DNS客户端：返回ID和问题，去掉cache-flush位并限制TTL
+	req = new(dns.Msg)
+	req.SetQuestion("web-1._web._tcp.local.", dns.TypeSRV)
+	resp, unicast = r.respond(req, true)
+	require.NotNil(t, resp)
+	assert.True(t, unicast)
+	assert.Equal(t, req.Id, resp.Id)
+	assert.Equal(t, req.Question, resp.Question)
+	require.Len(t, resp.Answer, 1)
+	assert.Equal(t, uint16(dns.ClassINET), resp.Answer[0].Header().Class)
+	assert.Equal(t, uint32(legacyTTL), resp.Answer[0].Header().Ttl)
+	// 原记录不受影响
+	assert.Equal(t, uint32(recordTTL), r.records["web-1._web._tcp.local."][0].Header().Ttl)
+
+	// 不认识的名称不应答
+	req = new(dns.Msg)
+	req.SetQuestion("unknown.local.", dns.TypeA)
+	resp, _ = r.respond(req, false)
+	assert.Nil(t, resp)
+}
+
+func TestServe_LegacyUnicastQuery(t *testing.T) {
+	r := newTestResponder(t, testInstances()...)
+
+	// 使用回环地址上的普通UDP连接代替多播连接
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+	defer conn.Close()
+	go r.serve(&groupConn{UDPConn: conn, group: ipv4Group})
+
+	req := new(dns.Msg)
+	req.SetQuestion("api-1.local.", dns.TypeA)
+	resp, _, err := new(dns.Client).Exchange(req, conn.LocalAddr().String())
+	require.NoError(t, err)
+	require.Len(t, resp.Answer, 1)
+	assert.Equal(t, "192.168.1.20", resp.Answer[0].(*dns.A).A.String())
+}
+
+func TestDiffPointers(t *testing.T) {
+	instances := testInstances()
+	before := instancePointers(buildRecords(instances))
+	after := instancePointers(buildRecords(instances[1:]))
+
+	removed := diffPointers(before, after)
+	require.Len(t, removed, 1)
+	assert.Equal(t, "web-1._web._tcp.local.", removed[0].(*dns.PTR).Ptr)
+	assert.Empty(t, diffPointers(after, before))
+}
+
+func TestServiceResponder_Refresh(t *testing.T) {
+	// 跳过集成测试，除非明确要求运行
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	client := etcdclient.CreateEtcdClientForTest(t)
+	t.Cleanup(func() { _ = client.Close() })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	serviceName := fmt.Sprintf("mdns-test-%d", time.Now().UnixNano())
+	instance := &etcdclient.ServiceInstance{
+		ServiceName: serviceName,
+		InstanceID:  "mdns-1",
+		IPAddress:   "10.0.0.8",
+		Port:        8080,
+		TTL:         60,
+	}
+	require.NoError(t, client.RegisterService(ctx, instance))
+	t.Cleanup(func() { _ = client.DeregisterService(context.Background(), serviceName, instance.InstanceID) })
+
+	r := NewResponder(&config.Config{}, createTestLogger(t), client).(*ServiceResponder)
+	r.refresh(ctx)
+
+	req := new(dns.Msg)
+	req.SetQuestion(serviceType(serviceName), dns.TypePTR)
+	resp, _ := r.respond(req, false)
+	require.NotNil(t, resp)
+	require.Len(t, resp.Answer, 1)
+	assert.Equal(t, "mdns-1."+serviceType(serviceName), resp.Answer[0].(*dns.PTR).Ptr)
+}
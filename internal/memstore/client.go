@@ -0,0 +1,38 @@
+package memstore
+
+import (
+	"context"
+
+	"github.com/hewenyu/kong-discovery/internal/etcdapi"
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// Version 状态查询中返回的版本号，用于区分内存存储和etcd
+const Version = "memory"
+
+// Status 返回存储的状态
+func (s *Store) Status(context.Context) (*pb.StatusResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var size int64
+	for _, kv := range s.kvs {
+		size += int64(len(kv.Key) + len(kv.Value))
+	}
+	return &pb.StatusResponse{
+		Header:           s.header(),
+		Version:          Version,
+		DbSize:           size,
+		DbSizeInUse:      size,
+		Leader:           etcdapi.MemberID,
+		RaftIndex:        uint64(s.revision),
+		RaftTerm:         1,
+		RaftAppliedIndex: uint64(s.revision),
+	}, nil
+}
+
+// NewClient 创建使用该存储的etcd客户端，关闭客户端不影响存储中的数据和其他客户端
+func (s *Store) NewClient() *clientv3.Client {
+	return etcdapi.NewClient(s)
+}
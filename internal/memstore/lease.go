@@ -0,0 +1,166 @@
+package memstore
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/hewenyu/kong-discovery/internal/etcdapi"
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+	"google.golang.org/grpc"
+)
+
+// lease 租约，到期后删除关联的所有key
+type lease struct {
+	id     int64
+	ttl    int64
+	expiry time.Time
+	timer  *time.Timer
+	keys   map[string]struct{}
+}
+
+// refresh 续约：从现在起重新计算到期时间
+func (l *lease) refresh() {
+	d := time.Duration(l.ttl) * time.Second
+	l.expiry = time.Now().Add(d)
+	l.timer.Reset(d)
+}
+
+// attach 把键值关联到其租约，调用方需持有锁
+func (s *Store) attach(kv *mvccpb.KeyValue) {
+	if l := s.leases[kv.Lease]; kv.Lease != 0 && l != nil {
+		l.keys[string(kv.Key)] = struct{}{}
+	}
+}
+
+// detach 解除键值与其租约的关联，调用方需持有锁
+func (s *Store) detach(kv *mvccpb.KeyValue) {
+	if l := s.leases[kv.Lease]; kv.Lease != 0 && l != nil {
+		delete(l.keys, string(kv.Key))
+	}
+}
+
+// LeaseGrant 创建租约，未指定ID时自动分配
+func (s *Store) LeaseGrant(_ context.Context, in *pb.LeaseGrantRequest, _ ...grpc.CallOption) (*pb.LeaseGrantResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := in.ID
+	if id == 0 {
+		for id == 0 || s.leases[id] != nil {
+			s.nextLease++
+			id = s.nextLease
+		}
+	} else if s.leases[id] != nil {
+		return nil, rpctypes.ErrGRPCLeaseExist
+	}
+
+	l := &lease{id: id, ttl: max(in.TTL, etcdapi.MinLeaseTTL), keys: make(map[string]struct{})}
+	l.timer = time.AfterFunc(time.Duration(l.ttl)*time.Second, func() { s.expire(id) })
+	l.expiry = time.Now().Add(time.Duration(l.ttl) * time.Second)
+	s.leases[id] = l
+	return &pb.LeaseGrantResponse{Header: s.header(), ID: id, TTL: l.ttl}, nil
+}
+
+// expire 租约的定时器到期，期间续约过的租约不会被删除
+func (s *Store) expire(id int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if l := s.leases[id]; l != nil && !time.Now().Before(l.expiry) {
+		s.revokeLocked(l)
+	}
+}
+
+// revokeLocked 删除租约及其关联的所有key，这些key在同一个修订版本中删除，调用方需持有锁
+func (s *Store) revokeLocked(l *lease) {
+	l.timer.Stop()
+	delete(s.leases, l.id)
+
+	keys := make([]string, 0, len(l.keys))
+	for key := range l.keys {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	w := s.begin()
+	for _, key := range keys {
+		if kv := s.kvs[key]; kv != nil {
+			w.delete(kv)
+		}
+	}
+	w.commit()
+}
+
+// LeaseRevoke 撤销租约
+func (s *Store) LeaseRevoke(_ context.Context, in *pb.LeaseRevokeRequest, _ ...grpc.CallOption) (*pb.LeaseRevokeResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	l := s.leases[in.ID]
+	if l == nil {
+		return nil, rpctypes.ErrGRPCLeaseNotFound
+	}
+	s.revokeLocked(l)
+	return &pb.LeaseRevokeResponse{Header: s.header()}, nil
+}
+
+// LeaseKeepAlive 打开续约流
+func (s *Store) LeaseKeepAlive(ctx context.Context, _ ...grpc.CallOption) (pb.Lease_LeaseKeepAliveClient, error) {
+	return etcdapi.NewKeepAliveStream(ctx, s.keepAlive), nil
+}
+
+// keepAlive 续约，租约不存在时返回TTL为0的响应
+func (s *Store) keepAlive(_ context.Context, id int64) (*pb.LeaseKeepAliveResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	l := s.leases[id]
+	if l == nil {
+		return &pb.LeaseKeepAliveResponse{Header: s.header(), ID: id}, nil
+	}
+	l.refresh()
+	return &pb.LeaseKeepAliveResponse{Header: s.header(), ID: id, TTL: l.ttl}, nil
+}
+
+// LeaseTimeToLive 查询租约的剩余时间，租约不存在时与etcd一样返回TTL为-1的响应
+func (s *Store) LeaseTimeToLive(_ context.Context, in *pb.LeaseTimeToLiveRequest, _ ...grpc.CallOption) (*pb.LeaseTimeToLiveResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	l := s.leases[in.ID]
+	if l == nil {
+		return &pb.LeaseTimeToLiveResponse{Header: s.header(), ID: in.ID, TTL: -1}, nil
+	}
+	resp := &pb.LeaseTimeToLiveResponse{
+		Header:     s.header(),
+		ID:         l.id,
+		TTL:        int64(math.Round(time.Until(l.expiry).Seconds())),
+		GrantedTTL: l.ttl,
+	}
+	if in.Keys {
+		for key := range l.keys {
+			resp.Keys = append(resp.Keys, []byte(key))
+		}
+		slices.SortFunc(resp.Keys, bytes.Compare)
+	}
+	return resp, nil
+}
+
+// LeaseLeases 列出所有租约
+func (s *Store) LeaseLeases(_ context.Context, _ *pb.LeaseLeasesRequest, _ ...grpc.CallOption) (*pb.LeaseLeasesResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	resp := &pb.LeaseLeasesResponse{Header: s.header()}
+	for id := range s.leases {
+		resp.Leases = append(resp.Leases, &pb.LeaseStatus{ID: id})
+	}
+	slices.SortFunc(resp.Leases, func(a, b *pb.LeaseStatus) int { return cmp.Compare(a.ID, b.ID) })
+	return resp, nil
+}
@@ -0,0 +1,342 @@
+// Package memstore 在进程内存中实现etcd的存储接口
+//
+// Store实现了etcd客户端使用的KV、租约和监听的gRPC接口，通过NewClient包装为*clientv3.Client后，
+// 基于etcd客户端编写的代码（事务、租约、领导者选举、监听）无需修改即可运行。
+// 与etcd一样，每次写操作（包括事务）产生一个新的修订版本，保留最近的历史用于按版本读取和监听，
+// 历史超过上限后自动压缩。数据只保存在内存中，进程退出后丢失，用于演示和单元测试
+package memstore
+
+import (
+	"bytes"
+	"context"
+	"maps"
+	"slices"
+	"sort"
+	"sync"
+
+	"github.com/hewenyu/kong-discovery/internal/etcdapi"
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+	"google.golang.org/grpc"
+)
+
+// defaultMaxHistory 保留的历史事件数上限，超过后压缩较早的一半
+const defaultMaxHistory = 10000
+
+// Store 内存中的多版本键值存储，可供多个客户端共享，与连接同一个etcd集群一致
+type Store struct {
+	mu         sync.Mutex
+	revision   int64                       // 当前修订版本
+	compacted  int64                       // 已压缩到的修订版本，更早的版本无法读取和监听
+	kvs        map[string]*mvccpb.KeyValue // 当前的键值，存入后不再修改
+	history    []*mvccpb.Event             // 按修订版本排序的历史事件，总是记录PrevKv以便回溯
+	maxHistory int
+	leases     map[int64]*lease
+	nextLease  int64
+	watchers   map[*etcdapi.Watcher]struct{}
+}
+
+// New 创建空的内存存储
+func New() *Store {
+	return &Store{
+		revision:   1,
+		kvs:        make(map[string]*mvccpb.KeyValue),
+		maxHistory: defaultMaxHistory,
+		leases:     make(map[int64]*lease),
+		watchers:   make(map[*etcdapi.Watcher]struct{}),
+	}
+}
+
+// header 返回当前修订版本的响应头，调用方需持有锁
+func (s *Store) header() *pb.ResponseHeader {
+	return etcdapi.Header(s.revision)
+}
+
+// rangeKVs 返回kvs中在范围内的键值，按key排序
+func rangeKVs(kvs map[string]*mvccpb.KeyValue, r etcdapi.KeyRange) []*mvccpb.KeyValue {
+	if r.Single() {
+		if kv, ok := kvs[string(r.Key)]; ok {
+			return []*mvccpb.KeyValue{kv}
+		}
+		return nil
+	}
+	var result []*mvccpb.KeyValue
+	for _, kv := range kvs {
+		if r.Contains(kv.Key) {
+			result = append(result, kv)
+		}
+	}
+	slices.SortFunc(result, func(a, b *mvccpb.KeyValue) int { return bytes.Compare(a.Key, b.Key) })
+	return result
+}
+
+// kvsAt 返回指定修订版本时的键值，rev不大于0时返回当前键值，调用方需持有锁
+func (s *Store) kvsAt(rev int64) (map[string]*mvccpb.KeyValue, error) {
+	if rev <= 0 || rev == s.revision {
+		return s.kvs, nil
+	}
+	if rev > s.revision {
+		return nil, rpctypes.ErrGRPCFutureRev
+	}
+	if rev < s.compacted {
+		return nil, rpctypes.ErrGRPCCompacted
+	}
+
+	// 从当前状态倒序撤销rev之后的事件
+	kvs := maps.Clone(s.kvs)
+	for i := len(s.history) - 1; i >= 0 && s.history[i].Kv.ModRevision > rev; i-- {
+		event := s.history[i]
+		if event.PrevKv != nil {
+			kvs[string(event.Kv.Key)] = event.PrevKv
+		} else {
+			delete(kvs, string(event.Kv.Key))
+		}
+	}
+	return kvs, nil
+}
+
+// Range 读取键值
+func (s *Store) Range(_ context.Context, in *pb.RangeRequest, _ ...grpc.CallOption) (*pb.RangeResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	resp, err := s.rangeLocked(in)
+	if err != nil {
+		return nil, err
+	}
+	resp.Header = s.header()
+	return resp, nil
+}
+
+// rangeLocked 执行读取，不设置响应头，调用方需持有锁
+func (s *Store) rangeLocked(in *pb.RangeRequest) (*pb.RangeResponse, error) {
+	kvs, err := s.kvsAt(in.Revision)
+	if err != nil {
+		return nil, err
+	}
+	return etcdapi.RangeResponse(in, rangeKVs(kvs, etcdapi.KeyRange{Key: in.Key, End: in.RangeEnd})), nil
+}
+
+// Put 写入键值
+func (s *Store) Put(_ context.Context, in *pb.PutRequest, _ ...grpc.CallOption) (*pb.PutResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	w := s.begin()
+	resp, err := w.put(in)
+	if err != nil {
+		return nil, err
+	}
+	w.commit()
+	resp.Header = s.header()
+	return resp, nil
+}
+
+// DeleteRange 删除范围内的键值
+func (s *Store) DeleteRange(_ context.Context, in *pb.DeleteRangeRequest, _ ...grpc.CallOption) (*pb.DeleteRangeResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	w := s.begin()
+	resp := w.deleteRange(in)
+	w.commit()
+	resp.Header = s.header()
+	return resp, nil
+}
+
+// Txn 执行事务，事务中的所有修改使用同一个修订版本，任一操作失败时撤销全部修改
+func (s *Store) Txn(_ context.Context, in *pb.TxnRequest, _ ...grpc.CallOption) (*pb.TxnResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	w := s.begin()
+	resp, err := w.txn(in)
+	if err != nil {
+		w.rollback()
+		return nil, err
+	}
+	w.commit()
+	etcdapi.SetTxnHeaders(resp, s.header())
+	return resp, nil
+}
+
+// Compact 压缩到指定的修订版本，之后无法读取或监听更早的版本
+func (s *Store) Compact(_ context.Context, in *pb.CompactionRequest, _ ...grpc.CallOption) (*pb.CompactionResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if in.Revision > s.revision {
+		return nil, rpctypes.ErrGRPCFutureRev
+	}
+	if in.Revision <= s.compacted {
+		return nil, rpctypes.ErrGRPCCompacted
+	}
+	s.compactLocked(in.Revision)
+	return &pb.CompactionResponse{Header: s.header()}, nil
+}
+
+// compactLocked 丢弃修订版本早于rev的历史事件，调用方需持有锁
+func (s *Store) compactLocked(rev int64) {
+	i := sort.Search(len(s.history), func(i int) bool { return s.history[i].Kv.ModRevision >= rev })
+	s.history = slices.Clone(s.history[i:])
+	s.compacted = rev
+}
+
+// write 一次写操作（单个请求或事务），其中的所有修改使用同一个修订版本
+type write struct {
+	s      *Store
+	rev    int64
+	events []*mvccpb.Event
+}
+
+// begin 开始一次写操作，调用方需持有锁直到commit或rollback
+func (s *Store) begin() *write {
+	return &write{s: s, rev: s.revision + 1}
+}
+
+// put 写入键值，检查失败时不做任何修改
+func (w *write) put(in *pb.PutRequest) (*pb.PutResponse, error) {
+	s := w.s
+	prev := s.kvs[string(in.Key)]
+	if (in.IgnoreValue || in.IgnoreLease) && prev == nil {
+		return nil, rpctypes.ErrGRPCKeyNotFound
+	}
+
+	kv := &mvccpb.KeyValue{
+		Key:            bytes.Clone(in.Key),
+		Value:          bytes.Clone(in.Value),
+		Lease:          in.Lease,
+		CreateRevision: w.rev,
+		ModRevision:    w.rev,
+		Version:        1,
+	}
+	if in.IgnoreValue {
+		kv.Value = prev.Value
+	}
+	if in.IgnoreLease {
+		kv.Lease = prev.Lease
+	}
+	if kv.Lease != 0 && s.leases[kv.Lease] == nil {
+		return nil, rpctypes.ErrGRPCLeaseNotFound
+	}
+	if prev != nil {
+		kv.CreateRevision = prev.CreateRevision
+		kv.Version = prev.Version + 1
+		s.detach(prev)
+	}
+	s.kvs[string(kv.Key)] = kv
+	s.attach(kv)
+	w.events = append(w.events, &mvccpb.Event{Type: mvccpb.PUT, Kv: kv, PrevKv: prev})
+
+	resp := &pb.PutResponse{}
+	if in.PrevKv {
+		resp.PrevKv = prev
+	}
+	return resp, nil
+}
+
+// deleteRange 删除范围内的键值
+func (w *write) deleteRange(in *pb.DeleteRangeRequest) *pb.DeleteRangeResponse {
+	kvs := rangeKVs(w.s.kvs, etcdapi.KeyRange{Key: in.Key, End: in.RangeEnd})
+	resp := &pb.DeleteRangeResponse{Deleted: int64(len(kvs))}
+	for _, kv := range kvs {
+		w.delete(kv)
+		if in.PrevKv {
+			resp.PrevKvs = append(resp.PrevKvs, kv)
+		}
+	}
+	return resp
+}
+
+// delete 删除一个键值
+func (w *write) delete(prev *mvccpb.KeyValue) {
+	delete(w.s.kvs, string(prev.Key))
+	w.s.detach(prev)
+	w.events = append(w.events, &mvccpb.Event{
+		Type:   mvccpb.DELETE,
+		Kv:     &mvccpb.KeyValue{Key: prev.Key, ModRevision: w.rev},
+		PrevKv: prev,
+	})
+}
+
+// txn 执行事务中的操作，事务中的读取可以看到之前操作的修改
+func (w *write) txn(in *pb.TxnRequest) (*pb.TxnResponse, error) {
+	succeeded := true
+	for _, c := range in.Compare {
+		if !etcdapi.Compare(c, rangeKVs(w.s.kvs, etcdapi.KeyRange{Key: c.Key, End: c.RangeEnd})) {
+			succeeded = false
+			break
+		}
+	}
+	ops := in.Success
+	if !succeeded {
+		ops = in.Failure
+	}
+
+	resp := &pb.TxnResponse{Succeeded: succeeded, Responses: make([]*pb.ResponseOp, 0, len(ops))}
+	for _, op := range ops {
+		var out *pb.ResponseOp
+		switch r := op.Request.(type) {
+		case *pb.RequestOp_RequestRange:
+			rr, err := w.s.rangeLocked(r.RequestRange)
+			if err != nil {
+				return nil, err
+			}
+			out = &pb.ResponseOp{Response: &pb.ResponseOp_ResponseRange{ResponseRange: rr}}
+		case *pb.RequestOp_RequestPut:
+			pr, err := w.put(r.RequestPut)
+			if err != nil {
+				return nil, err
+			}
+			out = &pb.ResponseOp{Response: &pb.ResponseOp_ResponsePut{ResponsePut: pr}}
+		case *pb.RequestOp_RequestDeleteRange:
+			dr := w.deleteRange(r.RequestDeleteRange)
+			out = &pb.ResponseOp{Response: &pb.ResponseOp_ResponseDeleteRange{ResponseDeleteRange: dr}}
+		case *pb.RequestOp_RequestTxn:
+			tr, err := w.txn(r.RequestTxn)
+			if err != nil {
+				return nil, err
+			}
+			out = &pb.ResponseOp{Response: &pb.ResponseOp_ResponseTxn{ResponseTxn: tr}}
+		}
+		resp.Responses = append(resp.Responses, out)
+	}
+	return resp, nil
+}
+
+// commit 提交修改：修订版本加一，记录历史并通知监听者；没有修改时修订版本不变
+func (w *write) commit() {
+	if len(w.events) == 0 {
+		return
+	}
+	s := w.s
+	s.revision = w.rev
+	s.history = append(s.history, w.events...)
+	header := s.header()
+	for watcher := range s.watchers {
+		watcher.Send(header, w.events)
+	}
+	if len(s.history) > s.maxHistory {
+		s.compactLocked(s.history[len(s.history)/2].Kv.ModRevision)
+	}
+}
+
+// rollback 倒序撤销已做的修改
+func (w *write) rollback() {
+	s := w.s
+	for i := len(w.events) - 1; i >= 0; i-- {
+		event := w.events[i]
+		key := string(event.Kv.Key)
+		if current := s.kvs[key]; current != nil {
+			s.detach(current)
+		}
+		if event.PrevKv != nil {
+			s.kvs[key] = event.PrevKv
+			s.attach(event.PrevKv)
+		} else {
+			delete(s.kvs, key)
+		}
+	}
+	w.events = nil
+}
@@ -0,0 +1,290 @@
+package memstore
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hewenyu/kong-discovery/internal/etcdapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/client/v3/concurrency"
+)
+
+// newTestClient 创建使用新存储的客户端
+func newTestClient(t *testing.T) (*Store, *clientv3.Client) {
+	t.Helper()
+	s := New()
+	c := s.NewClient()
+	t.Cleanup(func() { _ = c.Close() })
+	return s, c
+}
+
+func TestStore_KV(t *testing.T) {
+	_, c := newTestClient(t)
+	ctx := context.Background()
+
+	put, err := c.Put(ctx, "/a/1", "v1")
+	require.NoError(t, err)
+	first := put.Header.Revision
+	_, err = c.Put(ctx, "/a/2", "v2")
+	require.NoError(t, err)
+	put, err = c.Put(ctx, "/a/1", "v1b", clientv3.WithPrevKV())
+	require.NoError(t, err)
+	assert.Equal(t, "v1", string(put.PrevKv.Value))
+
+	resp, err := c.Get(ctx, "/a/", clientv3.WithPrefix())
+	require.NoError(t, err)
+	require.Len(t, resp.Kvs, 2)
+	assert.Equal(t, "v1b", string(resp.Kvs[0].Value))
+	assert.Equal(t, first, resp.Kvs[0].CreateRevision)
+	assert.Equal(t, int64(2), resp.Kvs[0].Version)
+	assert.Equal(t, first+2, resp.Header.Revision)
+
+	// 排序、限制数量、只返回key和计数
+	resp, err = c.Get(ctx, "/a/", clientv3.WithPrefix(), clientv3.WithSort(clientv3.SortByModRevision, clientv3.SortDescend), clientv3.WithLimit(1))
+	require.NoError(t, err)
+	require.Len(t, resp.Kvs, 1)
+	assert.Equal(t, "/a/1", string(resp.Kvs[0].Key))
+	assert.True(t, resp.More)
+	resp, err = c.Get(ctx, "/a/", clientv3.WithPrefix(), clientv3.WithKeysOnly())
+	require.NoError(t, err)
+	assert.Empty(t, resp.Kvs[0].Value)
+	resp, err = c.Get(ctx, "/a/", clientv3.WithPrefix(), clientv3.WithCountOnly())
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), resp.Count)
+	assert.Empty(t, resp.Kvs)
+
+	// 按修订版本读取历史
+	resp, err = c.Get(ctx, "/a/1", clientv3.WithRev(first))
+	require.NoError(t, err)
+	assert.Equal(t, "v1", string(resp.Kvs[0].Value))
+
+	del, err := c.Delete(ctx, "/a/", clientv3.WithPrefix())
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), del.Deleted)
+	resp, err = c.Get(ctx, "/a/", clientv3.WithPrefix(), clientv3.WithRev(first+2))
+	require.NoError(t, err)
+	assert.Len(t, resp.Kvs, 2)
+
+	// 压缩后无法读取更早的版本
+	_, err = c.Compact(ctx, first+1)
+	require.NoError(t, err)
+	_, err = c.Get(ctx, "/a/1", clientv3.WithRev(first))
+	assert.ErrorIs(t, err, rpctypes.ErrCompacted)
+}
+
+func TestStore_Txn(t *testing.T) {
+	_, c := newTestClient(t)
+	ctx := context.Background()
+
+	// key不存在时CreateRevision为0
+	txn, err := c.Txn(ctx).
+		If(clientv3.Compare(clientv3.CreateRevision("/lock"), "=", 0)).
+		Then(clientv3.OpPut("/lock", "owner-1"), clientv3.OpPut("/lock-count", "1"), clientv3.OpGet("/lock")).
+		Commit()
+	require.NoError(t, err)
+	require.True(t, txn.Succeeded)
+	assert.Equal(t, "owner-1", string(txn.Responses[2].GetResponseRange().Kvs[0].Value))
+
+	// 事务中的修改使用同一个修订版本
+	resp, err := c.Get(ctx, "/lock", clientv3.WithPrefix())
+	require.NoError(t, err)
+	assert.Equal(t, resp.Kvs[0].ModRevision, resp.Kvs[1].ModRevision)
+	assert.Equal(t, txn.Header.Revision, resp.Header.Revision)
+
+	txn, err = c.Txn(ctx).
+		If(clientv3.Compare(clientv3.CreateRevision("/lock"), "=", 0)).
+		Then(clientv3.OpPut("/lock", "owner-2")).
+		Else(clientv3.OpGet("/lock")).
+		Commit()
+	require.NoError(t, err)
+	assert.False(t, txn.Succeeded)
+	assert.Equal(t, resp.Header.Revision, txn.Header.Revision, "没有修改的事务不增加修订版本")
+
+	// 比较值时key不存在，条件不成立
+	txn, err = c.Txn(ctx).If(clientv3.Compare(clientv3.Value("/missing"), "=", "")).Commit()
+	require.NoError(t, err)
+	assert.False(t, txn.Succeeded)
+
+	// 任一操作失败时撤销事务中的所有修改
+	_, err = c.Txn(ctx).Then(clientv3.OpDelete("/lock"), clientv3.OpPut("/x", "y", clientv3.WithLease(12345))).Commit()
+	assert.ErrorIs(t, err, rpctypes.ErrLeaseNotFound)
+	resp, err = c.Get(ctx, "/lock")
+	require.NoError(t, err)
+	assert.Len(t, resp.Kvs, 1)
+}
+
+func TestStore_Watch(t *testing.T) {
+	_, c := newTestClient(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	put, err := c.Put(ctx, "/w/1", "a")
+	require.NoError(t, err)
+
+	// 从历史版本开始监听，先收到历史事件
+	watchCh := c.Watch(ctx, "/w/", clientv3.WithPrefix(), clientv3.WithRev(put.Header.Revision), clientv3.WithPrevKV())
+	_, err = c.Put(ctx, "/w/1", "b")
+	require.NoError(t, err)
+	_, err = c.Delete(ctx, "/w/1")
+	require.NoError(t, err)
+	_, err = c.Put(ctx, "/other", "x")
+	require.NoError(t, err)
+
+	var events []*clientv3.Event
+	for len(events) < 3 {
+		select {
+		case resp := <-watchCh:
+			require.NoError(t, resp.Err())
+			events = append(events, resp.Events...)
+		case <-ctx.Done():
+			t.Fatal("等待监听事件超时")
+		}
+	}
+	assert.Equal(t, "a", string(events[0].Kv.Value))
+	assert.Equal(t, "b", string(events[1].Kv.Value))
+	assert.Equal(t, "a", string(events[1].PrevKv.Value))
+	assert.Equal(t, mvccpb.DELETE, events[2].Type)
+	assert.Equal(t, "b", string(events[2].PrevKv.Value))
+}
+
+func TestStore_WatchCompacted(t *testing.T) {
+	_, c := newTestClient(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	first, err := c.Put(ctx, "/c", "1")
+	require.NoError(t, err)
+	put, err := c.Put(ctx, "/c", "2")
+	require.NoError(t, err)
+	_, err = c.Compact(ctx, put.Header.Revision)
+	require.NoError(t, err)
+
+	resp := <-c.Watch(ctx, "/c", clientv3.WithRev(first.Header.Revision))
+	assert.Equal(t, put.Header.Revision, resp.CompactRevision)
+	assert.ErrorIs(t, resp.Err(), rpctypes.ErrCompacted)
+}
+
+func TestStore_Lease(t *testing.T) {
+	_, c := newTestClient(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	grant, err := c.Grant(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, int64(etcdapi.MinLeaseTTL), grant.TTL)
+	_, err = c.Put(ctx, "/lease/a", "1", clientv3.WithLease(grant.ID))
+	require.NoError(t, err)
+	_, err = c.Put(ctx, "/lease/b", "2", clientv3.WithLease(grant.ID))
+	require.NoError(t, err)
+
+	ttl, err := c.TimeToLive(ctx, grant.ID, clientv3.WithAttachedKeys())
+	require.NoError(t, err)
+	assert.Equal(t, int64(etcdapi.MinLeaseTTL), ttl.GrantedTTL)
+	assert.Len(t, ttl.Keys, 2)
+
+	// 租约到期后关联的key在同一个修订版本中删除
+	watchCh := c.Watch(ctx, "/lease/", clientv3.WithPrefix())
+	select {
+	case resp := <-watchCh:
+		require.Len(t, resp.Events, 2)
+		assert.Equal(t, mvccpb.DELETE, resp.Events[0].Type)
+		assert.Equal(t, resp.Events[0].Kv.ModRevision, resp.Events[1].Kv.ModRevision)
+	case <-ctx.Done():
+		t.Fatal("等待租约到期超时")
+	}
+
+	ttl, err = c.TimeToLive(ctx, grant.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(-1), ttl.TTL)
+	_, err = c.Revoke(ctx, grant.ID)
+	assert.ErrorIs(t, err, rpctypes.ErrLeaseNotFound)
+}
+
+func TestStore_KeepAlive(t *testing.T) {
+	_, c := newTestClient(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	grant, err := c.Grant(ctx, etcdapi.MinLeaseTTL)
+	require.NoError(t, err)
+	_, err = c.Put(ctx, "/ka", "1", clientv3.WithLease(grant.ID))
+	require.NoError(t, err)
+
+	kaCtx, kaCancel := context.WithCancel(ctx)
+	defer kaCancel()
+	_, err = c.KeepAlive(kaCtx, grant.ID)
+	require.NoError(t, err)
+
+	// 持续续约的租约超过TTL后仍然有效
+	time.Sleep(3 * time.Second)
+	resp, err := c.Get(ctx, "/ka")
+	require.NoError(t, err)
+	assert.Len(t, resp.Kvs, 1)
+
+	_, err = c.Revoke(ctx, grant.ID)
+	require.NoError(t, err)
+	resp, err = c.Get(ctx, "/ka")
+	require.NoError(t, err)
+	assert.Empty(t, resp.Kvs)
+}
+
+func TestStore_Election(t *testing.T) {
+	s, c1 := newTestClient(t)
+	c2 := s.NewClient()
+	t.Cleanup(func() { _ = c2.Close() })
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	s1, err := concurrency.NewSession(c1, concurrency.WithTTL(5))
+	require.NoError(t, err)
+	defer s1.Close()
+	s2, err := concurrency.NewSession(c2, concurrency.WithTTL(5))
+	require.NoError(t, err)
+	defer s2.Close()
+
+	e1 := concurrency.NewElection(s1, "/election/test")
+	e2 := concurrency.NewElection(s2, "/election/test")
+	require.NoError(t, e1.Campaign(ctx, "node-1"))
+
+	// 第二个候选者等待，直到领导者放弃
+	elected := make(chan error, 1)
+	go func() { elected <- e2.Campaign(ctx, "node-2") }()
+	select {
+	case <-elected:
+		t.Fatal("领导者未放弃时不应当选")
+	case <-time.After(300 * time.Millisecond):
+	}
+
+	require.NoError(t, e1.Resign(ctx))
+	require.NoError(t, <-elected)
+	leader, err := e2.Leader(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "node-2", string(leader.Kvs[0].Value))
+}
+
+func TestStore_AutoCompact(t *testing.T) {
+	s, c := newTestClient(t)
+	s.maxHistory = 10
+	ctx := context.Background()
+
+	for i := 0; i < 25; i++ {
+		_, err := c.Put(ctx, "/k", "v")
+		require.NoError(t, err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	assert.LessOrEqual(t, len(s.history), s.maxHistory)
+	assert.Positive(t, s.compacted)
+}
+
+func TestStore_Status(t *testing.T) {
+	_, c := newTestClient(t)
+	resp, err := c.Status(context.Background(), "")
+	require.NoError(t, err)
+	assert.Equal(t, Version, resp.Version)
+}
@@ -0,0 +1,72 @@
+package memstore
+
+import (
+	"context"
+	"sort"
+
+	"github.com/hewenyu/kong-discovery/internal/etcdapi"
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"google.golang.org/grpc"
+)
+
+// Watch 打开监听流，一个流中可以创建多个监听
+func (s *Store) Watch(ctx context.Context, _ ...grpc.CallOption) (pb.Watch_WatchClient, error) {
+	return etcdapi.NewWatchStream(ctx, s), nil
+}
+
+// CreateWatch 创建监听，起始版本已被压缩时返回压缩版本并取消监听
+func (s *Store) CreateWatch(ws *etcdapi.WatchStream, req *pb.WatchCreateRequest) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if ws.Closed() {
+		return
+	}
+
+	header := s.header()
+	w := ws.NewWatcher(req, header)
+	start := req.StartRevision
+	if start > 0 && start < s.compacted {
+		w.Compacted(header, s.compacted)
+		return
+	}
+	if start > 0 && start <= s.revision {
+		i := sort.Search(len(s.history), func(i int) bool { return s.history[i].Kv.ModRevision >= start })
+		w.Send(header, s.history[i:])
+	}
+	ws.Watchers[w.ID] = w
+	s.watchers[w] = struct{}{}
+}
+
+// CancelWatch 取消监听
+func (s *Store) CancelWatch(ws *etcdapi.WatchStream, id int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	w := ws.Watchers[id]
+	if w == nil {
+		return
+	}
+	delete(ws.Watchers, id)
+	delete(s.watchers, w)
+	w.Canceled(s.header())
+}
+
+// Progress 返回当前修订版本
+func (s *Store) Progress(ws *etcdapi.WatchStream) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ws.Push(&pb.WatchResponse{Header: s.header(), WatchId: -1})
+}
+
+// CloseWatchStream 删除流中的所有监听
+func (s *Store) CloseWatchStream(ws *etcdapi.WatchStream) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, w := range ws.Watchers {
+		delete(ws.Watchers, id)
+		delete(s.watchers, w)
+	}
+}
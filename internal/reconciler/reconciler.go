@@ -0,0 +1,309 @@
+package reconciler
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hewenyu/kong-discovery/internal/config"
+	"github.com/hewenyu/kong-discovery/internal/etcdclient"
+	"go.uber.org/zap"
+)
+
+// electionName 后台协调任务使用的选举名称
+const electionName = "reconciler"
+
+// 默认的全量检查间隔和选举失败后的重试间隔
+const (
+	defaultInterval    = 60 * time.Second
+	campaignRetryDelay = 5 * time.Second
+)
+
+// Reconciler 定义后台协调任务接口
+type Reconciler interface {
+	// Start 启动协调任务（非阻塞）
+	Start() error
+
+	// Stop 停止协调任务，持有领导权时主动放弃
+	Stop(ctx context.Context) error
+
+	// Stats 返回本节点执行协调的统计
+	Stats() Stats
+}
+
+// Stats 本节点执行协调的统计，只有领导者执行协调，其他节点的统计保持为0
+type Stats struct {
+	Leading       bool           `json:"leading"`            // 本节点当前是否为领导者
+	DryRun        bool           `json:"dry_run"`            // 是否只检查不修复
+	Runs          int            `json:"runs"`               // 全量检查的次数
+	LastRun       *RunStats      `json:"last_run,omitempty"` // 最近一次全量检查
+	Repaired      map[string]int `json:"repaired"`           // 全量检查累计修复的数量，按类别统计
+	EventCleanups int            `json:"event_cleanups"`     // 实例删除事件触发清理的残留DNS记录累计数量
+}
+
+// RunStats 一次全量检查的统计
+type RunStats struct {
+	StartedAt time.Time      `json:"started_at"`      // 开始时间
+	Duration  string         `json:"duration"`        // 耗时
+	Findings  int            `json:"findings"`        // 发现的不一致数量
+	Repaired  map[string]int `json:"repaired"`        // 修复的数量，按类别统计
+	Deferred  int            `json:"deferred"`        // 超过单次修复数量上限、留到下一次检查的数量
+	Error     string         `json:"error,omitempty"` // 检查失败的原因
+}
+
+// ServiceReconciler 实现Reconciler接口，修复服务实例、DNS记录和命名空间之间的数据不一致
+// 实例租约自然过期（心跳中断）时不会经过注销流程，服务域名下的记录会一直残留，领导者监听实例的删除事件及时清理；
+// 级联删除中途失败等情况留下的其他不一致由定期的全量检查发现和修复
+type ServiceReconciler struct {
+	cfg       *config.Config
+	logger    config.Logger
+	client    etcdclient.Client
+	candidate string
+	interval  time.Duration
+	jitter    time.Duration
+
+	mu    sync.Mutex
+	stats Stats
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewReconciler 创建后台协调任务
+func NewReconciler(cfg *config.Config, logger config.Logger, client etcdclient.Client) Reconciler {
+	interval := defaultInterval
+	if cfg.Reconciler.Interval > 0 {
+		interval = time.Duration(cfg.Reconciler.Interval) * time.Second
+	}
+
+	hostname, _ := os.Hostname()
+	return &ServiceReconciler{
+		cfg:       cfg,
+		logger:    logger,
+		client:    client,
+		candidate: fmt.Sprintf("%s-%s", hostname, uuid.New().String()[:8]),
+		interval:  interval,
+		jitter:    time.Duration(cfg.Reconciler.Jitter) * time.Second,
+		stats:     Stats{DryRun: cfg.Reconciler.DryRun, Repaired: map[string]int{}},
+	}
+}
+
+// Start 启动协调任务
+func (r *ServiceReconciler) Start() error {
+	if r.done != nil {
+		return fmt.Errorf("协调任务已启动")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r.cancel = cancel
+	r.done = make(chan struct{})
+
+	r.logger.Info("启动后台协调任务",
+		zap.String("candidate", r.candidate),
+		zap.Duration("interval", r.interval),
+		zap.Duration("jitter", r.jitter),
+		zap.Int("batch_size", r.cfg.Reconciler.BatchSize))
+
+	go r.run(ctx)
+	return nil
+}
+
+// Stop 停止协调任务
+func (r *ServiceReconciler) Stop(ctx context.Context) error {
+	if r.done == nil {
+		return nil
+	}
+
+	r.cancel()
+	select {
+	case <-r.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Stats 返回本节点执行协调的统计
+func (r *ServiceReconciler) Stats() Stats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stats := r.stats
+	stats.Repaired = make(map[string]int, len(r.stats.Repaired))
+	for kind, n := range r.stats.Repaired {
+		stats.Repaired[kind] = n
+	}
+	return stats
+}
+
+// setLeading 记录本节点是否为领导者
+func (r *ServiceReconciler) setLeading(leading bool) {
+	r.mu.Lock()
+	r.stats.Leading = leading
+	r.mu.Unlock()
+}
+
+// nextDelay 返回距下一次全量检查的等待时间：检查间隔加上0到jitter之间的随机时间
+func (r *ServiceReconciler) nextDelay(interval time.Duration) time.Duration {
+	if r.jitter <= 0 {
+		return interval
+	}
+	return interval + time.Duration(rand.Int63n(int64(r.jitter)))
+}
+
+// run 循环参与选举，成为领导者后执行协调，领导权丢失后重新参与选举
+func (r *ServiceReconciler) run(ctx context.Context) {
+	defer close(r.done)
+
+	for {
+		leadership, err := r.client.Campaign(ctx, electionName, r.candidate)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			r.logger.Warn("参与协调任务选举失败，稍后重试", zap.Error(err))
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(campaignRetryDelay):
+				continue
+			}
+		}
+
+		r.lead(ctx, leadership)
+		if ctx.Err() != nil {
+			return
+		}
+		r.logger.Warn("协调任务领导权丢失，重新参与选举")
+	}
+}
+
+// lead 作为领导者监听服务实例的删除事件并定期全量检查，直到领导权丢失或任务停止
+func (r *ServiceReconciler) lead(ctx context.Context, leadership *etcdclient.Leadership) {
+	watchCtx, cancelWatch := context.WithCancel(ctx)
+	defer cancelWatch()
+
+	r.setLeading(true)
+	defer r.setLeading(false)
+
+	// 成为领导者后的第一次检查同样随机延迟，多个节点先后成为领导者时不会集中执行
+	timer := time.NewTimer(r.nextDelay(0))
+	defer timer.Stop()
+
+	events := r.watch(watchCtx)
+	for {
+		select {
+		case <-ctx.Done():
+			resignCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			if err := leadership.Resign(resignCtx); err != nil {
+				r.logger.Warn("放弃协调任务领导权失败", zap.Error(err))
+			}
+			cancel()
+			return
+		case <-leadership.Done():
+			return
+		case event, ok := <-events:
+			if !ok {
+				// 监听中断时依靠定期全量检查，下一次检查时重新建立监听
+				r.logger.Warn("监听服务实例变更中断")
+				events = nil
+				continue
+			}
+			r.handleEvent(ctx, event)
+		case <-timer.C:
+			if events == nil {
+				events = r.watch(watchCtx)
+			}
+			r.sweep(ctx)
+			timer.Reset(r.nextDelay(r.interval))
+		}
+	}
+}
+
+// watch 监听etcd变更，失败时返回nil，等待下一次全量检查时重试
+func (r *ServiceReconciler) watch(ctx context.Context) <-chan *etcdclient.Event {
+	events, err := r.client.WatchEvents(ctx)
+	if err != nil {
+		r.logger.Warn("监听服务实例变更失败", zap.Error(err))
+		return nil
+	}
+	return events
+}
+
+// handleEvent 服务实例被删除（注销或租约过期）时清理该服务的残留DNS记录，dry-run时留给全量检查报告
+func (r *ServiceReconciler) handleEvent(ctx context.Context, event *etcdclient.Event) {
+	// 压缩期间的删除事件丢失，立即全量检查一次清理残留记录
+	if event.Type == etcdclient.EventResync {
+		r.sweep(ctx)
+		return
+	}
+	if r.cfg.Reconciler.DryRun || event.Kind != "services" || event.Type != etcdclient.EventDelete {
+		return
+	}
+	serviceName, ok := etcdclient.ServiceNameFromKey(event.Key)
+	if !ok {
+		return
+	}
+	deleted, err := r.client.CleanupServiceRecords(ctx, serviceName)
+	if err != nil {
+		r.logger.Error("清理服务DNS记录失败", zap.String("service", serviceName), zap.Error(err))
+		return
+	}
+	r.mu.Lock()
+	r.stats.EventCleanups += deleted
+	r.mu.Unlock()
+}
+
+// sweep 全量检查数据一致性并修复发现的问题，配置为dry-run时只记录不修复，返回检查结果
+func (r *ServiceReconciler) sweep(ctx context.Context) *etcdclient.DriftReport {
+	start := time.Now()
+	report, err := r.client.ReconcileDrift(ctx, etcdclient.DriftOptions{
+		DryRun:     r.cfg.Reconciler.DryRun,
+		MaxRepairs: r.cfg.Reconciler.BatchSize,
+	})
+	r.record(start, report, err)
+	if err != nil {
+		r.logger.Error("数据一致性检查失败", zap.Error(err))
+		return nil
+	}
+
+	for _, finding := range report.Findings {
+		if finding.Repaired {
+			continue
+		}
+		r.logger.Warn("发现数据不一致",
+			zap.String("kind", finding.Kind),
+			zap.String("key", finding.Key),
+			zap.String("detail", finding.Detail),
+			zap.String("error", finding.Error))
+	}
+	return report
+}
+
+// record 记录一次全量检查的统计
+func (r *ServiceReconciler) record(start time.Time, report *etcdclient.DriftReport, err error) {
+	run := &RunStats{
+		StartedAt: start,
+		Duration:  time.Since(start).String(),
+		Repaired:  map[string]int{},
+	}
+	if err != nil {
+		run.Error = err.Error()
+	} else {
+		run.Findings = len(report.Findings)
+		run.Repaired = report.RepairedCounts()
+		run.Deferred = report.Deferred
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.stats.Runs++
+	r.stats.LastRun = run
+	for kind, n := range run.Repaired {
+		r.stats.Repaired[kind] += n
+	}
+}
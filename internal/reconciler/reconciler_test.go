@@ -0,0 +1,169 @@
+package reconciler
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hewenyu/kong-discovery/internal/config"
+	"github.com/hewenyu/kong-discovery/internal/etcdclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// 创建测试用的配置
+func createTestConfig(t *testing.T) *config.Config {
+	t.Helper()
+
+	cfg := &config.Config{}
+	etcdclient.ApplyTestStorage(cfg)
+	cfg.Reconciler.Enabled = true
+	// 全量检查间隔设置得足够长，保证测试中的清理来自事件监听
+	cfg.Reconciler.Interval = 3600
+	return cfg
+}
+
+// 创建测试用的日志记录器
+func createTestLogger(t *testing.T) config.Logger {
+	t.Helper()
+
+	logger, err := config.NewLogger(true)
+	require.NoError(t, err, "创建测试日志记录器失败")
+	return logger
+}
+
+// putServiceRecord 在服务域名下创建一条由服务维护的DNS记录，测试结束时删除
+func putServiceRecord(t *testing.T, client etcdclient.Client, serviceName string) string {
+	t.Helper()
+
+	domain := serviceName + ".default.svc.cluster.local"
+	require.NoError(t, client.PutDNSRecord(context.Background(), domain, &etcdclient.DNSRecord{
+		Type: "A", Value: "10.0.0.1", TTL: 60, Owner: etcdclient.ServiceOwner(serviceName),
+	}))
+	t.Cleanup(func() {
+		_, _ = client.ApplyDNSRecordBatch(context.Background(), []*etcdclient.DNSRecordOperation{
+			{Op: etcdclient.DNSRecordOpDelete, Domain: domain, Type: "A", Force: true},
+		})
+	})
+	return domain
+}
+
+func TestServiceReconciler_Sweep(t *testing.T) {
+	// 跳过集成测试，除非明确要求运行
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	client := etcdclient.CreateEtcdClientForTest(t)
+	t.Cleanup(func() { _ = client.Close() })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	suffix := time.Now().UnixNano()
+	alive := fmt.Sprintf("reconcile-alive-%d", suffix)
+	orphan := fmt.Sprintf("reconcile-orphan-%d", suffix)
+	aliveDomain := putServiceRecord(t, client, alive)
+	orphanDomain := putServiceRecord(t, client, orphan)
+
+	require.NoError(t, client.RegisterService(ctx, &etcdclient.ServiceInstance{
+		ServiceName: alive, InstanceID: "instance-001", IPAddress: "10.0.0.1", Port: 8080, TTL: 30,
+	}))
+	t.Cleanup(func() { _ = client.DeregisterService(context.Background(), alive, "instance-001") })
+
+	// dry-run只报告残留记录，不做修改
+	cfg := createTestConfig(t)
+	cfg.Reconciler.DryRun = true
+	r := NewReconciler(cfg, createTestLogger(t), client).(*ServiceReconciler)
+	report := r.sweep(ctx)
+	require.NotNil(t, report)
+	reported := make(map[string]string)
+	for _, finding := range report.Findings {
+		reported[finding.Key] = finding.Kind
+		assert.False(t, finding.Repaired)
+	}
+	assert.Equal(t, etcdclient.DriftOrphanedDNSRecord, reported["/dns/records/"+orphanDomain+"/A"])
+	assert.NotContains(t, reported, "/dns/records/"+aliveDomain+"/A", "仍有实例的服务记录不是残留记录")
+
+	_, err := client.GetDNSRecordEntry(ctx, orphanDomain, "A", "")
+	assert.NoError(t, err, "dry-run不应删除记录")
+
+	stats := r.Stats()
+	assert.Equal(t, 1, stats.Runs)
+	require.NotNil(t, stats.LastRun)
+	assert.GreaterOrEqual(t, stats.LastRun.Findings, 1)
+	assert.Empty(t, stats.LastRun.Repaired, "dry-run不应计入修复数量")
+
+	// 删除事件在dry-run时同样不清理
+	r.handleEvent(ctx, &etcdclient.Event{Type: etcdclient.EventDelete, Kind: "services", Key: "/services/" + orphan + "/instance-001"})
+	_, err = client.GetDNSRecordEntry(ctx, orphanDomain, "A", "")
+	assert.NoError(t, err)
+
+	// 关闭dry-run后删除事件触发清理，仍有实例的服务记录保留
+	cfg.Reconciler.DryRun = false
+	for _, name := range []string{orphan, alive} {
+		r.handleEvent(ctx, &etcdclient.Event{Type: etcdclient.EventDelete, Kind: "services", Key: "/services/" + name + "/instance-001"})
+	}
+	_, err = client.GetDNSRecordEntry(ctx, orphanDomain, "A", "")
+	assert.ErrorIs(t, err, etcdclient.ErrDNSRecordNotFound, "没有实例的服务记录应被清理")
+	_, err = client.GetDNSRecordEntry(ctx, aliveDomain, "A", "")
+	assert.NoError(t, err, "仍有实例的服务记录应保留")
+	assert.Equal(t, 1, r.Stats().EventCleanups)
+}
+
+func TestServiceReconciler_NextDelay(t *testing.T) {
+	cfg := createTestConfig(t)
+	cfg.Reconciler.Interval = 60
+
+	r := NewReconciler(cfg, createTestLogger(t), nil).(*ServiceReconciler)
+	assert.Equal(t, time.Minute, r.nextDelay(r.interval), "未配置jitter时按固定间隔执行")
+
+	cfg.Reconciler.Jitter = 10
+	r = NewReconciler(cfg, createTestLogger(t), nil).(*ServiceReconciler)
+	for i := 0; i < 100; i++ {
+		delay := r.nextDelay(r.interval)
+		assert.GreaterOrEqual(t, delay, time.Minute)
+		assert.Less(t, delay, time.Minute+10*time.Second)
+	}
+	assert.Less(t, r.nextDelay(0), 10*time.Second, "成为领导者后的第一次检查同样随机延迟")
+}
+
+func TestServiceReconciler_LeaseExpiry(t *testing.T) {
+	// 跳过集成测试，除非明确要求运行
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	client := etcdclient.CreateEtcdClientForTest(t)
+	t.Cleanup(func() { _ = client.Close() })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	serviceName := fmt.Sprintf("reconcile-expiry-%d", time.Now().UnixNano())
+	domain := putServiceRecord(t, client, serviceName)
+
+	// 实例注册后不再发送心跳，等待租约自然过期
+	require.NoError(t, client.RegisterService(ctx, &etcdclient.ServiceInstance{
+		ServiceName: serviceName, InstanceID: "instance-001", IPAddress: "10.0.0.1", Port: 8080, TTL: 1,
+	}))
+
+	r := NewReconciler(createTestConfig(t), createTestLogger(t), client)
+	require.NoError(t, r.Start())
+	t.Cleanup(func() {
+		stopCtx, stopCancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer stopCancel()
+		assert.NoError(t, r.Stop(stopCtx))
+	})
+
+	// 首次全量检查时实例仍然存在，记录应保留到租约过期之后
+	assert.Eventually(t, func() bool {
+		_, err := client.GetDNSRecordEntry(ctx, domain, "A", "")
+		return err != nil
+	}, 8*time.Second, 100*time.Millisecond, "租约过期后残留的DNS记录应被清理")
+
+	instances, err := client.GetServiceInstances(ctx, serviceName)
+	require.NoError(t, err)
+	assert.Empty(t, instances)
+}
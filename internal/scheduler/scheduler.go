@@ -0,0 +1,173 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hewenyu/kong-discovery/internal/config"
+	"github.com/hewenyu/kong-discovery/internal/etcdclient"
+	"go.uber.org/zap"
+)
+
+// electionName 定时变更调度器使用的选举名称
+const electionName = "dns-scheduler"
+
+// 默认的检查间隔和选举失败后的重试间隔
+const (
+	defaultCheckInterval = 5 * time.Second
+	campaignRetryDelay   = 5 * time.Second
+)
+
+// Scheduler 定义定时DNS变更调度器接口
+type Scheduler interface {
+	// Start 启动调度器（非阻塞）
+	Start() error
+
+	// Stop 停止调度器，持有领导权时主动放弃
+	Stop(ctx context.Context) error
+}
+
+// DNSChangeScheduler 实现Scheduler接口
+// 多个节点通过etcd选举产生唯一的领导者，只有领导者执行到期的定时变更
+type DNSChangeScheduler struct {
+	cfg       *config.Config
+	logger    config.Logger
+	client    etcdclient.Client
+	candidate string
+	interval  time.Duration
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewScheduler 创建定时DNS变更调度器
+func NewScheduler(cfg *config.Config, logger config.Logger, client etcdclient.Client) Scheduler {
+	interval := defaultCheckInterval
+	if cfg.Scheduler.CheckInterval > 0 {
+		interval = time.Duration(cfg.Scheduler.CheckInterval) * time.Second
+	}
+
+	hostname, _ := os.Hostname()
+	return &DNSChangeScheduler{
+		cfg:       cfg,
+		logger:    logger,
+		client:    client,
+		candidate: fmt.Sprintf("%s-%s", hostname, uuid.New().String()[:8]),
+		interval:  interval,
+	}
+}
+
+// Start 启动调度器
+func (s *DNSChangeScheduler) Start() error {
+	if s.done != nil {
+		return fmt.Errorf("调度器已启动")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	s.done = make(chan struct{})
+
+	s.logger.Info("启动定时DNS变更调度器",
+		zap.String("candidate", s.candidate),
+		zap.Duration("interval", s.interval))
+
+	go s.run(ctx)
+	return nil
+}
+
+// Stop 停止调度器
+func (s *DNSChangeScheduler) Stop(ctx context.Context) error {
+	if s.done == nil {
+		return nil
+	}
+
+	s.cancel()
+	select {
+	case <-s.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// run 循环参与选举，成为领导者后执行调度，领导权丢失后重新参与选举
+func (s *DNSChangeScheduler) run(ctx context.Context) {
+	defer close(s.done)
+
+	for {
+		leadership, err := s.client.Campaign(ctx, electionName, s.candidate)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			s.logger.Warn("参与调度器选举失败，稍后重试", zap.Error(err))
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(campaignRetryDelay):
+				continue
+			}
+		}
+
+		s.lead(ctx, leadership)
+		if ctx.Err() != nil {
+			return
+		}
+		s.logger.Warn("调度器领导权丢失，重新参与选举")
+	}
+}
+
+// lead 作为领导者定期执行到期的定时变更，直到领导权丢失或调度器停止
+func (s *DNSChangeScheduler) lead(ctx context.Context, leadership *etcdclient.Leadership) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	s.applyDue(ctx, time.Now())
+	for {
+		select {
+		case <-ctx.Done():
+			resignCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			if err := leadership.Resign(resignCtx); err != nil {
+				s.logger.Warn("放弃调度器领导权失败", zap.Error(err))
+			}
+			cancel()
+			return
+		case <-leadership.Done():
+			return
+		case now := <-ticker.C:
+			s.applyDue(ctx, now)
+		}
+	}
+}
+
+// applyDue 按生效时间顺序执行所有到期的定时变更，返回成功执行的数量
+func (s *DNSChangeScheduler) applyDue(ctx context.Context, now time.Time) int {
+	changes, err := s.client.ListScheduledChanges(ctx)
+	if err != nil {
+		s.logger.Error("获取定时变更失败", zap.Error(err))
+		return 0
+	}
+
+	applied := 0
+	for _, change := range changes {
+		if change.Status != etcdclient.ScheduleStatusPending || change.ApplyAt.After(now) {
+			continue
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		if _, err := s.client.ApplyScheduledChange(ctx, change.ID); err != nil {
+			s.logger.Error("执行定时DNS变更失败",
+				zap.String("id", change.ID),
+				zap.Time("apply_at", change.ApplyAt),
+				zap.Error(err))
+			continue
+		}
+		applied++
+	}
+	return applied
+}
@@ -0,0 +1,115 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hewenyu/kong-discovery/internal/config"
+	"github.com/hewenyu/kong-discovery/internal/etcdclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// 创建测试用的配置
+func createTestConfig(t *testing.T) *config.Config {
+	t.Helper()
+
+	cfg := &config.Config{}
+	etcdclient.ApplyTestStorage(cfg)
+	cfg.Scheduler.Enabled = true
+	cfg.Scheduler.CheckInterval = 1
+	return cfg
+}
+
+// 创建测试用的日志记录器
+func createTestLogger(t *testing.T) config.Logger {
+	t.Helper()
+
+	logger, err := config.NewLogger(true)
+	require.NoError(t, err, "创建测试日志记录器失败")
+	return logger
+}
+
+func TestDNSChangeScheduler_ApplyDue(t *testing.T) {
+	// 跳过集成测试，除非明确要求运行
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	client := etcdclient.CreateEtcdClientForTest(t)
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	domain := fmt.Sprintf("scheduler-%d.example.com", time.Now().UnixNano())
+	defer func() {
+		_ = client.DeleteDNSRecord(context.Background(), domain, "A", "")
+	}()
+
+	now := time.Now()
+	dueID, err := client.ScheduleDNSChange(ctx, &etcdclient.ScheduledChange{
+		ApplyAt:    now.Add(-time.Second),
+		Operations: []*etcdclient.DNSRecordOperation{{Op: etcdclient.DNSRecordOpPut, Domain: domain, Record: &etcdclient.DNSRecord{Type: "A", Value: "10.0.0.1"}}},
+	})
+	require.NoError(t, err)
+	futureID, err := client.ScheduleDNSChange(ctx, &etcdclient.ScheduledChange{
+		ApplyAt:    now.Add(time.Hour),
+		Operations: []*etcdclient.DNSRecordOperation{{Op: etcdclient.DNSRecordOpPut, Domain: domain, Record: &etcdclient.DNSRecord{Type: "A", Value: "10.0.0.2"}}},
+	})
+	require.NoError(t, err)
+	defer func() { _ = client.CancelScheduledChange(context.Background(), futureID) }()
+
+	s := NewScheduler(createTestConfig(t), createTestLogger(t), client).(*DNSChangeScheduler)
+	assert.GreaterOrEqual(t, s.applyDue(ctx, now), 1)
+
+	// 到期的变更已应用并删除，未到期的保持不变
+	_, err = client.GetScheduledChange(ctx, dueID)
+	assert.ErrorIs(t, err, etcdclient.ErrScheduledChangeNotFound)
+	entry, err := client.GetDNSRecordEntry(ctx, domain, "A", "")
+	require.NoError(t, err)
+	assert.Equal(t, "10.0.0.1", entry.Value)
+
+	future, err := client.GetScheduledChange(ctx, futureID)
+	require.NoError(t, err)
+	assert.Equal(t, etcdclient.ScheduleStatusPending, future.Status)
+}
+
+func TestDNSChangeScheduler_StartStop(t *testing.T) {
+	// 跳过集成测试，除非明确要求运行
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	client := etcdclient.CreateEtcdClientForTest(t)
+	defer client.Close()
+
+	domain := fmt.Sprintf("scheduler-run-%d.example.com", time.Now().UnixNano())
+	defer func() {
+		_ = client.DeleteDNSRecord(context.Background(), domain, "A", "")
+	}()
+
+	s := NewScheduler(createTestConfig(t), createTestLogger(t), client)
+	require.NoError(t, s.Start())
+	assert.Error(t, s.Start(), "重复启动应返回错误")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	id, err := client.ScheduleDNSChange(ctx, &etcdclient.ScheduledChange{
+		ApplyAt:    time.Now(),
+		Operations: []*etcdclient.DNSRecordOperation{{Op: etcdclient.DNSRecordOpPut, Domain: domain, Record: &etcdclient.DNSRecord{Type: "A", Value: "10.0.0.3"}}},
+	})
+	require.NoError(t, err)
+	defer func() { _ = client.CancelScheduledChange(context.Background(), id) }()
+
+	// 成为领导者后在下一个检查周期内应用变更
+	assert.Eventually(t, func() bool {
+		_, err := client.GetDNSRecordEntry(context.Background(), domain, "A", "")
+		return err == nil
+	}, 4*time.Second, 100*time.Millisecond)
+
+	require.NoError(t, s.Stop(ctx))
+}
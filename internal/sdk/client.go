@@ -0,0 +1,410 @@
+package sdk
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hewenyu/kong-discovery/internal/config"
+	"github.com/hewenyu/kong-discovery/internal/version"
+	"go.uber.org/zap"
+)
+
+// 默认的HTTP请求超时时间
+const defaultRequestTimeout = 5 * time.Second
+
+// maxDiscoveryWait 服务端长轮询的最大等待时间
+const maxDiscoveryWait = 5 * time.Minute
+
+// Instance 描述要注册的服务实例
+type Instance struct {
+	ServiceName string            `json:"service_name"`        // 服务名称
+	InstanceID  string            `json:"instance_id"`         // 实例ID，为空时注册前由SDK生成
+	IPAddress   string            `json:"ip_address"`          // IP地址
+	Port        int               `json:"port"`                // 端口
+	Ports       []NamedPort       `json:"ports,omitempty"`     // 可选命名端口列表，每个端口可以通过 _<名称>._<协议>.<服务域名> 的SRV记录发现
+	TTL         int               `json:"ttl"`                 // 租约TTL（秒）
+	DNSTTL      int               `json:"dns_ttl,omitempty"`   // 可选DNS应答TTL（秒）
+	Health      string            `json:"health,omitempty"`    // 可选初始健康状态
+	Namespace   string            `json:"namespace,omitempty"` // 可选命名空间
+	Metadata    map[string]string `json:"metadata,omitempty"`  // 可选元数据
+}
+
+// NamedPort 实例提供的一个命名端口，如http、grpc、metrics
+type NamedPort struct {
+	Name     string `json:"name"`               // 端口名称，小写字母、数字和连字符，不超过15个字符
+	Port     int    `json:"port"`               // 端口号
+	Protocol string `json:"protocol,omitempty"` // 传输协议（tcp、udp、sctp），为空表示tcp
+}
+
+// Response 服务注册API的通用响应
+type Response struct {
+	Success     bool   `json:"success"`               // 是否成功
+	ServiceName string `json:"service_name"`          // 服务名称
+	InstanceID  string `json:"instance_id,omitempty"` // 实例ID
+	Code        string `json:"code,omitempty"`        // 失败时的错误码
+	Message     string `json:"message,omitempty"`     // 可选消息
+	Detail      string `json:"detail,omitempty"`      // 失败时的附加说明
+	Timestamp   string `json:"timestamp"`             // 时间戳
+}
+
+// APIError 服务注册API返回的错误，可通过errors.As获取错误码
+type APIError struct {
+	StatusCode int    // HTTP状态码
+	Code       string // 机器可读的错误码，如INSTANCE_NOT_FOUND
+	Message    string // 提示信息
+	Detail     string // 附加说明
+}
+
+// Error 实现error接口
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("服务注册API返回错误(状态码%d, %s): %s", e.StatusCode, e.Code, e.Message)
+	if e.Detail != "" {
+		msg += ": " + e.Detail
+	}
+	return msg
+}
+
+// RetryPolicy 请求失败时的重试策略
+// 网络错误、5xx和429响应会按带随机抖动的指数退避重试，其他4xx响应直接返回
+type RetryPolicy struct {
+	MaxAttempts int           // 最大尝试次数，1表示不重试
+	BaseDelay   time.Duration // 第一次重试前的最长等待时间
+	MaxDelay    time.Duration // 单次等待时间的上限
+}
+
+// DefaultRetryPolicy 默认的重试策略
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   100 * time.Millisecond,
+	MaxDelay:    2 * time.Second,
+}
+
+// delay 返回第attempt次重试前的等待时间，在[0, min(BaseDelay*2^(attempt-1), MaxDelay))中随机选取
+func (p RetryPolicy) delay(attempt int) time.Duration {
+	backoff := p.BaseDelay << (attempt - 1)
+	if backoff <= 0 || backoff > p.MaxDelay {
+		backoff = p.MaxDelay
+	}
+	if backoff <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(backoff)))
+}
+
+// Client 服务注册API的客户端
+type Client struct {
+	endpoints  *endpointPool
+	httpClient *http.Client
+	logger     config.Logger
+	retry      RetryPolicy
+
+	// 用于优雅关闭：托管的注册和进行中的请求数，idle在没有进行中的请求时关闭
+	mu            sync.Mutex
+	registrations map[*Registration]struct{}
+	inflight      int
+	idle          chan struct{}
+}
+
+// NewClient 创建服务注册API客户端，baseURL形如 http://127.0.0.1:8081
+// 部署了多个服务端副本时可以传入逗号分隔的多个地址，请求失败时自动切换到其他地址
+func NewClient(baseURL string) *Client {
+	return &Client{
+		endpoints:  newEndpointPool(baseURL),
+		httpClient: &http.Client{Timeout: defaultRequestTimeout},
+		retry:      DefaultRetryPolicy,
+
+		registrations: make(map[*Registration]struct{}),
+	}
+}
+
+// SetRetryPolicy 设置请求失败时的重试策略
+func (c *Client) SetRetryPolicy(policy RetryPolicy) {
+	if policy.MaxAttempts < 1 {
+		policy.MaxAttempts = 1
+	}
+	c.retry = policy
+}
+
+// SetLogger 设置日志记录器，日志使用sdk组件的级别；未设置时不输出日志
+func (c *Client) SetLogger(logger config.Logger) {
+	c.logger = logger.Named(config.ComponentSDK)
+}
+
+// Register 注册服务实例
+// 未设置InstanceID时在第一次请求之前生成UUID并写回instance，重试的请求使用同一个ID，
+// 响应丢失后重试不会注册出多个实例；之后的心跳和注销使用该ID
+func (c *Client) Register(ctx context.Context, instance *Instance) error {
+	if instance.InstanceID == "" {
+		instance.InstanceID = uuid.New().String()
+	}
+	return c.do(ctx, http.MethodPost, "/v1/services/register", instance)
+}
+
+// Deregister 注销服务实例
+func (c *Client) Deregister(ctx context.Context, serviceName, instanceID string) error {
+	return c.do(ctx, http.MethodDelete, instancePath(serviceName, instanceID), nil)
+}
+
+// ReportedCheck 随心跳上报的一项自定义检查
+type ReportedCheck struct {
+	Name   string `json:"name"`             // 检查名称
+	Status string `json:"status"`           // 检查结果：passing、warning或critical（也可以使用pass、warn、fail）
+	Output string `json:"output,omitempty"` // 可选检查输出
+}
+
+// HealthReport 随心跳上报的健康信息，服务端保存在实例上并按状态更新实例的健康状态
+type HealthReport struct {
+	Status string             `json:"status,omitempty"` // 健康状态，为空时由检查结果中最严重的一项决定
+	Load   map[string]float64 `json:"load,omitempty"`   // 负载指标，如cpu、memory、connections
+	Checks []ReportedCheck    `json:"checks,omitempty"` // 自定义检查的结果
+}
+
+// heartbeatRequest 心跳请求体
+type heartbeatRequest struct {
+	TTL    int           `json:"ttl"`
+	Health *HealthReport `json:"health,omitempty"`
+}
+
+// Heartbeat 发送心跳刷新实例租约，ttl为0时沿用注册时的TTL
+func (c *Client) Heartbeat(ctx context.Context, serviceName, instanceID string, ttl int) error {
+	return c.HeartbeatWithHealth(ctx, serviceName, instanceID, ttl, nil)
+}
+
+// HeartbeatWithHealth 发送心跳刷新实例租约，同时上报健康信息；report为nil时与Heartbeat相同。
+// 摘流中的实例上报的状态只被记录，不会让实例重新出现在DNS应答中
+func (c *Client) HeartbeatWithHealth(ctx context.Context, serviceName, instanceID string, ttl int, report *HealthReport) error {
+	path := "/v1/services/heartbeat/" + url.PathEscape(serviceName) + "/" + url.PathEscape(instanceID)
+	return c.do(ctx, http.MethodPut, path, &heartbeatRequest{TTL: ttl, Health: report})
+}
+
+// SetMaintenance 开启或关闭实例的维护模式，维护中的实例不会出现在DNS应答中
+func (c *Client) SetMaintenance(ctx context.Context, serviceName, instanceID string, enabled bool) error {
+	body := map[string]bool{"enabled": enabled}
+	return c.do(ctx, http.MethodPut, instancePath(serviceName, instanceID)+"/maintenance", body)
+}
+
+// SetHealth 更新实例的健康状态（passing、warning、critical或draining），draining的实例不再出现在新的DNS应答中
+func (c *Client) SetHealth(ctx context.Context, serviceName, instanceID, status string) error {
+	body := map[string]string{"status": status}
+	return c.do(ctx, http.MethodPut, instancePath(serviceName, instanceID)+"/health", body)
+}
+
+// Discovery 服务发现查询结果
+type Discovery struct {
+	Namespace   string      `json:"namespace"`    // 命名空间
+	ServiceName string      `json:"service_name"` // 服务名称
+	Index       int64       `json:"index"`        // 传给下一次Discover以等待之后的变更
+	Instances   []*Instance `json:"instances"`    // 可用的实例
+	Maintenance bool        `json:"-"`            // 服务是否处于维护模式
+}
+
+// discoveryResponse 服务发现端点的响应结构
+type discoveryResponse struct {
+	Success     bool            `json:"success"`
+	Code        string          `json:"code,omitempty"`
+	Message     string          `json:"message,omitempty"`
+	Detail      string          `json:"detail,omitempty"`
+	Namespace   string          `json:"namespace"`
+	ServiceName string          `json:"service_name"`
+	Index       int64           `json:"index"`
+	Instances   []*Instance     `json:"instances"`
+	Maintenance json.RawMessage `json:"maintenance,omitempty"`
+}
+
+// Discover 查询服务的可用实例
+// index为0时立即返回；否则进行长轮询，服务在index之后发生变更或等待wait时间后返回，
+// wait为0时使用服务端的默认等待时间
+func (c *Client) Discover(ctx context.Context, namespace, serviceName string, index int64, wait time.Duration) (*Discovery, error) {
+	query := url.Values{}
+	httpClient := c.httpClient
+	if index > 0 {
+		query.Set("index", strconv.FormatInt(index, 10))
+		if wait > 0 {
+			query.Set("wait", wait.String())
+		}
+		// 长轮询的请求超时需要覆盖服务端的等待时间
+		timeout := maxDiscoveryWait
+		if wait > 0 && wait < timeout {
+			timeout = wait
+		}
+		httpClient = &http.Client{Transport: c.httpClient.Transport, Timeout: timeout + defaultRequestTimeout}
+	}
+
+	path := "/v1/discovery/" + url.PathEscape(namespace) + "/" + url.PathEscape(serviceName)
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+
+	resp, err := c.send(ctx, httpClient, http.MethodGet, path, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result discoveryResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("解析响应失败(状态码%d): %w", resp.StatusCode, err)
+	}
+	if resp.StatusCode >= http.StatusBadRequest || !result.Success {
+		return nil, &APIError{
+			StatusCode: resp.StatusCode,
+			Code:       result.Code,
+			Message:    result.Message,
+			Detail:     result.Detail,
+		}
+	}
+
+	return &Discovery{
+		Namespace:   result.Namespace,
+		ServiceName: result.ServiceName,
+		Index:       result.Index,
+		Instances:   result.Instances,
+		Maintenance: len(result.Maintenance) > 0 && string(result.Maintenance) != "null",
+	}, nil
+}
+
+// instancePath 生成服务实例的URL路径
+func instancePath(serviceName, instanceID string) string {
+	return "/v1/services/" + url.PathEscape(serviceName) + "/" + url.PathEscape(instanceID)
+}
+
+// do 发送请求并解析通用响应
+func (c *Client) do(ctx context.Context, method, path string, body interface{}) error {
+	_, err := c.doResponse(ctx, method, path, body)
+	return err
+}
+
+// doResponse 发送请求，返回解析后的通用响应
+func (c *Client) doResponse(ctx context.Context, method, path string, body interface{}) (*Response, error) {
+	var data []byte
+	if body != nil {
+		var err error
+		if data, err = json.Marshal(body); err != nil {
+			return nil, fmt.Errorf("序列化请求失败: %w", err)
+		}
+	}
+
+	resp, err := c.send(ctx, c.httpClient, method, path, data)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result Response
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("解析响应失败(状态码%d): %w", resp.StatusCode, err)
+	}
+
+	if resp.StatusCode >= http.StatusBadRequest || !result.Success {
+		if c.logger != nil {
+			c.logger.Warn("服务注册API返回错误",
+				zap.String("method", method),
+				zap.String("path", path),
+				zap.Int("status", resp.StatusCode),
+				zap.String("code", result.Code))
+		}
+		return nil, &APIError{
+			StatusCode: resp.StatusCode,
+			Code:       result.Code,
+			Message:    result.Message,
+			Detail:     result.Detail,
+		}
+	}
+
+	return &result, nil
+}
+
+// send 发送请求，网络错误和可重试的响应按重试策略重试，返回最后一次的响应
+// 配置了多个地址时，失败的地址暂停使用并立即改用下一个健康的地址，每个地址至少尝试一次
+func (c *Client) send(ctx context.Context, httpClient *http.Client, method, path string, body []byte) (*http.Response, error) {
+	c.beginRequest()
+	defer c.endRequest()
+
+	maxAttempts := c.retry.MaxAttempts
+	if n := c.endpoints.size(); maxAttempts < n {
+		maxAttempts = n
+	}
+
+	for attempt := 1; ; attempt++ {
+		var reader io.Reader
+		if body != nil {
+			reader = bytes.NewReader(body)
+		}
+		index, baseURL := c.endpoints.pick()
+		req, err := http.NewRequestWithContext(ctx, method, baseURL+path, reader)
+		if err != nil {
+			return nil, fmt.Errorf("创建请求失败: %w", err)
+		}
+		if body != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		req.Header.Set("User-Agent", version.UserAgent())
+		req.Header.Set(version.SDKHeader, version.Version)
+
+		if c.logger != nil {
+			c.logger.Debug("发送服务注册API请求",
+				zap.String("endpoint", baseURL),
+				zap.String("method", method),
+				zap.String("path", path),
+				zap.Int("attempt", attempt))
+		}
+
+		resp, err := httpClient.Do(req)
+		retryable := err != nil || resp.StatusCode >= http.StatusInternalServerError || resp.StatusCode == http.StatusTooManyRequests
+		failover := false
+		if retryable && ctx.Err() == nil {
+			failover = c.endpoints.markFailed(index)
+		} else if !retryable {
+			c.endpoints.markOK(index)
+		}
+		if !retryable || attempt >= maxAttempts || ctx.Err() != nil {
+			if err != nil {
+				if c.logger != nil {
+					c.logger.Warn("请求服务注册API失败",
+						zap.String("endpoint", baseURL),
+						zap.String("method", method),
+						zap.String("path", path),
+						zap.Error(err))
+				}
+				return nil, fmt.Errorf("请求服务注册API失败: %w", err)
+			}
+			return resp, nil
+		}
+
+		// 丢弃本次响应，等待后重试
+		if resp != nil {
+			_, _ = io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		// 还有其他健康的地址时立即切换，否则按退避时间等待
+		var delay time.Duration
+		if !failover {
+			delay = c.retry.delay(attempt)
+		}
+		if c.logger != nil {
+			c.logger.Debug("服务注册API请求失败，稍后重试",
+				zap.String("endpoint", baseURL),
+				zap.Bool("failover", failover),
+				zap.String("method", method),
+				zap.String("path", path),
+				zap.Int("attempt", attempt),
+				zap.Duration("delay", delay),
+				zap.Error(err))
+		}
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("请求服务注册API失败: %w", ctx.Err())
+		case <-time.After(delay):
+		}
+	}
+}
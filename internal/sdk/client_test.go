@@ -0,0 +1,316 @@
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hewenyu/kong-discovery/internal/version"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_SetMaintenance(t *testing.T) {
+	var gotPath, gotSDKVersion, gotUserAgent string
+	var gotBody map[string]bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.Method + " " + r.URL.Path
+		gotSDKVersion, gotUserAgent = r.Header.Get(version.SDKHeader), r.UserAgent()
+		_ = json.NewDecoder(r.Body).Decode(&gotBody)
+		_ = json.NewEncoder(w).Encode(&Response{Success: true})
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL + "/")
+	err := client.SetMaintenance(context.Background(), "order-service", "instance-001", true)
+	require.NoError(t, err)
+	assert.Equal(t, "PUT /v1/services/order-service/instance-001/maintenance", gotPath)
+	assert.True(t, gotBody["enabled"])
+
+	// 每个请求都上报SDK版本，服务端据此拒绝过低的版本
+	assert.Equal(t, version.Version, gotSDKVersion)
+	assert.Equal(t, version.UserAgent(), gotUserAgent)
+}
+
+func TestRegistration_HealthReporter(t *testing.T) {
+	var mu sync.Mutex
+	var reports []*HealthReport
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/v1/services/heartbeat/") {
+			var req heartbeatRequest
+			_ = json.NewDecoder(r.Body).Decode(&req)
+			mu.Lock()
+			reports = append(reports, req.Health)
+			mu.Unlock()
+		}
+		_ = json.NewEncoder(w).Encode(&Response{Success: true})
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	instance := &Instance{ServiceName: "order-service", InstanceID: "instance-001", IPAddress: "10.0.0.1", Port: 8080, TTL: 1}
+	registration, err := client.Start(context.Background(), instance, nil)
+	require.NoError(t, err)
+	defer func() { _ = registration.Stop(context.Background()) }()
+
+	registration.SetHealthReporter(func() *HealthReport {
+		return &HealthReport{
+			Load:   map[string]float64{"cpu": 0.25},
+			Checks: []ReportedCheck{{Name: "db", Status: "pass"}},
+		}
+	})
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(reports) > 0 && reports[len(reports)-1] != nil
+	}, 3*time.Second, 20*time.Millisecond)
+
+	mu.Lock()
+	report := reports[len(reports)-1]
+	mu.Unlock()
+	assert.Equal(t, 0.25, report.Load["cpu"])
+	assert.Equal(t, "db", report.Checks[0].Name)
+}
+
+func TestClient_Register_GeneratedInstanceID(t *testing.T) {
+	var mu sync.Mutex
+	var registered []string
+	var heartbeatPath string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.Method == http.MethodPut {
+			heartbeatPath = r.URL.Path
+		}
+		if r.Method == http.MethodPost {
+			var instance Instance
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&instance))
+			registered = append(registered, instance.InstanceID)
+			// 第一次注册在服务端写入后返回5xx，模拟响应丢失
+			if len(registered) == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				_ = json.NewEncoder(w).Encode(&Response{Success: false, Code: "STORAGE_ERROR"})
+				return
+			}
+		}
+		_ = json.NewEncoder(w).Encode(&Response{Success: true, ServiceName: "order-service"})
+	}))
+	defer server.Close()
+
+	// 未设置实例ID时在第一次请求之前生成，重试使用同一个ID，之后的心跳也使用该ID
+	client := NewClient(server.URL)
+	client.SetRetryPolicy(RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond})
+	instance := &Instance{ServiceName: "order-service", IPAddress: "10.0.0.1", Port: 8080, TTL: 30}
+	registration, err := client.Start(context.Background(), instance, nil)
+	require.NoError(t, err)
+	id := registration.Instance().InstanceID
+	require.NotEmpty(t, id)
+	mu.Lock()
+	assert.Equal(t, []string{id, id}, registered, "重试的注册请求应使用同一个实例ID")
+	mu.Unlock()
+
+	registration.beat(context.Background())
+	mu.Lock()
+	assert.Equal(t, "/v1/services/heartbeat/order-service/"+id, heartbeatPath)
+	mu.Unlock()
+	require.NoError(t, registration.Stop(context.Background()))
+
+	// 已设置的实例ID不会被覆盖
+	instance = &Instance{ServiceName: "order-service", InstanceID: "instance-001", IPAddress: "10.0.0.1", Port: 8080, TTL: 30}
+	require.NoError(t, client.Register(context.Background(), instance))
+	assert.Equal(t, "instance-001", instance.InstanceID)
+}
+
+func TestClient_ErrorResponse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		_ = json.NewEncoder(w).Encode(&Response{Success: false, Code: "INSTANCE_NOT_FOUND", Message: "服务实例不存在"})
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	err := client.Deregister(context.Background(), "order-service", "missing")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "服务实例不存在")
+
+	var apiErr *APIError
+	require.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, http.StatusNotFound, apiErr.StatusCode)
+	assert.Equal(t, "INSTANCE_NOT_FOUND", apiErr.Code)
+}
+
+func TestClient_Discover(t *testing.T) {
+	var gotQuery string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/discovery/prod/order-service", r.URL.Path)
+		gotQuery = r.URL.RawQuery
+		_, _ = w.Write([]byte(`{
+			"success": true, "namespace": "prod", "service_name": "order-service", "index": 42,
+			"maintenance": {"service_name": "order-service", "since": "2026-01-01T00:00:00Z"},
+			"instances": [{"service_name": "order-service", "instance_id": "i-1", "ip_address": "10.0.0.1", "port": 8080, "metadata": {"zone": "a"}}]
+		}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	discovery, err := client.Discover(context.Background(), "prod", "order-service", 0, 0)
+	require.NoError(t, err)
+	assert.Empty(t, gotQuery, "index为0时不进行长轮询")
+	assert.Equal(t, int64(42), discovery.Index)
+	assert.True(t, discovery.Maintenance)
+	require.Len(t, discovery.Instances, 1)
+	assert.Equal(t, "a", discovery.Instances[0].Metadata["zone"])
+
+	_, err = client.Discover(context.Background(), "prod", "order-service", 42, 30*time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, "index=42&wait=30s", gotQuery)
+}
+
+func TestClient_Retry(t *testing.T) {
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_ = json.NewEncoder(w).Encode(&Response{Success: false, Code: "STORAGE_ERROR"})
+			return
+		}
+		_ = json.NewEncoder(w).Encode(&Response{Success: true})
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	client.SetRetryPolicy(RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond})
+
+	// 5xx响应重试后成功
+	require.NoError(t, client.Heartbeat(context.Background(), "order-service", "instance-001", 0))
+	assert.Equal(t, int32(3), atomic.LoadInt32(&attempts))
+
+	// 重试次数用尽后返回最后一次的错误
+	atomic.StoreInt32(&attempts, -10)
+	err := client.Heartbeat(context.Background(), "order-service", "instance-001", 0)
+	var apiErr *APIError
+	require.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, http.StatusServiceUnavailable, apiErr.StatusCode)
+	assert.Equal(t, int32(-7), atomic.LoadInt32(&attempts))
+}
+
+func TestClient_RetryNotOnClientError(t *testing.T) {
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(&Response{Success: false, Code: "INVALID_PARAMETER"})
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	client.SetRetryPolicy(RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond})
+	assert.Error(t, client.Register(context.Background(), &Instance{ServiceName: "order-service"}))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&attempts), "4xx响应不应重试")
+}
+
+// fakeRegistry 模拟服务注册API，可以手动删除实例模拟服务端数据丢失
+type fakeRegistry struct {
+	mu         sync.Mutex
+	instances  map[string]bool
+	health     map[string]string // 实例最近一次注册或更新的健康状态
+	registered int
+}
+
+func newFakeRegistry(t *testing.T) (*fakeRegistry, *httptest.Server) {
+	registry := &fakeRegistry{instances: make(map[string]bool), health: make(map[string]string)}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		registry.mu.Lock()
+		defer registry.mu.Unlock()
+
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/v1/services/register":
+			var instance Instance
+			_ = json.NewDecoder(r.Body).Decode(&instance)
+			registry.instances[instance.InstanceID] = true
+			registry.health[instance.InstanceID] = instance.Health
+			registry.registered++
+		case r.Method == http.MethodPut && strings.HasSuffix(r.URL.Path, "/health"):
+			var req struct {
+				Status string `json:"status"`
+			}
+			_ = json.NewDecoder(r.Body).Decode(&req)
+			id := strings.TrimSuffix(r.URL.Path, "/health")
+			registry.health[id[strings.LastIndex(id, "/")+1:]] = req.Status
+		case r.Method == http.MethodPut && strings.HasPrefix(r.URL.Path, "/v1/services/heartbeat/"):
+			id := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+			if !registry.instances[id] {
+				w.WriteHeader(http.StatusNotFound)
+				_ = json.NewEncoder(w).Encode(&Response{Success: false, Code: "INSTANCE_NOT_FOUND"})
+				return
+			}
+		case r.Method == http.MethodDelete:
+			id := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+			delete(registry.instances, id)
+		}
+		_ = json.NewEncoder(w).Encode(&Response{Success: true})
+	}))
+	t.Cleanup(server.Close)
+	return registry, server
+}
+
+func (f *fakeRegistry) lose(id string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.instances, id)
+}
+
+func (f *fakeRegistry) has(id string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.instances[id]
+}
+
+func TestRegistration_Reregister(t *testing.T) {
+	registry, server := newFakeRegistry(t)
+
+	var mu sync.Mutex
+	var transitions []string
+	onChange := func(from, to RegistrationState, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		transitions = append(transitions, string(from)+"->"+string(to))
+	}
+
+	client := NewClient(server.URL)
+	instance := &Instance{ServiceName: "order-service", InstanceID: "instance-001", IPAddress: "10.0.0.1", Port: 8080, TTL: 1}
+	registration, err := client.Start(context.Background(), instance, onChange)
+	require.NoError(t, err)
+	assert.Equal(t, StateRegistered, registration.State())
+	assert.True(t, registry.has("instance-001"))
+
+	// 服务端丢失实例后，下一次心跳发现并自动重新注册
+	registry.lose("instance-001")
+	assert.Eventually(t, func() bool {
+		registry.mu.Lock()
+		defer registry.mu.Unlock()
+		return registry.registered >= 2 && registry.instances["instance-001"]
+	}, 3*time.Second, 20*time.Millisecond)
+	assert.Eventually(t, func() bool { return registration.State() == StateRegistered }, time.Second, 10*time.Millisecond)
+
+	require.NoError(t, registration.Stop(context.Background()))
+	assert.Equal(t, StateDeregistered, registration.State())
+	assert.False(t, registry.has("instance-001"))
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{
+		"registering->registered",
+		"registered->lost",
+		"lost->registering",
+		"registering->registered",
+		"registered->deregistered",
+	}, transitions)
+}
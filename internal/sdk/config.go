@@ -0,0 +1,208 @@
+package sdk
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// 从环境变量读取配置时使用的变量名
+const (
+	EnvAddr        = "KONG_DISCOVERY_ADDR" // 服务注册API地址，如 http://kong-discovery:8081，多个地址用逗号分隔
+	EnvServiceName = "SERVICE_NAME"        // 服务名称
+	EnvNamespace   = "NAMESPACE"           // 命名空间
+	EnvInstanceID  = "INSTANCE_ID"         // 实例ID，未设置时根据主机名生成
+	EnvIPAddress   = "SERVICE_IP"          // 注册的IP地址，未设置时自动探测
+	EnvPort        = "SERVICE_PORT"        // 注册的端口
+	EnvPortAlt     = "PORT"                // 未设置SERVICE_PORT时使用的端口变量（十二要素应用的惯例）
+	EnvTTL         = "SERVICE_TTL"         // 租约TTL（秒）
+	EnvMetadata    = "SERVICE_METADATA"    // 元数据，格式为 key1=value1,key2=value2
+)
+
+// 默认的服务注册API地址和租约TTL
+const (
+	defaultAddr = "http://127.0.0.1:8081"
+	defaultTTL  = 30
+)
+
+// Config 服务注册的配置，可以直接填写，也可以通过ConfigFromEnv从环境变量读取
+type Config struct {
+	Addr        string            // 服务注册API地址，多个副本的地址用逗号分隔
+	ServiceName string            // 服务名称
+	Namespace   string            // 命名空间，为空表示default
+	InstanceID  string            // 实例ID，为空时根据主机名生成
+	IPAddress   string            // 注册的IP地址，为空时自动探测本机的主要非回环地址
+	Port        int               // 注册的端口
+	TTL         int               // 租约TTL（秒），为0时使用30秒
+	Metadata    map[string]string // 元数据
+}
+
+// ConfigFromEnv 从环境变量读取配置，未设置的变量保持为空，由Instance填充默认值
+func ConfigFromEnv() (*Config, error) {
+	cfg := &Config{
+		Addr:        os.Getenv(EnvAddr),
+		ServiceName: os.Getenv(EnvServiceName),
+		Namespace:   os.Getenv(EnvNamespace),
+		InstanceID:  os.Getenv(EnvInstanceID),
+		IPAddress:   os.Getenv(EnvIPAddress),
+	}
+
+	port := os.Getenv(EnvPort)
+	name := EnvPort
+	if port == "" {
+		port, name = os.Getenv(EnvPortAlt), EnvPortAlt
+	}
+	if port != "" {
+		value, err := strconv.Atoi(port)
+		if err != nil || value <= 0 || value > 65535 {
+			return nil, fmt.Errorf("环境变量%s不是有效的端口: %s", name, port)
+		}
+		cfg.Port = value
+	}
+
+	if ttl := os.Getenv(EnvTTL); ttl != "" {
+		value, err := strconv.Atoi(ttl)
+		if err != nil || value <= 0 {
+			return nil, fmt.Errorf("环境变量%s不是有效的TTL: %s", EnvTTL, ttl)
+		}
+		cfg.TTL = value
+	}
+
+	if metadata := os.Getenv(EnvMetadata); metadata != "" {
+		cfg.Metadata = make(map[string]string)
+		for _, pair := range strings.Split(metadata, ",") {
+			key, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+			if !ok || key == "" {
+				return nil, fmt.Errorf("环境变量%s格式错误，应为key=value: %s", EnvMetadata, pair)
+			}
+			cfg.Metadata[key] = value
+		}
+	}
+
+	return cfg, nil
+}
+
+// NewClient 使用配置中的地址创建服务注册API客户端，未设置地址时使用本机默认地址
+func (c *Config) NewClient() *Client {
+	addr := c.Addr
+	if addr == "" {
+		addr = defaultAddr
+	}
+	return NewClient(addr)
+}
+
+// Instance 根据配置生成要注册的服务实例，填充实例ID、IP地址和TTL的默认值
+func (c *Config) Instance() (*Instance, error) {
+	if c.ServiceName == "" {
+		return nil, fmt.Errorf("未设置服务名称（环境变量%s）", EnvServiceName)
+	}
+	if c.Port <= 0 {
+		return nil, fmt.Errorf("未设置服务端口（环境变量%s或%s）", EnvPort, EnvPortAlt)
+	}
+
+	instance := &Instance{
+		ServiceName: c.ServiceName,
+		InstanceID:  c.InstanceID,
+		IPAddress:   c.IPAddress,
+		Port:        c.Port,
+		TTL:         c.TTL,
+		Namespace:   c.Namespace,
+		Metadata:    c.Metadata,
+	}
+
+	if instance.InstanceID == "" {
+		hostname, _ := os.Hostname()
+		if hostname == "" {
+			hostname = c.ServiceName
+		}
+		instance.InstanceID = hostname + "-" + uuid.New().String()[:8]
+	}
+	if instance.IPAddress == "" {
+		ip, err := DetectIP()
+		if err != nil {
+			return nil, err
+		}
+		instance.IPAddress = ip
+	}
+	if instance.TTL <= 0 {
+		instance.TTL = defaultTTL
+	}
+	return instance, nil
+}
+
+// DetectIP 探测本机的主要非回环地址
+// 优先使用访问外部地址时选择的出口地址（不会实际发送数据），无默认路由时取第一个可用网卡的IPv4地址，
+// 只有IPv6地址的主机（IPv6单栈）使用第一个全局单播IPv6地址
+func DetectIP() (string, error) {
+	if conn, err := net.Dial("udp", "8.8.8.8:53"); err == nil {
+		addr := conn.LocalAddr().(*net.UDPAddr)
+		conn.Close()
+		if !addr.IP.IsLoopback() && !addr.IP.IsUnspecified() {
+			return addr.IP.String(), nil
+		}
+	}
+
+	interfaces, err := net.Interfaces()
+	if err != nil {
+		return "", fmt.Errorf("获取网卡列表失败: %w", err)
+	}
+	var ipv6 string
+	for _, iface := range interfaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, addr := range addrs {
+			ipNet, ok := addr.(*net.IPNet)
+			if !ok || !ipNet.IP.IsGlobalUnicast() {
+				continue
+			}
+			if ipNet.IP.To4() != nil {
+				return ipNet.IP.String(), nil
+			}
+			if ipv6 == "" {
+				ipv6 = ipNet.IP.String()
+			}
+		}
+	}
+	if ipv6 != "" {
+		return ipv6, nil
+	}
+	return "", errors.New("未找到可用的非回环IP地址，请通过环境变量" + EnvIPAddress + "指定")
+}
+
+// PortFromListener 返回监听器实际绑定的端口，适用于以:0监听、由系统分配端口的服务
+func PortFromListener(ln net.Listener) (int, error) {
+	addr, ok := ln.Addr().(*net.TCPAddr)
+	if !ok {
+		return 0, fmt.Errorf("不是TCP监听器: %s", ln.Addr())
+	}
+	return addr.Port, nil
+}
+
+// PortFromServer 从HTTP服务器的监听地址（如 :8080）中读取端口
+func PortFromServer(srv *http.Server) (int, error) {
+	addr := srv.Addr
+	if addr == "" {
+		// 与http.Server.ListenAndServe的默认值一致
+		addr = ":http"
+	}
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return 0, fmt.Errorf("解析服务器监听地址失败: %w", err)
+	}
+	value, err := net.LookupPort("tcp", port)
+	if err != nil || value == 0 {
+		return 0, fmt.Errorf("服务器监听地址中没有固定端口: %s", srv.Addr)
+	}
+	return value, nil
+}
@@ -0,0 +1,87 @@
+package sdk
+
+import (
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigFromEnv(t *testing.T) {
+	t.Setenv(EnvAddr, "http://discovery:8081")
+	t.Setenv(EnvServiceName, "order-service")
+	t.Setenv(EnvNamespace, "prod")
+	t.Setenv(EnvIPAddress, "10.0.0.5")
+	t.Setenv(EnvPort, "")
+	t.Setenv(EnvPortAlt, "9090")
+	t.Setenv(EnvTTL, "15")
+	t.Setenv(EnvMetadata, "version=1.2.0, zone=a")
+
+	cfg, err := ConfigFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, "http://discovery:8081", cfg.Addr)
+	assert.Equal(t, 9090, cfg.Port, "未设置SERVICE_PORT时使用PORT")
+	assert.Equal(t, map[string]string{"version": "1.2.0", "zone": "a"}, cfg.Metadata)
+
+	instance, err := cfg.Instance()
+	require.NoError(t, err)
+	assert.Equal(t, "order-service", instance.ServiceName)
+	assert.Equal(t, "prod", instance.Namespace)
+	assert.Equal(t, "10.0.0.5", instance.IPAddress)
+	assert.Equal(t, 15, instance.TTL)
+	assert.NotEmpty(t, instance.InstanceID, "未设置实例ID时自动生成")
+
+	// SERVICE_PORT优先于PORT
+	t.Setenv(EnvPort, "8080")
+	cfg, err = ConfigFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, 8080, cfg.Port)
+
+	// 无效的值
+	t.Setenv(EnvPort, "http")
+	_, err = ConfigFromEnv()
+	assert.Error(t, err)
+	t.Setenv(EnvPort, "8080")
+	t.Setenv(EnvMetadata, "version")
+	_, err = ConfigFromEnv()
+	assert.Error(t, err)
+}
+
+func TestConfig_Instance_Defaults(t *testing.T) {
+	_, err := (&Config{Port: 8080}).Instance()
+	assert.Error(t, err, "缺少服务名称")
+	_, err = (&Config{ServiceName: "order-service"}).Instance()
+	assert.Error(t, err, "缺少端口")
+
+	instance, err := (&Config{ServiceName: "order-service", Port: 8080}).Instance()
+	if err != nil {
+		t.Skipf("当前环境没有可用的非回环地址: %v", err)
+	}
+	assert.Equal(t, defaultTTL, instance.TTL)
+	ip := net.ParseIP(instance.IPAddress)
+	require.NotNil(t, ip)
+	assert.False(t, ip.IsLoopback())
+}
+
+func TestPortDetection(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+
+	port, err := PortFromListener(ln)
+	require.NoError(t, err)
+	assert.Equal(t, ln.Addr().(*net.TCPAddr).Port, port)
+
+	port, err = PortFromServer(&http.Server{Addr: ":8080"})
+	require.NoError(t, err)
+	assert.Equal(t, 8080, port)
+
+	port, err = PortFromServer(&http.Server{})
+	require.NoError(t, err)
+	assert.Equal(t, 80, port)
+
+	_, err = PortFromServer(&http.Server{Addr: ":0"})
+	assert.Error(t, err)
+}
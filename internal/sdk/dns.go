@@ -0,0 +1,288 @@
+package sdk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hewenyu/kong-discovery/internal/config"
+	"github.com/miekg/dns"
+	"go.uber.org/zap"
+)
+
+// ErrNoRecords 域名没有可用的DNS记录
+var ErrNoRecords = errors.New("没有可用的DNS记录")
+
+// Endpoint 通过SRV记录发现的服务地址
+type Endpoint struct {
+	IP       string // 目标的IP地址
+	Port     int    // 端口
+	Target   string // SRV记录的目标域名
+	Priority uint16 // SRV优先级
+	Weight   uint16 // SRV权重
+}
+
+// Addr 返回 ip:port 形式的地址
+func (e Endpoint) Addr() string {
+	return net.JoinHostPort(e.IP, strconv.Itoa(e.Port))
+}
+
+// dnsCacheEntry 缓存的解析结果，在记录的最小TTL之后过期
+type dnsCacheEntry struct {
+	endpoints  []Endpoint
+	fetched    time.Time
+	ttl        time.Duration
+	refreshing bool
+}
+
+// DNSDiscovery 通过kong-discovery的DNS服务发现服务，按DNS应答的TTL缓存解析结果
+type DNSDiscovery struct {
+	server       string // DNS服务器地址 host:port
+	client       *dns.Client
+	logger       config.Logger
+	refreshAhead float64
+
+	mu    sync.Mutex
+	cache map[string]*dnsCacheEntry
+	now   func() time.Time
+}
+
+// NewDNSDiscovery 创建DNS服务发现客户端，server为DNS服务器地址，如 127.0.0.1:53
+func NewDNSDiscovery(server string) *DNSDiscovery {
+	return &DNSDiscovery{
+		server: server,
+		client: &dns.Client{Timeout: defaultRequestTimeout},
+		cache:  make(map[string]*dnsCacheEntry),
+		now:    time.Now,
+	}
+}
+
+// SetLogger 设置日志记录器，日志使用sdk组件的级别；未设置时不输出日志
+func (d *DNSDiscovery) SetLogger(logger config.Logger) {
+	d.logger = logger.Named(config.ComponentSDK)
+}
+
+// SetRefreshAhead 开启提前刷新：缓存的结果在经过TTL的fraction比例后被读取时，
+// 立即返回缓存的结果并在后台重新解析，使缓存在过期前得到更新。fraction取值(0,1)，0表示关闭
+func (d *DNSDiscovery) SetRefreshAhead(fraction float64) {
+	if fraction < 0 || fraction >= 1 {
+		fraction = 0
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.refreshAhead = fraction
+}
+
+// ResolveHost 解析域名的A和AAAA记录，返回IP地址列表（IPv4在前）
+// 只要有一种地址族解析成功就返回结果
+func (d *DNSDiscovery) ResolveHost(ctx context.Context, host string) ([]string, error) {
+	var ips []string
+	var firstErr error
+	for _, qtype := range []uint16{dns.TypeA, dns.TypeAAAA} {
+		endpoints, err := d.resolve(ctx, qtype, host)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		for _, e := range endpoints {
+			ips = append(ips, e.IP)
+		}
+	}
+	if len(ips) == 0 {
+		return nil, firstErr
+	}
+	return ips, nil
+}
+
+// ResolveService 解析服务域名的SRV记录，返回每个实例的IP和端口
+// 如 order-service.default.svc.cluster.local；目标的IP优先取自应答的附加段，没有时再单独解析
+func (d *DNSDiscovery) ResolveService(ctx context.Context, service string) ([]Endpoint, error) {
+	return d.resolve(ctx, dns.TypeSRV, service)
+}
+
+// Invalidate 删除域名的缓存，下次查询时重新解析
+func (d *DNSDiscovery) Invalidate(name string) {
+	name = dns.Fqdn(strings.ToLower(name))
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.cache, cacheKey(dns.TypeA, name))
+	delete(d.cache, cacheKey(dns.TypeAAAA, name))
+	delete(d.cache, cacheKey(dns.TypeSRV, name))
+}
+
+// cacheKey 生成缓存键
+func cacheKey(qtype uint16, name string) string {
+	return dns.TypeToString[qtype] + " " + name
+}
+
+// resolve 优先返回未过期的缓存，否则查询DNS并按TTL缓存结果；解析失败的结果不缓存
+func (d *DNSDiscovery) resolve(ctx context.Context, qtype uint16, name string) ([]Endpoint, error) {
+	name = dns.Fqdn(strings.ToLower(name))
+	key := cacheKey(qtype, name)
+
+	d.mu.Lock()
+	if entry, ok := d.cache[key]; ok {
+		age := d.now().Sub(entry.fetched)
+		if age < entry.ttl {
+			if d.refreshAhead > 0 && !entry.refreshing && age >= time.Duration(float64(entry.ttl)*d.refreshAhead) {
+				entry.refreshing = true
+				go d.refresh(qtype, name, entry)
+			}
+			d.mu.Unlock()
+			return entry.endpoints, nil
+		}
+		delete(d.cache, key)
+	}
+	d.mu.Unlock()
+
+	endpoints, ttl, err := d.lookup(ctx, qtype, name)
+	if err != nil {
+		return nil, err
+	}
+	d.store(key, endpoints, ttl)
+	return endpoints, nil
+}
+
+// refresh 在后台重新解析即将过期的缓存
+func (d *DNSDiscovery) refresh(qtype uint16, name string, entry *dnsCacheEntry) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultRequestTimeout)
+	defer cancel()
+
+	endpoints, ttl, err := d.lookup(ctx, qtype, name)
+	if err != nil {
+		if d.logger != nil {
+			d.logger.Debug("提前刷新DNS缓存失败", zap.String("name", name), zap.Error(err))
+		}
+		// 保留原有缓存直到过期，之后的读取会再次触发刷新
+		d.mu.Lock()
+		entry.refreshing = false
+		d.mu.Unlock()
+		return
+	}
+	d.store(cacheKey(qtype, name), endpoints, ttl)
+}
+
+// store 缓存解析结果，TTL为0时不缓存
+func (d *DNSDiscovery) store(key string, endpoints []Endpoint, ttl time.Duration) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if ttl <= 0 {
+		delete(d.cache, key)
+		return
+	}
+	d.cache[key] = &dnsCacheEntry{endpoints: endpoints, fetched: d.now(), ttl: ttl}
+}
+
+// lookup 查询DNS服务器，返回解析结果和应答中记录的最小TTL
+func (d *DNSDiscovery) lookup(ctx context.Context, qtype uint16, name string) ([]Endpoint, time.Duration, error) {
+	resp, err := d.exchange(ctx, qtype, name)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var endpoints []Endpoint
+	minTTL := ^uint32(0)
+	for _, rr := range resp.Answer {
+		switch record := rr.(type) {
+		case *dns.A:
+			endpoints = append(endpoints, Endpoint{IP: record.A.String(), Target: name})
+		case *dns.AAAA:
+			endpoints = append(endpoints, Endpoint{IP: record.AAAA.String(), Target: name})
+		case *dns.SRV:
+			endpoints = append(endpoints, Endpoint{
+				Port:     int(record.Port),
+				Target:   strings.ToLower(record.Target),
+				Priority: record.Priority,
+				Weight:   record.Weight,
+			})
+		default:
+			continue
+		}
+		if rr.Header().Ttl < minTTL {
+			minTTL = rr.Header().Ttl
+		}
+	}
+	if len(endpoints) == 0 {
+		return nil, 0, fmt.Errorf("%w: %s %s", ErrNoRecords, dns.TypeToString[qtype], name)
+	}
+
+	if qtype == dns.TypeSRV {
+		// 附加段中的A和AAAA记录作为目标的地址
+		glue := make(map[string]string)
+		for _, rr := range resp.Extra {
+			var ip string
+			switch record := rr.(type) {
+			case *dns.A:
+				ip = record.A.String()
+			case *dns.AAAA:
+				ip = record.AAAA.String()
+			default:
+				continue
+			}
+			name := strings.ToLower(rr.Header().Name)
+			if _, ok := glue[name]; !ok {
+				glue[name] = ip
+			}
+			if rr.Header().Ttl < minTTL {
+				minTTL = rr.Header().Ttl
+			}
+		}
+
+		resolved := endpoints[:0]
+		for _, e := range endpoints {
+			e.IP = glue[e.Target]
+			if e.IP == "" {
+				ips, ttl, err := d.lookup(ctx, dns.TypeA, e.Target)
+				if errors.Is(err, ErrNoRecords) {
+					ips, ttl, err = d.lookup(ctx, dns.TypeAAAA, e.Target)
+				}
+				if err != nil {
+					if d.logger != nil {
+						d.logger.Debug("解析SRV目标失败", zap.String("target", e.Target), zap.Error(err))
+					}
+					continue
+				}
+				e.IP = ips[0].IP
+				if seconds := uint32(ttl / time.Second); seconds < minTTL {
+					minTTL = seconds
+				}
+			}
+			resolved = append(resolved, e)
+		}
+		if len(resolved) == 0 {
+			return nil, 0, fmt.Errorf("%w: 无法解析 %s 的SRV目标", ErrNoRecords, name)
+		}
+		endpoints = resolved
+	}
+
+	return endpoints, time.Duration(minTTL) * time.Second, nil
+}
+
+// exchange 发送DNS查询，UDP应答被截断时改用TCP
+func (d *DNSDiscovery) exchange(ctx context.Context, qtype uint16, name string) (*dns.Msg, error) {
+	m := new(dns.Msg)
+	m.SetQuestion(name, qtype)
+
+	resp, _, err := d.client.ExchangeContext(ctx, m, d.server)
+	if err == nil && resp.Truncated {
+		tcp := &dns.Client{Net: "tcp", Timeout: d.client.Timeout}
+		resp, _, err = tcp.ExchangeContext(ctx, m, d.server)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查询DNS服务器 %s 失败: %w", d.server, err)
+	}
+	if resp.Rcode == dns.RcodeNameError {
+		return nil, fmt.Errorf("%w: %s 不存在", ErrNoRecords, name)
+	}
+	if resp.Rcode != dns.RcodeSuccess {
+		return nil, fmt.Errorf("DNS服务器返回错误 %s: %s", dns.RcodeToString[resp.Rcode], name)
+	}
+	return resp, nil
+}
@@ -0,0 +1,156 @@
+package sdk
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// startTestDNSServer 启动本地DNS服务器，返回地址和收到的查询数
+func startTestDNSServer(t *testing.T, handler dns.HandlerFunc) (string, *int32) {
+	var queries int32
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	started := make(chan struct{})
+	server := &dns.Server{
+		PacketConn:        pc,
+		NotifyStartedFunc: func() { close(started) },
+		Handler: dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
+			atomic.AddInt32(&queries, 1)
+			handler(w, r)
+		}),
+	}
+	go func() { _ = server.ActivateAndServe() }()
+	<-started
+	t.Cleanup(func() { _ = server.Shutdown() })
+	return pc.LocalAddr().String(), &queries
+}
+
+func mustRR(t *testing.T, s string) dns.RR {
+	rr, err := dns.NewRR(s)
+	require.NoError(t, err)
+	return rr
+}
+
+func TestDNSDiscovery_ResolveService(t *testing.T) {
+	const service = "order-service.default.svc.cluster.local."
+	addr, queries := startTestDNSServer(t, func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		q := r.Question[0]
+		switch {
+		case q.Qtype == dns.TypeSRV && q.Name == service:
+			m.Answer = append(m.Answer,
+				mustRR(t, "order-service.default.svc.cluster.local. 30 SRV 10 10 8080 i-1.order-service.default.svc.cluster.local."),
+				mustRR(t, "order-service.default.svc.cluster.local. 30 SRV 10 10 9090 i-2.order-service.default.svc.cluster.local."))
+			// 只有i-1带附加段，i-2需要单独解析
+			m.Extra = append(m.Extra, mustRR(t, "i-1.order-service.default.svc.cluster.local. 30 A 10.0.0.1"))
+		case q.Qtype == dns.TypeA && q.Name == "i-2.order-service.default.svc.cluster.local.":
+			m.Answer = append(m.Answer, mustRR(t, "i-2.order-service.default.svc.cluster.local. 20 A 10.0.0.2"))
+		default:
+			m.SetRcode(r, dns.RcodeNameError)
+		}
+		_ = w.WriteMsg(m)
+	})
+
+	discovery := NewDNSDiscovery(addr)
+	now := time.Unix(1000, 0)
+	discovery.now = func() time.Time { return now }
+
+	endpoints, err := discovery.ResolveService(context.Background(), "Order-Service.default.svc.cluster.local")
+	require.NoError(t, err)
+	require.Len(t, endpoints, 2)
+	assert.Equal(t, "10.0.0.1:8080", endpoints[0].Addr())
+	assert.Equal(t, "10.0.0.2:9090", endpoints[1].Addr())
+	assert.Equal(t, int32(2), atomic.LoadInt32(queries))
+
+	// TTL内使用缓存，缓存时间取所有记录的最小TTL
+	now = now.Add(19 * time.Second)
+	_, err = discovery.ResolveService(context.Background(), service)
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(queries))
+
+	now = now.Add(time.Second)
+	_, err = discovery.ResolveService(context.Background(), service)
+	require.NoError(t, err)
+	assert.Equal(t, int32(4), atomic.LoadInt32(queries), "缓存过期后重新解析")
+
+	// 不存在的域名返回ErrNoRecords且不缓存
+	_, err = discovery.ResolveService(context.Background(), "missing.default.svc.cluster.local")
+	assert.ErrorIs(t, err, ErrNoRecords)
+}
+
+func TestDNSDiscovery_DualStack(t *testing.T) {
+	addr, _ := startTestDNSServer(t, func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		q := r.Question[0]
+		switch q.Qtype {
+		case dns.TypeA:
+			m.Answer = append(m.Answer, mustRR(t, q.Name+" 30 A 10.0.0.1"))
+		case dns.TypeAAAA:
+			m.Answer = append(m.Answer, mustRR(t, q.Name+" 30 AAAA 2001:db8::1"))
+		case dns.TypeSRV:
+			m.Answer = append(m.Answer, mustRR(t, q.Name+" 30 SRV 10 10 8080 i-6."+q.Name))
+			m.Extra = append(m.Extra, mustRR(t, "i-6."+q.Name+" 30 AAAA 2001:db8::6"))
+		}
+		_ = w.WriteMsg(m)
+	})
+
+	discovery := NewDNSDiscovery(addr)
+	ips, err := discovery.ResolveHost(context.Background(), "web.default.svc.cluster.local")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.1", "2001:db8::1"}, ips)
+
+	// IPv6实例的地址带方括号
+	endpoints, err := discovery.ResolveService(context.Background(), "web.default.svc.cluster.local")
+	require.NoError(t, err)
+	require.Len(t, endpoints, 1)
+	assert.Equal(t, "[2001:db8::6]:8080", endpoints[0].Addr())
+}
+
+func TestDNSDiscovery_RefreshAhead(t *testing.T) {
+	var ip atomic.Value
+	ip.Store("10.0.0.1")
+	var queries int32
+	addr, _ := startTestDNSServer(t, func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		// 只有IPv4地址，AAAA查询返回空应答
+		if r.Question[0].Qtype == dns.TypeA {
+			atomic.AddInt32(&queries, 1)
+			m.Answer = append(m.Answer, mustRR(t, r.Question[0].Name+" 10 A "+ip.Load().(string)))
+		}
+		_ = w.WriteMsg(m)
+	})
+
+	discovery := NewDNSDiscovery(addr)
+	discovery.SetRefreshAhead(0.8)
+	var now atomic.Int64
+	now.Store(time.Unix(1000, 0).UnixNano())
+	discovery.now = func() time.Time { return time.Unix(0, now.Load()) }
+
+	ips, err := discovery.ResolveHost(context.Background(), "db.internal")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.1"}, ips)
+
+	// 超过TTL的80%后读取，立即返回缓存并在后台刷新
+	ip.Store("10.0.0.2")
+	now.Add(int64(9 * time.Second))
+	ips, err = discovery.ResolveHost(context.Background(), "db.internal")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.1"}, ips)
+
+	assert.Eventually(t, func() bool {
+		ips, err := discovery.ResolveHost(context.Background(), "db.internal")
+		return err == nil && ips[0] == "10.0.0.2"
+	}, 2*time.Second, 10*time.Millisecond)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&queries), "刷新后的缓存从刷新时开始计算TTL")
+}
@@ -0,0 +1,89 @@
+package sdk
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// endpointCooldown 地址请求失败后暂停使用的时间，期间优先使用其他地址
+const endpointCooldown = 10 * time.Second
+
+// endpointPool 服务注册API的多个地址，请求固定发往当前地址，失败时切换到下一个健康的地址
+type endpointPool struct {
+	mu        sync.Mutex
+	urls      []string
+	downUntil []time.Time // 各地址暂停使用的截止时间
+	current   int
+	now       func() time.Time
+}
+
+// newEndpointPool 解析逗号分隔的地址列表，如 http://10.0.0.1:8081,http://10.0.0.2:8081
+func newEndpointPool(addrs string) *endpointPool {
+	var urls []string
+	for _, addr := range strings.Split(addrs, ",") {
+		if addr = strings.TrimRight(strings.TrimSpace(addr), "/"); addr != "" {
+			urls = append(urls, addr)
+		}
+	}
+	if len(urls) == 0 {
+		// 保持与单地址时的行为一致，请求时由http包报告地址错误
+		urls = []string{""}
+	}
+	return &endpointPool{
+		urls:      urls,
+		downUntil: make([]time.Time, len(urls)),
+		now:       time.Now,
+	}
+}
+
+// size 返回地址数量
+func (p *endpointPool) size() int {
+	return len(p.urls)
+}
+
+// pick 返回当前使用的地址及其序号
+// 当前地址处于暂停期时切换到下一个健康的地址；全部暂停时选择最早恢复的地址
+func (p *endpointPool) pick() (int, string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.now()
+	if now.Before(p.downUntil[p.current]) {
+		earliest := p.current
+		for i := 1; i < len(p.urls); i++ {
+			next := (p.current + i) % len(p.urls)
+			if !now.Before(p.downUntil[next]) {
+				earliest = next
+				break
+			}
+			if p.downUntil[next].Before(p.downUntil[earliest]) {
+				earliest = next
+			}
+		}
+		p.current = earliest
+	}
+	return p.current, p.urls[p.current]
+}
+
+// markFailed 标记地址请求失败，暂停使用一段时间；返回是否还有其他健康的地址
+func (p *endpointPool) markFailed(index int) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.now()
+	p.downUntil[index] = now.Add(endpointCooldown)
+	for i := range p.urls {
+		if i != index && !now.Before(p.downUntil[i]) {
+			return true
+		}
+	}
+	return false
+}
+
+// markOK 标记地址请求成功，恢复为健康状态
+func (p *endpointPool) markOK(index int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.downUntil[index] = time.Time{}
+}
@@ -0,0 +1,75 @@
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEndpointPool(t *testing.T) {
+	pool := newEndpointPool(" http://a:8081/, http://b:8081 ,,http://c:8081")
+	require.Equal(t, 3, pool.size())
+
+	now := time.Unix(1000, 0)
+	pool.now = func() time.Time { return now }
+
+	// 请求固定发往当前地址
+	index, url := pool.pick()
+	assert.Equal(t, 0, index)
+	assert.Equal(t, "http://a:8081", url)
+
+	// 失败后切换到下一个健康的地址
+	assert.True(t, pool.markFailed(0))
+	_, url = pool.pick()
+	assert.Equal(t, "http://b:8081", url)
+	assert.True(t, pool.markFailed(1))
+	_, url = pool.pick()
+	assert.Equal(t, "http://c:8081", url)
+
+	// 全部失败时选择最早恢复的地址
+	now = now.Add(time.Second)
+	assert.False(t, pool.markFailed(2))
+	_, url = pool.pick()
+	assert.Equal(t, "http://a:8081", url)
+
+	// 暂停期结束后重新可用，成功的地址保持为当前地址
+	now = now.Add(endpointCooldown)
+	pool.markOK(0)
+	_, url = pool.pick()
+	assert.Equal(t, "http://a:8081", url)
+}
+
+func TestClient_Failover(t *testing.T) {
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	downURL := down.URL
+	down.Close()
+
+	var served int32
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&served, 1)
+		_ = json.NewEncoder(w).Encode(&Response{Success: true})
+	}))
+	defer up.Close()
+
+	// 不重试时也会尝试每个地址
+	client := NewClient(downURL + "," + up.URL)
+	client.SetRetryPolicy(RetryPolicy{MaxAttempts: 1})
+
+	start := time.Now()
+	require.NoError(t, client.Heartbeat(context.Background(), "order-service", "instance-001", 0))
+	assert.Less(t, time.Since(start), time.Second, "有健康地址时不等待退避")
+	assert.Equal(t, int32(1), atomic.LoadInt32(&served))
+
+	// 之后的请求直接发往健康的地址
+	require.NoError(t, client.Heartbeat(context.Background(), "order-service", "instance-001", 0))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&served))
+	index, _ := client.endpoints.pick()
+	assert.Equal(t, 1, index)
+}
@@ -0,0 +1,253 @@
+package sdk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// RegistrationState 托管注册的状态
+type RegistrationState string
+
+// 托管注册的状态
+const (
+	StateRegistering  RegistrationState = "registering"  // 正在注册（首次注册或重新注册）
+	StateRegistered   RegistrationState = "registered"   // 已注册，心跳正常
+	StateDraining     RegistrationState = "draining"     // 已摘流，不再出现在新的DNS应答中，心跳继续直到注销
+	StateLost         RegistrationState = "lost"         // 服务端已没有该实例（租约过期或数据丢失），即将重新注册
+	StateFailing      RegistrationState = "failing"      // 心跳或重新注册失败，稍后重试
+	StateDeregistered RegistrationState = "deregistered" // 已停止并注销
+)
+
+// HealthReporter 每次发送心跳前调用，返回随心跳上报的健康信息，返回nil时不上报
+// 在心跳协程中同步执行，不应长时间阻塞
+type HealthReporter func() *HealthReport
+
+// StateChangeFunc 托管注册状态变化时的回调，err为导致变化的错误（如果有）
+// 回调在心跳协程中同步执行，不应长时间阻塞
+type StateChangeFunc func(from, to RegistrationState, err error)
+
+// errCodeInstanceNotFound 服务端返回的实例不存在错误码
+const errCodeInstanceNotFound = "INSTANCE_NOT_FOUND"
+
+// healthDraining 摘流中的实例健康状态
+const healthDraining = "draining"
+
+// Registration 托管的服务实例注册，按TTL定期发送心跳，实例在服务端丢失时自动重新注册
+type Registration struct {
+	client   *Client
+	instance *Instance
+	interval time.Duration
+	onChange StateChangeFunc
+
+	mu       sync.Mutex
+	state    RegistrationState
+	draining bool // 已摘流，心跳成功和重新注册后保持draining
+	reporter HealthReporter
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// Start 注册服务实例并在后台保持注册，onChange可以为nil
+// 首次注册失败时直接返回错误；之后心跳发现实例丢失（服务端重启、etcd数据丢失、租约过期）时自动重新注册
+func (c *Client) Start(ctx context.Context, instance *Instance, onChange StateChangeFunc) (*Registration, error) {
+	if instance.TTL <= 0 {
+		return nil, fmt.Errorf("TTL必须大于0")
+	}
+
+	r := &Registration{
+		client:   c,
+		instance: instance,
+		// 每个TTL周期发送三次心跳，单次失败不会导致租约过期
+		interval: time.Duration(instance.TTL) * time.Second / 3,
+		onChange: onChange,
+		state:    StateRegistering,
+		done:     make(chan struct{}),
+	}
+
+	if err := c.Register(ctx, instance); err != nil {
+		return nil, err
+	}
+	r.setState(StateRegistered, nil)
+
+	heartbeatCtx, cancel := context.WithCancel(context.Background())
+	r.cancel = cancel
+	go r.run(heartbeatCtx)
+
+	c.mu.Lock()
+	c.registrations[r] = struct{}{}
+	c.mu.Unlock()
+	return r, nil
+}
+
+// Instance 返回托管的服务实例
+func (r *Registration) Instance() *Instance {
+	return r.instance
+}
+
+// State 返回当前的注册状态
+func (r *Registration) State() RegistrationState {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.state
+}
+
+// Stop 停止心跳并注销实例，正在发送的心跳会先完成
+func (r *Registration) Stop(ctx context.Context) error {
+	r.client.mu.Lock()
+	delete(r.client.registrations, r)
+	r.client.mu.Unlock()
+
+	r.cancel()
+	select {
+	case <-r.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	if r.State() == StateDeregistered {
+		return nil
+	}
+	if err := r.client.Deregister(ctx, r.instance.ServiceName, r.instance.InstanceID); err != nil {
+		return err
+	}
+	r.setState(StateDeregistered, nil)
+	return nil
+}
+
+// Drain 把实例的健康状态设为draining，实例不再出现在新的DNS应答中，但心跳继续、租约不会过期，
+// 已建立的连接可以继续处理；之后调用Stop注销。实例在服务端丢失后重新注册时同样以draining注册
+func (r *Registration) Drain(ctx context.Context) error {
+	r.mu.Lock()
+	r.draining = true
+	r.mu.Unlock()
+
+	if err := r.client.SetHealth(ctx, r.instance.ServiceName, r.instance.InstanceID, healthDraining); err != nil {
+		return err
+	}
+	r.setState(StateDraining, nil)
+	return nil
+}
+
+// SetHealthReporter 设置随心跳上报健康信息的函数，reporter为nil时停止上报
+func (r *Registration) SetHealthReporter(reporter HealthReporter) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.reporter = reporter
+}
+
+// healthReport 返回本次心跳上报的健康信息
+func (r *Registration) healthReport() *HealthReport {
+	r.mu.Lock()
+	reporter := r.reporter
+	r.mu.Unlock()
+	if reporter == nil {
+		return nil
+	}
+	return reporter()
+}
+
+// activeState 返回心跳正常时的状态：已摘流时为draining，否则为registered
+func (r *Registration) activeState() RegistrationState {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.draining {
+		return StateDraining
+	}
+	return StateRegistered
+}
+
+// setState 更新状态并在状态变化时调用回调
+func (r *Registration) setState(to RegistrationState, err error) {
+	r.mu.Lock()
+	from := r.state
+	r.state = to
+	r.mu.Unlock()
+
+	if from == to {
+		return
+	}
+	if logger := r.client.logger; logger != nil {
+		logger.Info("服务实例注册状态变化",
+			zap.String("service", r.instance.ServiceName),
+			zap.String("id", r.instance.InstanceID),
+			zap.String("from", string(from)),
+			zap.String("to", string(to)),
+			zap.Error(err))
+	}
+	if r.onChange != nil {
+		r.onChange(from, to, err)
+	}
+}
+
+// run 定期发送心跳，直到Stop被调用
+func (r *Registration) run(ctx context.Context) {
+	defer close(r.done)
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.beat(ctx)
+		}
+	}
+}
+
+// beat 发送一次心跳，实例丢失或上一次失败时重新注册
+// 请求不随ctx取消，Stop时等待正在发送的心跳完成后再注销
+func (r *Registration) beat(ctx context.Context) {
+	reqCtx, cancel := context.WithTimeout(context.Background(), r.interval)
+	defer cancel()
+
+	// 心跳失败后实例可能已经过期，直接重新注册恢复
+	if r.State() == StateFailing {
+		r.reregister(reqCtx, nil)
+		return
+	}
+
+	err := r.client.HeartbeatWithHealth(reqCtx, r.instance.ServiceName, r.instance.InstanceID, 0, r.healthReport())
+	switch {
+	case err == nil:
+		r.setState(r.activeState(), nil)
+	case ctx.Err() != nil:
+		// 正在停止
+	case isInstanceNotFound(err):
+		r.setState(StateLost, err)
+		r.reregister(reqCtx, err)
+	default:
+		r.setState(StateFailing, err)
+	}
+}
+
+// reregister 重新注册实例，已摘流时以draining注册，避免实例重新出现在DNS应答中
+func (r *Registration) reregister(ctx context.Context, cause error) {
+	r.setState(StateRegistering, cause)
+	instance := r.instance
+	state := r.activeState()
+	if state == StateDraining {
+		draining := *r.instance
+		draining.Health = healthDraining
+		instance = &draining
+	}
+	if err := r.client.Register(ctx, instance); err != nil {
+		r.setState(StateFailing, err)
+		return
+	}
+	r.setState(state, nil)
+}
+
+// isInstanceNotFound 判断错误是否表示服务端已没有该实例
+func isInstanceNotFound(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && (apiErr.Code == errCodeInstanceNotFound || apiErr.StatusCode == http.StatusNotFound)
+}
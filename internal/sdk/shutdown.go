@@ -0,0 +1,238 @@
+package sdk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// DefaultShutdownTimeout 收到退出信号后清理的默认超时时间
+const DefaultShutdownTimeout = 10 * time.Second
+
+// DefaultDrainDelay 两阶段关闭时摘流后等待的默认最长时间
+const DefaultDrainDelay = 15 * time.Second
+
+// shutdownSignals 触发优雅关闭的信号
+var shutdownSignals = []os.Signal{syscall.SIGINT, syscall.SIGTERM}
+
+// Shutdown 停止客户端托管的所有注册并注销实例，然后等待进行中的请求完成
+// 正在发送的心跳会先完成再注销，避免注销后被心跳重新注册；ctx到期时返回ctx的错误
+func (c *Client) Shutdown(ctx context.Context) error {
+	c.mu.Lock()
+	registrations := make([]*Registration, 0, len(c.registrations))
+	for r := range c.registrations {
+		registrations = append(registrations, r)
+	}
+	c.mu.Unlock()
+
+	var errs []error
+	for _, r := range registrations {
+		if err := r.Stop(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("注销服务实例 %s/%s 失败: %w",
+				r.instance.ServiceName, r.instance.InstanceID, err))
+		}
+	}
+
+	select {
+	case <-c.idleChan():
+	case <-ctx.Done():
+		errs = append(errs, fmt.Errorf("等待进行中的请求完成超时: %w", ctx.Err()))
+	}
+
+	return errors.Join(errs...)
+}
+
+// Drain 把客户端托管的所有注册摘流，实例不再出现在新的DNS应答中，心跳继续直到Shutdown注销
+func (c *Client) Drain(ctx context.Context) error {
+	c.mu.Lock()
+	registrations := make([]*Registration, 0, len(c.registrations))
+	for r := range c.registrations {
+		registrations = append(registrations, r)
+	}
+	c.mu.Unlock()
+
+	var errs []error
+	for _, r := range registrations {
+		if err := r.Drain(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("摘流服务实例 %s/%s 失败: %w",
+				r.instance.ServiceName, r.instance.InstanceID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// beginRequest 记录一个进行中的请求
+func (c *Client) beginRequest() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.inflight == 0 {
+		c.idle = make(chan struct{})
+	}
+	c.inflight++
+}
+
+// endRequest 请求结束，没有进行中的请求时关闭idle
+func (c *Client) endRequest() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.inflight--; c.inflight == 0 {
+		close(c.idle)
+	}
+}
+
+// idleChan 返回在当前所有进行中的请求结束后关闭的通道
+func (c *Client) idleChan() <-chan struct{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.inflight == 0 {
+		done := make(chan struct{})
+		close(done)
+		return done
+	}
+	return c.idle
+}
+
+// RegisterShutdownHook 捕获SIGINT和SIGTERM，收到信号后在timeout内调用Shutdown完成清理
+// 返回的通道在清理完成后收到Shutdown的结果并关闭，调用方可以等待该通道后再退出进程：
+//
+//	done := client.RegisterShutdownHook(sdk.DefaultShutdownTimeout)
+//	...
+//	if err := <-done; err != nil {
+//		log.Printf("优雅关闭失败: %v", err)
+//	}
+//
+// timeout小于等于0时使用DefaultShutdownTimeout；捕获信号后不再执行默认的退出动作
+func (c *Client) RegisterShutdownHook(timeout time.Duration) <-chan error {
+	if timeout <= 0 {
+		timeout = DefaultShutdownTimeout
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, shutdownSignals...)
+
+	done := make(chan error, 1)
+	go func() {
+		defer close(done)
+
+		sig := <-signals
+		signal.Stop(signals)
+		if c.logger != nil {
+			c.logger.Info("收到退出信号，开始注销服务实例", zap.String("signal", sig.String()))
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		err := c.Shutdown(ctx)
+		if err != nil && c.logger != nil {
+			c.logger.Error("优雅关闭失败", zap.Error(err))
+		}
+		done <- err
+	}()
+	return done
+}
+
+// DrainOptions 两阶段关闭的选项
+type DrainOptions struct {
+	Delay   time.Duration // 摘流后等待的最长时间，之后注销；应不短于实例DNS应答的TTL，为0时使用DefaultDrainDelay
+	Timeout time.Duration // 摘流请求和注销各自的超时时间，为0时使用DefaultShutdownTimeout
+}
+
+// DrainHook 两阶段关闭：收到退出信号后先摘流，等待应用处理完已有的请求后再注销
+type DrainHook struct {
+	draining     chan struct{}
+	complete     chan struct{}
+	completeOnce sync.Once
+	done         chan error
+}
+
+// Draining 返回在收到退出信号、摘流请求完成后关闭的通道，应用此时应停止接收新的工作
+func (h *DrainHook) Draining() <-chan struct{} {
+	return h.draining
+}
+
+// Complete 通知已有的请求已处理完，不再等待Delay，立即注销。可以多次调用
+func (h *DrainHook) Complete() {
+	h.completeOnce.Do(func() { close(h.complete) })
+}
+
+// Done 返回的通道在注销完成后收到摘流和注销的结果并关闭，调用方可以等待该通道后再退出进程
+func (h *DrainHook) Done() <-chan error {
+	return h.done
+}
+
+// RegisterDrainHook 捕获SIGINT和SIGTERM，收到信号后分两个阶段关闭：先把所有实例设为draining，
+// 实例不再出现在新的DNS应答中，心跳继续；等待Delay或应用调用Complete后再调用Shutdown注销实例：
+//
+//	hook := client.RegisterDrainHook(sdk.DrainOptions{Delay: 30 * time.Second})
+//	go func() {
+//		<-hook.Draining()
+//		_ = server.Shutdown(context.Background()) // 处理完已有的请求
+//		hook.Complete()
+//	}()
+//	if err := <-hook.Done(); err != nil {
+//		log.Printf("优雅关闭失败: %v", err)
+//	}
+//
+// 摘流失败时仍然等待并注销；捕获信号后不再执行默认的退出动作
+func (c *Client) RegisterDrainHook(opts DrainOptions) *DrainHook {
+	if opts.Delay <= 0 {
+		opts.Delay = DefaultDrainDelay
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultShutdownTimeout
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, shutdownSignals...)
+
+	h := &DrainHook{
+		draining: make(chan struct{}),
+		complete: make(chan struct{}),
+		done:     make(chan error, 1),
+	}
+	go func() {
+		defer close(h.done)
+
+		sig := <-signals
+		signal.Stop(signals)
+		if c.logger != nil {
+			c.logger.Info("收到退出信号，开始摘流服务实例",
+				zap.String("signal", sig.String()),
+				zap.Duration("delay", opts.Delay))
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), opts.Timeout)
+		drainErr := c.Drain(ctx)
+		cancel()
+		if drainErr != nil && c.logger != nil {
+			c.logger.Error("摘流失败", zap.Error(drainErr))
+		}
+		close(h.draining)
+
+		timer := time.NewTimer(opts.Delay)
+		select {
+		case <-timer.C:
+		case <-h.complete:
+			timer.Stop()
+		}
+		if c.logger != nil {
+			c.logger.Info("摘流结束，开始注销服务实例")
+		}
+
+		ctx, cancel = context.WithTimeout(context.Background(), opts.Timeout)
+		defer cancel()
+		err := errors.Join(drainErr, c.Shutdown(ctx))
+		if err != nil && c.logger != nil {
+			c.logger.Error("优雅关闭失败", zap.Error(err))
+		}
+		h.done <- err
+	}()
+	return h
+}
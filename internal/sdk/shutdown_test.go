@@ -0,0 +1,104 @@
+package sdk
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_Shutdown_WaitsForInflight(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		w.Write([]byte(`{"success":true}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	go func() { _ = client.Heartbeat(context.Background(), "order-service", "instance-001", 0) }()
+	time.Sleep(50 * time.Millisecond)
+
+	// 请求未完成时超时返回错误
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, client.Shutdown(ctx), context.DeadlineExceeded)
+
+	// 请求完成后正常返回
+	close(release)
+	assert.NoError(t, client.Shutdown(context.Background()))
+}
+
+func TestClient_RegisterShutdownHook(t *testing.T) {
+	registry, server := newFakeRegistry(t)
+
+	client := NewClient(server.URL)
+	instance := &Instance{ServiceName: "order-service", InstanceID: "instance-001", IPAddress: "10.0.0.1", Port: 8080, TTL: 30}
+	registration, err := client.Start(context.Background(), instance, nil)
+	require.NoError(t, err)
+	require.True(t, registry.has("instance-001"))
+
+	done := client.RegisterShutdownHook(time.Second)
+	require.NoError(t, syscall.Kill(syscall.Getpid(), syscall.SIGTERM))
+
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(3 * time.Second):
+		t.Fatal("收到信号后未完成清理")
+	}
+	assert.Equal(t, StateDeregistered, registration.State())
+	assert.False(t, registry.has("instance-001"))
+
+	// 已停止的注册不再由Shutdown处理
+	assert.NoError(t, client.Shutdown(context.Background()))
+}
+
+func TestClient_RegisterDrainHook(t *testing.T) {
+	registry, server := newFakeRegistry(t)
+
+	client := NewClient(server.URL)
+	instance := &Instance{ServiceName: "order-service", InstanceID: "instance-001", IPAddress: "10.0.0.1", Port: 8080, TTL: 1}
+	registration, err := client.Start(context.Background(), instance, nil)
+	require.NoError(t, err)
+
+	hook := client.RegisterDrainHook(DrainOptions{Delay: time.Minute, Timeout: time.Second})
+	require.NoError(t, syscall.Kill(syscall.Getpid(), syscall.SIGTERM))
+
+	select {
+	case <-hook.Draining():
+	case <-time.After(3 * time.Second):
+		t.Fatal("收到信号后未摘流")
+	}
+	assert.Equal(t, StateDraining, registration.State())
+	registry.mu.Lock()
+	assert.Equal(t, healthDraining, registry.health["instance-001"])
+	registry.mu.Unlock()
+
+	// 摘流期间实例丢失时以draining重新注册，心跳成功后保持draining
+	registry.lose("instance-001")
+	assert.Eventually(t, func() bool {
+		registry.mu.Lock()
+		defer registry.mu.Unlock()
+		return registry.instances["instance-001"] && registry.health["instance-001"] == healthDraining
+	}, 3*time.Second, 20*time.Millisecond)
+	assert.Eventually(t, func() bool { return registration.State() == StateDraining }, time.Second, 10*time.Millisecond)
+	assert.True(t, registry.has("instance-001"), "等待期间不应注销")
+
+	// 应用处理完已有的请求后立即注销，不等待Delay
+	hook.Complete()
+	hook.Complete()
+	select {
+	case err := <-hook.Done():
+		assert.NoError(t, err)
+	case <-time.After(3 * time.Second):
+		t.Fatal("Complete后未注销")
+	}
+	assert.Equal(t, StateDeregistered, registration.State())
+	assert.False(t, registry.has("instance-001"))
+}
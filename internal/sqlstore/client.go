@@ -0,0 +1,30 @@
+package sqlstore
+
+import (
+	"context"
+
+	"github.com/hewenyu/kong-discovery/internal/etcdapi"
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// Status 返回存储的状态，版本号为数据库名称，用于区分SQL存储和etcd
+func (s *Store) Status(ctx context.Context) (*pb.StatusResponse, error) {
+	rev, _, err := s.meta(ctx, s.db)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.StatusResponse{
+		Header:           etcdapi.Header(rev),
+		Version:          s.name,
+		Leader:           etcdapi.MemberID,
+		RaftIndex:        uint64(rev),
+		RaftTerm:         1,
+		RaftAppliedIndex: uint64(rev),
+	}, nil
+}
+
+// NewClient 创建使用该存储的etcd客户端，关闭客户端不影响存储中的数据和其他客户端
+func (s *Store) NewClient() *clientv3.Client {
+	return etcdapi.NewClient(s)
+}
@@ -0,0 +1,132 @@
+package sqlstore
+
+import (
+	"strconv"
+	"strings"
+
+	_ "github.com/jackc/pgx/v5/stdlib" // PostgreSQL驱动，注册为"pgx"
+	_ "github.com/mattn/go-sqlite3"    // SQLite驱动，注册为"sqlite3"，需要CGO
+)
+
+// 支持的数据库
+const (
+	SQLite   = "sqlite"
+	Postgres = "postgres"
+)
+
+// notifyChannel PostgreSQL中通知其他节点有新写入的频道
+const notifyChannel = "kd_changes"
+
+// dialect 不同数据库在驱动、建表语句、加锁和通知方式上的差异
+type dialect struct {
+	driver   string   // database/sql驱动名
+	schema   []string // 建表语句，可以重复执行
+	lockMeta string   // 在写事务中读取并锁定元数据行
+	notify   string   // 在写事务中通知其他节点，为空时其他节点只通过轮询发现写入
+	numbered bool     // 占位符是否为$1、$2……
+}
+
+var dialects = map[string]*dialect{
+	SQLite: {
+		driver: "sqlite3",
+		schema: []string{
+			`CREATE TABLE IF NOT EXISTS kd_kv (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				revision INTEGER NOT NULL,
+				key BLOB NOT NULL,
+				value BLOB,
+				create_revision INTEGER NOT NULL,
+				version INTEGER NOT NULL,
+				lease INTEGER NOT NULL,
+				deleted INTEGER NOT NULL,
+				prev_id INTEGER NOT NULL
+			)`,
+			`CREATE INDEX IF NOT EXISTS kd_kv_key_id ON kd_kv (key, id)`,
+			`CREATE INDEX IF NOT EXISTS kd_kv_revision ON kd_kv (revision)`,
+			`CREATE INDEX IF NOT EXISTS kd_kv_lease ON kd_kv (lease)`,
+			`CREATE TABLE IF NOT EXISTS kd_meta (
+				id INTEGER PRIMARY KEY,
+				revision INTEGER NOT NULL,
+				compacted INTEGER NOT NULL
+			)`,
+			`INSERT INTO kd_meta (id, revision, compacted) VALUES (1, 1, 0) ON CONFLICT (id) DO NOTHING`,
+			`CREATE TABLE IF NOT EXISTS kd_lease (
+				id INTEGER PRIMARY KEY,
+				ttl INTEGER NOT NULL,
+				expires_at INTEGER NOT NULL
+			)`,
+		},
+		// 连接串中的_txlock=immediate使写事务开始时即获得写锁，写操作因此串行执行
+		lockMeta: `SELECT revision, compacted FROM kd_meta WHERE id = 1`,
+	},
+	Postgres: {
+		driver: "pgx",
+		schema: []string{
+			// 多个节点同时启动时串行建表
+			`SELECT pg_advisory_xact_lock(7262)`,
+			`CREATE TABLE IF NOT EXISTS kd_kv (
+				id BIGSERIAL PRIMARY KEY,
+				revision BIGINT NOT NULL,
+				key BYTEA NOT NULL,
+				value BYTEA,
+				create_revision BIGINT NOT NULL,
+				version BIGINT NOT NULL,
+				lease BIGINT NOT NULL,
+				deleted SMALLINT NOT NULL,
+				prev_id BIGINT NOT NULL
+			)`,
+			`CREATE INDEX IF NOT EXISTS kd_kv_key_id ON kd_kv (key, id)`,
+			`CREATE INDEX IF NOT EXISTS kd_kv_revision ON kd_kv (revision)`,
+			`CREATE INDEX IF NOT EXISTS kd_kv_lease ON kd_kv (lease)`,
+			`CREATE TABLE IF NOT EXISTS kd_meta (
+				id INTEGER PRIMARY KEY,
+				revision BIGINT NOT NULL,
+				compacted BIGINT NOT NULL
+			)`,
+			`INSERT INTO kd_meta (id, revision, compacted) VALUES (1, 1, 0) ON CONFLICT (id) DO NOTHING`,
+			`CREATE TABLE IF NOT EXISTS kd_lease (
+				id BIGINT PRIMARY KEY,
+				ttl BIGINT NOT NULL,
+				expires_at BIGINT NOT NULL
+			)`,
+		},
+		lockMeta: `SELECT revision, compacted FROM kd_meta WHERE id = 1 FOR UPDATE`,
+		notify:   `NOTIFY ` + notifyChannel,
+		numbered: true,
+	},
+}
+
+// rebind 把查询中的?占位符转换为数据库使用的形式
+func (d *dialect) rebind(query string) string {
+	if !d.numbered {
+		return query
+	}
+	var b strings.Builder
+	n := 0
+	for _, c := range query {
+		if c == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+// sqliteDSN 补充SQLite连接串的参数：写事务立即加锁、锁等待超时和WAL日志模式（读写互不阻塞），
+// 连接串中已经指定的参数保持不变
+func sqliteDSN(dsn string) string {
+	sep := "?"
+	if strings.Contains(dsn, "?") {
+		sep = "&"
+	}
+	for _, param := range []string{"_txlock=immediate", "_busy_timeout=10000", "_journal_mode=WAL"} {
+		name := param[:strings.IndexByte(param, '=')+1]
+		if !strings.Contains(dsn, name) {
+			dsn += sep + param
+			sep = "&"
+		}
+	}
+	return dsn
+}
@@ -0,0 +1,203 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"time"
+
+	"github.com/hewenyu/kong-discovery/internal/etcdapi"
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+	"google.golang.org/grpc"
+)
+
+// 租约的到期时间以毫秒时间戳保存，多个节点共享数据库时各节点的时钟需要同步
+
+// expiresAt 返回从现在起ttl秒后的到期时间
+func expiresAt(ttl int64) int64 {
+	return time.Now().Add(time.Duration(ttl) * time.Second).UnixMilli()
+}
+
+// LeaseGrant 创建租约，未指定ID时随机分配，避免多个节点分配相同的ID
+func (s *Store) LeaseGrant(ctx context.Context, in *pb.LeaseGrantRequest, _ ...grpc.CallOption) (*pb.LeaseGrantResponse, error) {
+	id := in.ID
+	for id == 0 {
+		id = rand.Int64()
+	}
+	ttl := max(in.TTL, etcdapi.MinLeaseTTL)
+
+	rev, err := s.update(ctx, func(w *write) error {
+		var n int
+		if err := w.tx.QueryRowContext(ctx, s.q(`SELECT COUNT(*) FROM kd_lease WHERE id = ?`), id).Scan(&n); err != nil {
+			return fmt.Errorf("读取租约失败: %w", err)
+		}
+		if n > 0 {
+			return rpctypes.ErrGRPCLeaseExist
+		}
+		_, err := w.tx.ExecContext(ctx, s.q(`INSERT INTO kd_lease (id, ttl, expires_at) VALUES (?, ?, ?)`), id, ttl, expiresAt(ttl))
+		if err != nil {
+			return fmt.Errorf("创建租约失败: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &pb.LeaseGrantResponse{Header: etcdapi.Header(rev), ID: id, TTL: ttl}, nil
+}
+
+// revoke 删除租约及其关联的所有key，这些key在同一个修订版本中删除；
+// expiredBefore大于0时只删除在该时间之前到期的租约，返回租约是否被删除
+func (w *write) revoke(id, expiredBefore int64) (bool, error) {
+	query, args := `DELETE FROM kd_lease WHERE id = ?`, []any{id}
+	if expiredBefore > 0 {
+		query, args = query+` AND expires_at <= ?`, append(args, expiredBefore)
+	}
+	res, err := w.tx.ExecContext(w.ctx, w.s.q(query), args...)
+	if err != nil {
+		return false, fmt.Errorf("删除租约失败: %w", err)
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return false, err
+	}
+
+	rows, err := w.s.kvsAt(w.ctx, w.tx, w.rev, `k.lease = ?`, id)
+	if err != nil {
+		return false, err
+	}
+	return true, w.delete(rows)
+}
+
+// expireLeases 删除已到期的租约，期间被续约的租约不会被删除
+func (s *Store) expireLeases(ctx context.Context) error {
+	now := time.Now().UnixMilli()
+	rows, err := s.db.QueryContext(ctx, s.q(`SELECT id FROM kd_lease WHERE expires_at <= ?`), now)
+	if err != nil {
+		return fmt.Errorf("读取到期租约失败: %w", err)
+	}
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return fmt.Errorf("读取到期租约失败: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("读取到期租约失败: %w", err)
+	}
+
+	for _, id := range ids {
+		_, err := s.update(ctx, func(w *write) error {
+			_, err := w.revoke(id, now)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LeaseRevoke 撤销租约
+func (s *Store) LeaseRevoke(ctx context.Context, in *pb.LeaseRevokeRequest, _ ...grpc.CallOption) (*pb.LeaseRevokeResponse, error) {
+	rev, err := s.update(ctx, func(w *write) error {
+		ok, err := w.revoke(in.ID, 0)
+		if err == nil && !ok {
+			return rpctypes.ErrGRPCLeaseNotFound
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &pb.LeaseRevokeResponse{Header: etcdapi.Header(rev)}, nil
+}
+
+// LeaseKeepAlive 打开续约流
+func (s *Store) LeaseKeepAlive(ctx context.Context, _ ...grpc.CallOption) (pb.Lease_LeaseKeepAliveClient, error) {
+	return etcdapi.NewKeepAliveStream(ctx, s.keepAlive), nil
+}
+
+// keepAlive 续约，租约不存在时返回TTL为0的响应
+func (s *Store) keepAlive(ctx context.Context, id int64) (*pb.LeaseKeepAliveResponse, error) {
+	res, err := s.db.ExecContext(ctx, s.q(`UPDATE kd_lease SET expires_at = ? + ttl * 1000 WHERE id = ?`), time.Now().UnixMilli(), id)
+	if err != nil {
+		return nil, fmt.Errorf("续约失败: %w", err)
+	}
+	rev, _, err := s.meta(ctx, s.db)
+	if err != nil {
+		return nil, err
+	}
+	resp := &pb.LeaseKeepAliveResponse{Header: etcdapi.Header(rev), ID: id}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return resp, nil
+	}
+	err = s.db.QueryRowContext(ctx, s.q(`SELECT ttl FROM kd_lease WHERE id = ?`), id).Scan(&resp.TTL)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("读取租约失败: %w", err)
+	}
+	return resp, nil
+}
+
+// LeaseTimeToLive 查询租约的剩余时间，租约不存在时与etcd一样返回TTL为-1的响应
+func (s *Store) LeaseTimeToLive(ctx context.Context, in *pb.LeaseTimeToLiveRequest, _ ...grpc.CallOption) (*pb.LeaseTimeToLiveResponse, error) {
+	rev, _, err := s.meta(ctx, s.db)
+	if err != nil {
+		return nil, err
+	}
+	resp := &pb.LeaseTimeToLiveResponse{Header: etcdapi.Header(rev), ID: in.ID}
+
+	var expires int64
+	err = s.db.QueryRowContext(ctx, s.q(`SELECT ttl, expires_at FROM kd_lease WHERE id = ?`), in.ID).Scan(&resp.GrantedTTL, &expires)
+	if errors.Is(err, sql.ErrNoRows) {
+		resp.TTL = -1
+		return resp, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取租约失败: %w", err)
+	}
+	resp.TTL = int64(math.Round(time.Until(time.UnixMilli(expires)).Seconds()))
+	if in.Keys {
+		rows, err := s.kvsAt(ctx, s.db, rev, `k.lease = ?`, in.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range rows {
+			resp.Keys = append(resp.Keys, r.kv.Key)
+		}
+	}
+	return resp, nil
+}
+
+// LeaseLeases 列出所有租约
+func (s *Store) LeaseLeases(ctx context.Context, _ *pb.LeaseLeasesRequest, _ ...grpc.CallOption) (*pb.LeaseLeasesResponse, error) {
+	rev, _, err := s.meta(ctx, s.db)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.db.QueryContext(ctx, `SELECT id FROM kd_lease ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("读取租约失败: %w", err)
+	}
+	defer rows.Close()
+
+	resp := &pb.LeaseLeasesResponse{Header: etcdapi.Header(rev)}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("读取租约失败: %w", err)
+		}
+		resp.Leases = append(resp.Leases, &pb.LeaseStatus{ID: id})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("读取租约失败: %w", err)
+	}
+	return resp, nil
+}
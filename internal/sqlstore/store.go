@@ -0,0 +1,568 @@
+// Package sqlstore 在SQL数据库（SQLite或PostgreSQL）上实现etcd的存储接口
+//
+// 与memstore一样，Store实现了etcd客户端使用的KV、租约和监听的gRPC接口，通过NewClient包装为
+// *clientv3.Client后，基于etcd客户端编写的代码无需修改即可运行，用于不能运行etcd的环境。
+// 每次写操作向kd_kv表追加一行（删除时追加一条删除标记），行中记录修订版本，读取历史版本时取
+// 该版本之前每个key的最后一行；写操作通过锁定kd_meta中的修订版本串行执行。
+// 多个节点可以共享同一个数据库：每个节点轮询其他节点写入的新版本并通知本地的监听者，
+// PostgreSQL通过LISTEN/NOTIFY在写入后立即唤醒轮询
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/hewenyu/kong-discovery/internal/config"
+	"github.com/hewenyu/kong-discovery/internal/etcdapi"
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+)
+
+// 选项的默认值
+const (
+	defaultPollInterval = time.Second
+	defaultRetention    = 10000
+)
+
+// 后台任务的执行间隔
+const (
+	leaseCheckInterval = 500 * time.Millisecond
+	compactInterval    = time.Minute
+)
+
+// Options SQL存储的选项
+type Options struct {
+	PollInterval time.Duration // 轮询其他节点写入的间隔
+	Retention    int64         // 保留的历史修订版本数，更早的历史定期压缩
+	Logger       config.Logger // 记录后台任务的错误
+}
+
+// Store SQL数据库中的多版本键值存储
+type Store struct {
+	db      *sql.DB
+	name    string
+	dialect *dialect
+	opts    Options
+	logger  config.Logger
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	kickc  chan struct{}
+
+	mu        sync.Mutex
+	revision  int64 // 已通知监听者的修订版本
+	compacted int64 // 最近一次轮询读取到的压缩版本
+	watchers  map[*etcdapi.Watcher]struct{}
+}
+
+// querier 数据库或事务
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// Open 打开数据库并创建所需的表，name为SQLite或Postgres
+// SQLite的dsn为数据库文件路径，Postgres的dsn为连接URL或键值形式的连接串
+func Open(name, dsn string, opts Options) (*Store, error) {
+	d := dialects[name]
+	if d == nil {
+		return nil, fmt.Errorf("不支持的数据库: %s", name)
+	}
+	if dsn == "" {
+		return nil, fmt.Errorf("未配置%s连接串", name)
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = defaultPollInterval
+	}
+	if opts.Retention <= 0 {
+		opts.Retention = defaultRetention
+	}
+	if name == SQLite {
+		dsn = sqliteDSN(dsn)
+	}
+
+	db, err := sql.Open(d.driver, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("打开%s失败: %w", name, err)
+	}
+	s := &Store{
+		db:       db,
+		name:     name,
+		dialect:  d,
+		opts:     opts,
+		logger:   opts.Logger,
+		kickc:    make(chan struct{}, 1),
+		watchers: make(map[*etcdapi.Watcher]struct{}),
+	}
+	if err := s.migrate(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	if s.revision, s.compacted, err = s.meta(context.Background(), db); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	s.wg.Add(2)
+	go s.pollLoop()
+	go s.maintainLoop()
+	if d.notify != "" {
+		s.wg.Add(1)
+		go s.listenLoop()
+	}
+	return s, nil
+}
+
+// Close 停止后台任务并关闭数据库
+func (s *Store) Close() error {
+	s.cancel()
+	s.wg.Wait()
+	return s.db.Close()
+}
+
+// migrate 创建所需的表
+func (s *Store) migrate() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("连接%s失败: %w", s.name, err)
+	}
+	defer tx.Rollback()
+	for _, stmt := range s.dialect.schema {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("创建%s表失败: %w", s.name, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("创建%s表失败: %w", s.name, err)
+	}
+	return nil
+}
+
+// q 转换查询中的占位符
+func (s *Store) q(query string) string {
+	return s.dialect.rebind(query)
+}
+
+// meta 读取当前修订版本和压缩版本
+func (s *Store) meta(ctx context.Context, q querier) (revision, compacted int64, err error) {
+	err = q.QueryRowContext(ctx, `SELECT revision, compacted FROM kd_meta WHERE id = 1`).Scan(&revision, &compacted)
+	if err != nil {
+		return 0, 0, fmt.Errorf("读取修订版本失败: %w", err)
+	}
+	return revision, compacted, nil
+}
+
+// kvRow kd_kv中的一行
+type kvRow struct {
+	id int64
+	kv *mvccpb.KeyValue
+}
+
+// kvsAt 返回修订版本rev时满足条件的键值，按key排序；cond为k表上的条件
+func (s *Store) kvsAt(ctx context.Context, q querier, rev int64, cond string, args ...any) ([]kvRow, error) {
+	query := `SELECT k.id, k.key, k.value, k.create_revision, k.revision, k.version, k.lease FROM kd_kv k
+		WHERE ` + cond + ` AND k.id = (SELECT MAX(m.id) FROM kd_kv m WHERE m.key = k.key AND m.revision <= ?)
+		AND k.deleted = 0 ORDER BY k.key`
+	rows, err := q.QueryContext(ctx, s.q(query), append(args, rev)...)
+	if err != nil {
+		return nil, fmt.Errorf("读取键值失败: %w", err)
+	}
+	defer rows.Close()
+
+	var result []kvRow
+	for rows.Next() {
+		r := kvRow{kv: &mvccpb.KeyValue{}}
+		if err := rows.Scan(&r.id, &r.kv.Key, &r.kv.Value, &r.kv.CreateRevision, &r.kv.ModRevision, &r.kv.Version, &r.kv.Lease); err != nil {
+			return nil, fmt.Errorf("读取键值失败: %w", err)
+		}
+		result = append(result, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("读取键值失败: %w", err)
+	}
+	return result, nil
+}
+
+// rangeAt 返回修订版本rev时范围内的键值，按key排序
+func (s *Store) rangeAt(ctx context.Context, q querier, r etcdapi.KeyRange, rev int64) ([]kvRow, error) {
+	switch {
+	case r.Single():
+		return s.kvsAt(ctx, q, rev, `k.key = ?`, r.Key)
+	case r.Unbounded():
+		return s.kvsAt(ctx, q, rev, `k.key >= ?`, r.Key)
+	default:
+		return s.kvsAt(ctx, q, rev, `k.key >= ? AND k.key < ?`, r.Key, r.End)
+	}
+}
+
+// read 执行读取请求，不设置响应头；current和compacted为当前修订版本和压缩版本
+func (s *Store) read(ctx context.Context, q querier, in *pb.RangeRequest, current, compacted int64) (*pb.RangeResponse, error) {
+	rev := current
+	if in.Revision > 0 {
+		if in.Revision > current {
+			return nil, rpctypes.ErrGRPCFutureRev
+		}
+		if in.Revision < compacted {
+			return nil, rpctypes.ErrGRPCCompacted
+		}
+		rev = in.Revision
+	}
+	rows, err := s.rangeAt(ctx, q, etcdapi.KeyRange{Key: in.Key, End: in.RangeEnd}, rev)
+	if err != nil {
+		return nil, err
+	}
+	kvs := make([]*mvccpb.KeyValue, 0, len(rows))
+	for _, r := range rows {
+		kvs = append(kvs, r.kv)
+	}
+	return etcdapi.RangeResponse(in, kvs), nil
+}
+
+// Range 读取键值
+func (s *Store) Range(ctx context.Context, in *pb.RangeRequest, _ ...grpc.CallOption) (*pb.RangeResponse, error) {
+	rev, compacted, err := s.meta(ctx, s.db)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.read(ctx, s.db, in, rev, compacted)
+	if err != nil {
+		return nil, err
+	}
+	resp.Header = etcdapi.Header(rev)
+	return resp, nil
+}
+
+// Put 写入键值
+func (s *Store) Put(ctx context.Context, in *pb.PutRequest, _ ...grpc.CallOption) (*pb.PutResponse, error) {
+	var resp *pb.PutResponse
+	rev, err := s.update(ctx, func(w *write) (err error) {
+		resp, err = w.put(in)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	resp.Header = etcdapi.Header(rev)
+	return resp, nil
+}
+
+// DeleteRange 删除范围内的键值
+func (s *Store) DeleteRange(ctx context.Context, in *pb.DeleteRangeRequest, _ ...grpc.CallOption) (*pb.DeleteRangeResponse, error) {
+	var resp *pb.DeleteRangeResponse
+	rev, err := s.update(ctx, func(w *write) (err error) {
+		resp, err = w.deleteRange(in)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	resp.Header = etcdapi.Header(rev)
+	return resp, nil
+}
+
+// Txn 执行事务，事务中的所有修改使用同一个修订版本，任一操作失败时撤销全部修改
+func (s *Store) Txn(ctx context.Context, in *pb.TxnRequest, _ ...grpc.CallOption) (*pb.TxnResponse, error) {
+	var resp *pb.TxnResponse
+	rev, err := s.update(ctx, func(w *write) (err error) {
+		resp, err = w.txn(in)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	etcdapi.SetTxnHeaders(resp, etcdapi.Header(rev))
+	return resp, nil
+}
+
+// Compact 压缩到指定的修订版本，之后无法读取或监听更早的版本
+func (s *Store) Compact(ctx context.Context, in *pb.CompactionRequest, _ ...grpc.CallOption) (*pb.CompactionResponse, error) {
+	rev, err := s.update(ctx, func(w *write) error {
+		if in.Revision > w.current() {
+			return rpctypes.ErrGRPCFutureRev
+		}
+		if in.Revision <= w.compacted {
+			return rpctypes.ErrGRPCCompacted
+		}
+		return w.compact(in.Revision)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &pb.CompactionResponse{Header: etcdapi.Header(rev)}, nil
+}
+
+// write 一次写操作（单个请求或事务），在锁定了修订版本的数据库事务中执行，其中的所有修改使用同一个修订版本
+type write struct {
+	s         *Store
+	ctx       context.Context
+	tx        *sql.Tx
+	rev       int64 // 本次写操作的修订版本
+	compacted int64
+	changed   bool
+}
+
+// update 在数据库事务中执行写操作：有修改时修订版本加一并通知其他节点，返回写操作后的修订版本
+func (s *Store) update(ctx context.Context, fn func(w *write) error) (int64, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("开始事务失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	w := &write{s: s, ctx: ctx, tx: tx}
+	if err := tx.QueryRowContext(ctx, s.dialect.lockMeta).Scan(&w.rev, &w.compacted); err != nil {
+		return 0, fmt.Errorf("读取修订版本失败: %w", err)
+	}
+	w.rev++
+	if err := fn(w); err != nil {
+		return 0, err
+	}
+	if w.changed {
+		if _, err := tx.ExecContext(ctx, s.q(`UPDATE kd_meta SET revision = ? WHERE id = 1`), w.rev); err != nil {
+			return 0, fmt.Errorf("更新修订版本失败: %w", err)
+		}
+		if s.dialect.notify != "" {
+			if _, err := tx.ExecContext(ctx, s.dialect.notify); err != nil {
+				return 0, fmt.Errorf("发送变更通知失败: %w", err)
+			}
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("提交事务失败: %w", err)
+	}
+	if w.changed {
+		s.kick()
+	}
+	return w.current(), nil
+}
+
+// current 返回写操作中当前可见的修订版本
+func (w *write) current() int64 {
+	if w.changed {
+		return w.rev
+	}
+	return w.rev - 1
+}
+
+// insert 追加一行，tombstone表示删除标记
+func (w *write) insert(kv *mvccpb.KeyValue, tombstone bool, prevID int64) error {
+	deleted := 0
+	if tombstone {
+		deleted = 1
+	}
+	_, err := w.tx.ExecContext(w.ctx, w.s.q(`INSERT INTO kd_kv
+		(revision, key, value, create_revision, version, lease, deleted, prev_id) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`),
+		w.rev, kv.Key, kv.Value, kv.CreateRevision, kv.Version, kv.Lease, deleted, prevID)
+	if err != nil {
+		return fmt.Errorf("写入键值失败: %w", err)
+	}
+	w.changed = true
+	return nil
+}
+
+// put 写入键值，检查失败时不做任何修改
+func (w *write) put(in *pb.PutRequest) (*pb.PutResponse, error) {
+	rows, err := w.s.rangeAt(w.ctx, w.tx, etcdapi.KeyRange{Key: in.Key}, w.rev)
+	if err != nil {
+		return nil, err
+	}
+	var prev *kvRow
+	if len(rows) > 0 {
+		prev = &rows[0]
+	}
+	if (in.IgnoreValue || in.IgnoreLease) && prev == nil {
+		return nil, rpctypes.ErrGRPCKeyNotFound
+	}
+
+	kv := &mvccpb.KeyValue{
+		Key:            in.Key,
+		Value:          in.Value,
+		Lease:          in.Lease,
+		CreateRevision: w.rev,
+		ModRevision:    w.rev,
+		Version:        1,
+	}
+	if in.IgnoreValue {
+		kv.Value = prev.kv.Value
+	}
+	if in.IgnoreLease {
+		kv.Lease = prev.kv.Lease
+	}
+	if kv.Lease != 0 {
+		var n int
+		if err := w.tx.QueryRowContext(w.ctx, w.s.q(`SELECT COUNT(*) FROM kd_lease WHERE id = ?`), kv.Lease).Scan(&n); err != nil {
+			return nil, fmt.Errorf("读取租约失败: %w", err)
+		}
+		if n == 0 {
+			return nil, rpctypes.ErrGRPCLeaseNotFound
+		}
+	}
+	var prevID int64
+	if prev != nil {
+		kv.CreateRevision = prev.kv.CreateRevision
+		kv.Version = prev.kv.Version + 1
+		prevID = prev.id
+	}
+	if err := w.insert(kv, false, prevID); err != nil {
+		return nil, err
+	}
+
+	resp := &pb.PutResponse{}
+	if in.PrevKv && prev != nil {
+		resp.PrevKv = prev.kv
+	}
+	return resp, nil
+}
+
+// deleteRange 删除范围内的键值
+func (w *write) deleteRange(in *pb.DeleteRangeRequest) (*pb.DeleteRangeResponse, error) {
+	rows, err := w.s.rangeAt(w.ctx, w.tx, etcdapi.KeyRange{Key: in.Key, End: in.RangeEnd}, w.rev)
+	if err != nil {
+		return nil, err
+	}
+	if err := w.delete(rows); err != nil {
+		return nil, err
+	}
+	resp := &pb.DeleteRangeResponse{Deleted: int64(len(rows))}
+	if in.PrevKv {
+		for _, r := range rows {
+			resp.PrevKvs = append(resp.PrevKvs, r.kv)
+		}
+	}
+	return resp, nil
+}
+
+// delete 为键值追加删除标记
+func (w *write) delete(rows []kvRow) error {
+	for _, r := range rows {
+		if err := w.insert(&mvccpb.KeyValue{Key: r.kv.Key}, true, r.id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// txn 执行事务中的操作，事务中的读取可以看到之前操作的修改
+func (w *write) txn(in *pb.TxnRequest) (*pb.TxnResponse, error) {
+	succeeded := true
+	for _, c := range in.Compare {
+		rows, err := w.s.rangeAt(w.ctx, w.tx, etcdapi.KeyRange{Key: c.Key, End: c.RangeEnd}, w.rev)
+		if err != nil {
+			return nil, err
+		}
+		kvs := make([]*mvccpb.KeyValue, 0, len(rows))
+		for _, r := range rows {
+			kvs = append(kvs, r.kv)
+		}
+		if !etcdapi.Compare(c, kvs) {
+			succeeded = false
+			break
+		}
+	}
+	ops := in.Success
+	if !succeeded {
+		ops = in.Failure
+	}
+
+	resp := &pb.TxnResponse{Succeeded: succeeded, Responses: make([]*pb.ResponseOp, 0, len(ops))}
+	for _, op := range ops {
+		var out *pb.ResponseOp
+		switch r := op.Request.(type) {
+		case *pb.RequestOp_RequestRange:
+			rr, err := w.s.read(w.ctx, w.tx, r.RequestRange, w.current(), w.compacted)
+			if err != nil {
+				return nil, err
+			}
+			out = &pb.ResponseOp{Response: &pb.ResponseOp_ResponseRange{ResponseRange: rr}}
+		case *pb.RequestOp_RequestPut:
+			pr, err := w.put(r.RequestPut)
+			if err != nil {
+				return nil, err
+			}
+			out = &pb.ResponseOp{Response: &pb.ResponseOp_ResponsePut{ResponsePut: pr}}
+		case *pb.RequestOp_RequestDeleteRange:
+			dr, err := w.deleteRange(r.RequestDeleteRange)
+			if err != nil {
+				return nil, err
+			}
+			out = &pb.ResponseOp{Response: &pb.ResponseOp_ResponseDeleteRange{ResponseDeleteRange: dr}}
+		case *pb.RequestOp_RequestTxn:
+			tr, err := w.txn(r.RequestTxn)
+			if err != nil {
+				return nil, err
+			}
+			out = &pb.ResponseOp{Response: &pb.ResponseOp_ResponseTxn{ResponseTxn: tr}}
+		}
+		resp.Responses = append(resp.Responses, out)
+	}
+	return resp, nil
+}
+
+// compact 删除读取和监听rev及之后的版本不再需要的行：rev之前被覆盖的旧值和删除标记，
+// 被rev版本覆盖的旧值作为该版本事件的PrevKv保留
+func (w *write) compact(rev int64) error {
+	_, err := w.tx.ExecContext(w.ctx, w.s.q(`DELETE FROM kd_kv WHERE revision < ? AND (deleted = 1 OR
+		id < (SELECT MAX(m.id) FROM kd_kv m WHERE m.key = kd_kv.key AND m.revision < ?))`), rev, rev)
+	if err != nil {
+		return fmt.Errorf("压缩历史失败: %w", err)
+	}
+	if _, err := w.tx.ExecContext(w.ctx, w.s.q(`UPDATE kd_meta SET compacted = ? WHERE id = 1`), rev); err != nil {
+		return fmt.Errorf("更新压缩版本失败: %w", err)
+	}
+	return nil
+}
+
+// autoCompact 历史超过保留的修订版本数时压缩，多个节点同时压缩时只有一个生效
+func (s *Store) autoCompact(ctx context.Context) error {
+	rev, compacted, err := s.meta(ctx, s.db)
+	if err != nil {
+		return err
+	}
+	target := rev - s.opts.Retention
+	if target <= compacted {
+		return nil
+	}
+	_, err = s.Compact(ctx, &pb.CompactionRequest{Revision: target})
+	if errors.Is(err, rpctypes.ErrGRPCCompacted) {
+		return nil
+	}
+	return err
+}
+
+// maintainLoop 定期删除到期的租约和压缩历史
+func (s *Store) maintainLoop() {
+	defer s.wg.Done()
+
+	leaseTicker := time.NewTicker(leaseCheckInterval)
+	defer leaseTicker.Stop()
+	compactTicker := time.NewTicker(compactInterval)
+	defer compactTicker.Stop()
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-leaseTicker.C:
+			if err := s.expireLeases(s.ctx); err != nil && s.ctx.Err() == nil {
+				s.logger.Error("删除到期租约失败", zap.Error(err))
+			}
+		case <-compactTicker.C:
+			if err := s.autoCompact(s.ctx); err != nil && s.ctx.Err() == nil {
+				s.logger.Error("压缩历史失败", zap.Error(err))
+			}
+		}
+	}
+}
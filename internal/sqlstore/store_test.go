@@ -0,0 +1,400 @@
+package sqlstore
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hewenyu/kong-discovery/internal/config"
+	"github.com/hewenyu/kong-discovery/internal/etcdapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/client/v3/concurrency"
+)
+
+// testDatabase 测试使用的数据库：默认使用临时目录中的SQLite文件，
+// 设置KONG_DISCOVERY_POSTGRES_DSN时使用该PostgreSQL数据库（测试前清空kd_开头的表）
+func testDatabase(t *testing.T) (string, string) {
+	t.Helper()
+	if dsn := os.Getenv("KONG_DISCOVERY_POSTGRES_DSN"); dsn != "" {
+		s, err := Open(Postgres, dsn, Options{Logger: testLogger(t)})
+		require.NoError(t, err)
+		for _, table := range []string{"kd_kv", "kd_lease"} {
+			_, err := s.db.Exec("DELETE FROM " + table)
+			require.NoError(t, err)
+		}
+		_, err = s.db.Exec("UPDATE kd_meta SET revision = 1, compacted = 0")
+		require.NoError(t, err)
+		require.NoError(t, s.Close())
+		return Postgres, dsn
+	}
+	return SQLite, filepath.Join(t.TempDir(), "kong-discovery.db")
+}
+
+func testLogger(t *testing.T) config.Logger {
+	t.Helper()
+	logger, err := config.NewLogger(true)
+	require.NoError(t, err)
+	return logger
+}
+
+// openTestStore 打开数据库上的存储，测试结束时关闭
+func openTestStore(t *testing.T, name, dsn string) (*Store, *clientv3.Client) {
+	t.Helper()
+	s, err := Open(name, dsn, Options{PollInterval: 50 * time.Millisecond, Logger: testLogger(t)})
+	require.NoError(t, err)
+	c := s.NewClient()
+	t.Cleanup(func() {
+		_ = c.Close()
+		_ = s.Close()
+	})
+	return s, c
+}
+
+// newTestClient 创建使用新数据库的客户端
+func newTestClient(t *testing.T) (*Store, *clientv3.Client) {
+	t.Helper()
+	name, dsn := testDatabase(t)
+	return openTestStore(t, name, dsn)
+}
+
+func TestStore_KV(t *testing.T) {
+	_, c := newTestClient(t)
+	ctx := context.Background()
+
+	put, err := c.Put(ctx, "/a/1", "v1")
+	require.NoError(t, err)
+	first := put.Header.Revision
+	_, err = c.Put(ctx, "/a/2", "v2")
+	require.NoError(t, err)
+	put, err = c.Put(ctx, "/a/1", "v1b", clientv3.WithPrevKV())
+	require.NoError(t, err)
+	assert.Equal(t, "v1", string(put.PrevKv.Value))
+
+	resp, err := c.Get(ctx, "/a/", clientv3.WithPrefix())
+	require.NoError(t, err)
+	require.Len(t, resp.Kvs, 2)
+	assert.Equal(t, "v1b", string(resp.Kvs[0].Value))
+	assert.Equal(t, first, resp.Kvs[0].CreateRevision)
+	assert.Equal(t, int64(2), resp.Kvs[0].Version)
+	assert.Equal(t, first+2, resp.Header.Revision)
+
+	// 排序和限制数量
+	resp, err = c.Get(ctx, "/a/", clientv3.WithPrefix(), clientv3.WithSort(clientv3.SortByModRevision, clientv3.SortDescend), clientv3.WithLimit(1))
+	require.NoError(t, err)
+	require.Len(t, resp.Kvs, 1)
+	assert.Equal(t, "/a/1", string(resp.Kvs[0].Key))
+	assert.True(t, resp.More)
+
+	// 按修订版本读取历史
+	resp, err = c.Get(ctx, "/a/1", clientv3.WithRev(first))
+	require.NoError(t, err)
+	assert.Equal(t, "v1", string(resp.Kvs[0].Value))
+	_, err = c.Get(ctx, "/a/1", clientv3.WithRev(first+100))
+	assert.ErrorIs(t, err, rpctypes.ErrFutureRev)
+
+	del, err := c.Delete(ctx, "/a/", clientv3.WithPrefix(), clientv3.WithPrevKV())
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), del.Deleted)
+	assert.Len(t, del.PrevKvs, 2)
+	resp, err = c.Get(ctx, "/a/", clientv3.WithPrefix())
+	require.NoError(t, err)
+	assert.Empty(t, resp.Kvs)
+	resp, err = c.Get(ctx, "/a/", clientv3.WithPrefix(), clientv3.WithRev(first+2))
+	require.NoError(t, err)
+	assert.Len(t, resp.Kvs, 2)
+
+	// 删除后重新写入的key从版本1开始
+	put, err = c.Put(ctx, "/a/1", "v1c")
+	require.NoError(t, err)
+	resp, err = c.Get(ctx, "/a/1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), resp.Kvs[0].Version)
+	assert.Equal(t, put.Header.Revision, resp.Kvs[0].CreateRevision)
+
+	// 压缩后无法读取更早的版本，压缩版本及之后的仍然可以读取
+	_, err = c.Compact(ctx, first+2)
+	require.NoError(t, err)
+	_, err = c.Get(ctx, "/a/1", clientv3.WithRev(first))
+	assert.ErrorIs(t, err, rpctypes.ErrCompacted)
+	resp, err = c.Get(ctx, "/a/", clientv3.WithPrefix(), clientv3.WithRev(first+2))
+	require.NoError(t, err)
+	assert.Len(t, resp.Kvs, 2)
+	resp, err = c.Get(ctx, "/a/1")
+	require.NoError(t, err)
+	assert.Equal(t, "v1c", string(resp.Kvs[0].Value))
+}
+
+func TestStore_Txn(t *testing.T) {
+	_, c := newTestClient(t)
+	ctx := context.Background()
+
+	// key不存在时CreateRevision为0
+	txn, err := c.Txn(ctx).
+		If(clientv3.Compare(clientv3.CreateRevision("/lock"), "=", 0)).
+		Then(clientv3.OpPut("/lock", "owner-1"), clientv3.OpPut("/lock-count", "1"), clientv3.OpGet("/lock")).
+		Commit()
+	require.NoError(t, err)
+	require.True(t, txn.Succeeded)
+	assert.Equal(t, "owner-1", string(txn.Responses[2].GetResponseRange().Kvs[0].Value))
+
+	// 事务中的修改使用同一个修订版本
+	resp, err := c.Get(ctx, "/lock", clientv3.WithPrefix())
+	require.NoError(t, err)
+	assert.Equal(t, resp.Kvs[0].ModRevision, resp.Kvs[1].ModRevision)
+	assert.Equal(t, txn.Header.Revision, resp.Header.Revision)
+
+	txn, err = c.Txn(ctx).
+		If(clientv3.Compare(clientv3.CreateRevision("/lock"), "=", 0)).
+		Then(clientv3.OpPut("/lock", "owner-2")).
+		Else(clientv3.OpGet("/lock")).
+		Commit()
+	require.NoError(t, err)
+	assert.False(t, txn.Succeeded)
+	assert.Equal(t, resp.Header.Revision, txn.Header.Revision, "没有修改的事务不增加修订版本")
+
+	// 任一操作失败时撤销事务中的所有修改
+	_, err = c.Txn(ctx).Then(clientv3.OpDelete("/lock"), clientv3.OpPut("/x", "y", clientv3.WithLease(12345))).Commit()
+	assert.ErrorIs(t, err, rpctypes.ErrLeaseNotFound)
+	resp, err = c.Get(ctx, "/lock")
+	require.NoError(t, err)
+	assert.Len(t, resp.Kvs, 1)
+	assert.Equal(t, txn.Header.Revision, resp.Header.Revision)
+}
+
+func TestStore_Watch(t *testing.T) {
+	_, c := newTestClient(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	put, err := c.Put(ctx, "/w/1", "a")
+	require.NoError(t, err)
+
+	// 从历史版本开始监听，先收到历史事件
+	watchCh := c.Watch(ctx, "/w/", clientv3.WithPrefix(), clientv3.WithRev(put.Header.Revision), clientv3.WithPrevKV())
+	_, err = c.Put(ctx, "/w/1", "b")
+	require.NoError(t, err)
+	_, err = c.Delete(ctx, "/w/1")
+	require.NoError(t, err)
+	_, err = c.Put(ctx, "/other", "x")
+	require.NoError(t, err)
+
+	var events []*clientv3.Event
+	for len(events) < 3 {
+		select {
+		case resp := <-watchCh:
+			require.NoError(t, resp.Err())
+			events = append(events, resp.Events...)
+		case <-ctx.Done():
+			t.Fatal("等待监听事件超时")
+		}
+	}
+	assert.Equal(t, "a", string(events[0].Kv.Value))
+	assert.Equal(t, "b", string(events[1].Kv.Value))
+	assert.Equal(t, "a", string(events[1].PrevKv.Value))
+	assert.Equal(t, mvccpb.DELETE, events[2].Type)
+	assert.Equal(t, "b", string(events[2].PrevKv.Value))
+}
+
+func TestStore_WatchCompacted(t *testing.T) {
+	s, c := newTestClient(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	first, err := c.Put(ctx, "/c", "1")
+	require.NoError(t, err)
+	put, err := c.Put(ctx, "/c", "2")
+	require.NoError(t, err)
+	_, err = c.Compact(ctx, put.Header.Revision)
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return s.compacted == put.Header.Revision
+	}, 2*time.Second, 10*time.Millisecond)
+
+	resp := <-c.Watch(ctx, "/c", clientv3.WithRev(first.Header.Revision))
+	assert.Equal(t, put.Header.Revision, resp.CompactRevision)
+	assert.ErrorIs(t, resp.Err(), rpctypes.ErrCompacted)
+}
+
+func TestStore_Lease(t *testing.T) {
+	_, c := newTestClient(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	grant, err := c.Grant(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, int64(etcdapi.MinLeaseTTL), grant.TTL)
+	_, err = c.Put(ctx, "/lease/a", "1", clientv3.WithLease(grant.ID))
+	require.NoError(t, err)
+	_, err = c.Put(ctx, "/lease/b", "2", clientv3.WithLease(grant.ID))
+	require.NoError(t, err)
+
+	ttl, err := c.TimeToLive(ctx, grant.ID, clientv3.WithAttachedKeys())
+	require.NoError(t, err)
+	assert.Equal(t, int64(etcdapi.MinLeaseTTL), ttl.GrantedTTL)
+	assert.Len(t, ttl.Keys, 2)
+
+	// 租约到期后关联的key在同一个修订版本中删除
+	watchCh := c.Watch(ctx, "/lease/", clientv3.WithPrefix())
+	select {
+	case resp := <-watchCh:
+		require.Len(t, resp.Events, 2)
+		assert.Equal(t, mvccpb.DELETE, resp.Events[0].Type)
+		assert.Equal(t, resp.Events[0].Kv.ModRevision, resp.Events[1].Kv.ModRevision)
+	case <-ctx.Done():
+		t.Fatal("等待租约到期超时")
+	}
+
+	ttl, err = c.TimeToLive(ctx, grant.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(-1), ttl.TTL)
+	_, err = c.Revoke(ctx, grant.ID)
+	assert.ErrorIs(t, err, rpctypes.ErrLeaseNotFound)
+}
+
+func TestStore_KeepAlive(t *testing.T) {
+	_, c := newTestClient(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	grant, err := c.Grant(ctx, etcdapi.MinLeaseTTL)
+	require.NoError(t, err)
+	_, err = c.Put(ctx, "/ka", "1", clientv3.WithLease(grant.ID))
+	require.NoError(t, err)
+
+	kaCtx, kaCancel := context.WithCancel(ctx)
+	defer kaCancel()
+	_, err = c.KeepAlive(kaCtx, grant.ID)
+	require.NoError(t, err)
+
+	// 持续续约的租约超过TTL后仍然有效
+	time.Sleep(3 * time.Second)
+	resp, err := c.Get(ctx, "/ka")
+	require.NoError(t, err)
+	assert.Len(t, resp.Kvs, 1)
+
+	_, err = c.Revoke(ctx, grant.ID)
+	require.NoError(t, err)
+	resp, err = c.Get(ctx, "/ka")
+	require.NoError(t, err)
+	assert.Empty(t, resp.Kvs)
+}
+
+func TestStore_SharedDatabase(t *testing.T) {
+	name, dsn := testDatabase(t)
+	_, c1 := openTestStore(t, name, dsn)
+	_, c2 := openTestStore(t, name, dsn)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// 一个节点的写入通知到另一个节点的监听者
+	watchCh := c2.Watch(ctx, "/shared/", clientv3.WithPrefix())
+	put, err := c1.Put(ctx, "/shared/a", "1")
+	require.NoError(t, err)
+	select {
+	case resp := <-watchCh:
+		require.Len(t, resp.Events, 1)
+		assert.Equal(t, put.Header.Revision, resp.Events[0].Kv.ModRevision)
+	case <-ctx.Done():
+		t.Fatal("等待其他节点的写入超时")
+	}
+
+	// 两个节点上的选举互斥
+	s1, err := concurrency.NewSession(c1, concurrency.WithTTL(5))
+	require.NoError(t, err)
+	defer s1.Close()
+	s2, err := concurrency.NewSession(c2, concurrency.WithTTL(5))
+	require.NoError(t, err)
+	defer s2.Close()
+
+	e1 := concurrency.NewElection(s1, "/election/test")
+	e2 := concurrency.NewElection(s2, "/election/test")
+	require.NoError(t, e1.Campaign(ctx, "node-1"))
+
+	elected := make(chan error, 1)
+	go func() { elected <- e2.Campaign(ctx, "node-2") }()
+	select {
+	case <-elected:
+		t.Fatal("领导者未放弃时不应当选")
+	case <-time.After(300 * time.Millisecond):
+	}
+
+	require.NoError(t, e1.Resign(ctx))
+	require.NoError(t, <-elected)
+	leader, err := e1.Leader(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "node-2", string(leader.Kvs[0].Value))
+}
+
+func TestStore_AutoCompact(t *testing.T) {
+	s, c := newTestClient(t)
+	s.opts.Retention = 10
+	ctx := context.Background()
+
+	for i := 0; i < 25; i++ {
+		_, err := c.Put(ctx, "/k", "v")
+		require.NoError(t, err)
+	}
+	require.NoError(t, s.autoCompact(ctx))
+
+	rev, compacted, err := s.meta(ctx, s.db)
+	require.NoError(t, err)
+	assert.Equal(t, rev-10, compacted)
+	// 保留压缩版本及之后的行，以及作为压缩版本事件PrevKv的旧值
+	var rows int64
+	require.NoError(t, s.db.QueryRow("SELECT COUNT(*) FROM kd_kv").Scan(&rows))
+	assert.Equal(t, rev-compacted+2, rows)
+
+	resp, err := c.Get(ctx, "/k", clientv3.WithRev(compacted))
+	require.NoError(t, err)
+	assert.Len(t, resp.Kvs, 1)
+}
+
+func TestStore_Reopen(t *testing.T) {
+	name, dsn := testDatabase(t)
+	s, err := Open(name, dsn, Options{Logger: testLogger(t)})
+	require.NoError(t, err)
+	c := s.NewClient()
+	put, err := c.Put(context.Background(), "/persist", "v")
+	require.NoError(t, err)
+	_ = c.Close()
+	require.NoError(t, s.Close())
+
+	// 重新打开后数据和修订版本保留
+	_, c = openTestStore(t, name, dsn)
+	resp, err := c.Get(context.Background(), "/persist")
+	require.NoError(t, err)
+	require.Len(t, resp.Kvs, 1)
+	assert.Equal(t, put.Header.Revision, resp.Header.Revision)
+}
+
+func TestStore_Status(t *testing.T) {
+	s, c := newTestClient(t)
+	resp, err := c.Status(context.Background(), "")
+	require.NoError(t, err)
+	assert.Equal(t, s.name, resp.Version)
+}
+
+func TestOpen_Invalid(t *testing.T) {
+	_, err := Open("mysql", "dsn", Options{})
+	assert.Error(t, err)
+	_, err = Open(SQLite, "", Options{})
+	assert.Error(t, err)
+}
+
+func TestSQLiteDSN(t *testing.T) {
+	assert.Equal(t, "a.db?_txlock=immediate&_busy_timeout=10000&_journal_mode=WAL", sqliteDSN("a.db"))
+	assert.Equal(t, "a.db?_busy_timeout=1&_txlock=immediate&_journal_mode=WAL", sqliteDSN("a.db?_busy_timeout=1"))
+}
+
+func TestRebind(t *testing.T) {
+	assert.Equal(t, "a = ? AND b = ?", dialects[SQLite].rebind("a = ? AND b = ?"))
+	assert.Equal(t, "a = $1 AND b = $2", dialects[Postgres].rebind("a = ? AND b = ?"))
+}
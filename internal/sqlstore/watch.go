@@ -0,0 +1,319 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"time"
+
+	"github.com/hewenyu/kong-discovery/internal/etcdapi"
+	"github.com/jackc/pgx/v5/stdlib"
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+)
+
+// maxPollRevisions 每次轮询最多读取的修订版本数，积压较多时分批读取
+const maxPollRevisions = 1000
+
+// listenRetryInterval LISTEN连接中断后重新建立的间隔，期间依靠轮询读取变更
+const listenRetryInterval = 5 * time.Second
+
+// revisionEvents 一个修订版本中的所有事件
+type revisionEvents struct {
+	revision int64
+	events   []*mvccpb.Event
+}
+
+// changes 读取修订版本在(from, to]之间的事件，按修订版本分组；事件总是带有PrevKv
+func (s *Store) changes(ctx context.Context, from, to int64) ([]revisionEvents, error) {
+	rows, err := s.db.QueryContext(ctx, s.q(`SELECT
+		k.revision, k.key, k.value, k.create_revision, k.version, k.lease, k.deleted,
+		p.id, p.key, p.value, p.create_revision, p.revision, p.version, p.lease
+		FROM kd_kv k LEFT JOIN kd_kv p ON p.id = k.prev_id
+		WHERE k.revision > ? AND k.revision <= ? ORDER BY k.id`), from, to)
+	if err != nil {
+		return nil, fmt.Errorf("读取变更失败: %w", err)
+	}
+	defer rows.Close()
+
+	var result []revisionEvents
+	for rows.Next() {
+		var (
+			kv                                   mvccpb.KeyValue
+			deleted                              int
+			prevID, prevCreate, prevMod, prevVer sql.NullInt64
+			prevLease                            sql.NullInt64
+			prevKey, prevValue                   []byte
+		)
+		if err := rows.Scan(&kv.ModRevision, &kv.Key, &kv.Value, &kv.CreateRevision, &kv.Version, &kv.Lease, &deleted,
+			&prevID, &prevKey, &prevValue, &prevCreate, &prevMod, &prevVer, &prevLease); err != nil {
+			return nil, fmt.Errorf("读取变更失败: %w", err)
+		}
+
+		event := &mvccpb.Event{Type: mvccpb.PUT, Kv: &kv}
+		if deleted != 0 {
+			event.Type = mvccpb.DELETE
+			event.Kv = &mvccpb.KeyValue{Key: kv.Key, ModRevision: kv.ModRevision}
+		}
+		if prevID.Valid {
+			event.PrevKv = &mvccpb.KeyValue{
+				Key:            prevKey,
+				Value:          prevValue,
+				CreateRevision: prevCreate.Int64,
+				ModRevision:    prevMod.Int64,
+				Version:        prevVer.Int64,
+				Lease:          prevLease.Int64,
+			}
+		}
+		if n := len(result); n == 0 || result[n-1].revision != kv.ModRevision {
+			result = append(result, revisionEvents{revision: kv.ModRevision})
+		}
+		result[len(result)-1].events = append(result[len(result)-1].events, event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("读取变更失败: %w", err)
+	}
+	return result, nil
+}
+
+// kick 唤醒轮询，本节点写入或收到其他节点的通知后立即读取变更
+func (s *Store) kick() {
+	select {
+	case s.kickc <- struct{}{}:
+	default:
+	}
+}
+
+// pollLoop 定期或被唤醒时读取新的修订版本并通知监听者
+func (s *Store) pollLoop() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.opts.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+		case <-s.kickc:
+		}
+		if err := s.poll(s.ctx); err != nil && s.ctx.Err() == nil {
+			s.logger.Warn("读取数据库变更失败", zap.Error(err))
+		}
+	}
+}
+
+// poll 读取上次通知之后的修订版本并按顺序通知监听者
+func (s *Store) poll(ctx context.Context) error {
+	rev, compacted, err := s.meta(ctx, s.db)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	from := s.revision
+	s.mu.Unlock()
+
+	to, changes, err := s.fetch(ctx, from, rev, compacted)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.notifyLocked(from, to, compacted, changes)
+	if to < rev {
+		s.kick()
+	}
+	return nil
+}
+
+// fetch 读取(from, rev]之间的变更，积压较多时只读取前maxPollRevisions个修订版本，返回读取到的修订版本；
+// 中间的历史已被压缩时不读取
+func (s *Store) fetch(ctx context.Context, from, rev, compacted int64) (int64, []revisionEvents, error) {
+	if rev <= from {
+		return from, nil, nil
+	}
+	if from+1 < compacted {
+		return rev, nil, nil
+	}
+	to := min(rev, from+maxPollRevisions)
+	changes, err := s.changes(ctx, from, to)
+	if err != nil {
+		return 0, nil, err
+	}
+	return to, changes, nil
+}
+
+// notifyLocked 把fetch读取到的(from, to]之间的变更通知监听者，已经通知过的修订版本跳过；
+// 落后期间的历史已被压缩时，无法补发的监听者与etcd一样收到压缩通知。调用方需持有锁
+func (s *Store) notifyLocked(from, to, compacted int64, changes []revisionEvents) {
+	if from == s.revision && from+1 < compacted {
+		header := etcdapi.Header(to)
+		for w := range s.watchers {
+			w.Compacted(header, compacted)
+			delete(w.Stream().Watchers, w.ID)
+			delete(s.watchers, w)
+		}
+	}
+	for _, c := range changes {
+		if c.revision <= s.revision {
+			continue
+		}
+		header := etcdapi.Header(c.revision)
+		for w := range s.watchers {
+			w.Send(header, c.events)
+		}
+	}
+	s.revision = max(s.revision, to)
+	s.compacted = max(s.compacted, compacted)
+}
+
+// catchUpLocked 通知到数据库中的当前修订版本，调用方需持有锁
+func (s *Store) catchUpLocked(ctx context.Context) error {
+	rev, compacted, err := s.meta(ctx, s.db)
+	if err != nil {
+		return err
+	}
+	for s.revision < rev {
+		from := s.revision
+		to, changes, err := s.fetch(ctx, from, rev, compacted)
+		if err != nil {
+			return err
+		}
+		s.notifyLocked(from, to, compacted, changes)
+	}
+	s.compacted = max(s.compacted, compacted)
+	return nil
+}
+
+// listenLoop 通过PostgreSQL的LISTEN接收其他节点的写入通知，连接中断后重新建立
+func (s *Store) listenLoop() {
+	defer s.wg.Done()
+
+	for {
+		err := s.listen()
+		if s.ctx.Err() != nil {
+			return
+		}
+		s.logger.Warn("PostgreSQL变更通知中断，稍后重新监听，期间通过轮询读取变更", zap.Error(err))
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-time.After(listenRetryInterval):
+		}
+	}
+}
+
+// listen 占用一个连接等待通知，直到连接出错或存储关闭
+func (s *Store) listen() error {
+	conn, err := s.db.Conn(s.ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	var listenErr error
+	_ = conn.Raw(func(driverConn any) error {
+		c, ok := driverConn.(*stdlib.Conn)
+		if !ok {
+			listenErr = fmt.Errorf("不支持的PostgreSQL驱动连接: %T", driverConn)
+			return driver.ErrBadConn
+		}
+		if _, listenErr = c.Conn().Exec(s.ctx, "LISTEN "+notifyChannel); listenErr != nil {
+			return driver.ErrBadConn
+		}
+		// 开始监听前的写入由这次轮询读取
+		s.kick()
+		for {
+			if _, listenErr = c.Conn().WaitForNotification(s.ctx); listenErr != nil {
+				// 监听过的连接不放回连接池
+				return driver.ErrBadConn
+			}
+			s.kick()
+		}
+	})
+	return listenErr
+}
+
+// Watch 打开监听流，一个流中可以创建多个监听
+func (s *Store) Watch(ctx context.Context, _ ...grpc.CallOption) (pb.Watch_WatchClient, error) {
+	return etcdapi.NewWatchStream(ctx, s), nil
+}
+
+// CreateWatch 创建监听，起始版本已被压缩时返回压缩版本并取消监听；
+// 先把已有的监听通知到数据库中的当前修订版本，与etcd一致，未指定起始版本的监听只收到之后的写入，
+// 起始版本之后的历史从数据库补发
+func (s *Store) CreateWatch(ws *etcdapi.WatchStream, req *pb.WatchCreateRequest) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if ws.Closed() {
+		return
+	}
+
+	err := s.catchUpLocked(ws.Context())
+	header := etcdapi.Header(s.revision)
+	w := ws.NewWatcher(req, header)
+	if err != nil {
+		s.logger.Error("创建监听时读取数据库变更失败", zap.Error(err))
+		w.Canceled(header)
+		return
+	}
+	start := req.StartRevision
+	if start > 0 && start < s.compacted {
+		w.Compacted(header, s.compacted)
+		return
+	}
+	if start > 0 && start <= s.revision {
+		changes, err := s.changes(ws.Context(), start-1, s.revision)
+		if err != nil {
+			s.logger.Error("读取监听的历史事件失败", zap.Error(err))
+			w.Canceled(header)
+			return
+		}
+		var events []*mvccpb.Event
+		for _, c := range changes {
+			events = append(events, c.events...)
+		}
+		w.Send(header, events)
+	}
+	ws.Watchers[w.ID] = w
+	s.watchers[w] = struct{}{}
+}
+
+// CancelWatch 取消监听
+func (s *Store) CancelWatch(ws *etcdapi.WatchStream, id int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	w := ws.Watchers[id]
+	if w == nil {
+		return
+	}
+	delete(ws.Watchers, id)
+	delete(s.watchers, w)
+	w.Canceled(etcdapi.Header(s.revision))
+}
+
+// Progress 返回已通知的修订版本
+func (s *Store) Progress(ws *etcdapi.WatchStream) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ws.Push(&pb.WatchResponse{Header: etcdapi.Header(s.revision), WatchId: -1})
+}
+
+// CloseWatchStream 删除流中的所有监听
+func (s *Store) CloseWatchStream(ws *etcdapi.WatchStream) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, w := range ws.Watchers {
+		delete(ws.Watchers, id)
+		delete(s.watchers, w)
+	}
+}
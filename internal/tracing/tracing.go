@@ -0,0 +1,88 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hewenyu/kong-discovery/internal/config"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// instrumentationName 本项目创建的span使用的instrumentation名称
+const instrumentationName = "github.com/hewenyu/kong-discovery"
+
+// ShutdownFunc 刷新并关闭追踪导出器
+type ShutdownFunc func(ctx context.Context) error
+
+// Setup 按配置初始化全局的TracerProvider和上下文传播器
+// 未启用追踪时保留OpenTelemetry默认的空实现，创建span几乎没有开销
+func Setup(cfg *config.Config, logger config.Logger) (ShutdownFunc, error) {
+	// 无论是否启用都注册W3C传播器，保证经过本服务的trace上下文不会丢失
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	if !cfg.Tracing.Enabled {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	opts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(cfg.Tracing.Endpoint)}
+	if cfg.Tracing.Insecure {
+		opts = append(opts, otlptracegrpc.WithInsecure())
+	}
+	// 导出器在后台连接，接收端不可用时不会阻塞启动
+	exporter, err := otlptracegrpc.New(context.Background(), opts...)
+	if err != nil {
+		return nil, fmt.Errorf("创建OTLP导出器失败: %w", err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(
+		attribute.String("service.name", cfg.Tracing.ServiceName),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("创建追踪资源失败: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.Tracing.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+
+	logger.Info("已启用链路追踪",
+		zap.String("endpoint", cfg.Tracing.Endpoint),
+		zap.String("service_name", cfg.Tracing.ServiceName),
+		zap.Float64("sample_ratio", cfg.Tracing.SampleRatio))
+
+	return provider.Shutdown, nil
+}
+
+// Tracer 返回本项目使用的Tracer，始终从全局TracerProvider获取，
+// 因此在Setup之前创建的组件也能使用之后配置的导出器
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// Start 创建一个子span，是Tracer().Start的简写
+func Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return Tracer().Start(ctx, name, opts...)
+}
+
+// EndSpan 记录错误（如果有）并结束span
+func EndSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
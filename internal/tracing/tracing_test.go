@@ -0,0 +1,74 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hewenyu/kong-discovery/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// 创建测试用的日志记录器
+func createTestLogger(t *testing.T) config.Logger {
+	t.Helper()
+
+	logger, err := config.NewLogger(true)
+	require.NoError(t, err, "创建测试日志记录器失败")
+	return logger
+}
+
+// restoreGlobals 测试结束后恢复全局的TracerProvider和传播器
+func restoreGlobals(t *testing.T) {
+	provider := otel.GetTracerProvider()
+	propagator := otel.GetTextMapPropagator()
+	t.Cleanup(func() {
+		otel.SetTracerProvider(provider)
+		otel.SetTextMapPropagator(propagator)
+	})
+}
+
+func TestSetup_Disabled(t *testing.T) {
+	restoreGlobals(t)
+	before := otel.GetTracerProvider()
+
+	shutdown, err := Setup(&config.Config{}, createTestLogger(t))
+	require.NoError(t, err)
+	require.NoError(t, shutdown(context.Background()))
+
+	// 未启用时不替换TracerProvider，但仍注册W3C传播器
+	assert.Equal(t, before, otel.GetTracerProvider())
+	assert.Contains(t, otel.GetTextMapPropagator().Fields(), "traceparent")
+
+	_, span := Start(context.Background(), "noop")
+	assert.False(t, span.SpanContext().IsValid())
+	span.End()
+}
+
+func TestSetup_Enabled(t *testing.T) {
+	restoreGlobals(t)
+
+	cfg := &config.Config{}
+	cfg.Tracing.Enabled = true
+	cfg.Tracing.Endpoint = "127.0.0.1:1" // 接收端不可用时不应阻塞启动
+	cfg.Tracing.Insecure = true
+	cfg.Tracing.ServiceName = "kong-discovery-test"
+	cfg.Tracing.SampleRatio = 1
+
+	shutdown, err := Setup(cfg, createTestLogger(t))
+	require.NoError(t, err)
+	_, ok := otel.GetTracerProvider().(*sdktrace.TracerProvider)
+	assert.True(t, ok)
+
+	_, span := Start(context.Background(), "enabled")
+	assert.True(t, span.SpanContext().IsValid())
+	assert.True(t, span.SpanContext().IsSampled())
+	span.End()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	_ = shutdown(ctx)
+}
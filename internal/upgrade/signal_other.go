@@ -0,0 +1,10 @@
+//go:build !unix
+
+package upgrade
+
+import "os"
+
+// Signals 触发升级的信号，当前平台不支持传递套接字
+func Signals() []os.Signal {
+	return nil
+}
@@ -0,0 +1,13 @@
+//go:build unix
+
+package upgrade
+
+import (
+	"os"
+	"syscall"
+)
+
+// Signals 触发升级的信号
+func Signals() []os.Signal {
+	return []os.Signal{syscall.SIGUSR2}
+}
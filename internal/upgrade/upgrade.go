@@ -0,0 +1,246 @@
+// Package upgrade 实现不中断服务的二进制升级：旧进程把DNS和HTTP监听套接字传递给新启动的进程，
+// 新进程就绪后旧进程停止接收新请求、处理完进行中的请求再退出。
+// 套接字通过LISTEN_FDS和LISTEN_FDNAMES环境变量传递，与systemd套接字激活的约定一致。
+package upgrade
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// 传递的套接字名称
+const (
+	NameDNS          = "dns"          // DNS服务器的UDP和TCP套接字
+	NameManagement   = "management"   // 管理API的监听套接字
+	NameRegistration = "registration" // 服务注册API的监听套接字
+
+	nameReady = "ready" // 新进程就绪后写入的管道
+)
+
+// 传递的第一个文件描述符，0-2为标准输入输出
+const listenFDsStart = 3
+
+// envUpgrade 标记进程由升级启动，值为旧进程的PID
+const envUpgrade = "KONG_DISCOVERY_UPGRADE_PID"
+
+// namedFile 继承的文件描述符及其名称
+type namedFile struct {
+	name string
+	file *os.File
+}
+
+var inherited struct {
+	once  sync.Once
+	err   error
+	files []namedFile
+	ready *os.File
+}
+
+// load 读取继承的文件描述符，只执行一次；读取后清除相关环境变量，避免子进程重复使用
+func load() error {
+	inherited.once.Do(func() {
+		files, err := listenFiles()
+		inherited.err = err
+		for _, f := range files {
+			if f.name == nameReady && os.Getenv(envUpgrade) != "" {
+				inherited.ready = f.file
+				continue
+			}
+			inherited.files = append(inherited.files, f)
+		}
+	})
+	return inherited.err
+}
+
+// listenFiles 解析LISTEN_FDS、LISTEN_PID和LISTEN_FDNAMES
+// LISTEN_PID设置且不是当前进程时忽略；升级时新进程的PID在启动前未知，因此不设置LISTEN_PID
+func listenFiles() ([]namedFile, error) {
+	fdsEnv := os.Getenv("LISTEN_FDS")
+	if fdsEnv == "" {
+		return nil, nil
+	}
+	pidEnv := os.Getenv("LISTEN_PID")
+	namesEnv := os.Getenv("LISTEN_FDNAMES")
+	os.Unsetenv("LISTEN_FDS")
+	os.Unsetenv("LISTEN_PID")
+	os.Unsetenv("LISTEN_FDNAMES")
+
+	if pidEnv != "" {
+		pid, err := strconv.Atoi(pidEnv)
+		if err != nil {
+			return nil, fmt.Errorf("无效的LISTEN_PID: %s", pidEnv)
+		}
+		if pid != os.Getpid() {
+			return nil, nil
+		}
+	}
+
+	count, err := strconv.Atoi(fdsEnv)
+	if err != nil || count < 0 {
+		return nil, fmt.Errorf("无效的LISTEN_FDS: %s", fdsEnv)
+	}
+
+	var names []string
+	if namesEnv != "" {
+		names = strings.Split(namesEnv, ":")
+	}
+
+	files := make([]namedFile, 0, count)
+	for i := 0; i < count; i++ {
+		fd := listenFDsStart + i
+		name := "unknown"
+		if i < len(names) && names[i] != "" {
+			name = names[i]
+		}
+		files = append(files, namedFile{name: name, file: os.NewFile(uintptr(fd), name)})
+	}
+	return files, nil
+}
+
+// IsChild 判断当前进程是否由升级启动
+func IsChild() bool {
+	return os.Getenv(envUpgrade) != ""
+}
+
+// Take 取出继承的指定名称的套接字，取出后不会再次返回
+func Take(name string) ([]*os.File, error) {
+	if err := load(); err != nil {
+		return nil, err
+	}
+	var taken []*os.File
+	rest := inherited.files[:0]
+	for _, f := range inherited.files {
+		if f.name == name {
+			taken = append(taken, f.file)
+			continue
+		}
+		rest = append(rest, f)
+	}
+	inherited.files = rest
+	return taken, nil
+}
+
+// TakeAll 取出所有尚未取出的继承套接字，用于systemd套接字激活
+func TakeAll() ([]*os.File, error) {
+	if err := load(); err != nil {
+		return nil, err
+	}
+	taken := make([]*os.File, 0, len(inherited.files))
+	for _, f := range inherited.files {
+		taken = append(taken, f.file)
+	}
+	inherited.files = nil
+	return taken, nil
+}
+
+// Ready 通知旧进程新进程已经就绪，旧进程随后开始退出；不是由升级启动时什么也不做
+func Ready() error {
+	if err := load(); err != nil {
+		return err
+	}
+	if inherited.ready == nil {
+		return nil
+	}
+	defer func() {
+		inherited.ready.Close()
+		inherited.ready = nil
+	}()
+	if _, err := inherited.ready.Write([]byte{1}); err != nil {
+		return fmt.Errorf("通知旧进程失败: %w", err)
+	}
+	return nil
+}
+
+// Spawn 以相同的可执行文件和参数启动新进程，并传递files中的套接字
+// 等待新进程调用Ready后返回；新进程提前退出或ctx到期时终止新进程并返回错误，旧进程继续提供服务
+func Spawn(ctx context.Context, files map[string][]*os.File) (*os.Process, error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("获取可执行文件路径失败: %w", err)
+	}
+
+	readyR, readyW, err := os.Pipe()
+	if err != nil {
+		return nil, fmt.Errorf("创建就绪通知管道失败: %w", err)
+	}
+	defer readyR.Close()
+
+	var extra []*os.File
+	var names []string
+	for _, name := range []string{NameDNS, NameManagement, NameRegistration} {
+		for _, f := range files[name] {
+			extra = append(extra, f)
+			names = append(names, name)
+		}
+	}
+	extra = append(extra, readyW)
+	names = append(names, nameReady)
+
+	cmd := exec.Command(executable, os.Args[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.ExtraFiles = extra
+	cmd.Env = append(childEnv(),
+		"LISTEN_FDS="+strconv.Itoa(len(extra)),
+		"LISTEN_FDNAMES="+strings.Join(names, ":"),
+		envUpgrade+"="+strconv.Itoa(os.Getpid()))
+
+	if err := cmd.Start(); err != nil {
+		readyW.Close()
+		return nil, fmt.Errorf("启动新进程失败: %w", err)
+	}
+	// 关闭本进程持有的写端，新进程退出时读端收到EOF
+	readyW.Close()
+
+	result := make(chan error, 1)
+	go func() {
+		buf := make([]byte, 1)
+		if _, err := readyR.Read(buf); err != nil {
+			if errors.Is(err, io.EOF) {
+				err = errors.New("新进程在就绪前退出")
+			}
+			result <- err
+			return
+		}
+		result <- nil
+	}()
+
+	select {
+	case err = <-result:
+	case <-ctx.Done():
+		err = fmt.Errorf("等待新进程就绪超时: %w", ctx.Err())
+	}
+	if err != nil {
+		cmd.Process.Kill()
+		cmd.Wait()
+		return nil, err
+	}
+
+	// 新进程由旧进程启动，旧进程退出后由init接管，无需等待
+	go cmd.Wait()
+	return cmd.Process, nil
+}
+
+// childEnv 返回去掉套接字传递相关变量的环境变量
+func childEnv() []string {
+	var env []string
+	for _, kv := range os.Environ() {
+		switch {
+		case strings.HasPrefix(kv, "LISTEN_FDS="),
+			strings.HasPrefix(kv, "LISTEN_PID="),
+			strings.HasPrefix(kv, "LISTEN_FDNAMES="),
+			strings.HasPrefix(kv, envUpgrade+"="):
+			continue
+		}
+		env = append(env, kv)
+	}
+	return env
+}
@@ -0,0 +1,52 @@
+package upgrade
+
+import (
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListenFiles(t *testing.T) {
+	// 没有LISTEN_FDS时没有继承的套接字
+	t.Setenv("LISTEN_FDS", "")
+	files, err := listenFiles()
+	require.NoError(t, err)
+	assert.Nil(t, files)
+
+	// LISTEN_PID不是当前进程时忽略，并清除环境变量
+	t.Setenv("LISTEN_FDS", "2")
+	t.Setenv("LISTEN_PID", strconv.Itoa(os.Getpid()+1))
+	files, err = listenFiles()
+	require.NoError(t, err)
+	assert.Nil(t, files)
+	assert.Empty(t, os.Getenv("LISTEN_FDS"))
+	assert.Empty(t, os.Getenv("LISTEN_PID"))
+
+	// 无效的LISTEN_FDS
+	t.Setenv("LISTEN_FDS", "abc")
+	t.Setenv("LISTEN_PID", strconv.Itoa(os.Getpid()))
+	_, err = listenFiles()
+	assert.Error(t, err)
+
+	t.Setenv("LISTEN_FDS", "0")
+	files, err = listenFiles()
+	require.NoError(t, err)
+	assert.Empty(t, files)
+}
+
+func TestChildEnv(t *testing.T) {
+	t.Setenv("LISTEN_FDS", "3")
+	t.Setenv("LISTEN_FDNAMES", "dns:dns:ready")
+	t.Setenv(envUpgrade, "100")
+	t.Setenv("KONG_DISCOVERY_DNS_PORT", "53")
+
+	env := childEnv()
+	assert.Contains(t, env, "KONG_DISCOVERY_DNS_PORT=53")
+	for _, kv := range env {
+		assert.NotContains(t, kv, "LISTEN_")
+		assert.NotContains(t, kv, envUpgrade)
+	}
+}
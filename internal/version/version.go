@@ -0,0 +1,125 @@
+package version
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+)
+
+// 构建信息，发布构建时通过 -ldflags "-X" 注入，例如
+// go build -ldflags "-X github.com/hewenyu/kong-discovery/internal/version.Version=1.2.0"
+var (
+	Version   = "0.1.0"   // 语义化版本号
+	GitCommit = ""        // 构建时的git提交，未注入时从Go模块的构建信息读取
+	BuildDate = "unknown" // 构建时间（RFC3339）
+)
+
+// APIVersions 服务支持的HTTP API版本
+var APIVersions = []string{"v1"}
+
+// SDKHeader SDK在每个请求中携带自身版本号的请求头
+const SDKHeader = "X-SDK-Version"
+
+// SDKUserAgentPrefix SDK的User-Agent前缀，完整格式为 kong-discovery-sdk/<版本号>
+const SDKUserAgentPrefix = "kong-discovery-sdk/"
+
+// Info 版本和构建信息
+type Info struct {
+	Version     string   `json:"version"`      // 语义化版本号
+	GitCommit   string   `json:"git_commit"`   // git提交
+	BuildDate   string   `json:"build_date"`   // 构建时间
+	GoVersion   string   `json:"go_version"`   // 编译使用的Go版本
+	APIVersions []string `json:"api_versions"` // 支持的HTTP API版本
+}
+
+// Get 返回当前程序的版本和构建信息
+func Get() Info {
+	return Info{
+		Version:     Version,
+		GitCommit:   gitCommit(),
+		BuildDate:   BuildDate,
+		GoVersion:   runtime.Version(),
+		APIVersions: APIVersions,
+	}
+}
+
+// gitCommit 返回注入的git提交，未注入时使用go build记录的vcs.revision
+func gitCommit() string {
+	if GitCommit != "" {
+		return GitCommit
+	}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range info.Settings {
+			if setting.Key == "vcs.revision" {
+				return setting.Value
+			}
+		}
+	}
+	return "unknown"
+}
+
+// UserAgent 返回SDK使用的User-Agent
+func UserAgent() string {
+	return SDKUserAgentPrefix + Version
+}
+
+// FromUserAgent 从User-Agent中提取SDK版本号，不是SDK发出的请求时返回false
+func FromUserAgent(userAgent string) (string, bool) {
+	for _, product := range strings.Fields(userAgent) {
+		if v, ok := strings.CutPrefix(product, SDKUserAgentPrefix); ok && v != "" {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// Validate 校验版本号格式
+func Validate(v string) error {
+	_, err := parse(v)
+	return err
+}
+
+// Compare 比较两个语义化版本号的主版本、次版本和修订号，a小于、等于、大于b时分别返回-1、0、1
+// 允许省略的"v"前缀和缺少的次版本或修订号（视为0），忽略预发布和构建元数据
+func Compare(a, b string) (int, error) {
+	pa, err := parse(a)
+	if err != nil {
+		return 0, err
+	}
+	pb, err := parse(b)
+	if err != nil {
+		return 0, err
+	}
+	for i := range pa {
+		switch {
+		case pa[i] < pb[i]:
+			return -1, nil
+		case pa[i] > pb[i]:
+			return 1, nil
+		}
+	}
+	return 0, nil
+}
+
+// parse 解析版本号的主版本、次版本和修订号
+func parse(v string) ([3]int, error) {
+	var parts [3]int
+	core := strings.TrimPrefix(strings.TrimSpace(v), "v")
+	if i := strings.IndexAny(core, "-+"); i >= 0 {
+		core = core[:i]
+	}
+	fields := strings.Split(core, ".")
+	if core == "" || len(fields) > 3 {
+		return parts, fmt.Errorf("无效的版本号: %q", v)
+	}
+	for i, field := range fields {
+		n, err := strconv.Atoi(field)
+		if err != nil || n < 0 {
+			return parts, fmt.Errorf("无效的版本号: %q", v)
+		}
+		parts[i] = n
+	}
+	return parts, nil
+}
@@ -0,0 +1,59 @@
+package version
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.2.3", "1.2.3", 0},
+		{"v1.2.3", "1.2.3", 0},
+		{"1.2", "1.2.0", 0},
+		{"1", "1.0.0", 0},
+		{"1.2.3", "1.10.0", -1},
+		{"2.0.0", "1.99.99", 1},
+		{"1.2.4-rc.1", "1.2.3", 1},
+		{"1.2.3+build.5", "1.2.3", 0},
+	}
+	for _, tt := range tests {
+		got, err := Compare(tt.a, tt.b)
+		require.NoError(t, err, "%s vs %s", tt.a, tt.b)
+		assert.Equal(t, tt.want, got, "%s vs %s", tt.a, tt.b)
+	}
+
+	for _, invalid := range []string{"", "v", "1.x", "1.2.3.4", "-1.0"} {
+		_, err := Compare(invalid, "1.0.0")
+		assert.Error(t, err, "%q应无效", invalid)
+	}
+}
+
+func TestFromUserAgent(t *testing.T) {
+	v, ok := FromUserAgent("kong-discovery-sdk/1.4.0")
+	assert.True(t, ok)
+	assert.Equal(t, "1.4.0", v)
+
+	v, ok = FromUserAgent("my-app/2.0 kong-discovery-sdk/0.9.1 (linux)")
+	assert.True(t, ok)
+	assert.Equal(t, "0.9.1", v)
+
+	_, ok = FromUserAgent("curl/8.5.0")
+	assert.False(t, ok)
+
+	v, ok = FromUserAgent(UserAgent())
+	assert.True(t, ok)
+	assert.Equal(t, Version, v)
+}
+
+func TestGet(t *testing.T) {
+	info := Get()
+	assert.Equal(t, Version, info.Version)
+	assert.NotEmpty(t, info.GitCommit)
+	assert.NotEmpty(t, info.GoVersion)
+	assert.Contains(t, info.APIVersions, "v1")
+}
@@ -0,0 +1,362 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hewenyu/kong-discovery/internal/config"
+	"github.com/hewenyu/kong-discovery/internal/etcdclient"
+	"go.uber.org/zap"
+)
+
+// electionName webhook投递使用的选举名称，多个节点只有领导者投递，避免重复回调
+const electionName = "webhook-dispatcher"
+
+// 投递请求的HTTP头
+const (
+	HeaderEvent     = "X-Kong-Discovery-Event"     // 事件类型
+	HeaderDelivery  = "X-Kong-Discovery-Delivery"  // 投递ID，重试时不变，接收方可据此去重
+	HeaderSignature = "X-Kong-Discovery-Signature" // 请求体的HMAC-SHA256签名，格式为 sha256=<hex>
+)
+
+// 默认的投递参数
+const (
+	defaultMaxAttempts   = 5
+	defaultTimeout       = 10 * time.Second
+	defaultRetryInterval = 2 * time.Second
+	maxRetryInterval     = time.Minute
+	campaignRetryDelay   = 5 * time.Second
+)
+
+// Payload 投递给回调地址的请求体
+type Payload struct {
+	ID        string          `json:"id"`                 // 投递ID
+	Event     string          `json:"event"`              // 事件类型
+	Timestamp time.Time       `json:"timestamp"`          // 事件被处理的时间
+	Key       string          `json:"key"`                // 变更的etcd键
+	Revision  int64           `json:"revision"`           // etcd修订版本
+	Data      json.RawMessage `json:"data,omitempty"`     // 变更后的服务实例或DNS记录，删除事件为删除前的值
+	Previous  json.RawMessage `json:"previous,omitempty"` // 变更前的值（健康状态变化和DNS记录更新）
+}
+
+// Dispatcher 定义webhook投递器接口
+type Dispatcher interface {
+	// Start 启动投递器（非阻塞）
+	Start() error
+
+	// Stop 停止投递器，等待进行中的投递结束
+	Stop(ctx context.Context) error
+}
+
+// WebhookDispatcher 监听发现数据的变更，将事件投递到订阅的webhook
+// 多个节点通过etcd选举产生唯一的领导者，只有领导者投递事件
+type WebhookDispatcher struct {
+	cfg           *config.Config
+	logger        config.Logger
+	client        etcdclient.Client
+	httpClient    *http.Client
+	candidate     string
+	maxAttempts   int
+	retryInterval time.Duration
+
+	deliveries sync.WaitGroup
+	cancel     context.CancelFunc
+	done       chan struct{}
+}
+
+// NewDispatcher 创建webhook投递器
+func NewDispatcher(cfg *config.Config, logger config.Logger, client etcdclient.Client) Dispatcher {
+	timeout := defaultTimeout
+	if cfg.Webhook.Timeout > 0 {
+		timeout = time.Duration(cfg.Webhook.Timeout) * time.Second
+	}
+	maxAttempts := defaultMaxAttempts
+	if cfg.Webhook.MaxAttempts > 0 {
+		maxAttempts = cfg.Webhook.MaxAttempts
+	}
+	retryInterval := defaultRetryInterval
+	if cfg.Webhook.RetryInterval > 0 {
+		retryInterval = time.Duration(cfg.Webhook.RetryInterval) * time.Second
+	}
+
+	hostname, _ := os.Hostname()
+	return &WebhookDispatcher{
+		cfg:           cfg,
+		logger:        logger,
+		client:        client,
+		httpClient:    &http.Client{Timeout: timeout},
+		candidate:     fmt.Sprintf("%s-%s", hostname, uuid.New().String()[:8]),
+		maxAttempts:   maxAttempts,
+		retryInterval: retryInterval,
+	}
+}
+
+// Start 启动投递器
+func (d *WebhookDispatcher) Start() error {
+	if d.done != nil {
+		return fmt.Errorf("webhook投递器已启动")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	d.cancel = cancel
+	d.done = make(chan struct{})
+
+	d.logger.Info("启动webhook投递器",
+		zap.String("candidate", d.candidate),
+		zap.Int("max_attempts", d.maxAttempts))
+
+	go d.run(ctx)
+	return nil
+}
+
+// Stop 停止投递器，未完成的重试会被放弃
+func (d *WebhookDispatcher) Stop(ctx context.Context) error {
+	if d.done == nil {
+		return nil
+	}
+
+	d.cancel()
+	select {
+	case <-d.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// run 循环参与选举，成为领导者后投递事件，领导权丢失后重新参与选举
+func (d *WebhookDispatcher) run(ctx context.Context) {
+	defer close(d.done)
+	defer d.deliveries.Wait()
+
+	for {
+		leadership, err := d.client.Campaign(ctx, electionName, d.candidate)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			d.logger.Warn("参与webhook投递器选举失败，稍后重试", zap.Error(err))
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(campaignRetryDelay):
+				continue
+			}
+		}
+
+		d.lead(ctx, leadership)
+		if ctx.Err() != nil {
+			return
+		}
+		d.logger.Warn("webhook投递器领导权丢失，重新参与选举")
+	}
+}
+
+// lead 作为领导者监听变更并投递，直到领导权丢失或投递器停止
+func (d *WebhookDispatcher) lead(ctx context.Context, leadership *etcdclient.Leadership) {
+	watchCtx, cancelWatch := context.WithCancel(ctx)
+	defer cancelWatch()
+
+	events, err := d.client.WatchEvents(watchCtx)
+	if err != nil {
+		d.logger.Error("监听变更事件失败", zap.Error(err))
+		resignCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		_ = leadership.Resign(resignCtx)
+		cancel()
+		return
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			resignCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			if err := leadership.Resign(resignCtx); err != nil {
+				d.logger.Warn("放弃webhook投递器领导权失败", zap.Error(err))
+			}
+			cancel()
+			return
+		case <-leadership.Done():
+			return
+		case event, ok := <-events:
+			if !ok {
+				d.logger.Warn("监听变更事件中断，重新参与选举")
+				resignCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				_ = leadership.Resign(resignCtx)
+				cancel()
+				return
+			}
+			d.dispatch(ctx, event)
+		}
+	}
+}
+
+// dispatch 把etcd变更转换为webhook事件，并投递到所有订阅的webhook
+func (d *WebhookDispatcher) dispatch(ctx context.Context, event *etcdclient.Event) {
+	eventType, ok := Translate(event)
+	if !ok {
+		return
+	}
+
+	webhooks, err := d.client.ListWebhooks(ctx)
+	if err != nil {
+		d.logger.Error("获取webhook列表失败，事件未投递", zap.String("event", eventType), zap.Error(err))
+		return
+	}
+
+	for _, webhook := range webhooks {
+		if !webhook.Subscribes(eventType) {
+			continue
+		}
+		payload := &Payload{
+			ID:        etcdclient.NewWebhookDeliveryID(),
+			Event:     eventType,
+			Timestamp: time.Now(),
+			Key:       event.Key,
+			Revision:  event.Revision,
+			Data:      event.Value,
+			Previous:  event.Previous,
+		}
+		d.deliveries.Add(1)
+		go func(webhook *etcdclient.Webhook) {
+			defer d.deliveries.Done()
+			d.deliver(ctx, webhook, payload)
+		}(webhook)
+	}
+}
+
+// Translate 把etcd变更转换为webhook事件类型，不需要投递的变更返回false
+func Translate(event *etcdclient.Event) (string, bool) {
+	switch event.Kind {
+	case "services":
+		if event.Type == etcdclient.EventDelete {
+			return etcdclient.WebhookServiceDeregistered, true
+		}
+		if event.Previous == nil {
+			return etcdclient.WebhookServiceRegistered, true
+		}
+		var before, after etcdclient.ServiceInstance
+		if json.Unmarshal(event.Previous, &before) != nil || json.Unmarshal(event.Value, &after) != nil {
+			return "", false
+		}
+		if before.HealthStatus() != after.HealthStatus() || before.Maintenance != after.Maintenance {
+			return etcdclient.WebhookHealthChanged, true
+		}
+		// 重复注册或其他字段的更新不投递
+		return "", false
+	case "dns":
+		// 定时变更本身不是DNS记录，执行时产生的记录变更会单独投递
+		if strings.HasPrefix(event.Key, "/dns/scheduled/") {
+			return "", false
+		}
+		return etcdclient.WebhookDNSRecordChanged, true
+	}
+	return "", false
+}
+
+// deliver 投递事件，失败时按指数退避重试，每次尝试后记录投递状态
+func (d *WebhookDispatcher) deliver(ctx context.Context, webhook *etcdclient.Webhook, payload *Payload) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		d.logger.Error("序列化webhook事件失败", zap.Error(err))
+		return
+	}
+
+	delivery := &etcdclient.WebhookDelivery{
+		ID:        payload.ID,
+		WebhookID: webhook.ID,
+		Event:     payload.Event,
+		Status:    etcdclient.DeliveryPending,
+		CreatedAt: payload.Timestamp,
+	}
+
+	interval := d.retryInterval
+	for {
+		delivery.Attempts++
+		delivery.ResponseCode, err = d.send(ctx, webhook, payload, body)
+		delivery.UpdatedAt = time.Now()
+		delivery.LastError = ""
+		switch {
+		case err == nil:
+			delivery.Status = etcdclient.DeliverySucceeded
+		case delivery.Attempts >= d.maxAttempts:
+			delivery.Status = etcdclient.DeliveryFailed
+			delivery.LastError = err.Error()
+		default:
+			delivery.LastError = err.Error()
+		}
+		d.record(delivery)
+
+		if delivery.Status != etcdclient.DeliveryPending {
+			if delivery.Status == etcdclient.DeliveryFailed {
+				d.logger.Warn("webhook投递失败",
+					zap.String("webhook", webhook.ID),
+					zap.String("event", payload.Event),
+					zap.Int("attempts", delivery.Attempts),
+					zap.Error(err))
+			}
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+		interval = min(interval*2, maxRetryInterval)
+	}
+}
+
+// send 发送一次回调请求，2xx状态码视为成功
+func (d *WebhookDispatcher) send(ctx context.Context, webhook *etcdclient.Webhook, payload *Payload, body []byte) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("创建请求失败: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "kong-discovery-webhook")
+	req.Header.Set(HeaderEvent, payload.Event)
+	req.Header.Set(HeaderDelivery, payload.ID)
+	if webhook.Secret != "" {
+		req.Header.Set(HeaderSignature, Sign(webhook.Secret, body))
+	}
+
+	resp, err := d.httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("回调地址返回状态码 %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// record 保存投递状态，投递器停止时仍然写入最后的状态
+func (d *WebhookDispatcher) record(delivery *etcdclient.WebhookDelivery) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := d.client.PutWebhookDelivery(ctx, delivery); err != nil {
+		d.logger.Warn("保存webhook投递状态失败", zap.String("delivery", delivery.ID), zap.Error(err))
+	}
+}
+
+// Sign 计算请求体的HMAC-SHA256签名，接收方使用相同的密钥验证请求来源
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
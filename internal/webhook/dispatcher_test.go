@@ -0,0 +1,230 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hewenyu/kong-discovery/internal/config"
+	"github.com/hewenyu/kong-discovery/internal/etcdclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// 创建测试用的配置
+func createTestConfig(t *testing.T) *config.Config {
+	t.Helper()
+
+	cfg := &config.Config{}
+	etcdclient.ApplyTestStorage(cfg)
+	cfg.Webhook.Enabled = true
+	cfg.Webhook.MaxAttempts = 3
+	cfg.Webhook.Timeout = 2
+	cfg.Webhook.RetryInterval = 1
+	return cfg
+}
+
+// 创建测试用的日志记录器
+func createTestLogger(t *testing.T) config.Logger {
+	t.Helper()
+
+	logger, err := config.NewLogger(true)
+	require.NoError(t, err, "创建测试日志记录器失败")
+	return logger
+}
+
+// instanceJSON 序列化服务实例
+func instanceJSON(t *testing.T, instance *etcdclient.ServiceInstance) json.RawMessage {
+	t.Helper()
+
+	data, err := json.Marshal(instance)
+	require.NoError(t, err)
+	return data
+}
+
+func TestTranslate(t *testing.T) {
+	passing := instanceJSON(t, &etcdclient.ServiceInstance{ServiceName: "svc", InstanceID: "i1", Port: 80})
+	critical := instanceJSON(t, &etcdclient.ServiceInstance{ServiceName: "svc", InstanceID: "i1", Port: 80, Health: etcdclient.HealthCritical})
+	maintenance := instanceJSON(t, &etcdclient.ServiceInstance{ServiceName: "svc", InstanceID: "i1", Port: 80, Maintenance: true})
+	moved := instanceJSON(t, &etcdclient.ServiceInstance{ServiceName: "svc", InstanceID: "i1", Port: 81})
+
+	tests := []struct {
+		name  string
+		event *etcdclient.Event
+		want  string
+	}{
+		{"注册", &etcdclient.Event{Type: etcdclient.EventPut, Kind: "services", Value: passing}, etcdclient.WebhookServiceRegistered},
+		{"注销", &etcdclient.Event{Type: etcdclient.EventDelete, Kind: "services", Value: passing}, etcdclient.WebhookServiceDeregistered},
+		{"健康状态变化", &etcdclient.Event{Type: etcdclient.EventPut, Kind: "services", Value: critical, Previous: passing}, etcdclient.WebhookHealthChanged},
+		{"进入维护模式", &etcdclient.Event{Type: etcdclient.EventPut, Kind: "services", Value: maintenance, Previous: passing}, etcdclient.WebhookHealthChanged},
+		{"其他字段更新", &etcdclient.Event{Type: etcdclient.EventPut, Kind: "services", Value: moved, Previous: passing}, ""},
+		{"重复注册", &etcdclient.Event{Type: etcdclient.EventPut, Kind: "services", Value: passing, Previous: passing}, ""},
+		{"DNS记录变更", &etcdclient.Event{Type: etcdclient.EventPut, Kind: "dns", Key: "/dns/records/example.com/A"}, etcdclient.WebhookDNSRecordChanged},
+		{"DNS记录删除", &etcdclient.Event{Type: etcdclient.EventDelete, Kind: "dns", Key: "/dns/records/example.com/A"}, etcdclient.WebhookDNSRecordChanged},
+		{"定时变更", &etcdclient.Event{Type: etcdclient.EventPut, Kind: "dns", Key: "/dns/scheduled/abc"}, ""},
+		{"配置变更", &etcdclient.Event{Type: etcdclient.EventPut, Kind: "config", Key: "/config/upstream"}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := Translate(tt.event)
+			assert.Equal(t, tt.want != "", ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestSign(t *testing.T) {
+	body := []byte(`{"event":"service.registered"}`)
+	signature := Sign("secret", body)
+	assert.Regexp(t, `^sha256=[0-9a-f]{64}$`, signature)
+	assert.Equal(t, signature, Sign("secret", body))
+	assert.NotEqual(t, signature, Sign("other", body))
+}
+
+// receiver 记录收到的回调请求，前failures次返回500
+type receiver struct {
+	mu       sync.Mutex
+	failures int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.requests = append(r.requests, req)
+	r.bodies = append(r.bodies, body)
+	if len(r.requests) <= r.failures {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (r *receiver) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.requests)
+}
+
+func TestWebhookDispatcher_DeliverRetries(t *testing.T) {
+	// 跳过集成测试，除非明确要求运行
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	client := etcdclient.CreateEtcdClientForTest(t)
+	t.Cleanup(func() { _ = client.Close() })
+
+	recv := &receiver{failures: 1}
+	server := httptest.NewServer(recv)
+	defer server.Close()
+
+	webhook := &etcdclient.Webhook{ID: fmt.Sprintf("deliver-test-%d", time.Now().UnixNano()), URL: server.URL, Secret: "secret"}
+	t.Cleanup(func() { _ = client.DeleteWebhook(context.Background(), webhook.ID) })
+	require.NoError(t, client.PutWebhook(context.Background(), webhook))
+
+	d := NewDispatcher(createTestConfig(t), createTestLogger(t), client).(*WebhookDispatcher)
+	payload := &Payload{
+		ID:        etcdclient.NewWebhookDeliveryID(),
+		Event:     etcdclient.WebhookServiceRegistered,
+		Timestamp: time.Now(),
+		Key:       "/services/svc/i1",
+	}
+	d.deliver(context.Background(), webhook, payload)
+
+	// 第一次失败，第二次成功，两次请求的投递ID相同且签名有效
+	require.Equal(t, 2, recv.count())
+	for i, req := range recv.requests {
+		assert.Equal(t, payload.ID, req.Header.Get(HeaderDelivery))
+		assert.Equal(t, etcdclient.WebhookServiceRegistered, req.Header.Get(HeaderEvent))
+		assert.Equal(t, Sign("secret", recv.bodies[i]), req.Header.Get(HeaderSignature))
+	}
+
+	deliveries, err := client.ListWebhookDeliveries(context.Background(), webhook.ID)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	assert.Equal(t, etcdclient.DeliverySucceeded, deliveries[0].Status)
+	assert.Equal(t, 2, deliveries[0].Attempts)
+	assert.Equal(t, http.StatusNoContent, deliveries[0].ResponseCode)
+	assert.Empty(t, deliveries[0].LastError)
+
+	// 始终失败时重试次数用尽后记录为失败
+	recv.failures = 100
+	failing := *payload
+	failing.ID = etcdclient.NewWebhookDeliveryID()
+	d.deliver(context.Background(), webhook, &failing)
+
+	deliveries, err = client.ListWebhookDeliveries(context.Background(), webhook.ID)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 2)
+	assert.Equal(t, failing.ID, deliveries[0].ID)
+	assert.Equal(t, etcdclient.DeliveryFailed, deliveries[0].Status)
+	assert.Equal(t, 3, deliveries[0].Attempts)
+	assert.Equal(t, http.StatusInternalServerError, deliveries[0].ResponseCode)
+	assert.NotEmpty(t, deliveries[0].LastError)
+}
+
+func TestWebhookDispatcher_Start(t *testing.T) {
+	// 跳过集成测试，除非明确要求运行
+	if testing.Short() {
+		t.Skip("跳过集成测试")
+	}
+
+	client := etcdclient.CreateEtcdClientForTest(t)
+	t.Cleanup(func() { _ = client.Close() })
+
+	recv := &receiver{}
+	server := httptest.NewServer(recv)
+	defer server.Close()
+
+	webhook := &etcdclient.Webhook{
+		ID:     fmt.Sprintf("start-test-%d", time.Now().UnixNano()),
+		URL:    server.URL,
+		Events: []string{etcdclient.WebhookServiceRegistered, etcdclient.WebhookServiceDeregistered},
+	}
+	t.Cleanup(func() { _ = client.DeleteWebhook(context.Background(), webhook.ID) })
+	require.NoError(t, client.PutWebhook(context.Background(), webhook))
+
+	d := NewDispatcher(createTestConfig(t), createTestLogger(t), client)
+	require.NoError(t, d.Start())
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		assert.NoError(t, d.Stop(ctx))
+	}()
+
+	// 等待成为领导者并开始监听
+	time.Sleep(500 * time.Millisecond)
+
+	serviceName := fmt.Sprintf("webhook-service-%d", time.Now().UnixNano())
+	instance := &etcdclient.ServiceInstance{ServiceName: serviceName, InstanceID: "instance-001", IPAddress: "10.0.0.1", Port: 8080, TTL: 30}
+	require.NoError(t, client.RegisterService(context.Background(), instance))
+	require.NoError(t, client.DeregisterService(context.Background(), serviceName, "instance-001"))
+
+	require.Eventually(t, func() bool { return recv.count() >= 2 }, 5*time.Second, 50*time.Millisecond)
+
+	recv.mu.Lock()
+	defer recv.mu.Unlock()
+	var events []string
+	for i, body := range recv.bodies {
+		var payload Payload
+		require.NoError(t, json.Unmarshal(body, &payload))
+		if payload.Key == "/services/"+serviceName+"/instance-001" {
+			events = append(events, payload.Event)
+		}
+		// 未设置密钥时不签名
+		assert.Empty(t, recv.requests[i].Header.Get(HeaderSignature))
+	}
+	// 每个事件单独投递，到达顺序不保证
+	assert.ElementsMatch(t, []string{etcdclient.WebhookServiceRegistered, etcdclient.WebhookServiceDeregistered}, events)
+}
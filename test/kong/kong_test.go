@@ -0,0 +1,188 @@
+//go:build kong
+
+// Package kong 验证kong-discovery作为Kong网关dns_resolver时的行为
+//
+// 测试需要 docker-compose.yml 中的环境，通过 make kong-test 启动并运行：
+// Kong的上游主机名指向kong-discovery中的服务，测试注册实例后经Kong代理发送请求，
+// 根据whoami后端返回的主机名统计流量的分配
+package kong
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// 测试后端在docker网络中的地址
+var backends = map[string]string{
+	"whoami-1": "172.30.0.21",
+	"whoami-2": "172.30.0.22",
+	"whoami-3": "172.30.0.23",
+}
+
+// env 返回环境变量，未设置时使用默认值
+func env(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+var (
+	proxyURL        = env("KONG_PROXY_URL", "http://localhost:18000")
+	registrationURL = env("KONG_DISCOVERY_REGISTRATION_URL", "http://localhost:18081")
+	managementURL   = env("KONG_DISCOVERY_MANAGEMENT_URL", "http://localhost:18080")
+)
+
+var httpClient = &http.Client{Timeout: 5 * time.Second}
+
+// call 发送JSON请求并检查状态码
+func call(t *testing.T, method, url string, body interface{}) {
+	t.Helper()
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		require.NoError(t, err)
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, url, reader)
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := httpClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	require.Less(t, resp.StatusCode, 300, "%s %s: %s", method, url, data)
+}
+
+// register 注册whoami后端为whoami服务的实例
+func register(t *testing.T, backend string, metadata map[string]string) {
+	t.Helper()
+	call(t, http.MethodPost, registrationURL+"/services/register", map[string]interface{}{
+		"service_name": "whoami",
+		"instance_id":  backend,
+		"ip_address":   backends[backend],
+		"port":         80,
+		"ttl":          60,
+		"dns_ttl":      2,
+		"metadata":     metadata,
+	})
+	t.Cleanup(func() { deregister(backend) })
+}
+
+// deregister 注销实例，实例不存在时忽略
+func deregister(backend string) {
+	req, _ := http.NewRequest(http.MethodDelete, registrationURL+"/services/whoami/"+backend, nil)
+	if resp, err := httpClient.Do(req); err == nil {
+		resp.Body.Close()
+	}
+}
+
+// hit 经Kong代理发送请求，返回处理请求的后端，请求失败时返回空字符串
+func hit(path string) string {
+	resp, err := httpClient.Get(proxyURL + path)
+	if err != nil {
+		return ""
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return ""
+	}
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		if host, ok := strings.CutPrefix(scanner.Text(), "Hostname: "); ok {
+			return strings.TrimSpace(host)
+		}
+	}
+	return ""
+}
+
+// distribution 发送n个请求，统计每个后端处理的请求数
+func distribution(path string, n int) map[string]int {
+	counts := make(map[string]int)
+	for i := 0; i < n; i++ {
+		counts[hit(path)]++
+	}
+	return counts
+}
+
+// waitFor 在超时前反复检查条件
+func waitFor(t *testing.T, timeout time.Duration, msg string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+	t.Fatalf("超时: %s", msg)
+}
+
+func TestKong_SRVWeightAndPriority(t *testing.T) {
+	// whoami-3的优先级数值更大，只作为备用，不接收流量；whoami-2的权重是whoami-1的3倍
+	register(t, "whoami-1", map[string]string{"srv_weight": "10"})
+	register(t, "whoami-2", map[string]string{"srv_weight": "30"})
+	register(t, "whoami-3", map[string]string{"srv_priority": "20"})
+
+	waitFor(t, 20*time.Second, "Kong开始把请求转发到whoami服务", func() bool {
+		counts := distribution("/srv", 20)
+		return counts["whoami-1"] > 0 && counts["whoami-2"] > 0
+	})
+
+	counts := distribution("/srv", 400)
+	t.Logf("请求分配: %v", counts)
+	assert.Zero(t, counts[""], "请求失败")
+	assert.Zero(t, counts["whoami-3"], "备用实例不应接收流量")
+	require.NotZero(t, counts["whoami-1"])
+	ratio := float64(counts["whoami-2"]) / float64(counts["whoami-1"])
+	assert.InDelta(t, 3.0, ratio, 1.0, "流量比例应接近权重比例")
+}
+
+func TestKong_AddressFallback(t *testing.T) {
+	// 只有A记录的域名：SRV查询返回NODATA，Kong继续按dns_order查询A记录
+	call(t, http.MethodPut, managementURL+"/admin/dns/records/legacy.kong.test", map[string]interface{}{
+		"type":  "A",
+		"value": backends["whoami-1"],
+		"ttl":   2,
+	})
+	t.Cleanup(func() {
+		req, _ := http.NewRequest(http.MethodDelete, managementURL+"/admin/dns/records/legacy.kong.test/A", nil)
+		if resp, err := httpClient.Do(req); err == nil {
+			resp.Body.Close()
+		}
+	})
+
+	waitFor(t, 20*time.Second, "Kong通过A记录转发请求", func() bool {
+		return hit("/legacy") == "whoami-1"
+	})
+	assert.Equal(t, map[string]int{"whoami-1": 50}, distribution("/legacy", 50))
+}
+
+func TestKong_TTLRefresh(t *testing.T) {
+	register(t, "whoami-1", nil)
+	register(t, "whoami-2", nil)
+
+	waitFor(t, 20*time.Second, "Kong把请求转发到两个实例", func() bool {
+		counts := distribution("/srv", 20)
+		return counts["whoami-1"] > 0 && counts["whoami-2"] > 0
+	})
+
+	// 注销后实例在TTL（2秒）到期、Kong重新解析后不再接收流量
+	deregister("whoami-2")
+	start := time.Now()
+	waitFor(t, 15*time.Second, "Kong在TTL到期后停止转发到已注销的实例", func() bool {
+		return distribution("/srv", 20)["whoami-2"] == 0
+	})
+	t.Logf("注销在%s后生效", time.Since(start).Round(100*time.Millisecond))
+	assert.Equal(t, map[string]int{"whoami-1": 100}, distribution("/srv", 100), "注销后的请求分配")
+}